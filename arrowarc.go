@@ -0,0 +1,372 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package arrowarc is the library interface to ArrowArc. A Flow reads
+// records from an endpoint, transforms them and writes them to another:
+//
+//	res, err := arrowarc.Read("events.csv").
+//		Filter("amount > 100").
+//		Transform(masker).
+//		Write(ctx, "gs://bucket/events.parquet")
+//
+// Endpoints are the URIs of package endpoints: file paths, format schemes
+// such as parquet://, object stores, databases, Flight servers and the
+// schemes other modules register. ReadFrom and WriteTo take readers and
+// writers instead.
+package arrowarc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/arrowarc/arrowarc/internal/flow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/endpoints"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+	"github.com/arrowarc/arrowarc/pkg/history"
+	"github.com/arrowarc/arrowarc/pkg/limit"
+	"github.com/arrowarc/arrowarc/pkg/sample"
+)
+
+// Reader returns records until io.EOF.
+type Reader = interfaces.Reader
+
+// Writer writes records.
+type Writer = interfaces.Writer
+
+// Transformer rewrites each record. It does not own the record it is
+// given, and returns a record the caller owns, or nil to drop it.
+type Transformer = interfaces.Transformer
+
+// TransformFunc adapts a function to a Transformer.
+type TransformFunc func(arrow.Record) (arrow.Record, error)
+
+// Transform calls f(record).
+func (f TransformFunc) Transform(record arrow.Record) (arrow.Record, error) {
+	return f(record)
+}
+
+// Result is the outcome of a run.
+type Result struct {
+	// RunID is the ID the run's log entries carry.
+	RunID string
+	// Report is the pipeline's JSON report.
+	Report string
+	// Metrics are the pipeline's counters.
+	Metrics *pipeline.Metrics
+}
+
+// Flow is a run being built. Its methods return the flow itself, and the
+// first error one of them meets is returned by Write or WriteTo.
+type Flow struct {
+	open         func(ctx context.Context, opts *endpoints.Options) (Reader, error)
+	source       string // the input endpoint, for the history of runs
+	opts         endpoints.Options
+	offset       int64
+	limit        int64
+	sample       float64
+	seed         int64
+	transformers []Transformer
+	monitor      pipeline.Monitor
+	progress     func(pipeline.ProgressEvent)
+	memoryLimit  int64
+	spill        int64
+	spillDir     string
+	batch        *pipeline.BatchPolicy
+	verify       bool
+	onEmpty      pipeline.EmptyPolicy
+	err          error
+}
+
+// Read starts a flow reading the endpoint uri names.
+func Read(uri string) *Flow {
+	f := &Flow{open: func(ctx context.Context, opts *endpoints.Options) (Reader, error) {
+		return endpoints.NewReader(ctx, uri, opts)
+	}, source: uri}
+	if uri == "" {
+		f.err = errors.New("input endpoint cannot be empty")
+	}
+	return f
+}
+
+// ReadFrom starts a flow reading r, which it closes.
+func ReadFrom(r Reader) *Flow {
+	f := &Flow{open: func(context.Context, *endpoints.Options) (Reader, error) {
+		return r, nil
+	}}
+	if r == nil {
+		f.err = errors.New("reader cannot be nil")
+	}
+	return f
+}
+
+// Options configures how the flow's endpoints are opened.
+func (f *Flow) Options(opts endpoints.Options) *Flow {
+	f.opts = opts
+	return f
+}
+
+// Offset skips the first n rows read.
+func (f *Flow) Offset(n int64) *Flow {
+	if n < 0 {
+		f.fail(fmt.Errorf("offset cannot be negative, got %d", n))
+	}
+	f.offset = n
+	return f
+}
+
+// Limit stops reading after n rows, past the offset.
+func (f *Flow) Limit(n int64) *Flow {
+	if n < 0 {
+		f.fail(fmt.Errorf("limit cannot be negative, got %d", n))
+	}
+	f.limit = n
+	return f
+}
+
+// Sample keeps each row read with probability fraction, drawn at random
+// with seed.
+func (f *Flow) Sample(fraction float64, seed int64) *Flow {
+	if fraction < 0 || fraction > 1 {
+		f.fail(fmt.Errorf("the sample fraction must be between 0 and 1, got %v", fraction))
+	}
+	f.sample, f.seed = fraction, seed
+	return f
+}
+
+// Filter keeps the rows matching expr, a filter.Parse expression such as
+// "country = 'NL' AND amount > 100". It runs in order with Transform.
+func (f *Flow) Filter(expr string) *Flow {
+	e, err := filter.Parse(expr)
+	if err != nil {
+		f.fail(fmt.Errorf("invalid filter %q: %w", expr, err))
+		return f
+	}
+	return f.Transform(&filterTransformer{expr: e})
+}
+
+// Transform adds transformers, run in order on each record.
+func (f *Flow) Transform(ts ...Transformer) *Flow {
+	for _, t := range ts {
+		if t == nil {
+			f.fail(errors.New("transformer cannot be nil"))
+			return f
+		}
+	}
+	f.transformers = append(f.transformers, ts...)
+	return f
+}
+
+// Monitor follows the run with m. Runs are unmonitored unless one is set.
+func (f *Flow) Monitor(m pipeline.Monitor) *Flow {
+	f.monitor = m
+	return f
+}
+
+// Progress calls fn with the progress of the run every second, and once
+// it ends, as pipeline.DataPipeline.WithProgress does.
+func (f *Flow) Progress(fn func(pipeline.ProgressEvent)) *Flow {
+	f.progress = fn
+	return f
+}
+
+// MemoryLimit fails the run once its pipeline allocates more than limit
+// bytes, as pipeline.DataPipeline.WithMemoryLimit does. Only the batching
+// and spill buffers count: readers and writers allocate elsewhere.
+func (f *Flow) MemoryLimit(limit int64) *Flow {
+	f.memoryLimit = limit
+	return f
+}
+
+// Spill spills the records queued for a slower writer past threshold
+// bytes to files in dir, as pipeline.DataPipeline.WithSpill does.
+func (f *Flow) Spill(threshold int64, dir string) *Flow {
+	f.spill, f.spillDir = threshold, dir
+	return f
+}
+
+// Batch coalesces the records read into batches bounded by policy before
+// they are written, as pipeline.DataPipeline.WithBatching does.
+func (f *Flow) Batch(policy pipeline.BatchPolicy) *Flow {
+	if err := policy.Validate(); err != nil {
+		f.fail(err)
+	}
+	f.batch = &policy
+	return f
+}
+
+// Verify reads the output back once written by Write, and fails the run
+// unless it holds the rows written.
+func (f *Flow) Verify() *Flow {
+	f.verify = true
+	return f
+}
+
+// OnEmpty sets what the flow writes when its source yields no rows: by
+// default, an empty output with the schema of the records, or nothing with
+// pipeline.EmptySkip, or nothing and a pipeline.ErrEmptyResult error with
+// pipeline.EmptyFail.
+func (f *Flow) OnEmpty(policy pipeline.EmptyPolicy) *Flow {
+	if _, err := pipeline.ParseEmptyPolicy(string(policy)); err != nil {
+		f.fail(err)
+	}
+	f.onEmpty = policy
+	return f
+}
+
+// Write runs the flow into the endpoint uri names, created for the schema
+// of the records written.
+func (f *Flow) Write(ctx context.Context, uri string) (*Result, error) {
+	if uri == "" {
+		f.fail(errors.New("output endpoint cannot be empty"))
+	}
+	var verify func(ctx context.Context) (interfaces.Reader, error)
+	if f.verify {
+		verify = func(ctx context.Context) (interfaces.Reader, error) {
+			return endpoints.NewReader(ctx, uri, &f.opts)
+		}
+	}
+	return f.run(ctx, uri, func(ctx context.Context, schema *arrow.Schema) (interfaces.Writer, error) {
+		return endpoints.NewWriter(ctx, uri, schema, &f.opts)
+	}, verify)
+}
+
+// WriteTo runs the flow into w, which it closes. Verify does not apply.
+func (f *Flow) WriteTo(ctx context.Context, w Writer) (*Result, error) {
+	if w == nil {
+		f.fail(errors.New("writer cannot be nil"))
+	}
+	if f.verify {
+		f.fail(errors.New("cannot verify the output of a writer"))
+	}
+	return f.run(ctx, "", func(context.Context, *arrow.Schema) (interfaces.Writer, error) {
+		return w, nil
+	}, nil)
+}
+
+func (f *Flow) run(ctx context.Context, destination string, create func(context.Context, *arrow.Schema) (interfaces.Writer, error), verify func(context.Context) (interfaces.Reader, error)) (*Result, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var p *pipeline.DataPipeline
+	report, err := flow.Run(ctx, flow.Spec{
+		Open:         f.openReader,
+		Create:       create,
+		Transformers: f.transformers,
+		OnEmpty:      f.onEmpty,
+		History: history.Run{
+			Source:      history.RedactURI(f.source),
+			Destination: history.RedactURI(destination),
+		},
+		Configure: func(dp *pipeline.DataPipeline) {
+			p = dp
+			dp.WithMonitor(f.monitor)
+			if f.progress != nil {
+				dp.WithProgress(f.progress)
+			}
+			if f.memoryLimit > 0 {
+				dp.WithMemoryLimit(f.memoryLimit)
+			}
+			if f.spill > 0 {
+				dp.WithSpill(f.spill, f.spillDir)
+			}
+			if f.batch != nil {
+				dp.WithBatching(*f.batch)
+			}
+			if verify != nil {
+				dp.WithVerification(verify)
+			}
+		},
+	})
+	if p == nil {
+		return nil, err
+	}
+	return &Result{RunID: p.RunID(), Report: report, Metrics: p.Metrics()}, err
+}
+
+// openReader opens the flow's reader, restricted to its offset, limit and
+// sample.
+func (f *Flow) openReader(ctx context.Context) (interfaces.Reader, error) {
+	reader, err := f.open(ctx, &f.opts)
+	if err != nil {
+		return nil, err
+	}
+	if f.offset > 0 || f.limit > 0 {
+		limited, err := limit.NewReader(reader, f.offset, f.limit)
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+		reader = limited
+	}
+	if f.sample > 0 && f.sample < 1 {
+		sampled, err := sample.NewSamplingReader(reader, f.sample, f.seed)
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+		reader = sampled
+	}
+	return reader, nil
+}
+
+func (f *Flow) fail(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+// filterTransformer keeps the rows of each record matching expr.
+type filterTransformer struct {
+	expr filter.Expr
+}
+
+func (t *filterTransformer) Name() string {
+	return "filter"
+}
+
+// Schema returns src: filtering keeps the columns.
+func (t *filterTransformer) Schema(src *arrow.Schema) (*arrow.Schema, error) {
+	return src, nil
+}
+
+func (t *filterTransformer) Transform(record arrow.Record) (arrow.Record, error) {
+	filtered, err := filter.Apply(context.Background(), record, t.expr)
+	if err != nil {
+		return nil, err
+	}
+	if filtered.NumRows() == 0 {
+		filtered.Release()
+		return nil, nil
+	}
+	return filtered, nil
+}
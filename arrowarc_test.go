@@ -0,0 +1,218 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrowarc
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pkg/endpoints"
+)
+
+// sliceReader returns its records, one per Read.
+type sliceReader struct {
+	schema  *arrow.Schema
+	records []arrow.Record
+}
+
+func (r *sliceReader) Schema() *arrow.Schema { return r.schema }
+
+func (r *sliceReader) Read() (arrow.Record, error) {
+	if len(r.records) == 0 {
+		return nil, io.EOF
+	}
+	rec := r.records[0]
+	r.records = r.records[1:]
+	return rec, nil
+}
+
+func (r *sliceReader) Close() error { return nil }
+
+// collectWriter keeps the records written to it.
+type collectWriter struct {
+	records []arrow.Record
+	closed  bool
+}
+
+func (w *collectWriter) Write(rec arrow.Record) error {
+	rec.Retain()
+	w.records = append(w.records, rec)
+	return nil
+}
+
+func (w *collectWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+// ids returns the values of the id column of records.
+func ids(records []arrow.Record) []string {
+	var got []string
+	for _, rec := range records {
+		col := rec.Column(rec.Schema().FieldIndices("id")[0])
+		for i := 0; i < col.Len(); i++ {
+			got = append(got, col.ValueStr(i))
+		}
+	}
+	return got
+}
+
+func testReader(batches ...[]int64) *sliceReader {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+	}, nil)
+	r := &sliceReader{schema: schema}
+	for _, batch := range batches {
+		b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+		for _, id := range batch {
+			b.Field(0).(*array.Int64Builder).Append(id)
+			b.Field(1).(*array.StringBuilder).Append(strings.Repeat("x", int(id)))
+		}
+		r.records = append(r.records, b.NewRecord())
+		b.Release()
+	}
+	return r
+}
+
+func readAll(t *testing.T, uri string) []arrow.Record {
+	t.Helper()
+	r, err := endpoints.NewReader(context.Background(), uri, nil)
+	if err != nil {
+		t.Fatalf("NewReader(%q): %v", uri, err)
+	}
+	defer r.Close()
+	var records []arrow.Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec.Retain()
+		records = append(records, rec)
+	}
+}
+
+func TestReadTransformWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "in.csv")
+	if err := os.WriteFile(csvPath, []byte("id,name\n1,a\n2,b\n3,c\n4,d\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keep the id column only, so the output has the transformed schema.
+	project := TransformFunc(func(rec arrow.Record) (arrow.Record, error) {
+		schema := arrow.NewSchema([]arrow.Field{rec.Schema().Field(0)}, nil)
+		return array.NewRecord(schema, rec.Columns()[:1], rec.NumRows()), nil
+	})
+	out := filepath.Join(dir, "out.parquet")
+	res, err := Read(csvPath).Filter("id > 1").Transform(project).Monitor(nil).Verify().Write(ctx, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RunID == "" || res.Report == "" || res.Metrics == nil {
+		t.Errorf("incomplete result %+v", res)
+	}
+
+	got := readAll(t, out)
+	if want := []string{"2", "3", "4"}; !reflect.DeepEqual(ids(got), want) {
+		t.Errorf("ids = %v, want %v", ids(got), want)
+	}
+	if n := got[0].NumCols(); n != 1 {
+		t.Errorf("output has %d columns, want 1", n)
+	}
+}
+
+func TestReadFromWriteTo(t *testing.T) {
+	w := &collectWriter{}
+	_, err := ReadFrom(testReader([]int64{1, 2, 3}, []int64{4, 5, 6})).
+		Offset(2).
+		Limit(3).
+		Monitor(nil).
+		WriteTo(context.Background(), w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"3", "4", "5"}; !reflect.DeepEqual(ids(w.records), want) {
+		t.Errorf("ids = %v, want %v", ids(w.records), want)
+	}
+	if !w.closed {
+		t.Error("the writer was not closed")
+	}
+}
+
+func TestWriteWithoutRows(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.parquet")
+	// Filtering out every row still writes the output, with the schema
+	// of the input.
+	if _, err := ReadFrom(testReader([]int64{1, 2})).Filter("id > 10").Monitor(nil).Write(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(readAll(t, out)); len(got) != 0 {
+		t.Errorf("ids = %v, want none", got)
+	}
+}
+
+func TestFlowErrors(t *testing.T) {
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "out.parquet")
+	tests := []struct {
+		name string
+		run  func() (*Result, error)
+		want string
+	}{
+		{"empty input", func() (*Result, error) { return Read("").Write(ctx, out) }, "input endpoint"},
+		{"empty output", func() (*Result, error) { return ReadFrom(testReader()).Write(ctx, "") }, "output endpoint"},
+		{"filter", func() (*Result, error) { return ReadFrom(testReader()).Filter("id >").Write(ctx, out) }, "invalid filter"},
+		{"sample", func() (*Result, error) { return ReadFrom(testReader()).Sample(2, 0).Write(ctx, out) }, "sample fraction"},
+		{"verify writer", func() (*Result, error) { return ReadFrom(testReader()).Verify().WriteTo(ctx, &collectWriter{}) }, "cannot verify"},
+		{"scheme", func() (*Result, error) { return Read("nosuch://host/x").Write(ctx, out) }, "nosuch"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.run()
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want one mentioning %q", err, test.want)
+			}
+		})
+	}
+}
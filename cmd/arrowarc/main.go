@@ -1,13 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
 	cli "github.com/arrowarc/arrowarc/internal/cli"
+	"github.com/arrowarc/arrowarc/internal/ui"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		// Interrupting cancels the command's context, which stops its
+		// readers and closes its writers.
+		ctx, stop := ui.NotifyInterrupt(context.Background())
+		err := cli.RunArgs(ctx, os.Args[1:])
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	if err := cli.RunMenu(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
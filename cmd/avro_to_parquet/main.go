@@ -33,10 +33,15 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/apache/arrow-go/v18/parquet/compress"
 	converter "github.com/arrowarc/arrowarc/converter"
+	"github.com/arrowarc/arrowarc/internal/ui"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/projection"
 	"github.com/docopt/docopt-go"
 )
 
@@ -44,15 +49,26 @@ func main() {
 	usage := `Avro to Parquet Converter.
 
 Usage:
-  avro_to_parquet --avro=<avro_file> --parquet=<parquet_file> [--chunk-size=<bytes>] [--compression=<type>]
+  avro_to_parquet --avro=<avro_file> --parquet=<parquet_file> [--chunk-size=<bytes>] [--compression=<type>] [--concurrency=<n>] [--ordered] [--reproducible] [--partition-by=<col1,col2,...> [--resume]] [--select=<col1,col2,...>] [--rename=<old=new,...>] [--spill-threshold=<size> [--spill-dir=<dir>]] [--no-tui]
   avro_to_parquet -h | --help
 
 Options:
   -h --help                                 Show this screen.
-  --avro=<avro_file>                        Path to the input Avro file.
+  --avro=<avro_file>                        Input Avro file, directory or glob (e.g. 'data/*.avro'), or - for stdin.
   --parquet=<parquet_file>                  Path to the output Parquet file.
   --chunk-size=<bytes>                      Number of bytes to read per chunk [default: 8192].
   --compression=<type>                      Compression type to use (e.g., none, snappy, gzip) [default: snappy].
+  --concurrency=<n>                         Number of input files to read concurrently [default: 4].
+  --ordered                                 Write the records of files read concurrently in file order, for reproducible output.
+  --reproducible                            Alias for --ordered: write the same bytes whenever the same input is converted.
+  --select=<col1,col2,...>                  Columns to keep, in the order written.
+  --rename=<old=new,...>                    Columns to rename in the output.
+  --no-tui                                  Log progress lines instead of the live progress view.
+  --spill-threshold=<size>                  Spill the records queued for a slow output past this size to disk, e.g. 256MiB.
+  --spill-dir=<dir>                         Directory to spill records to, the system's temporary directory by default.
+  --partition-by=<col1,col2,...>            Write Hive-style partitions under the output directory.
+  --resume                                  Skip the input files the output's manifest lists as completed and replace
+                                            the files of incomplete ones, to restart an interrupted conversion.
 `
 
 	arguments, err := docopt.ParseDoc(usage)
@@ -64,6 +80,19 @@ Options:
 	parquetFilePath, _ := arguments.String("--parquet")
 	chunkSize, _ := arguments.Int("--chunk-size")
 	compressionTypeStr, _ := arguments.String("--compression")
+	concurrency, _ := arguments.Int("--concurrency")
+	ordered, _ := arguments.Bool("--ordered")
+	reproducible, _ := arguments.Bool("--reproducible")
+	noTUI, _ := arguments.Bool("--no-tui")
+	var spillThreshold int64
+	if v, _ := arguments.String("--spill-threshold"); v != "" {
+		if spillThreshold, err = config.ParseByteSize(v); err != nil || spillThreshold <= 0 {
+			log.Fatalf("Invalid --spill-threshold: %q", v)
+		}
+	}
+	spillDir, _ := arguments.String("--spill-dir")
+	partitionBy, _ := arguments.String("--partition-by")
+	resume, _ := arguments.Bool("--resume")
 
 	// Map compression type to the appropriate constant
 	var compressionType compress.Compression
@@ -82,19 +111,55 @@ Options:
 		log.Fatalf("Invalid compression type: %s", compressionTypeStr)
 	}
 
+	selected, _ := arguments.String("--select")
+	renamed, _ := arguments.String("--rename")
+	project, err := projection.Parse(selected, renamed)
+	if err != nil {
+		log.Fatalf("Error parsing arguments: %v", err)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
+	// Interrupting stops the run and closes the output written so far.
+	ctx, stop := ui.NotifyInterrupt(ctx)
+	defer stop()
 
-	metrics, err := converter.ConvertAvroToParquet(
+	metrics, err := converter.ConvertAvroToParquetWithOptions(
 		ctx,
 		avroFilePath,
 		parquetFilePath,
-		int64(chunkSize),
-		compressionType,
+		&converter.AvroToParquetOptions{
+			ChunkSize:      int64(chunkSize),
+			Compression:    &compressionType,
+			Concurrency:    concurrency,
+			PartitionBy:    parseCommaSeparatedList(partitionBy),
+			Resume:         resume,
+			Project:        project,
+			Ordered:        ordered,
+			Reproducible:   reproducible,
+			Monitor:        ui.NewProgressMonitor("Avro to Parquet", os.Stderr, noTUI),
+			SpillThreshold: spillThreshold,
+			SpillDir:       spillDir,
+		},
 	)
 	if err != nil {
+		if metrics != "" {
+			fmt.Fprintf(os.Stderr, "Conversion failed. Summary: %s\n", metrics)
+		}
 		log.Fatalf("Failed to convert Avro to Parquet: %v", err)
 	}
 
-	fmt.Printf("Conversion completed. Summary: %s\n", metrics)
+	if metrics == "" {
+		fmt.Fprintln(os.Stderr, "Nothing to convert: the manifest lists every input as completed.")
+		return
+	}
+	// Report on stderr so that output written to stdout stays clean.
+	fmt.Fprintf(os.Stderr, "Conversion completed. Summary: %s\n", metrics)
+}
+
+func parseCommaSeparatedList(input string) []string {
+	if input == "" {
+		return nil
+	}
+	return strings.Split(input, ",")
 }
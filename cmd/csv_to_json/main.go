@@ -33,10 +33,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 	"time"
 
 	converter "github.com/arrowarc/arrowarc/converter"
+	"github.com/arrowarc/arrowarc/internal/ui"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/projection"
 	"github.com/docopt/docopt-go"
 )
 
@@ -44,18 +48,25 @@ func main() {
 	usage := `CSV to JSON Converter.
 
 Usage:
-  csv_to_json --csv=<csv_file> --json=<json_file> [--header=<true|false>] [--chunk-size=<bytes>] [--delimiter=<char>] [--null=<value>] [--strings-can-be-null=<true|false>]
+  csv_to_json --csv=<csv_file> --json=<json_file> [--header=<true|false>] [--chunk-size=<bytes>] [--delimiter=<char>] [--null=<value>] [--strings-can-be-null=<true|false>] [--concurrency=<n>] [--ordered] [--select=<col1,col2,...>] [--rename=<old=new,...>] [--spill-threshold=<size> [--spill-dir=<dir>]] [--no-tui]
   csv_to_json -h | --help
 
 Options:
   -h --help                             Show this screen.
-  --csv=<csv_file>                      Path to the input CSV file.
+  --csv=<csv_file>                      Input CSV file, directory or glob (e.g. 'data/*.csv'), or - for stdin.
   --json=<json_file>                    Path to the output JSON file.
   --header=<true|false>                 Indicates if the CSV file has a header [default: true].
   --chunk-size=<bytes>                  Number of bytes to read per chunk [default: 1024].
   --delimiter=<char>                    Delimiter used in the CSV file [default: ,].
   --null=<value>                        Value to be considered as null [default: null].
   --strings-can-be-null=<true|false>   Indicates if strings can be considered as null [default: false].
+  --concurrency=<n>                     Number of input files to read concurrently [default: 4].
+  --ordered                             Write the records of files read concurrently in file order, for reproducible output.
+  --select=<col1,col2,...>              Columns to keep, in the order written.
+  --rename=<old=new,...>                Columns to rename in the output.
+  --no-tui                              Log progress lines instead of the live progress view.
+  --spill-threshold=<size>              Spill the records queued for a slow output past this size to disk, e.g. 256MiB.
+  --spill-dir=<dir>                     Directory to spill records to, the system's temporary directory by default.
 `
 
 	arguments, err := docopt.ParseDoc(usage)
@@ -70,13 +81,49 @@ Options:
 	delimiter, _ := arguments.String("--delimiter")
 	nullValues, _ := arguments.String("--null")
 	stringsCanBeNull, _ := arguments.Bool("--strings-can-be-null")
+	concurrency, _ := arguments.Int("--concurrency")
+	ordered, _ := arguments.Bool("--ordered")
+	noTUI, _ := arguments.Bool("--no-tui")
+	var spillThreshold int64
+	if v, _ := arguments.String("--spill-threshold"); v != "" {
+		if spillThreshold, err = config.ParseByteSize(v); err != nil || spillThreshold <= 0 {
+			log.Fatalf("Invalid --spill-threshold: %q", v)
+		}
+	}
+	spillDir, _ := arguments.String("--spill-dir")
+
+	selected, _ := arguments.String("--select")
+	renamed, _ := arguments.String("--rename")
+	project, err := projection.Parse(selected, renamed)
+	if err != nil {
+		log.Fatalf("Error parsing arguments: %v", err)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
+	// Interrupting stops the run and closes the output written so far.
+	ctx, stop := ui.NotifyInterrupt(ctx)
+	defer stop()
 
-	metrics, err := converter.ConvertCSVToJSON(ctx, csvPath, jsonPath, hasHeader, int64(chunkSize), rune(delimiter[0]), strings.Split(nullValues, ","), stringsCanBeNull)
+	metrics, err := converter.ConvertCSVToJSONWithOptions(ctx, csvPath, jsonPath, &converter.CSVToJSONOptions{
+		HasHeader:        hasHeader,
+		ChunkSize:        int64(chunkSize),
+		Delimiter:        rune(delimiter[0]),
+		NullValues:       strings.Split(nullValues, ","),
+		StringsCanBeNull: stringsCanBeNull,
+		Concurrency:      concurrency,
+		Project:          project,
+		Ordered:          ordered,
+		Monitor:          ui.NewProgressMonitor("CSV to JSON", os.Stderr, noTUI),
+		SpillThreshold:   spillThreshold,
+		SpillDir:         spillDir,
+	})
 	if err != nil {
+		if metrics != "" {
+			fmt.Fprintf(os.Stderr, "Conversion failed. Summary: %s\n", metrics)
+		}
 		log.Fatalf("Error converting CSV to JSON: %v", err)
 	}
-	fmt.Printf("Conversion completed. Summary: %s\n", metrics)
+	// Report on stderr so that output written to stdout stays clean.
+	fmt.Fprintf(os.Stderr, "Conversion completed. Summary: %s\n", metrics)
 }
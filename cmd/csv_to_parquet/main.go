@@ -33,9 +33,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	converter "github.com/arrowarc/arrowarc/converter"
+	"github.com/arrowarc/arrowarc/internal/ui"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/projection"
 	"github.com/docopt/docopt-go"
 )
 
@@ -43,18 +48,29 @@ func main() {
 	usage := `CSV to Parquet Converter.
 
 Usage:
-  csv_to_parquet --csv=<csv_file> --parquet=<parquet_file> [--header=<true|false>] [--chunk-size=<bytes>] [--delimiter=<char>] [--null=<value>] [--strings-can-be-null=<true|false>]
+  csv_to_parquet --csv=<csv_file> --parquet=<parquet_file> [--header=<true|false>] [--chunk-size=<bytes>] [--delimiter=<char>] [--null=<value>] [--strings-can-be-null=<true|false>] [--concurrency=<n>] [--ordered] [--reproducible] [--partition-by=<col1,col2,...> [--resume]] [--select=<col1,col2,...>] [--rename=<old=new,...>] [--spill-threshold=<size> [--spill-dir=<dir>]] [--no-tui]
   csv_to_parquet -h | --help
 
 Options:
   -h --help                             Show this screen.
-  --csv=<csv_file>                      Path to the input CSV file.
+  --csv=<csv_file>                      Input CSV file, directory or glob (e.g. 'data/*.csv'), or - for stdin.
   --parquet=<parquet_file>              Path to the output Parquet file.
   --header=<true|false>                 Indicates if the CSV file has a header [default: true].
   --chunk-size=<bytes>                  Number of bytes to read per chunk [default: 1024].
   --delimiter=<char>                    Delimiter used in the CSV file [default: ,].
   --null=<value>                        Value representing null in the CSV file [default: NULL].
   --strings-can-be-null=<true|false>    Indicates if strings can be null [default: true].
+  --concurrency=<n>                     Number of input files to read concurrently [default: 4].
+  --ordered                             Write the records of files read concurrently in file order, for reproducible output.
+  --reproducible                        Alias for --ordered: write the same bytes whenever the same input is converted.
+  --select=<col1,col2,...>              Columns to keep, in the order written.
+  --rename=<old=new,...>                Columns to rename in the output.
+  --no-tui                              Log progress lines instead of the live progress view.
+  --spill-threshold=<size>              Spill the records queued for a slow output past this size to disk, e.g. 256MiB.
+  --spill-dir=<dir>                     Directory to spill records to, the system's temporary directory by default.
+  --partition-by=<col1,col2,...>        Write Hive-style partitions under the output directory.
+  --resume                              Skip the input files the output's manifest lists as completed and replace
+                                        the files of incomplete ones, to restart an interrupted conversion.
 `
 
 	arguments, err := docopt.ParseDoc(usage)
@@ -68,13 +84,65 @@ Options:
 	chunkSize, _ := arguments.Int("--chunk-size")
 	delimiter, _ := arguments.String("--delimiter")
 	stringsCanBeNull, _ := arguments.Bool("--strings-can-be-null")
+	concurrency, _ := arguments.Int("--concurrency")
+	ordered, _ := arguments.Bool("--ordered")
+	reproducible, _ := arguments.Bool("--reproducible")
+	noTUI, _ := arguments.Bool("--no-tui")
+	var spillThreshold int64
+	if v, _ := arguments.String("--spill-threshold"); v != "" {
+		if spillThreshold, err = config.ParseByteSize(v); err != nil || spillThreshold <= 0 {
+			log.Fatalf("Invalid --spill-threshold: %q", v)
+		}
+	}
+	spillDir, _ := arguments.String("--spill-dir")
+	partitionBy, _ := arguments.String("--partition-by")
+	resume, _ := arguments.Bool("--resume")
+
+	selected, _ := arguments.String("--select")
+	renamed, _ := arguments.String("--rename")
+	project, err := projection.Parse(selected, renamed)
+	if err != nil {
+		log.Fatalf("Error parsing arguments: %v", err)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
+	// Interrupting stops the run and closes the output written so far.
+	ctx, stop := ui.NotifyInterrupt(ctx)
+	defer stop()
 
-	metrics, err := converter.ConvertCSVToParquet(ctx, csvPath, parquetPath, hasHeader, int64(chunkSize), rune(delimiter[0]), []string{}, stringsCanBeNull)
+	metrics, err := converter.ConvertCSVToParquetWithOptions(ctx, csvPath, parquetPath, &converter.CSVToParquetOptions{
+		HasHeader:        hasHeader,
+		ChunkSize:        int64(chunkSize),
+		Delimiter:        rune(delimiter[0]),
+		StringsCanBeNull: stringsCanBeNull,
+		Concurrency:      concurrency,
+		PartitionBy:      parseCommaSeparatedList(partitionBy),
+		Resume:           resume,
+		Project:          project,
+		Ordered:          ordered,
+		Reproducible:     reproducible,
+		Monitor:          ui.NewProgressMonitor("CSV to Parquet", os.Stderr, noTUI),
+		SpillThreshold:   spillThreshold,
+		SpillDir:         spillDir,
+	})
 	if err != nil {
+		if metrics != "" {
+			fmt.Fprintf(os.Stderr, "Conversion failed. Summary: %s\n", metrics)
+		}
 		log.Fatalf("Error converting CSV to Parquet: %v", err)
 	}
-	fmt.Printf("Conversion completed. Summary: %s\n", metrics)
+	if metrics == "" {
+		fmt.Fprintln(os.Stderr, "Nothing to convert: the manifest lists every input as completed.")
+		return
+	}
+	// Report on stderr so that output written to stdout stays clean.
+	fmt.Fprintf(os.Stderr, "Conversion completed. Summary: %s\n", metrics)
+}
+
+func parseCommaSeparatedList(input string) []string {
+	if input == "" {
+		return nil
+	}
+	return strings.Split(input, ",")
 }
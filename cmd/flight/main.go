@@ -33,15 +33,18 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/apache/arrow-go/v18/arrow/flight"
 	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
 	sqlite "github.com/arrowarc/arrowarc/integrations/flight/sqlite"
+	"github.com/arrowarc/arrowarc/pkg/health"
 	"github.com/docopt/docopt-go"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -51,12 +54,18 @@ func main() {
 	usage := `Flight SQL Server.
 
 Usage:
-  flight_server --address=<address>
+  flight_server [--address=<address>] [--health-address=<address>] [--db=<path> [--read-only]]
   flight_server -h | --help
 
 Options:
   -h --help                      Show this screen.
   --address=<address>            Address to bind the server to [default: localhost:12345].
+  --health-address=<address>     Serve the /healthz and /readyz probes over HTTP at <address>.
+  --db=<path>                    Serve the SQLite database file at <path> instead of an in-memory sample database.
+  --read-only                    Open the database file read-only.
+
+The server also serves the gRPC health service, ready while the database
+answers a ping.
 `
 
 	arguments, err := docopt.ParseDoc(usage)
@@ -65,6 +74,12 @@ Options:
 	}
 
 	address, _ := arguments.String("--address")
+	healthAddress, _ := arguments.String("--health-address")
+	dbPath, _ := arguments.String("--db")
+	readOnly, _ := arguments.Bool("--read-only")
+	if readOnly && dbPath == "" {
+		log.Fatal("--read-only requires --db")
+	}
 
 	// Validate address
 	if err := validateAddress(address); err != nil {
@@ -72,7 +87,7 @@ Options:
 	}
 
 	// Start the server in the main goroutine
-	startFlightSQLServer(address)
+	startFlightSQLServer(address, healthAddress, dbPath, readOnly)
 
 	// Run the client code in a separate goroutine to validate the server is up
 	go func() {
@@ -116,12 +131,23 @@ func validateAddress(address string) error {
 	return nil
 }
 
-// startFlightSQLServer initializes and starts the Flight SQL server using the SQLite example
-func startFlightSQLServer(address string) {
+// startFlightSQLServer initializes and starts the Flight SQL server over the
+// SQLite database file at dbPath, or over the in-memory example database
+// when dbPath is empty. Health probes are served over HTTP at healthAddress,
+// if set.
+func startFlightSQLServer(address, healthAddress, dbPath string, readOnly bool) {
 	// Initialize the SQLite database
-	db, err := sqlite.CreateDB()
+	var (
+		db  *sql.DB
+		err error
+	)
+	if dbPath != "" {
+		db, err = sqlite.OpenDB(dbPath, readOnly)
+	} else {
+		db, err = sqlite.CreateDB()
+	}
 	if err != nil {
-		log.Fatalf("Failed to create SQLite database: %v", err)
+		log.Fatalf("Failed to open SQLite database: %v", err)
 	}
 	defer db.Close()
 
@@ -131,9 +157,11 @@ func startFlightSQLServer(address string) {
 		log.Fatalf("Failed to create Flight SQL server: %v", err)
 	}
 
-	// Initialize the Flight server with middleware (if needed)
+	// Give each client a session of its own, so temporary tables and
+	// prepared statements are not shared between clients
+	sessions, connTracking := srv.Sessions()
 	server := flight.NewServerWithMiddleware(
-		[]flight.ServerMiddleware{},
+		[]flight.ServerMiddleware{sessions}, connTracking,
 	)
 	server.Init(address)
 
@@ -141,6 +169,19 @@ func startFlightSQLServer(address string) {
 	flightSQLServer := flightsql.NewFlightServer(srv)
 	server.RegisterFlightService(flightSQLServer)
 
+	// Report the database's reachability to health probes
+	checker := health.New(map[string]health.Check{"db": health.Ping(db)})
+	checker.RegisterGRPC(server)
+	if healthAddress != "" {
+		go func() {
+			probes := &http.Server{Addr: healthAddress, Handler: checker.Handler(), ReadHeaderTimeout: 10 * time.Second}
+			log.Printf("Serving health probes on http://%s\n", healthAddress)
+			if err := probes.ListenAndServe(); err != nil {
+				log.Fatalf("Failed to serve health probes: %v", err)
+			}
+		}()
+	}
+
 	log.Printf("Starting Flight SQL server on %s...\n", address)
 
 	// Start the Flight SQL server
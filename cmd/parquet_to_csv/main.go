@@ -33,11 +33,16 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	converter "github.com/arrowarc/arrowarc/converter"
+	"github.com/arrowarc/arrowarc/internal/ui"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+	"github.com/arrowarc/arrowarc/pkg/projection"
 	"github.com/docopt/docopt-go"
 )
 
@@ -45,12 +50,12 @@ func main() {
 	usage := `Parquet to CSV Converter.
 
 Usage:
-  parquet_to_csv --parquet=<parquet_file> --csv=<csv_file> [--memory-map] [--chunk-size=<bytes>] [--delimiter=<char>] [--header=<true|false>] [--null=<value>] [--columns=<col1,col2,...>] [--row-groups=<rg1,rg2,...>] [--parallel]
+  parquet_to_csv --parquet=<parquet_file> --csv=<csv_file> [--memory-map] [--chunk-size=<bytes>] [--delimiter=<char>] [--header=<true|false>] [--null=<value>] [--columns=<col1,col2,...>] [--row-groups=<rg1,rg2,...>] [--filter=<expr>] [--parallel] [--concurrency=<n>] [--ordered] [--select=<col1,col2,...>] [--rename=<old=new,...>] [--spill-threshold=<size> [--spill-dir=<dir>]] [--no-tui]
   parquet_to_csv -h | --help
 
 Options:
   -h --help                               Show this screen.
-  --parquet=<parquet_file>                Path to the input Parquet file.
+  --parquet=<parquet_file>                Input Parquet file, directory or glob (e.g. 'data/*.parquet').
   --csv=<csv_file>                        Path to the output CSV file.
   --memory-map                            Enable memory mapping for reading the input file.
   --chunk-size=<bytes>                    Number of bytes to read per chunk [default: 1024].
@@ -59,7 +64,15 @@ Options:
   --null=<value>                          String representing null values in the CSV file [default: NULL].
   --columns=<col1,col2,...>               List of columns to read.
   --row-groups=<rg1,rg2,...>              List of row groups to read.
-  --parallel                              Enable parallel processing.
+  --filter=<expr>                         Only convert rows matching the expression, e.g. "id >= 10 AND name = 'x'".
+  --parallel                              Read row groups in parallel, keeping their order.
+  --concurrency=<n>                       Number of input files to read concurrently [default: 4].
+  --ordered                               Write the records of files read concurrently in file order, for reproducible output.
+  --select=<col1,col2,...>                Columns to keep, in the order written.
+  --rename=<old=new,...>                  Columns to rename in the output.
+  --no-tui                                Log progress lines instead of the live progress view.
+  --spill-threshold=<size>                Spill the records queued for a slow output past this size to disk, e.g. 256MiB.
+  --spill-dir=<dir>                       Directory to spill records to, the system's temporary directory by default.
 `
 
 	arguments, err := docopt.ParseDoc(usage)
@@ -76,10 +89,31 @@ Options:
 	nullValue, _ := arguments.String("--null")
 	columns, _ := arguments.String("--columns")
 	rowGroups, _ := arguments.String("--row-groups")
+	filterExpr, _ := arguments.String("--filter")
 	parallel, _ := arguments.Bool("--parallel")
+	concurrency, _ := arguments.Int("--concurrency")
+	ordered, _ := arguments.Bool("--ordered")
+	noTUI, _ := arguments.Bool("--no-tui")
+	var spillThreshold int64
+	if v, _ := arguments.String("--spill-threshold"); v != "" {
+		if spillThreshold, err = config.ParseByteSize(v); err != nil || spillThreshold <= 0 {
+			log.Fatalf("Invalid --spill-threshold: %q", v)
+		}
+	}
+	spillDir, _ := arguments.String("--spill-dir")
+
+	selected, _ := arguments.String("--select")
+	renamed, _ := arguments.String("--rename")
+	project, err := projection.Parse(selected, renamed)
+	if err != nil {
+		log.Fatalf("Error parsing arguments: %v", err)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
+	// Interrupting stops the run and closes the output written so far.
+	ctx, stop := ui.NotifyInterrupt(ctx)
+	defer stop()
 
 	columnsList := parseCommaSeparatedList(columns)
 	rowGroupsList := parseCommaSeparatedList(rowGroups)
@@ -92,12 +126,40 @@ Options:
 		intRowGroupsList[i] = intRowGroup
 	}
 
-	metrics, err := converter.ConvertParquetToCSV(ctx, parquetPath, csvPath, memoryMap, int64(chunkSize), columnsList, intRowGroupsList, parallel, rune(delimiter[0]), includeHeader, nullValue, nil, nil)
+	var rowFilter filter.Expr
+	if filterExpr != "" {
+		rowFilter, err = filter.Parse(filterExpr)
+		if err != nil {
+			log.Fatalf("Error parsing filter: %v", err)
+		}
+	}
+
+	metrics, err := converter.ConvertParquetToCSVWithOptions(ctx, parquetPath, csvPath, &converter.ParquetToCSVOptions{
+		MemoryMap:      memoryMap,
+		ChunkSize:      int64(chunkSize),
+		Columns:        columnsList,
+		RowGroups:      intRowGroupsList,
+		Parallel:       parallel,
+		Filter:         rowFilter,
+		Concurrency:    concurrency,
+		Delimiter:      rune(delimiter[0]),
+		IncludeHeader:  includeHeader,
+		NullValue:      nullValue,
+		Project:        project,
+		Ordered:        ordered,
+		Monitor:        ui.NewProgressMonitor("Parquet to CSV", os.Stderr, noTUI),
+		SpillThreshold: spillThreshold,
+		SpillDir:       spillDir,
+	})
 	if err != nil {
+		if metrics != "" {
+			fmt.Fprintf(os.Stderr, "Conversion failed. Summary: %s\n", metrics)
+		}
 		log.Fatalf("Error converting Parquet to CSV: %v", err)
 	}
 
-	fmt.Printf("Conversion completed. Summary: %s\n", metrics)
+	// Report on stderr so that output written to stdout stays clean.
+	fmt.Fprintf(os.Stderr, "Conversion completed. Summary: %s\n", metrics)
 }
 
 func parseCommaSeparatedList(input string) []string {
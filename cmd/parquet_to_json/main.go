@@ -33,11 +33,16 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	converter "github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/ui"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/projection"
 	"github.com/docopt/docopt-go"
 )
 
@@ -45,19 +50,29 @@ func main() {
 	usage := `Parquet to JSON Converter.
 
 Usage:
-  parquet_to_json --parquet=<parquet_file> --json=<json_file> [--memory-map] [--chunk-size=<bytes>] [--columns=<col1,col2,...>] [--row-groups=<rg1,rg2,...>] [--parallel] [--include-structs]
+  parquet_to_json --parquet=<parquet_file> --json=<json_file> [--memory-map] [--chunk-size=<bytes>] [--columns=<col1,col2,...>] [--row-groups=<rg1,rg2,...>] [--parallel] [--include-structs] [--mode=<mode>] [--null-value=<json>] [--gzip] [--concurrency=<n>] [--ordered] [--select=<col1,col2,...>] [--rename=<old=new,...>] [--spill-threshold=<size> [--spill-dir=<dir>]] [--no-tui]
   parquet_to_json -h | --help
 
 Options:
   -h --help                               Show this screen.
-  --parquet=<parquet_file>                Path to the input Parquet file.
+  --parquet=<parquet_file>                Input Parquet file, directory or glob (e.g. 'data/*.parquet').
   --json=<json_file>                      Path to the output JSON file.
   --memory-map                            Enable memory mapping for reading the input file.
   --chunk-size=<bytes>                    Number of bytes to read per chunk [default: 1024].
   --columns=<col1,col2,...>               List of columns to read.
   --row-groups=<rg1,rg2,...>              List of row groups to read.
-  --parallel                              Enable parallel processing.
-  --include-structs                       Include nested structures in the JSON output.
+  --parallel                              Read row groups in parallel, keeping their order.
+  --include-structs                       Keep struct columns nested instead of flattening them into parent_field columns.
+  --mode=<mode>                           Output layout: array, ndjson or pretty [default: array].
+  --null-value=<json>                     JSON text written for null values, such as '""' [default: null].
+  --gzip                                  Compress the output with gzip, as a --json name ending in .gz does.
+  --concurrency=<n>                       Number of input files to read concurrently [default: 4].
+  --ordered                               Write the records of files read concurrently in file order, for reproducible output.
+  --select=<col1,col2,...>                Columns to keep, in the order written.
+  --rename=<old=new,...>                  Columns to rename in the output.
+  --no-tui                                Log progress lines instead of the live progress view.
+  --spill-threshold=<size>                Spill the records queued for a slow output past this size to disk, e.g. 256MiB.
+  --spill-dir=<dir>                       Directory to spill records to, the system's temporary directory by default.
 `
 
 	arguments, err := docopt.ParseDoc(usage)
@@ -73,9 +88,37 @@ Options:
 	rowGroups, _ := arguments.String("--row-groups")
 	parallel, _ := arguments.Bool("--parallel")
 	includeStructs, _ := arguments.Bool("--include-structs")
+	modeName, _ := arguments.String("--mode")
+	nullValue, _ := arguments.String("--null-value")
+	gzip, _ := arguments.Bool("--gzip")
+	concurrency, _ := arguments.Int("--concurrency")
+	ordered, _ := arguments.Bool("--ordered")
+	noTUI, _ := arguments.Bool("--no-tui")
+	var spillThreshold int64
+	if v, _ := arguments.String("--spill-threshold"); v != "" {
+		if spillThreshold, err = config.ParseByteSize(v); err != nil || spillThreshold <= 0 {
+			log.Fatalf("Invalid --spill-threshold: %q", v)
+		}
+	}
+	spillDir, _ := arguments.String("--spill-dir")
+
+	mode, err := integrations.ParseJSONMode(modeName)
+	if err != nil {
+		log.Fatalf("Error parsing arguments: %v", err)
+	}
+
+	selected, _ := arguments.String("--select")
+	renamed, _ := arguments.String("--rename")
+	project, err := projection.Parse(selected, renamed)
+	if err != nil {
+		log.Fatalf("Error parsing arguments: %v", err)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
+	// Interrupting stops the run and closes the output written so far.
+	ctx, stop := ui.NotifyInterrupt(ctx)
+	defer stop()
 
 	columnsList := parseCommaSeparatedList(columns)
 	rowGroupsList := parseCommaSeparatedList(rowGroups)
@@ -88,11 +131,33 @@ Options:
 		intRowGroupsList[i] = intRowGroup
 	}
 
-	metrics, err := converter.ConvertParquetToJSON(ctx, parquetPath, jsonPath, memoryMap, int64(chunkSize), columnsList, intRowGroupsList, parallel, includeStructs)
+	metrics, err := converter.ConvertParquetToJSONWithOptions(ctx, parquetPath, jsonPath, &converter.ParquetToJSONOptions{
+		MemoryMap:      memoryMap,
+		ChunkSize:      int64(chunkSize),
+		Columns:        columnsList,
+		RowGroups:      intRowGroupsList,
+		Parallel:       parallel,
+		Concurrency:    concurrency,
+		IncludeStructs: includeStructs,
+		JSON: &integrations.JSONWriteOptions{
+			Mode:      mode,
+			NullValue: nullValue,
+			Gzip:      gzip,
+		},
+		Project:        project,
+		Ordered:        ordered,
+		Monitor:        ui.NewProgressMonitor("Parquet to JSON", os.Stderr, noTUI),
+		SpillThreshold: spillThreshold,
+		SpillDir:       spillDir,
+	})
 	if err != nil {
+		if metrics != "" {
+			fmt.Fprintf(os.Stderr, "Conversion failed. Summary: %s\n", metrics)
+		}
 		log.Fatalf("Error converting Parquet to JSON: %v", err)
 	}
-	fmt.Printf("Conversion completed. Summary: %s\n", metrics)
+	// Report on stderr so that output written to stdout stays clean.
+	fmt.Fprintf(os.Stderr, "Conversion completed. Summary: %s\n", metrics)
 
 	log.Println("Parquet to JSON conversion completed successfully")
 }
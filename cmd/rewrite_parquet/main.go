@@ -36,6 +36,7 @@ import (
 
 	pq "github.com/apache/arrow-go/v18/parquet"
 	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/arrowarc/arrowarc/internal/ui"
 	parquet "github.com/arrowarc/arrowarc/pkg/parquet"
 	"github.com/docopt/docopt-go"
 )
@@ -53,7 +54,7 @@ Options:
   --output=<output_file>    Path to the output Parquet file.
   --memory-map              Enable memory mapping for reading the input file.
   --chunk-size=<bytes>      Number of bytes to read per chunk [default: 1024].
-  --parallel                Enable parallel processing.
+  --parallel                Read row groups in parallel, keeping their order.
 `
 
 	arguments, err := docopt.ParseDoc(usage)
@@ -71,6 +72,9 @@ Options:
 	// Set up context with a timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
+	// Interrupting stops the run and closes the output written so far.
+	ctx, stop := ui.NotifyInterrupt(ctx)
+	defer stop()
 
 	if err != nil {
 		log.Fatalf("Error converting row group to integer: %v", err)
@@ -0,0 +1,94 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/arrowarc/arrowarc/internal/ui"
+	parquet "github.com/arrowarc/arrowarc/pkg/parquet"
+	"github.com/docopt/docopt-go"
+)
+
+func main() {
+	usage := `Split Parquet File.
+
+Usage:
+  split_parquet --input=<input_file> --output=<pattern> (--max-rows=<rows> | --max-bytes=<bytes>)
+  split_parquet -h | --help
+
+Options:
+  -h --help                 Show this screen.
+  --input=<input_file>      Path to the input Parquet file.
+  --output=<pattern>        Output path pattern with one integer verb, e.g. part-%03d.parquet.
+  --max-rows=<rows>         Maximum number of rows per output file.
+  --max-bytes=<bytes>       Maximum in-memory size of the rows in each output file.
+`
+
+	arguments, err := docopt.ParseDoc(usage)
+	if err != nil {
+		log.Fatalf("Error parsing arguments: %v", err)
+	}
+
+	// Parse input arguments
+	inputFilePath, _ := arguments.String("--input")
+	outputPattern, _ := arguments.String("--output")
+
+	var limit parquet.SplitLimit
+	if arguments["--max-rows"] != nil {
+		maxRows, err := arguments.Int("--max-rows")
+		if err != nil {
+			log.Fatalf("Invalid --max-rows: %v", err)
+		}
+		limit.MaxRows = int64(maxRows)
+	} else {
+		maxBytes, err := arguments.Int("--max-bytes")
+		if err != nil {
+			log.Fatalf("Invalid --max-bytes: %v", err)
+		}
+		limit.MaxBytes = int64(maxBytes)
+	}
+
+	// Set up context with a timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	// Interrupting stops the run and closes the output written so far.
+	ctx, stop := ui.NotifyInterrupt(ctx)
+	defer stop()
+
+	paths, err := parquet.SplitFile(ctx, inputFilePath, outputPattern, limit)
+	if err != nil {
+		log.Fatalf("Error splitting Parquet file: %v", err)
+	}
+
+	log.Printf("Parquet file split into %d files", len(paths))
+}
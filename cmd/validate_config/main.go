@@ -30,11 +30,16 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 
 	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/endpoints"
+	"github.com/arrowarc/arrowarc/pkg/notify"
 	"github.com/docopt/docopt-go"
 )
 
@@ -44,12 +49,13 @@ func main() {
 	usage := `ArrowArc Configuration Validator.
 
 Usage:
-  arrowarc-validate-config [--config=<config_file>]
+  arrowarc-validate-config [--config=<config_file>] [--dry-run]
   arrowarc-validate-config -h | --help
 
 Options:
   -h --help                          Show this screen.
   --config=<config_file>             Path to the ArrowArc configuration file. [default: ../config/workflow.yaml]
+  --dry-run                          Also estimate the bytes and on-demand cost of the BigQuery reads and writes of the tasks, and print them.
 `
 
 	// Parse command-line arguments
@@ -67,11 +73,37 @@ Options:
 		log.Fatalf("Failed to parse config: %v", err)
 	}
 
+	// Log as the workflow asks; an invalid setting fails validation below.
+	if logger, err := cfg.Logger(os.Stderr); err == nil {
+		slog.SetDefault(logger)
+	}
+
 	if err := cfg.Validate(); err != nil {
 		log.Fatalf("Configuration validation failed: %v", err)
 	}
+	if err := endpoints.CheckIntegrations(cfg, nil); err != nil {
+		log.Fatalf("Configuration validation failed: %v", err)
+	}
+	if _, err := notify.FromConfig(cfg); err != nil {
+		log.Fatalf("Configuration validation failed: %v", err)
+	}
 
 	fmt.Println("Configuration is valid.")
+
+	if dryRun, _ := arguments.Bool("--dry-run"); dryRun {
+		estimates, err := endpoints.EstimateWorkflow(context.Background(), cfg, nil)
+		if err != nil {
+			log.Fatalf("Dry run failed: %v", err)
+		}
+		if estimates == nil {
+			estimates = []endpoints.TaskEstimate{}
+		}
+		report, err := json.MarshalIndent(map[string]any{"bigquery": estimates}, "", "  ")
+		if err != nil {
+			log.Fatalf("Dry run failed: %v", err)
+		}
+		fmt.Println(string(report))
+	}
 }
 
 // getConfigPath returns the config path provided by the user or the default path
@@ -35,62 +35,111 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/parquet/compress"
 	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/flow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
 	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/projection"
 )
 
-// ConvertAvroToParquet converts an Avro OCF file to a Parquet file.
-func ConvertAvroToParquet(ctx context.Context, avroPath, parquetPath string, chunkSize int64, compression compress.Compression) (string, error) {
-	// Validate inputs before proceeding
-	if err := validateInputs(ctx, avroPath, parquetPath, chunkSize); err != nil {
-		return "", err
-	}
+// AvroToParquetOptions configures ConvertAvroToParquetWithOptions.
+type AvroToParquetOptions struct {
+	// ChunkSize is the number of rows per record read.
+	ChunkSize int64
+	// Compression is the codec of the Parquet output, Snappy if nil.
+	Compression *compress.Compression
+	// Concurrency is the number of input files read at once, and Ordered
+	// writes their records in file order.
+	Concurrency int
+	Ordered     bool
+	// Project, if set, selects and renames columns.
+	Project *projection.Options
+	// PartitionBy, if set, writes Hive-style partitions of these columns
+	// under the output directory, and Resume resumes a partitioned output
+	// from its manifest, skipping the input files it lists as completed.
+	PartitionBy []string
+	Resume      bool
+	// Reproducible is an alias for Ordered. Parquet output carries no
+	// run-dependent metadata, so reading the input files in order is all
+	// it takes for converting the same input again to write the same bytes.
+	Reproducible bool
+	// Monitor, if set, follows the conversion pipeline.
+	Monitor pipeline.Monitor
+	// SpillThreshold and SpillDir spill the records queued for a slower
+	// writer to disk, as they do in ConvertOptions.
+	SpillThreshold int64
+	SpillDir       string
+}
 
-	// Initialize the Avro reader
-	avroReader, err := integrations.NewAvroReader(ctx, avroPath, &integrations.AvroReadOptions{
-		ChunkSize: chunkSize,
+// ConvertAvroToParquet converts an Avro OCF file to a Parquet file. With
+// resume set, a partitioned output is resumed from its manifest, skipping
+// the input files it lists as completed. project selects and renames
+// columns. With ordered set, the records of several files read
+// concurrently are written in file order. With reproducible set,
+// converting the same input again writes the same bytes.
+//
+// Deprecated: Use ConvertAvroToParquetWithOptions.
+func ConvertAvroToParquet(ctx context.Context, avroPath, parquetPath string, chunkSize int64, compression compress.Compression, concurrency int, partitionBy []string, resume bool, project *projection.Options, ordered, reproducible bool) (string, error) {
+	return ConvertAvroToParquetWithOptions(ctx, avroPath, parquetPath, &AvroToParquetOptions{
+		ChunkSize:    chunkSize,
+		Compression:  &compression,
+		Concurrency:  concurrency,
+		Ordered:      ordered,
+		Project:      project,
+		PartitionBy:  partitionBy,
+		Resume:       resume,
+		Reproducible: reproducible,
 	})
-	if err != nil {
-		return "", fmt.Errorf("failed to create Avro reader: %w", err)
-	}
-
-	// Ensure Avro reader is closed once the function completes
-	defer func() {
-		if closeErr := avroReader.Close(); closeErr != nil {
-			err = fmt.Errorf("failed to close Avro reader: %w", closeErr)
-		}
-	}()
+}
 
-	// Initialize the Parquet writer
-	parquetWriter, err := integrations.NewParquetWriter(parquetPath, avroReader.Schema(), integrations.NewDefaultParquetWriterProperties())
+// ConvertAvroToParquetWithOptions converts an Avro OCF file to a Parquet file.
+func ConvertAvroToParquetWithOptions(ctx context.Context, avroPath, parquetPath string, opts *AvroToParquetOptions) (string, error) {
+	if opts == nil {
+		opts = &AvroToParquetOptions{}
+	}
+	// Validate inputs before proceeding
+	if err := validateInputs(ctx, avroPath, parquetPath, opts.ChunkSize); err != nil {
+		return "", err
+	}
+	transformers, _, err := projectionStage(opts.Project)
 	if err != nil {
-		return "", fmt.Errorf("failed to create Parquet writer: %w", err)
+		return "", err
 	}
-
-	// Ensure Parquet writer is closed once the function completes
-	defer func() {
-		if closeErr := parquetWriter.Close(); closeErr != nil {
-			err = fmt.Errorf("failed to close Parquet writer: %w", closeErr)
-		}
-	}()
-
-	// Create a new data pipeline
-	p := pipeline.NewDataPipeline(avroReader, parquetWriter)
-
-	// Run the pipeline and capture metrics
-	metrics, err := p.Start(ctx)
+	paths, err := parquetInputs(avroPath, parquetPath, avroExtensions, opts.PartitionBy, opts.Resume)
 	if err != nil {
-		return "", fmt.Errorf("pipeline failed to start: %w", err)
+		return "", err
 	}
-
-	// Wait for the pipeline to complete
-	if pipelineErr := <-p.Done(); pipelineErr != nil {
-		return "", fmt.Errorf("pipeline encountered an error: %w", pipelineErr)
+	if len(paths) == 0 {
+		// Every input is in the output already.
+		return "", nil
 	}
 
-	// Return the pipeline metrics
-	return metrics, nil
+	return flow.Run(ctx, flow.Spec{
+		// Avro reader over one or more files
+		Open: func(ctx context.Context) (interfaces.Reader, error) {
+			avroReader, err := openParquetInputs(ctx, paths, opts.Concurrency, opts.Ordered || opts.Reproducible, opts.PartitionBy, opts.Resume, func(ctx context.Context, path string) (integrations.FileReader, error) {
+				return integrations.NewAvroReader(ctx, path, &integrations.AvroReadOptions{
+					ChunkSize: opts.ChunkSize,
+				})
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Avro reader: %w", err)
+			}
+			return avroReader, nil
+		},
+		// Parquet writer, or partitioned writer
+		Create: func(ctx context.Context, schema *arrow.Schema) (interfaces.Writer, error) {
+			return newParquetOutput(ctx, parquetPath, schema, opts.PartitionBy, opts.Resume, &integrations.ParquetWriteOptions{
+				Compression: opts.Compression,
+			})
+		},
+		Transformers:   transformers,
+		Monitor:        opts.Monitor,
+		SpillThreshold: opts.SpillThreshold,
+		SpillDir:       opts.SpillDir,
+	})
 }
 
 // validateInputs ensures the provided inputs are valid
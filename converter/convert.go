@@ -0,0 +1,396 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	grpcsource "github.com/arrowarc/arrowarc/integrations/grpc"
+	"github.com/arrowarc/arrowarc/internal/flow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"github.com/arrowarc/arrowarc/pipeline"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/history"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"github.com/arrowarc/arrowarc/pkg/projection"
+	"github.com/arrowarc/arrowarc/pkg/sample"
+	"github.com/arrowarc/arrowarc/pkg/seal"
+)
+
+// FormatNDJSON names newline-delimited JSON, which Convert reads and writes
+// alongside the integrations source formats.
+const FormatNDJSON = "ndjson"
+
+// ndjsonInferRows is the number of JSON lines sampled to infer a schema.
+const ndjsonInferRows = 1000
+
+var ndjsonExtensions = []string{".ndjson", ".jsonl", ".json"}
+
+// ConvertOptions configures Convert.
+type ConvertOptions struct {
+	// FromFormat and ToFormat name the input and output formats: ndjson or
+	// one of the integrations Source constants. They are detected from the
+	// file extensions when empty, and must be set for standard input and
+	// output.
+	FromFormat string
+	ToFormat   string
+	// ChunkSize is the number of rows per record read.
+	ChunkSize int64
+	// CSV configures reading CSV input.
+	CSV csvschema.CSVReadOptions
+	// Protobuf names the descriptor set and message type of protobuf input.
+	Protobuf integrations.ProtobufReadOptions
+	// GRPC configures reading the responses of a grpc:// input.
+	GRPC grpcsource.StreamOptions
+	// Events configures reading a WebSocket or server-sent events input.
+	Events integrations.EventReadOptions
+	// HTTP configures reading an http:// or https:// input, and the
+	// credentials sent to it. Nil keeps those set on the context by
+	// integrations.WithHTTPOptions, sending none if there are none.
+	HTTP *integrations.HTTPOptions
+	// Offset skips the first rows of the input, and Limit stops reading it
+	// after that many rows, zero meaning no limit.
+	Offset int64
+	Limit  int64
+	// Sample, if set, is the fraction of rows to convert, each drawn at
+	// random with SampleSeed, to extract a small sample of a large input.
+	Sample     float64
+	SampleSeed int64
+	// Select lists the columns to convert, in the order written, and
+	// Rename maps the names of columns to their names in the output.
+	// Parquet input reads only the selected columns.
+	Select []string
+	Rename map[string]string
+	// Verify reads the output back once written and fails the conversion
+	// unless it holds the same rows and values as were written, as
+	// pipeline.DataPipeline.WithVerification does. The output cannot be
+	// standard output.
+	Verify bool
+	// OnEmpty says what to write when the input has no rows: an empty
+	// output with the input's schema by default.
+	OnEmpty pipeline.EmptyPolicy
+	// Monitor, if set, follows the conversion pipeline.
+	Monitor pipeline.Monitor
+	// SpillThreshold, if positive, spills the records queued for a slower
+	// writer past that many bytes to files in SpillDir, or the system's
+	// temporary directory if empty, so that a stalled output does not run
+	// the conversion out of memory.
+	SpillThreshold int64
+	SpillDir       string
+	// Progress, if set, is called with the progress of the conversion
+	// every second and once it ends.
+	Progress func(pipeline.ProgressEvent)
+	// Batch, if set, coalesces the records read before they are written,
+	// as pipeline.DataPipeline.WithBatching does, so that a slow stream
+	// source is written in batches of a sensible size.
+	Batch *pipeline.BatchPolicy
+	// Timestamps controls how timestamps are read from CSV and NDJSON
+	// input and written to CSV and NDJSON output. Input timestamps are
+	// detected as text, so integrations.TimestampEpoch applies to output
+	// only.
+	Timestamps integrations.TimestampOptions
+	// Seal, if set, encrypts the output once written and writes its
+	// checksum sidecar, for delivery to third parties. Encrypted output
+	// cannot be verified.
+	Seal *seal.Options
+}
+
+// Convert copies the records of the file at from into a new file at to,
+// converting between formats. from may be an http:// or https:// URL.
+// Either path may be integrations.StdioPath to read an IPC stream, CSV or
+// NDJSON from standard input, or to write to standard output.
+func Convert(ctx context.Context, from, to string, opts *ConvertOptions) (string, error) {
+	if from == "" || to == "" {
+		return "", fmt.Errorf("input and output paths cannot be empty")
+	}
+	if opts == nil {
+		opts = &ConvertOptions{}
+	}
+	if opts.Sample < 0 || opts.Sample > 1 {
+		return "", fmt.Errorf("the sample fraction must be between 0 and 1, got %v", opts.Sample)
+	}
+	// The reader, writer and pipeline log as one run.
+	ctx, _ = logging.NewRun(ctx)
+	toFormat, err := convertFormat(to, opts.ToFormat)
+	if err != nil {
+		return "", err
+	}
+	if opts.Verify && integrations.IsStdio(to) {
+		return "", fmt.Errorf("cannot verify output written to standard output")
+	}
+	if opts.Verify && opts.OnEmpty == pipeline.EmptySkip {
+		return "", fmt.Errorf("cannot verify output that is skipped when empty")
+	}
+	if opts.Seal != nil {
+		if integrations.IsStdio(to) {
+			return "", fmt.Errorf("cannot seal output written to standard output")
+		}
+		if opts.Verify && opts.Seal.Encrypted() {
+			return "", fmt.Errorf("cannot verify encrypted output")
+		}
+		// Check the recipients before converting anything.
+		if _, err := seal.NewSealer(opts.Seal); err != nil {
+			return "", err
+		}
+	}
+	transformers, _, err := projectionStage(&projection.Options{Select: opts.Select, Rename: opts.Rename})
+	if err != nil {
+		return "", err
+	}
+
+	return flow.Run(ctx, flow.Spec{
+		Open: func(ctx context.Context) (interfaces.Reader, error) {
+			reader, err := OpenInput(ctx, from, opts)
+			if err != nil {
+				return nil, err
+			}
+			if opts.Sample > 0 && opts.Sample < 1 {
+				sampled, err := sample.NewSamplingReader(reader, opts.Sample, opts.SampleSeed)
+				if err != nil {
+					reader.Close()
+					return nil, err
+				}
+				return sampled, nil
+			}
+			return reader, nil
+		},
+		Create: func(ctx context.Context, schema *arrow.Schema) (interfaces.Writer, error) {
+			writer, err := newOutput(ctx, to, toFormat, schema, opts.Timestamps)
+			if err != nil || opts.Seal == nil {
+				return writer, err
+			}
+			sealed, err := integrations.NewSealedWriter(writer, to, opts.Seal)
+			if err != nil {
+				writer.Close()
+				return nil, err
+			}
+			return sealed, nil
+		},
+		Transformers:   transformers,
+		OnEmpty:        opts.OnEmpty,
+		Monitor:        opts.Monitor,
+		SpillThreshold: opts.SpillThreshold,
+		SpillDir:       opts.SpillDir,
+		History: history.Run{
+			Command:     "convert",
+			Source:      history.RedactURI(from),
+			Destination: history.RedactURI(to),
+			ConfigHash:  opts.configHash(from, to),
+		},
+		Configure: func(p *pipeline.DataPipeline) {
+			if opts.Progress != nil {
+				p.WithProgress(opts.Progress)
+			}
+			if opts.Batch != nil {
+				p.WithBatching(*opts.Batch)
+			}
+			if opts.Verify {
+				p.WithVerification(func(ctx context.Context) (interfaces.Reader, error) {
+					return openOutput(ctx, to, toFormat, opts.ChunkSize, opts.Timestamps)
+				})
+			}
+		},
+	})
+}
+
+// configHash returns the history.ConfigHash of the conversion of from
+// into to with opts, from the options that change its output.
+func (opts *ConvertOptions) configHash(from, to string) string {
+	return history.ConfigHash(struct {
+		From, To, FromFormat, ToFormat string
+		ChunkSize, Offset, Limit       int64
+		Sample                         float64
+		SampleSeed                     int64
+		Select                         []string
+		Rename                         map[string]string
+		Verify                         bool
+		OnEmpty                        pipeline.EmptyPolicy
+		CSV                            csvschema.CSVReadOptions
+		Timestamps                     integrations.TimestampOptions
+		Seal                           *seal.Options
+	}{
+		from, to, opts.FromFormat, opts.ToFormat,
+		opts.ChunkSize, opts.Offset, opts.Limit,
+		opts.Sample, opts.SampleSeed, opts.Select, opts.Rename, opts.Verify, opts.OnEmpty,
+		opts.CSV, opts.Timestamps, opts.Seal,
+	})
+}
+
+// OpenInput opens a reader over path as Convert reads its input: in
+// opts.FromFormat, or the format detected from its extension, JSON lines
+// included. Parquet input reads only the opts.Select columns, which are
+// neither reordered nor renamed.
+func OpenInput(ctx context.Context, path string, opts *ConvertOptions) (integrations.FileReader, error) {
+	if opts == nil {
+		opts = &ConvertOptions{}
+	}
+	if opts.HTTP != nil {
+		ctx = integrations.WithHTTPOptions(ctx, opts.HTTP)
+	}
+	format, err := convertFormat(path, opts.FromFormat)
+	if err != nil {
+		return nil, err
+	}
+	var reader integrations.FileReader
+	if format == FormatNDJSON {
+		reader, err = openNDJSON(ctx, path, opts)
+	} else {
+		reader, err = integrations.OpenSource(ctx, path, &integrations.SourceOptions{
+			Format:     format,
+			ChunkSize:  opts.ChunkSize,
+			CSV:        opts.CSV,
+			Protobuf:   opts.Protobuf,
+			GRPC:       opts.GRPC,
+			Events:     opts.Events,
+			Offset:     opts.Offset,
+			Limit:      opts.Limit,
+			Columns:    opts.Select,
+			Timestamps: inputTimestamps(opts.Timestamps),
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open '%s': %w", path, err)
+	}
+	return reader, nil
+}
+
+// inputTimestamps returns the options input timestamps are read with.
+// Input schemas are inferred, and read epoch timestamps as integers.
+func inputTimestamps(opts integrations.TimestampOptions) integrations.TimestampOptions {
+	if opts.Format == integrations.TimestampEpoch {
+		opts.Format = ""
+	}
+	return opts
+}
+
+// convertFormat returns format, or the format detected from the extension
+// of path when format is empty.
+func convertFormat(path, format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "":
+	case "json", "jsonl", FormatNDJSON:
+		return FormatNDJSON, nil
+	default:
+		return strings.ToLower(format), nil
+	}
+	if !integrations.IsEventStream(path) && slices.Contains(ndjsonExtensions, strings.ToLower(integrations.FileExt(path))) {
+		return FormatNDJSON, nil
+	}
+	return integrations.DetectSourceFormat(path)
+}
+
+// openNDJSON opens a reader over the JSON lines at path, with a schema
+// inferred from its first lines.
+func openNDJSON(ctx context.Context, path string, opts *ConvertOptions) (integrations.FileReader, error) {
+	var schema *arrow.Schema
+	var err error
+	if integrations.IsStdio(path) {
+		var sample []byte
+		if sample, err = integrations.SampleStdin(); err == nil {
+			schema, _, err = InferSchemaFromReader(bytes.NewReader(sample), ndjsonInferRows)
+		}
+	} else {
+		var f io.ReadCloser
+		if f, err = integrations.OpenFile(ctx, path); err == nil {
+			schema, _, err = InferSchemaFromReader(f, ndjsonInferRows)
+			f.Close()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to infer schema: %w", err)
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 1024
+	}
+	return integrations.NewJSONReader(ctx, path, schema, &integrations.JSONReadOptions{
+		ChunkSize:  int(chunkSize),
+		Offset:     opts.Offset,
+		Limit:      opts.Limit,
+		Timestamps: inputTimestamps(opts.Timestamps),
+	})
+}
+
+// openOutput opens a reader over the output written at path, in format.
+func openOutput(ctx context.Context, path, format string, chunkSize int64, timestamps integrations.TimestampOptions) (interfaces.Reader, error) {
+	if format == FormatNDJSON {
+		return openNDJSON(ctx, path, &ConvertOptions{ChunkSize: chunkSize, Timestamps: timestamps})
+	}
+	return integrations.OpenSource(ctx, path, &integrations.SourceOptions{
+		Format:     format,
+		ChunkSize:  chunkSize,
+		CSV:        csvschema.CSVReadOptions{Delimiter: ',', HasHeader: true},
+		Timestamps: inputTimestamps(timestamps),
+	})
+}
+
+// CreateOutput creates a writer at path as Convert creates its output: in
+// format, or the format detected from the extension of path when empty.
+func CreateOutput(ctx context.Context, path, format string, schema *arrow.Schema) (interfaces.Writer, error) {
+	format, err := convertFormat(path, format)
+	if err != nil {
+		return nil, err
+	}
+	return newOutput(ctx, path, format, schema, integrations.TimestampOptions{})
+}
+
+// newOutput creates a writer of format at path, writing timestamps to CSV
+// and NDJSON as timestamps says.
+func newOutput(ctx context.Context, path, format string, schema *arrow.Schema, timestamps integrations.TimestampOptions) (interfaces.Writer, error) {
+	var writer interfaces.Writer
+	var err error
+	switch format {
+	case integrations.SourceParquet:
+		return newParquetOutput(ctx, path, schema, nil, false, nil)
+	case integrations.SourceCSV:
+		writer, err = integrations.NewCSVWriter(ctx, path, schema, &integrations.CSVWriteOptions{IncludeHeader: true, Timestamps: timestamps})
+	case FormatNDJSON:
+		writer, err = integrations.NewJSONWriterWithOptions(ctx, path, &integrations.JSONWriteOptions{Lines: true, Timestamps: timestamps})
+	case integrations.SourceIPC:
+		writer, err = integrations.NewIPCRecordWriterWithOptions(ctx, path, schema, nil)
+	case integrations.SourceFeather:
+		writer, err = integrations.NewFeatherWriter(ctx, path, schema, nil)
+	case integrations.SourceAvro:
+		writer, err = integrations.NewAvroWriter(ctx, path, schema, nil)
+	default:
+		return nil, fmt.Errorf("unsupported output format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s writer for '%s': %w", format, path, err)
+	}
+	return writer, nil
+}
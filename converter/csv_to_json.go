@@ -35,12 +35,45 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/apache/arrow-go/v18/arrow"
 	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/flow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
 	"github.com/arrowarc/arrowarc/pipeline"
 	csv "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/projection"
 )
 
-// ConvertCSVToParquet converts a CSV file to a Parquet file using Arrow
+// CSVToJSONOptions configures ConvertCSVToJSONWithOptions.
+type CSVToJSONOptions struct {
+	// HasHeader reads the first row of each file as the column names.
+	HasHeader bool
+	// ChunkSize is the number of bytes read per record.
+	ChunkSize int64
+	// Delimiter separates the fields read.
+	Delimiter rune
+	// NullValues are the texts read as nulls, and StringsCanBeNull reads
+	// them as nulls in string columns too.
+	NullValues       []string
+	StringsCanBeNull bool
+	// Concurrency is the number of input files read at once, and Ordered
+	// writes their records in file order.
+	Concurrency int
+	Ordered     bool
+	// Project, if set, selects and renames columns.
+	Project *projection.Options
+	// Monitor, if set, follows the conversion pipeline.
+	Monitor pipeline.Monitor
+	// SpillThreshold and SpillDir spill the records queued for a slower
+	// writer to disk, as they do in ConvertOptions.
+	SpillThreshold int64
+	SpillDir       string
+}
+
+// ConvertCSVToJSON converts CSV files to a JSON file, inferring the schema
+// from the first file.
+//
+// Deprecated: Use ConvertCSVToJSONWithOptions.
 func ConvertCSVToJSON(
 	ctx context.Context,
 	csvFilePath, jsonFilePath string,
@@ -48,8 +81,28 @@ func ConvertCSVToJSON(
 	delimiter rune,
 	nullValues []string,
 	stringsCanBeNull bool,
+	concurrency int,
+	project *projection.Options,
+	ordered bool,
 ) (string, error) {
+	return ConvertCSVToJSONWithOptions(ctx, csvFilePath, jsonFilePath, &CSVToJSONOptions{
+		HasHeader:        hasHeader,
+		ChunkSize:        chunkSize,
+		Delimiter:        delimiter,
+		NullValues:       nullValues,
+		StringsCanBeNull: stringsCanBeNull,
+		Concurrency:      concurrency,
+		Ordered:          ordered,
+		Project:          project,
+	})
+}
 
+// ConvertCSVToJSONWithOptions converts CSV files to a JSON file, inferring
+// the schema from the first file.
+func ConvertCSVToJSONWithOptions(ctx context.Context, csvFilePath, jsonFilePath string, opts *CSVToJSONOptions) (string, error) {
+	if opts == nil {
+		opts = &CSVToJSONOptions{}
+	}
 	// Validate input parameters
 	if csvFilePath == "" {
 		return "", errors.New("CSV file path cannot be empty")
@@ -57,57 +110,61 @@ func ConvertCSVToJSON(
 	if jsonFilePath == "" {
 		return "", errors.New("JSON file path cannot be empty")
 	}
-	if chunkSize <= 0 {
+	if opts.ChunkSize <= 0 {
 		return "", errors.New("chunk size must be greater than zero")
 	}
 	if ctx == nil {
 		return "", errors.New("context cannot be nil")
 	}
 
-	// Step 1: Infer schema from the CSV file
-	schema, err := csv.InferCSVArrowSchema(ctx, csvFilePath, &csv.CSVReadOptions{
-		HasHeader:        hasHeader,
-		Delimiter:        delimiter,
-		NullValues:       nullValues,
-		StringsCanBeNull: stringsCanBeNull,
-	})
+	// Step 1: Infer schema from the first CSV file
+	samplePath, err := firstInput(csvFilePath, csvExtensions)
 	if err != nil {
-		return "", fmt.Errorf("failed to infer schema: %w", err)
+		return "", err
 	}
-
-	// Step 2: Create CSV reader with the inferred schema
-	csvReader, err := integrations.NewCSVReader(ctx, csvFilePath, schema, &integrations.CSVReadOptions{
-		HasHeader:        hasHeader,
-		ChunkSize:        chunkSize,
-		Delimiter:        delimiter,
-		NullValues:       nullValues,
-		StringsCanBeNull: stringsCanBeNull,
+	schema, err := integrations.InferCSVSchema(ctx, samplePath, &csv.CSVReadOptions{
+		HasHeader:        opts.HasHeader,
+		Delimiter:        opts.Delimiter,
+		NullValues:       opts.NullValues,
+		StringsCanBeNull: opts.StringsCanBeNull,
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create CSV reader: %w", err)
+		return "", fmt.Errorf("failed to infer schema: %w", err)
 	}
-	defer csvReader.Close()
-
-	// Step 3: Setup Parquet writer with the inferred schema
-	jsonWriter, err := integrations.NewJSONWriter(ctx, jsonFilePath)
+	transformers, _, err := projectionStage(opts.Project)
 	if err != nil {
-		return "", fmt.Errorf("failed to create JSON writer: %w", err)
-	}
-	defer jsonWriter.Close()
-
-	// Create pipeline
-	p := pipeline.NewDataPipeline(csvReader, jsonWriter)
-
-	// Start the pipeline and wait for completion
-	metrics, startErr := p.Start(ctx)
-	if startErr != nil {
-		return "", fmt.Errorf("failed to start conversion pipeline: %w", startErr)
+		return "", err
 	}
 
-	// Wait for the pipeline to finish
-	if pipelineErr := <-p.Done(); pipelineErr != nil {
-		return "", fmt.Errorf("pipeline encountered an error: %w", pipelineErr)
-	}
-
-	return metrics, nil
+	return flow.Run(ctx, flow.Spec{
+		// CSV reader over one or more files with the inferred schema
+		Open: func(ctx context.Context) (interfaces.Reader, error) {
+			csvReader, err := openInput(ctx, csvFilePath, opts.Concurrency, opts.Ordered, csvExtensions, func(ctx context.Context, path string) (integrations.FileReader, error) {
+				return integrations.NewCSVReader(ctx, path, schema, &integrations.CSVReadOptions{
+					HasHeader:        opts.HasHeader,
+					ChunkSize:        opts.ChunkSize,
+					Delimiter:        opts.Delimiter,
+					NullValues:       opts.NullValues,
+					StringsCanBeNull: opts.StringsCanBeNull,
+					MaxBatchBytes:    integrations.DefaultCSVMaxBatchBytes,
+					BufferedBatches:  integrations.DefaultCSVBufferedBatches,
+				})
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create CSV reader: %w", err)
+			}
+			return csvReader, nil
+		},
+		Create: func(ctx context.Context, _ *arrow.Schema) (interfaces.Writer, error) {
+			jsonWriter, err := integrations.NewJSONWriter(ctx, jsonFilePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create JSON writer: %w", err)
+			}
+			return jsonWriter, nil
+		},
+		Transformers:   transformers,
+		Monitor:        opts.Monitor,
+		SpillThreshold: opts.SpillThreshold,
+		SpillDir:       opts.SpillDir,
+	})
 }
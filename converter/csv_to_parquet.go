@@ -34,12 +34,63 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/apache/arrow-go/v18/arrow"
 	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/flow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
 	"github.com/arrowarc/arrowarc/pipeline"
 	csv "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/projection"
 )
 
-// ConvertCSVToParquet converts a CSV file to a Parquet file using Arrow
+// csvParquetRowGroupLength is the number of rows per row group written by
+// ConvertCSVToParquet.
+const csvParquetRowGroupLength = 1 << 20
+
+// CSVToParquetOptions configures ConvertCSVToParquetWithOptions.
+type CSVToParquetOptions struct {
+	// HasHeader reads the first row of each file as the column names.
+	HasHeader bool
+	// ChunkSize is the number of bytes read per record.
+	ChunkSize int64
+	// Delimiter separates the fields read.
+	Delimiter rune
+	// NullValues are the texts read as nulls, and StringsCanBeNull reads
+	// them as nulls in string columns too.
+	NullValues       []string
+	StringsCanBeNull bool
+	// Concurrency is the number of input files read at once, and Ordered
+	// writes their records in file order.
+	Concurrency int
+	Ordered     bool
+	// Project, if set, selects and renames columns.
+	Project *projection.Options
+
+	// PartitionBy, if set, writes Hive-style partitions of these columns
+	// under the output directory, and Resume resumes a partitioned output
+	// from its manifest, skipping the input files it lists as completed.
+	PartitionBy []string
+	Resume      bool
+	// Reproducible is an alias for Ordered. Parquet output carries no
+	// run-dependent metadata, so reading the input files in order is all
+	// it takes for converting the same input again to write the same bytes.
+	Reproducible bool
+	// Monitor, if set, follows the conversion pipeline.
+	Monitor pipeline.Monitor
+	// SpillThreshold and SpillDir spill the records queued for a slower
+	// writer to disk, as they do in ConvertOptions.
+	SpillThreshold int64
+	SpillDir       string
+}
+
+// ConvertCSVToParquet converts a CSV file to a Parquet file using Arrow.
+// With resume set, a partitioned output is resumed from its manifest,
+// skipping the input files it lists as completed. With ordered set, the
+// records of several files read concurrently are written in file order.
+// With reproducible set, converting the same input again writes the same
+// bytes.
+//
+// Deprecated: Use ConvertCSVToParquetWithOptions.
 func ConvertCSVToParquet(
 	ctx context.Context,
 	csvFilePath, parquetFilePath string,
@@ -47,8 +98,33 @@ func ConvertCSVToParquet(
 	delimiter rune,
 	nullValues []string,
 	stringsCanBeNull bool,
+	concurrency int,
+	partitionBy []string,
+	resume bool,
+	project *projection.Options,
+	ordered bool,
+	reproducible bool,
 ) (string, error) {
+	return ConvertCSVToParquetWithOptions(ctx, csvFilePath, parquetFilePath, &CSVToParquetOptions{
+		HasHeader:        hasHeader,
+		ChunkSize:        chunkSize,
+		Delimiter:        delimiter,
+		NullValues:       nullValues,
+		StringsCanBeNull: stringsCanBeNull,
+		Concurrency:      concurrency,
+		Ordered:          ordered,
+		Project:          project,
+		PartitionBy:      partitionBy,
+		Resume:           resume,
+		Reproducible:     reproducible,
+	})
+}
 
+// ConvertCSVToParquetWithOptions converts a CSV file to a Parquet file using Arrow.
+func ConvertCSVToParquetWithOptions(ctx context.Context, csvFilePath, parquetFilePath string, opts *CSVToParquetOptions) (string, error) {
+	if opts == nil {
+		opts = &CSVToParquetOptions{}
+	}
 	// Validate input parameters
 	if csvFilePath == "" {
 		return "", errors.New("CSV file path cannot be empty")
@@ -56,62 +132,67 @@ func ConvertCSVToParquet(
 	if parquetFilePath == "" {
 		return "", errors.New("parquet file path cannot be empty")
 	}
-	if chunkSize <= 0 {
+	if opts.ChunkSize <= 0 {
 		return "", errors.New("chunk size must be greater than zero")
 	}
 	if ctx == nil {
 		return "", errors.New("context cannot be nil")
 	}
-
-	// Step 1: Infer schema from the CSV file
-	schema, err := csv.InferCSVArrowSchema(ctx, csvFilePath, &csv.CSVReadOptions{
-		HasHeader:        hasHeader,
-		Delimiter:        delimiter,
-		NullValues:       nullValues,
-		StringsCanBeNull: stringsCanBeNull,
-	})
+	transformers, _, err := projectionStage(opts.Project)
 	if err != nil {
-		return "", fmt.Errorf("failed to infer schema: %w", err)
+		return "", err
 	}
 
-	// Step 2: Create CSV reader with the inferred schema
-	csvReader, err := integrations.NewCSVReader(ctx, csvFilePath, schema, &integrations.CSVReadOptions{
-		HasHeader:        hasHeader,
-		ChunkSize:        chunkSize,
-		Delimiter:        delimiter,
-		NullValues:       nullValues,
-		StringsCanBeNull: stringsCanBeNull,
-	})
+	paths, err := parquetInputs(csvFilePath, parquetFilePath, csvExtensions, opts.PartitionBy, opts.Resume)
 	if err != nil {
-		return "", fmt.Errorf("failed to create CSV reader: %w", err)
+		return "", err
 	}
-	defer csvReader.Close()
-
-	// Step 3: Setup Parquet writer with the inferred schema
-	parquetWriterProps := integrations.NewDefaultParquetWriterProperties()
-	parquetWriter, err := integrations.NewParquetWriter(parquetFilePath, schema, parquetWriterProps)
-	if err != nil {
-		return "", fmt.Errorf("failed to create Parquet writer for file '%s': %w", parquetFilePath, err)
-	}
-	defer func() {
-		if cerr := parquetWriter.Close(); cerr != nil {
-			err = fmt.Errorf("failed to close Parquet writer: %w", cerr)
-		}
-	}()
-
-	// Create pipeline
-	p := pipeline.NewDataPipeline(csvReader, parquetWriter)
-
-	// Start the pipeline and wait for completion
-	metrics, startErr := p.Start(ctx)
-	if startErr != nil {
-		return "", fmt.Errorf("failed to start conversion pipeline: %w", startErr)
+	if len(paths) == 0 {
+		// Every input is in the output already.
+		return "", nil
 	}
 
-	// Wait for the pipeline to finish
-	if pipelineErr := <-p.Done(); pipelineErr != nil {
-		return "", fmt.Errorf("pipeline encountered an error: %w", pipelineErr)
+	// Step 1: Infer schema from the first CSV file
+	schema, err := integrations.InferCSVSchema(ctx, paths[0], &csv.CSVReadOptions{
+		HasHeader:        opts.HasHeader,
+		Delimiter:        opts.Delimiter,
+		NullValues:       opts.NullValues,
+		StringsCanBeNull: opts.StringsCanBeNull,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to infer schema: %w", err)
 	}
 
-	return metrics, nil
+	return flow.Run(ctx, flow.Spec{
+		// CSV reader over one or more files with the inferred schema
+		Open: func(ctx context.Context) (interfaces.Reader, error) {
+			csvReader, err := openParquetInputs(ctx, paths, opts.Concurrency, opts.Ordered || opts.Reproducible, opts.PartitionBy, opts.Resume, func(ctx context.Context, path string) (integrations.FileReader, error) {
+				return integrations.NewCSVReader(ctx, path, schema, &integrations.CSVReadOptions{
+					HasHeader:        opts.HasHeader,
+					ChunkSize:        opts.ChunkSize,
+					Delimiter:        opts.Delimiter,
+					NullValues:       opts.NullValues,
+					StringsCanBeNull: opts.StringsCanBeNull,
+					MaxBatchBytes:    integrations.DefaultCSVMaxBatchBytes,
+					BufferedBatches:  integrations.DefaultCSVBufferedBatches,
+				})
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create CSV reader: %w", err)
+			}
+			return csvReader, nil
+		},
+		// Parquet writer, or partitioned writer, with the inferred schema.
+		// Column chunks are buffered until their row group ends, so bound
+		// row groups as well as input batches.
+		Create: func(ctx context.Context, schema *arrow.Schema) (interfaces.Writer, error) {
+			return newParquetOutput(ctx, parquetFilePath, schema, opts.PartitionBy, opts.Resume, &integrations.ParquetWriteOptions{
+				MaxRowGroupLength: csvParquetRowGroupLength,
+			})
+		},
+		Transformers:   transformers,
+		Monitor:        opts.Monitor,
+		SpillThreshold: opts.SpillThreshold,
+		SpillDir:       opts.SpillDir,
+	})
 }
@@ -0,0 +1,144 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package converter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+)
+
+// File extensions picked up when an input names a directory.
+var (
+	parquetExtensions = []string{".parquet", ".pq"}
+	csvExtensions     = []string{".csv", ".tsv", ".txt"}
+	avroExtensions    = []string{".avro"}
+)
+
+// openInput resolves input, which may be a file, a directory or a glob
+// pattern, and returns a reader over all the files it names. Up to
+// concurrency files are read at once, their records returned in file order
+// if ordered is set.
+func openInput(ctx context.Context, input string, concurrency int, ordered bool, extensions []string, open integrations.FileReaderFactory) (integrations.FileReader, error) {
+	paths, err := integrations.ExpandInputPaths(input, extensions...)
+	if err != nil {
+		return nil, err
+	}
+	return openPaths(ctx, paths, concurrency, ordered, open)
+}
+
+// openPaths returns a reader over all the files in paths, reading up to
+// concurrency of them at once.
+func openPaths(ctx context.Context, paths []string, concurrency int, ordered bool, open integrations.FileReaderFactory) (integrations.FileReader, error) {
+	if len(paths) == 1 {
+		return open(ctx, paths[0])
+	}
+	return integrations.NewMultiFileReaderWithOptions(ctx, paths, open, &integrations.MultiFileReadOptions{
+		Concurrency: concurrency,
+		Ordered:     ordered,
+	})
+}
+
+// parquetInputs returns the files named by input to convert into the
+// Parquet output at output. When resuming a partitioned output, the files
+// its manifest lists as completed are left out, which may leave none.
+func parquetInputs(input, output string, extensions, partitionBy []string, resume bool) ([]string, error) {
+	if resume && len(partitionBy) == 0 {
+		return nil, fmt.Errorf("resuming needs a partitioned output")
+	}
+	paths, err := integrations.ExpandInputPaths(input, extensions...)
+	if err != nil || !resume {
+		return paths, err
+	}
+	manifest, err := integrations.ReadPartitionManifest(output, "")
+	if err != nil {
+		return nil, err
+	}
+	pending := paths[:0]
+	for _, path := range paths {
+		if !manifest.IsCompleted(path) {
+			pending = append(pending, path)
+		}
+	}
+	return pending, nil
+}
+
+// openParquetInputs opens paths for the Parquet output of parquetInputs.
+// The records of a partitioned output name the file they were read from,
+// for its manifest. When resuming, files are read one after the other so
+// that the writer completes each before the next.
+func openParquetInputs(ctx context.Context, paths []string, concurrency int, ordered bool, partitionBy []string, resume bool, open integrations.FileReaderFactory) (integrations.FileReader, error) {
+	if resume {
+		concurrency = 1
+	}
+	reader, err := openPaths(ctx, paths, concurrency, ordered, open)
+	if err != nil || len(partitionBy) == 0 {
+		return reader, err
+	}
+	return integrations.NewSourceTaggingReader(reader, paths[0]), nil
+}
+
+// firstInput returns the first file named by input.
+func firstInput(input string, extensions []string) (string, error) {
+	paths, err := integrations.ExpandInputPaths(input, extensions...)
+	if err != nil {
+		return "", err
+	}
+	return paths[0], nil
+}
+
+// newParquetOutput creates a single Parquet file at path, or a directory
+// of Hive-style partitions under path when partitionBy is set, resuming
+// the output of an earlier run if resume is set. Files are tuned by opts
+// if not nil.
+func newParquetOutput(ctx context.Context, path string, schema *arrow.Schema, partitionBy []string, resume bool, opts *integrations.ParquetWriteOptions) (interfaces.Writer, error) {
+	if len(partitionBy) > 0 {
+		if integrations.IsStdio(path) {
+			return nil, fmt.Errorf("partitioned output cannot be written to standard output")
+		}
+		writer, err := integrations.NewPartitionedParquetWriter(ctx, path, schema, &integrations.PartitionedParquetWriteOptions{
+			PartitionColumns: partitionBy,
+			FileOptions:      opts,
+			Resume:           resume,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create partitioned Parquet writer for '%s': %w", path, err)
+		}
+		return writer, nil
+	}
+	writer, err := integrations.NewParquetWriterWithOptions(path, schema, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Parquet writer for file '%s': %w", path, err)
+	}
+	return writer, nil
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"sort"
 
 	"go.uber.org/zap"
 
@@ -15,6 +14,7 @@ import (
 	"github.com/apache/arrow-go/v18/arrow/memory"
 	"github.com/apache/arrow-go/v18/parquet"
 	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
 )
 
 // InferSchemaFromReader reads JSON lines from r and infers an Arrow schema
@@ -45,7 +45,7 @@ func InferSchemaFromReader(r io.Reader, maxCount int) (*arrow.Schema, int, error
 		logger.Error("scanner error", zap.Error(err))
 		return nil, count, err
 	}
-	schema, err := inferSchema(samples)
+	schema, err := integrations.InferJSONSchema(samples)
 	if err != nil {
 		logger.Error("failed to infer schema", zap.Error(err))
 		return nil, count, err
@@ -53,56 +53,6 @@ func InferSchemaFromReader(r io.Reader, maxCount int) (*arrow.Schema, int, error
 	return schema, count, nil
 }
 
-// inferSchema infers an Arrow schema from a slice of JSON objects.
-// For each field, if conflicting types are encountered, the type falls back to string.
-func inferSchema(samples []map[string]interface{}) (*arrow.Schema, error) {
-	fieldTypes := make(map[string]arrow.DataType)
-	for _, obj := range samples {
-		for k, v := range obj {
-			inferred, err := inferType(v)
-			if err != nil {
-				return nil, err
-			}
-			if existing, ok := fieldTypes[k]; ok {
-				if existing.ID() != inferred.ID() {
-					// Conflicting types: default to string.
-					fieldTypes[k] = arrow.BinaryTypes.String
-				}
-			} else {
-				fieldTypes[k] = inferred
-			}
-		}
-	}
-	var fields []arrow.Field
-	for name, dt := range fieldTypes {
-		fields = append(fields, arrow.Field{Name: name, Type: dt, Nullable: true})
-	}
-	// Sort fields alphabetically for consistency.
-	sort.Slice(fields, func(i, j int) bool {
-		return fields[i].Name < fields[j].Name
-	})
-	return arrow.NewSchema(fields, nil), nil
-}
-
-// inferType determines an Arrow data type from a JSON value.
-// JSON numbers become float64; booleans and strings are mapped to their Arrow equivalents.
-// If v is nil or an unrecognized type, string is returned.
-func inferType(v interface{}) (arrow.DataType, error) {
-	if v == nil {
-		return arrow.BinaryTypes.String, nil
-	}
-	switch v.(type) {
-	case bool:
-		return arrow.FixedWidthTypes.Boolean, nil
-	case float64:
-		return arrow.PrimitiveTypes.Float64, nil
-	case string:
-		return arrow.BinaryTypes.String, nil
-	default:
-		return arrow.BinaryTypes.String, nil
-	}
-}
-
 // SchemaFromFile opens a JSON file and infers its Arrow schema by reading up to maxCount records.
 func SchemaFromFile(inputFile string, maxCount int) (*arrow.Schema, int, error) {
 	logger, _ := zap.NewProduction()
@@ -35,10 +35,57 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/apache/arrow-go/v18/arrow"
 	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/flow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
 	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+	"github.com/arrowarc/arrowarc/pkg/projection"
 )
 
+// ParquetToCSVOptions configures ConvertParquetToCSVWithOptions.
+type ParquetToCSVOptions struct {
+	// MemoryMap maps the input files into memory instead of reading them.
+	MemoryMap bool
+	// ChunkSize is the number of rows per record read.
+	ChunkSize int64
+	// Columns and RowGroups, if set, are the only columns and row groups
+	// read, and Parallel reads row groups concurrently.
+	Columns   []string
+	RowGroups []int
+	Parallel  bool
+	// Filter, if set, drops the rows it does not match.
+	Filter filter.Expr
+	// Concurrency is the number of input files read at once, and Ordered
+	// writes their records in file order.
+	Concurrency int
+	Ordered     bool
+	// Project, if set, selects and renames columns, Columns being read
+	// unless it selects some.
+	Project *projection.Options
+
+	// Delimiter separates the fields written, and IncludeHeader writes a
+	// header row of the column names.
+	Delimiter     rune
+	IncludeHeader bool
+	// NullValue is the text of nulls. StringsReplacer, if set, rewrites
+	// strings before they are written, and BoolFormatter, if set, formats
+	// booleans.
+	NullValue       string
+	StringsReplacer *strings.Replacer
+	BoolFormatter   func(bool) string
+	// Monitor, if set, follows the conversion pipeline.
+	Monitor pipeline.Monitor
+	// SpillThreshold and SpillDir spill the records queued for a slower
+	// writer to disk, as they do in ConvertOptions.
+	SpillThreshold int64
+	SpillDir       string
+}
+
+// ConvertParquetToCSV writes the rows of Parquet files as CSV.
+//
+// Deprecated: Use ConvertParquetToCSVWithOptions.
 func ConvertParquetToCSV(
 	ctx context.Context,
 	parquetFilePath, csvFilePath string,
@@ -47,7 +94,34 @@ func ConvertParquetToCSV(
 	delimiter rune, includeHeader bool,
 	nullValue string, stringsReplacer *strings.Replacer,
 	boolFormatter func(bool) string,
+	rowFilter filter.Expr,
+	concurrency int,
+	project *projection.Options,
+	ordered bool,
 ) (string, error) {
+	return ConvertParquetToCSVWithOptions(ctx, parquetFilePath, csvFilePath, &ParquetToCSVOptions{
+		MemoryMap:       memoryMap,
+		ChunkSize:       chunkSize,
+		Columns:         columns,
+		RowGroups:       rowGroups,
+		Parallel:        parallel,
+		Filter:          rowFilter,
+		Concurrency:     concurrency,
+		Ordered:         ordered,
+		Project:         project,
+		Delimiter:       delimiter,
+		IncludeHeader:   includeHeader,
+		NullValue:       nullValue,
+		StringsReplacer: stringsReplacer,
+		BoolFormatter:   boolFormatter,
+	})
+}
+
+// ConvertParquetToCSVWithOptions writes the rows of Parquet files as CSV.
+func ConvertParquetToCSVWithOptions(ctx context.Context, parquetFilePath, csvFilePath string, opts *ParquetToCSVOptions) (string, error) {
+	if opts == nil {
+		opts = &ParquetToCSVOptions{}
+	}
 	// Validate input parameters
 	if parquetFilePath == "" {
 		return "", errors.New("parquet file path cannot be empty")
@@ -55,58 +129,55 @@ func ConvertParquetToCSV(
 	if csvFilePath == "" {
 		return "", errors.New("CSV file path cannot be empty")
 	}
-	if chunkSize <= 0 {
+	if opts.ChunkSize <= 0 {
 		return "", errors.New("chunk size must be greater than zero")
 	}
 	if ctx == nil {
 		return "", errors.New("context cannot be nil")
 	}
-
-	// Create Parquet reader
-	reader, err := integrations.NewParquetReader(ctx, parquetFilePath, &integrations.ParquetReadOptions{
-		MemoryMap: memoryMap,
-		RowGroups: rowGroups,
-		Parallel:  parallel,
-	})
+	transformers, columns, err := projectionStage(withSelect(opts.Project, opts.Columns))
 	if err != nil {
-		return "", fmt.Errorf("failed to create Parquet reader for file '%s': %w", parquetFilePath, err)
+		return "", err
 	}
-	defer func() {
-		if cerr := reader.Close(); cerr != nil {
-			err = fmt.Errorf("failed to close Parquet reader: %w", cerr)
-		}
-	}()
-
-	// Create CSV writer
-	writer, err := integrations.NewCSVWriter(ctx, csvFilePath, reader.Schema(), &integrations.CSVWriteOptions{
-		Delimiter:       delimiter,
-		IncludeHeader:   includeHeader,
-		NullValue:       nullValue,
-		StringsReplacer: stringsReplacer,
-		BoolFormatter:   boolFormatter,
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to create CSV writer for file '%s': %w", csvFilePath, err)
-	}
-	defer func() {
-		if cerr := writer.Close(); cerr != nil {
-			err = fmt.Errorf("failed to close CSV writer: %w", cerr)
-		}
-	}()
-
-	// Setup pipeline
-	p := pipeline.NewDataPipeline(reader, writer)
-
-	// Start the pipeline and wait for completion
-	metrics, startErr := p.Start(ctx)
-	if startErr != nil {
-		return "", fmt.Errorf("failed to start conversion pipeline: %w", startErr)
+	if opts.Filter != nil {
+		// The filter may read columns the projection drops.
+		columns = nil
 	}
 
-	// Wait for the pipeline to finish
-	if pipelineErr := <-p.Done(); pipelineErr != nil {
-		return "", fmt.Errorf("pipeline encountered an error: %w", pipelineErr)
-	}
-
-	return metrics, nil
+	return flow.Run(ctx, flow.Spec{
+		// Parquet reader over one or more files
+		Open: func(ctx context.Context) (interfaces.Reader, error) {
+			reader, err := openInput(ctx, parquetFilePath, opts.Concurrency, opts.Ordered, parquetExtensions, func(ctx context.Context, path string) (integrations.FileReader, error) {
+				return integrations.NewParquetReader(ctx, path, &integrations.ParquetReadOptions{
+					MemoryMap: opts.MemoryMap,
+					ChunkSize: opts.ChunkSize,
+					RowGroups: opts.RowGroups,
+					Parallel:  opts.Parallel,
+					Filter:    opts.Filter,
+					Columns:   columns,
+				})
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Parquet reader for '%s': %w", parquetFilePath, err)
+			}
+			return reader, nil
+		},
+		Create: func(ctx context.Context, schema *arrow.Schema) (interfaces.Writer, error) {
+			writer, err := integrations.NewCSVWriter(ctx, csvFilePath, schema, &integrations.CSVWriteOptions{
+				Delimiter:       opts.Delimiter,
+				IncludeHeader:   opts.IncludeHeader,
+				NullValue:       opts.NullValue,
+				StringsReplacer: opts.StringsReplacer,
+				BoolFormatter:   opts.BoolFormatter,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create CSV writer for file '%s': %w", csvFilePath, err)
+			}
+			return writer, nil
+		},
+		Transformers:   transformers,
+		Monitor:        opts.Monitor,
+		SpillThreshold: opts.SpillThreshold,
+		SpillDir:       opts.SpillDir,
+	})
 }
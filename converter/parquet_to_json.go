@@ -33,11 +33,77 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/apache/arrow-go/v18/arrow"
 	filesystem "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/flow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
 	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/flatten"
+	"github.com/arrowarc/arrowarc/pkg/projection"
 )
 
-func ConvertParquetToJSON(ctx context.Context, parquetFilePath, jsonFilePath string, memoryMap bool, chunkSize int64, columns []string, rowGroups []int, parallel bool, includeStructs bool) (string, error) {
+// ParquetToJSONOptions configures ConvertParquetToJSONWithOptions.
+type ParquetToJSONOptions struct {
+	// MemoryMap maps the input files into memory instead of reading them.
+	MemoryMap bool
+	// ChunkSize is the number of rows per record read.
+	ChunkSize int64
+	// Columns and RowGroups, if set, are the only columns and row groups
+	// read, and Parallel reads row groups concurrently.
+	Columns   []string
+	RowGroups []int
+	Parallel  bool
+	// Concurrency is the number of input files read at once, and Ordered
+	// writes their records in file order.
+	Concurrency int
+	Ordered     bool
+	// Project, if set, selects and renames columns, Columns being read
+	// unless it selects some.
+	Project *projection.Options
+
+	// IncludeStructs writes struct columns as objects. Otherwise they are
+	// flattened into top-level columns named parent_field.
+	IncludeStructs bool
+	// JSON selects the output mode, the text of nulls and compression; if
+	// nil a JSON array of row objects is written per record.
+	JSON *filesystem.JSONWriteOptions
+	// Monitor, if set, follows the conversion pipeline.
+	Monitor pipeline.Monitor
+	// SpillThreshold and SpillDir spill the records queued for a slower
+	// writer to disk, as they do in ConvertOptions.
+	SpillThreshold int64
+	SpillDir       string
+}
+
+// ConvertParquetToJSON writes the rows of Parquet files as JSON. Unless
+// includeStructs is set, struct columns are flattened into top-level
+// columns named parent_field. opts selects the output mode, the text of
+// nulls and compression; it may be nil to write a JSON array of row objects
+// per record. project selects and renames columns, columns being selected
+// unless it selects some. With ordered set, the records of several files
+// read concurrently are written in file order.
+//
+// Deprecated: Use ConvertParquetToJSONWithOptions.
+func ConvertParquetToJSON(ctx context.Context, parquetFilePath, jsonFilePath string, memoryMap bool, chunkSize int64, columns []string, rowGroups []int, parallel bool, includeStructs bool, concurrency int, opts *filesystem.JSONWriteOptions, project *projection.Options, ordered bool) (string, error) {
+	return ConvertParquetToJSONWithOptions(ctx, parquetFilePath, jsonFilePath, &ParquetToJSONOptions{
+		MemoryMap:      memoryMap,
+		ChunkSize:      chunkSize,
+		Columns:        columns,
+		RowGroups:      rowGroups,
+		Parallel:       parallel,
+		Concurrency:    concurrency,
+		Ordered:        ordered,
+		Project:        project,
+		IncludeStructs: includeStructs,
+		JSON:           opts,
+	})
+}
+
+// ConvertParquetToJSONWithOptions writes the rows of Parquet files as JSON.
+func ConvertParquetToJSONWithOptions(ctx context.Context, parquetFilePath, jsonFilePath string, opts *ParquetToJSONOptions) (string, error) {
+	if opts == nil {
+		opts = &ParquetToJSONOptions{}
+	}
 	// Validate input parameters
 	if parquetFilePath == "" {
 		return "", fmt.Errorf("parquet file path cannot be empty")
@@ -45,43 +111,48 @@ func ConvertParquetToJSON(ctx context.Context, parquetFilePath, jsonFilePath str
 	if jsonFilePath == "" {
 		return "", fmt.Errorf("JSON file path cannot be empty")
 	}
-	if chunkSize <= 0 {
+	if opts.ChunkSize <= 0 {
 		return "", fmt.Errorf("chunk size must be greater than zero")
 	}
-
-	// Setup the reader
-	reader, err := filesystem.NewParquetReader(ctx, parquetFilePath, &filesystem.ParquetReadOptions{
-		MemoryMap: memoryMap,
-		RowGroups: rowGroups,
-		Parallel:  parallel,
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to create Parquet reader for file '%s': %w", parquetFilePath, err)
-	}
-
-	// Setup the writer
-	writer, err := filesystem.NewJSONWriter(ctx, jsonFilePath)
+	transformers, columns, err := projectionStage(withSelect(opts.Project, opts.Columns))
 	if err != nil {
-		return "", fmt.Errorf("failed to create JSON writer for file '%s': %w", jsonFilePath, err)
+		return "", err
 	}
-	defer func() {
-		if cerr := writer.Close(); cerr != nil {
-			err = fmt.Errorf("failed to close JSON writer: %w", cerr)
+	if !opts.IncludeStructs {
+		flattener, err := flatten.New(nil)
+		if err != nil {
+			return "", err
 		}
-	}()
-	// Setup pipeline
-	p := pipeline.NewDataPipeline(reader, writer)
-
-	// Start the pipeline and wait for completion
-	metrics, startErr := p.Start(ctx)
-	if startErr != nil {
-		return "", fmt.Errorf("failed to start conversion pipeline: %w", startErr)
-	}
-
-	// Wait for the pipeline to finish
-	if pipelineErr := <-p.Done(); pipelineErr != nil {
-		return "", fmt.Errorf("pipeline encountered an error: %w", pipelineErr)
+		transformers = append(transformers, flattener)
 	}
 
-	return metrics, nil
+	return flow.Run(ctx, flow.Spec{
+		// Reader over one or more files
+		Open: func(ctx context.Context) (interfaces.Reader, error) {
+			reader, err := openInput(ctx, parquetFilePath, opts.Concurrency, opts.Ordered, parquetExtensions, func(ctx context.Context, path string) (filesystem.FileReader, error) {
+				return filesystem.NewParquetReader(ctx, path, &filesystem.ParquetReadOptions{
+					MemoryMap: opts.MemoryMap,
+					ChunkSize: opts.ChunkSize,
+					RowGroups: opts.RowGroups,
+					Parallel:  opts.Parallel,
+					Columns:   columns,
+				})
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Parquet reader for '%s': %w", parquetFilePath, err)
+			}
+			return reader, nil
+		},
+		Create: func(ctx context.Context, _ *arrow.Schema) (interfaces.Writer, error) {
+			writer, err := filesystem.NewJSONWriterWithOptions(ctx, jsonFilePath, opts.JSON)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create JSON writer for file '%s': %w", jsonFilePath, err)
+			}
+			return writer, nil
+		},
+		Transformers:   transformers,
+		Monitor:        opts.Monitor,
+		SpillThreshold: opts.SpillThreshold,
+		SpillDir:       opts.SpillDir,
+	})
 }
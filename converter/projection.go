@@ -0,0 +1,62 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package converter
+
+import (
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"github.com/arrowarc/arrowarc/pkg/projection"
+)
+
+// projectionStage returns the transformers selecting and renaming columns
+// as project says, none when it keeps every column as it is, and the
+// columns readers able to skip the others should read.
+func projectionStage(project *projection.Options) ([]interfaces.Transformer, []string, error) {
+	if project.IsZero() {
+		return nil, nil, nil
+	}
+	t, err := projection.New(project)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []interfaces.Transformer{t}, t.Columns(), nil
+}
+
+// withSelect returns project, selecting columns unless it selects some
+// already, for converters taking a list of columns to read.
+func withSelect(project *projection.Options, columns []string) *projection.Options {
+	if len(columns) == 0 || (project != nil && len(project.Select) > 0) {
+		return project
+	}
+	out := &projection.Options{Select: columns}
+	if project != nil {
+		out.Rename = project.Rename
+	}
+	return out
+}
@@ -226,6 +226,14 @@ func (p *ParquetRows) Next(dest []driver.Value) error {
 			} else {
 				dest[i] = time.Unix(int64(col.Value(p.curRowIndex)), 0).UTC()
 			}
+		case *array.Map, *array.FixedSizeList, *array.List, *array.LargeList, *array.Struct:
+			// Nested values are rendered as JSON text, a value every
+			// driver consumer can scan.
+			if col.IsNull(p.curRowIndex) {
+				dest[i] = nil
+			} else {
+				dest[i] = col.ValueStr(p.curRowIndex)
+			}
 		default:
 			return fmt.Errorf("unsupported column type: %s", col.DataType().ID().String())
 		}
@@ -298,11 +306,8 @@ func (p *ParquetRows) ColumnTypeScanType(index int) reflect.Type {
 		return reflect.TypeOf(time.Time{})
 	case arrow.BINARY:
 		return reflect.TypeOf([]byte{})
-	case arrow.LIST, arrow.FIXED_SIZE_LIST:
-		return reflect.TypeOf([]interface{}{})
-	case arrow.STRUCT:
-		return reflect.TypeOf(struct{}{})
-	case arrow.STRING:
+	case arrow.STRING, arrow.MAP, arrow.LIST, arrow.LARGE_LIST, arrow.FIXED_SIZE_LIST, arrow.STRUCT:
+		// Nested values are scanned as JSON text.
 		return reflect.TypeOf("")
 	}
 	return reflect.TypeOf(nil)
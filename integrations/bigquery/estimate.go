@@ -0,0 +1,182 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"fmt"
+
+	bq "cloud.google.com/go/bigquery"
+	storagepb "cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+)
+
+// Operations an Estimate describes.
+const (
+	OperationQuery        = "query"
+	OperationRead         = "read"
+	OperationStorageWrite = "storage_write"
+	OperationLoadJob      = "load_job"
+)
+
+// Pricing holds the on-demand prices estimates are made with, in US
+// dollars.
+type Pricing struct {
+	QueryPerTiB float64 // bytes scanned by queries
+	ReadPerTiB  float64 // bytes read with the Storage Read API
+	WritePerGiB float64 // bytes written with the Storage Write API
+}
+
+// DefaultPricing is the on-demand list pricing of the US multi-region. Set
+// it to the prices of another region or contract before estimating.
+var DefaultPricing = Pricing{QueryPerTiB: 6.25, ReadPerTiB: 1.10, WritePerGiB: 0.025}
+
+// Default quotas of the Storage Write API and of load jobs.
+const (
+	WriteThroughputMultiRegion = 3 << 30   // bytes per second per project, US and EU multi-regions
+	WriteThroughputRegion      = 300 << 20 // bytes per second per project, other regions
+	LoadJobsPerTablePerDay     = 1500
+	LoadJobMaxBytes            = 15 << 40
+)
+
+const (
+	gib = 1 << 30
+	tib = 1 << 40
+)
+
+// Estimate is what a BigQuery operation is expected to process and cost
+// before it runs.
+type Estimate struct {
+	Operation string `json:"operation"`
+	// Target is the table read or written, or the query run.
+	Target string `json:"target"`
+	// Bytes is the number of bytes scanned, read or written, zero when
+	// unknown, and Rows the number of rows when known.
+	Bytes int64 `json:"bytes"`
+	Rows  int64 `json:"rows,omitempty"`
+	// Cost is the on-demand cost of Bytes in US dollars.
+	Cost float64 `json:"cost_usd"`
+	// Quotas lists the quotas that bound the operation.
+	Quotas []string `json:"quotas,omitempty"`
+}
+
+// Log logs e to the logger of ctx.
+func (e *Estimate) Log(ctx context.Context) {
+	attrs := []any{"operation", e.Operation, "target", e.Target, "bytes", e.Bytes, "cost_usd", fmt.Sprintf("%.4f", e.Cost)}
+	if e.Rows > 0 {
+		attrs = append(attrs, "rows", e.Rows)
+	}
+	if len(e.Quotas) > 0 {
+		attrs = append(attrs, "quotas", e.Quotas)
+	}
+	logging.FromContext(ctx).Info("bigquery estimate", attrs...)
+}
+
+// EstimateQuery dry-runs sql, which BigQuery does free of charge, and
+// returns the bytes it would scan and their cost.
+func EstimateQuery(ctx context.Context, client *bq.Client, sql string, params ...bq.QueryParameter) (*Estimate, error) {
+	query := client.Query(sql)
+	query.Parameters = params
+	query.DryRun = true
+	job, err := query.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dry-run query: %w", err)
+	}
+	status := job.LastStatus()
+	if status == nil || status.Statistics == nil {
+		return nil, fmt.Errorf("dry run of query returned no statistics")
+	}
+	if err := status.Err(); err != nil {
+		return nil, fmt.Errorf("query is invalid: %w", err)
+	}
+	return queryEstimate(sql, status.Statistics.TotalBytesProcessed), nil
+}
+
+func queryEstimate(sql string, bytes int64) *Estimate {
+	return &Estimate{
+		Operation: OperationQuery,
+		Target:    sql,
+		Bytes:     bytes,
+		Cost:      float64(bytes) / tib * DefaultPricing.QueryPerTiB,
+	}
+}
+
+// EstimateTableRead returns the bytes and rows of a table, which reading
+// it whole with the Storage Read API costs.
+func EstimateTableRead(ctx context.Context, client *bq.Client, datasetID, tableID string) (*Estimate, error) {
+	md, err := client.Dataset(datasetID).Table(tableID).Metadata(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table metadata: %w", err)
+	}
+	return readEstimate(fmt.Sprintf("%s.%s.%s", client.Project(), datasetID, tableID), md.NumBytes, int64(md.NumRows)), nil
+}
+
+// sessionEstimate returns the estimate of a read session, which accounts
+// for the columns selected.
+func sessionEstimate(session *storagepb.ReadSession) *Estimate {
+	return readEstimate(session.GetTable(), session.GetEstimatedTotalBytesScanned(), session.GetEstimatedRowCount())
+}
+
+func readEstimate(table string, bytes, rows int64) *Estimate {
+	return &Estimate{
+		Operation: OperationRead,
+		Target:    table,
+		Bytes:     bytes,
+		Rows:      rows,
+		Cost:      float64(bytes) / tib * DefaultPricing.ReadPerTiB,
+	}
+}
+
+// EstimateWrite returns the estimate of writing bytes to table with the
+// Storage Write API, or with load jobs when loadJob is set, which are free.
+// bytes may be zero when unknown; the quotas apply either way.
+func EstimateWrite(table string, bytes int64, loadJob bool) *Estimate {
+	if loadJob {
+		return &Estimate{
+			Operation: OperationLoadJob,
+			Target:    table,
+			Bytes:     bytes,
+			Quotas: []string{
+				fmt.Sprintf("%d load jobs per table per day", LoadJobsPerTablePerDay),
+				fmt.Sprintf("%d TB per load job", LoadJobMaxBytes>>40),
+			},
+		}
+	}
+	return &Estimate{
+		Operation: OperationStorageWrite,
+		Target:    table,
+		Bytes:     bytes,
+		Cost:      float64(bytes) / gib * DefaultPricing.WritePerGiB,
+		Quotas: []string{
+			fmt.Sprintf("throughput of %d GB/s per project in the US and EU multi-regions, %d MB/s in other regions",
+				WriteThroughputMultiRegion>>30, WriteThroughputRegion>>20),
+		},
+	}
+}
@@ -0,0 +1,200 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	memoryPool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BigQueryFlightServerOptions configures a BigQueryFlightServer.
+type BigQueryFlightServerOptions struct {
+	// ProjectID is the project of descriptors that name only a dataset and
+	// a table.
+	ProjectID string
+	// WriteStreamMode is the stream each DoPut call appends to.
+	// WriteStreamModePending commits a call's rows only when the client
+	// ends it successfully.
+	WriteStreamMode WriteStreamMode
+	// CreateTable, when set, creates missing destination tables from the
+	// schema of the stream.
+	CreateTable *BigQueryTableOptions
+	// Schemas, when set, lists the tables the server accepts, keyed by
+	// "dataset.table" or "project.dataset.table", with the schema their
+	// streams must have.
+	Schemas map[string]*arrow.Schema
+	// Retry is the policy of failed appends.
+	Retry *retry.Policy
+}
+
+// NewDefaultBigQueryFlightServerOptions returns options writing each call
+// to a pending stream.
+func NewDefaultBigQueryFlightServerOptions() *BigQueryFlightServerOptions {
+	return &BigQueryFlightServerOptions{WriteStreamMode: WriteStreamModePending}
+}
+
+// BigQueryFlightServer is a Flight service loading the record batches of
+// DoPut calls into BigQuery with the Storage Write API. The descriptor of a
+// call is a path of [dataset, table] or [project, dataset, table]; each call
+// writes to a stream of its own, and in pending mode its rows become visible
+// at once when the client ends the call, or not at all.
+type BigQueryFlightServer struct {
+	flight.BaseFlightServer
+
+	client *BigQueryWriteClient
+	opts   BigQueryFlightServerOptions
+}
+
+// BigQueryPutResult is the JSON app metadata of the PutResult a
+// BigQueryFlightServer sends once a DoPut call is written.
+type BigQueryPutResult struct {
+	Table  string `json:"table"`
+	Stream string `json:"stream"`
+	Rows   int64  `json:"rows"`
+}
+
+// NewBigQueryFlightServer creates a gateway writing through client, whose
+// own schema is ignored: each call is written with the schema of its stream.
+func NewBigQueryFlightServer(client *BigQueryWriteClient, opts *BigQueryFlightServerOptions) *BigQueryFlightServer {
+	if opts == nil {
+		opts = NewDefaultBigQueryFlightServerOptions()
+	}
+	return &BigQueryFlightServer{client: client, opts: *opts}
+}
+
+// DoPut writes one stream of record batches to the table named by its
+// descriptor. The call fails without writing anything when the descriptor
+// or the schema is rejected; a pending stream is abandoned, uncommitted,
+// when the call fails part way.
+func (s *BigQueryFlightServer) DoPut(stream flight.FlightService_DoPutServer) error {
+	reader, err := flight.NewRecordReader(stream)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to read stream: %v", err)
+	}
+	defer reader.Release()
+
+	projectID, datasetID, tableID, err := s.destination(reader.LatestFlightDescriptor())
+	if err != nil {
+		return err
+	}
+	table := fmt.Sprintf("%s.%s.%s", projectID, datasetID, tableID)
+	schema := reader.Schema()
+	if err := s.checkSchema(datasetID, tableID, table, schema); err != nil {
+		return err
+	}
+
+	writer, err := NewBigQueryRecordWriter(stream.Context(), s.client.withSchema(schema), projectID, datasetID, tableID, &BigQueryWriteOptions{
+		WriteStreamMode: s.opts.WriteStreamMode,
+		Allocator:       memoryPool.GetAllocator(),
+		CreateTable:     s.opts.CreateTable,
+		Retry:           s.opts.Retry,
+	})
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to open %s: %v", table, err)
+	}
+
+	var rows int64
+	for reader.Next() {
+		record := reader.Record()
+		if err := writer.Write(record); err != nil {
+			_ = writer.Abort()
+			return status.Errorf(codes.Internal, "failed to write to %s: %v", table, err)
+		}
+		rows += record.NumRows()
+	}
+	if err := reader.Err(); err != nil && err != io.EOF {
+		_ = writer.Abort()
+		return status.Errorf(codes.InvalidArgument, "failed to read stream to %s: %v", table, err)
+	}
+	if err := writer.Close(); err != nil {
+		return status.Errorf(codes.Internal, "failed to commit %s: %v", table, err)
+	}
+
+	metadata, err := json.Marshal(BigQueryPutResult{Table: table, Stream: writer.StreamName(), Rows: rows})
+	if err != nil {
+		return err
+	}
+	return stream.Send(&flight.PutResult{AppMetadata: metadata})
+}
+
+// destination returns the table named by the path of desc.
+func (s *BigQueryFlightServer) destination(desc *flight.FlightDescriptor) (projectID, datasetID, tableID string, err error) {
+	if desc.GetType() != flight.DescriptorPATH {
+		return "", "", "", status.Error(codes.InvalidArgument, "descriptor must be a path of [project,] dataset, table")
+	}
+	path := desc.GetPath()
+	switch {
+	case len(path) == 3:
+		projectID, datasetID, tableID = path[0], path[1], path[2]
+	case len(path) == 2 && s.opts.ProjectID != "":
+		projectID, datasetID, tableID = s.opts.ProjectID, path[0], path[1]
+	case len(path) == 2:
+		return "", "", "", status.Error(codes.InvalidArgument, "descriptor must name the project: the server has no default")
+	default:
+		return "", "", "", status.Errorf(codes.InvalidArgument, "descriptor path %q must be [project,] dataset, table", path)
+	}
+	for _, part := range []string{projectID, datasetID, tableID} {
+		if part == "" || strings.ContainsAny(part, "/") {
+			return "", "", "", status.Errorf(codes.InvalidArgument, "invalid descriptor path %q", path)
+		}
+	}
+	return projectID, datasetID, tableID, nil
+}
+
+// checkSchema rejects streams for tables outside the configured schemas,
+// streams whose schema differs from the configured one and schemas that
+// have no BigQuery equivalent.
+func (s *BigQueryFlightServer) checkSchema(datasetID, tableID, table string, schema *arrow.Schema) error {
+	if s.opts.Schemas != nil {
+		want, ok := s.opts.Schemas[table]
+		if !ok {
+			want, ok = s.opts.Schemas[datasetID+"."+tableID]
+		}
+		if !ok {
+			return status.Errorf(codes.PermissionDenied, "table %s is not served", table)
+		}
+		if !want.Equal(schema) {
+			return status.Errorf(codes.InvalidArgument, "schema mismatch for %s: expected %v but got %v", table, want, schema)
+		}
+	}
+	if _, err := ArrowSchemaToBigQuery(schema); err != nil {
+		return status.Errorf(codes.InvalidArgument, "schema of %s: %v", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,399 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	bq "cloud.google.com/go/bigquery"
+	"cloud.google.com/go/storage"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	memoryPool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+)
+
+const (
+	defaultLoadFileRows     = 1_000_000
+	defaultLoadPollInterval = 5 * time.Second
+	// bigQueryMaxLoadURIs is the number of source URIs a load job accepts.
+	bigQueryMaxLoadURIs = 10_000
+)
+
+// BigQueryLoadOptions configures a BigQueryLoadWriter.
+type BigQueryLoadOptions struct {
+	// Bucket is the GCS bucket records are staged to as Parquet files.
+	Bucket string
+	// Prefix is the path within Bucket under which each writer stages its
+	// files in a directory of its own.
+	Prefix string
+	// FileRows is the number of rows of a staged file. Defaults to 1,000,000.
+	FileRows int64
+	// Partition, when set, loads into a single partition of the table
+	// through a decorator such as "20240101", "2024010112" or
+	// "__UNPARTITIONED__".
+	Partition string
+	// WriteDisposition defaults to bigquery.WriteAppend.
+	WriteDisposition bq.TableWriteDisposition
+	// CreateDisposition defaults to bigquery.CreateIfNeeded.
+	CreateDisposition bq.TableCreateDisposition
+	// CreateTable sets the partitioning and clustering of the table when
+	// the load job creates it.
+	CreateTable *BigQueryTableOptions
+	// PollInterval is the time between checks of the load job's status.
+	// Defaults to five seconds.
+	PollInterval time.Duration
+	// Timeout, when set, bounds the time waited for the load job.
+	Timeout time.Duration
+	// KeepStagedFiles leaves the staged files in GCS once they are loaded.
+	// Files of a failed load are always kept.
+	KeepStagedFiles bool
+	// Labels are set on the load job.
+	Labels    map[string]string
+	Allocator memory.Allocator
+}
+
+// NewDefaultBigQueryLoadOptions returns options staging to bucket.
+func NewDefaultBigQueryLoadOptions(bucket string) *BigQueryLoadOptions {
+	return &BigQueryLoadOptions{
+		Bucket:           bucket,
+		FileRows:         defaultLoadFileRows,
+		WriteDisposition: bq.WriteAppend,
+		PollInterval:     defaultLoadPollInterval,
+		Allocator:        memoryPool.GetAllocator(),
+	}
+}
+
+// BigQueryLoadOptionsFromConfig returns the load options of a task's
+// bigquery settings, which must select the load_job mode.
+func BigQueryLoadOptionsFromConfig(s *config.BigQueryTaskSettings) (*BigQueryLoadOptions, error) {
+	if !s.LoadJob() {
+		return nil, fmt.Errorf("bigquery mode is not %s", config.BigQueryModeLoadJob)
+	}
+	if s.StagingBucket == "" {
+		return nil, errors.New("bigquery staging_bucket is required")
+	}
+	opts := NewDefaultBigQueryLoadOptions(s.StagingBucket)
+	opts.Prefix = s.StagingPrefix
+	opts.Partition = s.Partition
+	opts.KeepStagedFiles = s.KeepStagedFiles
+	if s.FileRows > 0 {
+		opts.FileRows = s.FileRows
+	}
+	switch s.WriteDisposition {
+	case "", config.WriteDispositionAppend:
+	case config.WriteDispositionTruncate:
+		opts.WriteDisposition = bq.WriteTruncate
+	case config.WriteDispositionEmpty:
+		opts.WriteDisposition = bq.WriteEmpty
+	default:
+		return nil, fmt.Errorf("unknown bigquery write_disposition %q", s.WriteDisposition)
+	}
+	switch s.CreateDisposition {
+	case "", config.CreateDispositionIfNeeded:
+	case config.CreateDispositionNever:
+		opts.CreateDisposition = bq.CreateNever
+	default:
+		return nil, fmt.Errorf("unknown bigquery create_disposition %q", s.CreateDisposition)
+	}
+	for name, field := range map[string]struct {
+		value string
+		dst   *time.Duration
+	}{
+		"poll_interval": {s.PollInterval, &opts.PollInterval},
+		"timeout":       {s.Timeout, &opts.Timeout},
+	} {
+		if field.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(field.value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid bigquery %s %q", name, field.value)
+		}
+		*field.dst = d
+	}
+	return opts, nil
+}
+
+// BigQueryLoadWriter loads records into BigQuery with load jobs instead of
+// the Storage Write API, which suits large backfills: records are staged to
+// GCS as Parquet files and Close loads them all in one job, so the table
+// receives every row or none.
+type BigQueryLoadWriter struct {
+	ctx       context.Context
+	bqClient  *bq.Client
+	gcsClient *storage.Client
+	datasetID string
+	tableID   string
+	schema    *arrow.Schema
+	opts      *BigQueryLoadOptions
+	dir       string
+
+	files   []string
+	object  *storage.Writer
+	cancel  context.CancelFunc
+	parquet *pqarrow.FileWriter
+	rows    int64
+	job     *bq.Job
+}
+
+// NewBigQueryLoadWriter creates a writer loading records of schema into
+// datasetID.tableID of bqClient's project, staging them with gcsClient.
+func NewBigQueryLoadWriter(ctx context.Context, bqClient *bq.Client, gcsClient *storage.Client, datasetID, tableID string, schema *arrow.Schema, opts *BigQueryLoadOptions) (*BigQueryLoadWriter, error) {
+	if opts == nil || opts.Bucket == "" {
+		return nil, errors.New("a staging bucket is required")
+	}
+	if opts.FileRows <= 0 {
+		opts.FileRows = defaultLoadFileRows
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultLoadPollInterval
+	}
+	if opts.Allocator == nil {
+		opts.Allocator = memoryPool.GetAllocator()
+	}
+	if opts.CreateTable != nil {
+		if _, err := NewBigQueryTableMetadata(schema, opts.CreateTable); err != nil {
+			return nil, fmt.Errorf("failed to build table definition: %w", err)
+		}
+	}
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	dir := fmt.Sprintf("%s-%s-%s", tableID, time.Now().UTC().Format("20060102T150405"), hex.EncodeToString(id))
+	EstimateWrite(fmt.Sprintf("%s.%s.%s", bqClient.Project(), datasetID, tableID), 0, true).Log(ctx)
+
+	return &BigQueryLoadWriter{
+		ctx:       ctx,
+		bqClient:  bqClient,
+		gcsClient: gcsClient,
+		datasetID: datasetID,
+		tableID:   tableID,
+		schema:    schema,
+		opts:      opts,
+		dir:       path.Join(opts.Prefix, dir),
+	}, nil
+}
+
+// StagedFiles returns the gs:// URIs of the files staged so far.
+func (w *BigQueryLoadWriter) StagedFiles() []string {
+	uris := make([]string, len(w.files))
+	for i, name := range w.files {
+		uris[i] = fmt.Sprintf("gs://%s/%s", w.opts.Bucket, name)
+	}
+	return uris
+}
+
+// JobID returns the ID of the load job, once Close has started it.
+func (w *BigQueryLoadWriter) JobID() string {
+	if w.job == nil {
+		return ""
+	}
+	return w.job.ID()
+}
+
+// Write stages a record, starting a new file once the current one holds
+// FileRows rows.
+func (w *BigQueryLoadWriter) Write(record arrow.Record) error {
+	if !w.schema.Equal(record.Schema()) {
+		return fmt.Errorf("schema mismatch: expected %v but got %v", w.schema, record.Schema())
+	}
+	if w.parquet == nil {
+		if err := w.openFile(); err != nil {
+			return err
+		}
+	}
+	if err := w.parquet.WriteBuffered(record); err != nil {
+		return fmt.Errorf("failed to stage record: %w", err)
+	}
+	w.rows += record.NumRows()
+	if w.rows >= w.opts.FileRows {
+		return w.closeFile()
+	}
+	return nil
+}
+
+func (w *BigQueryLoadWriter) openFile() error {
+	if len(w.files) == bigQueryMaxLoadURIs {
+		return fmt.Errorf("a load job takes at most %d files; raise FileRows", bigQueryMaxLoadURIs)
+	}
+	name := path.Join(w.dir, fmt.Sprintf("part-%05d.parquet", len(w.files)))
+	ctx, cancel := context.WithCancel(w.ctx)
+	object := w.gcsClient.Bucket(w.opts.Bucket).Object(name).NewWriter(ctx)
+	object.ContentType = "application/vnd.apache.parquet"
+
+	props := parquet.NewWriterProperties(
+		parquet.WithAllocator(w.opts.Allocator),
+		parquet.WithCompression(compress.Codecs.Snappy),
+	)
+	pw, err := pqarrow.NewFileWriter(w.schema, object, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to create Parquet writer: %w", err)
+	}
+	w.files = append(w.files, name)
+	w.object, w.cancel, w.parquet, w.rows = object, cancel, pw, 0
+	return nil
+}
+
+// closeFile completes the upload of the current file.
+func (w *BigQueryLoadWriter) closeFile() error {
+	defer w.cancel()
+	err := w.parquet.Close()
+	if closeErr := w.object.Close(); err == nil {
+		err = closeErr
+	}
+	w.object, w.parquet = nil, nil
+	if err != nil {
+		return fmt.Errorf("failed to stage %s: %w", w.files[len(w.files)-1], err)
+	}
+	return nil
+}
+
+// Close stages the last file, loads every staged file in one load job and
+// waits for it. The staged files are deleted once loaded unless
+// KeepStagedFiles is set.
+func (w *BigQueryLoadWriter) Close() error {
+	defer memoryPool.PutAllocator(w.opts.Allocator)
+	if w.parquet != nil {
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+	}
+	if len(w.files) == 0 {
+		return nil
+	}
+
+	if err := w.load(); err != nil {
+		return err
+	}
+	if !w.opts.KeepStagedFiles {
+		return w.deleteStaged()
+	}
+	return nil
+}
+
+// Abort stops staging and deletes the files staged so far without loading
+// them.
+func (w *BigQueryLoadWriter) Abort() error {
+	defer memoryPool.PutAllocator(w.opts.Allocator)
+	if w.parquet != nil {
+		// Cancelling the upload before closing it discards the object.
+		w.cancel()
+		_ = w.parquet.Close()
+		_ = w.object.Close()
+		w.files = w.files[:len(w.files)-1]
+		w.object, w.parquet = nil, nil
+	}
+	return w.deleteStaged()
+}
+
+func (w *BigQueryLoadWriter) load() error {
+	src := bq.NewGCSReference(w.StagedFiles()...)
+	src.SourceFormat = bq.Parquet
+	src.ParquetOptions = &bq.ParquetOptions{EnableListInference: true}
+
+	table := w.tableID
+	if w.opts.Partition != "" {
+		table += "$" + w.opts.Partition
+	}
+	loader := w.bqClient.Dataset(w.datasetID).Table(table).LoaderFrom(src)
+	loader.WriteDisposition = w.opts.WriteDisposition
+	loader.CreateDisposition = w.opts.CreateDisposition
+	loader.Labels = w.opts.Labels
+	if opts := w.opts.CreateTable; opts != nil {
+		loader.TimePartitioning = opts.TimePartitioning
+		loader.RangePartitioning = opts.RangePartitioning
+		if len(opts.Clustering) > 0 {
+			loader.Clustering = &bq.Clustering{Fields: opts.Clustering}
+		}
+	}
+
+	ctx := w.ctx
+	if w.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.opts.Timeout)
+		defer cancel()
+	}
+	job, err := loader.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start load job: %w", err)
+	}
+	w.job = job
+	return w.wait(ctx)
+}
+
+// wait polls the load job every PollInterval until it is done.
+func (w *BigQueryLoadWriter) wait(ctx context.Context) error {
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := w.job.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get status of load job %s: %w", w.job.ID(), err)
+		}
+		if status.Done() {
+			if err := status.Err(); err != nil {
+				return fmt.Errorf("load job %s failed: %w", w.job.ID(), err)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up waiting for load job %s: %w", w.job.ID(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *BigQueryLoadWriter) deleteStaged() error {
+	bucket := w.gcsClient.Bucket(w.opts.Bucket)
+	var errs []error
+	for _, name := range w.files {
+		if err := bucket.Object(name).Delete(w.ctx); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to delete staged files: %w", err)
+	}
+	return nil
+}
@@ -36,6 +36,7 @@ import (
 	"io"
 	"time"
 
+	"cloud.google.com/go/bigquery"
 	bqStorage "cloud.google.com/go/bigquery/storage/apiv1"
 	storagepb "cloud.google.com/go/bigquery/storage/apiv1/storagepb"
 	"github.com/apache/arrow-go/v18/arrow"
@@ -43,14 +44,17 @@ import (
 	"github.com/apache/arrow-go/v18/arrow/memory"
 	memoryPool "github.com/arrowarc/arrowarc/internal/memory"
 	helper "github.com/arrowarc/arrowarc/pkg/common/utils"
+	"github.com/arrowarc/arrowarc/pkg/gcpauth"
+	"github.com/arrowarc/arrowarc/pkg/limit"
 	"github.com/googleapis/gax-go/v2"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc/codes"
 )
 
 type BigQueryReadClient struct {
-	client      *bqStorage.BigQueryReadClient
-	callOptions *BigQueryReadCallOptions
+	client        *bqStorage.BigQueryReadClient
+	callOptions   *BigQueryReadCallOptions
+	clientOptions []option.ClientOption
 }
 
 type BigQueryReadCallOptions struct {
@@ -58,6 +62,17 @@ type BigQueryReadCallOptions struct {
 	ReadRows          []gax.CallOption
 }
 
+// NewBigQueryReadClientWithAuth creates a read client authenticating as
+// auth says, with Application Default Credentials if it is nil, and the
+// further client options opts.
+func NewBigQueryReadClientWithAuth(ctx context.Context, auth *gcpauth.Options, opts ...option.ClientOption) (*BigQueryReadClient, error) {
+	opts, err := auth.ClientOptions(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewBigQueryReadClient(ctx, opts...)
+}
+
 func NewBigQueryReadClient(ctx context.Context, opts ...option.ClientOption) (*BigQueryReadClient, error) {
 	client, err := bqStorage.NewBigQueryReadClient(ctx, opts...)
 	if err != nil {
@@ -65,8 +80,9 @@ func NewBigQueryReadClient(ctx context.Context, opts ...option.ClientOption) (*B
 	}
 
 	return &BigQueryReadClient{
-		client:      client,
-		callOptions: defaultBigQueryReadCallOptions(),
+		client:        client,
+		callOptions:   defaultBigQueryReadCallOptions(),
+		clientOptions: opts,
 	}, nil
 }
 
@@ -99,8 +115,38 @@ func defaultBigQueryReadCallOptions() *BigQueryReadCallOptions {
 	}
 }
 
+// BigQueryReadOptions configures a BigQueryReader.
+type BigQueryReadOptions struct {
+	// Offset skips the first rows of the table, and Limit stops reading
+	// after that many rows, zero meaning no limit. The Storage Read API has
+	// no row limits, so skipped rows are still downloaded, but no more
+	// are read once the limit is reached.
+	Offset int64
+	Limit  int64
+	// Columns names the top-level columns to read, the others being
+	// skipped by the Storage Read API.
+	Columns []string
+}
+
 // NewBigQueryReader creates a new BigQueryReader for the specified table
 func (bq *BigQueryReadClient) NewBigQueryReader(ctx context.Context, projectID, datasetID, tableID string) (*BigQueryReader, error) {
+	return bq.NewBigQueryReaderWithOptions(ctx, projectID, datasetID, tableID, nil)
+}
+
+// NewBigQueryReaderWithOptions creates a new BigQueryReader for the
+// specified table with the given options.
+func (bq *BigQueryReadClient) NewBigQueryReaderWithOptions(ctx context.Context, projectID, datasetID, tableID string, opts *BigQueryReadOptions) (*BigQueryReader, error) {
+	if opts == nil {
+		opts = &BigQueryReadOptions{}
+	}
+	var window *limit.Window
+	if opts.Offset != 0 || opts.Limit != 0 {
+		var err error
+		if window, err = limit.NewWindow(opts.Offset, opts.Limit); err != nil {
+			return nil, err
+		}
+	}
+
 	// Define the ArrowSerializationOptions with compression
 	arrowSerializationOptions := &storagepb.ArrowSerializationOptions{
 		BufferCompression: storagepb.ArrowSerializationOptions_LZ4_FRAME,
@@ -108,6 +154,7 @@ func (bq *BigQueryReadClient) NewBigQueryReader(ctx context.Context, projectID,
 
 	// Create ReadOptions and set ArrowSerializationOptions
 	readOptions := &storagepb.ReadSession_TableReadOptions{
+		SelectedFields: opts.Columns,
 		OutputFormatSerializationOptions: &storagepb.ReadSession_TableReadOptions_ArrowSerializationOptions{
 			ArrowSerializationOptions: arrowSerializationOptions,
 		},
@@ -128,11 +175,9 @@ func (bq *BigQueryReadClient) NewBigQueryReader(ctx context.Context, projectID,
 	if err != nil {
 		return nil, fmt.Errorf("failed to create read session: %w", err)
 	}
+	sessionEstimate(session).Log(ctx)
 
-	if len(session.GetStreams()) == 0 {
-		return nil, fmt.Errorf("no streams available in session")
-	}
-
+	// An empty table has a schema but no streams; Read then returns io.EOF.
 	alloc := memoryPool.GetAllocator()
 
 	// Ensure schema is properly initialized
@@ -157,6 +202,7 @@ func (bq *BigQueryReadClient) NewBigQueryReader(ctx context.Context, projectID,
 		mem:         alloc,
 		buf:         bytes.NewBuffer(nil),
 		r:           ipcReader,
+		window:      window,
 	}, nil
 }
 
@@ -171,11 +217,22 @@ type BigQueryReader struct {
 	offset      int64
 	r           *ipc.Reader
 	buf         *bytes.Buffer
+	window      *limit.Window
 }
 
 // Read reads the next record from the BigQuery stream
 func (r *BigQueryReader) Read() (arrow.Record, error) {
+	if r.window != nil {
+		return r.window.Read(r.read)
+	}
+	return r.read()
+}
+
+func (r *BigQueryReader) read() (arrow.Record, error) {
 	for {
+		if err := r.ctx.Err(); err != nil {
+			return nil, err
+		}
 		if r.r != nil && r.r.Next() {
 			record := r.r.Record()
 			record.Retain()
@@ -198,6 +255,50 @@ func (r *BigQueryReader) Read() (arrow.Record, error) {
 	}
 }
 
+// NewBigQueryQueryReader runs sql as a query job billed to projectID and
+// reads its results with the Storage Read API from the table the job writes
+// them to. params bind the query's named or positional parameters.
+func (bq *BigQueryReadClient) NewBigQueryQueryReader(ctx context.Context, projectID, sql string, params ...bigquery.QueryParameter) (*BigQueryReader, error) {
+	client, err := bigquery.NewClient(ctx, projectID, bq.clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BigQuery client: %w", err)
+	}
+	defer client.Close()
+
+	estimate, err := EstimateQuery(ctx, client, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	estimate.Log(ctx)
+
+	query := client.Query(sql)
+	query.Parameters = params
+	job, err := query.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start query: %w", err)
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for query job %s: %w", job.ID(), err)
+	}
+	if err := status.Err(); err != nil {
+		return nil, fmt.Errorf("query job %s failed: %w", job.ID(), err)
+	}
+
+	config, err := job.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query job configuration: %w", err)
+	}
+	queryConfig, ok := config.(*bigquery.QueryConfig)
+	if !ok || queryConfig.Dst == nil {
+		// Scripts and DDL statements do not write a result table.
+		return nil, fmt.Errorf("query job %s has no destination table to read", job.ID())
+	}
+
+	dst := queryConfig.Dst
+	return bq.NewBigQueryReader(ctx, dst.ProjectID, dst.DatasetID, dst.TableID)
+}
+
 // readNextResponse reads the next response from the BigQuery stream
 func (r *BigQueryReader) readNextResponse() (*storagepb.ReadRowsResponse, error) {
 	if r.stream == nil {
@@ -220,6 +321,11 @@ func (r *BigQueryReader) readNextResponse() (*storagepb.ReadRowsResponse, error)
 		return nil, err
 	}
 	if err != nil {
+		// The stream fails with a gRPC status once the context is done;
+		// return the context's error so callers can tell it apart.
+		if ctxErr := r.ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, fmt.Errorf("error receiving stream response: %w", err)
 	}
 
@@ -0,0 +1,331 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	bq "cloud.google.com/go/bigquery"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/arrowarc/arrowarc/pkg/geo"
+	"google.golang.org/api/googleapi"
+)
+
+// BigQuery NUMERIC holds 38 digits with up to 9 after the decimal point;
+// wider decimals need BIGNUMERIC.
+const (
+	bigQueryNumericScale        = 9
+	bigQueryNumericIntDigits    = 29
+	bigQueryBigNumericScale     = 38
+	bigQueryBigNumericIntDigits = 38
+	bigQueryMaxClusteringCount  = 4
+)
+
+// BigQueryTableOptions configures the table NewBigQueryRecordWriter creates
+// when the destination table does not exist.
+type BigQueryTableOptions struct {
+	// TimePartitioning partitions the table by ingestion time, or by a DATE,
+	// TIMESTAMP or DATETIME column when Field is set.
+	TimePartitioning *bq.TimePartitioning
+	// RangePartitioning partitions the table by ranges of an INTEGER column.
+	RangePartitioning *bq.RangePartitioning
+	// Clustering lists up to four top-level columns to cluster by.
+	Clustering  []string
+	Description string
+	Labels      map[string]string
+}
+
+// ArrowSchemaToBigQuery maps an Arrow schema to a BigQuery table schema.
+// Structs become RECORD fields, lists become REPEATED fields and maps become
+// repeated key/value records. Non-nullable fields are REQUIRED. The WKB
+// geometry columns named by GeoParquet metadata become GEOGRAPHY fields.
+func ArrowSchemaToBigQuery(schema *arrow.Schema) (bq.Schema, error) {
+	if schema == nil {
+		return nil, errors.New("schema is nil")
+	}
+	fields, err := bigQueryFields(schema.Fields())
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range geo.Columns(schema) {
+		fields[i].Type = bq.GeographyFieldType
+	}
+	return fields, nil
+}
+
+func bigQueryFields(fields []arrow.Field) (bq.Schema, error) {
+	out := make(bq.Schema, 0, len(fields))
+	for _, field := range fields {
+		fs, err := bigQueryField(field)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, fs)
+	}
+	return out, nil
+}
+
+func bigQueryField(field arrow.Field) (*bq.FieldSchema, error) {
+	dt := field.Type
+	repeated := false
+	if elem, ok := listElement(dt); ok {
+		if _, nested := listElement(elem.Type); nested {
+			return nil, fmt.Errorf("field %q: BigQuery does not support nested repeated fields", field.Name)
+		}
+		if _, isMap := elem.Type.(*arrow.MapType); isMap {
+			return nil, fmt.Errorf("field %q: BigQuery does not support repeated maps", field.Name)
+		}
+		dt, repeated = elem.Type, true
+	}
+
+	fs := &bq.FieldSchema{
+		Name:     field.Name,
+		Repeated: repeated,
+		Required: !repeated && !field.Nullable,
+	}
+	if err := setBigQueryType(fs, dt); err != nil {
+		return nil, fmt.Errorf("field %q: %w", field.Name, err)
+	}
+	return fs, nil
+}
+
+// listElement returns the element field of the Arrow list types.
+func listElement(dt arrow.DataType) (arrow.Field, bool) {
+	switch t := dt.(type) {
+	case *arrow.ListType:
+		return t.ElemField(), true
+	case *arrow.LargeListType:
+		return t.ElemField(), true
+	case *arrow.FixedSizeListType:
+		return t.ElemField(), true
+	case *arrow.ListViewType:
+		return t.ElemField(), true
+	case *arrow.LargeListViewType:
+		return t.ElemField(), true
+	}
+	return arrow.Field{}, false
+}
+
+func setBigQueryType(fs *bq.FieldSchema, dt arrow.DataType) error {
+	switch t := dt.(type) {
+	case *arrow.BooleanType:
+		fs.Type = bq.BooleanFieldType
+	case *arrow.Int8Type, *arrow.Int16Type, *arrow.Int32Type, *arrow.Int64Type,
+		*arrow.Uint8Type, *arrow.Uint16Type, *arrow.Uint32Type:
+		fs.Type = bq.IntegerFieldType
+	case *arrow.Uint64Type:
+		// Values above math.MaxInt64 do not fit INTEGER.
+		fs.Type = bq.NumericFieldType
+	case *arrow.Float16Type, *arrow.Float32Type, *arrow.Float64Type:
+		fs.Type = bq.FloatFieldType
+	case *arrow.StringType, *arrow.LargeStringType, *arrow.StringViewType:
+		fs.Type = bq.StringFieldType
+	case *arrow.BinaryType, *arrow.LargeBinaryType, *arrow.BinaryViewType, *arrow.FixedSizeBinaryType:
+		fs.Type = bq.BytesFieldType
+	case *arrow.Decimal128Type:
+		setBigQueryDecimal(fs, t.Precision, t.Scale)
+	case *arrow.Decimal256Type:
+		setBigQueryDecimal(fs, t.Precision, t.Scale)
+	case *arrow.Date32Type, *arrow.Date64Type:
+		fs.Type = bq.DateFieldType
+	case *arrow.Time32Type, *arrow.Time64Type:
+		fs.Type = bq.TimeFieldType
+	case *arrow.TimestampType:
+		if t.TimeZone == "" {
+			fs.Type = bq.DateTimeFieldType
+		} else {
+			fs.Type = bq.TimestampFieldType
+		}
+	case *arrow.DurationType, *arrow.MonthIntervalType, *arrow.DayTimeIntervalType, *arrow.MonthDayNanoIntervalType:
+		fs.Type = bq.IntervalFieldType
+	case *arrow.StructType:
+		nested, err := bigQueryFields(t.Fields())
+		if err != nil {
+			return err
+		}
+		fs.Type = bq.RecordFieldType
+		fs.Schema = nested
+	case *arrow.MapType:
+		key, err := bigQueryField(t.KeyField())
+		if err != nil {
+			return err
+		}
+		value, err := bigQueryField(t.ItemField())
+		if err != nil {
+			return err
+		}
+		key.Required = true
+		fs.Type = bq.RecordFieldType
+		fs.Schema = bq.Schema{key, value}
+		fs.Repeated, fs.Required = true, false
+	case *arrow.DictionaryType:
+		return setBigQueryType(fs, t.ValueType)
+	case *arrow.NullType:
+		fs.Type = bq.StringFieldType
+		fs.Required = false
+	case arrow.ExtensionType:
+		switch t.ExtensionName() {
+		case "arrow.json":
+			fs.Type = bq.JSONFieldType
+		case "arrow.uuid":
+			fs.Type = bq.StringFieldType
+		default:
+			return setBigQueryType(fs, t.StorageType())
+		}
+	default:
+		return fmt.Errorf("unsupported Arrow type %s", dt)
+	}
+	return nil
+}
+
+func setBigQueryDecimal(fs *bq.FieldSchema, precision, scale int32) {
+	if scale < 0 || scale > precision {
+		// Negative or oversized scales have no BigQuery equivalent; fall
+		// back to the widest default.
+		fs.Type = bq.BigNumericFieldType
+		return
+	}
+	fs.Type = bq.NumericFieldType
+	if scale > bigQueryNumericScale || precision-scale > bigQueryNumericIntDigits {
+		fs.Type = bq.BigNumericFieldType
+		if scale > bigQueryBigNumericScale || precision-scale > bigQueryBigNumericIntDigits {
+			return
+		}
+	}
+	fs.Precision = int64(precision)
+	fs.Scale = int64(scale)
+}
+
+// NewBigQueryTableMetadata returns the metadata for a table holding records
+// of the given Arrow schema, checking the partitioning and clustering columns
+// against it.
+func NewBigQueryTableMetadata(schema *arrow.Schema, opts *BigQueryTableOptions) (*bq.TableMetadata, error) {
+	if opts == nil {
+		opts = &BigQueryTableOptions{}
+	}
+	bqSchema, err := ArrowSchemaToBigQuery(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.TimePartitioning != nil && opts.RangePartitioning != nil {
+		return nil, errors.New("a table can use time or range partitioning, not both")
+	}
+	if tp := opts.TimePartitioning; tp != nil && tp.Field != "" {
+		if err := checkBigQueryColumn(bqSchema, tp.Field, "partitioning",
+			bq.DateFieldType, bq.TimestampFieldType, bq.DateTimeFieldType); err != nil {
+			return nil, err
+		}
+	}
+	if rp := opts.RangePartitioning; rp != nil {
+		if err := checkBigQueryColumn(bqSchema, rp.Field, "partitioning", bq.IntegerFieldType); err != nil {
+			return nil, err
+		}
+	}
+
+	md := &bq.TableMetadata{
+		Schema:            bqSchema,
+		Description:       opts.Description,
+		Labels:            opts.Labels,
+		TimePartitioning:  opts.TimePartitioning,
+		RangePartitioning: opts.RangePartitioning,
+	}
+	if len(opts.Clustering) > 0 {
+		if len(opts.Clustering) > bigQueryMaxClusteringCount {
+			return nil, fmt.Errorf("at most %d clustering columns are allowed, got %d", bigQueryMaxClusteringCount, len(opts.Clustering))
+		}
+		for _, name := range opts.Clustering {
+			if err := checkBigQueryColumn(bqSchema, name, "clustering"); err != nil {
+				return nil, err
+			}
+		}
+		md.Clustering = &bq.Clustering{Fields: opts.Clustering}
+	}
+	return md, nil
+}
+
+// checkBigQueryColumn reports an error unless name is a top-level,
+// non-repeated column of one of the given types (any type when none is given).
+func checkBigQueryColumn(schema bq.Schema, name, use string, types ...bq.FieldType) error {
+	for _, fs := range schema {
+		if fs.Name != name {
+			continue
+		}
+		if fs.Repeated || fs.Type == bq.RecordFieldType {
+			return fmt.Errorf("%s column %q must be a top-level scalar column", use, name)
+		}
+		if len(types) == 0 {
+			return nil
+		}
+		for _, t := range types {
+			if fs.Type == t {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s column %q has type %s, want one of %v", use, name, fs.Type, types)
+	}
+	return fmt.Errorf("%s column %q is not in the schema", use, name)
+}
+
+// ensureBigQueryTable creates the table from the Arrow schema unless it
+// already exists.
+func ensureBigQueryTable(ctx context.Context, client *BigQueryWriteClient, projectID, datasetID, tableID string, opts *BigQueryTableOptions) error {
+	md, err := NewBigQueryTableMetadata(client.schema, opts)
+	if err != nil {
+		return fmt.Errorf("failed to build table definition: %w", err)
+	}
+
+	bqClient, err := bq.NewClient(ctx, projectID, client.clientOptions...)
+	if err != nil {
+		return fmt.Errorf("failed to create BigQuery client: %w", err)
+	}
+	defer bqClient.Close()
+
+	table := bqClient.Dataset(datasetID).Table(tableID)
+	if _, err := table.Metadata(ctx); err == nil {
+		return nil
+	} else if !isGoogleAPIStatus(err, http.StatusNotFound) {
+		return fmt.Errorf("failed to look up table %s.%s: %w", datasetID, tableID, err)
+	}
+
+	// Another writer may create the table between the lookup and here.
+	if err := table.Create(ctx, md); err != nil && !isGoogleAPIStatus(err, http.StatusConflict) {
+		return fmt.Errorf("failed to create table %s.%s: %w", datasetID, tableID, err)
+	}
+	return nil
+}
+
+func isGoogleAPIStatus(err error, code int) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
@@ -32,11 +32,10 @@ package integrations
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"os"
-	"sync"
-
 	"io"
+	"sync"
 	"time"
 
 	storage "cloud.google.com/go/bigquery/storage/apiv1"
@@ -46,51 +45,130 @@ import (
 	"github.com/apache/arrow-go/v18/arrow/memory"
 	memoryPool "github.com/arrowarc/arrowarc/internal/memory"
 	helper "github.com/arrowarc/arrowarc/pkg/common/utils"
+	"github.com/arrowarc/arrowarc/pkg/gcpauth"
+	"github.com/arrowarc/arrowarc/pkg/retry"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 type BigQueryWriteClient struct {
-	client *storage.BigQueryWriteClient
-	schema *arrow.Schema
+	client        *storage.BigQueryWriteClient
+	schema        *arrow.Schema
+	clientOptions []option.ClientOption
+}
+
+// WriteStreamMode selects the kind of Storage Write API stream a
+// BigQueryRecordWriter appends to.
+type WriteStreamMode int
+
+const (
+	// WriteStreamModeDefault appends to the table's default stream. Rows are
+	// visible immediately; a retried append may be written twice.
+	WriteStreamModeDefault WriteStreamMode = iota
+	// WriteStreamModeCommitted appends to a dedicated stream at explicit
+	// offsets. Rows are visible once acknowledged and retries never duplicate
+	// them.
+	WriteStreamModeCommitted
+	// WriteStreamModePending appends to a dedicated stream at explicit
+	// offsets and commits all rows atomically on Close, giving exactly-once
+	// semantics for the whole write.
+	WriteStreamModePending
+)
+
+func (m WriteStreamMode) String() string {
+	switch m {
+	case WriteStreamModeDefault:
+		return "default"
+	case WriteStreamModeCommitted:
+		return "committed"
+	case WriteStreamModePending:
+		return "pending"
+	}
+	return fmt.Sprintf("WriteStreamMode(%d)", int(m))
+}
+
+// defaultAppendRetry is the retry policy of appends when the options set
+// none: three attempts, one second apart and then two.
+func defaultAppendRetry() *retry.Policy {
+	return &retry.Policy{
+		MaxAttempts:     3,
+		InitialInterval: time.Second,
+		MaxInterval:     10 * time.Second,
+		Multiplier:      2,
+	}
 }
 
 type BigQueryWriteOptions struct {
-	WriteStreamType storagepb.WriteStream_Type
+	WriteStreamMode WriteStreamMode
 	Allocator       memory.Allocator
+	// CreateTable, when set, creates the destination table from the Arrow
+	// schema if it does not exist yet.
+	CreateTable *BigQueryTableOptions
+	// Retry is the policy of failed appends. Without a classifier of its
+	// own, it retries dropped connections and transient gRPC errors.
+	Retry *retry.Policy
 }
 
 func NewDefaultBigQueryWriteOptions() *BigQueryWriteOptions {
 	return &BigQueryWriteOptions{
-		WriteStreamType: storagepb.WriteStream_COMMITTED,
+		WriteStreamMode: WriteStreamModeCommitted,
 		Allocator:       memoryPool.GetAllocator(),
 	}
 }
 
 func NewBigQueryWriteClient(ctx context.Context, serviceAccountJSON string, schema *arrow.Schema) (*BigQueryWriteClient, error) {
-	// Check if the provided string is a file path
-	if _, err := os.Stat(serviceAccountJSON); err == nil {
-		content, err := os.ReadFile(serviceAccountJSON)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read service account JSON file: %w", err)
-		}
-		serviceAccountJSON = string(content)
+	return NewBigQueryWriteClientWithAuth(ctx, schema, gcpauth.FromKey(serviceAccountJSON))
+}
+
+// NewBigQueryWriteClientWithAuth creates a write client authenticating as
+// auth says, with Application Default Credentials if it is nil, and the
+// further client options opts.
+func NewBigQueryWriteClientWithAuth(ctx context.Context, schema *arrow.Schema, auth *gcpauth.Options, opts ...option.ClientOption) (*BigQueryWriteClient, error) {
+	opts, err := auth.ClientOptions(ctx, opts...)
+	if err != nil {
+		return nil, err
 	}
+	return NewBigQueryWriteClientWithOptions(ctx, schema, opts...)
+}
 
-	client, err := storage.NewBigQueryWriteClient(ctx, option.WithCredentialsJSON([]byte(serviceAccountJSON)))
+// NewBigQueryWriteClientWithOptions creates a write client from arbitrary
+// client options, such as application default credentials or an endpoint.
+func NewBigQueryWriteClientWithOptions(ctx context.Context, schema *arrow.Schema, opts ...option.ClientOption) (*BigQueryWriteClient, error) {
+	client, err := storage.NewBigQueryWriteClient(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create BigQuery Storage API client: %w", err)
 	}
 
 	return &BigQueryWriteClient{
-		client: client,
-		schema: schema,
+		client:        client,
+		schema:        schema,
+		clientOptions: opts,
 	}, nil
 }
 
+// Close closes the underlying Storage API connection.
+func (c *BigQueryWriteClient) Close() error {
+	return c.client.Close()
+}
+
+// withSchema returns a client sharing c's connection that writes records
+// of schema. Closing it closes c.
+func (c *BigQueryWriteClient) withSchema(schema *arrow.Schema) *BigQueryWriteClient {
+	return &BigQueryWriteClient{
+		client:        c.client,
+		schema:        schema,
+		clientOptions: c.clientOptions,
+	}
+}
+
 type BigQueryRecordWriter struct {
 	client        *BigQueryWriteClient
 	appendClient  storagepb.BigQueryWrite_AppendRowsClient
-	writeStream   *storagepb.WriteStream
+	tableName     string
+	streamName    string
+	offset        int64
 	protoSchema   *storagepb.ProtoSchema
 	buffer        *bytes.Buffer
 	ipcWriter     *ipc.Writer
@@ -102,23 +180,46 @@ func NewBigQueryRecordWriter(ctx context.Context, client *BigQueryWriteClient, p
 	if opts == nil {
 		opts = NewDefaultBigQueryWriteOptions()
 	}
+	if opts.Allocator == nil {
+		opts.Allocator = memoryPool.GetAllocator()
+	}
+
+	if opts.CreateTable != nil {
+		if err := ensureBigQueryTable(ctx, client, projectID, datasetID, tableID, opts.CreateTable); err != nil {
+			return nil, err
+		}
+	}
 
 	tableName := fmt.Sprintf("projects/%s/datasets/%s/tables/%s", projectID, datasetID, tableID)
 
-	writeStream, err := client.client.CreateWriteStream(ctx, &storagepb.CreateWriteStreamRequest{
-		Parent: tableName,
-		WriteStream: &storagepb.WriteStream{
-			Type: opts.WriteStreamType,
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create write stream: %w", err)
+	var streamName string
+	switch opts.WriteStreamMode {
+	case WriteStreamModeDefault:
+		streamName = tableName + "/streams/_default"
+	case WriteStreamModeCommitted, WriteStreamModePending:
+		streamType := storagepb.WriteStream_COMMITTED
+		if opts.WriteStreamMode == WriteStreamModePending {
+			streamType = storagepb.WriteStream_PENDING
+		}
+		writeStream, err := client.client.CreateWriteStream(ctx, &storagepb.CreateWriteStreamRequest{
+			Parent: tableName,
+			WriteStream: &storagepb.WriteStream{
+				Type: streamType,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create write stream: %w", err)
+		}
+		streamName = writeStream.GetName()
+	default:
+		return nil, fmt.Errorf("unknown write stream mode %v", opts.WriteStreamMode)
 	}
 
 	appendClient, err := client.client.AppendRows(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open AppendRows client: %w", err)
 	}
+	EstimateWrite(tableName, 0, false).Log(ctx)
 
 	buffer := &bytes.Buffer{}
 	ipcWriter := ipc.NewWriter(buffer, ipc.WithSchema(client.schema), ipc.WithAllocator(opts.Allocator))
@@ -126,7 +227,8 @@ func NewBigQueryRecordWriter(ctx context.Context, client *BigQueryWriteClient, p
 	return &BigQueryRecordWriter{
 		client:        client,
 		appendClient:  appendClient,
-		writeStream:   writeStream,
+		tableName:     tableName,
+		streamName:    streamName,
 		protoSchema:   helper.ConvertSchemaSPB(client.schema),
 		buffer:        buffer,
 		ipcWriter:     ipcWriter,
@@ -134,6 +236,17 @@ func NewBigQueryRecordWriter(ctx context.Context, client *BigQueryWriteClient, p
 	}, nil
 }
 
+// StreamName returns the name of the write stream rows are appended to.
+func (w *BigQueryRecordWriter) StreamName() string {
+	return w.streamName
+}
+
+// Offset returns the number of rows acknowledged on a committed or pending
+// stream, which is the offset of the next append.
+func (w *BigQueryRecordWriter) Offset() int64 {
+	return w.offset
+}
+
 func (w *BigQueryRecordWriter) Write(record arrow.Record) error {
 	if !w.client.schema.Equal(record.Schema()) {
 		return fmt.Errorf("schema mismatch: expected %v but got %v", w.client.schema, record.Schema())
@@ -158,60 +271,150 @@ func (w *BigQueryRecordWriter) Write(record arrow.Record) error {
 	}
 
 	appendReq := &storagepb.AppendRowsRequest{
-		WriteStream: w.writeStream.GetName(),
+		WriteStream: w.streamName,
 		Rows:        &storagepb.AppendRowsRequest_ProtoRows{ProtoRows: protoData},
 	}
+	// The default stream does not accept offsets.
+	if w.writerOptions.WriteStreamMode != WriteStreamModeDefault {
+		appendReq.Offset = wrapperspb.Int64(w.offset)
+	}
 
-	maxRetries := 3
-	var lastErr error
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		err := w.appendClient.Send(appendReq)
-		if err == nil {
-			fmt.Printf("AppendRowsRequest sent successfully on attempt %d\n", attempt+1)
-			return nil // Success, exit the function
-		}
-
-		lastErr = err
-		fmt.Printf("Error sending AppendRowsRequest (attempt %d of %d): %v\n", attempt+1, maxRetries, err)
+	if err := w.append(appendReq); err != nil {
+		return err
+	}
+	w.offset += int64(len(protoData.Rows.SerializedRows))
+	return nil
+}
 
-		if err == io.EOF {
+// append sends a request and waits for its acknowledgement, reconnecting and
+// resending on transient failures. Offsets make the resend safe: if the first
+// attempt was written after all, the service reports ALREADY_EXISTS.
+func (w *BigQueryRecordWriter) append(req *storagepb.AppendRowsRequest) error {
+	policy := w.writerOptions.Retry
+	if policy == nil {
+		policy = defaultAppendRetry()
+	}
+	attempt := 0
+	err := policy.OrRetryable(isRetryableAppendError).Do(context.Background(), func(context.Context) error {
+		if attempt++; attempt > 1 {
 			if err := w.recreateAppendClient(); err != nil {
-				return fmt.Errorf("failed to recreate append client: %w", err)
+				return retry.Permanent(fmt.Errorf("failed to recreate append client: %w", err))
 			}
-			continue
 		}
+		err := w.sendAndReceive(req)
+		if err != nil && req.GetOffset() != nil && status.Code(err) == codes.AlreadyExists {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append rows at offset %d: %w", w.offset, err)
+	}
+	return nil
+}
 
-		// Add a small delay before retrying
-		time.Sleep(time.Second * time.Duration(attempt+1))
+func (w *BigQueryRecordWriter) sendAndReceive(req *storagepb.AppendRowsRequest) error {
+	if err := w.appendClient.Send(req); err != nil {
+		return err
+	}
+	resp, err := w.appendClient.Recv()
+	if err != nil {
+		return err
 	}
+	if st := resp.GetError(); st != nil {
+		err := status.ErrorProto(st)
+		for _, rowErr := range resp.GetRowErrors() {
+			err = fmt.Errorf("%w; row %d: %s", err, rowErr.GetIndex(), rowErr.GetMessage())
+		}
+		return err
+	}
+	return nil
+}
 
-	return fmt.Errorf("failed to send AppendRowsRequest after %d attempts: %w", maxRetries, lastErr)
+func isRetryableAppendError(err error) bool {
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Internal, codes.Aborted, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
 }
 
 func (w *BigQueryRecordWriter) recreateAppendClient() error {
 	var err error
 	ctx := context.Background()
+	if w.appendClient != nil {
+		_ = w.appendClient.CloseSend()
+	}
 	w.appendClient, err = w.client.client.AppendRows(ctx)
 	return err
 }
 
+// Abort closes the writer without finalizing its stream. The rows of a
+// pending stream are never committed; those of other streams stay written.
+func (w *BigQueryRecordWriter) Abort() error {
+	w.writeDone.Wait()
+	defer memoryPool.PutAllocator(w.writerOptions.Allocator)
+
+	if err := w.ipcWriter.Close(); err != nil {
+		return fmt.Errorf("failed to close IPC writer: %w", err)
+	}
+	return w.appendClient.CloseSend()
+}
+
 func (w *BigQueryRecordWriter) Close() error {
 	w.writeDone.Wait()
+	defer memoryPool.PutAllocator(w.writerOptions.Allocator)
 
 	if err := w.ipcWriter.Close(); err != nil {
 		return fmt.Errorf("failed to close IPC writer: %w", err)
 	}
+	if err := w.appendClient.CloseSend(); err != nil {
+		return fmt.Errorf("failed to close append stream: %w", err)
+	}
+
+	// The default stream is never finalized.
+	if w.writerOptions.WriteStreamMode == WriteStreamModeDefault {
+		return nil
+	}
+
+	ctx := context.Background()
 
 	// Finalize the write stream
 	finalizeRequest := &storagepb.FinalizeWriteStreamRequest{
-		Name: w.writeStream.Name,
+		Name: w.streamName,
 	}
-	_, err := w.client.client.FinalizeWriteStream(context.Background(), finalizeRequest)
+	resp, err := w.client.client.FinalizeWriteStream(ctx, finalizeRequest)
 	if err != nil {
 		return fmt.Errorf("failed to finalize write stream: %w", err)
 	}
+	if resp.GetRowCount() != w.offset {
+		return fmt.Errorf("write stream finalized with %d rows, expected %d", resp.GetRowCount(), w.offset)
+	}
 
-	defer memoryPool.PutAllocator(w.writerOptions.Allocator)
+	if w.writerOptions.WriteStreamMode != WriteStreamModePending {
+		return nil
+	}
+
+	commit, err := w.client.client.BatchCommitWriteStreams(ctx, &storagepb.BatchCommitWriteStreamsRequest{
+		Parent:       w.tableName,
+		WriteStreams: []string{w.streamName},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit write stream: %w", err)
+	}
+	if streamErrs := commit.GetStreamErrors(); len(streamErrs) > 0 {
+		errs := make([]error, 0, len(streamErrs))
+		for _, e := range streamErrs {
+			errs = append(errs, fmt.Errorf("%s: %s: %s", e.GetEntity(), e.GetCode(), e.GetErrorMessage()))
+		}
+		return fmt.Errorf("failed to commit write stream: %w", errors.Join(errs...))
+	}
+	if commit.GetCommitTime() == nil {
+		return errors.New("write stream was not committed")
+	}
 
 	return nil
 }
@@ -32,8 +32,10 @@ package integrations
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/apache/arrow-adbc/go/adbc"
 	"github.com/apache/arrow-adbc/go/adbc/drivermgr"
@@ -42,6 +44,8 @@ import (
 	"github.com/apache/arrow-go/v18/arrow/ipc"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/merge"
+	"github.com/arrowarc/arrowarc/pkg/retry"
 )
 
 // DuckDBReader reads records from DuckDB and implements the Reader interface.
@@ -58,6 +62,28 @@ type DuckDBReader struct {
 type DuckDBReadOptions struct {
 	Extensions []DuckDBExtension
 	Query      string
+	// Offset skips the first rows of the query's result, and Limit keeps at
+	// most that many, zero meaning no limit. Both are pushed down into the
+	// query.
+	Offset int64
+	Limit  int64
+}
+
+// windowQuery wraps query to return only the rows after offset, up to
+// limit rows when limit is positive.
+func windowQuery(query string, offset, limit int64) string {
+	if offset == 0 && limit == 0 {
+		return query
+	}
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	windowed := fmt.Sprintf("SELECT * FROM (%s)", query)
+	if limit > 0 {
+		windowed += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if offset > 0 {
+		windowed += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return windowed
 }
 
 // DuckDBExtension represents a DuckDB extension with its name and load preference.
@@ -75,36 +101,58 @@ func DefaultExtensions() []DuckDBExtension {
 
 // RunSQL runs a SQL query on DuckDB and returns the results as Arrow records.
 func (r *DuckDBReader) RunSQL(sql string) ([]arrow.Record, error) {
+	records, _, err := r.query(sql)
+	return records, err
+}
+
+// query runs a SQL query and returns the records of its result with their
+// schema, which is known even when there are none.
+func (r *DuckDBReader) query(sql string) ([]arrow.Record, *arrow.Schema, error) {
 	stmt, err := r.conn.NewStatement()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create new statement: %w", err)
+		return nil, nil, fmt.Errorf("failed to create new statement: %w", err)
 	}
 	defer stmt.Close()
 
 	if err := stmt.SetSqlQuery(sql); err != nil {
-		return nil, fmt.Errorf("failed to set SQL query: %w", err)
+		return nil, nil, fmt.Errorf("failed to set SQL query: %w", err)
 	}
 
 	out, _, err := stmt.ExecuteQuery(r.ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return nil, nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 	defer out.Release()
 
 	var result []arrow.Record
 	for out.Next() {
+		if err := r.ctx.Err(); err != nil {
+			releaseRecords(result)
+			return nil, nil, err
+		}
 		rec := out.Record()
 		rec.Retain()
 		result = append(result, rec)
 	}
 	if err := out.Err(); err != nil {
-		return nil, err
+		releaseRecords(result)
+		return nil, nil, err
+	}
+	return result, out.Schema(), nil
+}
+
+// releaseRecords releases records read before a failure.
+func releaseRecords(records []arrow.Record) {
+	for _, rec := range records {
+		rec.Release()
 	}
-	return result, nil
 }
 
 // NewDuckDBReader creates a new DuckDB reader.
 func NewDuckDBReader(ctx context.Context, dbURL string, opts *DuckDBReadOptions) (*DuckDBReader, error) {
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return nil, fmt.Errorf("offset and limit cannot be negative")
+	}
 	alloc := pool.GetAllocator()
 
 	runner, err := newDuckDBSQLRunner(ctx, dbURL, opts.Extensions)
@@ -113,20 +161,17 @@ func NewDuckDBReader(ctx context.Context, dbURL string, opts *DuckDBReadOptions)
 		return nil, fmt.Errorf("failed to create DuckDB runner: %w", err)
 	}
 
-	records, err := runner.RunSQL(opts.Query)
+	// A query returning no rows still has a schema, read from its result.
+	records, schema, err := runner.query(windowQuery(opts.Query, opts.Offset, opts.Limit))
 	if err != nil {
 		runner.Close()
 		pool.PutAllocator(alloc)
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
-
-	if len(records) == 0 {
-		runner.Close()
-		pool.PutAllocator(alloc)
-		return nil, fmt.Errorf("no records returned from query")
+	if len(records) > 0 {
+		schema = records[0].Schema()
 	}
 
-	schema := records[0].Schema()
 	reader, err := array.NewRecordReader(schema, records)
 	if err != nil {
 		runner.Close()
@@ -135,6 +180,7 @@ func NewDuckDBReader(ctx context.Context, dbURL string, opts *DuckDBReadOptions)
 	}
 
 	return &DuckDBReader{
+		ctx:          ctx,
 		recordReader: reader,
 		conn:         runner.conn,
 		schema:       schema,
@@ -144,6 +190,9 @@ func NewDuckDBReader(ctx context.Context, dbURL string, opts *DuckDBReadOptions)
 
 // Read reads the next record from DuckDB.
 func (d *DuckDBReader) Read() (arrow.Record, error) {
+	if err := d.ctx.Err(); err != nil {
+		return nil, err
+	}
 	if d.recordReader.Next() {
 		record := d.recordReader.Record()
 		record.Retain() // Retain the record to ensure it stays valid
@@ -169,17 +218,47 @@ func (d *DuckDBReader) Schema() *arrow.Schema {
 
 // DuckDBWriter writes records to DuckDB and implements the Writer interface.
 type DuckDBWriter struct {
+	ctx   context.Context
 	conn  adbc.Connection
 	stmt  adbc.Statement
 	table string
 	alloc memory.Allocator
+	retry *retry.Policy
+	merge *merge.Options
+}
+
+// DuckDBWriteOptions defines options for writing to DuckDB.
+type DuckDBWriteOptions struct {
+	Extensions []DuckDBExtension
+	// Retry is the policy of failed inserts. Without a classifier of its
+	// own, it retries I/O errors, timeouts and locks held by another
+	// process. Defaults to retry.Default.
+	Retry *retry.Policy
+	// Merge, when set, merges records into the table by their key columns
+	// instead of appending them: rows replace the rows with their key, and
+	// rows whose op column is "d" delete them. Each record is applied in a
+	// transaction of its own.
+	Merge *merge.Options
 }
 
 // NewDuckDBWriter creates a new DuckDB writer.
 func NewDuckDBWriter(ctx context.Context, dbURL string, tableName string, extensions []DuckDBExtension) (*DuckDBWriter, error) {
+	return NewDuckDBWriterWithOptions(ctx, dbURL, tableName, &DuckDBWriteOptions{Extensions: extensions})
+}
+
+// NewDuckDBWriterWithOptions creates a new DuckDB writer with the given options.
+func NewDuckDBWriterWithOptions(ctx context.Context, dbURL string, tableName string, opts *DuckDBWriteOptions) (*DuckDBWriter, error) {
+	if opts == nil {
+		opts = &DuckDBWriteOptions{}
+	}
+	if opts.Merge != nil {
+		if err := opts.Merge.Validate(); err != nil {
+			return nil, err
+		}
+	}
 	alloc := pool.GetAllocator()
 
-	runner, err := newDuckDBSQLRunner(ctx, dbURL, extensions)
+	runner, err := newDuckDBSQLRunner(ctx, dbURL, opts.Extensions)
 	if err != nil {
 		pool.PutAllocator(alloc)
 		return nil, fmt.Errorf("failed to create DuckDB runner: %w", err)
@@ -206,10 +285,13 @@ func NewDuckDBWriter(ctx context.Context, dbURL string, tableName string, extens
 	}
 
 	return &DuckDBWriter{
+		ctx:   ctx,
 		conn:  runner.conn,
 		stmt:  stmt,
 		table: tableName,
 		alloc: alloc,
+		retry: opts.Retry.OrRetryable(isRetryableDuckDBError),
+		merge: opts.Merge,
 	}, nil
 }
 
@@ -218,6 +300,12 @@ func (w *DuckDBWriter) Write(record arrow.Record) error {
 	if record.NumRows() == 0 {
 		return fmt.Errorf("received record with no rows")
 	}
+	if w.merge != nil {
+		// A failed merge rolls back, so it is retried as a whole.
+		return w.retry.Do(w.ctx, func(ctx context.Context) error {
+			return merge.ApplyADBC(ctx, w.conn, w.table, record, w.merge)
+		})
+	}
 
 	buf := new(bytes.Buffer)
 	writer := ipc.NewWriter(buf, ipc.WithSchema(record.Schema()), ipc.WithAllocator(w.alloc))
@@ -228,20 +316,39 @@ func (w *DuckDBWriter) Write(record arrow.Record) error {
 		return fmt.Errorf("failed to close IPC writer: %w", err)
 	}
 
-	reader, err := ipc.NewReader(buf, ipc.WithAllocator(w.alloc))
-	if err != nil {
-		return fmt.Errorf("failed to create IPC reader: %w", err)
-	}
-	defer reader.Release()
+	// Each attempt binds a fresh reader over the serialized record, as a
+	// failed attempt may have consumed part of the previous one.
+	return w.retry.Do(w.ctx, func(ctx context.Context) error {
+		reader, err := ipc.NewReader(bytes.NewReader(buf.Bytes()), ipc.WithAllocator(w.alloc))
+		if err != nil {
+			return retry.Permanent(fmt.Errorf("failed to create IPC reader: %w", err))
+		}
+		defer reader.Release()
 
-	if err := w.stmt.BindStream(context.Background(), reader); err != nil {
-		return fmt.Errorf("failed to bind stream: %w", err)
-	}
-	if _, err := w.stmt.ExecuteUpdate(context.Background()); err != nil {
-		return fmt.Errorf("failed to execute update: %w", err)
-	}
+		if err := w.stmt.BindStream(ctx, reader); err != nil {
+			return fmt.Errorf("failed to bind stream: %w", err)
+		}
+		if _, err := w.stmt.ExecuteUpdate(ctx); err != nil {
+			return fmt.Errorf("failed to execute update: %w", err)
+		}
+		return nil
+	})
+}
 
-	return nil
+// isRetryableDuckDBError retries I/O errors and timeouts, which remote
+// databases and files raise, and locks held by another process.
+func isRetryableDuckDBError(err error) bool {
+	var adbcErr adbc.Error
+	if errors.As(err, &adbcErr) {
+		switch adbcErr.Code {
+		case adbc.StatusIO, adbc.StatusTimeout:
+			return true
+		}
+		if strings.Contains(adbcErr.Msg, "Could not set lock on file") {
+			return true
+		}
+	}
+	return retry.IsRetryable(err)
 }
 
 // Close closes the DuckDB writer and releases resources.
@@ -0,0 +1,228 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// Formats of the files an SQLSession reads itself.
+const (
+	SQLParquet = "parquet"
+	SQLCSV     = "csv"
+	SQLJSON    = "json"
+)
+
+// RecordSource is a stream of records with a known schema, ending with
+// io.EOF.
+type RecordSource interface {
+	Read() (arrow.Record, error)
+	Schema() *arrow.Schema
+}
+
+// SQLSession is an in-memory DuckDB database for ad hoc queries over
+// files and other sources registered under a name.
+type SQLSession struct {
+	ctx    context.Context
+	runner *DuckDBReader
+}
+
+// NewSQLSession opens an in-memory DuckDB database.
+func NewSQLSession(ctx context.Context, extensions []DuckDBExtension) (*SQLSession, error) {
+	runner, err := newDuckDBSQLRunner(ctx, ":memory:", extensions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DuckDB runner: %w", err)
+	}
+	return &SQLSession{ctx: ctx, runner: runner}, nil
+}
+
+// RegisterFile creates a view name over the Parquet, CSV or JSON file at
+// path, which DuckDB reads whenever the view is queried. CSV and JSON
+// files are read with DuckDB's type detection.
+func (s *SQLSession) RegisterFile(name, path, format string) error {
+	var reader string
+	switch format {
+	case SQLParquet:
+		reader = "read_parquet"
+	case SQLCSV:
+		reader = "read_csv_auto"
+	case SQLJSON:
+		reader = "read_json_auto"
+	default:
+		return fmt.Errorf("unsupported format %q, expected parquet, csv or json", format)
+	}
+	sql := fmt.Sprintf("CREATE VIEW %s AS SELECT * FROM %s(%s);", quoteIdentifier(name), reader, quoteLiteral(path))
+	if err := executeQuery(s.runner.conn, sql); err != nil {
+		return fmt.Errorf("failed to register %s as %q: %w", path, name, err)
+	}
+	return nil
+}
+
+// RegisterSource loads the records of source into a table name.
+func (s *SQLSession) RegisterSource(name string, source RecordSource) error {
+	reader := &sourceRecordReader{source: source, refs: 1}
+	defer reader.Release()
+	if err := ingest(s.ctx, s.runner.conn, name, reader); err != nil {
+		return err
+	}
+	return reader.err
+}
+
+// Tables returns the names of the tables and views, sorted.
+func (s *SQLSession) Tables() ([]string, error) {
+	return s.runner.tables()
+}
+
+// Query runs sql and returns its results. Statements returning no rows,
+// such as CREATE TABLE, return an empty result.
+func (s *SQLSession) Query(sql string) (*SQLResult, error) {
+	stmt, err := s.runner.conn.NewStatement()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new statement: %w", err)
+	}
+	defer stmt.Close()
+	if err := stmt.SetSqlQuery(sql); err != nil {
+		return nil, fmt.Errorf("failed to set SQL query: %w", err)
+	}
+	out, _, err := stmt.ExecuteQuery(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Release()
+
+	result := &SQLResult{schema: out.Schema()}
+	for out.Next() {
+		if err := s.ctx.Err(); err != nil {
+			result.Close()
+			return nil, err
+		}
+		rec := out.Record()
+		rec.Retain()
+		result.records = append(result.records, rec)
+	}
+	if err := out.Err(); err != nil {
+		result.Close()
+		return nil, err
+	}
+	return result, nil
+}
+
+// Close closes the database.
+func (s *SQLSession) Close() error {
+	if err := s.runner.conn.Close(); err != nil {
+		return err
+	}
+	return s.runner.db.Close()
+}
+
+// SQLResult holds the records returned by a query.
+type SQLResult struct {
+	schema  *arrow.Schema
+	records []arrow.Record
+}
+
+// Schema returns the schema of the results.
+func (r *SQLResult) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Read returns the next record, or io.EOF after the last one. The caller
+// releases the record.
+func (r *SQLResult) Read() (arrow.Record, error) {
+	if len(r.records) == 0 {
+		return nil, io.EOF
+	}
+	rec := r.records[0]
+	r.records = r.records[1:]
+	return rec, nil
+}
+
+// Close releases the records not read.
+func (r *SQLResult) Close() error {
+	for _, rec := range r.records {
+		rec.Release()
+	}
+	r.records = nil
+	return nil
+}
+
+// sourceRecordReader adapts a RecordSource to array.RecordReader, keeping
+// the error the source failed with.
+type sourceRecordReader struct {
+	source RecordSource
+	refs   int64
+	cur    arrow.Record
+	err    error
+}
+
+func (r *sourceRecordReader) Retain() { atomic.AddInt64(&r.refs, 1) }
+
+func (r *sourceRecordReader) Release() {
+	if atomic.AddInt64(&r.refs, -1) == 0 && r.cur != nil {
+		r.cur.Release()
+		r.cur = nil
+	}
+}
+
+func (r *sourceRecordReader) Schema() *arrow.Schema { return r.source.Schema() }
+
+func (r *sourceRecordReader) Next() bool {
+	if r.cur != nil {
+		r.cur.Release()
+		r.cur = nil
+	}
+	if r.err != nil {
+		return false
+	}
+	rec, err := r.source.Read()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			r.err = err
+		}
+		return false
+	}
+	r.cur = rec
+	return true
+}
+
+func (r *sourceRecordReader) Record() arrow.Record { return r.cur }
+
+func (r *sourceRecordReader) Err() error { return r.err }
+
+func quoteIdentifier(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,244 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+)
+
+// Benchmarks whose datasets GenerateTPC generates, with the DuckDB
+// extension of the same name.
+const (
+	TPCH  = "tpch"
+	TPCDS = "tpcds"
+)
+
+// Output formats of GenerateTPC.
+const (
+	TPCParquet = "parquet"
+	TPCCSV     = "csv"
+	// TPCArrow writes Arrow IPC files, which Feather v2 readers also read.
+	TPCArrow = "arrow"
+)
+
+// TPCOptions configures GenerateTPC.
+type TPCOptions struct {
+	// Benchmark is TPCH, the default, or TPCDS.
+	Benchmark string
+	// ScaleFactor sizes the data: 1 is about 1 GB of TPC-H data. Defaults
+	// to 1.
+	ScaleFactor float64
+	// Format is TPCParquet, the default, TPCCSV or TPCArrow.
+	Format string
+	// Tables selects the tables to write. All are written when empty.
+	Tables []string
+	// Compression is the codec of Parquet files, such as snappy or zstd,
+	// or of Arrow files, lz4 or zstd. DuckDB's default when empty.
+	Compression string
+}
+
+// GenerateTPC generates the tables of a TPC-H or TPC-DS dataset with
+// DuckDB's tpch or tpcds extension and writes each to dir, as
+// <table>.parquet, <table>.csv or <table>.arrow. It returns the paths
+// written, in table order.
+func GenerateTPC(ctx context.Context, dir string, opts *TPCOptions) ([]string, error) {
+	if opts == nil {
+		opts = &TPCOptions{}
+	}
+	benchmark, generate := opts.Benchmark, "dbgen"
+	switch benchmark {
+	case "", TPCH:
+		benchmark = TPCH
+	case TPCDS:
+		generate = "dsdgen"
+	default:
+		return nil, fmt.Errorf("unknown benchmark %q, expected %s or %s", opts.Benchmark, TPCH, TPCDS)
+	}
+	format := opts.Format
+	switch format {
+	case "":
+		format = TPCParquet
+	case TPCParquet, TPCCSV, TPCArrow:
+	default:
+		return nil, fmt.Errorf("unsupported format %q, expected %s, %s or %s", opts.Format, TPCParquet, TPCCSV, TPCArrow)
+	}
+	scale := opts.ScaleFactor
+	if scale == 0 {
+		scale = 1
+	}
+	if scale < 0 {
+		return nil, fmt.Errorf("invalid scale factor %v", scale)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	runner, err := newDuckDBSQLRunner(ctx, "", []DuckDBExtension{{Name: benchmark, LoadByDefault: true}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DuckDB runner: %w", err)
+	}
+	defer runner.conn.Close()
+	if err := executeQuery(runner.conn, fmt.Sprintf("CALL %s(sf = %g);", generate, scale)); err != nil {
+		return nil, fmt.Errorf("failed to generate %s data: %w", benchmark, err)
+	}
+
+	tables, err := runner.tables()
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Tables) > 0 {
+		for _, table := range opts.Tables {
+			if !slices.Contains(tables, table) {
+				return nil, fmt.Errorf("%s has no table %q", benchmark, table)
+			}
+		}
+		tables = opts.Tables
+	}
+
+	var paths []string
+	for _, table := range tables {
+		if err := ctx.Err(); err != nil {
+			return paths, err
+		}
+		path := filepath.Join(dir, table+"."+format)
+		if format == TPCArrow {
+			err = runner.writeArrowFile(table, path, opts.Compression)
+		} else {
+			err = executeQuery(runner.conn, copyStatement(table, path, format, opts.Compression))
+		}
+		if err != nil {
+			return paths, fmt.Errorf("failed to write table %s: %w", table, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// copyStatement returns the COPY statement writing table to path in
+// format, Parquet or CSV.
+func copyStatement(table, path, format, compression string) string {
+	options := "FORMAT PARQUET"
+	if format == TPCCSV {
+		options = "FORMAT CSV, HEADER"
+	}
+	if compression != "" {
+		options += ", COMPRESSION " + quoteLiteral(compression)
+	}
+	return fmt.Sprintf("COPY %s TO %s (%s);", table, quoteLiteral(path), options)
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// tables returns the names of the tables of the main schema, sorted.
+func (r *DuckDBReader) tables() ([]string, error) {
+	records, err := r.RunSQL("SELECT table_name FROM information_schema.tables WHERE table_schema = 'main' ORDER BY table_name;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	var tables []string
+	for _, rec := range records {
+		if names, ok := rec.Column(0).(*array.String); ok {
+			for i := 0; i < names.Len(); i++ {
+				tables = append(tables, names.Value(i))
+			}
+		}
+		rec.Release()
+	}
+	return tables, nil
+}
+
+// writeArrowFile streams the rows of table into an Arrow IPC file at path,
+// compressed with lz4 or zstd if set.
+func (r *DuckDBReader) writeArrowFile(table, path, compression string) error {
+	alloc := pool.GetAllocator()
+	defer pool.PutAllocator(alloc)
+	ipcOpts := []ipc.Option{ipc.WithAllocator(alloc)}
+	switch compression {
+	case "":
+	case "lz4":
+		ipcOpts = append(ipcOpts, ipc.WithLZ4())
+	case "zstd":
+		ipcOpts = append(ipcOpts, ipc.WithZstd())
+	default:
+		return fmt.Errorf("unsupported Arrow compression %q, expected lz4 or zstd", compression)
+	}
+
+	stmt, err := r.conn.NewStatement()
+	if err != nil {
+		return fmt.Errorf("failed to create new statement: %w", err)
+	}
+	defer stmt.Close()
+	if err := stmt.SetSqlQuery(fmt.Sprintf("SELECT * FROM %s;", table)); err != nil {
+		return fmt.Errorf("failed to set SQL query: %w", err)
+	}
+	out, _, err := stmt.ExecuteQuery(r.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer out.Release()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w, err := ipc.NewFileWriter(f, append(ipcOpts, ipc.WithSchema(out.Schema()))...)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	for out.Next() {
+		if err := w.Write(out.Record()); err != nil {
+			w.Close()
+			f.Close()
+			return err
+		}
+	}
+	if err := out.Err(); err != nil {
+		w.Close()
+		f.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
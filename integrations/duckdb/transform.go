@@ -0,0 +1,219 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// DefaultSQLTransformTable is the table name incoming records are
+// registered under when SQLTransformOptions.Table is empty.
+const DefaultSQLTransformTable = "batch"
+
+// SQLTransformOptions defines options for transforming records with SQL.
+type SQLTransformOptions struct {
+	// SQL is the statement run against the incoming records, which are
+	// registered as a table named Table.
+	SQL string
+	// Table is the name the incoming records are registered under.
+	// Defaults to DefaultSQLTransformTable.
+	Table string
+	// WindowRows, if positive, buffers incoming records until at least
+	// this many rows have arrived and runs SQL once over the whole window.
+	// Otherwise SQL runs once per record.
+	WindowRows int64
+	// Extensions are loaded into the DuckDB database before any query.
+	Extensions []DuckDBExtension
+}
+
+// SQLTransformer is a pipeline Transformer that runs a SQL statement in an
+// embedded DuckDB database over the records flowing through it and emits
+// the result.
+type SQLTransformer struct {
+	ctx    context.Context
+	runner *DuckDBReader
+	opts   SQLTransformOptions
+
+	window     []arrow.Record
+	windowRows int64
+}
+
+// NewSQLTransformer creates a SQLTransformer backed by the DuckDB database
+// at dbURL, usually ":memory:".
+func NewSQLTransformer(ctx context.Context, dbURL string, opts *SQLTransformOptions) (*SQLTransformer, error) {
+	if opts == nil || opts.SQL == "" {
+		return nil, fmt.Errorf("SQL statement cannot be empty")
+	}
+	options := *opts
+	if options.Table == "" {
+		options.Table = DefaultSQLTransformTable
+	}
+
+	runner, err := newDuckDBSQLRunner(ctx, dbURL, options.Extensions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DuckDB runner: %w", err)
+	}
+	return &SQLTransformer{ctx: ctx, runner: runner, opts: options}, nil
+}
+
+// Transform runs the SQL statement over record, or adds record to the
+// current window and returns nil until the window is full.
+func (t *SQLTransformer) Transform(record arrow.Record) (arrow.Record, error) {
+	record.Retain()
+	t.window = append(t.window, record)
+	t.windowRows += record.NumRows()
+	if t.windowRows < t.opts.WindowRows {
+		return nil, nil
+	}
+	return t.Flush()
+}
+
+// Flush runs the SQL statement over the buffered records, if any.
+func (t *SQLTransformer) Flush() (arrow.Record, error) {
+	if len(t.window) == 0 {
+		return nil, nil
+	}
+	defer func() {
+		for _, rec := range t.window {
+			rec.Release()
+		}
+		t.window, t.windowRows = nil, 0
+	}()
+
+	if err := t.register(t.window); err != nil {
+		return nil, err
+	}
+	results, err := t.runner.RunSQL(t.opts.SQL)
+	if dropErr := executeQuery(t.runner.conn, fmt.Sprintf("DROP TABLE IF EXISTS %s", t.opts.Table)); dropErr != nil && err == nil {
+		err = fmt.Errorf("failed to drop table %q: %w", t.opts.Table, dropErr)
+	}
+	if err != nil {
+		for _, rec := range results {
+			rec.Release()
+		}
+		return nil, fmt.Errorf("failed to run SQL transform: %w", err)
+	}
+	return concatRecords(results)
+}
+
+// register ingests records into a new table named after opts.Table.
+func (t *SQLTransformer) register(records []arrow.Record) error {
+	reader, err := array.NewRecordReader(records[0].Schema(), records)
+	if err != nil {
+		return fmt.Errorf("failed to create record reader: %w", err)
+	}
+	defer reader.Release()
+	return ingest(t.ctx, t.runner.conn, t.opts.Table, reader)
+}
+
+// ingest creates table holding the records of reader.
+func ingest(ctx context.Context, conn adbc.Connection, table string, reader array.RecordReader) error {
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return fmt.Errorf("failed to create statement: %w", err)
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetOption(adbc.OptionKeyIngestMode, adbc.OptionValueIngestModeCreate); err != nil {
+		return fmt.Errorf("failed to set ingest mode: %w", err)
+	}
+	if err := stmt.SetOption(adbc.OptionKeyIngestTargetTable, table); err != nil {
+		return fmt.Errorf("failed to set target table: %w", err)
+	}
+	if err := stmt.BindStream(ctx, reader); err != nil {
+		return fmt.Errorf("failed to bind stream: %w", err)
+	}
+	if _, err := stmt.ExecuteUpdate(ctx); err != nil {
+		return fmt.Errorf("failed to register records as %q: %w", table, err)
+	}
+	return nil
+}
+
+// concatRecords combines records into one, releasing them. It returns nil
+// if there are no rows.
+func concatRecords(records []arrow.Record) (arrow.Record, error) {
+	defer func() {
+		for _, rec := range records {
+			rec.Release()
+		}
+	}()
+
+	var rows int64
+	for _, rec := range records {
+		rows += rec.NumRows()
+	}
+	if rows == 0 {
+		return nil, nil
+	}
+	if len(records) == 1 {
+		records[0].Retain()
+		return records[0], nil
+	}
+
+	schema := records[0].Schema()
+	cols := make([]arrow.Array, schema.NumFields())
+	defer func() {
+		for _, col := range cols {
+			if col != nil {
+				col.Release()
+			}
+		}
+	}()
+	for i := range cols {
+		chunks := make([]arrow.Array, len(records))
+		for j, rec := range records {
+			chunks[j] = rec.Column(i)
+		}
+		col, err := array.Concatenate(chunks, memory.DefaultAllocator)
+		if err != nil {
+			return nil, fmt.Errorf("failed to concatenate results: %w", err)
+		}
+		cols[i] = col
+	}
+	return array.NewRecord(schema, cols, rows), nil
+}
+
+// Close releases any buffered records and closes the DuckDB connection.
+func (t *SQLTransformer) Close() error {
+	for _, rec := range t.window {
+		rec.Release()
+	}
+	t.window, t.windowRows = nil, 0
+	if err := t.runner.conn.Close(); err != nil {
+		return err
+	}
+	return t.runner.db.Close()
+}
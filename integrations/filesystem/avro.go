@@ -33,68 +33,121 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/apache/arrow-go/v18/arrow"
-	"github.com/apache/arrow-go/v18/arrow/avro"
+	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/hamba/avro/v2"
 )
 
+const defaultAvroChunkSize = 1024
+
 // AvroReader reads records from Avro files and implements the Reader interface.
 type AvroReader struct {
-	reader *avro.OCFReader
-	file   *os.File
-	schema *arrow.Schema
-	alloc  memory.Allocator
+	ctx       context.Context
+	container *avroContainer
+	plan      *avroRecordPlan
+	file      io.ReadCloser
+	schema    *arrow.Schema
+	alloc     memory.Allocator
+	chunkSize int
 }
 
 // AvroReadOptions defines options for reading Avro files.
 type AvroReadOptions struct {
 	ChunkSize int64
+	// ReaderSchema is an optional Avro schema, in JSON, to read the file as.
+	// The schema the file was written with is resolved against it following
+	// the Avro specification: fields are matched by name or alias, missing
+	// fields take their default and numeric types are promoted.
+	ReaderSchema string
 }
 
-// NewAvroReader creates a new reader for reading records from an Avro file.
+// NewAvroReader creates a new reader for reading records from an Avro file,
+// a URL, or standard input when filePath is StdioPath.
+//
+// Logical types map to the matching Arrow types: decimals to Decimal128 or
+// Decimal256, dates, times and timestamps to their temporal types, and
+// UUIDs to the UUID extension type. Unions of null and one type are nullable
+// columns, unions of numeric types use the widest type, and other unions are
+// structs with one field per branch.
 func NewAvroReader(ctx context.Context, filePath string, opts *AvroReadOptions) (*AvroReader, error) {
-	alloc := pool.GetAllocator()
+	if opts == nil {
+		opts = &AvroReadOptions{}
+	}
 
-	file, err := os.Open(filePath)
+	file, err := OpenFile(ctx, filePath)
 	if err != nil {
-		pool.PutAllocator(alloc)
 		return nil, fmt.Errorf("failed to open Avro file: %w", err)
 	}
 
-	avroReader, err := avro.NewOCFReader(file, avro.WithAllocator(alloc), avro.WithChunk(int(opts.ChunkSize)))
+	container, err := newAvroContainer(file)
 	if err != nil {
 		file.Close()
-		pool.PutAllocator(alloc)
-		return nil, fmt.Errorf("failed to create Avro OCF reader: %w", err)
+		return nil, err
+	}
+
+	readerSchema := container.schema
+	if opts.ReaderSchema != "" {
+		if readerSchema, err = avro.ParseWithCache(opts.ReaderSchema, "", &avro.SchemaCache{}); err != nil {
+			container.close()
+			file.Close()
+			return nil, fmt.Errorf("invalid Avro reader schema: %w", err)
+		}
+	}
+
+	plan, err := newAvroRecordPlan(container.schema, readerSchema)
+	if err != nil {
+		container.close()
+		file.Close()
+		return nil, fmt.Errorf("failed to resolve Avro schema: %w", err)
+	}
+
+	chunkSize := int(opts.ChunkSize)
+	if chunkSize <= 0 {
+		chunkSize = defaultAvroChunkSize
 	}
 
 	return &AvroReader{
-		reader: avroReader,
-		file:   file,
-		schema: avroReader.Schema(),
-		alloc:  alloc,
+		ctx:       ctx,
+		container: container,
+		plan:      plan,
+		file:      file,
+		schema:    arrow.NewSchema(plan.fields, nil),
+		alloc:     pool.GetAllocator(),
+		chunkSize: chunkSize,
 	}, nil
 }
 
 // Read reads the next record from the Avro file.
 func (r *AvroReader) Read() (arrow.Record, error) {
-	if !r.reader.Next() {
-		if err := r.reader.Err(); err != nil && err != io.EOF {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	bldr := array.NewRecordBuilder(r.alloc, r.schema)
+	defer bldr.Release()
+
+	rows := 0
+	for rows < r.chunkSize {
+		ok, err := r.container.next()
+		if err != nil {
 			return nil, fmt.Errorf("error reading Avro record: %w", err)
 		}
-		return nil, io.EOF
+		if !ok {
+			break
+		}
+		datum := r.container.datum
+		r.plan.decode(datum, bldr.Field)
+		if datum.Error != nil {
+			return nil, fmt.Errorf("error decoding Avro record: %w", datum.Error)
+		}
+		rows++
 	}
-
-	record := r.reader.Record()
-	if record == nil {
+	if rows == 0 {
 		return nil, io.EOF
 	}
-
-	record.Retain()
-	return record, nil
+	return bldr.NewRecord(), nil
 }
 
 // Schema returns the schema of the records being read from the Avro file.
@@ -105,8 +158,57 @@ func (r *AvroReader) Schema() *arrow.Schema {
 // Close releases resources associated with the Avro reader.
 func (r *AvroReader) Close() error {
 	defer pool.PutAllocator(r.alloc)
-	if r.reader != nil {
-		r.reader.Release()
-	}
+	r.container.close()
 	return r.file.Close()
 }
+
+// AvroDatumDecoder decodes single binary-encoded Avro values without a
+// container, such as the messages of a topic with an Avro schema, into rows.
+type AvroDatumDecoder struct {
+	plan   *avroRecordPlan
+	schema *arrow.Schema
+	reader *avro.Reader
+}
+
+// NewAvroDatumDecoder returns a decoder for values written with
+// writerSchema, read as readerSchema if it is not empty. Both are Avro
+// schemas in JSON.
+func NewAvroDatumDecoder(writerSchema, readerSchema string) (*AvroDatumDecoder, error) {
+	writer, err := avro.ParseWithCache(writerSchema, "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	reader := writer
+	if readerSchema != "" {
+		if reader, err = avro.ParseWithCache(readerSchema, "", &avro.SchemaCache{}); err != nil {
+			return nil, fmt.Errorf("invalid Avro reader schema: %w", err)
+		}
+	}
+	plan, err := newAvroRecordPlan(writer, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve Avro schema: %w", err)
+	}
+	return &AvroDatumDecoder{
+		plan:   plan,
+		schema: arrow.NewSchema(plan.fields, nil),
+		reader: avro.NewReader(nil, 0),
+	}, nil
+}
+
+// Schema returns the schema of the rows the decoder appends.
+func (d *AvroDatumDecoder) Schema() *arrow.Schema {
+	return d.schema
+}
+
+// Append decodes datum and appends it as a row to b, which must have been
+// created with the decoder's schema. After an error, b holds a partial row
+// and should be discarded.
+func (d *AvroDatumDecoder) Append(b *array.RecordBuilder, datum []byte) error {
+	d.reader.Reset(datum)
+	d.reader.Error = nil
+	d.plan.decode(d.reader, b.Field)
+	if err := d.reader.Error; err != nil {
+		return fmt.Errorf("error decoding Avro value: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hamba/avro/v2"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// avroMagic starts every Avro object container file.
+var avroMagic = []byte("Obj\x01")
+
+// avroContainer reads the values of an Avro object container file one
+// block at a time.
+type avroContainer struct {
+	r         *avro.Reader
+	schema    avro.Schema
+	codec     string
+	sync      [16]byte
+	datum     *avro.Reader
+	remaining int64
+	zstd      *zstd.Decoder
+}
+
+// newAvroContainer reads the header of the container file in r.
+func newAvroContainer(r io.Reader) (*avroContainer, error) {
+	c := &avroContainer{r: avro.NewReader(r, 64*1024), datum: avro.NewReader(nil, 0)}
+	magic := make([]byte, len(avroMagic))
+	c.r.Read(magic)
+	if c.r.Error != nil {
+		return nil, fmt.Errorf("failed to read Avro header: %w", c.r.Error)
+	}
+	if !bytes.Equal(magic, avroMagic) {
+		return nil, fmt.Errorf("not an Avro object container file")
+	}
+	meta := map[string][]byte{}
+	c.r.ReadMapCB(func(r *avro.Reader, key string) bool {
+		meta[key] = r.ReadBytes()
+		return true
+	})
+	c.r.Read(c.sync[:])
+	if c.r.Error != nil {
+		return nil, fmt.Errorf("failed to read Avro header: %w", c.r.Error)
+	}
+
+	// A private cache keeps the named types of one file from clashing with
+	// those of another.
+	schema, err := avro.ParseBytesWithCache(meta["avro.schema"], "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	c.schema = schema
+	c.codec = string(meta["avro.codec"])
+	switch c.codec {
+	case "", "null", "deflate", "snappy":
+	case "zstandard":
+		if c.zstd, err = zstd.NewReader(nil); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Avro codec %q", c.codec)
+	}
+	return c, nil
+}
+
+// next positions datum on the next value, reading a new block when the
+// current one is exhausted. It returns false at the end of the file.
+func (c *avroContainer) next() (bool, error) {
+	for c.remaining == 0 {
+		count := c.r.ReadLong()
+		if errors.Is(c.r.Error, io.EOF) {
+			return false, nil
+		}
+		size := c.r.ReadLong()
+		if c.r.Error != nil {
+			return false, fmt.Errorf("failed to read Avro block: %w", c.r.Error)
+		}
+		if count < 0 || size < 0 {
+			return false, fmt.Errorf("invalid Avro block of %d values in %d bytes", count, size)
+		}
+		data := make([]byte, size)
+		c.r.Read(data)
+		var sync [16]byte
+		c.r.Read(sync[:])
+		if c.r.Error != nil {
+			return false, fmt.Errorf("failed to read Avro block: %w", c.r.Error)
+		}
+		if sync != c.sync {
+			return false, fmt.Errorf("invalid Avro block sync marker")
+		}
+		block, err := c.decompress(data)
+		if err != nil {
+			return false, fmt.Errorf("failed to decompress Avro block: %w", err)
+		}
+		c.datum.Error = nil
+		c.datum.Reset(block)
+		c.remaining = count
+	}
+	c.remaining--
+	return true, nil
+}
+
+func (c *avroContainer) decompress(data []byte) ([]byte, error) {
+	switch c.codec {
+	case "deflate":
+		return io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	case "snappy":
+		// The block ends with a CRC32 of the uncompressed data.
+		if len(data) < 4 {
+			return nil, fmt.Errorf("snappy block too short")
+		}
+		return snappy.Decode(nil, data[:len(data)-4])
+	case "zstandard":
+		return c.zstd.DecodeAll(data, nil)
+	default:
+		return data, nil
+	}
+}
+
+func (c *avroContainer) close() {
+	if c.zstd != nil {
+		c.zstd.Close()
+	}
+}
@@ -0,0 +1,875 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/decimal256"
+	"github.com/apache/arrow-go/v18/arrow/extensions"
+	"github.com/google/uuid"
+	"github.com/hamba/avro/v2"
+)
+
+// avroDecoder reads one value written with the writer's schema from r and
+// appends it to b as the reader's Arrow type. Failures are reported on r.
+type avroDecoder func(r *avro.Reader, b array.Builder)
+
+// avroRecordPlan decodes the top-level values of an Avro file into the
+// columns of an Arrow record.
+type avroRecordPlan struct {
+	fields []arrow.Field
+	steps  []func(r *avro.Reader, column func(int) array.Builder)
+}
+
+func (p *avroRecordPlan) decode(r *avro.Reader, column func(int) array.Builder) {
+	for _, step := range p.steps {
+		step(r, column)
+	}
+}
+
+// newAvroRecordPlan resolves the writer schema of a file against the reader
+// schema it is read as, following the schema resolution rules of the Avro
+// specification. A top-level value that is not a record becomes a single
+// "value" column.
+func newAvroRecordPlan(writer, reader avro.Schema) (*avroRecordPlan, error) {
+	c := &avroResolver{records: map[string]bool{}}
+	writer, reader = derefAvro(writer), derefAvro(reader)
+	if rr, ok := reader.(*avro.RecordSchema); ok {
+		wr, ok := writer.(*avro.RecordSchema)
+		if !ok {
+			return nil, fmt.Errorf("cannot read Avro %s as record %s", avroTypeName(writer), rr.FullName())
+		}
+		return c.resolveRecord(wr, rr)
+	}
+	field, dec, err := c.resolve(writer, reader)
+	if err != nil {
+		return nil, err
+	}
+	field.Name = "value"
+	return &avroRecordPlan{
+		fields: []arrow.Field{field},
+		steps: []func(*avro.Reader, func(int) array.Builder){
+			func(r *avro.Reader, column func(int) array.Builder) { dec(r, column(0)) },
+		},
+	}, nil
+}
+
+// avroResolver maps Avro schemas to Arrow types.
+type avroResolver struct {
+	// records holds the records being resolved, to reject recursive types,
+	// which Arrow cannot represent.
+	records map[string]bool
+}
+
+func (c *avroResolver) resolve(w, rd avro.Schema) (arrow.Field, avroDecoder, error) {
+	w, rd = derefAvro(w), derefAvro(rd)
+	if ru, ok := rd.(*avro.UnionSchema); ok {
+		return c.resolveUnion(w, ru)
+	}
+	if wu, ok := w.(*avro.UnionSchema); ok {
+		// Each branch the writer used must resolve against the reader type;
+		// branches that do not fail only when a value uses them.
+		field, _, err := c.resolve(rd, rd)
+		if err != nil {
+			return arrow.Field{}, nil, err
+		}
+		decoders := make([]avroDecoder, len(wu.Types()))
+		for i, branch := range wu.Types() {
+			if derefAvro(branch).Type() == avro.Null {
+				field.Nullable = true
+				decoders[i] = appendAvroNull
+				continue
+			}
+			if _, decoders[i], err = c.resolve(branch, rd); err != nil {
+				decoders[i] = failAvroDecoder(err)
+			}
+		}
+		return field, avroUnionDecoder(decoders), nil
+	}
+	if err := checkAvroPromotion(w, rd); err != nil {
+		return arrow.Field{}, nil, err
+	}
+
+	switch rd.Type() {
+	case avro.Null:
+		return arrow.Field{Type: arrow.Null, Nullable: true}, appendAvroNull, nil
+	case avro.Boolean:
+		return arrow.Field{Type: arrow.FixedWidthTypes.Boolean}, func(r *avro.Reader, b array.Builder) {
+			b.(*array.BooleanBuilder).Append(r.ReadBool())
+		}, nil
+	case avro.Int:
+		switch avroLogicalType(rd) {
+		case avro.Date:
+			return arrow.Field{Type: arrow.FixedWidthTypes.Date32}, func(r *avro.Reader, b array.Builder) {
+				b.(*array.Date32Builder).Append(arrow.Date32(r.ReadInt()))
+			}, nil
+		case avro.TimeMillis:
+			return arrow.Field{Type: arrow.FixedWidthTypes.Time32ms}, func(r *avro.Reader, b array.Builder) {
+				b.(*array.Time32Builder).Append(arrow.Time32(r.ReadInt()))
+			}, nil
+		}
+		return arrow.Field{Type: arrow.PrimitiveTypes.Int32}, func(r *avro.Reader, b array.Builder) {
+			b.(*array.Int32Builder).Append(r.ReadInt())
+		}, nil
+	case avro.Long:
+		read := avroLongReader(w.Type())
+		var dt arrow.DataType
+		switch avroLogicalType(rd) {
+		case avro.TimeMicros:
+			return arrow.Field{Type: arrow.FixedWidthTypes.Time64us}, func(r *avro.Reader, b array.Builder) {
+				b.(*array.Time64Builder).Append(arrow.Time64(read(r)))
+			}, nil
+		case avro.TimestampMillis:
+			dt = &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}
+		case avro.TimestampMicros:
+			dt = &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+		case avro.LocalTimestampMillis:
+			dt = &arrow.TimestampType{Unit: arrow.Millisecond}
+		case avro.LocalTimestampMicros:
+			dt = &arrow.TimestampType{Unit: arrow.Microsecond}
+		default:
+			return arrow.Field{Type: arrow.PrimitiveTypes.Int64}, func(r *avro.Reader, b array.Builder) {
+				b.(*array.Int64Builder).Append(read(r))
+			}, nil
+		}
+		return arrow.Field{Type: dt}, func(r *avro.Reader, b array.Builder) {
+			b.(*array.TimestampBuilder).Append(arrow.Timestamp(read(r)))
+		}, nil
+	case avro.Float:
+		read := avroDoubleReader(w.Type())
+		return arrow.Field{Type: arrow.PrimitiveTypes.Float32}, func(r *avro.Reader, b array.Builder) {
+			b.(*array.Float32Builder).Append(float32(read(r)))
+		}, nil
+	case avro.Double:
+		read := avroDoubleReader(w.Type())
+		return arrow.Field{Type: arrow.PrimitiveTypes.Float64}, func(r *avro.Reader, b array.Builder) {
+			b.(*array.Float64Builder).Append(read(r))
+		}, nil
+	case avro.String:
+		if avroLogicalType(rd) == avro.UUID {
+			return arrow.Field{Type: extensions.NewUUIDType()}, func(r *avro.Reader, b array.Builder) {
+				s := r.ReadString()
+				id, err := uuid.Parse(s)
+				if err != nil {
+					r.ReportError("uuid", fmt.Sprintf("invalid UUID %q", s))
+					return
+				}
+				b.(*extensions.UUIDBuilder).Append(id)
+			}, nil
+		}
+		return arrow.Field{Type: arrow.BinaryTypes.String}, func(r *avro.Reader, b array.Builder) {
+			b.(*array.StringBuilder).Append(r.ReadString())
+		}, nil
+	case avro.Bytes:
+		if avroLogicalType(rd) == avro.Decimal {
+			return avroDecimal(rd, (*avro.Reader).ReadBytes)
+		}
+		return arrow.Field{Type: arrow.BinaryTypes.Binary}, func(r *avro.Reader, b array.Builder) {
+			b.(*array.BinaryBuilder).Append(r.ReadBytes())
+		}, nil
+	case avro.Fixed:
+		return avroFixed(rd.(*avro.FixedSchema))
+	case avro.Enum:
+		return avroEnum(w.(*avro.EnumSchema), rd.(*avro.EnumSchema))
+	case avro.Array:
+		item, dec, err := c.resolve(w.(*avro.ArraySchema).Items(), rd.(*avro.ArraySchema).Items())
+		if err != nil {
+			return arrow.Field{}, nil, err
+		}
+		item.Name = "item"
+		return arrow.Field{Type: arrow.ListOfField(item)}, func(r *avro.Reader, b array.Builder) {
+			lb := b.(*array.ListBuilder)
+			lb.Append(true)
+			vb := lb.ValueBuilder()
+			r.ReadArrayCB(func(r *avro.Reader) bool {
+				dec(r, vb)
+				return r.Error == nil
+			})
+		}, nil
+	case avro.Map:
+		value, dec, err := c.resolve(w.(*avro.MapSchema).Values(), rd.(*avro.MapSchema).Values())
+		if err != nil {
+			return arrow.Field{}, nil, err
+		}
+		return arrow.Field{Type: arrow.MapOf(arrow.BinaryTypes.String, value.Type)}, func(r *avro.Reader, b array.Builder) {
+			mb := b.(*array.MapBuilder)
+			mb.Append(true)
+			kb, ib := mb.KeyBuilder().(*array.StringBuilder), mb.ItemBuilder()
+			r.ReadMapCB(func(r *avro.Reader, key string) bool {
+				kb.Append(key)
+				dec(r, ib)
+				return r.Error == nil
+			})
+		}, nil
+	case avro.Record:
+		plan, err := c.resolveRecord(w.(*avro.RecordSchema), rd.(*avro.RecordSchema))
+		if err != nil {
+			return arrow.Field{}, nil, err
+		}
+		return arrow.Field{Type: arrow.StructOf(plan.fields...)}, func(r *avro.Reader, b array.Builder) {
+			sb := b.(*array.StructBuilder)
+			sb.Append(true)
+			plan.decode(r, sb.FieldBuilder)
+		}, nil
+	}
+	return arrow.Field{}, nil, fmt.Errorf("unsupported Avro type %s", rd.Type())
+}
+
+// resolveRecord matches reader fields to writer fields by name or alias.
+// Writer fields the reader does not know are skipped, and reader fields the
+// writer did not write take their default value.
+func (c *avroResolver) resolveRecord(w, rd *avro.RecordSchema) (*avroRecordPlan, error) {
+	if !avroNamesMatch(w, rd) {
+		return nil, fmt.Errorf("cannot read Avro record %s as %s", w.FullName(), rd.FullName())
+	}
+	if c.records[rd.FullName()] {
+		return nil, fmt.Errorf("recursive Avro record %s is not supported", rd.FullName())
+	}
+	c.records[rd.FullName()] = true
+	defer delete(c.records, rd.FullName())
+
+	plan := &avroRecordPlan{fields: make([]arrow.Field, len(rd.Fields()))}
+	matched := make([]bool, len(rd.Fields()))
+	for _, wf := range w.Fields() {
+		i := slices.IndexFunc(rd.Fields(), func(rf *avro.Field) bool {
+			return rf.Name() == wf.Name() || slices.Contains(rf.Aliases(), wf.Name())
+		})
+		if i < 0 {
+			skip := wf.Type()
+			plan.steps = append(plan.steps, func(r *avro.Reader, _ func(int) array.Builder) {
+				skipAvro(r, skip)
+			})
+			continue
+		}
+		rf := rd.Fields()[i]
+		field, dec, err := c.resolve(wf.Type(), rf.Type())
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", rf.Name(), err)
+		}
+		field.Name = rf.Name()
+		plan.fields[i], matched[i] = field, true
+		plan.steps = append(plan.steps, func(r *avro.Reader, column func(int) array.Builder) {
+			dec(r, column(i))
+		})
+	}
+
+	for i, rf := range rd.Fields() {
+		if matched[i] {
+			continue
+		}
+		if !rf.HasDefault() {
+			return nil, fmt.Errorf("field %s is missing from the written data and has no default", rf.Name())
+		}
+		field, dec, err := c.resolve(rf.Type(), rf.Type())
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", rf.Name(), err)
+		}
+		// The default is encoded once and decoded for every value, so that
+		// it goes through the same conversions as written data.
+		buf := avro.NewWriter(nil, 64)
+		if err := writeAvroDefault(buf, rf.Type(), rf.Default()); err != nil {
+			return nil, fmt.Errorf("field %s: invalid default: %w", rf.Name(), err)
+		}
+		def := bytes.Clone(buf.Buffer())
+		field.Name = rf.Name()
+		plan.fields[i] = field
+		dr := avro.NewReader(nil, 0)
+		plan.steps = append(plan.steps, func(r *avro.Reader, column func(int) array.Builder) {
+			dr.Reset(def)
+			dec(dr, column(i))
+			if dr.Error != nil {
+				r.ReportError("default", dr.Error.Error())
+			}
+		})
+	}
+	return plan, nil
+}
+
+// resolveUnion maps a reader union to Arrow. Null branches make the column
+// nullable, a union of one other type maps to that type, a union of numeric
+// types maps to the widest of them, and any other union maps to a struct
+// with one child per branch, of which only the written branch is set.
+func (c *avroResolver) resolveUnion(w avro.Schema, ru *avro.UnionSchema) (arrow.Field, avroDecoder, error) {
+	types := ru.Types()
+	var branches []int
+	nullable := false
+	for i, t := range types {
+		if derefAvro(t).Type() == avro.Null {
+			nullable = true
+		} else {
+			branches = append(branches, i)
+		}
+	}
+
+	var field arrow.Field
+	target := func(k int) avro.Schema { return types[k] }
+	wrap := func(_ int, dec avroDecoder) avroDecoder { return dec }
+	switch widest := widestAvroNumeric(types, branches); {
+	case len(branches) == 0:
+		field = arrow.Field{Type: arrow.Null}
+	case len(branches) == 1:
+		var err error
+		if field, _, err = c.resolve(types[branches[0]], types[branches[0]]); err != nil {
+			return arrow.Field{}, nil, err
+		}
+	case widest != nil:
+		field, _, _ = c.resolve(widest, widest)
+		target = func(int) avro.Schema { return widest }
+	default:
+		children := make([]arrow.Field, len(branches))
+		child := make(map[int]int, len(branches))
+		for j, k := range branches {
+			f, _, err := c.resolve(types[k], types[k])
+			if err != nil {
+				return arrow.Field{}, nil, err
+			}
+			f.Name, f.Nullable = avroTypeName(types[k]), true
+			children[j], child[k] = f, j
+		}
+		field = arrow.Field{Type: arrow.StructOf(children...)}
+		wrap = func(k int, dec avroDecoder) avroDecoder {
+			j := child[k]
+			return func(r *avro.Reader, b array.Builder) {
+				sb := b.(*array.StructBuilder)
+				sb.Append(true)
+				for i := 0; i < sb.NumField(); i++ {
+					if i == j {
+						dec(r, sb.FieldBuilder(i))
+					} else {
+						sb.FieldBuilder(i).AppendNull()
+					}
+				}
+			}
+		}
+	}
+	field.Nullable = field.Nullable || nullable
+
+	branch := func(wb avro.Schema) (avroDecoder, error) {
+		if derefAvro(wb).Type() == avro.Null {
+			if !nullable {
+				return nil, fmt.Errorf("reader union %s does not accept null", ru)
+			}
+			return appendAvroNull, nil
+		}
+		k := matchAvroBranch(wb, types)
+		if k < 0 {
+			return nil, fmt.Errorf("no branch of reader union %s accepts %s", ru, avroTypeName(wb))
+		}
+		_, dec, err := c.resolve(wb, target(k))
+		if err != nil {
+			return nil, err
+		}
+		return wrap(k, dec), nil
+	}
+
+	wu, ok := w.(*avro.UnionSchema)
+	if !ok {
+		dec, err := branch(w)
+		if err != nil {
+			return arrow.Field{}, nil, err
+		}
+		return field, dec, nil
+	}
+	decoders := make([]avroDecoder, len(wu.Types()))
+	for i, wb := range wu.Types() {
+		dec, err := branch(wb)
+		if err != nil {
+			dec = failAvroDecoder(err)
+		}
+		decoders[i] = dec
+	}
+	return field, avroUnionDecoder(decoders), nil
+}
+
+func avroUnionDecoder(decoders []avroDecoder) avroDecoder {
+	return func(r *avro.Reader, b array.Builder) {
+		i := r.ReadLong()
+		if i < 0 || i >= int64(len(decoders)) {
+			r.ReportError("union", fmt.Sprintf("invalid union branch %d", i))
+			return
+		}
+		decoders[i](r, b)
+	}
+}
+
+func appendAvroNull(_ *avro.Reader, b array.Builder) {
+	b.AppendNull()
+}
+
+func failAvroDecoder(err error) avroDecoder {
+	return func(r *avro.Reader, _ array.Builder) {
+		r.ReportError("resolve", err.Error())
+	}
+}
+
+// avroDecimal maps a decimal to Decimal128, or to Decimal256 when its
+// precision exceeds 38 digits. read returns the big-endian two's complement
+// unscaled value.
+func avroDecimal(s avro.Schema, read func(*avro.Reader) []byte) (arrow.Field, avroDecoder, error) {
+	d := s.(avro.LogicalTypeSchema).Logical().(*avro.DecimalLogicalSchema)
+	precision, scale := int32(d.Precision()), int32(d.Scale())
+	switch {
+	case precision <= 38:
+		return arrow.Field{Type: &arrow.Decimal128Type{Precision: precision, Scale: scale}}, func(r *avro.Reader, b array.Builder) {
+			w, ok := decimalWords(read(r), 2)
+			if !ok {
+				r.ReportError("decimal", fmt.Sprintf("value overflows decimal(%d, %d)", precision, scale))
+				return
+			}
+			b.(*array.Decimal128Builder).Append(decimal128.New(int64(w[0]), w[1]))
+		}, nil
+	case precision <= 76:
+		return arrow.Field{Type: &arrow.Decimal256Type{Precision: precision, Scale: scale}}, func(r *avro.Reader, b array.Builder) {
+			w, ok := decimalWords(read(r), 4)
+			if !ok {
+				r.ReportError("decimal", fmt.Sprintf("value overflows decimal(%d, %d)", precision, scale))
+				return
+			}
+			b.(*array.Decimal256Builder).Append(decimal256.New(w[0], w[1], w[2], w[3]))
+		}, nil
+	}
+	return arrow.Field{}, nil, fmt.Errorf("decimal precision %d exceeds 76 digits", precision)
+}
+
+// decimalWords sign-extends the big-endian two's complement integer b to n
+// 64-bit words, most significant first. It reports false if b does not fit.
+func decimalWords(b []byte, n int) ([]uint64, bool) {
+	var fill byte
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		fill = 0xff
+	}
+	width := n * 8
+	if len(b) > width {
+		for _, x := range b[:len(b)-width] {
+			if x != fill {
+				return nil, false
+			}
+		}
+		b = b[len(b)-width:]
+		if b[0]&0x80 != fill&0x80 {
+			return nil, false
+		}
+	}
+	buf := bytes.Repeat([]byte{fill}, width)
+	copy(buf[width-len(b):], b)
+	words := make([]uint64, n)
+	for i := range words {
+		words[i] = binary.BigEndian.Uint64(buf[i*8:])
+	}
+	return words, true
+}
+
+func avroFixed(s *avro.FixedSchema) (arrow.Field, avroDecoder, error) {
+	size := s.Size()
+	buf := make([]byte, size)
+	read := func(r *avro.Reader) []byte {
+		r.Read(buf)
+		return buf
+	}
+	switch avroLogicalType(s) {
+	case avro.Decimal:
+		return avroDecimal(s, read)
+	case avro.UUID:
+		if size == 16 {
+			return arrow.Field{Type: extensions.NewUUIDType()}, func(r *avro.Reader, b array.Builder) {
+				b.(*extensions.UUIDBuilder).AppendBytes([16]byte(read(r)))
+			}, nil
+		}
+	case avro.Duration:
+		// A duration is three little-endian unsigned ints: months, days and
+		// milliseconds.
+		if size == 12 {
+			return arrow.Field{Type: arrow.FixedWidthTypes.MonthDayNanoInterval}, func(r *avro.Reader, b array.Builder) {
+				v := read(r)
+				b.(*array.MonthDayNanoIntervalBuilder).Append(arrow.MonthDayNanoInterval{
+					Months:      int32(binary.LittleEndian.Uint32(v[0:])),
+					Days:        int32(binary.LittleEndian.Uint32(v[4:])),
+					Nanoseconds: int64(binary.LittleEndian.Uint32(v[8:])) * 1e6,
+				})
+			}, nil
+		}
+	}
+	return arrow.Field{Type: &arrow.FixedSizeBinaryType{ByteWidth: size}}, func(r *avro.Reader, b array.Builder) {
+		b.(*array.FixedSizeBinaryBuilder).Append(read(r))
+	}, nil
+}
+
+// avroEnum maps an enum to a string dictionary. Symbols the reader does not
+// know take the reader's default symbol.
+func avroEnum(w, rd *avro.EnumSchema) (arrow.Field, avroDecoder, error) {
+	symbols := make([]string, len(w.Symbols()))
+	for i, s := range w.Symbols() {
+		switch {
+		case slices.Contains(rd.Symbols(), s):
+			symbols[i] = s
+		case rd.HasDefault():
+			symbols[i] = rd.Default()
+		}
+	}
+	dt := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+	return arrow.Field{Type: dt}, func(r *avro.Reader, b array.Builder) {
+		i := r.ReadInt()
+		if i < 0 || int(i) >= len(symbols) {
+			r.ReportError("enum", fmt.Sprintf("invalid symbol index %d", i))
+			return
+		}
+		if symbols[i] == "" {
+			r.ReportError("enum", fmt.Sprintf("symbol %s is not in reader enum %s", w.Symbols()[i], rd.FullName()))
+			return
+		}
+		if err := b.(*array.BinaryDictionaryBuilder).AppendString(symbols[i]); err != nil {
+			r.ReportError("enum", err.Error())
+		}
+	}, nil
+}
+
+// checkAvroPromotion reports whether data written as w can be read as rd.
+func checkAvroPromotion(w, rd avro.Schema) error {
+	if w.Type() == rd.Type() {
+		switch rd := rd.(type) {
+		case avro.NamedSchema:
+			if !avroNamesMatch(w.(avro.NamedSchema), rd) {
+				return fmt.Errorf("cannot read Avro %s as %s", avroTypeName(w), avroTypeName(rd))
+			}
+			if f, ok := rd.(*avro.FixedSchema); ok && f.Size() != w.(*avro.FixedSchema).Size() {
+				return fmt.Errorf("cannot read Avro fixed of size %d as size %d", w.(*avro.FixedSchema).Size(), f.Size())
+			}
+		}
+		return nil
+	}
+	if avroPromotable(w.Type(), rd.Type()) {
+		return nil
+	}
+	return fmt.Errorf("cannot read Avro %s as %s", avroTypeName(w), avroTypeName(rd))
+}
+
+func avroPromotable(w, rd avro.Type) bool {
+	switch w {
+	case avro.Int:
+		return rd == avro.Long || rd == avro.Float || rd == avro.Double
+	case avro.Long:
+		return rd == avro.Float || rd == avro.Double
+	case avro.Float:
+		return rd == avro.Double
+	case avro.String:
+		return rd == avro.Bytes
+	case avro.Bytes:
+		return rd == avro.String
+	}
+	return false
+}
+
+// avroNamesMatch compares unqualified names, also accepting the reader's
+// aliases.
+func avroNamesMatch(w, rd avro.NamedSchema) bool {
+	return w.Name() == rd.Name() || w.FullName() == rd.FullName() ||
+		slices.Contains(rd.Aliases(), w.FullName()) || slices.Contains(rd.Aliases(), w.Name())
+}
+
+// matchAvroBranch returns the first reader branch that matches wb exactly,
+// or else the first one wb can be promoted to, or -1.
+func matchAvroBranch(wb avro.Schema, types avro.Schemas) int {
+	wb = derefAvro(wb)
+	for i, t := range types {
+		t = derefAvro(t)
+		if t.Type() == wb.Type() && checkAvroPromotion(wb, t) == nil {
+			return i
+		}
+	}
+	for i, t := range types {
+		if avroPromotable(wb.Type(), derefAvro(t).Type()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// widestAvroNumeric returns the type that holds every branch of a union of
+// plain numeric types, or nil if some branch is not numeric.
+func widestAvroNumeric(types avro.Schemas, branches []int) avro.Schema {
+	if len(branches) < 2 {
+		return nil
+	}
+	widest := avro.Long
+	for _, k := range branches {
+		t := derefAvro(types[k])
+		if avroLogicalType(t) != "" {
+			return nil
+		}
+		switch t.Type() {
+		case avro.Int, avro.Long:
+		case avro.Float, avro.Double:
+			widest = avro.Double
+		default:
+			return nil
+		}
+	}
+	return avro.NewPrimitiveSchema(widest, nil)
+}
+
+func avroLongReader(t avro.Type) func(*avro.Reader) int64 {
+	if t == avro.Int {
+		return func(r *avro.Reader) int64 { return int64(r.ReadInt()) }
+	}
+	return (*avro.Reader).ReadLong
+}
+
+func avroDoubleReader(t avro.Type) func(*avro.Reader) float64 {
+	switch t {
+	case avro.Int:
+		return func(r *avro.Reader) float64 { return float64(r.ReadInt()) }
+	case avro.Long:
+		return func(r *avro.Reader) float64 { return float64(r.ReadLong()) }
+	case avro.Float:
+		return func(r *avro.Reader) float64 { return float64(r.ReadFloat()) }
+	}
+	return (*avro.Reader).ReadDouble
+}
+
+// skipAvro reads past a value the reader schema does not use.
+func skipAvro(r *avro.Reader, s avro.Schema) {
+	switch s := derefAvro(s).(type) {
+	case *avro.RecordSchema:
+		for _, f := range s.Fields() {
+			skipAvro(r, f.Type())
+		}
+	case *avro.UnionSchema:
+		i := r.ReadLong()
+		if i < 0 || i >= int64(len(s.Types())) {
+			r.ReportError("union", fmt.Sprintf("invalid union branch %d", i))
+			return
+		}
+		skipAvro(r, s.Types()[i])
+	case *avro.ArraySchema:
+		r.ReadArrayCB(func(r *avro.Reader) bool {
+			skipAvro(r, s.Items())
+			return r.Error == nil
+		})
+	case *avro.MapSchema:
+		r.ReadMapCB(func(r *avro.Reader, _ string) bool {
+			skipAvro(r, s.Values())
+			return r.Error == nil
+		})
+	case *avro.FixedSchema:
+		r.SkipNBytes(s.Size())
+	default:
+		switch s.Type() {
+		case avro.Boolean:
+			r.SkipBool()
+		case avro.Int, avro.Enum:
+			r.SkipInt()
+		case avro.Long:
+			r.SkipLong()
+		case avro.Float:
+			r.SkipFloat()
+		case avro.Double:
+			r.SkipDouble()
+		case avro.String, avro.Bytes:
+			r.SkipBytes()
+		}
+	}
+}
+
+// writeAvroDefault encodes the default value of a field, as parsed from its
+// JSON schema, in the Avro binary encoding. A union's default is a value of
+// its first branch.
+func writeAvroDefault(w *avro.Writer, s avro.Schema, v any) error {
+	s = derefAvro(s)
+	switch s := s.(type) {
+	case *avro.UnionSchema:
+		w.WriteLong(0)
+		return writeAvroDefault(w, s.Types()[0], v)
+	case *avro.RecordSchema:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%v is not a record", v)
+		}
+		for _, f := range s.Fields() {
+			fv, ok := m[f.Name()]
+			if !ok {
+				if !f.HasDefault() {
+					return fmt.Errorf("record default misses field %s", f.Name())
+				}
+				fv = f.Default()
+			}
+			if err := writeAvroDefault(w, f.Type(), fv); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *avro.ArraySchema:
+		items, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%v is not an array", v)
+		}
+		if len(items) > 0 {
+			w.WriteLong(int64(len(items)))
+			for _, item := range items {
+				if err := writeAvroDefault(w, s.Items(), item); err != nil {
+					return err
+				}
+			}
+		}
+		w.WriteLong(0)
+		return nil
+	case *avro.MapSchema:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%v is not a map", v)
+		}
+		if len(m) > 0 {
+			w.WriteLong(int64(len(m)))
+			for k, item := range m {
+				w.WriteString(k)
+				if err := writeAvroDefault(w, s.Values(), item); err != nil {
+					return err
+				}
+			}
+		}
+		w.WriteLong(0)
+		return nil
+	case *avro.EnumSchema:
+		i := slices.Index(s.Symbols(), fmt.Sprint(v))
+		if i < 0 {
+			return fmt.Errorf("%v is not a symbol of %s", v, s.FullName())
+		}
+		w.WriteInt(int32(i))
+		return nil
+	}
+
+	switch s.Type() {
+	case avro.Null:
+	case avro.Boolean:
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("%v is not a boolean", v)
+		}
+		w.WriteBool(b)
+	case avro.Int, avro.Long:
+		n, ok := avroDefaultNumber(v)
+		if !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%v is not an integer", v)
+		}
+		if s.Type() == avro.Int {
+			w.WriteInt(int32(n))
+		} else {
+			w.WriteLong(int64(n))
+		}
+	case avro.Float, avro.Double:
+		n, ok := avroDefaultNumber(v)
+		if !ok {
+			return fmt.Errorf("%v is not a number", v)
+		}
+		if s.Type() == avro.Float {
+			w.WriteFloat(float32(n))
+		} else {
+			w.WriteDouble(n)
+		}
+	case avro.String:
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a string", v)
+		}
+		w.WriteString(str)
+	case avro.Bytes, avro.Fixed:
+		b, ok := avroDefaultBytes(v)
+		if !ok {
+			return fmt.Errorf("%v is not a byte string", v)
+		}
+		if s.Type() == avro.Bytes {
+			w.WriteBytes(b)
+		} else {
+			w.Write(b)
+		}
+	}
+	return nil
+}
+
+func avroDefaultNumber(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.CanInt():
+		return float64(rv.Int()), true
+	case rv.CanFloat():
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// avroDefaultBytes accepts the []byte of a bytes default, the [N]byte of a
+// fixed default or the string of either.
+func avroDefaultBytes(v any) ([]byte, bool) {
+	switch v := v.(type) {
+	case []byte:
+		return v, true
+	case string:
+		return []byte(v), true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Array || rv.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, false
+	}
+	b := make([]byte, rv.Len())
+	reflect.Copy(reflect.ValueOf(b), rv)
+	return b, true
+}
+
+func derefAvro(s avro.Schema) avro.Schema {
+	if ref, ok := s.(*avro.RefSchema); ok {
+		return ref.Schema()
+	}
+	return s
+}
+
+func avroLogicalType(s avro.Schema) avro.LogicalType {
+	if lts, ok := s.(avro.LogicalTypeSchema); ok && lts.Logical() != nil {
+		return lts.Logical().Type()
+	}
+	return ""
+}
+
+// avroTypeName names a union branch: named types by their name, others by
+// their type.
+func avroTypeName(s avro.Schema) string {
+	s = derefAvro(s)
+	if n, ok := s.(avro.NamedSchema); ok {
+		return n.Name()
+	}
+	return string(s.Type())
+}
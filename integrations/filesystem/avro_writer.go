@@ -0,0 +1,613 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/extensions"
+	"github.com/hamba/avro/v2"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// AvroCodec is the codec used to compress the blocks of an Avro object
+// container file.
+type AvroCodec string
+
+const (
+	AvroCodecNull      AvroCodec = "null"
+	AvroCodecDeflate   AvroCodec = "deflate"
+	AvroCodecSnappy    AvroCodec = "snappy"
+	AvroCodecZstandard AvroCodec = "zstandard"
+)
+
+const defaultAvroBlockRows = 10000
+
+// AvroWriteOptions configures an AvroWriter.
+type AvroWriteOptions struct {
+	Codec AvroCodec
+	// BlockRows is the number of rows written per block. Larger blocks
+	// compress better; smaller ones let readers start sooner.
+	BlockRows int
+	// RecordName names the top-level Avro record. Defaults to "Record".
+	RecordName string
+}
+
+// NewDefaultAvroWriteOptions returns snappy compression with blocks of
+// 10,000 rows.
+func NewDefaultAvroWriteOptions() *AvroWriteOptions {
+	return &AvroWriteOptions{Codec: AvroCodecSnappy, BlockRows: defaultAvroBlockRows}
+}
+
+// AvroWriter writes records to an Avro object container file and
+// implements the Writer interface.
+type AvroWriter struct {
+	file      io.WriteCloser
+	out       *avro.Writer
+	block     *avro.Writer
+	encoders  []avroEncoder
+	codec     AvroCodec
+	sync      [16]byte
+	rows      int
+	blockRows int
+	zstd      *zstd.Encoder
+}
+
+// NewAvroWriter creates a new writer for writing records with the given
+// Arrow schema to an Avro file, or to standard output when filePath is
+// StdioPath. The Avro schema is derived from the Arrow schema with
+// AvroSchemaFromArrow. A nil opts uses NewDefaultAvroWriteOptions.
+func NewAvroWriter(ctx context.Context, filePath string, schema *arrow.Schema, opts *AvroWriteOptions) (*AvroWriter, error) {
+	if opts == nil {
+		opts = NewDefaultAvroWriteOptions()
+	}
+	avroSchema, encoders, err := avroSchemaFromArrow(schema, opts.RecordName)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &AvroWriter{
+		codec:     opts.Codec,
+		encoders:  encoders,
+		blockRows: opts.BlockRows,
+		block:     avro.NewWriter(nil, 64*1024),
+	}
+	if w.codec == "" {
+		w.codec = AvroCodecNull
+	}
+	if w.blockRows <= 0 {
+		w.blockRows = defaultAvroBlockRows
+	}
+	switch w.codec {
+	case AvroCodecNull, AvroCodecDeflate, AvroCodecSnappy:
+	case AvroCodecZstandard:
+		if w.zstd, err = zstd.NewWriter(nil); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Avro codec %q", w.codec)
+	}
+	if _, err := rand.Read(w.sync[:]); err != nil {
+		return nil, err
+	}
+
+	file, err := createFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Avro file: %w", err)
+	}
+	w.file = file
+	w.out = avro.NewWriter(file, 64*1024)
+
+	w.out.Write(avroMagic)
+	w.out.WriteBlockHeader(2, 0)
+	w.out.WriteString("avro.schema")
+	w.out.WriteBytes([]byte(avroSchema.String()))
+	w.out.WriteString("avro.codec")
+	w.out.WriteBytes([]byte(w.codec))
+	w.out.WriteLong(0)
+	w.out.Write(w.sync[:])
+	if err := w.out.Flush(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write Avro header: %w", err)
+	}
+	return w, nil
+}
+
+// Write appends the rows of record, writing a block every BlockRows rows.
+func (w *AvroWriter) Write(record arrow.Record) error {
+	cols := record.Columns()
+	for i := 0; i < int(record.NumRows()); i++ {
+		for j, enc := range w.encoders {
+			if err := enc(w.block, cols[j], i); err != nil {
+				return fmt.Errorf("column %s: %w", record.ColumnName(j), err)
+			}
+		}
+		w.rows++
+		if w.rows >= w.blockRows {
+			if err := w.flushBlock(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *AvroWriter) flushBlock() error {
+	if w.rows == 0 {
+		return nil
+	}
+	data, err := w.compress(w.block.Buffer())
+	if err != nil {
+		return fmt.Errorf("failed to compress Avro block: %w", err)
+	}
+	w.out.WriteLong(int64(w.rows))
+	w.out.WriteLong(int64(len(data)))
+	w.out.Write(data)
+	w.out.Write(w.sync[:])
+	w.block.Reset(nil)
+	w.rows = 0
+	if err := w.out.Flush(); err != nil {
+		return fmt.Errorf("failed to write Avro block: %w", err)
+	}
+	return nil
+}
+
+func (w *AvroWriter) compress(data []byte) ([]byte, error) {
+	switch w.codec {
+	case AvroCodecDeflate:
+		var buf bytes.Buffer
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := fw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case AvroCodecSnappy:
+		// The block ends with a CRC32 of the uncompressed data.
+		out := snappy.Encode(nil, data)
+		return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(data)), nil
+	case AvroCodecZstandard:
+		return w.zstd.EncodeAll(data, nil), nil
+	}
+	return data, nil
+}
+
+// Close writes the last block and closes the file.
+func (w *AvroWriter) Close() error {
+	err := w.flushBlock()
+	if w.zstd != nil {
+		w.zstd.Close()
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// AvroSchemaFromArrow derives the Avro schema AvroWriter writes records of
+// schema with. Nullable fields become unions with null, and Arrow types
+// without an Avro equivalent map to the closest logical type: timestamps
+// and times in seconds or nanoseconds are written in milliseconds or
+// microseconds, and dictionaries are written as their values.
+func AvroSchemaFromArrow(schema *arrow.Schema, recordName string) (avro.Schema, error) {
+	s, _, err := avroSchemaFromArrow(schema, recordName)
+	return s, err
+}
+
+func avroSchemaFromArrow(schema *arrow.Schema, recordName string) (*avro.RecordSchema, []avroEncoder, error) {
+	if recordName == "" {
+		recordName = "Record"
+	}
+	b := &avroSchemaBuilder{names: map[string]bool{}}
+	return b.record(recordName, schema.Fields())
+}
+
+// avroEncoder writes row i of arr to w.
+type avroEncoder func(w *avro.Writer, arr arrow.Array, i int) error
+
+// avroSchemaBuilder derives Avro types from Arrow types, keeping the names
+// of records and fixed types unique as Avro requires.
+type avroSchemaBuilder struct {
+	names map[string]bool
+}
+
+var invalidAvroName = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// avroName turns an Arrow field name into a valid Avro name.
+func avroName(name string) string {
+	name = invalidAvroName.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+func (b *avroSchemaBuilder) uniqueName(name string) string {
+	name = avroName(name)
+	unique := name
+	for i := 2; b.names[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	b.names[unique] = true
+	return unique
+}
+
+func (b *avroSchemaBuilder) record(name string, fields []arrow.Field) (*avro.RecordSchema, []avroEncoder, error) {
+	name = b.uniqueName(name)
+	avroFields := make([]*avro.Field, len(fields))
+	encoders := make([]avroEncoder, len(fields))
+	seen := map[string]bool{}
+	for i, f := range fields {
+		s, enc, err := b.field(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		fieldName := avroName(f.Name)
+		for n := 2; seen[fieldName]; n++ {
+			fieldName = avroName(f.Name) + "_" + strconv.Itoa(n)
+		}
+		seen[fieldName] = true
+		if avroFields[i], err = avro.NewField(fieldName, s); err != nil {
+			return nil, nil, err
+		}
+		encoders[i] = enc
+	}
+	rs, err := avro.NewRecordSchema(name, "", avroFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rs, encoders, nil
+}
+
+// field maps f to Avro, as a union with null when f is nullable.
+func (b *avroSchemaBuilder) field(f arrow.Field) (avro.Schema, avroEncoder, error) {
+	s, enc, err := b.value(f.Name, f.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.Type.ID() == arrow.NULL {
+		return s, func(*avro.Writer, arrow.Array, int) error { return nil }, nil
+	}
+	if !f.Nullable {
+		return s, func(w *avro.Writer, arr arrow.Array, i int) error {
+			if arr.IsNull(i) {
+				return fmt.Errorf("null value in non-nullable field %s", f.Name)
+			}
+			return enc(w, arr, i)
+		}, nil
+	}
+	union, err := avro.NewUnionSchema([]avro.Schema{avro.NewNullSchema(), s})
+	if err != nil {
+		return nil, nil, err
+	}
+	return union, func(w *avro.Writer, arr arrow.Array, i int) error {
+		if arr.IsNull(i) {
+			w.WriteLong(0)
+			return nil
+		}
+		w.WriteLong(1)
+		return enc(w, arr, i)
+	}, nil
+}
+
+func (b *avroSchemaBuilder) value(name string, dt arrow.DataType) (avro.Schema, avroEncoder, error) {
+	logical := func(t avro.Type, lt avro.LogicalType) avro.Schema {
+		return avro.NewPrimitiveSchema(t, avro.NewPrimitiveLogicalSchema(lt))
+	}
+
+	switch dt := dt.(type) {
+	case *arrow.NullType:
+		return avro.NewNullSchema(), nil, nil
+	case *arrow.BooleanType:
+		return avro.NewPrimitiveSchema(avro.Boolean, nil), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteBool(arr.(*array.Boolean).Value(i))
+			return nil
+		}, nil
+	case *arrow.Int8Type, *arrow.Int16Type, *arrow.Int32Type, *arrow.Uint8Type, *arrow.Uint16Type:
+		return avro.NewPrimitiveSchema(avro.Int, nil), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteInt(int32(avroIntegerValue(arr, i)))
+			return nil
+		}, nil
+	case *arrow.Int64Type, *arrow.Uint32Type:
+		return avro.NewPrimitiveSchema(avro.Long, nil), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteLong(avroIntegerValue(arr, i))
+			return nil
+		}, nil
+	case *arrow.Uint64Type:
+		return avro.NewPrimitiveSchema(avro.Long, nil), func(w *avro.Writer, arr arrow.Array, i int) error {
+			v := arr.(*array.Uint64).Value(i)
+			if v > math.MaxInt64 {
+				return fmt.Errorf("value %d overflows an Avro long", v)
+			}
+			w.WriteLong(int64(v))
+			return nil
+		}, nil
+	case *arrow.Float16Type:
+		return avro.NewPrimitiveSchema(avro.Float, nil), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteFloat(arr.(*array.Float16).Value(i).Float32())
+			return nil
+		}, nil
+	case *arrow.Float32Type:
+		return avro.NewPrimitiveSchema(avro.Float, nil), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteFloat(arr.(*array.Float32).Value(i))
+			return nil
+		}, nil
+	case *arrow.Float64Type:
+		return avro.NewPrimitiveSchema(avro.Double, nil), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteDouble(arr.(*array.Float64).Value(i))
+			return nil
+		}, nil
+	case *arrow.StringType, *arrow.LargeStringType, *arrow.StringViewType:
+		return avro.NewPrimitiveSchema(avro.String, nil), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteString(arr.(interface{ Value(int) string }).Value(i))
+			return nil
+		}, nil
+	case *arrow.BinaryType, *arrow.LargeBinaryType, *arrow.BinaryViewType:
+		return avro.NewPrimitiveSchema(avro.Bytes, nil), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteBytes(arr.(interface{ Value(int) []byte }).Value(i))
+			return nil
+		}, nil
+	case *arrow.FixedSizeBinaryType:
+		s, err := avro.NewFixedSchema(b.uniqueName(name), "", dt.ByteWidth, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.Write(arr.(*array.FixedSizeBinary).Value(i))
+			return nil
+		}, nil
+	case *arrow.Decimal128Type:
+		s := avro.NewPrimitiveSchema(avro.Bytes, avro.NewDecimalLogicalSchema(int(dt.Precision), int(dt.Scale)))
+		return s, func(w *avro.Writer, arr arrow.Array, i int) error {
+			v := arr.(*array.Decimal128).Value(i)
+			w.WriteBytes(minimalTwosComplement(uint64(v.HighBits()), v.LowBits()))
+			return nil
+		}, nil
+	case *arrow.Decimal256Type:
+		s := avro.NewPrimitiveSchema(avro.Bytes, avro.NewDecimalLogicalSchema(int(dt.Precision), int(dt.Scale)))
+		return s, func(w *avro.Writer, arr arrow.Array, i int) error {
+			v := arr.(*array.Decimal256).Value(i).Array()
+			w.WriteBytes(minimalTwosComplement(v[3], v[2], v[1], v[0]))
+			return nil
+		}, nil
+	case *arrow.Date32Type:
+		return logical(avro.Int, avro.Date), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteInt(int32(arr.(*array.Date32).Value(i)))
+			return nil
+		}, nil
+	case *arrow.Date64Type:
+		return logical(avro.Int, avro.Date), func(w *avro.Writer, arr arrow.Array, i int) error {
+			ms := int64(arr.(*array.Date64).Value(i))
+			w.WriteInt(int32(floorDiv(ms, 86400000)))
+			return nil
+		}, nil
+	case *arrow.Time32Type:
+		scale := int32(1)
+		if dt.Unit == arrow.Second {
+			scale = 1000
+		}
+		return logical(avro.Int, avro.TimeMillis), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteInt(int32(arr.(*array.Time32).Value(i)) * scale)
+			return nil
+		}, nil
+	case *arrow.Time64Type:
+		div := int64(1)
+		if dt.Unit == arrow.Nanosecond {
+			div = 1000
+		}
+		return logical(avro.Long, avro.TimeMicros), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteLong(int64(arr.(*array.Time64).Value(i)) / div)
+			return nil
+		}, nil
+	case *arrow.TimestampType:
+		lt, mul, div := avro.TimestampMillis, int64(1), int64(1)
+		switch dt.Unit {
+		case arrow.Second:
+			mul = 1000
+		case arrow.Microsecond:
+			lt = avro.TimestampMicros
+		case arrow.Nanosecond:
+			lt, div = avro.TimestampMicros, 1000
+		}
+		if dt.TimeZone == "" {
+			lt = map[avro.LogicalType]avro.LogicalType{
+				avro.TimestampMillis: avro.LocalTimestampMillis,
+				avro.TimestampMicros: avro.LocalTimestampMicros,
+			}[lt]
+		}
+		return logical(avro.Long, lt), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteLong(floorDiv(int64(arr.(*array.Timestamp).Value(i))*mul, div))
+			return nil
+		}, nil
+	case *arrow.DurationType:
+		return avro.NewPrimitiveSchema(avro.Long, nil), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteLong(int64(arr.(*array.Duration).Value(i)))
+			return nil
+		}, nil
+	case *arrow.MonthDayNanoIntervalType:
+		s, err := avro.NewFixedSchema(b.uniqueName(name), "", 12, avro.NewPrimitiveLogicalSchema(avro.Duration))
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, func(w *avro.Writer, arr arrow.Array, i int) error {
+			v := arr.(*array.MonthDayNanoInterval).Value(i)
+			if v.Months < 0 || v.Days < 0 || v.Nanoseconds < 0 || v.Nanoseconds/1e6 > math.MaxUint32 {
+				return fmt.Errorf("interval %v is not a valid Avro duration", v)
+			}
+			var buf [12]byte
+			binary.LittleEndian.PutUint32(buf[0:], uint32(v.Months))
+			binary.LittleEndian.PutUint32(buf[4:], uint32(v.Days))
+			binary.LittleEndian.PutUint32(buf[8:], uint32(v.Nanoseconds/1e6))
+			w.Write(buf[:])
+			return nil
+		}, nil
+	case *extensions.UUIDType:
+		return logical(avro.String, avro.UUID), func(w *avro.Writer, arr arrow.Array, i int) error {
+			w.WriteString(arr.(*extensions.UUIDArray).Value(i).String())
+			return nil
+		}, nil
+	case arrow.ExtensionType:
+		s, enc, err := b.value(name, dt.StorageType())
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, func(w *avro.Writer, arr arrow.Array, i int) error {
+			return enc(w, arr.(array.ExtensionArray).Storage(), i)
+		}, nil
+	case *arrow.DictionaryType:
+		s, enc, err := b.value(name, dt.ValueType)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, func(w *avro.Writer, arr arrow.Array, i int) error {
+			d := arr.(*array.Dictionary)
+			return enc(w, d.Dictionary(), d.GetValueIndex(i))
+		}, nil
+	case *arrow.MapType:
+		if id := dt.KeyType().ID(); id != arrow.STRING && id != arrow.LARGE_STRING {
+			return nil, nil, fmt.Errorf("map keys of type %s are not supported, Avro map keys are strings", dt.KeyType())
+		}
+		s, enc, err := b.field(dt.ItemField())
+		if err != nil {
+			return nil, nil, err
+		}
+		return avro.NewMapSchema(s), func(w *avro.Writer, arr arrow.Array, i int) error {
+			m := arr.(*array.Map)
+			keys, items := m.Keys().(interface{ Value(int) string }), m.Items()
+			start, end := m.ValueOffsets(i)
+			if end > start {
+				w.WriteLong(end - start)
+				for j := int(start); j < int(end); j++ {
+					w.WriteString(keys.Value(j))
+					if err := enc(w, items, j); err != nil {
+						return err
+					}
+				}
+			}
+			w.WriteLong(0)
+			return nil
+		}, nil
+	case arrow.ListLikeType:
+		s, enc, err := b.field(dt.ElemField())
+		if err != nil {
+			return nil, nil, err
+		}
+		return avro.NewArraySchema(s), func(w *avro.Writer, arr arrow.Array, i int) error {
+			l := arr.(array.ListLike)
+			values := l.ListValues()
+			start, end := l.ValueOffsets(i)
+			if end > start {
+				w.WriteLong(end - start)
+				for j := int(start); j < int(end); j++ {
+					if err := enc(w, values, j); err != nil {
+						return err
+					}
+				}
+			}
+			w.WriteLong(0)
+			return nil
+		}, nil
+	case *arrow.StructType:
+		s, encoders, err := b.record(name, dt.Fields())
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, func(w *avro.Writer, arr arrow.Array, i int) error {
+			st := arr.(*array.Struct)
+			for j, enc := range encoders {
+				if err := enc(w, st.Field(j), i); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported Arrow type %s", dt)
+}
+
+func avroIntegerValue(arr arrow.Array, i int) int64 {
+	switch arr := arr.(type) {
+	case *array.Int8:
+		return int64(arr.Value(i))
+	case *array.Int16:
+		return int64(arr.Value(i))
+	case *array.Int32:
+		return int64(arr.Value(i))
+	case *array.Int64:
+		return arr.Value(i)
+	case *array.Uint8:
+		return int64(arr.Value(i))
+	case *array.Uint16:
+		return int64(arr.Value(i))
+	case *array.Uint32:
+		return int64(arr.Value(i))
+	}
+	return 0
+}
+
+// minimalTwosComplement returns the big-endian two's complement bytes of the
+// integer made of words, most significant first, without redundant sign
+// bytes, as Avro decimals are encoded.
+func minimalTwosComplement(words ...uint64) []byte {
+	buf := make([]byte, 0, len(words)*8)
+	for _, w := range words {
+		buf = binary.BigEndian.AppendUint64(buf, w)
+	}
+	for len(buf) > 1 && (buf[0] == 0 && buf[1]&0x80 == 0 || buf[0] == 0xff && buf[1]&0x80 != 0) {
+		buf = buf[1:]
+	}
+	return buf
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
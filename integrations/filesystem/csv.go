@@ -30,96 +30,282 @@
 package integrations
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
+	stdcsv "encoding/csv"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/csv"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/geo"
+	"github.com/klauspost/compress/zstd"
 )
 
 // CSVReader reads records from a CSV file and implements the Reader interface.
+// It parses rows as it goes, so memory does not grow with the size of the
+// file; see CSVReadOptions for bounding it.
 type CSVReader struct {
-	reader *csv.Reader
-	file   *os.File
+	ctx    context.Context
+	csv    *stdcsv.Reader
+	file   io.ReadCloser
 	alloc  memory.Allocator
 	schema *arrow.Schema
+	opts   CSVReadOptions
+
+	slots    []*csvSlot
+	next     int
+	lastRows int
+	header   bool  // the header row is still to be skipped
+	skip     int64 // rows still to skip
+	left     int64 // rows still to read, or -1 for no limit
+	done     bool
+	size     int64        // size of the file, or zero when unknown
+	consumed atomic.Int64 // bytes of the file parsed so far
 }
 
 // CSVWriter writes records to a CSV file and implements the Writer interface.
 type CSVWriter struct {
-	writer *csv.Writer
-	file   *os.File
-	alloc  memory.Allocator
+	buf        *bufio.Writer
+	compressor io.WriteCloser
+	file       io.WriteCloser
+	alloc      memory.Allocator
+	schema     *arrow.Schema
+	opts       CSVWriteOptions
+	timestamps *timestampCodec
+	newline    string
+	cells      []string
+	nulls      []bool
 }
 
 // CSVReadOptions defines options for reading CSV files.
 type CSVReadOptions struct {
+	// ChunkSize is the number of rows per record; a negative size reads
+	// the whole file into one record.
 	ChunkSize        int64
 	Delimiter        rune
 	HasHeader        bool
 	NullValues       []string
 	StringsCanBeNull bool
+	// MaxBatchBytes ends a record once this many bytes of CSV have been
+	// read into it, even if it has fewer than ChunkSize rows. Zero means
+	// no limit.
+	MaxBatchBytes int64
+	// BufferedBatches is the size of the ring of record builders. Read
+	// waits for the record last built by a builder to be released before
+	// reusing it, so at most this many records are in memory however far
+	// behind the writer falls. Zero means no limit. The records must not
+	// be held back until a later one is read, or Read waits forever.
+	BufferedBatches int
+	// Offset skips the first rows, which are parsed but not converted, and
+	// Limit stops reading after that many rows, zero meaning no limit.
+	Offset int64
+	Limit  int64
+	// Timestamps controls how timestamp columns are read. The reader's
+	// schema has the unit and time zone they are read with.
+	Timestamps TimestampOptions
+	// DateLayouts are the time layouts tried, in order, when reading date
+	// columns. Defaults to ISO 8601 dates (2006-01-02).
+	DateLayouts []string
 }
 
+// Batch limits used by the CSV converters, keeping their memory around
+// DefaultCSVBufferedBatches times DefaultCSVMaxBatchBytes of CSV input,
+// whatever the size of the file.
+const (
+	DefaultCSVMaxBatchBytes   = 16 << 20
+	DefaultCSVBufferedBatches = 4
+)
+
+// CSVQuoteStyle controls which CSV fields are enclosed in quotes.
+type CSVQuoteStyle int
+
+const (
+	// QuoteMinimal quotes only fields containing the delimiter, quotes, line breaks or leading spaces.
+	QuoteMinimal CSVQuoteStyle = iota
+	// QuoteAll quotes every non-null field.
+	QuoteAll
+	// QuoteNonNumeric quotes every non-null field of a non-numeric column.
+	QuoteNonNumeric
+	// QuoteNone never quotes; fields that would require quoting are rejected.
+	QuoteNone
+)
+
 // CSVWriteOptions defines options for writing CSV files.
+// Output is compressed with gzip or zstd when the file name ends in .gz or .zst.
 type CSVWriteOptions struct {
 	Delimiter       rune
 	IncludeHeader   bool
 	NullValue       string
 	StringsReplacer *strings.Replacer
 	BoolFormatter   func(bool) string
+	Quoting         CSVQuoteStyle
+	UseCRLF         bool   // Terminate lines with \r\n instead of \n
+	WriteBOM        bool   // Emit a UTF-8 byte order mark before the first line
+	TimestampLayout string // Go time layout for timestamps, defaults to time.RFC3339Nano
+	FloatPrecision  int    // Digits after the decimal point for floats, 0 for the shortest exact form
+	// Timestamps controls how timestamps are written. Its Format, when
+	// set, takes precedence over TimestampLayout.
+	Timestamps TimestampOptions
 }
 
-// NewCSVReader creates a new CSV reader for reading records from a CSV file.
+// utf8BOM is the UTF-8 encoded byte order mark.
+const utf8BOM = "\xEF\xBB\xBF"
+
+// NewCSVReader creates a new CSV reader for reading records from a CSV file,
+// a URL, or standard input when filePath is StdioPath.
 func NewCSVReader(ctx context.Context, filePath string, schema *arrow.Schema, opts *CSVReadOptions) (*CSVReader, error) {
+	if opts == nil {
+		opts = &CSVReadOptions{ChunkSize: 1024, HasHeader: true}
+	}
+	options := *opts
+	if options.Delimiter == 0 {
+		options.Delimiter = ','
+	}
+	if options.ChunkSize == 0 {
+		options.ChunkSize = 1
+	}
+	if len(options.NullValues) == 0 {
+		options.NullValues = csv.DefaultNullValues
+	}
+	if options.MaxBatchBytes < 0 || options.BufferedBatches < 0 {
+		return nil, fmt.Errorf("CSV batch limits cannot be negative")
+	}
+	if options.Offset < 0 || options.Limit < 0 {
+		return nil, fmt.Errorf("offset and limit cannot be negative")
+	}
+	codec, err := options.Timestamps.codec()
+	if err != nil {
+		return nil, err
+	}
+	if codec != nil {
+		schema = codec.schema(schema)
+	}
 
 	alloc := pool.GetAllocator()
 
-	file, err := os.Open(filePath)
+	file, err := OpenFile(ctx, filePath)
 	if err != nil {
 		pool.PutAllocator(alloc)
 		return nil, fmt.Errorf("failed to open CSV file: %w", err)
 	}
 
-	options := []csv.Option{
-		csv.WithChunk(int(opts.ChunkSize)),
-		csv.WithComma(opts.Delimiter),
-		csv.WithHeader(opts.HasHeader),
-		csv.WithNullReader(opts.StringsCanBeNull, opts.NullValues...),
-		csv.WithAllocator(alloc),
-	}
-
-	reader := csv.NewReader(file, schema, options...)
+	reader := stdcsv.NewReader(file)
+	reader.Comma = options.Delimiter
+	reader.FieldsPerRecord = schema.NumFields()
+	reader.ReuseRecord = true
 
-	return &CSVReader{
-		reader: reader,
+	r := &CSVReader{
+		ctx:    ctx,
+		csv:    reader,
 		file:   file,
 		alloc:  alloc,
 		schema: schema,
-	}, nil
+		opts:   options,
+		header: options.HasHeader,
+		skip:   options.Offset,
+		left:   options.Limit,
+	}
+	if options.Limit == 0 {
+		r.left = -1
+		// Without a limit the whole file is read, so the share of it
+		// parsed tells how far the reader is.
+		if f, ok := file.(*os.File); ok {
+			if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+				r.size = info.Size()
+			}
+		}
+	}
+	if options.BufferedBatches > 0 {
+		for i := 0; i < options.BufferedBatches; i++ {
+			r.slots = append(r.slots, newCSVSlot(newCSVSlotAllocator(alloc), schema, &options))
+		}
+	} else {
+		r.slots = []*csvSlot{newCSVSlot(alloc, schema, &options)}
+	}
+	return r, nil
 }
 
 // Read reads the next record from the CSV file.
 func (r *CSVReader) Read() (arrow.Record, error) {
-	if !r.reader.Next() {
-		if err := r.reader.Err(); err != nil && err != io.EOF {
-			return nil, fmt.Errorf("error reading CSV record: %w", err)
-		}
+	if r.done || r.left == 0 {
 		return nil, io.EOF
 	}
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	slot := r.slots[r.next]
+	r.next = (r.next + 1) % len(r.slots)
+	if err := slot.waitIdle(r.ctx); err != nil {
+		return nil, err
+	}
+	b := slot.builder
+	if r.lastRows > 0 {
+		b.Reserve(r.lastRows)
+	}
 
-	record := r.reader.Record()
-	if record == nil {
+	start := r.csv.InputOffset()
+	defer func() { r.consumed.Store(r.csv.InputOffset()) }()
+	rows := 0
+	for (r.opts.ChunkSize < 0 || int64(rows) < r.opts.ChunkSize) && r.left != 0 {
+		if r.opts.MaxBatchBytes > 0 && rows > 0 && r.csv.InputOffset()-start >= r.opts.MaxBatchBytes {
+			break
+		}
+		fields, err := r.csv.Read()
+		if err == io.EOF {
+			r.done = true
+			break
+		}
+		if err != nil {
+			r.done = true
+			return nil, fmt.Errorf("error reading CSV record: %w", err)
+		}
+		if r.header {
+			r.header = false
+			continue
+		}
+		if r.skip > 0 {
+			r.skip--
+			continue
+		}
+		for i, field := range fields {
+			if err := slot.convert[i](field); err != nil {
+				r.done = true
+				line, _ := r.csv.FieldPos(i)
+				return nil, fmt.Errorf("error reading CSV record: line %d, column %q: %w", line, r.schema.Field(i).Name, err)
+			}
+		}
+		rows++
+		if r.left > 0 {
+			r.left--
+		}
+	}
+	if rows == 0 {
 		return nil, io.EOF
 	}
+	r.lastRows = rows
+	return b.NewRecord(), nil
+}
+
+// BytesRead returns the number of bytes of the file parsed so far. It is
+// safe to call while Read runs.
+func (r *CSVReader) BytesRead() int64 {
+	return r.consumed.Load()
+}
 
-	record.Retain() // Retain the record to manage its memory lifecycle
-	return record, nil
+// Size returns the size of the file, or zero for a stream or when a limit
+// is set.
+func (r *CSVReader) Size() int64 {
+	return r.size
 }
 
 // Schema returns the schema of the records being read from the CSV file.
@@ -130,50 +316,131 @@ func (r *CSVReader) Schema() *arrow.Schema {
 // Close releases resources associated with the CSV reader.
 func (r *CSVReader) Close() error {
 	defer pool.PutAllocator(r.alloc)
-	if r.reader != nil {
-		r.reader.Release()
+	for _, slot := range r.slots {
+		slot.builder.Release()
 	}
+	r.slots = nil
 	return r.file.Close()
 }
 
-// NewCSVWriter creates a new CSV writer for writing records to a CSV file.
+// NewCSVWriter creates a new CSV writer for writing records to a CSV file,
+// or to standard output when filePath is StdioPath.
 func NewCSVWriter(ctx context.Context, filePath string, schema *arrow.Schema, opts *CSVWriteOptions) (*CSVWriter, error) {
-	alloc := pool.GetAllocator()
+	options, codec, err := csvWriterOptions(opts)
+	if err != nil {
+		return nil, err
+	}
 
-	file, err := os.Create(filePath)
+	file, err := createFile(filePath)
 	if err != nil {
-		pool.PutAllocator(alloc)
 		return nil, fmt.Errorf("failed to create CSV file: %w", err)
 	}
 
+	var compressor io.WriteCloser
+	switch {
+	case strings.HasSuffix(filePath, ".gz"):
+		compressor = gzip.NewWriter(file)
+	case strings.HasSuffix(filePath, ".zst"):
+		compressor, err = zstd.NewWriter(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+	}
+	return newCSVWriter(file, compressor, schema, options, codec)
+}
+
+// csvWriterOptions returns opts with its defaults filled in, and the codec
+// of its timestamps.
+func csvWriterOptions(opts *CSVWriteOptions) (CSVWriteOptions, *timestampCodec, error) {
+	if opts == nil {
+		opts = &CSVWriteOptions{IncludeHeader: true}
+	}
+	options := *opts
+	if options.Delimiter == 0 {
+		options.Delimiter = ','
+	}
+	if options.Delimiter == '"' || options.Delimiter == '\r' || options.Delimiter == '\n' {
+		return options, nil, fmt.Errorf("invalid CSV delimiter %q", options.Delimiter)
+	}
 	// Initialize a no-op strings.Replacer if nil
-	if opts.StringsReplacer == nil {
-		opts.StringsReplacer = strings.NewReplacer()
+	if options.StringsReplacer == nil {
+		options.StringsReplacer = strings.NewReplacer()
+	}
+	if options.BoolFormatter == nil {
+		options.BoolFormatter = strconv.FormatBool
+	}
+	if options.TimestampLayout == "" {
+		options.TimestampLayout = time.RFC3339Nano
 	}
+	if options.Timestamps.Format == "" {
+		options.Timestamps.Format = options.TimestampLayout
+	}
+	codec, err := options.Timestamps.codec()
+	return options, codec, err
+}
 
-	writer := csv.NewWriter(file, schema,
-		csv.WithComma(opts.Delimiter),
-		csv.WithHeader(opts.IncludeHeader),
-		csv.WithNullWriter(opts.NullValue),
-		csv.WithStringsReplacer(opts.StringsReplacer),
-		csv.WithBoolWriter(opts.BoolFormatter),
-	)
+// newCSVWriter returns a writer to file, through compressor if not nil,
+// and writes the header if the options ask for one.
+func newCSVWriter(file, compressor io.WriteCloser, schema *arrow.Schema, options CSVWriteOptions, codec *timestampCodec) (*CSVWriter, error) {
+	var out io.Writer = file
+	if compressor != nil {
+		out = compressor
+	}
 
-	return &CSVWriter{
-		writer: writer,
-		file:   file,
-		alloc:  alloc,
-	}, nil
+	w := &CSVWriter{
+		buf:        bufio.NewWriter(out),
+		compressor: compressor,
+		file:       file,
+		alloc:      pool.GetAllocator(),
+		schema:     schema,
+		opts:       options,
+		timestamps: codec,
+		newline:    "\n",
+		cells:      make([]string, schema.NumFields()),
+		nulls:      make([]bool, schema.NumFields()),
+	}
+	if options.UseCRLF {
+		w.newline = "\r\n"
+	}
+
+	if options.WriteBOM {
+		w.buf.WriteString(utf8BOM)
+	}
+	if options.IncludeHeader {
+		for i, f := range schema.Fields() {
+			w.cells[i] = f.Name
+		}
+		if err := w.writeLine(true); err != nil {
+			w.Close()
+			return nil, fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+
+	return w, nil
 }
 
 // Write writes a record to the CSV file.
 func (w *CSVWriter) Write(record arrow.Record) error {
-	if err := w.writer.Write(record); err != nil {
+	if !record.Schema().Equal(w.schema) {
+		return fmt.Errorf("record schema does not match CSV writer schema")
+	}
+	// Geometries are written as WKT.
+	record, err := geo.WKTRecord(w.alloc, record)
+	if err != nil {
 		return fmt.Errorf("failed to write record to CSV: %w", err)
 	}
+	defer record.Release()
 
-	if err := w.writer.Error(); err != nil {
-		return fmt.Errorf("CSV writer encountered an error: %w", err)
+	cols := record.Columns()
+	for row := 0; row < int(record.NumRows()); row++ {
+		for i, col := range cols {
+			w.nulls[i] = col.IsNull(row)
+			w.cells[i] = w.formatValue(col, row)
+		}
+		if err := w.writeLine(false); err != nil {
+			return fmt.Errorf("failed to write record to CSV: row %d: %w", row, err)
+		}
 	}
 
 	return nil
@@ -182,8 +449,116 @@ func (w *CSVWriter) Write(record arrow.Record) error {
 // Close flushes and closes the CSV writer.
 func (w *CSVWriter) Close() error {
 	defer pool.PutAllocator(w.alloc)
-	if w.writer != nil {
-		w.writer.Flush()
+	err := w.buf.Flush()
+	if w.compressor != nil {
+		if cerr := w.compressor.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to close CSV writer: %w", err)
+	}
+	return nil
+}
+
+// formatValue renders a single cell using the configured per-type formatting.
+func (w *CSVWriter) formatValue(col arrow.Array, row int) string {
+	if col.IsNull(row) {
+		return w.opts.NullValue
+	}
+	switch arr := col.(type) {
+	case *array.Boolean:
+		return w.opts.BoolFormatter(arr.Value(row))
+	case *array.Float32:
+		return w.formatFloat(float64(arr.Value(row)), 32)
+	case *array.Float64:
+		return w.formatFloat(arr.Value(row), 64)
+	case *array.Timestamp:
+		s, err := w.timestamps.formatValue(arr.Value(row), arr.DataType().(*arrow.TimestampType))
+		if err != nil {
+			return arr.ValueStr(row)
+		}
+		return s
+	case *array.String:
+		return w.opts.StringsReplacer.Replace(arr.Value(row))
+	case *array.LargeString:
+		return w.opts.StringsReplacer.Replace(arr.Value(row))
+	case *array.Dictionary:
+		// Decode only the cell being written.
+		return w.formatValue(arr.Dictionary(), arr.GetValueIndex(row))
+	default:
+		return col.ValueStr(row)
+	}
+}
+
+func (w *CSVWriter) formatFloat(v float64, bitSize int) string {
+	if w.opts.FloatPrecision > 0 {
+		return strconv.FormatFloat(v, 'f', w.opts.FloatPrecision, bitSize)
+	}
+	return strconv.FormatFloat(v, 'g', -1, bitSize)
+}
+
+// writeLine writes the buffered cells as one line; header lines are never numeric.
+func (w *CSVWriter) writeLine(header bool) error {
+	for i, cell := range w.cells {
+		if i > 0 {
+			w.buf.WriteRune(w.opts.Delimiter)
+		}
+		// Nulls are never quoted so they stay distinguishable from empty strings.
+		if w.nulls[i] {
+			w.buf.WriteString(cell)
+			continue
+		}
+		quote, err := w.needsQuotes(cell, !header && isNumeric(w.schema.Field(i).Type))
+		if err != nil {
+			return fmt.Errorf("column %q: %w", w.schema.Field(i).Name, err)
+		}
+		if !quote {
+			w.buf.WriteString(cell)
+			continue
+		}
+		w.buf.WriteByte('"')
+		w.buf.WriteString(strings.ReplaceAll(cell, `"`, `""`))
+		w.buf.WriteByte('"')
+	}
+	_, err := w.buf.WriteString(w.newline)
+	return err
+}
+
+// needsQuotes applies the configured quoting policy to a single field.
+func (w *CSVWriter) needsQuotes(field string, numeric bool) (bool, error) {
+	special := field != "" && (strings.ContainsRune(field, w.opts.Delimiter) ||
+		strings.ContainsAny(field, "\"\r\n") || field[0] == ' ' || field[0] == '\t')
+	switch w.opts.Quoting {
+	case QuoteAll:
+		return true, nil
+	case QuoteNonNumeric:
+		return !numeric || special, nil
+	case QuoteNone:
+		if special {
+			return false, fmt.Errorf("field %q requires quoting", field)
+		}
+		return false, nil
+	default:
+		return special, nil
+	}
+}
+
+// isNumeric reports whether values of dt are written as bare numbers.
+func isNumeric(dt arrow.DataType) bool {
+	if dict, ok := dt.(*arrow.DictionaryType); ok {
+		return isNumeric(dict.ValueType)
+	}
+	switch dt.ID() {
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64,
+		arrow.UINT8, arrow.UINT16, arrow.UINT32, arrow.UINT64,
+		arrow.FLOAT16, arrow.FLOAT32, arrow.FLOAT64,
+		arrow.DECIMAL128, arrow.DECIMAL256:
+		return true
+	default:
+		return false
 	}
-	return w.file.Close()
 }
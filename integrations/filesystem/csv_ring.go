@@ -0,0 +1,204 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// csvSlot is one builder of a CSVReader's ring, with the converters that
+// append parsed fields to it.
+type csvSlot struct {
+	alloc   *csvSlotAllocator // nil when the ring is unbounded
+	builder *array.RecordBuilder
+	convert []func(string) error
+}
+
+// newCSVSlot returns a slot building records with mem, which bounds the
+// ring if it is a *csvSlotAllocator.
+func newCSVSlot(mem memory.Allocator, schema *arrow.Schema, opts *CSVReadOptions) *csvSlot {
+	slot := &csvSlot{builder: array.NewRecordBuilder(mem, schema)}
+	slot.alloc, _ = mem.(*csvSlotAllocator)
+	for _, b := range slot.builder.Fields() {
+		slot.convert = append(slot.convert, csvConverter(b, opts))
+	}
+	return slot
+}
+
+// waitIdle waits until the last record built by the slot is released.
+func (s *csvSlot) waitIdle(ctx context.Context) error {
+	if s.alloc == nil {
+		return nil
+	}
+	return s.alloc.waitIdle(ctx)
+}
+
+// csvConverter returns a function parsing a CSV field and appending it to b,
+// following the null handling of arrow's CSV reader.
+func csvConverter(b array.Builder, opts *CSVReadOptions) func(string) error {
+	isNull := func(s string) bool { return slices.Contains(opts.NullValues, s) }
+	switch b := b.(type) {
+	case *array.StringBuilder:
+		if !opts.StringsCanBeNull {
+			return func(s string) error { b.Append(s); return nil }
+		}
+		return func(s string) error {
+			if isNull(s) {
+				b.AppendNull()
+			} else {
+				b.Append(s)
+			}
+			return nil
+		}
+	case *array.LargeStringBuilder:
+		if !opts.StringsCanBeNull {
+			return func(s string) error { b.Append(s); return nil }
+		}
+		return func(s string) error {
+			if isNull(s) {
+				b.AppendNull()
+			} else {
+				b.Append(s)
+			}
+			return nil
+		}
+	case *array.TimestampBuilder:
+		codec, _ := opts.Timestamps.codec()
+		if codec == nil {
+			break
+		}
+		dt := b.Type().(*arrow.TimestampType)
+		return func(s string) error {
+			if isNull(s) {
+				b.AppendNull()
+				return nil
+			}
+			v, err := codec.parse(s, dt)
+			if err != nil {
+				b.AppendNull()
+				return err
+			}
+			b.Append(v)
+			return nil
+		}
+	case *array.Date32Builder:
+		if len(opts.DateLayouts) == 0 {
+			break
+		}
+		return func(s string) error {
+			if isNull(s) {
+				b.AppendNull()
+				return nil
+			}
+			for _, layout := range opts.DateLayouts {
+				if t, err := time.Parse(layout, s); err == nil {
+					b.Append(arrow.Date32FromTime(t))
+					return nil
+				}
+			}
+			b.AppendNull()
+			return fmt.Errorf("cannot parse %q as a date with layouts %q", s, opts.DateLayouts)
+		}
+	}
+	return func(s string) error {
+		if isNull(s) {
+			b.AppendNull()
+			return nil
+		}
+		return b.AppendValueFromString(s)
+	}
+}
+
+// csvSlotAllocator counts the bytes allocated through it, so that a slot
+// can wait for the record it built to be released before building another.
+type csvSlotAllocator struct {
+	parent memory.Allocator
+
+	mu    sync.Mutex
+	inUse int
+	idle  chan struct{} // closed once inUse drops to zero, if waited on
+}
+
+func newCSVSlotAllocator(parent memory.Allocator) *csvSlotAllocator {
+	return &csvSlotAllocator{parent: parent}
+}
+
+func (a *csvSlotAllocator) add(delta int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inUse += delta
+	if a.inUse == 0 && a.idle != nil {
+		close(a.idle)
+		a.idle = nil
+	}
+}
+
+func (a *csvSlotAllocator) Allocate(size int) []byte {
+	a.add(size)
+	return a.parent.Allocate(size)
+}
+
+func (a *csvSlotAllocator) Reallocate(size int, b []byte) []byte {
+	a.add(size - len(b))
+	return a.parent.Reallocate(size, b)
+}
+
+func (a *csvSlotAllocator) Free(b []byte) {
+	a.parent.Free(b)
+	a.add(-len(b))
+}
+
+func (a *csvSlotAllocator) waitIdle(ctx context.Context) error {
+	a.mu.Lock()
+	if a.inUse == 0 {
+		a.mu.Unlock()
+		return nil
+	}
+	if a.idle == nil {
+		a.idle = make(chan struct{})
+	}
+	idle := a.idle
+	a.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,484 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"golang.org/x/net/websocket"
+)
+
+// Event stream protocols of an EventReader.
+const (
+	EventProtocolWebSocket = "websocket"
+	EventProtocolSSE       = "sse"
+)
+
+// Defaults of NewDefaultEventReadOptions.
+const (
+	defaultEventBatchSize     = 1024
+	defaultEventFlushInterval = 5 * time.Second
+	defaultWarmupEvents       = 100
+	defaultWarmupTimeout      = 10 * time.Second
+)
+
+// EventReadOptions configures an EventReader.
+type EventReadOptions struct {
+	// Protocol is EventProtocolWebSocket or EventProtocolSSE. When empty
+	// it follows the URL: ws:// and wss:// are WebSocket, http:// and
+	// https:// server-sent events.
+	Protocol string
+	// Headers are sent with the request opening the stream, e.g. for
+	// authentication.
+	Headers http.Header
+	// Client opens server-sent event streams. Defaults to
+	// http.DefaultClient; a client timeout would end the stream.
+	Client *http.Client
+	// Subscribe, if set, is sent as a text message once a WebSocket is
+	// open, for feeds that stream once subscribed.
+	Subscribe string
+	// EventType keeps only the server-sent events of that type. Empty
+	// keeps every event.
+	EventType string
+	// Schema is the schema of the events. When nil it is inferred from the
+	// events of a warm-up window: the first WarmupEvents, or those received
+	// within WarmupTimeout, no more than MaxEvents. Fields missing from the
+	// schema are dropped.
+	Schema        *arrow.Schema
+	WarmupEvents  int
+	WarmupTimeout time.Duration
+	// BatchSize is the number of events per record, and FlushInterval
+	// returns a partial record when no full batch arrived in that time.
+	// Zero FlushInterval waits for full batches.
+	BatchSize     int
+	FlushInterval time.Duration
+	// MaxEvents ends the stream after that many events. Zero reads until
+	// the server ends the stream or the reader is closed.
+	MaxEvents int64
+}
+
+// NewDefaultEventReadOptions returns options batching 1024 events or what
+// arrived within 5s, inferring the schema from the first 100 events or
+// those of the first 10s.
+func NewDefaultEventReadOptions() *EventReadOptions {
+	return &EventReadOptions{
+		BatchSize:     defaultEventBatchSize,
+		FlushInterval: defaultEventFlushInterval,
+		WarmupEvents:  defaultWarmupEvents,
+		WarmupTimeout: defaultWarmupTimeout,
+	}
+}
+
+// IsEventStream reports whether path is a ws:// or wss:// URL.
+func IsEventStream(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "ws://") || strings.HasPrefix(lower, "wss://")
+}
+
+// EventReader reads the JSON events of a WebSocket or server-sent events
+// stream as Arrow records, for live feeds such as tickers or telemetry.
+// Each message or event holds a JSON object, or an array of objects read
+// as one row each; other messages, such as heartbeats, are skipped.
+type EventReader struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	url      string
+	opts     EventReadOptions
+	schema   *arrow.Schema
+	alloc    memory.Allocator
+	conn     io.Closer
+	msgs     chan []byte
+	err      error
+	drained  bool
+	ended    bool
+	pending  [][]byte
+	received int64
+	skipped  int64
+}
+
+// NewEventReader connects to the stream at rawURL and, unless the options
+// set a schema, reads its warm-up window to infer one.
+func NewEventReader(ctx context.Context, rawURL string, opts *EventReadOptions) (*EventReader, error) {
+	if opts == nil {
+		opts = NewDefaultEventReadOptions()
+	}
+	r := &EventReader{url: rawURL, opts: *opts}
+	if r.opts.BatchSize <= 0 {
+		r.opts.BatchSize = defaultEventBatchSize
+	}
+	if r.opts.WarmupEvents <= 0 {
+		r.opts.WarmupEvents = defaultWarmupEvents
+	}
+	if r.opts.WarmupTimeout <= 0 {
+		r.opts.WarmupTimeout = defaultWarmupTimeout
+	}
+	protocol := r.opts.Protocol
+	switch {
+	case protocol != "":
+	case IsEventStream(rawURL):
+		protocol = EventProtocolWebSocket
+	case IsURL(rawURL):
+		protocol = EventProtocolSSE
+	default:
+		return nil, fmt.Errorf("events: %s is not a ws://, wss://, http:// or https:// URL", displayURL(rawURL))
+	}
+
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	var next func() ([]byte, error)
+	var err error
+	switch protocol {
+	case EventProtocolWebSocket:
+		next, err = r.dialWebSocket()
+	case EventProtocolSSE:
+		next, err = r.openSSE()
+	default:
+		err = fmt.Errorf("unknown protocol %q", protocol)
+	}
+	if err != nil {
+		r.cancel()
+		return nil, fmt.Errorf("events: %w", err)
+	}
+	r.msgs = make(chan []byte, r.opts.BatchSize)
+	go r.receive(next)
+
+	r.alloc = pool.GetAllocator()
+	r.schema = r.opts.Schema
+	if r.schema == nil {
+		if r.schema, err = r.warmUp(); err != nil {
+			r.Close()
+			return nil, fmt.Errorf("events: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// dialWebSocket opens the WebSocket and sends the subscription message.
+func (r *EventReader) dialWebSocket() (func() ([]byte, error), error) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", displayURL(r.url), err)
+	}
+	origin := &url.URL{Scheme: "http", Host: u.Host}
+	if strings.EqualFold(u.Scheme, "wss") {
+		origin.Scheme = "https"
+	}
+	config, err := websocket.NewConfig(r.url, origin.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", displayURL(r.url), err)
+	}
+	config.Header = r.opts.Headers.Clone()
+	ws, err := config.DialContext(r.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", displayURL(r.url), err)
+	}
+	if r.opts.Subscribe != "" {
+		if err := websocket.Message.Send(ws, r.opts.Subscribe); err != nil {
+			ws.Close()
+			return nil, fmt.Errorf("failed to subscribe to %s: %w", displayURL(r.url), err)
+		}
+	}
+	r.conn = ws
+	return func() ([]byte, error) {
+		var msg []byte
+		err := websocket.Message.Receive(ws, &msg)
+		return msg, err
+	}, nil
+}
+
+// openSSE sends the request opening a server-sent events stream, and
+// returns a function returning the data of each event in turn.
+func (r *EventReader) openSSE() (func() ([]byte, error), error) {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", displayURL(r.url), err)
+	}
+	for name, values := range r.opts.Headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	client := r.opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", displayURL(r.url), err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", displayURL(r.url), resp.Status)
+	}
+	r.conn = resp.Body
+	lines := bufio.NewReader(resp.Body)
+	return func() ([]byte, error) {
+		return nextSSEEvent(lines, r.opts.EventType)
+	}, nil
+}
+
+// nextSSEEvent returns the data of the next event of eventType, or of any
+// type if it is empty, joining the lines of multi-line data.
+func nextSSEEvent(lines *bufio.Reader, eventType string) ([]byte, error) {
+	var data []byte
+	var hasData bool
+	event := "message"
+	for {
+		line, err := lines.ReadBytes('\n')
+		if err != nil && (len(line) == 0 || err != io.EOF) {
+			return nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			if hasData && (eventType == "" || event == eventType) {
+				return data, nil
+			}
+			data, hasData, event = data[:0], false, "message"
+			continue
+		}
+		field, value, _ := bytes.Cut(line, []byte(":"))
+		value = bytes.TrimPrefix(value, []byte(" "))
+		switch string(field) {
+		case "data":
+			if hasData {
+				data = append(data, '\n')
+			}
+			data = append(data, value...)
+			hasData = true
+		case "event":
+			event = string(value)
+		}
+	}
+}
+
+// receive forwards messages until the stream ends. r.err is set before
+// msgs is closed, so it is only read once r.drained.
+func (r *EventReader) receive(next func() ([]byte, error)) {
+	defer close(r.msgs)
+	for {
+		msg, err := next()
+		if err != nil {
+			if err != io.EOF && r.ctx.Err() == nil {
+				r.err = err
+			}
+			return
+		}
+		select {
+		case r.msgs <- msg:
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// rows returns the JSON objects of msg, which holds one or an array of
+// them, or nil, counting it as skipped, if it holds neither.
+func (r *EventReader) rows(msg []byte) [][]byte {
+	msg = bytes.TrimSpace(msg)
+	switch {
+	case len(msg) > 0 && msg[0] == '{' && json.Valid(msg):
+		return [][]byte{msg}
+	case len(msg) > 0 && msg[0] == '[':
+		var elems []json.RawMessage
+		if json.Unmarshal(msg, &elems) == nil {
+			rows := make([][]byte, 0, len(elems))
+			for _, e := range elems {
+				if len(e) > 0 && e[0] == '{' {
+					rows = append(rows, e)
+				}
+			}
+			if len(rows) > 0 {
+				return rows
+			}
+		}
+	}
+	r.skipped++
+	logging.FromContext(r.ctx).Debug("skipped event that is not a JSON object", "url", displayURL(r.url), "size", len(msg))
+	return nil
+}
+
+// warmUp reads the events of the warm-up window, no more than MaxEvents,
+// keeping them to be read, and infers their schema.
+func (r *EventReader) warmUp() (*arrow.Schema, error) {
+	timer := time.NewTimer(r.opts.WarmupTimeout)
+	defer timer.Stop()
+	want := int64(r.opts.WarmupEvents)
+	if r.opts.MaxEvents > 0 {
+		want = min(want, r.opts.MaxEvents)
+	}
+warmup:
+	for int64(len(r.pending)) < want {
+		select {
+		case msg, ok := <-r.msgs:
+			if !ok {
+				r.drained, r.ended = true, true
+				break warmup
+			}
+			r.pending = append(r.pending, r.rows(msg)...)
+		case <-timer.C:
+			break warmup
+		}
+	}
+	if len(r.pending) == 0 {
+		if r.drained && r.err != nil {
+			return nil, fmt.Errorf("stream failed: %w", r.err)
+		}
+		if r.drained {
+			return nil, fmt.Errorf("no events from %s before the stream ended", displayURL(r.url))
+		}
+		return nil, fmt.Errorf("no events from %s within the %s warm-up", displayURL(r.url), r.opts.WarmupTimeout)
+	}
+	samples := make([]map[string]interface{}, len(r.pending))
+	for i, row := range r.pending {
+		if err := json.Unmarshal(row, &samples[i]); err != nil {
+			return nil, err
+		}
+	}
+	schema, err := InferJSONSchema(samples)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(r.ctx).Debug("inferred event schema", "url", displayURL(r.url), "events", len(samples), "fields", schema.NumFields())
+	return schema, nil
+}
+
+// Read returns the next batch of events, once BatchSize arrived or
+// FlushInterval passed with some waiting.
+func (r *EventReader) Read() (arrow.Record, error) {
+	size := r.opts.BatchSize
+	if r.opts.MaxEvents > 0 {
+		size = int(min(int64(size), r.opts.MaxEvents-r.received))
+	}
+	n := min(size, len(r.pending))
+	batch := slices.Clone(r.pending[:n])
+	r.pending = r.pending[n:]
+
+	var timeout <-chan time.Time
+	if r.opts.FlushInterval > 0 {
+		timer := time.NewTimer(r.opts.FlushInterval)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+loop:
+	for len(batch) < size && !r.ended {
+		select {
+		case msg, ok := <-r.msgs:
+			if !ok {
+				r.drained, r.ended = true, true
+				break loop
+			}
+			rows := r.rows(msg)
+			n := min(size-len(batch), len(rows))
+			batch = append(batch, rows[:n]...)
+			r.pending = append(r.pending, rows[n:]...)
+		case <-timeout:
+			if len(batch) > 0 {
+				break loop
+			}
+			timeout = nil
+		case <-r.ctx.Done():
+			r.ended = true
+			break loop
+		}
+	}
+	r.received += int64(len(batch))
+	if r.opts.MaxEvents > 0 && r.received >= r.opts.MaxEvents {
+		r.stop()
+	}
+
+	if len(batch) == 0 {
+		if r.drained && r.err != nil {
+			return nil, fmt.Errorf("events: stream failed: %w", r.err)
+		}
+		return nil, io.EOF
+	}
+	return r.decode(batch)
+}
+
+// stop ends receiving; events already buffered are still read.
+func (r *EventReader) stop() {
+	r.cancel()
+	r.ended = true
+}
+
+// decode builds a record of rows.
+func (r *EventReader) decode(rows [][]byte) (arrow.Record, error) {
+	record, err := DecodeJSONObjects(rows, r.schema, r.alloc)
+	if err != nil {
+		return nil, fmt.Errorf("events: %w", err)
+	}
+	return record, nil
+}
+
+// Skipped returns the number of messages skipped for not holding JSON
+// objects.
+func (r *EventReader) Skipped() int64 {
+	return r.skipped
+}
+
+// Schema returns the schema of the events.
+func (r *EventReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Close closes the stream.
+func (r *EventReader) Close() error {
+	r.cancel()
+	var err error
+	if r.conn != nil {
+		err = r.conn.Close()
+	}
+	// Wait for the receiver, which may be blocked sending.
+	for range r.msgs {
+	}
+	if r.alloc != nil {
+		pool.PutAllocator(r.alloc)
+		r.alloc = nil
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,206 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/extensions"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/schema"
+	"github.com/goccy/go-json"
+)
+
+// jsonColumns returns the names of the top-level columns of fileSchema
+// annotated as JSON, which pqarrow reads as binary.
+func jsonColumns(fileSchema *schema.Schema) map[string]bool {
+	var names map[string]bool
+	root := fileSchema.Root()
+	for i := 0; i < root.NumFields(); i++ {
+		node := root.Field(i)
+		if _, ok := node.LogicalType().(schema.JSONLogicalType); ok {
+			if names == nil {
+				names = make(map[string]bool)
+			}
+			names[node.Name()] = true
+		}
+	}
+	return names
+}
+
+// jsonSchema returns s with the binary columns named in names typed as
+// arrow.json, or s itself if there are none.
+func jsonSchema(s *arrow.Schema, names map[string]bool) *arrow.Schema {
+	if len(names) == 0 {
+		return s
+	}
+	fields := s.Fields()
+	changed := false
+	for i, f := range fields {
+		if names[f.Name] && isBinaryOrString(f.Type) {
+			fields[i].Type = jsonExtension()
+			changed = true
+		}
+	}
+	if !changed {
+		return s
+	}
+	md := s.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
+
+func isBinaryOrString(dt arrow.DataType) bool {
+	return dt.ID() == arrow.BINARY || dt.ID() == arrow.STRING
+}
+
+func jsonExtension() arrow.ExtensionType {
+	dt, _ := extensions.NewJSONType(arrow.BinaryTypes.String)
+	return dt
+}
+
+// withJSONColumns returns record with the binary columns named in names
+// as arrow.json arrays over the same buffers. It releases record.
+func withJSONColumns(record arrow.Record, names map[string]bool) arrow.Record {
+	out := jsonSchema(record.Schema(), names)
+	if out == record.Schema() {
+		return record
+	}
+	defer record.Release()
+	cols := slices.Clone(record.Columns())
+	for i, col := range cols {
+		if out.Field(i).Type.ID() == arrow.EXTENSION && col.DataType().ID() != arrow.EXTENSION {
+			cols[i] = jsonArray(col)
+		} else {
+			col.Retain()
+		}
+	}
+	defer releaseArrays(cols)
+	return array.NewRecord(out, cols, record.NumRows())
+}
+
+// jsonArray returns the strings or binaries of col, or of its storage if it
+// is an extension array, as an arrow.json array over the same buffers.
+func jsonArray(col arrow.Array) arrow.Array {
+	if ext, ok := col.(array.ExtensionArray); ok {
+		col = ext.Storage()
+	}
+	data := col.Data()
+	strData := array.NewData(arrow.BinaryTypes.String, data.Len(), data.Buffers(), nil, data.NullN(), data.Offset())
+	defer strData.Release()
+	storage := array.MakeFromData(strData)
+	defer storage.Release()
+	return array.NewExtensionArrayWithStorage(jsonExtension(), storage)
+}
+
+func releaseArrays(cols []arrow.Array) {
+	for _, col := range cols {
+		col.Release()
+	}
+}
+
+// rawJSONType is read in place of arrow.json by JSON readers: its builder
+// takes JSON values of any kind as their text, where arrow.json's takes
+// JSON strings only.
+type rawJSONType struct {
+	arrow.ExtensionBase
+}
+
+func newRawJSONType() *rawJSONType {
+	return &rawJSONType{ExtensionBase: arrow.ExtensionBase{Storage: arrow.BinaryTypes.String}}
+}
+
+func (*rawJSONType) ArrayType() reflect.Type { return reflect.TypeOf(rawJSONArray{}) }
+
+func (*rawJSONType) ExtensionName() string { return "arrowarc.raw_json" }
+
+func (*rawJSONType) Serialize() string { return "" }
+
+func (t *rawJSONType) Deserialize(storage arrow.DataType, _ string) (arrow.ExtensionType, error) {
+	return t, nil
+}
+
+func (t *rawJSONType) ExtensionEquals(other arrow.ExtensionType) bool {
+	return t.ExtensionName() == other.ExtensionName()
+}
+
+func (t *rawJSONType) NewBuilder(mem memory.Allocator) array.Builder {
+	return &rawJSONBuilder{ExtensionBuilder: array.NewExtensionBuilder(mem, t)}
+}
+
+type rawJSONArray struct {
+	array.ExtensionArrayBase
+}
+
+type rawJSONBuilder struct {
+	*array.ExtensionBuilder
+}
+
+func (b *rawJSONBuilder) UnmarshalOne(dec *json.Decoder) error {
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	if string(raw) == "null" {
+		b.AppendNull()
+		return nil
+	}
+	b.Builder.(*array.StringBuilder).Append(string(raw))
+	return nil
+}
+
+func (b *rawJSONBuilder) Unmarshal(dec *json.Decoder) error {
+	for dec.More() {
+		if err := b.UnmarshalOne(dec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rawJSONSchema returns s with its arrow.json columns read as rawJSONType,
+// and their indices.
+func rawJSONSchema(s *arrow.Schema) (*arrow.Schema, []int) {
+	fields := s.Fields()
+	var indices []int
+	for i, f := range fields {
+		if ext, ok := f.Type.(arrow.ExtensionType); ok && ext.ExtensionName() == "arrow.json" {
+			fields[i].Type = newRawJSONType()
+			indices = append(indices, i)
+		}
+	}
+	if indices == nil {
+		return s, nil
+	}
+	md := s.Metadata()
+	return arrow.NewSchema(fields, &md), indices
+}
@@ -0,0 +1,231 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	memoryPool "github.com/arrowarc/arrowarc/internal/memory"
+)
+
+// featherMagic starts and ends every Feather V2 (Arrow IPC file format) file.
+var featherMagic = []byte("ARROW1")
+
+// IsFeatherFile reports whether the file or URL at path is in the Arrow IPC
+// file format, also known as Feather V2, rather than the IPC stream format.
+func IsFeatherFile(path string) (bool, error) {
+	file, _, err := openReaderAt(context.Background(), path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	head := make([]byte, len(featherMagic))
+	if _, err := file.ReadAt(head, 0); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return bytes.Equal(head, featherMagic), nil
+}
+
+// FeatherReadOptions configures a FeatherReader.
+type FeatherReadOptions struct {
+	// MemoryMap maps the file into memory so uncompressed buffers are read
+	// without copying. Records then reference the mapping and are only
+	// valid until the reader is closed.
+	MemoryMap bool
+}
+
+// FeatherReader reads records from a Feather V2 file.
+type FeatherReader struct {
+	ctx     context.Context
+	reader  *ipc.FileReader
+	file    io.Closer
+	unmap   func() error
+	next    int
+	current int
+	alloc   memory.Allocator
+	closed  bool
+}
+
+// NewFeatherReader opens the Feather V2 file at filePath, which may be an
+// http:// or https:// URL read with range requests. URLs are never memory
+// mapped.
+func NewFeatherReader(ctx context.Context, filePath string, opts *FeatherReadOptions) (*FeatherReader, error) {
+	if opts == nil {
+		opts = &FeatherReadOptions{}
+	}
+	if IsStdio(filePath) {
+		return nil, errNotSeekable("Feather")
+	}
+	file, _, err := openReaderAt(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Feather file: %w", err)
+	}
+
+	alloc := memoryPool.GetAllocator()
+	r := &FeatherReader{ctx: ctx, file: file, alloc: alloc}
+	if osFile, ok := file.(*os.File); ok && opts.MemoryMap {
+		data, unmap, err := mapFile(osFile)
+		if err != nil {
+			file.Close()
+			memoryPool.PutAllocator(alloc)
+			return nil, err
+		}
+		r.unmap = unmap
+		r.reader, err = ipc.NewMappedFileReader(data, ipc.WithAllocator(alloc))
+	} else {
+		r.reader, err = ipc.NewFileReader(file, ipc.WithAllocator(alloc))
+	}
+	if err != nil {
+		if r.unmap != nil {
+			r.unmap()
+		}
+		file.Close()
+		memoryPool.PutAllocator(alloc)
+		return nil, fmt.Errorf("failed to create Feather reader: %w", err)
+	}
+	return r, nil
+}
+
+// Read returns the next record batch of the file.
+func (r *FeatherReader) Read() (arrow.Record, error) {
+	if r.closed || r.next >= r.reader.NumRecords() {
+		return nil, io.EOF
+	}
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.current = r.next
+	record, err := r.reader.RecordAt(r.next)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Feather record %d: %w", r.next, err)
+	}
+	r.next++
+	return record, nil
+}
+
+// Position returns the index of the record batch last read in the file.
+func (r *FeatherReader) Position() string {
+	return fmt.Sprintf("record batch %d", r.current)
+}
+
+// Schema returns the schema of the file.
+func (r *FeatherReader) Schema() *arrow.Schema {
+	return r.reader.Schema()
+}
+
+// Close releases the reader and the file. It is safe to call more than once.
+func (r *FeatherReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	defer memoryPool.PutAllocator(r.alloc)
+	r.reader.Close()
+	if r.unmap != nil {
+		if err := r.unmap(); err != nil {
+			r.file.Close()
+			return fmt.Errorf("failed to unmap Feather file: %w", err)
+		}
+	}
+	return r.file.Close()
+}
+
+// FeatherWriter writes records to a Feather V2 file.
+type FeatherWriter struct {
+	writer *ipc.FileWriter
+	file   io.WriteCloser
+	schema *arrow.Schema
+	alloc  memory.Allocator
+	closed bool
+}
+
+// NewFeatherWriter creates a Feather V2 file at filePath, or writes one to
+// standard output for StdioPath, compressed as set by opts. A nil opts uses
+// NewDefaultIPCWriteOptions.
+func NewFeatherWriter(ctx context.Context, filePath string, schema *arrow.Schema, opts *IPCWriteOptions) (*FeatherWriter, error) {
+	alloc := memoryPool.GetAllocator()
+	ipcOpts, err := opts.ipcOptions(schema, alloc)
+	if err != nil {
+		memoryPool.PutAllocator(alloc)
+		return nil, err
+	}
+
+	file, err := createFile(filePath)
+	if err != nil {
+		memoryPool.PutAllocator(alloc)
+		return nil, fmt.Errorf("could not create Feather file: %w", err)
+	}
+
+	writer, err := ipc.NewFileWriter(file, ipcOpts...)
+	if err != nil {
+		file.Close()
+		memoryPool.PutAllocator(alloc)
+		return nil, fmt.Errorf("failed to create Feather writer: %w", err)
+	}
+	return &FeatherWriter{writer: writer, file: file, schema: schema, alloc: alloc}, nil
+}
+
+// Write appends a record batch to the file.
+func (w *FeatherWriter) Write(record arrow.Record) error {
+	if err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("could not write record: %w", err)
+	}
+	return nil
+}
+
+// Close writes the file footer and closes the file. It is safe to call
+// more than once.
+func (w *FeatherWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer memoryPool.PutAllocator(w.alloc)
+	if err := w.writer.Close(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to close Feather writer: %w", err)
+	}
+	return w.file.Close()
+}
+
+// Schema returns the schema of the records being written.
+func (w *FeatherWriter) Schema() *arrow.Schema {
+	return w.schema
+}
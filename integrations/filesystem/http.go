@@ -0,0 +1,548 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+)
+
+// Environment variables read by NewHTTPOptionsFromEnv.
+const (
+	// EnvHTTPURLPrefix is the URL prefix the headers and bearer token of
+	// the environment are sent to, such as "https://example.com/data/".
+	// They are sent nowhere unless it is set.
+	EnvHTTPURLPrefix = "ARROWARC_HTTP_URL_PREFIX"
+	// EnvHTTPHeaders holds extra request headers as "Name: value" pairs
+	// separated by newlines or semicolons.
+	EnvHTTPHeaders = "ARROWARC_HTTP_HEADERS"
+	// EnvHTTPBearerToken is sent as an "Authorization: Bearer" header.
+	EnvHTTPBearerToken = "ARROWARC_HTTP_BEARER_TOKEN"
+)
+
+// HTTPOptions configures reading http:// and https:// inputs.
+type HTTPOptions struct {
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Credentials are sent with the requests to the URLs they cover, and
+	// with no others, redirects included.
+	Credentials []HTTPCredentials
+	// MaxRetries is the number of times a request failing with a network
+	// error, a 429 or a 5xx status is retried. A download interrupted part
+	// way is resumed with a range request.
+	MaxRetries int
+	// Backoff is the delay before the first retry, doubled on each retry.
+	Backoff time.Duration
+}
+
+// HTTPCredentials are the headers, e.g. for authentication, sent with the
+// requests to the URLs under Prefix.
+type HTTPCredentials struct {
+	// Prefix is a URL such as "https://example.com/data/". It covers the
+	// URLs of the same scheme and host whose path is the path of Prefix or
+	// below it.
+	Prefix  string
+	Headers http.Header
+	// BearerToken, if set, is sent as an "Authorization: Bearer" header.
+	// It is never sent over plain http.
+	BearerToken string
+}
+
+// prefixURL parses Prefix, which must hold a scheme and host.
+func (c *HTTPCredentials) prefixURL() (*url.URL, error) {
+	prefix, err := url.Parse(c.Prefix)
+	if err != nil || prefix.Scheme == "" || prefix.Host == "" {
+		return nil, fmt.Errorf("invalid HTTP credentials prefix %s: a URL with a scheme and host is required", displayURL(c.Prefix))
+	}
+	return prefix, nil
+}
+
+// covers reports whether the credentials apply to u.
+func (c *HTTPCredentials) covers(u *url.URL) (bool, error) {
+	prefix, err := c.prefixURL()
+	if err != nil {
+		return false, err
+	}
+	if !strings.EqualFold(prefix.Scheme, u.Scheme) || !strings.EqualFold(prefix.Host, u.Host) {
+		return false, nil
+	}
+	dir := strings.TrimSuffix(prefix.Path, "/")
+	return dir == "" || u.Path == dir || strings.HasPrefix(u.Path, dir+"/"), nil
+}
+
+// isPlaintext reports whether u is sent unencrypted.
+func isPlaintext(u *url.URL) bool {
+	return strings.EqualFold(u.Scheme, "http") || strings.EqualFold(u.Scheme, "ws")
+}
+
+// NewDefaultHTTPOptions returns three retries starting at 500ms, sending no
+// credentials.
+func NewDefaultHTTPOptions() *HTTPOptions {
+	return &HTTPOptions{MaxRetries: 3, Backoff: 500 * time.Millisecond}
+}
+
+// NewHTTPOptionsFromEnv returns NewDefaultHTTPOptions with the headers set
+// by EnvHTTPHeaders and EnvHTTPBearerToken, sent to the URLs under
+// EnvHTTPURLPrefix. Setting either without a prefix is an error.
+func NewHTTPOptionsFromEnv() (*HTTPOptions, error) {
+	opts := NewDefaultHTTPOptions()
+	creds := HTTPCredentials{Prefix: os.Getenv(EnvHTTPURLPrefix), Headers: http.Header{}, BearerToken: os.Getenv(EnvHTTPBearerToken)}
+	for _, line := range strings.FieldsFunc(os.Getenv(EnvHTTPHeaders), func(r rune) bool { return r == '\n' || r == ';' }) {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(name) != "" {
+			creds.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+	}
+	if len(creds.Headers) == 0 && creds.BearerToken == "" {
+		return opts, nil
+	}
+	if creds.Prefix == "" {
+		return nil, fmt.Errorf("%s must name the URLs the headers of %s and %s are sent to", EnvHTTPURLPrefix, EnvHTTPHeaders, EnvHTTPBearerToken)
+	}
+	opts.Credentials = []HTTPCredentials{creds}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// validate checks the credential prefixes, and that no bearer token is set
+// for plain http URLs.
+func (o *HTTPOptions) validate() error {
+	for i := range o.Credentials {
+		prefix, err := o.Credentials[i].prefixURL()
+		if err != nil {
+			return err
+		}
+		if o.Credentials[i].BearerToken != "" && isPlaintext(prefix) {
+			return fmt.Errorf("refusing to send a bearer token over plain %s to %s", prefix.Scheme, displayURL(o.Credentials[i].Prefix))
+		}
+	}
+	return nil
+}
+
+// HeadersFor returns the headers of the credentials covering rawURL. A
+// bearer token covering a plain http or ws URL is an error.
+func (o *HTTPOptions) HeadersFor(rawURL string) (http.Header, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", displayURL(rawURL), err)
+	}
+	return o.headersFor(u)
+}
+
+func (o *HTTPOptions) headersFor(u *url.URL) (http.Header, error) {
+	headers := http.Header{}
+	for i := range o.Credentials {
+		c := &o.Credentials[i]
+		ok, err := c.covers(u)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		for name, values := range c.Headers {
+			headers[http.CanonicalHeaderKey(name)] = values
+		}
+		if c.BearerToken != "" {
+			if isPlaintext(u) {
+				return nil, fmt.Errorf("refusing to send a bearer token over plain %s to %s", u.Scheme, displayURL(u.String()))
+			}
+			headers.Set("Authorization", "Bearer "+c.BearerToken)
+		}
+	}
+	return headers, nil
+}
+
+// client returns the client sending requests, which on a redirect drops
+// the credential headers and adds those covering the new URL.
+func (o *HTTPOptions) client() *http.Client {
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(o.Credentials) == 0 {
+		return client
+	}
+	scoped := *client
+	scoped.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		req.Header.Del("Authorization")
+		for _, c := range o.Credentials {
+			for name := range c.Headers {
+				req.Header.Del(name)
+			}
+		}
+		headers, err := o.headersFor(req.URL)
+		if err != nil {
+			return err
+		}
+		for name, values := range headers {
+			req.Header[name] = values
+		}
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &scoped
+}
+
+type httpOptionsKey struct{}
+
+// WithHTTPOptions returns a context whose readers of http:// and https://
+// URLs use opts.
+func WithHTTPOptions(ctx context.Context, opts *HTTPOptions) context.Context {
+	return context.WithValue(ctx, httpOptionsKey{}, opts)
+}
+
+// httpOptionsFrom returns the options set by WithHTTPOptions, or
+// NewDefaultHTTPOptions.
+func httpOptionsFrom(ctx context.Context) *HTTPOptions {
+	if opts, ok := ctx.Value(httpOptionsKey{}).(*HTTPOptions); ok && opts != nil {
+		return opts
+	}
+	return NewDefaultHTTPOptions()
+}
+
+// IsURL reports whether path is an http:// or https:// URL.
+func IsURL(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// FileExt returns the extension of path, or of the path of a URL, ignoring
+// any query.
+func FileExt(p string) string {
+	if !IsURL(p) {
+		return filepath.Ext(p)
+	}
+	u, err := url.Parse(p)
+	if err != nil {
+		return ""
+	}
+	return path.Ext(u.Path)
+}
+
+// displayURL drops the query and credentials of rawURL, which often hold
+// secrets, for use in errors.
+func displayURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid URL>"
+	}
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+	return u.String()
+}
+
+// do sends a request, retrying network errors, 429 and 5xx responses.
+func (o *HTTPOptions) do(ctx context.Context, method, rawURL string, rangeHeader string) (*http.Response, error) {
+	client := o.client()
+	backoff := o.Backoff
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL %s: %w", displayURL(rawURL), err)
+		}
+		headers, err := o.headersFor(req.URL)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range headers {
+			req.Header[name] = values
+		}
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp, err := client.Do(req)
+		switch {
+		case err != nil:
+			lastErr = err
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			resp.Body.Close()
+			lastErr = fmt.Errorf("%s %s: %s", method, displayURL(rawURL), resp.Status)
+		default:
+			return resp, nil
+		}
+		if attempt >= o.MaxRetries || ctx.Err() != nil {
+			return nil, fmt.Errorf("%s %s failed after %d attempts: %w", method, displayURL(rawURL), attempt+1, lastErr)
+		}
+		logging.FromContext(ctx).Warn("retrying HTTP request", "method", method, "url", displayURL(rawURL),
+			"attempt", attempt+1, "delay", backoff, "error", lastErr)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// httpFile reads a URL at random offsets with range requests, for formats
+// such as Parquet that read a footer and then selected column chunks.
+type httpFile struct {
+	ctx    context.Context
+	url    string
+	opts   *HTTPOptions
+	size   int64
+	offset int64
+}
+
+// openHTTPFile finds the size of the resource at rawURL and checks that the
+// server accepts range requests.
+func openHTTPFile(ctx context.Context, rawURL string) (*httpFile, error) {
+	f := &httpFile{ctx: ctx, url: rawURL, opts: httpOptionsFrom(ctx)}
+	// A one byte range request both checks range support and returns the
+	// size, and unlike HEAD is allowed by presigned GET URLs.
+	resp, err := f.opts.do(ctx, http.MethodGet, rawURL, "bytes=0-0")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		_, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/")
+		if f.size, err = strconv.ParseInt(total, 10, 64); !ok || err != nil {
+			return nil, fmt.Errorf("GET %s: unknown size in Content-Range %q", displayURL(rawURL), resp.Header.Get("Content-Range"))
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// An empty resource.
+	case http.StatusOK:
+		return nil, fmt.Errorf("GET %s: the server does not support range requests", displayURL(rawURL))
+	default:
+		return nil, fmt.Errorf("GET %s: %s", displayURL(rawURL), resp.Status)
+	}
+	return f, nil
+}
+
+// ReadAt reads len(p) bytes at off with a single range request.
+func (f *httpFile) ReadAt(p []byte, off int64) (int, error) {
+	if off >= f.size {
+		return 0, io.EOF
+	}
+	end := off + int64(len(p))
+	if end > f.size {
+		end = f.size
+	}
+	if end == off {
+		return 0, nil
+	}
+	resp, err := f.opts.do(f.ctx, http.MethodGet, f.url, fmt.Sprintf("bytes=%d-%d", off, end-1))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("GET %s: range request answered with %s", displayURL(f.url), resp.Status)
+	}
+	n, err := io.ReadFull(resp.Body, p[:end-off])
+	if err != nil {
+		return n, fmt.Errorf("GET %s: %w", displayURL(f.url), err)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *httpFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *httpFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Size returns the size of the resource.
+func (f *httpFile) Size() int64 {
+	return f.size
+}
+
+func (f *httpFile) Close() error {
+	return nil
+}
+
+// httpStream downloads a URL sequentially, for formats read front to back
+// such as CSV and JSON. A failed download is resumed where it stopped.
+type httpStream struct {
+	ctx     context.Context
+	url     string
+	opts    *HTTPOptions
+	body    io.ReadCloser
+	offset  int64
+	retries int
+}
+
+// openHTTPStream starts downloading rawURL.
+func openHTTPStream(ctx context.Context, rawURL string) (*httpStream, error) {
+	s := &httpStream{ctx: ctx, url: rawURL, opts: httpOptionsFrom(ctx)}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *httpStream) open() error {
+	var rangeHeader string
+	if s.offset > 0 {
+		rangeHeader = fmt.Sprintf("bytes=%d-", s.offset)
+	}
+	resp, err := s.opts.do(s.ctx, http.MethodGet, s.url, rangeHeader)
+	if err != nil {
+		return err
+	}
+	switch {
+	case s.offset == 0 && resp.StatusCode == http.StatusOK:
+	case s.offset > 0 && resp.StatusCode == http.StatusPartialContent:
+	default:
+		resp.Body.Close()
+		return fmt.Errorf("GET %s: %s", displayURL(s.url), resp.Status)
+	}
+	s.body = resp.Body
+	return nil
+}
+
+func (s *httpStream) Read(p []byte) (int, error) {
+	n, err := s.body.Read(p)
+	s.offset += int64(n)
+	if err == nil || errors.Is(err, io.EOF) || s.ctx.Err() != nil {
+		return n, err
+	}
+	if s.retries >= s.opts.MaxRetries {
+		return n, fmt.Errorf("GET %s: %w", displayURL(s.url), err)
+	}
+	s.retries++
+	logging.FromContext(s.ctx).Warn("resuming HTTP download", "url", displayURL(s.url), "offset", s.offset, "error", err)
+	s.body.Close()
+	if oerr := s.open(); oerr != nil {
+		return n, fmt.Errorf("GET %s: resuming after %v: %w", displayURL(s.url), err, oerr)
+	}
+	return n, nil
+}
+
+func (s *httpStream) Close() error {
+	return s.body.Close()
+}
+
+// randomAccessFile is a local file or URL opened for random access.
+type randomAccessFile interface {
+	io.ReadSeeker
+	io.ReaderAt
+	io.Closer
+}
+
+// openReaderAt opens the local file or URL at path for random access and
+// returns its size.
+func openReaderAt(ctx context.Context, path string) (randomAccessFile, int64, error) {
+	if IsURL(path) {
+		f, err := openHTTPFile(ctx, path)
+		if err != nil {
+			return nil, 0, err
+		}
+		return f, f.Size(), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// HTTPOptionsFromConfig returns NewDefaultHTTPOptions updated with the
+// workflow's http settings. Header values and bearer tokens have
+// environment variables expanded.
+func HTTPOptionsFromConfig(s config.HTTPSettings) (*HTTPOptions, error) {
+	opts := NewDefaultHTTPOptions()
+	for _, c := range s.Credentials {
+		creds := HTTPCredentials{Prefix: c.URLPrefix, Headers: http.Header{}, BearerToken: os.ExpandEnv(c.BearerToken)}
+		for name, value := range c.Headers {
+			creds.Headers.Set(name, os.ExpandEnv(value))
+		}
+		opts.Credentials = append(opts.Credentials, creds)
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if s.MaxRetries > 0 {
+		opts.MaxRetries = s.MaxRetries
+	}
+	if s.RetryBackoff != "" {
+		backoff, err := time.ParseDuration(s.RetryBackoff)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http retry_backoff: %w", err)
+		}
+		opts.Backoff = backoff
+	}
+	if s.Timeout != "" {
+		timeout, err := time.ParseDuration(s.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http timeout: %w", err)
+		}
+		opts.Client = &http.Client{Timeout: timeout}
+	}
+	return opts, nil
+}
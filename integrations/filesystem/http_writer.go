@@ -0,0 +1,358 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"github.com/arrowarc/arrowarc/pkg/retry"
+)
+
+// Body formats of an HTTPWriter.
+const (
+	// HTTPFormatIPC sends each batch as an Arrow IPC stream.
+	HTTPFormatIPC = "ipc"
+	// HTTPFormatNDJSON sends each batch as one JSON object per row.
+	HTTPFormatNDJSON = "ndjson"
+	// HTTPFormatCSV sends each batch as CSV, with a header row unless the
+	// CSV options leave it out.
+	HTTPFormatCSV = "csv"
+)
+
+// DefaultHTTPBatchRows is the number of rows an HTTPWriter sends per
+// request unless its options set another.
+const DefaultHTTPBatchRows = 10000
+
+// httpContentTypes maps body formats to their content type.
+var httpContentTypes = map[string]string{
+	HTTPFormatIPC:    "application/vnd.apache.arrow.stream",
+	HTTPFormatNDJSON: "application/x-ndjson",
+	HTTPFormatCSV:    "text/csv",
+}
+
+// HTTPWriteOptions configures an HTTPWriter.
+type HTTPWriteOptions struct {
+	// Format is the encoding of request bodies, HTTPFormatIPC by default.
+	Format string
+	// CSV configures the HTTPFormatCSV format.
+	CSV *CSVWriteOptions
+	// Method is POST unless set, e.g. to PUT.
+	Method string
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Headers are added to every request, e.g. for authentication.
+	Headers http.Header
+	// BearerToken, if set, is sent as an "Authorization: Bearer" header.
+	// It is never sent over plain http.
+	BearerToken string
+	// Gzip compresses request bodies, sent with Content-Encoding: gzip.
+	Gzip bool
+	// BatchRows is the most rows sent in one request; Write sends a
+	// request each time that many rows are buffered.
+	BatchRows int64
+	// Retry is the policy of failed requests, which retries network
+	// errors, 408, 429 and 5xx responses by default. Nil uses
+	// retry.Default.
+	Retry *retry.Policy
+	// Timeout bounds each attempt, unless zero.
+	Timeout time.Duration
+}
+
+// NewDefaultHTTPWriteOptions returns options sending Arrow IPC batches of
+// DefaultHTTPBatchRows rows.
+func NewDefaultHTTPWriteOptions() *HTTPWriteOptions {
+	return &HTTPWriteOptions{
+		Format:    HTTPFormatIPC,
+		BatchRows: DefaultHTTPBatchRows,
+	}
+}
+
+// HTTPWriter sends records to an HTTP endpoint, encoding the rows buffered
+// up to BatchRows as the body of each request. Close sends what remains. It
+// is a generic sink for services with no integration of their own.
+type HTTPWriter struct {
+	ctx     context.Context
+	url     string
+	schema  *arrow.Schema
+	opts    HTTPWriteOptions
+	csv     CSVWriteOptions
+	codec   *timestampCodec
+	pending []arrow.Record
+	rows    int64
+	batches int64
+	closed  bool
+}
+
+// NewHTTPWriter returns a writer sending records of schema to rawURL, an
+// http:// or https:// URL.
+func NewHTTPWriter(ctx context.Context, rawURL string, schema *arrow.Schema, opts *HTTPWriteOptions) (*HTTPWriter, error) {
+	if opts == nil {
+		opts = NewDefaultHTTPWriteOptions()
+	}
+	if !IsURL(rawURL) {
+		return nil, fmt.Errorf("http upload: %s is not an http:// or https:// URL", displayURL(rawURL))
+	}
+	if opts.BearerToken != "" && strings.HasPrefix(strings.ToLower(rawURL), "http://") {
+		return nil, fmt.Errorf("http upload: refusing to send a bearer token over plain http to %s", displayURL(rawURL))
+	}
+	w := &HTTPWriter{ctx: ctx, url: rawURL, schema: schema, opts: *opts}
+	if w.opts.Format == "" {
+		w.opts.Format = HTTPFormatIPC
+	}
+	w.opts.Format = strings.ToLower(w.opts.Format)
+	if _, ok := httpContentTypes[w.opts.Format]; !ok {
+		return nil, fmt.Errorf("http upload: unknown format %q", opts.Format)
+	}
+	if w.opts.Method == "" {
+		w.opts.Method = http.MethodPost
+	}
+	if w.opts.BatchRows <= 0 {
+		w.opts.BatchRows = DefaultHTTPBatchRows
+	}
+	if w.opts.Format == HTTPFormatCSV {
+		var err error
+		if w.csv, w.codec, err = csvWriterOptions(opts.CSV); err != nil {
+			return nil, fmt.Errorf("http upload: %w", err)
+		}
+	}
+	if _, err := http.NewRequestWithContext(ctx, w.opts.Method, rawURL, nil); err != nil {
+		return nil, fmt.Errorf("http upload: invalid request to %s: %w", displayURL(rawURL), err)
+	}
+	return w, nil
+}
+
+// Write buffers the rows of record, sending a request each time BatchRows
+// rows are buffered.
+func (w *HTTPWriter) Write(record arrow.Record) error {
+	if w.closed {
+		return errors.New("http upload: writer is closed")
+	}
+	if !record.Schema().Equal(w.schema) {
+		return fmt.Errorf("http upload: record schema does not match writer schema")
+	}
+	for offset := int64(0); offset < record.NumRows(); {
+		n := min(record.NumRows()-offset, w.opts.BatchRows-w.rows)
+		w.pending = append(w.pending, record.NewSlice(offset, offset+n))
+		w.rows += n
+		offset += n
+		if w.rows >= w.opts.BatchRows {
+			if err := w.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush sends the buffered rows as one request.
+func (w *HTTPWriter) flush() error {
+	defer func() {
+		for _, record := range w.pending {
+			record.Release()
+		}
+		w.pending, w.rows = nil, 0
+	}()
+	body, err := w.encode()
+	if err != nil {
+		return fmt.Errorf("http upload: failed to encode batch %d: %w", w.batches+1, err)
+	}
+	if err := w.send(body); err != nil {
+		return fmt.Errorf("http upload: batch %d of %d rows to %s: %w", w.batches+1, w.rows, displayURL(w.url), err)
+	}
+	w.batches++
+	logging.FromContext(w.ctx).Debug("http upload sent batch", "url", displayURL(w.url), "batch", w.batches, "rows", w.rows, "bytes", len(body))
+	return nil
+}
+
+// encode returns the buffered rows in the body format, compressed if asked.
+func (w *HTTPWriter) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	var out io.Writer = &buf
+	var gz *gzip.Writer
+	if w.opts.Gzip {
+		gz = gzip.NewWriter(&buf)
+		out = gz
+	}
+	var err error
+	switch w.opts.Format {
+	case HTTPFormatIPC:
+		err = w.encodeIPC(out)
+	case HTTPFormatNDJSON:
+		err = w.encodeNDJSON(out)
+	case HTTPFormatCSV:
+		err = w.encodeCSV(out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (w *HTTPWriter) encodeIPC(out io.Writer) error {
+	iw := ipc.NewWriter(out, ipc.WithSchema(w.schema))
+	for _, record := range w.pending {
+		if err := iw.Write(record); err != nil {
+			iw.Close()
+			return err
+		}
+	}
+	return iw.Close()
+}
+
+func (w *HTTPWriter) encodeNDJSON(out io.Writer) error {
+	enc, err := newJSONRowEncoder(JSONModeNDJSON, "")
+	if err != nil {
+		return err
+	}
+	for _, record := range w.pending {
+		if err := enc.write(out, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *HTTPWriter) encodeCSV(out io.Writer) error {
+	cw, err := newCSVWriter(nopWriteCloser{out}, nil, w.schema, w.csv, w.codec)
+	if err != nil {
+		return err
+	}
+	for _, record := range w.pending {
+		if err := cw.Write(record); err != nil {
+			cw.Close()
+			return err
+		}
+	}
+	return cw.Close()
+}
+
+// nopWriteCloser adds a Close doing nothing to a writer.
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// send sends body, retrying as the policy says.
+func (w *HTTPWriter) send(body []byte) error {
+	client := w.opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return w.opts.Retry.OrRetryable(isHTTPUploadRetryable).Do(w.ctx, func(ctx context.Context) error {
+		if w.opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, w.opts.Timeout)
+			defer cancel()
+		}
+		req, err := http.NewRequestWithContext(ctx, w.opts.Method, w.url, bytes.NewReader(body))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		for name, values := range w.opts.Headers {
+			req.Header[name] = values
+		}
+		if w.opts.BearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+w.opts.BearerToken)
+		}
+		req.Header.Set("Content-Type", httpContentTypes[w.opts.Format])
+		if w.opts.Gzip {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			return nil
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &HTTPStatusError{Code: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(msg))}
+	})
+}
+
+// HTTPStatusError is returned when an upload is answered with a status
+// other than 2xx.
+type HTTPStatusError struct {
+	Code   int
+	Status string
+	// Body is the start of the response body.
+	Body string
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.Body == "" {
+		return e.Status
+	}
+	return e.Status + ": " + e.Body
+}
+
+// isHTTPUploadRetryable retries what retry.IsRetryable does, and the
+// statuses 408, 429 and 5xx.
+func isHTTPUploadRetryable(err error) bool {
+	var status *HTTPStatusError
+	if errors.As(err, &status) {
+		return status.Code == http.StatusRequestTimeout || status.Code == http.StatusTooManyRequests || status.Code >= 500
+	}
+	return retry.IsRetryable(err)
+}
+
+// Batches returns the number of batches sent.
+func (w *HTTPWriter) Batches() int64 {
+	return w.batches
+}
+
+// Close sends the buffered rows.
+func (w *HTTPWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.rows == 0 {
+		return nil
+	}
+	return w.flush()
+}
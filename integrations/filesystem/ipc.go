@@ -33,7 +33,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/arrio"
@@ -56,14 +55,17 @@ type SchemaWriter interface {
 
 // IPCRecordReader implements SchemaReader for reading records from IPC files.
 type IPCRecordReader struct {
+	ctx    context.Context
 	reader *ipc.Reader
-	file   *os.File
+	file   io.ReadCloser
 	alloc  memory.Allocator
+	read   int // record batches read, including the one being read
 }
 
-// NewIPCRecordReader creates a new reader for reading records from an IPC file.
+// NewIPCRecordReader creates a new reader for reading records from an IPC
+// stream file, a URL, or standard input when filePath is StdioPath.
 func NewIPCRecordReader(ctx context.Context, filePath string) (SchemaReader, error) {
-	file, err := os.Open(filePath)
+	file, err := OpenFile(ctx, filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open IPC file: %w", err)
 	}
@@ -79,11 +81,15 @@ func NewIPCRecordReader(ctx context.Context, filePath string) (SchemaReader, err
 		return nil, fmt.Errorf("failed to create IPC reader: %w", err)
 	}
 
-	return &IPCRecordReader{reader: reader, file: file, alloc: alloc}, nil
+	return &IPCRecordReader{ctx: ctx, reader: reader, file: file, alloc: alloc}, nil
 }
 
 // Read reads the next record from the IPC file.
 func (r *IPCRecordReader) Read() (arrow.Record, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.read++
 	if !r.reader.Next() {
 		if err := r.reader.Err(); err != nil && err != io.EOF {
 			return nil, fmt.Errorf("error reading IPC file: %w", err)
@@ -96,6 +102,11 @@ func (r *IPCRecordReader) Read() (arrow.Record, error) {
 	return record, nil
 }
 
+// Position returns the index of the record batch last read in the stream.
+func (r *IPCRecordReader) Position() string {
+	return fmt.Sprintf("record batch %d", r.read-1)
+}
+
 // Schema returns the schema of the records being read from the IPC file.
 func (r *IPCRecordReader) Schema() *arrow.Schema {
 	return r.reader.Schema()
@@ -113,22 +124,76 @@ func (r *IPCRecordReader) Close() error {
 // IPCRecordWriter implements SchemaWriter for writing records to IPC files.
 type IPCRecordWriter struct {
 	writer *ipc.Writer
-	file   *os.File
+	file   io.WriteCloser
 	schema *arrow.Schema
 	alloc  memory.Allocator
 }
 
+// IPCCompression is the codec used for the record batch buffers of IPC and
+// Feather files.
+type IPCCompression string
+
+const (
+	IPCCompressionNone IPCCompression = "none"
+	IPCCompressionLZ4  IPCCompression = "lz4"
+	IPCCompressionZstd IPCCompression = "zstd"
+)
+
+// IPCWriteOptions configures IPC stream and Feather writers.
+type IPCWriteOptions struct {
+	Compression         IPCCompression
+	CompressConcurrency int
+}
+
+// NewDefaultIPCWriteOptions returns Zstd compression on two goroutines.
+func NewDefaultIPCWriteOptions() *IPCWriteOptions {
+	return &IPCWriteOptions{Compression: IPCCompressionZstd, CompressConcurrency: 2}
+}
+
+// ipcOptions translates opts into ipc writer options.
+func (o *IPCWriteOptions) ipcOptions(schema *arrow.Schema, alloc memory.Allocator) ([]ipc.Option, error) {
+	if o == nil {
+		o = NewDefaultIPCWriteOptions()
+	}
+	opts := []ipc.Option{ipc.WithSchema(schema), ipc.WithAllocator(alloc)}
+	switch o.Compression {
+	case IPCCompressionNone, "":
+	case IPCCompressionLZ4:
+		opts = append(opts, ipc.WithLZ4())
+	case IPCCompressionZstd:
+		opts = append(opts, ipc.WithZstd())
+	default:
+		return nil, fmt.Errorf("unsupported IPC compression %q", o.Compression)
+	}
+	if o.CompressConcurrency > 0 {
+		opts = append(opts, ipc.WithCompressConcurrency(o.CompressConcurrency))
+	}
+	return opts, nil
+}
+
 // NewIPCRecordWriter creates a new writer for writing records to an IPC file.
 func NewIPCRecordWriter(ctx context.Context, filePath string, schema *arrow.Schema) (SchemaWriter, error) {
-	file, err := os.Create(filePath)
+	return NewIPCRecordWriterWithOptions(ctx, filePath, schema, nil)
+}
+
+// NewIPCRecordWriterWithOptions creates a writer for an IPC stream file, or
+// for standard output when filePath is StdioPath, compressed as set by opts.
+// A nil opts uses NewDefaultIPCWriteOptions.
+func NewIPCRecordWriterWithOptions(ctx context.Context, filePath string, schema *arrow.Schema, opts *IPCWriteOptions) (*IPCRecordWriter, error) {
+	alloc := memoryPool.GetAllocator()
+	ipcOpts, err := opts.ipcOptions(schema, alloc)
 	if err != nil {
-		return nil, fmt.Errorf("could not create IPC file: %w", err)
+		memoryPool.PutAllocator(alloc)
+		return nil, err
 	}
 
-	alloc := memoryPool.GetAllocator()
-	opts := []ipc.Option{ipc.WithSchema(schema), ipc.WithAllocator(alloc), ipc.WithCompressConcurrency(2), ipc.WithZstd()}
+	file, err := createFile(filePath)
+	if err != nil {
+		memoryPool.PutAllocator(alloc)
+		return nil, fmt.Errorf("could not create IPC file: %w", err)
+	}
 
-	writer := ipc.NewWriter(file, opts...)
+	writer := ipc.NewWriter(file, ipcOpts...)
 
 	return &IPCRecordWriter{writer: writer, file: file, alloc: alloc, schema: schema}, nil
 }
@@ -30,51 +30,155 @@
 package integrations
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
-	"os"
+	"slices"
+	"strings"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/geo"
+	"github.com/arrowarc/arrowarc/pkg/limit"
 	"github.com/goccy/go-json"
+	"github.com/klauspost/compress/zstd"
 )
 
 // JSONReader reads records from a JSON file and implements the Reader interface.
 type JSONReader struct {
 	ctx        context.Context
-	file       *os.File
+	file       io.ReadCloser
 	jsonReader *array.JSONReader
 	schema     *arrow.Schema
 	alloc      memory.Allocator
+	window     *limit.Window
+	timestamps *timestampCodec
+	parsed     []int // timestamp columns read as strings, to parse
+	raw        []int // arrow.json columns read as raw JSON text
 }
 
 // JSONWriter writes records to a JSON file and implements the Writer interface.
 type JSONWriter struct {
-	file    *os.File
-	encoder *json.Encoder
-	alloc   memory.Allocator
+	file       io.WriteCloser
+	compressor io.WriteCloser
+	buf        *bufio.Writer
+	encoder    *json.Encoder
+	rows       *jsonRowEncoder
+	alloc      memory.Allocator
+	lines      bool
+	wrote      bool
+	timestamps *timestampCodec
 }
 
 // JSONReadOptions defines options for reading JSON files.
 type JSONReadOptions struct {
 	ChunkSize int
+	// Offset skips the first rows, and Limit stops reading after that many
+	// rows, zero meaning no limit.
+	Offset int64
+	Limit  int64
+	// Timestamps controls how timestamp columns are read. The reader's
+	// schema has the unit and time zone they are read with. Timestamps
+	// are read from JSON numbers with TimestampEpoch, and from strings
+	// otherwise.
+	Timestamps TimestampOptions
+}
+
+// JSONMode is the layout of a JSON output file.
+type JSONMode string
+
+const (
+	// JSONModeArray writes all rows as one JSON array.
+	JSONModeArray JSONMode = "array"
+	// JSONModeNDJSON writes one JSON object per line.
+	JSONModeNDJSON JSONMode = "ndjson"
+	// JSONModePretty writes all rows as one indented JSON array.
+	JSONModePretty JSONMode = "pretty"
+)
+
+// ParseJSONMode parses a JSON output mode name.
+func ParseJSONMode(s string) (JSONMode, error) {
+	switch mode := JSONMode(strings.ToLower(s)); mode {
+	case JSONModeArray, JSONModeNDJSON, JSONModePretty:
+		return mode, nil
+	case "lines", "jsonl":
+		return JSONModeNDJSON, nil
+	}
+	return "", fmt.Errorf("unknown JSON output mode %q: expected array, ndjson or pretty", s)
+}
+
+// JSONWriteOptions defines options for writing JSON files.
+// Output is compressed with gzip or zstd when the file name ends in .gz or .zst.
+type JSONWriteOptions struct {
+	// Lines writes one JSON object per line instead of a JSON array of
+	// row objects per record.
+	Lines bool
+	// Mode, when set, takes precedence over Lines. Rows are then written
+	// with their fields in schema order, structs and maps as JSON objects
+	// and lists as JSON arrays.
+	Mode JSONMode
+	// NullValue is the JSON text written for null values with Mode, such
+	// as `""` or `"N/A"`. It defaults to null.
+	NullValue string
+	// Gzip compresses the output with gzip whatever the file name, such
+	// as when writing to standard output.
+	Gzip bool
+	// Timestamps controls how timestamps are written. TimestampEpoch
+	// writes them as JSON numbers.
+	Timestamps TimestampOptions
 }
 
-// NewJSONReader creates a new reader for reading records from a JSON file.
+// NewJSONReader creates a new reader for reading records from a
+// newline-delimited JSON file, a URL, or standard input when filePath is
+// StdioPath.
 func NewJSONReader(ctx context.Context, filePath string, schema *arrow.Schema, opts *JSONReadOptions) (*JSONReader, error) {
+	codec, err := opts.Timestamps.codec()
+	if err != nil {
+		return nil, err
+	}
+	readSchema := schema
+	var parsed []int
+	if codec != nil {
+		schema = codec.schema(schema)
+		readSchema = schema
+		if codec.layout() || (codec.loc != nil && codec.format != TimestampEpoch) {
+			// arrow parses RFC 3339 only, and as UTC when there is no
+			// offset, so read the timestamps as strings and parse them
+			// after.
+			fields := schema.Fields()
+			for i, f := range fields {
+				if _, ok := f.Type.(*arrow.TimestampType); ok {
+					fields[i].Type = arrow.BinaryTypes.String
+					parsed = append(parsed, i)
+				}
+			}
+			md := schema.Metadata()
+			readSchema = arrow.NewSchema(fields, &md)
+		}
+	}
+	// arrow.json columns are written as JSON values, which arrow reads
+	// from JSON strings only.
+	readSchema, raw := rawJSONSchema(readSchema)
+	var window *limit.Window
+	if opts.Offset != 0 || opts.Limit != 0 {
+		if window, err = limit.NewWindow(opts.Offset, opts.Limit); err != nil {
+			return nil, err
+		}
+	}
 	alloc := pool.GetAllocator()
 
-	file, err := os.Open(filePath)
+	file, err := OpenFile(ctx, filePath)
 	if err != nil {
 		pool.PutAllocator(alloc)
 		return nil, fmt.Errorf("failed to open JSON file: %w", err)
 	}
 
-	jsonReader := array.NewJSONReader(file, schema, array.WithChunk(opts.ChunkSize))
+	jsonReader := array.NewJSONReader(file, readSchema, array.WithChunk(opts.ChunkSize))
 
 	return &JSONReader{
 		ctx:        ctx,
@@ -82,11 +186,22 @@ func NewJSONReader(ctx context.Context, filePath string, schema *arrow.Schema, o
 		jsonReader: jsonReader,
 		schema:     schema,
 		alloc:      alloc,
+		window:     window,
+		timestamps: codec,
+		parsed:     parsed,
+		raw:        raw,
 	}, nil
 }
 
 // Read reads the next record from the JSON file.
 func (r *JSONReader) Read() (arrow.Record, error) {
+	if r.window != nil {
+		return r.window.Read(r.read)
+	}
+	return r.read()
+}
+
+func (r *JSONReader) read() (arrow.Record, error) {
 	select {
 	case <-r.ctx.Done():
 		return nil, r.ctx.Err()
@@ -101,10 +216,39 @@ func (r *JSONReader) Read() (arrow.Record, error) {
 	}
 
 	record := r.jsonReader.Record()
-	if record != nil {
+	if record == nil {
+		return nil, nil
+	}
+	if len(r.parsed) == 0 && len(r.raw) == 0 {
 		record.Retain()
+		return record, nil
+	}
+	cols := slices.Clone(record.Columns())
+	for _, i := range r.raw {
+		cols[i] = jsonArray(cols[i])
+	}
+	defer func() {
+		for _, i := range r.raw {
+			cols[i].Release()
+		}
+	}()
+	for _, i := range r.parsed {
+		col, err := r.timestamps.parseColumn(r.alloc, cols[i].(*array.String), r.schema.Field(i).Type.(*arrow.TimestampType))
+		if err != nil {
+			for _, j := range r.parsed {
+				if j < i {
+					cols[j].Release()
+				}
+			}
+			return nil, fmt.Errorf("error reading JSON record: column %s: %w", r.schema.Field(i).Name, err)
+		}
+		cols[i] = col
+	}
+	out := array.NewRecord(r.schema, cols, record.NumRows())
+	for _, i := range r.parsed {
+		cols[i].Release()
 	}
-	return record, nil
+	return out, nil
 }
 
 // Schema returns the schema of the records being read from the JSON file.
@@ -119,38 +263,164 @@ func (r *JSONReader) Close() error {
 	return r.file.Close()
 }
 
-// NewJSONWriter creates a new writer for writing records to a JSON file.
+// NewJSONWriter creates a new writer for writing records to a JSON file,
+// or to standard output when filePath is StdioPath. Each record is written
+// as a JSON array of row objects.
 func NewJSONWriter(ctx context.Context, filePath string) (*JSONWriter, error) {
+	return NewJSONWriterWithOptions(ctx, filePath, nil)
+}
+
+// NewJSONWriterWithOptions is like NewJSONWriter, with the given options.
+func NewJSONWriterWithOptions(ctx context.Context, filePath string, opts *JSONWriteOptions) (*JSONWriter, error) {
+	if opts == nil {
+		opts = &JSONWriteOptions{}
+	}
+	codec, err := opts.Timestamps.codec()
+	if err != nil {
+		return nil, err
+	}
+	var rows *jsonRowEncoder
+	if opts.Mode != "" {
+		if rows, err = newJSONRowEncoder(opts.Mode, opts.NullValue); err != nil {
+			return nil, err
+		}
+	} else if opts.NullValue != "" {
+		return nil, fmt.Errorf("a JSON null value needs an output mode")
+	}
 	alloc := pool.GetAllocator()
 
-	file, err := os.Create(filePath)
+	file, err := createFile(filePath)
 	if err != nil {
 		pool.PutAllocator(alloc)
 		return nil, fmt.Errorf("failed to create JSON file: %w", err)
 	}
 
-	encoder := json.NewEncoder(file)
+	var out io.Writer = file
+	var compressor io.WriteCloser
+	switch {
+	case opts.Gzip || strings.HasSuffix(filePath, ".gz"):
+		compressor = gzip.NewWriter(file)
+	case strings.HasSuffix(filePath, ".zst"):
+		compressor, err = zstd.NewWriter(file)
+		if err != nil {
+			file.Close()
+			pool.PutAllocator(alloc)
+			return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+	}
+	if compressor != nil {
+		out = compressor
+	}
+	buf := bufio.NewWriter(out)
 
 	return &JSONWriter{
-		file:    file,
-		encoder: encoder,
-		alloc:   alloc,
+		file:       file,
+		compressor: compressor,
+		buf:        buf,
+		encoder:    json.NewEncoder(buf),
+		rows:       rows,
+		alloc:      alloc,
+		lines:      opts.Lines,
+		timestamps: codec,
 	}, nil
 }
 
+// NewNDJSONWriter is like NewJSONWriter but writes one JSON object per line.
+func NewNDJSONWriter(ctx context.Context, filePath string) (*JSONWriter, error) {
+	return NewJSONWriterWithOptions(ctx, filePath, &JSONWriteOptions{Lines: true})
+}
+
 // Write writes a record to the JSON file.
 func (w *JSONWriter) Write(record arrow.Record) error {
+	// Geometries are written as WKT.
+	record, err := geo.WKTRecord(w.alloc, record)
+	if err != nil {
+		return fmt.Errorf("error writing JSON record: %w", err)
+	}
+	defer record.Release()
+	if w.timestamps != nil {
+		formatted, err := w.formatTimestamps(record)
+		if err != nil {
+			return fmt.Errorf("error writing JSON record: %w", err)
+		}
+		defer formatted.Release()
+		record = formatted
+	}
+	if w.rows != nil {
+		if err := w.rows.write(w.buf, record); err != nil {
+			return fmt.Errorf("error writing JSON record: %w", err)
+		}
+		return nil
+	}
+	if w.lines {
+		if err := array.RecordToJSON(record, w.buf); err != nil {
+			return fmt.Errorf("error writing JSON record: %w", err)
+		}
+		return nil
+	}
 	structArray := array.RecordToStructArray(record)
+	defer structArray.Release()
 	if err := w.encoder.Encode(structArray); err != nil {
 		return fmt.Errorf("error writing JSON record: %w", err)
 	}
+	w.wrote = true
 	return nil
 }
 
-// Close closes the JSON writer.
+// formatTimestamps returns record with its timestamp columns formatted.
+func (w *JSONWriter) formatTimestamps(record arrow.Record) (arrow.Record, error) {
+	fields := record.Schema().Fields()
+	cols := slices.Clone(record.Columns())
+	var formatted []arrow.Array
+	defer func() {
+		for _, col := range formatted {
+			col.Release()
+		}
+	}()
+	for i, col := range cols {
+		ts, ok := col.(*array.Timestamp)
+		if !ok {
+			continue
+		}
+		out, err := w.timestamps.formatColumn(w.alloc, ts)
+		if err != nil {
+			return nil, err
+		}
+		formatted = append(formatted, out)
+		cols[i] = out
+		fields[i].Type = out.DataType()
+	}
+	md := record.Schema().Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &md), cols, record.NumRows()), nil
+}
+
+// Close ends the JSON array of the array and pretty modes, and flushes and
+// closes the JSON writer. Without records, a writer of JSON arrays writes
+// an empty one.
 func (w *JSONWriter) Close() error {
 	defer pool.PutAllocator(w.alloc)
-	return w.file.Close()
+	var err error
+	switch {
+	case w.rows != nil:
+		err = w.rows.close(w.buf)
+	case !w.lines && !w.wrote:
+		_, err = w.buf.WriteString("[]\n")
+	}
+	if ferr := w.buf.Flush(); err == nil {
+		err = ferr
+	}
+	if w.compressor != nil {
+		if cerr := w.compressor.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to close JSON writer: %w", err)
+	}
+	return nil
 }
 
 // Marshal safely marshals the provided value to JSON.
@@ -0,0 +1,196 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// InferJSONSchema infers an Arrow schema from a slice of JSON objects.
+// For each field, if conflicting types are encountered, the type falls back to
+// arrow.json, read as the text of each value.
+func InferJSONSchema(samples []map[string]interface{}) (*arrow.Schema, error) {
+	fieldTypes := make(map[string]arrow.DataType)
+	for _, obj := range samples {
+		for k, v := range obj {
+			inferred, err := inferType(v)
+			if err != nil {
+				return nil, err
+			}
+			fieldTypes[k] = mergeType(fieldTypes[k], inferred)
+		}
+	}
+	var fields []arrow.Field
+	for name, dt := range fieldTypes {
+		fields = append(fields, arrow.Field{Name: name, Type: resolveType(dt), Nullable: true})
+	}
+	// Sort fields alphabetically for consistency.
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// inferType determines an Arrow data type from a JSON value.
+// JSON numbers become float64; booleans and strings are mapped to their Arrow equivalents.
+// Arrays become lists and objects structs, so that nested values read back
+// as nested columns. Null has the null type until other values tell its type.
+func inferType(v interface{}) (arrow.DataType, error) {
+	switch v := v.(type) {
+	case nil:
+		return arrow.Null, nil
+	case bool:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case float64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case string:
+		return arrow.BinaryTypes.String, nil
+	case []interface{}:
+		var elem arrow.DataType = arrow.Null
+		for _, e := range v {
+			inferred, err := inferType(e)
+			if err != nil {
+				return nil, err
+			}
+			elem = mergeType(elem, inferred)
+		}
+		return arrow.ListOf(elem), nil
+	case map[string]interface{}:
+		fields := make([]arrow.Field, 0, len(v))
+		for name, e := range v {
+			inferred, err := inferType(e)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, arrow.Field{Name: name, Type: inferred, Nullable: true})
+		}
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].Name < fields[j].Name
+		})
+		return arrow.StructOf(fields...), nil
+	default:
+		return arrow.BinaryTypes.String, nil
+	}
+}
+
+// mergeType returns the type holding values of types a and b, either of
+// which may be nil or null for unknown: the union of the fields of two
+// structs, lists of the merged elements, and otherwise arrow.json when they
+// conflict.
+func mergeType(a, b arrow.DataType) arrow.DataType {
+	switch {
+	case a == nil || a.ID() == arrow.NULL:
+		return b
+	case b == nil || b.ID() == arrow.NULL:
+		return a
+	case a.ID() != b.ID():
+		return jsonExtension()
+	}
+	switch a := a.(type) {
+	case *arrow.ListType:
+		return arrow.ListOf(mergeType(a.Elem(), b.(*arrow.ListType).Elem()))
+	case *arrow.StructType:
+		fields := a.Fields()
+		for _, f := range b.(*arrow.StructType).Fields() {
+			if i, ok := a.FieldIdx(f.Name); ok {
+				fields[i].Type = mergeType(fields[i].Type, f.Type)
+			} else {
+				fields = append(fields, f)
+			}
+		}
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].Name < fields[j].Name
+		})
+		return arrow.StructOf(fields...)
+	}
+	return a
+}
+
+// resolveType replaces the null types left in dt, of values only ever
+// null or empty arrays, with string.
+func resolveType(dt arrow.DataType) arrow.DataType {
+	switch dt := dt.(type) {
+	case *arrow.NullType:
+		return arrow.BinaryTypes.String
+	case *arrow.ListType:
+		return arrow.ListOf(resolveType(dt.Elem()))
+	case *arrow.StructType:
+		fields := dt.Fields()
+		for i := range fields {
+			fields[i].Type = resolveType(fields[i].Type)
+		}
+		return arrow.StructOf(fields...)
+	}
+	return dt
+}
+
+// DecodeJSONObjects builds a record of schema from rows, each a JSON
+// object. Fields missing from the schema are dropped, and arrow.json
+// columns hold the text of their values.
+func DecodeJSONObjects(rows [][]byte, schema *arrow.Schema, alloc memory.Allocator) (arrow.Record, error) {
+	var buf bytes.Buffer
+	for _, row := range rows {
+		buf.Write(row)
+		buf.WriteByte('\n')
+	}
+	readSchema, raw := rawJSONSchema(schema)
+	reader := array.NewJSONReader(&buf, readSchema, array.WithAllocator(alloc), array.WithChunk(-1))
+	defer reader.Release()
+	if !reader.Next() {
+		err := reader.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("failed to decode JSON objects: %w", err)
+	}
+	record := reader.Record()
+	if len(raw) == 0 {
+		record.Retain()
+		return record, nil
+	}
+	cols := slices.Clone(record.Columns())
+	for _, i := range raw {
+		cols[i] = jsonArray(cols[i])
+	}
+	defer func() {
+		for _, i := range raw {
+			cols[i].Release()
+		}
+	}()
+	return array.NewRecord(schema, cols, record.NumRows()), nil
+}
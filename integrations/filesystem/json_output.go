@@ -0,0 +1,203 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/goccy/go-json"
+)
+
+// jsonRowEncoder writes rows in a JSONMode.
+type jsonRowEncoder struct {
+	mode   JSONMode
+	null   []byte
+	rows   int64
+	row    []byte
+	indent bytes.Buffer
+}
+
+func newJSONRowEncoder(mode JSONMode, nullValue string) (*jsonRowEncoder, error) {
+	if _, err := ParseJSONMode(string(mode)); err != nil {
+		return nil, err
+	}
+	null := []byte("null")
+	if nullValue != "" {
+		if !json.Valid([]byte(nullValue)) {
+			return nil, fmt.Errorf("JSON null value %q is not valid JSON", nullValue)
+		}
+		null = []byte(nullValue)
+	}
+	return &jsonRowEncoder{mode: mode, null: null}, nil
+}
+
+// write writes the rows of record.
+func (e *jsonRowEncoder) write(w io.Writer, record arrow.Record) error {
+	fields := record.Schema().Fields()
+	for i := 0; i < int(record.NumRows()); i++ {
+		row := append(e.row[:0], '{')
+		for j, f := range fields {
+			if j > 0 {
+				row = append(row, ',')
+			}
+			row = appendJSONString(row, f.Name)
+			row = append(row, ':')
+			var err error
+			if row, err = e.appendValue(row, record.Column(j), i); err != nil {
+				return fmt.Errorf("row %d, column %q: %w", i, f.Name, err)
+			}
+		}
+		e.row = append(row, '}')
+		if err := e.writeRow(w, e.row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *jsonRowEncoder) writeRow(w io.Writer, row []byte) error {
+	var sep string
+	switch {
+	case e.mode == JSONModeNDJSON:
+	case e.rows == 0 && e.mode == JSONModePretty:
+		sep = "[\n  "
+	case e.rows == 0:
+		sep = "["
+	case e.mode == JSONModePretty:
+		sep = ",\n  "
+	default:
+		sep = ","
+	}
+	e.rows++
+	if _, err := io.WriteString(w, sep); err != nil {
+		return err
+	}
+	if e.mode == JSONModePretty {
+		e.indent.Reset()
+		if err := json.Indent(&e.indent, row, "  ", "  "); err != nil {
+			return err
+		}
+		row = e.indent.Bytes()
+	}
+	if _, err := w.Write(row); err != nil {
+		return err
+	}
+	if e.mode == JSONModeNDJSON {
+		_, err := io.WriteString(w, "\n")
+		return err
+	}
+	return nil
+}
+
+// close ends the JSON array of the array and pretty modes.
+func (e *jsonRowEncoder) close(w io.Writer) error {
+	var end string
+	switch {
+	case e.mode == JSONModeNDJSON:
+		return nil
+	case e.rows == 0:
+		end = "[]\n"
+	case e.mode == JSONModePretty:
+		end = "\n]\n"
+	default:
+		end = "]\n"
+	}
+	_, err := io.WriteString(w, end)
+	return err
+}
+
+// appendValue appends the JSON text of row i of arr. Map keys that are
+// not strings are written as their string form.
+func (e *jsonRowEncoder) appendValue(dst []byte, arr arrow.Array, i int) ([]byte, error) {
+	if arr.IsNull(i) {
+		return append(dst, e.null...), nil
+	}
+	var err error
+	switch arr := arr.(type) {
+	case *array.Struct:
+		st := arr.DataType().(*arrow.StructType)
+		dst = append(dst, '{')
+		for j, f := range st.Fields() {
+			if j > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSONString(dst, f.Name)
+			dst = append(dst, ':')
+			if dst, err = e.appendValue(dst, arr.Field(j), i); err != nil {
+				return nil, err
+			}
+		}
+		return append(dst, '}'), nil
+	case *array.Map:
+		keys, items := arr.Keys(), arr.Items()
+		start, end := arr.ValueOffsets(i)
+		dst = append(dst, '{')
+		for j := int(start); j < int(end); j++ {
+			if j > int(start) {
+				dst = append(dst, ',')
+			}
+			dst = appendJSONString(dst, keys.ValueStr(j))
+			dst = append(dst, ':')
+			if dst, err = e.appendValue(dst, items, j); err != nil {
+				return nil, err
+			}
+		}
+		return append(dst, '}'), nil
+	case array.ListLike:
+		values := arr.ListValues()
+		start, end := arr.ValueOffsets(i)
+		dst = append(dst, '[')
+		for j := int(start); j < int(end); j++ {
+			if j > int(start) {
+				dst = append(dst, ',')
+			}
+			if dst, err = e.appendValue(dst, values, j); err != nil {
+				return nil, err
+			}
+		}
+		return append(dst, ']'), nil
+	case *array.Dictionary:
+		return e.appendValue(dst, arr.Dictionary(), arr.GetValueIndex(i))
+	}
+	b, err := json.Marshal(arr.GetOneForMarshal(i))
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, b...), nil
+}
+
+func appendJSONString(dst []byte, s string) []byte {
+	b, _ := json.Marshal(s)
+	return append(dst, b...)
+}
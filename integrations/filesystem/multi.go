@@ -0,0 +1,448 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+)
+
+// FileReader reads the records of one file.
+type FileReader interface {
+	Read() (arrow.Record, error)
+	Schema() *arrow.Schema
+	Close() error
+}
+
+// FileReaderFactory opens a FileReader for the file at path.
+type FileReaderFactory func(ctx context.Context, path string) (FileReader, error)
+
+// ExpandInputPaths resolves an input argument to the files it names: every
+// file with one of the given extensions under a directory, the matches of a
+// glob pattern, or a single file. Paths are returned sorted. StdioPath and
+// URLs are returned as is.
+func ExpandInputPaths(input string, extensions ...string) ([]string, error) {
+	if IsStdio(input) || IsURL(input) {
+		return []string{input}, nil
+	}
+	hasExt := func(path string) bool {
+		if len(extensions) == 0 {
+			return true
+		}
+		ext := strings.ToLower(filepath.Ext(strings.TrimSuffix(strings.TrimSuffix(path, ".gz"), ".zst")))
+		return slices.Contains(extensions, ext)
+	}
+
+	info, statErr := os.Stat(input)
+	switch {
+	case statErr == nil && info.IsDir():
+		var paths []string
+		err := filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			name := d.Name()
+			if path != input && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() && hasExt(path) {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", input, err)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no %s files found in %s", strings.Join(extensions, "/"), input)
+		}
+		return paths, nil
+	case statErr == nil:
+		return []string{input}, nil
+	}
+
+	if !strings.ContainsAny(input, "*?[") {
+		return nil, fmt.Errorf("input %s: %w", input, statErr)
+	}
+	matches, err := filepath.Glob(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", input, err)
+	}
+	var paths []string
+	for _, path := range matches {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files match %q", input)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// MultiFileReader reads several files with the same columns as one stream
+// of records, reading up to a given number of files concurrently. With a
+// concurrency of one, or in order, records are returned in file order.
+type MultiFileReader struct {
+	ctx      context.Context // the reader's, not canceled by Close
+	schema   *arrow.Schema
+	results  chan multiFileResult // shared by all files when unordered
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	err      error
+	position string
+	source   string
+
+	// In order, each file has its own channel, drained one after the
+	// other, and a file is only started while fewer than cap(slots)
+	// files are read or waiting to be returned.
+	ordered bool
+	files   []chan multiFileResult
+	current int
+	slots   chan struct{}
+	done    chan struct{}
+}
+
+// multiFileResult is a record, or the error that ended reading, and where
+// it came from: the file and its reader's position within it, if known.
+type multiFileResult struct {
+	record   arrow.Record
+	err      error
+	position string
+	source   string
+}
+
+// defaultReorderBuffer is the number of records a file read ahead of the
+// one being returned may hold in order.
+const defaultReorderBuffer = 2
+
+// MultiFileReadOptions configures a MultiFileReader.
+type MultiFileReadOptions struct {
+	// Concurrency is the number of files read at once, one if below one.
+	Concurrency int
+	// Ordered returns the records in file order whatever the concurrency,
+	// so that the output is the same from run to run. Files read ahead of
+	// the one being returned hold up to ReorderBuffer records each, 2 by
+	// default, and at most twice Concurrency files are started ahead.
+	Ordered       bool
+	ReorderBuffer int
+}
+
+// NewMultiFileReader opens the first of paths to learn the schema and
+// starts reading all of them. concurrency below one is treated as one.
+func NewMultiFileReader(ctx context.Context, paths []string, concurrency int, open FileReaderFactory) (*MultiFileReader, error) {
+	return NewMultiFileReaderWithOptions(ctx, paths, open, &MultiFileReadOptions{Concurrency: concurrency})
+}
+
+// NewMultiFileReaderWithOptions opens the first of paths to learn the
+// schema and starts reading all of them as opts says.
+func NewMultiFileReaderWithOptions(ctx context.Context, paths []string, open FileReaderFactory, opts *MultiFileReadOptions) (*MultiFileReader, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no input files")
+	}
+	if opts == nil {
+		opts = &MultiFileReadOptions{}
+	}
+	concurrency := max(1, min(opts.Concurrency, len(paths)))
+
+	first, err := open(ctx, paths[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", paths[0], err)
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	r := &MultiFileReader{
+		ctx:     parent,
+		schema:  first.Schema(),
+		cancel:  cancel,
+		ordered: opts.Ordered,
+		done:    make(chan struct{}),
+	}
+	if r.ordered {
+		buffer := opts.ReorderBuffer
+		if buffer <= 0 {
+			buffer = defaultReorderBuffer
+		}
+		r.files = make([]chan multiFileResult, len(paths))
+		for i := range r.files {
+			r.files[i] = make(chan multiFileResult, buffer)
+		}
+		r.slots = make(chan struct{}, 2*concurrency)
+		r.slots <- struct{}{} // taken by the first file
+	} else {
+		r.results = make(chan multiFileResult, concurrency)
+	}
+
+	jobs := make(chan int)
+	r.wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		var initial FileReader
+		if i == 0 {
+			initial = first
+		}
+		go r.work(ctx, jobs, initial, paths, open)
+	}
+	go func() {
+		defer close(jobs)
+		for i := 1; i < len(paths); i++ {
+			if r.ordered {
+				select {
+				case r.slots <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		r.wg.Wait()
+		if !r.ordered {
+			close(r.results)
+		}
+		close(r.done)
+	}()
+	return r, nil
+}
+
+// work reads initial, the first file, if set, then every file whose index
+// is received on jobs.
+func (r *MultiFileReader) work(ctx context.Context, jobs <-chan int, initial FileReader, paths []string, open FileReaderFactory) {
+	defer r.wg.Done()
+	if initial != nil && !r.readFile(ctx, 0, initial, paths, open) {
+		return
+	}
+	for i := range jobs {
+		if !r.readFile(ctx, i, nil, paths, open) {
+			return
+		}
+	}
+}
+
+// readFile sends every record of the i-th file, opening it unless reader
+// is set, and reports whether to continue.
+func (r *MultiFileReader) readFile(ctx context.Context, i int, reader FileReader, paths []string, open FileReaderFactory) bool {
+	out := r.results
+	if r.ordered {
+		out = r.files[i]
+		defer close(out)
+	}
+	path := paths[i]
+	if reader == nil {
+		var err error
+		if reader, err = open(ctx, path); err != nil {
+			r.send(ctx, out, multiFileResult{err: fmt.Errorf("failed to open %s: %w", path, err)})
+			return false
+		}
+		if !sameColumns(reader.Schema(), r.schema) {
+			reader.Close()
+			r.send(ctx, out, multiFileResult{err: fmt.Errorf("schema of %s does not match %s", path, paths[0])})
+			return false
+		}
+	}
+	return r.drain(ctx, out, reader, path)
+}
+
+// drain sends every record of reader to out and closes it, reporting
+// whether to continue.
+func (r *MultiFileReader) drain(ctx context.Context, out chan<- multiFileResult, reader FileReader, path string) bool {
+	defer reader.Close()
+	positioned, _ := reader.(interfaces.PositionedReader)
+	position := func() string {
+		if positioned == nil || positioned.Position() == "" {
+			return path
+		}
+		return path + ", " + positioned.Position()
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return true
+		}
+		if err != nil {
+			r.send(ctx, out, multiFileResult{err: fmt.Errorf("failed to read %s: %w", path, err), position: position()})
+			return false
+		}
+		if record == nil {
+			return true
+		}
+		if !record.Schema().Equal(r.schema) {
+			// Present every record with the same schema, metadata included.
+			rewrapped := array.NewRecord(r.schema, record.Columns(), record.NumRows())
+			record.Release()
+			record = rewrapped
+		}
+		if !r.send(ctx, out, multiFileResult{record: record, position: position(), source: path}) {
+			record.Release()
+			return false
+		}
+	}
+}
+
+func (r *MultiFileReader) send(ctx context.Context, out chan<- multiFileResult, res multiFileResult) bool {
+	select {
+	case out <- res:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sameColumns reports whether a and b have the same field names, types and
+// nullability, ignoring metadata.
+func sameColumns(a, b *arrow.Schema) bool {
+	if a.NumFields() != b.NumFields() {
+		return false
+	}
+	for i := 0; i < a.NumFields(); i++ {
+		fa, fb := a.Field(i), b.Field(i)
+		if fa.Name != fb.Name || fa.Nullable != fb.Nullable || !arrow.TypeEqual(fa.Type, fb.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// Read returns the next record from any of the files, or from the files
+// in order.
+func (r *MultiFileReader) Read() (arrow.Record, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	var res multiFileResult
+	var ok bool
+	if r.ordered {
+		res, ok = r.next()
+	} else {
+		res, ok = <-r.results
+	}
+	if !ok {
+		// The workers also stop once the context is done.
+		if err := r.ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	r.position = res.position
+	r.source = res.source
+	if res.err != nil {
+		r.err = res.err
+		r.cancel()
+		return nil, res.err
+	}
+	return res.record, nil
+}
+
+// next returns the next result in file order, moving on to the next file
+// once one is done, which lets another file start.
+func (r *MultiFileReader) next() (multiFileResult, bool) {
+	for r.current < len(r.files) {
+		select {
+		case res, ok := <-r.files[r.current]:
+			if ok {
+				return res, true
+			}
+			r.current++
+			<-r.slots
+		case <-r.ctx.Done():
+			return multiFileResult{}, false
+		}
+	}
+	return multiFileResult{}, false
+}
+
+// Position returns the file the record last read came from, followed by
+// the position within it when its reader tells.
+func (r *MultiFileReader) Position() string {
+	return r.position
+}
+
+// Source returns the file the record last read came from.
+func (r *MultiFileReader) Source() string {
+	return r.source
+}
+
+// Schema returns the schema shared by the files.
+func (r *MultiFileReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Close stops reading and releases unread records.
+func (r *MultiFileReader) Close() error {
+	r.cancel()
+	if !r.ordered {
+		for res := range r.results {
+			if res.record != nil {
+				res.record.Release()
+			}
+		}
+		return nil
+	}
+	<-r.done
+	for _, ch := range r.files {
+	drain:
+		for {
+			select {
+			case res, ok := <-ch:
+				if !ok {
+					break drain
+				}
+				if res.record != nil {
+					res.record.Release()
+				}
+			default:
+				break drain
+			}
+		}
+	}
+	return nil
+}
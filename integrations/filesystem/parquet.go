@@ -31,9 +31,12 @@ package integrations
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"sort"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/memory"
@@ -41,15 +44,36 @@ import (
 	"github.com/apache/arrow-go/v18/parquet/compress"
 	"github.com/apache/arrow-go/v18/parquet/file"
 	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/apache/arrow-go/v18/parquet/schema"
 	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+	"github.com/arrowarc/arrowarc/pkg/geo"
+	"github.com/arrowarc/arrowarc/pkg/limit"
 )
 
 // ParquetReader reads Parquet files and implements the Reader interface.
 type ParquetReader struct {
+	ctx          context.Context
 	recordReader pqarrow.RecordReader
 	fileReader   *file.Reader
 	schema       *arrow.Schema
 	alloc        memory.Allocator
+	filter       filter.Expr
+	rowGroups    []int
+	parallel     *parallelRowGroups
+	window       *limit.Window
+
+	// groupEnds holds the running total of rows at the end of each row
+	// group read, to tell which row groups the rows scanned so far span.
+	groupEnds []int64
+	scanned   int64
+	position  string
+
+	// jsonColumns names the columns annotated as JSON, read as arrow.json.
+	jsonColumns map[string]bool
+	// geo is the GeoParquet metadata of the file, added to the schema of
+	// the records read when pqarrow leaves it out.
+	geo string
 }
 
 // ReadOptions defines options for reading Parquet files.
@@ -57,15 +81,87 @@ type ParquetReadOptions struct {
 	MemoryMap     bool
 	ColumnIndices []int
 	RowGroups     []int
-	Parallel      bool
 	ChunkSize     int64
+
+	// Columns names the top-level columns to read, the others being
+	// skipped. It takes precedence over ColumnIndices, which index leaf
+	// columns. Records keep the order of the columns in the file.
+	Columns []string
+
+	// Parallel reads row groups concurrently, each with its own reader, on
+	// Workers goroutines (GOMAXPROCS by default). Records still come out
+	// in row group order unless Unordered is set, for writers that do not
+	// care about order.
+	Parallel  bool
+	Workers   int
+	Unordered bool
+
+	// Filter, when set, skips row groups whose statistics or bloom filters
+	// rule out a match and drops non-matching rows from every record read.
+	Filter filter.Expr
+
+	// ReadDictionary reads string and binary columns as dictionary arrays,
+	// keeping the dictionary pages of the file instead of materializing a
+	// value per row. Columns written as dictionaries by ParquetWriter are
+	// read back as dictionaries either way.
+	ReadDictionary bool
+
+	// ReadLarge reads string and binary columns as LargeString and
+	// LargeBinary, whose 64-bit offsets address more than 2 GiB of values
+	// in a record. Columns written as large types by ParquetWriter are
+	// read back as large types either way.
+	ReadLarge bool
+
+	// Offset skips the first rows read, and Limit stops reading after that
+	// many rows, zero meaning no limit. Without a Filter, row groups wholly
+	// outside these rows are not read at all.
+	Offset int64
+	Limit  int64
 }
 
-func (o *ParquetReadOptions) toArrowReadProperties() pqarrow.ArrowReadProperties {
-	return pqarrow.ArrowReadProperties{
+// parquetColumnIndices returns the indices of the leaf columns of the
+// top-level columns of schema named by columns.
+func parquetColumnIndices(fileReader *pqarrow.FileReader, schema *arrow.Schema, columns []string) ([]int, error) {
+	fields := make([]int, 0, len(columns))
+	for _, name := range columns {
+		indices := schema.FieldIndices(name)
+		if len(indices) == 0 {
+			return nil, fmt.Errorf("column %q not found", name)
+		}
+		fields = append(fields, indices...)
+	}
+	slices.Sort(fields)
+	leaves, err := fileReader.Manifest.GetFieldIndices(slices.Compact(fields))
+	if err != nil {
+		return nil, fmt.Errorf("failed to select columns: %w", err)
+	}
+	return leaves, nil
+}
+
+func (o *ParquetReadOptions) toArrowReadProperties(fileSchema *schema.Schema) pqarrow.ArrowReadProperties {
+	batchSize := int64(64 * 1024 * 1024) // 64MB batch size
+	if o.ChunkSize > 0 {
+		batchSize = o.ChunkSize
+	}
+	props := pqarrow.ArrowReadProperties{
 		Parallel:  true,
-		BatchSize: 64 * 1024 * 1024, // 64MB batch size
+		BatchSize: batchSize,
+	}
+	if o.ReadDictionary {
+		for i := 0; i < fileSchema.NumColumns(); i++ {
+			if fileSchema.Column(i).PhysicalType() == parquet.Types.ByteArray {
+				props.SetReadDict(i, true)
+			}
+		}
 	}
+	if o.ReadLarge {
+		for i := 0; i < fileSchema.NumColumns(); i++ {
+			if fileSchema.Column(i).PhysicalType() == parquet.Types.ByteArray {
+				props.SetForceLarge(i, true)
+			}
+		}
+	}
+	return props
 }
 
 // NewDefaultParquetWriteOptions returns default write options for Parquet files.
@@ -77,28 +173,149 @@ func NewDefaultParquetWriteOptions() pqarrow.ArrowWriterProperties {
 
 // NewDefaultParquetWriterProperties returns default writer properties.
 func NewDefaultParquetWriterProperties() *parquet.WriterProperties {
-	return parquet.NewWriterProperties(
+	return parquet.NewWriterProperties(defaultParquetWriterProperties()...)
+}
+
+func defaultParquetWriterProperties() []parquet.WriterProperty {
+	return []parquet.WriterProperty{
 		parquet.WithCompression(compress.Codecs.Snappy),
-		parquet.WithBatchSize(64*1024*1024), // 64MB batch size
+		parquet.WithBatchSize(64 * 1024 * 1024), // 64MB batch size
 		parquet.WithAllocator(pool.GetAllocator()),
 		parquet.WithVersion(parquet.V2_LATEST),
-		parquet.WithDataPageSize(1024*1024),
-		parquet.WithMaxRowGroupLength(64*1024*1024), // 64MB row group length
-		parquet.WithCreatedBy("ArrowArc"),
-	)
+		parquet.WithDataPageSize(1024 * 1024),
+		parquet.WithMaxRowGroupLength(64 * 1024 * 1024), // 64MB row group length
+		parquet.WithCreatedBy(ParquetCreatedBy),
+	}
+}
+
+// ParquetCreatedBy is the created_by string of the Parquet files written.
+const ParquetCreatedBy = "ArrowArc"
+
+// ParquetWriteOptions tunes the layout of Parquet files written by
+// NewParquetWriterWithOptions. Zero values keep the defaults of
+// NewDefaultParquetWriterProperties. Columns are addressed by their dotted
+// Parquet path, which for top-level columns is the field name.
+type ParquetWriteOptions struct {
+	MaxRowGroupLength int64                           // Maximum number of rows per row group
+	DataPageSize      int64                           // Target size of data pages in bytes
+	Compression       *compress.Compression           // Codec for columns without an override, Snappy if nil
+	ColumnCompression map[string]compress.Compression // Per-column codec overrides
+	DisableDictionary bool                            // Turn dictionary encoding off for columns without an override
+	ColumnDictionary  map[string]bool                 // Per-column dictionary encoding overrides
+
+	// BloomFilterColumns lists the columns to write split-block bloom filters
+	// for. Filters are sized from the number of values in each column chunk.
+	BloomFilterColumns []string
+	// BloomFilterBitsPerValue sizes the filters, defaults to 10 (about 1% false positives).
+	BloomFilterBitsPerValue uint
+
+	// SortingColumns is recorded in the row group metadata. The writer does
+	// not sort; records must already be in this order.
+	SortingColumns []ParquetSortingColumn
+
+	// Geometry lists columns to write as GeoParquet geometries, along with
+	// those the GeoParquet metadata of the schema names: binary columns
+	// holding WKB, and string columns holding WKT, written as WKB.
+	Geometry []string
+}
+
+// ParquetSortingColumn describes the sort order of one column.
+type ParquetSortingColumn struct {
+	Column     string
+	Descending bool
+	NullsFirst bool
 }
 
-// NewParquetReader creates a new Parquet file reader.
+const defaultBloomFilterBitsPerValue = 10
+
+// writerProperties builds writer properties for schema from the defaults
+// and the options, rejecting options that name unknown columns.
+func (o *ParquetWriteOptions) writerProperties(schema *arrow.Schema) (*parquet.WriterProperties, error) {
+	if narrowed := parquetSchema(schema); narrowed != nil {
+		schema = narrowed
+	}
+	pqschema, err := pqarrow.ToParquet(schema, parquet.NewWriterProperties(), pqarrow.NewArrowWriterProperties())
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert schema to Parquet: %w", err)
+	}
+	columnIndex := func(path string) (int, error) {
+		idx := pqschema.ColumnIndexByName(path)
+		if idx < 0 {
+			return 0, fmt.Errorf("column %q not found in Parquet schema", path)
+		}
+		return idx, nil
+	}
+
+	props := defaultParquetWriterProperties()
+	if o.MaxRowGroupLength < 0 || o.DataPageSize < 0 {
+		return nil, errors.New("row group length and data page size cannot be negative")
+	}
+	if o.MaxRowGroupLength > 0 {
+		props = append(props, parquet.WithMaxRowGroupLength(o.MaxRowGroupLength))
+	}
+	if o.DataPageSize > 0 {
+		props = append(props, parquet.WithDataPageSize(o.DataPageSize))
+	}
+	if o.Compression != nil {
+		props = append(props, parquet.WithCompression(*o.Compression))
+	}
+	for path, codec := range o.ColumnCompression {
+		if _, err := columnIndex(path); err != nil {
+			return nil, err
+		}
+		props = append(props, parquet.WithCompressionFor(path, codec))
+	}
+	if o.DisableDictionary {
+		props = append(props, parquet.WithDictionaryDefault(false))
+	}
+	for path, enabled := range o.ColumnDictionary {
+		if _, err := columnIndex(path); err != nil {
+			return nil, err
+		}
+		props = append(props, parquet.WithDictionaryFor(path, enabled))
+	}
+	for _, path := range o.BloomFilterColumns {
+		idx, err := columnIndex(path)
+		if err != nil {
+			return nil, err
+		}
+		if pqschema.Column(idx).PhysicalType() == parquet.Types.Boolean {
+			return nil, fmt.Errorf("bloom filters are not supported for boolean column %q", path)
+		}
+	}
+	if len(o.SortingColumns) > 0 {
+		sorting := make([]parquet.SortingColumn, len(o.SortingColumns))
+		for i, sc := range o.SortingColumns {
+			idx, err := columnIndex(sc.Column)
+			if err != nil {
+				return nil, err
+			}
+			sorting[i] = parquet.SortingColumn{ColumnIdx: int32(idx), Descending: sc.Descending, NullsFirst: sc.NullsFirst}
+		}
+		props = append(props, parquet.WithSortingColumns(sorting))
+	}
+
+	return parquet.NewWriterProperties(props...), nil
+}
+
+// NewParquetReader creates a new Parquet file reader. filePath may be an
+// http:// or https:// URL, read with range requests.
 func NewParquetReader(ctx context.Context, filePath string, opts *ParquetReadOptions) (*ParquetReader, error) {
+	if IsStdio(filePath) {
+		return nil, errNotSeekable("Parquet")
+	}
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return nil, fmt.Errorf("offset and limit cannot be negative")
+	}
 	alloc := pool.GetAllocator()
 
-	rdr, err := file.OpenParquetFile(filePath, opts.MemoryMap)
+	rdr, err := openParquetFile(ctx, filePath, opts.MemoryMap)
 	if err != nil {
 		pool.PutAllocator(alloc)
 		return nil, fmt.Errorf("failed to open Parquet file: %w", err)
 	}
 
-	fileReader, err := pqarrow.NewFileReader(rdr, opts.toArrowReadProperties(), alloc)
+	fileReader, err := pqarrow.NewFileReader(rdr, opts.toArrowReadProperties(rdr.MetaData().Schema), alloc)
 	if err != nil {
 		pool.PutAllocator(alloc)
 		rdr.Close()
@@ -112,36 +329,250 @@ func NewParquetReader(ctx context.Context, filePath string, opts *ParquetReadOpt
 		return nil, fmt.Errorf("failed to get schema: %w", err)
 	}
 
-	recordReader, err := fileReader.GetRecordReader(ctx, opts.ColumnIndices, opts.RowGroups)
+	rowGroups := opts.RowGroups
+	if rowGroups == nil {
+		rowGroups = make([]int, rdr.NumRowGroups())
+		for i := range rowGroups {
+			rowGroups[i] = i
+		}
+	}
+	if opts.Filter != nil {
+		if err := opts.Filter.Validate(schema); err != nil {
+			pool.PutAllocator(alloc)
+			rdr.Close()
+			return nil, err
+		}
+		rowGroups, err = pruneRowGroups(ctx, filePath, rdr, rowGroups, opts.Filter)
+		if err != nil {
+			pool.PutAllocator(alloc)
+			rdr.Close()
+			return nil, fmt.Errorf("failed to prune row groups: %w", err)
+		}
+	}
+
+	offset := opts.Offset
+	if opts.Filter == nil {
+		rowGroups, offset = windowRowGroups(rdr, rowGroups, offset, opts.Limit)
+	}
+
+	columnIndices := opts.ColumnIndices
+	if len(opts.Columns) > 0 {
+		if columnIndices, err = parquetColumnIndices(fileReader, schema, opts.Columns); err != nil {
+			pool.PutAllocator(alloc)
+			rdr.Close()
+			return nil, err
+		}
+	}
+
+	recordReader, err := fileReader.GetRecordReader(ctx, columnIndices, rowGroups)
 	if err != nil {
 		pool.PutAllocator(alloc)
 		rdr.Close()
 		return nil, fmt.Errorf("failed to create record reader: %w", err)
 	}
+	if opts.Filter != nil {
+		if err := opts.Filter.Validate(recordReader.Schema()); err != nil {
+			recordReader.Release()
+			pool.PutAllocator(alloc)
+			rdr.Close()
+			return nil, fmt.Errorf("filter columns must be among the columns read: %w", err)
+		}
+	}
 
-	return &ParquetReader{
+	if columnIndices != nil {
+		schema = recordReader.Schema()
+	}
+	jsonColumns := jsonColumns(rdr.MetaData().Schema)
+	schema = jsonSchema(schema, jsonColumns)
+	geoMetadata := fileGeoMetadata(rdr.MetaData().KeyValueMetadata())
+	if geoMetadata != "" {
+		schema = withGeoMetadata(schema, geoMetadata)
+	}
+	p := &ParquetReader{
+		ctx:          ctx,
 		recordReader: recordReader,
 		fileReader:   rdr,
 		schema:       schema,
 		alloc:        alloc,
-	}, nil
+		filter:       opts.Filter,
+		rowGroups:    rowGroups,
+		jsonColumns:  jsonColumns,
+		geo:          geoMetadata,
+	}
+	if offset > 0 || opts.Limit > 0 {
+		p.window, _ = limit.NewWindow(offset, opts.Limit)
+	}
+	// Each row group has its own dictionaries, and pqarrow cannot read a
+	// batch spanning two of them, so dictionary columns are read one row
+	// group at a time.
+	dictionaries := hasDictionary(recordReader.Schema())
+	if (opts.Parallel || dictionaries) && len(rowGroups) > 1 {
+		workers, ordered := opts.Workers, !opts.Unordered
+		if !opts.Parallel {
+			workers, ordered = 1, true
+		}
+		recordReader.Release()
+		p.recordReader = nil
+		p.parallel = newParallelRowGroups(ctx, fileReader, columnIndices, rowGroups, opts.Filter, workers, ordered)
+		return p, nil
+	}
+	var total int64
+	p.groupEnds = make([]int64, len(rowGroups))
+	for i, rg := range rowGroups {
+		total += rdr.MetaData().RowGroup(rg).NumRows()
+		p.groupEnds[i] = total
+	}
+	return p, nil
+}
+
+// openParquetFile opens a local Parquet file, or a URL without memory
+// mapping.
+func openParquetFile(ctx context.Context, filePath string, memoryMap bool) (*file.Reader, error) {
+	if !IsURL(filePath) {
+		return file.OpenParquetFile(filePath, memoryMap)
+	}
+	f, err := openHTTPFile(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return file.NewParquetReader(f)
+}
+
+// windowRowGroups drops the row groups holding only rows before offset, or
+// after offset plus limit rows when limit is positive. It returns the row
+// groups left and the number of their rows still to skip.
+func windowRowGroups(rdr *file.Reader, rowGroups []int, offset, limit int64) ([]int, int64) {
+	rows := func(i int) int64 { return rdr.MetaData().RowGroup(rowGroups[i]).NumRows() }
+	start := 0
+	for start < len(rowGroups) && rows(start) <= offset {
+		offset -= rows(start)
+		start++
+	}
+	end := len(rowGroups)
+	if limit > 0 {
+		end = start
+		for need := offset + limit; end < len(rowGroups) && need > 0; end++ {
+			need -= rows(end)
+		}
+	}
+	return rowGroups[start:end], offset
 }
 
 func (p *ParquetReader) Read() (arrow.Record, error) {
-	if p.recordReader.Next() {
+	if p.window != nil {
+		return p.window.Read(p.read)
+	}
+	return p.read()
+}
+
+// read returns the next record of the row groups read, with its JSON
+// columns typed as such and its GeoParquet metadata.
+func (p *ParquetReader) read() (arrow.Record, error) {
+	record, err := p.next()
+	if err != nil || record == nil {
+		return record, err
+	}
+	if p.jsonColumns != nil {
+		record = withJSONColumns(record, p.jsonColumns)
+	}
+	if p.geo != "" {
+		record = withGeoRecord(record, p.geo)
+	}
+	return record, nil
+}
+
+// next returns the next record as pqarrow reads it.
+func (p *ParquetReader) next() (arrow.Record, error) {
+	if err := p.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if p.parallel != nil {
+		record, rg, err := p.parallel.Read()
+		if rg >= 0 {
+			p.position = fmt.Sprintf("row group %d", rg)
+		}
+		return record, err
+	}
+	for p.recordReader.Next() {
 		record := p.recordReader.Record()
-		record.Retain() // Retain the record to ensure it stays valid
-		return record, nil
+		start := p.scanned
+		p.scanned += record.NumRows()
+		p.position = p.rowGroupSpan(start, p.scanned)
+		if p.filter == nil {
+			record.Retain() // Retain the record to ensure it stays valid
+			return record, nil
+		}
+
+		filtered, err := filter.Apply(p.ctx, record, p.filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter record: %w", err)
+		}
+		if filtered.NumRows() > 0 {
+			return filtered, nil
+		}
+		filtered.Release()
+		// A selective filter may skip many records before returning one.
+		if err := p.ctx.Err(); err != nil {
+			return nil, err
+		}
 	}
 	if err := p.recordReader.Err(); err != nil && err != io.EOF {
+		p.position = p.rowGroupSpan(p.scanned, p.scanned+1)
 		return nil, err
 	}
 	return nil, io.EOF
 }
 
+// Position returns the row group, or row groups, the record last read came
+// from.
+func (p *ParquetReader) Position() string {
+	return p.position
+}
+
+// rowGroupSpan names the row groups holding rows start to end, excluding
+// end, counted across the row groups read.
+func (p *ParquetReader) rowGroupSpan(start, end int64) string {
+	first := sort.Search(len(p.groupEnds), func(i int) bool { return p.groupEnds[i] > start })
+	last := sort.Search(len(p.groupEnds), func(i int) bool { return p.groupEnds[i] >= end })
+	if first >= len(p.rowGroups) {
+		return ""
+	}
+	last = min(last, len(p.rowGroups)-1)
+	if first == last {
+		return fmt.Sprintf("row group %d", p.rowGroups[first])
+	}
+	return fmt.Sprintf("row groups %d-%d", p.rowGroups[first], p.rowGroups[last])
+}
+
+// NumRows returns the number of rows to be read, or zero when a filter
+// makes the count unknown.
+func (p *ParquetReader) NumRows() int64 {
+	if p.filter != nil {
+		return 0
+	}
+	var n int64
+	for _, rg := range p.rowGroups {
+		n += p.fileReader.MetaData().RowGroup(rg).NumRows()
+	}
+	if p.window != nil {
+		n = p.window.Rows(n)
+	}
+	return n
+}
+
+// RowGroups returns the indices of the row groups being read, after any
+// pruning by the filter.
+func (p *ParquetReader) RowGroups() []int {
+	return p.rowGroups
+}
+
 func (p *ParquetReader) Close() error {
 	defer pool.PutAllocator(p.alloc)
-	p.recordReader.Release()
+	if p.parallel != nil {
+		p.parallel.Close()
+	} else {
+		p.recordReader.Release()
+	}
 	return p.fileReader.Close()
 }
 
@@ -151,26 +582,59 @@ func (p *ParquetReader) Schema() *arrow.Schema {
 
 // ParquetWriter writes records to Parquet files.
 type ParquetWriter struct {
-	writer *pqarrow.FileWriter
-	file   *os.File
-	alloc  memory.Allocator
+	writer       *pqarrow.FileWriter
+	file         io.WriteCloser
+	path         string
+	alloc        memory.Allocator
+	bloomColumns []string
+	bloomBits    uint
+	// narrowed, if set, is the schema records are cast to before they are
+	// written.
+	narrowed *arrow.Schema
+	// geo, if set, writes the geometry columns and the GeoParquet metadata.
+	geo *parquetGeo
 }
 
-// NewParquetWriter creates a new Parquet file writer.
+// NewParquetWriter creates a new Parquet file writer. A filePath of
+// StdioPath writes the file to standard output.
 func NewParquetWriter(
 	filePath string, schema *arrow.Schema,
 	parquetWriterProps *parquet.WriterProperties,
 ) (*ParquetWriter, error) {
+	return newParquetWriter(filePath, schema, parquetWriterProps, nil)
+}
+
+func newParquetWriter(filePath string, schema *arrow.Schema, parquetWriterProps *parquet.WriterProperties, geometry []string) (*ParquetWriter, error) {
+	geoColumns, err := newParquetGeo(schema, geometry)
+	if err != nil {
+		return nil, err
+	}
+	if geoColumns != nil {
+		schema = geoColumns.schema
+	}
 
 	alloc := pool.GetAllocator()
 
-	file, err := os.Create(filePath)
+	file, err := createFile(filePath)
 	if err != nil {
 		pool.PutAllocator(alloc)
 		return nil, fmt.Errorf("failed to create file: %w", err)
 	}
 
-	writer, err := pqarrow.NewFileWriter(schema, file, parquetWriterProps, pqarrow.NewArrowWriterProperties())
+	// Dictionary columns are written from their dictionaries as is; storing
+	// the Arrow schema lets readers get them back as dictionaries, and
+	// large strings and binaries back as large types. Large lists are
+	// written as lists, which they are read back as. UUID and JSON columns
+	// are annotated with their logical types and read back from those.
+	arrowProps := pqarrow.NewArrowWriterProperties()
+	if hasDictionary(schema) || slices.ContainsFunc(schema.Fields(), func(f arrow.Field) bool { return hasLargeType(f.Type) }) {
+		arrowProps = pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema())
+	}
+	narrowed := parquetSchema(schema)
+	if narrowed != nil {
+		schema = narrowed
+	}
+	writer, err := pqarrow.NewFileWriter(schema, file, parquetWriterProps, arrowProps)
 	if err != nil {
 		file.Close()
 		pool.PutAllocator(alloc)
@@ -178,13 +642,69 @@ func NewParquetWriter(
 	}
 
 	return &ParquetWriter{
-		writer: writer,
-		file:   file,
-		alloc:  alloc,
+		writer:   writer,
+		file:     file,
+		path:     filePath,
+		alloc:    alloc,
+		narrowed: narrowed,
+		geo:      geoColumns,
 	}, nil
 }
 
+// hasDictionary reports whether any top-level field of schema is
+// dictionary encoded.
+func hasDictionary(schema *arrow.Schema) bool {
+	for _, f := range schema.Fields() {
+		if f.Type.ID() == arrow.DICTIONARY {
+			return true
+		}
+	}
+	return false
+}
+
+// NewParquetWriterWithOptions creates a new Parquet file writer tuned by opts.
+func NewParquetWriterWithOptions(filePath string, schema *arrow.Schema, opts *ParquetWriteOptions) (*ParquetWriter, error) {
+	if opts == nil {
+		return NewParquetWriter(filePath, schema, NewDefaultParquetWriterProperties())
+	}
+
+	if len(opts.BloomFilterColumns) > 0 && IsStdio(filePath) {
+		return nil, fmt.Errorf("bloom filters cannot be written to standard output")
+	}
+	props, err := opts.writerProperties(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newParquetWriter(filePath, schema, props, opts.Geometry)
+	if err != nil {
+		return nil, err
+	}
+	w.bloomColumns = opts.BloomFilterColumns
+	w.bloomBits = opts.BloomFilterBitsPerValue
+	if w.bloomBits == 0 {
+		w.bloomBits = defaultBloomFilterBitsPerValue
+	}
+	return w, nil
+}
+
 func (p *ParquetWriter) Write(record arrow.Record) error {
+	if p.geo != nil {
+		written, err := p.geo.record(p.alloc, record)
+		if err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+		defer written.Release()
+		record = written
+	}
+	if p.narrowed != nil {
+		narrowed, err := narrowRecord(record, p.narrowed)
+		if err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+		defer narrowed.Release()
+		record = narrowed
+	}
 	if err := p.writer.Write(record); err != nil {
 		return fmt.Errorf("failed to write record: %w", err)
 	}
@@ -193,8 +713,28 @@ func (p *ParquetWriter) Write(record arrow.Record) error {
 
 func (p *ParquetWriter) Close() error {
 	defer pool.PutAllocator(p.alloc)
+	if p.geo != nil {
+		value, err := p.geo.metadata()
+		if err != nil {
+			p.writer.Close()
+			return fmt.Errorf("failed to write GeoParquet metadata: %w", err)
+		}
+		if err := p.writer.AppendKeyValueMetadata(geo.MetadataKey, value); err != nil {
+			p.writer.Close()
+			return fmt.Errorf("failed to write GeoParquet metadata: %w", err)
+		}
+	}
 	if err := p.writer.Close(); err != nil {
 		return fmt.Errorf("failed to close Parquet writer: %w", err)
 	}
-	return p.file.Close()
+	// The Parquet writer closes the file along with itself.
+	if err := p.file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	if len(p.bloomColumns) > 0 {
+		if err := writeBloomFilters(p.path, p.bloomColumns, p.bloomBits); err != nil {
+			return fmt.Errorf("failed to write bloom filters: %w", err)
+		}
+	}
+	return nil
 }
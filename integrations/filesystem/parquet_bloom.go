@@ -0,0 +1,241 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/parquet-go/parquet-go/bloom"
+	"github.com/parquet-go/parquet-go/format"
+	"github.com/segmentio/encoding/thrift"
+)
+
+// The Arrow Parquet writer cannot emit bloom filters, so they are added to a
+// finished file: the filters are built from the written column chunks,
+// appended after the last row group, and the footer is rewritten to point at
+// them. The layout follows the Parquet spec (split-block filters hashed with
+// XXH64 over the plain-encoded value), so any spec-compliant reader can use
+// them.
+
+const (
+	parquetMagic        = "PAR1"
+	bloomReadBatchSize  = 4096
+	parquetFooterLength = 8 // 4-byte metadata length followed by the magic
+)
+
+type bloomFilterChunk struct {
+	rowGroup, column int
+	filter           bloom.SplitBlockFilter
+}
+
+// writeBloomFilters adds bloom filters for the named columns to every row
+// group of the Parquet file at path.
+func writeBloomFilters(path string, columns []string, bitsPerValue uint) error {
+	chunks, err := buildBloomFilters(path, columns, bitsPerValue)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	meta, footerStart, err := readFooter(f)
+	if err != nil {
+		return err
+	}
+
+	// Overwrite the old footer with the filters, then append the new footer.
+	if _, err := f.Seek(footerStart, io.SeekStart); err != nil {
+		return err
+	}
+	offset := footerStart
+	protocol := new(thrift.CompactProtocol)
+	for _, c := range chunks {
+		bitset := c.filter.Bytes()
+		header, err := thrift.Marshal(protocol, &format.BloomFilterHeader{
+			NumBytes:    int32(len(bitset)),
+			Algorithm:   format.BloomFilterAlgorithm{Block: &format.SplitBlockAlgorithm{}},
+			Hash:        format.BloomFilterHash{XxHash: &format.XxHash{}},
+			Compression: format.BloomFilterCompression{Uncompressed: &format.BloomFilterUncompressed{}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode bloom filter header: %w", err)
+		}
+		if _, err := f.Write(header); err != nil {
+			return err
+		}
+		if _, err := f.Write(bitset); err != nil {
+			return err
+		}
+		meta.RowGroups[c.rowGroup].Columns[c.column].MetaData.BloomFilterOffset = offset
+		offset += int64(len(header) + len(bitset))
+	}
+
+	footer, err := thrift.Marshal(protocol, meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode file metadata: %w", err)
+	}
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	footer = append(footer, parquetMagic...)
+	if _, err := f.Write(footer); err != nil {
+		return err
+	}
+	if err := f.Truncate(offset + int64(len(footer))); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// readFooter decodes the file metadata and returns it with the offset at
+// which the footer begins.
+func readFooter(f *os.File) (*format.FileMetaData, int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+	if size < int64(len(parquetMagic)+parquetFooterLength) {
+		return nil, 0, fmt.Errorf("%s is too small to be a Parquet file", f.Name())
+	}
+
+	tail := make([]byte, parquetFooterLength)
+	if _, err := f.ReadAt(tail, size-parquetFooterLength); err != nil {
+		return nil, 0, err
+	}
+	if string(tail[4:]) != parquetMagic {
+		return nil, 0, fmt.Errorf("%s is not a Parquet file or its footer is encrypted", f.Name())
+	}
+
+	metaLen := int64(binary.LittleEndian.Uint32(tail))
+	footerStart := size - parquetFooterLength - metaLen
+	buf := make([]byte, metaLen)
+	if _, err := f.ReadAt(buf, footerStart); err != nil {
+		return nil, 0, err
+	}
+
+	meta := new(format.FileMetaData)
+	if err := thrift.Unmarshal(new(thrift.CompactProtocol), buf, meta); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode file metadata: %w", err)
+	}
+	return meta, footerStart, nil
+}
+
+// buildBloomFilters reads back the named columns of every row group and
+// builds one filter per column chunk.
+func buildBloomFilters(path string, columns []string, bitsPerValue uint) ([]bloomFilterChunk, error) {
+	rdr, err := file.OpenParquetFile(path, false)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+
+	schema := rdr.MetaData().Schema
+	indices := make([]int, len(columns))
+	for i, name := range columns {
+		if indices[i] = schema.ColumnIndexByName(name); indices[i] < 0 {
+			return nil, fmt.Errorf("column %q not found in Parquet schema", name)
+		}
+	}
+
+	var chunks []bloomFilterChunk
+	for rg := 0; rg < rdr.NumRowGroups(); rg++ {
+		rgr := rdr.RowGroup(rg)
+		for _, col := range indices {
+			numValues := rgr.MetaData().NumRows()
+			if cc, err := rgr.MetaData().ColumnChunk(col); err == nil {
+				numValues = cc.NumValues()
+			}
+			filter := make(bloom.SplitBlockFilter, bloom.NumSplitBlocksOf(numValues, bitsPerValue))
+
+			cr, err := rgr.Column(col)
+			if err != nil {
+				return nil, err
+			}
+			if err := insertColumnValues(cr, filter); err != nil {
+				return nil, fmt.Errorf("column %q, row group %d: %w", schema.Column(col).Path(), rg, err)
+			}
+			chunks = append(chunks, bloomFilterChunk{rowGroup: rg, column: col, filter: filter})
+		}
+	}
+	return chunks, nil
+}
+
+func insertColumnValues(cr file.ColumnChunkReader, filter bloom.SplitBlockFilter) error {
+	var h bloom.XXH64
+	switch r := cr.(type) {
+	case *file.Int32ColumnChunkReader:
+		return insertValues(r, filter, func(v int32) uint64 { return h.Sum64Uint32(uint32(v)) })
+	case *file.Int64ColumnChunkReader:
+		return insertValues(r, filter, func(v int64) uint64 { return h.Sum64Uint64(uint64(v)) })
+	case *file.Float32ColumnChunkReader:
+		return insertValues(r, filter, func(v float32) uint64 { return h.Sum64Uint32(math.Float32bits(v)) })
+	case *file.Float64ColumnChunkReader:
+		return insertValues(r, filter, func(v float64) uint64 { return h.Sum64Uint64(math.Float64bits(v)) })
+	case *file.Int96ColumnChunkReader:
+		return insertValues(r, filter, func(v parquet.Int96) uint64 { return h.Sum64(v[:]) })
+	case *file.ByteArrayColumnChunkReader:
+		return insertValues(r, filter, func(v parquet.ByteArray) uint64 { return h.Sum64(v) })
+	case *file.FixedLenByteArrayColumnChunkReader:
+		return insertValues(r, filter, func(v parquet.FixedLenByteArray) uint64 { return h.Sum64(v) })
+	default:
+		return fmt.Errorf("bloom filters are not supported for %s columns", cr.Type())
+	}
+}
+
+// batchReader is implemented by the typed column chunk readers.
+type batchReader[T any] interface {
+	HasNext() bool
+	ReadBatch(batchSize int64, values []T, defLvls, repLvls []int16) (int64, int, error)
+}
+
+func insertValues[T any](r batchReader[T], filter bloom.SplitBlockFilter, hash func(T) uint64) error {
+	values := make([]T, bloomReadBatchSize)
+	defLvls := make([]int16, bloomReadBatchSize)
+	repLvls := make([]int16, bloomReadBatchSize)
+	for r.HasNext() {
+		_, n, err := r.ReadBatch(bloomReadBatchSize, values, defLvls, repLvls)
+		if err != nil {
+			return err
+		}
+		for _, v := range values[:n] {
+			filter.Insert(hash(v))
+		}
+	}
+	return nil
+}
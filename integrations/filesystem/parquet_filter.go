@@ -0,0 +1,217 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/metadata"
+	"github.com/apache/arrow-go/v18/parquet/schema"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+	pq "github.com/parquet-go/parquet-go"
+)
+
+// pruneRowGroups returns the row groups that may contain rows matching expr.
+func pruneRowGroups(ctx context.Context, filePath string, rdr *file.Reader, rowGroups []int, expr filter.Expr) ([]int, error) {
+	blooms := &bloomFilters{ctx: ctx, path: filePath}
+	defer blooms.close()
+
+	kept := make([]int, 0, len(rowGroups))
+	for _, rg := range rowGroups {
+		if rg < 0 || rg >= rdr.NumRowGroups() {
+			return nil, fmt.Errorf("row group %d out of range", rg)
+		}
+		stats := &rowGroupStats{
+			meta:   rdr.MetaData().RowGroup(rg),
+			schema: rdr.MetaData().Schema,
+			index:  rg,
+			blooms: blooms,
+		}
+		if expr.MayMatch(stats) {
+			kept = append(kept, rg)
+		}
+	}
+	return kept, nil
+}
+
+// rowGroupStats exposes the column chunk statistics and bloom filters of a
+// row group to filter expressions. Only top-level primitive columns are
+// summarized; anything else is reported as unknown.
+type rowGroupStats struct {
+	meta   *metadata.RowGroupMetaData
+	schema *schema.Schema
+	index  int
+	blooms *bloomFilters
+}
+
+func (s *rowGroupStats) column(name string) (int, *metadata.ColumnChunkMetaData, bool) {
+	idx := s.schema.ColumnIndexByName(name)
+	if idx < 0 {
+		return 0, nil, false
+	}
+	cc, err := s.meta.ColumnChunk(idx)
+	if err != nil {
+		return 0, nil, false
+	}
+	return idx, cc, true
+}
+
+func (s *rowGroupStats) MinMax(name string) (any, any, bool) {
+	idx, cc, ok := s.column(name)
+	if !ok {
+		return nil, nil, false
+	}
+	if set, err := cc.StatsSet(); err != nil || !set {
+		return nil, nil, false
+	}
+	stats, err := cc.Statistics()
+	if err != nil || stats == nil || !stats.HasMinMax() {
+		return nil, nil, false
+	}
+
+	// Unsigned integers are stored in signed physical types.
+	if lt, ok := s.schema.Column(idx).LogicalType().(schema.IntLogicalType); ok && !lt.IsSigned() {
+		return nil, nil, false
+	}
+
+	switch st := stats.(type) {
+	case *metadata.Int32Statistics:
+		return int64(st.Min()), int64(st.Max()), true
+	case *metadata.Int64Statistics:
+		return st.Min(), st.Max(), true
+	case *metadata.Float32Statistics:
+		return float64(st.Min()), float64(st.Max()), true
+	case *metadata.Float64Statistics:
+		return st.Min(), st.Max(), true
+	case *metadata.BooleanStatistics:
+		return st.Min(), st.Max(), true
+	case *metadata.ByteArrayStatistics:
+		if _, ok := s.schema.Column(idx).LogicalType().(schema.StringLogicalType); ok {
+			return string(st.Min()), string(st.Max()), true
+		}
+	}
+	return nil, nil, false
+}
+
+func (s *rowGroupStats) MightContain(name string, value any) bool {
+	idx, cc, ok := s.column(name)
+	if !ok || cc.BloomFilterOffset() <= 0 {
+		return true
+	}
+	v, ok := physicalValue(cc.Type(), value)
+	if !ok {
+		return true
+	}
+	return s.blooms.check(s.index, idx, v)
+}
+
+// physicalValue converts a filter literal to a Parquet value of the column's
+// physical type, as hashed by bloom filters.
+func physicalValue(typ parquet.Type, value any) (pq.Value, bool) {
+	switch typ {
+	case parquet.Types.Int32:
+		if i, ok := value.(int64); ok && i >= math.MinInt32 && i <= math.MaxInt32 {
+			return pq.ValueOf(int32(i)), true
+		}
+	case parquet.Types.Int64:
+		if i, ok := value.(int64); ok {
+			return pq.ValueOf(i), true
+		}
+	case parquet.Types.Double:
+		if f, ok := value.(float64); ok {
+			return pq.ValueOf(f), true
+		}
+	case parquet.Types.ByteArray:
+		if s, ok := value.(string); ok {
+			return pq.ValueOf(s), true
+		}
+	}
+	return pq.Value{}, false
+}
+
+// bloomFilters lazily opens the file with parquet-go, which can read the
+// bloom filters the Arrow reader does not expose. Any failure to read them
+// only disables bloom filter pruning.
+type bloomFilters struct {
+	ctx  context.Context
+	path string
+	f    io.Closer
+	file *pq.File
+	err  error
+}
+
+func (b *bloomFilters) check(rowGroup, column int, v pq.Value) bool {
+	if b.file == nil && b.err == nil {
+		b.open()
+	}
+	if b.err != nil {
+		return true
+	}
+	rowGroups := b.file.RowGroups()
+	if rowGroup >= len(rowGroups) || column >= len(rowGroups[rowGroup].ColumnChunks()) {
+		return true
+	}
+	bf := rowGroups[rowGroup].ColumnChunks()[column].BloomFilter()
+	if bf == nil {
+		return true
+	}
+	ok, err := bf.Check(v)
+	if err != nil {
+		b.err = fmt.Errorf("failed to check bloom filter: %w", err)
+		return true
+	}
+	return ok
+}
+
+func (b *bloomFilters) open() {
+	f, size, err := openReaderAt(b.ctx, b.path)
+	if err != nil {
+		b.err = err
+		return
+	}
+	pf, err := pq.OpenFile(f, size, pq.SkipPageIndex(true))
+	if err != nil {
+		f.Close()
+		b.err = fmt.Errorf("failed to read bloom filters: %w", err)
+		return
+	}
+	b.f, b.file = f, pf
+}
+
+func (b *bloomFilters) close() {
+	if b.f != nil {
+		b.f.Close()
+	}
+}
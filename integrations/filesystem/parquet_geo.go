@@ -0,0 +1,223 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/metadata"
+	"github.com/arrowarc/arrowarc/pkg/geo"
+)
+
+// parquetGeo writes the geometry columns of a GeoParquet file: it writes
+// WKT columns as WKB, and sums up the values of each column for the
+// GeoParquet metadata written on close.
+type parquetGeo struct {
+	meta    *geo.Metadata
+	indices []int
+	columns []*geo.Column
+	wkt     []bool
+	// schema is the schema written: WKT columns as binary, and without the
+	// GeoParquet metadata, which is written on close.
+	schema *arrow.Schema
+}
+
+// bigQueryGeography is the extension name the BigQuery Storage Read API
+// gives GEOGRAPHY columns, read as WKT.
+const bigQueryGeography = "google:sqlType:geography"
+
+// newParquetGeo returns the geometry columns to write for records of
+// schema: those named by its GeoParquet metadata and in geometry, and
+// BigQuery GEOGRAPHY columns; binary columns holding WKB and string columns
+// WKT. It returns nil if there are none.
+func newParquetGeo(schema *arrow.Schema, geometry []string) (*parquetGeo, error) {
+	for _, f := range schema.Fields() {
+		if name, ok := f.Metadata.GetValue("ARROW:extension:name"); ok && name == bigQueryGeography && !slices.Contains(geometry, f.Name) {
+			geometry = append(slices.Clone(geometry), f.Name)
+		}
+	}
+	meta, err := geo.FromSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		if len(geometry) == 0 {
+			return nil, nil
+		}
+		meta = &geo.Metadata{Columns: make(map[string]*geo.Column)}
+	}
+	meta.Version = geo.Version
+	for _, name := range geometry {
+		if _, ok := meta.Columns[name]; !ok {
+			meta.Columns[name] = &geo.Column{Encoding: geo.EncodingWKB}
+		}
+	}
+
+	g := &parquetGeo{meta: meta}
+	fields := schema.Fields()
+	for _, name := range meta.Names() {
+		col := meta.Columns[name]
+		indices := schema.FieldIndices(name)
+		if len(indices) == 0 {
+			if slices.Contains(geometry, name) {
+				return nil, fmt.Errorf("geometry column %q not found", name)
+			}
+			// The column was not kept, by a projection for one.
+			delete(meta.Columns, name)
+			continue
+		}
+		i := indices[0]
+		var wkt bool
+		switch fields[i].Type.ID() {
+		case arrow.BINARY, arrow.LARGE_BINARY:
+		case arrow.STRING, arrow.LARGE_STRING:
+			wkt = true
+			fields[i].Type = arrow.BinaryTypes.Binary
+			fields[i].Metadata = arrow.Metadata{}
+		default:
+			if slices.Contains(geometry, name) || col.Encoding == geo.EncodingWKB {
+				return nil, fmt.Errorf("geometry column %q: WKB or WKT expected, got %s", name, fields[i].Type)
+			}
+			// Other encodings are written as they are.
+			continue
+		}
+		col.Encoding = geo.EncodingWKB
+		col.GeometryTypes, col.BBox = nil, nil
+		g.indices = append(g.indices, i)
+		g.columns = append(g.columns, col)
+		g.wkt = append(g.wkt, wkt)
+	}
+	if len(meta.Columns) == 0 {
+		return nil, nil
+	}
+	if _, ok := meta.Columns[meta.PrimaryColumn]; !ok {
+		meta.PrimaryColumn = meta.Names()[0]
+		if len(geometry) > 0 {
+			meta.PrimaryColumn = geometry[0]
+		}
+	}
+
+	md := schema.Metadata()
+	keys, values := md.Keys(), md.Values()
+	if i := md.FindKey(geo.MetadataKey); i >= 0 {
+		keys = slices.Delete(slices.Clone(keys), i, i+1)
+		values = slices.Delete(slices.Clone(values), i, i+1)
+	}
+	md = arrow.NewMetadata(keys, values)
+	g.schema = arrow.NewSchema(fields, &md)
+	return g, nil
+}
+
+// record returns record as written, its WKT columns as WKB, after counting
+// its geometries. It does not release record.
+func (g *parquetGeo) record(mem memory.Allocator, record arrow.Record) (arrow.Record, error) {
+	cols := slices.Clone(record.Columns())
+	var built []arrow.Array
+	defer func() {
+		for _, col := range built {
+			col.Release()
+		}
+	}()
+	for j, i := range g.indices {
+		col := cols[i]
+		var b *array.BinaryBuilder
+		if g.wkt[j] {
+			b = array.NewBinaryBuilder(mem, arrow.BinaryTypes.Binary)
+			defer b.Release()
+		}
+		for row := 0; row < col.Len(); row++ {
+			if col.IsNull(row) {
+				if b != nil {
+					b.AppendNull()
+				}
+				continue
+			}
+			var shape *geo.Geometry
+			var err error
+			if b != nil {
+				shape, err = geo.ParseWKT(col.ValueStr(row))
+			} else {
+				shape, err = geo.ParseWKB(col.(interface{ Value(int) []byte }).Value(row))
+			}
+			if err != nil {
+				return nil, fmt.Errorf("geometry column %s, row %d: %w", g.schema.Field(i).Name, row, err)
+			}
+			g.columns[j].Add(shape)
+			if b != nil {
+				b.Append(shape.WKB())
+			}
+		}
+		if b != nil {
+			cols[i] = b.NewArray()
+			built = append(built, cols[i])
+		}
+	}
+	return array.NewRecord(g.schema, cols, record.NumRows()), nil
+}
+
+// metadata returns the GeoParquet metadata of the columns written.
+func (g *parquetGeo) metadata() (string, error) {
+	return g.meta.Marshal()
+}
+
+// fileGeoMetadata returns the GeoParquet metadata of a file, or "" if it
+// has none.
+func fileGeoMetadata(kv metadata.KeyValueMetadata) string {
+	if v := kv.FindValue(geo.MetadataKey); v != nil {
+		return *v
+	}
+	return ""
+}
+
+// withGeoMetadata returns s with value as its GeoParquet metadata, or s
+// itself if it has it already.
+func withGeoMetadata(s *arrow.Schema, value string) *arrow.Schema {
+	md := s.Metadata()
+	if md.FindKey(geo.MetadataKey) >= 0 {
+		return s
+	}
+	md = arrow.NewMetadata(append(slices.Clone(md.Keys()), geo.MetadataKey), append(slices.Clone(md.Values()), value))
+	return arrow.NewSchema(s.Fields(), &md)
+}
+
+// withGeoRecord returns record with value as the GeoParquet metadata of its
+// schema. It releases record if it returns another.
+func withGeoRecord(record arrow.Record, value string) arrow.Record {
+	schema := withGeoMetadata(record.Schema(), value)
+	if schema == record.Schema() {
+		return record
+	}
+	defer record.Release()
+	return array.NewRecord(schema, record.Columns(), record.NumRows())
+}
@@ -0,0 +1,143 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// parquetType returns dt with its large lists, which pqarrow cannot write,
+// replaced by lists, and whether it changed.
+func parquetType(dt arrow.DataType) (arrow.DataType, bool) {
+	switch t := dt.(type) {
+	case *arrow.LargeListType:
+		elem, _ := parquetField(t.ElemField())
+		return arrow.ListOfField(elem), true
+	case *arrow.ListType:
+		if elem, changed := parquetField(t.ElemField()); changed {
+			return arrow.ListOfField(elem), true
+		}
+	case *arrow.FixedSizeListType:
+		if elem, changed := parquetField(t.ElemField()); changed {
+			return arrow.FixedSizeListOfField(t.Len(), elem), true
+		}
+	case *arrow.MapType:
+		if item, changed := parquetField(t.ItemField()); changed {
+			key := t.KeyField()
+			m := arrow.MapOfWithMetadata(key.Type, key.Metadata, item.Type, item.Metadata)
+			m.KeysSorted = t.KeysSorted
+			return m, true
+		}
+	case *arrow.StructType:
+		fields := t.Fields()
+		changed := false
+		for i, f := range fields {
+			var c bool
+			fields[i], c = parquetField(f)
+			changed = changed || c
+		}
+		if changed {
+			return arrow.StructOf(fields...), true
+		}
+	}
+	return dt, false
+}
+
+func parquetField(f arrow.Field) (arrow.Field, bool) {
+	dt, changed := parquetType(f.Type)
+	f.Type = dt
+	return f, changed
+}
+
+// parquetSchema returns the schema records of schema are written to
+// Parquet with, or nil if they are written as they are.
+func parquetSchema(schema *arrow.Schema) *arrow.Schema {
+	fields := schema.Fields()
+	changed := false
+	for i, f := range fields {
+		var c bool
+		fields[i], c = parquetField(f)
+		changed = changed || c
+	}
+	if !changed {
+		return nil
+	}
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
+
+// hasLargeType reports whether dt is, or nests, a type with 64-bit
+// offsets.
+func hasLargeType(dt arrow.DataType) bool {
+	switch t := dt.(type) {
+	case *arrow.LargeStringType, *arrow.LargeBinaryType, *arrow.LargeListType:
+		return true
+	case arrow.NestedType:
+		for _, f := range t.Fields() {
+			if hasLargeType(f.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// narrowRecord casts the columns of record to the types of schema, a
+// schema returned by parquetSchema, failing if a large list holds more
+// values than a list can address.
+func narrowRecord(record arrow.Record, schema *arrow.Schema) (arrow.Record, error) {
+	cols := make([]arrow.Array, record.NumCols())
+	defer func() {
+		for _, col := range cols {
+			if col != nil {
+				col.Release()
+			}
+		}
+	}()
+	for i, col := range record.Columns() {
+		dt := schema.Field(i).Type
+		if arrow.TypeEqual(col.DataType(), dt) {
+			col.Retain()
+			cols[i] = col
+			continue
+		}
+		out, err := compute.CastArray(context.Background(), col, compute.SafeCastOptions(dt))
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", schema.Field(i).Name, err)
+		}
+		cols[i] = out
+	}
+	return array.NewRecord(schema, cols, record.NumRows()), nil
+}
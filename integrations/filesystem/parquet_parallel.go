@@ -0,0 +1,219 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+)
+
+// rowGroupBuffer is the number of records a row group worker may read
+// ahead of the consumer.
+const rowGroupBuffer = 2
+
+// rowGroupResult is a record, or the error that ended a row group.
+type rowGroupResult struct {
+	record   arrow.Record
+	err      error
+	rowGroup int
+}
+
+// parallelRowGroups reads row groups with a pool of workers, each with its
+// own record reader. In order, records come out in row group order: each
+// group has its own channel and the consumer drains them one after the
+// other. Otherwise they come out as soon as they are read.
+type parallelRowGroups struct {
+	ctx     context.Context // the reader's, not canceled by Close
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	ordered bool
+
+	groups  []chan rowGroupResult // one per row group when ordered
+	current int
+	merged  chan rowGroupResult // shared by all groups when unordered
+}
+
+func newParallelRowGroups(ctx context.Context, reader *pqarrow.FileReader, columns, rowGroups []int, rowFilter filter.Expr, workers int, ordered bool) *parallelRowGroups {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(rowGroups))
+	p := &parallelRowGroups{ctx: ctx, ordered: ordered}
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	jobs := make(chan int, len(rowGroups))
+	for i := range rowGroups {
+		jobs <- i
+	}
+	close(jobs)
+	if ordered {
+		p.groups = make([]chan rowGroupResult, len(rowGroups))
+		for i := range p.groups {
+			p.groups[i] = make(chan rowGroupResult, rowGroupBuffer)
+		}
+	} else {
+		p.merged = make(chan rowGroupResult, workers*rowGroupBuffer)
+	}
+
+	for w := 0; w < workers; w++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for i := range jobs {
+				out := p.merged
+				if ordered {
+					out = p.groups[i]
+				}
+				readRowGroup(ctx, reader, columns, rowGroups[i], rowFilter, out)
+				if ordered {
+					close(out)
+				}
+			}
+		}()
+	}
+	if !ordered {
+		go func() {
+			p.wg.Wait()
+			close(p.merged)
+		}()
+	}
+	return p
+}
+
+// readRowGroup sends the records of row group rg to out, stopping at the
+// first error or when ctx is done.
+func readRowGroup(ctx context.Context, reader *pqarrow.FileReader, columns []int, rg int, rowFilter filter.Expr, out chan<- rowGroupResult) {
+	send := func(r rowGroupResult) bool {
+		select {
+		case out <- r:
+			return true
+		case <-ctx.Done():
+			if r.record != nil {
+				r.record.Release()
+			}
+			return false
+		}
+	}
+
+	rr, err := reader.GetRecordReader(ctx, columns, []int{rg})
+	if err != nil {
+		send(rowGroupResult{rowGroup: rg, err: fmt.Errorf("failed to create record reader for row group %d: %w", rg, err)})
+		return
+	}
+	defer rr.Release()
+	for rr.Next() {
+		record := rr.Record()
+		if rowFilter == nil {
+			record.Retain()
+		} else {
+			record, err = filter.Apply(ctx, record, rowFilter)
+			if err != nil {
+				send(rowGroupResult{rowGroup: rg, err: fmt.Errorf("failed to filter record: %w", err)})
+				return
+			}
+			if record.NumRows() == 0 {
+				record.Release()
+				continue
+			}
+		}
+		if !send(rowGroupResult{rowGroup: rg, record: record}) {
+			return
+		}
+	}
+	if err := rr.Err(); err != nil && err != io.EOF {
+		send(rowGroupResult{rowGroup: rg, err: fmt.Errorf("failed to read row group %d: %w", rg, err)})
+	}
+}
+
+// Read returns the next record and the row group it came from, or io.EOF
+// and -1 once every row group is read. Workers stop early once the reader's
+// context is done, so Read then returns its error rather than io.EOF.
+func (p *parallelRowGroups) Read() (arrow.Record, int, error) {
+	if !p.ordered {
+		r, ok := <-p.merged
+		if !ok {
+			return nil, -1, p.end()
+		}
+		return r.record, r.rowGroup, r.err
+	}
+	for p.current < len(p.groups) {
+		r, ok := <-p.groups[p.current]
+		if !ok {
+			p.current++
+			continue
+		}
+		return r.record, r.rowGroup, r.err
+	}
+	return nil, -1, p.end()
+}
+
+// end returns the error ending the records: io.EOF, unless the workers
+// were stopped by the reader's context.
+func (p *parallelRowGroups) end() error {
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// Close stops the workers and releases the records they read ahead.
+func (p *parallelRowGroups) Close() {
+	p.cancel()
+	drain := func(ch chan rowGroupResult) {
+		for {
+			select {
+			case r, ok := <-ch:
+				if !ok {
+					return
+				}
+				if r.record != nil {
+					r.record.Release()
+				}
+			default:
+				return
+			}
+		}
+	}
+	p.wg.Wait()
+	if p.ordered {
+		for _, ch := range p.groups {
+			drain(ch)
+		}
+	} else {
+		drain(p.merged)
+	}
+}
@@ -0,0 +1,659 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/arrow/util"
+	"github.com/arrowarc/arrowarc/pkg/compute"
+	"github.com/arrowarc/arrowarc/pkg/seal"
+)
+
+// HivePartitionNull is the directory value used for null partition values.
+const HivePartitionNull = "__HIVE_DEFAULT_PARTITION__"
+
+// DefaultPartitionManifest is the name of the manifest written to the base
+// directory of a PartitionedParquetWriter.
+const DefaultPartitionManifest = "_manifest.json"
+
+// PartitionedParquetWriteOptions configures a PartitionedParquetWriter.
+type PartitionedParquetWriteOptions struct {
+	// PartitionColumns are the columns whose values select the output
+	// directory, as col1=value1/col2=value2/.
+	PartitionColumns []string
+	// KeepPartitionColumns also writes the partition columns into the
+	// files. By default they are only encoded in the directory names.
+	KeepPartitionColumns bool
+	// SortColumns, when set, sorts the rows of each file. Rows are buffered
+	// in memory until a file is complete.
+	SortColumns []ParquetSortingColumn
+	// MaxFileBytes starts a new file in a partition once the current one has
+	// received about this many bytes of Arrow data. Zero means no limit.
+	MaxFileBytes int64
+	// FileOptions tunes each Parquet file. SortingColumns is filled in from
+	// SortColumns when unset.
+	FileOptions *ParquetWriteOptions
+	// ManifestName is the name of the manifest listing the files written,
+	// DefaultPartitionManifest if empty.
+	ManifestName string
+	// Resume continues the output of an earlier run from its manifest. The
+	// files of inputs it completed are kept and new files are numbered
+	// after them; other files are removed so that their inputs can be
+	// written again. While resuming, the open files are completed and the
+	// manifest is saved whenever the records written switch to another
+	// input, as named by RecordSource, so that an interrupted run loses at
+	// most the input it was writing. The records of each input must then
+	// be written one input after the other.
+	Resume bool
+	// Seal encrypts each file once complete and writes its checksum
+	// sidecar. The manifest then lists the encrypted files.
+	Seal *seal.Options
+}
+
+// PartitionManifest lists the files written by a PartitionedParquetWriter.
+type PartitionManifest struct {
+	Files []PartitionFile `json:"files"`
+	// Completed lists the inputs all of whose rows are in Files.
+	Completed []string `json:"completed,omitempty"`
+	// Complete is whether the run that wrote the manifest finished.
+	Complete bool `json:"complete"`
+}
+
+// PartitionFile describes one file in a PartitionManifest.
+type PartitionFile struct {
+	Path      string            `json:"path"` // relative to the base directory
+	Partition map[string]string `json:"partition"`
+	Rows      int64             `json:"rows"`
+	Bytes     int64             `json:"bytes"`
+	Checksum  string            `json:"checksum"`          // SHA-256 of the file, hex encoded
+	Sources   []string          `json:"sources,omitempty"` // inputs the rows were read from
+	// Encryption is seal.EncryptionAge or seal.EncryptionGPG for sealed
+	// files, whose Bytes and Checksum are those of the encrypted file.
+	Encryption string `json:"encryption,omitempty"`
+}
+
+// IsCompleted reports whether m lists source as completed.
+func (m PartitionManifest) IsCompleted(source string) bool {
+	return slices.Contains(m.Completed, source)
+}
+
+// ReadPartitionManifest reads the manifest named name, or
+// DefaultPartitionManifest if empty, from baseDir. A missing manifest reads
+// as an empty one.
+func ReadPartitionManifest(baseDir, name string) (PartitionManifest, error) {
+	if name == "" {
+		name = DefaultPartitionManifest
+	}
+	var m PartitionManifest
+	data, err := os.ReadFile(filepath.Join(baseDir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	return m, nil
+}
+
+// PartitionedParquetWriter splits records by partition column values into
+// Hive-style directories of Parquet files and implements the Writer
+// interface.
+type PartitionedParquetWriter struct {
+	ctx        context.Context
+	baseDir    string
+	opts       PartitionedParquetWriteOptions
+	partIdx    []int
+	fileSchema *arrow.Schema
+	fileIdx    []int // columns of the input written to files
+	sortKeys   []compute.SortKey
+	sealer     *seal.Sealer
+
+	partitions map[string]*partition
+	manifest   PartitionManifest
+	nextSeq    map[string]int // first free file number of partitions resumed
+	source     string         // input of the records last written
+	sources    []string       // inputs of the records written by this run
+	closed     bool
+}
+
+// partition is the state of one output directory.
+type partition struct {
+	dir    string // relative to the base directory
+	values map[string]string
+	seq    int
+
+	writer  *ParquetWriter
+	path    string
+	rows    int64
+	bytes   int64
+	sources []string       // inputs of the rows in the current file
+	pending []arrow.Record // buffered for sorting
+}
+
+// NewPartitionedParquetWriter creates a writer for records with schema
+// under baseDir.
+func NewPartitionedParquetWriter(ctx context.Context, baseDir string, schema *arrow.Schema, opts *PartitionedParquetWriteOptions) (*PartitionedParquetWriter, error) {
+	if opts == nil || len(opts.PartitionColumns) == 0 {
+		return nil, fmt.Errorf("at least one partition column is required")
+	}
+	options := *opts
+	if options.MaxFileBytes < 0 {
+		return nil, fmt.Errorf("max file bytes cannot be negative")
+	}
+	if options.ManifestName == "" {
+		options.ManifestName = DefaultPartitionManifest
+	}
+
+	w := &PartitionedParquetWriter{
+		ctx:        ctx,
+		baseDir:    baseDir,
+		opts:       options,
+		partitions: make(map[string]*partition),
+	}
+	for _, name := range options.PartitionColumns {
+		indices := schema.FieldIndices(name)
+		if len(indices) == 0 {
+			return nil, fmt.Errorf("partition column %q not found", name)
+		}
+		w.partIdx = append(w.partIdx, indices[0])
+	}
+
+	var fields []arrow.Field
+	for i, field := range schema.Fields() {
+		if options.KeepPartitionColumns || !slices.Contains(w.partIdx, i) {
+			w.fileIdx = append(w.fileIdx, i)
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no columns left to write besides the partition columns")
+	}
+	md := schema.Metadata()
+	w.fileSchema = arrow.NewSchema(fields, &md)
+
+	for _, sc := range options.SortColumns {
+		if len(w.fileSchema.FieldIndices(sc.Column)) == 0 {
+			return nil, fmt.Errorf("sort column %q is not written to the files", sc.Column)
+		}
+		w.sortKeys = append(w.sortKeys, compute.SortKey{Column: sc.Column, Descending: sc.Descending, NullsFirst: sc.NullsFirst})
+	}
+	if len(options.SortColumns) > 0 {
+		fileOpts := ParquetWriteOptions{}
+		if options.FileOptions != nil {
+			fileOpts = *options.FileOptions
+		}
+		if fileOpts.SortingColumns == nil {
+			fileOpts.SortingColumns = options.SortColumns
+		}
+		w.opts.FileOptions = &fileOpts
+	}
+	// Check the file options against the file schema up front.
+	if w.opts.FileOptions != nil {
+		if _, err := w.opts.FileOptions.writerProperties(w.fileSchema); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Seal != nil {
+		sealer, err := seal.NewSealer(options.Seal)
+		if err != nil {
+			return nil, err
+		}
+		w.sealer = sealer
+	}
+
+	if err := os.MkdirAll(baseDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if options.Resume {
+		if err := w.resume(); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// resume keeps the files of the inputs the manifest in the base directory
+// lists as completed and removes every other file a writer wrote there.
+func (w *PartitionedParquetWriter) resume() error {
+	m, err := ReadPartitionManifest(w.baseDir, w.opts.ManifestName)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool)
+	w.nextSeq = make(map[string]int)
+	for _, f := range m.Files {
+		completed := m.Complete
+		for _, source := range f.Sources {
+			completed = m.IsCompleted(source)
+			if !completed {
+				break
+			}
+		}
+		if !completed {
+			continue
+		}
+		w.manifest.Files = append(w.manifest.Files, f)
+		keep[filepath.FromSlash(f.Path)] = true
+		dir, name := filepath.Split(filepath.FromSlash(f.Path))
+		var seq int
+		if _, err := fmt.Sscanf(name, "part-%05d.parquet", &seq); err == nil {
+			dir = filepath.Clean(dir)
+			w.nextSeq[dir] = max(w.nextSeq[dir], seq+1)
+		}
+	}
+	w.manifest.Completed = m.Completed
+
+	return filepath.WalkDir(w.baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(w.baseDir, path)
+		if err != nil {
+			return err
+		}
+		if !isPartFile(d.Name()) || keep[rel] || keep[strings.TrimSuffix(rel, seal.ChecksumSuffix)] || filepath.Dir(rel) == "." {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove incomplete file: %w", err)
+		}
+		return nil
+	})
+}
+
+// Write splits record by partition and writes each part to its partition.
+func (w *PartitionedParquetWriter) Write(record arrow.Record) error {
+	if w.closed {
+		return fmt.Errorf("partitioned writer is closed")
+	}
+	if source := RecordSource(record); source != w.source {
+		if w.opts.Resume && w.source != "" {
+			if err := w.checkpoint(); err != nil {
+				return err
+			}
+		}
+		w.source = source
+		if source != "" && !slices.Contains(w.sources, source) {
+			w.sources = append(w.sources, source)
+		}
+	}
+
+	groups := make(map[string][]int)
+	var order []string
+	values := make([]string, len(w.partIdx))
+	for row := 0; row < int(record.NumRows()); row++ {
+		for i, idx := range w.partIdx {
+			values[i] = partitionValue(record.Column(idx), row)
+		}
+		dir := w.partitionDir(values)
+		if _, ok := groups[dir]; !ok {
+			order = append(order, dir)
+			if _, ok := w.partitions[dir]; !ok {
+				w.partitions[dir] = w.newPartition(dir, values)
+			}
+		}
+		groups[dir] = append(groups[dir], row)
+	}
+
+	projected := w.project(record)
+	defer projected.Release()
+
+	for _, dir := range order {
+		rows := groups[dir]
+		var part arrow.Record
+		if len(rows) == int(record.NumRows()) {
+			projected.Retain()
+			part = projected
+		} else {
+			var err error
+			part, err = compute.TakeRows(w.ctx, projected, rows)
+			if err != nil {
+				return err
+			}
+		}
+		err := w.writePartition(w.partitions[dir], part)
+		part.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *PartitionedParquetWriter) partitionDir(values []string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = w.opts.PartitionColumns[i] + "=" + url.PathEscape(v)
+	}
+	return filepath.Join(parts...)
+}
+
+func (w *PartitionedParquetWriter) newPartition(dir string, values []string) *partition {
+	p := &partition{dir: dir, values: make(map[string]string, len(values)), seq: w.nextSeq[dir]}
+	for i, v := range values {
+		p.values[w.opts.PartitionColumns[i]] = v
+	}
+	return p
+}
+
+func partitionValue(arr arrow.Array, row int) string {
+	if arr.IsNull(row) {
+		return HivePartitionNull
+	}
+	return arr.ValueStr(row)
+}
+
+// project returns record limited to the columns written to files.
+func (w *PartitionedParquetWriter) project(record arrow.Record) arrow.Record {
+	if len(w.fileIdx) == int(record.NumCols()) {
+		record.Retain()
+		return record
+	}
+	cols := make([]arrow.Array, len(w.fileIdx))
+	for i, idx := range w.fileIdx {
+		cols[i] = record.Column(idx)
+	}
+	return array.NewRecord(w.fileSchema, cols, record.NumRows())
+}
+
+// writePartition writes or buffers record for p, rotating files by size.
+func (w *PartitionedParquetWriter) writePartition(p *partition, record arrow.Record) error {
+	size := util.TotalRecordSize(record)
+	if w.source != "" && !slices.Contains(p.sources, w.source) {
+		p.sources = append(p.sources, w.source)
+	}
+	if len(w.sortKeys) > 0 {
+		record.Retain()
+		p.pending = append(p.pending, record)
+		p.rows += record.NumRows()
+		p.bytes += size
+		if w.opts.MaxFileBytes > 0 && p.bytes >= w.opts.MaxFileBytes {
+			return w.finishFile(p)
+		}
+		return nil
+	}
+
+	if p.writer == nil {
+		if err := w.openFile(p); err != nil {
+			return err
+		}
+	}
+	if err := p.writer.Write(record); err != nil {
+		return fmt.Errorf("partition %s: %w", p.dir, err)
+	}
+	p.rows += record.NumRows()
+	p.bytes += size
+	if w.opts.MaxFileBytes > 0 && p.bytes >= w.opts.MaxFileBytes {
+		return w.finishFile(p)
+	}
+	return nil
+}
+
+func (w *PartitionedParquetWriter) openFile(p *partition) error {
+	dir := filepath.Join(w.baseDir, p.dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create partition directory: %w", err)
+	}
+	p.path = filepath.Join(p.dir, fmt.Sprintf("part-%05d.parquet", p.seq))
+	p.seq++
+	writer, err := NewParquetWriterWithOptions(filepath.Join(w.baseDir, p.path), w.fileSchema, w.opts.FileOptions)
+	if err != nil {
+		return fmt.Errorf("partition %s: %w", p.dir, err)
+	}
+	p.writer = writer
+	return nil
+}
+
+// finishFile completes the current file of p, sorting buffered rows first,
+// and records it in the manifest.
+func (w *PartitionedParquetWriter) finishFile(p *partition) error {
+	if len(p.pending) > 0 {
+		if err := w.writeSorted(p); err != nil {
+			return err
+		}
+	}
+	if p.writer == nil {
+		return nil
+	}
+
+	err := p.writer.Close()
+	p.writer = nil
+	if err != nil {
+		return fmt.Errorf("partition %s: %w", p.dir, err)
+	}
+	file := PartitionFile{
+		Path:      filepath.ToSlash(p.path),
+		Partition: p.values,
+		Rows:      p.rows,
+		Sources:   p.sources,
+	}
+	if w.sealer != nil {
+		res, err := w.sealer.Seal(filepath.Join(w.baseDir, p.path))
+		if err != nil {
+			return fmt.Errorf("partition %s: %w", p.dir, err)
+		}
+		file.Path = filepath.ToSlash(filepath.Join(p.dir, filepath.Base(res.Path)))
+		file.Bytes, file.Checksum, file.Encryption = res.Bytes, res.Checksum, res.Encryption
+	} else {
+		file.Bytes, file.Checksum, err = checksumFile(filepath.Join(w.baseDir, p.path))
+		if err != nil {
+			return fmt.Errorf("partition %s: %w", p.dir, err)
+		}
+	}
+	w.manifest.Files = append(w.manifest.Files, file)
+	p.rows, p.bytes, p.sources = 0, 0, nil
+	return nil
+}
+
+// isPartFile reports whether name is that of a file a writer writes: a
+// Parquet file, sealed or not, or its checksum sidecar.
+func isPartFile(name string) bool {
+	name = strings.TrimSuffix(name, seal.ChecksumSuffix)
+	name = strings.TrimSuffix(strings.TrimSuffix(name, seal.AgeSuffix), seal.GPGSuffix)
+	matched, _ := filepath.Match("part-*.parquet", name)
+	return matched
+}
+
+// checksumFile returns the size and the hex encoded SHA-256 of the file at
+// path.
+func checksumFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to checksum %s: %w", path, err)
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeSorted writes the buffered records of p, sorted, to a new file.
+func (w *PartitionedParquetWriter) writeSorted(p *partition) error {
+	pending := p.pending
+	p.pending = nil
+	defer func() {
+		for _, rec := range pending {
+			rec.Release()
+		}
+	}()
+
+	combined, err := concatRecords(pending)
+	if err != nil {
+		return err
+	}
+	defer combined.Release()
+	sorted, err := compute.SortRecord(w.ctx, combined, w.sortKeys)
+	if err != nil {
+		return fmt.Errorf("partition %s: %w", p.dir, err)
+	}
+	defer sorted.Release()
+
+	if err := w.openFile(p); err != nil {
+		return err
+	}
+	if err := p.writer.Write(sorted); err != nil {
+		return fmt.Errorf("partition %s: %w", p.dir, err)
+	}
+	return nil
+}
+
+// Manifest returns the files completed so far.
+func (w *PartitionedParquetWriter) Manifest() PartitionManifest {
+	return w.manifest
+}
+
+// Close completes every open file and writes the manifest. Unless the
+// writer's context is done, which means the run was interrupted, the inputs
+// of the records written are listed as completed.
+func (w *PartitionedParquetWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.finishFiles(); err != nil {
+		return err
+	}
+	if w.ctx.Err() == nil {
+		for _, source := range w.sources {
+			w.complete(source)
+		}
+		w.manifest.Complete = true
+	}
+	return w.writeManifest()
+}
+
+// checkpoint completes the open files, lists the input of the records
+// written last as completed and saves the manifest.
+func (w *PartitionedParquetWriter) checkpoint() error {
+	if err := w.finishFiles(); err != nil {
+		return err
+	}
+	w.complete(w.source)
+	return w.writeManifest()
+}
+
+// finishFiles completes the open file of every partition.
+func (w *PartitionedParquetWriter) finishFiles() error {
+	dirs := make([]string, 0, len(w.partitions))
+	for dir := range w.partitions {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var firstErr error
+	for _, dir := range dirs {
+		if err := w.finishFile(w.partitions[dir]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (w *PartitionedParquetWriter) complete(source string) {
+	if !w.manifest.IsCompleted(source) {
+		w.manifest.Completed = append(w.manifest.Completed, source)
+	}
+}
+
+// writeManifest replaces the manifest with the files completed so far.
+func (w *PartitionedParquetWriter) writeManifest() error {
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	// Write a temporary file first, so that a crash leaves the previous
+	// manifest rather than a truncated one.
+	path := filepath.Join(w.baseDir, w.opts.ManifestName)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// concatRecords combines records with the same schema into one record.
+func concatRecords(records []arrow.Record) (arrow.Record, error) {
+	if len(records) == 1 {
+		records[0].Retain()
+		return records[0], nil
+	}
+	schema := records[0].Schema()
+	var rows int64
+	for _, rec := range records {
+		rows += rec.NumRows()
+	}
+	cols := make([]arrow.Array, schema.NumFields())
+	defer func() {
+		for _, col := range cols {
+			if col != nil {
+				col.Release()
+			}
+		}
+	}()
+	for i := range cols {
+		chunks := make([]arrow.Array, len(records))
+		for j, rec := range records {
+			chunks[j] = rec.Column(i)
+		}
+		col, err := array.Concatenate(chunks, memory.DefaultAllocator)
+		if err != nil {
+			return nil, fmt.Errorf("failed to concatenate records: %w", err)
+		}
+		cols[i] = col
+	}
+	return array.NewRecord(schema, cols, rows), nil
+}
@@ -0,0 +1,261 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/util"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"github.com/google/uuid"
+)
+
+// RotationOptions decides when a RotatingWriter starts a new file. A zero
+// value for a limit disables it; with no limits every record goes to a
+// single file.
+type RotationOptions struct {
+	MaxRows  int64         // Rows per file; records are split to honor it exactly
+	MaxBytes int64         // Approximate bytes of Arrow data per file
+	Interval time.Duration // Age of a file after which the next write starts a new one
+}
+
+func (o RotationOptions) enabled() bool {
+	return o.MaxRows > 0 || o.MaxBytes > 0 || o.Interval > 0
+}
+
+// WriterFactory opens a writer for a new file at path.
+type WriterFactory func(path string) (interfaces.Writer, error)
+
+// RotatingWriter writes records to a sequence of files named from a
+// template, starting a new file whenever a rotation limit is reached. It
+// implements the Writer interface.
+//
+// The template may contain these placeholders:
+//
+//	{date}  the UTC date the file was opened, as 2006-01-02
+//	{time}  the UTC time the file was opened, as 150405
+//	{seq}   the zero-padded sequence number of the file, starting at 0
+//	{uuid}  a random UUID
+type RotatingWriter struct {
+	template string
+	opts     RotationOptions
+	open     WriterFactory
+	now      func() time.Time
+
+	writer interfaces.Writer
+	opened time.Time
+	rows   int64
+	bytes  int64
+	seq    int
+	files  []string
+	closed bool
+}
+
+// NewRotatingWriter creates a RotatingWriter that opens files with open.
+// When rotation is enabled the template must contain {seq} or {uuid} so
+// that file names do not repeat.
+func NewRotatingWriter(template string, opts RotationOptions, open WriterFactory) (*RotatingWriter, error) {
+	if template == "" {
+		return nil, fmt.Errorf("file name template cannot be empty")
+	}
+	if open == nil {
+		return nil, fmt.Errorf("writer factory cannot be nil")
+	}
+	if opts.MaxRows < 0 || opts.MaxBytes < 0 || opts.Interval < 0 {
+		return nil, fmt.Errorf("rotation limits cannot be negative")
+	}
+	if opts.enabled() && !strings.Contains(template, "{seq}") && !strings.Contains(template, "{uuid}") {
+		return nil, fmt.Errorf("file name template %q must contain {seq} or {uuid} when rotating", template)
+	}
+	return &RotatingWriter{template: template, opts: opts, open: open, now: time.Now}, nil
+}
+
+// NewRotatingParquetWriter creates a RotatingWriter of Parquet files.
+func NewRotatingParquetWriter(template string, schema *arrow.Schema, writeOpts *ParquetWriteOptions, opts RotationOptions) (*RotatingWriter, error) {
+	if writeOpts != nil {
+		if _, err := writeOpts.writerProperties(schema); err != nil {
+			return nil, err
+		}
+	}
+	return NewRotatingWriter(template, opts, func(path string) (interfaces.Writer, error) {
+		return NewParquetWriterWithOptions(path, schema, writeOpts)
+	})
+}
+
+// NewRotatingCSVWriter creates a RotatingWriter of CSV files. Each file
+// gets its own header when the options ask for one.
+func NewRotatingCSVWriter(ctx context.Context, template string, schema *arrow.Schema, writeOpts *CSVWriteOptions, opts RotationOptions) (*RotatingWriter, error) {
+	return NewRotatingWriter(template, opts, func(path string) (interfaces.Writer, error) {
+		return NewCSVWriter(ctx, path, schema, writeOpts)
+	})
+}
+
+// NewRotatingJSONWriter creates a RotatingWriter of JSON files.
+func NewRotatingJSONWriter(ctx context.Context, template string, opts RotationOptions) (*RotatingWriter, error) {
+	return NewRotatingWriter(template, opts, func(path string) (interfaces.Writer, error) {
+		return NewJSONWriter(ctx, path)
+	})
+}
+
+// NewRotatingIPCWriter creates a RotatingWriter of Arrow IPC files.
+func NewRotatingIPCWriter(ctx context.Context, template string, schema *arrow.Schema, opts RotationOptions) (*RotatingWriter, error) {
+	return NewRotatingWriter(template, opts, func(path string) (interfaces.Writer, error) {
+		w, err := NewIPCRecordWriter(ctx, path, schema)
+		if err != nil {
+			return nil, err
+		}
+		return w.(*IPCRecordWriter), nil
+	})
+}
+
+// Write writes record, splitting it across files if it would exceed
+// MaxRows.
+func (w *RotatingWriter) Write(record arrow.Record) error {
+	if w.closed {
+		return fmt.Errorf("rotating writer is closed")
+	}
+	if w.writer != nil && w.opts.Interval > 0 && w.now().Sub(w.opened) >= w.opts.Interval {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if record.NumRows() == 0 {
+		if w.writer == nil {
+			if err := w.openNext(); err != nil {
+				return err
+			}
+		}
+		return w.writer.Write(record)
+	}
+
+	for offset := int64(0); offset < record.NumRows(); {
+		if w.writer == nil {
+			if err := w.openNext(); err != nil {
+				return err
+			}
+		}
+
+		n := record.NumRows() - offset
+		if w.opts.MaxRows > 0 && n > w.opts.MaxRows-w.rows {
+			n = w.opts.MaxRows - w.rows
+		}
+		if err := w.writeChunk(record, offset, n); err != nil {
+			return err
+		}
+		offset += n
+
+		if (w.opts.MaxRows > 0 && w.rows >= w.opts.MaxRows) || (w.opts.MaxBytes > 0 && w.bytes >= w.opts.MaxBytes) {
+			if err := w.rotate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeChunk writes n rows of record starting at offset to the current file.
+func (w *RotatingWriter) writeChunk(record arrow.Record, offset, n int64) error {
+	chunk := record
+	if n < record.NumRows() {
+		chunk = record.NewSlice(offset, offset+n)
+		defer chunk.Release()
+	}
+	if err := w.writer.Write(chunk); err != nil {
+		return err
+	}
+	w.rows += n
+	w.bytes += util.TotalRecordSize(chunk)
+	return nil
+}
+
+// openNext opens the next file of the sequence.
+func (w *RotatingWriter) openNext() error {
+	w.opened = w.now()
+	path := w.fileName(w.opened)
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	writer, err := w.open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	w.writer = writer
+	w.rows, w.bytes = 0, 0
+	w.seq++
+	w.files = append(w.files, path)
+	return nil
+}
+
+func (w *RotatingWriter) fileName(t time.Time) string {
+	t = t.UTC()
+	r := strings.NewReplacer(
+		"{date}", t.Format(time.DateOnly),
+		"{time}", t.Format("150405"),
+		"{seq}", fmt.Sprintf("%05d", w.seq),
+		"{uuid}", uuid.NewString(),
+	)
+	return r.Replace(w.template)
+}
+
+// rotate closes the current file; the next write opens a new one.
+func (w *RotatingWriter) rotate() error {
+	err := w.writer.Close()
+	w.writer = nil
+	if err != nil {
+		return fmt.Errorf("failed to close %s: %w", w.files[len(w.files)-1], err)
+	}
+	return nil
+}
+
+// Files returns the paths of the files opened so far, in order.
+func (w *RotatingWriter) Files() []string {
+	return append([]string(nil), w.files...)
+}
+
+// Close closes the current file.
+func (w *RotatingWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.writer == nil {
+		return nil
+	}
+	return w.rotate()
+}
@@ -0,0 +1,88 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"github.com/arrowarc/arrowarc/pkg/seal"
+)
+
+// SealedWriter seals the file a writer writes once it is closed: it
+// encrypts the file and writes its checksum sidecar as the seal options
+// say. It implements the Writer interface.
+type SealedWriter struct {
+	writer interfaces.Writer
+	path   string
+	sealer *seal.Sealer
+	result seal.Result
+	closed bool
+}
+
+// NewSealedWriter wraps writer, which writes the file at path, to seal
+// that file on Close.
+func NewSealedWriter(writer interfaces.Writer, path string, opts *seal.Options) (*SealedWriter, error) {
+	if IsStdio(path) {
+		return nil, fmt.Errorf("cannot seal standard output")
+	}
+	sealer, err := seal.NewSealer(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SealedWriter{writer: writer, path: path, sealer: sealer}, nil
+}
+
+func (w *SealedWriter) Write(record arrow.Record) error {
+	return w.writer.Write(record)
+}
+
+// Close closes the underlying writer, then seals its file.
+func (w *SealedWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if err := w.writer.Close(); err != nil {
+		return err
+	}
+	res, err := w.sealer.Seal(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to seal %s: %w", w.path, err)
+	}
+	w.result = res
+	return nil
+}
+
+// Result describes the sealed file, once the writer is closed.
+func (w *SealedWriter) Result() seal.Result {
+	return w.result
+}
@@ -0,0 +1,333 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	duckdb "github.com/arrowarc/arrowarc/integrations/duckdb"
+	grpcsource "github.com/arrowarc/arrowarc/integrations/grpc"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/limit"
+)
+
+// Source formats understood by OpenSource.
+const (
+	SourceParquet = "parquet"
+	SourceCSV     = "csv"
+	SourceAvro    = "avro"
+	SourceIPC     = "ipc"
+	SourceFeather = "feather"
+	SourceDuckDB  = "duckdb"
+	// SourceProtobuf is a stream of length-delimited protobuf messages. It
+	// is never detected from an extension and needs SourceOptions.Protobuf.
+	SourceProtobuf = "protobuf"
+	// SourceGRPC is the response stream of an RPC, named by a grpc:// or
+	// grpcs:// target such as grpc://localhost:50051/pkg.Service/Method.
+	SourceGRPC = "grpc"
+	// SourceEvents is a stream of JSON events: a WebSocket, named by a
+	// ws:// or wss:// URL, or server-sent events at an http(s) URL.
+	SourceEvents = "events"
+)
+
+var sourceExtensions = map[string]string{
+	".parquet": SourceParquet,
+	".pq":      SourceParquet,
+	".csv":     SourceCSV,
+	".tsv":     SourceCSV,
+	".avro":    SourceAvro,
+	".arrow":   SourceIPC,
+	".arrows":  SourceIPC,
+	".ipc":     SourceIPC,
+	".feather": SourceFeather,
+	".duckdb":  SourceDuckDB,
+	".db":      SourceDuckDB,
+}
+
+// SourceOptions configures OpenSource.
+type SourceOptions struct {
+	// Format is one of the Source constants. When empty it is detected
+	// from the file extension, or is SourceDuckDB when Query is set.
+	Format string
+	// Query is the SQL run against a DuckDB source. The path names the
+	// database file; an empty path uses an in-memory database.
+	Query string
+	// ChunkSize is the number of rows per record, where the format allows.
+	ChunkSize int64
+	// MemoryMap memory-maps Parquet and Feather files.
+	MemoryMap bool
+	// CSV configures reading CSV sources. The schema is inferred unless
+	// CSV.Schema is set.
+	CSV csvschema.CSVReadOptions
+	// Protobuf names the descriptor set and message type of protobuf
+	// sources.
+	Protobuf ProtobufReadOptions
+	// GRPC configures gRPC sources.
+	GRPC grpcsource.StreamOptions
+	// Events configures event stream sources. Its zero fields take the
+	// values of NewDefaultEventReadOptions, and nil Headers those of the
+	// HTTP credentials covering the URL.
+	Events EventReadOptions
+	// Offset skips the first rows of the source, and Limit stops reading
+	// after that many rows, zero meaning no limit. Parquet, CSV and DuckDB
+	// sources skip rows without converting them, or without reading them
+	// at all.
+	Offset int64
+	Limit  int64
+	// Columns names the columns a Parquet source reads, the others being
+	// skipped. Other sources read every column.
+	Columns []string
+	// Timestamps controls how the timestamp columns of CSV sources are
+	// read. Setting it infers timestamp columns of CSV sources whose
+	// schema is inferred, from values in its format.
+	Timestamps TimestampOptions
+	// HTTP configures reading sources given as URLs, and the credentials
+	// sent to them. Nil uses the options of the context, set by
+	// WithHTTPOptions, or else NewDefaultHTTPOptions, which sends none.
+	HTTP *HTTPOptions
+}
+
+// DetectSourceFormat returns the source format for path from its extension.
+func DetectSourceFormat(path string) (string, error) {
+	if IsStdio(path) {
+		return "", fmt.Errorf("cannot detect the format of standard input; set it explicitly")
+	}
+	if grpcsource.IsTarget(path) {
+		return SourceGRPC, nil
+	}
+	if IsEventStream(path) {
+		return SourceEvents, nil
+	}
+	if format, ok := sourceExtensions[strings.ToLower(FileExt(path))]; ok {
+		return format, nil
+	}
+	return "", fmt.Errorf("cannot detect the format of %q; set it explicitly", displayPath(path))
+}
+
+// displayPath returns path, or for a URL the part safe to show in errors.
+func displayPath(path string) string {
+	if IsURL(path) {
+		return displayURL(path)
+	}
+	return path
+}
+
+// OpenSource opens a reader over any supported source: a Parquet, CSV, Avro,
+// Arrow IPC stream, Feather or delimited protobuf file, or the result of a
+// DuckDB query, or the responses of a gRPC call. Files may be http:// or
+// https:// URLs, and a path of StdioPath reads a CSV, Avro, protobuf or IPC
+// stream from standard input.
+func OpenSource(ctx context.Context, path string, opts *SourceOptions) (FileReader, error) {
+	if opts == nil {
+		opts = &SourceOptions{}
+	}
+	if opts.HTTP != nil {
+		ctx = WithHTTPOptions(ctx, opts.HTTP)
+	}
+	format := opts.Format
+	if format == "" {
+		if opts.Query != "" {
+			format = SourceDuckDB
+		} else {
+			var err error
+			if format, err = DetectSourceFormat(path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return nil, fmt.Errorf("offset and limit cannot be negative")
+	}
+
+	reader, err := openSource(ctx, path, format, opts)
+	if err != nil || opts.Offset == 0 && opts.Limit == 0 {
+		return reader, err
+	}
+	switch format {
+	case SourceParquet, SourceCSV, SourceDuckDB:
+		return reader, nil
+	}
+	window, err := limit.NewReader(reader, opts.Offset, opts.Limit)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	return &windowReader{Reader: window, schema: reader.Schema()}, nil
+}
+
+// windowReader is a FileReader returning a window of the rows of another,
+// for sources that cannot skip rows themselves.
+type windowReader struct {
+	*limit.Reader
+	schema *arrow.Schema
+}
+
+func (r *windowReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// openSource opens a reader over a source of the given format, leaving
+// the offset and limit to formats that apply them as they read.
+func openSource(ctx context.Context, path, format string, opts *SourceOptions) (FileReader, error) {
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 1024
+	}
+
+	switch format {
+	case SourceParquet:
+		return NewParquetReader(ctx, path, &ParquetReadOptions{
+			ChunkSize: chunkSize,
+			MemoryMap: opts.MemoryMap,
+			Columns:   opts.Columns,
+			Offset:    opts.Offset,
+			Limit:     opts.Limit,
+		})
+	case SourceCSV:
+		csvOpts := opts.CSV
+		if csvOpts.Delimiter == 0 {
+			csvOpts.Delimiter = ','
+			if strings.EqualFold(filepath.Ext(path), ".tsv") {
+				csvOpts.Delimiter = '\t'
+			}
+		}
+		if opts.Timestamps != (TimestampOptions{}) && !csvOpts.ParseTimestamps {
+			switch opts.Timestamps.Format {
+			case TimestampEpoch:
+				// Epoch timestamps cannot be told from integers.
+			case "", TimestampRFC3339:
+				csvOpts.ParseTimestamps, csvOpts.TimestampFormat = true, time.RFC3339Nano
+			default:
+				csvOpts.ParseTimestamps, csvOpts.TimestampFormat = true, opts.Timestamps.Format
+			}
+		}
+		schema, err := InferCSVSchema(ctx, path, &csvOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to infer schema of %s: %w", path, err)
+		}
+		return NewCSVReader(ctx, path, schema, &CSVReadOptions{
+			ChunkSize:        chunkSize,
+			Delimiter:        csvOpts.Delimiter,
+			HasHeader:        csvOpts.HasHeader,
+			NullValues:       csvOpts.NullValues,
+			StringsCanBeNull: csvOpts.StringsCanBeNull,
+			Offset:           opts.Offset,
+			Limit:            opts.Limit,
+			Timestamps:       opts.Timestamps,
+		})
+	case SourceFeather:
+		return NewFeatherReader(ctx, path, &FeatherReadOptions{MemoryMap: opts.MemoryMap})
+	case SourceAvro:
+		return NewAvroReader(ctx, path, &AvroReadOptions{ChunkSize: chunkSize})
+	case SourceProtobuf:
+		pbOpts := opts.Protobuf
+		if pbOpts.ChunkSize <= 0 {
+			pbOpts.ChunkSize = int(chunkSize)
+		}
+		return NewProtobufReader(ctx, path, &pbOpts)
+	case SourceGRPC:
+		grpcOpts := opts.GRPC
+		if grpcOpts.ChunkSize <= 0 {
+			grpcOpts.ChunkSize = int(chunkSize)
+		}
+		return grpcsource.NewStreamReader(ctx, path, &grpcOpts)
+	case SourceEvents:
+		eventOpts := opts.Events
+		defaults := NewDefaultEventReadOptions()
+		if eventOpts.Headers == nil {
+			headers, err := httpOptionsFrom(ctx).HeadersFor(path)
+			if err != nil {
+				return nil, err
+			}
+			eventOpts.Headers = headers
+		}
+		if eventOpts.BatchSize <= 0 {
+			eventOpts.BatchSize = int(chunkSize)
+		}
+		if eventOpts.FlushInterval <= 0 {
+			eventOpts.FlushInterval = defaults.FlushInterval
+		}
+		return NewEventReader(ctx, path, &eventOpts)
+	case SourceIPC:
+		// .arrow and .ipc files may hold either IPC format.
+		if feather, err := IsFeatherFile(path); err == nil && feather {
+			return NewFeatherReader(ctx, path, &FeatherReadOptions{MemoryMap: opts.MemoryMap})
+		}
+		reader, err := NewIPCRecordReader(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return reader.(FileReader), nil
+	case SourceDuckDB:
+		if opts.Query == "" {
+			return nil, fmt.Errorf("a query is required to read from DuckDB")
+		}
+		if path == "" {
+			path = ":memory:"
+		}
+		return duckdb.NewDuckDBReader(ctx, path, &duckdb.DuckDBReadOptions{
+			Extensions: duckdb.DefaultExtensions(),
+			Query:      opts.Query,
+			Offset:     opts.Offset,
+			Limit:      opts.Limit,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported source format %q", format)
+	}
+}
+
+// InferCSVSchema infers the schema of the CSV file or URL at path. For
+// StdioPath it is inferred from a sample of standard input, which is left
+// unread.
+func InferCSVSchema(ctx context.Context, path string, opts *csvschema.CSVReadOptions) (*arrow.Schema, error) {
+	switch {
+	case IsStdio(path):
+		sample, err := SampleStdin()
+		if err != nil {
+			return nil, err
+		}
+		return csvschema.InferCSVArrowSchemaFromReader(ctx, bytes.NewReader(sample), opts)
+	case IsURL(path):
+		stream, err := openHTTPStream(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		defer stream.Close()
+		return csvschema.InferCSVArrowSchemaFromReader(ctx, stream, opts)
+	default:
+		return csvschema.InferCSVArrowSchema(ctx, path, opts)
+	}
+}
@@ -0,0 +1,113 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+)
+
+// SourceMetadataKey is the schema metadata key naming the input a record
+// was read from, as set by NewSourceTaggingReader.
+const SourceMetadataKey = "arrowarc.source"
+
+// SourcedReader is a reader that tells which input the record it last
+// returned came from.
+type SourcedReader interface {
+	Source() string
+}
+
+// RecordSource returns the input record was read from, or "" if it is not
+// tagged with one.
+func RecordSource(record arrow.Record) string {
+	md := record.Schema().Metadata()
+	if i := md.FindKey(SourceMetadataKey); i >= 0 {
+		return md.Values()[i]
+	}
+	return ""
+}
+
+// sourceTaggingReader tags each record with the input it came from.
+type sourceTaggingReader struct {
+	FileReader
+	source  string
+	schemas map[string]*arrow.Schema
+}
+
+// NewSourceTaggingReader returns a reader over the records of r that names
+// the input of each record under SourceMetadataKey in its schema metadata:
+// the Source of r if it is a SourcedReader, or source otherwise.
+func NewSourceTaggingReader(r FileReader, source string) FileReader {
+	return &sourceTaggingReader{FileReader: r, source: source, schemas: make(map[string]*arrow.Schema)}
+}
+
+func (r *sourceTaggingReader) Read() (arrow.Record, error) {
+	record, err := r.FileReader.Read()
+	if err != nil || record == nil {
+		return record, err
+	}
+	source := r.source
+	if sourced, ok := r.FileReader.(SourcedReader); ok && sourced.Source() != "" {
+		source = sourced.Source()
+	}
+	schema, ok := r.schemas[source]
+	if !ok {
+		schema = withSource(record.Schema(), source)
+		r.schemas[source] = schema
+	}
+	tagged := array.NewRecord(schema, record.Columns(), record.NumRows())
+	record.Release()
+	return tagged, nil
+}
+
+// Position returns the position of the underlying reader, if it tells.
+func (r *sourceTaggingReader) Position() string {
+	if positioned, ok := r.FileReader.(interfaces.PositionedReader); ok {
+		return positioned.Position()
+	}
+	return ""
+}
+
+// withSource returns schema with source under SourceMetadataKey.
+func withSource(schema *arrow.Schema, source string) *arrow.Schema {
+	md := schema.Metadata()
+	keys, values := make([]string, 0, md.Len()+1), make([]string, 0, md.Len()+1)
+	for i, key := range md.Keys() {
+		if key != SourceMetadataKey {
+			keys = append(keys, key)
+			values = append(values, md.Values()[i])
+		}
+	}
+	keys = append(keys, SourceMetadataKey)
+	values = append(values, source)
+	tagged := arrow.NewMetadata(keys, values)
+	return arrow.NewSchema(schema.Fields(), &tagged)
+}
@@ -0,0 +1,110 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StdioPath is the path that names standard input when reading and
+// standard output when writing.
+const StdioPath = "-"
+
+// stdinSampleSize bounds the head of standard input kept for schema
+// inference.
+const stdinSampleSize = 1 << 20
+
+// stdin buffers standard input so its head can be sampled before the
+// stream is read.
+var stdin = bufio.NewReaderSize(os.Stdin, stdinSampleSize)
+
+// IsStdio reports whether path names standard input or output.
+func IsStdio(path string) bool {
+	return path == StdioPath
+}
+
+// SampleStdin returns up to the first MiB of standard input, cut after the
+// last complete line, without consuming it: readers opened on StdioPath
+// still see these bytes.
+func SampleStdin() ([]byte, error) {
+	sample, err := stdin.Peek(stdinSampleSize)
+	switch {
+	case err == nil || errors.Is(err, bufio.ErrBufferFull):
+		if i := bytes.LastIndexByte(sample, '\n'); i >= 0 {
+			sample = sample[:i+1]
+		}
+	case errors.Is(err, io.EOF):
+	default:
+		return nil, fmt.Errorf("failed to read standard input: %w", err)
+	}
+	return sample, nil
+}
+
+// OpenFile opens path for sequential reading: a local file, standard input
+// for StdioPath, or a download of an http:// or https:// URL.
+func OpenFile(ctx context.Context, path string) (io.ReadCloser, error) {
+	switch {
+	case IsStdio(path):
+		return io.NopCloser(stdin), nil
+	case IsURL(path):
+		return openHTTPStream(ctx, path)
+	default:
+		return os.Open(path)
+	}
+}
+
+// createFile creates path for writing, or returns a buffered standard
+// output for StdioPath.
+func createFile(path string) (io.WriteCloser, error) {
+	if IsStdio(path) {
+		return stdoutWriter{bufio.NewWriter(os.Stdout)}, nil
+	}
+	return os.Create(path)
+}
+
+// stdoutWriter writes to standard output. Close flushes it and leaves the
+// stream open.
+type stdoutWriter struct {
+	*bufio.Writer
+}
+
+func (w stdoutWriter) Close() error { return w.Flush() }
+
+// errNotSeekable is returned when a format that needs random access is read
+// from standard input.
+func errNotSeekable(format string) error {
+	return fmt.Errorf("%s input cannot be read from standard input; it needs a seekable file", format)
+}
@@ -0,0 +1,275 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// Formats of TimestampOptions.
+const (
+	// TimestampRFC3339 writes RFC 3339 timestamps with as many fractional
+	// digits as needed, and reads RFC 3339 and its ISO 8601 variants.
+	TimestampRFC3339 = "rfc3339"
+	// TimestampEpoch writes and reads timestamps as integer counts of the
+	// column's unit since the Unix epoch.
+	TimestampEpoch = "epoch"
+)
+
+// TimestampOptions controls how the CSV and JSON readers and writers treat
+// timestamp columns, so that every converter handles them alike. The zero
+// value keeps the default behavior of each.
+type TimestampOptions struct {
+	// Unit, one of s, ms, us or ns, normalizes timestamp columns to that
+	// unit. Empty keeps the unit of each column.
+	Unit string
+	// TimeZone is an IANA time zone name such as UTC or Europe/Paris.
+	// Readers attach it to timestamp columns without one, reading values
+	// without an offset as times in that zone; writers convert timestamps
+	// to it before formatting them. Empty keeps the zone of each column.
+	TimeZone string
+	// Format is TimestampRFC3339, TimestampEpoch or a Go time layout.
+	// Empty means TimestampRFC3339, except that the JSON writer then keeps
+	// arrow's format.
+	Format string
+}
+
+// ParseTimeUnit returns the time unit named s, ms, us or ns.
+func ParseTimeUnit(name string) (arrow.TimeUnit, error) {
+	for _, unit := range []arrow.TimeUnit{arrow.Second, arrow.Millisecond, arrow.Microsecond, arrow.Nanosecond} {
+		if unit.String() == name {
+			return unit, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time unit %q: want s, ms, us or ns", name)
+}
+
+// timestampCodec is the checked form of TimestampOptions.
+type timestampCodec struct {
+	unit    arrow.TimeUnit
+	hasUnit bool
+	zone    string
+	loc     *time.Location // nil to keep the zone of each column
+	format  string         // empty for the default
+}
+
+// codec checks o and returns its codec, or nil for the zero value.
+func (o TimestampOptions) codec() (*timestampCodec, error) {
+	if o == (TimestampOptions{}) {
+		return nil, nil
+	}
+	c := &timestampCodec{zone: o.TimeZone, format: o.Format}
+	if o.Unit != "" {
+		unit, err := ParseTimeUnit(o.Unit)
+		if err != nil {
+			return nil, err
+		}
+		c.unit, c.hasUnit = unit, true
+	}
+	if o.TimeZone != "" {
+		loc, err := time.LoadLocation(o.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone: %w", err)
+		}
+		c.loc = loc
+	}
+	if c.format == TimestampRFC3339 {
+		c.format = ""
+	}
+	return c, nil
+}
+
+// layout reports whether the format is a Go time layout.
+func (c *timestampCodec) layout() bool {
+	return c.format != "" && c.format != TimestampEpoch
+}
+
+// columnType returns the type timestamp columns of type dt are read as.
+func (c *timestampCodec) columnType(dt *arrow.TimestampType) *arrow.TimestampType {
+	out := &arrow.TimestampType{Unit: dt.Unit, TimeZone: dt.TimeZone}
+	if c.hasUnit {
+		out.Unit = c.unit
+	}
+	if out.TimeZone == "" {
+		out.TimeZone = c.zone
+	}
+	return out
+}
+
+// schema returns schema with the types its timestamp columns are read as.
+func (c *timestampCodec) schema(schema *arrow.Schema) *arrow.Schema {
+	fields := schema.Fields()
+	changed := false
+	for i, f := range fields {
+		if dt, ok := f.Type.(*arrow.TimestampType); ok {
+			fields[i].Type = c.columnType(dt)
+			changed = true
+		}
+	}
+	if !changed {
+		return schema
+	}
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
+
+// parse parses s as a timestamp of type dt, a type returned by columnType.
+func (c *timestampCodec) parse(s string, dt *arrow.TimestampType) (arrow.Timestamp, error) {
+	if c.format == TimestampEpoch {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid epoch timestamp %q", s)
+		}
+		return arrow.Timestamp(n), nil
+	}
+	loc, err := dt.GetZone()
+	if err != nil {
+		return 0, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	if c.layout() {
+		t, err := time.ParseInLocation(c.format, s, loc)
+		if err != nil {
+			return 0, err
+		}
+		return arrow.TimestampFromTime(t, dt.Unit)
+	}
+	ts, hasZone, err := arrow.TimestampFromStringInLocation(s, dt.Unit, time.UTC)
+	if err != nil || hasZone || loc == time.UTC {
+		return ts, err
+	}
+	// arrow reads times without an offset as UTC, so read their wall
+	// clock again in loc.
+	t := ts.ToTime(dt.Unit)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	return arrow.TimestampFromTime(t, dt.Unit)
+}
+
+// parseColumn parses the strings of arr as timestamps of type dt, a type
+// returned by columnType.
+func (c *timestampCodec) parseColumn(mem memory.Allocator, arr *array.String, dt *arrow.TimestampType) (arrow.Array, error) {
+	b := array.NewTimestampBuilder(mem, dt)
+	defer b.Release()
+	b.Reserve(arr.Len())
+	for i := 0; i < arr.Len(); i++ {
+		if arr.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		v, err := c.parse(arr.Value(i), dt)
+		if err != nil {
+			return nil, err
+		}
+		b.Append(v)
+	}
+	return b.NewArray(), nil
+}
+
+// epoch returns v, of type dt, as a count of the codec's unit.
+func (c *timestampCodec) epoch(v arrow.Timestamp, dt *arrow.TimestampType) int64 {
+	if !c.hasUnit || c.unit == dt.Unit {
+		return int64(v)
+	}
+	if c.unit > dt.Unit {
+		return int64(v) * int64(dt.Unit.Multiplier()/c.unit.Multiplier())
+	}
+	// Round towards negative infinity, so that times before the epoch
+	// truncate like times after it.
+	d := int64(c.unit.Multiplier() / dt.Unit.Multiplier())
+	n := int64(v) / d
+	if int64(v)%d < 0 {
+		n--
+	}
+	return n
+}
+
+// formatValue formats v, of type dt, as text.
+func (c *timestampCodec) formatValue(v arrow.Timestamp, dt *arrow.TimestampType) (string, error) {
+	if c.format == TimestampEpoch {
+		return strconv.FormatInt(c.epoch(v, dt), 10), nil
+	}
+	toTime, err := dt.GetToTimeFunc()
+	if err != nil {
+		return "", err
+	}
+	t := toTime(v)
+	if c.loc != nil {
+		t = t.In(c.loc)
+	}
+	if c.hasUnit {
+		t = t.Truncate(c.unit.Multiplier())
+	}
+	if c.layout() {
+		return t.Format(c.format), nil
+	}
+	return t.Format(time.RFC3339Nano), nil
+}
+
+// formatColumn returns arr formatted as strings, or as integers for
+// TimestampEpoch.
+func (c *timestampCodec) formatColumn(mem memory.Allocator, arr *array.Timestamp) (arrow.Array, error) {
+	dt := arr.DataType().(*arrow.TimestampType)
+	if c.format == TimestampEpoch {
+		b := array.NewInt64Builder(mem)
+		defer b.Release()
+		b.Reserve(arr.Len())
+		for i := 0; i < arr.Len(); i++ {
+			if arr.IsNull(i) {
+				b.AppendNull()
+			} else {
+				b.Append(c.epoch(arr.Value(i), dt))
+			}
+		}
+		return b.NewArray(), nil
+	}
+	b := array.NewStringBuilder(mem)
+	defer b.Release()
+	b.Reserve(arr.Len())
+	for i := 0; i < arr.Len(); i++ {
+		if arr.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		s, err := c.formatValue(arr.Value(i), dt)
+		if err != nil {
+			return nil, err
+		}
+		b.Append(s)
+	}
+	return b.NewArray(), nil
+}
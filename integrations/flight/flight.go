@@ -0,0 +1,142 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// FlightWriter puts records to an Arrow Flight server with DoPut and
+// implements the Writer interface. Each record is put in a call of its
+// own so that a failed call can be retried as a whole; a call that failed
+// after the server stored its record may store it twice.
+type FlightWriter struct {
+	ctx        context.Context
+	client     flight.Client
+	descriptor *flight.FlightDescriptor
+	schema     *arrow.Schema
+	alloc      memory.Allocator
+	retry      *retry.Policy
+}
+
+// FlightWriteOptions defines options for writing to a Flight server.
+type FlightWriteOptions struct {
+	// Path is the path of the flight descriptor records are put under.
+	Path []string
+	// DialOptions configure the connection, which is insecure by default.
+	DialOptions []grpc.DialOption
+	// Retry is the policy of failed puts. Without a classifier of its own,
+	// it retries transient gRPC errors. Defaults to retry.Default.
+	Retry *retry.Policy
+}
+
+// NewFlightWriter creates a writer putting records of the given schema to
+// the Flight server at addr.
+func NewFlightWriter(ctx context.Context, addr string, schema *arrow.Schema, opts *FlightWriteOptions) (*FlightWriter, error) {
+	if opts == nil {
+		opts = &FlightWriteOptions{}
+	}
+	dialOpts := opts.DialOptions
+	if len(dialOpts) == 0 {
+		dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	client, err := flight.NewClientWithMiddlewareCtx(ctx, addr, nil, nil, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Flight client: %w", err)
+	}
+	return &FlightWriter{
+		ctx:        ctx,
+		client:     client,
+		descriptor: &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: opts.Path},
+		schema:     schema,
+		alloc:      pool.GetAllocator(),
+		retry:      opts.Retry.OrRetryable(retry.IsRetryable),
+	}, nil
+}
+
+// Write puts a record, retrying the call under the writer's policy.
+func (w *FlightWriter) Write(record arrow.Record) error {
+	if !w.schema.Equal(record.Schema()) {
+		return fmt.Errorf("schema mismatch: expected %v but got %v", w.schema, record.Schema())
+	}
+	if err := w.retry.Do(w.ctx, func(ctx context.Context) error { return w.put(ctx, record) }); err != nil {
+		return fmt.Errorf("failed to put record: %w", err)
+	}
+	return nil
+}
+
+// put sends record in one DoPut call and waits for the server to end it.
+func (w *FlightWriter) put(ctx context.Context, record arrow.Record) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := w.client.DoPut(ctx)
+	if err != nil {
+		return err
+	}
+	writer := flight.NewRecordWriter(stream, ipc.WithSchema(w.schema), ipc.WithAllocator(w.alloc))
+	writer.SetFlightDescriptor(w.descriptor)
+	// A send fails with io.EOF when the server ended the call; its status
+	// is then returned by Recv.
+	if err := writer.Write(record); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if err := writer.Close(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Close closes the connection to the server.
+func (w *FlightWriter) Close() error {
+	defer pool.PutAllocator(w.alloc)
+	return w.client.Close()
+}
@@ -0,0 +1,168 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// FlightSQLReader runs a query on a Flight SQL server and streams its
+// results with DoGet, one endpoint after the other, and implements the
+// Reader interface. Every endpoint is fetched from the server the query
+// was sent to.
+type FlightSQLReader struct {
+	ctx       context.Context
+	client    *flightsql.Client
+	endpoints []*flight.FlightEndpoint
+	stream    *flight.Reader
+	schema    *arrow.Schema
+	alloc     memory.Allocator
+}
+
+// FlightSQLReadOptions defines options for querying a Flight SQL server.
+type FlightSQLReadOptions struct {
+	// DialOptions configure the connection, which is insecure by default.
+	DialOptions []grpc.DialOption
+	// Headers are sent with every call, such as an authorization header.
+	Headers map[string]string
+}
+
+// NewFlightSQLReader runs query on the Flight SQL server at addr.
+func NewFlightSQLReader(ctx context.Context, addr, query string, opts *FlightSQLReadOptions) (*FlightSQLReader, error) {
+	if opts == nil {
+		opts = &FlightSQLReadOptions{}
+	}
+	dialOpts := opts.DialOptions
+	if len(dialOpts) == 0 {
+		dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	for name, value := range opts.Headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, name, value)
+	}
+	client, err := flightsql.NewClientCtx(ctx, addr, nil, nil, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Flight SQL client: %w", err)
+	}
+	r := &FlightSQLReader{ctx: ctx, client: client, alloc: pool.GetAllocator()}
+	client.Alloc = r.alloc
+
+	info, err := client.Execute(ctx, query)
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	if len(info.Schema) > 0 {
+		if r.schema, err = flight.DeserializeSchema(info.Schema, r.alloc); err != nil {
+			r.Close()
+			return nil, fmt.Errorf("failed to read the schema of the results: %w", err)
+		}
+	}
+	r.endpoints = info.Endpoint
+	// Open the first endpoint, whose stream starts with the schema the
+	// server may have left out of the flight info.
+	if err := r.nextEndpoint(); err != nil && !errors.Is(err, io.EOF) {
+		r.Close()
+		return nil, err
+	}
+	if r.schema == nil {
+		if r.stream == nil {
+			r.Close()
+			return nil, fmt.Errorf("the query returned neither a schema nor results")
+		}
+		r.schema = r.stream.Schema()
+	}
+	return r, nil
+}
+
+// nextEndpoint opens the stream of the next endpoint, or returns io.EOF
+// when there is none left.
+func (r *FlightSQLReader) nextEndpoint() error {
+	if r.stream != nil {
+		r.stream.Release()
+		r.stream = nil
+	}
+	if len(r.endpoints) == 0 {
+		return io.EOF
+	}
+	endpoint := r.endpoints[0]
+	r.endpoints = r.endpoints[1:]
+	stream, err := r.client.DoGet(r.ctx, endpoint.Ticket)
+	if err != nil {
+		return fmt.Errorf("failed to fetch results: %w", err)
+	}
+	r.stream = stream
+	return nil
+}
+
+// Schema returns the schema of the results.
+func (r *FlightSQLReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Read returns the next record of the results, or io.EOF after the last
+// endpoint.
+func (r *FlightSQLReader) Read() (arrow.Record, error) {
+	for r.stream != nil {
+		rec, err := r.stream.Read()
+		if err == nil {
+			rec.Retain()
+			return rec, nil
+		}
+		if !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read results: %w", err)
+		}
+		if err := r.nextEndpoint(); err != nil {
+			return nil, err
+		}
+	}
+	return nil, io.EOF
+}
+
+// Close closes the connection to the server.
+func (r *FlightSQLReader) Close() error {
+	defer pool.PutAllocator(r.alloc)
+	if r.stream != nil {
+		r.stream.Release()
+		r.stream = nil
+	}
+	return r.client.Close()
+}
@@ -0,0 +1,322 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Compression codecs of the record batches a relay sends.
+const (
+	RelayCompressionNone = "none"
+	RelayCompressionLZ4  = "lz4"
+	RelayCompressionZstd = "zstd"
+)
+
+// RelayReader is a source of records served by a FlightRelayServer. Every
+// file reader of the filesystem integration is one.
+type RelayReader interface {
+	Read() (arrow.Record, error)
+	Schema() *arrow.Schema
+	Close() error
+}
+
+// RelaySource opens a new reader over a source for each call fetching it.
+type RelaySource func(ctx context.Context) (RelayReader, error)
+
+// RelayTicket is the JSON ticket of a DoGet call to a FlightRelayServer.
+type RelayTicket struct {
+	// Source names the source to stream.
+	Source string `json:"source"`
+	// Compression is the codec of the record batches. Empty uses the
+	// server's default.
+	Compression string `json:"compression,omitempty"`
+}
+
+// FlightRelayServerOptions configures a FlightRelayServer.
+type FlightRelayServerOptions struct {
+	// Compression is the codec of calls whose ticket does not choose one.
+	// Empty sends uncompressed batches.
+	Compression string
+}
+
+// FlightRelayServer is a Flight service streaming named sources with
+// DoGet, so that another instance reads them with a FlightRelayReader and
+// the records stay in Arrow format across the network. Each call opens
+// its source anew. ListFlights and GetFlightInfo describe the sources,
+// with one endpoint each, served by this server.
+type FlightRelayServer struct {
+	flight.BaseFlightServer
+
+	sources map[string]RelaySource
+	opts    FlightRelayServerOptions
+}
+
+// NewFlightRelayServer creates a relay serving sources, keyed by name.
+func NewFlightRelayServer(sources map[string]RelaySource, opts *FlightRelayServerOptions) (*FlightRelayServer, error) {
+	if opts == nil {
+		opts = &FlightRelayServerOptions{}
+	}
+	if _, err := relayCompression(opts.Compression); err != nil {
+		return nil, err
+	}
+	return &FlightRelayServer{sources: sources, opts: *opts}, nil
+}
+
+// relayCompression returns the IPC writer option of codec, or nil for no
+// compression.
+func relayCompression(codec string) (ipc.Option, error) {
+	switch codec {
+	case "", RelayCompressionNone:
+		return nil, nil
+	case RelayCompressionLZ4:
+		return ipc.WithLZ4(), nil
+	case RelayCompressionZstd:
+		return ipc.WithZstd(), nil
+	default:
+		return nil, fmt.Errorf("unsupported relay compression %q", codec)
+	}
+}
+
+// ListFlights describes every source, in name order.
+func (s *FlightRelayServer) ListFlights(_ *flight.Criteria, stream flight.FlightService_ListFlightsServer) error {
+	names := make([]string, 0, len(s.sources))
+	for name := range s.sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		info, err := s.flightInfo(stream.Context(), name)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetFlightInfo describes the source named by a descriptor path of one
+// element.
+func (s *FlightRelayServer) GetFlightInfo(ctx context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	if desc.GetType() != flight.DescriptorPATH || len(desc.GetPath()) != 1 {
+		return nil, status.Error(codes.InvalidArgument, "descriptor must be a path naming a source")
+	}
+	return s.flightInfo(ctx, desc.GetPath()[0])
+}
+
+// flightInfo opens the source name for its schema.
+func (s *FlightRelayServer) flightInfo(ctx context.Context, name string) (*flight.FlightInfo, error) {
+	open, ok := s.sources[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no source %q", name)
+	}
+	reader, err := open(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to open source %q: %v", name, err)
+	}
+	schema := reader.Schema()
+	reader.Close()
+	ticket, err := json.Marshal(RelayTicket{Source: name})
+	if err != nil {
+		return nil, err
+	}
+	return &flight.FlightInfo{
+		Schema:           flight.SerializeSchema(schema, memory.DefaultAllocator),
+		FlightDescriptor: &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{name}},
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: ticket}}},
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+// DoGet streams the source named by the ticket until it ends, or the
+// client cancels the call.
+func (s *FlightRelayServer) DoGet(tkt *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	var ticket RelayTicket
+	if err := json.Unmarshal(tkt.GetTicket(), &ticket); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid ticket: %v", err)
+	}
+	open, ok := s.sources[ticket.Source]
+	if !ok {
+		return status.Errorf(codes.NotFound, "no source %q", ticket.Source)
+	}
+	codec := ticket.Compression
+	if codec == "" {
+		codec = s.opts.Compression
+	}
+	compression, err := relayCompression(codec)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx := stream.Context()
+	reader, err := open(ctx)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to open source %q: %v", ticket.Source, err)
+	}
+	defer reader.Close()
+	alloc := pool.GetAllocator()
+	defer pool.PutAllocator(alloc)
+	ipcOpts := []ipc.Option{ipc.WithSchema(reader.Schema()), ipc.WithAllocator(alloc)}
+	if compression != nil {
+		ipcOpts = append(ipcOpts, compression)
+	}
+	writer := flight.NewRecordWriter(stream, ipcOpts...)
+	defer writer.Close()
+
+	var batches, rows int64
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to read source %q: %v", ticket.Source, err)
+		}
+		err = writer.Write(record)
+		rows += record.NumRows()
+		record.Release()
+		if err != nil {
+			return err
+		}
+		batches++
+	}
+	logging.FromContext(ctx).Debug("relayed source", "source", ticket.Source, "compression", codec, "batches", batches, "rows", rows)
+	return nil
+}
+
+// FlightRelayReader reads a source of a FlightRelayServer with DoGet and
+// implements the Reader interface.
+type FlightRelayReader struct {
+	client flight.Client
+	cancel context.CancelFunc
+	stream *flight.Reader
+	alloc  memory.Allocator
+}
+
+// FlightRelayReadOptions defines options for reading from a relay.
+type FlightRelayReadOptions struct {
+	// DialOptions configure the connection, which is insecure by default.
+	DialOptions []grpc.DialOption
+	// Headers are sent with the call, such as an authorization header.
+	Headers map[string]string
+	// Compression asks the server for record batches compressed with
+	// RelayCompressionLZ4 or RelayCompressionZstd, or with
+	// RelayCompressionNone for uncompressed ones. Empty leaves the choice
+	// to the server.
+	Compression string
+}
+
+// NewFlightRelayReader reads the source named source of the relay at addr.
+func NewFlightRelayReader(ctx context.Context, addr, source string, opts *FlightRelayReadOptions) (*FlightRelayReader, error) {
+	if opts == nil {
+		opts = &FlightRelayReadOptions{}
+	}
+	if _, err := relayCompression(opts.Compression); err != nil {
+		return nil, err
+	}
+	ticket, err := json.Marshal(RelayTicket{Source: source, Compression: opts.Compression})
+	if err != nil {
+		return nil, err
+	}
+	dialOpts := opts.DialOptions
+	if len(dialOpts) == 0 {
+		dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	client, err := flight.NewClientWithMiddlewareCtx(ctx, addr, nil, nil, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Flight client: %w", err)
+	}
+	for name, value := range opts.Headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, name, value)
+	}
+	r := &FlightRelayReader{client: client, alloc: pool.GetAllocator()}
+	ctx, r.cancel = context.WithCancel(ctx)
+	call, err := client.DoGet(ctx, &flight.Ticket{Ticket: ticket})
+	if err == nil {
+		// The stream starts with the schema, so a missing source or a
+		// rejected ticket fails here.
+		r.stream, err = flight.NewRecordReader(call, ipc.WithAllocator(r.alloc))
+	}
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to fetch source %q: %w", source, err)
+	}
+	return r, nil
+}
+
+// Schema returns the schema of the source.
+func (r *FlightRelayReader) Schema() *arrow.Schema {
+	return r.stream.Schema()
+}
+
+// Read returns the next record of the source, or io.EOF once the server
+// ended the stream.
+func (r *FlightRelayReader) Read() (arrow.Record, error) {
+	rec, err := r.stream.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read relay stream: %w", err)
+	}
+	rec.Retain()
+	return rec, nil
+}
+
+// Close ends the call and closes the connection to the server.
+func (r *FlightRelayReader) Close() error {
+	defer pool.PutAllocator(r.alloc)
+	r.cancel()
+	if r.stream != nil {
+		r.stream.Release()
+		r.stream = nil
+	}
+	return r.client.Close()
+}
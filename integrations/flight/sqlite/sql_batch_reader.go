@@ -34,35 +34,70 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/wrapperspb"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
+// sqliteTypeName lower-cases a declared column type and strips any size
+// or precision arguments, so "VARCHAR(100)" becomes "varchar".
+func sqliteTypeName(dbtype string) string {
+	dbtype = strings.ToLower(strings.TrimSpace(dbtype))
+	if i := strings.IndexByte(dbtype, '('); i >= 0 {
+		dbtype = strings.TrimSpace(dbtype[:i])
+	}
+	return dbtype
+}
+
+// sqliteAffinity returns the storage class a declared column type maps to,
+// following the type affinity rules in https://www.sqlite.org/datatype3.html.
+// Columns with NUMERIC affinity are reported as SQLITE_FLOAT.
+func sqliteAffinity(dbtype string) int {
+	dbtype = sqliteTypeName(dbtype)
+	switch {
+	case dbtype == "":
+		return sqlite3.SQLITE_NULL
+	case strings.Contains(dbtype, "int"):
+		return sqlite3.SQLITE_INTEGER
+	case strings.Contains(dbtype, "char"), strings.Contains(dbtype, "clob"), strings.Contains(dbtype, "text"):
+		return sqlite3.SQLITE_TEXT
+	case strings.Contains(dbtype, "blob"):
+		return sqlite3.SQLITE_BLOB
+	case strings.Contains(dbtype, "real"), strings.Contains(dbtype, "floa"), strings.Contains(dbtype, "doub"):
+		return sqlite3.SQLITE_FLOAT
+	}
+
+	switch dbtype {
+	// SQLite has no date or time storage class; these are stored as ISO-8601 text.
+	case "date", "datetime", "time", "timestamp":
+		return sqlite3.SQLITE_TEXT
+	}
+	return sqlite3.SQLITE_FLOAT
+}
+
 func getArrowTypeFromString(dbtype string) arrow.DataType {
-	dbtype = strings.ToLower(dbtype)
+	dbtype = sqliteTypeName(dbtype)
 	if dbtype == "" {
 		// SQLite may not know the type yet.
 		return &arrow.NullType{}
 	}
-	if strings.HasPrefix(dbtype, "varchar") {
-		return arrow.BinaryTypes.String
-	}
 
 	switch dbtype {
-	case "tinyint":
+	case "tinyint", "bool", "boolean":
 		return arrow.PrimitiveTypes.Int8
 	case "mediumint":
 		return arrow.PrimitiveTypes.Int32
-	case "int", "integer":
-		return arrow.PrimitiveTypes.Int64
 	case "float":
 		return arrow.PrimitiveTypes.Float32
-	case "real", "double":
-		return arrow.PrimitiveTypes.Float64
-	case "blob":
-		return arrow.BinaryTypes.Binary
-	case "text", "date", "char", "clob":
+	}
+
+	switch sqliteAffinity(dbtype) {
+	case sqlite3.SQLITE_INTEGER:
+		return arrow.PrimitiveTypes.Int64
+	case sqlite3.SQLITE_TEXT:
 		return arrow.BinaryTypes.String
+	case sqlite3.SQLITE_BLOB:
+		return arrow.BinaryTypes.Binary
 	default:
-		panic("invalid sqlite type: " + dbtype)
+		return arrow.PrimitiveTypes.Float64
 	}
 }
 
@@ -116,12 +151,19 @@ func NewSqlBatchReaderWithSchema(mem memory.Allocator, schema *arrow.Schema, row
 		switch f.Type.ID() {
 		case arrow.DENSE_UNION, arrow.SPARSE_UNION:
 			rowdest[i] = new(interface{})
-		case arrow.UINT8, arrow.INT8:
+		case arrow.UINT8:
 			if f.Nullable {
 				rowdest[i] = &sql.NullByte{}
 			} else {
 				rowdest[i] = new(uint8)
 			}
+		case arrow.INT8:
+			// scanned wider so negative values don't fail the conversion
+			if f.Nullable {
+				rowdest[i] = &sql.NullInt16{}
+			} else {
+				rowdest[i] = new(int16)
+			}
 		case arrow.INT32:
 			if f.Nullable {
 				rowdest[i] = &sql.NullInt32{}
@@ -182,12 +224,19 @@ func NewSqlBatchReader(mem memory.Allocator, rows *sql.Rows) (*SqlBatchReader, e
 		switch fields[i].Type.ID() {
 		case arrow.DENSE_UNION, arrow.SPARSE_UNION:
 			rowdest[i] = new(interface{})
-		case arrow.UINT8, arrow.INT8:
+		case arrow.UINT8:
 			if fields[i].Nullable {
 				rowdest[i] = &sql.NullByte{}
 			} else {
 				rowdest[i] = new(uint8)
 			}
+		case arrow.INT8:
+			// scanned wider so negative values don't fail the conversion
+			if fields[i].Nullable {
+				rowdest[i] = &sql.NullInt16{}
+			} else {
+				rowdest[i] = new(int16)
+			}
 		case arrow.INT32:
 			if fields[i].Nullable {
 				rowdest[i] = &sql.NullInt32{}
@@ -282,6 +331,14 @@ func (r *SqlBatchReader) Next() bool {
 				} else {
 					fb.(*array.Uint8Builder).Append(v.Byte)
 				}
+			case *int16:
+				fb.(*array.Int8Builder).Append(int8(*v))
+			case *sql.NullInt16:
+				if !v.Valid {
+					fb.AppendNull()
+				} else {
+					fb.(*array.Int8Builder).Append(int8(v.Int16))
+				}
 			case *int64:
 				fb.(*array.Int64Builder).Append(*v)
 			case *sql.NullInt64:
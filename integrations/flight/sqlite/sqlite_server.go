@@ -41,9 +41,16 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
 	"math/rand"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
@@ -73,42 +80,63 @@ func genRandomString() []byte {
 	return out
 }
 
-func prepareQueryForGetTables(cmd flightsql.GetTables) string {
-	var b strings.Builder
-	b.WriteString(`SELECT 'main' AS catalog_name, '' AS schema_name,
-		name AS table_name, type AS table_type FROM sqlite_master WHERE 1=1`)
+// prepareQueryForGetTables lists the tables and views of every attached
+// database, each database being reported as a catalog with a single unnamed
+// schema. Filters are passed as bound arguments.
+func prepareQueryForGetTables(catalogs []string, cmd flightsql.GetTables) (string, []any) {
+	var (
+		b    strings.Builder
+		args []any
+	)
+	b.WriteString(`SELECT catalog_name, db_schema_name, table_name, table_type FROM (`)
+	for i, c := range catalogs {
+		if i != 0 {
+			b.WriteString(" UNION ALL ")
+		}
+		fmt.Fprintf(&b, `SELECT %s AS catalog_name, '' AS db_schema_name,
+			name AS table_name, upper(type) AS table_type FROM %s.sqlite_master
+			WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite\_%%' ESCAPE '\'`,
+			quoteLiteral(c), quoteIdentifier(c))
+	}
+	b.WriteString(") WHERE 1=1")
 
 	if cmd.GetCatalog() != nil {
-		b.WriteString(" and catalog_name = '")
-		b.WriteString(*cmd.GetCatalog())
-		b.WriteByte('\'')
+		b.WriteString(" AND catalog_name = ?")
+		args = append(args, *cmd.GetCatalog())
 	}
 
 	if cmd.GetDBSchemaFilterPattern() != nil {
-		b.WriteString(" and schema_name LIKE '")
-		b.WriteString(*cmd.GetDBSchemaFilterPattern())
-		b.WriteByte('\'')
+		b.WriteString(` AND db_schema_name LIKE ? ESCAPE '\'`)
+		args = append(args, *cmd.GetDBSchemaFilterPattern())
 	}
 
 	if cmd.GetTableNameFilterPattern() != nil {
-		b.WriteString(" and table_name LIKE '")
-		b.WriteString(*cmd.GetTableNameFilterPattern())
-		b.WriteByte('\'')
+		b.WriteString(` AND table_name LIKE ? ESCAPE '\'`)
+		args = append(args, *cmd.GetTableNameFilterPattern())
 	}
 
 	if len(cmd.GetTableTypes()) > 0 {
-		b.WriteString(" and table_type IN (")
+		b.WriteString(" AND table_type IN (")
 		for i, t := range cmd.GetTableTypes() {
 			if i != 0 {
 				b.WriteByte(',')
 			}
-			fmt.Fprintf(&b, "'%s'", t)
+			b.WriteString("upper(?)")
+			args = append(args, t)
 		}
 		b.WriteByte(')')
 	}
 
-	b.WriteString(" order by table_name")
-	return b.String()
+	b.WriteString(" ORDER BY catalog_name, db_schema_name, table_name, table_type")
+	return b.String(), args
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
 }
 
 func prepareQueryForGetKeys(filter string) string {
@@ -179,6 +207,36 @@ func CreateDB() (*sql.DB, error) {
 	return db, nil
 }
 
+// OpenDB opens the SQLite database file at path. A database that doesn't
+// exist yet is created, unless readOnly is set, in which case it must exist
+// and any statement that writes to it is refused by SQLite.
+func OpenDB(path string, readOnly bool) (*sql.DB, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{"_pragma": {"busy_timeout(5000)"}}
+	if readOnly {
+		if _, err := os.Stat(abs); err != nil {
+			return nil, err
+		}
+		params.Set("mode", "ro")
+		params.Add("_pragma", "query_only(1)")
+	}
+	dsn := "file:" + (&url.URL{Path: filepath.ToSlash(abs)}).EscapedPath() + "?" + params.Encode()
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return db, nil
+}
+
 func encodeTransactionQuery(query string, transactionID flightsql.Transaction) ([]byte, error) {
 	return flightsql.CreateStatementQueryTicket(
 		bytes.Join([][]byte{transactionID, []byte(query)}, []byte(":")))
@@ -199,6 +257,18 @@ func decodeTransactionQuery(ticket []byte) (txnID, query string, err error) {
 type Statement struct {
 	stmt   *sql.Stmt
 	params [][]interface{}
+	// schema is the result schema, when it could be determined up front.
+	schema *arrow.Schema
+	// names holds the name of each parameter, "" for anonymous ones.
+	names []string
+	// session is the token of the session the statement belongs to.
+	session string
+}
+
+// transaction is an open transaction and the session it belongs to.
+type transaction struct {
+	*sql.Tx
+	session string
 }
 
 type SQLiteFlightSQLServer struct {
@@ -207,6 +277,8 @@ type SQLiteFlightSQLServer struct {
 
 	prepared         sync.Map
 	openTransactions sync.Map
+	sessions         sync.Map
+	sessionMgr       *sessionManager
 }
 
 func NewSQLiteFlightSQLServer(db *sql.DB) (*SQLiteFlightSQLServer, error) {
@@ -215,6 +287,13 @@ func NewSQLiteFlightSQLServer(db *sql.DB) (*SQLiteFlightSQLServer, error) {
 	for k, v := range SqlInfoResultMap() {
 		ret.RegisterSqlInfo(flightsql.SqlInfo(k), v)
 	}
+
+	// advertise databases opened with OpenDB(path, true) as read-only
+	var queryOnly bool
+	if err := db.QueryRow("PRAGMA query_only").Scan(&queryOnly); err != nil {
+		return nil, err
+	}
+	ret.RegisterSqlInfo(flightsql.SqlInfoFlightSqlServerReadOnly, queryOnly)
 	return ret, nil
 }
 
@@ -249,13 +328,16 @@ func (s *SQLiteFlightSQLServer) DoGetStatement(ctx context.Context, cmd flightsq
 		return nil, nil, err
 	}
 
-	var db dbQueryCtx = s.db
+	db, err := s.conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
 	if txnid != "" {
-		tx, loaded := s.openTransactions.Load(txnid)
+		tx, loaded := s.loadTransaction(ctx, txnid)
 		if !loaded {
 			return nil, nil, fmt.Errorf("%w: invalid transaction id specified: %s", arrow.ErrInvalid, txnid)
 		}
-		db = tx.(*sql.Tx)
+		db = tx
 	}
 
 	return doGetQuery(ctx, s.Alloc, db, query, nil)
@@ -265,61 +347,75 @@ func (s *SQLiteFlightSQLServer) GetFlightInfoCatalogs(_ context.Context, desc *f
 	return s.flightInfoForCommand(desc, schema_ref.Catalogs), nil
 }
 
-func (s *SQLiteFlightSQLServer) DoGetCatalogs(context.Context) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+// catalogQuery lists the databases open on the connection. The "temp"
+// database is an implementation detail of SQLite and is never reported.
+const catalogQuery = `SELECT name FROM pragma_database_list WHERE name != 'temp'`
+
+func (s *SQLiteFlightSQLServer) catalogs(ctx context.Context) ([]string, error) {
+	db, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, catalogQuery+" ORDER BY seq")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLiteFlightSQLServer) DoGetCatalogs(ctx context.Context) (*arrow.Schema, <-chan flight.StreamChunk, error) {
 	// https://www.sqlite.org/cli.html
 	// > The ".databases" command shows a list of all databases open
 	// > in the current connection. There will always be at least
 	// > 2. The first one is "main", the original database opened. The
 	// > second is "temp", the database used for temporary tables.
-	// For our purposes, return only "main" and ignore other databases.
-
-	schema := schema_ref.Catalogs
-
-	catalogs, _, err := array.FromJSON(s.Alloc, arrow.BinaryTypes.String, strings.NewReader(`["main"]`))
+	// Every database other than "temp" is reported as a catalog, so
+	// ATTACHed databases are visible to clients.
+	db, err := s.conn(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer catalogs.Release()
-
-	batch := array.NewRecord(schema, []arrow.Array{catalogs}, 1)
-
-	ch := make(chan flight.StreamChunk, 1)
-	ch <- flight.StreamChunk{Data: batch}
-	close(ch)
-
-	return schema, ch, nil
+	return doGetQuery(ctx, s.Alloc, db, catalogQuery+" ORDER BY name", schema_ref.Catalogs)
 }
 
 func (s *SQLiteFlightSQLServer) GetFlightInfoSchemas(_ context.Context, cmd flightsql.GetDBSchemas, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
 	return s.flightInfoForCommand(desc, schema_ref.DBSchemas), nil
 }
 
-func (s *SQLiteFlightSQLServer) DoGetDBSchemas(_ context.Context, cmd flightsql.GetDBSchemas) (*arrow.Schema, <-chan flight.StreamChunk, error) {
-	// SQLite doesn't support schemas, so pretend we have a single unnamed schema.
-	schema := schema_ref.DBSchemas
-
-	ch := make(chan flight.StreamChunk, 1)
-
-	if cmd.GetDBSchemaFilterPattern() == nil || *cmd.GetDBSchemaFilterPattern() == "" {
-		catalogs, _, err := array.FromJSON(s.Alloc, arrow.BinaryTypes.String, strings.NewReader(`["main"]`))
-		if err != nil {
-			return nil, nil, err
-		}
-		defer catalogs.Release()
-
-		dbSchemas, _, err := array.FromJSON(s.Alloc, arrow.BinaryTypes.String, strings.NewReader(`[""]`))
-		if err != nil {
-			return nil, nil, err
-		}
-		defer dbSchemas.Release()
-
-		batch := array.NewRecord(schema, []arrow.Array{catalogs, dbSchemas}, 1)
-		ch <- flight.StreamChunk{Data: batch}
+func (s *SQLiteFlightSQLServer) DoGetDBSchemas(ctx context.Context, cmd flightsql.GetDBSchemas) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	// SQLite doesn't support schemas, so pretend each catalog has a
+	// single unnamed schema.
+	var (
+		b    strings.Builder
+		args []any
+	)
+	b.WriteString(`SELECT name AS catalog_name, '' AS db_schema_name FROM pragma_database_list WHERE name != 'temp'`)
+	if cmd.GetCatalog() != nil {
+		b.WriteString(" AND name = ?")
+		args = append(args, *cmd.GetCatalog())
 	}
+	if cmd.GetDBSchemaFilterPattern() != nil {
+		b.WriteString(` AND '' LIKE ? ESCAPE '\'`)
+		args = append(args, *cmd.GetDBSchemaFilterPattern())
+	}
+	b.WriteString(" ORDER BY name")
 
-	close(ch)
-
-	return schema, ch, nil
+	db, err := s.conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doGetQuery(ctx, s.Alloc, db, b.String(), schema_ref.DBSchemas, args...)
 }
 
 func (s *SQLiteFlightSQLServer) GetFlightInfoTables(_ context.Context, cmd flightsql.GetTables, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
@@ -331,9 +427,18 @@ func (s *SQLiteFlightSQLServer) GetFlightInfoTables(_ context.Context, cmd fligh
 }
 
 func (s *SQLiteFlightSQLServer) DoGetTables(ctx context.Context, cmd flightsql.GetTables) (*arrow.Schema, <-chan flight.StreamChunk, error) {
-	query := prepareQueryForGetTables(cmd)
+	catalogs, err := s.catalogs(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := s.conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	rows, err := s.db.QueryContext(ctx, query)
+	query, args := prepareQueryForGetTables(catalogs, cmd)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -347,7 +452,13 @@ func (s *SQLiteFlightSQLServer) DoGetTables(ctx context.Context, cmd flightsql.G
 
 	ch := make(chan flight.StreamChunk, 2)
 	if cmd.GetIncludeSchema() {
-		rdr, err = NewSqliteTablesSchemaBatchReader(ctx, s.Alloc, rdr, s.db, query)
+		// read the table list up front: looking up each table's columns
+		// needs a connection, and the database may only have one.
+		rdr, err = materialize(rdr)
+		if err != nil {
+			return nil, nil, err
+		}
+		rdr, err = NewSqliteTablesSchemaBatchReader(ctx, s.Alloc, rdr, db)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -358,6 +469,28 @@ func (s *SQLiteFlightSQLServer) DoGetTables(ctx context.Context, cmd flightsql.G
 	return schema, ch, nil
 }
 
+// materialize reads every record of rdr and releases it, returning a reader
+// over the buffered records.
+func materialize(rdr array.RecordReader) (array.RecordReader, error) {
+	defer rdr.Release()
+
+	var recs []arrow.Record
+	defer func() {
+		for _, r := range recs {
+			r.Release()
+		}
+	}()
+	for rdr.Next() {
+		rec := rdr.Record()
+		rec.Retain()
+		recs = append(recs, rec)
+	}
+	if err := rdr.Err(); err != nil {
+		return nil, err
+	}
+	return array.NewRecordReader(rdr.Schema(), recs)
+}
+
 func (s *SQLiteFlightSQLServer) GetFlightInfoXdbcTypeInfo(_ context.Context, _ flightsql.GetXdbcTypeInfo, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
 	return s.flightInfoForCommand(desc, schema_ref.XdbcTypeInfo), nil
 }
@@ -381,8 +514,20 @@ func (s *SQLiteFlightSQLServer) GetFlightInfoTableTypes(_ context.Context, desc
 }
 
 func (s *SQLiteFlightSQLServer) DoGetTableTypes(ctx context.Context) (*arrow.Schema, <-chan flight.StreamChunk, error) {
-	query := "SELECT DISTINCT type AS table_type FROM sqlite_master"
-	return doGetQuery(ctx, s.Alloc, s.db, query, schema_ref.TableTypes)
+	// GetTables only reports tables and views, so advertise exactly those.
+	tableTypes, _, err := array.FromJSON(s.Alloc, arrow.BinaryTypes.String, strings.NewReader(`["TABLE", "VIEW"]`))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tableTypes.Release()
+
+	batch := array.NewRecord(schema_ref.TableTypes, []arrow.Array{tableTypes}, int64(tableTypes.Len()))
+
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: batch}
+	close(ch)
+
+	return schema_ref.TableTypes, ch, nil
 }
 
 func (s *SQLiteFlightSQLServer) DoPutCommandStatementUpdate(ctx context.Context, cmd flightsql.StatementUpdate) (int64, error) {
@@ -392,14 +537,18 @@ func (s *SQLiteFlightSQLServer) DoPutCommandStatementUpdate(ctx context.Context,
 	)
 
 	if len(cmd.GetTransactionId()) > 0 {
-		tx, loaded := s.openTransactions.Load(string(cmd.GetTransactionId()))
+		tx, loaded := s.loadTransaction(ctx, string(cmd.GetTransactionId()))
 		if !loaded {
 			return -1, status.Error(codes.InvalidArgument, "invalid transaction handle provided")
 		}
 
-		res, err = tx.(*sql.Tx).ExecContext(ctx, cmd.GetQuery())
+		res, err = tx.ExecContext(ctx, cmd.GetQuery())
 	} else {
-		res, err = s.db.ExecContext(ctx, cmd.GetQuery())
+		var db dbConn
+		if db, err = s.conn(ctx); err != nil {
+			return 0, err
+		}
+		res, err = db.ExecContext(ctx, cmd.GetQuery())
 	}
 
 	if err != nil {
@@ -409,58 +558,229 @@ func (s *SQLiteFlightSQLServer) DoPutCommandStatementUpdate(ctx context.Context,
 }
 
 func (s *SQLiteFlightSQLServer) CreatePreparedStatement(ctx context.Context, req flightsql.ActionCreatePreparedStatementRequest) (result flightsql.ActionCreatePreparedStatementResult, err error) {
-	var stmt *sql.Stmt
+	db, err := s.conn(ctx)
+	if err != nil {
+		return result, err
+	}
 
 	if len(req.GetTransactionId()) > 0 {
-		tx, loaded := s.openTransactions.Load(string(req.GetTransactionId()))
+		tx, loaded := s.loadTransaction(ctx, string(req.GetTransactionId()))
 		if !loaded {
 			return result, status.Error(codes.InvalidArgument, "invalid transaction handle provided")
 		}
-		stmt, err = tx.(*sql.Tx).PrepareContext(ctx, req.GetQuery())
-	} else {
-		stmt, err = s.db.PrepareContext(ctx, req.GetQuery())
+		db = tx
 	}
 
+	stmt, err := db.PrepareContext(ctx, req.GetQuery())
 	if err != nil {
 		return result, err
 	}
 
+	params := parseParameters(req.GetQuery())
+	schema := s.datasetSchema(ctx, db, req.GetQuery(), params)
+
 	handle := genRandomString()
-	s.prepared.Store(string(handle), Statement{stmt: stmt})
+	s.prepared.Store(string(handle), Statement{stmt: stmt, schema: schema, names: params, session: sessionID(ctx)})
 
 	result.Handle = handle
-	// no way to get the dataset or parameter schemas from sql.DB
+	result.DatasetSchema = schema
+	result.ParameterSchema = parameterSchema(params)
 	return
 }
 
+// datasetSchema determines the result schema of a query without running it,
+// by selecting no rows from it with every parameter bound to NULL. It
+// returns nil for statements that don't produce a result set.
+func (s *SQLiteFlightSQLServer) datasetSchema(ctx context.Context, db dbQueryCtx, query string, params []string) *arrow.Schema {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	keyword, _, _ := strings.Cut(strings.TrimLeft(query, "( \t\r\n"), " ")
+	switch strings.ToLower(strings.TrimSpace(keyword)) {
+	case "select", "with", "values":
+	default:
+		return nil
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT * FROM (\n"+query+"\n) LIMIT 0", bindArgs(params, make([]any, len(params)))...)
+	if err != nil {
+		return nil
+	}
+
+	rdr, err := NewSqlBatchReader(s.Alloc, rows)
+	if err != nil {
+		return nil
+	}
+	defer rdr.Release()
+	return rdr.Schema()
+}
+
+// sqliteParameterType is advertised for every prepared statement parameter:
+// SQLite binds values dynamically, so any of its storage classes is accepted.
+var sqliteParameterType = arrow.DenseUnionOf([]arrow.Field{
+	{Name: "int", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	{Name: "float", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	{Name: "string", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "binary", Type: arrow.BinaryTypes.Binary, Nullable: true},
+}, []arrow.UnionTypeCode{0, 1, 2, 3})
+
+func parameterSchema(params []string) *arrow.Schema {
+	fields := make([]arrow.Field, len(params))
+	for i, name := range params {
+		if name == "" {
+			name = "parameter_" + strconv.Itoa(i+1)
+		}
+		fields[i] = arrow.Field{Name: name, Type: sqliteParameterType, Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// bindArgs pairs parameter values with the statement's parameter names:
+// the driver only binds :name, @name and $name parameters to sql.Named
+// arguments.
+func bindArgs(names []string, values []any) []any {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+		if i < len(names) && len(names[i]) > 1 && names[i][0] != '?' {
+			if c := names[i][1]; c < '0' || c > '9' {
+				args[i] = sql.Named(names[i][1:], v)
+			}
+		}
+	}
+	return args
+}
+
+// parseParameters returns the parameters of a SQLite statement indexed as
+// sqlite3_bind_parameter_index would number them. Named parameters (:a, @a,
+// $a) and numbered ones (?NNN) keep their name; anonymous "?" parameters are
+// reported with an empty name.
+func parseParameters(query string) []string {
+	var params []string
+	isIdent := func(c byte) bool {
+		return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+	}
+	skipTo := func(i int, end string) int {
+		if j := strings.Index(query[i:], end); j >= 0 {
+			return i + j + len(end)
+		}
+		return len(query)
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipTo(i+1, string(c))
+		case c == '[':
+			i = skipTo(i+1, "]")
+		case strings.HasPrefix(query[i:], "--"):
+			i = skipTo(i+2, "\n")
+		case strings.HasPrefix(query[i:], "/*"):
+			i = skipTo(i+2, "*/")
+		case c == '?':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if j == i+1 {
+				params = append(params, "")
+			} else if n, err := strconv.Atoi(query[i+1 : j]); err == nil && n > 0 {
+				for len(params) < n {
+					params = append(params, "")
+				}
+				params[n-1] = query[i:j]
+			}
+			i = j
+		case c == ':' || c == '@' || c == '$':
+			j := i + 1
+			for j < len(query) && isIdent(query[j]) {
+				j++
+			}
+			if j > i+1 && !slices.Contains(params, query[i:j]) {
+				params = append(params, query[i:j])
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	return params
+}
+
 func (s *SQLiteFlightSQLServer) ClosePreparedStatement(ctx context.Context, request flightsql.ActionClosePreparedStatementRequest) error {
 	handle := request.GetPreparedStatementHandle()
-	if val, loaded := s.prepared.LoadAndDelete(string(handle)); loaded {
-		stmt := val.(Statement)
-		return stmt.stmt.Close()
+	if _, ok := s.loadStatement(ctx, handle); ok {
+		if val, loaded := s.prepared.LoadAndDelete(string(handle)); loaded {
+			return val.(Statement).stmt.Close()
+		}
 	}
 
 	return status.Error(codes.InvalidArgument, "prepared statement not found")
 }
 
-func (s *SQLiteFlightSQLServer) GetFlightInfoPreparedStatement(_ context.Context, cmd flightsql.PreparedStatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
-	_, ok := s.prepared.Load(string(cmd.GetPreparedStatementHandle()))
+func (s *SQLiteFlightSQLServer) GetFlightInfoPreparedStatement(ctx context.Context, cmd flightsql.PreparedStatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	stmt, ok := s.loadStatement(ctx, cmd.GetPreparedStatementHandle())
 	if !ok {
 		return nil, status.Error(codes.InvalidArgument, "prepared statement not found")
 	}
 
-	return &flight.FlightInfo{
+	info := &flight.FlightInfo{
 		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: desc.Cmd}}},
 		FlightDescriptor: desc,
 		TotalRecords:     -1,
 		TotalBytes:       -1,
-	}, nil
+	}
+	if schema := stmt.schema; schema != nil {
+		info.Schema = flight.SerializeSchema(schema, s.Alloc)
+	}
+	return info, nil
+}
+
+func (s *SQLiteFlightSQLServer) GetSchemaPreparedStatement(ctx context.Context, cmd flightsql.PreparedStatementQuery, _ *flight.FlightDescriptor) (*flight.SchemaResult, error) {
+	stmt, ok := s.loadStatement(ctx, cmd.GetPreparedStatementHandle())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "prepared statement not found")
+	}
+
+	schema := stmt.schema
+	if schema == nil {
+		return nil, status.Error(codes.Unimplemented, "statement has no result set")
+	}
+	return &flight.SchemaResult{Schema: flight.SerializeSchema(schema, s.Alloc)}, nil
 }
 
 type dbQueryCtx interface {
 	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
 }
 
+// dbConn is satisfied by *sql.DB, *sql.Conn and *sql.Tx.
+type dbConn interface {
+	dbQueryCtx
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+}
+
+// loadStatement returns the prepared statement with the given handle if it
+// belongs to the caller's session.
+func (s *SQLiteFlightSQLServer) loadStatement(ctx context.Context, handle []byte) (Statement, bool) {
+	val, ok := s.prepared.Load(string(handle))
+	if !ok {
+		return Statement{}, false
+	}
+	stmt := val.(Statement)
+	return stmt, stmt.session == sessionID(ctx)
+}
+
+// loadTransaction returns the open transaction with the given id if it
+// belongs to the caller's session.
+func (s *SQLiteFlightSQLServer) loadTransaction(ctx context.Context, id string) (*sql.Tx, bool) {
+	val, ok := s.openTransactions.Load(id)
+	if !ok {
+		return nil, false
+	}
+	txn := val.(transaction)
+	return txn.Tx, txn.session == sessionID(ctx)
+}
+
 func doGetQuery(ctx context.Context, mem memory.Allocator, db dbQueryCtx, query string, schema *arrow.Schema, args ...interface{}) (*arrow.Schema, <-chan flight.StreamChunk, error) {
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -490,12 +810,11 @@ func doGetQuery(ctx context.Context, mem memory.Allocator, db dbQueryCtx, query
 }
 
 func (s *SQLiteFlightSQLServer) DoGetPreparedStatement(ctx context.Context, cmd flightsql.PreparedStatementQuery) (schema *arrow.Schema, out <-chan flight.StreamChunk, err error) {
-	val, ok := s.prepared.Load(string(cmd.GetPreparedStatementHandle()))
+	stmt, ok := s.loadStatement(ctx, cmd.GetPreparedStatementHandle())
 	if !ok {
 		return nil, nil, status.Error(codes.InvalidArgument, "prepared statement not found")
 	}
 
-	stmt := val.(Statement)
 	readers := make([]array.RecordReader, 0, len(stmt.params))
 	if len(stmt.params) == 0 {
 		rows, err := stmt.stmt.QueryContext(ctx)
@@ -553,40 +872,83 @@ func (s *SQLiteFlightSQLServer) DoGetPreparedStatement(ctx context.Context, cmd
 	return
 }
 
+// scalarToIFace converts a bound parameter to a value the SQLite driver
+// accepts. Dates and times are bound as ISO-8601 text, which is how SQLite's
+// date and time functions expect them.
 func scalarToIFace(s scalar.Scalar) (interface{}, error) {
 	if !s.IsValid() {
 		return nil, nil
 	}
 
 	switch val := s.(type) {
+	case *scalar.Boolean:
+		return val.Value, nil
 	case *scalar.Int8:
 		return val.Value, nil
 	case *scalar.Uint8:
 		return val.Value, nil
+	case *scalar.Int16:
+		return int64(val.Value), nil
+	case *scalar.Uint16:
+		return int64(val.Value), nil
 	case *scalar.Int32:
 		return val.Value, nil
+	case *scalar.Uint32:
+		return int64(val.Value), nil
 	case *scalar.Int64:
 		return val.Value, nil
+	case *scalar.Uint64:
+		if val.Value > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: uint64 parameter %d overflows a SQLite integer", arrow.ErrInvalid, val.Value)
+		}
+		return int64(val.Value), nil
+	case *scalar.Float16:
+		return float64(val.Value.Float32()), nil
 	case *scalar.Float32:
 		return val.Value, nil
 	case *scalar.Float64:
 		return val.Value, nil
+	case *scalar.Decimal128:
+		return val.Value.ToString(val.DataType().(*arrow.Decimal128Type).Scale), nil
+	case *scalar.Decimal256:
+		return val.Value.ToString(val.DataType().(*arrow.Decimal256Type).Scale), nil
 	case *scalar.String:
 		return string(val.Value.Bytes()), nil
+	case *scalar.LargeString:
+		return string(val.Value.Bytes()), nil
 	case *scalar.Binary:
-		return val.Value.Bytes(), nil
+		// copy, the parameter outlives the record it was read from
+		return bytes.Clone(val.Value.Bytes()), nil
+	case *scalar.LargeBinary:
+		return bytes.Clone(val.Value.Bytes()), nil
+	case *scalar.FixedSizeBinary:
+		return bytes.Clone(val.Value.Bytes()), nil
 	case scalar.DateScalar:
-		return val.ToTime(), nil
+		return val.ToTime().Format(time.DateOnly), nil
 	case scalar.TimeScalar:
+		return val.ToTime().Format("15:04:05.999999999"), nil
+	case *scalar.Timestamp:
 		return val.ToTime(), nil
+	case *scalar.Duration:
+		return int64(val.Value), nil
 	case *scalar.DenseUnion:
+		return scalarToIFace(val.ChildValue())
+	case *scalar.SparseUnion:
+		return scalarToIFace(val.ChildValue())
+	case *scalar.Dictionary:
+		v, err := val.GetEncodedValue()
+		if err != nil {
+			return nil, err
+		}
+		return scalarToIFace(v)
+	case *scalar.Extension:
 		return scalarToIFace(val.Value)
 	default:
 		return nil, fmt.Errorf("unsupported type: %s", val)
 	}
 }
 
-func getParamsForStatement(rdr flight.MessageReader) (params [][]interface{}, err error) {
+func getParamsForStatement(rdr flight.MessageReader, names []string) (params [][]interface{}, err error) {
 	params = make([][]interface{}, 0)
 	for rdr.Next() {
 		rec := rdr.Record()
@@ -602,30 +964,28 @@ func getParamsForStatement(rdr flight.MessageReader) (params [][]interface{}, er
 				if err != nil {
 					return nil, err
 				}
+				invokeParams[c], err = scalarToIFace(sc)
 				if r, ok := sc.(scalar.Releasable); ok {
 					r.Release()
 				}
-
-				invokeParams[c], err = scalarToIFace(sc)
 				if err != nil {
 					return nil, err
 				}
 			}
-			params = append(params, invokeParams)
+			params = append(params, bindArgs(names, invokeParams))
 		}
 	}
 
 	return params, rdr.Err()
 }
 
-func (s *SQLiteFlightSQLServer) DoPutPreparedStatementQuery(_ context.Context, cmd flightsql.PreparedStatementQuery, rdr flight.MessageReader, _ flight.MetadataWriter) ([]byte, error) {
-	val, ok := s.prepared.Load(string(cmd.GetPreparedStatementHandle()))
+func (s *SQLiteFlightSQLServer) DoPutPreparedStatementQuery(ctx context.Context, cmd flightsql.PreparedStatementQuery, rdr flight.MessageReader, _ flight.MetadataWriter) ([]byte, error) {
+	stmt, ok := s.loadStatement(ctx, cmd.GetPreparedStatementHandle())
 	if !ok {
 		return nil, status.Error(codes.InvalidArgument, "prepared statement not found")
 	}
 
-	stmt := val.(Statement)
-	args, err := getParamsForStatement(rdr)
+	args, err := getParamsForStatement(rdr, stmt.names)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "error gathering parameters for prepared statement query: %s", err.Error())
 	}
@@ -636,13 +996,12 @@ func (s *SQLiteFlightSQLServer) DoPutPreparedStatementQuery(_ context.Context, c
 }
 
 func (s *SQLiteFlightSQLServer) DoPutPreparedStatementUpdate(ctx context.Context, cmd flightsql.PreparedStatementUpdate, rdr flight.MessageReader) (int64, error) {
-	val, ok := s.prepared.Load(string(cmd.GetPreparedStatementHandle()))
+	stmt, ok := s.loadStatement(ctx, cmd.GetPreparedStatementHandle())
 	if !ok {
 		return 0, status.Error(codes.InvalidArgument, "prepared statement not found")
 	}
 
-	stmt := val.(Statement)
-	args, err := getParamsForStatement(rdr)
+	args, err := getParamsForStatement(rdr, stmt.names)
 	if err != nil {
 		return 0, status.Errorf(codes.Internal, "error gathering parameters for prepared statement: %s", err.Error())
 	}
@@ -684,26 +1043,25 @@ func (s *SQLiteFlightSQLServer) GetFlightInfoPrimaryKeys(_ context.Context, cmd
 }
 
 func (s *SQLiteFlightSQLServer) DoGetPrimaryKeys(ctx context.Context, cmd flightsql.TableRef) (*arrow.Schema, <-chan flight.StreamChunk, error) {
-	// the field key_name cannot be recovered by sqlite so it is
-	// being set to null following the same pattern for catalog name and schema_name
-	var b strings.Builder
-
-	b.WriteString(`
-	SELECT null AS catalog_name, null AS schema_name, table_name, name AS column_name, pk AS key_sequence, null as key_name
-	FROM pragma_table_info(table_name)
-		JOIN (SELECT null AS catalog_name, null AS schema_name, name AS table_name, type AS table_type
-			FROM sqlite_master) where 1=1 AND pk !=0`)
-
+	// SQLite doesn't name primary key constraints, so key_name is null.
+	catalog := "main"
 	if cmd.Catalog != nil {
-		fmt.Fprintf(&b, " and catalog_name LIKE '%s'", *cmd.Catalog)
+		catalog = *cmd.Catalog
+	}
+	db, err := s.conn(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
-	if cmd.DBSchema != nil {
-		fmt.Fprintf(&b, " and schema_name LIKE '%s'", *cmd.DBSchema)
+	if cmd.DBSchema != nil && *cmd.DBSchema != "" {
+		// every table lives in the catalog's single unnamed schema
+		return doGetQuery(ctx, s.Alloc, db, `SELECT NULL, NULL, NULL, NULL, NULL, NULL WHERE 0`, schema_ref.PrimaryKeys)
 	}
 
-	fmt.Fprintf(&b, " and table_name LIKE '%s'", cmd.Table)
+	const query = `SELECT ? AS catalog_name, '' AS db_schema_name, ? AS table_name,
+		name AS column_name, pk AS key_sequence, NULL AS key_name
+		FROM pragma_table_info(?, ?) WHERE pk != 0 ORDER BY pk`
 
-	return doGetQuery(ctx, s.Alloc, s.db, b.String(), schema_ref.PrimaryKeys)
+	return doGetQuery(ctx, s.Alloc, db, query, schema_ref.PrimaryKeys, catalog, cmd.Table, cmd.Table, catalog)
 }
 
 func (s *SQLiteFlightSQLServer) GetFlightInfoImportedKeys(_ context.Context, _ flightsql.TableRef, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
@@ -719,7 +1077,11 @@ func (s *SQLiteFlightSQLServer) DoGetImportedKeys(ctx context.Context, ref fligh
 		filter += " AND fk_schema_name = '" + *ref.DBSchema + "'"
 	}
 	query := prepareQueryForGetKeys(filter)
-	return doGetQuery(ctx, s.Alloc, s.db, query, schema_ref.ImportedKeys)
+	db, err := s.conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doGetQuery(ctx, s.Alloc, db, query, schema_ref.ImportedKeys)
 }
 
 func (s *SQLiteFlightSQLServer) GetFlightInfoExportedKeys(_ context.Context, _ flightsql.TableRef, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
@@ -735,7 +1097,11 @@ func (s *SQLiteFlightSQLServer) DoGetExportedKeys(ctx context.Context, ref fligh
 		filter += " AND pk_schema_name = '" + *ref.DBSchema + "'"
 	}
 	query := prepareQueryForGetKeys(filter)
-	return doGetQuery(ctx, s.Alloc, s.db, query, schema_ref.ExportedKeys)
+	db, err := s.conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doGetQuery(ctx, s.Alloc, db, query, schema_ref.ExportedKeys)
 }
 
 func (s *SQLiteFlightSQLServer) GetFlightInfoCrossReference(_ context.Context, _ flightsql.CrossTableRef, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
@@ -761,28 +1127,35 @@ func (s *SQLiteFlightSQLServer) DoGetCrossReference(ctx context.Context, cmd fli
 		filter += " AND fk_schema_name = '" + *fkref.DBSchema + "'"
 	}
 	query := prepareQueryForGetKeys(filter)
-	return doGetQuery(ctx, s.Alloc, s.db, query, schema_ref.ExportedKeys)
+	db, err := s.conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doGetQuery(ctx, s.Alloc, db, query, schema_ref.ExportedKeys)
 }
 
-func (s *SQLiteFlightSQLServer) BeginTransaction(_ context.Context, req flightsql.ActionBeginTransactionRequest) (id []byte, err error) {
-	tx, err := s.db.Begin()
+func (s *SQLiteFlightSQLServer) BeginTransaction(ctx context.Context, req flightsql.ActionBeginTransactionRequest) (id []byte, err error) {
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to begin transaction: %s", err.Error())
 	}
 
 	handle := genRandomString()
-	s.openTransactions.Store(string(handle), tx)
+	s.openTransactions.Store(string(handle), transaction{Tx: tx, session: sessionID(ctx)})
 	return handle, nil
 }
 
-func (s *SQLiteFlightSQLServer) EndTransaction(_ context.Context, req flightsql.ActionEndTransactionRequest) error {
+func (s *SQLiteFlightSQLServer) EndTransaction(ctx context.Context, req flightsql.ActionEndTransactionRequest) error {
 	if req.GetAction() == flightsql.EndTransactionUnspecified {
 		return status.Error(codes.InvalidArgument, "must specify Commit or Rollback to end transaction")
 	}
 
 	handle := string(req.GetTransactionId())
+	if _, ok := s.loadTransaction(ctx, handle); !ok {
+		return status.Error(codes.InvalidArgument, "transaction id not found")
+	}
 	if tx, loaded := s.openTransactions.LoadAndDelete(handle); loaded {
-		txn := tx.(*sql.Tx)
+		txn := tx.(transaction)
 		switch req.GetAction() {
 		case flightsql.EndTransactionCommit:
 			if err := txn.Commit(); err != nil {
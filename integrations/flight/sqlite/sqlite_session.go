@@ -0,0 +1,244 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package experiments
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/session"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// sqliteSession is the server side of one client session: a connection of
+// its own, which holds the session's temporary tables, and the transport
+// connection the client last called from.
+type sqliteSession struct {
+	once     sync.Once
+	conn     *sql.Conn
+	err      error
+	lastSeen atomic.Uint64
+	srv      session.ServerSession
+}
+
+// Sessions gives each client a session of its own, identified by the
+// arrow_flight_session_id cookie. Temporary tables, prepared statements and
+// transactions are only visible within the session that created them, and
+// are dropped when the client calls CloseSession or disconnects.
+//
+// Register the returned middleware and server option with the Flight server:
+//
+//	mw, opt := srv.Sessions()
+//	server := flight.NewServerWithMiddleware([]flight.ServerMiddleware{mw}, opt)
+//
+// Each open session holds a connection of the database, so the database
+// must allow as many connections as there are clients.
+func (s *SQLiteFlightSQLServer) Sessions() (flight.ServerMiddleware, grpc.ServerOption) {
+	s.sessionMgr = &sessionManager{
+		srv:     s,
+		store:   session.NewSessionStore(),
+		factory: session.NewSessionFactory(newSessionID),
+	}
+	return flight.CreateServerMiddleware(session.NewServerSessionMiddleware(s.sessionMgr)),
+		grpc.StatsHandler(&connTracker{mgr: s.sessionMgr})
+}
+
+// newSessionID returns an unguessable session id that is a valid cookie value.
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func (s *SQLiteFlightSQLServer) CloseSession(ctx context.Context, _ *flight.CloseSessionRequest) (*flight.CloseSessionResult, error) {
+	sess, err := session.GetSessionFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, "sessions are not enabled")
+	}
+
+	// the session middleware invalidates the client's cookie and
+	// calls sessionManager.CloseSession once the call completes
+	if err := sess.Close(); err != nil {
+		return nil, err
+	}
+	return &flight.CloseSessionResult{Status: flight.CloseSessionResultClosed}, nil
+}
+
+// conn returns the connection queries of the caller should run on: its
+// session's own connection, or the shared pool without sessions.
+func (s *SQLiteFlightSQLServer) conn(ctx context.Context) (dbConn, error) {
+	sess, err := session.GetSessionFromContext(ctx)
+	if err != nil {
+		return s.db, nil
+	}
+
+	val, _ := s.sessions.LoadOrStore(sess.Token(), &sqliteSession{srv: sess})
+	state := val.(*sqliteSession)
+	state.once.Do(func() {
+		// the connection outlives the call that opens it
+		state.conn, state.err = s.db.Conn(context.Background())
+	})
+	if state.err != nil {
+		return nil, state.err
+	}
+	if id, ok := ctx.Value(connIDKey{}).(uint64); ok {
+		state.lastSeen.Store(id)
+	}
+	return state.conn, nil
+}
+
+// begin starts a transaction on the caller's connection. It is not tied to
+// the context of the call beginning it.
+func (s *SQLiteFlightSQLServer) begin(ctx context.Context) (*sql.Tx, error) {
+	db, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if conn, ok := db.(*sql.Conn); ok {
+		return conn.BeginTx(context.Background(), nil)
+	}
+	return s.db.Begin()
+}
+
+// sessionID returns the token of the caller's session, or "" when
+// sessions are not enabled.
+func sessionID(ctx context.Context) string {
+	if sess, err := session.GetSessionFromContext(ctx); err == nil {
+		return sess.Token()
+	}
+	return ""
+}
+
+// closeSession releases everything the session with the given token holds.
+func (s *SQLiteFlightSQLServer) closeSession(token string) {
+	s.prepared.Range(func(k, v any) bool {
+		if stmt := v.(Statement); stmt.session == token {
+			s.prepared.Delete(k)
+			stmt.stmt.Close()
+		}
+		return true
+	})
+	s.openTransactions.Range(func(k, v any) bool {
+		if txn := v.(transaction); txn.session == token {
+			s.openTransactions.Delete(k)
+			txn.Rollback()
+		}
+		return true
+	})
+
+	if val, loaded := s.sessions.LoadAndDelete(token); loaded {
+		state := val.(*sqliteSession)
+		// wait for the connection to be opened, if it is being opened
+		state.once.Do(func() {})
+		if state.conn != nil {
+			// discard the connection rather than return it to the pool,
+			// where the next session would find the temporary tables
+			state.conn.Raw(func(any) error { return driver.ErrBadConn })
+			state.conn.Close()
+		}
+	}
+}
+
+// sessionManager keeps sessions in memory like the stock stateful manager,
+// but treats a cookie of a session that no longer exists as no session at
+// all, so clients holding a stale cookie are given a new session.
+type sessionManager struct {
+	srv     *SQLiteFlightSQLServer
+	store   session.SessionStore
+	factory session.SessionFactory
+}
+
+func (m *sessionManager) CreateSession(context.Context) (session.ServerSession, error) {
+	sess, err := m.factory.CreateSession()
+	if err != nil {
+		return nil, err
+	}
+	return sess, m.store.Put(sess)
+}
+
+func (m *sessionManager) GetSession(ctx context.Context) (session.ServerSession, error) {
+	if sess, err := session.GetSessionFromContext(ctx); err == nil {
+		return sess, nil
+	}
+
+	cookie, err := session.GetIncomingCookieByName(ctx, session.StatefulSessionCookieName)
+	if errors.Is(err, http.ErrNoCookie) {
+		return nil, session.ErrNoSession
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := m.store.Get(cookie.Value)
+	if err != nil || sess.Closed() {
+		return nil, session.ErrNoSession
+	}
+	return sess, nil
+}
+
+func (m *sessionManager) CloseSession(sess session.ServerSession) error {
+	m.srv.closeSession(sess.Token())
+	return m.store.Remove(sess.Token())
+}
+
+type connIDKey struct{}
+
+// connTracker numbers transport connections and closes the sessions whose
+// client was last seen on a connection when it ends.
+type connTracker struct {
+	mgr  *sessionManager
+	next atomic.Uint64
+}
+
+func (c *connTracker) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return context.WithValue(ctx, connIDKey{}, c.next.Add(1))
+}
+
+func (c *connTracker) HandleConn(ctx context.Context, st stats.ConnStats) {
+	if _, ok := st.(*stats.ConnEnd); !ok {
+		return
+	}
+
+	id, _ := ctx.Value(connIDKey{}).(uint64)
+	c.mgr.srv.sessions.Range(func(_, v any) bool {
+		if state := v.(*sqliteSession); state.lastSeen.Load() == id {
+			state.srv.Close()
+			c.mgr.CloseSession(state.srv)
+		}
+		return true
+	})
+}
+
+func (c *connTracker) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context { return ctx }
+
+func (c *connTracker) HandleRPC(context.Context, stats.RPCStats) {}
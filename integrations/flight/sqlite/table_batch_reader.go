@@ -22,7 +22,6 @@ package experiments
 import (
 	"context"
 	"database/sql"
-	"strings"
 	"sync/atomic"
 
 	"github.com/apache/arrow-go/v18/arrow"
@@ -46,10 +45,10 @@ type SqliteTablesSchemaBatchReader struct {
 	err        error
 }
 
-func NewSqliteTablesSchemaBatchReader(ctx context.Context, mem memory.Allocator, rdr array.RecordReader, db *sql.DB, mainQuery string) (*SqliteTablesSchemaBatchReader, error) {
-	schemaQuery := `SELECT table_name, name, type, [notnull] 
-					FROM pragma_table_info(table_name)
-					JOIN (` + mainQuery + `) WHERE table_name = ?`
+// NewSqliteTablesSchemaBatchReader wraps a GetTables result and appends the
+// serialized schema of each table, looked up in the table's own catalog.
+func NewSqliteTablesSchemaBatchReader(ctx context.Context, mem memory.Allocator, rdr array.RecordReader, db dbConn) (*SqliteTablesSchemaBatchReader, error) {
+	const schemaQuery = `SELECT name, type, "notnull", pk FROM pragma_table_info(?, ?) ORDER BY cid`
 
 	stmt, err := db.PrepareContext(ctx, schemaQuery)
 	if err != nil {
@@ -94,28 +93,7 @@ func (s *SqliteTablesSchemaBatchReader) Schema() *arrow.Schema {
 func (s *SqliteTablesSchemaBatchReader) Record() arrow.Record { return s.record }
 
 func getSqlTypeFromTypeName(sqltype string) int {
-	if sqltype == "" {
-		return sqlite3.SQLITE_NULL
-	}
-
-	sqltype = strings.ToLower(sqltype)
-
-	if strings.HasPrefix(sqltype, "varchar") || strings.HasPrefix(sqltype, "char") {
-		return sqlite3.SQLITE_TEXT
-	}
-
-	switch sqltype {
-	case "int", "integer":
-		return sqlite3.SQLITE_INTEGER
-	case "real":
-		return sqlite3.SQLITE_FLOAT
-	case "blob":
-		return sqlite3.SQLITE_BLOB
-	case "text", "date":
-		return sqlite3.SQLITE_TEXT
-	default:
-		return sqlite3.SQLITE_NULL
-	}
+	return sqliteAffinity(sqltype)
 }
 
 func getPrecisionFromCol(sqltype int) int {
@@ -144,6 +122,27 @@ func getColumnMetadata(bldr *flightsql.ColumnMetadataBuilder, sqltype int, table
 	return bldr.Metadata()
 }
 
+// getTableColumnMetadata describes a column of a catalogued table, including
+// its declared type name and whether it is an INTEGER PRIMARY KEY (a rowid
+// alias, which SQLite fills in automatically).
+func getTableColumnMetadata(bldr *flightsql.ColumnMetadataBuilder, catalog, table, typ string, rowid bool) arrow.Metadata {
+	defer bldr.Clear()
+
+	sqltype := getSqlTypeFromTypeName(typ)
+	bldr.CatalogName(catalog).TableName(table).TypeName(typ).
+		IsReadOnly(false).IsAutoIncrement(rowid).
+		IsCaseSensitive(sqltype == sqlite3.SQLITE_TEXT).IsSearchable(true)
+	switch sqltype {
+	case sqlite3.SQLITE_TEXT, sqlite3.SQLITE_BLOB, sqlite3.SQLITE_NULL:
+	case sqlite3.SQLITE_INTEGER:
+		bldr.Precision(19).Scale(0)
+	default:
+		bldr.Precision(int32(getPrecisionFromCol(sqltype))).Scale(15)
+	}
+
+	return bldr.Metadata()
+}
+
 func (s *SqliteTablesSchemaBatchReader) Next() bool {
 	if s.record != nil {
 		s.record.Release()
@@ -155,33 +154,42 @@ func (s *SqliteTablesSchemaBatchReader) Next() bool {
 	}
 
 	rec := s.rdr.Record()
+	catalogArr := rec.Column(rec.Schema().FieldIndices("catalog_name")[0]).(*array.String)
 	tableNameArr := rec.Column(rec.Schema().FieldIndices("table_name")[0]).(*array.String)
 
+	type column struct {
+		name, typ string
+		notNull   bool
+		pk        bool
+	}
+
 	bldr := flightsql.NewColumnMetadataBuilder()
+	columns := make([]column, 0)
 	columnFields := make([]arrow.Field, 0)
 	for i := 0; i < tableNameArr.Len(); i++ {
-		table := tableNameArr.Value(i)
-		rows, err := s.stmt.QueryContext(s.ctx, table)
+		catalog, table := catalogArr.Value(i), tableNameArr.Value(i)
+		rows, err := s.stmt.QueryContext(s.ctx, table, catalog)
 		if err != nil {
 			s.err = err
 			return false
 		}
 
-		var tableName, name, typ string
-		var nn int
+		var pkCols int
 		for rows.Next() {
-			if err := rows.Scan(&tableName, &name, &typ, &nn); err != nil {
+			var (
+				c      column
+				nn, pk int
+			)
+			if err := rows.Scan(&c.name, &c.typ, &nn, &pk); err != nil {
 				rows.Close()
 				s.err = err
 				return false
 			}
-
-			columnFields = append(columnFields, arrow.Field{
-				Name:     name,
-				Type:     getArrowTypeFromString(typ),
-				Nullable: nn == 0,
-				Metadata: getColumnMetadata(bldr, getSqlTypeFromTypeName(typ), tableName),
-			})
+			c.notNull, c.pk = nn != 0, pk > 0
+			if c.pk {
+				pkCols++
+			}
+			columns = append(columns, c)
 		}
 
 		rows.Close()
@@ -189,10 +197,21 @@ func (s *SqliteTablesSchemaBatchReader) Next() bool {
 			s.err = rows.Err()
 			return false
 		}
+
+		for _, c := range columns {
+			// a lone INTEGER PRIMARY KEY is an alias for the rowid
+			rowid := c.pk && pkCols == 1 && sqliteTypeName(c.typ) == "integer"
+			columnFields = append(columnFields, arrow.Field{
+				Name:     c.name,
+				Type:     getArrowTypeFromString(c.typ),
+				Nullable: !c.notNull && !rowid,
+				Metadata: getTableColumnMetadata(bldr, catalog, table, c.typ, rowid),
+			})
+		}
 		val := flight.SerializeSchema(arrow.NewSchema(columnFields, nil), s.mem)
 		s.schemaBldr.Append(val)
 
-		columnFields = columnFields[:0]
+		columns, columnFields = columns[:0], columnFields[:0]
 	}
 
 	schemaCol := s.schemaBldr.NewArray()
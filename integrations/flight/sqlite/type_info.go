@@ -20,99 +20,137 @@
 package experiments
 
 import (
-	"strings"
-
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/flight/flightsql/schema_ref"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 )
 
-func GetTypeInfoResult(mem memory.Allocator) arrow.Record {
-	typeNames, _, _ := array.FromJSON(mem, arrow.BinaryTypes.String,
-		strings.NewReader(`["bit", "tinyint", "bigint", "longvarbinary",
-						    "varbinary", "text", "longvarchar", "char",
-							"integer", "smallint", "float", "double",
-							"numeric", "varchar", "date", "time", "timestamp"]`))
-	defer typeNames.Release()
-
-	dataType, _, _ := array.FromJSON(mem, arrow.PrimitiveTypes.Int32,
-		strings.NewReader(`[-7, -6, -5, -4, -3, -1, -1, 1, 4, 5, 6, 8, 8, 12, 91, 92, 93]`))
-	defer dataType.Release()
-
-	columnSize, _, _ := array.FromJSON(mem, arrow.PrimitiveTypes.Int32,
-		strings.NewReader(`[1, 3, 19, 65536, 255, 65536, 65536, 255, 9, 5, 7, 15, 15, 255, 10, 8, 32]`))
-	defer columnSize.Release()
-
-	literalPrefix, _, _ := array.FromJSON(mem, arrow.BinaryTypes.String,
-		strings.NewReader(`[null, null, null, null, null, "'", "'", "'", null, null, null, null, null, "'" ,"'", "'", "'"]`))
-	defer literalPrefix.Release()
-
-	literalSuffix, _, _ := array.FromJSON(mem, arrow.BinaryTypes.String,
-		strings.NewReader(`[null, null, null, null, null, "'", "'", "'", null, null, null, null, null, "'" ,"'", "'", "'"]`))
-	defer literalSuffix.Release()
+// xdbcType describes one row of the GetXdbcTypeInfo result.
+type xdbcType struct {
+	name          string
+	dataType      int32 // java.sql.Types / ODBC SQL type code
+	columnSize    int32
+	literal       string // used as both prefix and suffix
+	createParams  []string
+	numeric       bool
+	maxScale      int32
+	autoIncrement bool
+	caseSensitive bool
+	dateTimeSub   int32 // ODBC SQL_CODE_* subcode for date/time types
+}
 
-	createParams, _, _ := array.FromJSON(mem, arrow.ListOfField(arrow.Field{Name: "item", Type: arrow.BinaryTypes.String, Nullable: false}),
-		strings.NewReader(`[[], [], [], [], [], ["length"], ["length"], ["length"], [], [], [], [], [], ["length"], [], [], []]`))
-	defer createParams.Release()
+// sqliteMaxLength is SQLite's default SQLITE_MAX_LENGTH, the largest
+// string or blob it stores.
+const sqliteMaxLength = 1000000000
+
+// xdbcTypes lists the type names SQLite accepts in column declarations,
+// ordered by data type as JDBC's getTypeInfo requires. The preferred name
+// for each data type comes first.
+var xdbcTypes = []xdbcType{
+	{name: "bit", dataType: -7, columnSize: 1, numeric: true},
+	{name: "tinyint", dataType: -6, columnSize: 3, numeric: true},
+	{name: "bigint", dataType: -5, columnSize: 19, numeric: true},
+	{name: "blob", dataType: -4, columnSize: sqliteMaxLength},
+	{name: "varbinary", dataType: -3, columnSize: sqliteMaxLength, createParams: []string{"length"}},
+	{name: "text", dataType: -1, columnSize: sqliteMaxLength, literal: "'", caseSensitive: true},
+	{name: "longvarchar", dataType: -1, columnSize: sqliteMaxLength, literal: "'", caseSensitive: true},
+	{name: "char", dataType: 1, columnSize: sqliteMaxLength, literal: "'", createParams: []string{"length"}, caseSensitive: true},
+	{name: "numeric", dataType: 2, columnSize: 15, numeric: true, maxScale: 15, createParams: []string{"precision", "scale"}},
+	{name: "decimal", dataType: 3, columnSize: 15, numeric: true, maxScale: 15, createParams: []string{"precision", "scale"}},
+	{name: "integer", dataType: 4, columnSize: 19, numeric: true, autoIncrement: true},
+	{name: "smallint", dataType: 5, columnSize: 5, numeric: true},
+	{name: "float", dataType: 6, columnSize: 15, numeric: true, maxScale: 15},
+	{name: "real", dataType: 7, columnSize: 15, numeric: true, maxScale: 15},
+	{name: "double", dataType: 8, columnSize: 15, numeric: true, maxScale: 15},
+	{name: "varchar", dataType: 12, columnSize: sqliteMaxLength, literal: "'", createParams: []string{"length"}, caseSensitive: true},
+	{name: "boolean", dataType: 16, columnSize: 1, numeric: true},
+	{name: "date", dataType: 91, columnSize: 10, literal: "'", dateTimeSub: 1},
+	{name: "time", dataType: 92, columnSize: 8, literal: "'", dateTimeSub: 2},
+	{name: "timestamp", dataType: 93, columnSize: 23, literal: "'", dateTimeSub: 3},
+}
 
-	nullable, _, _ := array.FromJSON(mem, arrow.PrimitiveTypes.Int32,
-		strings.NewReader(`[1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1]`))
-	defer nullable.Release()
+func typeInfoRecord(mem memory.Allocator, types []xdbcType) arrow.Record {
+	bldr := array.NewRecordBuilder(mem, schema_ref.XdbcTypeInfo)
+	defer bldr.Release()
 
-	// reference for creating a boolean() array with only zeros
-	zeroBoolArray, _, err := array.FromJSON(mem, arrow.FixedWidthTypes.Boolean,
-		strings.NewReader(`[0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0]`), array.WithUseNumber())
-	if err != nil {
-		panic(err)
+	appendString := func(b array.Builder, v string) {
+		if v == "" {
+			b.AppendNull()
+			return
+		}
+		b.(*array.StringBuilder).Append(v)
+	}
+	appendInt := func(b array.Builder, v int32, valid bool) {
+		if !valid {
+			b.AppendNull()
+			return
+		}
+		b.(*array.Int32Builder).Append(v)
+	}
+	appendBool := func(b array.Builder, v, valid bool) {
+		if !valid {
+			b.AppendNull()
+			return
+		}
+		b.(*array.BooleanBuilder).Append(v)
 	}
-	defer zeroBoolArray.Release()
-	caseSensitive := zeroBoolArray
-
-	searchable, _, _ := array.FromJSON(mem, arrow.PrimitiveTypes.Int32,
-		strings.NewReader(`[3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3]`))
-	defer searchable.Release()
-
-	unsignedAttribute := zeroBoolArray
-	fixedPrecScale := zeroBoolArray
-	autoUniqueVal := zeroBoolArray
 
-	localTypeName := typeNames
+	const (
+		typeNullable   = 1 // SQL_NULLABLE
+		typeBasic      = 2 // SQL_PRED_BASIC: usable with any comparison except LIKE
+		typeSearchable = 3 // SQL_SEARCHABLE: usable with any comparison, including LIKE
+	)
+
+	for _, t := range types {
+		bldr.Field(0).(*array.StringBuilder).Append(t.name)
+		bldr.Field(1).(*array.Int32Builder).Append(t.dataType)
+		appendInt(bldr.Field(2), t.columnSize, true)
+		appendString(bldr.Field(3), t.literal)
+		appendString(bldr.Field(4), t.literal)
+
+		params := bldr.Field(5).(*array.ListBuilder)
+		if len(t.createParams) == 0 {
+			params.AppendNull()
+		} else {
+			params.Append(true)
+			for _, p := range t.createParams {
+				params.ValueBuilder().(*array.StringBuilder).Append(p)
+			}
+		}
 
-	zeroIntArray, _, _ := array.FromJSON(mem, arrow.PrimitiveTypes.Int32,
-		strings.NewReader(`[0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0]`))
-	defer zeroIntArray.Release()
+		bldr.Field(6).(*array.Int32Builder).Append(typeNullable)
+		bldr.Field(7).(*array.BooleanBuilder).Append(t.caseSensitive)
+		if t.caseSensitive {
+			bldr.Field(8).(*array.Int32Builder).Append(typeSearchable)
+		} else {
+			bldr.Field(8).(*array.Int32Builder).Append(typeBasic)
+		}
+		appendBool(bldr.Field(9), false, t.numeric)
+		bldr.Field(10).(*array.BooleanBuilder).Append(false)
+		appendBool(bldr.Field(11), t.autoIncrement, t.numeric)
+		appendString(bldr.Field(12), t.name)
+		appendInt(bldr.Field(13), 0, t.numeric)
+		appendInt(bldr.Field(14), t.maxScale, t.numeric)
+		bldr.Field(15).(*array.Int32Builder).Append(t.dataType)
+		appendInt(bldr.Field(16), t.dateTimeSub, t.dateTimeSub != 0)
+		appendInt(bldr.Field(17), 10, t.numeric)
+		bldr.Field(18).AppendNull()
+	}
 
-	minimalScale := zeroIntArray
-	maximumScale := zeroIntArray
-	sqlDataType := dataType
-	sqlDateTimeSub := zeroIntArray
-	numPrecRadix := zeroIntArray
-	intervalPrecision := zeroIntArray
+	return bldr.NewRecord()
+}
 
-	return array.NewRecord(schema_ref.XdbcTypeInfo, []arrow.Array{
-		typeNames, dataType, columnSize, literalPrefix, literalSuffix,
-		createParams, nullable, caseSensitive, searchable, unsignedAttribute,
-		fixedPrecScale, autoUniqueVal, localTypeName, minimalScale, maximumScale,
-		sqlDataType, sqlDateTimeSub, numPrecRadix, intervalPrecision}, 17)
+func GetTypeInfoResult(mem memory.Allocator) arrow.Record {
+	return typeInfoRecord(mem, xdbcTypes)
 }
 
 func GetFilteredTypeInfoResult(mem memory.Allocator, filter int32) arrow.Record {
-	batch := GetTypeInfoResult(mem)
-	defer batch.Release()
-
-	dataTypeVector := []int32{-7, -6, -5, -4, -3, -1, -1, 1, 4, 5, 6, 8, 8, 12, 91, 92, 93}
-	start, end := -1, -1
-	for i, v := range dataTypeVector {
-		if filter == v {
-			if start == -1 {
-				start = i
-			}
-		} else if start != -1 && end == -1 {
-			end = i
-			break
+	types := make([]xdbcType, 0, 2)
+	for _, t := range xdbcTypes {
+		if t.dataType == filter {
+			types = append(types, t)
 		}
 	}
-
-	return batch.NewSlice(int64(start), int64(end))
+	return typeInfoRecord(mem, types)
 }
@@ -0,0 +1,288 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	ipcv16 "github.com/apache/arrow/go/v16/arrow/ipc"
+	memoryv16 "github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/polarsignals/frostdb"
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+)
+
+// DefaultDatabase is the FrostDB database used when no database is set.
+const DefaultDatabase = "arrowarc"
+
+// FrostDBWriteOptions configures a FrostDBWriter.
+type FrostDBWriteOptions struct {
+	// Store is the column store to write to, left open on Close. When nil,
+	// the writer opens a store persisted under StoragePath, with a
+	// write-ahead log, and closes it on Close.
+	Store       *frostdb.ColumnStore
+	StoragePath string
+	// Database is the database of the table, DefaultDatabase if empty.
+	Database string
+	// SortingColumns are the columns rows are sorted by within the table,
+	// ascending.
+	SortingColumns []string
+}
+
+// FrostDBWriter ingests records into a FrostDB table whose schema is
+// derived from the Arrow schema of the records. FrostDB stores strings,
+// 64-bit integers, unsigned 64-bit integers, doubles and booleans: other
+// integer and floating point columns are widened, dictionaries of strings
+// are decoded, and dates, times, timestamps and durations are stored as
+// their integer values.
+type FrostDBWriter struct {
+	ctx       context.Context
+	store     *frostdb.ColumnStore
+	ownsStore bool
+	table     *frostdb.Table
+	schema    *arrow.Schema // of the records inserted
+	alloc     memory.Allocator
+}
+
+// NewFrostDBWriter returns a writer of records with the given schema into
+// the FrostDB table name, created if needed.
+func NewFrostDBWriter(ctx context.Context, name string, schema *arrow.Schema, opts *FrostDBWriteOptions) (*FrostDBWriter, error) {
+	if opts == nil {
+		opts = &FrostDBWriteOptions{}
+	}
+	def, stored, err := FrostDBSchema(name, schema, opts.SortingColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &FrostDBWriter{ctx: ctx, schema: stored, alloc: memory.NewGoAllocator()}
+	if w.store, w.ownsStore, err = openStore(opts.Store, opts.StoragePath); err != nil {
+		return nil, err
+	}
+	database := databaseName(opts.Database)
+	db, err := w.store.DB(ctx, database)
+	if err != nil {
+		w.closeStore()
+		return nil, fmt.Errorf("frostdb: failed to open database %s: %w", database, err)
+	}
+	if w.table, err = db.Table(name, frostdb.NewTableConfig(def)); err != nil {
+		w.closeStore()
+		return nil, fmt.Errorf("frostdb: failed to open table %s: %w", name, err)
+	}
+	return w, nil
+}
+
+// openStore returns store, or when nil a store persisted under path, and
+// whether the store was opened.
+func openStore(store *frostdb.ColumnStore, path string) (*frostdb.ColumnStore, bool, error) {
+	if store != nil {
+		return store, false, nil
+	}
+	if path == "" {
+		return nil, false, fmt.Errorf("frostdb: a store or a storage path is required")
+	}
+	store, err := frostdb.New(frostdb.WithWAL(), frostdb.WithStoragePath(path))
+	if err != nil {
+		return nil, false, fmt.Errorf("frostdb: failed to open store at %s: %w", path, err)
+	}
+	return store, true, nil
+}
+
+func databaseName(name string) string {
+	if name == "" {
+		return DefaultDatabase
+	}
+	return name
+}
+
+// FrostDBSchema returns the FrostDB schema definition of a table name
+// holding records of schema, and the Arrow schema the records are
+// converted to before being inserted.
+func FrostDBSchema(name string, schema *arrow.Schema, sortingColumns []string) (*schemapb.Schema, *arrow.Schema, error) {
+	def := &schemapb.Schema{Name: name}
+	fields := make([]arrow.Field, schema.NumFields())
+	for i, f := range schema.Fields() {
+		layout, dt, err := frostDBType(f.Type)
+		if err != nil {
+			return nil, nil, fmt.Errorf("frostdb: column %q: %w", f.Name, err)
+		}
+		def.Columns = append(def.Columns, &schemapb.Column{
+			Name:          f.Name,
+			StorageLayout: &schemapb.StorageLayout{Type: layout, Nullable: f.Nullable},
+		})
+		fields[i] = arrow.Field{Name: f.Name, Type: dt, Nullable: f.Nullable}
+	}
+	for _, col := range sortingColumns {
+		if len(schema.FieldIndices(col)) == 0 {
+			return nil, nil, fmt.Errorf("frostdb: unknown sorting column %q", col)
+		}
+		def.SortingColumns = append(def.SortingColumns, &schemapb.SortingColumn{
+			Name:      col,
+			Direction: schemapb.SortingColumn_DIRECTION_ASCENDING,
+		})
+	}
+	return def, arrow.NewSchema(fields, nil), nil
+}
+
+// frostDBType returns the storage type of columns of type dt and the Arrow
+// type their values are converted to.
+func frostDBType(dt arrow.DataType) (schemapb.StorageLayout_Type, arrow.DataType, error) {
+	switch dt.ID() {
+	case arrow.STRING, arrow.LARGE_STRING:
+		return schemapb.StorageLayout_TYPE_STRING, arrow.BinaryTypes.String, nil
+	case arrow.DICTIONARY:
+		if value := dt.(*arrow.DictionaryType).ValueType; value.ID() == arrow.STRING || value.ID() == arrow.LARGE_STRING {
+			return schemapb.StorageLayout_TYPE_STRING, arrow.BinaryTypes.String, nil
+		}
+	case arrow.BOOL:
+		return schemapb.StorageLayout_TYPE_BOOL, arrow.FixedWidthTypes.Boolean, nil
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64, arrow.UINT8, arrow.UINT16, arrow.UINT32,
+		arrow.DATE32, arrow.DATE64, arrow.TIME32, arrow.TIME64, arrow.TIMESTAMP, arrow.DURATION:
+		// FrostDB stores 32-bit integers but cannot query them.
+		return schemapb.StorageLayout_TYPE_INT64, arrow.PrimitiveTypes.Int64, nil
+	case arrow.UINT64:
+		return schemapb.StorageLayout_TYPE_UINT64, arrow.PrimitiveTypes.Uint64, nil
+	case arrow.FLOAT16, arrow.FLOAT32, arrow.FLOAT64:
+		return schemapb.StorageLayout_TYPE_DOUBLE, arrow.PrimitiveTypes.Float64, nil
+	}
+	return 0, nil, fmt.Errorf("unsupported type %s", dt)
+}
+
+// Write converts record to the table's types and inserts it.
+func (w *FrostDBWriter) Write(record arrow.Record) error {
+	converted, err := w.convert(record)
+	if err != nil {
+		return fmt.Errorf("frostdb: %w", err)
+	}
+	defer converted.Release()
+
+	// FrostDB is built on an older Arrow module; records cross over as IPC.
+	var buf bytes.Buffer
+	iw := ipc.NewWriter(&buf, ipc.WithSchema(w.schema), ipc.WithAllocator(w.alloc))
+	if err := iw.Write(converted); err != nil {
+		iw.Close()
+		return fmt.Errorf("frostdb: failed to serialize record: %w", err)
+	}
+	if err := iw.Close(); err != nil {
+		return fmt.Errorf("frostdb: failed to serialize record: %w", err)
+	}
+	ir, err := ipcv16.NewReader(&buf, ipcv16.WithAllocator(memoryv16.NewGoAllocator()))
+	if err != nil {
+		return fmt.Errorf("frostdb: failed to read serialized record: %w", err)
+	}
+	defer ir.Release()
+	for ir.Next() {
+		if _, err := w.table.InsertRecord(w.ctx, ir.Record()); err != nil {
+			return fmt.Errorf("frostdb: failed to insert record: %w", err)
+		}
+	}
+	return ir.Err()
+}
+
+// convert returns record with its columns cast to the table's types.
+func (w *FrostDBWriter) convert(record arrow.Record) (arrow.Record, error) {
+	if record.NumCols() != int64(w.schema.NumFields()) {
+		return nil, fmt.Errorf("record has %d columns, the table %d", record.NumCols(), w.schema.NumFields())
+	}
+	cols := make([]arrow.Array, record.NumCols())
+	defer func() {
+		for _, col := range cols {
+			if col != nil {
+				col.Release()
+			}
+		}
+	}()
+	for i, col := range record.Columns() {
+		to := w.schema.Field(i).Type
+		if arrow.TypeEqual(col.DataType(), to) {
+			col.Retain()
+			cols[i] = col
+			continue
+		}
+		var err error
+		if cols[i], err = castColumn(col, to); err != nil {
+			return nil, fmt.Errorf("column %q: %w", w.schema.Field(i).Name, err)
+		}
+	}
+	return array.NewRecord(w.schema, cols, record.NumRows()), nil
+}
+
+// castColumn casts arr to type to, going through the storage integers of
+// temporal types.
+func castColumn(arr arrow.Array, to arrow.DataType) (arrow.Array, error) {
+	ctx := context.Background()
+	switch dt := arr.DataType().(type) {
+	case *arrow.DictionaryType:
+		return compute.CastArray(ctx, arr, compute.SafeCastOptions(to))
+	case arrow.TemporalWithUnit, *arrow.Date32Type, *arrow.Date64Type:
+		// Reinterpret the values as the integers of the same width, then
+		// widen if needed.
+		storage := arrow.PrimitiveTypes.Int64
+		if dt.(arrow.FixedWidthDataType).BitWidth() == 32 {
+			storage = arrow.PrimitiveTypes.Int32
+		}
+		data := array.NewData(storage, arr.Len(), arr.Data().Buffers(), nil, arr.NullN(), arr.Data().Offset())
+		ints := array.MakeFromData(data)
+		data.Release()
+		if arrow.TypeEqual(storage, to) {
+			return ints, nil
+		}
+		defer ints.Release()
+		return compute.CastArray(ctx, ints, compute.SafeCastOptions(to))
+	}
+	return compute.CastArray(ctx, arr, compute.SafeCastOptions(to))
+}
+
+// Table returns the table written to.
+func (w *FrostDBWriter) Table() *frostdb.Table {
+	return w.table
+}
+
+// Close closes the store if the writer opened it, persisting its
+// write-ahead log.
+func (w *FrostDBWriter) Close() error {
+	return w.closeStore()
+}
+
+func (w *FrostDBWriter) closeStore() error {
+	if !w.ownsStore {
+		return nil
+	}
+	if err := w.store.Close(); err != nil {
+		return fmt.Errorf("frostdb: failed to close store: %w", err)
+	}
+	return nil
+}
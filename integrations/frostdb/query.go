@@ -0,0 +1,394 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	arrowv16 "github.com/apache/arrow/go/v16/arrow"
+	ipcv16 "github.com/apache/arrow/go/v16/arrow/ipc"
+	memoryv16 "github.com/apache/arrow/go/v16/arrow/memory"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+	"github.com/parquet-go/parquet-go"
+	"github.com/polarsignals/frostdb"
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// FrostDBQuery is a query of a FrostDB table. It can be loaded from YAML,
+// such as the config of a workflow integration:
+//
+//	table: metrics
+//	filter: host = 'a' AND cpu > 2
+//	aggregations: [sum(cpu), max(load)]
+//	group_by: [host]
+type FrostDBQuery struct {
+	Table string `yaml:"table"`
+	// Filter keeps the rows matching a pkg/filter expression.
+	Filter string `yaml:"filter"`
+	// Columns projects the rows onto these columns, all columns if empty.
+	Columns []string `yaml:"columns"`
+	// Aggregations are functions of a column computed for each group of
+	// rows with the same GroupBy values: sum, min, max, avg, count or
+	// unique, written as "sum(cpu)".
+	Aggregations []string `yaml:"aggregations"`
+	GroupBy      []string `yaml:"group_by"`
+	// Limit caps the number of rows returned when positive.
+	Limit uint64 `yaml:"limit"`
+}
+
+// FrostDBReadOptions configures a FrostDBReader.
+type FrostDBReadOptions struct {
+	// Store is the column store to query, left open on Close. When nil, the
+	// reader opens the store persisted under StoragePath and closes it on
+	// Close.
+	Store       *frostdb.ColumnStore
+	StoragePath string
+	// Database is the database of the table, DefaultDatabase if empty.
+	Database  string
+	Allocator memory.Allocator
+}
+
+// FrostDBReader streams the results of a query of a FrostDB table.
+type FrostDBReader struct {
+	store     *frostdb.ColumnStore
+	ownsStore bool
+	alloc     memory.Allocator
+
+	records chan arrow.Record
+	cancel  context.CancelFunc
+	err     error // of the query, set before records is closed
+
+	next   arrow.Record // read ahead by Schema
+	schema *arrow.Schema
+}
+
+// NewFrostDBReader starts executing q.
+func NewFrostDBReader(ctx context.Context, q FrostDBQuery, opts *FrostDBReadOptions) (*FrostDBReader, error) {
+	if opts == nil {
+		opts = &FrostDBReadOptions{}
+	}
+	r := &FrostDBReader{alloc: opts.Allocator, records: make(chan arrow.Record, 1)}
+	if r.alloc == nil {
+		r.alloc = pool.GetAllocator()
+	}
+	var err error
+	if r.store, r.ownsStore, err = openStore(opts.Store, opts.StoragePath); err != nil {
+		return nil, err
+	}
+	builder, err := r.plan(ctx, q, databaseName(opts.Database))
+	if err != nil {
+		r.closeStore()
+		return nil, err
+	}
+
+	queryCtx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+	go func() {
+		defer close(r.records)
+		r.err = builder.Execute(queryCtx, func(ctx context.Context, rec arrowv16.Record) error {
+			converted, err := r.fromV16(rec)
+			if err != nil {
+				return err
+			}
+			select {
+			case r.records <- converted:
+				return nil
+			case <-ctx.Done():
+				converted.Release()
+				return ctx.Err()
+			}
+		})
+	}()
+	return r, nil
+}
+
+// plan builds the query plan of q.
+func (r *FrostDBReader) plan(ctx context.Context, q FrostDBQuery, database string) (query.Builder, error) {
+	if q.Table == "" {
+		return nil, errors.New("frostdb: a table is required")
+	}
+	if len(q.Columns) > 0 && len(q.Aggregations) > 0 {
+		return nil, errors.New("frostdb: columns cannot be projected in an aggregation, group by them instead")
+	}
+	if len(q.GroupBy) > 0 && len(q.Aggregations) == 0 {
+		return nil, errors.New("frostdb: group_by requires aggregations")
+	}
+	db, err := r.store.DB(ctx, database)
+	if err != nil {
+		return nil, fmt.Errorf("frostdb: failed to open database %s: %w", database, err)
+	}
+	table, err := db.GetTable(q.Table)
+	if err != nil {
+		return nil, fmt.Errorf("frostdb: %w", err)
+	}
+
+	builder := query.NewEngine(memoryv16.NewGoAllocator(), db.TableProvider()).ScanTable(q.Table)
+	if q.Filter != "" {
+		f, err := filter.Parse(q.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("frostdb: invalid filter: %w", err)
+		}
+		expr, err := filterExpr(table.Schema(), f)
+		if err != nil {
+			return nil, fmt.Errorf("frostdb: invalid filter: %w", err)
+		}
+		builder = builder.Filter(expr)
+	}
+	if len(q.Aggregations) > 0 {
+		aggs := make([]*logicalplan.AggregationFunction, len(q.Aggregations))
+		for i, a := range q.Aggregations {
+			if aggs[i], err = aggregation(a); err != nil {
+				return nil, fmt.Errorf("frostdb: %w", err)
+			}
+		}
+		builder = builder.Aggregate(aggs, logicalplan.Cols(q.GroupBy...))
+	} else if len(q.Columns) > 0 {
+		builder = builder.Project(logicalplan.Cols(q.Columns...)...)
+	}
+	if q.Limit > 0 {
+		builder = builder.Limit(logicalplan.Literal(q.Limit))
+	}
+	return builder, nil
+}
+
+// aggregation parses an aggregation such as "sum(cpu)".
+func aggregation(text string) (*logicalplan.AggregationFunction, error) {
+	name, rest, ok := strings.Cut(strings.TrimSpace(text), "(")
+	column, closed := strings.CutSuffix(strings.TrimSpace(rest), ")")
+	if !ok || !closed || strings.TrimSpace(column) == "" {
+		return nil, fmt.Errorf("invalid aggregation %q, expected function(column)", text)
+	}
+	col := logicalplan.Col(strings.TrimSpace(column))
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "sum":
+		return logicalplan.Sum(col), nil
+	case "min":
+		return logicalplan.Min(col), nil
+	case "max":
+		return logicalplan.Max(col), nil
+	case "avg":
+		return logicalplan.Avg(col), nil
+	case "count":
+		return logicalplan.Count(col), nil
+	case "unique":
+		return logicalplan.Unique(col), nil
+	}
+	return nil, fmt.Errorf("unknown aggregation function %q", name)
+}
+
+// filterExpr translates f into a FrostDB expression, converting its
+// literals to the types of the columns they are compared with.
+func filterExpr(schema *dynparquet.Schema, f filter.Expr) (logicalplan.Expr, error) {
+	switch f := f.(type) {
+	case filter.And:
+		exprs, err := filterExprs(schema, f)
+		if err != nil {
+			return nil, err
+		}
+		return logicalplan.And(exprs...), nil
+	case filter.Or:
+		exprs, err := filterExprs(schema, f)
+		if err != nil {
+			return nil, err
+		}
+		return logicalplan.Or(exprs...), nil
+	case filter.Comparison:
+		def, ok := schema.FindColumn(f.Column)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", f.Column)
+		}
+		value, err := literalFor(def.StorageLayout, f.Value)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", f.Column, err)
+		}
+		col, lit := logicalplan.Col(f.Column), logicalplan.Literal(value)
+		switch f.Op {
+		case filter.Eq:
+			return col.Eq(lit), nil
+		case filter.Ne:
+			return col.NotEq(lit), nil
+		case filter.Lt:
+			return col.Lt(lit), nil
+		case filter.Le:
+			return col.LtEq(lit), nil
+		case filter.Gt:
+			return col.Gt(lit), nil
+		case filter.Ge:
+			return col.GtEq(lit), nil
+		}
+		return nil, fmt.Errorf("unsupported operator %s", f.Op)
+	}
+	return nil, fmt.Errorf("unsupported expression %s", f)
+}
+
+func filterExprs(schema *dynparquet.Schema, fs []filter.Expr) ([]logicalplan.Expr, error) {
+	exprs := make([]logicalplan.Expr, len(fs))
+	for i, f := range fs {
+		var err error
+		if exprs[i], err = filterExpr(schema, f); err != nil {
+			return nil, err
+		}
+	}
+	return exprs, nil
+}
+
+// literalFor converts a filter literal to the type of a column stored as
+// node.
+func literalFor(node parquet.Node, v any) (any, error) {
+	switch node.Type().Kind() {
+	case parquet.ByteArray:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return fmt.Sprint(v), nil
+	case parquet.Boolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case parquet.Double:
+		return nil, errors.New("FrostDB cannot filter on double columns")
+	case parquet.Int64:
+		unsigned := false
+		if lt := node.Type().LogicalType(); lt != nil && lt.Integer != nil {
+			unsigned = !lt.Integer.IsSigned
+		}
+		switch v := v.(type) {
+		case int64:
+			if !unsigned {
+				return v, nil
+			}
+			if v >= 0 {
+				return uint64(v), nil
+			}
+		case uint64:
+			if unsigned {
+				return v, nil
+			}
+			if v <= math.MaxInt64 {
+				return int64(v), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("cannot compare %v with a %s column", v, node.Type())
+}
+
+// fromV16 copies a result record into a record of this module's Arrow
+// version, going through IPC.
+func (r *FrostDBReader) fromV16(rec arrowv16.Record) (arrow.Record, error) {
+	var buf bytes.Buffer
+	iw := ipcv16.NewWriter(&buf, ipcv16.WithSchema(rec.Schema()))
+	if err := iw.Write(rec); err != nil {
+		iw.Close()
+		return nil, fmt.Errorf("frostdb: failed to serialize result: %w", err)
+	}
+	if err := iw.Close(); err != nil {
+		return nil, fmt.Errorf("frostdb: failed to serialize result: %w", err)
+	}
+	ir, err := ipc.NewReader(&buf, ipc.WithAllocator(r.alloc))
+	if err != nil {
+		return nil, fmt.Errorf("frostdb: failed to read serialized result: %w", err)
+	}
+	defer ir.Release()
+	if !ir.Next() {
+		if err := ir.Err(); err != nil {
+			return nil, fmt.Errorf("frostdb: failed to read serialized result: %w", err)
+		}
+		return nil, errors.New("frostdb: empty serialized result")
+	}
+	out := ir.Record()
+	out.Retain()
+	return out, nil
+}
+
+// Schema returns the schema of the results, waiting for the first one. It
+// returns nil when the query returns no records.
+func (r *FrostDBReader) Schema() *arrow.Schema {
+	if r.schema == nil && r.next == nil {
+		if rec, ok := <-r.records; ok {
+			r.next, r.schema = rec, rec.Schema()
+		}
+	}
+	return r.schema
+}
+
+// Read returns the next result record, or io.EOF once the query completes.
+func (r *FrostDBReader) Read() (arrow.Record, error) {
+	if r.next != nil {
+		rec := r.next
+		r.next = nil
+		return rec, nil
+	}
+	rec, ok := <-r.records
+	if !ok {
+		if r.err != nil {
+			return nil, fmt.Errorf("frostdb: query failed: %w", r.err)
+		}
+		return nil, io.EOF
+	}
+	if r.schema == nil {
+		r.schema = rec.Schema()
+	}
+	return rec, nil
+}
+
+// Close stops the query and closes the store if the reader opened it.
+func (r *FrostDBReader) Close() error {
+	r.cancel()
+	if r.next != nil {
+		r.next.Release()
+		r.next = nil
+	}
+	for rec := range r.records {
+		rec.Release()
+	}
+	return r.closeStore()
+}
+
+func (r *FrostDBReader) closeStore() error {
+	if !r.ownsStore {
+		return nil
+	}
+	if err := r.store.Close(); err != nil {
+		return fmt.Errorf("frostdb: failed to close store: %w", err)
+	}
+	return nil
+}
@@ -41,6 +41,9 @@ import (
 	"github.com/apache/arrow-go/v18/parquet"
 	"github.com/apache/arrow-go/v18/parquet/pqarrow"
 	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/gcpauth"
+	"github.com/arrowarc/arrowarc/pkg/retry"
+	"github.com/googleapis/gax-go/v2"
 	"google.golang.org/api/option"
 )
 
@@ -56,11 +59,25 @@ const (
 type GCSSink struct {
 	client     *storage.Client
 	bucketName string
+	// Retry, if set, replaces the client's retry policy for uploads. The
+	// upload of each chunk is retried for at most its MaxElapsed, and errors
+	// are classified by storage.ShouldRetry unless it sets a classifier.
+	Retry *retry.Policy
 }
 
 // NewGCSSink creates a new GCSSink with the specified bucket name and credentials file.
 func NewGCSSink(ctx context.Context, bucketName, credsFile string) (*GCSSink, error) {
-	client, err := storage.NewClient(ctx, option.WithCredentialsFile(credsFile))
+	return NewGCSSinkWithAuth(ctx, bucketName, &gcpauth.Options{CredentialsFile: credsFile})
+}
+
+// NewGCSSinkWithAuth creates a GCSSink authenticating as auth says, with
+// Application Default Credentials if it is nil.
+func NewGCSSinkWithAuth(ctx context.Context, bucketName string, auth *gcpauth.Options, opts ...option.ClientOption) (*GCSSink, error) {
+	opts, err := auth.ClientOptions(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	client, err := storage.NewClient(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GCS client: %w", err)
 	}
@@ -75,8 +92,15 @@ func NewGCSSink(ctx context.Context, bucketName, credsFile string) (*GCSSink, er
 func (s *GCSSink) WriteToGCS(ctx context.Context, reader arrio.Reader, filePath string, format FileFormat, delimiter rune, includeHeader bool, nullValue string, stringsReplacer *strings.Replacer, boolFormatter func(bool) string) error {
 	bucket := s.client.Bucket(s.bucketName)
 	obj := bucket.Object(filePath)
+	if s.Retry != nil {
+		obj = obj.Retryer(retryOptions(s.Retry)...)
+	}
 	writer := obj.NewWriter(ctx)
-	defer writer.Close()
+	if s.Retry != nil {
+		if maxElapsed := s.Retry.Effective().MaxElapsed; maxElapsed > 0 {
+			writer.ChunkRetryDeadline = maxElapsed
+		}
+	}
 
 	var err error
 	switch format {
@@ -85,15 +109,39 @@ func (s *GCSSink) WriteToGCS(ctx context.Context, reader arrio.Reader, filePath
 	case CSVFormat:
 		err = s.writeCSV(ctx, reader, writer, delimiter, includeHeader, nullValue, stringsReplacer, boolFormatter)
 	default:
+		writer.Close()
 		return fmt.Errorf("unsupported file format: %s", format)
 	}
 
+	// The upload completes, or fails, when the writer is closed.
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
 		return fmt.Errorf("failed to write to GCS: %w", err)
 	}
 	return nil
 }
 
+// retryOptions translates a retry policy into the storage client's own.
+// Uploads overwrite the whole object, so they are always safe to retry.
+func retryOptions(p *retry.Policy) []storage.RetryOption {
+	c := p.OrRetryable(storage.ShouldRetry).Effective()
+	opts := []storage.RetryOption{
+		storage.WithBackoff(gax.Backoff{
+			Initial:    c.InitialInterval,
+			Max:        c.MaxInterval,
+			Multiplier: c.Multiplier,
+		}),
+		storage.WithPolicy(storage.RetryAlways),
+		storage.WithErrorFunc(c.Retryable),
+	}
+	if c.MaxAttempts > 0 {
+		opts = append(opts, storage.WithMaxAttempts(c.MaxAttempts))
+	}
+	return opts
+}
+
 // writeParquet writes data from an Arrow reader to a Parquet file on GCS.
 func (s *GCSSink) writeParquet(ctx context.Context, reader arrio.Reader, writer io.Writer) error {
 	alloc := pool.GetAllocator()
@@ -0,0 +1,284 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// SchemeGRPC prefixes plaintext targets, e.g. grpc://localhost:50051.
+	SchemeGRPC = "grpc://"
+	// SchemeGRPCS prefixes TLS targets.
+	SchemeGRPCS = "grpcs://"
+	// EnvGRPCMetadata holds metadata sent with every call, as "Name: value"
+	// pairs separated by newlines or semicolons.
+	EnvGRPCMetadata = "ARROWARC_GRPC_METADATA"
+)
+
+// reflectionMethods are tried in order; older servers only implement
+// v1alpha, whose messages are wire compatible with v1.
+var reflectionMethods = []string{
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+}
+
+// DialOptions configures connections to gRPC servers.
+type DialOptions struct {
+	// Plaintext disables TLS.
+	Plaintext bool
+	// Metadata is sent with every call.
+	Metadata map[string]string
+}
+
+// NewDefaultDialOptions returns TLS with the metadata set by
+// EnvGRPCMetadata.
+func NewDefaultDialOptions() *DialOptions {
+	md := map[string]string{}
+	for _, pair := range strings.FieldsFunc(os.Getenv(EnvGRPCMetadata), func(r rune) bool { return r == '\n' || r == ';' }) {
+		if name, value, ok := strings.Cut(pair, ":"); ok {
+			md[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+		}
+	}
+	return &DialOptions{Metadata: md}
+}
+
+// IsTarget reports whether path is a grpc:// or grpcs:// target.
+func IsTarget(path string) bool {
+	return strings.HasPrefix(path, SchemeGRPC) || strings.HasPrefix(path, SchemeGRPCS)
+}
+
+// ParseTarget splits a target such as grpc://host:port/pkg.Service/Method
+// into the server address and the method, which is empty when the target
+// names only a server.
+func ParseTarget(target string) (address, method string, plaintext bool, err error) {
+	rest, plaintext := strings.CutPrefix(target, SchemeGRPC)
+	if !plaintext {
+		var ok bool
+		if rest, ok = strings.CutPrefix(target, SchemeGRPCS); !ok {
+			return "", "", false, fmt.Errorf("invalid gRPC target %q, expected %shost:port/pkg.Service/Method", target, SchemeGRPC)
+		}
+	}
+	address, method, _ = strings.Cut(rest, "/")
+	if address == "" {
+		return "", "", false, fmt.Errorf("invalid gRPC target %q: missing address", target)
+	}
+	return address, method, plaintext, nil
+}
+
+// Dial connects to the server at address. A nil opts uses
+// NewDefaultDialOptions.
+func Dial(address string, opts *DialOptions) (*grpc.ClientConn, error) {
+	if opts == nil {
+		opts = NewDefaultDialOptions()
+	}
+	creds := credentials.NewTLS(&tls.Config{})
+	if opts.Plaintext {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	return conn, nil
+}
+
+func outgoingContext(ctx context.Context, md map[string]string) context.Context {
+	for name, value := range md {
+		ctx = metadata.AppendToOutgoingContext(ctx, name, value)
+	}
+	return ctx
+}
+
+// Resolver fetches descriptors from a server through the gRPC reflection
+// service, so that its messages can be decoded without local proto files.
+type Resolver struct {
+	conn     *grpc.ClientConn
+	metadata map[string]string
+	files    map[string]*descriptorpb.FileDescriptorProto
+}
+
+// NewResolver returns a Resolver using conn. md is sent with every call.
+func NewResolver(conn *grpc.ClientConn, md map[string]string) *Resolver {
+	return &Resolver{conn: conn, metadata: md, files: map[string]*descriptorpb.FileDescriptorProto{}}
+}
+
+// ResolveMessage connects to target, a grpc:// or grpcs:// address, and
+// returns the descriptor of the fully qualified message name.
+func ResolveMessage(ctx context.Context, target, name string) (protoreflect.MessageDescriptor, error) {
+	address, _, plaintext, err := ParseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	opts := NewDefaultDialOptions()
+	opts.Plaintext = plaintext
+	conn, err := Dial(address, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return NewResolver(conn, opts.Metadata).FindMessage(ctx, name)
+}
+
+// FindMessage returns the descriptor of the fully qualified message name.
+func (r *Resolver) FindMessage(ctx context.Context, name string) (protoreflect.MessageDescriptor, error) {
+	d, err := r.find(ctx, strings.TrimPrefix(name, "."))
+	if err != nil {
+		return nil, err
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", name)
+	}
+	return md, nil
+}
+
+// FindMethod returns the descriptor of a method written as
+// pkg.Service/Method or pkg.Service.Method.
+func (r *Resolver) FindMethod(ctx context.Context, name string) (protoreflect.MethodDescriptor, error) {
+	name = strings.TrimPrefix(name, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[:i] + "." + name[i+1:]
+	}
+	d, err := r.find(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	md, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a method", name)
+	}
+	return md, nil
+}
+
+func (r *Resolver) find(ctx context.Context, symbol string) (protoreflect.Descriptor, error) {
+	var err error
+	for _, method := range reflectionMethods {
+		var files *protoregistry.Files
+		files, err = r.fetch(ctx, method, symbol)
+		if status.Code(err) == codes.Unimplemented {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", symbol, err)
+		}
+		return files.FindDescriptorByName(protoreflect.FullName(symbol))
+	}
+	return nil, fmt.Errorf("server does not support reflection: %w", err)
+}
+
+// fetch asks the server for the file defining symbol and for any of its
+// dependencies it did not send along.
+func (r *Resolver) fetch(ctx context.Context, method, symbol string) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithCancel(outgoingContext(ctx, r.metadata))
+	defer cancel()
+	stream, err := r.conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, method)
+	if err != nil {
+		return nil, err
+	}
+	ask := func(req *rpb.ServerReflectionRequest) error {
+		if err := stream.SendMsg(req); err != nil {
+			return err
+		}
+		resp := new(rpb.ServerReflectionResponse)
+		if err := stream.RecvMsg(resp); err != nil {
+			return err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+		}
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := new(descriptorpb.FileDescriptorProto)
+			if err := proto.Unmarshal(raw, fd); err != nil {
+				return fmt.Errorf("invalid file descriptor: %w", err)
+			}
+			r.files[fd.GetName()] = fd
+		}
+		return nil
+	}
+
+	if err := ask(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol},
+	}); err != nil {
+		return nil, err
+	}
+	for name := r.missingDependency(); name != ""; name = r.missingDependency() {
+		err := ask(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+		})
+		if _, ok := r.files[name]; ok {
+			continue
+		}
+		// Servers may omit the well-known types.
+		if fd, gerr := protoregistry.GlobalFiles.FindFileByPath(name); gerr == nil {
+			r.files[name] = protodesc.ToFileDescriptorProto(fd)
+			continue
+		}
+		if err == nil {
+			err = fmt.Errorf("server did not return it")
+		}
+		return nil, fmt.Errorf("failed to fetch %s: %w", name, err)
+	}
+	stream.CloseSend()
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fd := range r.files {
+		set.File = append(set.File, fd)
+	}
+	return protodesc.NewFiles(set)
+}
+
+func (r *Resolver) missingDependency() string {
+	for _, fd := range r.files {
+		for _, dep := range fd.GetDependency() {
+			if _, ok := r.files[dep]; !ok {
+				return dep
+			}
+		}
+	}
+	return ""
+}
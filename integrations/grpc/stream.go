@@ -0,0 +1,237 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/arrowproto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// StreamOptions configures a StreamReader.
+type StreamOptions struct {
+	// Dial configures the connection. A nil Dial uses
+	// NewDefaultDialOptions, with TLS set by the target's scheme.
+	Dial *DialOptions
+	// Request is the request message in protobuf JSON. Empty sends an
+	// empty message.
+	Request string
+	// ChunkSize is the number of responses per record.
+	ChunkSize int
+	// MaxMessages stops the stream after that many responses. Zero reads
+	// until the server ends the stream.
+	MaxMessages int64
+	// FlushInterval returns a partial record when no full chunk arrived in
+	// that time, so that slow streams still make progress. Zero waits for
+	// full chunks.
+	FlushInterval time.Duration
+}
+
+// StreamReader calls a unary or server-streaming RPC and reads its responses
+// as Arrow records, using descriptors fetched through server reflection.
+// It implements the Reader interface.
+type StreamReader struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	conn     *grpc.ClientConn
+	output   protoreflect.MessageDescriptor
+	builder  *arrowproto.RecordBuilder
+	alloc    memory.Allocator
+	msgs     chan proto.Message
+	err      error
+	ended    bool
+	chunk    int
+	max      int64
+	received int64
+	flush    time.Duration
+}
+
+// NewStreamReader calls the method named by target, such as
+// grpc://localhost:50051/pkg.Service/Method, and returns a reader over its
+// responses.
+func NewStreamReader(ctx context.Context, target string, opts *StreamOptions) (*StreamReader, error) {
+	if opts == nil {
+		opts = &StreamOptions{}
+	}
+	address, method, plaintext, err := ParseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	if method == "" {
+		return nil, fmt.Errorf("gRPC target %q names no method", target)
+	}
+	dialOpts := opts.Dial
+	if dialOpts == nil {
+		dialOpts = NewDefaultDialOptions()
+		dialOpts.Plaintext = plaintext
+	}
+	conn, err := Dial(address, dialOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &StreamReader{conn: conn, chunk: opts.ChunkSize, max: opts.MaxMessages, flush: opts.FlushInterval}
+	if r.chunk <= 0 {
+		r.chunk = 1024
+	}
+	if err := r.start(ctx, NewResolver(conn, dialOpts.Metadata), method, opts.Request, dialOpts.Metadata); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *StreamReader) start(ctx context.Context, resolver *Resolver, method, request string, md map[string]string) error {
+	desc, err := resolver.FindMethod(ctx, method)
+	if err != nil {
+		return err
+	}
+	if desc.IsStreamingClient() {
+		return fmt.Errorf("method %s streams requests, which is not supported", desc.FullName())
+	}
+
+	req := dynamicpb.NewMessage(desc.Input())
+	if request != "" {
+		if err := protojson.Unmarshal([]byte(request), req); err != nil {
+			return fmt.Errorf("invalid %s request: %w", desc.Input().FullName(), err)
+		}
+	}
+
+	r.alloc = pool.GetAllocator()
+	if r.builder, err = arrowproto.NewRecordBuilder(r.alloc, desc.Output()); err != nil {
+		pool.PutAllocator(r.alloc)
+		return fmt.Errorf("failed to map %s to Arrow: %w", desc.Output().FullName(), err)
+	}
+	r.output = desc.Output()
+
+	r.ctx, r.cancel = context.WithCancel(outgoingContext(ctx, md))
+	fullMethod := fmt.Sprintf("/%s/%s", desc.Parent().FullName(), desc.Name())
+	stream, err := r.conn.NewStream(r.ctx, &grpc.StreamDesc{ServerStreams: true}, fullMethod)
+	if err == nil {
+		if err = stream.SendMsg(req); err == nil {
+			err = stream.CloseSend()
+		}
+	}
+	if err != nil {
+		r.cancel()
+		r.builder.Release()
+		pool.PutAllocator(r.alloc)
+		return fmt.Errorf("failed to call %s: %w", fullMethod, err)
+	}
+
+	r.msgs = make(chan proto.Message, r.chunk)
+	go r.receive(stream)
+	return nil
+}
+
+// receive forwards responses until the stream ends. r.err is set before
+// msgs is closed, so Read may only look at it once it saw msgs closed.
+func (r *StreamReader) receive(stream grpc.ClientStream) {
+	defer close(r.msgs)
+	for {
+		msg := dynamicpb.NewMessage(r.output)
+		if err := stream.RecvMsg(msg); err != nil {
+			if err != io.EOF && r.ctx.Err() == nil {
+				r.err = err
+			}
+			return
+		}
+		select {
+		case r.msgs <- msg:
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// Read returns the next chunk of responses.
+func (r *StreamReader) Read() (arrow.Record, error) {
+	var timeout <-chan time.Time
+	if r.flush > 0 {
+		timer := time.NewTimer(r.flush)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+loop:
+	for r.builder.Len() < int64(r.chunk) && (r.max <= 0 || r.received < r.max) {
+		select {
+		case msg, ok := <-r.msgs:
+			if !ok {
+				r.ended = true
+				break loop
+			}
+			if err := r.builder.Append(msg); err != nil {
+				return nil, fmt.Errorf("error converting %s: %w", r.output.FullName(), err)
+			}
+			r.received++
+		case <-timeout:
+			if r.builder.Len() > 0 {
+				break loop
+			}
+			timeout = nil
+		}
+	}
+	if r.max > 0 && r.received >= r.max {
+		r.cancel()
+	}
+
+	if r.builder.Len() == 0 {
+		if r.ended && r.err != nil {
+			return nil, fmt.Errorf("gRPC stream failed: %w", r.err)
+		}
+		return nil, io.EOF
+	}
+	return r.builder.NewRecord(), nil
+}
+
+// Schema returns the Arrow schema the responses are mapped to.
+func (r *StreamReader) Schema() *arrow.Schema {
+	return r.builder.Schema()
+}
+
+// Close cancels the call and closes the connection.
+func (r *StreamReader) Close() error {
+	defer pool.PutAllocator(r.alloc)
+	r.cancel()
+	r.builder.Release()
+	return r.conn.Close()
+}
@@ -0,0 +1,439 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/polarsignals/iceberg-go"
+	"github.com/polarsignals/iceberg-go/catalog"
+	"github.com/polarsignals/iceberg-go/table"
+	"github.com/thanos-io/objstore"
+)
+
+// Table parameters of Iceberg tables kept in a metastore.
+const (
+	ParamTableType                = "table_type"
+	ParamMetadataLocation         = "metadata_location"
+	ParamPreviousMetadataLocation = "previous_metadata_location"
+	IcebergTableType              = "ICEBERG"
+)
+
+// ErrCommitConflict is returned when a table changed in the metastore
+// since it was loaded, so that the snapshot written on top of it would
+// lose the other writer's.
+var ErrCommitConflict = errors.New("iceberg table changed since it was loaded")
+
+// IcebergCatalog is an Iceberg catalog kept in a Hive Metastore, as Spark,
+// Trino and Flink keep theirs: each table is an external table whose
+// metadata_location parameter names its current metadata file. It
+// implements catalog.Catalog, so it can back integrations/iceberg.
+//
+// Tables are named {database, table}, or by a single path whose last two
+// elements are the database and the table, as integrations/iceberg names
+// them. Commits check that the table has not changed since it was loaded
+// but do not take the metastore's locks.
+type IcebergCatalog struct {
+	metastore *Metastore
+	warehouse string
+	bucket    objstore.Bucket
+}
+
+var _ catalog.Catalog = (*IcebergCatalog)(nil)
+
+// NewIcebergCatalog returns a catalog of the Iceberg tables of metastore,
+// whose files are in bucket. Locations are full URIs under warehouseURI,
+// such as s3://bucket/warehouse, which bucket is rooted at.
+func NewIcebergCatalog(metastore *Metastore, warehouseURI string, bucket objstore.Bucket) *IcebergCatalog {
+	return &IcebergCatalog{
+		metastore: metastore,
+		warehouse: warehouseURI,
+		bucket:    catalog.NewIcebucket(warehouseURI, bucket),
+	}
+}
+
+func (c *IcebergCatalog) CatalogType() catalog.CatalogType {
+	return catalog.Hive
+}
+
+// identParts splits ident into its path elements, less the warehouse.
+func (c *IcebergCatalog) identParts(ident table.Identifier) []string {
+	var parts []string
+	for _, p := range ident {
+		if c.warehouse != "" {
+			p = strings.TrimPrefix(p, c.warehouse)
+		}
+		for _, s := range strings.Split(p, "/") {
+			if s != "" {
+				parts = append(parts, s)
+			}
+		}
+	}
+	return parts
+}
+
+// tableName returns the database and the table ident names.
+func (c *IcebergCatalog) tableName(ident table.Identifier) (string, string, error) {
+	parts := c.identParts(ident)
+	if len(parts) < 2 {
+		return "", "", fmt.Errorf("%w: %q does not name a database and a table", catalog.ErrNoSuchTable, strings.Join(ident, "."))
+	}
+	return parts[len(parts)-2], parts[len(parts)-1], nil
+}
+
+// namespace returns the database ident names.
+func (c *IcebergCatalog) namespace(ident table.Identifier) (string, error) {
+	parts := c.identParts(ident)
+	if len(parts) == 0 {
+		return "", fmt.Errorf("%w: no database named", catalog.ErrNoSuchNamespace)
+	}
+	return parts[len(parts)-1], nil
+}
+
+// ListNamespaces returns the databases of the metastore. They are not
+// nested, so parent is ignored.
+func (c *IcebergCatalog) ListNamespaces(ctx context.Context, _ table.Identifier) ([]table.Identifier, error) {
+	dbs, err := c.metastore.Databases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make([]table.Identifier, 0, len(dbs))
+	for _, db := range dbs {
+		namespaces = append(namespaces, table.Identifier{db})
+	}
+	return namespaces, nil
+}
+
+// ListTables returns the Iceberg tables of a database, as {database,
+// table}.
+func (c *IcebergCatalog) ListTables(ctx context.Context, namespace table.Identifier) ([]table.Identifier, error) {
+	db, err := c.namespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+	names, err := c.metastore.Tables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var tables []table.Identifier
+	for _, name := range names {
+		t, err := c.metastore.Table(ctx, db, name)
+		if errors.Is(err, ErrNoSuchObject) {
+			continue // dropped meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isIcebergTable(t) {
+			tables = append(tables, table.Identifier{db, name})
+		}
+	}
+	return tables, nil
+}
+
+func isIcebergTable(t *Table) bool {
+	return strings.EqualFold(t.Parameters[ParamTableType], IcebergTableType)
+}
+
+// LoadTable loads the current metadata of a table.
+func (c *IcebergCatalog) LoadTable(ctx context.Context, ident table.Identifier, _ iceberg.Properties) (table.Table, error) {
+	db, name, err := c.tableName(ident)
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.metastore.Table(ctx, db, name)
+	if errors.Is(err, ErrNoSuchObject) {
+		return nil, fmt.Errorf("%w: %s.%s", catalog.ErrorTableNotFound, db, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isIcebergTable(t) {
+		return nil, fmt.Errorf("%s.%s is not an Iceberg table", db, name)
+	}
+	location := t.Parameters[ParamMetadataLocation]
+	if location == "" {
+		return nil, fmt.Errorf("%s.%s has no %s", db, name, ParamMetadataLocation)
+	}
+
+	r, err := c.bucket.Get(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata file: %w", err)
+	}
+	defer r.Close()
+	md, err := table.ParseMetadata(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	return c.newTable(db, name, md, location), nil
+}
+
+// metadataVersion matches the version of metadata files named
+// v3.metadata.json, as this package writes them, or
+// 00003-<uuid>.metadata.json, as other engines do.
+var metadataVersion = regexp.MustCompile(`^v?(\d+)[.-]`)
+
+func (c *IcebergCatalog) newTable(db, name string, md table.Metadata, location string) *hiveTable {
+	version := 0
+	if m := metadataVersion.FindStringSubmatch(filepath.Base(location)); m != nil {
+		version, _ = strconv.Atoi(m[1])
+	}
+	return &hiveTable{
+		Table:   table.NewHDFSTable(version, table.Identifier{db, name}, md, location, c.bucket),
+		catalog: c,
+		db:      db,
+		name:    name,
+		version: version,
+	}
+}
+
+// CreateTable creates a table at location, whose last two path elements
+// name its database and the table. Its first metadata file is written
+// and the table is registered in the metastore.
+func (c *IcebergCatalog) CreateTable(ctx context.Context, location string, schema *iceberg.Schema, props iceberg.Properties, options ...catalog.TableOption) (table.Table, error) {
+	db, name, err := c.tableName(table.Identifier{location})
+	if err != nil {
+		return nil, err
+	}
+	// The Hadoop catalog builds the metadata without writing anything.
+	created, err := catalog.NewHDFS(c.warehouse, c.bucket).CreateTable(ctx, location, schema, props, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataLocation := filepath.Join(location, "metadata", "v0.metadata.json")
+	js, err := json.Marshal(created.Metadata())
+	if err != nil {
+		return nil, err
+	}
+	if err := c.bucket.Upload(ctx, metadataLocation, bytes.NewReader(js)); err != nil {
+		return nil, err
+	}
+
+	err = c.metastore.CreateTable(ctx, &Table{
+		Database:     db,
+		Name:         name,
+		Type:         ExternalTable,
+		Location:     location,
+		InputFormat:  "org.apache.hadoop.mapred.FileInputFormat",
+		OutputFormat: "org.apache.hadoop.mapred.FileOutputFormat",
+		SerDe:        "org.apache.hadoop.hive.serde2.lazy.LazySimpleSerDe",
+		Columns:      hiveColumns(schema),
+		Parameters: map[string]string{
+			"EXTERNAL":            "TRUE",
+			ParamTableType:        IcebergTableType,
+			ParamMetadataLocation: metadataLocation,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register %s.%s: %w", db, name, err)
+	}
+	return c.newTable(db, name, created.Metadata(), metadataLocation), nil
+}
+
+// hiveColumns returns the columns of schema with their Hive types, for
+// engines that read them from the metastore.
+func hiveColumns(schema *iceberg.Schema) []Column {
+	cols := make([]Column, 0, schema.NumFields())
+	for _, f := range schema.Fields() {
+		typ := f.Type.String()
+		switch typ {
+		case "long":
+			typ = "bigint"
+		case "int", "string", "boolean", "double", "float", "binary", "date", "timestamp":
+		default:
+			typ = "string"
+		}
+		cols = append(cols, Column{Name: f.Name, Type: typ, Comment: f.Doc})
+	}
+	return cols
+}
+
+// DropTable removes a table from the metastore, leaving its files.
+func (c *IcebergCatalog) DropTable(ctx context.Context, ident table.Identifier) error {
+	db, name, err := c.tableName(ident)
+	if err != nil {
+		return err
+	}
+	return c.metastore.DropTable(ctx, db, name, false)
+}
+
+// RenameTable renames a table, which may move it to another database.
+func (c *IcebergCatalog) RenameTable(ctx context.Context, from, to table.Identifier) (table.Table, error) {
+	db, name, err := c.tableName(from)
+	if err != nil {
+		return nil, err
+	}
+	toDB, toName, err := c.tableName(to)
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.metastore.Table(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+	t.Database, t.Name = toDB, toName
+	if err := c.metastore.AlterTable(ctx, db, name, t); err != nil {
+		return nil, err
+	}
+	return c.LoadTable(ctx, table.Identifier{toDB, toName}, nil)
+}
+
+// CreateNamespace creates a database. A "location" property sets its
+// location, and a "comment" its description; the others are kept as its
+// parameters.
+func (c *IcebergCatalog) CreateNamespace(ctx context.Context, namespace table.Identifier, props iceberg.Properties) error {
+	db, err := c.namespace(namespace)
+	if err != nil {
+		return err
+	}
+	d := &Database{Name: db, Parameters: map[string]string{}}
+	for k, v := range props {
+		switch k {
+		case "location":
+			d.Location = v
+		case "comment":
+			d.Description = v
+		default:
+			d.Parameters[k] = v
+		}
+	}
+	return c.metastore.CreateDatabase(ctx, d)
+}
+
+// DropNamespace drops an empty database.
+func (c *IcebergCatalog) DropNamespace(ctx context.Context, namespace table.Identifier) error {
+	db, err := c.namespace(namespace)
+	if err != nil {
+		return err
+	}
+	if err := c.metastore.DropDatabase(ctx, db, false); errors.Is(err, ErrNoSuchObject) {
+		return fmt.Errorf("%w: %s", catalog.ErrNoSuchNamespace, db)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// LoadNamespaceProperties returns the parameters of a database, with its
+// location and description as "location" and "comment".
+func (c *IcebergCatalog) LoadNamespaceProperties(ctx context.Context, namespace table.Identifier) (iceberg.Properties, error) {
+	d, err := c.database(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	props := iceberg.Properties{}
+	for k, v := range d.Parameters {
+		props[k] = v
+	}
+	if d.Location != "" {
+		props["location"] = d.Location
+	}
+	if d.Description != "" {
+		props["comment"] = d.Description
+	}
+	return props, nil
+}
+
+// UpdateNamespaceProperties removes and sets parameters of a database.
+func (c *IcebergCatalog) UpdateNamespaceProperties(ctx context.Context, namespace table.Identifier, removals []string, updates iceberg.Properties) (catalog.PropertiesUpdateSummary, error) {
+	var summary catalog.PropertiesUpdateSummary
+	d, err := c.database(ctx, namespace)
+	if err != nil {
+		return summary, err
+	}
+	if d.Parameters == nil {
+		d.Parameters = map[string]string{}
+	}
+	for _, k := range removals {
+		if _, ok := d.Parameters[k]; !ok {
+			summary.Missing = append(summary.Missing, k)
+			continue
+		}
+		delete(d.Parameters, k)
+		summary.Removed = append(summary.Removed, k)
+	}
+	for k, v := range updates {
+		d.Parameters[k] = v
+		summary.Updated = append(summary.Updated, k)
+	}
+	return summary, c.metastore.AlterDatabase(ctx, d.Name, d)
+}
+
+func (c *IcebergCatalog) database(ctx context.Context, namespace table.Identifier) (*Database, error) {
+	db, err := c.namespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+	d, err := c.metastore.Database(ctx, db)
+	if errors.Is(err, ErrNoSuchObject) {
+		return nil, fmt.Errorf("%w: %s", catalog.ErrNoSuchNamespace, db)
+	}
+	return d, err
+}
+
+// hiveTable is an Iceberg table of an IcebergCatalog, whose snapshots are
+// committed by pointing the metastore table at their metadata file.
+type hiveTable struct {
+	table.Table
+	catalog  *IcebergCatalog
+	db, name string
+	version  int
+}
+
+func (t *hiveTable) SnapshotWriter(options ...table.WriterOption) (table.SnapshotWriter, error) {
+	w := table.NewSnapshotWriter(t.commit, t.version, t.Bucket(), t, options...)
+	return &w, nil
+}
+
+// commit points the metastore table at the metadata file of version, as
+// the snapshot writer names it, unless another writer moved it first.
+func (t *hiveTable) commit(ctx context.Context, version int) error {
+	ms := t.catalog.metastore
+	current, err := ms.Table(ctx, t.db, t.name)
+	if err != nil {
+		return err
+	}
+	previous := current.Parameters[ParamMetadataLocation]
+	if previous != t.MetadataLocation() {
+		return fmt.Errorf("%w: %s.%s is at %s", ErrCommitConflict, t.db, t.name, previous)
+	}
+	current.Parameters[ParamPreviousMetadataLocation] = previous
+	current.Parameters[ParamMetadataLocation] = filepath.Join(t.Location(), "metadata", fmt.Sprintf("v%d.metadata.json", version))
+	return ms.AlterTable(ctx, t.db, t.name, current)
+}
@@ -0,0 +1,445 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// DefaultMetastoreTimeout bounds connecting to the metastore and each call.
+const DefaultMetastoreTimeout = 30 * time.Second
+
+// Table types of the metastore.
+const (
+	ManagedTable  = "MANAGED_TABLE"
+	ExternalTable = "EXTERNAL_TABLE"
+)
+
+// ErrNoSuchObject is returned when the database, table or partition asked
+// for does not exist.
+var ErrNoSuchObject = errors.New("no such object in the metastore")
+
+// MetastoreOptions configures NewMetastore.
+type MetastoreOptions struct {
+	// Framed uses the framed transport, for metastores that enable
+	// hive.metastore.thrift.framed.transport.enabled.
+	Framed bool
+	// Timeout bounds connecting and each call. Defaults to
+	// DefaultMetastoreTimeout.
+	Timeout time.Duration
+	// TLS connects with TLS when set.
+	TLS *tls.Config
+}
+
+// Metastore is a client of the Thrift API of a Hive Metastore. Calls share
+// one connection and are serialized; after a transport error the client
+// must be closed and a new one opened.
+type Metastore struct {
+	mu        sync.Mutex
+	transport thrift.TTransport
+	protocol  thrift.TProtocol
+	seq       int32
+}
+
+// Database is a metastore database.
+type Database struct {
+	Name        string
+	Description string
+	Location    string
+	Parameters  map[string]string
+	raw         object
+}
+
+// Column is a column of a metastore table and its Hive type, such as
+// string, bigint or array<int>.
+type Column struct {
+	Name    string
+	Type    string
+	Comment string
+}
+
+// Table is a metastore table. Fields it does not name are kept from the
+// table read, so that it can be changed and passed to AlterTable.
+type Table struct {
+	Database string
+	Name     string
+	Owner    string
+	// Type is ManagedTable, ExternalTable or VIRTUAL_VIEW.
+	Type          string
+	Location      string
+	InputFormat   string
+	OutputFormat  string
+	SerDe         string
+	Columns       []Column
+	PartitionKeys []Column
+	Parameters    map[string]string
+	raw           object
+}
+
+// Partition is a partition of a metastore table: its values of the
+// table's partition keys, in order, and where its files are.
+type Partition struct {
+	Values     []string
+	Location   string
+	Parameters map[string]string
+}
+
+// NewMetastore connects to the metastore at addr, a host:port or a
+// thrift:// URI.
+func NewMetastore(ctx context.Context, addr string, opts *MetastoreOptions) (*Metastore, error) {
+	if opts == nil {
+		opts = &MetastoreOptions{}
+	}
+	addr = strings.TrimPrefix(addr, "thrift://")
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultMetastoreTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	conf := &thrift.TConfiguration{ConnectTimeout: timeout, SocketTimeout: timeout, TLSConfig: opts.TLS}
+
+	var transport thrift.TTransport
+	if opts.TLS != nil {
+		transport = thrift.NewTSSLSocketConf(addr, conf)
+	} else {
+		transport = thrift.NewTSocketConf(addr, conf)
+	}
+	if opts.Framed {
+		transport = thrift.NewTFramedTransportConf(transport, conf)
+	} else {
+		transport = thrift.NewTBufferedTransport(transport, 8192)
+	}
+	if err := transport.Open(); err != nil {
+		return nil, fmt.Errorf("failed to connect to the metastore at %s: %w", addr, err)
+	}
+	return &Metastore{
+		transport: transport,
+		protocol:  thrift.NewTBinaryProtocolConf(transport, conf),
+	}, nil
+}
+
+// Close closes the connection.
+func (m *Metastore) Close() error {
+	return m.transport.Close()
+}
+
+// call calls method with args and returns its result. Exceptions the
+// method declares become errors, wrapping ErrNoSuchObject when their field
+// is one of notFound.
+func (m *Metastore) call(ctx context.Context, method string, args object, notFound ...int16) (any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	p := m.protocol
+	if err := p.WriteMessageBegin(ctx, method, thrift.CALL, m.seq); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	if err := writeObject(ctx, p, args); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	if err := p.WriteMessageEnd(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	if err := p.Flush(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+
+	_, typ, seq, err := p.ReadMessageBegin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	if typ == thrift.EXCEPTION {
+		exc := thrift.NewTApplicationException(thrift.UNKNOWN_APPLICATION_EXCEPTION, "")
+		if err := exc.Read(ctx, p); err != nil {
+			return nil, fmt.Errorf("%s: %w", method, err)
+		}
+		p.ReadMessageEnd(ctx)
+		return nil, fmt.Errorf("%s: %w", method, exc)
+	}
+	if seq != m.seq {
+		return nil, fmt.Errorf("%s: response out of sequence", method)
+	}
+	result, err := readObject(ctx, p, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	if err := p.ReadMessageEnd(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+
+	if success, ok := result[0]; ok {
+		return success.v, nil
+	}
+	for id, f := range result {
+		exc, ok := f.v.(object)
+		if !ok {
+			continue
+		}
+		if slices.Contains(notFound, id) {
+			return nil, fmt.Errorf("%s: %w: %s", method, ErrNoSuchObject, exc.str(1))
+		}
+		return nil, fmt.Errorf("%s: %s", method, exc.str(1))
+	}
+	return nil, nil
+}
+
+// Databases returns the names of the databases.
+func (m *Metastore) Databases(ctx context.Context) ([]string, error) {
+	v, err := m.call(ctx, "get_all_databases", object{})
+	if err != nil {
+		return nil, err
+	}
+	return object{0: {thrift.LIST, v}}.strings(0), nil
+}
+
+// Database returns the database called name.
+func (m *Metastore) Database(ctx context.Context, name string) (*Database, error) {
+	v, err := m.call(ctx, "get_database", object{1: {thrift.STRING, name}}, 1)
+	if err != nil {
+		return nil, err
+	}
+	o, _ := v.(object)
+	return &Database{
+		Name:        o.str(1),
+		Description: o.str(2),
+		Location:    o.str(3),
+		Parameters:  o.stringMap(4),
+		raw:         o,
+	}, nil
+}
+
+func (d *Database) object() object {
+	o := object{}
+	for id, f := range d.raw {
+		o[id] = f
+	}
+	o.setStr(1, d.Name)
+	o.setStr(2, d.Description)
+	o.setStr(3, d.Location)
+	o.setStringMap(4, d.Parameters)
+	return o
+}
+
+// CreateDatabase creates a database.
+func (m *Metastore) CreateDatabase(ctx context.Context, d *Database) error {
+	_, err := m.call(ctx, "create_database", object{1: {thrift.STRUCT, d.object()}})
+	return err
+}
+
+// AlterDatabase replaces the database called name with d.
+func (m *Metastore) AlterDatabase(ctx context.Context, name string, d *Database) error {
+	_, err := m.call(ctx, "alter_database", object{1: {thrift.STRING, name}, 2: {thrift.STRUCT, d.object()}}, 2)
+	return err
+}
+
+// DropDatabase drops the database called name, which must be empty unless
+// cascade is set. Table files are left in place.
+func (m *Metastore) DropDatabase(ctx context.Context, name string, cascade bool) error {
+	_, err := m.call(ctx, "drop_database", object{
+		1: {thrift.STRING, name},
+		2: {thrift.BOOL, false},
+		3: {thrift.BOOL, cascade},
+	}, 1)
+	return err
+}
+
+// Tables returns the names of the tables of database db.
+func (m *Metastore) Tables(ctx context.Context, db string) ([]string, error) {
+	v, err := m.call(ctx, "get_all_tables", object{1: {thrift.STRING, db}})
+	if err != nil {
+		return nil, err
+	}
+	return object{0: {thrift.LIST, v}}.strings(0), nil
+}
+
+// Table returns table name of database db.
+func (m *Metastore) Table(ctx context.Context, db, name string) (*Table, error) {
+	v, err := m.call(ctx, "get_table", object{1: {thrift.STRING, db}, 2: {thrift.STRING, name}}, 2)
+	if err != nil {
+		return nil, err
+	}
+	o, _ := v.(object)
+	sd := o.object(7)
+	return &Table{
+		Database:      o.str(2),
+		Name:          o.str(1),
+		Owner:         o.str(3),
+		Type:          o.str(12),
+		Location:      sd.str(2),
+		InputFormat:   sd.str(3),
+		OutputFormat:  sd.str(4),
+		SerDe:         sd.object(7).str(2),
+		Columns:       columns(sd.objects(1)),
+		PartitionKeys: columns(o.objects(8)),
+		Parameters:    o.stringMap(9),
+		raw:           o,
+	}, nil
+}
+
+func columns(fields []object) []Column {
+	cols := make([]Column, 0, len(fields))
+	for _, f := range fields {
+		cols = append(cols, Column{Name: f.str(1), Type: f.str(2), Comment: f.str(3)})
+	}
+	return cols
+}
+
+func columnObjects(cols []Column) []object {
+	fields := make([]object, 0, len(cols))
+	for _, c := range cols {
+		f := object{}
+		f.setStr(1, c.Name)
+		f.setStr(2, c.Type)
+		f.setStr(3, c.Comment)
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// object returns t as a Thrift struct, over the fields of the table read.
+func (t *Table) object() object {
+	o := object{}
+	for id, f := range t.raw {
+		o[id] = f
+	}
+	if t.raw == nil {
+		now := int32(time.Now().Unix())
+		o[4] = value{thrift.I32, now}
+		o[5] = value{thrift.I32, int32(0)}
+		o[6] = value{thrift.I32, int32(0)}
+	}
+	o.setStr(1, t.Name)
+	o.setStr(2, t.Database)
+	o.setStr(3, t.Owner)
+	o.setStr(12, t.Type)
+	o[8] = objectList(columnObjects(t.PartitionKeys))
+	o.setStringMap(9, t.Parameters)
+
+	sd := object{}
+	for id, f := range t.raw.object(7) {
+		sd[id] = f
+	}
+	if t.raw == nil {
+		sd[5] = value{thrift.BOOL, false}
+		sd[6] = value{thrift.I32, int32(-1)}
+		sd[8] = stringList(nil)
+		sd[9] = objectList(nil)
+		sd.setStringMap(10, nil)
+	}
+	sd[1] = objectList(columnObjects(t.Columns))
+	sd.setStr(2, t.Location)
+	sd.setStr(3, t.InputFormat)
+	sd.setStr(4, t.OutputFormat)
+	serde := object{}
+	for id, f := range t.raw.object(7).object(7) {
+		serde[id] = f
+	}
+	serde.setStr(2, t.SerDe)
+	if _, ok := serde[3]; !ok {
+		serde.setStringMap(3, nil)
+	}
+	sd[7] = value{thrift.STRUCT, serde}
+	o[7] = value{thrift.STRUCT, sd}
+	return o
+}
+
+// CreateTable creates a table.
+func (m *Metastore) CreateTable(ctx context.Context, t *Table) error {
+	_, err := m.call(ctx, "create_table", object{1: {thrift.STRUCT, t.object()}}, 4)
+	return err
+}
+
+// AlterTable replaces table name of database db with t, which may rename
+// it.
+func (m *Metastore) AlterTable(ctx context.Context, db, name string, t *Table) error {
+	_, err := m.call(ctx, "alter_table", object{
+		1: {thrift.STRING, db},
+		2: {thrift.STRING, name},
+		3: {thrift.STRUCT, t.object()},
+	})
+	return err
+}
+
+// DropTable drops table name of database db, deleting its files if
+// deleteData is set and the metastore manages them.
+func (m *Metastore) DropTable(ctx context.Context, db, name string, deleteData bool) error {
+	_, err := m.call(ctx, "drop_table", object{
+		1: {thrift.STRING, db},
+		2: {thrift.STRING, name},
+		3: {thrift.BOOL, deleteData},
+	}, 1)
+	return err
+}
+
+// Partitions returns the partitions of table name of database db. A
+// filter, in the metastore's syntax such as `dt >= "2024-01-01" and
+// region = "eu"`, returns only the partitions matching it.
+func (m *Metastore) Partitions(ctx context.Context, db, name, filter string) ([]Partition, error) {
+	var v any
+	var err error
+	if filter == "" {
+		v, err = m.call(ctx, "get_partitions", object{
+			1: {thrift.STRING, db},
+			2: {thrift.STRING, name},
+			3: {thrift.I16, int16(-1)},
+		}, 1)
+	} else {
+		v, err = m.call(ctx, "get_partitions_by_filter", object{
+			1: {thrift.STRING, db},
+			2: {thrift.STRING, name},
+			3: {thrift.STRING, filter},
+			4: {thrift.I16, int16(-1)},
+		}, 2)
+	}
+	if err != nil {
+		return nil, err
+	}
+	objects := object{0: {thrift.LIST, v}}.objects(0)
+	partitions := make([]Partition, 0, len(objects))
+	for _, o := range objects {
+		partitions = append(partitions, Partition{
+			Values:     o.strings(1),
+			Location:   o.object(6).str(2),
+			Parameters: o.stringMap(7),
+		})
+	}
+	return partitions, nil
+}
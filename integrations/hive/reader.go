@@ -0,0 +1,437 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	filesystem "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/thanos-io/objstore"
+)
+
+// HiveReadOptions configures NewHiveReader.
+type HiveReadOptions struct {
+	// Filter selects the partitions read, in the metastore's filter
+	// syntax, such as `dt >= "2024-01-01"`. Empty reads them all.
+	Filter string
+	// Bucket holds the files of the table, named by the path of their
+	// location: s3://lake/events/dt=1/0.parquet is events/dt=1/0.parquet.
+	// Nil reads local files, for file: locations.
+	Bucket objstore.Bucket
+	// ChunkSize is the number of rows per record. Defaults to 1024.
+	ChunkSize int64
+	// Concurrency is the number of files read at once. Defaults to one,
+	// which keeps the records in file order.
+	Concurrency int
+}
+
+// hiveFile is a data file of a table and the values of the partition it
+// is in.
+type hiveFile struct {
+	name   string
+	values []string
+}
+
+// NewHiveReader reads table name of database db, a Hive table of Parquet
+// files. The partitions, and where their files are, come from the
+// metastore; each record carries the columns of the files followed by the
+// partition keys, with the values of the partition it came from.
+func NewHiveReader(ctx context.Context, metastore *Metastore, db, name string, opts *HiveReadOptions) (*filesystem.MultiFileReader, error) {
+	if opts == nil {
+		opts = &HiveReadOptions{}
+	}
+	t, err := metastore.Table(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(strings.ToLower(t.InputFormat+t.SerDe), "parquet") {
+		return nil, fmt.Errorf("%s.%s is not stored as Parquet", db, name)
+	}
+	keys := make([]arrow.Field, len(t.PartitionKeys))
+	for i, key := range t.PartitionKeys {
+		keys[i] = arrow.Field{Name: key.Name, Type: hiveArrowType(key.Type), Nullable: true}
+	}
+
+	var store hiveStore = localStore{}
+	if opts.Bucket != nil {
+		store = bucketStore{opts.Bucket}
+	}
+
+	var files []hiveFile
+	if len(keys) == 0 {
+		if opts.Filter != "" {
+			return nil, fmt.Errorf("%s.%s is not partitioned", db, name)
+		}
+		if files, err = listHiveFiles(ctx, store, t.Location, nil); err != nil {
+			return nil, err
+		}
+	} else {
+		partitions, err := metastore.Partitions(ctx, db, name, opts.Filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range partitions {
+			if len(p.Values) != len(keys) {
+				return nil, fmt.Errorf("partition %v of %s.%s does not have %d values", p.Values, db, name, len(keys))
+			}
+			found, err := listHiveFiles(ctx, store, p.Location, p.Values)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, found...)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no data files in %s.%s", db, name)
+	}
+
+	byName := make(map[string][]string, len(files))
+	names := make([]string, len(files))
+	for i, f := range files {
+		byName[f.name] = f.values
+		names[i] = f.name
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 1024
+	}
+	open := func(ctx context.Context, name string) (filesystem.FileReader, error) {
+		return openHiveFile(ctx, store, name, keys, byName[name], chunkSize)
+	}
+	return filesystem.NewMultiFileReader(ctx, names, opts.Concurrency, open)
+}
+
+// hiveStore lists and opens the files of a table.
+type hiveStore interface {
+	// list returns the files under location, recursively, less the
+	// hidden ones.
+	list(ctx context.Context, location string) ([]string, error)
+	open(ctx context.Context, name string) (parquet.ReaderAtSeeker, error)
+}
+
+// listHiveFiles returns the data files under location, sorted.
+func listHiveFiles(ctx context.Context, store hiveStore, location string, values []string) ([]hiveFile, error) {
+	names, err := store.list(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", location, err)
+	}
+	var files []hiveFile
+	for _, name := range names {
+		files = append(files, hiveFile{name: name, values: values})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files, nil
+}
+
+// hidden reports whether a file or directory is hidden from readers of a
+// table, its name starting with a dot or an underscore.
+func hidden(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// bucketStore is a hiveStore over a bucket, in which a location is named
+// by its path.
+type bucketStore struct {
+	bucket objstore.Bucket
+}
+
+// objectName returns the name in the bucket of location.
+func objectName(location string) string {
+	if scheme, rest, ok := strings.Cut(location, "://"); ok && !strings.Contains(scheme, "/") {
+		_, p, _ := strings.Cut(rest, "/")
+		return p
+	}
+	return strings.TrimLeft(strings.TrimPrefix(location, "file:"), "/")
+}
+
+func (s bucketStore) list(ctx context.Context, location string) ([]string, error) {
+	dir := strings.TrimSuffix(objectName(location), "/") + "/"
+	var names []string
+	err := s.bucket.Iter(ctx, dir, func(name string) error {
+		if strings.HasSuffix(name, "/") {
+			return nil
+		}
+		for _, elem := range strings.Split(strings.TrimPrefix(name, dir), "/") {
+			if hidden(elem) {
+				return nil
+			}
+		}
+		names = append(names, name)
+		return nil
+	}, objstore.WithRecursiveIter)
+	return names, err
+}
+
+func (s bucketStore) open(ctx context.Context, name string) (parquet.ReaderAtSeeker, error) {
+	attrs, err := s.bucket.Attributes(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(&objectReaderAt{ctx: ctx, bucket: s.bucket, name: name}, 0, attrs.Size), nil
+}
+
+// localStore is a hiveStore of local files, at file: locations or paths.
+type localStore struct{}
+
+func (localStore) list(_ context.Context, location string) ([]string, error) {
+	dir := location
+	if rest, ok := strings.CutPrefix(location, "file:"); ok {
+		dir = "/" + strings.TrimLeft(rest, "/")
+	}
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && hidden(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			names = append(names, path)
+		}
+		return nil
+	})
+	return names, err
+}
+
+func (localStore) open(_ context.Context, name string) (parquet.ReaderAtSeeker, error) {
+	return os.Open(name)
+}
+
+// hiveArrowType returns the Arrow type of partition values of a Hive type.
+// Types without a counterpart are read as strings.
+func hiveArrowType(hiveType string) arrow.DataType {
+	typ, _, _ := strings.Cut(strings.ToLower(hiveType), "(")
+	switch typ {
+	case "tinyint":
+		return arrow.PrimitiveTypes.Int8
+	case "smallint":
+		return arrow.PrimitiveTypes.Int16
+	case "int", "integer":
+		return arrow.PrimitiveTypes.Int32
+	case "bigint":
+		return arrow.PrimitiveTypes.Int64
+	case "float":
+		return arrow.PrimitiveTypes.Float32
+	case "double":
+		return arrow.PrimitiveTypes.Float64
+	case "boolean":
+		return arrow.FixedWidthTypes.Boolean
+	case "date":
+		return arrow.FixedWidthTypes.Date32
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+// partitionArray returns an array of n copies of the partition value s,
+// of type dt, null for filesystem.HivePartitionNull.
+func partitionArray(mem memory.Allocator, dt arrow.DataType, s string, n int) (arrow.Array, error) {
+	b := array.NewBuilder(mem, dt)
+	defer b.Release()
+	if s == filesystem.HivePartitionNull {
+		b.AppendNulls(n)
+		return b.NewArray(), nil
+	}
+	var err error
+	switch b := b.(type) {
+	case *array.StringBuilder:
+		for i := 0; i < n; i++ {
+			b.Append(s)
+		}
+	case *array.BooleanBuilder:
+		var v bool
+		v, err = strconv.ParseBool(s)
+		for i := 0; i < n && err == nil; i++ {
+			b.Append(v)
+		}
+	case *array.Date32Builder:
+		var d time.Time
+		d, err = time.Parse(time.DateOnly, s)
+		for i := 0; i < n && err == nil; i++ {
+			b.Append(arrow.Date32FromTime(d))
+		}
+	case *array.Float32Builder:
+		var v float64
+		v, err = strconv.ParseFloat(s, 32)
+		for i := 0; i < n && err == nil; i++ {
+			b.Append(float32(v))
+		}
+	case *array.Float64Builder:
+		var v float64
+		v, err = strconv.ParseFloat(s, 64)
+		for i := 0; i < n && err == nil; i++ {
+			b.Append(v)
+		}
+	case *array.Int8Builder:
+		var v int64
+		v, err = strconv.ParseInt(s, 10, 8)
+		for i := 0; i < n && err == nil; i++ {
+			b.Append(int8(v))
+		}
+	case *array.Int16Builder:
+		var v int64
+		v, err = strconv.ParseInt(s, 10, 16)
+		for i := 0; i < n && err == nil; i++ {
+			b.Append(int16(v))
+		}
+	case *array.Int32Builder:
+		var v int64
+		v, err = strconv.ParseInt(s, 10, 32)
+		for i := 0; i < n && err == nil; i++ {
+			b.Append(int32(v))
+		}
+	case *array.Int64Builder:
+		var v int64
+		v, err = strconv.ParseInt(s, 10, 64)
+		for i := 0; i < n && err == nil; i++ {
+			b.Append(v)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s partition value %q: %w", dt, s, err)
+	}
+	return b.NewArray(), nil
+}
+
+// hiveFileReader reads a Parquet file of a Hive table, appending the
+// values of its partition to each record.
+type hiveFileReader struct {
+	file    *file.Reader
+	records pqarrow.RecordReader
+	schema  *arrow.Schema
+	keys    []arrow.Field
+	values  []string
+}
+
+func openHiveFile(ctx context.Context, store hiveStore, name string, keys []arrow.Field, values []string, chunkSize int64) (*hiveFileReader, error) {
+	f, err := store.open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := file.NewParquetReader(f)
+	if err != nil {
+		if c, ok := f.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, fmt.Errorf("failed to open Parquet file: %w", err)
+	}
+	fileReader, err := pqarrow.NewFileReader(rdr, pqarrow.ArrowReadProperties{BatchSize: chunkSize}, memory.DefaultAllocator)
+	if err != nil {
+		rdr.Close()
+		return nil, err
+	}
+	records, err := fileReader.GetRecordReader(ctx, nil, nil)
+	if err != nil {
+		rdr.Close()
+		return nil, err
+	}
+	fields := append(records.Schema().Fields(), keys...)
+	md := records.Schema().Metadata()
+	return &hiveFileReader{
+		file:    rdr,
+		records: records,
+		schema:  arrow.NewSchema(fields, &md),
+		keys:    keys,
+		values:  values,
+	}, nil
+}
+
+func (r *hiveFileReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+func (r *hiveFileReader) Read() (arrow.Record, error) {
+	if !r.records.Next() {
+		if err := r.records.Err(); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	record := r.records.Record()
+	if len(r.keys) == 0 {
+		record.Retain()
+		return record, nil
+	}
+	cols := append([]arrow.Array{}, record.Columns()...)
+	for i, key := range r.keys {
+		col, err := partitionArray(memory.DefaultAllocator, key.Type, r.values[i], int(record.NumRows()))
+		if err != nil {
+			return nil, err
+		}
+		defer col.Release()
+		cols = append(cols, col)
+	}
+	return array.NewRecord(r.schema, cols, record.NumRows()), nil
+}
+
+func (r *hiveFileReader) Close() error {
+	r.records.Release()
+	return r.file.Close()
+}
+
+// objectReaderAt reads an object of a bucket at any offset.
+type objectReaderAt struct {
+	ctx    context.Context
+	bucket objstore.Bucket
+	name   string
+}
+
+func (o *objectReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	rc, err := o.bucket.GetRange(o.ctx, o.name, off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	n, err := io.ReadFull(rc, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,334 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// The metastore's structs are decoded generically, field by field, rather
+// than from generated code. Fields this package does not know about are
+// kept, so that a table read, changed and written back loses nothing.
+
+// object is a Thrift struct: its fields by id.
+type object map[int16]value
+
+// value is a Thrift value and its type. Lists and sets hold a *list, maps a
+// *dict, structs an object, and the other types their Go value, with
+// binary fields as strings.
+type value struct {
+	typ thrift.TType
+	v   any
+}
+
+type list struct {
+	elem  thrift.TType
+	items []any
+}
+
+type dict struct {
+	key, val   thrift.TType
+	keys, vals []any
+}
+
+func (o object) str(id int16) string {
+	s, _ := o[id].v.(string)
+	return s
+}
+
+func (o object) i32(id int16) int32 {
+	n, _ := o[id].v.(int32)
+	return n
+}
+
+func (o object) object(id int16) object {
+	s, _ := o[id].v.(object)
+	return s
+}
+
+func (o object) objects(id int16) []object {
+	l, _ := o[id].v.(*list)
+	if l == nil {
+		return nil
+	}
+	out := make([]object, 0, len(l.items))
+	for _, item := range l.items {
+		if s, ok := item.(object); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (o object) strings(id int16) []string {
+	l, _ := o[id].v.(*list)
+	if l == nil {
+		return nil
+	}
+	out := make([]string, 0, len(l.items))
+	for _, item := range l.items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (o object) stringMap(id int16) map[string]string {
+	d, _ := o[id].v.(*dict)
+	if d == nil {
+		return nil
+	}
+	out := make(map[string]string, len(d.keys))
+	for i, k := range d.keys {
+		ks, _ := k.(string)
+		vs, _ := d.vals[i].(string)
+		out[ks] = vs
+	}
+	return out
+}
+
+func (o object) setStr(id int16, s string) {
+	o[id] = value{thrift.STRING, s}
+}
+
+func (o object) setStringMap(id int16, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	d := &dict{key: thrift.STRING, val: thrift.STRING}
+	for _, k := range keys {
+		d.keys = append(d.keys, k)
+		d.vals = append(d.vals, m[k])
+	}
+	o[id] = value{thrift.MAP, d}
+}
+
+func stringList(items []string) value {
+	l := &list{elem: thrift.STRING}
+	for _, s := range items {
+		l.items = append(l.items, s)
+	}
+	return value{thrift.LIST, l}
+}
+
+func objectList(items []object) value {
+	l := &list{elem: thrift.STRUCT}
+	for _, s := range items {
+		l.items = append(l.items, s)
+	}
+	return value{thrift.LIST, l}
+}
+
+// maxDepth bounds the nesting of the values read.
+const maxDepth = 64
+
+func readObject(ctx context.Context, p thrift.TProtocol, depth int) (object, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("thrift struct nested too deeply")
+	}
+	if _, err := p.ReadStructBegin(ctx); err != nil {
+		return nil, err
+	}
+	o := object{}
+	for {
+		_, typ, id, err := p.ReadFieldBegin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if typ == thrift.STOP {
+			break
+		}
+		v, err := readValue(ctx, p, typ, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		o[id] = value{typ, v}
+		if err := p.ReadFieldEnd(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return o, p.ReadStructEnd(ctx)
+}
+
+func readValue(ctx context.Context, p thrift.TProtocol, typ thrift.TType, depth int) (any, error) {
+	switch typ {
+	case thrift.BOOL:
+		return p.ReadBool(ctx)
+	case thrift.BYTE:
+		return p.ReadByte(ctx)
+	case thrift.I16:
+		return p.ReadI16(ctx)
+	case thrift.I32:
+		return p.ReadI32(ctx)
+	case thrift.I64:
+		return p.ReadI64(ctx)
+	case thrift.DOUBLE:
+		return p.ReadDouble(ctx)
+	case thrift.STRING:
+		return p.ReadString(ctx)
+	case thrift.STRUCT:
+		return readObject(ctx, p, depth)
+	case thrift.LIST, thrift.SET:
+		var elem thrift.TType
+		var size int
+		var err error
+		if typ == thrift.LIST {
+			elem, size, err = p.ReadListBegin(ctx)
+		} else {
+			elem, size, err = p.ReadSetBegin(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		l := &list{elem: elem, items: make([]any, 0, min(size, 1024))}
+		for i := 0; i < size; i++ {
+			item, err := readValue(ctx, p, elem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			l.items = append(l.items, item)
+		}
+		if typ == thrift.LIST {
+			return l, p.ReadListEnd(ctx)
+		}
+		return l, p.ReadSetEnd(ctx)
+	case thrift.MAP:
+		key, val, size, err := p.ReadMapBegin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		d := &dict{key: key, val: val}
+		for i := 0; i < size; i++ {
+			k, err := readValue(ctx, p, key, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			v, err := readValue(ctx, p, val, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			d.keys, d.vals = append(d.keys, k), append(d.vals, v)
+		}
+		return d, p.ReadMapEnd(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported thrift type %v", typ)
+	}
+}
+
+func writeObject(ctx context.Context, p thrift.TProtocol, o object) error {
+	if err := p.WriteStructBegin(ctx, ""); err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(o))
+	for id := range o {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		f := o[int16(id)]
+		if err := p.WriteFieldBegin(ctx, "", f.typ, int16(id)); err != nil {
+			return err
+		}
+		if err := writeValue(ctx, p, f.typ, f.v); err != nil {
+			return err
+		}
+		if err := p.WriteFieldEnd(ctx); err != nil {
+			return err
+		}
+	}
+	if err := p.WriteFieldStop(ctx); err != nil {
+		return err
+	}
+	return p.WriteStructEnd(ctx)
+}
+
+func writeValue(ctx context.Context, p thrift.TProtocol, typ thrift.TType, v any) error {
+	switch typ {
+	case thrift.BOOL:
+		return p.WriteBool(ctx, v.(bool))
+	case thrift.BYTE:
+		return p.WriteByte(ctx, v.(int8))
+	case thrift.I16:
+		return p.WriteI16(ctx, v.(int16))
+	case thrift.I32:
+		return p.WriteI32(ctx, v.(int32))
+	case thrift.I64:
+		return p.WriteI64(ctx, v.(int64))
+	case thrift.DOUBLE:
+		return p.WriteDouble(ctx, v.(float64))
+	case thrift.STRING:
+		return p.WriteString(ctx, v.(string))
+	case thrift.STRUCT:
+		return writeObject(ctx, p, v.(object))
+	case thrift.LIST, thrift.SET:
+		l := v.(*list)
+		var err error
+		if typ == thrift.LIST {
+			err = p.WriteListBegin(ctx, l.elem, len(l.items))
+		} else {
+			err = p.WriteSetBegin(ctx, l.elem, len(l.items))
+		}
+		if err != nil {
+			return err
+		}
+		for _, item := range l.items {
+			if err := writeValue(ctx, p, l.elem, item); err != nil {
+				return err
+			}
+		}
+		if typ == thrift.LIST {
+			return p.WriteListEnd(ctx)
+		}
+		return p.WriteSetEnd(ctx)
+	case thrift.MAP:
+		d := v.(*dict)
+		if err := p.WriteMapBegin(ctx, d.key, d.val, len(d.keys)); err != nil {
+			return err
+		}
+		for i := range d.keys {
+			if err := writeValue(ctx, p, d.key, d.keys[i]); err != nil {
+				return err
+			}
+			if err := writeValue(ctx, p, d.val, d.vals[i]); err != nil {
+				return err
+			}
+		}
+		return p.WriteMapEnd(ctx)
+	default:
+		return fmt.Errorf("unsupported thrift type %v", typ)
+	}
+}
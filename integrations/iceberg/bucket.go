@@ -24,6 +24,7 @@ type FileReaderAt struct {
 // BucketReaderAt implements the Bucket interface.
 type BucketReaderAt struct {
 	objstore.Bucket
+	cache *BlockCache
 }
 
 // NewBucketReaderAt returns a new Bucket.
@@ -31,8 +32,17 @@ func NewBucketReaderAt(bucket objstore.Bucket) *BucketReaderAt {
 	return &BucketReaderAt{Bucket: bucket}
 }
 
+// NewCachedBucketReaderAt returns a new Bucket whose readers go through
+// cache, which may be shared with other buckets.
+func NewCachedBucketReaderAt(bucket objstore.Bucket, cache *BlockCache) *BucketReaderAt {
+	return &BucketReaderAt{Bucket: bucket, cache: cache}
+}
+
 // GetReaderAt returns a io.ReaderAt for the given filename.
 func (b *BucketReaderAt) GetReaderAt(ctx context.Context, name string) (io.ReaderAt, error) {
+	if b.cache != nil {
+		return &cachedReaderAt{ctx: ctx, bucket: b.Bucket, name: name, cache: b.cache}, nil
+	}
 	return &FileReaderAt{
 		Bucket: b.Bucket,
 		name:   name,
@@ -0,0 +1,215 @@
+package integrations
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/thanos-io/objstore"
+)
+
+// Read cache defaults.
+const (
+	DefaultReadCacheBlockSize = 64 * 1024         // 64KiB
+	DefaultReadCacheSize      = 256 * 1024 * 1024 // 256MiB
+)
+
+// BlockCache is an LRU cache of fixed-size blocks of objects, bounded by
+// the total size of the blocks it holds. A cache may be shared by any
+// number of readers, of any number of buckets as long as they name objects
+// differently. Objects are assumed never to change once written, as
+// Iceberg data and metadata files do not.
+type BlockCache struct {
+	blockSize int64
+	maxBytes  int64
+
+	mu     sync.Mutex
+	lru    *list.List
+	blocks map[blockKey]*list.Element
+	size   int64
+	stats  CacheStats
+}
+
+// CacheStats counts the block lookups of a BlockCache.
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	// Requests is the number of range requests made to fill the cache.
+	Requests int64
+	// Bytes is the size of the blocks held.
+	Bytes int64
+}
+
+type blockKey struct {
+	name  string
+	index int64
+}
+
+type cacheBlock struct {
+	key  blockKey
+	data []byte
+}
+
+// NewBlockCache returns a cache of blocks of blockSize bytes holding at
+// most maxBytes. Zero values use the defaults.
+func NewBlockCache(blockSize, maxBytes int64) *BlockCache {
+	if blockSize <= 0 {
+		blockSize = DefaultReadCacheBlockSize
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultReadCacheSize
+	}
+	return &BlockCache{
+		blockSize: blockSize,
+		maxBytes:  maxBytes,
+		lru:       list.New(),
+		blocks:    map[blockKey]*list.Element{},
+	}
+}
+
+// Stats returns the counters of the cache.
+func (c *BlockCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Bytes = c.size
+	return stats
+}
+
+// get returns the cached block, marking it as recently used.
+func (c *BlockCache) get(key blockKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.blocks[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheBlock).data, true
+}
+
+// put adds a block, evicting the least recently used ones over budget.
+func (c *BlockCache) put(key blockKey, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.blocks[key]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.blocks[key] = c.lru.PushFront(&cacheBlock{key: key, data: data})
+	c.size += int64(len(data))
+	for c.size > c.maxBytes && c.lru.Len() > 1 {
+		e := c.lru.Back()
+		b := e.Value.(*cacheBlock)
+		c.lru.Remove(e)
+		delete(c.blocks, b.key)
+		c.size -= int64(len(b.data))
+		c.stats.Evictions++
+	}
+}
+
+// cachedReaderAt reads an object through a BlockCache.
+type cachedReaderAt struct {
+	ctx    context.Context
+	bucket objstore.Bucket
+	name   string
+	cache  *BlockCache
+}
+
+// ReadAt implements the io.ReaderAt interface. The blocks covering p are
+// taken from the cache, and those missing are fetched with a single range
+// request.
+func (r *cachedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	size := r.cache.blockSize
+	first, last := off/size, (off+int64(len(p))-1)/size
+
+	blocks := make([][]byte, last-first+1)
+	missing := int64(-1)
+	for i := first; i <= last; i++ {
+		data, ok := r.cache.get(blockKey{r.name, i})
+		if !ok {
+			if missing < 0 {
+				missing = i
+			}
+			continue
+		}
+		blocks[i-first] = data
+		if int64(len(data)) < size {
+			// The object ends within this block.
+			last = i
+			break
+		}
+	}
+	if missing >= 0 && missing <= last {
+		if err := r.fetch(blocks[missing-first:last-first+1], missing, last); err != nil {
+			return 0, err
+		}
+	}
+
+	n := 0
+	for i, data := range blocks {
+		start := int64(0)
+		if i == 0 {
+			start = off - first*size
+		}
+		if start >= int64(len(data)) {
+			break
+		}
+		n += copy(p[n:], data[start:])
+		if n == len(p) || int64(len(data)) < size {
+			break
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// fetch reads blocks first to last of the object into blocks and the
+// cache, keeping the ones already cached.
+func (r *cachedReaderAt) fetch(blocks [][]byte, first, last int64) error {
+	size := r.cache.blockSize
+	rc, err := r.bucket.GetRange(r.ctx, r.name, first*size, (last-first+1)*size)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	r.cache.mu.Lock()
+	r.cache.stats.Requests++
+	r.cache.mu.Unlock()
+
+	for i := range blocks {
+		data := make([]byte, size)
+		n, err := io.ReadFull(rc, data)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		if n == 0 {
+			// Past the end of the object, which an empty block records.
+			if blocks[i] == nil {
+				r.cache.put(blockKey{r.name, first + int64(i)}, []byte{})
+			}
+			break
+		}
+		if blocks[i] == nil {
+			blocks[i] = data[:n:n]
+			r.cache.put(blockKey{r.name, first + int64(i)}, blocks[i])
+		}
+		if n < len(data) {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/parquet-go/parquet-go"
+	"github.com/polarsignals/iceberg-go"
+	"github.com/polarsignals/iceberg-go/table"
+)
+
+// Compaction defaults.
+const (
+	DefaultCompactionMinFiles       = 10
+	DefaultCompactionMaxFileSize    = 32 * 1024 * 1024  // 32MiB
+	DefaultCompactionTargetFileSize = 128 * 1024 * 1024 // 128MiB
+)
+
+// compactionFile is a data file chosen for compaction, opened for reading.
+type compactionFile struct {
+	data iceberg.DataFile
+	file *parquet.File
+}
+
+// WithCompaction compacts tables during maintenance. Once a partition holds
+// at least minFiles data files smaller than maxFileSize, they are rewritten
+// into files of about the target size, set by WithCompactionTargetSize.
+// Zero values use the defaults.
+func WithCompaction(minFiles int, maxFileSize int64) IcebergOption {
+	return func(i *Iceberg) {
+		if minFiles <= 0 {
+			minFiles = DefaultCompactionMinFiles
+		}
+		if maxFileSize <= 0 {
+			maxFileSize = DefaultCompactionMaxFileSize
+		}
+		i.compactionMinFiles = max(minFiles, 2)
+		i.compactionMaxFileSize = maxFileSize
+	}
+}
+
+// WithCompactionTargetSize sets the size of the data files compaction
+// writes, DefaultCompactionTargetFileSize by default.
+func WithCompactionTargetSize(size int64) IcebergOption {
+	return func(i *Iceberg) {
+		i.compactionTargetSize = size
+	}
+}
+
+// Compact rewrites the small data files of each partition of t into files
+// of the target size, as a single new snapshot. It does nothing unless
+// compaction is enabled by WithCompaction, or no partition has enough small
+// files.
+//
+// Data files are written unpartitioned, so a file belongs to a partition
+// when each partition source column holds a single value in it, as its
+// bounds tell. Files spanning several partitions are left alone, and files
+// are only merged with files of the same Parquet schema and metadata.
+func (i *Iceberg) Compact(ctx context.Context, t table.Table) error {
+	if i.compactionMinFiles == 0 || t.CurrentSnapshot() == nil {
+		return nil
+	}
+	groups, err := i.compactionGroups(ctx, t)
+	if err != nil {
+		return err
+	}
+
+	targetSize := i.compactionTargetSize
+	if targetSize <= 0 {
+		targetSize = DefaultCompactionTargetFileSize
+	}
+	var bins [][]compactionFile
+	for _, files := range groups {
+		if len(files) >= i.compactionMinFiles {
+			bins = append(bins, packFiles(files, targetSize)...)
+		}
+	}
+	if len(bins) == 0 {
+		return nil
+	}
+
+	w, err := t.SnapshotWriter(defaultWriterOptions...)
+	if err != nil {
+		return err
+	}
+	replaced := map[string]struct{}{}
+	for _, bin := range bins {
+		buf, err := rewriteDataFiles(bin)
+		if err != nil {
+			return err
+		}
+		if err := w.Append(ctx, buf); err != nil {
+			return err
+		}
+		for _, f := range bin {
+			replaced[f.data.FilePath()] = struct{}{}
+		}
+	}
+	if err := w.DeleteDataFile(ctx, func(d iceberg.DataFile) bool {
+		_, ok := replaced[d.FilePath()]
+		return ok
+	}); err != nil {
+		return err
+	}
+	if err := w.Close(ctx); err != nil {
+		return err
+	}
+	level.Info(i.logger).Log("msg", "compacted iceberg table", "table", t.Location(), "files", len(replaced), "written", len(bins))
+	return nil
+}
+
+// compactionGroups returns the data files of the current snapshot of t
+// smaller than the maximum compaction size, grouped by partition and
+// schema, each group sorted by path.
+func (i *Iceberg) compactionGroups(ctx context.Context, t table.Table) (map[string][]compactionFile, error) {
+	manifests, err := t.CurrentSnapshot().Manifests(i.bucket)
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest list: %w", err)
+	}
+
+	bkt := i.bucketReaderAt()
+	groups := map[string][]compactionFile{}
+	for _, manifest := range manifests {
+		entries, _, err := manifest.FetchEntries(i.bucket, true)
+		if err != nil {
+			return nil, fmt.Errorf("fetch entries %s: %w", manifest.FilePath(), err)
+		}
+		for _, e := range entries {
+			d := e.DataFile()
+			if d.ContentType() != iceberg.EntryContentData || d.FileFormat() != iceberg.ParquetFile || d.FileSizeBytes() >= i.compactionMaxFileSize {
+				continue
+			}
+			partition, ok := partitionKey(t, d)
+			if !ok {
+				continue
+			}
+
+			r, err := bkt.GetReaderAt(ctx, d.FilePath())
+			if err != nil {
+				return nil, err
+			}
+			file, err := parquet.OpenFile(r, d.FileSizeBytes(), parquet.SkipPageIndex(true), parquet.SkipBloomFilters(true))
+			if err != nil {
+				return nil, fmt.Errorf("failed to open file %s: %w", d.FilePath(), err)
+			}
+			key := partition + "\x00" + file.Schema().String() + "\x00" + fmt.Sprint(file.Metadata().KeyValueMetadata)
+			groups[key] = append(groups[key], compactionFile{data: d, file: file})
+		}
+	}
+
+	for _, files := range groups {
+		sort.Slice(files, func(a, b int) bool {
+			return files[a].data.FilePath() < files[b].data.FilePath()
+		})
+	}
+	return groups, nil
+}
+
+// partitionKey returns the partition of d within t, or false if d spans
+// several partitions.
+func partitionKey(t table.Table, d iceberg.DataFile) (string, bool) {
+	if p := d.Partition(); len(p) > 0 {
+		names := make([]string, 0, len(p))
+		for name := range p {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var key strings.Builder
+		for _, name := range names {
+			fmt.Fprintf(&key, "%s=%v/", name, p[name])
+		}
+		return key.String(), true
+	}
+
+	spec := t.Metadata().PartitionSpec()
+	if spec.IsUnpartitioned() {
+		return "", true
+	}
+	fields := t.Schema().Fields()
+	var key strings.Builder
+	for n := 0; n < spec.NumFields(); n++ {
+		field := spec.Field(n)
+		col := -1
+		for c, f := range fields {
+			if f.ID == field.SourceID {
+				col = c
+				break
+			}
+		}
+		if col < 0 {
+			return "", false
+		}
+		lower, upper := d.LowerBoundValues()[col], d.UpperBoundValues()[col]
+		if lower == nil || !bytes.Equal(lower, upper) {
+			return "", false
+		}
+		fmt.Fprintf(&key, "%s=%s/", field.Name, hex.EncodeToString(lower))
+	}
+	return key.String(), true
+}
+
+// packFiles splits files into runs whose total size stays within
+// targetSize, keeping only the runs of several files.
+func packFiles(files []compactionFile, targetSize int64) [][]compactionFile {
+	var bins [][]compactionFile
+	var bin []compactionFile
+	var size int64
+	flush := func() {
+		if len(bin) > 1 {
+			bins = append(bins, bin)
+		}
+		bin, size = nil, 0
+	}
+	for _, f := range files {
+		if len(bin) > 0 && size+f.data.FileSizeBytes() > targetSize {
+			flush()
+		}
+		bin = append(bin, f)
+		size += f.data.FileSizeBytes()
+	}
+	flush()
+	return bins
+}
+
+// rewriteDataFiles copies the rows of files, which share a schema, into a
+// single Parquet file. Each row group is copied whole so that sorted row
+// groups stay sorted.
+func rewriteDataFiles(files []compactionFile) (*bytes.Buffer, error) {
+	first := files[0].file
+	options := []parquet.WriterOption{first.Schema(), parquet.Compression(&parquet.Zstd)}
+	for _, kv := range first.Metadata().KeyValueMetadata {
+		options = append(options, parquet.KeyValueMetadata(kv.Key, kv.Value))
+	}
+
+	buf := &bytes.Buffer{}
+	w := parquet.NewGenericWriter[any](buf, options...)
+	for _, f := range files {
+		for _, rg := range f.file.RowGroups() {
+			rows := rg.Rows()
+			_, err := parquet.CopyRows(w, rows)
+			rows.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to copy rows of %s: %w", f.data.FilePath(), err)
+			}
+			if err := w.Flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
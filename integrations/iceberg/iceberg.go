@@ -50,6 +50,12 @@ type Iceberg struct {
 	maintenanceCtx      context.Context
 	maintenanceDone     context.CancelFunc
 	maintenanceWg       sync.WaitGroup
+
+	compactionMinFiles    int
+	compactionMaxFileSize int64
+	compactionTargetSize  int64
+
+	readCache *BlockCache
 }
 
 // IcebergOption configures an Iceberg DataSink/DataSource.
@@ -107,6 +113,15 @@ func (i *Iceberg) Close() error {
 	return nil
 }
 
+// Check reports whether the catalog is reachable, by listing the
+// namespaces of the bucket. It is a readiness check of pkg/health.
+func (i *Iceberg) Check(ctx context.Context) error {
+	if _, err := i.catalog.ListNamespaces(ctx, []string{i.bucketURI}); err != nil {
+		return fmt.Errorf("iceberg catalog: %w", err)
+	}
+	return nil
+}
+
 // Maintenance performs maintenance tasks on Iceberg tables.
 func (i *Iceberg) Maintenance(ctx context.Context) error {
 	dbs, err := i.catalog.ListNamespaces(ctx, []string{i.bucketURI})
@@ -132,7 +147,8 @@ func (i *Iceberg) Maintenance(ctx context.Context) error {
 
 // maintainTable performs maintenance tasks for a specific Iceberg table.
 func (i *Iceberg) maintainTable(ctx context.Context, db, tbl []string) error {
-	tablePath := filepath.Join(i.bucketURI, db[0], tbl[0])
+	// The Hive catalog names tables {database, table}, the Hadoop catalog by name.
+	tablePath := filepath.Join(i.bucketURI, db[len(db)-1], tbl[len(tbl)-1])
 	t, err := i.catalog.LoadTable(ctx, []string{tablePath}, iceberg.Properties{})
 	if err != nil {
 		return err
@@ -150,6 +166,18 @@ func (i *Iceberg) maintainTable(ctx context.Context, db, tbl []string) error {
 		}
 	}
 
+	if i.compactionMinFiles > 0 {
+		if err := i.Compact(ctx, t); err != nil {
+			return err
+		}
+
+		// Reload the compacted table
+		t, err = i.catalog.LoadTable(ctx, []string{tablePath}, iceberg.Properties{})
+		if err != nil {
+			return err
+		}
+	}
+
 	// Delete orphaned files
 	return table.DeleteOrphanFiles(ctx, t, i.orphanedFileAge)
 }
@@ -191,6 +219,14 @@ func WithMaintenanceSchedule(schedule time.Duration) IcebergOption {
 	}
 }
 
+// WithReadCache reads data files through cache, which may be shared with
+// other Iceberg instances.
+func WithReadCache(cache *BlockCache) IcebergOption {
+	return func(i *Iceberg) {
+		i.readCache = cache
+	}
+}
+
 func WithLogger(l log.Logger) IcebergOption {
 	return func(i *Iceberg) {
 		i.logger = l
@@ -258,8 +294,7 @@ func (i *Iceberg) Scan(ctx context.Context, prefix string, _ *dynparquet.Schema,
 
 // processDataFile reads and processes a data file.
 func (i *Iceberg) processDataFile(ctx context.Context, e iceberg.ManifestEntry, fltr expr.TrueNegativeFilter, callback func(context.Context, any) error) error {
-	bkt := NewBucketReaderAt(i.bucket)
-	r, err := bkt.GetReaderAt(ctx, e.DataFile().FilePath())
+	r, err := i.bucketReaderAt().GetReaderAt(ctx, e.DataFile().FilePath())
 	if err != nil {
 		return err
 	}
@@ -293,6 +328,15 @@ func (i *Iceberg) processDataFile(ctx context.Context, e iceberg.ManifestEntry,
 	return nil
 }
 
+// bucketReaderAt returns a Bucket reading data files, through the read
+// cache if one is set.
+func (i *Iceberg) bucketReaderAt() *BucketReaderAt {
+	if i.readCache != nil {
+		return NewCachedBucketReaderAt(i.bucket, i.readCache)
+	}
+	return NewBucketReaderAt(i.bucket)
+}
+
 // Upload uploads a Parquet file into the Iceberg table.
 func (i *Iceberg) Upload(ctx context.Context, name string, r io.Reader) error {
 	tablePath := filepath.Join(i.bucketURI, filepath.Dir(filepath.Dir(name)))
@@ -0,0 +1,58 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+
+	"github.com/arrowarc/arrowarc/pkg/awsauth"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+)
+
+// NewKinesisClient creates a Kinesis Data Streams client with the
+// credentials and region auth selects, or the default ones if it is nil.
+func NewKinesisClient(ctx context.Context, auth *awsauth.Options) (*kinesis.Client, error) {
+	cfg, err := auth.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return kinesis.NewFromConfig(cfg), nil
+}
+
+// NewFirehoseClient creates a Firehose client with the credentials and
+// region auth selects, or the default ones if it is nil.
+func NewFirehoseClient(ctx context.Context, auth *awsauth.Options) (*firehose.Client, error) {
+	cfg, err := auth.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return firehose.NewFromConfig(cfg), nil
+}
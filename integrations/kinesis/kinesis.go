@@ -0,0 +1,452 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	firehosetypes "github.com/aws/aws-sdk-go-v2/service/firehose/types"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	kinesistypes "github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/aws/smithy-go"
+)
+
+// KinesisFormat is the encoding of the records put to a stream.
+type KinesisFormat int
+
+const (
+	// KinesisJSON puts one JSON object per row, newline terminated so that
+	// Firehose deliveries to S3 are NDJSON, or Parquet when the delivery
+	// stream converts the record format.
+	KinesisJSON KinesisFormat = iota
+	// KinesisParquet puts each Arrow record as a self-contained Parquet
+	// file, split by rows to respect the record size limit. Meant for Data
+	// Streams consumers: Firehose concatenates records, which does not
+	// produce readable Parquet objects.
+	KinesisParquet
+)
+
+// Service limits of PutRecords and PutRecordBatch.
+const (
+	kinesisMaxBatchRecords  = 500
+	kinesisMaxBatchBytes    = 5 << 20
+	kinesisMaxRecordBytes   = 1 << 20
+	firehoseMaxBatchBytes   = 4 << 20
+	firehoseMaxRecordBytes  = 1000 << 10
+	defaultKinesisRetries   = 5
+	defaultKinesisBackoff   = 100 * time.Millisecond
+	maxKinesisRetryInterval = 5 * time.Second
+)
+
+// KinesisAPI is the part of the Kinesis Data Streams client the writer uses.
+type KinesisAPI interface {
+	PutRecords(ctx context.Context, params *kinesis.PutRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error)
+	ListShards(ctx context.Context, params *kinesis.ListShardsInput, optFns ...func(*kinesis.Options)) (*kinesis.ListShardsOutput, error)
+}
+
+// FirehoseAPI is the part of the Firehose client the writer uses.
+type FirehoseAPI interface {
+	PutRecordBatch(ctx context.Context, params *firehose.PutRecordBatchInput, optFns ...func(*firehose.Options)) (*firehose.PutRecordBatchOutput, error)
+}
+
+// KinesisWriteOptions configures a KinesisWriter.
+type KinesisWriteOptions struct {
+	Format KinesisFormat
+	// PartitionKeyColumn names the column whose value is the partition key
+	// of a row; in Parquet format, the first row's value keys the record.
+	// When empty, records are spread evenly over the open shards by
+	// explicit hash key. Ignored by Firehose.
+	PartitionKeyColumn string
+	// MaxBatchRecords and MaxBatchBytes bound each request; they default to
+	// the service limits.
+	MaxBatchRecords int
+	MaxBatchBytes   int
+	// MaxRetries is the number of times records failing with a throttling or
+	// internal error are resent, waiting RetryBackoff, doubled each time.
+	MaxRetries   int
+	RetryBackoff time.Duration
+}
+
+func NewDefaultKinesisWriteOptions() *KinesisWriteOptions {
+	return &KinesisWriteOptions{
+		Format:       KinesisJSON,
+		MaxRetries:   defaultKinesisRetries,
+		RetryBackoff: defaultKinesisBackoff,
+	}
+}
+
+// kinesisEntry is a record waiting to be put.
+type kinesisEntry struct {
+	data         []byte
+	partitionKey string
+	hashKey      string
+}
+
+// putFunc puts a batch and returns, for each entry, the error code it
+// failed with or "" on success.
+type putFunc func(ctx context.Context, entries []kinesisEntry) ([]string, error)
+
+// KinesisWriter puts rows to a Kinesis data stream or a Firehose delivery
+// stream. Entries are buffered into requests of up to MaxBatchRecords and
+// MaxBatchBytes; Close sends what remains.
+type KinesisWriter struct {
+	ctx            context.Context
+	opts           *KinesisWriteOptions
+	put            putFunc
+	maxRecordBytes int
+	firehose       bool
+
+	hashKeys []string
+	next     int64
+	pending  []kinesisEntry
+	size     int
+}
+
+// NewKinesisStreamWriter returns a writer putting records to a Kinesis data
+// stream.
+func NewKinesisStreamWriter(ctx context.Context, client KinesisAPI, streamName string, opts *KinesisWriteOptions) (*KinesisWriter, error) {
+	w, err := newKinesisWriter(ctx, opts, kinesisMaxBatchBytes, kinesisMaxRecordBytes)
+	if err != nil {
+		return nil, err
+	}
+	w.put = func(ctx context.Context, entries []kinesisEntry) ([]string, error) {
+		records := make([]kinesistypes.PutRecordsRequestEntry, len(entries))
+		for i, e := range entries {
+			records[i] = kinesistypes.PutRecordsRequestEntry{Data: e.data, PartitionKey: aws.String(e.partitionKey)}
+			if e.hashKey != "" {
+				records[i].ExplicitHashKey = aws.String(e.hashKey)
+			}
+		}
+		out, err := client.PutRecords(ctx, &kinesis.PutRecordsInput{StreamName: aws.String(streamName), Records: records})
+		if err != nil {
+			return nil, err
+		}
+		codes := make([]string, len(entries))
+		for i, r := range out.Records {
+			if i < len(codes) {
+				codes[i] = aws.ToString(r.ErrorCode)
+			}
+		}
+		return codes, nil
+	}
+
+	if w.opts.PartitionKeyColumn == "" {
+		if w.hashKeys, err = openShardHashKeys(ctx, client, streamName); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// NewFirehoseWriter returns a writer putting records to a Firehose delivery
+// stream.
+func NewFirehoseWriter(ctx context.Context, client FirehoseAPI, deliveryStreamName string, opts *KinesisWriteOptions) (*KinesisWriter, error) {
+	w, err := newKinesisWriter(ctx, opts, firehoseMaxBatchBytes, firehoseMaxRecordBytes)
+	if err != nil {
+		return nil, err
+	}
+	w.firehose = true
+	w.put = func(ctx context.Context, entries []kinesisEntry) ([]string, error) {
+		records := make([]firehosetypes.Record, len(entries))
+		for i, e := range entries {
+			records[i] = firehosetypes.Record{Data: e.data}
+		}
+		out, err := client.PutRecordBatch(ctx, &firehose.PutRecordBatchInput{
+			DeliveryStreamName: aws.String(deliveryStreamName),
+			Records:            records,
+		})
+		if err != nil {
+			return nil, err
+		}
+		codes := make([]string, len(entries))
+		for i, r := range out.RequestResponses {
+			if i < len(codes) {
+				codes[i] = aws.ToString(r.ErrorCode)
+			}
+		}
+		return codes, nil
+	}
+	return w, nil
+}
+
+func newKinesisWriter(ctx context.Context, opts *KinesisWriteOptions, maxBatchBytes, maxRecordBytes int) (*KinesisWriter, error) {
+	if opts == nil {
+		opts = NewDefaultKinesisWriteOptions()
+	}
+	if opts.Format != KinesisJSON && opts.Format != KinesisParquet {
+		return nil, fmt.Errorf("kinesis: unknown format %d", opts.Format)
+	}
+	if opts.MaxBatchRecords <= 0 || opts.MaxBatchRecords > kinesisMaxBatchRecords {
+		opts.MaxBatchRecords = kinesisMaxBatchRecords
+	}
+	if opts.MaxBatchBytes <= 0 || opts.MaxBatchBytes > maxBatchBytes {
+		opts.MaxBatchBytes = maxBatchBytes
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaultKinesisBackoff
+	}
+	return &KinesisWriter{ctx: ctx, opts: opts, maxRecordBytes: min(maxRecordBytes, opts.MaxBatchBytes)}, nil
+}
+
+// openShardHashKeys returns the starting hash key of each open shard.
+func openShardHashKeys(ctx context.Context, client KinesisAPI, streamName string) ([]string, error) {
+	var keys []string
+	input := &kinesis.ListShardsInput{StreamName: aws.String(streamName)}
+	for {
+		out, err := client.ListShards(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("kinesis: failed to list shards of %s: %w", streamName, err)
+		}
+		for _, shard := range out.Shards {
+			if shard.SequenceNumberRange != nil && shard.SequenceNumberRange.EndingSequenceNumber != nil {
+				continue // closed by a reshard
+			}
+			if shard.HashKeyRange != nil {
+				keys = append(keys, aws.ToString(shard.HashKeyRange.StartingHashKey))
+			}
+		}
+		if out.NextToken == nil {
+			break
+		}
+		input = &kinesis.ListShardsInput{NextToken: out.NextToken}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("kinesis: stream %s has no open shards", streamName)
+	}
+	return keys, nil
+}
+
+// Write encodes the record and puts it, in as many requests as needed.
+// Entries that do not fill a request are sent by a later Write or Close.
+func (w *KinesisWriter) Write(record arrow.Record) error {
+	keyColumn := -1
+	if w.opts.PartitionKeyColumn != "" && !w.firehose {
+		indices := record.Schema().FieldIndices(w.opts.PartitionKeyColumn)
+		if len(indices) == 0 {
+			return fmt.Errorf("kinesis: partition key column %q is not in the schema", w.opts.PartitionKeyColumn)
+		}
+		keyColumn = indices[0]
+	}
+
+	var payloads [][]byte
+	var firstRows []int
+	var err error
+	if w.opts.Format == KinesisParquet {
+		payloads, firstRows, err = w.parquetPayloads(record, 0)
+	} else {
+		payloads, err = jsonRows(record)
+		firstRows = make([]int, len(payloads))
+		for i := range firstRows {
+			firstRows[i] = i
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, data := range payloads {
+		entry := kinesisEntry{data: data}
+		if !w.firehose {
+			if keyColumn >= 0 {
+				entry.partitionKey = record.Column(keyColumn).ValueStr(firstRows[i])
+				if record.Column(keyColumn).IsNull(firstRows[i]) || entry.partitionKey == "" {
+					// Partition keys must not be empty.
+					entry.partitionKey = "null"
+				}
+			} else {
+				entry.partitionKey = strconv.FormatInt(w.next, 10)
+				entry.hashKey = w.hashKeys[w.next%int64(len(w.hashKeys))]
+				w.next++
+			}
+		}
+		if size := len(entry.data) + len(entry.partitionKey); size > w.maxRecordBytes {
+			return fmt.Errorf("kinesis: row %d encodes to %d bytes, over the %d byte record limit", firstRows[i], size, w.maxRecordBytes)
+		}
+		if err := w.add(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonRows returns each row of the record as a newline-terminated JSON
+// object.
+func jsonRows(record arrow.Record) ([][]byte, error) {
+	if record.NumRows() == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := array.RecordToJSON(record, &buf); err != nil {
+		return nil, fmt.Errorf("kinesis: failed to encode rows: %w", err)
+	}
+	rows := bytes.SplitAfter(buf.Bytes(), []byte("\n"))
+	if len(rows[len(rows)-1]) == 0 {
+		rows = rows[:len(rows)-1]
+	}
+	return rows, nil
+}
+
+// parquetPayloads encodes the record as Parquet files small enough for one
+// record each, halving it until they fit. It returns the files and the
+// offset of each file's first row.
+func (w *KinesisWriter) parquetPayloads(record arrow.Record, offset int) ([][]byte, []int, error) {
+	if record.NumRows() == 0 {
+		return nil, nil, nil
+	}
+	var buf bytes.Buffer
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	fw, err := pqarrow.NewFileWriter(record.Schema(), &buf, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return nil, nil, fmt.Errorf("kinesis: failed to create Parquet writer: %w", err)
+	}
+	if err := fw.Write(record); err != nil {
+		return nil, nil, fmt.Errorf("kinesis: failed to encode Parquet: %w", err)
+	}
+	if err := fw.Close(); err != nil {
+		return nil, nil, fmt.Errorf("kinesis: failed to encode Parquet: %w", err)
+	}
+
+	// Leave room for the partition key.
+	if buf.Len() <= w.maxRecordBytes-256 || record.NumRows() == 1 {
+		return [][]byte{buf.Bytes()}, []int{offset}, nil
+	}
+	half := record.NumRows() / 2
+	var payloads [][]byte
+	var firstRows []int
+	for _, part := range []struct{ from, to int64 }{{0, half}, {half, record.NumRows()}} {
+		slice := record.NewSlice(part.from, part.to)
+		p, f, err := w.parquetPayloads(slice, offset+int(part.from))
+		slice.Release()
+		if err != nil {
+			return nil, nil, err
+		}
+		payloads = append(payloads, p...)
+		firstRows = append(firstRows, f...)
+	}
+	return payloads, firstRows, nil
+}
+
+// add buffers an entry, first sending the buffer if the entry would
+// overflow the request.
+func (w *KinesisWriter) add(entry kinesisEntry) error {
+	size := len(entry.data) + len(entry.partitionKey)
+	if len(w.pending) > 0 && (len(w.pending) >= w.opts.MaxBatchRecords || w.size+size > w.opts.MaxBatchBytes) {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	w.pending = append(w.pending, entry)
+	w.size += size
+	return nil
+}
+
+// flush sends the buffered entries, resending those that failed with a
+// retryable error after a backoff.
+func (w *KinesisWriter) flush() error {
+	entries := w.pending
+	w.pending, w.size = nil, 0
+	backoff := w.opts.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		codes, err := w.put(w.ctx, entries)
+		var failed []kinesisEntry
+		var lastCode string
+		if err != nil {
+			if !isKinesisRetryable(err) || attempt >= w.opts.MaxRetries {
+				return fmt.Errorf("kinesis: failed to put %d records: %w", len(entries), err)
+			}
+			failed = entries
+		} else {
+			for i, code := range codes {
+				if code == "" {
+					continue
+				}
+				if !isKinesisRetryableCode(code) {
+					return fmt.Errorf("kinesis: record failed with %s", code)
+				}
+				failed = append(failed, entries[i])
+				lastCode = code
+			}
+			if len(failed) == 0 {
+				return nil
+			}
+			if attempt >= w.opts.MaxRetries {
+				return fmt.Errorf("kinesis: %d records still failing with %s after %d retries", len(failed), lastCode, attempt)
+			}
+		}
+
+		logging.FromContext(w.ctx).Warn("retrying kinesis put", "records", len(failed), "attempt", attempt+1, "delay", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		}
+		backoff = min(2*backoff, maxKinesisRetryInterval)
+		entries = failed
+	}
+}
+
+func isKinesisRetryableCode(code string) bool {
+	switch code {
+	case "ProvisionedThroughputExceededException", "ThrottlingException", "LimitExceededException",
+		"KMSThrottlingException", "ServiceUnavailableException", "ServiceUnavailable", "InternalFailure":
+		return true
+	}
+	return false
+}
+
+func isKinesisRetryable(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && isKinesisRetryableCode(apiErr.ErrorCode())
+}
+
+// Close sends the remaining buffered entries.
+func (w *KinesisWriter) Close() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	return w.flush()
+}
@@ -17,11 +17,37 @@ import (
 	"github.com/arrowarc/arrowarc/internal/memory"
 	"github.com/arrowarc/arrowarc/pkg/arrowproto"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 // AppendArrowRecordToBigQuery writes Arrow records to BigQuery using the managed writer.
+// The proto descriptor is derived from the Arrow schema, so no compiled proto is required.
 func AppendArrowRecordToBigQuery(w io.Writer, projectID, datasetID, tableID string, tableSchema *bq.Schema) (*managedwriter.AppendResult, error) {
 	ctx := context.Background()
+
+	arrowRecords, err := generateDefaultArrowMessages(1)
+	if err != nil {
+		return nil, fmt.Errorf("generateDefaultArrowMessages: %w", err)
+	}
+	defer arrowRecords[0].Release()
+
+	return AppendArrowRecordToDefaultStream(ctx, projectID, datasetID, tableID, arrowRecords[0], nil)
+}
+
+// AppendArrowRecordToDefaultStream appends an Arrow record to the default stream of a table.
+// When tableSchema is nil the proto descriptor is derived from the record's Arrow schema,
+// otherwise it is derived from the given BigQuery storage schema.
+func AppendArrowRecordToDefaultStream(ctx context.Context, projectID, datasetID, tableID string, record arrow.Record, tableSchema *storagepb.TableSchema) (*managedwriter.AppendResult, error) {
+	descriptorProto, err := descriptorFor(record.Schema(), tableSchema)
+	if err != nil {
+		return nil, err
+	}
+	messageType, err := arrowproto.NewMessage(descriptorProto)
+	if err != nil {
+		return nil, fmt.Errorf("NewMessage: %w", err)
+	}
+
 	// Instantiate a managedwriter client to handle interactions with the service.
 	client, err := managedwriter.NewClient(ctx, projectID,
 		managedwriter.WithMultiplexing(), // Enables connection sharing.
@@ -32,12 +58,6 @@ func AppendArrowRecordToBigQuery(w io.Writer, projectID, datasetID, tableID stri
 	// Close the client when we exit the function.
 	defer client.Close()
 
-	var m *exampleproto.SampleData
-	descriptorProto, err := adapt.NormalizeDescriptor(m.ProtoReflect().Descriptor())
-	if err != nil {
-		return nil, fmt.Errorf("NormalizeDescriptor: %w", err)
-	}
-
 	// Define the table reference
 	tableReference := managedwriter.TableParentFromParts(projectID, datasetID, tableID)
 
@@ -52,12 +72,7 @@ func AppendArrowRecordToBigQuery(w io.Writer, projectID, datasetID, tableID stri
 	}
 	defer managedStream.Close()
 
-	arrowRecords, err := generateDefaultArrowMessages(1)
-	if err != nil {
-		return nil, fmt.Errorf("generateDefaultArrowMessages: %w", err)
-	}
-
-	protoMessages, err := arrowproto.ConvertArrowRecordToProtoMessages(arrowRecords[0], descriptorProto)
+	protoMessages, err := arrowproto.ConvertArrowRecordToProtoMessages(record, messageType)
 	if err != nil {
 		return nil, fmt.Errorf("ConvertArrowRecordToProtoMessages: %w", err)
 	}
@@ -75,9 +90,37 @@ func AppendArrowRecordToBigQuery(w io.Writer, projectID, datasetID, tableID stri
 	if err != nil {
 		return nil, fmt.Errorf("AppendRows error: %w", err)
 	}
+	// Wait for the append to be acknowledged before the stream is closed.
+	if _, err := result.GetResult(ctx); err != nil {
+		return nil, fmt.Errorf("append returned error: %w", err)
+	}
 	return result, nil
 }
 
+// descriptorFor returns a self-contained descriptor for the given schemas, preferring the table schema.
+func descriptorFor(schema *arrow.Schema, tableSchema *storagepb.TableSchema) (*descriptorpb.DescriptorProto, error) {
+	if tableSchema == nil {
+		descriptorProto, err := arrowproto.DescriptorFromArrowSchema(schema)
+		if err != nil {
+			return nil, fmt.Errorf("DescriptorFromArrowSchema: %w", err)
+		}
+		return descriptorProto, nil
+	}
+	descriptor, err := adapt.StorageSchemaToProto2Descriptor(tableSchema, "root")
+	if err != nil {
+		return nil, fmt.Errorf("StorageSchemaToProto2Descriptor: %w", err)
+	}
+	messageDescriptor, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("adapted descriptor is not a message descriptor")
+	}
+	descriptorProto, err := adapt.NormalizeDescriptor(messageDescriptor)
+	if err != nil {
+		return nil, fmt.Errorf("NormalizeDescriptor: %w", err)
+	}
+	return descriptorProto, nil
+}
+
 func generateDefaultArrowMessages(numMessages int) ([]arrow.Record, error) {
 	// Define the Arrow schema matching your example Protobuf message
 	schema := arrow.NewSchema([]arrow.Field{
@@ -0,0 +1,477 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// mongoKind orders the inferred kinds of a field. Numeric kinds widen to the
+// larger one; any other conflict falls back to a string.
+type mongoKind int
+
+const (
+	mongoNull mongoKind = iota
+	mongoBool
+	mongoInt32
+	mongoInt64
+	mongoDecimal
+	mongoDouble
+	mongoString
+	mongoDateTime
+	mongoTimestamp
+	mongoBinary
+	mongoDocument
+	mongoArray
+)
+
+// decimal128MaxPrecision is the precision of the Decimal128 columns BSON
+// decimals map to.
+const decimal128MaxPrecision = 38
+
+// mongoType is the type inferred for a field from the values seen so far.
+type mongoType struct {
+	kind mongoKind
+	// scale and intDigits bound the decimals seen.
+	scale, intDigits int32
+	// names and fields hold the fields of documents in order of appearance.
+	names  []string
+	fields map[string]*mongoType
+	// elem is the type of array elements.
+	elem *mongoType
+}
+
+func (t *mongoType) observeDocument(doc bson.Raw) error {
+	elems, err := doc.Elements()
+	if err != nil {
+		return fmt.Errorf("mongodb: invalid document: %w", err)
+	}
+	if t.kind == mongoNull {
+		t.kind = mongoDocument
+	}
+	if t.kind != mongoDocument {
+		t.kind = mongoString
+		return nil
+	}
+	if t.fields == nil {
+		t.fields = map[string]*mongoType{}
+	}
+	for _, e := range elems {
+		key := e.Key()
+		child, ok := t.fields[key]
+		if !ok {
+			child = &mongoType{}
+			t.fields[key] = child
+			t.names = append(t.names, key)
+		}
+		if err := child.observe(e.Value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *mongoType) observe(v bson.RawValue) error {
+	switch v.Type {
+	case bson.TypeNull, bson.TypeUndefined:
+		return nil
+	case bson.TypeEmbeddedDocument:
+		if t.kind != mongoNull && t.kind != mongoDocument {
+			t.kind = mongoString
+			return nil
+		}
+		return t.observeDocument(v.Document())
+	case bson.TypeArray:
+		if t.kind != mongoNull && t.kind != mongoArray {
+			t.kind = mongoString
+			return nil
+		}
+		t.kind = mongoArray
+		if t.elem == nil {
+			t.elem = &mongoType{}
+		}
+		values, err := v.Array().Values()
+		if err != nil {
+			return fmt.Errorf("mongodb: invalid array: %w", err)
+		}
+		for _, ev := range values {
+			if err := t.elem.observe(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	case bson.TypeDecimal128:
+		if scale, intDigits, ok := decimalShape(v.Decimal128()); ok {
+			t.scale = max(t.scale, scale)
+			t.intDigits = max(t.intDigits, intDigits)
+		}
+		t.merge(mongoDecimal)
+		return nil
+	}
+	t.merge(mongoKindOf(v.Type))
+	return nil
+}
+
+func mongoKindOf(bt bson.Type) mongoKind {
+	switch bt {
+	case bson.TypeBoolean:
+		return mongoBool
+	case bson.TypeInt32:
+		return mongoInt32
+	case bson.TypeInt64:
+		return mongoInt64
+	case bson.TypeDouble:
+		return mongoDouble
+	case bson.TypeDateTime:
+		return mongoDateTime
+	case bson.TypeTimestamp:
+		return mongoTimestamp
+	case bson.TypeBinary:
+		return mongoBinary
+	}
+	// Object IDs, regular expressions, symbols, code and min/max keys are
+	// read as strings.
+	return mongoString
+}
+
+func isMongoNumeric(k mongoKind) bool {
+	return k >= mongoInt32 && k <= mongoDouble
+}
+
+func (t *mongoType) merge(k mongoKind) {
+	switch {
+	case t.kind == mongoNull || t.kind == k:
+		t.kind = k
+	case isMongoNumeric(t.kind) && isMongoNumeric(k):
+		t.kind = max(t.kind, k)
+		if t.kind == mongoDecimal {
+			// Integers need up to 19 digits before the point.
+			t.intDigits = max(t.intDigits, 19)
+		}
+	case (t.kind == mongoDateTime && k == mongoTimestamp) || (t.kind == mongoTimestamp && k == mongoDateTime):
+		t.kind = mongoDateTime
+	default:
+		t.kind = mongoString
+	}
+}
+
+// decimalShape returns the number of digits after and before the decimal
+// point of d, or false for NaN and infinities.
+func decimalShape(d bson.Decimal128) (scale, intDigits int32, ok bool) {
+	coef, exp, err := d.BigInt()
+	if err != nil {
+		return 0, 0, false
+	}
+	digits := int32(len(new(big.Int).Abs(coef).String()))
+	if exp < 0 {
+		scale = int32(-exp)
+		return scale, max(digits-scale, 0), true
+	}
+	return 0, digits + int32(exp), true
+}
+
+func (t *mongoType) arrowType() arrow.DataType {
+	switch t.kind {
+	case mongoBool:
+		return arrow.FixedWidthTypes.Boolean
+	case mongoInt32:
+		return arrow.PrimitiveTypes.Int32
+	case mongoInt64:
+		return arrow.PrimitiveTypes.Int64
+	case mongoDouble:
+		return arrow.PrimitiveTypes.Float64
+	case mongoDecimal:
+		if t.scale+t.intDigits > decimal128MaxPrecision {
+			return arrow.BinaryTypes.String
+		}
+		return &arrow.Decimal128Type{Precision: decimal128MaxPrecision, Scale: t.scale}
+	case mongoDateTime:
+		return &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}
+	case mongoTimestamp:
+		return &arrow.TimestampType{Unit: arrow.Second, TimeZone: "UTC"}
+	case mongoBinary:
+		return arrow.BinaryTypes.Binary
+	case mongoDocument:
+		if len(t.names) == 0 {
+			return arrow.BinaryTypes.String
+		}
+		return arrow.StructOf(t.arrowFields()...)
+	case mongoArray:
+		return arrow.ListOf(t.elem.arrowType())
+	}
+	// Fields that were always null are read as strings.
+	return arrow.BinaryTypes.String
+}
+
+func (t *mongoType) arrowFields() []arrow.Field {
+	fields := make([]arrow.Field, len(t.names))
+	for i, name := range t.names {
+		fields[i] = arrow.Field{Name: name, Type: t.fields[name].arrowType(), Nullable: true}
+	}
+	return fields
+}
+
+// schema returns the record schema for a root document type along with the
+// path each column is looked up by.
+func (t *mongoType) schema(flatten bool) (*arrow.Schema, [][]string) {
+	var fields []arrow.Field
+	var paths [][]string
+	var walk func(t *mongoType, prefix []string)
+	walk = func(t *mongoType, prefix []string) {
+		for _, name := range t.names {
+			child := t.fields[name]
+			path := append(append([]string(nil), prefix...), name)
+			if flatten && child.kind == mongoDocument && len(child.names) > 0 {
+				walk(child, path)
+				continue
+			}
+			fields = append(fields, arrow.Field{Name: strings.Join(path, "."), Type: child.arrowType(), Nullable: true})
+			paths = append(paths, path)
+		}
+	}
+	walk(t, nil)
+	return arrow.NewSchema(fields, nil), paths
+}
+
+// appendMongoValue appends a BSON value to a builder, converting it to the
+// builder's type. Missing and null values append a null.
+func appendMongoValue(b array.Builder, v bson.RawValue) error {
+	if v.Type == 0 || v.Type == bson.TypeNull || v.Type == bson.TypeUndefined {
+		b.AppendNull()
+		return nil
+	}
+
+	switch b := b.(type) {
+	case *array.BooleanBuilder:
+		if x, ok := v.BooleanOK(); ok {
+			b.Append(x)
+			return nil
+		}
+	case *array.Int32Builder:
+		if x, ok := mongoInt(v); ok && x >= math.MinInt32 && x <= math.MaxInt32 {
+			b.Append(int32(x))
+			return nil
+		}
+	case *array.Int64Builder:
+		if x, ok := mongoInt(v); ok {
+			b.Append(x)
+			return nil
+		}
+	case *array.Float64Builder:
+		if x, ok := mongoFloat(v); ok {
+			b.Append(x)
+			return nil
+		}
+	case *array.StringBuilder:
+		b.Append(mongoStringValue(v))
+		return nil
+	case *array.BinaryBuilder:
+		if _, data, ok := v.BinaryOK(); ok {
+			b.Append(data)
+			return nil
+		}
+		if id, ok := v.ObjectIDOK(); ok {
+			b.Append(id[:])
+			return nil
+		}
+	case *array.TimestampBuilder:
+		if ts, ok := mongoTime(v); ok {
+			unit := b.Type().(*arrow.TimestampType).Unit
+			value, err := arrow.TimestampFromTime(ts, unit)
+			if err != nil {
+				return err
+			}
+			b.Append(value)
+			return nil
+		}
+	case *array.Date32Builder:
+		if ts, ok := mongoTime(v); ok {
+			b.Append(arrow.Date32FromTime(ts))
+			return nil
+		}
+	case *array.Decimal128Builder:
+		dt := b.Type().(*arrow.Decimal128Type)
+		if x, ok := mongoDecimal128(v, dt.Precision, dt.Scale); ok {
+			b.Append(x)
+			return nil
+		}
+	case *array.StructBuilder:
+		if doc, ok := v.DocumentOK(); ok {
+			st := b.Type().(*arrow.StructType)
+			b.Append(true)
+			for i, f := range st.Fields() {
+				if err := appendMongoValue(b.FieldBuilder(i), doc.Lookup(f.Name)); err != nil {
+					return fmt.Errorf("%s: %w", f.Name, err)
+				}
+			}
+			return nil
+		}
+	case *array.ListBuilder:
+		if arr, ok := v.ArrayOK(); ok {
+			values, err := arr.Values()
+			if err != nil {
+				return err
+			}
+			b.Append(true)
+			for _, ev := range values {
+				if err := appendMongoValue(b.ValueBuilder(), ev); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported Arrow type %s", b.Type())
+	}
+	return fmt.Errorf("cannot convert BSON %s to %s", v.Type, b.Type())
+}
+
+func mongoInt(v bson.RawValue) (int64, bool) {
+	switch v.Type {
+	case bson.TypeInt32, bson.TypeInt64:
+		return v.AsInt64OK()
+	case bson.TypeDouble:
+		f := v.Double()
+		if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f), true
+		}
+	}
+	return 0, false
+}
+
+func mongoFloat(v bson.RawValue) (float64, bool) {
+	switch v.Type {
+	case bson.TypeDouble:
+		return v.Double(), true
+	case bson.TypeInt32, bson.TypeInt64:
+		x, ok := v.AsInt64OK()
+		return float64(x), ok
+	case bson.TypeDecimal128:
+		coef, exp, err := v.Decimal128().BigInt()
+		if err != nil {
+			return 0, false
+		}
+		f, _ := new(big.Float).SetInt(coef).Float64()
+		return f * math.Pow10(exp), true
+	}
+	return 0, false
+}
+
+func mongoTime(v bson.RawValue) (time.Time, bool) {
+	switch v.Type {
+	case bson.TypeDateTime:
+		return v.Time().UTC(), true
+	case bson.TypeTimestamp:
+		secs, _ := v.Timestamp()
+		return time.Unix(int64(secs), 0).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// mongoDecimal128 converts a decimal or integer value to an Arrow decimal
+// with the given scale, rounding extra digits half away from zero.
+func mongoDecimal128(v bson.RawValue, precision, scale int32) (decimal128.Num, bool) {
+	var coef *big.Int
+	var exp int
+	switch v.Type {
+	case bson.TypeDecimal128:
+		var err error
+		coef, exp, err = v.Decimal128().BigInt()
+		if err != nil {
+			return decimal128.Num{}, false
+		}
+	case bson.TypeInt32, bson.TypeInt64:
+		x, _ := v.AsInt64OK()
+		coef = big.NewInt(x)
+	case bson.TypeDouble:
+		n, err := decimal128.FromFloat64(v.Double(), precision, scale)
+		return n, err == nil
+	default:
+		return decimal128.Num{}, false
+	}
+
+	shift := exp + int(scale)
+	ten := big.NewInt(10)
+	if shift >= 0 {
+		coef = new(big.Int).Mul(coef, new(big.Int).Exp(ten, big.NewInt(int64(shift)), nil))
+	} else {
+		div := new(big.Int).Exp(ten, big.NewInt(int64(-shift)), nil)
+		q, r := new(big.Int).QuoRem(coef, div, new(big.Int))
+		if new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(div) >= 0 {
+			q.Add(q, big.NewInt(int64(coef.Sign())))
+		}
+		coef = q
+	}
+	if coef.BitLen() > 127 {
+		return decimal128.Num{}, false
+	}
+	n := decimal128.FromBigInt(coef)
+	if !n.FitsInPrecision(precision) {
+		return decimal128.Num{}, false
+	}
+	return n, true
+}
+
+// mongoStringValue renders a value for a string column. Object IDs become
+// their hex form, scalars their plain text and documents, arrays and other
+// values their extended JSON.
+func mongoStringValue(v bson.RawValue) string {
+	switch v.Type {
+	case bson.TypeString:
+		return v.StringValue()
+	case bson.TypeObjectID:
+		return v.ObjectID().Hex()
+	case bson.TypeSymbol:
+		return v.Symbol()
+	case bson.TypeJavaScript:
+		return v.JavaScript()
+	case bson.TypeDecimal128:
+		return v.Decimal128().String()
+	case bson.TypeDateTime:
+		return v.Time().UTC().Format(time.RFC3339Nano)
+	case bson.TypeInt32, bson.TypeInt64:
+		return strconv.FormatInt(v.AsInt64(), 10)
+	case bson.TypeDouble:
+		return strconv.FormatFloat(v.Double(), 'g', -1, 64)
+	case bson.TypeBoolean:
+		return strconv.FormatBool(v.Boolean())
+	}
+	return v.String()
+}
@@ -0,0 +1,261 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultMongoBatchSize  = 1024
+	defaultMongoSampleSize = 1000
+)
+
+// MongoSource holds a connection to a MongoDB deployment.
+type MongoSource struct {
+	client *mongo.Client
+}
+
+// NewMongoSource connects to the deployment at uri, for example
+// mongodb://localhost:27017.
+func NewMongoSource(ctx context.Context, uri string) (*MongoSource, error) {
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
+	}
+	return &MongoSource{client: client}, nil
+}
+
+// Close disconnects from the deployment.
+func (s *MongoSource) Close(ctx context.Context) error {
+	return s.client.Disconnect(ctx)
+}
+
+// MongoReadOptions selects the documents to read and how they map to Arrow.
+type MongoReadOptions struct {
+	Database   string
+	Collection string
+	// Filter, Projection, Sort and Limit configure a find command. Filter
+	// defaults to matching every document.
+	Filter     any
+	Projection any
+	Sort       any
+	Limit      int64
+	// Pipeline, when set, runs an aggregation pipeline instead of find.
+	Pipeline any
+	// Schema fixes the Arrow schema. When nil, it is inferred from the first
+	// SampleSize documents.
+	Schema     *arrow.Schema
+	SampleSize int
+	// BatchSize is the number of documents per record.
+	BatchSize int
+	// Flatten expands embedded documents into top-level columns named by
+	// their dotted path, such as address.city, instead of struct columns.
+	Flatten   bool
+	Allocator memory.Allocator
+}
+
+func NewDefaultMongoReadOptions() *MongoReadOptions {
+	return &MongoReadOptions{
+		SampleSize: defaultMongoSampleSize,
+		BatchSize:  defaultMongoBatchSize,
+		Allocator:  pool.GetAllocator(),
+	}
+}
+
+// MongoReader reads documents from a MongoDB cursor as Arrow records.
+type MongoReader struct {
+	ctx       context.Context
+	cursor    *mongo.Cursor
+	schema    *arrow.Schema
+	paths     [][]string
+	pending   []bson.Raw
+	alloc     memory.Allocator
+	batchSize int
+}
+
+// NewMongoReader runs the find command or aggregation pipeline described by
+// opts and returns a reader over its results.
+func (s *MongoSource) NewMongoReader(ctx context.Context, opts *MongoReadOptions) (*MongoReader, error) {
+	if opts == nil {
+		return nil, errors.New("mongodb: read options are required")
+	}
+	if opts.Database == "" || opts.Collection == "" {
+		return nil, errors.New("mongodb: database and collection are required")
+	}
+	coll := s.client.Database(opts.Database).Collection(opts.Collection)
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultMongoBatchSize
+	}
+
+	var cursor *mongo.Cursor
+	var err error
+	if opts.Pipeline != nil {
+		cursor, err = coll.Aggregate(ctx, opts.Pipeline, options.Aggregate().SetBatchSize(int32(batchSize)))
+	} else {
+		filter := opts.Filter
+		if filter == nil {
+			filter = bson.D{}
+		}
+		find := options.Find().SetBatchSize(int32(batchSize))
+		if opts.Projection != nil {
+			find.SetProjection(opts.Projection)
+		}
+		if opts.Sort != nil {
+			find.SetSort(opts.Sort)
+		}
+		if opts.Limit > 0 {
+			find.SetLimit(opts.Limit)
+		}
+		cursor, err = coll.Find(ctx, filter, find)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s.%s: %w", opts.Database, opts.Collection, err)
+	}
+	return NewMongoCursorReader(ctx, cursor, opts)
+}
+
+// NewMongoCursorReader returns a reader over the documents of an open
+// cursor. Only the schema, sampling, batching and flattening options apply.
+// The reader closes the cursor.
+func NewMongoCursorReader(ctx context.Context, cursor *mongo.Cursor, opts *MongoReadOptions) (*MongoReader, error) {
+	if opts == nil {
+		opts = NewDefaultMongoReadOptions()
+	}
+	alloc := opts.Allocator
+	if alloc == nil {
+		alloc = pool.GetAllocator()
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultMongoBatchSize
+	}
+
+	r := &MongoReader{
+		ctx:       ctx,
+		cursor:    cursor,
+		alloc:     alloc,
+		batchSize: batchSize,
+	}
+
+	if opts.Schema != nil {
+		r.schema = opts.Schema
+		r.paths = make([][]string, opts.Schema.NumFields())
+		for i, f := range opts.Schema.Fields() {
+			if opts.Flatten {
+				r.paths[i] = strings.Split(f.Name, ".")
+			} else {
+				r.paths[i] = []string{f.Name}
+			}
+		}
+		return r, nil
+	}
+
+	sampleSize := opts.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultMongoSampleSize
+	}
+	root := &mongoType{}
+	for len(r.pending) < sampleSize && cursor.Next(ctx) {
+		// The cursor reuses Current, so keep a copy of sampled documents.
+		doc := append(bson.Raw(nil), cursor.Current...)
+		if err := root.observeDocument(doc); err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.pending = append(r.pending, doc)
+	}
+	if err := cursor.Err(); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to read documents: %w", err)
+	}
+	r.schema, r.paths = root.schema(opts.Flatten)
+	return r, nil
+}
+
+// Schema returns the schema of the records being read.
+func (r *MongoReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Read returns the next batch of documents as a record, or io.EOF when the
+// cursor is exhausted.
+func (r *MongoReader) Read() (arrow.Record, error) {
+	b := array.NewRecordBuilder(r.alloc, r.schema)
+	defer b.Release()
+
+	rows := 0
+	for rows < r.batchSize {
+		var doc bson.Raw
+		if len(r.pending) > 0 {
+			doc, r.pending = r.pending[0], r.pending[1:]
+		} else if r.cursor.Next(r.ctx) {
+			doc = r.cursor.Current
+		} else {
+			break
+		}
+		for i, path := range r.paths {
+			if err := appendMongoValue(b.Field(i), doc.Lookup(path...)); err != nil {
+				return nil, fmt.Errorf("mongodb: field %q: %w", r.schema.Field(i).Name, err)
+			}
+		}
+		rows++
+	}
+	if rows == 0 {
+		if err := r.cursor.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read documents: %w", err)
+		}
+		return nil, io.EOF
+	}
+	return b.NewRecord(), nil
+}
+
+// Close closes the cursor and releases resources.
+func (r *MongoReader) Close() error {
+	defer pool.PutAllocator(r.alloc)
+	return r.cursor.Close(r.ctx)
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/apache/arrow-adbc/go/adbc/drivermgr"
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/arrowarc/arrowarc/pkg/merge"
 )
 
 // PostgresSource handles connection to a PostgreSQL database using ADBC.
@@ -193,6 +194,13 @@ func (p *PostgresSink) IngestToPostgres(ctx context.Context, tableName string, s
 	return nil
 }
 
+// MergeToPostgres merges the rows of record into the specified PostgreSQL
+// table by the key columns of opts: rows replace the rows with their key,
+// and rows whose op column is "d" delete them, in one transaction.
+func (p *PostgresSink) MergeToPostgres(ctx context.Context, tableName string, record arrow.Record, opts *merge.Options) error {
+	return merge.ApplyADBC(ctx, p.conn, tableName, record, opts)
+}
+
 // Close closes the ADBC connection associated with PostgresSink.
 func (p *PostgresSink) Close() error {
 	return p.conn.Close()
@@ -0,0 +1,333 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	filesystem "github.com/arrowarc/arrowarc/integrations/filesystem"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+)
+
+// PubSubFormat is the encoding of message payloads.
+type PubSubFormat int
+
+const (
+	// PubSubJSON messages hold one JSON object each.
+	PubSubJSON PubSubFormat = iota
+	// PubSubAvro messages hold one binary-encoded Avro value each, as
+	// published to topics with an Avro schema.
+	PubSubAvro
+)
+
+// PubSubAckMode selects when read messages are acknowledged.
+type PubSubAckMode int
+
+const (
+	// AckOnWrite acknowledges messages once their rows have been handed to
+	// the writer. Suits writers that persist each write, such as BigQuery.
+	AckOnWrite PubSubAckMode = iota
+	// AckOnClose acknowledges messages only once the writer has been closed,
+	// for writers that persist everything on Close, such as Parquet files.
+	AckOnClose
+)
+
+const defaultPubSubBatchSize = 1000
+
+// PubSubReadOptions configures a PubSubReader.
+type PubSubReadOptions struct {
+	Format PubSubFormat
+	// Schema is the schema of JSON messages. Fields missing from a message
+	// are null.
+	Schema *arrow.Schema
+	// AvroSchema is the Avro schema, in JSON, Avro messages were written
+	// with, and AvroReaderSchema an optional schema to read them as.
+	AvroSchema       string
+	AvroReaderSchema string
+	// BatchSize is the number of messages per record.
+	BatchSize int
+	// FlushInterval bounds how long a partial batch waits for more messages.
+	FlushInterval time.Duration
+	// IdleTimeout ends the stream when no message arrives for this long.
+	// Zero reads until MaxMessages or Close.
+	IdleTimeout time.Duration
+	// MaxMessages ends the stream after this many messages. Zero is
+	// unlimited.
+	MaxMessages int64
+	AckMode     PubSubAckMode
+	Allocator   memory.Allocator
+}
+
+func NewDefaultPubSubReadOptions() *PubSubReadOptions {
+	return &PubSubReadOptions{
+		Format:        PubSubJSON,
+		BatchSize:     defaultPubSubBatchSize,
+		FlushInterval: 5 * time.Second,
+		AckMode:       AckOnWrite,
+		Allocator:     pool.GetAllocator(),
+	}
+}
+
+// pubSubBatch holds the messages of a record until they are acknowledged.
+type pubSubBatch struct {
+	seq  int64
+	msgs []*pubsub.Message
+}
+
+// PubSubReader receives messages from a subscription and batches them into
+// records. It implements interfaces.AckingReader: messages are acknowledged
+// once a pipeline has written their rows, and messages still pending when
+// the reader is closed are nacked for redelivery.
+type PubSubReader struct {
+	opts   *PubSubReadOptions
+	schema *arrow.Schema
+	avro   *filesystem.AvroDatumDecoder
+	alloc  memory.Allocator
+
+	msgs     chan *pubsub.Message
+	cancel   context.CancelFunc
+	recvDone chan struct{}
+	recvErr  error
+
+	received int64
+	seq      int64
+	ended    bool
+
+	mu      sync.Mutex
+	pending []pubSubBatch
+}
+
+// NewPubSubReader starts receiving from subscriptionID.
+func NewPubSubReader(ctx context.Context, client *pubsub.Client, subscriptionID string, opts *PubSubReadOptions) (*PubSubReader, error) {
+	if opts == nil {
+		opts = NewDefaultPubSubReadOptions()
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultPubSubBatchSize
+	}
+	alloc := opts.Allocator
+	if alloc == nil {
+		alloc = pool.GetAllocator()
+	}
+
+	r := &PubSubReader{
+		opts:     opts,
+		alloc:    alloc,
+		msgs:     make(chan *pubsub.Message, opts.BatchSize),
+		recvDone: make(chan struct{}),
+	}
+	switch opts.Format {
+	case PubSubJSON:
+		if opts.Schema == nil {
+			return nil, errors.New("pubsub: a schema is required for JSON messages")
+		}
+		r.schema = opts.Schema
+	case PubSubAvro:
+		dec, err := filesystem.NewAvroDatumDecoder(opts.AvroSchema, opts.AvroReaderSchema)
+		if err != nil {
+			return nil, fmt.Errorf("pubsub: %w", err)
+		}
+		r.avro, r.schema = dec, dec.Schema()
+	default:
+		return nil, fmt.Errorf("pubsub: unknown format %d", opts.Format)
+	}
+
+	sub := client.Subscription(subscriptionID)
+	// Messages stay outstanding until written, so allow several batches in
+	// flight.
+	if sub.ReceiveSettings.MaxOutstandingMessages < 4*opts.BatchSize {
+		sub.ReceiveSettings.MaxOutstandingMessages = 4 * opts.BatchSize
+	}
+
+	recvCtx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+	go func() {
+		defer close(r.recvDone)
+		r.recvErr = sub.Receive(recvCtx, func(ctx context.Context, m *pubsub.Message) {
+			select {
+			case r.msgs <- m:
+			case <-ctx.Done():
+				m.Nack()
+			}
+		})
+	}()
+	return r, nil
+}
+
+// Schema returns the schema of the records being read.
+func (r *PubSubReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Read returns the next batch of messages as a record. It returns a partial
+// batch after FlushInterval and io.EOF once MaxMessages or IdleTimeout ends
+// the stream.
+func (r *PubSubReader) Read() (arrow.Record, error) {
+	if r.ended {
+		return nil, io.EOF
+	}
+
+	var batch []*pubsub.Message
+	var flush, idle <-chan time.Time
+	if r.opts.IdleTimeout > 0 {
+		idleTimer := time.NewTimer(r.opts.IdleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+
+collect:
+	for len(batch) < r.opts.BatchSize {
+		if r.opts.MaxMessages > 0 && r.received >= r.opts.MaxMessages {
+			r.stop()
+			break
+		}
+		select {
+		case m := <-r.msgs:
+			batch = append(batch, m)
+			r.received++
+			if len(batch) == 1 && r.opts.FlushInterval > 0 {
+				flushTimer := time.NewTimer(r.opts.FlushInterval)
+				defer flushTimer.Stop()
+				flush = flushTimer.C
+			}
+		case <-flush:
+			break collect
+		case <-idle:
+			if len(batch) == 0 {
+				r.stop()
+				break collect
+			}
+		case <-r.recvDone:
+			r.ended = true
+			if r.recvErr != nil && len(batch) == 0 {
+				return nil, fmt.Errorf("pubsub: receive failed: %w", r.recvErr)
+			}
+			break collect
+		}
+	}
+
+	if len(batch) == 0 {
+		return nil, io.EOF
+	}
+	record, err := r.decode(batch)
+	if err != nil {
+		for _, m := range batch {
+			m.Nack()
+		}
+		return nil, err
+	}
+
+	r.seq++
+	r.mu.Lock()
+	r.pending = append(r.pending, pubSubBatch{seq: r.seq, msgs: batch})
+	r.mu.Unlock()
+	return record, nil
+}
+
+// stop ends receiving; messages already buffered are still read.
+func (r *PubSubReader) stop() {
+	r.cancel()
+	r.ended = true
+}
+
+func (r *PubSubReader) decode(batch []*pubsub.Message) (arrow.Record, error) {
+	b := array.NewRecordBuilder(r.alloc, r.schema)
+	defer b.Release()
+	for _, m := range batch {
+		var err error
+		if r.avro != nil {
+			err = r.avro.Append(b, m.Data)
+		} else {
+			err = b.UnmarshalJSON(m.Data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("pubsub: message %s: %w", m.ID, err)
+		}
+	}
+	return b.NewRecord(), nil
+}
+
+// Acknowledge acks the messages of the first records read. With AckOnClose,
+// only the final acknowledgement acks.
+func (r *PubSubReader) Acknowledge(records int64, final bool) error {
+	if r.opts.AckMode == AckOnClose && !final {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for n < len(r.pending) && r.pending[n].seq <= records {
+		for _, m := range r.pending[n].msgs {
+			m.Ack()
+		}
+		n++
+	}
+	r.pending = r.pending[n:]
+	return nil
+}
+
+// Close stops receiving and nacks the messages that were not acknowledged,
+// so that they are redelivered.
+func (r *PubSubReader) Close() error {
+	defer pool.PutAllocator(r.alloc)
+	r.cancel()
+	<-r.recvDone
+	for {
+		select {
+		case m := <-r.msgs:
+			m.Nack()
+			continue
+		default:
+		}
+		break
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, b := range r.pending {
+		for _, m := range b.msgs {
+			m.Nack()
+		}
+	}
+	r.pending = nil
+	if r.recvErr != nil && !errors.Is(r.recvErr, context.Canceled) {
+		return fmt.Errorf("pubsub: receive failed: %w", r.recvErr)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+)
+
+// PubSubPublishMode selects how rows map to messages.
+type PubSubPublishMode int
+
+const (
+	// PublishPerRow publishes each row as a JSON object.
+	PublishPerRow PubSubPublishMode = iota
+	// PublishPerBatch publishes each record as an Arrow IPC stream.
+	PublishPerBatch
+)
+
+// PubSubWriteOptions configures a PubSubWriter.
+type PubSubWriteOptions struct {
+	Mode PubSubPublishMode
+	// OrderingKeyColumn names the column whose value is the ordering key of
+	// each message; per batch, the value of the first row is used. Requires
+	// a subscription with message ordering enabled to take effect.
+	OrderingKeyColumn string
+	// Attributes are set on every message.
+	Attributes map[string]string
+}
+
+func NewDefaultPubSubWriteOptions() *PubSubWriteOptions {
+	return &PubSubWriteOptions{Mode: PublishPerRow}
+}
+
+// PubSubWriter publishes records to a topic. Write returns once the service
+// has accepted every message of the record.
+type PubSubWriter struct {
+	ctx   context.Context
+	topic *pubsub.Topic
+	opts  *PubSubWriteOptions
+}
+
+// NewPubSubWriter returns a writer publishing to topicID.
+func NewPubSubWriter(ctx context.Context, client *pubsub.Client, topicID string, opts *PubSubWriteOptions) (*PubSubWriter, error) {
+	if opts == nil {
+		opts = NewDefaultPubSubWriteOptions()
+	}
+	if opts.Mode != PublishPerRow && opts.Mode != PublishPerBatch {
+		return nil, fmt.Errorf("pubsub: unknown publish mode %d", opts.Mode)
+	}
+	topic := client.Topic(topicID)
+	topic.EnableMessageOrdering = opts.OrderingKeyColumn != ""
+	return &PubSubWriter{ctx: ctx, topic: topic, opts: opts}, nil
+}
+
+// Write publishes the record and waits for the results.
+func (w *PubSubWriter) Write(record arrow.Record) error {
+	keys := -1
+	if w.opts.OrderingKeyColumn != "" {
+		indices := record.Schema().FieldIndices(w.opts.OrderingKeyColumn)
+		if len(indices) == 0 {
+			return fmt.Errorf("pubsub: ordering key column %q is not in the schema", w.opts.OrderingKeyColumn)
+		}
+		keys = indices[0]
+	}
+	orderingKey := func(row int) string {
+		if keys < 0 || record.Column(keys).IsNull(row) {
+			return ""
+		}
+		return record.Column(keys).ValueStr(row)
+	}
+
+	var results []*pubsub.PublishResult
+	var keysUsed []string
+	publish := func(data []byte, key string) {
+		results = append(results, w.topic.Publish(w.ctx, &pubsub.Message{
+			Data:        data,
+			Attributes:  w.opts.Attributes,
+			OrderingKey: key,
+		}))
+		keysUsed = append(keysUsed, key)
+	}
+
+	if w.opts.Mode == PublishPerBatch {
+		var buf bytes.Buffer
+		iw := ipc.NewWriter(&buf, ipc.WithSchema(record.Schema()))
+		if err := iw.Write(record); err != nil {
+			return fmt.Errorf("pubsub: failed to serialize record: %w", err)
+		}
+		if err := iw.Close(); err != nil {
+			return fmt.Errorf("pubsub: failed to serialize record: %w", err)
+		}
+		key := ""
+		if record.NumRows() > 0 {
+			key = orderingKey(0)
+		}
+		publish(buf.Bytes(), key)
+	} else {
+		data, err := rowsJSON(record)
+		if err != nil {
+			return err
+		}
+		for row, d := range data {
+			publish(d, orderingKey(row))
+		}
+	}
+
+	var errs []error
+	for i, res := range results {
+		if _, err := res.Get(w.ctx); err != nil {
+			errs = append(errs, err)
+			// Publishing with a key stops after a failure until resumed.
+			if keysUsed[i] != "" {
+				w.topic.ResumePublish(keysUsed[i])
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("pubsub: %d of %d messages failed to publish: %w", len(errs), len(results), errors.Join(errs...))
+	}
+	return nil
+}
+
+// rowsJSON returns each row of the record as a JSON object. RecordToJSON
+// writes one object per line, escaping newlines within values.
+func rowsJSON(record arrow.Record) ([][]byte, error) {
+	if record.NumRows() == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := array.RecordToJSON(record, &buf); err != nil {
+		return nil, fmt.Errorf("pubsub: failed to encode rows: %w", err)
+	}
+	return bytes.Split(bytes.TrimRight(buf.Bytes(), "\n"), []byte("\n")), nil
+}
+
+// Close flushes pending messages and stops the publisher.
+func (w *PubSubWriter) Close() error {
+	w.topic.Stop()
+	return nil
+}
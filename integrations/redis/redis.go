@@ -0,0 +1,296 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// RedisMode selects the command rows are written with.
+type RedisMode int
+
+const (
+	// RedisStream appends each row as an entry of a stream with XADD.
+	RedisStream RedisMode = iota
+	// RedisList pushes each row onto the tail of a list with RPUSH.
+	RedisList
+)
+
+// RedisEncoding selects how rows are serialized.
+type RedisEncoding int
+
+const (
+	RedisEncodingJSON RedisEncoding = iota
+	RedisEncodingMsgpack
+)
+
+const (
+	defaultRedisStreamField  = "data"
+	defaultRedisPipelineSize = 500
+)
+
+// RedisWriteOptions configures a RedisWriter.
+type RedisWriteOptions struct {
+	Mode     RedisMode
+	Encoding RedisEncoding
+	// Key names the stream or list a row is written to. Column names in
+	// braces are replaced by the row's value, as in "events:{customer_id}";
+	// write "{{" and "}}" for literal braces. Null values render empty.
+	Key string
+	// StreamField is the stream entry field holding the encoded row.
+	StreamField string
+	// MaxLen caps streams at about this many entries (XADD MAXLEN ~). Zero
+	// leaves them unbounded.
+	MaxLen int64
+	// PipelineSize is the number of commands sent per round trip.
+	PipelineSize int
+}
+
+func NewDefaultRedisWriteOptions() *RedisWriteOptions {
+	return &RedisWriteOptions{
+		Mode:         RedisStream,
+		Encoding:     RedisEncodingJSON,
+		StreamField:  defaultRedisStreamField,
+		PipelineSize: defaultRedisPipelineSize,
+	}
+}
+
+// RedisWriter publishes rows to Redis streams or lists.
+type RedisWriter struct {
+	ctx    context.Context
+	client *redis.Client
+	opts   *RedisWriteOptions
+	key    []keyPart
+}
+
+// keyPart is a literal piece of a key template, or a column reference.
+type keyPart struct {
+	literal string
+	column  string
+}
+
+// NewRedisWriter connects to the server at redisURL, such as
+// redis://localhost:6379/0, and returns a writer publishing to it.
+func NewRedisWriter(ctx context.Context, redisURL string, opts *RedisWriteOptions) (*RedisWriter, error) {
+	if opts == nil {
+		opts = NewDefaultRedisWriteOptions()
+	}
+	if opts.StreamField == "" {
+		opts.StreamField = defaultRedisStreamField
+	}
+	if opts.PipelineSize <= 0 {
+		opts.PipelineSize = defaultRedisPipelineSize
+	}
+	if opts.Mode != RedisStream && opts.Mode != RedisList {
+		return nil, fmt.Errorf("redis: unknown mode %d", opts.Mode)
+	}
+	if opts.Encoding != RedisEncodingJSON && opts.Encoding != RedisEncodingMsgpack {
+		return nil, fmt.Errorf("redis: unknown encoding %d", opts.Encoding)
+	}
+	key, err := parseKeyTemplate(opts.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOpts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("redis: invalid URL: %w", err)
+	}
+	client := redis.NewClient(clientOpts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis: failed to connect: %w", err)
+	}
+
+	return &RedisWriter{ctx: ctx, client: client, opts: opts, key: key}, nil
+}
+
+func parseKeyTemplate(tmpl string) ([]keyPart, error) {
+	if tmpl == "" {
+		return nil, errors.New("redis: a key is required")
+	}
+	var parts []keyPart
+	var lit strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		switch {
+		case c == '{' && i+1 < len(tmpl) && tmpl[i+1] == '{', c == '}' && i+1 < len(tmpl) && tmpl[i+1] == '}':
+			lit.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(tmpl[i+1:], '}')
+			if end <= 0 {
+				return nil, fmt.Errorf("redis: invalid key template %q", tmpl)
+			}
+			if lit.Len() > 0 {
+				parts = append(parts, keyPart{literal: lit.String()})
+				lit.Reset()
+			}
+			parts = append(parts, keyPart{column: tmpl[i+1 : i+1+end]})
+			i += end + 1
+		case c == '}':
+			return nil, fmt.Errorf("redis: invalid key template %q", tmpl)
+		default:
+			lit.WriteByte(c)
+		}
+	}
+	if lit.Len() > 0 {
+		parts = append(parts, keyPart{literal: lit.String()})
+	}
+	return parts, nil
+}
+
+// Write publishes every row of the record, pipelining the commands.
+func (w *RedisWriter) Write(record arrow.Record) error {
+	schema := record.Schema()
+	columns := make([]int, len(w.key))
+	for i, part := range w.key {
+		if part.column == "" {
+			continue
+		}
+		indices := schema.FieldIndices(part.column)
+		if len(indices) == 0 {
+			return fmt.Errorf("redis: key column %q is not in the schema", part.column)
+		}
+		columns[i] = indices[0]
+	}
+
+	pipe := w.client.Pipeline()
+	var key strings.Builder
+	for row := 0; row < int(record.NumRows()); row++ {
+		key.Reset()
+		for i, part := range w.key {
+			if part.column == "" {
+				key.WriteString(part.literal)
+			} else if col := record.Column(columns[i]); col.IsValid(row) {
+				key.WriteString(col.ValueStr(row))
+			}
+		}
+
+		payload, err := w.encodeRow(record, row)
+		if err != nil {
+			return err
+		}
+		if w.opts.Mode == RedisStream {
+			pipe.XAdd(w.ctx, &redis.XAddArgs{
+				Stream: key.String(),
+				MaxLen: w.opts.MaxLen,
+				Approx: w.opts.MaxLen > 0,
+				Values: []any{w.opts.StreamField, payload},
+			})
+		} else {
+			pipe.RPush(w.ctx, key.String(), payload)
+		}
+
+		if pipe.Len() >= w.opts.PipelineSize {
+			if _, err := pipe.Exec(w.ctx); err != nil {
+				return fmt.Errorf("redis: failed to write rows: %w", err)
+			}
+		}
+	}
+	if pipe.Len() > 0 {
+		if _, err := pipe.Exec(w.ctx); err != nil {
+			return fmt.Errorf("redis: failed to write rows: %w", err)
+		}
+	}
+	return nil
+}
+
+func (w *RedisWriter) encodeRow(record arrow.Record, row int) ([]byte, error) {
+	values := make(map[string]any, record.NumCols())
+	for i, field := range record.Schema().Fields() {
+		values[field.Name] = redisValue(record.Column(i), row)
+	}
+	if w.opts.Encoding == RedisEncodingJSON {
+		return json.Marshal(values)
+	}
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(values); err != nil {
+		return nil, fmt.Errorf("redis: failed to encode row: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// redisValue returns a value of arr that both encodings represent natively:
+// structs and maps become maps, lists slices, and other types their JSON
+// form.
+func redisValue(arr arrow.Array, i int) any {
+	if arr.IsNull(i) {
+		return nil
+	}
+	switch a := arr.(type) {
+	case *array.Struct:
+		st := a.DataType().(*arrow.StructType)
+		m := make(map[string]any, a.NumField())
+		for f := 0; f < a.NumField(); f++ {
+			m[st.Field(f).Name] = redisValue(a.Field(f), i)
+		}
+		return m
+	case *array.Map:
+		start, end := a.ValueOffsets(i)
+		m := make(map[string]any, end-start)
+		for j := start; j < end; j++ {
+			m[a.Keys().ValueStr(int(j))] = redisValue(a.Items(), int(j))
+		}
+		return m
+	case array.ListLike:
+		start, end := a.ValueOffsets(i)
+		values := make([]any, 0, end-start)
+		for j := start; j < end; j++ {
+			values = append(values, redisValue(a.ListValues(), int(j)))
+		}
+		return values
+	case *array.Dictionary:
+		return redisValue(a.Dictionary(), a.GetValueIndex(i))
+	}
+
+	switch v := arr.GetOneForMarshal(i).(type) {
+	case bool, int8, int16, int32, int64, uint8, uint16, uint32, uint64, float32, float64, string, []byte:
+		return v
+	}
+	return arr.ValueStr(i)
+}
+
+// Close closes the connection.
+func (w *RedisWriter) Close() error {
+	return w.client.Close()
+}
@@ -0,0 +1,174 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arrowarc/arrowarc/pkg/awsauth"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// unsignedPayload leaves request bodies out of signatures, which S3 allows
+// and which spares reading uploads twice.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Bucket reads and writes the objects of an S3 bucket with the S3 REST
+// API. Objects are uploaded with a single PutObject request, so they are
+// limited to 5 GiB.
+type S3Bucket struct {
+	cfg    aws.Config
+	bucket string
+	signer *v4.Signer
+	client *http.Client
+}
+
+// NewS3Bucket returns the named bucket, accessed with the credentials and
+// region auth selects, or the default ones if it is nil. With an endpoint
+// set, objects are addressed by path, as LocalStack and most S3-compatible
+// stores expect.
+func NewS3Bucket(ctx context.Context, bucket string, auth *awsauth.Options) (*S3Bucket, error) {
+	cfg, err := auth.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Bucket{
+		cfg:    cfg,
+		bucket: bucket,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true }),
+		client: http.DefaultClient,
+	}, nil
+}
+
+// objectURL returns the URL of the object key.
+func (b *S3Bucket) objectURL(key string) (*url.URL, error) {
+	escaped := make([]string, 0, 2)
+	var base string
+	if b.cfg.BaseEndpoint != nil {
+		base = strings.TrimSuffix(*b.cfg.BaseEndpoint, "/")
+		escaped = append(escaped, url.PathEscape(b.bucket))
+	} else {
+		base = "https://" + b.bucket + ".s3." + b.cfg.Region + ".amazonaws.com"
+	}
+	for _, part := range strings.Split(key, "/") {
+		escaped = append(escaped, url.PathEscape(part))
+	}
+	return url.Parse(base + "/" + strings.Join(escaped, "/"))
+}
+
+// do signs and sends a request for the object key, returning an error for
+// any response but a success.
+func (b *S3Bucket) do(ctx context.Context, method, key string, body io.Reader, size int64) (*http.Response, error) {
+	u, err := b.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	creds, err := b.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.signer.SignHTTP(ctx, creds, req, unsignedPayload, "s3", b.cfg.Region, time.Now()); err != nil {
+		return nil, err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+		return nil, fmt.Errorf("s3://%s/%s: %s: %s", b.bucket, key, e.Code, e.Message)
+	}
+	return nil, fmt.Errorf("s3://%s/%s: %s", b.bucket, key, resp.Status)
+}
+
+// Get opens the object key for reading.
+func (b *S3Bucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := b.do(ctx, http.MethodGet, key, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Upload writes the content of r to the object key. Files are streamed;
+// other readers are read into memory first.
+func (b *S3Bucket) Upload(ctx context.Context, key string, r io.Reader) error {
+	var size int64
+	if f, ok := r.(*os.File); ok {
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		size = info.Size()
+	} else {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+	resp, err := b.do(ctx, http.MethodPut, key, io.NopCloser(r), size)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Close releases nothing; it makes S3Bucket an endpoints.Bucket.
+func (b *S3Bucket) Close() error {
+	return nil
+}
@@ -0,0 +1,163 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/arrowarc/arrowarc/pkg/bench"
+	"github.com/docopt/docopt-go"
+)
+
+const benchUsage = `Run benchmark scenarios against local files.
+
+The suite lists scenarios, each run for every combination of its rows, codecs
+and concurrency. Inputs are files, or data generated from a datagen spec:
+
+  scenarios:
+    - name: parquet-to-csv
+      kind: convert            # read, convert or write
+      to_format: csv
+      rows: [100000, 1000000]
+      concurrency: [1, 4]
+      repeat: 3
+    - name: write-parquet
+      kind: write
+      codecs: [snappy, zstd]
+
+Usage:
+  arrowarc bench [options] <suite>
+  arrowarc bench -h | --help
+
+Options:
+  -h --help                     Show this screen.
+  --format=<format>             Print the results as text, json or csv [default: text].
+  --out=<path>                  Also save the results, as JSON or CSV after the extension.
+  --baseline=<path>             JSON results of an earlier run to compare with.
+  --threshold=<percent>         Throughput drop from the baseline counted as a regression [default: 10].
+  --dir=<path>                  Directory for generated inputs and outputs. A temporary directory by default.
+`
+
+// Bench runs the bench command with the given arguments, the first of
+// which is the command name.
+func Bench(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(benchUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	suitePath, _ := arguments.String("<suite>")
+	format, _ := arguments.String("--format")
+	out, _ := arguments.String("--out")
+	baselinePath, _ := arguments.String("--baseline")
+	dir, _ := arguments.String("--dir")
+	thresholdArg, _ := arguments.String("--threshold")
+	threshold, err := strconv.ParseFloat(thresholdArg, 64)
+	if err != nil || threshold < 0 {
+		return fmt.Errorf("invalid --threshold %q", thresholdArg)
+	}
+	write, err := reportWriter(format)
+	if err != nil {
+		return err
+	}
+
+	suite, err := bench.LoadSuite(suitePath)
+	if err != nil {
+		return err
+	}
+	var baseline *bench.Report
+	if baselinePath != "" {
+		if baseline, err = bench.ReadReport(baselinePath); err != nil {
+			return err
+		}
+	}
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", "arrowarc-bench-"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	results, err := bench.Run(ctx, suite, dir)
+	if err != nil {
+		return err
+	}
+	report := &bench.Report{Results: results}
+	if baseline != nil {
+		report.Comparisons = bench.Compare(baseline.Results, results, threshold/100)
+	}
+	if err := write(os.Stdout, report); err != nil {
+		return err
+	}
+	if out != "" {
+		if err := saveReport(out, report); err != nil {
+			return err
+		}
+	}
+	if n := report.Regressions(); n > 0 {
+		return fmt.Errorf("%d cases regressed by more than %v%% from the baseline", n, threshold)
+	}
+	return nil
+}
+
+func reportWriter(format string) (func(io.Writer, *bench.Report) error, error) {
+	switch format {
+	case "text":
+		return bench.WriteText, nil
+	case "json":
+		return bench.WriteJSON, nil
+	case "csv":
+		return bench.WriteCSV, nil
+	}
+	return nil, fmt.Errorf("invalid --format %q, expected text, json or csv", format)
+}
+
+// saveReport writes report at path, as CSV if its extension is .csv and as
+// JSON otherwise.
+func saveReport(path string, report *bench.Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	write := bench.WriteJSON
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		write = bench.WriteCSV
+	}
+	if err := write(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -33,9 +33,9 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/apache/arrow-go/v18/parquet/compress"
 	converter "github.com/arrowarc/arrowarc/converter"
 	generator "github.com/arrowarc/arrowarc/generator"
+	"github.com/arrowarc/arrowarc/pipeline"
 	pq "github.com/arrowarc/arrowarc/pkg/parquet"
 )
 
@@ -47,30 +47,53 @@ func Help() error {
 	fmt.Println("  CSV to Parquet - Convert a CSV file to Parquet")
 	fmt.Println("  Parquet to JSON - Convert a Parquet file to JSON")
 	fmt.Println("  Rewrite Parquet - Rewrite a Parquet file")
+	fmt.Println("  Split Parquet - Split a Parquet file into smaller files")
 	fmt.Println("  Run Flight Tests - Run Arrow Flight tests")
 	fmt.Println("  Avro to Parquet - Convert an Avro file to Parquet")
 	fmt.Println("  CSV to JSON - Convert a CSV file to JSON")
+	fmt.Println()
+	fmt.Println("Run without arguments for the interactive menu, or:")
+	fmt.Println("  arrowarc head|tail|cat <source> - Print rows of a file or DuckDB query")
+	fmt.Println("  arrowarc schema [diff] <source> [<other>] - Print or compare schemas")
+	fmt.Println("  arrowarc convert [--from=<path>] --to=<path> - Convert between formats; - is stdin/stdout")
+	fmt.Println("  arrowarc diff [--key=<cols>] <a> <b> - Compare the rows of two datasets, across formats")
+	fmt.Println("  arrowarc generate --spec=<path> --to=<path> - Generate synthetic data from a YAML spec")
+	fmt.Println("  arrowarc bench [--baseline=<path>] <suite> - Run benchmark scenarios and compare with a baseline")
+	fmt.Println("  arrowarc tpch|tpcds --scale=<factor> --to=<dir> - Generate TPC-H or TPC-DS tables with DuckDB")
+	fmt.Println("  arrowarc sql [-e <sql>] <input>... - Query files with SQL in DuckDB, interactively or once")
+	fmt.Println("  arrowarc flightsql query --addr=<host:port> --sql=<sql> [--out=<path>] - Query a Flight SQL server")
+	fmt.Println("  arrowarc relay serve|get [--addr=<host:port>] - Stream sources between instances over Arrow Flight")
+	fmt.Println("  arrowarc watch --to=<dir> <dir> - Convert files dropped into a directory as they arrive")
+	fmt.Println("  arrowarc serve [--addr=<host:port>] - Run pipelines submitted over an HTTP API")
+	fmt.Println("  arrowarc runs list|show [<id>] - Inspect the history of pipeline runs")
+	fmt.Println("Pass --debug-alloc before the command to log buffers left unreleased,")
+	fmt.Println("--log-level=debug|info|warn|error or --log-json to set up logging,")
+	fmt.Println("--history=<path> to record its runs in a JSON lines or SQLite history,")
+	fmt.Println("and --pprof-addr=<host:port> or --trace-out=<path> to serve pprof profiles")
+	fmt.Println("or write an execution trace while it runs.")
 	return nil
 }
 
-func ExecuteCommand(ctx context.Context, command string) error {
+func ExecuteCommand(ctx context.Context, command string, monitor pipeline.Monitor) error {
 	switch command {
 	case "Generate Parquet":
 		return GenerateParquet(ctx)
 	case "Parquet to CSV":
-		return ParquetToCSV(ctx)
+		return ParquetToCSV(ctx, monitor)
 	case "CSV to Parquet":
-		return CSVToParquet(ctx)
+		return CSVToParquet(ctx, monitor)
 	case "Parquet to JSON":
-		return ParquetToJSON(ctx)
+		return ParquetToJSON(ctx, monitor)
 	case "Rewrite Parquet":
 		return RewriteParquet(ctx)
+	case "Split Parquet":
+		return SplitParquet(ctx)
 	case "Run Flight Tests":
 		return RunFlightTests(ctx)
 	case "Avro to Parquet":
-		return AvroToParquet(ctx)
+		return AvroToParquet(ctx, monitor)
 	case "CSV to JSON":
-		return CSVToJSON(ctx)
+		return CSVToJSON(ctx, monitor)
 	case "Help":
 		return Help()
 	case "Quit":
@@ -93,60 +116,99 @@ func GenerateParquet(ctx context.Context) error {
 	return generator.GenerateParquetFile(path, targetSize, complex)
 }
 
-func ParquetToCSV(ctx context.Context) error {
+func ParquetToCSV(ctx context.Context, monitor pipeline.Monitor) error {
 	fmt.Print("Enter the path of the Parquet file: ")
 	var parquetPath string
 	fmt.Scanln(&parquetPath)
 	fmt.Print("Enter the path for the output CSV file: ")
 	var csvPath string
 	fmt.Scanln(&csvPath)
-	metrics, err := converter.ConvertParquetToCSV(context.Background(), parquetPath, csvPath, true, 100000, []string{}, []int{}, false, ',', false, "", nil, nil)
+	metrics, err := converter.ConvertParquetToCSVWithOptions(ctx, parquetPath, csvPath, &converter.ParquetToCSVOptions{
+		MemoryMap:   true,
+		ChunkSize:   100000,
+		Concurrency: 1,
+		Delimiter:   ',',
+		Monitor:     monitor,
+	})
 	if err != nil {
+		if metrics != "" {
+			fmt.Printf("Conversion failed. Summary: %s\n", metrics)
+		}
 		return err
 	}
 	fmt.Printf("Conversion completed. Summary: %s\n", metrics)
 	return nil
 }
 
-func CSVToParquet(ctx context.Context) error {
+func CSVToParquet(ctx context.Context, monitor pipeline.Monitor) error {
 	fmt.Print("Enter the path of the CSV file: ")
 	var csvPath string
 	fmt.Scanln(&csvPath)
 	fmt.Print("Enter the path for the output Parquet file: ")
 	var parquetPath string
 	fmt.Scanln(&parquetPath)
-	metrics, err := converter.ConvertCSVToParquet(context.Background(), csvPath, parquetPath, true, 100000, ',', []string{}, true)
+	metrics, err := converter.ConvertCSVToParquetWithOptions(ctx, csvPath, parquetPath, &converter.CSVToParquetOptions{
+		HasHeader:        true,
+		ChunkSize:        100000,
+		Delimiter:        ',',
+		StringsCanBeNull: true,
+		Concurrency:      1,
+		Monitor:          monitor,
+	})
 	if err != nil {
+		if metrics != "" {
+			fmt.Printf("Conversion failed. Summary: %s\n", metrics)
+		}
 		return err
 	}
 	fmt.Printf("Conversion completed. Summary: %s\n", metrics)
 	return nil
 }
 
-func CSVToJSON(ctx context.Context) error {
+func CSVToJSON(ctx context.Context, monitor pipeline.Monitor) error {
 	fmt.Print("Enter the path of the CSV file: ")
 	var csvPath string
 	fmt.Scanln(&csvPath)
 	fmt.Print("Enter the path for the output JSON file: ")
 	var jsonPath string
 	fmt.Scanln(&jsonPath)
-	metrics, err := converter.ConvertCSVToJSON(context.Background(), csvPath, jsonPath, true, 100000, ',', []string{}, true)
+	metrics, err := converter.ConvertCSVToJSONWithOptions(ctx, csvPath, jsonPath, &converter.CSVToJSONOptions{
+		HasHeader:        true,
+		ChunkSize:        100000,
+		Delimiter:        ',',
+		StringsCanBeNull: true,
+		Concurrency:      1,
+		Monitor:          monitor,
+	})
 	if err != nil {
+		if metrics != "" {
+			fmt.Printf("Conversion failed. Summary: %s\n", metrics)
+		}
 		return err
 	}
 	fmt.Printf("Conversion completed. Summary: %s\n", metrics)
 	return nil
 }
 
-func ParquetToJSON(ctx context.Context) error {
+func ParquetToJSON(ctx context.Context, monitor pipeline.Monitor) error {
 	fmt.Print("Enter the path of the Parquet file: ")
 	var parquetPath string
 	fmt.Scanln(&parquetPath)
 	fmt.Print("Enter the path for the output JSON file: ")
 	var jsonPath string
 	fmt.Scanln(&jsonPath)
-	metrics, err := converter.ConvertParquetToJSON(context.Background(), parquetPath, jsonPath, true, 100000, []string{}, []int{}, true, true)
+	metrics, err := converter.ConvertParquetToJSONWithOptions(ctx, parquetPath, jsonPath, &converter.ParquetToJSONOptions{
+		MemoryMap:      true,
+		ChunkSize:      100000,
+		Parallel:       true,
+		Concurrency:    1,
+		IncludeStructs: true,
+		Monitor:        monitor,
+	})
 	if err != nil {
+		if metrics != "" {
+			fmt.Printf("Conversion failed. Summary: %s\n", metrics)
+		}
 		return err
 	}
 	fmt.Printf("Conversion completed. Summary: %s\n", metrics)
@@ -160,7 +222,25 @@ func RewriteParquet(ctx context.Context) error {
 	fmt.Print("Enter the path for the rewritten Parquet file: ")
 	var outputPath string
 	fmt.Scanln(&outputPath)
-	return pq.RewriteParquetFile(context.Background(), inputPath, outputPath, true, 100000, []string{}, []int{}, true, nil)
+	return pq.RewriteParquetFile(ctx, inputPath, outputPath, true, 100000, []string{}, []int{}, true, nil)
+}
+
+func SplitParquet(ctx context.Context) error {
+	fmt.Print("Enter the path of the Parquet file to split: ")
+	var inputPath string
+	fmt.Scanln(&inputPath)
+	fmt.Print("Enter the output path pattern (e.g. part-%03d.parquet): ")
+	var outputPattern string
+	fmt.Scanln(&outputPattern)
+	fmt.Print("Enter the maximum number of rows per file: ")
+	var maxRows int64
+	fmt.Scanln(&maxRows)
+	paths, err := pq.SplitFile(ctx, inputPath, outputPattern, pq.SplitLimit{MaxRows: maxRows})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Split into %d files.\n", len(paths))
+	return nil
 }
 
 func RunFlightTests(ctx context.Context) error {
@@ -169,15 +249,22 @@ func RunFlightTests(ctx context.Context) error {
 	return fmt.Errorf("arrow Flight tests not implemented yet")
 }
 
-func AvroToParquet(ctx context.Context) error {
+func AvroToParquet(ctx context.Context, monitor pipeline.Monitor) error {
 	fmt.Print("Enter the path of the Avro file: ")
 	var avroPath string
 	fmt.Scanln(&avroPath)
 	fmt.Print("Enter the path for the output Parquet file: ")
 	var parquetPath string
 	fmt.Scanln(&parquetPath)
-	metrics, err := converter.ConvertAvroToParquet(context.Background(), avroPath, parquetPath, 100000, compress.Codecs.Snappy)
+	metrics, err := converter.ConvertAvroToParquetWithOptions(ctx, avroPath, parquetPath, &converter.AvroToParquetOptions{
+		ChunkSize:   100000,
+		Concurrency: 1,
+		Monitor:     monitor,
+	})
 	if err != nil {
+		if metrics != "" {
+			fmt.Printf("Conversion failed. Summary: %s\n", metrics)
+		}
 		return err
 	}
 	fmt.Printf("Conversion completed. Summary: %s\n", metrics)
@@ -0,0 +1,269 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/ui"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/projection"
+	"github.com/arrowarc/arrowarc/pkg/seal"
+	"github.com/docopt/docopt-go"
+)
+
+const convertUsage = `Convert a file from one format to another.
+
+Use - as the input or output path to read from standard input or write to standard
+output, e.g. cat events.ndjson | arrowarc convert --from-format ndjson --to events.parquet.
+Standard input can hold CSV, NDJSON, Avro, delimited protobuf or an Arrow IPC stream.
+An input of grpc://host:port/pkg.Service/Method calls that RPC and converts its responses.
+An input of ws:// or wss://, or an http(s) URL with --from-format events for server-sent
+events, converts a stream of JSON events, inferring their schema from the first events.
+
+Usage:
+  arrowarc convert [options] --to=<path>
+  arrowarc convert -h | --help
+
+Options:
+  -h --help                     Show this screen.
+  --from=<path>                 Input file, or - for standard input [default: -].
+  --from-format=<format>        Input format: parquet, csv, ndjson, avro, ipc, feather, protobuf or events. Detected from the extension by default.
+  --to=<path>                   Output file, or - for standard output.
+  --to-format=<format>          Output format: parquet, csv, ndjson, avro, ipc or feather. Detected from the extension by default.
+  --delimiter=<char>            Delimiter of CSV input [default: ,].
+  --no-header                   The CSV input has no header row.
+  --proto-descriptors=<path>    FileDescriptorSet describing protobuf messages (protoc --include_imports --descriptor_set_out).
+  --proto-message=<name>        Fully qualified name of the message type of a protobuf source.
+  --proto-reflection=<target>   grpc:// or grpcs:// server to fetch the protobuf message descriptor from by reflection.
+  --grpc-request=<json>         Request message of a grpc:// source, in protobuf JSON.
+  --max-messages=<n>            Stop a grpc:// or event stream source after n responses or events.
+  --flush-interval=<duration>   Emit partial records of a grpc:// or event stream source after this long, e.g. 5s.
+  --subscribe=<message>         Message to send once a ws:// or wss:// source is open.
+  --chunk-size=<rows>           Number of rows per record [default: 1024].
+  --batch-rows=<rows>           Coalesce the records read into batches of up to this many rows before writing them.
+  --batch-bytes=<size>          Coalesce the records read into batches of up to this size, e.g. 64MiB.
+  --batch-latency=<duration>    Write a coalesced batch at the latest this long after its first record was read, e.g. 30s.
+  --spill-threshold=<size>      Spill the records queued for a slow output past this size to disk, e.g. 256MiB.
+  --spill-dir=<dir>             Directory to spill records to, the system's temporary directory by default.
+  --offset=<rows>               Skip the first rows of the input.
+  --limit=<rows>                Convert at most this many rows.
+  --sample=<fraction>           Convert a random sample of the rows, each kept with this probability, e.g. 0.01.
+  --seed=<n>                    Seed of --sample, to draw the same sample again. Random by default.
+  --select=<col1,col2,...>      Columns to convert, in the order written. Parquet input reads only these.
+  --rename=<old=new,...>        Columns to rename in the output.
+  --timestamp-unit=<unit>       Normalize timestamp columns of CSV and NDJSON to s, ms, us or ns.
+  --timezone=<zone>             Time zone, e.g. UTC or Europe/Paris, attached to CSV and NDJSON input timestamps without one, and written in CSV and NDJSON output.
+  --timestamp-format=<format>   Format of CSV and NDJSON timestamps: rfc3339, epoch or a Go time layout.
+  --on-empty=<policy>           When the input has no rows: write an empty output with its schema, skip the output, or fail [default: write].
+  --verify                      Read the output back once written and check it holds the rows and values converted.
+  --checksum                    Write the SHA-256 of the output to a sidecar file named after it with .sha256 appended.
+  --age-recipient=<keys>        Encrypt the output to these comma-separated age or SSH public keys, appending .age to its name.
+  --gpg-keyring=<path>          Encrypt the output to the OpenPGP public keys in this file, appending .gpg to its name.
+  --no-tui                      Log progress lines instead of the live progress view.
+`
+
+// Convert runs the convert command with the given arguments, the first of
+// which is the command name.
+func Convert(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(convertUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	from, _ := arguments.String("--from")
+	fromFormat, _ := arguments.String("--from-format")
+	to, _ := arguments.String("--to")
+	toFormat, _ := arguments.String("--to-format")
+	noTUI, _ := arguments.Bool("--no-tui")
+	verifyOutput, _ := arguments.Bool("--verify")
+	chunkSize, err := arguments.Int("--chunk-size")
+	if err != nil || chunkSize <= 0 {
+		return fmt.Errorf("invalid --chunk-size")
+	}
+	sourceOpts, err := sourceOptions(arguments)
+	if err != nil {
+		return err
+	}
+	timestamps, err := timestampOptions(arguments)
+	if err != nil {
+		return err
+	}
+	var fraction float64
+	if v, _ := arguments.String("--sample"); v != "" {
+		fraction, err = strconv.ParseFloat(v, 64)
+		if err != nil || fraction <= 0 || fraction > 1 {
+			return fmt.Errorf("invalid --sample: the fraction must be in (0, 1]")
+		}
+	}
+	seed := rand.Int64()
+	if v, _ := arguments.String("--seed"); v != "" {
+		if seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("invalid --seed")
+		}
+	}
+
+	selected, _ := arguments.String("--select")
+	renamed, _ := arguments.String("--rename")
+	project, err := projection.Parse(selected, renamed)
+	if err != nil {
+		return fmt.Errorf("invalid --rename: %w", err)
+	}
+
+	onEmptyName, _ := arguments.String("--on-empty")
+	onEmpty, err := pipeline.ParseEmptyPolicy(onEmptyName)
+	if err != nil {
+		return fmt.Errorf("invalid --on-empty: %w", err)
+	}
+
+	sealOpts := sealOptions(arguments)
+	batch, err := batchPolicy(arguments)
+	if err != nil {
+		return err
+	}
+	var spillThreshold int64
+	if v, _ := arguments.String("--spill-threshold"); v != "" {
+		if spillThreshold, err = config.ParseByteSize(v); err != nil || spillThreshold <= 0 {
+			return fmt.Errorf("invalid --spill-threshold")
+		}
+	}
+	spillDir, _ := arguments.String("--spill-dir")
+
+	metrics, err := converter.Convert(ctx, from, to, &converter.ConvertOptions{
+		FromFormat:     fromFormat,
+		ToFormat:       toFormat,
+		ChunkSize:      int64(chunkSize),
+		CSV:            sourceOpts.CSV,
+		Protobuf:       sourceOpts.Protobuf,
+		GRPC:           sourceOpts.GRPC,
+		Events:         sourceOpts.Events,
+		HTTP:           sourceOpts.HTTP,
+		Offset:         sourceOpts.Offset,
+		Limit:          sourceOpts.Limit,
+		Sample:         fraction,
+		SampleSeed:     seed,
+		Select:         project.Select,
+		Rename:         project.Rename,
+		Verify:         verifyOutput,
+		OnEmpty:        onEmpty,
+		Timestamps:     timestamps,
+		Seal:           sealOpts,
+		Batch:          batch,
+		Monitor:        ui.NewProgressMonitor("Convert", os.Stderr, noTUI),
+		SpillThreshold: spillThreshold,
+		SpillDir:       spillDir,
+	})
+	if err != nil {
+		if metrics != "" {
+			fmt.Fprintf(os.Stderr, "Conversion failed. Summary: %s\n", metrics)
+		}
+		return err
+	}
+	// Report on stderr so that output written to stdout stays clean.
+	fmt.Fprintf(os.Stderr, "Conversion completed. Summary: %s\n", metrics)
+	return nil
+}
+
+// sealOptions returns the seal options of the parsed arguments, or nil
+// when the output is not to be sealed.
+func sealOptions(arguments docopt.Opts) *seal.Options {
+	var opts seal.Options
+	opts.Checksum, _ = arguments.Bool("--checksum")
+	opts.GPGKeyring, _ = arguments.String("--gpg-keyring")
+	if recipients, _ := arguments.String("--age-recipient"); recipients != "" {
+		for _, r := range strings.Split(recipients, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				opts.AgeRecipients = append(opts.AgeRecipients, r)
+			}
+		}
+	}
+	if !opts.Checksum && !opts.Encrypted() {
+		return nil
+	}
+	return &opts
+}
+
+// batchPolicy returns the batch policy of the parsed arguments, or nil
+// when the records read are written as they are.
+func batchPolicy(arguments docopt.Opts) (*pipeline.BatchPolicy, error) {
+	var policy pipeline.BatchPolicy
+	if v, _ := arguments.String("--batch-rows"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid --batch-rows")
+		}
+		policy.MaxRows = n
+	}
+	if v, _ := arguments.String("--batch-bytes"); v != "" {
+		n, err := config.ParseByteSize(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid --batch-bytes")
+		}
+		policy.MaxBytes = n
+	}
+	if v, _ := arguments.String("--batch-latency"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid --batch-latency")
+		}
+		policy.MaxLatency = d
+	}
+	if policy == (pipeline.BatchPolicy{}) {
+		return nil, nil
+	}
+	return &policy, nil
+}
+
+// timestampOptions returns the timestamp options of the parsed arguments.
+func timestampOptions(arguments docopt.Opts) (integrations.TimestampOptions, error) {
+	var opts integrations.TimestampOptions
+	opts.Unit, _ = arguments.String("--timestamp-unit")
+	opts.TimeZone, _ = arguments.String("--timezone")
+	opts.Format, _ = arguments.String("--timestamp-format")
+	if opts.Unit != "" {
+		if _, err := integrations.ParseTimeUnit(opts.Unit); err != nil {
+			return opts, fmt.Errorf("invalid --timestamp-unit: %w", err)
+		}
+	}
+	if opts.TimeZone != "" {
+		if _, err := time.LoadLocation(opts.TimeZone); err != nil {
+			return opts, fmt.Errorf("invalid --timezone: %w", err)
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,130 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	converter "github.com/arrowarc/arrowarc/converter"
+	"github.com/arrowarc/arrowarc/pkg/datadiff"
+	"github.com/docopt/docopt-go"
+)
+
+const diffUsage = `Compare two datasets by schema and row content.
+
+<a> and <b> are Parquet, CSV, JSON, Avro, Arrow IPC or Feather files, or http(s) URLs,
+in the same or different formats. Rows are matched by --key, or by position when no
+key is given. Columns of <a> also in <b> are compared, by name. The command fails
+when rows differ or columns were added or removed.
+
+Usage:
+  arrowarc diff [options] <a> <b>
+  arrowarc diff -h | --help
+
+Options:
+  -h --help                     Show this screen.
+  --key=<col1,col2,...>         Columns identifying a row. The rows of <b> are held in memory.
+  --tolerance=<x>               Largest difference between floating point values still counted as equal [default: 0].
+  --max-examples=<n>            Number of differing rows to show [default: 10].
+  --json                        Print the report as JSON.
+  --delimiter=<char>            Delimiter of CSV datasets [default: ,].
+  --no-header                   CSV datasets have no header row.
+`
+
+// errDatasetsDiffer is returned by Diff once the report of differing
+// datasets is printed, so that the command exits with an error.
+var errDatasetsDiffer = errors.New("datasets differ")
+
+// Diff runs the diff command with the given arguments, the first of which
+// is the command name.
+func Diff(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(diffUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	pathA, _ := arguments.String("<a>")
+	pathB, _ := arguments.String("<b>")
+	keys, _ := arguments.String("--key")
+	asJSON, _ := arguments.Bool("--json")
+	toleranceArg, _ := arguments.String("--tolerance")
+	tolerance, err := strconv.ParseFloat(toleranceArg, 64)
+	if err != nil || tolerance < 0 {
+		return fmt.Errorf("invalid --tolerance %q", toleranceArg)
+	}
+	maxExamples, err := arguments.Int("--max-examples")
+	if err != nil || maxExamples < 0 {
+		return fmt.Errorf("invalid --max-examples")
+	}
+	if maxExamples == 0 {
+		maxExamples = -1
+	}
+	sourceOpts, err := sourceOptions(arguments)
+	if err != nil {
+		return err
+	}
+
+	inputOpts := &converter.ConvertOptions{CSV: sourceOpts.CSV, HTTP: sourceOpts.HTTP}
+	a, err := converter.OpenInput(ctx, pathA, inputOpts)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	b, err := converter.OpenInput(ctx, pathB, inputOpts)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	opts := datadiff.Options{Tolerance: tolerance, MaxExamples: maxExamples}
+	if keys != "" {
+		opts.Keys = strings.Split(keys, ",")
+	}
+	report, err := datadiff.Diff(a, b, opts)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		err = datadiff.WriteJSON(os.Stdout, report)
+	} else {
+		err = datadiff.WriteText(os.Stdout, report)
+	}
+	if err != nil {
+		return err
+	}
+	if !report.Equal() {
+		return errDatasetsDiffer
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arrowarc/arrowarc/converter"
+	flight "github.com/arrowarc/arrowarc/integrations/flight"
+	"github.com/arrowarc/arrowarc/internal/ui"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/preview"
+	"github.com/docopt/docopt-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const flightSQLUsage = `Run a query on an Arrow Flight SQL server, such as the SQLite server of arrowarc.
+
+The results are streamed with DoGet and printed as a table, or written to --out.
+
+Usage:
+  arrowarc flightsql query [options] --addr=<host:port> --sql=<sql> [--header=<name:value>...]
+  arrowarc flightsql -h | --help
+
+Options:
+  -h --help                     Show this screen.
+  --addr=<host:port>            Address of the Flight SQL server.
+  --sql=<sql>                   Query to run.
+  --out=<path>                  Output file, or - for standard output.
+  --out-format=<format>         Output format: parquet, csv, ndjson, avro, ipc or feather. Detected from the extension by default.
+  --header=<name:value>         Header sent with every call, such as "authorization: Bearer <token>".
+  --tls                         Connect with TLS, verifying the server against the system roots.
+  --max-width=<n>               Truncate table cells longer than n characters [default: 40].
+  --no-tui                      Log progress lines instead of the live progress view.
+`
+
+// FlightSQL runs the flightsql command with the given arguments, the first
+// of which is the command name.
+func FlightSQL(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(flightSQLUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	addr, _ := arguments.String("--addr")
+	query, _ := arguments.String("--sql")
+	out, _ := arguments.String("--out")
+	outFormat, _ := arguments.String("--out-format")
+	useTLS, _ := arguments.Bool("--tls")
+	noTUI, _ := arguments.Bool("--no-tui")
+	headers, _ := arguments["--header"].([]string)
+	maxWidth, err := arguments.Int("--max-width")
+	if err != nil {
+		return fmt.Errorf("invalid --max-width: %w", err)
+	}
+
+	opts := &flight.FlightSQLReadOptions{Headers: map[string]string{}}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid --header %q, expected name:value", header)
+		}
+		opts.Headers[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	if useTLS {
+		opts.DialOptions = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))}
+	}
+
+	reader, err := flight.NewFlightSQLReader(ctx, addr, query, opts)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		defer reader.Close()
+		return preview.Cat(reader, os.Stdout, preview.Options{Format: preview.Table, MaxWidth: maxWidth})
+	}
+
+	writer, err := converter.CreateOutput(ctx, out, outFormat, reader.Schema())
+	if err != nil {
+		reader.Close()
+		return err
+	}
+	p := pipeline.NewDataPipeline(reader, writer).WithMonitor(ui.NewProgressMonitor("Flight SQL", os.Stderr, noTUI))
+	metrics, err := p.Start(ctx)
+	if err == nil {
+		err = <-p.Done()
+	}
+	if err != nil {
+		if metrics != "" {
+			fmt.Fprintf(os.Stderr, "Query failed. Summary: %s\n", metrics)
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Query completed. Summary: %s\n", metrics)
+	return nil
+}
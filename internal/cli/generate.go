@@ -0,0 +1,119 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/arrowarc/arrowarc/converter"
+	"github.com/arrowarc/arrowarc/internal/ui"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/datagen"
+	"github.com/docopt/docopt-go"
+)
+
+const generateUsage = `Generate synthetic data from a YAML spec.
+
+The spec lists the columns to generate, each with a type and a distribution:
+
+  rows: 1000000
+  seed: 42
+  columns:
+    - {name: id, type: int64, distribution: sequence, min: 1}
+    - {name: country, type: string, distribution: categorical, values: [fr, de, us], weights: [1, 1, 8]}
+    - {name: product, type: int32, distribution: zipf, min: 1, max: 10000}
+    - {name: price, type: float64, min: 1, max: 500, null_rate: 0.05}
+
+Usage:
+  arrowarc generate [options] --spec=<path> --to=<path>
+  arrowarc generate -h | --help
+
+Options:
+  -h --help                     Show this screen.
+  --spec=<path>                 YAML spec of the data.
+  --to=<path>                   Output file, or - for standard output.
+  --to-format=<format>          Output format: parquet, csv, ndjson, avro, ipc or feather. Detected from the extension by default.
+  --rows=<n>                    Number of rows, overriding the spec.
+  --seed=<n>                    Seed, overriding the spec.
+  --no-tui                      Log progress lines instead of the live progress view.
+`
+
+// Generate runs the generate command with the given arguments, the first
+// of which is the command name.
+func Generate(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(generateUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	specPath, _ := arguments.String("--spec")
+	to, _ := arguments.String("--to")
+	toFormat, _ := arguments.String("--to-format")
+	noTUI, _ := arguments.Bool("--no-tui")
+
+	spec, err := datagen.LoadSpec(specPath)
+	if err != nil {
+		return err
+	}
+	if v, _ := arguments.String("--rows"); v != "" {
+		if spec.Rows, err = strconv.ParseInt(v, 10, 64); err != nil || spec.Rows < 0 {
+			return fmt.Errorf("invalid --rows")
+		}
+	}
+	if v, _ := arguments.String("--seed"); v != "" {
+		if spec.Seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("invalid --seed")
+		}
+	}
+	generator, err := datagen.NewGenerator(spec, nil)
+	if err != nil {
+		return err
+	}
+	writer, err := converter.CreateOutput(ctx, to, toFormat, generator.Schema())
+	if err != nil {
+		return err
+	}
+
+	p := pipeline.NewDataPipeline(generator, writer).WithMonitor(ui.NewProgressMonitor("Generate", os.Stderr, noTUI))
+	metrics, err := p.Start(ctx)
+	if err == nil {
+		err = <-p.Done()
+	}
+	if err != nil {
+		if metrics != "" {
+			fmt.Fprintf(os.Stderr, "Generation failed. Summary: %s\n", metrics)
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Generation completed. Summary: %s\n", metrics)
+	return nil
+}
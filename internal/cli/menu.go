@@ -33,6 +33,7 @@ package cli
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/arrowarc/arrowarc/internal/ui"
 	"github.com/charmbracelet/bubbles/list"
@@ -62,6 +63,7 @@ func initialModel() model {
 		item{title: "CSV to JSON", desc: "Convert CSV to JSON"},
 		item{title: "Parquet to JSON", desc: "Convert Parquet to JSON"},
 		item{title: "Rewrite Parquet", desc: "Rewrite a Parquet file"},
+		item{title: "Split Parquet", desc: "Split a Parquet file into smaller files"},
 		item{title: "Run Flight Tests", desc: "Execute Arrow Flight tests"},
 		item{title: "Avro to Parquet", desc: "Convert Avro to Parquet"},
 		item{title: "Help", desc: "Show help"},
@@ -151,7 +153,10 @@ func RunMenu() error {
 				return nil
 			}
 			if m.choice != "" {
-				err := ExecuteCommand(ctx, m.choice)
+				// Interrupting stops the command and returns to the menu.
+				cmdCtx, stop := ui.NotifyInterrupt(ctx)
+				err := ExecuteCommand(cmdCtx, m.choice, ui.NewProgressMonitor(m.choice, os.Stderr, false))
+				stop()
 				if err != nil {
 					fmt.Printf("Error executing command: %v\n", err)
 				}
@@ -0,0 +1,295 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	grpcsource "github.com/arrowarc/arrowarc/integrations/grpc"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/history"
+	"github.com/arrowarc/arrowarc/pkg/preview"
+	"github.com/docopt/docopt-go"
+)
+
+const previewUsage = `Print rows of a Parquet, CSV, Avro, Arrow IPC or Feather file, or of a DuckDB query.
+Files may be http(s) URLs. A <source> of - reads CSV, Avro or an Arrow IPC stream from standard input; set --format.
+A <source> of grpc://host:port/pkg.Service/Method calls that RPC and reads its responses, using server reflection.
+A <source> of ws:// or wss://, or an http(s) URL with --format events for server-sent events, reads a stream of JSON events.
+
+Usage:
+  arrowarc head [options] [<source>]
+  arrowarc tail [options] [<source>]
+  arrowarc cat [options] [<source>]
+  arrowarc head -h | --help
+
+Options:
+  -h --help                     Show this screen.
+  -n <rows> --rows=<rows>       Number of rows to print [default: 10].
+  --columns=<col1,col2,...>     Columns to print, in order.
+  --json                        Print one JSON object per row instead of a table.
+  --format=<format>             Source format: parquet, csv, avro, ipc, feather, protobuf, events or duckdb. Detected from the extension by default.
+  --query=<sql>                 SQL to run against the DuckDB database <source>, or an in-memory database.
+  --delimiter=<char>            Delimiter of a CSV source [default: ,].
+  --no-header                   The CSV source has no header row.
+  --proto-descriptors=<path>    FileDescriptorSet describing protobuf messages (protoc --include_imports --descriptor_set_out).
+  --proto-message=<name>        Fully qualified name of the message type of a protobuf source.
+  --proto-reflection=<target>   grpc:// or grpcs:// server to fetch the protobuf message descriptor from by reflection.
+  --grpc-request=<json>         Request message of a grpc:// source, in protobuf JSON.
+  --max-messages=<n>            Stop a grpc:// or event stream source after n responses or events.
+  --flush-interval=<duration>   Emit partial records of a grpc:// or event stream source after this long, e.g. 5s.
+  --subscribe=<message>         Message to send once a ws:// or wss:// source is open.
+  --offset=<rows>               Skip the first rows of the source.
+  --limit=<rows>                Read at most this many rows of the source.
+  --max-width=<n>               Truncate table cells longer than n characters [default: 40].
+`
+
+// Preview runs the head, tail and cat commands with the given arguments,
+// the first of which is the command name.
+func Preview(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(previewUsage, argv, "")
+	if err != nil {
+		return err
+	}
+
+	source, _ := arguments.String("<source>")
+	rows, err := arguments.Int("--rows")
+	if err != nil || rows < 0 {
+		return fmt.Errorf("invalid number of rows")
+	}
+	columns, _ := arguments.String("--columns")
+	asJSON, _ := arguments.Bool("--json")
+	maxWidth, err := arguments.Int("--max-width")
+	if err != nil {
+		return fmt.Errorf("invalid --max-width: %w", err)
+	}
+	sourceOpts, err := sourceOptions(arguments)
+	if err != nil {
+		return err
+	}
+	if source == "" && sourceOpts.Query == "" {
+		return fmt.Errorf("a source file or --query is required")
+	}
+
+	reader, err := integrations.OpenSource(ctx, source, sourceOpts)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	opts := preview.Options{Format: preview.Table, MaxWidth: maxWidth}
+	if asJSON {
+		opts.Format = preview.JSON
+	}
+	if columns != "" {
+		opts.Columns = strings.Split(columns, ",")
+	}
+
+	switch argv[0] {
+	case "head":
+		return preview.Head(reader, int64(rows), os.Stdout, opts)
+	case "tail":
+		return preview.Tail(reader, int64(rows), os.Stdout, opts)
+	default:
+		return preview.Cat(reader, os.Stdout, opts)
+	}
+}
+
+// sourceOptions reads the --format, --query, --delimiter, --no-header,
+// --offset and --limit options shared by the commands that open a source,
+// and the HTTP credentials of the environment.
+func sourceOptions(arguments docopt.Opts) (*integrations.SourceOptions, error) {
+	format, _ := arguments.String("--format")
+	query, _ := arguments.String("--query")
+	delimiter, _ := arguments.String("--delimiter")
+	noHeader, _ := arguments.Bool("--no-header")
+	descriptors, _ := arguments.String("--proto-descriptors")
+	message, _ := arguments.String("--proto-message")
+	reflection, _ := arguments.String("--proto-reflection")
+	request, _ := arguments.String("--grpc-request")
+	subscribe, _ := arguments.String("--subscribe")
+	var maxMessages int
+	if v, _ := arguments.String("--max-messages"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid --max-messages")
+		}
+		maxMessages = n
+	}
+	var flushInterval time.Duration
+	if v, _ := arguments.String("--flush-interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid --flush-interval")
+		}
+		flushInterval = d
+	}
+	var window [2]int64
+	for i, name := range []string{"--offset", "--limit"} {
+		if v, _ := arguments.String(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			window[i] = n
+		}
+	}
+	if delimiter == `\t` {
+		delimiter = "\t"
+	}
+	if len(delimiter) != 1 {
+		return nil, fmt.Errorf("the delimiter must be a single character")
+	}
+	httpOpts, err := integrations.NewHTTPOptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &integrations.SourceOptions{
+		Format: format,
+		Query:  query,
+		CSV: csvschema.CSVReadOptions{
+			Delimiter: rune(delimiter[0]),
+			HasHeader: !noHeader,
+		},
+		Protobuf: integrations.ProtobufReadOptions{
+			DescriptorSet: descriptors,
+			Reflection:    reflection,
+			MessageName:   message,
+		},
+		GRPC: grpcsource.StreamOptions{
+			Request:       request,
+			MaxMessages:   int64(maxMessages),
+			FlushInterval: flushInterval,
+		},
+		Events: integrations.EventReadOptions{
+			Subscribe:     subscribe,
+			MaxEvents:     int64(maxMessages),
+			FlushInterval: flushInterval,
+		},
+		Offset: window[0],
+		Limit:  window[1],
+		HTTP:   httpOpts,
+	}, nil
+}
+
+// RunArgs runs the command named by the first argument. Options before
+// the command apply to all commands: --debug-alloc turns on the debug
+// allocator, as ARROWARC_DEBUG_ALLOC=1 does, so that pipelines log the
+// buffers they leave unreleased; --log-level=<level> and --log-json set
+// up logging, as ARROWARC_LOG_LEVEL and ARROWARC_LOG_FORMAT=json do;
+// --history=<path> records the pipeline runs of the command in a history
+// file, as ARROWARC_HISTORY does.
+func RunArgs(ctx context.Context, argv []string) (err error) {
+	logLevel, logFormat := os.Getenv(logLevelEnv), os.Getenv(logFormatEnv)
+	historyPath := os.Getenv(historyEnv)
+	var pprofAddr, traceOut string
+global:
+	for len(argv) > 0 {
+		switch arg := argv[0]; {
+		case arg == "--debug-alloc":
+			pool.SetDebug(true)
+		case arg == "--log-json":
+			logFormat = "json"
+		case strings.HasPrefix(arg, "--log-level="):
+			logLevel = strings.TrimPrefix(arg, "--log-level=")
+		case strings.HasPrefix(arg, "--history="):
+			historyPath = strings.TrimPrefix(arg, "--history=")
+		case strings.HasPrefix(arg, "--pprof-addr="):
+			pprofAddr = strings.TrimPrefix(arg, "--pprof-addr=")
+		case strings.HasPrefix(arg, "--trace-out="):
+			traceOut = strings.TrimPrefix(arg, "--trace-out=")
+		default:
+			break global
+		}
+		argv = argv[1:]
+	}
+	if err := setupLogging(logLevel, logFormat); err != nil {
+		return err
+	}
+	if len(argv) == 0 {
+		return Help()
+	}
+	stopProfiling, err := startProfiling(pprofAddr, traceOut)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if stopErr := stopProfiling(); err == nil {
+			err = stopErr
+		}
+	}()
+	if historyPath != "" {
+		store, err := history.Open(historyPath)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		ctx = history.NewContext(ctx, store)
+	}
+	switch argv[0] {
+	case "head", "tail", "cat":
+		return Preview(ctx, argv)
+	case "schema":
+		return Schema(ctx, argv)
+	case "convert":
+		return Convert(ctx, argv)
+	case "diff":
+		return Diff(ctx, argv)
+	case "generate":
+		return Generate(ctx, argv)
+	case "bench":
+		return Bench(ctx, argv)
+	case "tpch", "tpcds":
+		return TPC(ctx, argv)
+	case "sql":
+		return SQL(ctx, argv)
+	case "flightsql":
+		return FlightSQL(ctx, argv)
+	case "relay":
+		return Relay(ctx, argv)
+	case "watch":
+		return Watch(ctx, argv)
+	case "serve":
+		return Serve(ctx, argv)
+	case "runs":
+		return Runs(ctx, argv)
+	case "-h", "--help", "help":
+		return Help()
+	default:
+		return fmt.Errorf("unknown command %q", argv[0])
+	}
+}
@@ -0,0 +1,215 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	flightrelay "github.com/arrowarc/arrowarc/integrations/flight"
+	"github.com/arrowarc/arrowarc/internal/ui"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/endpoints"
+	"github.com/arrowarc/arrowarc/pkg/preview"
+	"github.com/docopt/docopt-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const relayUsage = `Stream sources between arrowarc instances over Arrow Flight.
+
+relay serve streams each <name=source> to the Flight DoGet calls naming it,
+opening the source anew for each call, until interrupted. A source is a
+file or an endpoint URI, such as duckdb://warehouse.db?table=orders. relay get reads a
+source of a relay and prints it as a table, or writes it to --out. Records
+stay in Arrow format end to end; --compression compresses them with LZ4 or
+Zstd on the wire. Another arrowarc reads the same source as the endpoint
+flight://host:port/<name>.
+
+Usage:
+  arrowarc relay serve [options] <name=source>...
+  arrowarc relay get [options] --source=<name> [--header=<name:value>...]
+  arrowarc relay -h | --help
+
+Options:
+  -h --help                     Show this screen.
+  --addr=<host:port>            Address to listen on, or of the relay to read from [default: localhost:8815].
+  --compression=<codec>         Codec of the record batches: none, lz4 or zstd. The server's is the default of calls that choose none.
+  --source=<name>               Source to read.
+  --out=<path>                  Output file, or - for standard output.
+  --out-format=<format>         Output format: parquet, csv, ndjson, avro, ipc or feather. Detected from the extension by default.
+  --header=<name:value>         Header sent with the call, such as "authorization: Bearer <token>".
+  --tls                         Connect with TLS, verifying the server against the system roots.
+  --max-width=<n>               Truncate table cells longer than n characters [default: 40].
+  --no-tui                      Log progress lines instead of the live progress view.
+`
+
+// Relay runs the relay command with the given arguments, the first of
+// which is the command name.
+func Relay(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(relayUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	if serve, _ := arguments.Bool("serve"); serve {
+		return relayServe(ctx, arguments)
+	}
+	return relayGet(ctx, arguments)
+}
+
+// relayServe serves the sources named by the arguments until interrupted.
+func relayServe(ctx context.Context, arguments docopt.Opts) error {
+	addr, _ := arguments.String("--addr")
+	compression, _ := arguments.String("--compression")
+	specs, _ := arguments["<name=source>"].([]string)
+	httpOpts, err := integrations.NewHTTPOptionsFromEnv()
+	if err != nil {
+		return err
+	}
+
+	sources := make(map[string]flightrelay.RelaySource, len(specs))
+	for _, spec := range specs {
+		name, path, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || path == "" {
+			return fmt.Errorf("invalid source %q, expected name=source", spec)
+		}
+		if _, dup := sources[name]; dup {
+			return fmt.Errorf("source %q named twice", name)
+		}
+		open := func(ctx context.Context) (flightrelay.RelayReader, error) {
+			reader, err := endpoints.NewReader(ctx, path, &endpoints.Options{HTTP: httpOpts})
+			if err != nil {
+				return nil, err
+			}
+			relayReader, ok := reader.(flightrelay.RelayReader)
+			if !ok {
+				reader.Close()
+				return nil, fmt.Errorf("%s has no schema to relay", path)
+			}
+			return relayReader, nil
+		}
+		// Open the source once, so a missing file fails now rather than
+		// on the first call.
+		reader, err := open(ctx)
+		if err != nil {
+			return fmt.Errorf("source %s: %w", name, err)
+		}
+		reader.Close()
+		sources[name] = open
+	}
+	relay, err := flightrelay.NewFlightRelayServer(sources, &flightrelay.FlightRelayServerOptions{Compression: compression})
+	if err != nil {
+		return err
+	}
+
+	server := flight.NewServerWithMiddleware(nil)
+	if err := server.Init(addr); err != nil {
+		return err
+	}
+	server.RegisterFlightService(relay)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- server.Serve() }()
+	fmt.Fprintf(os.Stderr, "Relaying %d sources on %s\n", len(sources), server.Addr())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	server.Shutdown()
+	return nil
+}
+
+// relayGet reads a source of a relay.
+func relayGet(ctx context.Context, arguments docopt.Opts) error {
+	addr, _ := arguments.String("--addr")
+	source, _ := arguments.String("--source")
+	compression, _ := arguments.String("--compression")
+	out, _ := arguments.String("--out")
+	outFormat, _ := arguments.String("--out-format")
+	useTLS, _ := arguments.Bool("--tls")
+	noTUI, _ := arguments.Bool("--no-tui")
+	headers, _ := arguments["--header"].([]string)
+	maxWidth, err := arguments.Int("--max-width")
+	if err != nil {
+		return fmt.Errorf("invalid --max-width: %w", err)
+	}
+
+	opts := &flightrelay.FlightRelayReadOptions{Compression: compression, Headers: map[string]string{}}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid --header %q, expected name:value", header)
+		}
+		opts.Headers[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	if useTLS {
+		opts.DialOptions = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))}
+	}
+
+	reader, err := flightrelay.NewFlightRelayReader(ctx, addr, source, opts)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		defer reader.Close()
+		return preview.Cat(reader, os.Stdout, preview.Options{Format: preview.Table, MaxWidth: maxWidth})
+	}
+
+	writer, err := converter.CreateOutput(ctx, out, outFormat, reader.Schema())
+	if err != nil {
+		reader.Close()
+		return err
+	}
+	p := pipeline.NewDataPipeline(reader, writer).WithMonitor(ui.NewProgressMonitor("Relay", os.Stderr, noTUI))
+	metrics, err := p.Start(ctx)
+	if err == nil {
+		err = <-p.Done()
+	}
+	if err != nil {
+		if metrics != "" {
+			fmt.Fprintf(os.Stderr, "Relay failed. Summary: %s\n", metrics)
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Relay completed. Summary: %s\n", metrics)
+	return nil
+}
@@ -0,0 +1,168 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/arrowarc/arrowarc/pkg/history"
+	"github.com/docopt/docopt-go"
+)
+
+const historyEnv = "ARROWARC_HISTORY"
+
+const runsUsage = `Inspect the history of pipeline runs.
+
+Runs are recorded when --history=<path> is passed before a command, or ARROWARC_HISTORY
+is set: to a SQLite database if the path ends in .db, .sqlite or .sqlite3, to a file of
+JSON lines otherwise. A run ID may be shortened to a prefix naming a single run.
+
+Usage:
+  arrowarc runs list [options]
+  arrowarc runs show [options] <id>
+  arrowarc runs -h | --help
+
+Options:
+  -h --help          Show this screen.
+  --history=<path>   History file to read, the one runs are recorded to by default.
+  --limit=<n>        List at most this many runs, the latest first [default: 20].
+  --failed           List failed runs only.
+  --json             Print runs as JSON.
+`
+
+// Runs runs the runs command with the given arguments, the first of which
+// is the command name.
+func Runs(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(runsUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	store := history.FromContext(ctx)
+	if path, _ := arguments.String("--history"); path != "" {
+		if store, err = history.Open(path); err != nil {
+			return err
+		}
+		defer store.Close()
+	}
+	if store == nil {
+		return fmt.Errorf("no history to read: pass --history=<path> or set %s", historyEnv)
+	}
+	asJSON, _ := arguments.Bool("--json")
+
+	if show, _ := arguments.Bool("show"); show {
+		id, _ := arguments.String("<id>")
+		run, err := store.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			return printJSON(run)
+		}
+		printRun(run)
+		return nil
+	}
+
+	limit, err := arguments.Int("--limit")
+	if err != nil || limit < 0 {
+		return fmt.Errorf("invalid --limit")
+	}
+	opts := history.ListOptions{Limit: limit}
+	if failed, _ := arguments.Bool("--failed"); failed {
+		opts.Outcome = history.Failed
+	}
+	runs, err := store.List(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(runs)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tCOMMAND\tOUTCOME\tRECORDS\tDURATION\tSOURCE\tDESTINATION")
+	for _, run := range runs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n", shortID(run.ID), run.StartTime.Local().Format(time.DateTime),
+			orDash(run.Command), run.Outcome, run.Records, run.Duration().Round(time.Millisecond), orDash(run.Source), orDash(run.Destination))
+	}
+	return w.Flush()
+}
+
+// printRun prints the fields of run, then its report.
+func printRun(run history.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, field := range [][2]string{
+		{"ID", run.ID},
+		{"Command", orDash(run.Command)},
+		{"Source", orDash(run.Source)},
+		{"Destination", orDash(run.Destination)},
+		{"Config hash", orDash(run.ConfigHash)},
+		{"Outcome", run.Outcome},
+		{"Error", orDash(run.Error)},
+		{"Started", run.StartTime.Local().Format(time.RFC3339)},
+		{"Duration", run.Duration().Round(time.Millisecond).String()},
+		{"Records", fmt.Sprint(run.Records)},
+		{"Bytes", fmt.Sprint(run.Bytes)},
+	} {
+		fmt.Fprintf(w, "%s:\t%s\n", field[0], field[1])
+	}
+	w.Flush()
+	if len(run.Report) > 0 {
+		fmt.Println("Report:")
+		var report any
+		if json.Unmarshal(run.Report, &report) == nil {
+			printJSON(report)
+		}
+	}
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// shortID returns the first 8 characters of a run ID, enough to name it.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,145 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	bigquery "github.com/arrowarc/arrowarc/integrations/bigquery"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pkg/arrowschema"
+	"github.com/docopt/docopt-go"
+)
+
+const bigQueryScheme = "bigquery://"
+
+const schemaUsage = `Print the Arrow schema of a source, or compare the schemas of two sources.
+
+A source is a Parquet, CSV, Avro, Arrow IPC or Feather file, a DuckDB database with
+--query, a BigQuery table written as bigquery://project/dataset/table, the results
+of a BigQuery --query run in bigquery://project, or the responses of an RPC
+written as grpc://host:port/pkg.Service/Method.
+CSV schemas are inferred from the data.
+
+Usage:
+  arrowarc schema [options] <source>
+  arrowarc schema diff [options] <source> <other>
+  arrowarc schema -h | --help
+
+Options:
+  -h --help                     Show this screen.
+  --json                        Print JSON instead of text.
+  --format=<format>             Source format: parquet, csv, avro, ipc, feather, protobuf or duckdb. Detected from the extension by default.
+  --query=<sql>                 SQL to run against DuckDB database or BigQuery project sources.
+  --delimiter=<char>            Delimiter of CSV sources [default: ,].
+  --no-header                   CSV sources have no header row.
+  --proto-descriptors=<path>    FileDescriptorSet describing protobuf messages (protoc --include_imports --descriptor_set_out).
+  --proto-message=<name>        Fully qualified name of the message type of a protobuf source.
+  --proto-reflection=<target>   grpc:// or grpcs:// server to fetch the protobuf message descriptor from by reflection.
+`
+
+// Schema runs the schema command with the given arguments, the first of
+// which is the command name.
+func Schema(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(schemaUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	source, _ := arguments.String("<source>")
+	asJSON, _ := arguments.Bool("--json")
+	diff, _ := arguments.Bool("diff")
+	opts, err := sourceOptions(arguments)
+	if err != nil {
+		return err
+	}
+
+	schema, err := sourceSchema(ctx, source, opts)
+	if err != nil {
+		return err
+	}
+	if !diff {
+		if asJSON {
+			return arrowschema.WriteJSON(os.Stdout, schema)
+		}
+		return arrowschema.WriteText(os.Stdout, schema)
+	}
+
+	otherSource, _ := arguments.String("<other>")
+	other, err := sourceSchema(ctx, otherSource, opts)
+	if err != nil {
+		return err
+	}
+	changes := arrowschema.Diff(schema, other)
+	if asJSON {
+		return arrowschema.WriteDiffJSON(os.Stdout, changes)
+	}
+	if len(changes) == 0 {
+		fmt.Println("Schemas match.")
+		return nil
+	}
+	return arrowschema.WriteDiffText(os.Stdout, changes)
+}
+
+// sourceSchema returns the schema of source without reading its rows,
+// where the format allows.
+func sourceSchema(ctx context.Context, source string, opts *integrations.SourceOptions) (*arrow.Schema, error) {
+	if table, ok := strings.CutPrefix(source, bigQueryScheme); ok {
+		parts := strings.Split(strings.TrimSuffix(table, "/"), "/")
+		if len(parts) != 3 && !(len(parts) == 1 && opts.Query != "") {
+			return nil, fmt.Errorf("invalid BigQuery source %q, expected %sproject/dataset/table or %sproject with --query", source, bigQueryScheme, bigQueryScheme)
+		}
+		client, err := bigquery.NewBigQueryReadClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var reader *bigquery.BigQueryReader
+		if len(parts) == 1 {
+			reader, err = client.NewBigQueryQueryReader(ctx, parts[0], opts.Query)
+		} else {
+			reader, err = client.NewBigQueryReader(ctx, parts[0], parts[1], parts[2])
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return reader.Schema()
+	}
+
+	reader, err := integrations.OpenSource(ctx, source, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return reader.Schema(), nil
+}
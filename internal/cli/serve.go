@@ -0,0 +1,144 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/health"
+	"github.com/arrowarc/arrowarc/pkg/server"
+	"github.com/docopt/docopt-go"
+)
+
+const serveUsage = `Serve the pipeline control-plane API over HTTP.
+
+Clients submit runs of conversion steps as JSON to POST /runs, follow them
+with GET /runs/{id}, cancel them with DELETE /runs/{id} and fetch their
+reports from GET /runs/{id}/report. Runs read and write paths on this
+machine, so only listen on addresses trusted clients can reach.
+Runs until interrupted.
+
+GET /healthz answers while the server runs, and GET /readyz while the
+dependencies named by --ready-url and --ready-tcp are reachable and the
+server is not shutting down, for use as liveness and readiness probes.
+
+Usage:
+  arrowarc serve [options] [--ready-url=<url>...] [--ready-tcp=<host:port>...]
+  arrowarc serve -h | --help
+
+Options:
+  -h --help                Show this screen.
+  --addr=<host:port>       Address to listen on [default: localhost:8080].
+  --max-finished=<n>       Number of finished runs kept for queries [default: 100].
+  --ready-url=<url>        Only be ready while a GET of <url> succeeds, such as
+                           a catalog's config endpoint.
+  --ready-tcp=<host:port>  Only be ready while <host:port>, such as a database,
+                           accepts connections.
+  --config=<path>          Workflow config whose resources.spill_threshold,
+                           settings.temp_directory and settings.http apply to the
+                           steps of every run.
+`
+
+// Serve runs the serve command with the given arguments, the first of
+// which is the command name.
+func Serve(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(serveUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	addr, _ := arguments.String("--addr")
+	maxFinished, err := arguments.Int("--max-finished")
+	if err != nil || maxFinished <= 0 {
+		return fmt.Errorf("invalid --max-finished")
+	}
+
+	checks := map[string]health.Check{}
+	urls, _ := arguments["--ready-url"].([]string)
+	for _, url := range urls {
+		checks[url] = health.HTTP(url)
+	}
+	addrs, _ := arguments["--ready-tcp"].([]string)
+	for _, addr := range addrs {
+		checks["tcp "+addr] = health.Dial(addr)
+	}
+
+	opts := &server.Options{MaxFinishedRuns: maxFinished, Checks: checks}
+	if path, _ := arguments.String("--config"); path != "" {
+		cfg, err := config.ParseConfig(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+		// Validate checked the threshold parses.
+		opts.SpillThreshold, _ = cfg.SpillThresholdBytes()
+		opts.SpillDir = cfg.Workflow.Settings.TempDirectory
+		if opts.HTTP, err = integrations.HTTPOptionsFromConfig(cfg.Workflow.Settings.HTTP); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+	}
+
+	s := server.New(opts)
+	defer s.Close()
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- httpServer.ListenAndServe() }()
+	fmt.Fprintf(os.Stderr, "Serving on http://%s\n", addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	s.Health().Drain()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	converter "github.com/arrowarc/arrowarc/converter"
+	duckdb "github.com/arrowarc/arrowarc/integrations/duckdb"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pkg/preview"
+	"github.com/docopt/docopt-go"
+	"github.com/mattn/go-isatty"
+)
+
+const sqlUsage = `Query files with SQL in an embedded DuckDB database.
+
+Each <input> is registered under the name of its file without the extension, or as
+name=path. Parquet, CSV and JSON files become views DuckDB reads as they are queried;
+Arrow IPC, Feather and Avro files are loaded into tables. Without -e, statements are
+read from standard input, each ending with a semicolon: .tables lists the registered
+names and .quit exits.
+
+Usage:
+  arrowarc sql [options] [<input>...]
+  arrowarc sql -h | --help
+
+Options:
+  -h --help                     Show this screen.
+  -e <sql> --execute=<sql>      Run a statement, print its results and exit.
+  --output=<format>             Results as a table, or as csv, ndjson, parquet, ipc, feather or avro. A table by default, or detected from the extension of --to.
+  --to=<path>                   File to write the results of -e to, instead of standard output.
+  --max-width=<n>               Truncate table cells longer than n characters [default: 40].
+`
+
+// sqlName matches the names inputs can be registered under with name=path.
+var sqlName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQL runs the sql command with the given arguments, the first of which is
+// the command name.
+func SQL(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(sqlUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	inputs, _ := arguments["<input>"].([]string)
+	statement, _ := arguments.String("--execute")
+	output, _ := arguments.String("--output")
+	to, _ := arguments.String("--to")
+	maxWidth, err := arguments.Int("--max-width")
+	if err != nil {
+		return fmt.Errorf("invalid --max-width: %w", err)
+	}
+	if to != "" && statement == "" {
+		return fmt.Errorf("--to requires -e")
+	}
+
+	session, err := duckdb.NewSQLSession(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	for _, input := range inputs {
+		if err := registerInput(ctx, session, input); err != nil {
+			return err
+		}
+	}
+
+	run := func(sql string) error {
+		result, err := session.Query(sql)
+		if err != nil {
+			return err
+		}
+		defer result.Close()
+		return writeSQLResult(ctx, result, output, to, maxWidth)
+	}
+	if statement != "" {
+		return run(statement)
+	}
+	return sqlREPL(os.Stdin, os.Stderr, session, run)
+}
+
+// registerInput registers the file named by an <input> argument.
+func registerInput(ctx context.Context, session *duckdb.SQLSession, input string) error {
+	name, path, ok := strings.Cut(input, "=")
+	if !ok || !sqlName.MatchString(name) {
+		path = input
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	switch strings.ToLower(integrations.FileExt(path)) {
+	case ".json", ".jsonl", ".ndjson":
+		return session.RegisterFile(name, path, duckdb.SQLJSON)
+	}
+	format, err := integrations.DetectSourceFormat(path)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case integrations.SourceParquet:
+		return session.RegisterFile(name, path, duckdb.SQLParquet)
+	case integrations.SourceCSV:
+		return session.RegisterFile(name, path, duckdb.SQLCSV)
+	case integrations.SourceIPC, integrations.SourceFeather, integrations.SourceAvro:
+		reader, err := integrations.OpenSource(ctx, path, &integrations.SourceOptions{Format: format})
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		if err := session.RegisterSource(name, reader); err != nil {
+			return fmt.Errorf("failed to load %s: %w", path, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot query %s files", format)
+}
+
+// writeSQLResult prints result as a table, or writes it in format to path.
+func writeSQLResult(ctx context.Context, result *duckdb.SQLResult, format, path string, maxWidth int) error {
+	schema := result.Schema()
+	if schema == nil || schema.NumFields() == 0 {
+		return nil
+	}
+	if format == "table" || (format == "" && path == "") {
+		return preview.Cat(result, os.Stdout, preview.Options{Format: preview.Table, MaxWidth: maxWidth})
+	}
+	if path == "" {
+		path = integrations.StdioPath
+	}
+	w, err := converter.CreateOutput(ctx, path, format, schema)
+	if err != nil {
+		return err
+	}
+	for {
+		rec, err := result.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			w.Close()
+			return err
+		}
+		err = w.Write(rec)
+		rec.Release()
+		if err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// sqlREPL runs the statements read from in, printing prompts and errors to
+// errOut. Prompts are only printed when in is a terminal.
+func sqlREPL(in *os.File, errOut io.Writer, session *duckdb.SQLSession, run func(sql string) error) error {
+	interactive := isatty.IsTerminal(in.Fd())
+	prompt := func(continued bool) {
+		if !interactive {
+			return
+		}
+		if continued {
+			fmt.Fprint(errOut, "   ...> ")
+		} else {
+			fmt.Fprint(errOut, "sql> ")
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var pending strings.Builder
+	prompt(false)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if pending.Len() == 0 && strings.HasPrefix(line, ".") {
+			switch line {
+			case ".quit", ".exit":
+				return nil
+			case ".tables":
+				tables, err := session.Tables()
+				if err != nil {
+					fmt.Fprintln(errOut, "Error:", err)
+				}
+				for _, table := range tables {
+					fmt.Println(table)
+				}
+			default:
+				fmt.Fprintf(errOut, "Error: unknown command %s\n", line)
+			}
+			prompt(false)
+			continue
+		}
+		if line != "" {
+			pending.WriteString(line)
+			pending.WriteByte('\n')
+		}
+		if !strings.HasSuffix(line, ";") {
+			prompt(pending.Len() > 0)
+			continue
+		}
+		if err := run(pending.String()); err != nil {
+			fmt.Fprintln(errOut, "Error:", err)
+		}
+		pending.Reset()
+		prompt(false)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(pending.String()) != "" {
+		return run(pending.String())
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	duckdb "github.com/arrowarc/arrowarc/integrations/duckdb"
+	"github.com/docopt/docopt-go"
+)
+
+const tpcUsage = `Generate TPC-H or TPC-DS benchmark data with DuckDB's tpch and tpcds extensions.
+
+Each table is written to <dir> as <table>.parquet, <table>.csv or <table>.arrow.
+
+Usage:
+  arrowarc tpch [options] --to=<dir>
+  arrowarc tpcds [options] --to=<dir>
+  arrowarc tpch -h | --help
+
+Options:
+  -h --help                     Show this screen.
+  --to=<dir>                    Directory to write the tables to.
+  --scale=<factor>              Scale factor, 1 being about 1 GB of TPC-H data [default: 1].
+  --format=<format>             Output format: parquet, csv or arrow [default: parquet].
+  --tables=<t1,t2,...>          Tables to write. All by default.
+  --compression=<codec>         Codec of Parquet files, e.g. snappy or zstd, or of Arrow files, lz4 or zstd.
+`
+
+// TPC runs the tpch and tpcds commands with the given arguments, the first
+// of which is the command name.
+func TPC(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(tpcUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	dir, _ := arguments.String("--to")
+	format, _ := arguments.String("--format")
+	tables, _ := arguments.String("--tables")
+	compression, _ := arguments.String("--compression")
+	scaleArg, _ := arguments.String("--scale")
+	scale, err := strconv.ParseFloat(scaleArg, 64)
+	if err != nil || scale <= 0 {
+		return fmt.Errorf("invalid --scale %q", scaleArg)
+	}
+
+	opts := &duckdb.TPCOptions{Benchmark: argv[0], ScaleFactor: scale, Format: format, Compression: compression}
+	if tables != "" {
+		opts.Tables = strings.Split(tables, ",")
+	}
+	paths, err := duckdb.GenerateTPC(ctx, dir, opts)
+	for _, path := range paths {
+		fmt.Fprintln(os.Stderr, "Wrote", path)
+	}
+	return err
+}
@@ -0,0 +1,149 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/ui"
+	"github.com/arrowarc/arrowarc/pkg/watch"
+	"github.com/docopt/docopt-go"
+)
+
+const watchUsage = `Watch a drop folder and convert each CSV, JSON, Avro or Parquet file that lands in it.
+
+Files are converted once they have stopped changing, then moved to the done
+directory, or to the failed directory if the conversion fails. A ledger of
+content hashes in the watched directory makes ingestion idempotent: a file
+with the same content as one already converted is moved to done untouched.
+Runs until interrupted.
+
+Usage:
+  arrowarc watch [options] --to=<dir> <dir>
+  arrowarc watch -h | --help
+
+Options:
+  -h --help                Show this screen.
+  --to=<dir>               Directory to write converted files to, named after the input.
+  --to-format=<format>     Output format: parquet, csv, ndjson, avro, ipc or feather [default: parquet].
+  --done=<dir>             Directory for converted files, <dir>/done by default.
+  --failed=<dir>           Directory for files that failed, <dir>/failed by default.
+  --ledger=<path>          Ledger file, <dir>/.arrowarc-ledger.jsonl by default.
+  --settle=<duration>      Time a file must go unchanged before it is converted [default: 1s].
+  --delimiter=<char>       Delimiter of CSV input [default: ,].
+  --no-header              The CSV input has no header row.
+  --chunk-size=<rows>      Number of rows per record [default: 1024].
+`
+
+// outputExtensions names the extension of the files written in each
+// output format.
+var outputExtensions = map[string]string{
+	integrations.SourceParquet: ".parquet",
+	integrations.SourceCSV:     ".csv",
+	converter.FormatNDJSON:     ".ndjson",
+	integrations.SourceAvro:    ".avro",
+	integrations.SourceIPC:     ".arrow",
+	integrations.SourceFeather: ".feather",
+}
+
+// Watch runs the watch command with the given arguments, the first of
+// which is the command name.
+func Watch(ctx context.Context, argv []string) error {
+	arguments, err := docopt.ParseArgs(watchUsage, argv, "")
+	if err != nil {
+		return err
+	}
+	dir, _ := arguments.String("<dir>")
+	to, _ := arguments.String("--to")
+	toFormat, _ := arguments.String("--to-format")
+	done, _ := arguments.String("--done")
+	failed, _ := arguments.String("--failed")
+	ledger, _ := arguments.String("--ledger")
+	settleArg, _ := arguments.String("--settle")
+	settle, err := time.ParseDuration(settleArg)
+	if err != nil || settle <= 0 {
+		return fmt.Errorf("invalid --settle")
+	}
+	chunkSize, err := arguments.Int("--chunk-size")
+	if err != nil || chunkSize <= 0 {
+		return fmt.Errorf("invalid --chunk-size")
+	}
+	ext, ok := outputExtensions[toFormat]
+	if !ok {
+		return fmt.Errorf("unsupported output format %q", toFormat)
+	}
+	sourceOpts, err := sourceOptions(arguments)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(to, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	convert := func(ctx context.Context, path string) error {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + ext
+		// Write to a hidden file first so that readers of the output
+		// directory never see a partial file.
+		tmp := filepath.Join(to, "."+name+".tmp")
+		_, err := converter.Convert(ctx, path, tmp, &converter.ConvertOptions{
+			ToFormat:  toFormat,
+			ChunkSize: int64(chunkSize),
+			CSV:       sourceOpts.CSV,
+		})
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return os.Rename(tmp, filepath.Join(to, name))
+	}
+
+	w, err := watch.New(dir, convert, &watch.Options{
+		DoneDir:    done,
+		FailedDir:  failed,
+		Ledger:     ledger,
+		SettleTime: settle,
+	})
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	ctx, stop := ui.NotifyInterrupt(ctx)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Watching %s, writing %s files to %s\n", dir, toFormat, to)
+	return w.Run(ctx)
+}
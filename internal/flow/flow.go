@@ -0,0 +1,257 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package flow runs one reader through a pipeline into one writer. It is
+// the engine shared by the arrowarc package and the converter functions.
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/history"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+)
+
+// Spec describes a run.
+type Spec struct {
+	// Open opens the reader.
+	Open func(ctx context.Context) (interfaces.Reader, error)
+	// Create creates the writer of records of schema. It is called before
+	// the pipeline starts with the reader's schema if the reader has one
+	// and no transformer runs, since the records written then have that
+	// schema. Otherwise it is called with the schema of the first record
+	// written. If nothing is written, it is called with the reader's
+	// schema as the transformers change it, unless OnEmpty says otherwise.
+	Create func(ctx context.Context, schema *arrow.Schema) (interfaces.Writer, error)
+	// Transformers rewrite the records between the reader and the writer.
+	Transformers []interfaces.Transformer
+	// OnEmpty says what to do when no rows are written; empty means
+	// pipeline.EmptyWrite.
+	OnEmpty pipeline.EmptyPolicy
+	// Monitor, if set, follows the pipeline.
+	Monitor pipeline.Monitor
+	// SpillThreshold, if positive, spills the records queued for a slower
+	// writer past that many bytes to files in SpillDir, as
+	// pipeline.DataPipeline.WithSpill does.
+	SpillThreshold int64
+	SpillDir       string
+	// Configure, if set, sets up the pipeline before it starts.
+	Configure func(p *pipeline.DataPipeline)
+	// History describes the run to the history store of the context, if
+	// it carries one, which records it along with its outcome and
+	// metrics.
+	History history.Run
+}
+
+// Run runs spec and returns the pipeline's report. The reader and the
+// writer are closed once it returns.
+func Run(ctx context.Context, spec Spec) (report string, err error) {
+	ctx, runID := logging.NewRun(ctx)
+	if store := history.FromContext(ctx); store != nil {
+		start := time.Now()
+		var metrics *pipeline.Metrics
+		defer func() {
+			record(ctx, store, spec.History, runID, start, metrics, report, err)
+		}()
+		spec.Configure = withMetrics(spec.Configure, &metrics)
+	}
+
+	reader, err := spec.Open(ctx)
+	if err != nil {
+		return "", err
+	}
+	schema := readerSchema(reader)
+	writeEmpty := spec.OnEmpty == "" || spec.OnEmpty == pipeline.EmptyWrite
+	lazy := &lazyWriter{ctx: ctx, create: spec.Create, writeEmpty: writeEmpty}
+	if writeEmpty {
+		lazy.schema = transformedSchema(schema, spec.Transformers)
+	}
+	if schema != nil && len(spec.Transformers) == 0 && writeEmpty {
+		if lazy.writer, err = spec.Create(ctx, schema); err != nil {
+			reader.Close()
+			return "", err
+		}
+	}
+
+	p := pipeline.NewDataPipeline(reader, lazy).WithTransformers(spec.Transformers...).WithMonitor(spec.Monitor)
+	if spec.SpillThreshold > 0 {
+		p.WithSpill(spec.SpillThreshold, spec.SpillDir)
+	}
+	if spec.Configure != nil {
+		spec.Configure(p)
+	}
+	report, err = p.Start(ctx)
+	if err != nil {
+		// The report tells how far the pipeline got before failing.
+		return report, fmt.Errorf("failed to start conversion pipeline: %w", err)
+	}
+	if err := <-p.Done(); err != nil {
+		return "", fmt.Errorf("pipeline encountered an error: %w", err)
+	}
+	// The pipeline does not report the errors of closing the writer, which
+	// creates it if nothing was written.
+	if lazy.err != nil {
+		return "", lazy.err
+	}
+	if !lazy.wrote && spec.OnEmpty == pipeline.EmptyFail {
+		return report, pipeline.ErrEmptyResult
+	}
+	return report, nil
+}
+
+// withMetrics returns configure, extended to keep the metrics of the
+// pipeline in metrics.
+func withMetrics(configure func(p *pipeline.DataPipeline), metrics **pipeline.Metrics) func(p *pipeline.DataPipeline) {
+	return func(p *pipeline.DataPipeline) {
+		*metrics = p.Metrics()
+		if configure != nil {
+			configure(p)
+		}
+	}
+}
+
+// record adds the run to store. Failing to record a run fails neither the
+// run nor those that follow, so the error is only logged.
+func record(ctx context.Context, store history.Store, run history.Run, runID string, start time.Time, metrics *pipeline.Metrics, report string, err error) {
+	run.ID = runID
+	run.StartTime, run.EndTime = start, time.Now()
+	run.Outcome = history.Succeeded
+	if err != nil {
+		run.Outcome, run.Error = history.Failed, err.Error()
+	}
+	if metrics != nil {
+		run.StartTime = metrics.StartTime
+		run.Records = atomic.LoadInt64(&metrics.RecordsProcessed)
+		run.Bytes = atomic.LoadInt64(&metrics.TotalBytes)
+	}
+	if json.Valid([]byte(report)) {
+		run.Report = json.RawMessage(report)
+	}
+	if err := store.Append(ctx, run); err != nil {
+		logging.FromContext(ctx).Warn("failed to record run in history", "error", err)
+	}
+}
+
+// transformedSchema returns the schema of the records transformers make
+// of records with schema, or schema if one of them does not tell.
+func transformedSchema(schema *arrow.Schema, transformers []interfaces.Transformer) *arrow.Schema {
+	if schema == nil {
+		return nil
+	}
+	out := schema
+	for _, t := range transformers {
+		st, ok := t.(interfaces.SchemaTransformer)
+		if !ok {
+			return schema
+		}
+		var err error
+		if out, err = st.Schema(out); err != nil {
+			return schema
+		}
+	}
+	return out
+}
+
+// readerSchema returns the schema of reader, or nil if it does not tell.
+func readerSchema(reader interfaces.Reader) *arrow.Schema {
+	if r, ok := reader.(interface{ Schema() *arrow.Schema }); ok {
+		return r.Schema()
+	}
+	return nil
+}
+
+// lazyWriter creates its writer for the first record written to it, unless
+// it was created up front. Records without rows do not create it; when no
+// rows are written, it is created on close with schema if writeEmpty is
+// set.
+type lazyWriter struct {
+	ctx        context.Context
+	create     func(ctx context.Context, schema *arrow.Schema) (interfaces.Writer, error)
+	schema     *arrow.Schema
+	writeEmpty bool
+	writer     interfaces.Writer
+	wrote      bool
+	closed     bool
+	err        error
+}
+
+func (w *lazyWriter) Name() string {
+	if named, ok := w.writer.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return "writer"
+}
+
+func (w *lazyWriter) Write(record arrow.Record) error {
+	if record.NumRows() > 0 {
+		w.wrote = true
+	}
+	if w.writer == nil && record.NumRows() == 0 {
+		if w.writeEmpty {
+			w.schema = record.Schema()
+		}
+		return nil
+	}
+	if w.writer == nil {
+		writer, err := w.create(w.ctx, record.Schema())
+		if err != nil {
+			w.err = err
+			return err
+		}
+		w.writer = writer
+	}
+	return w.writer.Write(record)
+}
+
+func (w *lazyWriter) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.writer == nil {
+		if w.err != nil || w.schema == nil {
+			// Nothing was written, and there is no schema to write.
+			return w.err
+		}
+		if w.writer, w.err = w.create(w.ctx, w.schema); w.err != nil {
+			return w.err
+		}
+	}
+	if err := w.writer.Close(); err != nil && w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
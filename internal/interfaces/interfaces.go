@@ -59,11 +59,77 @@ type Reader interface {
 	Close() error
 }
 
+// SizedReader is a Reader that knows how many rows it will return, letting
+// a pipeline estimate the time left. NumRows returns zero when unknown.
+type SizedReader interface {
+	Reader
+	NumRows() int64
+}
+
+// ByteSizedReader is a Reader over a file of known size that can tell how
+// many of its bytes it has consumed, letting a pipeline estimate how far it
+// is when the number of rows is unknown. BytesRead must be safe to call
+// while Read runs. Size returns zero when unknown.
+type ByteSizedReader interface {
+	Reader
+	BytesRead() int64
+	Size() int64
+}
+
+// AckingReader is a Reader over a source that redelivers its input until
+// it is acknowledged, such as a message subscription. A pipeline calls
+// Acknowledge with the number of records, counted from the first Read, whose
+// rows have reached the writer; final is true once the writer has also been
+// closed successfully, so everything it wrote is durable.
+type AckingReader interface {
+	Reader
+	Acknowledge(records int64, final bool) error
+}
+
+// PositionedReader is a Reader that can tell where in its source the record
+// last returned by Read, or the one Read failed on, came from, such as a
+// row group or a file, for a pipeline to include in its errors.
+type PositionedReader interface {
+	Reader
+	Position() string
+}
+
 type Writer interface {
 	Write(arrow.Record) error
 	Close() error
 }
 
+// Transformer rewrites records flowing from a Reader to a Writer. The
+// returned record is owned by the caller; a Transformer that passes its input
+// through must Retain it. Returning nil drops the record.
+type Transformer interface {
+	Transform(arrow.Record) (arrow.Record, error)
+}
+
+// FlushingTransformer is a Transformer that may hold records back, for
+// example to process them in windows. Flush returns what it still holds
+// once the input ends, or nil.
+type FlushingTransformer interface {
+	Transformer
+	Flush() (arrow.Record, error)
+}
+
+// SchemaTransformer is a Transformer that can tell the schema of the
+// records it makes of records with schema src, so that an empty output can
+// still be written with the right columns.
+type SchemaTransformer interface {
+	Transformer
+	Schema(src *arrow.Schema) (*arrow.Schema, error)
+}
+
+// CountingTransformer is a Transformer that keeps counters, such as rows
+// passed and rejected, to include in the pipeline metrics report.
+type CountingTransformer interface {
+	Transformer
+	Name() string
+	Counts() map[string]int64
+}
+
 type SourceSink interface {
 	Source
 	Sink
@@ -0,0 +1,224 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package memory
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// DebugAllocEnv enables the debug allocator when set to a true value, such
+// as 1.
+const DebugAllocEnv = "ARROWARC_DEBUG_ALLOC"
+
+// maxLeakFrames bounds the allocation stack kept for each buffer.
+const maxLeakFrames = 16
+
+var (
+	debugOn    atomic.Bool
+	debugMu    sync.Mutex
+	debugAlloc *DebugAllocator
+)
+
+func init() {
+	on, _ := strconv.ParseBool(os.Getenv(DebugAllocEnv))
+	debugOn.Store(on)
+}
+
+// SetDebug turns the debug allocator on or off. While it is on,
+// GetAllocator, NewGoAllocator and NewAllocator all return the shared
+// DebugAllocator, so that pipelines can report the buffers left unreleased.
+func SetDebug(on bool) {
+	debugOn.Store(on)
+}
+
+// Debug returns the shared DebugAllocator, or nil if debugging is off.
+func Debug() *DebugAllocator {
+	if !debugOn.Load() {
+		return nil
+	}
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	if debugAlloc == nil {
+		debugAlloc = NewDebugAllocator(memory.NewGoAllocator())
+	}
+	return debugAlloc
+}
+
+// allocation is a live buffer of a DebugAllocator.
+type allocation struct {
+	seq   uint64
+	size  int
+	stack []uintptr
+}
+
+// DebugAllocator wraps an allocator and remembers where each live buffer
+// was allocated, like memory.CheckedAllocator, so that buffers never
+// released can be traced to the code that allocated them.
+type DebugAllocator struct {
+	parent memory.Allocator
+
+	mu   sync.Mutex
+	seq  uint64
+	live map[uintptr]*allocation
+}
+
+var _ memory.Allocator = (*DebugAllocator)(nil)
+
+// NewDebugAllocator returns a DebugAllocator drawing from parent.
+func NewDebugAllocator(parent memory.Allocator) *DebugAllocator {
+	return &DebugAllocator{parent: parent, live: make(map[uintptr]*allocation)}
+}
+
+func bufferAddr(b []byte) uintptr {
+	return uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+}
+
+func (a *DebugAllocator) track(b []byte, size int) {
+	stack := make([]uintptr, maxLeakFrames)
+	// Skip runtime.Callers, track and the Allocate or Reallocate method.
+	stack = stack[:runtime.Callers(3, stack)]
+	a.mu.Lock()
+	a.seq++
+	a.live[bufferAddr(b)] = &allocation{seq: a.seq, size: size, stack: stack}
+	a.mu.Unlock()
+}
+
+func (a *DebugAllocator) untrack(b []byte) {
+	a.mu.Lock()
+	delete(a.live, bufferAddr(b))
+	a.mu.Unlock()
+}
+
+func (a *DebugAllocator) Allocate(size int) []byte {
+	b := a.parent.Allocate(size)
+	a.track(b, size)
+	return b
+}
+
+func (a *DebugAllocator) Reallocate(size int, b []byte) []byte {
+	a.untrack(b)
+	b = a.parent.Reallocate(size, b)
+	a.track(b, size)
+	return b
+}
+
+func (a *DebugAllocator) Free(b []byte) {
+	a.untrack(b)
+	a.parent.Free(b)
+}
+
+// Mark returns a position in the allocation sequence; pass it to Leaks to
+// only consider the buffers allocated after it.
+func (a *DebugAllocator) Mark() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.seq
+}
+
+// Leak is a group of unreleased buffers allocated from the same place.
+type Leak struct {
+	Buffers int
+	Bytes   int64
+	Stack   string
+}
+
+// Leaks returns the buffers allocated after mark and not yet released,
+// grouped by allocation stack, largest first.
+func (a *DebugAllocator) Leaks(mark uint64) []Leak {
+	a.mu.Lock()
+	stacks := make(map[string]*Leak)
+	var raw [][]uintptr
+	var sizes []int
+	for _, alloc := range a.live {
+		if alloc.seq > mark {
+			raw = append(raw, alloc.stack)
+			sizes = append(sizes, alloc.size)
+		}
+	}
+	a.mu.Unlock()
+
+	for i, pcs := range raw {
+		stack := formatStack(pcs)
+		leak, ok := stacks[stack]
+		if !ok {
+			leak = &Leak{Stack: stack}
+			stacks[stack] = leak
+		}
+		leak.Buffers++
+		leak.Bytes += int64(sizes[i])
+	}
+	leaks := make([]Leak, 0, len(stacks))
+	for _, leak := range stacks {
+		leaks = append(leaks, *leak)
+	}
+	sort.Slice(leaks, func(i, j int) bool {
+		if leaks[i].Bytes != leaks[j].Bytes {
+			return leaks[i].Bytes > leaks[j].Bytes
+		}
+		return leaks[i].Stack < leaks[j].Stack
+	})
+	return leaks
+}
+
+// formatStack renders pcs one "function file:line" frame per line, leaving
+// out the arrow memory package, whose frames are the same for every buffer.
+func formatStack(pcs []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/apache/arrow-go/v18/arrow/memory.") {
+			fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// FormatLeaks describes leaks for a log message.
+func FormatLeaks(leaks []Leak) string {
+	var sb strings.Builder
+	for _, leak := range leaks {
+		fmt.Fprintf(&sb, "%d unreleased buffers, %d bytes, allocated at:\n%s", leak.Buffers, leak.Bytes, leak.Stack)
+	}
+	return sb.String()
+}
@@ -48,12 +48,18 @@ func init() {
 
 // getAllocator retrieves an allocator from the pool
 func getAllocator() memory.Allocator {
+	if debug := Debug(); debug != nil {
+		return debug
+	}
 	// Get an allocator from the pool, or create a new one if the pool is empty
 	return memPool.Get().(memory.Allocator)
 }
 
 // putAllocator returns an allocator back to the pool
 func putAllocator(alloc memory.Allocator) {
+	if _, ok := alloc.(*DebugAllocator); ok {
+		return
+	}
 	// Reset or clean up the allocator if necessary before putting it back
 	memPool.Put(alloc)
 }
@@ -70,11 +76,17 @@ func PutAllocator(alloc memory.Allocator) {
 
 // NewGoAllocator creates a new Go allocator without using the pool
 func NewGoAllocator() memory.Allocator {
+	if debug := Debug(); debug != nil {
+		return debug
+	}
 	return memory.NewGoAllocator()
 }
 
 // NewAllocator returns the default allocator, which in this case is also a GoAllocator
 func NewAllocator() memory.Allocator {
+	if debug := Debug(); debug != nil {
+		return debug
+	}
 	return memory.DefaultAllocator
 }
 
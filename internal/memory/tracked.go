@@ -0,0 +1,184 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// ErrMemoryLimitExceeded is matched by the LimitError a TrackedAllocator
+// panics with when an allocation would exceed its budget.
+var ErrMemoryLimitExceeded = errors.New("memory limit exceeded")
+
+// LimitError describes an allocation refused by a TrackedAllocator.
+type LimitError struct {
+	Limit     int64
+	InUse     int64
+	Requested int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("memory limit of %d bytes exceeded: allocating %d bytes with %d bytes in use", e.Limit, e.Requested, e.InUse)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrMemoryLimitExceeded
+}
+
+// TrackedAllocator wraps an allocator, counting the bytes in use and their
+// peak. With a positive limit, an allocation that would take the bytes in
+// use over it panics with a *LimitError instead: the arrow Allocator
+// interface has no error return. Recover it with RecoverLimitError.
+type TrackedAllocator struct {
+	parent memory.Allocator
+	limit  int64
+
+	mu          sync.Mutex
+	inUse       int64
+	peak        int64
+	allocations int64
+	allocated   int64
+	err         *LimitError
+}
+
+var _ memory.Allocator = (*TrackedAllocator)(nil)
+
+// NewTrackedAllocator returns an allocator drawing from parent, or from the
+// default allocator if parent is nil, with a budget of limit bytes. A limit
+// of zero or less only counts.
+func NewTrackedAllocator(parent memory.Allocator, limit int64) *TrackedAllocator {
+	if parent == nil {
+		parent = memory.DefaultAllocator
+	}
+	return &TrackedAllocator{parent: parent, limit: limit}
+}
+
+// reserve accounts for delta more bytes, panicking if they do not fit, and
+// counts a new allocation if allocation is set.
+func (a *TrackedAllocator) reserve(delta int64, allocation bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if delta > 0 && a.limit > 0 && a.inUse+delta > a.limit {
+		err := &LimitError{Limit: a.limit, InUse: a.inUse, Requested: delta}
+		if a.err == nil {
+			a.err = err
+		}
+		panic(err)
+	}
+	a.inUse += delta
+	if a.inUse > a.peak {
+		a.peak = a.inUse
+	}
+	if delta > 0 {
+		a.allocated += delta
+	}
+	if allocation {
+		a.allocations++
+	}
+}
+
+func (a *TrackedAllocator) Allocate(size int) []byte {
+	a.reserve(int64(size), true)
+	return a.parent.Allocate(size)
+}
+
+func (a *TrackedAllocator) Reallocate(size int, b []byte) []byte {
+	a.reserve(int64(size-len(b)), false)
+	return a.parent.Reallocate(size, b)
+}
+
+func (a *TrackedAllocator) Free(b []byte) {
+	a.reserve(-int64(len(b)), false)
+	a.parent.Free(b)
+}
+
+// Limit returns the budget in bytes, or zero or less if there is none.
+func (a *TrackedAllocator) Limit() int64 {
+	return a.limit
+}
+
+// CurrentBytes returns the number of bytes allocated and not yet freed.
+func (a *TrackedAllocator) CurrentBytes() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.inUse
+}
+
+// PeakBytes returns the largest number of bytes in use at once.
+func (a *TrackedAllocator) PeakBytes() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.peak
+}
+
+// Allocations returns the number of buffers allocated, freed or not.
+func (a *TrackedAllocator) Allocations() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.allocations
+}
+
+// AllocatedBytes returns the number of bytes allocated, freed or not,
+// counting the growth of reallocated buffers.
+func (a *TrackedAllocator) AllocatedBytes() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.allocated
+}
+
+// Err returns the first allocation refused for exceeding the limit, if any.
+func (a *TrackedAllocator) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err == nil {
+		return nil
+	}
+	return a.err
+}
+
+// RecoverLimitError stores in *err the LimitError a deferred call recovers
+// from, and re-panics with any other value.
+//
+//	defer memory.RecoverLimitError(&err)
+func RecoverLimitError(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if limitErr, ok := r.(*LimitError); ok {
+		*err = limitErr
+		return
+	}
+	panic(r)
+}
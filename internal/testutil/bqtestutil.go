@@ -1,11 +1,15 @@
 package testutil
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"regexp"
 	"strings"
 
 	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"github.com/apache/arrow-go/v18/arrow"
+	mw "github.com/arrowarc/arrowarc/integrations/managed_writer"
 	"github.com/google/uuid"
 )
 
@@ -54,3 +58,13 @@ func BuildAppendRowsRequest(data [][]byte) *storagepb.AppendRowsRequest {
 		},
 	}
 }
+
+// AppendToDefaultStream appends an Arrow record to the default stream of a table,
+// using the given BigQuery storage schema to derive the proto descriptor.
+func AppendToDefaultStream(w io.Writer, projectID, datasetID, tableID string, record arrow.Record, tableSchema *storagepb.TableSchema) error {
+	if _, err := mw.AppendArrowRecordToDefaultStream(context.Background(), projectID, datasetID, tableID, record, tableSchema); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "appended %d rows to %s.%s\n", record.NumRows(), datasetID, tableID)
+	return nil
+}
@@ -0,0 +1,80 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package testutil
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+)
+
+// WriteParquet writes a Parquet file of schema at path, tuned by opts if
+// not nil, holding the rows fill appends to the builder as one record.
+func WriteParquet(t testing.TB, path string, schema *arrow.Schema, opts *integrations.ParquetWriteOptions, fill func(b *array.RecordBuilder)) {
+	t.Helper()
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	fill(b)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	writer, err := integrations.NewParquetWriterWithOptions(path, schema, opts)
+	if err != nil {
+		t.Fatalf("failed to create Parquet writer: %v", err)
+	}
+	if err := writer.Write(rec); err != nil {
+		writer.Close()
+		t.Fatalf("failed to write Parquet file: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close Parquet writer: %v", err)
+	}
+}
+
+// JSONRows returns a fill function for WriteParquet appending rows, a JSON
+// array of objects.
+func JSONRows(t testing.TB, rows string) func(b *array.RecordBuilder) {
+	return func(b *array.RecordBuilder) {
+		t.Helper()
+		var objects []json.RawMessage
+		if err := json.Unmarshal([]byte(rows), &objects); err != nil {
+			t.Fatalf("failed to parse rows: %v", err)
+		}
+		for _, object := range objects {
+			if err := b.UnmarshalJSON(object); err != nil {
+				t.Fatalf("failed to build rows: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,327 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-isatty"
+)
+
+// LogInterval is how often the plain-log progress monitor reports.
+var LogInterval = 5 * time.Second
+
+var (
+	stageStyles = map[pipeline.StageState]lipgloss.Style{
+		pipeline.StagePending: lipgloss.NewStyle().Foreground(lipgloss.Color("#757575")),
+		pipeline.StageRunning: lipgloss.NewStyle().Foreground(lipgloss.Color("#2196F3")),
+		pipeline.StageDone:    lipgloss.NewStyle().Foreground(lipgloss.Color("#25A065")),
+		pipeline.StageFailed:  lipgloss.NewStyle().Foreground(lipgloss.Color("#E53935")),
+	}
+	stageIcons = map[pipeline.StageState]string{
+		pipeline.StagePending: "·",
+		pipeline.StageDone:    "✓",
+		pipeline.StageFailed:  "✗",
+	}
+	statLabelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#757575")).Width(10)
+)
+
+// NewProgressMonitor returns a pipeline.Monitor that draws a live progress
+// view on out, or logs progress lines to it when out is not a terminal or
+// noTUI is set.
+func NewProgressMonitor(title string, out io.Writer, noTUI bool) pipeline.Monitor {
+	if f, ok := out.(*os.File); ok && !noTUI && isatty.IsTerminal(f.Fd()) {
+		return &tuiMonitor{title: title, out: out}
+	}
+	return &logMonitor{title: title, logger: log.New(out, "", log.LstdFlags)}
+}
+
+// logMonitor logs a progress line every LogInterval.
+type logMonitor struct {
+	title  string
+	logger *log.Logger
+	stop   chan struct{}
+	done   chan struct{}
+	dp     *pipeline.DataPipeline
+}
+
+func (m *logMonitor) Start(dp *pipeline.DataPipeline) {
+	m.dp = dp
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(LogInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.logger.Printf("%s: %s", m.title, progressLine(dp.Progress()))
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (m *logMonitor) Stop(err error) {
+	close(m.stop)
+	<-m.done
+	p := m.dp.Progress()
+	if err != nil {
+		m.logger.Printf("%s: failed after %s: %v", m.title, p.Elapsed.Round(time.Millisecond), err)
+		return
+	}
+	m.logger.Printf("%s: completed, %s", m.title, progressLine(p))
+}
+
+// progressLine summarises p on one line.
+func progressLine(p pipeline.Progress) string {
+	line := fmt.Sprintf("%d records (%.0f/s), %s (%s/s), %s elapsed",
+		p.Records, p.RecordsPerSec(), formatBytes(p.Bytes), formatBytes(int64(p.BytesPerSec())),
+		p.Elapsed.Round(time.Second))
+	if f, ok := p.Fraction(); ok && !p.Done {
+		line += fmt.Sprintf(", %.0f%%", f*100)
+		if eta, ok := p.ETA(); ok {
+			line += fmt.Sprintf(", ETA %s", eta.Round(time.Second))
+		}
+	}
+	return line
+}
+
+// logOutput is where LogOutput writes.
+var logOutput = &switchWriter{w: os.Stderr}
+
+// LogOutput returns a writer to standard error that a live progress view
+// redirects while it draws, so that loggers writing to it do not garble
+// the display.
+func LogOutput() io.Writer {
+	return logOutput
+}
+
+// switchWriter writes to a writer that can be swapped while in use.
+type switchWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// swap makes s write to w and returns the writer it wrote to.
+func (s *switchWriter) swap(w io.Writer) io.Writer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.w
+	s.w = w
+	return prev
+}
+
+// tuiMonitor runs a bubbletea program while the pipeline runs. Log output
+// is held back meanwhile and only printed if the pipeline fails.
+type tuiMonitor struct {
+	title   string
+	out     io.Writer
+	program *tea.Program
+	done    chan struct{}
+	logs    lockedBuffer
+	prevLog io.Writer
+	prevOut io.Writer
+}
+
+func (m *tuiMonitor) Start(dp *pipeline.DataPipeline) {
+	m.prevLog = log.Writer()
+	log.SetOutput(&m.logs)
+	m.prevOut = logOutput.swap(&m.logs)
+
+	m.program = tea.NewProgram(newProgressModel(m.title, dp),
+		tea.WithOutput(m.out), tea.WithInput(nil), tea.WithoutSignalHandler())
+	m.done = make(chan struct{})
+	go func() {
+		defer close(m.done)
+		if _, err := m.program.Run(); err != nil {
+			fmt.Fprintf(m.prevOut, "progress display failed: %v\n", err)
+		}
+	}()
+}
+
+func (m *tuiMonitor) Stop(err error) {
+	m.program.Send(finishedMsg{err: err})
+	<-m.done
+	log.SetOutput(m.prevLog)
+	logOutput.swap(m.prevOut)
+	if err != nil {
+		m.prevOut.Write(m.logs.Bytes())
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes by loggers.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
+
+type tickMsg time.Time
+
+type finishedMsg struct {
+	err error
+}
+
+type progressModel struct {
+	title    string
+	dp       *pipeline.DataPipeline
+	progress pipeline.Progress
+	bar      progress.Model
+	spinner  spinner.Model
+	finished bool
+	err      error
+}
+
+func newProgressModel(title string, dp *pipeline.DataPipeline) progressModel {
+	return progressModel{
+		title:    title,
+		dp:       dp,
+		progress: dp.Progress(),
+		bar:      progress.New(progress.WithDefaultGradient(), progress.WithWidth(40)),
+		spinner:  spinner.New(spinner.WithSpinner(spinner.Dot)),
+	}
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(200*time.Millisecond, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m progressModel) Init() tea.Cmd {
+	return tea.Batch(tick(), m.spinner.Tick)
+}
+
+func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tickMsg:
+		if m.finished {
+			return m, nil
+		}
+		m.progress = m.dp.Progress()
+		return m, tick()
+	case finishedMsg:
+		m.progress = m.dp.Progress()
+		m.finished = true
+		m.err = msg.err
+		return m, tea.Quit
+	case tea.WindowSizeMsg:
+		m.bar.Width = max(10, min(60, msg.Width-20))
+		return m, nil
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m progressModel) View() string {
+	p := m.progress
+	var b strings.Builder
+	b.WriteString(TitleStyle.Render(m.title) + "\n\n")
+
+	if f, ok := p.Fraction(); ok {
+		b.WriteString("  " + m.bar.ViewAs(f))
+		if eta, ok := p.ETA(); ok && !m.finished {
+			b.WriteString("  ETA " + eta.Round(time.Second).String())
+		}
+		b.WriteString("\n\n")
+	}
+
+	stat := func(label, value string) {
+		b.WriteString("  " + statLabelStyle.Render(label) + value + "\n")
+	}
+	stat("Records", fmt.Sprintf("%d (%.0f/s)", p.Records, p.RecordsPerSec()))
+	stat("Data", fmt.Sprintf("%s (%s/s)", formatBytes(p.Bytes), formatBytes(int64(p.BytesPerSec()))))
+	stat("Elapsed", p.Elapsed.Round(time.Second).String())
+	b.WriteString("\n")
+
+	for _, s := range p.Stages {
+		icon := stageIcons[s.State]
+		if s.State == pipeline.StageRunning {
+			icon = m.spinner.View()
+		}
+		b.WriteString(fmt.Sprintf("  %s %-32s %12d rows  %s\n",
+			stageStyles[s.State].Render(icon), s.Name, s.Rows, stageStyles[s.State].Render(s.State.String())))
+	}
+
+	if m.finished {
+		if m.err != nil {
+			b.WriteString("\n" + stageStyles[pipeline.StageFailed].Render("  Failed: "+m.err.Error()) + "\n")
+		} else {
+			b.WriteString("\n" + stageStyles[pipeline.StageDone].Render("  Completed") + "\n")
+		}
+	}
+	return b.String()
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,117 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// BatchError is the error a pipeline fails with when a stage fails on a
+// record batch. Batches are counted from zero in the order the reader
+// returned them, and rows likewise, before any transformer runs.
+type BatchError struct {
+	Stage    string // name of the stage that failed
+	Batch    int64
+	FirstRow int64  // index of the batch's first row
+	Rows     int64  // rows in the batch, zero if the reader failed to read it
+	Position string // where in the source the batch came from, if known
+	Err      error
+}
+
+// Error describes the batch, such as "batch 3 (rows 300-399, row group
+// 1): disk full".
+func (e *BatchError) Error() string {
+	details := []string{fmt.Sprintf("from row %d", e.FirstRow)}
+	if e.Rows > 0 {
+		details[0] = fmt.Sprintf("rows %d-%d", e.FirstRow, e.FirstRow+e.Rows-1)
+	}
+	if e.Position != "" {
+		details = append(details, e.Position)
+	}
+	return fmt.Sprintf("batch %d (%s): %v", e.Batch, strings.Join(details, ", "), e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// batchInfo tells where a record sent by the reader came from.
+type batchInfo struct {
+	seq      int64
+	firstRow int64
+	rows     int64
+	position string
+	count    int64 // records read in the batch, more than one if coalesced
+}
+
+func (b batchInfo) fail(stage string, err error) *BatchError {
+	return &BatchError{Stage: stage, Batch: b.seq, FirstRow: b.firstRow, Rows: b.rows, Position: b.position, Err: err}
+}
+
+// batchQueue passes the batchInfo of each record from the reader to the
+// writer alongside the record channel. Records are neither reordered nor
+// dropped on the way, so the writer pops one entry per record it receives.
+type batchQueue struct {
+	mu    sync.Mutex
+	infos []batchInfo
+}
+
+func (q *batchQueue) push(b batchInfo) {
+	q.mu.Lock()
+	q.infos = append(q.infos, b)
+	q.mu.Unlock()
+}
+
+func (q *batchQueue) pop() (batchInfo, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.infos) == 0 {
+		return batchInfo{}, false
+	}
+	b := q.infos[0]
+	q.infos[0] = batchInfo{}
+	q.infos = q.infos[1:]
+	return b, true
+}
+
+// FailureReport describes, in a metrics report, why a pipeline failed and
+// how far it got: the batches and rows the writer was done with, whether
+// written or dropped by a transformer.
+type FailureReport struct {
+	Stage            string `json:"stage,omitempty"`
+	Batch            *int64 `json:"batch,omitempty"`
+	Rows             string `json:"rows,omitempty"`
+	Position         string `json:"position,omitempty"`
+	Error            string `json:"error"`
+	CompletedBatches int64  `json:"completed_batches"`
+	CompletedRows    int64  `json:"completed_rows"`
+}
@@ -0,0 +1,246 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// BatchPolicy tells a pipeline when to pass the records it coalesces on to
+// the writer, so that a low-rate stream of small records reaches a sink
+// such as a Parquet file as fewer, larger batches. A batch is flushed once
+// it holds MaxRows rows or MaxBytes bytes, or MaxLatency after its first
+// record was read, whichever comes first; zero fields set no bound. A record
+// that would take a batch past MaxRows or MaxBytes starts the next one, and
+// a record larger than the bounds is passed on alone, unsplit.
+type BatchPolicy struct {
+	MaxRows    int64
+	MaxBytes   int64
+	MaxLatency time.Duration
+}
+
+// Validate reports whether the policy bounds its batches.
+func (p BatchPolicy) Validate() error {
+	if p.MaxRows < 0 || p.MaxBytes < 0 || p.MaxLatency < 0 {
+		return fmt.Errorf("batch policy bounds cannot be negative")
+	}
+	if p.MaxRows == 0 && p.MaxBytes == 0 && p.MaxLatency == 0 {
+		return fmt.Errorf("batch policy needs a row, byte or latency bound")
+	}
+	return nil
+}
+
+// full reports whether a batch of rows and size bytes must be flushed.
+func (p BatchPolicy) full(rows, size int64) bool {
+	return (p.MaxRows > 0 && rows >= p.MaxRows) || (p.MaxBytes > 0 && size >= p.MaxBytes)
+}
+
+// exceeds reports whether a batch of rows and size bytes is past the bounds.
+func (p BatchPolicy) exceeds(rows, size int64) bool {
+	return (p.MaxRows > 0 && rows > p.MaxRows) || (p.MaxBytes > 0 && size > p.MaxBytes)
+}
+
+// WithBatching coalesces the records read into batches bounded by policy
+// before they are transformed and written. Batch errors and the records
+// acknowledged to an interfaces.AckingReader still count the records as
+// read. It must be called before Start.
+func (dp *DataPipeline) WithBatching(policy BatchPolicy) *DataPipeline {
+	dp.batching = &policy
+	return dp
+}
+
+// pendingBatch is the batch a batcher is filling.
+type pendingBatch struct {
+	records []arrow.Record
+	infos   []batchInfo
+	rows    int64
+	size    int64
+	started time.Time
+}
+
+func (b *pendingBatch) empty() bool {
+	return len(b.records) == 0
+}
+
+func (b *pendingBatch) add(record arrow.Record, info batchInfo, size int64) {
+	if b.empty() {
+		b.started = time.Now()
+	}
+	b.records = append(b.records, record)
+	b.infos = append(b.infos, info)
+	b.rows += record.NumRows()
+	b.size += size
+}
+
+// take returns the batch as one record and the batchInfo of the records it
+// was made of, and empties it.
+func (b *pendingBatch) take(mem memory.Allocator) (arrow.Record, batchInfo, error) {
+	defer b.release()
+	info := b.infos[0]
+	for _, next := range b.infos[1:] {
+		info.rows += next.rows
+		info.count += next.count
+	}
+	if len(b.records) == 1 {
+		b.records[0].Retain()
+		return b.records[0], info, nil
+	}
+	schema := b.records[0].Schema()
+	cols := make([]arrow.Array, schema.NumFields())
+	for i := range cols {
+		chunks := make([]arrow.Array, len(b.records))
+		for j, r := range b.records {
+			chunks[j] = r.Column(i)
+		}
+		col, err := array.Concatenate(chunks, mem)
+		if err != nil {
+			for _, c := range cols[:i] {
+				c.Release()
+			}
+			return nil, info, fmt.Errorf("failed to coalesce records: %w", err)
+		}
+		cols[i] = col
+	}
+	record := array.NewRecord(schema, cols, b.rows)
+	for _, c := range cols {
+		c.Release()
+	}
+	return record, info, nil
+}
+
+func (b *pendingBatch) release() {
+	for _, r := range b.records {
+		r.Release()
+	}
+	*b = pendingBatch{}
+}
+
+// startBatching moves records from in to out, coalesced as the pipeline's
+// batch policy says. The reader queues the batchInfo of its records in
+// dp.unbatched, and the batches sent on are queued in dp.batches.
+func (dp *DataPipeline) startBatching(ctx context.Context, in <-chan arrow.Record, out chan<- arrow.Record, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer close(out)
+	policy := *dp.batching
+	var pending pendingBatch
+	defer pending.release()
+	// Release whatever the reader still sends if batching stops early.
+	defer func() {
+		for record := range in {
+			record.Release()
+		}
+	}()
+
+	flush := func(reason string) bool {
+		record, info, err := pending.take(dp.Allocator())
+		if err != nil {
+			dp.logger.Error("batching failed", "error", err)
+			dp.fail(fmt.Errorf("batching error: %w", info.fail("batching", err)))
+			return false
+		}
+		dp.logger.Debug("batch flushed", "reason", reason, "rows", record.NumRows(), "records", info.count)
+		dp.batches.push(info)
+		select {
+		case out <- record:
+			return true
+		case <-ctx.Done():
+			record.Release()
+			return false
+		}
+	}
+
+	var timer *time.Timer
+	var expired <-chan time.Time
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, expired = nil, nil
+		}
+	}
+	defer stopTimer()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expired:
+			timer, expired = nil, nil
+			if !pending.empty() && !flush("latency") {
+				return
+			}
+		case record, ok := <-in:
+			if !ok {
+				if !pending.empty() {
+					flush("end of input")
+				}
+				return
+			}
+			info, ok := dp.unbatched.pop()
+			if !ok {
+				info = batchInfo{rows: record.NumRows(), count: 1}
+			}
+			size := calculateRecordSize(record)
+			reason := ""
+			switch {
+			case pending.empty():
+			case !pending.records[0].Schema().Equal(record.Schema()):
+				reason = "schema change"
+			case policy.exceeds(pending.rows+record.NumRows(), pending.size+size):
+				reason = "size"
+			}
+			if reason != "" {
+				stopTimer()
+				if !flush(reason) {
+					record.Release()
+					return
+				}
+			}
+			pending.add(record, info, size)
+			if policy.full(pending.rows, pending.size) {
+				stopTimer()
+				if !flush("size") {
+					return
+				}
+				continue
+			}
+			if policy.MaxLatency > 0 && timer == nil {
+				timer = time.NewTimer(policy.MaxLatency - time.Since(pending.started))
+				expired = timer.C
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EmptyPolicy says what a conversion writes when its source yields no
+// rows.
+type EmptyPolicy string
+
+const (
+	// EmptyWrite writes a valid empty output with the schema of the
+	// records that would have been written: a Parquet file with its
+	// footer, a CSV header, an empty JSON array. It is the default.
+	EmptyWrite EmptyPolicy = "write"
+	// EmptySkip creates no output at all.
+	EmptySkip EmptyPolicy = "skip"
+	// EmptyFail creates no output and fails with ErrEmptyResult.
+	EmptyFail EmptyPolicy = "fail"
+)
+
+// ErrEmptyResult is returned under EmptyFail when the source yields no
+// rows.
+var ErrEmptyResult = errors.New("the source returned no rows")
+
+// ParseEmptyPolicy parses the name of an EmptyPolicy; an empty name is
+// EmptyWrite.
+func ParseEmptyPolicy(s string) (EmptyPolicy, error) {
+	switch policy := EmptyPolicy(strings.ToLower(s)); policy {
+	case "":
+		return EmptyWrite, nil
+	case EmptyWrite, EmptySkip, EmptyFail:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown empty result policy %q: expected write, skip or fail", s)
+}
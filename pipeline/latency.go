@@ -0,0 +1,198 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// Latency histograms keep 32 buckets per power of two above 64ns, as HDR
+// histograms do, so that percentiles are within about 3% of the recorded
+// values in a fixed amount of memory.
+const (
+	latencySubBits    = 5
+	latencySubBuckets = 1 << latencySubBits
+	latencyLinear     = 2 * latencySubBuckets
+	latencyBuckets    = latencyLinear + (64-latencySubBits-1)*latencySubBuckets
+)
+
+// latencyHistogram records durations for percentile summaries. It is safe
+// for concurrent use.
+type latencyHistogram struct {
+	mu     sync.Mutex
+	counts []int64
+	count  int64
+	total  time.Duration
+	max    time.Duration
+}
+
+func latencyBucket(v uint64) int {
+	if v < latencyLinear {
+		return int(v)
+	}
+	shift := bits.Len64(v) - latencySubBits - 1
+	return latencyLinear + (shift-1)*latencySubBuckets + int(v>>shift) - latencySubBuckets
+}
+
+// latencyBucketMax returns the largest value of bucket i.
+func latencyBucketMax(i int) uint64 {
+	if i < latencyLinear {
+		return uint64(i)
+	}
+	shift := (i-latencyLinear)/latencySubBuckets + 1
+	m := uint64((i-latencyLinear)%latencySubBuckets + latencySubBuckets)
+	return (m+1)<<shift - 1
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.counts == nil {
+		h.counts = make([]int64, latencyBuckets)
+	}
+	h.counts[latencyBucket(uint64(d))]++
+	h.count++
+	h.total += d
+	h.max = max(h.max, d)
+}
+
+// summary returns the histogram's count, total and percentiles.
+func (h *latencyHistogram) summary() StageLatency {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := StageLatency{Batches: h.count, Total: h.total, Max: h.max}
+	if h.count == 0 {
+		return s
+	}
+	s.Mean = h.total / time.Duration(h.count)
+	s.P50 = h.percentile(50)
+	s.P95 = h.percentile(95)
+	s.P99 = h.percentile(99)
+	return s
+}
+
+// percentile returns the smallest bucket bound at or below which p percent
+// of the values fall, capped at the largest value recorded.
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	rank := int64(math.Ceil(p / 100 * float64(h.count)))
+	var seen int64
+	for i, n := range h.counts {
+		if seen += n; seen >= max(rank, 1) {
+			return min(time.Duration(latencyBucketMax(i)), h.max)
+		}
+	}
+	return h.max
+}
+
+// Stage kinds of a StageLatency.
+const (
+	StageKindRead      = "read"
+	StageKindTransform = "transform"
+	StageKindWrite     = "write"
+)
+
+// StageLatency summarizes how long one stage took per batch: the reader's
+// Read, a transformer's Transform or the writer's Write calls.
+type StageLatency struct {
+	Stage   string
+	Kind    string
+	Batches int64
+	Total   time.Duration
+	Mean    time.Duration
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// stageLatencies returns the latency summary of each stage, in order.
+func (dp *DataPipeline) stageLatencies() []StageLatency {
+	latencies := make([]StageLatency, 0, len(dp.stages))
+	for i, s := range dp.stages {
+		l := s.latency.summary()
+		l.Stage, l.Kind = s.name, StageKindTransform
+		switch i {
+		case 0:
+			l.Kind = StageKindRead
+		case len(dp.stages) - 1:
+			l.Kind = StageKindWrite
+		}
+		latencies = append(latencies, l)
+	}
+	return latencies
+}
+
+// StageLatencyReport is a StageLatency in a metrics report.
+type StageLatencyReport struct {
+	Stage   string `json:"stage"`
+	Kind    string `json:"kind"`
+	Batches int64  `json:"batches"`
+	Total   string `json:"total"`
+	Mean    string `json:"mean"`
+	P50     string `json:"p50"`
+	P95     string `json:"p95"`
+	P99     string `json:"p99"`
+	Max     string `json:"max"`
+}
+
+func latencyReports(latencies []StageLatency) []StageLatencyReport {
+	var reports []StageLatencyReport
+	for _, l := range latencies {
+		reports = append(reports, StageLatencyReport{
+			Stage:   l.Stage,
+			Kind:    l.Kind,
+			Batches: l.Batches,
+			Total:   formatLatency(l.Total),
+			Mean:    formatLatency(l.Mean),
+			P50:     formatLatency(l.P50),
+			P95:     formatLatency(l.P95),
+			P99:     formatLatency(l.P99),
+			Max:     formatLatency(l.Max),
+		})
+	}
+	return reports
+}
+
+// formatLatency formats d to about three significant digits, such as
+// "1.23ms" or "845µs".
+func formatLatency(d time.Duration) string {
+	for unit := time.Duration(1); unit < time.Minute; unit *= 10 {
+		if d < 1000*unit {
+			return d.Round(unit).String()
+		}
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,156 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// memStatsInterval is how often a running pipeline samples the heap for
+// its peak.
+const memStatsInterval = 250 * time.Millisecond
+
+// MemoryStats describes the memory a run used. The heap and garbage
+// collection figures come from runtime.ReadMemStats, sampled while the
+// pipeline runs, so they cover the whole process, including whatever else
+// it did meanwhile. The allocator figures come from the allocator set up by
+// WithMemoryLimit, and are zero without one.
+type MemoryStats struct {
+	PeakHeapBytes  int64 // largest heap in use among the samples
+	HeapAllocBytes int64 // heap bytes allocated, freed or not
+	HeapObjects    int64 // heap objects allocated
+	GCCycles       int64
+	GCPauseTotal   time.Duration
+	// AllocatorAllocations and AllocatorBytes count the buffers and bytes
+	// allocated from the pipeline's allocator, freed or not.
+	AllocatorAllocations int64
+	AllocatorBytes       int64
+}
+
+// memStatsSampler tracks the runtime memory statistics of a run from the
+// moment it is started.
+type memStatsSampler struct {
+	mu       sync.Mutex
+	start    runtime.MemStats
+	peakHeap uint64
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func startMemStats() *memStatsSampler {
+	s := &memStatsSampler{stop: make(chan struct{}), stopped: make(chan struct{})}
+	runtime.ReadMemStats(&s.start)
+	s.peakHeap = s.start.HeapAlloc
+	go func() {
+		defer close(s.stopped)
+		ticker := time.NewTicker(memStatsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.sample()
+			}
+		}
+	}()
+	return s
+}
+
+// sample reads the memory statistics, updating the peak heap, and returns
+// those of the run so far.
+func (s *memStatsSampler) sample() MemoryStats {
+	var now runtime.MemStats
+	runtime.ReadMemStats(&now)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peakHeap = max(s.peakHeap, now.HeapAlloc)
+	return MemoryStats{
+		PeakHeapBytes:  int64(s.peakHeap),
+		HeapAllocBytes: int64(now.TotalAlloc - s.start.TotalAlloc),
+		HeapObjects:    int64(now.Mallocs - s.start.Mallocs),
+		GCCycles:       int64(now.NumGC - s.start.NumGC),
+		GCPauseTotal:   time.Duration(now.PauseTotalNs - s.start.PauseTotalNs),
+	}
+}
+
+// finish stops sampling and returns the statistics of the whole run.
+func (s *memStatsSampler) finish() MemoryStats {
+	close(s.stop)
+	<-s.stopped
+	return s.sample()
+}
+
+// memoryStats returns the memory statistics of the run so far, or of the
+// whole run once finished is set.
+func (dp *DataPipeline) memoryStats(finished bool) MemoryStats {
+	var stats MemoryStats
+	if finished {
+		stats = dp.memStats.finish()
+	} else {
+		stats = dp.memStats.sample()
+	}
+	if dp.allocator != nil {
+		stats.AllocatorAllocations = dp.allocator.Allocations()
+		stats.AllocatorBytes = dp.allocator.AllocatedBytes()
+	}
+	return stats
+}
+
+// MemoryReport is the memory section of a metrics report.
+type MemoryReport struct {
+	PeakHeap             string `json:"peak_heap"`
+	HeapAllocated        string `json:"heap_allocated"`
+	HeapObjects          string `json:"heap_objects"`
+	GCCycles             int64  `json:"gc_cycles"`
+	GCPauseTotal         string `json:"gc_pause_total"`
+	AllocatorAllocations string `json:"allocator_allocations,omitempty"`
+	AllocatorAllocated   string `json:"allocator_allocated,omitempty"`
+}
+
+func memoryReport(stats MemoryStats) *MemoryReport {
+	if stats.PeakHeapBytes == 0 {
+		return nil
+	}
+	report := &MemoryReport{
+		PeakHeap:      formatBytes(stats.PeakHeapBytes),
+		HeapAllocated: formatBytes(stats.HeapAllocBytes),
+		HeapObjects:   formatLargeNumber(float64(stats.HeapObjects)),
+		GCCycles:      stats.GCCycles,
+		GCPauseTotal:  formatLatency(stats.GCPauseTotal),
+	}
+	if stats.AllocatorAllocations > 0 {
+		report.AllocatorAllocations = formatLargeNumber(float64(stats.AllocatorAllocations))
+		report.AllocatorAllocated = formatBytes(stats.AllocatorBytes)
+	}
+	return report
+}
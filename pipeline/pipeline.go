@@ -34,16 +34,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
 	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"github.com/arrowarc/arrowarc/pkg/verify"
 )
 
 // Metrics stores pipeline processing metrics
@@ -55,7 +60,21 @@ type Metrics struct {
 	TotalDuration    int64 // nanoseconds
 	Throughput       int64 // records per second * 100 (for two decimal places)
 	ThroughputBytes  int64 // bytes per second
+	Transforms       map[string]map[string]int64
+	Latency          []StageLatency
+	Memory           MemoryStats
+	PeakMemoryBytes  int64 // peak bytes in use by the pipeline's allocator, if any
+	MemoryLimitBytes int64
+	UnreleasedBytes  int64 // bytes left allocated when the debug allocator is on
+	SpilledRecords   int64 // records written to spill files
+	SpilledBytes     int64 // in-memory size of the records spilled
+	RunID            string
+	BatchesCompleted int64       // batches the writer wrote or a transformer dropped
+	RowsCompleted    int64       // rows read in those batches
+	Failure          *BatchError // the batch the pipeline failed on, if any
+	Verification     *verify.Result
 	endTimeUnix      int64
+	err              error
 }
 
 // UpdateMetrics calculates the total duration, throughput, and throughput in bytes.
@@ -90,47 +109,258 @@ func (m *Metrics) Report() string {
 
 // DataPipeline defines the structure for a data processing pipeline
 type DataPipeline struct {
-	reader  interfaces.Reader
-	writer  interfaces.Writer
-	errCh   chan error
-	metrics *Metrics
+	reader       interfaces.Reader
+	writer       interfaces.Writer
+	transformers []interfaces.Transformer
+	errCh        chan error
+	writerDone   chan struct{}
+	metrics      *Metrics
+
+	stages           []*stage
+	expectedRows     int64
+	monitor          Monitor
+	onProgress       func(ProgressEvent)
+	progressInterval time.Duration
+	allocator        *pool.TrackedAllocator
+	spillDir         string
+	spillThreshold   int64
+	memStats         *memStatsSampler
+	batching         *BatchPolicy
+	logger           *slog.Logger
+	verifyOpen       func(context.Context) (interfaces.Reader, error)
+	written          *verify.Hasher
+	batches          batchQueue
+	unbatched        batchQueue
+	done             atomic.Bool
+	errMu            sync.Mutex
+	runErr           error
+	failErr          error
+	failure          *BatchError
 }
 
 // NewDataPipeline creates a new DataPipeline instance
 func NewDataPipeline(reader interfaces.Reader, writer interfaces.Writer) *DataPipeline {
-	return &DataPipeline{
+	dp := &DataPipeline{
 		reader: reader,
 		writer: writer,
 		errCh:  make(chan error, 1), // Buffer size of 1 to capture any errors
+		// Closed once the writer is closed, so that a failed or canceled
+		// run returns only after its output is finalized.
+		writerDone: make(chan struct{}),
 		metrics: &Metrics{
 			StartTime: time.Now(),
 		},
 	}
+	if sized, ok := reader.(interfaces.SizedReader); ok {
+		dp.expectedRows = sized.NumRows()
+	}
+	return dp
+}
+
+// WithTransformers adds stages that rewrite each record, in order, between
+// the reader and the writer. It must be called before Start.
+func (dp *DataPipeline) WithTransformers(transformers ...interfaces.Transformer) *DataPipeline {
+	dp.transformers = append(dp.transformers, transformers...)
+	return dp
+}
+
+// WithExpectedRows sets the number of rows the reader is expected to
+// return, used to estimate the time left. Readers that implement
+// interfaces.SizedReader set it themselves.
+func (dp *DataPipeline) WithExpectedRows(n int64) *DataPipeline {
+	dp.expectedRows = n
+	return dp
+}
+
+// WithMonitor reports the pipeline's progress to m. Pipelines run
+// unmonitored unless one is set.
+func (dp *DataPipeline) WithMonitor(m Monitor) *DataPipeline {
+	dp.monitor = m
+	return dp
+}
+
+// WithMemoryLimit gives the pipeline an allocator with a budget of limit
+// bytes, or one that only counts if limit is zero or less. Pass Allocator()
+// to the reader, transformers and writer: the pipeline fails with an error
+// matching memory.ErrMemoryLimitExceeded as soon as one of them allocates
+// past the budget, and reports the peak usage. It must be called before
+// Start.
+func (dp *DataPipeline) WithMemoryLimit(limit int64) *DataPipeline {
+	dp.allocator = pool.NewTrackedAllocator(pool.NewGoAllocator(), limit)
+	dp.metrics.MemoryLimitBytes = limit
+	return dp
+}
+
+// WithSpill bounds the records queued between the reader and a slower
+// writer to about threshold bytes. Records past it are written to
+// LZ4-compressed Arrow IPC files in dir, or os.TempDir() if dir is empty,
+// and read back in order once the writer catches up. It must be called
+// before Start.
+func (dp *DataPipeline) WithSpill(threshold int64, dir string) *DataPipeline {
+	dp.spillThreshold = threshold
+	dp.spillDir = dir
+	return dp
+}
+
+// WithLogger logs the pipeline's events to l instead of the logger of the
+// context passed to Start. Entries carry the run ID either way. It must be
+// called before Start.
+func (dp *DataPipeline) WithLogger(l *slog.Logger) *DataPipeline {
+	dp.logger = l
+	return dp
+}
+
+// WithVerification digests the records given to the writer, counting
+// their rows and hashing the values of each column. Once the writer is
+// closed, the pipeline reads the output back from the reader open returns
+// and fails with an error matching verify.ErrMismatch unless it holds the
+// same rows. The report includes the comparison. It must be called before
+// Start.
+func (dp *DataPipeline) WithVerification(open func(ctx context.Context) (interfaces.Reader, error)) *DataPipeline {
+	dp.verifyOpen = open
+	dp.written = verify.NewHasher()
+	return dp
+}
+
+// RunID returns the ID the pipeline's log entries and report carry: that
+// of the context passed to Start, or a new one. It is set once Start is
+// called.
+func (dp *DataPipeline) RunID() string {
+	return dp.metrics.RunID
+}
+
+// Allocator returns the allocator set up by WithMemoryLimit, or the default
+// allocator if there is none.
+func (dp *DataPipeline) Allocator() memory.Allocator {
+	if dp.allocator == nil {
+		return memory.DefaultAllocator
+	}
+	return dp.allocator
 }
 
 // Start begins the pipeline processing and returns the metrics report
-func (dp *DataPipeline) Start(ctx context.Context) (string, error) {
+func (dp *DataPipeline) Start(ctx context.Context) (_ string, err error) {
+	if dp.batching != nil {
+		if err := dp.batching.Validate(); err != nil {
+			dp.writer.Close()
+			dp.reader.Close()
+			return "", err
+		}
+	}
 	var wg sync.WaitGroup
+	ctx, runID := logging.NewRun(ctx)
+	if dp.logger == nil {
+		dp.logger = logging.FromContext(ctx)
+	} else {
+		dp.logger = dp.logger.With("run_id", runID)
+	}
+	dp.metrics.RunID = runID
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	dp.stages = make([]*stage, 0, len(dp.transformers)+2)
+	dp.stages = append(dp.stages, &stage{name: stageName(dp.reader)})
+	for _, t := range dp.transformers {
+		dp.stages = append(dp.stages, &stage{name: stageName(t)})
+	}
+	dp.stages = append(dp.stages, &stage{name: stageName(dp.writer)})
+	for _, s := range dp.stages {
+		s.set(StageRunning)
+	}
+	dp.logger.Info("pipeline started", "reader", dp.readerStage().name, "writer", dp.writerStage().name)
+	defer func() {
+		if err != nil {
+			dp.logger.Error("pipeline failed", "error", err)
+			return
+		}
+		dp.logger.Info("pipeline finished",
+			"records", atomic.LoadInt64(&dp.metrics.RecordsProcessed),
+			"bytes", atomic.LoadInt64(&dp.metrics.TotalBytes),
+			"duration", time.Duration(atomic.LoadInt64(&dp.metrics.TotalDuration)))
+	}()
+	if dp.monitor != nil {
+		dp.monitor.Start(dp)
+		defer func() {
+			dp.errMu.Lock()
+			dp.runErr = err
+			dp.errMu.Unlock()
+			dp.monitor.Stop(err)
+		}()
+	}
+	if dp.onProgress != nil {
+		stopProgress := dp.startProgress()
+		defer func() { stopProgress(err) }()
+	}
+
+	// With the debug allocator on, buffers allocated from here on and still
+	// live once the reader and writer are closed were never released.
+	// Buffers of other pipelines running at the same time show up too.
+	debug := pool.Debug()
+	var mark uint64
+	if debug != nil {
+		mark = debug.Mark()
+	}
+
+	dp.memStats = startMemStats()
+
 	// Channel for records with a buffer size of 100
 	recordChan := make(chan arrow.Record, 100)
+	writerChan := recordChan
+
+	// With spilling on, the spill queue holds the records in flight
+	// instead of the channel buffer.
+	if dp.spillThreshold > 0 {
+		recordChan = make(chan arrow.Record)
+		writerChan = make(chan arrow.Record)
+		wg.Add(1)
+		go dp.startSpill(ctx, recordChan, writerChan, &wg)
+	}
+
+	// With batching on, the batcher holds the records it coalesces, ahead
+	// of the channel buffer or the spill queue.
+	readerChan := recordChan
+	if dp.batching != nil {
+		readerChan = make(chan arrow.Record)
+		wg.Add(1)
+		go dp.startBatching(ctx, readerChan, recordChan, &wg)
+	}
 
 	// Start the reader
 	wg.Add(1)
-	go dp.startReader(ctx, recordChan, &wg)
+	go dp.startReader(ctx, readerChan, &wg)
 
 	// Start the writer
 	wg.Add(1)
-	go dp.startWriter(ctx, recordChan, &wg)
+	go dp.startWriter(ctx, writerChan, &wg)
 
 	// Monitor goroutines and handle errors
 	errChan := make(chan error, 1)
 	go func() {
 		wg.Wait()
-		close(dp.errCh)
+		// Close the reader only once the writer is done: records may
+		// reference memory the reader owns, such as a memory-mapped file.
+		dp.reader.Close()
+		if dp.verifyOpen != nil {
+			dp.verify(ctx)
+		}
+		dp.metrics.Transforms = dp.transformCounts()
+		dp.metrics.Latency = dp.stageLatencies()
+		dp.metrics.Memory = dp.memoryStats(true)
+		if dp.allocator != nil {
+			atomic.StoreInt64(&dp.metrics.PeakMemoryBytes, dp.allocator.PeakBytes())
+		}
+		if debug != nil {
+			dp.reportLeaks(debug.Leaks(mark))
+		}
+		dp.errMu.Lock()
+		dp.metrics.Failure = dp.failure
+		dp.metrics.err = dp.failErr
+		dp.errMu.Unlock()
+		// Callers return as soon as dp.errCh closes, so the metrics must
+		// be final by then.
 		dp.metrics.UpdateMetrics()
+		close(dp.errCh)
+		dp.done.Store(true)
 		close(errChan)
 	}()
 
@@ -139,15 +369,33 @@ func (dp *DataPipeline) Start(ctx context.Context) (string, error) {
 	case err := <-dp.errCh:
 		if err != nil {
 			cancel() // Cancel the context to stop all operations
-			return "", err
+			<-dp.writerDone
+			return dp.partialReport(err), err
+		}
+		// Stages stop without an error once the context is done, which
+		// must not pass for a complete run.
+		if err := ctx.Err(); err != nil {
+			<-dp.writerDone
+			return dp.partialReport(err), err
+		}
+	case <-errChan:
+		// Every stage finished before the select ran; dp.errCh is
+		// closed and still holds the first error, if any.
+		if err := <-dp.errCh; err != nil {
+			return dp.partialReport(err), err
+		}
+		if err := ctx.Err(); err != nil {
+			return dp.partialReport(err), err
 		}
-	case err := <-errChan:
-		return "", fmt.Errorf("pipeline execution failed: %w", err)
 	case <-ctx.Done():
-		return "", ctx.Err()
+		err := ctx.Err()
+		<-dp.writerDone
+		return dp.partialReport(err), err
 	case <-time.After(30 * time.Minute): // Adjust timeout as needed
 		cancel()
-		return "", fmt.Errorf("pipeline execution timed out")
+		<-dp.writerDone
+		err := fmt.Errorf("pipeline execution timed out")
+		return dp.partialReport(err), err
 	}
 
 	// Create a transport report
@@ -161,6 +409,7 @@ func (dp *DataPipeline) Start(ctx context.Context) (string, error) {
 }
 
 type MetricsReport struct {
+	RunID           string `json:"run_id,omitempty"`
 	StartTime       string `json:"start_time"`
 	EndTime         string `json:"end_time"`
 	Records         string `json:"records"`
@@ -168,6 +417,20 @@ type MetricsReport struct {
 	Duration        string `json:"duration"`
 	RecordsPerSec   string `json:"records_per_second"`
 	TransferRate    string `json:"transfer_rate"`
+
+	PeakMemory       string `json:"peak_memory,omitempty"`
+	MemoryLimit      string `json:"memory_limit,omitempty"`
+	UnreleasedMemory string `json:"unreleased_memory,omitempty"`
+	SpilledRecords   string `json:"spilled_records,omitempty"`
+	SpilledData      string `json:"spilled_data,omitempty"`
+
+	Memory *MemoryReport `json:"memory,omitempty"`
+
+	Transforms map[string]map[string]int64 `json:"transforms,omitempty"`
+	Stages     []StageLatencyReport        `json:"stages,omitempty"`
+
+	Failure      *FailureReport `json:"failure,omitempty"`
+	Verification *verify.Result `json:"verification,omitempty"`
 }
 
 func generateMetricsReport(metrics *Metrics) MetricsReport {
@@ -177,7 +440,8 @@ func generateMetricsReport(metrics *Metrics) MetricsReport {
 	throughput := float64(atomic.LoadInt64(&metrics.Throughput)) / 100
 	throughputBytes := atomic.LoadInt64(&metrics.ThroughputBytes)
 
-	return MetricsReport{
+	report := MetricsReport{
+		RunID:           metrics.RunID,
 		StartTime:       metrics.StartTime.Format(time.RFC3339),
 		EndTime:         time.Unix(0, atomic.LoadInt64(&metrics.endTimeUnix)).Format(time.RFC3339),
 		Records:         formatLargeNumber(float64(recordsProcessed)), // Format records
@@ -185,7 +449,72 @@ func generateMetricsReport(metrics *Metrics) MetricsReport {
 		Duration:        formatDuration(duration),
 		RecordsPerSec:   formatThroughput(throughput),
 		TransferRate:    formatThroughputBytes(float64(throughputBytes)),
+		Transforms:      metrics.Transforms,
+		Stages:          latencyReports(metrics.Latency),
+		Memory:          memoryReport(metrics.Memory),
+		Verification:    metrics.Verification,
+	}
+	if peak := atomic.LoadInt64(&metrics.PeakMemoryBytes); peak > 0 {
+		report.PeakMemory = formatBytes(peak)
+	}
+	if metrics.MemoryLimitBytes > 0 {
+		report.MemoryLimit = formatBytes(metrics.MemoryLimitBytes)
+	}
+	if unreleased := atomic.LoadInt64(&metrics.UnreleasedBytes); unreleased > 0 {
+		report.UnreleasedMemory = formatBytes(unreleased)
+	}
+	if spilled := atomic.LoadInt64(&metrics.SpilledRecords); spilled > 0 {
+		report.SpilledRecords = formatLargeNumber(float64(spilled))
+		report.SpilledData = formatBytes(atomic.LoadInt64(&metrics.SpilledBytes))
+	}
+	if metrics.err != nil {
+		report.Failure = &FailureReport{
+			Error:            metrics.err.Error(),
+			CompletedBatches: atomic.LoadInt64(&metrics.BatchesCompleted),
+			CompletedRows:    atomic.LoadInt64(&metrics.RowsCompleted),
+		}
+		if f := metrics.Failure; f != nil {
+			batch := f.Batch
+			report.Failure.Stage = f.Stage
+			report.Failure.Batch = &batch
+			report.Failure.Position = f.Position
+			if f.Rows > 0 {
+				report.Failure.Rows = fmt.Sprintf("%d-%d", f.FirstRow, f.FirstRow+f.Rows-1)
+			}
+		}
 	}
+	return report
+}
+
+// partialReport returns the metrics report of a pipeline that failed with
+// err, from a snapshot of its metrics since its stages may still be
+// stopping.
+func (dp *DataPipeline) partialReport(err error) string {
+	m := dp.metrics
+	snapshot := &Metrics{
+		RecordsProcessed: atomic.LoadInt64(&m.RecordsProcessed),
+		TotalBytes:       atomic.LoadInt64(&m.TotalBytes),
+		StartTime:        m.StartTime,
+		MemoryLimitBytes: m.MemoryLimitBytes,
+		SpilledRecords:   atomic.LoadInt64(&m.SpilledRecords),
+		SpilledBytes:     atomic.LoadInt64(&m.SpilledBytes),
+		RunID:            m.RunID,
+		BatchesCompleted: atomic.LoadInt64(&m.BatchesCompleted),
+		RowsCompleted:    atomic.LoadInt64(&m.RowsCompleted),
+		Latency:          dp.stageLatencies(),
+		Memory:           dp.memoryStats(false),
+		Verification:     m.Verification,
+		err:              err,
+	}
+	dp.errMu.Lock()
+	snapshot.Failure = dp.failure
+	dp.errMu.Unlock()
+	snapshot.UpdateMetrics()
+	report, jsonErr := PrettyPrint(generateMetricsReport(snapshot))
+	if jsonErr != nil {
+		return ""
+	}
+	return report
 }
 
 func formatBytes(bytes int64) string {
@@ -241,43 +570,61 @@ func formatThroughputBytes(t float64) string {
 func (dp *DataPipeline) startReader(ctx context.Context, ch chan arrow.Record, wg *sync.WaitGroup) {
 	defer wg.Done()
 	defer close(ch)
-	defer dp.reader.Close()
+
+	positioned, _ := dp.reader.(interfaces.PositionedReader)
+	next := batchInfo{count: 1}
+	queue := &dp.batches
+	if dp.batching != nil {
+		queue = &dp.unbatched
+	}
+	position := func() string {
+		if positioned == nil {
+			return ""
+		}
+		return positioned.Position()
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Context canceled, stopping reader.")
+			dp.logger.Debug("reader stopped", "reason", "canceled")
 			return
 		default:
-			record, err := dp.reader.Read()
+			record, err := dp.read()
 			if err == io.EOF {
-				log.Println("Reached end of reader stream.")
+				dp.logger.Debug("reader finished", "stage", dp.readerStage().name, "rows", dp.readerStage().rows.Load())
+				dp.readerStage().set(StageDone)
 				return
 			}
 			if err != nil {
-				log.Printf("Error reading record: %v", err)
-				select {
-				case dp.errCh <- fmt.Errorf("reader error: %w", err):
-				default:
-					log.Printf("Error channel full, discarding error: %v", err)
-				}
+				next.position = position()
+				err = next.fail(dp.readerStage().name, err)
+				dp.logger.Error("read failed", "stage", dp.readerStage().name, "error", err)
+				dp.readerStage().set(StageFailed)
+				dp.fail(fmt.Errorf("reader error: %w", err))
 				return
 			}
 
 			if record == nil || record.NumCols() == 0 || record.NumRows() == 0 {
-				log.Println("Received empty or invalid record, skipping.")
+				dp.logger.Debug("skipping empty record", "stage", dp.readerStage().name)
 				record.Release()
 				continue
 			}
 
 			atomic.AddInt64(&dp.metrics.RecordsProcessed, int64(record.NumRows()))
+			dp.readerStage().rows.Add(record.NumRows())
 			recordSize := calculateRecordSize(record)
 			atomic.AddInt64(&dp.metrics.TotalBytes, recordSize)
 
+			next.rows = record.NumRows()
+			next.position = position()
+			queue.push(next)
+			next = batchInfo{seq: next.seq + 1, firstRow: next.firstRow + next.rows, count: 1}
+
 			select {
 			case ch <- record:
 			case <-ctx.Done():
-				log.Println("Context canceled, stopping reader.")
+				dp.logger.Debug("reader stopped", "reason", "canceled")
 				record.Release()
 				return
 			}
@@ -301,38 +648,337 @@ func calculateRecordSize(record arrow.Record) int64 {
 // startWriter receives records from the channel and writes them using the writer
 func (dp *DataPipeline) startWriter(ctx context.Context, ch chan arrow.Record, wg *sync.WaitGroup) {
 	defer wg.Done()
-	defer dp.writer.Close()
+	// Release the records still queued if the writer stops early, since
+	// a reader may wait for them before reading on.
+	defer func() {
+		for record := range ch {
+			if record != nil {
+				record.Release()
+			}
+		}
+	}()
+	defer close(dp.writerDone)
+	// Closing the writer finalizes what was written so far, such as a
+	// Parquet footer, also when the run is canceled or fails.
+	writerClosed := false
+	defer func() {
+		if !writerClosed {
+			dp.writer.Close()
+		}
+	}()
+	defer dp.closeTransformers()
+
+	// Records of an acking reader are acknowledged as they are written,
+	// unless a transformer may hold them back, and again once the writer
+	// is closed.
+	acker, _ := dp.reader.(interfaces.AckingReader)
+	ackEach := acker != nil && !dp.holdsRecords()
+	// received counts the records the writer received, and read the
+	// records the reader returned in them.
+	var received, read int64
+	fail := func(err error) {
+		dp.writerStage().set(StageFailed)
+		dp.fail(err)
+	}
+	completed := func(batch batchInfo) {
+		atomic.AddInt64(&dp.metrics.BatchesCompleted, batch.count)
+		atomic.AddInt64(&dp.metrics.RowsCompleted, batch.rows)
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Context canceled, stopping writer.")
+			dp.logger.Debug("writer stopped", "reason", "canceled")
 			return
 		case record, ok := <-ch:
 			if !ok {
-				log.Println("Channel closed, stopping writer.")
+				if err := dp.flush(); err != nil {
+					dp.logger.Error("flush failed", "error", err)
+					fail(err)
+					return
+				}
+				if acker != nil {
+					writerClosed = true
+					if err := dp.writer.Close(); err != nil {
+						fail(fmt.Errorf("writer error: %w", err))
+						return
+					}
+					if err := acker.Acknowledge(read, true); err != nil {
+						fail(fmt.Errorf("acknowledge error: %w", err))
+						return
+					}
+				}
+				for _, s := range dp.stages[1:] {
+					s.state.CompareAndSwap(int32(StageRunning), int32(StageDone))
+				}
+				dp.logger.Debug("writer finished", "stage", dp.writerStage().name, "rows", dp.writerStage().rows.Load())
 				return // Exit the writer when channel is closed
 			}
+			received++
+			batch, ok := dp.batches.pop()
+			if !ok {
+				batch = batchInfo{seq: received - 1, count: 1}
+			}
+			read += batch.count
 
 			if record == nil || record.NumCols() == 0 || record.NumRows() == 0 {
-				log.Println("Received empty or invalid record, skipping.")
+				dp.logger.Debug("skipping empty record", "stage", dp.writerStage().name)
 				record.Release() // Release the invalid or empty record to avoid memory leaks
 				continue
 			}
 
-			if err := dp.writer.Write(record); err != nil {
-				log.Printf("Error writing record: %v", err)
-				select {
-				case dp.errCh <- fmt.Errorf("writer error: %w", err):
-				default:
-					log.Printf("Error channel full, discarding error: %v", err)
+			record, failed, err := dp.transform(record)
+			if err != nil {
+				err = batch.fail(failed.name, err)
+				dp.logger.Error("transform failed", "error", err)
+				dp.fail(fmt.Errorf("transform error: %w", err))
+				return
+			}
+			if record == nil {
+				completed(batch)
+				if ackEach {
+					if err := acker.Acknowledge(read, false); err != nil {
+						fail(fmt.Errorf("acknowledge error: %w", err))
+						return
+					}
 				}
+				continue
+			}
+
+			if err := dp.write(record); err != nil {
+				err = batch.fail(dp.writerStage().name, err)
+				dp.logger.Error("write failed", "stage", dp.writerStage().name, "error", err)
+				fail(fmt.Errorf("writer error: %w", err))
 				record.Release()
 				return
 			}
+			dp.writerStage().rows.Add(record.NumRows())
+			record.Release()
+			completed(batch)
+			if ackEach {
+				if err := acker.Acknowledge(read, false); err != nil {
+					fail(fmt.Errorf("acknowledge error: %w", err))
+					return
+				}
+			}
+		}
+	}
+}
+
+// fail reports err as the pipeline's error, unless another stage failed
+// first, and records the batch it failed on, if any.
+func (dp *DataPipeline) fail(err error) {
+	dp.errMu.Lock()
+	if dp.failErr != nil {
+		dp.errMu.Unlock()
+		dp.logger.Warn("error discarded, another stage failed first", "error", err)
+		return
+	}
+	dp.failErr = err
+	errors.As(err, &dp.failure)
+	dp.errMu.Unlock()
+	dp.errCh <- err
+}
+
+// read reads the next record, failing if the pipeline's allocator ran out
+// of budget, even if the reader recovered from it.
+func (dp *DataPipeline) read() (_ arrow.Record, err error) {
+	defer pool.RecoverLimitError(&err)
+	start := time.Now()
+	record, err := dp.reader.Read()
+	if err == nil && record != nil {
+		dp.readerStage().latency.record(time.Since(start))
+	}
+	if err == nil && dp.allocator != nil {
+		if limitErr := dp.allocator.Err(); limitErr != nil {
+			if record != nil {
+				record.Release()
+			}
+			return nil, limitErr
+		}
+	}
+	return record, err
+}
+
+// transformOne runs one transformer, turning an allocation over the memory
+// limit into an error.
+func (dp *DataPipeline) transformOne(t interfaces.Transformer, record arrow.Record) (_ arrow.Record, err error) {
+	defer pool.RecoverLimitError(&err)
+	return t.Transform(record)
+}
+
+// write writes a record, turning an allocation over the memory limit into
+// an error.
+func (dp *DataPipeline) write(record arrow.Record) (err error) {
+	defer pool.RecoverLimitError(&err)
+	start := time.Now()
+	if err := dp.writer.Write(record); err != nil {
+		return err
+	}
+	dp.writerStage().latency.record(time.Since(start))
+	if dp.written != nil {
+		dp.written.Add(record)
+	}
+	return nil
+}
+
+// verify reads the output back and compares it with the records written,
+// unless the pipeline failed.
+func (dp *DataPipeline) verify(ctx context.Context) {
+	dp.errMu.Lock()
+	failed := dp.failErr != nil
+	dp.errMu.Unlock()
+	if failed {
+		return
+	}
+	reader, err := dp.verifyOpen(ctx)
+	if err != nil {
+		dp.fail(fmt.Errorf("verification error: %w", err))
+		return
+	}
+	actual, err := verify.DigestReader(reader)
+	reader.Close()
+	if err != nil {
+		dp.fail(fmt.Errorf("verification error: %w", err))
+		return
+	}
+	result := verify.Compare(dp.written.Digest(), actual)
+	dp.metrics.Verification = result
+	if err := result.Err(); err != nil {
+		dp.logger.Error("verification failed", "error", err)
+		dp.fail(err)
+		return
+	}
+	dp.logger.Info("verification passed", "rows", result.ActualRows, "columns", len(result.Columns))
+}
+
+// reportLeaks logs the buffers the debug allocator found unreleased.
+func (dp *DataPipeline) reportLeaks(leaks []pool.Leak) {
+	if len(leaks) == 0 {
+		return
+	}
+	var total int64
+	for _, leak := range leaks {
+		total += leak.Bytes
+	}
+	atomic.StoreInt64(&dp.metrics.UnreleasedBytes, total)
+	dp.logger.Warn("pipeline left memory unreleased", "bytes", total, "buffers", len(leaks), "leaks", pool.FormatLeaks(leaks))
+}
+
+// holdsRecords reports whether a transformer may hold records back until
+// a later record or the end of the input.
+func (dp *DataPipeline) holdsRecords() bool {
+	for _, t := range dp.transformers {
+		if _, ok := t.(interfaces.FlushingTransformer); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// transform runs record through the transformers, releasing each
+// intermediate record. It returns nil if a stage dropped the record or left
+// it empty, and the stage that failed along with its error.
+func (dp *DataPipeline) transform(record arrow.Record) (arrow.Record, *stage, error) {
+	return dp.transformFrom(0, record)
+}
+
+// transformFrom runs record through the transformers starting at index
+// start.
+func (dp *DataPipeline) transformFrom(start int, record arrow.Record) (arrow.Record, *stage, error) {
+	for i, t := range dp.transformers[start:] {
+		s := dp.transformerStage(start + i)
+		began := time.Now()
+		out, err := dp.transformOne(t, record)
+		s.latency.record(time.Since(began))
+		record.Release()
+		if err != nil {
+			s.set(StageFailed)
+			return nil, s, err
+		}
+		if out == nil {
+			return nil, nil, nil
+		}
+		s.rows.Add(out.NumRows())
+		if out.NumRows() == 0 {
+			out.Release()
+			return nil, nil, nil
+		}
+		record = out
+	}
+	return record, nil, nil
+}
+
+// flush drains the transformers that hold records back, in order, passing
+// what each returns through the stages after it and on to the writer.
+func (dp *DataPipeline) flush() (err error) {
+	defer pool.RecoverLimitError(&err)
+	for i, t := range dp.transformers {
+		f, ok := t.(interfaces.FlushingTransformer)
+		if !ok {
+			continue
+		}
+		record, err := f.Flush()
+		if err != nil {
+			return fmt.Errorf("transform error: %w", err)
+		}
+		if record == nil {
+			continue
+		}
+		if record.NumRows() == 0 {
 			record.Release()
+			continue
+		}
+		dp.transformerStage(i).rows.Add(record.NumRows())
+		record, _, err = dp.transformFrom(i+1, record)
+		if err != nil {
+			return fmt.Errorf("transform error: %w", err)
+		}
+		if record == nil {
+			continue
+		}
+		rows := record.NumRows()
+		err = dp.write(record)
+		record.Release()
+		if err != nil {
+			return fmt.Errorf("writer error: %w", err)
+		}
+		dp.writerStage().rows.Add(rows)
+	}
+	return nil
+}
+
+// closeTransformers closes the transformers that hold resources, such as a
+// dead-letter writer.
+func (dp *DataPipeline) closeTransformers() {
+	for _, t := range dp.transformers {
+		if c, ok := t.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				dp.logger.Warn("failed to close transformer", "stage", stageName(t), "error", err)
+			}
+		}
+	}
+}
+
+// transformCounts collects the counters of the transformers that keep them,
+// keyed by transformer name.
+func (dp *DataPipeline) transformCounts() map[string]map[string]int64 {
+	var counts map[string]map[string]int64
+	for i, t := range dp.transformers {
+		c, ok := t.(interfaces.CountingTransformer)
+		if !ok {
+			continue
+		}
+		if counts == nil {
+			counts = make(map[string]map[string]int64)
+		}
+		name := c.Name()
+		if _, exists := counts[name]; exists {
+			name = fmt.Sprintf("%s#%d", name, i)
 		}
+		counts[name] = c.Counts()
 	}
+	return counts
 }
 
 // PrettyPrint marshals the provided value into a pretty-printed JSON string.
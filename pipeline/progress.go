@@ -0,0 +1,263 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+)
+
+// StageState is the status of one pipeline stage.
+type StageState int32
+
+const (
+	StagePending StageState = iota
+	StageRunning
+	StageDone
+	StageFailed
+)
+
+func (s StageState) String() string {
+	switch s {
+	case StagePending:
+		return "pending"
+	case StageRunning:
+		return "running"
+	case StageDone:
+		return "done"
+	case StageFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("StageState(%d)", int32(s))
+	}
+}
+
+// StageProgress describes one stage: the reader, a transformer or the
+// writer. Rows counts the rows the stage has produced so far.
+type StageProgress struct {
+	Name  string
+	State StageState
+	Rows  int64
+}
+
+// Progress is a snapshot of a running pipeline.
+type Progress struct {
+	Records      int64
+	Bytes        int64
+	Elapsed      time.Duration
+	ExpectedRows int64 // zero when the reader cannot tell
+	// SourceBytes and SourceSize are the bytes of its file the reader has
+	// consumed and the size of the file, zero when the reader cannot tell.
+	SourceBytes int64
+	SourceSize  int64
+	Stages      []StageProgress
+	Done        bool
+	Err         error
+}
+
+// RecordsPerSec returns the average number of rows read per second.
+func (p Progress) RecordsPerSec() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Records) / p.Elapsed.Seconds()
+}
+
+// BytesPerSec returns the average number of bytes read per second.
+func (p Progress) BytesPerSec() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / p.Elapsed.Seconds()
+}
+
+// Fraction returns the share of the expected rows read so far, or else of
+// the source file, or false if neither total is known.
+func (p Progress) Fraction() (float64, bool) {
+	switch {
+	case p.ExpectedRows > 0:
+		return min(1, float64(p.Records)/float64(p.ExpectedRows)), true
+	case p.SourceSize > 0:
+		return min(1, float64(p.SourceBytes)/float64(p.SourceSize)), true
+	}
+	return 0, false
+}
+
+// ETA estimates the time left at the average rate so far, or false if the
+// total is unknown or nothing has been read yet.
+func (p Progress) ETA() (time.Duration, bool) {
+	f, ok := p.Fraction()
+	if !ok || f <= 0 || p.Elapsed <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(p.Elapsed) * (1 - f) / f), true
+}
+
+// ProgressEvent is what a pipeline passes to the function given to
+// WithProgress: a snapshot of its progress with the estimates made from it.
+type ProgressEvent struct {
+	Progress
+	// Percent is the share of the source read so far, from 0 to 100, or -1
+	// when its size is unknown.
+	Percent float64
+	// Remaining is the estimated time left, or -1 when unknown.
+	Remaining time.Duration
+}
+
+func newProgressEvent(p Progress) ProgressEvent {
+	e := ProgressEvent{Progress: p, Percent: -1, Remaining: -1}
+	if f, ok := p.Fraction(); ok {
+		e.Percent = f * 100
+	}
+	if eta, ok := p.ETA(); ok {
+		e.Remaining = eta
+	}
+	return e
+}
+
+// DefaultProgressInterval is how often a pipeline calls the function given
+// to WithProgress, unless WithProgressInterval sets another period.
+const DefaultProgressInterval = time.Second
+
+// WithProgress calls fn with the pipeline's progress periodically while it
+// runs, and a last time when Start returns, with Done set and the error
+// Start returns, if any. Calls are made from one goroutine at a time, and
+// fn must return quickly. It must be called before Start.
+func (dp *DataPipeline) WithProgress(fn func(ProgressEvent)) *DataPipeline {
+	dp.onProgress = fn
+	return dp
+}
+
+// WithProgressInterval sets the period of the calls to the function given
+// to WithProgress, DefaultProgressInterval by default. It must be called
+// before Start.
+func (dp *DataPipeline) WithProgressInterval(d time.Duration) *DataPipeline {
+	dp.progressInterval = d
+	return dp
+}
+
+// startProgress calls the progress function every interval until the
+// function it returns is called, which calls it a last time with the
+// outcome of the run.
+func (dp *DataPipeline) startProgress() func(err error) {
+	interval := dp.progressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				dp.onProgress(newProgressEvent(dp.Progress()))
+			}
+		}
+	}()
+	return func(err error) {
+		close(stop)
+		<-stopped
+		p := dp.Progress()
+		p.Done, p.Err = true, err
+		dp.onProgress(newProgressEvent(p))
+	}
+}
+
+// Monitor follows a pipeline run, for example to display its progress.
+// Start is called once the pipeline is running and Stop when Start returns,
+// with the error it returns, if any.
+type Monitor interface {
+	Start(dp *DataPipeline)
+	Stop(err error)
+}
+
+// stage tracks the state of one stage while the pipeline runs.
+type stage struct {
+	name    string
+	state   atomic.Int32
+	rows    atomic.Int64
+	latency latencyHistogram
+}
+
+func (s *stage) set(state StageState) {
+	s.state.Store(int32(state))
+}
+
+// readerStage, transformerStage and writerStage return the stages tracked
+// for the reader, the i-th transformer and the writer.
+func (dp *DataPipeline) readerStage() *stage { return dp.stages[0] }
+
+func (dp *DataPipeline) transformerStage(i int) *stage { return dp.stages[1+i] }
+
+func (dp *DataPipeline) writerStage() *stage { return dp.stages[len(dp.stages)-1] }
+
+// stageName names a stage after its Name method or its type.
+func stageName(v any) string {
+	if n, ok := v.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", v), "*")
+}
+
+// Progress returns a snapshot of the pipeline's progress. It is safe to
+// call while the pipeline runs.
+func (dp *DataPipeline) Progress() Progress {
+	p := Progress{
+		Records:      atomic.LoadInt64(&dp.metrics.RecordsProcessed),
+		Bytes:        atomic.LoadInt64(&dp.metrics.TotalBytes),
+		ExpectedRows: dp.expectedRows,
+		Stages:       make([]StageProgress, len(dp.stages)),
+		Done:         dp.done.Load(),
+	}
+	if end := atomic.LoadInt64(&dp.metrics.endTimeUnix); end > 0 {
+		p.Elapsed = time.Unix(0, end).Sub(dp.metrics.StartTime)
+	} else {
+		p.Elapsed = time.Since(dp.metrics.StartTime)
+	}
+	if sized, ok := dp.reader.(interfaces.ByteSizedReader); ok {
+		p.SourceBytes, p.SourceSize = sized.BytesRead(), sized.Size()
+	}
+	dp.errMu.Lock()
+	p.Err = dp.runErr
+	dp.errMu.Unlock()
+	for i, s := range dp.stages {
+		p.Stages[i] = StageProgress{Name: s.name, State: StageState(s.state.Load()), Rows: s.rows.Load()}
+	}
+	return p
+}
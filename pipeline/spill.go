@@ -0,0 +1,280 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// queuedRecord is a record held in memory by a spillQueue.
+type queuedRecord struct {
+	record arrow.Record
+	size   int64
+}
+
+// spillQueue is a FIFO of records that keeps up to threshold bytes in
+// memory and writes the rest to spill files. Once a record is spilled,
+// later ones are spilled too until the files are drained, so records come
+// out in the order they went in. It is used by a single goroutine.
+type spillQueue struct {
+	dir       string
+	threshold int64
+	mem       memory.Allocator
+	metrics   *Metrics
+	logger    *slog.Logger
+
+	queued      []queuedRecord
+	queuedBytes int64
+	files       []*spillFile
+}
+
+func newSpillQueue(dir string, threshold int64, mem memory.Allocator, metrics *Metrics, logger *slog.Logger) *spillQueue {
+	return &spillQueue{dir: dir, threshold: threshold, mem: mem, metrics: metrics, logger: logger}
+}
+
+func (q *spillQueue) empty() bool {
+	return len(q.queued) == 0 && len(q.files) == 0
+}
+
+// push adds record to the queue, which takes ownership of it. A record
+// is kept in memory if it fits under the threshold, or if the queue is
+// empty so that it would be read back right away.
+func (q *spillQueue) push(record arrow.Record) error {
+	size := calculateRecordSize(record)
+	if q.empty() || (len(q.files) == 0 && q.queuedBytes+size <= q.threshold) {
+		q.queued = append(q.queued, queuedRecord{record, size})
+		q.queuedBytes += size
+		return nil
+	}
+	defer record.Release()
+
+	var f *spillFile
+	if n := len(q.files); n > 0 && q.files[n-1].schema.Equal(record.Schema()) {
+		f = q.files[n-1]
+	} else {
+		var err error
+		if f, err = newSpillFile(q.dir, record.Schema(), q.mem); err != nil {
+			return err
+		}
+		q.files = append(q.files, f)
+	}
+	if err := f.write(record); err != nil {
+		return err
+	}
+	atomic.AddInt64(&q.metrics.SpilledRecords, 1)
+	atomic.AddInt64(&q.metrics.SpilledBytes, size)
+	return nil
+}
+
+// peek returns the oldest record without removing it, reading it back
+// from disk if needed, or nil if the queue is empty.
+func (q *spillQueue) peek() (arrow.Record, error) {
+	if len(q.queued) > 0 {
+		return q.queued[0].record, nil
+	}
+	if len(q.files) == 0 {
+		return nil, nil
+	}
+	f := q.files[0]
+	record, err := f.next()
+	if err != nil {
+		return nil, err
+	}
+	if f.read == f.written {
+		q.files = q.files[1:]
+		if err := f.remove(); err != nil {
+			record.Release()
+			return nil, err
+		}
+	}
+	size := calculateRecordSize(record)
+	q.queued = append(q.queued, queuedRecord{record, size})
+	q.queuedBytes += size
+	return record, nil
+}
+
+// pop removes the oldest record, handing its ownership to the caller.
+func (q *spillQueue) pop() {
+	q.queuedBytes -= q.queued[0].size
+	q.queued[0] = queuedRecord{}
+	q.queued = q.queued[1:]
+}
+
+// close releases the queued records and removes the spill files.
+func (q *spillQueue) close() {
+	for _, r := range q.queued {
+		r.record.Release()
+	}
+	q.queued, q.queuedBytes = nil, 0
+	for _, f := range q.files {
+		if err := f.remove(); err != nil {
+			q.logger.Warn("failed to remove spill file", "error", err)
+		}
+	}
+	q.files = nil
+}
+
+// spillFile is an LZ4-compressed Arrow IPC stream of records with the same
+// schema, appended to through one handle and read back through another.
+type spillFile struct {
+	schema *arrow.Schema
+	mem    memory.Allocator
+	path   string
+
+	out    *os.File
+	buf    *bufio.Writer
+	writer *ipc.Writer
+	in     *os.File
+	reader *ipc.Reader
+
+	written, read int
+}
+
+func newSpillFile(dir string, schema *arrow.Schema, mem memory.Allocator) (*spillFile, error) {
+	out, err := os.CreateTemp(dir, "arrowarc-spill-*.arrows")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+	buf := bufio.NewWriter(out)
+	return &spillFile{
+		schema: schema,
+		mem:    mem,
+		path:   out.Name(),
+		out:    out,
+		buf:    buf,
+		writer: ipc.NewWriter(buf, ipc.WithSchema(schema), ipc.WithLZ4(), ipc.WithAllocator(mem)),
+	}, nil
+}
+
+func (f *spillFile) write(record arrow.Record) error {
+	if err := f.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to spill record: %w", err)
+	}
+	// Flush so that the record can be read back whole.
+	if err := f.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to spill record: %w", err)
+	}
+	f.written++
+	return nil
+}
+
+// next reads back the oldest record not read yet. The caller owns it.
+func (f *spillFile) next() (arrow.Record, error) {
+	if f.reader == nil {
+		in, err := os.Open(f.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open spill file: %w", err)
+		}
+		f.in = in
+		if f.reader, err = ipc.NewReader(bufio.NewReader(in), ipc.WithAllocator(f.mem)); err != nil {
+			return nil, fmt.Errorf("failed to read spill file: %w", err)
+		}
+	}
+	if !f.reader.Next() {
+		err := f.reader.Err()
+		if err == nil {
+			err = fmt.Errorf("%d of %d records missing", f.written-f.read, f.written)
+		}
+		return nil, fmt.Errorf("failed to read spill file: %w", err)
+	}
+	f.read++
+	record := f.reader.Record()
+	record.Retain()
+	return record, nil
+}
+
+func (f *spillFile) remove() error {
+	if f.reader != nil {
+		f.reader.Release()
+		f.in.Close()
+	}
+	f.writer.Close()
+	f.out.Close()
+	return os.Remove(f.path)
+}
+
+// startSpill moves records from in to out, spilling them to disk while
+// the writer lags too far behind the reader.
+func (dp *DataPipeline) startSpill(ctx context.Context, in <-chan arrow.Record, out chan<- arrow.Record, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer close(out)
+	q := newSpillQueue(dp.spillDir, dp.spillThreshold, dp.Allocator(), dp.metrics, dp.logger)
+	defer q.close()
+	// Release whatever the reader still sends if the queue stops early.
+	defer func() {
+		for record := range in {
+			record.Release()
+		}
+	}()
+
+	fail := func(err error) {
+		dp.logger.Error("spill failed", "error", err)
+		dp.fail(fmt.Errorf("spill error: %w", err))
+	}
+
+	src := in
+	for src != nil || !q.empty() {
+		head, err := q.peek()
+		if err != nil {
+			fail(err)
+			return
+		}
+		var dst chan<- arrow.Record
+		if head != nil {
+			dst = out
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case record, ok := <-src:
+			if !ok {
+				src = nil
+				continue
+			}
+			if err := q.push(record); err != nil {
+				fail(err)
+				return
+			}
+		case dst <- head:
+			q.pop()
+		}
+	}
+}
@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
+	"strconv"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/extensions"
 	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pkg/geo"
 	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
 	"golang.org/x/exp/constraints"
 	"google.golang.org/genproto/googleapis/type/date"
@@ -16,7 +20,6 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
-	"google.golang.org/protobuf/runtime/protoimpl"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -24,16 +27,49 @@ import (
 const (
 	maxDepth = 10
 	Unknown  = "@unknown"
+
+	// Layouts for civil (zone-less) values, as accepted by BigQuery string columns.
+	civilDateLayout     = "2006-01-02"
+	civilTimeLayout     = "15:04:05.999999"
+	civilDateTimeLayout = "2006-01-02 15:04:05.999999"
+	millisecondsPerDay  = 24 * 60 * 60 * 1000
 )
 
 var (
-	ErrMaxDepth       = errors.New("max depth reached, either the message is deeply nested or a circular dependency was introduced")
-	otelAnyDescriptor = (&commonv1.AnyValue{}).ProtoReflect().Descriptor()
+	ErrMaxDepth        = errors.New("max depth reached, either the message is deeply nested or a circular dependency was introduced")
+	ErrUnsupportedType = errors.New("unsupported type")
+	ErrOutOfRange      = errors.New("value out of range")
+	ErrFieldNotFound   = errors.New("field not found")
+	otelAnyDescriptor  = (&commonv1.AnyValue{}).ProtoReflect().Descriptor()
 )
 
+// FieldError reports a conversion failure together with the path of the field
+// that caused it, e.g. "struct_list[2].sub_int_col".
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %s: %v", e.Path, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError wraps err with path unless it already carries a (deeper) field path.
+func fieldError(path string, err error) error {
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		return err
+	}
+	return &FieldError{Path: path, Err: err}
+}
+
 // Helper types
 type valueFn func(protoreflect.Value, bool) error
-type encodeFn func(value protoreflect.Value, a arrow.Array, row int) protoreflect.Value
+type encodeFn func(value protoreflect.Value, a arrow.Array, row int) (protoreflect.Value, error)
 
 // Node represents a mapping between proto fields and Arrow schema.
 type node struct {
@@ -55,6 +91,15 @@ func ConvertArrowRecordToProtoMessages(record arrow.Record, messageType proto.Me
 	if record == nil {
 		return nil, errors.New("arrow record is nil")
 	}
+	if messageType == nil {
+		return nil, errors.New("message type is nil")
+	}
+	// Geometries are sent as WKT.
+	record, err := geo.WKTRecord(memory.DefaultAllocator, record)
+	if err != nil {
+		return nil, err
+	}
+	defer record.Release()
 
 	numRows := int(record.NumRows())
 	messages := make([]proto.Message, numRows)
@@ -85,20 +130,17 @@ func unmarshalRow(record arrow.Record, row int, msg protoreflect.Message) error
 			continue
 		}
 
-		value, err := getArrowValue(col, row, fd)
-		if err != nil {
-			return fmt.Errorf("error getting value for field %s: %w", fd.Name(), err)
+		if err := setProtoField(msg, fd, col, row, string(fd.Name())); err != nil {
+			return err
 		}
-
-		setProtoField(msg, fd, value)
 	}
 
-	handleExtensions(record, row, msg)
-	return nil
+	return handleExtensions(record, row, msg)
 }
 
 // handleExtensions processes extensions for a proto message.
-func handleExtensions(record arrow.Record, row int, msg protoreflect.Message) {
+func handleExtensions(record arrow.Record, row int, msg protoreflect.Message) error {
+	var err error
 	protoregistry.GlobalTypes.RangeExtensionsByMessage(msg.Descriptor().FullName(), func(xt protoreflect.ExtensionType) bool {
 		xd := xt.TypeDescriptor()
 		colIndex := record.Schema().FieldIndices(string(xd.Name()))
@@ -111,39 +153,87 @@ func handleExtensions(record arrow.Record, row int, msg protoreflect.Message) {
 			return true
 		}
 
-		value, err := getArrowValue(col, row, xd)
-		if err != nil {
-			return true
-		}
-
-		msg.Set(xd, protoreflect.ValueOf(value))
-		return true
+		err = setProtoField(msg, xd, col, row, string(xd.Name()))
+		return err == nil
 	})
+	return err
 }
 
-// setProtoField sets the value of a field in a proto message based on its descriptor.
-func setProtoField(msg protoreflect.Message, fd protoreflect.FieldDescriptor, value interface{}) {
+// setProtoField sets the value of a field in a proto message from the given Arrow column and row.
+func setProtoField(msg protoreflect.Message, fd protoreflect.FieldDescriptor, col arrow.Array, row int, path string) error {
 	switch {
-	case fd.IsList():
-		list := msg.Mutable(fd).List()
-		listValue := reflect.ValueOf(value)
-		for j := 0; j < listValue.Len(); j++ {
-			list.Append(protoreflect.ValueOf(listValue.Index(j).Interface()))
-		}
 	case fd.IsMap():
+		arr, ok := col.(*array.Map)
+		if !ok {
+			return fieldError(path, fmt.Errorf("%w: cannot assign Arrow %s to map field", ErrUnsupportedType, col.DataType()))
+		}
 		mapValue := msg.Mutable(fd).Map()
-		mapReflect := reflect.ValueOf(value)
-		for _, key := range mapReflect.MapKeys() {
-			mapKey := fieldValue(fd.MapKey(), key.Interface())
-			mapVal := fieldValue(fd.MapValue(), mapReflect.MapIndex(key).Interface())
-			mapValue.Set(mapKey.MapKey(), mapVal)
+		start, end := arr.ValueOffsets(row)
+		for i := start; i < end; i++ {
+			elemPath := fmt.Sprintf("%s[%d]", path, i-start)
+			key, err := elementValue(nil, fd.MapKey(), arr.Keys(), int(i), elemPath+".key")
+			if err != nil {
+				return err
+			}
+			value, err := elementValue(mapValue.NewValue, fd.MapValue(), arr.Items(), int(i), elemPath+".value")
+			if err != nil {
+				return err
+			}
+			mapValue.Set(key.MapKey(), value)
+		}
+	case fd.IsList():
+		arr, ok := col.(array.ListLike)
+		if !ok {
+			return fieldError(path, fmt.Errorf("%w: cannot assign Arrow %s to repeated field", ErrUnsupportedType, col.DataType()))
+		}
+		list := msg.Mutable(fd).List()
+		values := arr.ListValues()
+		start, end := arr.ValueOffsets(row)
+		for i := start; i < end; i++ {
+			value, err := elementValue(list.NewElement, fd, values, int(i), fmt.Sprintf("%s[%d]", path, i-start))
+			if err != nil {
+				return err
+			}
+			list.Append(value)
 		}
 	default:
-		msg.Set(fd, protoreflect.ValueOf(value))
+		value, err := elementValue(func() protoreflect.Value { return msg.NewField(fd) }, fd, col, row, path)
+		if err != nil {
+			return err
+		}
+		msg.Set(fd, value)
+	}
+	return nil
+}
+
+// elementValue converts a single (non-repeated) Arrow value into a proto value for fd.
+// newValue allocates a mutable message value when fd is a message field.
+func elementValue(newValue func() protoreflect.Value, fd protoreflect.FieldDescriptor, col arrow.Array, row int, path string) (protoreflect.Value, error) {
+	if arr, ok := col.(*array.Struct); ok {
+		if fd.Message() == nil || newValue == nil {
+			return protoreflect.Value{}, fieldError(path, fmt.Errorf("%w: cannot assign Arrow struct to %v field", ErrUnsupportedType, fd.Kind()))
+		}
+		value := newValue()
+		if err := setStructValue(arr, row, value.Message(), path); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return value, nil
+	}
+
+	raw, err := getArrowValue(col, row, fd)
+	if err != nil {
+		return protoreflect.Value{}, fieldError(path, err)
 	}
+	value, err := fieldValue(fd, raw)
+	if err != nil {
+		return protoreflect.Value{}, fieldError(path, err)
+	}
+	return value, nil
 }
 
-// getArrowValue converts an Arrow column value to the corresponding Go type compatible with Proto field.
+// getArrowValue converts a scalar Arrow value to the corresponding Go type compatible with Proto fields.
+// Temporal and decimal values are rendered according to the kind of fd, so that the same
+// column can target well-known message types or the scalar encodings used by BigQuery.
 func getArrowValue(col arrow.Array, row int, fd protoreflect.FieldDescriptor) (interface{}, error) {
 	switch arr := col.(type) {
 	case *array.Boolean:
@@ -152,73 +242,122 @@ func getArrowValue(col arrow.Array, row int, fd protoreflect.FieldDescriptor) (i
 		return int32(arr.Value(row)), nil
 	case *array.Int16:
 		return int32(arr.Value(row)), nil
-	case *array.Int32, *array.Int64, *array.Uint8, *array.Uint16, *array.Uint32, *array.Uint64, *array.Float32, *array.Float64, *array.String, *array.Binary:
-		return arr.(interface{ Value(int) interface{} }).Value(row), nil
+	case *array.Int32:
+		return arr.Value(row), nil
+	case *array.Int64:
+		return arr.Value(row), nil
+	case *array.Uint8:
+		return uint32(arr.Value(row)), nil
+	case *array.Uint16:
+		return uint32(arr.Value(row)), nil
+	case *array.Uint32:
+		return arr.Value(row), nil
+	case *array.Uint64:
+		return arr.Value(row), nil
+	case *array.Float32:
+		return arr.Value(row), nil
+	case *array.Float64:
+		return arr.Value(row), nil
+	case *array.String:
+		return arr.Value(row), nil
+	case *array.LargeString:
+		return arr.Value(row), nil
+	case *array.Binary:
+		return arr.Value(row), nil
+	case *array.LargeBinary:
+		return arr.Value(row), nil
 	case *array.Timestamp:
-		return timestampToProto(arr, row, fd)
+		t := arr.Value(row).ToTime(arr.DataType().(*arrow.TimestampType).Unit)
+		switch fd.Kind() {
+		case protoreflect.MessageKind:
+			return timestamppb.New(t), nil
+		case protoreflect.StringKind:
+			return t.UTC().Format(civilDateTimeLayout), nil
+		default:
+			return t.UnixMicro(), nil
+		}
 	case *array.Date32:
-		return dateToProto32(arr, row)
+		switch fd.Kind() {
+		case protoreflect.MessageKind:
+			return dateToProto32(arr, row), nil
+		case protoreflect.StringKind:
+			return arr.Value(row).ToTime().Format(civilDateLayout), nil
+		default:
+			return int32(arr.Value(row)), nil
+		}
 	case *array.Date64:
-		return dateToProto64(arr, row)
+		switch fd.Kind() {
+		case protoreflect.MessageKind:
+			return dateToProto64(arr, row), nil
+		case protoreflect.StringKind:
+			return arr.Value(row).ToTime().Format(civilDateLayout), nil
+		default:
+			return int32(int64(arr.Value(row)) / millisecondsPerDay), nil
+		}
 	case *array.Time32:
-		return time32ToProto(arr, row)
+		switch fd.Kind() {
+		case protoreflect.MessageKind:
+			return time32ToProto(arr, row), nil
+		case protoreflect.StringKind:
+			unit := arr.DataType().(*arrow.Time32Type).Unit
+			return arr.Value(row).ToTime(unit).Format(civilTimeLayout), nil
+		default:
+			return int64(arr.Value(row)), nil
+		}
 	case *array.Time64:
-		return time64ToProto(arr, row)
-	case *array.List:
-		return getListValue(arr, row, fd)
-	case *array.Struct:
-		return getStructValue(arr, row, fd)
-	case *array.Map:
-		return getMapValue(arr, row, fd)
-	default:
-		return nil, fmt.Errorf("unsupported Arrow type: %T", col)
-	}
-}
-
-// Helper functions for handling specific types
-
-func getMapValue(arr *array.Map, row int, fd protoreflect.FieldDescriptor) (interface{}, error) {
-	start, end := arr.ValueOffsets(row)
-	mapValue := make(map[interface{}]interface{}, end-start)
-	for i := start; i < end; i++ {
-		key, err := getArrowValue(arr.Keys(), int(i), fd.MapKey())
-		if err != nil {
-			return nil, fmt.Errorf("error converting map key for field %s: %w", fd.Name(), err)
+		switch fd.Kind() {
+		case protoreflect.MessageKind:
+			return time64ToProto(arr, row), nil
+		case protoreflect.StringKind:
+			unit := arr.DataType().(*arrow.Time64Type).Unit
+			return arr.Value(row).ToTime(unit).Format(civilTimeLayout), nil
+		default:
+			return int64(arr.Value(row)), nil
 		}
-		value, err := getArrowValue(arr.Items(), int(i), fd.MapValue())
-		if err != nil {
-			return nil, fmt.Errorf("error converting map value for field %s: %w", fd.Name(), err)
+	case *array.Decimal128:
+		scale := arr.DataType().(*arrow.Decimal128Type).Scale
+		if fd.Kind() == protoreflect.DoubleKind || fd.Kind() == protoreflect.FloatKind {
+			return arr.Value(row).ToFloat64(scale), nil
+		}
+		return arr.Value(row).ToString(scale), nil
+	case *array.Decimal256:
+		scale := arr.DataType().(*arrow.Decimal256Type).Scale
+		if fd.Kind() == protoreflect.DoubleKind || fd.Kind() == protoreflect.FloatKind {
+			return arr.Value(row).ToFloat64(scale), nil
 		}
-		mapValue[key] = value
+		return arr.Value(row).ToString(scale), nil
+	case *extensions.UUIDArray:
+		return arr.ValueStr(row), nil
+	case *extensions.JSONArray:
+		return arr.ValueStr(row), nil
+	case array.ExtensionArray:
+		return getArrowValue(arr.Storage(), row, fd)
+	default:
+		return nil, fmt.Errorf("%w: Arrow %s", ErrUnsupportedType, col.DataType())
 	}
-	return mapValue, nil
 }
 
-func timestampToProto(arr *array.Timestamp, row int, fd protoreflect.FieldDescriptor) (*timestamppb.Timestamp, error) {
-	ts := arr.Value(row)
-	t := ts.ToTime(arr.DataType().(*arrow.TimestampType).Unit)
-	return timestamppb.New(t), nil
-}
+// Helper functions for handling specific types
 
-func dateToProto32(arr *array.Date32, row int) (*date.Date, error) {
+func dateToProto32(arr *array.Date32, row int) *date.Date {
 	dateVal := arr.Value(row).ToTime()
 	return &date.Date{
 		Year:  int32(dateVal.Year()),
 		Month: int32(dateVal.Month()),
 		Day:   int32(dateVal.Day()),
-	}, nil
+	}
 }
 
-func dateToProto64(arr *array.Date64, row int) (*date.Date, error) {
+func dateToProto64(arr *array.Date64, row int) *date.Date {
 	dateVal := arr.Value(row).ToTime()
 	return &date.Date{
 		Year:  int32(dateVal.Year()),
 		Month: int32(dateVal.Month()),
 		Day:   int32(dateVal.Day()),
-	}, nil
+	}
 }
 
-func time32ToProto(arr *array.Time32, row int) (*timeofday.TimeOfDay, error) {
+func time32ToProto(arr *array.Time32, row int) *timeofday.TimeOfDay {
 	timeVal := arr.Value(row)
 	millis := int64(timeVal)
 	return &timeofday.TimeOfDay{
@@ -226,126 +365,174 @@ func time32ToProto(arr *array.Time32, row int) (*timeofday.TimeOfDay, error) {
 		Minutes: int32((millis % 3600000) / 60000),
 		Seconds: int32((millis % 60000) / 1000),
 		Nanos:   int32((millis % 1000) * 1000000),
-	}, nil
+	}
 }
 
-func time64ToProto(arr *array.Time64, row int) (*timestamppb.Timestamp, error) {
+func time64ToProto(arr *array.Time64, row int) *timestamppb.Timestamp {
 	timeVal := arr.Value(row)
 	nanos := int64(timeVal)
 	return &timestamppb.Timestamp{
 		Seconds: nanos / 1000000000,
 		Nanos:   int32(nanos % 1000000000),
-	}, nil
-}
-
-func getListValue(arr *array.List, row int, fd protoreflect.FieldDescriptor) (interface{}, error) {
-	start, end := arr.ValueOffsets(row)
-	values := make([]interface{}, end-start)
-	for i := start; i < end; i++ {
-		value, err := getArrowValue(arr.ListValues(), int(i), fd.Message().Fields().ByNumber(1))
-		if err != nil {
-			return nil, err
-		}
-		values[i-start] = value
 	}
-	return values, nil
 }
 
-func getStructValue(arr *array.Struct, row int, fd protoreflect.FieldDescriptor) (interface{}, error) {
-	msgType := protoimpl.X.MessageTypeOf(fd.Message().FullName())
-	msg := msgType.New().Interface()
-
+// setStructValue fills msg from the struct array at row, matching child fields by name.
+func setStructValue(arr *array.Struct, row int, msg protoreflect.Message, path string) error {
+	fields := msg.Descriptor().Fields()
+	typ := arr.DataType().(*arrow.StructType)
 	for i := 0; i < arr.NumField(); i++ {
+		name := typ.Field(i).Name
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			continue
+		}
 		fieldArr := arr.Field(i)
-		fieldDesc := fd.Message().Fields().ByNumber(protoreflect.FieldNumber(i + 1))
-		value, err := getArrowValue(fieldArr, row, fieldDesc)
-		if err != nil {
-			return nil, fmt.Errorf("error converting Arrow value for field %s: %w", fieldDesc.Name(), err)
+		if fieldArr.IsNull(row) {
+			continue
+		}
+		if err := setProtoField(msg, fd, fieldArr, row, path+"."+name); err != nil {
+			return err
 		}
-		setProtoField(msg.ProtoReflect(), fieldDesc, value)
 	}
-
-	return msg, nil
+	return nil
 }
 
-// Simplified functions
-func fieldValue(fd protoreflect.FieldDescriptor, v any) protoreflect.Value {
+// fieldValue converts a Go value into a proto value of the kind expected by fd.
+func fieldValue(fd protoreflect.FieldDescriptor, v any) (protoreflect.Value, error) {
 	switch o := v.(type) {
+	case bool:
+		if fd.Kind() != protoreflect.BoolKind {
+			return protoreflect.Value{}, fmt.Errorf("%w: bool for kind %v", ErrUnsupportedType, fd.Kind())
+		}
+		return protoreflect.ValueOfBool(o), nil
 	case int:
+		return handleIntField(fd, int64(o))
+	case int32:
+		return handleIntField(fd, int64(o))
+	case int64:
 		return handleIntField(fd, o)
+	case uint32:
+		return handleUintField(fd, uint64(o))
+	case uint64:
+		return handleUintField(fd, o)
+	case float32:
+		return handleFloatField(fd, float64(o))
 	case float64:
 		return handleFloatField(fd, o)
 	case string:
 		return handleStringField(fd, o)
 	case []byte:
 		if fd.Kind() != protoreflect.BytesKind {
-			panic(fmt.Sprintf("%v: unsupported type []byte for kind %v", fd.FullName(), fd.Kind()))
+			return protoreflect.Value{}, fmt.Errorf("%w: []byte for kind %v", ErrUnsupportedType, fd.Kind())
 		}
-		return protoreflect.ValueOf(append([]byte{}, o...))
+		return protoreflect.ValueOf(append([]byte{}, o...)), nil
+	case proto.Message:
+		return handleMessageField(fd, o.ProtoReflect())
 	default:
-		panic(fmt.Sprintf("%v: unsupported value type %T for kind %v", fd.FullName(), v, fd.Kind()))
+		return protoreflect.Value{}, fmt.Errorf("%w: value type %T for kind %v", ErrUnsupportedType, v, fd.Kind())
 	}
 }
 
 // Helper functions for fieldValue
-func handleIntField(fd protoreflect.FieldDescriptor, o int) protoreflect.Value {
+func handleIntField(fd protoreflect.FieldDescriptor, o int64) (protoreflect.Value, error) {
 	switch fd.Kind() {
 	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
-		return checkRange(fd, int32(o), math.MinInt32, math.MaxInt32)
+		if err := checkRange(o, math.MinInt32, math.MaxInt32); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfInt32(int32(o)), nil
 	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
-		return checkRange(fd, uint32(o), 0, math.MaxUint32)
+		if err := checkRange(o, 0, math.MaxUint32); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfUint32(uint32(o)), nil
 	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
-		return protoreflect.ValueOf(int64(o))
+		return protoreflect.ValueOfInt64(o), nil
 	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
-		if o < 0 {
-			panic(fmt.Sprintf("%v: value %v out of range [%v, %v]", fd.FullName(), o, uint64(0), uint64(math.MaxUint64)))
+		if err := checkRange(o, 0, math.MaxInt64); err != nil {
+			return protoreflect.Value{}, err
 		}
-		return protoreflect.ValueOf(uint64(o))
+		return protoreflect.ValueOfUint64(uint64(o)), nil
 	case protoreflect.FloatKind:
-		return protoreflect.ValueOf(float32(o))
+		return protoreflect.ValueOfFloat32(float32(o)), nil
 	case protoreflect.DoubleKind:
-		return protoreflect.ValueOf(float64(o))
+		return protoreflect.ValueOfFloat64(float64(o)), nil
 	case protoreflect.EnumKind:
-		return protoreflect.ValueOf(protoreflect.EnumNumber(o))
+		if err := checkRange(o, math.MinInt32, math.MaxInt32); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(o)), nil
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(strconv.FormatInt(o, 10)), nil
 	default:
-		panic(fmt.Sprintf("%v: unsupported type int for kind %v", fd.FullName(), fd.Kind()))
+		return protoreflect.Value{}, fmt.Errorf("%w: int for kind %v", ErrUnsupportedType, fd.Kind())
 	}
 }
 
-func handleFloatField(fd protoreflect.FieldDescriptor, o float64) protoreflect.Value {
+func handleUintField(fd protoreflect.FieldDescriptor, o uint64) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(o), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if err := checkRange(o, 0, math.MaxUint32); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfUint32(uint32(o)), nil
+	default:
+		if err := checkRange(o, 0, math.MaxInt64); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return handleIntField(fd, int64(o))
+	}
+}
+
+func handleFloatField(fd protoreflect.FieldDescriptor, o float64) (protoreflect.Value, error) {
 	switch fd.Kind() {
 	case protoreflect.FloatKind:
 		if o < -math.MaxFloat32 || o > math.MaxFloat32 {
-			panic(fmt.Sprintf("%v: value %v out of range for float32", fd.FullName(), o))
+			return protoreflect.Value{}, fmt.Errorf("%w: %v for float32", ErrOutOfRange, o)
 		}
-		return protoreflect.ValueOf(float32(o))
+		return protoreflect.ValueOfFloat32(float32(o)), nil
 	case protoreflect.DoubleKind:
-		return protoreflect.ValueOf(o)
+		return protoreflect.ValueOfFloat64(o), nil
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(strconv.FormatFloat(o, 'f', -1, 64)), nil
 	default:
-		panic(fmt.Sprintf("%v: unsupported type float64 for kind %v", fd.FullName(), fd.Kind()))
+		return protoreflect.Value{}, fmt.Errorf("%w: float64 for kind %v", ErrUnsupportedType, fd.Kind())
 	}
 }
 
-func handleStringField(fd protoreflect.FieldDescriptor, o string) protoreflect.Value {
+func handleStringField(fd protoreflect.FieldDescriptor, o string) (protoreflect.Value, error) {
 	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(o), nil
 	case protoreflect.BytesKind:
-		return protoreflect.ValueOf([]byte(o))
+		return protoreflect.ValueOfBytes([]byte(o)), nil
 	case protoreflect.EnumKind:
 		enumVal := fd.Enum().Values().ByName(protoreflect.Name(o))
 		if enumVal == nil {
-			panic(fmt.Sprintf("%v: invalid enum value %v", fd.FullName(), o))
+			return protoreflect.Value{}, fmt.Errorf("invalid enum value %q for %v", o, fd.Enum().FullName())
 		}
-		return protoreflect.ValueOf(enumVal.Number())
+		return protoreflect.ValueOfEnum(enumVal.Number()), nil
 	default:
-		panic(fmt.Sprintf("%v: unsupported type string for kind %v", fd.FullName(), fd.Kind()))
+		return protoreflect.Value{}, fmt.Errorf("%w: string for kind %v", ErrUnsupportedType, fd.Kind())
+	}
+}
+
+func handleMessageField(fd protoreflect.FieldDescriptor, m protoreflect.Message) (protoreflect.Value, error) {
+	md := fd.Message()
+	if md == nil || md.FullName() != m.Descriptor().FullName() {
+		return protoreflect.Value{}, fmt.Errorf("%w: message %v for kind %v", ErrUnsupportedType, m.Descriptor().FullName(), fd.Kind())
 	}
+	return protoreflect.ValueOfMessage(m), nil
 }
 
-func checkRange[T constraints.Ordered](fd protoreflect.FieldDescriptor, val, min, max T) protoreflect.Value {
+func checkRange[T constraints.Ordered](val, min, max T) error {
 	if val < min || val > max {
-		panic(fmt.Sprintf("%v: value %v out of range [%v, %v]", fd.FullName(), val, min, max))
+		return fmt.Errorf("%w: %v not in [%v, %v]", ErrOutOfRange, val, min, max)
 	}
-	return protoreflect.ValueOf(val)
+	return nil
 }
 
 type message struct {
@@ -358,7 +545,7 @@ type message struct {
 type Value any
 
 // Build applies the template to a message.
-func (template Message) Build(m protoreflect.Message) {
+func (template Message) Build(m protoreflect.Message) error {
 	md := m.Descriptor()
 	fields := md.Fields()
 	exts := make(map[protoreflect.Name]protoreflect.FieldDescriptor)
@@ -369,7 +556,11 @@ func (template Message) Build(m protoreflect.Message) {
 	})
 	for k, v := range template {
 		if k == Unknown {
-			m.SetUnknown(protoreflect.RawFields(v.([]byte)))
+			raw, ok := v.([]byte)
+			if !ok {
+				return fmt.Errorf("%v.%v: %w: %T for unknown fields", md.FullName(), k, ErrUnsupportedType, v)
+			}
+			m.SetUnknown(protoreflect.RawFields(raw))
 			continue
 		}
 		fd := fields.ByName(k)
@@ -377,53 +568,92 @@ func (template Message) Build(m protoreflect.Message) {
 			fd = exts[k]
 		}
 		if fd == nil {
-			panic(fmt.Sprintf("%v.%v: not found", md.FullName(), k))
+			return fieldError(string(md.FullName()), fmt.Errorf("%w: %v", ErrFieldNotFound, k))
+		}
+		if err := assignField(m, fd, v); err != nil {
+			return fieldError(string(fd.FullName()), err)
 		}
-		assignField(m, fd, v)
 	}
+	return nil
 }
 
-func assignField(m protoreflect.Message, fd protoreflect.FieldDescriptor, v any) {
+func assignField(m protoreflect.Message, fd protoreflect.FieldDescriptor, v any) error {
 	switch {
 	case fd.IsList():
 		list := m.Mutable(fd).List()
 		s := reflect.ValueOf(v)
+		if s.Kind() != reflect.Slice && s.Kind() != reflect.Array {
+			return fmt.Errorf("%w: %T for repeated field", ErrUnsupportedType, v)
+		}
 		for i := 0; i < s.Len(); i++ {
 			if fd.Message() == nil {
-				list.Append(fieldValue(fd, s.Index(i).Interface()))
+				value, err := fieldValue(fd, s.Index(i).Interface())
+				if err != nil {
+					return err
+				}
+				list.Append(value)
 			} else {
 				e := list.NewElement()
-				s.Index(i).Interface().(Message).Build(e.Message())
+				if err := buildNested(s.Index(i).Interface(), e.Message()); err != nil {
+					return err
+				}
 				list.Append(e)
 			}
 		}
 	case fd.IsMap():
 		mapv := m.Mutable(fd).Map()
 		rm := reflect.ValueOf(v)
+		if rm.Kind() != reflect.Map {
+			return fmt.Errorf("%w: %T for map field", ErrUnsupportedType, v)
+		}
 		for _, k := range rm.MapKeys() {
-			mk := fieldValue(fd.MapKey(), k.Interface()).MapKey()
+			key, err := fieldValue(fd.MapKey(), k.Interface())
+			if err != nil {
+				return err
+			}
+			mk := key.MapKey()
 			if fd.MapValue().Message() == nil {
-				mv := fieldValue(fd.MapValue(), rm.MapIndex(k).Interface())
+				mv, err := fieldValue(fd.MapValue(), rm.MapIndex(k).Interface())
+				if err != nil {
+					return err
+				}
 				mapv.Set(mk, mv)
 			} else if mapv.Has(mk) {
-				mv := mapv.Get(mk).Message()
-				rm.MapIndex(k).Interface().(Message).Build(mv)
+				if err := buildNested(rm.MapIndex(k).Interface(), mapv.Get(mk).Message()); err != nil {
+					return err
+				}
 			} else {
 				mv := mapv.NewValue()
-				rm.MapIndex(k).Interface().(Message).Build(mv.Message())
+				if err := buildNested(rm.MapIndex(k).Interface(), mv.Message()); err != nil {
+					return err
+				}
 				mapv.Set(mk, mv)
 			}
 		}
 	default:
 		if fd.Message() == nil {
-			m.Set(fd, fieldValue(fd, v))
+			value, err := fieldValue(fd, v)
+			if err != nil {
+				return err
+			}
+			m.Set(fd, value)
 		} else {
-			v.(Message).Build(m.Mutable(fd).Message())
+			return buildNested(v, m.Mutable(fd).Message())
 		}
 	}
+	return nil
+}
+
+// buildNested applies a nested Message template to m.
+func buildNested(v any, m protoreflect.Message) error {
+	template, ok := v.(Message)
+	if !ok {
+		return fmt.Errorf("%w: %T for message %v", ErrUnsupportedType, v, m.Descriptor().FullName())
+	}
+	return template.Build(m)
 }
 
-func unmarshal[T proto.Message](n *node, r arrow.Record, rows []int) []T {
+func unmarshal[T proto.Message](n *node, r arrow.Record, rows []int) ([]T, error) {
 	if rows == nil {
 		rows = make([]int, r.NumRows())
 		for i := range rows {
@@ -439,37 +669,68 @@ func unmarshal[T proto.Message](n *node, r arrow.Record, rows []int) []T {
 			name := r.ColumnName(i)
 			nx, ok := n.hash[name]
 			if !ok {
-				panic(fmt.Sprintf("field %s not found in node %v", name, n.field.Name))
+				return nil, fieldError(name, ErrFieldNotFound)
 			}
 			if r.Column(i).IsNull(row) {
 				continue
 			}
-			fs := nx.desc.(protoreflect.FieldDescriptor)
-			switch {
-			case fs.IsList():
-				ls := r.Column(i).(*array.List)
-				start, end := ls.ValueOffsets(row)
-				val := ls.ListValues()
-				if start != end {
-					lv := msg.NewField(fs)
-					list := lv.List()
-					for k := start; k < end; k++ {
-						list.Append(nx.encode(list.NewElement(), val, int(k)))
-					}
-					msg.Set(fs, lv)
-				}
-			case fs.IsMap():
-				panic("MAP not supported")
-			default:
-				msg.Set(fs, nx.encode(msg.NewField(fs), r.Column(i), row))
+			if err := nx.decode(msg, r.Column(i), row); err != nil {
+				return nil, fieldError(nx.path(), err)
 			}
 		}
 		o[idx] = msg.Interface().(T)
 	}
-	return o
+	return o, nil
+}
+
+// decode sets the field described by n on msg from the Arrow array a at row.
+func (n *node) decode(msg protoreflect.Message, a arrow.Array, row int) error {
+	fs := n.desc.(protoreflect.FieldDescriptor)
+	if n.encode == nil {
+		return fmt.Errorf("%w: no decoder for kind %v", ErrUnsupportedType, fs.Kind())
+	}
+	switch {
+	case fs.IsList():
+		ls, ok := a.(array.ListLike)
+		if !ok {
+			return fmt.Errorf("%w: expected list array, got %s", ErrUnsupportedType, a.DataType())
+		}
+		start, end := ls.ValueOffsets(row)
+		if start == end {
+			return nil
+		}
+		lv := msg.NewField(fs)
+		list := lv.List()
+		va := ls.ListValues()
+		for k := start; k < end; k++ {
+			v, err := n.encode(list.NewElement(), va, int(k))
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+		msg.Set(fs, lv)
+	case fs.IsMap():
+		return fmt.Errorf("%w: MAP", ErrUnsupportedType)
+	default:
+		v, err := n.encode(msg.NewField(fs), a, row)
+		if err != nil {
+			return err
+		}
+		msg.Set(fs, v)
+	}
+	return nil
+}
+
+// path returns the dotted path recorded for the node when it was created.
+func (n *node) path() string {
+	if p, ok := n.field.Metadata.GetValue("path"); ok {
+		return p
+	}
+	return n.field.Name
 }
 
-func build(msg protoreflect.Message) *message {
+func build(msg protoreflect.Message) (*message, error) {
 	root := &node{
 		desc:  msg.Descriptor(),
 		field: arrow.Field{},
@@ -479,7 +740,10 @@ func build(msg protoreflect.Message) *message {
 	root.children = make([]*node, fields.Len())
 	a := make([]arrow.Field, fields.Len())
 	for i := 0; i < fields.Len(); i++ {
-		x := createNode(root, fields.Get(i), 0)
+		x, err := createNode(root, fields.Get(i), 0)
+		if err != nil {
+			return nil, err
+		}
 		root.children[i] = x
 		root.hash[x.field.Name] = x
 		a[i] = root.children[i].field
@@ -489,35 +753,39 @@ func build(msg protoreflect.Message) *message {
 	return &message{
 		root:   root,
 		schema: as,
-	}
+	}, nil
 }
 
-func (m *message) build(mem memory.Allocator) {
+func (m *message) build(mem memory.Allocator) error {
 	b := array.NewRecordBuilder(mem, m.schema)
 	for i, ch := range m.root.children {
-		ch.build(b.Field(i))
+		if err := ch.build(b.Field(i)); err != nil {
+			b.Release()
+			return err
+		}
 	}
 	m.builder = b
+	return nil
 }
 
-func (m *message) append(msg protoreflect.Message) {
-	m.root.WriteMessage(msg)
+func (m *message) append(msg protoreflect.Message) error {
+	return m.root.WriteMessage(msg)
 }
 
 func (m *message) NewRecord() arrow.Record {
 	return m.builder.NewRecord()
 }
 
-func createNode(parent *node, field protoreflect.FieldDescriptor, depth int) *node {
-	if depth >= maxDepth {
-		panic(ErrMaxDepth)
-	}
+func createNode(parent *node, field protoreflect.FieldDescriptor, depth int) (*node, error) {
 	name, ok := parent.field.Metadata.GetValue("path")
 	if ok {
 		name += "." + string(field.Name())
 	} else {
 		name = string(field.Name())
 	}
+	if depth >= maxDepth {
+		return nil, fieldError(name, ErrMaxDepth)
+	}
 	n := &node{
 		parent: parent,
 		desc:   field,
@@ -532,12 +800,32 @@ func createNode(parent *node, field protoreflect.FieldDescriptor, depth int) *no
 	}
 	t, err := n.baseType(field)
 	if err != nil {
-		panic(err)
+		return nil, fieldError(name, err)
 	}
 	n.field.Type = t
 
-	if n.field.Type != nil {
-		return n
+	if n.field.Type != nil && field.Message() == nil {
+		n.setup = scalarSetup(field)
+		if field.IsList() {
+			n.field.Type = arrow.ListOf(n.field.Type)
+			n.setup = listSetup(n.setup)
+		}
+		return n, nil
+	}
+
+	if field.IsMap() {
+		key, err := createNode(n, field.MapKey(), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := createNode(n, field.MapValue(), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		n.children = []*node{key, value}
+		n.field.Type = arrow.MapOf(key.field.Type, value.field.Type)
+		n.setup = mapSetup(key, value)
+		return n, nil
 	}
 
 	// Handling messages
@@ -562,41 +850,33 @@ func createNode(parent *node, field protoreflect.FieldDescriptor, depth int) *no
 					return nil
 				}
 			}
-			n.encode = func(value protoreflect.Value, a arrow.Array, row int) protoreflect.Value {
+			n.encode = func(value protoreflect.Value, a arrow.Array, row int) (protoreflect.Value, error) {
 				if a.IsNull(row) {
-					return protoreflect.Value{}
+					return protoreflect.Value{}, nil
+				}
+				var data []byte
+				switch bin := a.(type) {
+				case *array.Binary:
+					data = bin.Value(row)
+				case *array.LargeBinary:
+					data = bin.Value(row)
+				default:
+					return protoreflect.Value{}, fmt.Errorf("%w: expected binary array, got %s", ErrUnsupportedType, a.DataType())
 				}
 				msg := value.Message()
-				v := a.(*array.Binary).Value(row)
-				proto.Unmarshal(v, msg.Interface())
-				return value
+				if err := proto.Unmarshal(data, msg.Interface()); err != nil {
+					return protoreflect.Value{}, err
+				}
+				return value, nil
 			}
 		}
 
 		if n.field.Type != nil {
 			if field.IsList() {
 				n.field.Type = arrow.ListOf(n.field.Type)
-				setup := n.setup
-				n.setup = func(b array.Builder) valueFn {
-					ls := b.(*array.ListBuilder)
-					value := setup(ls.ValueBuilder())
-					return func(v protoreflect.Value, set bool) error {
-						if !v.IsValid() {
-							ls.AppendNull()
-							return nil
-						}
-						ls.Append(true)
-						list := v.List()
-						for i := 0; i < list.Len(); i++ {
-							if err := value(list.Get(i), true); err != nil {
-								return err
-							}
-						}
-						return nil
-					}
-				}
+				n.setup = listSetup(n.setup)
 			}
-			return n
+			return n, nil
 		}
 	}
 
@@ -605,7 +885,10 @@ func createNode(parent *node, field protoreflect.FieldDescriptor, depth int) *no
 	n.children = make([]*node, f.Len())
 	a := make([]arrow.Field, f.Len())
 	for i := 0; i < f.Len(); i++ {
-		x := createNode(n, f.Get(i), depth+1)
+		x, err := createNode(n, f.Get(i), depth+1)
+		if err != nil {
+			return nil, err
+		}
 		n.children[i] = x
 		n.hash[x.field.Name] = x
 		a[i] = n.children[i].field
@@ -616,10 +899,13 @@ func createNode(parent *node, field protoreflect.FieldDescriptor, depth int) *no
 		a := b.(*array.StructBuilder)
 		fs := make([]valueFn, len(n.children))
 		for i := range n.children {
+			if n.children[i].setup == nil {
+				continue
+			}
 			fs[i] = n.children[i].setup(a.FieldBuilder(i))
 		}
 		return func(v protoreflect.Value, set bool) error {
-			if !v.IsValid() {
+			if !v.IsValid() || !set {
 				a.AppendNull()
 				return nil
 			}
@@ -627,6 +913,9 @@ func createNode(parent *node, field protoreflect.FieldDescriptor, depth int) *no
 			msg := v.Message()
 			fields := msg.Descriptor().Fields()
 			for i := 0; i < fields.Len(); i++ {
+				if fs[i] == nil {
+					return fieldError(n.children[i].path(), fmt.Errorf("%w: no writer for kind %v", ErrUnsupportedType, fields.Get(i).Kind()))
+				}
 				if err := fs[i](msg.Get(fields.Get(i)), msg.Has(fields.Get(i))); err != nil {
 					return err
 				}
@@ -634,62 +923,31 @@ func createNode(parent *node, field protoreflect.FieldDescriptor, depth int) *no
 			return nil
 		}
 	}
-	n.encode = func(value protoreflect.Value, a arrow.Array, row int) protoreflect.Value {
+	n.encode = func(value protoreflect.Value, a arrow.Array, row int) (protoreflect.Value, error) {
 		msg := value.Message()
-		s := a.(*array.Struct)
-		typ := a.DataType().(*arrow.StructType)
+		s, ok := a.(*array.Struct)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("%w: expected struct array, got %s", ErrUnsupportedType, a.DataType())
+		}
+		typ := s.DataType().(*arrow.StructType)
 		for j := 0; j < s.NumField(); j++ {
 			f := typ.Field(j)
 			nx, ok := n.hash[f.Name]
 			if !ok {
-				panic(fmt.Sprintf("field %s not found in node %v", f.Name, n.field.Name))
+				return protoreflect.Value{}, fieldError(n.path()+"."+f.Name, ErrFieldNotFound)
 			}
 			if s.Field(j).IsNull(row) {
 				continue
 			}
-			fs := nx.desc.(protoreflect.FieldDescriptor)
-			switch {
-			case fs.IsList():
-				ls := s.Field(j).(*array.List)
-				start, end := ls.ValueOffsets(row)
-				if start != end {
-					lv := msg.Mutable(fs)
-					list := lv.List()
-					va := ls.ListValues()
-					for k := start; k < end; k++ {
-						list.Append(nx.encode(list.NewElement(), va, int(k)))
-					}
-					msg.Set(fs, lv)
-				}
-			case fs.IsMap():
-				panic("MAP not supported")
-			default:
-				msg.Set(fs, nx.encode(msg.NewField(fs), s.Field(j), row))
+			if err := nx.decode(msg, s.Field(j), row); err != nil {
+				return protoreflect.Value{}, fieldError(nx.path(), err)
 			}
 		}
-		return value
+		return value, nil
 	}
 	if field.IsList() {
 		n.field.Type = arrow.ListOf(n.field.Type)
-		setup := n.setup
-		n.setup = func(b array.Builder) valueFn {
-			ls := b.(*array.ListBuilder)
-			value := setup(ls.ValueBuilder())
-			return func(v protoreflect.Value, set bool) error {
-				if !v.IsValid() {
-					ls.AppendNull()
-					return nil
-				}
-				ls.Append(true)
-				list := v.List()
-				for i := 0; i < list.Len(); i++ {
-					if err := value(list.Get(i), true); err != nil {
-						return err
-					}
-				}
-				return nil
-			}
-		}
+		n.setup = listSetup(n.setup)
 	}
 	if field.ContainingOneof() != nil {
 		setup := n.setup
@@ -704,18 +962,139 @@ func createNode(parent *node, field protoreflect.FieldDescriptor, depth int) *no
 			}
 		}
 	}
-	return n
+	return n, nil
 }
 
-func (n *node) build(a array.Builder) {
+// scalarSetup returns the writer of a scalar field. Unset fields with
+// explicit presence are written as nulls.
+func scalarSetup(field protoreflect.FieldDescriptor) func(array.Builder) valueFn {
+	null := nullable(field)
+	return func(b array.Builder) valueFn {
+		var write func(protoreflect.Value)
+		switch b := b.(type) {
+		case *array.BooleanBuilder:
+			write = func(v protoreflect.Value) { b.Append(v.Bool()) }
+		case *array.Int32Builder:
+			if field.Kind() == protoreflect.EnumKind {
+				write = func(v protoreflect.Value) { b.Append(int32(v.Enum())) }
+			} else {
+				write = func(v protoreflect.Value) { b.Append(int32(v.Int())) }
+			}
+		case *array.Int64Builder:
+			write = func(v protoreflect.Value) { b.Append(v.Int()) }
+		case *array.Uint32Builder:
+			write = func(v protoreflect.Value) { b.Append(uint32(v.Uint())) }
+		case *array.Uint64Builder:
+			write = func(v protoreflect.Value) { b.Append(v.Uint()) }
+		case *array.Float32Builder:
+			write = func(v protoreflect.Value) { b.Append(float32(v.Float())) }
+		case *array.Float64Builder:
+			write = func(v protoreflect.Value) { b.Append(v.Float()) }
+		case *array.StringBuilder:
+			write = func(v protoreflect.Value) { b.Append(v.String()) }
+		case *array.BinaryBuilder:
+			write = func(v protoreflect.Value) { b.Append(v.Bytes()) }
+		}
+		return func(v protoreflect.Value, set bool) error {
+			if write == nil {
+				return fmt.Errorf("%w: no writer for kind %v", ErrUnsupportedType, field.Kind())
+			}
+			if !v.IsValid() || (null && !set) {
+				b.AppendNull()
+				return nil
+			}
+			write(v)
+			return nil
+		}
+	}
+}
+
+// listSetup wraps the writer of an element into the writer of a repeated field.
+func listSetup(setup func(array.Builder) valueFn) func(array.Builder) valueFn {
+	return func(b array.Builder) valueFn {
+		ls := b.(*array.ListBuilder)
+		value := setup(ls.ValueBuilder())
+		return func(v protoreflect.Value, set bool) error {
+			if !v.IsValid() {
+				ls.AppendNull()
+				return nil
+			}
+			ls.Append(true)
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				if err := value(list.Get(i), true); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// mapSetup returns the writer of a map field. Entries are written in key
+// order, since proto maps are unordered.
+func mapSetup(key, value *node) func(array.Builder) valueFn {
+	return func(b array.Builder) valueFn {
+		mb := b.(*array.MapBuilder)
+		writeKey := key.setup(mb.KeyBuilder())
+		writeValue := value.setup(mb.ItemBuilder())
+		return func(v protoreflect.Value, set bool) error {
+			if !v.IsValid() {
+				mb.AppendNull()
+				return nil
+			}
+			mb.Append(true)
+			m := v.Map()
+			keys := make([]protoreflect.MapKey, 0, m.Len())
+			m.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+				keys = append(keys, k)
+				return true
+			})
+			sort.Slice(keys, func(i, j int) bool { return mapKeyLess(keys[i], keys[j]) })
+			for _, k := range keys {
+				if err := writeKey(k.Value(), true); err != nil {
+					return err
+				}
+				if err := writeValue(m.Get(k), true); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+}
+
+func mapKeyLess(a, b protoreflect.MapKey) bool {
+	switch av := a.Interface().(type) {
+	case string:
+		return av < b.String()
+	case bool:
+		return !av && b.Bool()
+	case int32, int64:
+		return a.Int() < b.Int()
+	case uint32, uint64:
+		return a.Uint() < b.Uint()
+	}
+	return false
+}
+
+func (n *node) build(a array.Builder) error {
+	if n.setup == nil {
+		fd := n.desc.(protoreflect.FieldDescriptor)
+		return fieldError(n.path(), fmt.Errorf("%w: no writer for kind %v", ErrUnsupportedType, fd.Kind()))
+	}
 	n.write = n.setup(a)
+	return nil
 }
 
-func (n *node) WriteMessage(msg protoreflect.Message) {
+func (n *node) WriteMessage(msg protoreflect.Message) error {
 	f := msg.Descriptor().Fields()
 	for i := 0; i < f.Len(); i++ {
-		n.children[i].write(msg.Get(f.Get(i)), msg.Has(f.Get(i)))
+		if err := n.children[i].write(msg.Get(f.Get(i)), msg.Has(f.Get(i))); err != nil {
+			return fieldError(n.children[i].path(), err)
+		}
 	}
+	return nil
 }
 
 // baseType converts a protobuf field descriptor to an equivalent Arrow data type.
@@ -751,7 +1130,7 @@ func (n *node) baseType(field protoreflect.FieldDescriptor) (arrow.DataType, err
 		// Groups are deprecated in proto3, but we'll handle them as nested structs
 		return nil, nil
 	default:
-		return nil, fmt.Errorf("unsupported protobuf kind: %v", field.Kind())
+		return nil, fmt.Errorf("%w: protobuf kind %v", ErrUnsupportedType, field.Kind())
 	}
 }
 
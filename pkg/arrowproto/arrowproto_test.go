@@ -0,0 +1,135 @@
+package arrowproto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func singleColumnRecord(t *testing.T, field arrow.Field, appendFn func(array.Builder)) arrow.Record {
+	t.Helper()
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), arrow.NewSchema([]arrow.Field{field}, nil))
+	defer b.Release()
+	appendFn(b.Field(0))
+	return b.NewRecord()
+}
+
+func TestConvertArrowRecordToProtoMessages_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    arrow.Field
+		appendFn func(array.Builder)
+		wantErr  error
+	}{
+		{
+			name:  "out of range int32",
+			field: arrow.Field{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+			appendFn: func(b array.Builder) {
+				b.(*array.Int64Builder).Append(1 << 40)
+			},
+			wantErr: ErrOutOfRange,
+		},
+		{
+			name:  "string into int32",
+			field: arrow.Field{Name: "value", Type: arrow.BinaryTypes.String},
+			appendFn: func(b array.Builder) {
+				b.(*array.StringBuilder).Append("forty-two")
+			},
+			wantErr: ErrUnsupportedType,
+		},
+		{
+			name:  "unsupported arrow type",
+			field: arrow.Field{Name: "value", Type: arrow.FixedWidthTypes.Float16},
+			appendFn: func(b array.Builder) {
+				b.AppendEmptyValue()
+			},
+			wantErr: ErrUnsupportedType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := singleColumnRecord(t, tt.field, tt.appendFn)
+			defer rec.Release()
+
+			_, err := ConvertArrowRecordToProtoMessages(rec, &wrapperspb.Int32Value{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			var fe *FieldError
+			if !errors.As(err, &fe) || fe.Path != "value" {
+				t.Fatalf("expected FieldError for path %q, got %v", "value", err)
+			}
+		})
+	}
+}
+
+func TestConvertArrowRecordToProtoMessages_NestedPath(t *testing.T) {
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:   proto.String("nested_test.proto"),
+		Syntax: proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Inner"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("sub_int_col"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				},
+			},
+			{
+				Name: proto.String("Outer"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("struct_list"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".Inner"), Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()},
+				},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer := dynamicpb.NewMessage(fd.Messages().ByName("Outer"))
+
+	inner := arrow.StructOf(arrow.Field{Name: "sub_int_col", Type: arrow.PrimitiveTypes.Int64})
+	rec := singleColumnRecord(t, arrow.Field{Name: "struct_list", Type: arrow.ListOf(inner)}, func(b array.Builder) {
+		lb := b.(*array.ListBuilder)
+		sb := lb.ValueBuilder().(*array.StructBuilder)
+		lb.Append(true)
+		sb.Append(true)
+		sb.FieldBuilder(0).(*array.Int64Builder).Append(7)
+		sb.Append(true)
+		sb.FieldBuilder(0).(*array.Int64Builder).Append(1 << 40)
+	})
+	defer rec.Release()
+
+	_, err = ConvertArrowRecordToProtoMessages(rec, outer)
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected FieldError, got %v", err)
+	}
+	if want := "struct_list[1].sub_int_col"; fe.Path != want {
+		t.Fatalf("expected path %q, got %q", want, fe.Path)
+	}
+}
+
+func TestMessageBuild_UnknownField(t *testing.T) {
+	err := Message{"missing": 1}.Build((&wrapperspb.Int32Value{}).ProtoReflect())
+	if !errors.Is(err, ErrFieldNotFound) {
+		t.Fatalf("expected ErrFieldNotFound, got %v", err)
+	}
+
+	msg := &wrapperspb.Int32Value{}
+	if err := (Message{"value": 42}).Build(msg.ProtoReflect()); err != nil {
+		t.Fatal(err)
+	}
+	if msg.GetValue() != 42 {
+		t.Fatalf("expected 42, got %d", msg.GetValue())
+	}
+}
@@ -0,0 +1,186 @@
+package arrowproto
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/arrowarc/arrowarc/pkg/geo"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// RootMessageName is the name given to the top-level message generated by DescriptorFromArrowSchema.
+const RootMessageName = "ArrowRecord"
+
+// validFieldName matches names usable both as proto field names and BigQuery column names.
+var validFieldName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// DescriptorFromArrowSchema builds a self-contained proto2 descriptor for records of the given schema.
+// Nested message types are declared inline, which is the shape expected by the BigQuery Storage Write API.
+//
+// Types map as follows: integers and floats to their proto equivalents (uint64 as int64),
+// dates to int32 days since epoch, timestamps to int64 microseconds since epoch,
+// times and decimals to strings, structs to nested messages, lists to repeated fields,
+// and maps to repeated key/value entry messages. The WKB geometry columns named by
+// GeoParquet metadata map to strings holding WKT, as BigQuery GEOGRAPHY expects.
+func DescriptorFromArrowSchema(schema *arrow.Schema) (*descriptorpb.DescriptorProto, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("arrow schema is nil")
+	}
+	return messageDescriptor(RootMessageName, geo.WKTSchema(schema).Fields(), "", 0)
+}
+
+// NewMessage returns an empty dynamic message for a self-contained descriptor,
+// such as one returned by DescriptorFromArrowSchema. The result can be passed as
+// the message type to ConvertArrowRecordToProtoMessages.
+func NewMessage(dp *descriptorpb.DescriptorProto) (proto.Message, error) {
+	if dp == nil {
+		return nil, fmt.Errorf("descriptor is nil")
+	}
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String(dp.GetName() + ".proto"),
+		Syntax:      proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{dp},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build file descriptor: %w", err)
+	}
+	md := fd.Messages().ByName(fd.Messages().Get(0).Name())
+	return dynamicpb.NewMessage(md), nil
+}
+
+func messageDescriptor(name string, fields []arrow.Field, path string, depth int) (*descriptorpb.DescriptorProto, error) {
+	if depth >= maxDepth {
+		return nil, fieldError(path, ErrMaxDepth)
+	}
+	dp := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	nestedNames := make(map[string]bool)
+	for i, f := range fields {
+		fieldPath := f.Name
+		if path != "" {
+			fieldPath = path + "." + f.Name
+		}
+		if !validFieldName.MatchString(f.Name) {
+			return nil, fieldError(fieldPath, fmt.Errorf("invalid field name %q", f.Name))
+		}
+		fdp := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(f.Name),
+			Number: proto.Int32(int32(i + 1)),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+
+		typ := f.Type
+		if list, ok := typ.(arrow.ListLikeType); ok && typ.ID() != arrow.MAP {
+			fdp.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			typ = list.Elem()
+			if _, nested := typ.(arrow.ListLikeType); nested {
+				return nil, fieldError(fieldPath, fmt.Errorf("%w: nested lists", ErrUnsupportedType))
+			}
+		}
+
+		var nested *descriptorpb.DescriptorProto
+		switch t := typ.(type) {
+		case *arrow.StructType:
+			msg, err := messageDescriptor(nestedTypeName(f.Name, nestedNames), t.Fields(), fieldPath, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			nested = msg
+		case *arrow.MapType:
+			if fdp.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+				return nil, fieldError(fieldPath, fmt.Errorf("%w: lists of maps", ErrUnsupportedType))
+			}
+			// Maps become repeated key/value messages, which BigQuery accepts as REPEATED RECORD.
+			fdp.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			entry := []arrow.Field{
+				{Name: "key", Type: t.KeyType()},
+				{Name: "value", Type: t.ItemType(), Nullable: true},
+			}
+			msg, err := messageDescriptor(nestedTypeName(f.Name+"_entry", nestedNames), entry, fieldPath, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			nested = msg
+		default:
+			kind, err := scalarKind(typ)
+			if err != nil {
+				return nil, fieldError(fieldPath, err)
+			}
+			fdp.Type = kind.Enum()
+		}
+
+		if nested != nil {
+			dp.NestedType = append(dp.NestedType, nested)
+			fdp.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			fdp.TypeName = proto.String(nested.GetName())
+		}
+		dp.Field = append(dp.Field, fdp)
+	}
+	return dp, nil
+}
+
+// scalarKind maps a non-nested Arrow type to its proto field type.
+func scalarKind(dt arrow.DataType) (descriptorpb.FieldDescriptorProto_Type, error) {
+	switch dt.ID() {
+	case arrow.BOOL:
+		return descriptorpb.FieldDescriptorProto_TYPE_BOOL, nil
+	case arrow.INT8, arrow.INT16, arrow.INT32:
+		return descriptorpb.FieldDescriptorProto_TYPE_INT32, nil
+	case arrow.UINT8, arrow.UINT16, arrow.UINT32:
+		return descriptorpb.FieldDescriptorProto_TYPE_UINT32, nil
+	case arrow.INT64, arrow.UINT64:
+		return descriptorpb.FieldDescriptorProto_TYPE_INT64, nil
+	case arrow.FLOAT32:
+		return descriptorpb.FieldDescriptorProto_TYPE_FLOAT, nil
+	case arrow.FLOAT64:
+		return descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, nil
+	case arrow.STRING, arrow.LARGE_STRING:
+		return descriptorpb.FieldDescriptorProto_TYPE_STRING, nil
+	case arrow.BINARY, arrow.LARGE_BINARY:
+		return descriptorpb.FieldDescriptorProto_TYPE_BYTES, nil
+	case arrow.DATE32, arrow.DATE64:
+		return descriptorpb.FieldDescriptorProto_TYPE_INT32, nil
+	case arrow.TIMESTAMP:
+		return descriptorpb.FieldDescriptorProto_TYPE_INT64, nil
+	case arrow.TIME32, arrow.TIME64, arrow.DECIMAL128, arrow.DECIMAL256:
+		return descriptorpb.FieldDescriptorProto_TYPE_STRING, nil
+	case arrow.EXTENSION:
+		// UUIDs and JSON are carried as their text; other extensions as
+		// their storage.
+		ext := dt.(arrow.ExtensionType)
+		switch ext.ExtensionName() {
+		case "arrow.uuid", "arrow.json":
+			return descriptorpb.FieldDescriptorProto_TYPE_STRING, nil
+		}
+		return scalarKind(ext.StorageType())
+	default:
+		return 0, fmt.Errorf("%w: Arrow %s", ErrUnsupportedType, dt)
+	}
+}
+
+// nestedTypeName derives a CamelCase message name from a field name, unique among its siblings.
+func nestedTypeName(fieldName string, taken map[string]bool) string {
+	var b strings.Builder
+	for _, part := range strings.Split(fieldName, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	base := b.String()
+	if base == "" {
+		base = "Nested"
+	}
+	name := base
+	for i := 2; taken[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	taken[name] = true
+	return name
+}
@@ -0,0 +1,173 @@
+package arrowproto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/extensions"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestDescriptorFromArrowSchema_RoundTrip(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "day", Type: arrow.FixedWidthTypes.Date32},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String)},
+		{Name: "point", Type: arrow.StructOf(
+			arrow.Field{Name: "x", Type: arrow.PrimitiveTypes.Float64},
+			arrow.Field{Name: "y", Type: arrow.PrimitiveTypes.Float64},
+		)},
+	}, nil)
+
+	dp, err := DescriptorFromArrowSchema(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dp.GetName() != RootMessageName || len(dp.GetField()) != 5 || len(dp.GetNestedType()) != 1 {
+		t.Fatalf("unexpected descriptor: %v", dp)
+	}
+	msgType, err := NewMessage(dp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).Append(1)
+	b.Field(1).(*array.StringBuilder).Append("alpha")
+	b.Field(2).(*array.Date32Builder).Append(arrow.Date32(19000))
+	lb := b.Field(3).(*array.ListBuilder)
+	lb.Append(true)
+	lb.ValueBuilder().(*array.StringBuilder).AppendValues([]string{"a", "b"}, nil)
+	sb := b.Field(4).(*array.StructBuilder)
+	sb.Append(true)
+	sb.FieldBuilder(0).(*array.Float64Builder).Append(1.5)
+	sb.FieldBuilder(1).(*array.Float64Builder).Append(-2)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	msgs, err := ConvertArrowRecordToProtoMessages(rec, msgType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+	m := msgs[0].ProtoReflect()
+	fields := m.Descriptor().Fields()
+	if got := m.Get(fields.ByName("id")).Int(); got != 1 {
+		t.Errorf("id: expected 1, got %d", got)
+	}
+	if got := m.Get(fields.ByName("day")).Int(); got != 19000 {
+		t.Errorf("day: expected 19000, got %d", got)
+	}
+	if got := m.Get(fields.ByName("tags")).List().Len(); got != 2 {
+		t.Errorf("tags: expected 2 elements, got %d", got)
+	}
+	point := m.Get(fields.ByName("point")).Message()
+	if got := point.Get(point.Descriptor().Fields().ByName(protoreflect.Name("y"))).Float(); got != -2 {
+		t.Errorf("point.y: expected -2, got %v", got)
+	}
+}
+
+func TestDescriptorFromArrowSchema_Unsupported(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "nested", Type: arrow.ListOf(arrow.ListOf(arrow.PrimitiveTypes.Int32))},
+	}, nil)
+	_, err := DescriptorFromArrowSchema(schema)
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("expected ErrUnsupportedType, got %v", err)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Path != "nested" {
+		t.Fatalf("expected FieldError for path %q, got %v", "nested", err)
+	}
+}
+
+func TestDescriptorFromArrowSchema_LargeTypes(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "name", Type: arrow.BinaryTypes.LargeString, Nullable: true},
+		{Name: "payload", Type: arrow.BinaryTypes.LargeBinary, Nullable: true},
+		{Name: "tags", Type: arrow.LargeListOf(arrow.BinaryTypes.LargeString)},
+	}, nil)
+
+	dp, err := DescriptorFromArrowSchema(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgType, err := NewMessage(dp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*array.LargeStringBuilder).Append("alpha")
+	b.Field(1).(*array.BinaryBuilder).Append([]byte{1, 2})
+	lb := b.Field(2).(*array.LargeListBuilder)
+	lb.Append(true)
+	lb.ValueBuilder().(*array.LargeStringBuilder).AppendValues([]string{"a", "b"}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	msgs, err := ConvertArrowRecordToProtoMessages(rec, msgType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := msgs[0].ProtoReflect()
+	fields := m.Descriptor().Fields()
+	if got := m.Get(fields.ByName("name")).String(); got != "alpha" {
+		t.Errorf("name: expected alpha, got %q", got)
+	}
+	if got := m.Get(fields.ByName("payload")).Bytes(); len(got) != 2 {
+		t.Errorf("payload: expected 2 bytes, got %v", got)
+	}
+	if got := m.Get(fields.ByName("tags")).List(); got.Len() != 2 || got.Get(1).String() != "b" {
+		t.Errorf("tags: expected [a b], got %v", got)
+	}
+}
+
+func TestDescriptorFromArrowSchema_ExtensionTypes(t *testing.T) {
+	jsonType, err := extensions.NewJSONType(arrow.BinaryTypes.String)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: extensions.NewUUIDType(), Nullable: true},
+		{Name: "doc", Type: jsonType, Nullable: true},
+	}, nil)
+
+	dp, err := DescriptorFromArrowSchema(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgType, err := NewMessage(dp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, _, err := array.RecordFromJSON(memory.NewGoAllocator(), schema, strings.NewReader(
+		`[{"id":"123e4567-e89b-12d3-a456-426614174000","doc":"{\"a\":1}"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+
+	msgs, err := ConvertArrowRecordToProtoMessages(rec, msgType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := msgs[0].ProtoReflect()
+	fields := m.Descriptor().Fields()
+	if got := m.Get(fields.ByName("id")).String(); got != "123e4567-e89b-12d3-a456-426614174000" {
+		t.Errorf("id: expected the UUID text, got %q", got)
+	}
+	if got := m.Get(fields.ByName("doc")).String(); got != `{"a":1}` {
+		t.Errorf("doc: expected {\"a\":1}, got %q", got)
+	}
+}
@@ -0,0 +1,70 @@
+package arrowproto
+
+import (
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// RecordBuilder converts protobuf messages of a single type into Arrow records.
+//
+// Scalars map to their Arrow equivalents and enums to their int32 numbers,
+// messages to structs, repeated fields to lists and maps to Arrow maps. Fields
+// with explicit presence (optional, oneof members and messages) are null when unset.
+type RecordBuilder struct {
+	desc protoreflect.MessageDescriptor
+	m    *message
+	rows int64
+}
+
+// NewRecordBuilder returns a builder for messages described by desc. The
+// descriptor need not have a compiled Go type.
+func NewRecordBuilder(mem memory.Allocator, desc protoreflect.MessageDescriptor) (*RecordBuilder, error) {
+	m, err := build(dynamicpb.NewMessage(desc))
+	if err != nil {
+		return nil, err
+	}
+	if err := m.build(mem); err != nil {
+		return nil, err
+	}
+	return &RecordBuilder{desc: desc, m: m}, nil
+}
+
+// Schema returns the Arrow schema of the records built.
+func (b *RecordBuilder) Schema() *arrow.Schema {
+	return b.m.schema
+}
+
+// Append adds msg as a row of the next record. After an error the pending
+// rows are inconsistent and the builder should be released.
+func (b *RecordBuilder) Append(msg proto.Message) error {
+	ref := msg.ProtoReflect()
+	if ref.Descriptor().FullName() != b.desc.FullName() {
+		return fmt.Errorf("expected message %s, got %s", b.desc.FullName(), ref.Descriptor().FullName())
+	}
+	if err := b.m.append(ref); err != nil {
+		return err
+	}
+	b.rows++
+	return nil
+}
+
+// Len returns the number of rows appended since the last record was built.
+func (b *RecordBuilder) Len() int64 {
+	return b.rows
+}
+
+// NewRecord returns a record of the rows appended so far and resets the builder.
+func (b *RecordBuilder) NewRecord() arrow.Record {
+	b.rows = 0
+	return b.m.NewRecord()
+}
+
+// Release releases the memory held by the builder.
+func (b *RecordBuilder) Release() {
+	b.m.builder.Release()
+}
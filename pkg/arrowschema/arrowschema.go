@@ -0,0 +1,111 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package arrowschema prints Arrow schemas in a canonical text or JSON form
+// and reports the differences between two schemas.
+package arrowschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// Field is the canonical description of a schema field.
+type Field struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Nullable bool              `json:"nullable"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Fields   []Field           `json:"fields,omitempty"` // struct children
+}
+
+// Describe returns the canonical description of the fields of schema.
+func Describe(schema *arrow.Schema) []Field {
+	return describeFields(schema.Fields())
+}
+
+func describeFields(fields []arrow.Field) []Field {
+	out := make([]Field, len(fields))
+	for i, f := range fields {
+		out[i] = Field{Name: f.Name, Type: typeName(f.Type), Nullable: f.Nullable}
+		if f.Metadata.Len() > 0 {
+			out[i].Metadata = make(map[string]string, f.Metadata.Len())
+			for j, key := range f.Metadata.Keys() {
+				out[i].Metadata[key] = f.Metadata.Values()[j]
+			}
+		}
+		if st, ok := f.Type.(*arrow.StructType); ok {
+			out[i].Fields = describeFields(st.Fields())
+		}
+	}
+	return out
+}
+
+// typeName names dt, leaving the children of structs to Field.Fields.
+func typeName(dt arrow.DataType) string {
+	if dt.ID() == arrow.STRUCT {
+		return "struct"
+	}
+	return dt.String()
+}
+
+// WriteText writes one line per field, "name: type", followed by
+// "not null" for required fields. Struct children are indented below their
+// parent.
+func WriteText(w io.Writer, schema *arrow.Schema) error {
+	var b strings.Builder
+	writeTextFields(&b, Describe(schema), "")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeTextFields(b *strings.Builder, fields []Field, indent string) {
+	for _, f := range fields {
+		fmt.Fprintf(b, "%s%s: %s", indent, f.Name, f.Type)
+		if !f.Nullable {
+			b.WriteString(" not null")
+		}
+		b.WriteByte('\n')
+		writeTextFields(b, f.Fields, indent+"  ")
+	}
+}
+
+// WriteJSON writes the canonical description of schema as indented JSON,
+// field metadata included.
+func WriteJSON(w io.Writer, schema *arrow.Schema) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Fields []Field `json:"fields"`
+	}{Describe(schema)})
+}
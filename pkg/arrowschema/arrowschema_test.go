@@ -0,0 +1,83 @@
+package arrowschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/stretchr/testify/require"
+)
+
+func addressType(fields ...arrow.Field) arrow.DataType {
+	return arrow.StructOf(fields...)
+}
+
+var oldSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "score", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+	{Name: "address", Type: addressType(
+		arrow.Field{Name: "street", Type: arrow.BinaryTypes.String, Nullable: true},
+		arrow.Field{Name: "zip", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+	), Nullable: true, Metadata: arrow.NewMetadata([]string{"PARQUET:field_id"}, []string{"4"})},
+}, nil)
+
+var newSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "address", Type: addressType(
+		arrow.Field{Name: "street", Type: arrow.BinaryTypes.String, Nullable: true},
+		arrow.Field{Name: "zip", Type: arrow.BinaryTypes.String, Nullable: true},
+		arrow.Field{Name: "city", Type: arrow.BinaryTypes.String, Nullable: true},
+	), Nullable: true},
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	{Name: "score", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+}, nil)
+
+func TestWriteText(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WriteText(&out, oldSchema))
+	require.Equal(t, ""+
+		"id: int64 not null\n"+
+		"name: utf8\n"+
+		"score: int32\n"+
+		"address: struct\n"+
+		"  street: utf8\n"+
+		"  zip: int32\n", out.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WriteJSON(&out, oldSchema))
+	var parsed struct{ Fields []Field }
+	require.NoError(t, json.Unmarshal(out.Bytes(), &parsed))
+	require.Equal(t, Describe(oldSchema), parsed.Fields)
+	require.Equal(t, "4", parsed.Fields[3].Metadata["PARQUET:field_id"])
+	require.Len(t, parsed.Fields[3].Fields, 2)
+}
+
+func TestDiff(t *testing.T) {
+	changes := Diff(oldSchema, newSchema)
+	require.Equal(t, []Change{
+		{Kind: Nullability, Column: "id", From: "int64 not null", To: "int64"},
+		{Kind: Removed, Column: "name", From: "utf8"},
+		{Kind: Retyped, Column: "score", From: "int32", To: "float64"},
+		{Kind: Retyped, Column: "address.zip", From: "int32", To: "utf8"},
+		{Kind: Added, Column: "address.city", To: "utf8"},
+		{Kind: Added, Column: "tags", To: "list<item: utf8, nullable>"},
+	}, changes)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteDiffText(&out, changes[:3]))
+	require.Equal(t, ""+
+		"~ id: int64 not null -> int64\n"+
+		"- name: utf8\n"+
+		"~ score: int32 -> float64\n", out.String())
+}
+
+func TestDiffIdentical(t *testing.T) {
+	require.Empty(t, Diff(oldSchema, oldSchema))
+	var out bytes.Buffer
+	require.NoError(t, WriteDiffJSON(&out, nil))
+	require.Equal(t, "[]\n", out.String())
+}
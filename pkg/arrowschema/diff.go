@@ -0,0 +1,143 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrowschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// ChangeKind is the kind of difference between two schemas.
+type ChangeKind string
+
+const (
+	Added       ChangeKind = "added"
+	Removed     ChangeKind = "removed"
+	Retyped     ChangeKind = "retyped"
+	Nullability ChangeKind = "nullability"
+)
+
+// Change is one difference between two schemas. Column is the dotted path
+// of the field; From and To describe it in the old and new schema.
+type Change struct {
+	Kind   ChangeKind `json:"kind"`
+	Column string     `json:"column"`
+	From   string     `json:"from,omitempty"`
+	To     string     `json:"to,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Column, c.To)
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Column, c.From)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Column, c.From, c.To)
+	}
+}
+
+// Diff reports the columns added, removed, retyped or whose nullability
+// changed going from schema a to schema b. Columns are matched by name,
+// struct children included; order and metadata are ignored. Removed and
+// changed columns come first, in the order of a, then added columns in the
+// order of b.
+func Diff(a, b *arrow.Schema) []Change {
+	return diffFields(a.Fields(), b.Fields(), "")
+}
+
+func diffFields(a, b []arrow.Field, prefix string) []Change {
+	inB := make(map[string]arrow.Field, len(b))
+	for _, f := range b {
+		inB[f.Name] = f
+	}
+	inA := make(map[string]bool, len(a))
+
+	var changes []Change
+	for _, fa := range a {
+		inA[fa.Name] = true
+		path := prefix + fa.Name
+		fb, ok := inB[fa.Name]
+		if !ok {
+			changes = append(changes, Change{Kind: Removed, Column: path, From: fieldType(fa)})
+			continue
+		}
+		sa, aStruct := fa.Type.(*arrow.StructType)
+		sb, bStruct := fb.Type.(*arrow.StructType)
+		switch {
+		case aStruct && bStruct:
+			changes = append(changes, diffFields(sa.Fields(), sb.Fields(), path+".")...)
+		case !arrow.TypeEqual(fa.Type, fb.Type):
+			changes = append(changes, Change{Kind: Retyped, Column: path, From: fieldType(fa), To: fieldType(fb)})
+			continue
+		}
+		if fa.Nullable != fb.Nullable {
+			changes = append(changes, Change{Kind: Nullability, Column: path, From: fieldType(fa), To: fieldType(fb)})
+		}
+	}
+	for _, fb := range b {
+		if !inA[fb.Name] {
+			changes = append(changes, Change{Kind: Added, Column: prefix + fb.Name, To: fieldType(fb)})
+		}
+	}
+	return changes
+}
+
+// fieldType describes the type and nullability of f.
+func fieldType(f arrow.Field) string {
+	if f.Nullable {
+		return f.Type.String()
+	}
+	return f.Type.String() + " not null"
+}
+
+// WriteDiffText writes one line per change: "+" for added, "-" for removed
+// and "~" for changed columns.
+func WriteDiffText(w io.Writer, changes []Change) error {
+	for _, c := range changes {
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteDiffJSON writes changes as an indented JSON array.
+func WriteDiffJSON(w io.Writer, changes []Change) error {
+	if changes == nil {
+		changes = []Change{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(changes)
+}
@@ -0,0 +1,176 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package awsauth selects the credentials and region of the AWS clients of
+// the S3 and Kinesis integrations, so that each takes the same options.
+//
+// Credentials are static keys, or else those the AWS SDK resolves: the
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables, or a
+// named or default profile of the shared configuration files, with its
+// source_profile chain, credential_process, SSO session or web identity,
+// or else the container or instance role. Any of them may assume a role
+// through STS.
+package awsauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// DefaultRoleSessionName names the sessions of assumed roles unless
+// Options.RoleSessionName or the profile's role_session_name sets another.
+const DefaultRoleSessionName = "arrowarc"
+
+// Options selects the credentials and region of a client. At most one of
+// Profile and the static keys may be set.
+type Options struct {
+	// Profile names a profile of the shared configuration files, which
+	// defaults to AWS_PROFILE.
+	Profile string
+	// AccessKeyID, SecretAccessKey and SessionToken are static keys.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// RoleARN is a role the credentials assume, passing ExternalID if the
+	// role's trust policy requires one. A profile with a role_arn assumes
+	// its own role first.
+	RoleARN         string
+	ExternalID      string
+	RoleSessionName string
+	// Region defaults to AWS_REGION or AWS_DEFAULT_REGION, then to the
+	// profile's region.
+	Region string
+	// Endpoint replaces the endpoints of every service, STS included, as
+	// for LocalStack or a private gateway.
+	Endpoint string
+}
+
+// FromConfig returns the options of AWS secret settings, with environment
+// variables expanded, or nil, for the default credentials, if there are
+// none.
+func FromConfig(s *config.AWSAuthSettings) *Options {
+	if s == nil {
+		return nil
+	}
+	return &Options{
+		Profile:         os.ExpandEnv(s.Profile),
+		AccessKeyID:     os.ExpandEnv(s.AccessKeyID),
+		SecretAccessKey: os.ExpandEnv(s.SecretAccessKey),
+		SessionToken:    os.ExpandEnv(s.SessionToken),
+		RoleARN:         os.ExpandEnv(s.RoleARN),
+		ExternalID:      os.ExpandEnv(s.ExternalID),
+		RoleSessionName: os.ExpandEnv(s.RoleSessionName),
+		Region:          os.ExpandEnv(s.Region),
+		Endpoint:        os.ExpandEnv(s.Endpoint),
+	}
+}
+
+// Validate reports conflicting or incomplete options.
+func (o *Options) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if (o.AccessKeyID == "") != (o.SecretAccessKey == "") {
+		return errors.New("static keys need both an access key ID and a secret access key")
+	}
+	if o.AccessKeyID != "" && o.Profile != "" {
+		return errors.New("set either a profile or static keys, not both")
+	}
+	if o.SessionToken != "" && o.AccessKeyID == "" {
+		return errors.New("a session token needs static keys")
+	}
+	if o.ExternalID != "" && o.RoleARN == "" {
+		return errors.New("an external ID needs a role to assume")
+	}
+	return nil
+}
+
+// Config returns the configuration of clients authenticating as o says,
+// with the default credentials if o is nil. Assumed roles are refreshed
+// before their sessions expire.
+func (o *Options) Config(ctx context.Context) (aws.Config, error) {
+	if o == nil {
+		o = &Options{}
+	}
+	if err := o.Validate(); err != nil {
+		return aws.Config{}, err
+	}
+
+	loadOpts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithAssumeRoleCredentialOptions(func(ao *stscreds.AssumeRoleOptions) {
+			if ao.RoleSessionName == "" {
+				ao.RoleSessionName = DefaultRoleSessionName
+			}
+		}),
+	}
+	if o.Profile != "" {
+		loadOpts = append(loadOpts, awsconfig.WithSharedConfigProfile(o.Profile))
+	}
+	if o.AccessKeyID != "" {
+		loadOpts = append(loadOpts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(o.AccessKeyID, o.SecretAccessKey, o.SessionToken)))
+	}
+	if o.Region != "" {
+		loadOpts = append(loadOpts, awsconfig.WithRegion(o.Region))
+	}
+	if o.Endpoint != "" {
+		loadOpts = append(loadOpts, awsconfig.WithBaseEndpoint(o.Endpoint))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load the AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		return aws.Config{}, errors.New("no AWS region: set one, or AWS_REGION")
+	}
+
+	if o.RoleARN != "" {
+		sessionName := o.RoleSessionName
+		if sessionName == "" {
+			sessionName = DefaultRoleSessionName
+		}
+		role := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), o.RoleARN, func(ao *stscreds.AssumeRoleOptions) {
+			ao.RoleSessionName = sessionName
+			if o.ExternalID != "" {
+				ao.ExternalID = aws.String(o.ExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(role)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,156 @@
+package awsauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+)
+
+// isolate points the shared configuration files at dir and clears the
+// environment variables that select credentials.
+func isolate(t *testing.T, dir string) {
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	for _, name := range []string{"AWS_PROFILE", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_REGION", "AWS_DEFAULT_REGION"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStaticAndEnvironment(t *testing.T) {
+	ctx := context.Background()
+	isolate(t, t.TempDir())
+
+	cfg, err := (&Options{AccessKeyID: "AKID", SecretAccessKey: "secret", Region: "eu-west-1"}).Config(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil || creds.AccessKeyID != "AKID" || cfg.Region != "eu-west-1" {
+		t.Errorf("static keys: %+v, %v in %s", creds, err, cfg.Region)
+	}
+
+	if _, err := (*Options)(nil).Config(ctx); err == nil {
+		t.Error("no credentials accepted")
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "ENVKEY")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	if _, err := (*Options)(nil).Config(ctx); err == nil || !strings.Contains(err.Error(), "region") {
+		t.Errorf("no region: %v", err)
+	}
+	t.Setenv("AWS_DEFAULT_REGION", "us-east-2")
+	cfg, err = (*Options)(nil).Config(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds, _ := cfg.Credentials.Retrieve(ctx); creds.AccessKeyID != "ENVKEY" || cfg.Region != "us-east-2" {
+		t.Errorf("environment: %+v in %s", creds, cfg.Region)
+	}
+}
+
+func TestProfileAssumesRole(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	isolate(t, dir)
+	writeFile(t, filepath.Join(dir, "credentials"), "[base]\naws_access_key_id = BASEKEY\naws_secret_access_key = basesecret\n")
+	writeFile(t, filepath.Join(dir, "config"), `[default]
+region = us-east-1
+
+[profile loader]
+region = us-west-2
+role_arn = arn:aws:iam::123456789012:role/loader
+source_profile = base
+external_id = from-profile
+`)
+
+	var form http.Header
+	var params string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form, params = r.Header, r.Form.Encode()
+		w.Write([]byte(`<AssumeRoleResponse><AssumeRoleResult><Credentials>
+<AccessKeyId>ROLEKEY</AccessKeyId><SecretAccessKey>rolesecret</SecretAccessKey><SessionToken>token</SessionToken>
+<Expiration>2030-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`))
+	}))
+	defer srv.Close()
+
+	cfg, err := (&Options{Profile: "loader", Endpoint: srv.URL}).Config(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Region != "us-west-2" {
+		t.Errorf("region = %s, want the profile's", cfg.Region)
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AccessKeyID != "ROLEKEY" || creds.SessionToken != "token" || !creds.CanExpire {
+		t.Errorf("assumed credentials = %+v", creds)
+	}
+	if !strings.Contains(form.Get("Authorization"), "Credential=BASEKEY/") {
+		t.Errorf("AssumeRole signed with %q, want the source profile's keys", form.Get("Authorization"))
+	}
+	for _, want := range []string{"Action=AssumeRole", "ExternalId=from-profile", "RoleSessionName=" + DefaultRoleSessionName} {
+		if !strings.Contains(params, want) {
+			t.Errorf("AssumeRole parameters %s lack %s", params, want)
+		}
+	}
+
+	if _, err := (&Options{Profile: "missing"}).Config(ctx); err == nil {
+		t.Error("missing profile accepted")
+	}
+}
+
+func TestProfileCredentialProcess(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	isolate(t, dir)
+	writeFile(t, filepath.Join(dir, "config"), `[profile tool]
+region = eu-central-1
+credential_process = echo '{"Version":1,"AccessKeyId":"PROCKEY","SecretAccessKey":"procsecret"}'
+`)
+
+	cfg, err := (&Options{Profile: "tool"}).Config(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil || creds.AccessKeyID != "PROCKEY" || cfg.Region != "eu-central-1" {
+		t.Errorf("credential_process: %+v, %v in %s", creds, err, cfg.Region)
+	}
+}
+
+func TestFromConfigAndValidate(t *testing.T) {
+	t.Setenv("AWSAUTH_TEST_EXTERNAL_ID", "ext-42")
+	o := FromConfig(&config.AWSAuthSettings{RoleARN: "arn:aws:iam::1:role/r", ExternalID: "${AWSAUTH_TEST_EXTERNAL_ID}"})
+	if o.ExternalID != "ext-42" {
+		t.Errorf("ExternalID = %q", o.ExternalID)
+	}
+	if FromConfig(nil) != nil {
+		t.Error("FromConfig(nil) is not nil")
+	}
+	for _, o := range []*Options{
+		{AccessKeyID: "AKID"},
+		{AccessKeyID: "AKID", SecretAccessKey: "s", Profile: "p"},
+		{SessionToken: "t"},
+		{ExternalID: "e"},
+	} {
+		if err := o.Validate(); err == nil {
+			t.Errorf("%+v accepted", o)
+		}
+	}
+}
@@ -0,0 +1,564 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package bench runs read, convert and write scenarios against local files
+// through data pipelines, over a matrix of sizes, codecs and concurrency,
+// and compares their results with those of an earlier run.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"github.com/arrowarc/arrowarc/pipeline"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/datagen"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of scenario.
+const (
+	// Read reads the input and discards its records.
+	Read = "read"
+	// Convert reads the input and writes it in ToFormat.
+	Convert = "convert"
+	// Write writes generated records, held in memory beforehand, in Format.
+	Write = "write"
+)
+
+// DefaultRows is the size of generated data when a scenario sets none.
+const DefaultRows = 100000
+
+// Scenario is a benchmark run for every combination of its Rows, Codecs and
+// Concurrency.
+type Scenario struct {
+	Name string `yaml:"name"`
+	Kind string `yaml:"kind"`
+	// Input is the file read by read and convert scenarios. When empty, the
+	// input is generated from Spec, once per size, in Format.
+	Input string `yaml:"input"`
+	// Format is the format of generated inputs, and the output format of
+	// write scenarios. Defaults to parquet.
+	Format string `yaml:"format"`
+	// ToFormat is the output format of convert scenarios.
+	ToFormat string `yaml:"to_format"`
+	// Spec is the path of the datagen spec of generated data. A spec of
+	// ids, categories, numbers, strings and timestamps is used by default.
+	Spec string `yaml:"spec"`
+	// Rows are the sizes of generated data. Ignored when Input is set.
+	Rows []int64 `yaml:"rows"`
+	// Codecs are the compression codecs of Parquet outputs: uncompressed,
+	// snappy, gzip, brotli, zstd or lz4.
+	Codecs []string `yaml:"codecs"`
+	// Concurrency are the numbers of pipelines run at once, each over the
+	// whole input. Defaults to 1.
+	Concurrency []int `yaml:"concurrency"`
+	// ChunkSize is the number of rows per record read.
+	ChunkSize int64 `yaml:"chunk_size"`
+	// Repeat is the number of runs of each case, of which the fastest is
+	// kept. Defaults to 1.
+	Repeat int `yaml:"repeat"`
+}
+
+// Suite is a set of scenarios.
+type Suite struct {
+	Scenarios []Scenario `yaml:"scenarios"`
+}
+
+// ParseSuite parses a YAML suite.
+func ParseSuite(data []byte) (*Suite, error) {
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("bench: invalid suite: %w", err)
+	}
+	for i, s := range suite.Scenarios {
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("bench: scenario %d (%s): %w", i+1, s.Name, err)
+		}
+	}
+	return &suite, nil
+}
+
+// LoadSuite reads the YAML suite at path. Relative input and spec paths
+// are resolved against the directory of path.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	suite, err := ParseSuite(data)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	for i := range suite.Scenarios {
+		s := &suite.Scenarios[i]
+		if s.Input != "" && !filepath.IsAbs(s.Input) {
+			s.Input = filepath.Join(dir, s.Input)
+		}
+		if s.Spec != "" && !filepath.IsAbs(s.Spec) {
+			s.Spec = filepath.Join(dir, s.Spec)
+		}
+	}
+	return suite, nil
+}
+
+func (s *Scenario) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	switch s.Kind {
+	case Read, Write:
+	case Convert:
+		if s.ToFormat == "" {
+			return fmt.Errorf("convert scenarios need to_format")
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", s.Kind)
+	}
+	if s.Kind == Write && s.Input != "" {
+		return fmt.Errorf("write scenarios generate their input")
+	}
+	for _, codec := range s.Codecs {
+		if _, err := parseCodec(codec); err != nil {
+			return err
+		}
+	}
+	if len(s.Codecs) > 0 && s.Kind == Read {
+		return fmt.Errorf("read scenarios write no output to compress")
+	}
+	if len(s.Codecs) > 0 && s.outputFormat() != integrations.SourceParquet {
+		return fmt.Errorf("codecs apply to Parquet outputs only")
+	}
+	for _, n := range s.Concurrency {
+		if n < 1 {
+			return fmt.Errorf("invalid concurrency %d", n)
+		}
+	}
+	for _, n := range s.Rows {
+		if n < 1 {
+			return fmt.Errorf("invalid number of rows %d", n)
+		}
+	}
+	return nil
+}
+
+// outputFormat returns the format written by the scenario, if any.
+func (s *Scenario) outputFormat() string {
+	switch s.Kind {
+	case Convert:
+		return s.ToFormat
+	case Write:
+		return s.format()
+	}
+	return ""
+}
+
+func (s *Scenario) format() string {
+	if s.Format == "" {
+		return integrations.SourceParquet
+	}
+	return s.Format
+}
+
+var codecs = map[string]compress.Compression{
+	"uncompressed": compress.Codecs.Uncompressed,
+	"snappy":       compress.Codecs.Snappy,
+	"gzip":         compress.Codecs.Gzip,
+	"brotli":       compress.Codecs.Brotli,
+	"zstd":         compress.Codecs.Zstd,
+	"lz4":          compress.Codecs.Lz4Raw,
+}
+
+func parseCodec(name string) (compress.Compression, error) {
+	codec, ok := codecs[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown codec %q", name)
+	}
+	return codec, nil
+}
+
+// Case is one combination of the parameters of a scenario.
+type Case struct {
+	Rows        int64  // zero when the input is a file
+	Codec       string // empty for the writer's default
+	Concurrency int
+}
+
+// Cases returns the cases of s, every combination of its rows, codecs and
+// concurrency.
+func (s *Scenario) Cases() []Case {
+	rows := s.Rows
+	switch {
+	case s.Input != "":
+		rows = []int64{0}
+	case len(rows) == 0:
+		rows = []int64{DefaultRows}
+	}
+	codecs := s.Codecs
+	if len(codecs) == 0 {
+		codecs = []string{""}
+	}
+	concurrency := s.Concurrency
+	if len(concurrency) == 0 {
+		concurrency = []int{1}
+	}
+	var cases []Case
+	for _, n := range rows {
+		for _, codec := range codecs {
+			for _, c := range concurrency {
+				cases = append(cases, Case{Rows: n, Codec: codec, Concurrency: c})
+			}
+		}
+	}
+	return cases
+}
+
+// name names a case of scenario s after its parameters.
+func (s *Scenario) name(c Case) string {
+	parts := []string{s.Name}
+	if c.Rows > 0 {
+		parts = append(parts, fmt.Sprintf("rows=%d", c.Rows))
+	}
+	if c.Codec != "" {
+		parts = append(parts, "codec="+c.Codec)
+	}
+	parts = append(parts, fmt.Sprintf("concurrency=%d", c.Concurrency))
+	return strings.Join(parts, "/")
+}
+
+// Result is the outcome of a case: the fastest of its runs. Rows counts the
+// rows of all its pipelines, and OutputBytes the size of the output of one.
+type Result struct {
+	Name        string  `json:"name"`
+	Scenario    string  `json:"scenario"`
+	Kind        string  `json:"kind"`
+	Format      string  `json:"format,omitempty"`
+	ToFormat    string  `json:"to_format,omitempty"`
+	Rows        int64   `json:"rows"`
+	Codec       string  `json:"codec,omitempty"`
+	Concurrency int     `json:"concurrency"`
+	Runs        int     `json:"runs"`
+	Seconds     float64 `json:"seconds"`
+	RowsPerSec  float64 `json:"rows_per_second"`
+	BytesPerSec float64 `json:"bytes_per_second"` // in-memory Arrow bytes
+	OutputBytes int64   `json:"output_bytes,omitempty"`
+}
+
+// Run runs the scenarios of suite in order, writing generated inputs and
+// outputs under dir, and returns the result of each case.
+func Run(ctx context.Context, suite *Suite, dir string) ([]Result, error) {
+	r := &runner{dir: dir, inputs: make(map[string]string)}
+	var results []Result
+	for i := range suite.Scenarios {
+		s := &suite.Scenarios[i]
+		for _, c := range s.Cases() {
+			result, err := r.run(ctx, s, c)
+			if err != nil {
+				return results, fmt.Errorf("bench: %s: %w", s.name(c), err)
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// runner runs cases, sharing the inputs it generates between them.
+type runner struct {
+	dir    string
+	inputs map[string]string // generated input files by spec, size and format
+	seq    int
+}
+
+func (r *runner) run(ctx context.Context, s *Scenario, c Case) (Result, error) {
+	result := Result{
+		Name:        s.name(c),
+		Scenario:    s.Name,
+		Kind:        s.Kind,
+		Format:      s.Format,
+		ToFormat:    s.ToFormat,
+		Rows:        c.Rows,
+		Codec:       c.Codec,
+		Concurrency: c.Concurrency,
+	}
+	if s.Kind == Write {
+		result.Format = s.format()
+	}
+
+	var records []arrow.Record
+	input := s.Input
+	var err error
+	switch {
+	case s.Kind == Write:
+		if records, err = generateRecords(s, c.Rows); err != nil {
+			return result, err
+		}
+		defer func() {
+			for _, rec := range records {
+				rec.Release()
+			}
+		}()
+	case input == "":
+		result.Format = s.format()
+		if input, err = r.generateInput(ctx, s, c.Rows); err != nil {
+			return result, err
+		}
+	}
+
+	repeat := max(s.Repeat, 1)
+	for i := 0; i < repeat; i++ {
+		run, err := r.runOnce(ctx, s, c, input, records)
+		if err != nil {
+			return result, err
+		}
+		if i == 0 || run.Seconds < result.Seconds {
+			result.Rows, result.Seconds = run.Rows, run.Seconds
+			result.RowsPerSec, result.BytesPerSec = run.RowsPerSec, run.BytesPerSec
+			result.OutputBytes = run.OutputBytes
+		}
+		result.Runs++
+	}
+	return result, nil
+}
+
+// runOnce runs c.Concurrency pipelines at once and measures them as one.
+func (r *runner) runOnce(ctx context.Context, s *Scenario, c Case, input string, records []arrow.Record) (Result, error) {
+	var rows, bytes, outputBytes atomic.Int64
+	errs := make([]error, c.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < c.Concurrency; i++ {
+		r.seq++
+		output := filepath.Join(r.dir, fmt.Sprintf("output-%d.%s", r.seq, extension(s.outputFormat())))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			metrics, err := r.pipeline(ctx, s, c, input, output, records)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			rows.Add(atomic.LoadInt64(&metrics.RecordsProcessed))
+			bytes.Add(atomic.LoadInt64(&metrics.TotalBytes))
+			if s.outputFormat() != "" {
+				if info, err := os.Stat(output); err == nil {
+					outputBytes.Add(info.Size())
+				}
+				os.Remove(output)
+			}
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+	for _, err := range errs {
+		if err != nil {
+			return Result{}, err
+		}
+	}
+	return Result{
+		Rows:        rows.Load(),
+		Seconds:     elapsed,
+		RowsPerSec:  float64(rows.Load()) / elapsed,
+		BytesPerSec: float64(bytes.Load()) / elapsed,
+		OutputBytes: outputBytes.Load() / int64(c.Concurrency),
+	}, nil
+}
+
+// pipeline runs one pipeline of the case and returns its metrics.
+func (r *runner) pipeline(ctx context.Context, s *Scenario, c Case, input, output string, records []arrow.Record) (*pipeline.Metrics, error) {
+	var reader interfaces.Reader
+	var schema *arrow.Schema
+	if s.Kind == Write {
+		reader, schema = &replayReader{records: records}, records[0].Schema()
+	} else {
+		in, err := converter.OpenInput(ctx, input, &converter.ConvertOptions{
+			ChunkSize: s.ChunkSize,
+			CSV:       csvschema.CSVReadOptions{Delimiter: ',', HasHeader: true},
+		})
+		if err != nil {
+			return nil, err
+		}
+		reader, schema = in, in.Schema()
+	}
+
+	var writer interfaces.Writer = discardWriter{}
+	if s.Kind != Read {
+		var err error
+		if writer, err = newWriter(ctx, output, s.outputFormat(), c.Codec, schema); err != nil {
+			reader.Close()
+			return nil, err
+		}
+	}
+	p := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil)
+	if _, err := p.Start(ctx); err != nil {
+		return nil, err
+	}
+	if err := <-p.Done(); err != nil {
+		return nil, err
+	}
+	return p.Metrics(), nil
+}
+
+// generateInput writes the data of s at the given size in its format, once
+// for all the cases sharing them, and returns its path.
+func (r *runner) generateInput(ctx context.Context, s *Scenario, rows int64) (string, error) {
+	key := fmt.Sprintf("%s|%d|%s", s.Spec, rows, s.format())
+	if path, ok := r.inputs[key]; ok {
+		return path, nil
+	}
+	spec, err := loadSpec(s.Spec, rows)
+	if err != nil {
+		return "", err
+	}
+	g, err := datagen.NewGenerator(spec, nil)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(r.dir, fmt.Sprintf("input-%d.%s", len(r.inputs)+1, extension(s.format())))
+	w, err := newWriter(ctx, path, s.format(), "", g.Schema())
+	if err != nil {
+		return "", err
+	}
+	if err := datagen.Generate(ctx, spec, w); err != nil {
+		w.Close()
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	r.inputs[key] = path
+	return path, nil
+}
+
+// generateRecords returns the records of the data of s at the given size.
+func generateRecords(s *Scenario, rows int64) ([]arrow.Record, error) {
+	spec, err := loadSpec(s.Spec, rows)
+	if err != nil {
+		return nil, err
+	}
+	if s.ChunkSize > 0 {
+		spec.BatchSize = int(s.ChunkSize)
+	}
+	g, err := datagen.NewGenerator(spec, nil)
+	if err != nil {
+		return nil, err
+	}
+	var records []arrow.Record
+	for {
+		rec, err := g.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// loadSpec loads the spec at path, or the default spec, at the given size.
+func loadSpec(path string, rows int64) (*datagen.Spec, error) {
+	spec := &datagen.Spec{Seed: 1, Columns: []datagen.Column{
+		{Name: "id", Type: "int64", Distribution: datagen.Sequence},
+		{Name: "category", Type: "string", Distribution: datagen.Zipf, Values: []string{"alpha", "beta", "gamma", "delta", "epsilon"}},
+		{Name: "amount", Type: "float64", Max: "10000", NullRate: 0.05},
+		{Name: "quantity", Type: "int32", Max: "100"},
+		{Name: "label", Type: "string", Min: "5", Max: "20"},
+		{Name: "created", Type: "timestamp[us, tz=UTC]"},
+	}}
+	if path != "" {
+		var err error
+		if spec, err = datagen.LoadSpec(path); err != nil {
+			return nil, err
+		}
+	}
+	spec.Rows = rows
+	return spec, nil
+}
+
+// newWriter creates a writer of format at path, compressed with codec if
+// set, which only Parquet outputs take.
+func newWriter(ctx context.Context, path, format, codec string, schema *arrow.Schema) (interfaces.Writer, error) {
+	if format != integrations.SourceParquet || codec == "" {
+		return converter.CreateOutput(ctx, path, format, schema)
+	}
+	compression, err := parseCodec(codec)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewParquetWriterWithOptions(path, schema, &integrations.ParquetWriteOptions{Compression: &compression})
+}
+
+// extension returns the file extension of format.
+func extension(format string) string {
+	switch format {
+	case "":
+		return "out"
+	case "ipc":
+		return "arrow"
+	case "json", "jsonl":
+		return "ndjson"
+	}
+	return format
+}
+
+// replayReader returns records held in memory, retained for the caller.
+type replayReader struct {
+	records []arrow.Record
+	next    int
+}
+
+func (r *replayReader) Read() (arrow.Record, error) {
+	if r.next == len(r.records) {
+		return nil, io.EOF
+	}
+	rec := r.records[r.next]
+	r.next++
+	rec.Retain()
+	return rec, nil
+}
+
+func (r *replayReader) Close() error { return nil }
+
+type discardWriter struct{}
+
+func (discardWriter) Write(arrow.Record) error { return nil }
+func (discardWriter) Close() error             { return nil }
@@ -0,0 +1,171 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package bench
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arrowarc/arrowarc/pkg/datagen"
+)
+
+const testSuite = `
+scenarios:
+  - name: read
+    kind: read
+    rows: [1000, 2000]
+  - name: to-csv
+    kind: convert
+    to_format: csv
+    rows: [1000]
+    concurrency: [1, 2]
+    repeat: 2
+  - name: write
+    kind: write
+    rows: [1000]
+    codecs: [snappy, zstd]
+`
+
+func TestRun(t *testing.T) {
+	suite, err := ParseSuite([]byte(testSuite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := Run(context.Background(), suite, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Name)
+		if r.RowsPerSec <= 0 || r.Seconds <= 0 {
+			t.Errorf("%s: no throughput: %+v", r.Name, r)
+		}
+	}
+	want := []string{
+		"read/rows=1000/concurrency=1",
+		"read/rows=2000/concurrency=1",
+		"to-csv/rows=1000/concurrency=1",
+		"to-csv/rows=1000/concurrency=2",
+		"write/rows=1000/codec=snappy/concurrency=1",
+		"write/rows=1000/codec=zstd/concurrency=1",
+	}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Fatalf("cases = %v, want %v", names, want)
+	}
+	if r := results[1]; r.Rows != 2000 || r.OutputBytes != 0 || r.Format != "parquet" {
+		t.Errorf("read result = %+v", r)
+	}
+	if r := results[3]; r.Rows != 2000 || r.Runs != 2 || r.OutputBytes == 0 {
+		t.Errorf("convert result = %+v", r)
+	}
+	if r := results[5]; r.Rows != 1000 || r.Codec != "zstd" || r.OutputBytes == 0 {
+		t.Errorf("write result = %+v", r)
+	}
+}
+
+func TestRunFileInput(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.parquet")
+	if err := writeInput(input); err != nil {
+		t.Fatal(err)
+	}
+	suite := &Suite{Scenarios: []Scenario{{Name: "file", Kind: Convert, Input: input, ToFormat: "ndjson"}}}
+	results, err := Run(context.Background(), suite, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Name != "file/concurrency=1" || results[0].Rows != 300 {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func writeInput(path string) error {
+	spec := &datagen.Spec{Rows: 300, Columns: []datagen.Column{{Name: "id", Type: "int64"}}}
+	g, err := datagen.NewGenerator(spec, nil)
+	if err != nil {
+		return err
+	}
+	w, err := newWriter(context.Background(), path, "parquet", "", g.Schema())
+	if err != nil {
+		return err
+	}
+	if err := datagen.Generate(context.Background(), spec, w); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func TestParseSuiteErrors(t *testing.T) {
+	for _, test := range []struct {
+		suite string
+		err   string
+	}{
+		{"scenarios: [{kind: read}]", "missing name"},
+		{"scenarios: [{name: a, kind: copy}]", `unknown kind "copy"`},
+		{"scenarios: [{name: a, kind: convert}]", "convert scenarios need to_format"},
+		{"scenarios: [{name: a, kind: write, codecs: [lzo]}]", `unknown codec "lzo"`},
+		{"scenarios: [{name: a, kind: write, format: csv, codecs: [zstd]}]", "codecs apply to Parquet outputs only"},
+		{"scenarios: [{name: a, kind: read, concurrency: [0]}]", "invalid concurrency 0"},
+	} {
+		_, err := ParseSuite([]byte(test.suite))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want %q", test.suite, err, test.err)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := []Result{{Name: "a", RowsPerSec: 100}, {Name: "b", RowsPerSec: 100}}
+	current := []Result{{Name: "a", RowsPerSec: 85}, {Name: "b", RowsPerSec: 95}, {Name: "c", RowsPerSec: 10}}
+	report := &Report{Results: current, Comparisons: Compare(baseline, current, 0.1)}
+	if len(report.Comparisons) != 2 || report.Regressions() != 1 || !report.Comparisons[0].Regression {
+		t.Fatalf("comparisons = %+v", report.Comparisons)
+	}
+
+	var out bytes.Buffer
+	if err := WriteCSV(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.HasSuffix(lines[1], ",100,-0.15000000000000002") || !strings.HasSuffix(lines[3], ",,") {
+		t.Errorf("csv:\n%s", out.String())
+	}
+	out.Reset()
+	if err := WriteText(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "-15.0% regression") {
+		t.Errorf("text:\n%s", out.String())
+	}
+}
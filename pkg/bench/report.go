@@ -0,0 +1,175 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Comparison compares the throughput of a case with that of the same case,
+// by name, in a baseline run.
+type Comparison struct {
+	Name     string  `json:"name"`
+	Baseline float64 `json:"baseline_rows_per_second"`
+	Current  float64 `json:"rows_per_second"`
+	// Change is the relative change of throughput: -0.2 is 20% slower.
+	Change     float64 `json:"change"`
+	Regression bool    `json:"regression"`
+}
+
+// Report holds the results of a run and, if compared with a baseline,
+// their comparisons.
+type Report struct {
+	Results     []Result     `json:"results"`
+	Comparisons []Comparison `json:"comparisons,omitempty"`
+}
+
+// Compare compares the results of a run with those of a baseline, case by
+// case. A case is a regression when its throughput dropped by more than
+// threshold, a fraction. Cases missing from the baseline are skipped.
+func Compare(baseline, current []Result, threshold float64) []Comparison {
+	byName := make(map[string]Result, len(baseline))
+	for _, r := range baseline {
+		byName[r.Name] = r
+	}
+	var comparisons []Comparison
+	for _, r := range current {
+		base, ok := byName[r.Name]
+		if !ok || base.RowsPerSec <= 0 {
+			continue
+		}
+		change := r.RowsPerSec/base.RowsPerSec - 1
+		comparisons = append(comparisons, Comparison{
+			Name:       r.Name,
+			Baseline:   base.RowsPerSec,
+			Current:    r.RowsPerSec,
+			Change:     change,
+			Regression: change < -threshold,
+		})
+	}
+	return comparisons
+}
+
+// Regressions counts the comparisons of r that are regressions.
+func (r *Report) Regressions() int {
+	n := 0
+	for _, c := range r.Comparisons {
+		if c.Regression {
+			n++
+		}
+	}
+	return n
+}
+
+// ReadReport reads a report written by WriteJSON.
+func ReadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("bench: invalid report %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// WriteJSON writes r as indented JSON.
+func WriteJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes one line per result, with a header, followed by the
+// baseline throughput and change of its comparison, if any.
+func WriteCSV(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "scenario", "kind", "format", "to_format", "rows", "codec", "concurrency", "runs",
+		"seconds", "rows_per_second", "bytes_per_second", "output_bytes", "baseline_rows_per_second", "change"})
+	comparisons := r.comparisons()
+	for _, res := range r.Results {
+		line := []string{res.Name, res.Scenario, res.Kind, res.Format, res.ToFormat,
+			strconv.FormatInt(res.Rows, 10), res.Codec, strconv.Itoa(res.Concurrency), strconv.Itoa(res.Runs),
+			formatFloat(res.Seconds), formatFloat(res.RowsPerSec), formatFloat(res.BytesPerSec),
+			strconv.FormatInt(res.OutputBytes, 10), "", ""}
+		if c, ok := comparisons[res.Name]; ok {
+			line[13], line[14] = formatFloat(c.Baseline), formatFloat(c.Change)
+		}
+		cw.Write(line)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteText writes the results as an aligned table, with the change from
+// the baseline of each compared case.
+func WriteText(w io.Writer, r *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	comparisons := r.comparisons()
+	header := "CASE\tROWS\tSECONDS\tROWS/S\tMB/S\tOUTPUT"
+	if len(comparisons) > 0 {
+		header += "\tCHANGE"
+	}
+	fmt.Fprintln(tw, header)
+	for _, res := range r.Results {
+		output := "-"
+		if res.OutputBytes > 0 {
+			output = fmt.Sprintf("%.1f MB", float64(res.OutputBytes)/1e6)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.3f\t%.0f\t%.1f\t%s", res.Name, res.Rows, res.Seconds, res.RowsPerSec, res.BytesPerSec/1e6, output)
+		if c, ok := comparisons[res.Name]; ok {
+			fmt.Fprintf(tw, "\t%+.1f%%", c.Change*100)
+			if c.Regression {
+				fmt.Fprint(tw, " regression")
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func (r *Report) comparisons() map[string]Comparison {
+	m := make(map[string]Comparison, len(r.Comparisons))
+	for _, c := range r.Comparisons {
+		m[c.Name] = c
+	}
+	return m
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
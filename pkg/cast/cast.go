@@ -0,0 +1,325 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package cast converts whole Arrow arrays between types, writing the
+// output buffers directly instead of appending values one at a time
+// through a builder. The null bitmap of the input is copied as is.
+package cast
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/bitutil"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// validity returns a copy of the null bitmap of arr starting at bit zero,
+// or nil if arr has no nulls.
+func validity(mem memory.Allocator, arr arrow.Array) *memory.Buffer {
+	if arr.NullN() == 0 {
+		return nil
+	}
+	buf := memory.NewResizableBuffer(mem)
+	buf.Resize(int(bitutil.BytesForBits(int64(arr.Len()))))
+	bitutil.CopyBitmap(arr.NullBitmapBytes(), arr.Data().Offset(), arr.Len(), buf.Bytes(), 0)
+	return buf
+}
+
+// newFixedWidth allocates the validity and value buffers of an array of
+// n values of type dt, copying the validity of arr.
+func newFixedWidth(mem memory.Allocator, dt arrow.FixedWidthDataType, arr arrow.Array) (valid, values *memory.Buffer) {
+	values = memory.NewResizableBuffer(mem)
+	values.Resize(arr.Len() * dt.BitWidth() / 8)
+	return validity(mem, arr), values
+}
+
+// finish wraps the buffers in an array, releasing the caller's references.
+func finish(dt arrow.DataType, n, nulls int, buffers ...*memory.Buffer) arrow.Array {
+	data := array.NewData(dt, n, buffers, nil, nulls, 0)
+	defer data.Release()
+	for _, b := range buffers {
+		if b != nil {
+			b.Release()
+		}
+	}
+	return array.MakeFromData(data)
+}
+
+func release(buffers ...*memory.Buffer) {
+	for _, b := range buffers {
+		if b != nil {
+			b.Release()
+		}
+	}
+}
+
+// stringValues returns a function reading the i-th value of a string or
+// large string array.
+func stringValues(arr arrow.Array) (func(int) string, error) {
+	switch arr := arr.(type) {
+	case *array.String:
+		return arr.Value, nil
+	case *array.LargeString:
+		return arr.Value, nil
+	}
+	return nil, fmt.Errorf("expected a string array, got %s", arr.DataType())
+}
+
+// StringToTimestamp parses the strings of arr with layout, in the time zone
+// of to, or UTC if it has none. Runs of equal strings are parsed once.
+func StringToTimestamp(mem memory.Allocator, arr arrow.Array, to *arrow.TimestampType, layout string) (arrow.Array, error) {
+	value, err := stringValues(arr)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := to.GetZone()
+	if err != nil {
+		return nil, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	valid, values := newFixedWidth(mem, to, arr)
+	out := arrow.TimestampTraits.CastFromBytes(values.Bytes())
+	var last string
+	var lastTS arrow.Timestamp
+	parsed := false
+	for i := range out {
+		if arr.IsNull(i) {
+			continue
+		}
+		s := value(i)
+		if parsed && s == last {
+			out[i] = lastTS
+			continue
+		}
+		tm, err := time.ParseInLocation(layout, s, loc)
+		if err == nil {
+			lastTS, err = arrow.TimestampFromTime(tm, to.Unit)
+		}
+		if err != nil {
+			release(valid, values)
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		out[i], last, parsed = lastTS, s, true
+	}
+	return finish(to, arr.Len(), arr.NullN(), valid, values), nil
+}
+
+// StringToDate32 parses the strings of arr as dates with layout.
+func StringToDate32(mem memory.Allocator, arr arrow.Array, layout string) (arrow.Array, error) {
+	value, err := stringValues(arr)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, values := newFixedWidth(mem, arrow.FixedWidthTypes.Date32.(arrow.FixedWidthDataType), arr)
+	out := arrow.Date32Traits.CastFromBytes(values.Bytes())
+	var last string
+	var lastDate arrow.Date32
+	parsed := false
+	for i := range out {
+		if arr.IsNull(i) {
+			continue
+		}
+		s := value(i)
+		if parsed && s == last {
+			out[i] = lastDate
+			continue
+		}
+		tm, err := time.Parse(layout, s)
+		if err != nil {
+			release(valid, values)
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		lastDate = arrow.Date32FromTime(tm)
+		out[i], last, parsed = lastDate, s, true
+	}
+	return finish(arrow.FixedWidthTypes.Date32, arr.Len(), arr.NullN(), valid, values), nil
+}
+
+// IntToDecimalString formats the integers of arr in base 10, as decimals
+// with scale digits after the point if scale is positive: 12345 with a
+// scale of 2 becomes "123.45".
+func IntToDecimalString(mem memory.Allocator, arr arrow.Array, scale int32) (arrow.Array, error) {
+	if scale < 0 {
+		return nil, fmt.Errorf("scale cannot be negative, got %d", scale)
+	}
+	switch arr := arr.(type) {
+	case *array.Int8:
+		return formatInts(mem, arr, arr.Int8Values(), scale), nil
+	case *array.Int16:
+		return formatInts(mem, arr, arr.Int16Values(), scale), nil
+	case *array.Int32:
+		return formatInts(mem, arr, arr.Int32Values(), scale), nil
+	case *array.Int64:
+		return formatInts(mem, arr, arr.Int64Values(), scale), nil
+	case *array.Uint8:
+		return formatUints(mem, arr, arr.Uint8Values(), scale), nil
+	case *array.Uint16:
+		return formatUints(mem, arr, arr.Uint16Values(), scale), nil
+	case *array.Uint32:
+		return formatUints(mem, arr, arr.Uint32Values(), scale), nil
+	case *array.Uint64:
+		return formatUints(mem, arr, arr.Uint64Values(), scale), nil
+	}
+	return nil, fmt.Errorf("expected an integer array, got %s", arr.DataType())
+}
+
+func formatInts[T int8 | int16 | int32 | int64](mem memory.Allocator, arr arrow.Array, values []T, scale int32) arrow.Array {
+	return formatDigits(mem, arr, scale, func(i int) (uint64, bool) {
+		v := int64(values[i])
+		if v < 0 {
+			// Negating in uint64 also handles math.MinInt64.
+			return -uint64(v), true
+		}
+		return uint64(v), false
+	})
+}
+
+func formatUints[T uint8 | uint16 | uint32 | uint64](mem memory.Allocator, arr arrow.Array, values []T, scale int32) arrow.Array {
+	return formatDigits(mem, arr, scale, func(i int) (uint64, bool) {
+		return uint64(values[i]), false
+	})
+}
+
+// formatDigits builds a string array from the magnitude and sign of each
+// value of arr.
+func formatDigits(mem memory.Allocator, arr arrow.Array, scale int32, value func(int) (uint64, bool)) arrow.Array {
+	n := arr.Len()
+	offsets := memory.NewResizableBuffer(mem)
+	offsets.Resize((n + 1) * arrow.Int32SizeBytes)
+	offs := arrow.Int32Traits.CastFromBytes(offsets.Bytes())
+
+	// Leave room for 20 digits, a sign and a point per value.
+	data := make([]byte, 0, n*22)
+	var digits [20]byte
+	for i := 0; i < n; i++ {
+		offs[i] = int32(len(data))
+		if arr.IsNull(i) {
+			continue
+		}
+		mag, neg := value(i)
+		d := strconv.AppendUint(digits[:0], mag, 10)
+		if neg {
+			data = append(data, '-')
+		}
+		if scale == 0 {
+			data = append(data, d...)
+			continue
+		}
+		data = appendScaled(data, d, int(scale))
+	}
+	offs[n] = int32(len(data))
+
+	values := memory.NewResizableBuffer(mem)
+	values.Resize(len(data))
+	copy(values.Bytes(), data)
+	return finish(arrow.BinaryTypes.String, n, arr.NullN(), validity(mem, arr), offsets, values)
+}
+
+// appendScaled appends digits with a point before the last scale of them,
+// padding with zeros so that there is one digit before the point.
+func appendScaled(dst, digits []byte, scale int) []byte {
+	if len(digits) <= scale {
+		dst = append(dst, '0', '.')
+		for pad := scale - len(digits); pad > 0; pad-- {
+			dst = append(dst, '0')
+		}
+		return append(dst, digits...)
+	}
+	point := len(digits) - scale
+	dst = append(dst, digits[:point]...)
+	dst = append(dst, '.')
+	return append(dst, digits[point:]...)
+}
+
+// BoolToInt converts booleans to 1 and 0 of the integer type to.
+func BoolToInt(mem memory.Allocator, arr arrow.Array, to arrow.DataType) (arrow.Array, error) {
+	b, ok := arr.(*array.Boolean)
+	if !ok {
+		return nil, fmt.Errorf("expected a boolean array, got %s", arr.DataType())
+	}
+	fw, ok := to.(arrow.FixedWidthDataType)
+	if !ok {
+		return nil, fmt.Errorf("cannot cast bool to %s", to)
+	}
+	valid, values := newFixedWidth(mem, fw, arr)
+	bits, offset := b.Data().Buffers()[1].Bytes(), b.Data().Offset()
+	switch to.ID() {
+	case arrow.INT8:
+		fillBools(arrow.Int8Traits.CastFromBytes(values.Bytes()), bits, offset)
+	case arrow.INT16:
+		fillBools(arrow.Int16Traits.CastFromBytes(values.Bytes()), bits, offset)
+	case arrow.INT32:
+		fillBools(arrow.Int32Traits.CastFromBytes(values.Bytes()), bits, offset)
+	case arrow.INT64:
+		fillBools(arrow.Int64Traits.CastFromBytes(values.Bytes()), bits, offset)
+	case arrow.UINT8:
+		fillBools(arrow.Uint8Traits.CastFromBytes(values.Bytes()), bits, offset)
+	case arrow.UINT16:
+		fillBools(arrow.Uint16Traits.CastFromBytes(values.Bytes()), bits, offset)
+	case arrow.UINT32:
+		fillBools(arrow.Uint32Traits.CastFromBytes(values.Bytes()), bits, offset)
+	case arrow.UINT64:
+		fillBools(arrow.Uint64Traits.CastFromBytes(values.Bytes()), bits, offset)
+	default:
+		release(valid, values)
+		return nil, fmt.Errorf("cannot cast bool to %s", to)
+	}
+	return finish(to, arr.Len(), arr.NullN(), valid, values), nil
+}
+
+func fillBools[T int8 | int16 | int32 | int64 | uint8 | uint16 | uint32 | uint64](dst []T, bits []byte, offset int) {
+	for i := range dst {
+		if bitutil.BitIsSet(bits, offset+i) {
+			dst[i] = 1
+		} else {
+			dst[i] = 0
+		}
+	}
+}
+
+// DecodeDictionary returns the values of a dictionary array, taken from its
+// dictionary by index.
+func DecodeDictionary(mem memory.Allocator, arr arrow.Array) (arrow.Array, error) {
+	dict, ok := arr.(*array.Dictionary)
+	if !ok {
+		return nil, fmt.Errorf("expected a dictionary array, got %s", arr.DataType())
+	}
+	ctx := compute.WithAllocator(context.Background(), mem)
+	return compute.TakeArray(ctx, dict.Dictionary(), dict.Indices())
+}
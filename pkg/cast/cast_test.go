@@ -0,0 +1,202 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package cast
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestStringToTimestamp(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	b := array.NewStringBuilder(mem)
+	b.AppendValues([]string{"skip", "2024-01-02T03:04:05Z", "2024-01-02T03:04:05Z", "", "2024-06-01T00:00:00+02:00"}, []bool{true, true, true, false, true})
+	in := b.NewArray()
+	b.Release()
+	defer in.Release()
+	// Slice away the first value to check that offsets are honoured.
+	sliced := array.NewSlice(in, 1, int64(in.Len()))
+	defer sliced.Release()
+
+	to := &arrow.TimestampType{Unit: arrow.Second, TimeZone: "UTC"}
+	out, err := StringToTimestamp(mem, sliced, to, time.RFC3339)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+
+	ts := out.(*array.Timestamp)
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Unix()
+	if ts.Value(0) != arrow.Timestamp(want) || ts.Value(1) != arrow.Timestamp(want) {
+		t.Errorf("values = %v, want %d twice", ts.TimestampValues()[:2], want)
+	}
+	if !ts.IsNull(2) || ts.NullN() != 1 {
+		t.Errorf("nulls = %d, want only row 2", ts.NullN())
+	}
+	if got := ts.Value(3).ToTime(arrow.Second); !got.Equal(time.Date(2024, 5, 31, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("row 3 = %v", got)
+	}
+
+	bad := array.NewSlice(in, 0, 1)
+	defer bad.Release()
+	if _, err := StringToTimestamp(mem, bad, to, time.RFC3339); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestStringToDate32(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	b := array.NewLargeStringBuilder(mem)
+	b.AppendValues([]string{"1970-01-02", ""}, []bool{true, false})
+	in := b.NewArray()
+	b.Release()
+	defer in.Release()
+
+	out, err := StringToDate32(mem, in, time.DateOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	if got := out.(*array.Date32); got.Value(0) != 1 || !got.IsNull(1) {
+		t.Errorf("dates = %v", got)
+	}
+}
+
+func TestIntToDecimalString(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	b := array.NewInt64Builder(mem)
+	b.AppendValues([]int64{12345, -5, 0, math.MinInt64, 7}, []bool{true, true, true, true, false})
+	in := b.NewArray()
+	b.Release()
+	defer in.Release()
+
+	tests := []struct {
+		scale int32
+		want  []string
+	}{
+		{0, []string{"12345", "-5", "0", "-9223372036854775808"}},
+		{2, []string{"123.45", "-0.05", "0.00", "-92233720368547758.08"}},
+	}
+	for _, tt := range tests {
+		out, err := IntToDecimalString(mem, in, tt.scale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		strs := out.(*array.String)
+		for i, want := range tt.want {
+			if got := strs.Value(i); got != want {
+				t.Errorf("scale %d row %d = %q, want %q", tt.scale, i, got, want)
+			}
+		}
+		if !strs.IsNull(4) {
+			t.Errorf("scale %d: row 4 should be null", tt.scale)
+		}
+		out.Release()
+	}
+
+	u := array.NewUint8Builder(mem)
+	u.Append(255)
+	ua := u.NewArray()
+	u.Release()
+	defer ua.Release()
+	out, err := IntToDecimalString(mem, ua, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	if got := out.(*array.String).Value(0); got != "25.5" {
+		t.Errorf("uint8 = %q, want 25.5", got)
+	}
+}
+
+func TestBoolToInt(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	b := array.NewBooleanBuilder(mem)
+	b.AppendValues([]bool{false, true, false, true}, []bool{true, true, false, true})
+	in := b.NewArray()
+	b.Release()
+	defer in.Release()
+	sliced := array.NewSlice(in, 1, 4)
+	defer sliced.Release()
+
+	out, err := BoolToInt(mem, sliced, arrow.PrimitiveTypes.Int32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	ints := out.(*array.Int32)
+	if ints.Value(0) != 1 || !ints.IsNull(1) || ints.Value(2) != 1 {
+		t.Errorf("ints = %v, want [1 (null) 1]", ints)
+	}
+
+	if _, err := BoolToInt(mem, in, arrow.PrimitiveTypes.Float64); err == nil {
+		t.Error("expected an error casting to float64")
+	}
+}
+
+func TestDecodeDictionary(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	dt := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.BinaryTypes.String}
+	b := array.NewDictionaryBuilder(mem, dt).(*array.BinaryDictionaryBuilder)
+	for _, s := range []string{"a", "b", "a"} {
+		if err := b.AppendString(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.AppendNull()
+	in := b.NewArray()
+	b.Release()
+	defer in.Release()
+
+	out, err := DecodeDictionary(mem, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	strs := out.(*array.String)
+	if strs.Value(0) != "a" || strs.Value(1) != "b" || strs.Value(2) != "a" || !strs.IsNull(3) {
+		t.Errorf("decoded = %v", strs)
+	}
+}
@@ -32,8 +32,17 @@ package config
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"github.com/arrowarc/arrowarc/pkg/retry"
 	"gopkg.in/yaml.v3"
 )
 
@@ -47,7 +56,9 @@ type Config struct {
 		Tasks        []Task        `yaml:"tasks"`
 		Settings     Settings      `yaml:"settings"`
 		Secrets      []Secret      `yaml:"secrets"`
-		Monitoring   struct {
+		// Notifications are sent when a run of the workflow completes.
+		Notifications []Notification `yaml:"notifications"`
+		Monitoring    struct {
 			Enable          bool              `yaml:"enable"`
 			MetricsEndpoint string            `yaml:"metrics_endpoint"`
 			AlertThresholds map[string]string `yaml:"alert_thresholds"`
@@ -55,6 +66,7 @@ type Config struct {
 		Resources struct {
 			CPULimit         string `yaml:"cpu_limit"`
 			MemoryLimit      string `yaml:"memory_limit"`
+			SpillThreshold   string `yaml:"spill_threshold"`
 			StorageLimit     string `yaml:"storage_limit"`
 			ExecutionTimeout string `yaml:"execution_timeout"`
 			MaxRetries       int    `yaml:"max_retries"`
@@ -63,11 +75,126 @@ type Config struct {
 }
 
 type Settings struct {
-	ParallelTasks int    `yaml:"parallel_tasks"`
-	RetryAttempts int    `yaml:"retry_attempts"`
-	LogLevel      string `yaml:"log_level"`
-	TempDirectory string `yaml:"temp_directory"`
-	MaxMemory     string `yaml:"max_memory"`
+	ParallelTasks int          `yaml:"parallel_tasks"`
+	RetryAttempts int          `yaml:"retry_attempts"`
+	LogLevel      string       `yaml:"log_level"`
+	LogFormat     string       `yaml:"log_format"`
+	TempDirectory string       `yaml:"temp_directory"`
+	MaxMemory     string       `yaml:"max_memory"`
+	HTTP          HTTPSettings `yaml:"http"`
+	// Retry is the retry policy of writers whose integration sets none.
+	Retry *RetrySettings `yaml:"retry,omitempty"`
+}
+
+// HTTPSettings configures reading sources given as http:// or https:// URLs.
+type HTTPSettings struct {
+	Credentials  []HTTPCredentialSettings `yaml:"credentials"`
+	MaxRetries   int                      `yaml:"max_retries"`
+	RetryBackoff string                   `yaml:"retry_backoff"`
+	Timeout      string                   `yaml:"timeout"`
+}
+
+// HTTPCredentialSettings are headers and a bearer token sent only with the
+// requests to URLs under URLPrefix. Header values and the token may
+// reference environment variables as ${NAME}.
+type HTTPCredentialSettings struct {
+	URLPrefix   string            `yaml:"url_prefix"`
+	Headers     map[string]string `yaml:"headers"`
+	BearerToken string            `yaml:"bearer_token"`
+}
+
+// RetrySettings configures how a writer retries failed writes. Unset fields
+// keep the writer's defaults.
+type RetrySettings struct {
+	MaxAttempts     int     `yaml:"max_attempts"`
+	InitialInterval string  `yaml:"initial_interval"`
+	MaxInterval     string  `yaml:"max_interval"`
+	Multiplier      float64 `yaml:"multiplier"`
+	Jitter          float64 `yaml:"jitter"`
+	MaxElapsed      string  `yaml:"max_elapsed"`
+}
+
+// Policy returns the retry policy the settings describe.
+func (r *RetrySettings) Policy() (*retry.Policy, error) {
+	if r.MaxAttempts < 0 {
+		return nil, fmt.Errorf("retry max_attempts cannot be negative")
+	}
+	if r.Multiplier != 0 && r.Multiplier < 1 {
+		return nil, fmt.Errorf("retry multiplier must be at least 1")
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return nil, fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+	p := &retry.Policy{MaxAttempts: r.MaxAttempts, Multiplier: r.Multiplier, Jitter: r.Jitter}
+	for name, field := range map[string]struct {
+		value string
+		dst   *time.Duration
+	}{
+		"initial_interval": {r.InitialInterval, &p.InitialInterval},
+		"max_interval":     {r.MaxInterval, &p.MaxInterval},
+		"max_elapsed":      {r.MaxElapsed, &p.MaxElapsed},
+	} {
+		if field.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(field.value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("retry %s: invalid duration %q", name, field.value)
+		}
+		*field.dst = d
+	}
+	return p, nil
+}
+
+// Notification configures a webhook called, or a Slack message posted,
+// when a run of the workflow succeeds or fails, as OnSuccess and OnFailure
+// ask. Template, a text/template over the run, renders the request body of
+// a webhook or the text of a Slack message. The URL and header values may
+// reference environment variables as ${NAME}.
+type Notification struct {
+	Name      string            `yaml:"name"`
+	Type      string            `yaml:"type"`
+	URL       string            `yaml:"url"`
+	OnSuccess bool              `yaml:"on_success"`
+	OnFailure bool              `yaml:"on_failure"`
+	Template  string            `yaml:"template,omitempty"`
+	Headers   map[string]string `yaml:"headers,omitempty"`
+	Timeout   string            `yaml:"timeout,omitempty"`
+	// Retry replaces the default retry policy of the request.
+	Retry *RetrySettings `yaml:"retry,omitempty"`
+}
+
+// Notification types
+const (
+	NotificationWebhook = "webhook"
+	NotificationSlack   = "slack"
+)
+
+func (n Notification) validate() error {
+	switch n.Type {
+	case NotificationWebhook, NotificationSlack:
+	case "":
+		return fmt.Errorf("notification '%s' must have a type", n.Name)
+	default:
+		return fmt.Errorf("notification '%s': unknown type '%s'", n.Name, n.Type)
+	}
+	if n.URL == "" {
+		return fmt.Errorf("notification '%s' must have a url", n.Name)
+	}
+	if !n.OnSuccess && !n.OnFailure {
+		return fmt.Errorf("notification '%s' must set on_success or on_failure", n.Name)
+	}
+	if n.Timeout != "" {
+		if d, err := time.ParseDuration(n.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("notification '%s': invalid timeout %q", n.Name, n.Timeout)
+		}
+	}
+	if n.Retry != nil {
+		if _, err := n.Retry.Policy(); err != nil {
+			return fmt.Errorf("notification '%s': %w", n.Name, err)
+		}
+	}
+	return nil
 }
 
 type Secret struct {
@@ -77,6 +204,59 @@ type Secret struct {
 	Path     string `yaml:"path"`
 	Key      string `yaml:"key"`
 	Version  string `yaml:"version"`
+	// AWS holds the credentials of a secret of type SecretTypeAWS.
+	AWS *AWSAuthSettings `yaml:"aws,omitempty"`
+}
+
+// SecretTypeAWS is the type of secrets holding AWS credentials, which S3
+// and Kinesis integrations name in their credentials.
+const SecretTypeAWS = "aws_credentials"
+
+// AWSAuthSettings selects the credentials and region of an S3 or Kinesis
+// integration: a profile of the shared configuration files or static keys,
+// optionally assuming a role. Values may reference environment variables
+// as ${NAME}.
+type AWSAuthSettings struct {
+	Profile         string `yaml:"profile,omitempty"`
+	AccessKeyID     string `yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"`
+	SessionToken    string `yaml:"session_token,omitempty"`
+	RoleARN         string `yaml:"role_arn,omitempty"`
+	ExternalID      string `yaml:"external_id,omitempty"`
+	RoleSessionName string `yaml:"role_session_name,omitempty"`
+	Region          string `yaml:"region,omitempty"`
+	Endpoint        string `yaml:"endpoint,omitempty"`
+}
+
+func (a *AWSAuthSettings) validate() error {
+	if (a.AccessKeyID == "") != (a.SecretAccessKey == "") {
+		return fmt.Errorf("aws: set both access_key_id and secret_access_key")
+	}
+	if a.AccessKeyID != "" && a.Profile != "" {
+		return fmt.Errorf("aws: set either a profile or static keys, not both")
+	}
+	if a.ExternalID != "" && a.RoleARN == "" {
+		return fmt.Errorf("aws: external_id needs a role_arn")
+	}
+	return nil
+}
+
+// AWSAuth returns the AWS settings of the secret the named integration
+// names in its credentials, or nil if it names none.
+func (c *Config) AWSAuth(integration string) (*AWSAuthSettings, error) {
+	i, err := c.Integration(integration)
+	if err != nil || i.Credentials == "" {
+		return nil, err
+	}
+	for _, secret := range c.Workflow.Secrets {
+		if secret.Name == i.Credentials {
+			if secret.Type != SecretTypeAWS || secret.AWS == nil {
+				return nil, fmt.Errorf("integration '%s': secret '%s' holds no AWS credentials", integration, secret.Name)
+			}
+			return secret.AWS, nil
+		}
+	}
+	return nil, fmt.Errorf("integration '%s': no secret named '%s'", integration, i.Credentials)
 }
 
 type Integration struct {
@@ -85,6 +265,40 @@ type Integration struct {
 	Provider string                 `yaml:"provider"`
 	Mode     string                 `yaml:"mode"`
 	Config   map[string]interface{} `yaml:"config"`
+	// URI, if set, names the integration's endpoint as a pkg/endpoints
+	// URI, such as bq://project/dataset/table or a scheme registered by a
+	// plug-in.
+	URI string `yaml:"uri,omitempty"`
+	// Retry, if set, replaces settings.retry for this integration.
+	Retry *RetrySettings `yaml:"retry,omitempty"`
+	// Auth selects the credentials of a Google Cloud integration, which
+	// otherwise uses Application Default Credentials.
+	Auth *GCPAuthSettings `yaml:"auth,omitempty"`
+	// Credentials names the secret holding the AWS credentials of an S3 or
+	// Kinesis integration.
+	Credentials string `yaml:"credentials,omitempty"`
+}
+
+// GCPAuthSettings selects the credentials of a BigQuery, GCS or Pub/Sub
+// integration: a service account key, given as a file or inline, and a
+// service account to impersonate. Values may reference environment
+// variables as ${NAME}.
+type GCPAuthSettings struct {
+	CredentialsFile string   `yaml:"credentials_file,omitempty"`
+	CredentialsJSON string   `yaml:"credentials_json,omitempty"`
+	Impersonate     string   `yaml:"impersonate,omitempty"`
+	Delegates       []string `yaml:"delegates,omitempty"`
+	Scopes          []string `yaml:"scopes,omitempty"`
+}
+
+func (a *GCPAuthSettings) validate() error {
+	if a.CredentialsFile != "" && a.CredentialsJSON != "" {
+		return fmt.Errorf("auth: set either credentials_file or credentials_json, not both")
+	}
+	if len(a.Delegates) > 0 && a.Impersonate == "" {
+		return fmt.Errorf("auth: delegates need a service account to impersonate")
+	}
+	return nil
 }
 
 type Conversion struct {
@@ -95,14 +309,144 @@ type Conversion struct {
 }
 
 type Task struct {
+	Name        string      `yaml:"name"`
+	Source      string      `yaml:"source"`
+	Destination string      `yaml:"destination"`
+	Conversion  string      `yaml:"conversion"`
+	Query       string      `yaml:"query,omitempty"`
+	FileName    string      `yaml:"file_name,omitempty"`
+	Transforms  []Transform `yaml:"transforms,omitempty"`
+	// Select lists the columns the task copies, in the order written, and
+	// Rename maps the names of columns to their names in the destination.
+	Select []string          `yaml:"select,omitempty"`
+	Rename map[string]string `yaml:"rename,omitempty"`
+	// BigQuery selects how the task writes to a BigQuery destination.
+	BigQuery *BigQueryTaskSettings `yaml:"bigquery,omitempty"`
+}
+
+// BigQueryTaskSettings configures how a task writes to BigQuery: streamed
+// with the Storage Write API, or staged to GCS as Parquet files and loaded
+// with a load job, which suits large backfills.
+type BigQueryTaskSettings struct {
+	Mode string `yaml:"mode"`
+	// The remaining settings apply to the load_job mode.
+	StagingBucket     string `yaml:"staging_bucket"`
+	StagingPrefix     string `yaml:"staging_prefix"`
+	FileRows          int64  `yaml:"file_rows"`
+	Partition         string `yaml:"partition"`
+	WriteDisposition  string `yaml:"write_disposition"`
+	CreateDisposition string `yaml:"create_disposition"`
+	PollInterval      string `yaml:"poll_interval"`
+	Timeout           string `yaml:"timeout"`
+	KeepStagedFiles   bool   `yaml:"keep_staged_files"`
+}
+
+// BigQuery write modes
+const (
+	BigQueryModeStorageWrite = "storage_write"
+	BigQueryModeLoadJob      = "load_job"
+)
+
+// BigQuery load job dispositions
+const (
+	WriteDispositionAppend    = "append"
+	WriteDispositionTruncate  = "truncate"
+	WriteDispositionEmpty     = "empty"
+	CreateDispositionIfNeeded = "if_needed"
+	CreateDispositionNever    = "never"
+)
+
+// bigQueryPartition matches the partition decorators of load jobs: a
+// yearly, monthly, daily or hourly time, an integer range start or
+// __UNPARTITIONED__.
+var bigQueryPartition = regexp.MustCompile(`^(-?\d+|__UNPARTITIONED__)$`)
+
+// LoadJob reports whether the settings select load jobs. Tasks without
+// settings stream with the Storage Write API.
+func (b *BigQueryTaskSettings) LoadJob() bool {
+	return b != nil && b.Mode == BigQueryModeLoadJob
+}
+
+func (b *BigQueryTaskSettings) validate() error {
+	switch b.Mode {
+	case "", BigQueryModeStorageWrite:
+		if b.StagingBucket != "" {
+			return fmt.Errorf("bigquery staging_bucket requires mode '%s'", BigQueryModeLoadJob)
+		}
+		return nil
+	case BigQueryModeLoadJob:
+	default:
+		return fmt.Errorf("unknown bigquery mode '%s'", b.Mode)
+	}
+	if b.StagingBucket == "" {
+		return fmt.Errorf("bigquery mode '%s' requires a staging_bucket", BigQueryModeLoadJob)
+	}
+	if b.FileRows < 0 {
+		return fmt.Errorf("bigquery file_rows cannot be negative")
+	}
+	if b.Partition != "" && !bigQueryPartition.MatchString(b.Partition) {
+		return fmt.Errorf("bigquery partition '%s' must be a partition id such as 20240101", b.Partition)
+	}
+	switch b.WriteDisposition {
+	case "", WriteDispositionAppend, WriteDispositionTruncate, WriteDispositionEmpty:
+	default:
+		return fmt.Errorf("unknown bigquery write_disposition '%s'", b.WriteDisposition)
+	}
+	switch b.CreateDisposition {
+	case "", CreateDispositionIfNeeded, CreateDispositionNever:
+	default:
+		return fmt.Errorf("unknown bigquery create_disposition '%s'", b.CreateDisposition)
+	}
+	for name, value := range map[string]string{"poll_interval": b.PollInterval, "timeout": b.Timeout} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("bigquery %s: invalid duration %q", name, value)
+		}
+	}
+	return nil
+}
+
+// Transform configures a stage applied to records between a task's source
+// and destination.
+type Transform struct {
+	Transform string            `yaml:"transform"`
+	Columns   []TransformColumn `yaml:"columns"`
+	// Separator joins struct and field names in a flatten transform.
+	Separator string `yaml:"separator,omitempty"`
+}
+
+// TransformColumn configures how a transform treats one column.
+type TransformColumn struct {
 	Name        string `yaml:"name"`
-	Source      string `yaml:"source"`
-	Destination string `yaml:"destination"`
-	Conversion  string `yaml:"conversion"`
-	Query       string `yaml:"query,omitempty"`
-	FileName    string `yaml:"file_name,omitempty"`
+	Method      string `yaml:"method"`
+	Salt        string `yaml:"salt,omitempty"`
+	Pattern     string `yaml:"pattern,omitempty"`
+	Replacement string `yaml:"replacement,omitempty"`
+	Length      int    `yaml:"length,omitempty"`
 }
 
+// Transform types
+const (
+	TransformMask    = "mask"
+	TransformFlatten = "flatten"
+)
+
+// Mask methods
+const (
+	MaskHash     = "hash"
+	MaskTruncate = "truncate"
+	MaskRedact   = "redact"
+	MaskNull     = "null"
+)
+
+// Flatten methods
+const (
+	FlattenStruct  = "flatten"
+	FlattenExplode = "explode"
+)
+
 // SecretProvider enums
 type SecretProvider string
 
@@ -136,6 +480,14 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	// Validate resource limits
+	if _, err := c.MemoryLimitBytes(); err != nil {
+		return err
+	}
+	if _, err := c.SpillThresholdBytes(); err != nil {
+		return err
+	}
+
 	// Validate integrations (sources and destinations)
 	if err := c.validateIntegrations(); err != nil {
 		return err
@@ -156,6 +508,11 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	// Validate notifications
+	if err := c.validateNotifications(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -166,10 +523,148 @@ func (c *Config) validateSettings() error {
 	if c.Workflow.Settings.RetryAttempts <= 0 {
 		return fmt.Errorf("retry_attempts must be greater than 0")
 	}
+	if _, err := c.LoggingOptions(); err != nil {
+		return err
+	}
+	return c.Workflow.Settings.HTTP.validate()
+}
+
+func (h HTTPSettings) validate() error {
+	if h.MaxRetries < 0 {
+		return fmt.Errorf("http max_retries cannot be negative")
+	}
+	for name, value := range map[string]string{"retry_backoff": h.RetryBackoff, "timeout": h.Timeout} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("http %s: %w", name, err)
+		}
+	}
+	for i, c := range h.Credentials {
+		prefix, err := url.Parse(c.URLPrefix)
+		if err != nil || prefix.Host == "" || (prefix.Scheme != "http" && prefix.Scheme != "https") {
+			return fmt.Errorf("http credentials %d: url_prefix must be an http:// or https:// URL", i)
+		}
+		if c.BearerToken != "" && prefix.Scheme == "http" {
+			return fmt.Errorf("http credentials %d: a bearer_token cannot be sent over plain http", i)
+		}
+	}
 	return nil
 }
 
+// LoggingOptions returns the logging options set by settings.log_level
+// (debug, info, warn or error) and settings.log_format (text or json).
+func (c *Config) LoggingOptions() (*logging.Options, error) {
+	level, err := logging.ParseLevel(c.Workflow.Settings.LogLevel)
+	if err != nil {
+		return nil, fmt.Errorf("log_level: %w", err)
+	}
+	json, err := logging.ParseFormat(c.Workflow.Settings.LogFormat)
+	if err != nil {
+		return nil, fmt.Errorf("log_format: %w", err)
+	}
+	return &logging.Options{Level: level, JSON: json}, nil
+}
+
+// Logger returns a logger writing to w as the workflow's settings ask.
+func (c *Config) Logger(w io.Writer) (*slog.Logger, error) {
+	opts, err := c.LoggingOptions()
+	if err != nil {
+		return nil, err
+	}
+	return logging.New(w, opts), nil
+}
+
+// MemoryLimitBytes returns resources.memory_limit in bytes, or zero if it
+// is not set.
+func (c *Config) MemoryLimitBytes() (int64, error) {
+	limit := c.Workflow.Resources.MemoryLimit
+	if limit == "" {
+		return 0, nil
+	}
+	n, err := ParseByteSize(limit)
+	if err != nil {
+		return 0, fmt.Errorf("memory_limit: %w", err)
+	}
+	return n, nil
+}
+
+// SpillThresholdBytes returns resources.spill_threshold in bytes, or zero
+// if it is not set. Records queued past it are spilled to
+// settings.temp_directory.
+func (c *Config) SpillThresholdBytes() (int64, error) {
+	threshold := c.Workflow.Resources.SpillThreshold
+	if threshold == "" {
+		return 0, nil
+	}
+	n, err := ParseByteSize(threshold)
+	if err != nil {
+		return 0, fmt.Errorf("spill_threshold: %w", err)
+	}
+	return n, nil
+}
+
+// byteUnits maps size suffixes, lowercased, to their multiplier. Decimal
+// and binary units are both accepted; single letters are binary.
+var byteUnits = map[string]float64{
+	"": 1, "b": 1,
+	"k": 1 << 10, "kb": 1e3, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1e6, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1e9, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1e12, "tib": 1 << 40,
+}
+
+// ParseByteSize parses a size such as "512MiB", "2GB", "1.5g" or "1048576".
+func ParseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * unit), nil
+}
+
+// Integration returns the integration of the given name.
+func (c *Config) Integration(name string) (*Integration, error) {
+	for i := range c.Workflow.Integrations {
+		if c.Workflow.Integrations[i].Name == name {
+			return &c.Workflow.Integrations[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no integration named '%s'", name)
+}
+
+// RetryPolicy returns the retry policy of the named integration: its own
+// retry settings, or else settings.retry. It returns nil, leaving the
+// writer's default policy, if neither is set.
+func (c *Config) RetryPolicy(integration string) (*retry.Policy, error) {
+	settings := c.Workflow.Settings.Retry
+	for _, i := range c.Workflow.Integrations {
+		if i.Name == integration && i.Retry != nil {
+			settings = i.Retry
+		}
+	}
+	if settings == nil {
+		return nil, nil
+	}
+	return settings.Policy()
+}
+
 func (c *Config) validateIntegrations() error {
+	if c.Workflow.Settings.Retry != nil {
+		if _, err := c.Workflow.Settings.Retry.Policy(); err != nil {
+			return err
+		}
+	}
 	for _, integration := range c.Workflow.Integrations {
 		if integration.Name == "" {
 			return fmt.Errorf("integration name cannot be empty")
@@ -180,6 +675,21 @@ func (c *Config) validateIntegrations() error {
 		if integration.Provider == "" {
 			return fmt.Errorf("integration '%s' must have a provider", integration.Name)
 		}
+		if integration.Retry != nil {
+			if _, err := integration.Retry.Policy(); err != nil {
+				return fmt.Errorf("integration '%s': %w", integration.Name, err)
+			}
+		}
+		if integration.Auth != nil {
+			if err := integration.Auth.validate(); err != nil {
+				return fmt.Errorf("integration '%s': %w", integration.Name, err)
+			}
+		}
+		if integration.Credentials != "" {
+			if _, err := c.AWSAuth(integration.Name); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -204,6 +714,14 @@ func (c *Config) validateSecrets() error {
 		if secret.Provider == "" {
 			return fmt.Errorf("secret '%s' must have a provider", secret.Name)
 		}
+		if secret.Type == SecretTypeAWS {
+			if secret.AWS == nil {
+				return fmt.Errorf("secret '%s' must have aws settings", secret.Name)
+			}
+			if err := secret.AWS.validate(); err != nil {
+				return fmt.Errorf("secret '%s': %w", secret.Name, err)
+			}
+		}
 	}
 	return nil
 }
@@ -222,8 +740,114 @@ func (c *Config) validateTasks() error {
 		if task.Conversion == "" {
 			return fmt.Errorf("task '%s' must have a conversion", task.Name)
 		}
+		for _, transform := range task.Transforms {
+			if err := transform.validate(); err != nil {
+				return fmt.Errorf("task '%s': %w", task.Name, err)
+			}
+		}
+		if err := task.validateProjection(); err != nil {
+			return fmt.Errorf("task '%s': %w", task.Name, err)
+		}
+		if task.BigQuery != nil {
+			if err := task.BigQuery.validate(); err != nil {
+				return fmt.Errorf("task '%s': %w", task.Name, err)
+			}
+		}
 		// Additional checks could be added here to ensure that the source, destination,
 		// and conversion referenced in the task actually exist in the configuration
 	}
 	return nil
 }
+
+func (c *Config) validateNotifications() error {
+	names := make(map[string]bool, len(c.Workflow.Notifications))
+	for _, notification := range c.Workflow.Notifications {
+		if notification.Name == "" {
+			return fmt.Errorf("notification name cannot be empty")
+		}
+		if names[notification.Name] {
+			return fmt.Errorf("notification '%s' is defined twice", notification.Name)
+		}
+		names[notification.Name] = true
+		if err := notification.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProjection checks the columns a task selects and renames.
+func (t Task) validateProjection() error {
+	selected := make(map[string]bool, len(t.Select))
+	for _, name := range t.Select {
+		if name == "" {
+			return fmt.Errorf("selected column name cannot be empty")
+		}
+		if selected[name] {
+			return fmt.Errorf("column '%s' is selected twice", name)
+		}
+		selected[name] = true
+	}
+	for from, to := range t.Rename {
+		if from == "" || to == "" {
+			return fmt.Errorf("renamed column names cannot be empty")
+		}
+		if len(selected) > 0 && !selected[from] {
+			return fmt.Errorf("column '%s' is renamed but not selected", from)
+		}
+	}
+	return nil
+}
+
+func (t Transform) validate() error {
+	switch t.Transform {
+	case TransformMask:
+	case TransformFlatten:
+		return t.validateFlatten()
+	case "":
+		return fmt.Errorf("transform type cannot be empty")
+	default:
+		return fmt.Errorf("unknown transform '%s'", t.Transform)
+	}
+	if len(t.Columns) == 0 {
+		return fmt.Errorf("transform '%s' must list at least one column", t.Transform)
+	}
+	for _, col := range t.Columns {
+		if col.Name == "" {
+			return fmt.Errorf("transform '%s' column name cannot be empty", t.Transform)
+		}
+		switch col.Method {
+		case MaskHash, MaskNull:
+		case MaskTruncate:
+			if col.Length < 0 {
+				return fmt.Errorf("column '%s': truncate length cannot be negative", col.Name)
+			}
+		case MaskRedact:
+			if col.Pattern == "" {
+				return fmt.Errorf("column '%s': redact requires a pattern", col.Name)
+			}
+			if _, err := regexp.Compile(col.Pattern); err != nil {
+				return fmt.Errorf("column '%s': invalid pattern: %w", col.Name, err)
+			}
+		default:
+			return fmt.Errorf("column '%s': unknown mask method '%s'", col.Name, col.Method)
+		}
+	}
+	return nil
+}
+
+// validateFlatten checks a flatten transform, which flattens every struct
+// column when it lists none.
+func (t Transform) validateFlatten() error {
+	for _, col := range t.Columns {
+		if col.Name == "" {
+			return fmt.Errorf("transform '%s' column name cannot be empty", t.Transform)
+		}
+		switch col.Method {
+		case FlattenStruct, FlattenExplode:
+		default:
+			return fmt.Errorf("column '%s': unknown flatten method '%s'", col.Name, col.Method)
+		}
+	}
+	return nil
+}
@@ -33,7 +33,7 @@ package utils
 import (
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 
 	"github.com/apache/arrow-go/v18/arrow"
@@ -86,7 +86,7 @@ func IpcReaderToChannel(reader *ipc.Reader) (<-chan arrow.Record, error) {
 		for reader.Next() {
 			record := reader.Record()
 			if record == nil {
-				log.Println("Warning: Received nil record from IPC reader")
+				slog.Warn("received nil record from IPC reader")
 				continue
 			}
 			recordChan <- record
@@ -142,7 +142,7 @@ func CloneSourceStream(sourceChan <-chan arrow.Record, numClones int) ([]<-chan
 		go func() {
 			for record := range sourceChan {
 				if record == nil {
-					log.Println("Warning: Nil record encountered in source channel")
+					slog.Warn("nil record in source channel")
 					continue
 				}
 				cloneChan <- record
@@ -30,7 +30,7 @@
 package utils
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -47,13 +47,13 @@ func LoadEnv() {
 
 	absEnvPath, err := filepath.Abs(envPath)
 	if err != nil {
-		log.Printf("Error resolving absolute path for .env file: %v", err)
+		slog.Error("failed to resolve .env path", "path", envPath, "error", err)
 		return
 	}
 
 	if err := godotenv.Load(absEnvPath); err != nil {
-		log.Printf("Warning: Could not load .env file from %s: %v", absEnvPath, err)
+		slog.Warn("could not load .env file", "path", absEnvPath, "error", err)
 	} else {
-		log.Printf("Successfully loaded .env file from %s", absEnvPath)
+		slog.Debug("loaded .env file", "path", absEnvPath)
 	}
 }
@@ -0,0 +1,173 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package compute
+
+import (
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// accumulator holds the running state of one aggregate for every group.
+type accumulator interface {
+	// grow makes room for n groups.
+	grow(n int)
+	// update adds a non-null value to group g.
+	update(g int, v scalar)
+	// build appends the result of every group to b.
+	build(b array.Builder)
+}
+
+func newAccumulator(fn AggFunc, k kind) accumulator {
+	switch fn {
+	case Count:
+		return &countAcc{}
+	case Sum:
+		return &sumAcc{}
+	case Avg:
+		return &avgAcc{kind: k}
+	case Min:
+		return &extremeAcc{kind: k, want: -1}
+	default:
+		return &extremeAcc{kind: k, want: 1}
+	}
+}
+
+type countAcc struct {
+	counts []int64
+}
+
+func (a *countAcc) grow(n int) {
+	for len(a.counts) < n {
+		a.counts = append(a.counts, 0)
+	}
+}
+
+func (a *countAcc) update(g int, _ scalar) { a.counts[g]++ }
+
+func (a *countAcc) build(b array.Builder) {
+	b.(*array.Int64Builder).AppendValues(a.counts, nil)
+}
+
+type sumAcc struct {
+	sums  []scalar
+	valid []bool
+}
+
+func (a *sumAcc) grow(n int) {
+	for len(a.sums) < n {
+		a.sums = append(a.sums, scalar{})
+		a.valid = append(a.valid, false)
+	}
+}
+
+func (a *sumAcc) update(g int, v scalar) {
+	s := &a.sums[g]
+	s.i += v.i
+	s.u += v.u
+	s.f += v.f
+	a.valid[g] = true
+}
+
+func (a *sumAcc) build(b array.Builder) {
+	for g, s := range a.sums {
+		if !a.valid[g] {
+			b.AppendNull()
+			continue
+		}
+		appendScalar(b, s)
+	}
+}
+
+type avgAcc struct {
+	kind   kind
+	sums   []float64
+	counts []int64
+}
+
+func (a *avgAcc) grow(n int) {
+	for len(a.sums) < n {
+		a.sums = append(a.sums, 0)
+		a.counts = append(a.counts, 0)
+	}
+}
+
+func (a *avgAcc) update(g int, v scalar) {
+	switch a.kind {
+	case kindInt:
+		a.sums[g] += float64(v.i)
+	case kindUint:
+		a.sums[g] += float64(v.u)
+	default:
+		a.sums[g] += v.f
+	}
+	a.counts[g]++
+}
+
+func (a *avgAcc) build(b array.Builder) {
+	fb := b.(*array.Float64Builder)
+	for g, sum := range a.sums {
+		if a.counts[g] == 0 {
+			fb.AppendNull()
+			continue
+		}
+		fb.Append(sum / float64(a.counts[g]))
+	}
+}
+
+// extremeAcc keeps the minimum (want -1) or maximum (want 1) value.
+type extremeAcc struct {
+	kind   kind
+	want   int
+	values []scalar
+	valid  []bool
+}
+
+func (a *extremeAcc) grow(n int) {
+	for len(a.values) < n {
+		a.values = append(a.values, scalar{})
+		a.valid = append(a.valid, false)
+	}
+}
+
+func (a *extremeAcc) update(g int, v scalar) {
+	if !a.valid[g] || compareScalars(a.kind, v, a.values[g]) == a.want {
+		a.values[g] = v
+		a.valid[g] = true
+	}
+}
+
+func (a *extremeAcc) build(b array.Builder) {
+	for g, v := range a.values {
+		if !a.valid[g] {
+			b.AppendNull()
+			continue
+		}
+		appendScalar(b, v)
+	}
+}
@@ -0,0 +1,329 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package compute provides in-process computations over streams of Arrow
+// records.
+package compute
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+)
+
+// AggFunc is an aggregate function.
+type AggFunc string
+
+const (
+	Sum   AggFunc = "sum"
+	Min   AggFunc = "min"
+	Max   AggFunc = "max"
+	Count AggFunc = "count"
+	Avg   AggFunc = "avg"
+)
+
+// Aggregate computes Func over Column for each group. Null values are
+// ignored; Count with an empty Column counts rows. The result column is
+// named Name, or "<func>_<column>" if Name is empty.
+type Aggregate struct {
+	Column string
+	Func   AggFunc
+	Name   string
+}
+
+func (a Aggregate) name() string {
+	switch {
+	case a.Name != "":
+		return a.Name
+	case a.Column == "":
+		return string(a.Func)
+	}
+	return fmt.Sprintf("%s_%s", a.Func, a.Column)
+}
+
+// AggregationWriter is a Writer that groups the records written to it by
+// key columns and, on Close, writes one record with the aggregates of each
+// group to a downstream Writer. Groups are emitted in the order they were
+// first seen. Without key columns a single row aggregates all records.
+type AggregationWriter struct {
+	schema     *arrow.Schema
+	out        *arrow.Schema
+	downstream interfaces.Writer
+	mem        memory.Allocator
+
+	keyIdx   []int
+	keyKinds []kind
+	aggIdx   []int
+	accs     []accumulator
+
+	groups map[string]int
+	keys   [][]scalar // per group, per key column
+	valid  [][]bool   // per group, per key column
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// AggregateSchema returns the schema of the record an AggregationWriter
+// emits for input records with schema: the key columns followed by one
+// column per aggregate.
+func AggregateSchema(schema *arrow.Schema, groupBy []string, aggs []Aggregate) (*arrow.Schema, error) {
+	fields := make([]arrow.Field, 0, len(groupBy)+len(aggs))
+	for _, name := range groupBy {
+		idx, err := fieldIndex(schema, name)
+		if err != nil {
+			return nil, err
+		}
+		field := schema.Field(idx)
+		if _, ok := kindOf(field.Type); !ok {
+			return nil, fmt.Errorf("group by column %q has unsupported type %s", name, field.Type)
+		}
+		fields = append(fields, arrow.Field{Name: field.Name, Type: field.Type, Nullable: field.Nullable})
+	}
+
+	for _, agg := range aggs {
+		field := arrow.Field{Name: agg.name(), Nullable: true}
+		if agg.Func == Count && agg.Column == "" {
+			field.Type, field.Nullable = arrow.PrimitiveTypes.Int64, false
+			fields = append(fields, field)
+			continue
+		}
+		idx, err := fieldIndex(schema, agg.Column)
+		if err != nil {
+			return nil, err
+		}
+		in := schema.Field(idx).Type
+		k, ok := kindOf(in)
+		if !ok {
+			return nil, fmt.Errorf("%s(%s): unsupported type %s", agg.Func, agg.Column, in)
+		}
+
+		switch agg.Func {
+		case Count:
+			field.Type, field.Nullable = arrow.PrimitiveTypes.Int64, false
+		case Min, Max:
+			field.Type = in
+		case Sum, Avg:
+			if !arrow.IsInteger(in.ID()) && !arrow.IsFloating(in.ID()) {
+				return nil, fmt.Errorf("%s(%s): column must be numeric, got %s", agg.Func, agg.Column, in)
+			}
+			field.Type = arrow.PrimitiveTypes.Float64
+			if agg.Func == Sum && k == kindInt {
+				field.Type = arrow.PrimitiveTypes.Int64
+			} else if agg.Func == Sum && k == kindUint {
+				field.Type = arrow.PrimitiveTypes.Uint64
+			}
+		default:
+			return nil, fmt.Errorf("unknown aggregate function %q", agg.Func)
+		}
+		fields = append(fields, field)
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// NewAggregationWriter creates an AggregationWriter for records with
+// schema. downstream must accept records with the schema returned by
+// AggregateSchema, and is closed when the AggregationWriter is closed.
+func NewAggregationWriter(schema *arrow.Schema, groupBy []string, aggs []Aggregate, downstream interfaces.Writer) (*AggregationWriter, error) {
+	if len(aggs) == 0 {
+		return nil, fmt.Errorf("at least one aggregate is required")
+	}
+	if downstream == nil {
+		return nil, fmt.Errorf("downstream writer cannot be nil")
+	}
+	out, err := AggregateSchema(schema, groupBy, aggs)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &AggregationWriter{
+		schema:     schema,
+		out:        out,
+		downstream: downstream,
+		mem:        memory.DefaultAllocator,
+		groups:     make(map[string]int),
+	}
+	for _, name := range groupBy {
+		idx, _ := fieldIndex(schema, name)
+		k, _ := kindOf(schema.Field(idx).Type)
+		w.keyIdx = append(w.keyIdx, idx)
+		w.keyKinds = append(w.keyKinds, k)
+	}
+	for _, agg := range aggs {
+		idx := -1
+		var k kind
+		if agg.Column != "" {
+			idx, _ = fieldIndex(schema, agg.Column)
+			k, _ = kindOf(schema.Field(idx).Type)
+		}
+		w.aggIdx = append(w.aggIdx, idx)
+		w.accs = append(w.accs, newAccumulator(agg.Func, k))
+	}
+	if len(groupBy) == 0 {
+		w.group(nil, 0)
+	}
+	return w, nil
+}
+
+func fieldIndex(schema *arrow.Schema, name string) (int, error) {
+	indices := schema.FieldIndices(name)
+	if len(indices) == 0 {
+		return 0, fmt.Errorf("column %q not found", name)
+	}
+	return indices[0], nil
+}
+
+// Schema returns the schema of the record written downstream.
+func (w *AggregationWriter) Schema() *arrow.Schema {
+	return w.out
+}
+
+// Write adds the rows of record to their groups.
+func (w *AggregationWriter) Write(record arrow.Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return fmt.Errorf("aggregation writer is closed")
+	}
+	if !record.Schema().Equal(w.schema) {
+		return fmt.Errorf("record schema does not match aggregation writer schema")
+	}
+
+	keyCols := make([]arrow.Array, len(w.keyIdx))
+	keyReaders := make([]func(int) scalar, len(w.keyIdx))
+	for i, idx := range w.keyIdx {
+		keyCols[i] = record.Column(idx)
+		keyReaders[i] = scalarReader(keyCols[i])
+	}
+	aggCols := make([]arrow.Array, len(w.aggIdx))
+	aggReaders := make([]func(int) scalar, len(w.aggIdx))
+	for i, idx := range w.aggIdx {
+		if idx >= 0 {
+			aggCols[i] = record.Column(idx)
+			aggReaders[i] = scalarReader(aggCols[i])
+		}
+	}
+
+	var buf []byte
+	keys := make([]scalar, len(keyCols))
+	valid := make([]bool, len(keyCols))
+	for row := 0; row < int(record.NumRows()); row++ {
+		buf = buf[:0]
+		for i, col := range keyCols {
+			valid[i] = col.IsValid(row)
+			keys[i] = scalar{}
+			if valid[i] {
+				keys[i] = keyReaders[i](row)
+			}
+			buf = appendKey(buf, w.keyKinds[i], keys[i], valid[i])
+		}
+		g, ok := w.groups[string(buf)]
+		if !ok {
+			g = w.group(keys, len(w.keys))
+			w.groups[string(buf)] = g
+			w.valid[g] = append([]bool(nil), valid...)
+		}
+
+		for i, acc := range w.accs {
+			switch {
+			case aggCols[i] == nil:
+				acc.update(g, scalar{})
+			case aggCols[i].IsValid(row):
+				acc.update(g, aggReaders[i](row))
+			}
+		}
+	}
+	return nil
+}
+
+// group adds a group with the given key values and returns its index.
+func (w *AggregationWriter) group(keys []scalar, g int) int {
+	w.keys = append(w.keys, append([]scalar(nil), keys...))
+	w.valid = append(w.valid, nil)
+	for _, acc := range w.accs {
+		acc.grow(g + 1)
+	}
+	return g
+}
+
+// Close writes the aggregated record downstream and closes the downstream
+// writer.
+func (w *AggregationWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	record := w.build()
+	defer record.Release()
+	if record.NumRows() > 0 {
+		if err := w.downstream.Write(record); err != nil {
+			w.downstream.Close()
+			return fmt.Errorf("failed to write aggregates: %w", err)
+		}
+	}
+	return w.downstream.Close()
+}
+
+func (w *AggregationWriter) build() arrow.Record {
+	n := len(w.keys)
+	cols := make([]arrow.Array, 0, w.out.NumFields())
+	defer func() {
+		for _, col := range cols {
+			col.Release()
+		}
+	}()
+
+	for i := range w.keyIdx {
+		b := array.NewBuilder(w.mem, w.out.Field(i).Type)
+		b.Reserve(n)
+		for g := 0; g < n; g++ {
+			if w.valid[g][i] {
+				appendScalar(b, w.keys[g][i])
+			} else {
+				b.AppendNull()
+			}
+		}
+		cols = append(cols, b.NewArray())
+		b.Release()
+	}
+	for i, acc := range w.accs {
+		b := array.NewBuilder(w.mem, w.out.Field(len(w.keyIdx)+i).Type)
+		acc.build(b)
+		cols = append(cols, b.NewArray())
+		b.Release()
+	}
+	return array.NewRecord(w.out, cols, int64(n))
+}
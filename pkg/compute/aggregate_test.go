@@ -0,0 +1,160 @@
+package compute
+
+import (
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+var salesSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "region", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "units", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+	{Name: "price", Type: arrow.PrimitiveTypes.Float64},
+}, nil)
+
+func salesRecord(t *testing.T, regions []string, regionValid []bool, units []int32, unitsValid []bool, prices []float64) arrow.Record {
+	t.Helper()
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), salesSchema)
+	defer b.Release()
+	b.Field(0).(*array.StringBuilder).AppendValues(regions, regionValid)
+	b.Field(1).(*array.Int32Builder).AppendValues(units, unitsValid)
+	b.Field(2).(*array.Float64Builder).AppendValues(prices, nil)
+	return b.NewRecord()
+}
+
+// captureWriter keeps the last record written to it.
+type captureWriter struct {
+	record arrow.Record
+	closed bool
+}
+
+func (w *captureWriter) Write(rec arrow.Record) error {
+	rec.Retain()
+	w.record = rec
+	return nil
+}
+
+func (w *captureWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestAggregationWriterGroupBy(t *testing.T) {
+	out := &captureWriter{}
+	w, err := NewAggregationWriter(salesSchema, []string{"region"}, []Aggregate{
+		{Func: Count},
+		{Column: "units", Func: Sum},
+		{Column: "units", Func: Count},
+		{Column: "price", Func: Min},
+		{Column: "price", Func: Max, Name: "top_price"},
+		{Column: "units", Func: Avg},
+	}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batches := []arrow.Record{
+		salesRecord(t, []string{"eu", "us", "eu"}, nil, []int32{1, 2, 3}, nil, []float64{1.5, 2, 0.5}),
+		salesRecord(t, []string{"us", "", "eu"}, []bool{true, false, true}, []int32{0, 7, 0}, []bool{false, true, false}, []float64{4, 3, 9}),
+	}
+	for _, rec := range batches {
+		if err := w.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+		rec.Release()
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !out.closed || out.record == nil {
+		t.Fatal("expected the aggregates to be written and the downstream writer closed")
+	}
+	defer out.record.Release()
+
+	rec := out.record
+	wantNames := []string{"region", "count", "sum_units", "count_units", "min_price", "top_price", "avg_units"}
+	for i, name := range wantNames {
+		if rec.Schema().Field(i).Name != name {
+			t.Errorf("field %d = %q, want %q", i, rec.Schema().Field(i).Name, name)
+		}
+	}
+	if rec.NumRows() != 3 {
+		t.Fatalf("rows = %d, want 3", rec.NumRows())
+	}
+
+	region := rec.Column(0).(*array.String)
+	if region.Value(0) != "eu" || region.Value(1) != "us" || !region.IsNull(2) {
+		t.Errorf("region = %v", region)
+	}
+	assertInt64s(t, "count", rec.Column(1), []int64{3, 2, 1})
+	assertInt64s(t, "sum_units", rec.Column(2), []int64{4, 2, 7})
+	assertInt64s(t, "count_units", rec.Column(3), []int64{2, 1, 1})
+	assertFloat64s(t, "min_price", rec.Column(4), []float64{0.5, 2, 3})
+	assertFloat64s(t, "top_price", rec.Column(5), []float64{9, 4, 3})
+	assertFloat64s(t, "avg_units", rec.Column(6), []float64{2, 2, 7})
+}
+
+func TestAggregationWriterGlobal(t *testing.T) {
+	out := &captureWriter{}
+	w, err := NewAggregationWriter(salesSchema, nil, []Aggregate{
+		{Func: Count},
+		{Column: "units", Func: Max},
+	}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	defer out.record.Release()
+
+	if out.record.NumRows() != 1 {
+		t.Fatalf("rows = %d, want 1", out.record.NumRows())
+	}
+	assertInt64s(t, "count", out.record.Column(0), []int64{0})
+	if !out.record.Column(1).IsNull(0) {
+		t.Error("max of no values should be null")
+	}
+	if out.record.Schema().Field(1).Type.ID() != arrow.INT32 {
+		t.Errorf("max type = %s, want int32", out.record.Schema().Field(1).Type)
+	}
+}
+
+func TestAggregateSchemaErrors(t *testing.T) {
+	for name, aggs := range map[string][]Aggregate{
+		"missing column": {{Column: "missing", Func: Sum}},
+		"sum of string":  {{Column: "region", Func: Sum}},
+		"unknown func":   {{Column: "units", Func: "median"}},
+	} {
+		if _, err := AggregateSchema(salesSchema, nil, aggs); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := AggregateSchema(salesSchema, []string{"missing"}, []Aggregate{{Func: Count}}); err == nil {
+		t.Error("expected an error for a missing group by column")
+	}
+}
+
+func assertInt64s(t *testing.T, name string, arr arrow.Array, want []int64) {
+	t.Helper()
+	got := arr.(*array.Int64).Int64Values()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+	}
+}
+
+func assertFloat64s(t *testing.T, name string, arr arrow.Array, want []float64) {
+	t.Helper()
+	got := arr.(*array.Float64).Float64Values()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+	}
+}
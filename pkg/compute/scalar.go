@@ -0,0 +1,227 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package compute
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// kind is the Go representation used to hold values of an Arrow type.
+type kind int
+
+const (
+	kindInt kind = iota
+	kindUint
+	kindFloat
+	kindString
+	kindBool
+)
+
+// scalar holds one value; only the field matching its kind is set.
+type scalar struct {
+	i int64
+	u uint64
+	f float64
+	s string
+	b bool
+}
+
+// kindOf returns the kind holding values of dt, or false if dt is not
+// supported.
+func kindOf(dt arrow.DataType) (kind, bool) {
+	switch dt.ID() {
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64,
+		arrow.DATE32, arrow.DATE64, arrow.TIMESTAMP, arrow.TIME32, arrow.TIME64, arrow.DURATION:
+		return kindInt, true
+	case arrow.UINT8, arrow.UINT16, arrow.UINT32, arrow.UINT64:
+		return kindUint, true
+	case arrow.FLOAT32, arrow.FLOAT64:
+		return kindFloat, true
+	case arrow.STRING, arrow.LARGE_STRING:
+		return kindString, true
+	case arrow.BOOL:
+		return kindBool, true
+	}
+	return 0, false
+}
+
+// scalarReader returns a function reading the non-null value at a row of
+// arr. arr must have a type accepted by kindOf.
+func scalarReader(arr arrow.Array) func(int) scalar {
+	switch a := arr.(type) {
+	case *array.Int8:
+		return func(i int) scalar { return scalar{i: int64(a.Value(i))} }
+	case *array.Int16:
+		return func(i int) scalar { return scalar{i: int64(a.Value(i))} }
+	case *array.Int32:
+		return func(i int) scalar { return scalar{i: int64(a.Value(i))} }
+	case *array.Int64:
+		return func(i int) scalar { return scalar{i: a.Value(i)} }
+	case *array.Date32:
+		return func(i int) scalar { return scalar{i: int64(a.Value(i))} }
+	case *array.Date64:
+		return func(i int) scalar { return scalar{i: int64(a.Value(i))} }
+	case *array.Timestamp:
+		return func(i int) scalar { return scalar{i: int64(a.Value(i))} }
+	case *array.Time32:
+		return func(i int) scalar { return scalar{i: int64(a.Value(i))} }
+	case *array.Time64:
+		return func(i int) scalar { return scalar{i: int64(a.Value(i))} }
+	case *array.Duration:
+		return func(i int) scalar { return scalar{i: int64(a.Value(i))} }
+	case *array.Uint8:
+		return func(i int) scalar { return scalar{u: uint64(a.Value(i))} }
+	case *array.Uint16:
+		return func(i int) scalar { return scalar{u: uint64(a.Value(i))} }
+	case *array.Uint32:
+		return func(i int) scalar { return scalar{u: uint64(a.Value(i))} }
+	case *array.Uint64:
+		return func(i int) scalar { return scalar{u: a.Value(i)} }
+	case *array.Float32:
+		return func(i int) scalar { return scalar{f: float64(a.Value(i))} }
+	case *array.Float64:
+		return func(i int) scalar { return scalar{f: a.Value(i)} }
+	case *array.String:
+		return func(i int) scalar { return scalar{s: a.Value(i)} }
+	case *array.LargeString:
+		return func(i int) scalar { return scalar{s: a.Value(i)} }
+	case *array.Boolean:
+		return func(i int) scalar { return scalar{b: a.Value(i)} }
+	}
+	panic(fmt.Sprintf("unsupported type %s", arr.DataType()))
+}
+
+// appendScalar appends v to b, converting it to the builder's type.
+func appendScalar(b array.Builder, v scalar) {
+	switch b := b.(type) {
+	case *array.Int8Builder:
+		b.Append(int8(v.i))
+	case *array.Int16Builder:
+		b.Append(int16(v.i))
+	case *array.Int32Builder:
+		b.Append(int32(v.i))
+	case *array.Int64Builder:
+		b.Append(v.i)
+	case *array.Date32Builder:
+		b.Append(arrow.Date32(v.i))
+	case *array.Date64Builder:
+		b.Append(arrow.Date64(v.i))
+	case *array.TimestampBuilder:
+		b.Append(arrow.Timestamp(v.i))
+	case *array.Time32Builder:
+		b.Append(arrow.Time32(v.i))
+	case *array.Time64Builder:
+		b.Append(arrow.Time64(v.i))
+	case *array.DurationBuilder:
+		b.Append(arrow.Duration(v.i))
+	case *array.Uint8Builder:
+		b.Append(uint8(v.u))
+	case *array.Uint16Builder:
+		b.Append(uint16(v.u))
+	case *array.Uint32Builder:
+		b.Append(uint32(v.u))
+	case *array.Uint64Builder:
+		b.Append(v.u)
+	case *array.Float32Builder:
+		b.Append(float32(v.f))
+	case *array.Float64Builder:
+		b.Append(v.f)
+	case *array.StringBuilder:
+		b.Append(v.s)
+	case *array.LargeStringBuilder:
+		b.Append(v.s)
+	case *array.BooleanBuilder:
+		b.Append(v.b)
+	default:
+		panic(fmt.Sprintf("unsupported type %s", b.Type()))
+	}
+}
+
+// compareScalars orders a and b, which hold values of kind k.
+func compareScalars(k kind, a, b scalar) int {
+	switch k {
+	case kindInt:
+		return cmpOrdered(a.i, b.i)
+	case kindUint:
+		return cmpOrdered(a.u, b.u)
+	case kindFloat:
+		return cmpOrdered(a.f, b.f)
+	case kindString:
+		return strings.Compare(a.s, b.s)
+	default:
+		switch {
+		case a.b == b.b:
+			return 0
+		case b.b:
+			return -1
+		}
+		return 1
+	}
+}
+
+func cmpOrdered[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// appendKey appends an unambiguous encoding of v to buf.
+func appendKey(buf []byte, k kind, v scalar, valid bool) []byte {
+	if !valid {
+		return append(buf, 0)
+	}
+	buf = append(buf, 1)
+	switch k {
+	case kindInt:
+		return binary.AppendVarint(buf, v.i)
+	case kindUint:
+		return binary.AppendUvarint(buf, v.u)
+	case kindFloat:
+		return binary.AppendUvarint(buf, math.Float64bits(v.f))
+	case kindString:
+		buf = binary.AppendUvarint(buf, uint64(len(v.s)))
+		return append(buf, v.s...)
+	default:
+		if v.b {
+			return append(buf, 1)
+		}
+		return append(buf, 0)
+	}
+}
@@ -0,0 +1,133 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package compute
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	arrowcompute "github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// SortKey orders rows by one column. Nulls sort last unless NullsFirst is
+// set.
+type SortKey struct {
+	Column     string
+	Descending bool
+	NullsFirst bool
+}
+
+// SortIndices returns the indices of the rows of record in the order given
+// by keys. The sort is stable.
+func SortIndices(record arrow.Record, keys []SortKey) ([]int, error) {
+	type column struct {
+		arr  arrow.Array
+		kind kind
+		read func(int) scalar
+		key  SortKey
+	}
+	cols := make([]column, len(keys))
+	for i, key := range keys {
+		idx, err := fieldIndex(record.Schema(), key.Column)
+		if err != nil {
+			return nil, err
+		}
+		arr := record.Column(idx)
+		k, ok := kindOf(arr.DataType())
+		if !ok {
+			return nil, fmt.Errorf("cannot sort by column %q of type %s", key.Column, arr.DataType())
+		}
+		cols[i] = column{arr: arr, kind: k, read: scalarReader(arr), key: key}
+	}
+
+	indices := make([]int, record.NumRows())
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(a, b int) bool {
+		ra, rb := indices[a], indices[b]
+		for _, col := range cols {
+			nullA, nullB := col.arr.IsNull(ra), col.arr.IsNull(rb)
+			switch {
+			case nullA && nullB:
+				continue
+			case nullA || nullB:
+				return nullA == col.key.NullsFirst
+			}
+			c := compareScalars(col.kind, col.read(ra), col.read(rb))
+			if c == 0 {
+				continue
+			}
+			if col.key.Descending {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return indices, nil
+}
+
+// SortRecord returns a copy of record with its rows in the order given by
+// keys.
+func SortRecord(ctx context.Context, record arrow.Record, keys []SortKey) (arrow.Record, error) {
+	indices, err := SortIndices(record, keys)
+	if err != nil {
+		return nil, err
+	}
+	return TakeRows(ctx, record, indices)
+}
+
+// TakeRows returns a record made of the given rows of record, in order.
+func TakeRows(ctx context.Context, record arrow.Record, rows []int) (arrow.Record, error) {
+	b := array.NewInt64Builder(memory.DefaultAllocator)
+	defer b.Release()
+	b.Reserve(len(rows))
+	for _, row := range rows {
+		b.UnsafeAppend(int64(row))
+	}
+	indices := b.NewArray()
+	defer indices.Release()
+
+	values := arrowcompute.NewDatum(record)
+	defer values.Release()
+	idx := arrowcompute.NewDatum(indices)
+	defer idx.Release()
+
+	out, err := arrowcompute.Take(ctx, *arrowcompute.DefaultTakeOptions(), values, idx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take rows: %w", err)
+	}
+	return out.(*arrowcompute.RecordDatum).Value, nil
+}
@@ -0,0 +1,45 @@
+package compute
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+func TestSortRecord(t *testing.T) {
+	rec := salesRecord(t,
+		[]string{"us", "eu", "", "eu", "us"}, []bool{true, true, false, true, true},
+		[]int32{1, 2, 3, 4, 5}, nil,
+		[]float64{1, 1, 1, 2, 2})
+	defer rec.Release()
+
+	sorted, err := SortRecord(context.Background(), rec, []SortKey{
+		{Column: "region"},
+		{Column: "price", Descending: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sorted.Release()
+
+	got := sorted.Column(1).(*array.Int32).Int32Values()
+	want := []int32{4, 2, 5, 1, 3}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("units = %v, want %v", got, want)
+		}
+	}
+
+	indices, err := SortIndices(rec, []SortKey{{Column: "region", NullsFirst: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indices[0] != 2 {
+		t.Errorf("indices = %v, want the null region first", indices)
+	}
+
+	if _, err := SortIndices(rec, []SortKey{{Column: "missing"}}); err == nil {
+		t.Error("expected an error for a missing column")
+	}
+}
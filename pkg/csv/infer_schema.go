@@ -53,6 +53,23 @@ type CSVReadOptions struct {
 	NullValues       []string
 	ParseTimestamps  bool
 	TimestampFormat  string
+
+	// Schema, when set, is returned as-is (after checking it against the
+	// header) and no rows are sampled.
+	Schema *arrow.Schema
+	// ColumnTypes overrides the inferred type of the named columns. When
+	// every column is overridden the sampling pass is skipped entirely.
+	ColumnTypes map[string]arrow.DataType
+
+	// InferDecimals infers Decimal128 instead of Float64 for values with a
+	// fractional part, sized to the widest precision and scale observed.
+	InferDecimals bool
+	// ParseDates infers Date32 for values matching one of DateLayouts.
+	ParseDates bool
+	// DateLayouts are the time layouts tried when ParseDates is set.
+	// Defaults to ISO 8601 dates (2006-01-02). Files inferred with other
+	// layouts must be read with the same DateLayouts in the CSV reader.
+	DateLayouts []string
 }
 
 type inferenceError struct {
@@ -68,6 +85,8 @@ func (e *inferenceError) Error() string {
 const (
 	maxRowsToInfer = 1000
 	batchSize      = 100
+
+	maxDecimal128Precision = 38
 )
 
 // InferCSVArrowSchema infers the Arrow schema from a CSV file
@@ -95,89 +114,151 @@ func InferCSVArrowSchema(ctx context.Context, filePath string, opts *CSVReadOpti
 		}
 	}()
 
-	reader := csv.NewReader(file)
+	return InferCSVArrowSchemaFromReader(ctx, file, opts)
+}
+
+// InferCSVArrowSchemaFromReader infers the Arrow schema from CSV data read
+// from r. At most the rows needed for inference are read.
+func InferCSVArrowSchemaFromReader(ctx context.Context, r io.Reader, opts *CSVReadOptions) (*arrow.Schema, error) {
+	if err := validateOptions(opts); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
 	reader.Comma = opts.Delimiter
 	reader.TrimLeadingSpace = true
 
-	headers, err := readHeaders(reader, opts)
+	headers, firstRow, err := readHeaders(reader, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	columnTypes := make([]arrow.DataType, len(headers))
-	columnNullability := make([]bool, len(headers))
+	if opts.Schema != nil {
+		if err := checkSchema(headers, opts.Schema); err != nil {
+			return nil, err
+		}
+		return opts.Schema, nil
+	}
 
-	rowChannel := make(chan []string, batchSize)
-	errChan := make(chan *inferenceError, runtime.NumCPU())
+	if overridesAll(headers, opts.ColumnTypes) {
+		return buildSchema(headers, make([]columnState, len(headers)), opts), nil
+	}
 
-	var wg sync.WaitGroup
 	numWorkers := runtime.NumCPU()
+	batches := make(chan rowBatch, numWorkers)
+	results := make([][]columnState, numWorkers)
+	errs := make([]error, numWorkers)
 
-	// Start workers
+	// Each worker folds its batches into a private set of column states, so
+	// no state is shared until the merge below.
+	var wg sync.WaitGroup
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
-		go processRows(rowChannel, columnTypes, columnNullability, opts, &wg, errChan)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = processRows(batches, len(headers), opts)
+		}(i)
 	}
 
-	// Read and process rows
-	rowCount := 0
-	for rowCount < maxRowsToInfer {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			row, err := reader.Read()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return nil, fmt.Errorf("error reading CSV row: %w", err)
-			}
-			rowChannel <- row
-			rowCount++
+	readErr := readBatches(ctx, reader, firstRow, batches)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	close(rowChannel)
-	wg.Wait()
-
-	// Check for errors
-	select {
-	case err := <-errChan:
-		return nil, err
-	default:
+	// Merge in worker order; the join is commutative and associative, so the
+	// result does not depend on which worker saw which batch.
+	states := make([]columnState, len(headers))
+	for _, local := range results {
+		for i := range states {
+			states[i].merge(local[i])
+		}
 	}
 
-	return buildSchema(headers, columnTypes, columnNullability, opts), nil
+	return buildSchema(headers, states, opts), nil
 }
 
-// inferColumnType detects the type of a given column based on the observed value
-func inferColumnType(current arrow.DataType, value string, opts *CSVReadOptions) arrow.DataType {
-	// Early exit if already string type or empty value
-	if current == arrow.BinaryTypes.String || isNullValue(value, opts.NullValues) {
-		return current
+// rowBatch is a run of consecutive data rows starting at row number start.
+type rowBatch struct {
+	start int
+	rows  [][]string
+}
+
+// readBatches feeds up to maxRowsToInfer rows to the workers in batches and
+// closes the channel when done.
+func readBatches(ctx context.Context, reader *csv.Reader, firstRow []string, batches chan<- rowBatch) error {
+	defer close(batches)
+
+	batch := rowBatch{start: 1}
+	rowCount := 0
+	if firstRow != nil {
+		batch.rows = append(batch.rows, firstRow)
+		rowCount++
 	}
 
-	// Try parsing in order of specificity
-	if current == nil || current == arrow.PrimitiveTypes.Int64 {
-		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
-			return arrow.PrimitiveTypes.Int64
+	for rowCount < maxRowsToInfer {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-	}
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading CSV row: %w", err)
+		}
+		batch.rows = append(batch.rows, row)
+		rowCount++
 
-	if current == nil || current == arrow.PrimitiveTypes.Float64 || current == arrow.PrimitiveTypes.Int64 {
-		if _, err := strconv.ParseFloat(value, 64); err == nil {
-			return arrow.PrimitiveTypes.Float64
+		if len(batch.rows) == batchSize {
+			batches <- batch
+			batch = rowBatch{start: rowCount + 1}
 		}
 	}
 
-	if current == nil || current == arrow.FixedWidthTypes.Boolean {
-		lower := strings.ToLower(value)
-		if lower == "true" || lower == "false" || lower == "1" || lower == "0" {
-			return arrow.FixedWidthTypes.Boolean
+	if len(batch.rows) > 0 {
+		batches <- batch
+	}
+	return nil
+}
+
+// valueType returns the most specific type that can represent a single
+// non-null value.
+func valueType(value string, opts *CSVReadOptions) arrow.DataType {
+	// Try parsing in order of specificity
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return arrow.PrimitiveTypes.Int64
+	}
+
+	if opts.InferDecimals {
+		if intDigits, scale, ok := decimalDigits(value); ok && intDigits+scale <= maxDecimal128Precision {
+			return &arrow.Decimal128Type{Precision: intDigits + scale, Scale: scale}
 		}
 	}
 
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return arrow.PrimitiveTypes.Float64
+	}
+
+	lower := strings.ToLower(value)
+	if lower == "true" || lower == "false" {
+		return arrow.FixedWidthTypes.Boolean
+	}
+
+	// Try parsing as date if configured
+	if opts.ParseDates && isDate(value, opts.DateLayouts) {
+		return arrow.FixedWidthTypes.Date32
+	}
+
 	// Try parsing as timestamp if configured
 	if opts.ParseTimestamps {
 		if _, err := time.Parse(opts.TimestampFormat, value); err == nil {
@@ -191,6 +272,34 @@ func inferColumnType(current arrow.DataType, value string, opts *CSVReadOptions)
 
 // Helper functions for type detection
 
+// decimalDigits splits a plain decimal literal such as "-123.45" into its
+// integer and fractional digit counts.
+func decimalDigits(value string) (intDigits, scale int32, ok bool) {
+	value = strings.TrimLeft(value, "+-")
+	intPart, fracPart, _ := strings.Cut(value, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, 0, false
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return 0, 0, false
+			}
+		}
+	}
+	intPart = strings.TrimLeft(intPart, "0")
+	return max(int32(len(intPart)), 1), int32(len(fracPart)), true
+}
+
+func isDate(value string, layouts []string) bool {
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 func parseDate(value string) (bool, error) {
 	// Implement a simple date parsing logic
 	if len(value) == 10 && strings.Count(value, "-") == 2 {
@@ -230,57 +339,112 @@ func validateOptions(opts *CSVReadOptions) error {
 	if opts.NullValues == nil {
 		opts.NullValues = []string{"", "NULL", "null", "NA", "na"}
 	}
+	if opts.ParseDates && len(opts.DateLayouts) == 0 {
+		opts.DateLayouts = []string{time.DateOnly}
+	}
+	for name, dt := range opts.ColumnTypes {
+		if dt == nil {
+			return fmt.Errorf("column type override for %q cannot be nil", name)
+		}
+	}
+	return nil
+}
+
+// checkSchema verifies that a user-supplied schema lines up with the CSV header.
+func checkSchema(headers []string, schema *arrow.Schema) error {
+	if schema.NumFields() != len(headers) {
+		return fmt.Errorf("schema has %d fields but CSV has %d columns", schema.NumFields(), len(headers))
+	}
+	for _, name := range headers {
+		if len(schema.FieldIndices(name)) == 0 {
+			return fmt.Errorf("CSV column %q not found in schema", name)
+		}
+	}
 	return nil
 }
 
+// overridesAll reports whether every header has a type override.
+func overridesAll(headers []string, overrides map[string]arrow.DataType) bool {
+	if len(overrides) == 0 {
+		return false
+	}
+	for _, name := range headers {
+		if _, ok := overrides[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // Add metadata to schema
-func buildSchema(headers []string, types []arrow.DataType, nullability []bool, opts *CSVReadOptions) *arrow.Schema {
+func buildSchema(headers []string, states []columnState, opts *CSVReadOptions) *arrow.Schema {
 	fields := make([]arrow.Field, len(headers))
 	for i, name := range headers {
+		dt, source, nullable := states[i].dataType(), "csv", states[i].nullable
+		if override, ok := opts.ColumnTypes[name]; ok {
+			dt, source, nullable = override, "override", true
+		}
 		fields[i] = arrow.Field{
 			Name:     name,
-			Type:     types[i],
-			Nullable: nullability[i],
+			Type:     dt,
+			Nullable: nullable,
 			Metadata: arrow.MetadataFrom(map[string]string{
 				"original_index": strconv.Itoa(i),
-				"inferred_from":  "csv",
+				"inferred_from":  source,
 			}),
 		}
 	}
 
 	metadata := arrow.MetadataFrom(map[string]string{
-		"delimiter":   string(opts.Delimiter),
-		"has_header":  strconv.FormatBool(opts.HasHeader),
-		"inferred_at": time.Now().UTC().Format(time.RFC3339),
+		"delimiter":  string(opts.Delimiter),
+		"has_header": strconv.FormatBool(opts.HasHeader),
 	})
 	return arrow.NewSchema(fields, &metadata)
 }
 
-func processRows(rowChan chan []string, types []arrow.DataType, nullability []bool, opts *CSVReadOptions, wg *sync.WaitGroup, errChan chan *inferenceError) {
-	defer wg.Done()
+// processRows folds every batch it receives into worker-local column states.
+// It keeps draining the channel after an error so the reader never blocks.
+func processRows(batches <-chan rowBatch, numColumns int, opts *CSVReadOptions) ([]columnState, error) {
+	states := make([]columnState, numColumns)
+	var firstErr error
 
-	for row := range rowChan {
-		for colIndex, value := range row {
-			types[colIndex] = inferColumnType(types[colIndex], value, opts)
-			if isNullValue(value, opts.NullValues) {
-				nullability[colIndex] = true
+	for batch := range batches {
+		if firstErr != nil {
+			continue
+		}
+		for i, row := range batch.rows {
+			if len(row) > numColumns {
+				firstErr = &inferenceError{
+					Row:    batch.start + i,
+					Column: numColumns,
+					Err:    fmt.Errorf("row has %d fields, expected %d", len(row), numColumns),
+				}
+				break
+			}
+			for colIndex, value := range row {
+				states[colIndex].observe(value, opts)
 			}
 		}
 	}
+	return states, firstErr
 }
 
-func readHeaders(reader *csv.Reader, opts *CSVReadOptions) ([]string, error) {
-	headers, err := reader.Read()
+// readHeaders returns the column names. Without a header row the first record
+// is data, so it is returned as well to be included in inference.
+func readHeaders(reader *csv.Reader, opts *CSVReadOptions) ([]string, []string, error) {
+	record, err := reader.Read()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read headers: %w", err)
+		return nil, nil, fmt.Errorf("failed to read headers: %w", err)
 	}
 
-	if !opts.HasHeader {
-		// Generate default headers (col0, col1, etc.)
-		for i := range headers {
-			headers[i] = fmt.Sprintf("col%d", i)
-		}
+	if opts.HasHeader {
+		return record, nil, nil
 	}
 
-	return headers, nil
+	// Generate default headers (col0, col1, etc.)
+	headers := make([]string, len(record))
+	for i := range headers {
+		headers[i] = fmt.Sprintf("col%d", i)
+	}
+	return headers, record, nil
 }
@@ -0,0 +1,170 @@
+package csv
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+func writeTempCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInferCSVArrowSchema_ExplicitSchema(t *testing.T) {
+	path := writeTempCSV(t, "id,name\n1,a\n")
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "name", Type: arrow.BinaryTypes.LargeString},
+	}, nil)
+
+	got, err := InferCSVArrowSchema(context.Background(), path, &CSVReadOptions{HasHeader: true, Schema: schema})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != schema {
+		t.Fatalf("expected supplied schema to be returned, got %v", got)
+	}
+
+	mismatched := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int32}}, nil)
+	if _, err := InferCSVArrowSchema(context.Background(), path, &CSVReadOptions{HasHeader: true, Schema: mismatched}); err == nil {
+		t.Fatal("expected error for schema with missing columns")
+	}
+}
+
+func TestInferCSVArrowSchema_AllColumnsOverridden(t *testing.T) {
+	path := writeTempCSV(t, "id,amount\n1,2.50\n")
+	got, err := InferCSVArrowSchema(context.Background(), path, &CSVReadOptions{
+		HasHeader: true,
+		ColumnTypes: map[string]arrow.DataType{
+			"id":     arrow.PrimitiveTypes.Int16,
+			"amount": &arrow.Decimal128Type{Precision: 10, Scale: 2},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !arrow.TypeEqual(got.Field(0).Type, arrow.PrimitiveTypes.Int16) {
+		t.Errorf("id: expected int16, got %s", got.Field(0).Type)
+	}
+	if !arrow.TypeEqual(got.Field(1).Type, &arrow.Decimal128Type{Precision: 10, Scale: 2}) {
+		t.Errorf("amount: expected decimal(10, 2), got %s", got.Field(1).Type)
+	}
+	if v, _ := got.Field(1).Metadata.GetValue("inferred_from"); v != "override" {
+		t.Errorf("expected override metadata, got %q", v)
+	}
+}
+
+func TestInferColumnType_Decimal(t *testing.T) {
+	opts := &CSVReadOptions{InferDecimals: true}
+	if err := validateOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var state columnState
+	for _, v := range []string{"1.5", "-123.25", "7", "0.125"} {
+		state.observe(v, opts)
+	}
+	want := &arrow.Decimal128Type{Precision: 6, Scale: 3}
+	if dt := state.dataType(); !arrow.TypeEqual(dt, want) {
+		t.Fatalf("expected %s, got %s", want, dt)
+	}
+
+	state.observe("1e10", opts)
+	if dt := state.dataType(); dt != arrow.PrimitiveTypes.Float64 {
+		t.Fatalf("expected float64 after exponent literal, got %s", dt)
+	}
+}
+
+func TestInferColumnType_Date32(t *testing.T) {
+	opts := &CSVReadOptions{ParseDates: true, DateLayouts: []string{"2006-01-02", "02/01/2006"}}
+	if err := validateOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var state columnState
+	for _, v := range []string{"2024-03-01", "15/04/2024"} {
+		state.observe(v, opts)
+	}
+	if dt := state.dataType(); dt != arrow.FixedWidthTypes.Date32 {
+		t.Fatalf("expected date32, got %s", dt)
+	}
+	state.observe("not a date", opts)
+	if dt := state.dataType(); dt != arrow.BinaryTypes.String {
+		t.Fatalf("expected string, got %s", dt)
+	}
+}
+
+func TestInferCSVArrowSchema_Sampled(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("id,score,label,empty\n")
+	// Enough rows to spread batches across workers; the float and string
+	// values land in different batches from the integers they promote.
+	for i := 0; i < 3*batchSize; i++ {
+		score := strconv.Itoa(i)
+		if i == 2*batchSize+5 {
+			score = "2.5"
+		}
+		label := strconv.Itoa(i)
+		if i == batchSize+1 {
+			label = "x"
+		}
+		fmt.Fprintf(&sb, "%d,%s,%s,\n", i, score, label)
+	}
+	path := writeTempCSV(t, sb.String())
+
+	for run := 0; run < 5; run++ {
+		got, err := InferCSVArrowSchema(context.Background(), path, &CSVReadOptions{HasHeader: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []arrow.DataType{
+			arrow.PrimitiveTypes.Int64,
+			arrow.PrimitiveTypes.Float64,
+			arrow.BinaryTypes.String,
+			arrow.BinaryTypes.String,
+		}
+		for i, dt := range want {
+			if !arrow.TypeEqual(got.Field(i).Type, dt) {
+				t.Errorf("run %d, %s: expected %s, got %s", run, got.Field(i).Name, dt, got.Field(i).Type)
+			}
+		}
+		if got.Field(0).Nullable || !got.Field(3).Nullable {
+			t.Errorf("run %d: unexpected nullability %v", run, got)
+		}
+	}
+}
+
+func TestInferCSVArrowSchema_NoHeaderIncludesFirstRow(t *testing.T) {
+	path := writeTempCSV(t, "x,1\n2,3\n")
+	got, err := InferCSVArrowSchema(context.Background(), path, &CSVReadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Field(0).Name != "col0" || got.Field(0).Type != arrow.BinaryTypes.String {
+		t.Errorf("expected col0 string, got %s %s", got.Field(0).Name, got.Field(0).Type)
+	}
+	if got.Field(1).Type != arrow.PrimitiveTypes.Int64 {
+		t.Errorf("expected col1 int64, got %s", got.Field(1).Type)
+	}
+}
+
+func TestInferCSVArrowSchemaFromReader(t *testing.T) {
+	got, err := InferCSVArrowSchemaFromReader(context.Background(), strings.NewReader("id;name\n1;a\n2;b\n"), &CSVReadOptions{Delimiter: ';', HasHeader: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.NumFields() != 2 || got.Field(0).Name != "id" || got.Field(0).Type.ID() != arrow.INT64 {
+		t.Fatalf("unexpected schema %v", got)
+	}
+}
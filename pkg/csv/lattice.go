@@ -0,0 +1,145 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package csv
+
+import (
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// columnState accumulates what has been observed about one column. States
+// form a join-semilattice: merging is commutative and associative, so worker
+// results can be combined in any order with the same outcome.
+type columnState struct {
+	typ      arrow.DataType
+	nullable bool
+	// intDigits is the widest integer literal seen, used to size a Decimal128
+	// when integers and decimals share a column.
+	intDigits int32
+}
+
+func (s *columnState) observe(value string, opts *CSVReadOptions) {
+	if isNullValue(value, opts.NullValues) {
+		s.nullable = true
+		return
+	}
+	dt := valueType(value, opts)
+	if dt == arrow.PrimitiveTypes.Int64 {
+		if digits, _, ok := decimalDigits(value); ok {
+			s.intDigits = max(s.intDigits, digits)
+		}
+	}
+	s.typ = mergeTypes(s.typ, dt)
+}
+
+func (s *columnState) merge(other columnState) {
+	s.typ = mergeTypes(s.typ, other.typ)
+	s.nullable = s.nullable || other.nullable
+	s.intDigits = max(s.intDigits, other.intDigits)
+}
+
+// dataType resolves the final column type. Columns with only null values
+// default to string.
+func (s *columnState) dataType() arrow.DataType {
+	switch dt := s.typ.(type) {
+	case nil:
+		return arrow.BinaryTypes.String
+	case *arrow.Decimal128Type:
+		intDigits := max(dt.Precision-dt.Scale, s.intDigits)
+		if intDigits+dt.Scale > maxDecimal128Precision {
+			return arrow.PrimitiveTypes.Float64
+		}
+		return &arrow.Decimal128Type{Precision: intDigits + dt.Scale, Scale: dt.Scale}
+	}
+	return s.typ
+}
+
+// mergeTypes returns the narrowest type that can represent values of both a
+// and b. Numeric types promote int64 -> decimal128 -> float64, date32
+// promotes to timestamp, and any other mismatch falls back to string.
+func mergeTypes(a, b arrow.DataType) arrow.DataType {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case arrow.TypeEqual(a, b):
+		return a
+	}
+
+	ra, rb := numericRank(a), numericRank(b)
+	if ra > 0 && rb > 0 {
+		if ra < rb {
+			a, b = b, a
+			ra, rb = rb, ra
+		}
+		switch {
+		case ra == rankFloat:
+			return arrow.PrimitiveTypes.Float64
+		case rb == rankInt:
+			// Integer digits are tracked separately by columnState.
+			return a
+		}
+		da, db := a.(*arrow.Decimal128Type), b.(*arrow.Decimal128Type)
+		intDigits := max(da.Precision-da.Scale, db.Precision-db.Scale)
+		scale := max(da.Scale, db.Scale)
+		if intDigits+scale > maxDecimal128Precision {
+			return arrow.PrimitiveTypes.Float64
+		}
+		return &arrow.Decimal128Type{Precision: intDigits + scale, Scale: scale}
+	}
+
+	if isTemporal(a) && isTemporal(b) {
+		return arrow.FixedWidthTypes.Timestamp_us
+	}
+
+	return arrow.BinaryTypes.String
+}
+
+const (
+	rankInt = iota + 1
+	rankDecimal
+	rankFloat
+)
+
+func numericRank(dt arrow.DataType) int {
+	switch dt.(type) {
+	case *arrow.Int64Type:
+		return rankInt
+	case *arrow.Decimal128Type:
+		return rankDecimal
+	case *arrow.Float64Type:
+		return rankFloat
+	}
+	return 0
+}
+
+func isTemporal(dt arrow.DataType) bool {
+	return dt == arrow.FixedWidthTypes.Date32 || dt == arrow.FixedWidthTypes.Timestamp_us
+}
@@ -0,0 +1,68 @@
+package csv
+
+import (
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+func TestMergeTypes(t *testing.T) {
+	dec := func(p, s int32) arrow.DataType { return &arrow.Decimal128Type{Precision: p, Scale: s} }
+
+	tests := []struct {
+		a, b arrow.DataType
+		want arrow.DataType
+	}{
+		{nil, arrow.PrimitiveTypes.Int64, arrow.PrimitiveTypes.Int64},
+		{arrow.PrimitiveTypes.Int64, arrow.PrimitiveTypes.Int64, arrow.PrimitiveTypes.Int64},
+		{arrow.PrimitiveTypes.Int64, arrow.PrimitiveTypes.Float64, arrow.PrimitiveTypes.Float64},
+		{arrow.PrimitiveTypes.Int64, arrow.BinaryTypes.String, arrow.BinaryTypes.String},
+		{arrow.PrimitiveTypes.Float64, arrow.BinaryTypes.String, arrow.BinaryTypes.String},
+		{arrow.PrimitiveTypes.Int64, arrow.FixedWidthTypes.Boolean, arrow.BinaryTypes.String},
+		{arrow.PrimitiveTypes.Int64, dec(5, 2), dec(5, 2)},
+		{dec(5, 2), dec(4, 3), dec(6, 3)},
+		{dec(5, 2), arrow.PrimitiveTypes.Float64, arrow.PrimitiveTypes.Float64},
+		{dec(38, 0), dec(2, 2), arrow.PrimitiveTypes.Float64},
+		{arrow.FixedWidthTypes.Date32, arrow.FixedWidthTypes.Timestamp_us, arrow.FixedWidthTypes.Timestamp_us},
+	}
+
+	for _, tt := range tests {
+		for _, pair := range [][2]arrow.DataType{{tt.a, tt.b}, {tt.b, tt.a}} {
+			if got := mergeTypes(pair[0], pair[1]); !arrow.TypeEqual(got, tt.want) {
+				t.Errorf("mergeTypes(%v, %v) = %v, want %v", pair[0], pair[1], got, tt.want)
+			}
+		}
+	}
+}
+
+func TestColumnStateMergeIsOrderIndependent(t *testing.T) {
+	opts := &CSVReadOptions{InferDecimals: true}
+	if err := validateOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	chunks := [][]string{{"1", "NULL"}, {"12345", "2"}, {"0.25", "3"}}
+
+	observe := func(values []string) columnState {
+		var s columnState
+		for _, v := range values {
+			s.observe(v, opts)
+		}
+		return s
+	}
+
+	var forward, backward columnState
+	for i := range chunks {
+		forward.merge(observe(chunks[i]))
+		backward.merge(observe(chunks[len(chunks)-1-i]))
+	}
+
+	want := &arrow.Decimal128Type{Precision: 7, Scale: 2}
+	for _, s := range []columnState{forward, backward} {
+		if dt := s.dataType(); !arrow.TypeEqual(dt, want) {
+			t.Errorf("expected %s, got %s", want, dt)
+		}
+		if !s.nullable {
+			t.Error("expected column to be nullable")
+		}
+	}
+}
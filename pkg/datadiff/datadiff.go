@@ -0,0 +1,462 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package datadiff compares two record streams by schema and row content,
+// matching rows by key columns or by position, and summarizes the
+// differences.
+package datadiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/arrowarc/arrowarc/pkg/arrowschema"
+)
+
+// DefaultMaxExamples is the number of differing rows a Report shows by
+// default.
+const DefaultMaxExamples = 10
+
+// Reader is a stream of records with a known schema.
+type Reader interface {
+	Read() (arrow.Record, error)
+	Schema() *arrow.Schema
+}
+
+// Options configures a comparison.
+type Options struct {
+	// Keys names the columns identifying a row. Rows are matched by
+	// position when empty.
+	Keys []string
+	// Tolerance is the largest difference between two numbers, one of them
+	// at least floating point, that still counts as equal.
+	Tolerance float64
+	// MaxExamples is the number of differing rows kept in the report. Zero
+	// means DefaultMaxExamples and a negative value keeps none.
+	MaxExamples int
+}
+
+// Kinds of RowDiff.
+const (
+	Changed = "changed"
+	OnlyInA = "only in a"
+	OnlyInB = "only in b"
+)
+
+// CellDiff is a value that differs between the two sides of a row.
+type CellDiff struct {
+	Column string `json:"column"`
+	A      string `json:"a"`
+	B      string `json:"b"`
+}
+
+// RowDiff is a row that differs. Row is its key, such as "id=7", or its
+// position, such as "row 7", when rows are matched by position.
+type RowDiff struct {
+	Kind  string     `json:"kind"`
+	Row   string     `json:"row"`
+	Cells []CellDiff `json:"cells,omitempty"`
+}
+
+// ColumnSummary counts the changed rows in which a column differs.
+type ColumnSummary struct {
+	Name        string `json:"name"`
+	Differences int64  `json:"differences"`
+}
+
+// Report summarizes the differences between two datasets, a and b.
+type Report struct {
+	Schema   []arrowschema.Change `json:"schema"`
+	RowsA    int64                `json:"rows_a"`
+	RowsB    int64                `json:"rows_b"`
+	Matching int64                `json:"matching"`
+	Changed  int64                `json:"changed"`
+	OnlyInA  int64                `json:"only_in_a"`
+	OnlyInB  int64                `json:"only_in_b"`
+	// Columns lists the compared columns, those of a also in b, key
+	// columns excluded.
+	Columns  []ColumnSummary `json:"columns"`
+	Examples []RowDiff       `json:"examples,omitempty"`
+
+	maxExamples int
+}
+
+// Equal reports whether the datasets hold the same rows and columns. Type
+// and nullability changes do not count when the values still compare
+// equal, as between a Parquet file and a CSV conversion of it.
+func (r *Report) Equal() bool {
+	for _, c := range r.Schema {
+		if c.Kind == arrowschema.Added || c.Kind == arrowschema.Removed {
+			return false
+		}
+	}
+	return r.Changed == 0 && r.OnlyInA == 0 && r.OnlyInB == 0
+}
+
+// Diff reads a and b to the end and compares them. Only the columns of a
+// also in b, matched by name, are compared. With Keys, the rows of b are
+// held in memory, keyed, while a is streamed; duplicate keys are an error.
+// Without, both are streamed and compared row by row.
+func Diff(a, b Reader, opts Options) (*Report, error) {
+	r := &Report{Schema: arrowschema.Diff(a.Schema(), b.Schema()), maxExamples: opts.MaxExamples}
+	if r.Schema == nil {
+		r.Schema = []arrowschema.Change{}
+	}
+	if r.maxExamples == 0 {
+		r.maxExamples = DefaultMaxExamples
+	}
+	keys := make(map[string]bool, len(opts.Keys))
+	for _, key := range opts.Keys {
+		if len(a.Schema().FieldIndices(key)) == 0 {
+			return nil, fmt.Errorf("key column %q is not in the first dataset", key)
+		}
+		if len(b.Schema().FieldIndices(key)) == 0 {
+			return nil, fmt.Errorf("key column %q is not in the second dataset", key)
+		}
+		keys[key] = true
+	}
+	for _, f := range a.Schema().Fields() {
+		if !keys[f.Name] && len(b.Schema().FieldIndices(f.Name)) > 0 {
+			r.Columns = append(r.Columns, ColumnSummary{Name: f.Name})
+		}
+	}
+
+	ca := newCursor(a, opts.Keys, r.Columns)
+	cb := newCursor(b, opts.Keys, r.Columns)
+	defer ca.release()
+	defer cb.release()
+	var err error
+	if len(opts.Keys) == 0 {
+		err = r.diffByPosition(ca, cb, opts.Tolerance)
+	} else {
+		err = r.diffByKey(ca, cb, opts.Tolerance)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Report) diffByPosition(a, b *cursor, tolerance float64) error {
+	for {
+		rowA, okA, err := a.next()
+		if err != nil {
+			return err
+		}
+		rowB, okB, err := b.next()
+		if err != nil {
+			return err
+		}
+		label := fmt.Sprintf("row %d", r.RowsA)
+		if !okA {
+			label = fmt.Sprintf("row %d", r.RowsB)
+		}
+		switch {
+		case okA && okB:
+			r.RowsA++
+			r.RowsB++
+			r.compare(label, rowA, rowB, tolerance)
+		case okA:
+			r.RowsA++
+			r.OnlyInA++
+			r.example(RowDiff{Kind: OnlyInA, Row: label})
+		case okB:
+			r.RowsB++
+			r.OnlyInB++
+			r.example(RowDiff{Kind: OnlyInB, Row: label})
+		default:
+			return nil
+		}
+	}
+}
+
+// keyedRow is a row of b waiting for its match in a.
+type keyedRow struct {
+	values  []value
+	matched bool
+}
+
+func (r *Report) diffByKey(a, b *cursor, tolerance float64) error {
+	rowsB := make(map[string]*keyedRow)
+	var order []string
+	for {
+		row, ok, err := b.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		r.RowsB++
+		if _, dup := rowsB[row.key]; dup {
+			return fmt.Errorf("duplicate key %s in the second dataset", row.key)
+		}
+		rowsB[row.key] = &keyedRow{values: row.values}
+		order = append(order, row.key)
+	}
+
+	for {
+		row, ok, err := a.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		r.RowsA++
+		match, found := rowsB[row.key]
+		switch {
+		case !found:
+			r.OnlyInA++
+			r.example(RowDiff{Kind: OnlyInA, Row: row.key})
+		case match.matched:
+			return fmt.Errorf("duplicate key %s in the first dataset", row.key)
+		default:
+			match.matched = true
+			r.compare(row.key, row, cursorRow{values: match.values}, tolerance)
+		}
+	}
+	for _, key := range order {
+		if !rowsB[key].matched {
+			r.OnlyInB++
+			r.example(RowDiff{Kind: OnlyInB, Row: key})
+		}
+	}
+	return nil
+}
+
+// compare compares the values of a row present on both sides.
+func (r *Report) compare(label string, a, b cursorRow, tolerance float64) {
+	var cells []CellDiff
+	for i := range r.Columns {
+		if a.values[i].equal(b.values[i], tolerance) {
+			continue
+		}
+		r.Columns[i].Differences++
+		cells = append(cells, CellDiff{Column: r.Columns[i].Name, A: a.values[i].String(), B: b.values[i].String()})
+	}
+	if len(cells) == 0 {
+		r.Matching++
+		return
+	}
+	r.Changed++
+	r.example(RowDiff{Kind: Changed, Row: label, Cells: cells})
+}
+
+func (r *Report) example(d RowDiff) {
+	if len(r.Examples) < r.maxExamples {
+		r.Examples = append(r.Examples, d)
+	}
+}
+
+// value is a cell, kept as text and, for numbers, as a float64.
+type value struct {
+	null    bool
+	text    string
+	num     float64
+	numeric bool
+	float   bool
+}
+
+func newValue(arr arrow.Array, i int) value {
+	if arr.IsNull(i) {
+		return value{null: true}
+	}
+	v := value{text: arr.ValueStr(i), numeric: true}
+	switch a := arr.(type) {
+	case *array.Int8:
+		v.num = float64(a.Value(i))
+	case *array.Int16:
+		v.num = float64(a.Value(i))
+	case *array.Int32:
+		v.num = float64(a.Value(i))
+	case *array.Int64:
+		v.num = float64(a.Value(i))
+	case *array.Uint8:
+		v.num = float64(a.Value(i))
+	case *array.Uint16:
+		v.num = float64(a.Value(i))
+	case *array.Uint32:
+		v.num = float64(a.Value(i))
+	case *array.Uint64:
+		v.num = float64(a.Value(i))
+	case *array.Float16:
+		v.num, v.float = float64(a.Value(i).Float32()), true
+	case *array.Float32:
+		v.num, v.float = float64(a.Value(i)), true
+	case *array.Float64:
+		v.num, v.float = a.Value(i), true
+	default:
+		v.numeric = false
+	}
+	return v
+}
+
+// equal compares v and o as numbers within tolerance when both are
+// numbers and one is floating point, and as text otherwise.
+func (v value) equal(o value, tolerance float64) bool {
+	if v.null || o.null {
+		return v.null == o.null
+	}
+	if v.numeric && o.numeric && (v.float || o.float) {
+		if math.IsNaN(v.num) || math.IsNaN(o.num) {
+			return math.IsNaN(v.num) && math.IsNaN(o.num)
+		}
+		return v.num == o.num || math.Abs(v.num-o.num) <= tolerance
+	}
+	return v.text == o.text
+}
+
+func (v value) String() string {
+	if v.null {
+		return "NULL"
+	}
+	return v.text
+}
+
+// cursorRow is a row read by a cursor: its key, if rows are keyed, and the
+// values of the compared columns.
+type cursorRow struct {
+	key    string
+	values []value
+}
+
+// cursor reads the rows of a Reader one at a time.
+type cursor struct {
+	reader  Reader
+	keys    []string
+	record  arrow.Record
+	keyCols []int
+	cols    []int
+	row     int
+	done    bool
+}
+
+func newCursor(r Reader, keys []string, columns []ColumnSummary) *cursor {
+	c := &cursor{reader: r, keys: keys}
+	for _, key := range keys {
+		c.keyCols = append(c.keyCols, r.Schema().FieldIndices(key)[0])
+	}
+	for _, col := range columns {
+		c.cols = append(c.cols, r.Schema().FieldIndices(col.Name)[0])
+	}
+	return c
+}
+
+// next returns the next row, or false once the reader is exhausted.
+func (c *cursor) next() (cursorRow, bool, error) {
+	for c.record == nil || c.row >= int(c.record.NumRows()) {
+		if c.done {
+			return cursorRow{}, false, nil
+		}
+		c.release()
+		record, err := c.reader.Read()
+		if err == io.EOF || (err == nil && record == nil) {
+			c.done = true
+			return cursorRow{}, false, nil
+		}
+		if err != nil {
+			return cursorRow{}, false, err
+		}
+		c.record, c.row = record, 0
+	}
+	row := cursorRow{values: make([]value, len(c.cols))}
+	for i, col := range c.cols {
+		row.values[i] = newValue(c.record.Column(col), c.row)
+	}
+	if len(c.keyCols) > 0 {
+		parts := make([]string, len(c.keyCols))
+		for i, col := range c.keyCols {
+			parts[i] = c.keys[i] + "=" + newValue(c.record.Column(col), c.row).String()
+		}
+		row.key = strings.Join(parts, ", ")
+	}
+	c.row++
+	return row, true, nil
+}
+
+func (c *cursor) release() {
+	if c.record != nil {
+		c.record.Release()
+		c.record = nil
+	}
+}
+
+// WriteText writes a summary of r: the schema changes, the row counts,
+// the differences per column and the example rows.
+func WriteText(w io.Writer, r *Report) error {
+	var b strings.Builder
+	if len(r.Schema) > 0 {
+		b.WriteString("Schema:\n")
+		for _, c := range r.Schema {
+			fmt.Fprintf(&b, "  %s\n", c)
+		}
+	}
+	fmt.Fprintf(&b, "Rows: %d in a, %d in b\n", r.RowsA, r.RowsB)
+	fmt.Fprintf(&b, "  %d matching, %d changed, %d only in a, %d only in b\n", r.Matching, r.Changed, r.OnlyInA, r.OnlyInB)
+	var header bool
+	for _, c := range r.Columns {
+		if c.Differences == 0 {
+			continue
+		}
+		if !header {
+			b.WriteString("Columns with differences:\n")
+			header = true
+		}
+		fmt.Fprintf(&b, "  %s: %d\n", c.Name, c.Differences)
+	}
+	if len(r.Examples) > 0 {
+		b.WriteString("Examples:\n")
+		for _, d := range r.Examples {
+			fmt.Fprintf(&b, "  %s %s", d.Kind, d.Row)
+			for i, cell := range d.Cells {
+				sep := ", "
+				if i == 0 {
+					sep = ": "
+				}
+				fmt.Fprintf(&b, "%s%s %q -> %q", sep, cell.Column, cell.A, cell.B)
+			}
+			b.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes r as indented JSON.
+func WriteJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,161 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package datadiff
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// row is a row of a test dataset; a nil score is null.
+type row struct {
+	id    int64
+	name  string
+	score *float64
+}
+
+func score(f float64) *float64 { return &f }
+
+// sliceReader returns rows in records of two rows.
+type sliceReader struct {
+	schema *arrow.Schema
+	rows   []row
+}
+
+func dataset(rows ...row) *sliceReader {
+	return &sliceReader{schema: arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "score", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	}, nil), rows: rows}
+}
+
+func (r *sliceReader) Schema() *arrow.Schema { return r.schema }
+
+func (r *sliceReader) Read() (arrow.Record, error) {
+	if len(r.rows) == 0 {
+		return nil, io.EOF
+	}
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), r.schema)
+	defer b.Release()
+	for i := 0; i < 2 && len(r.rows) > 0; i++ {
+		row := r.rows[0]
+		r.rows = r.rows[1:]
+		b.Field(0).(*array.Int64Builder).Append(row.id)
+		b.Field(1).(*array.StringBuilder).Append(row.name)
+		if row.score == nil {
+			b.Field(2).AppendNull()
+		} else {
+			b.Field(2).(*array.Float64Builder).Append(*row.score)
+		}
+	}
+	return b.NewRecord(), nil
+}
+
+func TestDiffByPosition(t *testing.T) {
+	a := dataset(row{1, "a", score(1)}, row{2, "b", score(2)}, row{3, "c", nil})
+	b := dataset(row{1, "a", score(1.0001)}, row{2, "x", score(2)})
+	r, err := Diff(a, b, Options{Tolerance: 0.001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RowsA != 3 || r.RowsB != 2 || r.Matching != 1 || r.Changed != 1 || r.OnlyInA != 1 || r.OnlyInB != 0 {
+		t.Fatalf("report = %+v", r)
+	}
+	if r.Equal() {
+		t.Error("datasets reported equal")
+	}
+	var out bytes.Buffer
+	if err := WriteText(&out, r); err != nil {
+		t.Fatal(err)
+	}
+	want := `Rows: 3 in a, 2 in b
+  1 matching, 1 changed, 1 only in a, 0 only in b
+Columns with differences:
+  name: 1
+Examples:
+  changed row 1: name "b" -> "x"
+  only in a row 2
+`
+	if out.String() != want {
+		t.Errorf("text report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestDiffByKey(t *testing.T) {
+	a := dataset(row{1, "a", score(1)}, row{2, "b", score(2)}, row{3, "c", nil}, row{4, "d", nil})
+	b := dataset(row{4, "d", nil}, row{3, "c", score(0)}, row{1, "a", score(1)}, row{5, "e", nil})
+	r, err := Diff(a, b, Options{Keys: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Matching != 2 || r.Changed != 1 || r.OnlyInA != 1 || r.OnlyInB != 1 {
+		t.Fatalf("report = %+v", r)
+	}
+	if len(r.Columns) != 2 || r.Columns[1].Name != "score" || r.Columns[1].Differences != 1 {
+		t.Errorf("columns = %+v", r.Columns)
+	}
+	var kinds []string
+	for _, d := range r.Examples {
+		kinds = append(kinds, d.Kind+" "+d.Row)
+	}
+	if got := strings.Join(kinds, "; "); got != "only in a id=2; changed id=3; only in b id=5" {
+		t.Errorf("examples = %s", got)
+	}
+	if cell := r.Examples[1].Cells[0]; cell != (CellDiff{Column: "score", A: "NULL", B: "0"}) {
+		t.Errorf("changed cell = %+v", cell)
+	}
+}
+
+func TestDiffEqual(t *testing.T) {
+	r, err := Diff(dataset(row{1, "a", score(0.5)}), dataset(row{1, "a", score(0.5)}), Options{Keys: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Equal() || r.Matching != 1 {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	if _, err := Diff(dataset(), dataset(), Options{Keys: []string{"missing"}}); err == nil || !strings.Contains(err.Error(), `key column "missing"`) {
+		t.Errorf("unknown key: %v", err)
+	}
+	_, err := Diff(dataset(row{1, "a", nil}), dataset(row{1, "a", nil}, row{1, "b", nil}), Options{Keys: []string{"id"}})
+	if err == nil || err.Error() != "duplicate key id=1 in the second dataset" {
+		t.Errorf("duplicate key: %v", err)
+	}
+}
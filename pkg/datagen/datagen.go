@@ -0,0 +1,577 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package datagen generates synthetic Arrow records from a schema or a YAML
+// spec, drawing each column from a distribution, for benchmarks and demos.
+package datagen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"gopkg.in/yaml.v3"
+)
+
+// Distributions of column values.
+const (
+	// Uniform draws values evenly between Min and Max, inclusive for
+	// integers. For strings, Min and Max bound the length of random
+	// lowercase strings, or values are drawn evenly from Values if set.
+	Uniform = "uniform"
+	// Zipf draws the rank k of a value with probability proportional to
+	// 1/(k+1)^Skew, so the first values are the most frequent: the value is
+	// Values[k] if Values is set, or Min+k up to Max.
+	Zipf = "zipf"
+	// Categorical draws from Values, in proportion to Weights if set.
+	Categorical = "categorical"
+	// Sequence counts up from Min, one per row.
+	Sequence = "sequence"
+)
+
+// DefaultBatchSize is the number of rows per record when Spec.BatchSize is
+// not set.
+const DefaultBatchSize = 1024
+
+// DefaultSkew is the Zipf exponent used when Column.Skew is not set.
+const DefaultSkew = 1.2
+
+// Column describes how the values of a column are generated.
+type Column struct {
+	Name string `yaml:"name"`
+	// Type is an Arrow type name: bool, int8 to int64, uint8 to uint64,
+	// float32, float64, string (or utf8), date32, or timestamp[s|ms|us|ns]
+	// with an optional time zone, as in timestamp[us, tz=UTC].
+	Type string `yaml:"type"`
+	// Distribution is one of the distribution constants, Uniform by
+	// default.
+	Distribution string `yaml:"distribution"`
+	// Min and Max bound the values, written as values of the column type:
+	// dates as 2006-01-02, timestamps in RFC 3339. They default to 0 and
+	// 1000, 0 and 100 for 8-bit integers, 0 and 1 for floats, 8 and 16 for
+	// string lengths, and 2020 to 2025 for dates and timestamps.
+	Min string `yaml:"min"`
+	Max string `yaml:"max"`
+	// Skew is the exponent of the Zipf distribution, above 1.
+	Skew float64 `yaml:"skew"`
+	// Values are the values of Categorical columns, and of Zipf and Uniform
+	// columns if set, written as values of the column type.
+	Values []string `yaml:"values"`
+	// Weights are the relative frequencies of Values.
+	Weights []float64 `yaml:"weights"`
+	// NullRate is the probability of a value being null. The column is
+	// nullable if it is above zero or Nullable is set.
+	NullRate float64 `yaml:"null_rate"`
+	Nullable bool    `yaml:"nullable"`
+}
+
+// Spec describes a generated dataset.
+type Spec struct {
+	// Rows is the number of rows generated.
+	Rows int64 `yaml:"rows"`
+	// Seed makes the data reproducible: the same spec and seed generate the
+	// same rows. Each column draws from its own stream, so adding a column
+	// leaves the values of the others unchanged.
+	Seed int64 `yaml:"seed"`
+	// BatchSize is the number of rows per record.
+	BatchSize int      `yaml:"batch_size"`
+	Columns   []Column `yaml:"columns"`
+}
+
+// ParseSpec parses a YAML spec.
+func ParseSpec(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("datagen: invalid spec: %w", err)
+	}
+	return &spec, nil
+}
+
+// LoadSpec reads the YAML spec at path.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSpec(data)
+}
+
+// SpecFromSchema returns a spec generating rows uniform values for each
+// field of schema, keeping the nullability of the fields.
+func SpecFromSchema(schema *arrow.Schema, rows int64) *Spec {
+	spec := &Spec{Rows: rows}
+	for _, f := range schema.Fields() {
+		spec.Columns = append(spec.Columns, Column{Name: f.Name, Type: f.Type.String(), Nullable: f.Nullable})
+	}
+	return spec
+}
+
+// types maps the type names accepted in Column.Type to types.
+var types = func() map[string]arrow.DataType {
+	m := map[string]arrow.DataType{
+		"string":    arrow.BinaryTypes.String,
+		"timestamp": arrow.FixedWidthTypes.Timestamp_us,
+	}
+	for _, dt := range []arrow.DataType{
+		arrow.FixedWidthTypes.Boolean,
+		arrow.PrimitiveTypes.Int8, arrow.PrimitiveTypes.Int16, arrow.PrimitiveTypes.Int32, arrow.PrimitiveTypes.Int64,
+		arrow.PrimitiveTypes.Uint8, arrow.PrimitiveTypes.Uint16, arrow.PrimitiveTypes.Uint32, arrow.PrimitiveTypes.Uint64,
+		arrow.PrimitiveTypes.Float32, arrow.PrimitiveTypes.Float64,
+		arrow.BinaryTypes.String, arrow.FixedWidthTypes.Date32,
+	} {
+		m[dt.String()] = dt
+	}
+	for _, unit := range []arrow.TimeUnit{arrow.Second, arrow.Millisecond, arrow.Microsecond, arrow.Nanosecond} {
+		m[fmt.Sprintf("timestamp[%s]", unit)] = &arrow.TimestampType{Unit: unit}
+	}
+	return m
+}()
+
+// parseType returns the type named name.
+func parseType(name string) (arrow.DataType, error) {
+	if dt, ok := types[name]; ok {
+		return dt, nil
+	}
+	// Time zones follow the unit: timestamp[us, tz=UTC].
+	if inner, ok := strings.CutPrefix(name, "timestamp["); ok && strings.HasSuffix(inner, "]") {
+		unit, zone, _ := strings.Cut(strings.TrimSuffix(inner, "]"), ", tz=")
+		if dt, ok := types["timestamp["+unit+"]"]; ok {
+			return &arrow.TimestampType{Unit: dt.(*arrow.TimestampType).Unit, TimeZone: zone}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %q", name)
+}
+
+// Schema returns the schema of the records generated from spec.
+func (s *Spec) Schema() (*arrow.Schema, error) {
+	fields := make([]arrow.Field, len(s.Columns))
+	for i, c := range s.Columns {
+		dt, err := parseType(c.Type)
+		if err != nil {
+			return nil, fmt.Errorf("datagen: column %q: %w", c.Name, err)
+		}
+		fields[i] = arrow.Field{Name: c.Name, Type: dt, Nullable: c.Nullable || c.NullRate > 0}
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// Generator is a reader of the records generated from a spec.
+type Generator struct {
+	schema    *arrow.Schema
+	columns   []*column
+	mem       memory.Allocator
+	rows      int64
+	batchSize int
+	next      int64
+}
+
+// NewGenerator returns a Generator of the records of spec, allocated from
+// mem, or the default allocator if nil.
+func NewGenerator(spec *Spec, mem memory.Allocator) (*Generator, error) {
+	if spec.Rows < 0 {
+		return nil, fmt.Errorf("datagen: negative number of rows %d", spec.Rows)
+	}
+	if len(spec.Columns) == 0 {
+		return nil, fmt.Errorf("datagen: the spec has no columns")
+	}
+	schema, err := spec.Schema()
+	if err != nil {
+		return nil, err
+	}
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	g := &Generator{schema: schema, mem: mem, rows: spec.Rows, batchSize: spec.BatchSize}
+	if g.batchSize <= 0 {
+		g.batchSize = DefaultBatchSize
+	}
+	for i, c := range spec.Columns {
+		// Each column has its own stream, seeded by the spec seed and the
+		// column's position.
+		rng := rand.New(rand.NewPCG(uint64(spec.Seed), uint64(i)))
+		col, err := newColumn(c, schema.Field(i).Type, rng)
+		if err != nil {
+			return nil, fmt.Errorf("datagen: column %q: %w", c.Name, err)
+		}
+		g.columns = append(g.columns, col)
+	}
+	return g, nil
+}
+
+func (g *Generator) Schema() *arrow.Schema { return g.schema }
+
+// NumRows returns the number of rows generated in all.
+func (g *Generator) NumRows() int64 { return g.rows }
+
+func (g *Generator) Read() (arrow.Record, error) {
+	if g.next >= g.rows {
+		return nil, io.EOF
+	}
+	n := min(int64(g.batchSize), g.rows-g.next)
+	b := array.NewRecordBuilder(g.mem, g.schema)
+	defer b.Release()
+	for i, col := range g.columns {
+		field := b.Field(i)
+		field.Reserve(int(n))
+		for row := g.next; row < g.next+n; row++ {
+			col.append(field, row)
+		}
+	}
+	g.next += n
+	return b.NewRecord(), nil
+}
+
+func (g *Generator) Close() error { return nil }
+
+// Generate writes the records of spec to w, stopping early if ctx is
+// canceled. It does not close w.
+func Generate(ctx context.Context, spec *Spec, w interfaces.Writer) error {
+	g, err := NewGenerator(spec, nil)
+	if err != nil {
+		return err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record, err := g.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = w.Write(record)
+		record.Release()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// column draws the values of one column.
+type column struct {
+	nullRate float64
+	rng      *rand.Rand
+	// draw returns the value of a row: an int64 for integer, date and
+	// timestamp columns, a float64, a bool or a string.
+	draw func(row int64) any
+}
+
+func newColumn(c Column, dt arrow.DataType, rng *rand.Rand) (*column, error) {
+	if c.NullRate < 0 || c.NullRate > 1 {
+		return nil, fmt.Errorf("null rate must be between 0 and 1, got %v", c.NullRate)
+	}
+	col := &column{nullRate: c.NullRate, rng: rng}
+	values := make([]any, len(c.Values))
+	for i, s := range c.Values {
+		v, err := parseValue(dt, s)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	lo, hi, err := bounds(c, dt)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Distribution {
+	case "", Uniform:
+		switch {
+		case len(values) > 0:
+			col.draw = func(int64) any { return values[rng.IntN(len(values))] }
+		case dt.ID() == arrow.BOOL:
+			col.draw = func(int64) any { return rng.IntN(2) == 1 }
+		case dt.ID() == arrow.STRING:
+			lo, hi := lo.(int64), hi.(int64)
+			col.draw = func(int64) any { return randomString(rng, int(lo+rng.Int64N(hi-lo+1))) }
+		case isFloat(dt):
+			lo, hi := lo.(float64), hi.(float64)
+			col.draw = func(int64) any { return lo + rng.Float64()*(hi-lo) }
+		default:
+			lo, hi := lo.(int64), hi.(int64)
+			col.draw = func(int64) any { return lo + rng.Int64N(hi-lo+1) }
+		}
+	case Zipf:
+		skew := c.Skew
+		if skew == 0 {
+			skew = DefaultSkew
+		}
+		if skew <= 1 {
+			return nil, fmt.Errorf("zipf skew must be above 1, got %v", skew)
+		}
+		switch {
+		case len(values) > 0:
+			z := rand.NewZipf(rng, skew, 1, uint64(len(values)-1))
+			col.draw = func(int64) any { return values[z.Uint64()] }
+		case dt.ID() == arrow.BOOL || dt.ID() == arrow.STRING:
+			return nil, fmt.Errorf("zipf %s columns need values", dt)
+		case isFloat(dt):
+			lo, hi := lo.(float64), hi.(float64)
+			z := rand.NewZipf(rng, skew, 1, uint64(hi-lo))
+			col.draw = func(int64) any { return lo + float64(z.Uint64()) }
+		default:
+			lo, hi := lo.(int64), hi.(int64)
+			z := rand.NewZipf(rng, skew, 1, uint64(hi-lo))
+			col.draw = func(int64) any { return lo + int64(z.Uint64()) }
+		}
+	case Categorical:
+		if len(values) == 0 {
+			return nil, fmt.Errorf("categorical columns need values")
+		}
+		if len(c.Weights) == 0 {
+			col.draw = func(int64) any { return values[rng.IntN(len(values))] }
+			break
+		}
+		if len(c.Weights) != len(values) {
+			return nil, fmt.Errorf("%d weights for %d values", len(c.Weights), len(values))
+		}
+		// cumulative[i] is the sum of the weights up to value i.
+		cumulative := make([]float64, len(c.Weights))
+		var total float64
+		for i, w := range c.Weights {
+			if w < 0 {
+				return nil, fmt.Errorf("negative weight %v", w)
+			}
+			total += w
+			cumulative[i] = total
+		}
+		if total == 0 {
+			return nil, fmt.Errorf("the weights sum to zero")
+		}
+		col.draw = func(int64) any {
+			i, _ := slices.BinarySearch(cumulative, rng.Float64()*total)
+			return values[min(i, len(values)-1)]
+		}
+	case Sequence:
+		switch {
+		case isFloat(dt):
+			lo := lo.(float64)
+			col.draw = func(row int64) any { return lo + float64(row) }
+		case dt.ID() == arrow.BOOL || dt.ID() == arrow.STRING:
+			return nil, fmt.Errorf("sequence columns must be numbers, dates or timestamps")
+		default:
+			lo := lo.(int64)
+			col.draw = func(row int64) any { return lo + row }
+		}
+	default:
+		return nil, fmt.Errorf("unknown distribution %q", c.Distribution)
+	}
+	return col, nil
+}
+
+// bounds parses the Min and Max of c, or returns their defaults.
+func bounds(c Column, dt arrow.DataType) (lo, hi any, err error) {
+	var defLo, defHi string
+	switch dt := dt.(type) {
+	case *arrow.BooleanType:
+		return nil, nil, nil
+	case *arrow.StringType:
+		lo, hi := int64(8), int64(16)
+		if c.Min != "" {
+			if lo, err = strconv.ParseInt(c.Min, 10, 64); err != nil {
+				return nil, nil, fmt.Errorf("invalid min length %q", c.Min)
+			}
+		}
+		if c.Max != "" {
+			if hi, err = strconv.ParseInt(c.Max, 10, 64); err != nil {
+				return nil, nil, fmt.Errorf("invalid max length %q", c.Max)
+			}
+		}
+		if lo < 0 || hi < lo {
+			return nil, nil, fmt.Errorf("invalid length range %d to %d", lo, hi)
+		}
+		return lo, hi, nil
+	case *arrow.Date32Type:
+		defLo, defHi = "2020-01-01", "2025-01-01"
+	case *arrow.TimestampType:
+		defLo, defHi = "2020-01-01T00:00:00Z", "2025-01-01T00:00:00Z"
+	default:
+		defLo, defHi = "0", "1000"
+		switch dt.ID() {
+		case arrow.INT8, arrow.UINT8:
+			defHi = "100"
+		case arrow.FLOAT32, arrow.FLOAT64:
+			defHi = "1"
+		}
+	}
+	if c.Min != "" {
+		defLo = c.Min
+	}
+	if c.Max != "" {
+		defHi = c.Max
+	}
+	if lo, err = parseValue(dt, defLo); err != nil {
+		return nil, nil, err
+	}
+	if hi, err = parseValue(dt, defHi); err != nil {
+		return nil, nil, err
+	}
+	if isFloat(dt) {
+		if hi.(float64) < lo.(float64) {
+			return nil, nil, fmt.Errorf("max %s is below min %s", defHi, defLo)
+		}
+	} else if hi.(int64) < lo.(int64) {
+		return nil, nil, fmt.Errorf("max %s is below min %s", defHi, defLo)
+	}
+	return lo, hi, nil
+}
+
+// parseValue parses s as a value of type dt, as drawn by column.draw.
+func parseValue(dt arrow.DataType, s string) (any, error) {
+	var v any
+	var err error
+	switch dt := dt.(type) {
+	case *arrow.BooleanType:
+		v, err = strconv.ParseBool(s)
+	case *arrow.StringType:
+		v = s
+	case *arrow.Float32Type, *arrow.Float64Type:
+		v, err = strconv.ParseFloat(s, 64)
+	case *arrow.Date32Type:
+		var t time.Time
+		if t, err = time.Parse(time.DateOnly, s); err == nil {
+			v = int64(arrow.Date32FromTime(t))
+		}
+	case *arrow.TimestampType:
+		var t time.Time
+		if t, err = time.Parse(time.RFC3339Nano, s); err == nil {
+			var ts arrow.Timestamp
+			ts, err = arrow.TimestampFromTime(t, dt.Unit)
+			v = int64(ts)
+		}
+	default:
+		var n int64
+		if n, err = strconv.ParseInt(s, 10, 64); err == nil {
+			err = checkRange(dt, n)
+		}
+		v = n
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q", dt, s)
+	}
+	return v, nil
+}
+
+// checkRange fails if n does not fit in the integer type dt.
+func checkRange(dt arrow.DataType, n int64) error {
+	var lo, hi int64
+	switch dt.ID() {
+	case arrow.INT8:
+		lo, hi = math.MinInt8, math.MaxInt8
+	case arrow.INT16:
+		lo, hi = math.MinInt16, math.MaxInt16
+	case arrow.INT32:
+		lo, hi = math.MinInt32, math.MaxInt32
+	case arrow.UINT8:
+		hi = math.MaxUint8
+	case arrow.UINT16:
+		hi = math.MaxUint16
+	case arrow.UINT32:
+		hi = math.MaxUint32
+	case arrow.UINT64:
+		hi = math.MaxInt64
+	default:
+		return nil
+	}
+	if n < lo || n > hi {
+		return fmt.Errorf("out of range")
+	}
+	return nil
+}
+
+func isFloat(dt arrow.DataType) bool {
+	return dt.ID() == arrow.FLOAT32 || dt.ID() == arrow.FLOAT64
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyz"
+
+func randomString(rng *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[rng.IntN(len(letters))]
+	}
+	return string(b)
+}
+
+// append appends the value of row to b, or a null.
+func (c *column) append(b array.Builder, row int64) {
+	if c.nullRate > 0 && c.rng.Float64() < c.nullRate {
+		b.AppendNull()
+		return
+	}
+	switch v := c.draw(row).(type) {
+	case bool:
+		b.(*array.BooleanBuilder).Append(v)
+	case string:
+		b.(*array.StringBuilder).Append(v)
+	case float64:
+		switch b := b.(type) {
+		case *array.Float32Builder:
+			b.Append(float32(v))
+		case *array.Float64Builder:
+			b.Append(v)
+		}
+	case int64:
+		switch b := b.(type) {
+		case *array.Int8Builder:
+			b.Append(int8(v))
+		case *array.Int16Builder:
+			b.Append(int16(v))
+		case *array.Int32Builder:
+			b.Append(int32(v))
+		case *array.Int64Builder:
+			b.Append(v)
+		case *array.Uint8Builder:
+			b.Append(uint8(v))
+		case *array.Uint16Builder:
+			b.Append(uint16(v))
+		case *array.Uint32Builder:
+			b.Append(uint32(v))
+		case *array.Uint64Builder:
+			b.Append(uint64(v))
+		case *array.Date32Builder:
+			b.Append(arrow.Date32(v))
+		case *array.TimestampBuilder:
+			b.Append(arrow.Timestamp(v))
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package datagen
+
+import (
+	"context"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+const testSpec = `
+rows: 2500
+seed: 7
+batch_size: 1000
+columns:
+  - name: id
+    type: int64
+    distribution: sequence
+    min: 1
+  - name: score
+    type: float64
+    min: 10
+    max: 20
+    null_rate: 0.1
+  - name: country
+    type: string
+    distribution: categorical
+    values: [fr, de, us]
+    weights: [1, 1, 8]
+  - name: product
+    type: int32
+    distribution: zipf
+    min: 100
+    max: 199
+    skew: 2
+  - name: created
+    type: timestamp[ms, tz=UTC]
+    min: 2024-01-01T00:00:00Z
+    max: 2024-12-31T00:00:00Z
+  - name: label
+    type: string
+    min: 3
+    max: 5
+`
+
+// generate returns the records of spec.
+func generate(t *testing.T, spec *Spec) []arrow.Record {
+	t.Helper()
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	g, err := NewGenerator(spec, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []arrow.Record
+	for {
+		rec, err := g.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+	t.Cleanup(func() {
+		for _, rec := range records {
+			rec.Release()
+		}
+		mem.AssertSize(t, 0)
+	})
+	return records
+}
+
+func TestGenerateFromSpec(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := generate(t, spec)
+	if len(records) != 3 || records[2].NumRows() != 500 {
+		t.Fatalf("got %d records, the last of %d rows", len(records), records[len(records)-1].NumRows())
+	}
+	schema := records[0].Schema()
+	if got := schema.Field(4).Type.String(); got != "timestamp[ms, tz=UTC]" {
+		t.Errorf("created type = %s", got)
+	}
+	if !schema.Field(1).Nullable || schema.Field(0).Nullable {
+		t.Errorf("nullability: %v", schema)
+	}
+
+	var nulls, us, lowRanks int
+	next := int64(1)
+	for _, rec := range records {
+		for _, id := range rec.Column(0).(*array.Int64).Int64Values() {
+			if id != next {
+				t.Fatalf("id %d, want %d", id, next)
+			}
+			next++
+		}
+		scores := rec.Column(1).(*array.Float64)
+		nulls += scores.NullN()
+		for i := 0; i < scores.Len(); i++ {
+			if scores.IsValid(i) && (scores.Value(i) < 10 || scores.Value(i) > 20) {
+				t.Fatalf("score %v out of range", scores.Value(i))
+			}
+		}
+		countries := rec.Column(2).(*array.String)
+		for i := 0; i < countries.Len(); i++ {
+			if countries.Value(i) == "us" {
+				us++
+			}
+		}
+		for _, p := range rec.Column(3).(*array.Int32).Int32Values() {
+			if p < 100 || p > 199 {
+				t.Fatalf("product %d out of range", p)
+			}
+			if p <= 101 {
+				lowRanks++
+			}
+		}
+		labels := rec.Column(5).(*array.String)
+		for i := 0; i < labels.Len(); i++ {
+			if n := len(labels.Value(i)); n < 3 || n > 5 {
+				t.Fatalf("label %q has the wrong length", labels.Value(i))
+			}
+		}
+	}
+	if math.Abs(float64(nulls)/2500-0.1) > 0.03 {
+		t.Errorf("%d null scores, want about 250", nulls)
+	}
+	if math.Abs(float64(us)/2500-0.8) > 0.05 {
+		t.Errorf("%d us rows, want about 2000", us)
+	}
+	// With a skew of 2, the first two ranks hold about three quarters of
+	// the values.
+	if lowRanks < 1700 {
+		t.Errorf("%d products among the first two, want about 1900", lowRanks)
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := func(spec *Spec, col string) []string {
+		var out []string
+		for _, rec := range generate(t, spec) {
+			arr := rec.Column(rec.Schema().FieldIndices(col)[0])
+			for i := 0; i < arr.Len(); i++ {
+				out = append(out, arr.ValueStr(i))
+			}
+		}
+		return out
+	}
+	first := values(spec, "score")
+	if !reflect.DeepEqual(first, values(spec, "score")) {
+		t.Error("the same seed generated different values")
+	}
+	// Columns draw from their own streams.
+	extended := *spec
+	extended.Columns = append(append([]Column{}, spec.Columns...), Column{Name: "extra", Type: "bool"})
+	if !reflect.DeepEqual(first, values(&extended, "score")) {
+		t.Error("adding a column changed the values of another")
+	}
+	spec.Seed++
+	if reflect.DeepEqual(first, values(spec, "score")) {
+		t.Error("another seed generated the same values")
+	}
+}
+
+func TestSpecFromSchema(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "flag", Type: arrow.FixedWidthTypes.Boolean},
+		{Name: "day", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+		{Name: "at", Type: arrow.FixedWidthTypes.Timestamp_ns},
+		{Name: "small", Type: arrow.PrimitiveTypes.Uint8},
+	}, nil)
+	records := generate(t, SpecFromSchema(schema, 10))
+	if !records[0].Schema().Equal(schema) {
+		t.Errorf("schema = %v, want %v", records[0].Schema(), schema)
+	}
+	if records[0].NumRows() != 10 {
+		t.Errorf("%d rows", records[0].NumRows())
+	}
+}
+
+func TestSpecErrors(t *testing.T) {
+	for _, test := range []struct {
+		column Column
+		err    string
+	}{
+		{Column{Name: "a", Type: "decimal"}, `unsupported type "decimal"`},
+		{Column{Name: "a", Type: "int8", Max: "300"}, `invalid int8 value "300"`},
+		{Column{Name: "a", Type: "int64", Min: "5", Max: "1"}, "max 1 is below min 5"},
+		{Column{Name: "a", Type: "string", Distribution: Categorical}, "categorical columns need values"},
+		{Column{Name: "a", Type: "string", Distribution: Zipf}, "zipf utf8 columns need values"},
+		{Column{Name: "a", Type: "int64", Distribution: Zipf, Skew: 0.5}, "zipf skew must be above 1"},
+		{Column{Name: "a", Type: "string", Values: []string{"x"}, Weights: []float64{1, 2}, Distribution: Categorical}, "2 weights for 1 values"},
+		{Column{Name: "a", Type: "int64", NullRate: 2}, "null rate must be between 0 and 1"},
+		{Column{Name: "a", Type: "int64", Distribution: "normal"}, `unknown distribution "normal"`},
+	} {
+		_, err := NewGenerator(&Spec{Rows: 1, Columns: []Column{test.column}}, nil)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%+v: error %v, want %q", test.column, err, test.err)
+		}
+	}
+}
+
+// collectWriter keeps the number of rows written.
+type collectWriter struct{ rows int64 }
+
+func (w *collectWriter) Write(rec arrow.Record) error {
+	w.rows += rec.NumRows()
+	return nil
+}
+
+func (w *collectWriter) Close() error { return nil }
+
+func TestGenerateToWriter(t *testing.T) {
+	w := &collectWriter{}
+	spec := &Spec{Rows: 3000, Columns: []Column{{Name: "id", Type: "int64"}}}
+	if err := Generate(context.Background(), spec, w); err != nil {
+		t.Fatal(err)
+	}
+	if w.rows != 3000 {
+		t.Errorf("wrote %d rows", w.rows)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Generate(ctx, spec, w); err != context.Canceled {
+		t.Errorf("canceled: %v", err)
+	}
+}
@@ -0,0 +1,522 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package debezium decodes Debezium change events, such as the values of a
+// Kafka topic written by a Debezium connector, into flat rows of the table
+// they change: the after image of creates, updates and snapshot reads and
+// the before image of deletes, alongside envelope fields such as the
+// operation and its time.
+package debezium
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+)
+
+const (
+	// DefaultColumn is the column holding the events, as a Kafka source
+	// names message values.
+	DefaultColumn = "value"
+	// DefaultPrefix starts the names of envelope columns, so that they do
+	// not collide with the columns of the table.
+	DefaultPrefix = "__"
+)
+
+// Envelope fields Options.Fields adds as columns. Any field of the source
+// block can be added too, as source.<name>, such as source.table or
+// source.lsn.
+const (
+	FieldOp        = "op"
+	FieldTimestamp = "ts_ms"
+	FieldDeleted   = "deleted"
+)
+
+// Operations of change events.
+const (
+	OpCreate   = "c"
+	OpUpdate   = "u"
+	OpDelete   = "d"
+	OpRead     = "r"
+	OpTruncate = "t"
+)
+
+// Handling of delete events.
+const (
+	// DeletesRewrite makes a row of the before image of a deleted row.
+	DeletesRewrite = "rewrite"
+	// DeletesDrop skips delete events.
+	DeletesDrop = "drop"
+)
+
+// Count keys reported by Transformer.Counts.
+const (
+	CountEvents         = "events"
+	CountRows           = "rows"
+	CountTombstones     = "tombstones"
+	CountDeletesDropped = "deletes_dropped"
+	CountTruncates      = "truncates"
+	CountSchemaChanges  = "schema_changes"
+	CountColumnsDropped = "columns_dropped"
+)
+
+// Options configures a Transformer.
+type Options struct {
+	// Column names the string or binary column holding the JSON events,
+	// with or without the schema and payload envelope of the JSON
+	// converter. Defaults to DefaultColumn.
+	Column string
+	// Schema holds the columns of the table. When nil it is inferred from
+	// the rows of the first record holding any, and kept from then on.
+	// Columns of later events missing from it, such as columns added
+	// upstream, are dropped with a warning.
+	Schema *arrow.Schema
+	// Fields lists the envelope fields added as columns after those of
+	// the table, named by Prefix and the field with dots replaced by
+	// underscores, such as __source_table. Nil adds op and ts_ms; an empty
+	// slice adds none.
+	Fields []string
+	// Prefix starts the names of envelope columns. Defaults to
+	// DefaultPrefix.
+	Prefix string
+	// Deletes is DeletesRewrite, the default, or DeletesDrop.
+	Deletes string
+	// OnSchemaChange is called with the events of a schema history or
+	// schema change topic, which hold no rows and are otherwise only
+	// logged and counted.
+	OnSchemaChange func(SchemaChange)
+}
+
+// SchemaChange is a schema change event.
+type SchemaChange struct {
+	Database string
+	// DDL is the statement that changed the schema, when the connector
+	// includes it.
+	DDL string
+	// Tables are the identifiers of the tables changed.
+	Tables []string
+	Time   time.Time
+}
+
+// Transformer is a pipeline Transformer turning records of Debezium events
+// into records of rows. Tombstones, the null values following deletes, and
+// truncate events make no rows. Records making no rows are dropped. A
+// Transformer is not safe for concurrent use.
+type Transformer struct {
+	opts    Options
+	schema  *arrow.Schema
+	out     *arrow.Schema
+	dropped map[string]bool
+	logger  *slog.Logger
+	mem     memory.Allocator
+
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// New creates a Transformer. opts may be nil for the defaults. Warnings
+// and schema changes are logged to the logger of ctx.
+func New(ctx context.Context, opts *Options) (*Transformer, error) {
+	t := &Transformer{
+		dropped: make(map[string]bool),
+		logger:  logging.FromContext(ctx),
+		mem:     memory.DefaultAllocator,
+		counts: map[string]int64{
+			CountEvents: 0, CountRows: 0, CountTombstones: 0, CountDeletesDropped: 0,
+			CountTruncates: 0, CountSchemaChanges: 0, CountColumnsDropped: 0,
+		},
+	}
+	if opts != nil {
+		t.opts = *opts
+	}
+	if t.opts.Column == "" {
+		t.opts.Column = DefaultColumn
+	}
+	if t.opts.Prefix == "" {
+		t.opts.Prefix = DefaultPrefix
+	}
+	if t.opts.Fields == nil {
+		t.opts.Fields = []string{FieldOp, FieldTimestamp}
+	}
+	switch t.opts.Deletes {
+	case "":
+		t.opts.Deletes = DeletesRewrite
+	case DeletesRewrite, DeletesDrop:
+	default:
+		return nil, fmt.Errorf("unknown delete handling %q", t.opts.Deletes)
+	}
+	seen := make(map[string]bool, len(t.opts.Fields))
+	for _, field := range t.opts.Fields {
+		switch {
+		case field == FieldOp, field == FieldTimestamp, field == FieldDeleted:
+		case strings.HasPrefix(field, "source.") && len(field) > len("source."):
+		default:
+			return nil, fmt.Errorf("unknown envelope field %q", field)
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("envelope field %q listed twice", field)
+		}
+		seen[field] = true
+	}
+	if t.opts.Schema != nil {
+		if err := t.setSchema(t.opts.Schema); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Name names the transformer in pipeline reports.
+func (t *Transformer) Name() string {
+	return "debezium"
+}
+
+// Counts returns the number of events and rows, of the events making no
+// rows by kind, and of the columns dropped for missing from the schema.
+func (t *Transformer) Counts() map[string]int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.counts)
+}
+
+// count adds n to the counter key.
+func (t *Transformer) count(key string, n int64) {
+	t.mu.Lock()
+	t.counts[key] += n
+	t.mu.Unlock()
+}
+
+// columnName returns the name of the column of an envelope field.
+func (t *Transformer) columnName(field string) string {
+	return t.opts.Prefix + strings.ReplaceAll(field, ".", "_")
+}
+
+// setSchema sets the schema of the table and derives the output schema.
+func (t *Transformer) setSchema(schema *arrow.Schema) error {
+	fields := slices.Clone(schema.Fields())
+	for _, field := range t.opts.Fields {
+		name := t.columnName(field)
+		if schema.HasField(name) {
+			return fmt.Errorf("envelope column %q collides with a column of the table", name)
+		}
+		var dt arrow.DataType = arrow.BinaryTypes.String
+		switch field {
+		case FieldTimestamp:
+			dt = arrow.FixedWidthTypes.Timestamp_ms
+		case FieldDeleted:
+			dt = arrow.FixedWidthTypes.Boolean
+		}
+		fields = append(fields, arrow.Field{Name: name, Type: dt, Nullable: true})
+	}
+	t.schema = schema
+	t.out = arrow.NewSchema(fields, nil)
+	return nil
+}
+
+// Schema returns the schema of the records Transform produces, which is
+// known up front only when Options.Schema is set.
+func (t *Transformer) Schema(*arrow.Schema) (*arrow.Schema, error) {
+	if t.out == nil {
+		return nil, fmt.Errorf("the schema is inferred from the first events")
+	}
+	return t.out, nil
+}
+
+// event is the payload of a change event.
+type event struct {
+	Op     string                     `json:"op"`
+	Before json.RawMessage            `json:"before"`
+	After  json.RawMessage            `json:"after"`
+	Source map[string]json.RawMessage `json:"source"`
+	TsMs   json.RawMessage            `json:"ts_ms"`
+	// Schema change events
+	DDL          string `json:"ddl"`
+	DatabaseName string `json:"databaseName"`
+	TableChanges []struct {
+		ID string `json:"id"`
+	} `json:"tableChanges"`
+}
+
+// isSchemaChange reports whether e is a schema change event.
+func (e *event) isSchemaChange(raw map[string]json.RawMessage) bool {
+	_, ddl := raw["ddl"]
+	_, changes := raw["tableChanges"]
+	return e.Op == "" && (ddl || changes)
+}
+
+// row is the image of one row and the event it comes from.
+type row struct {
+	image map[string]json.RawMessage
+	event *event
+}
+
+// Transform decodes the events of record into rows.
+func (t *Transformer) Transform(record arrow.Record) (arrow.Record, error) {
+	idx := record.Schema().FieldIndices(t.opts.Column)
+	if len(idx) == 0 {
+		return nil, fmt.Errorf("no column %q holding Debezium events", t.opts.Column)
+	}
+	values, err := messages(record.Column(idx[0]))
+	if err != nil {
+		return nil, fmt.Errorf("column %q: %w", t.opts.Column, err)
+	}
+
+	var rows []row
+	for i, value := range values {
+		r, ok, err := t.decode(value)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		if ok {
+			rows = append(rows, r)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	if t.schema == nil {
+		if err := t.inferSchema(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	objects := make([][]byte, len(rows))
+	for i, r := range rows {
+		if objects[i], err = t.object(r); err != nil {
+			return nil, err
+		}
+	}
+	out, err := integrations.DecodeJSONObjects(objects, t.out, t.mem)
+	if err != nil {
+		return nil, err
+	}
+	t.count(CountRows, int64(len(rows)))
+	return out, nil
+}
+
+// messages returns the values of col, nil for nulls.
+func messages(col arrow.Array) ([][]byte, error) {
+	if ext, ok := col.(array.ExtensionArray); ok {
+		col = ext.Storage()
+	}
+	values := make([][]byte, col.Len())
+	for i := range values {
+		if col.IsNull(i) {
+			continue
+		}
+		switch col := col.(type) {
+		case *array.String:
+			values[i] = []byte(col.Value(i))
+		case *array.LargeString:
+			values[i] = []byte(col.Value(i))
+		case *array.Binary:
+			values[i] = col.Value(i)
+		case *array.LargeBinary:
+			values[i] = col.Value(i)
+		default:
+			return nil, fmt.Errorf("type %s cannot hold JSON events", col.DataType())
+		}
+	}
+	return values, nil
+}
+
+// decode returns the row of the event in value, or false if it makes
+// none.
+func (t *Transformer) decode(value []byte) (row, bool, error) {
+	var raw map[string]json.RawMessage
+	if len(value) == 0 || string(value) == "null" {
+		t.count(CountTombstones, 1)
+		return row{}, false, nil
+	}
+	if err := json.Unmarshal(value, &raw); err != nil {
+		return row{}, false, fmt.Errorf("invalid Debezium event: %w", err)
+	}
+	// The JSON converter with schemas enabled wraps events in an
+	// envelope of their schema and payload.
+	if payload, ok := raw["payload"]; ok {
+		if _, ok := raw["schema"]; ok || len(raw) == 1 {
+			if string(payload) == "null" {
+				t.count(CountTombstones, 1)
+				return row{}, false, nil
+			}
+			raw = nil
+			if err := json.Unmarshal(payload, &raw); err != nil {
+				return row{}, false, fmt.Errorf("invalid Debezium payload: %w", err)
+			}
+		}
+	}
+	var e event
+	if err := remarshal(raw, &e); err != nil {
+		return row{}, false, fmt.Errorf("invalid Debezium event: %w", err)
+	}
+	t.count(CountEvents, 1)
+
+	if e.isSchemaChange(raw) {
+		t.schemaChange(&e)
+		return row{}, false, nil
+	}
+	var image json.RawMessage
+	switch e.Op {
+	case OpCreate, OpUpdate, OpRead:
+		image = e.After
+	case OpDelete:
+		if t.opts.Deletes == DeletesDrop {
+			t.count(CountDeletesDropped, 1)
+			return row{}, false, nil
+		}
+		image = e.Before
+	case OpTruncate:
+		t.count(CountTruncates, 1)
+		t.logger.Info("skipped Debezium truncate event", "table", sourceText(e.Source["table"]))
+		return row{}, false, nil
+	case "":
+		return row{}, false, fmt.Errorf("not a Debezium change event: no op")
+	default:
+		return row{}, false, fmt.Errorf("unknown Debezium op %q", e.Op)
+	}
+	r := row{event: &e, image: map[string]json.RawMessage{}}
+	if len(image) > 0 && string(image) != "null" {
+		if err := json.Unmarshal(image, &r.image); err != nil {
+			return row{}, false, fmt.Errorf("invalid row image of op %q: %w", e.Op, err)
+		}
+	}
+	return r, true, nil
+}
+
+// remarshal decodes the fields in raw into v.
+func remarshal(raw map[string]json.RawMessage, v any) error {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// schemaChange reports a schema change event.
+func (t *Transformer) schemaChange(e *event) {
+	change := SchemaChange{Database: e.DatabaseName, DDL: e.DDL}
+	for _, table := range e.TableChanges {
+		change.Tables = append(change.Tables, table.ID)
+	}
+	ts := e.TsMs
+	if len(ts) == 0 {
+		ts = e.Source["ts_ms"]
+	}
+	var ms int64
+	if json.Unmarshal(ts, &ms) == nil && ms > 0 {
+		change.Time = time.UnixMilli(ms).UTC()
+	}
+	t.count(CountSchemaChanges, 1)
+	t.logger.Info("Debezium schema change", "database", change.Database, "tables", change.Tables, "ddl", change.DDL)
+	if t.opts.OnSchemaChange != nil {
+		t.opts.OnSchemaChange(change)
+	}
+}
+
+// inferSchema infers the schema of the table from rows.
+func (t *Transformer) inferSchema(rows []row) error {
+	samples := make([]map[string]interface{}, len(rows))
+	for i, r := range rows {
+		if err := remarshal(r.image, &samples[i]); err != nil {
+			return err
+		}
+	}
+	schema, err := integrations.InferJSONSchema(samples)
+	if err != nil {
+		return err
+	}
+	t.logger.Debug("inferred Debezium row schema", "fields", schema.NumFields())
+	return t.setSchema(schema)
+}
+
+// object returns the JSON object of the output row of r.
+func (t *Transformer) object(r row) ([]byte, error) {
+	obj := make(map[string]json.RawMessage, len(r.image)+len(t.opts.Fields))
+	for name, value := range r.image {
+		if !t.schema.HasField(name) {
+			if !t.dropped[name] {
+				t.dropped[name] = true
+				t.count(CountColumnsDropped, 1)
+				t.logger.Warn("dropped Debezium column missing from the schema", "column", name, "table", sourceText(r.event.Source["table"]))
+			}
+			continue
+		}
+		obj[name] = value
+	}
+	for _, field := range t.opts.Fields {
+		var value json.RawMessage
+		switch field {
+		case FieldOp:
+			value, _ = json.Marshal(r.event.Op)
+		case FieldTimestamp:
+			value = r.event.TsMs
+		case FieldDeleted:
+			value, _ = json.Marshal(r.event.Op == OpDelete)
+		default:
+			if text, ok := sourceValue(r.event.Source[strings.TrimPrefix(field, "source.")]); ok {
+				value, _ = json.Marshal(text)
+			}
+		}
+		if len(value) > 0 {
+			obj[t.columnName(field)] = value
+		}
+	}
+	return json.Marshal(obj)
+}
+
+// sourceValue returns a field of the source block as text: strings as is,
+// other values as JSON, and false for null or missing fields.
+func sourceValue(raw json.RawMessage) (string, bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", false
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s, true
+	}
+	return string(raw), true
+}
+
+// sourceText returns a field of the source block as text, or "".
+func sourceText(raw json.RawMessage) string {
+	s, _ := sourceValue(raw)
+	return s
+}
@@ -0,0 +1,203 @@
+package debezium
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// events makes a record of a nullable string column, value, holding msgs;
+// an empty message is null.
+func events(t *testing.T, msgs ...string) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{{Name: DefaultColumn, Type: arrow.BinaryTypes.String, Nullable: true}}, nil)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	for _, msg := range msgs {
+		if msg == "" {
+			b.Field(0).AppendNull()
+		} else {
+			b.Field(0).(*array.StringBuilder).Append(msg)
+		}
+	}
+	return b.NewRecord()
+}
+
+// column returns the values of the column name of rec as strings, "null"
+// for nulls.
+func column(t *testing.T, rec arrow.Record, name string) []string {
+	t.Helper()
+	idx := rec.Schema().FieldIndices(name)
+	if len(idx) == 0 {
+		t.Fatalf("no column %q in %v", name, rec.Schema())
+	}
+	col := rec.Column(idx[0])
+	values := make([]string, col.Len())
+	for i := range values {
+		values[i] = col.ValueStr(i)
+		if col.IsNull(i) {
+			values[i] = "null"
+		}
+	}
+	return values
+}
+
+const (
+	snapshot = `{"before":null,"after":{"id":1,"name":"ada"},"source":{"db":"inventory","table":"customers","lsn":101},"op":"r","ts_ms":1700000000000}`
+	create   = `{"schema":{"type":"struct"},"payload":{"before":null,"after":{"id":2,"name":"bob"},"source":{"table":"customers","lsn":102},"op":"c","ts_ms":1700000001000}}`
+	update   = `{"before":{"id":2,"name":"bob"},"after":{"id":2,"name":"rob"},"source":{"table":"customers"},"op":"u","ts_ms":1700000002000}`
+	remove   = `{"before":{"id":1,"name":"ada"},"after":null,"source":{"table":"customers"},"op":"d","ts_ms":1700000003000}`
+	truncate = `{"source":{"table":"customers"},"op":"t","ts_ms":1700000004000}`
+	alter    = `{"source":{"db":"inventory","ts_ms":1700000005000},"databaseName":"inventory","ddl":"ALTER TABLE customers ADD COLUMN email VARCHAR(255)","tableChanges":[{"type":"ALTER","id":"\"inventory\".\"customers\""}]}`
+	added    = `{"before":null,"after":{"id":3,"name":"cy","email":"cy@example.com"},"source":{"table":"customers"},"op":"c","ts_ms":1700000006000}`
+)
+
+func TestTransformEvents(t *testing.T) {
+	var changes []SchemaChange
+	tr, err := New(context.Background(), &Options{
+		Fields:         []string{FieldOp, FieldTimestamp, FieldDeleted, "source.table", "source.lsn"},
+		OnSchemaChange: func(c SchemaChange) { changes = append(changes, c) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.Schema(nil); err == nil {
+		t.Error("Schema should fail before the schema is inferred")
+	}
+
+	rec := events(t, snapshot, create, update, remove, "", `{"schema":{},"payload":null}`, truncate, alter)
+	defer rec.Release()
+	out, err := tr.Transform(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+
+	want := map[string][]string{
+		"id":             {"1", "2", "2", "1"},
+		"name":           {"ada", "bob", "rob", "ada"},
+		"__op":           {"r", "c", "u", "d"},
+		"__deleted":      {"false", "false", "false", "true"},
+		"__source_table": {"customers", "customers", "customers", "customers"},
+		"__source_lsn":   {"101", "102", "null", "null"},
+		"__ts_ms":        {"2023-11-14 22:13:20Z", "2023-11-14 22:13:21Z", "2023-11-14 22:13:22Z", "2023-11-14 22:13:23Z"},
+	}
+	for name, values := range want {
+		if got := column(t, out, name); !reflect.DeepEqual(got, values) {
+			t.Errorf("%s = %v, want %v", name, got, values)
+		}
+	}
+	if schema, err := tr.Schema(nil); err != nil || !schema.Equal(out.Schema()) {
+		t.Errorf("Schema() = %v, %v, want %v", schema, err, out.Schema())
+	}
+
+	if len(changes) != 1 || changes[0].Database != "inventory" || !strings.HasPrefix(changes[0].DDL, "ALTER TABLE") ||
+		len(changes[0].Tables) != 1 || changes[0].Time.UnixMilli() != 1700000005000 {
+		t.Errorf("schema changes = %+v", changes)
+	}
+
+	// Columns added upstream are dropped, keeping the schema.
+	next := events(t, added)
+	defer next.Release()
+	out2, err := tr.Transform(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out2.Release()
+	if !out2.Schema().Equal(out.Schema()) || column(t, out2, "name")[0] != "cy" {
+		t.Errorf("after the schema change got %v", out2)
+	}
+
+	counts := tr.Counts()
+	wantCounts := map[string]int64{
+		CountEvents: 7, CountRows: 5, CountTombstones: 2, CountDeletesDropped: 0,
+		CountTruncates: 1, CountSchemaChanges: 1, CountColumnsDropped: 1,
+	}
+	if !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("counts = %v, want %v", counts, wantCounts)
+	}
+}
+
+func TestTransformPinnedSchema(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "email", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	tr, err := New(context.Background(), &Options{Schema: schema, Fields: []string{}, Deletes: DeletesDrop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := tr.Schema(nil); err != nil || !got.Equal(schema) {
+		t.Errorf("Schema() = %v, %v, want %v", got, err, schema)
+	}
+
+	rec := events(t, remove, added, update)
+	defer rec.Release()
+	out, err := tr.Transform(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	if got := column(t, out, "id"); !reflect.DeepEqual(got, []string{"3", "2"}) {
+		t.Errorf("id = %v", got)
+	}
+	if got := column(t, out, "email"); !reflect.DeepEqual(got, []string{"cy@example.com", "null"}) {
+		t.Errorf("email = %v", got)
+	}
+	if counts := tr.Counts(); counts[CountDeletesDropped] != 1 || counts[CountColumnsDropped] != 1 {
+		t.Errorf("counts = %v", counts)
+	}
+
+	// A record of tombstones makes no rows and is dropped.
+	empty := events(t, "", "")
+	defer empty.Release()
+	if out, err := tr.Transform(empty); err != nil || out != nil {
+		t.Errorf("Transform(tombstones) = %v, %v, want nil", out, err)
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	for _, opts := range []*Options{
+		{Deletes: "ignore"},
+		{Fields: []string{"source."}},
+		{Fields: []string{"op", "op"}},
+		{Fields: []string{"after"}},
+		{Schema: arrow.NewSchema([]arrow.Field{{Name: "__op", Type: arrow.BinaryTypes.String}}, nil)},
+	} {
+		if _, err := New(context.Background(), opts); err == nil {
+			t.Errorf("New(%+v) should fail", opts)
+		}
+	}
+
+	for _, msg := range []string{
+		`not json`,
+		`{"after":{"id":1}}`,
+		`{"op":"x","after":{"id":1}}`,
+		`{"op":"c","after":[1]}`,
+	} {
+		tr, err := New(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := events(t, msg)
+		if _, err := tr.Transform(rec); err == nil {
+			t.Errorf("Transform(%s) should fail", msg)
+		}
+		rec.Release()
+	}
+
+	tr, err := New(context.Background(), &Options{Column: "payload"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := events(t, create)
+	defer rec.Release()
+	if _, err := tr.Transform(rec); err == nil || !strings.Contains(err.Error(), `no column "payload"`) {
+		t.Errorf("Transform without the column = %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package dedupe drops rows whose key columns repeat a key already seen in
+// the stream, within a bounded memory window that can spill to disk.
+package dedupe
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// DefaultMaxKeys is the number of keys kept in memory when Options.MaxKeys
+// is not set.
+const DefaultMaxKeys = 1_000_000
+
+// Count keys reported by Deduplicator.Counts.
+const (
+	CountPassed     = "rows_passed"
+	CountDuplicates = "rows_duplicate"
+	CountSpilled    = "keys_spilled"
+)
+
+// Options controls the memory window of a Deduplicator.
+type Options struct {
+	// MaxKeys bounds the number of keys held in memory. Defaults to
+	// DefaultMaxKeys.
+	MaxKeys int
+	// SpillDir, if set, is where keys are spilled to sorted run files once
+	// MaxKeys is reached, so duplicates are detected across the whole
+	// stream. If empty, the oldest keys are forgotten instead and
+	// duplicates further apart than MaxKeys distinct keys pass through.
+	SpillDir string
+}
+
+// key is a 128-bit hash of the key column values of one row.
+type key [16]byte
+
+// Deduplicator is a pipeline Transformer that keeps the first row for each
+// distinct combination of key column values.
+type Deduplicator struct {
+	columns []string
+	maxKeys int
+
+	seen  map[key]struct{}
+	order []key // insertion order of seen, used for eviction
+	next  int   // oldest entry in order once it is full
+	spill *spillStore
+
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// New creates a Deduplicator keyed on columns.
+func New(columns []string, opts *Options) (*Deduplicator, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("at least one key column is required")
+	}
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxKeys < 0 {
+		return nil, fmt.Errorf("max keys cannot be negative")
+	}
+	if o.MaxKeys == 0 {
+		o.MaxKeys = DefaultMaxKeys
+	}
+
+	d := &Deduplicator{
+		columns: columns,
+		maxKeys: o.MaxKeys,
+		seen:    make(map[key]struct{}),
+		counts:  map[string]int64{CountPassed: 0, CountDuplicates: 0},
+	}
+	if o.SpillDir != "" {
+		spill, err := newSpillStore(o.SpillDir)
+		if err != nil {
+			return nil, err
+		}
+		d.spill = spill
+		d.counts[CountSpilled] = 0
+	}
+	return d, nil
+}
+
+// Transform returns the rows of record whose key has not been seen before.
+func (d *Deduplicator) Transform(record arrow.Record) (arrow.Record, error) {
+	cols := make([]arrow.Array, len(d.columns))
+	for i, name := range d.columns {
+		indices := record.Schema().FieldIndices(name)
+		if len(indices) == 0 {
+			return nil, fmt.Errorf("key column %q not found", name)
+		}
+		cols[i] = record.Column(indices[0])
+	}
+
+	n := int(record.NumRows())
+	keep := make([]bool, n)
+	duplicates := 0
+	h := fnv.New128a()
+	var buf []byte
+	for row := 0; row < n; row++ {
+		h.Reset()
+		for _, col := range cols {
+			buf = appendValue(buf[:0], col, row)
+			h.Write(buf)
+		}
+		var k key
+		h.Sum(k[:0])
+
+		dup, err := d.contains(k)
+		if err != nil {
+			return nil, err
+		}
+		if dup {
+			duplicates++
+			continue
+		}
+		keep[row] = true
+		if err := d.add(k); err != nil {
+			return nil, err
+		}
+	}
+
+	d.mu.Lock()
+	d.counts[CountPassed] += int64(n - duplicates)
+	d.counts[CountDuplicates] += int64(duplicates)
+	d.mu.Unlock()
+
+	if duplicates == 0 {
+		record.Retain()
+		return record, nil
+	}
+
+	b := array.NewBooleanBuilder(memory.DefaultAllocator)
+	defer b.Release()
+	b.AppendValues(keep, nil)
+	selection := b.NewBooleanArray()
+	defer selection.Release()
+	return compute.FilterRecordBatch(context.Background(), record, selection, compute.DefaultFilterOptions())
+}
+
+// appendValue appends an unambiguous encoding of the value at row to buf:
+// a null marker, or a length-prefixed string form of the value.
+func appendValue(buf []byte, arr arrow.Array, row int) []byte {
+	if arr.IsNull(row) {
+		return append(buf, 0)
+	}
+	var s string
+	switch a := arr.(type) {
+	case *array.String:
+		s = a.Value(row)
+	case *array.LargeString:
+		s = a.Value(row)
+	default:
+		s = arr.ValueStr(row)
+	}
+	buf = append(buf, 1)
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func (d *Deduplicator) contains(k key) (bool, error) {
+	if _, ok := d.seen[k]; ok {
+		return true, nil
+	}
+	if d.spill == nil {
+		return false, nil
+	}
+	return d.spill.contains(k)
+}
+
+// add records k as seen, spilling or evicting keys when the window is full.
+func (d *Deduplicator) add(k key) error {
+	if len(d.seen) >= d.maxKeys {
+		if d.spill != nil {
+			if err := d.spill.write(d.order); err != nil {
+				return fmt.Errorf("failed to spill keys: %w", err)
+			}
+			d.mu.Lock()
+			d.counts[CountSpilled] += int64(len(d.order))
+			d.mu.Unlock()
+			clear(d.seen)
+			d.order = d.order[:0]
+		} else {
+			delete(d.seen, d.order[d.next])
+			d.order[d.next] = k
+			d.next = (d.next + 1) % len(d.order)
+			d.seen[k] = struct{}{}
+			return nil
+		}
+	}
+	d.seen[k] = struct{}{}
+	d.order = append(d.order, k)
+	return nil
+}
+
+// Name identifies the Deduplicator in the pipeline metrics report.
+func (d *Deduplicator) Name() string {
+	return "dedupe"
+}
+
+// Counts returns the number of rows passed and dropped as duplicates, and
+// the number of keys spilled to disk.
+func (d *Deduplicator) Counts() map[string]int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return maps.Clone(d.counts)
+}
+
+// Close removes any spill files.
+func (d *Deduplicator) Close() error {
+	if d.spill == nil {
+		return nil
+	}
+	return d.spill.close()
+}
@@ -0,0 +1,136 @@
+package dedupe
+
+import (
+	"os"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+var testSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	{Name: "region", Type: arrow.BinaryTypes.String, Nullable: true},
+}, nil)
+
+func makeRecord(t *testing.T, ids []int64, regions []string, valid []bool) arrow.Record {
+	t.Helper()
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), testSchema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues(ids, valid)
+	b.Field(1).(*array.StringBuilder).AppendValues(regions, nil)
+	return b.NewRecord()
+}
+
+func transformIDs(t *testing.T, d *Deduplicator, ids []int64) []int64 {
+	t.Helper()
+	regions := make([]string, len(ids))
+	rec := makeRecord(t, ids, regions, nil)
+	defer rec.Release()
+	out, err := d.Transform(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	return append([]int64(nil), out.Column(0).(*array.Int64).Int64Values()...)
+}
+
+func TestDedupeCompositeKey(t *testing.T) {
+	d, err := New([]string{"id", "region"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := makeRecord(t, []int64{1, 1, 2, 0, 0}, []string{"eu", "us", "eu", "eu", "eu"}, []bool{true, true, true, false, false})
+	defer first.Release()
+	out, err := d.Transform(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.NumRows() != 4 {
+		t.Errorf("first batch kept %d rows, want 4", out.NumRows())
+	}
+	out.Release()
+
+	second := makeRecord(t, []int64{1, 3}, []string{"us", "us"}, nil)
+	defer second.Release()
+	out, err = d.Transform(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.NumRows() != 1 || out.Column(0).(*array.Int64).Value(0) != 3 {
+		t.Errorf("second batch = %v, want [3]", out.Column(0))
+	}
+	out.Release()
+
+	counts := d.Counts()
+	if counts[CountPassed] != 5 || counts[CountDuplicates] != 2 {
+		t.Errorf("counts = %v", counts)
+	}
+}
+
+func TestDedupeEvictsOldestKeys(t *testing.T) {
+	d, err := New([]string{"id"}, &Options{MaxKeys: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := transformIDs(t, d, []int64{1, 2, 3, 3}); len(got) != 3 {
+		t.Errorf("got %v, want [1 2 3]", got)
+	}
+	// 1 has been evicted from the window, 3 is still in it.
+	if got := transformIDs(t, d, []int64{1, 3}); len(got) != 1 || got[0] != 1 {
+		t.Errorf("got %v, want [1]", got)
+	}
+}
+
+func TestDedupeSpillsToDisk(t *testing.T) {
+	dir := t.TempDir()
+	d, err := New([]string{"id"}, &Options{MaxKeys: 10, SpillDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]int64, 5000)
+	for i := range ids {
+		ids[i] = int64(i)
+	}
+	if got := transformIDs(t, d, ids); len(got) != len(ids) {
+		t.Fatalf("first pass kept %d rows, want %d", len(got), len(ids))
+	}
+	if got := transformIDs(t, d, ids); len(got) != 0 {
+		t.Fatalf("second pass kept %d rows, want 0", len(got))
+	}
+	if got := transformIDs(t, d, []int64{-1, 4999, 5000}); len(got) != 2 {
+		t.Errorf("got %v, want [-1 5000]", got)
+	}
+	if d.Counts()[CountSpilled] == 0 {
+		t.Error("expected keys to be spilled")
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("spill files left behind: %v", entries)
+	}
+}
+
+func TestDedupeMissingColumn(t *testing.T) {
+	d, err := New([]string{"missing"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := makeRecord(t, []int64{1}, []string{"eu"}, nil)
+	defer rec.Release()
+	if _, err := d.Transform(rec); err == nil {
+		t.Error("expected an error for a missing key column")
+	}
+	if _, err := New(nil, nil); err == nil {
+		t.Error("expected an error without key columns")
+	}
+}
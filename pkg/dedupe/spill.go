@@ -0,0 +1,212 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package dedupe
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+const (
+	// blockKeys is the number of keys between fence pointers in a run, so
+	// a lookup reads at most one block of a run from disk.
+	blockKeys = 1024
+	// maxRuns is the number of runs above which all runs are merged into
+	// one, bounding the disk reads per lookup.
+	maxRuns = 8
+)
+
+// run is a file of sorted, distinct keys.
+type run struct {
+	file   *os.File
+	n      int
+	fences []key // first key of every block
+}
+
+// spillStore holds keys evicted from memory in sorted runs on disk.
+type spillStore struct {
+	dir  string
+	runs []*run
+	seq  int
+	// block is the buffer contains reads a block of a run into.
+	block []byte
+}
+
+func newSpillStore(parent string) (*spillStore, error) {
+	dir, err := os.MkdirTemp(parent, "dedupe-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	return &spillStore{dir: dir, block: make([]byte, blockKeys*len(key{}))}, nil
+}
+
+func compareKeys(a, b key) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// write spills keys as a new run, merging runs if there are too many.
+func (s *spillStore) write(keys []key) error {
+	sorted := slices.Clone(keys)
+	slices.SortFunc(sorted, compareKeys)
+	r, err := s.writeRun(func(yield func(key) error) error {
+		for _, k := range sorted {
+			if err := yield(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, r)
+	if len(s.runs) > maxRuns {
+		return s.merge()
+	}
+	return nil
+}
+
+// writeRun creates a run from the keys produced, in order, by each.
+func (s *spillStore) writeRun(each func(yield func(key) error) error) (*run, error) {
+	s.seq++
+	f, err := os.Create(filepath.Join(s.dir, fmt.Sprintf("run-%06d", s.seq)))
+	if err != nil {
+		return nil, err
+	}
+	r := &run{file: f}
+	w := bufio.NewWriter(f)
+	err = each(func(k key) error {
+		if r.n%blockKeys == 0 {
+			r.fences = append(r.fences, k)
+		}
+		r.n++
+		_, err := w.Write(k[:])
+		return err
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return r, nil
+}
+
+// merge replaces all runs with a single run containing their union.
+func (s *spillStore) merge() error {
+	readers := make([]*bufio.Reader, len(s.runs))
+	heads := make([]key, len(s.runs))
+	live := make([]bool, len(s.runs))
+	for i, r := range s.runs {
+		readers[i] = bufio.NewReader(io.NewSectionReader(r.file, 0, int64(r.n*len(key{}))))
+		live[i] = readKey(readers[i], &heads[i])
+	}
+
+	merged, err := s.writeRun(func(yield func(key) error) error {
+		var last key
+		written := false
+		for {
+			next := -1
+			for i := range heads {
+				if live[i] && (next < 0 || compareKeys(heads[i], heads[next]) < 0) {
+					next = i
+				}
+			}
+			if next < 0 {
+				return nil
+			}
+			k := heads[next]
+			live[next] = readKey(readers[next], &heads[next])
+			if written && last == k {
+				continue
+			}
+			if err := yield(k); err != nil {
+				return err
+			}
+			last, written = k, true
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range s.runs {
+		r.file.Close()
+		os.Remove(r.file.Name())
+	}
+	s.runs = []*run{merged}
+	return nil
+}
+
+// readKey reads the next key from r into k, reporting whether there was one.
+func readKey(r *bufio.Reader, k *key) bool {
+	_, err := io.ReadFull(r, k[:])
+	return err == nil
+}
+
+// contains reports whether any run holds k.
+func (s *spillStore) contains(k key) (bool, error) {
+	for _, r := range s.runs {
+		// Find the last block whose first key is <= k.
+		b := sort.Search(len(r.fences), func(i int) bool { return compareKeys(r.fences[i], k) > 0 }) - 1
+		if b < 0 {
+			continue
+		}
+		count := min(blockKeys, r.n-b*blockKeys)
+		buf := s.block[:count*len(key{})]
+		if _, err := r.file.ReadAt(buf, int64(b*blockKeys*len(key{}))); err != nil {
+			return false, fmt.Errorf("failed to read spilled keys: %w", err)
+		}
+		i := sort.Search(count, func(i int) bool {
+			return bytes.Compare(buf[i*len(key{}):(i+1)*len(key{})], k[:]) >= 0
+		})
+		if i < count && bytes.Equal(buf[i*len(key{}):(i+1)*len(key{})], k[:]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// close removes the spill directory.
+func (s *spillStore) close() error {
+	for _, r := range s.runs {
+		r.file.Close()
+	}
+	s.runs = nil
+	return os.RemoveAll(s.dir)
+}
@@ -0,0 +1,755 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package endpoints opens readers and writers from URIs, so a pipeline can
+// be described by two strings instead of integration-specific constructors:
+//
+//	parquet:///data/orders.parquet    a file in a format named by the scheme
+//	/data/orders.csv                  a file in the format of its extension
+//	https://host/orders.csv           a file served over HTTP, or when
+//	                                  written, an endpoint records are
+//	                                  posted to in batches
+//	grpc://host:port/pkg.Service/Rpc  the responses of an RPC (read only)
+//	duckdb://warehouse.db?table=t     a DuckDB table, or ?query=SQL to read;
+//	                                  merge_keys=id,... merges into it
+//	bq://project/dataset/table        a BigQuery table, or bq://project?query=SQL
+//	flight://host:port/path           a Flight DoPut path, or when read, the
+//	                                  source of a relay of that name, or
+//	                                  ?query=SQL to query a Flight SQL server
+//	gs://bucket/key.csv               a file in GCS
+//	s3://bucket/key.csv               a file in S3
+//
+// A format query parameter, such as ?format=csv, overrides the format of
+// file endpoints. CSV files are read with a header row, as they are
+// written, unless the URI sets header=false.
+//
+// Other modules add schemes of their own with RegisterScheme.
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	bq "cloud.google.com/go/bigquery"
+	"cloud.google.com/go/storage"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/arrowarc/arrowarc/converter"
+	bigquery "github.com/arrowarc/arrowarc/integrations/bigquery"
+	duckdb "github.com/arrowarc/arrowarc/integrations/duckdb"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	flight "github.com/arrowarc/arrowarc/integrations/flight"
+	s3 "github.com/arrowarc/arrowarc/integrations/s3"
+	"github.com/arrowarc/arrowarc/pkg/awsauth"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/gcpauth"
+	"github.com/arrowarc/arrowarc/pkg/merge"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+// Schemes understood by NewReader and NewWriter, besides the file formats.
+const (
+	SchemeFile     = "file"
+	SchemeDuckDB   = "duckdb"
+	SchemeBQ       = "bq"
+	SchemeBigQuery = "bigquery"
+	SchemeFlight   = "flight"
+	SchemeGCS      = "gs"
+	SchemeS3       = "s3"
+)
+
+// formatSchemes maps the schemes naming a file format to that format.
+var formatSchemes = map[string]string{
+	"parquet": integrations.SourceParquet,
+	"csv":     integrations.SourceCSV,
+	"avro":    integrations.SourceAvro,
+	"ipc":     integrations.SourceIPC,
+	"arrow":   integrations.SourceIPC,
+	"feather": integrations.SourceFeather,
+	"json":    converter.FormatNDJSON,
+	"ndjson":  converter.FormatNDJSON,
+}
+
+// Bucket is the part of an object store bucket endpoints use. An
+// objstore.Bucket satisfies it.
+type Bucket interface {
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	Upload(ctx context.Context, name string, r io.Reader) error
+	Close() error
+}
+
+// Options configures NewReader and NewWriter.
+type Options struct {
+	// File configures reading file endpoints. Its FromFormat is replaced by
+	// the format named by the URI, if any.
+	File converter.ConvertOptions
+	// ClientOptions configure the BigQuery and GCS clients.
+	ClientOptions []option.ClientOption
+	// Auth selects the credentials of the BigQuery and GCS clients, ahead
+	// of ClientOptions. Nil uses Application Default Credentials, unless
+	// ClientOptions set others.
+	Auth *gcpauth.Options
+	// AWS selects the credentials and region of s3:// endpoints. Nil uses
+	// the default credentials.
+	AWS *awsauth.Options
+	// DialOptions configure Flight connections, which are insecure by
+	// default.
+	DialOptions []grpc.DialOption
+	// OpenBucket opens the bucket of an object store endpoint, replacing
+	// the GCS client of gs:// and the S3 client of s3:// endpoints.
+	OpenBucket func(ctx context.Context, scheme, bucket string) (Bucket, error)
+	// TempDir holds the local copies of object store files. Defaults to
+	// the system's temporary directory.
+	TempDir string
+	// HTTP configures http:// and https:// endpoints and file inputs
+	// given as URLs, and the credentials sent to them, unless File.HTTP
+	// is set. Nil sends no credentials.
+	HTTP *integrations.HTTPOptions
+}
+
+// Endpoint is a parsed endpoint URI.
+type Endpoint struct {
+	// URI is the endpoint as given.
+	URI string
+	// Scheme is lowercase, and empty for a bare path.
+	Scheme string
+	// Host is the part of the URI before the first slash after the scheme,
+	// such as a bucket, a host:port or a BigQuery project. It is empty for
+	// bare paths and file schemes.
+	Host string
+	// Path is the rest of the URI, without its query. For bare paths and
+	// file schemes it is the whole file path.
+	Path  string
+	Query url.Values
+}
+
+// Parse splits uri into an endpoint. Paths without a scheme are local files.
+func Parse(uri string) (*Endpoint, error) {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok || strings.ContainsAny(scheme, "/\\.") {
+		return &Endpoint{URI: uri, Path: uri, Query: url.Values{}}, nil
+	}
+	e := &Endpoint{URI: uri, Scheme: strings.ToLower(scheme)}
+	rest, rawQuery, _ := strings.Cut(rest, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query in %q: %w", uri, err)
+	}
+	e.Query = query
+	if _, isFormat := formatSchemes[e.Scheme]; isFormat || e.Scheme == SchemeFile || e.Scheme == SchemeDuckDB {
+		// parquet:///abs/path and parquet://rel/path both name files.
+		e.Path = rest
+		return e, nil
+	}
+	e.Host, e.Path, _ = strings.Cut(rest, "/")
+	if e.Host == "" {
+		return nil, fmt.Errorf("%q does not name a %s host", uri, e.Scheme)
+	}
+	return e, nil
+}
+
+// String returns the endpoint as given.
+func (e *Endpoint) String() string {
+	return e.URI
+}
+
+// format returns the file format the endpoint names, or "" to detect it
+// from the path.
+func (e *Endpoint) format() string {
+	if format := e.Query.Get("format"); format != "" {
+		return format
+	}
+	return formatSchemes[e.Scheme]
+}
+
+// NewReader opens a reader over the endpoint uri names.
+func NewReader(ctx context.Context, uri string, opts *Options) (Reader, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	e, err := Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	f, err := schemeFactory(e.Scheme, opts)
+	if err != nil {
+		return nil, err
+	}
+	if f.NewReader == nil {
+		return nil, fmt.Errorf("cannot read from %s endpoints", e.Scheme)
+	}
+	return f.NewReader(ctx, e, opts)
+}
+
+// NewWriter creates a writer of records of schema to the endpoint uri
+// names.
+func NewWriter(ctx context.Context, uri string, schema *arrow.Schema, opts *Options) (Writer, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	e, err := Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	f, err := schemeFactory(e.Scheme, opts)
+	if err != nil {
+		return nil, err
+	}
+	if f.NewWriter == nil {
+		return nil, fmt.Errorf("cannot write to %s endpoints", e.Scheme)
+	}
+	return f.NewWriter(ctx, e, schema, opts)
+}
+
+// schemeFactory returns the factory of scheme: files for bare paths, the
+// registered factory, or else an object store opened by opts.OpenBucket.
+func schemeFactory(scheme string, opts *Options) (Factory, error) {
+	if scheme == "" {
+		return fileFactory, nil
+	}
+	if f, ok := lookupScheme(scheme); ok {
+		return f, nil
+	}
+	if opts.OpenBucket != nil {
+		return objectFactory, nil
+	}
+	return Factory{}, fmt.Errorf("unknown endpoint scheme %q; registered schemes are %s", scheme, strings.Join(Schemes(), ", "))
+}
+
+var (
+	fileFactory = Factory{
+		NewReader: func(ctx context.Context, e *Endpoint, opts *Options) (Reader, error) {
+			return openFile(ctx, e, e.Path, opts)
+		},
+		NewWriter: func(ctx context.Context, e *Endpoint, schema *arrow.Schema, _ *Options) (Writer, error) {
+			return converter.CreateOutput(ctx, e.Path, e.format(), schema)
+		},
+	}
+	// remoteFileFactory reads the files of http:// and grpc:// sources,
+	// which keep their scheme and query.
+	remoteFileFactory = Factory{
+		NewReader: func(ctx context.Context, e *Endpoint, opts *Options) (Reader, error) {
+			return openFile(ctx, e, e.URI, opts)
+		},
+	}
+	httpFactory = Factory{
+		NewReader: remoteFileFactory.NewReader,
+		NewWriter: newHTTPWriter,
+	}
+	objectFactory = Factory{
+		NewReader: openObject,
+		NewWriter: createObject,
+	}
+)
+
+func init() {
+	RegisterScheme(SchemeFile, fileFactory)
+	for scheme := range formatSchemes {
+		RegisterScheme(scheme, fileFactory)
+	}
+	for _, scheme := range []string{"http", "https"} {
+		RegisterScheme(scheme, httpFactory)
+	}
+	for _, scheme := range []string{"grpc", "grpcs"} {
+		RegisterScheme(scheme, remoteFileFactory)
+	}
+	RegisterScheme(SchemeDuckDB, Factory{NewReader: newDuckDBReader, NewWriter: newDuckDBWriter})
+	bigQuery := Factory{NewReader: newBigQueryReader, NewWriter: newBigQueryWriter}
+	RegisterScheme(SchemeBQ, bigQuery)
+	RegisterScheme(SchemeBigQuery, bigQuery)
+	RegisterScheme(SchemeFlight, Factory{NewReader: newFlightReader, NewWriter: newFlightWriter})
+	RegisterScheme(SchemeGCS, objectFactory)
+	RegisterScheme(SchemeS3, objectFactory)
+}
+
+func newDuckDBReader(ctx context.Context, e *Endpoint, opts *Options) (Reader, error) {
+	query := e.Query.Get("query")
+	if table := e.Query.Get("table"); query == "" && table != "" {
+		query = "SELECT * FROM " + quoteIdentifier(table)
+	}
+	if query == "" {
+		return nil, fmt.Errorf("%s needs a table or a query to read", e.URI)
+	}
+	return integrations.OpenSource(ctx, e.Path, &integrations.SourceOptions{
+		Format: integrations.SourceDuckDB,
+		Query:  query,
+		Offset: opts.File.Offset,
+		Limit:  opts.File.Limit,
+	})
+}
+
+func newDuckDBWriter(ctx context.Context, e *Endpoint, _ *arrow.Schema, _ *Options) (Writer, error) {
+	table := e.Query.Get("table")
+	if table == "" {
+		return nil, fmt.Errorf("%s needs a table to write to", e.URI)
+	}
+	writeOpts := &duckdb.DuckDBWriteOptions{}
+	if keys := e.Query.Get("merge_keys"); keys != "" {
+		writeOpts.Merge = &merge.Options{
+			Keys:        strings.Split(keys, ","),
+			OpColumn:    e.Query.Get("op_column"),
+			Strategy:    merge.Strategy(e.Query.Get("merge_strategy")),
+			CreateTable: true,
+		}
+	}
+	writer, err := duckdb.NewDuckDBWriterWithOptions(ctx, e.Path, table, writeOpts)
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func newFlightReader(ctx context.Context, e *Endpoint, opts *Options) (Reader, error) {
+	query := e.Query.Get("query")
+	if query == "" && e.Path != "" {
+		reader, err := flight.NewFlightRelayReader(ctx, e.Host, e.Path, &flight.FlightRelayReadOptions{
+			DialOptions: opts.DialOptions,
+			Compression: e.Query.Get("compression"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return reader, nil
+	}
+	if query == "" {
+		return nil, fmt.Errorf("%s needs a relay source or a query to read from a Flight server", e.URI)
+	}
+	reader, err := flight.NewFlightSQLReader(ctx, e.Host, query, &flight.FlightSQLReadOptions{DialOptions: opts.DialOptions})
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+func newFlightWriter(ctx context.Context, e *Endpoint, schema *arrow.Schema, opts *Options) (Writer, error) {
+	var flightPath []string
+	if e.Path != "" {
+		flightPath = strings.Split(e.Path, "/")
+	}
+	writer, err := flight.NewFlightWriter(ctx, e.Host, schema, &flight.FlightWriteOptions{
+		Path:        flightPath,
+		DialOptions: opts.DialOptions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// httpWriteParams are the query parameters of http:// and https://
+// endpoints that configure the writer, and are not sent.
+var httpWriteParams = []string{"format", "gzip", "batch_rows", "method"}
+
+// newHTTPWriter posts batches of records to the URL, as an Arrow IPC
+// stream unless ?format=ndjson or csv, with ?gzip=true compressing them
+// and ?batch_rows and ?method setting the rows per request and the method.
+// The client and headers are those of opts.HTTP, with the credentials
+// covering the URL.
+func newHTTPWriter(ctx context.Context, e *Endpoint, schema *arrow.Schema, opts *Options) (Writer, error) {
+	writeOpts := integrations.NewDefaultHTTPWriteOptions()
+	if opts.HTTP != nil {
+		headers, err := opts.HTTP.HeadersFor(e.URI)
+		if err != nil {
+			return nil, err
+		}
+		writeOpts.Client, writeOpts.Headers = opts.HTTP.Client, headers
+	}
+	if format := e.Query.Get("format"); format != "" {
+		writeOpts.Format = format
+	}
+	if gzip := e.Query.Get("gzip"); gzip != "" {
+		on, err := strconv.ParseBool(gzip)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip %q in %s", gzip, e.Scheme+"://"+e.Host)
+		}
+		writeOpts.Gzip = on
+	}
+	if rows := e.Query.Get("batch_rows"); rows != "" {
+		n, err := strconv.ParseInt(rows, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid batch_rows %q in %s", rows, e.Scheme+"://"+e.Host)
+		}
+		writeOpts.BatchRows = n
+	}
+	writeOpts.Method = strings.ToUpper(e.Query.Get("method"))
+
+	query := url.Values{}
+	for key, values := range e.Query {
+		query[key] = values
+	}
+	for _, key := range httpWriteParams {
+		query.Del(key)
+	}
+	target, _, _ := strings.Cut(e.URI, "?")
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	writer, err := integrations.NewHTTPWriter(ctx, target, schema, writeOpts)
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func openFile(ctx context.Context, e *Endpoint, path string, opts *Options) (integrations.FileReader, error) {
+	fileOpts := opts.File
+	if fileOpts.HTTP == nil {
+		fileOpts.HTTP = opts.HTTP
+	}
+	if format := e.format(); format != "" {
+		fileOpts.FromFormat = format
+	}
+	fileOpts.CSV.HasHeader = e.Query.Get("header") != "false"
+	return converter.OpenInput(ctx, path, &fileOpts)
+}
+
+// quoteIdentifier quotes a SQL identifier, keeping dotted names such as
+// schema.table as separate parts.
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// bigQueryTable returns the project, dataset and table of a BigQuery
+// endpoint; dataset and table are empty for bq://project.
+func bigQueryTable(e *Endpoint) (project, dataset, table string, err error) {
+	if e.Path == "" {
+		return e.Host, "", "", nil
+	}
+	parts := strings.Split(strings.TrimSuffix(e.Path, "/"), "/")
+	if len(parts) != 2 {
+		return "", "", "", fmt.Errorf("invalid BigQuery endpoint %s, expected %s://project/dataset/table", e, e.Scheme)
+	}
+	return e.Host, parts[0], parts[1], nil
+}
+
+func newBigQueryReader(ctx context.Context, e *Endpoint, opts *Options) (Reader, error) {
+	project, dataset, table, err := bigQueryTable(e)
+	if err != nil {
+		return nil, err
+	}
+	query := e.Query.Get("query")
+	if (table == "") == (query == "") {
+		return nil, fmt.Errorf("invalid BigQuery endpoint %s, expected %s://project/dataset/table or %s://project?query=SQL", e, e.Scheme, e.Scheme)
+	}
+	client, err := bigquery.NewBigQueryReadClientWithAuth(ctx, opts.Auth, opts.ClientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	var reader *bigquery.BigQueryReader
+	if query != "" {
+		reader, err = client.NewBigQueryQueryReader(ctx, project, query)
+	} else {
+		reader, err = client.NewBigQueryReaderWithOptions(ctx, project, dataset, table, &bigquery.BigQueryReadOptions{
+			Offset: opts.File.Offset,
+			Limit:  opts.File.Limit,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// writeStreamModes maps the stream query parameter of BigQuery endpoints to
+// write stream modes.
+var writeStreamModes = map[string]bigquery.WriteStreamMode{
+	"default":   bigquery.WriteStreamModeDefault,
+	"committed": bigquery.WriteStreamModeCommitted,
+	"pending":   bigquery.WriteStreamModePending,
+}
+
+// newBigQueryWriter streams to the table with the Storage Write API, or
+// with ?mode=load_job stages files to GCS and loads them with a load job.
+// The query parameters are the keys of a workflow task's bigquery settings.
+func newBigQueryWriter(ctx context.Context, e *Endpoint, schema *arrow.Schema, opts *Options) (Writer, error) {
+	project, dataset, table, err := bigQueryTable(e)
+	if err != nil {
+		return nil, err
+	}
+	if table == "" {
+		return nil, fmt.Errorf("invalid BigQuery endpoint %s, expected %s://project/dataset/table", e, e.Scheme)
+	}
+
+	q := e.Query
+	settings := &config.BigQueryTaskSettings{
+		Mode:              q.Get("mode"),
+		StagingBucket:     q.Get("staging_bucket"),
+		StagingPrefix:     q.Get("staging_prefix"),
+		Partition:         q.Get("partition"),
+		WriteDisposition:  q.Get("write_disposition"),
+		CreateDisposition: q.Get("create_disposition"),
+		PollInterval:      q.Get("poll_interval"),
+		Timeout:           q.Get("timeout"),
+		KeepStagedFiles:   q.Get("keep_staged_files") == "true",
+	}
+	if rows := q.Get("file_rows"); rows != "" {
+		if settings.FileRows, err = strconv.ParseInt(rows, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid file_rows %q", rows)
+		}
+	}
+
+	if !settings.LoadJob() {
+		if settings.Mode != "" && settings.Mode != config.BigQueryModeStorageWrite {
+			return nil, fmt.Errorf("unknown BigQuery mode %q", settings.Mode)
+		}
+		writeOpts := bigquery.NewDefaultBigQueryWriteOptions()
+		if stream := q.Get("stream"); stream != "" {
+			mode, ok := writeStreamModes[stream]
+			if !ok {
+				return nil, fmt.Errorf("unknown BigQuery write stream %q", stream)
+			}
+			writeOpts.WriteStreamMode = mode
+		}
+		client, err := bigquery.NewBigQueryWriteClientWithAuth(ctx, schema, opts.Auth, opts.ClientOptions...)
+		if err != nil {
+			return nil, err
+		}
+		writer, err := bigquery.NewBigQueryRecordWriter(ctx, client, project, dataset, table, writeOpts)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		return &closingWriter{Writer: writer, after: client.Close}, nil
+	}
+
+	loadOpts, err := bigquery.BigQueryLoadOptionsFromConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+	clientOpts, err := opts.Auth.ClientOptions(ctx, opts.ClientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	bqClient, err := bq.NewClient(ctx, project, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BigQuery client: %w", err)
+	}
+	gcsClient, err := storage.NewClient(ctx, clientOpts...)
+	if err != nil {
+		bqClient.Close()
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	closeClients := func() error { return errors.Join(bqClient.Close(), gcsClient.Close()) }
+	writer, err := bigquery.NewBigQueryLoadWriter(ctx, bqClient, gcsClient, dataset, table, schema, loadOpts)
+	if err != nil {
+		closeClients()
+		return nil, err
+	}
+	return &closingWriter{Writer: writer, after: closeClients}, nil
+}
+
+// closingWriter runs after once its writer is closed.
+type closingWriter struct {
+	Writer
+	after func() error
+}
+
+func (w *closingWriter) Close() error {
+	err := w.Writer.Close()
+	if afterErr := w.after(); err == nil {
+		err = afterErr
+	}
+	return err
+}
+
+// openBucket opens the bucket an object store endpoint names.
+func openBucket(ctx context.Context, e *Endpoint, opts *Options) (Bucket, error) {
+	if opts.OpenBucket != nil {
+		return opts.OpenBucket(ctx, e.Scheme, e.Host)
+	}
+	if e.Scheme == SchemeGCS {
+		clientOpts, err := opts.Auth.ClientOptions(ctx, opts.ClientOptions...)
+		if err != nil {
+			return nil, err
+		}
+		client, err := storage.NewClient(ctx, clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCS client: %w", err)
+		}
+		return &gcsBucket{client: client, bucket: client.Bucket(e.Host)}, nil
+	}
+	if e.Scheme == SchemeS3 {
+		return s3.NewS3Bucket(ctx, e.Host, opts.AWS)
+	}
+	return nil, fmt.Errorf("unsupported endpoint scheme %q; set Options.OpenBucket to read and write %s:// objects", e.Scheme, e.Scheme)
+}
+
+// localCopy creates an empty temporary file named like key, so its format
+// can be detected from the extension.
+func localCopy(key string, opts *Options) (*os.File, error) {
+	return os.CreateTemp(opts.TempDir, "arrowarc-*-"+path.Base(key))
+}
+
+// openObject downloads an object store file and reads the local copy,
+// which Close removes.
+func openObject(ctx context.Context, e *Endpoint, opts *Options) (Reader, error) {
+	if e.Path == "" {
+		return nil, fmt.Errorf("%s does not name an object", e)
+	}
+	bucket, err := openBucket(ctx, e, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer bucket.Close()
+
+	f, err := localCopy(e.Path, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = download(ctx, bucket, e.Path, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("failed to download %s: %w", e, err)
+	}
+
+	reader, err := openFile(ctx, e, f.Name(), opts)
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &localCopyReader{FileReader: reader, path: f.Name()}, nil
+}
+
+func download(ctx context.Context, bucket Bucket, key string, w io.Writer) error {
+	r, err := bucket.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// localCopyReader reads a local copy of an object and removes it on Close.
+type localCopyReader struct {
+	integrations.FileReader
+	path string
+}
+
+func (r *localCopyReader) Close() error {
+	err := r.FileReader.Close()
+	if removeErr := os.Remove(r.path); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// createObject writes to a local file that Close uploads to the object
+// store and removes.
+func createObject(ctx context.Context, e *Endpoint, schema *arrow.Schema, opts *Options) (Writer, error) {
+	if e.Path == "" {
+		return nil, fmt.Errorf("%s does not name an object", e)
+	}
+	bucket, err := openBucket(ctx, e, opts)
+	if err != nil {
+		return nil, err
+	}
+	f, err := localCopy(e.Path, opts)
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		bucket.Close()
+		return nil, err
+	}
+	writer, err := converter.CreateOutput(ctx, f.Name(), e.format(), schema)
+	if err != nil {
+		bucket.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &uploadingWriter{Writer: writer, ctx: ctx, bucket: bucket, key: e.Path, path: f.Name()}, nil
+}
+
+// uploadingWriter uploads the local file it wrote once closed.
+type uploadingWriter struct {
+	Writer
+	ctx    context.Context
+	bucket Bucket
+	key    string
+	path   string
+}
+
+func (w *uploadingWriter) Close() error {
+	defer os.Remove(w.path)
+	defer w.bucket.Close()
+	if err := w.Writer.Close(); err != nil {
+		return err
+	}
+	f, err := os.Open(w.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := w.bucket.Upload(w.ctx, w.key, f); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", w.key, err)
+	}
+	return nil
+}
+
+// gcsBucket is a Bucket over a GCS bucket.
+type gcsBucket struct {
+	client *storage.Client
+	bucket *storage.BucketHandle
+}
+
+func (b *gcsBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return b.bucket.Object(name).NewReader(ctx)
+}
+
+func (b *gcsBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	// Cancelling the upload before closing it discards the partial object.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := b.bucket.Object(name).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		cancel()
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (b *gcsBucket) Close() error {
+	return b.client.Close()
+}
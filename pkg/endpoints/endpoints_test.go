@@ -0,0 +1,251 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want Endpoint
+	}{
+		{"data/orders.csv", Endpoint{Path: "data/orders.csv", Query: url.Values{}}},
+		{"parquet:///data/orders.pq?format=csv", Endpoint{Scheme: "parquet", Path: "/data/orders.pq", Query: url.Values{"format": {"csv"}}}},
+		{"file://rel/orders.avro", Endpoint{Scheme: "file", Path: "rel/orders.avro", Query: url.Values{}}},
+		{"duckdb://warehouse.db?table=sales.orders", Endpoint{Scheme: "duckdb", Path: "warehouse.db", Query: url.Values{"table": {"sales.orders"}}}},
+		{"BQ://project/dataset/table", Endpoint{Scheme: "bq", Host: "project", Path: "dataset/table", Query: url.Values{}}},
+		{"flight://localhost:8815/sales/orders", Endpoint{Scheme: "flight", Host: "localhost:8815", Path: "sales/orders", Query: url.Values{}}},
+		{"s3://bucket/2024/orders.csv", Endpoint{Scheme: "s3", Host: "bucket", Path: "2024/orders.csv", Query: url.Values{}}},
+	}
+	for _, test := range tests {
+		test.want.URI = test.uri
+		got, err := Parse(test.uri)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", test.uri, err)
+		}
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", test.uri, *got, test.want)
+		}
+	}
+
+	if _, err := Parse("s3:///key.csv"); err == nil {
+		t.Error("an s3 URI without a bucket should not parse")
+	}
+}
+
+func testRecord(t *testing.T) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+	}, nil)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"a", "b", "c"}, nil)
+	return b.NewRecord()
+}
+
+// roundTrip writes rec to uri and reads it back from readURI.
+func roundTrip(t *testing.T, uri, readURI string, rec arrow.Record, opts *Options) arrow.Record {
+	t.Helper()
+	ctx := context.Background()
+	w, err := NewWriter(ctx, uri, rec.Schema(), opts)
+	if err != nil {
+		t.Fatalf("NewWriter(%q): %v", uri, err)
+	}
+	if err := w.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(ctx, readURI, opts)
+	if err != nil {
+		t.Fatalf("NewReader(%q): %v", readURI, err)
+	}
+	defer r.Close()
+	got, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Retain()
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatalf("want a single record, got %v", err)
+	}
+	return got
+}
+
+// requireIDs checks the id column, which formats such as JSON lines may
+// read back as another numeric type.
+func requireIDs(t *testing.T, rec arrow.Record, want []string) {
+	t.Helper()
+	col := rec.Column(rec.Schema().FieldIndices("id")[0])
+	got := make([]string, col.Len())
+	for i := range got {
+		got[i] = col.ValueStr(i)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ids = %v, want %v", got, want)
+	}
+}
+
+func TestFileEndpoints(t *testing.T) {
+	rec := testRecord(t)
+	defer rec.Release()
+	dir := t.TempDir()
+
+	tests := []struct{ write, read string }{
+		{"parquet://" + filepath.Join(dir, "orders.data"), "file://" + filepath.Join(dir, "orders.data") + "?format=parquet"},
+		{filepath.Join(dir, "orders.csv"), "csv://" + filepath.Join(dir, "orders.csv")},
+		{"file://" + filepath.Join(dir, "orders.arrow"), "ipc://" + filepath.Join(dir, "orders.arrow")},
+		{"json://" + filepath.Join(dir, "orders.out"), "ndjson://" + filepath.Join(dir, "orders.out")},
+	}
+	for _, test := range tests {
+		got := roundTrip(t, test.write, test.read, rec, nil)
+		requireIDs(t, got, []string{"1", "2", "3"})
+		got.Release()
+	}
+}
+
+// memBucket is an in-memory Bucket.
+type memBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	closed  int
+}
+
+func (b *memBucket) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *memBucket) Upload(_ context.Context, name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[name] = data
+	return nil
+}
+
+func (b *memBucket) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed++
+	return nil
+}
+
+func TestObjectStoreEndpoints(t *testing.T) {
+	rec := testRecord(t)
+	defer rec.Release()
+
+	bucket := &memBucket{objects: map[string][]byte{}}
+	var opened []string
+	opts := &Options{
+		TempDir: t.TempDir(),
+		OpenBucket: func(_ context.Context, scheme, name string) (Bucket, error) {
+			opened = append(opened, scheme+"://"+name)
+			return bucket, nil
+		},
+	}
+
+	got := roundTrip(t, "s3://lake/2024/orders.csv", "s3://lake/2024/orders.csv", rec, opts)
+	requireIDs(t, got, []string{"1", "2", "3"})
+	got.Release()
+
+	if _, ok := bucket.objects["2024/orders.csv"]; !ok {
+		t.Fatalf("object not uploaded: %v", bucket.objects)
+	}
+	if !reflect.DeepEqual(opened, []string{"s3://lake", "s3://lake"}) {
+		t.Errorf("opened buckets %v", opened)
+	}
+	if bucket.closed != 2 {
+		t.Errorf("bucket closed %d times, want 2", bucket.closed)
+	}
+	if entries, _ := os.ReadDir(opts.TempDir); len(entries) != 0 {
+		t.Errorf("local copies left behind: %v", entries)
+	}
+}
+
+func TestEndpointErrors(t *testing.T) {
+	ctx := context.Background()
+	rec := testRecord(t)
+	defer rec.Release()
+
+	for _, uri := range []string{
+		"s3://lake/orders.csv",
+		"duckdb://warehouse.db",
+		"flight://localhost:8815",
+		"flight://localhost:8815/orders?compression=brotli",
+		"bq://project/dataset",
+	} {
+		if _, err := NewReader(ctx, uri, nil); err == nil {
+			t.Errorf("NewReader(%q) should fail", uri)
+		}
+	}
+	for _, uri := range []string{
+		"s3://lake/orders.csv",
+		"duckdb://warehouse.db",
+		"duckdb://warehouse.db?table=orders&merge_keys=id&merge_strategy=upsert",
+		"https://example.com/orders?batch_rows=0",
+		"grpc://localhost:50051/pkg.Service/Tail",
+		"bq://project",
+		"bq://project/dataset/table?stream=eventually",
+	} {
+		w, err := NewWriter(ctx, uri, rec.Schema(), nil)
+		if err == nil {
+			w.Close()
+			t.Errorf("NewWriter(%q) should fail", uri)
+		}
+	}
+}
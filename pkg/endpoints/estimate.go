@@ -0,0 +1,180 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package endpoints
+
+import (
+	"context"
+	"fmt"
+
+	bq "cloud.google.com/go/bigquery"
+	bigquery "github.com/arrowarc/arrowarc/integrations/bigquery"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+)
+
+// EstimateRead dry-runs reading the endpoint uri names and returns the
+// bytes it would scan and their cost, for a BigQuery table or query. It
+// returns nil for other endpoints, which cost nothing to read.
+func EstimateRead(ctx context.Context, uri string, opts *Options) (*bigquery.Estimate, error) {
+	e, err := Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	return estimateRead(ctx, e, e.Query.Get("query"), opts)
+}
+
+func estimateRead(ctx context.Context, e *Endpoint, query string, opts *Options) (*bigquery.Estimate, error) {
+	if !isBigQuery(e) {
+		return nil, nil
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+	project, dataset, table, err := bigQueryTable(e)
+	if err != nil {
+		return nil, err
+	}
+	if (table == "") == (query == "") {
+		return nil, fmt.Errorf("invalid BigQuery endpoint %s, expected %s://project/dataset/table or %s://project?query=SQL", e, e.Scheme, e.Scheme)
+	}
+	clientOpts, err := opts.Auth.ClientOptions(ctx, opts.ClientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	client, err := bq.NewClient(ctx, project, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BigQuery client: %w", err)
+	}
+	defer client.Close()
+	if query != "" {
+		return bigquery.EstimateQuery(ctx, client, query)
+	}
+	return bigquery.EstimateTableRead(ctx, client, dataset, table)
+}
+
+// EstimateWrite returns the estimate of writing bytes, zero when unknown,
+// to the BigQuery table uri names, with the mode its query sets. It returns
+// nil for other endpoints.
+func EstimateWrite(uri string, bytes int64) (*bigquery.Estimate, error) {
+	e, err := Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	return estimateWrite(e, bytes, e.Query.Get("mode") == config.BigQueryModeLoadJob)
+}
+
+func estimateWrite(e *Endpoint, bytes int64, loadJob bool) (*bigquery.Estimate, error) {
+	if !isBigQuery(e) {
+		return nil, nil
+	}
+	project, dataset, table, err := bigQueryTable(e)
+	if err != nil {
+		return nil, err
+	}
+	if table == "" {
+		return nil, fmt.Errorf("invalid BigQuery endpoint %s, expected %s://project/dataset/table", e, e.Scheme)
+	}
+	return bigquery.EstimateWrite(fmt.Sprintf("%s.%s.%s", project, dataset, table), bytes, loadJob), nil
+}
+
+func isBigQuery(e *Endpoint) bool {
+	return e.Scheme == SchemeBQ || e.Scheme == SchemeBigQuery
+}
+
+// TaskEstimate holds the estimates of the BigQuery operations of a
+// workflow task, and their total cost.
+type TaskEstimate struct {
+	Task      string               `json:"task"`
+	Estimates []*bigquery.Estimate `json:"estimates"`
+	Cost      float64              `json:"cost_usd"`
+}
+
+// EstimateWorkflow dry-runs the BigQuery reads and writes of the tasks of
+// a workflow, as far as their integrations have a uri, and returns their
+// estimates. Tasks without BigQuery operations are left out. A task's query
+// is run against a BigQuery source's project, and a write is estimated for
+// the bytes the task reads, when known.
+func EstimateWorkflow(ctx context.Context, cfg *config.Config, opts *Options) ([]TaskEstimate, error) {
+	var out []TaskEstimate
+	for _, task := range cfg.Workflow.Tasks {
+		te := TaskEstimate{Task: task.Name}
+		var bytes int64
+		if e, integration, err := taskEndpoint(cfg, task.Source); err != nil {
+			return nil, fmt.Errorf("task '%s': %w", task.Name, err)
+		} else if e != nil {
+			query := task.Query
+			if query == "" {
+				query = e.Query.Get("query")
+			}
+			readOpts, err := integrationOptions(cfg, integration, opts)
+			if err != nil {
+				return nil, fmt.Errorf("task '%s': %w", task.Name, err)
+			}
+			estimate, err := estimateRead(ctx, e, query, readOpts)
+			if err != nil {
+				return nil, fmt.Errorf("task '%s': %w", task.Name, err)
+			}
+			if estimate != nil {
+				te.Estimates = append(te.Estimates, estimate)
+				bytes = estimate.Bytes
+			}
+		}
+		if e, _, err := taskEndpoint(cfg, task.Destination); err != nil {
+			return nil, fmt.Errorf("task '%s': %w", task.Name, err)
+		} else if e != nil {
+			loadJob := task.BigQuery.LoadJob() || e.Query.Get("mode") == config.BigQueryModeLoadJob
+			estimate, err := estimateWrite(e, bytes, loadJob)
+			if err != nil {
+				return nil, fmt.Errorf("task '%s': %w", task.Name, err)
+			}
+			if estimate != nil {
+				te.Estimates = append(te.Estimates, estimate)
+			}
+		}
+		if len(te.Estimates) == 0 {
+			continue
+		}
+		for _, estimate := range te.Estimates {
+			te.Cost += estimate.Cost
+		}
+		out = append(out, te)
+	}
+	return out, nil
+}
+
+// taskEndpoint returns the named integration and its endpoint, which is nil
+// if it has no uri.
+func taskEndpoint(cfg *config.Config, name string) (*Endpoint, *config.Integration, error) {
+	integration, err := cfg.Integration(name)
+	if err != nil || integration.URI == "" {
+		return nil, integration, err
+	}
+	e, err := Parse(integration.URI)
+	return e, integration, err
+}
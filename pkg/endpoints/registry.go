@@ -0,0 +1,105 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package endpoints
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+)
+
+// Reader and Writer are the record streams pipelines connect, named here
+// so that packages outside this module can implement factories.
+type (
+	Reader = interfaces.Reader
+	Writer = interfaces.Writer
+)
+
+// Factory opens the readers and writers of an endpoint scheme. A scheme
+// that is only read or only written leaves the other function nil. The
+// endpoint's Host, Path and Query carry the parts of the URI; Options are
+// the caller's, never nil.
+type Factory struct {
+	NewReader func(ctx context.Context, e *Endpoint, opts *Options) (Reader, error)
+	NewWriter func(ctx context.Context, e *Endpoint, schema *arrow.Schema, opts *Options) (Writer, error)
+}
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+	// validScheme matches URI schemes, less the dots Parse takes for paths.
+	validScheme = regexp.MustCompile(`^[a-z][a-z0-9+-]*$`)
+)
+
+// RegisterScheme makes the endpoints of scheme, which is case-insensitive,
+// available to NewReader and NewWriter. It is meant to be called from the
+// init function of the package implementing the scheme, like
+// database/sql.Register, and panics if the scheme is invalid or already
+// registered, or if the factory opens neither readers nor writers.
+func RegisterScheme(scheme string, f Factory) {
+	scheme = strings.ToLower(scheme)
+	if !validScheme.MatchString(scheme) {
+		panic(fmt.Sprintf("endpoints: invalid scheme %q", scheme))
+	}
+	if f.NewReader == nil && f.NewWriter == nil {
+		panic(fmt.Sprintf("endpoints: scheme %q has neither a reader nor a writer", scheme))
+	}
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if _, dup := factories[scheme]; dup {
+		panic(fmt.Sprintf("endpoints: scheme %q is registered twice", scheme))
+	}
+	factories[scheme] = f
+}
+
+// Schemes returns the registered schemes, sorted.
+func Schemes() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	schemes := make([]string, 0, len(factories))
+	for scheme := range factories {
+		schemes = append(schemes, scheme)
+	}
+	slices.Sort(schemes)
+	return schemes
+}
+
+func lookupScheme(scheme string) (Factory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	f, ok := factories[scheme]
+	return f, ok
+}
@@ -0,0 +1,201 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package endpoints
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"gopkg.in/yaml.v3"
+)
+
+// memTables holds the records written to mem:// endpoints, by host.
+var memTables = map[string][]arrow.Record{}
+
+type memWriter struct{ table string }
+
+func (w *memWriter) Write(rec arrow.Record) error {
+	rec.Retain()
+	memTables[w.table] = append(memTables[w.table], rec)
+	return nil
+}
+
+func (w *memWriter) Close() error { return nil }
+
+type memReader struct{ records []arrow.Record }
+
+func (r *memReader) Read() (arrow.Record, error) {
+	if len(r.records) == 0 {
+		return nil, io.EOF
+	}
+	rec := r.records[0]
+	r.records = r.records[1:]
+	return rec, nil
+}
+
+func (r *memReader) Close() error { return nil }
+
+func init() {
+	RegisterScheme("Mem", Factory{
+		NewReader: func(_ context.Context, e *Endpoint, _ *Options) (Reader, error) {
+			return &memReader{records: memTables[e.Host]}, nil
+		},
+		NewWriter: func(_ context.Context, e *Endpoint, _ *arrow.Schema, _ *Options) (Writer, error) {
+			return &memWriter{table: e.Host}, nil
+		},
+	})
+	RegisterScheme("counter", Factory{
+		NewReader: func(context.Context, *Endpoint, *Options) (Reader, error) {
+			return &memReader{}, nil
+		},
+	})
+}
+
+func TestRegisteredScheme(t *testing.T) {
+	ctx := context.Background()
+	rec := testRecord(t)
+	defer rec.Release()
+
+	w, err := NewWriter(ctx, "mem://orders", rec.Schema(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	r, err := NewReader(ctx, "MEM://orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	got, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireIDs(t, got, []string{"1", "2", "3"})
+
+	if !slices.Contains(Schemes(), "mem") || !slices.Contains(Schemes(), "parquet") {
+		t.Errorf("Schemes() = %v", Schemes())
+	}
+	if _, err := NewWriter(ctx, "counter://x", rec.Schema(), nil); err == nil || !strings.Contains(err.Error(), "cannot write") {
+		t.Errorf("writing a read-only scheme: %v", err)
+	}
+	if _, err := NewReader(ctx, "nope://x", nil); err == nil || !strings.Contains(err.Error(), "mem") {
+		t.Errorf("an unknown scheme should fail and list the registered ones: %v", err)
+	}
+}
+
+func TestRegisterSchemePanics(t *testing.T) {
+	reader := func(context.Context, *Endpoint, *Options) (Reader, error) { return nil, nil }
+	for _, test := range []struct {
+		scheme  string
+		factory Factory
+	}{
+		{"mem", Factory{NewReader: reader}},
+		{"csv", Factory{NewReader: reader}},
+		{"my.scheme", Factory{NewReader: reader}},
+		{"", Factory{NewReader: reader}},
+		{"empty", Factory{}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterScheme(%q) should panic", test.scheme)
+				}
+			}()
+			RegisterScheme(test.scheme, test.factory)
+		}()
+	}
+}
+
+func TestIntegrationEndpoints(t *testing.T) {
+	var cfg config.Config
+	if err := yaml.Unmarshal([]byte(`
+workflow:
+  integrations:
+    - name: sink
+      type: custom
+      provider: mem
+      mode: write
+      uri: mem://integration
+    - name: counter
+      type: custom
+      provider: counter
+      mode: write
+      uri: counter://events
+    - name: unknown
+      type: custom
+      provider: nope
+      mode: read
+      uri: nope://x
+    - name: legacy
+      type: database
+      provider: postgres
+      mode: read
+`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	err := CheckIntegrations(&cfg, nil)
+	if err == nil {
+		t.Fatal("CheckIntegrations should fail")
+	}
+	for _, want := range []string{"'counter'", "'unknown'"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "'sink'") || strings.Contains(err.Error(), "'legacy'") {
+		t.Errorf("error %q mentions a valid integration", err)
+	}
+
+	rec := testRecord(t)
+	defer rec.Release()
+	w, err := NewIntegrationWriter(context.Background(), &cfg, "sink", rec.Schema(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	if len(memTables["integration"]) != 1 {
+		t.Errorf("the integration's uri was not written")
+	}
+	if _, err := NewIntegrationReader(context.Background(), &cfg, "legacy", nil); err == nil {
+		t.Error("an integration without a uri cannot be opened")
+	}
+}
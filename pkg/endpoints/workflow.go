@@ -0,0 +1,148 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/arrowarc/arrowarc/pkg/awsauth"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/gcpauth"
+)
+
+// Integration modes of a workflow.
+const (
+	ModeRead  = "read"
+	ModeWrite = "write"
+)
+
+// NewIntegrationReader opens a reader over the uri of the named integration
+// of a workflow, with the credentials of its auth settings or credentials
+// secret, if any.
+func NewIntegrationReader(ctx context.Context, cfg *config.Config, name string, opts *Options) (Reader, error) {
+	integration, err := uriIntegration(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	if opts, err = integrationOptions(cfg, integration, opts); err != nil {
+		return nil, err
+	}
+	return NewReader(ctx, integration.URI, opts)
+}
+
+// NewIntegrationWriter creates a writer to the uri of the named integration
+// of a workflow, with the credentials of its auth settings or credentials
+// secret, if any.
+func NewIntegrationWriter(ctx context.Context, cfg *config.Config, name string, schema *arrow.Schema, opts *Options) (Writer, error) {
+	integration, err := uriIntegration(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	if opts, err = integrationOptions(cfg, integration, opts); err != nil {
+		return nil, err
+	}
+	return NewWriter(ctx, integration.URI, schema, opts)
+}
+
+func uriIntegration(cfg *config.Config, name string) (*config.Integration, error) {
+	integration, err := cfg.Integration(name)
+	if err != nil {
+		return nil, err
+	}
+	if integration.URI == "" {
+		return nil, fmt.Errorf("integration '%s' has no uri", name)
+	}
+	return integration, nil
+}
+
+// integrationOptions returns opts with the GCP credentials of integration's
+// auth settings and the AWS credentials of its secret, or opts itself if it
+// has neither.
+func integrationOptions(cfg *config.Config, integration *config.Integration, opts *Options) (*Options, error) {
+	if integration == nil || integration.Auth == nil && integration.Credentials == "" {
+		return opts, nil
+	}
+	withAuth := Options{}
+	if opts != nil {
+		withAuth = *opts
+	}
+	if integration.Auth != nil {
+		withAuth.Auth = gcpauth.FromConfig(integration.Auth)
+	}
+	if integration.Credentials != "" {
+		settings, err := cfg.AWSAuth(integration.Name)
+		if err != nil {
+			return nil, err
+		}
+		withAuth.AWS = awsauth.FromConfig(settings)
+	}
+	return &withAuth, nil
+}
+
+// CheckIntegrations reports the integrations of a workflow whose uri does
+// not parse, names a scheme that is not registered, or names one that cannot
+// be read or written as the integration's mode asks. Schemes left to
+// opts.OpenBucket are accepted.
+func CheckIntegrations(cfg *config.Config, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	var errs []error
+	for _, integration := range cfg.Workflow.Integrations {
+		if integration.URI == "" {
+			continue
+		}
+		if err := checkIntegration(integration, opts); err != nil {
+			errs = append(errs, fmt.Errorf("integration '%s': %w", integration.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkIntegration(integration config.Integration, opts *Options) error {
+	e, err := Parse(integration.URI)
+	if err != nil {
+		return err
+	}
+	f, err := schemeFactory(e.Scheme, opts)
+	if err != nil {
+		return err
+	}
+	switch {
+	case integration.Mode == ModeRead && f.NewReader == nil:
+		return fmt.Errorf("cannot read from %s endpoints", e.Scheme)
+	case integration.Mode == ModeWrite && f.NewWriter == nil:
+		return fmt.Errorf("cannot write to %s endpoints", e.Scheme)
+	}
+	return nil
+}
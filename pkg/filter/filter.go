@@ -0,0 +1,353 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package filter provides a small row filter expression language: column
+// comparisons against literals combined with AND and OR. Expressions can be
+// evaluated against Arrow records and checked against column statistics to
+// skip data that cannot match.
+package filter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// Expr is a boolean expression over the columns of a record.
+type Expr interface {
+	fmt.Stringer
+	// Validate checks that every referenced column exists in schema with a
+	// type that can be compared to its literal.
+	Validate(schema *arrow.Schema) error
+	// Evaluate returns, for every row of record, whether the row matches.
+	// Comparisons against null values never match.
+	Evaluate(record arrow.Record) ([]bool, error)
+	// MayMatch reports whether any row summarized by stats could match. It
+	// returns true whenever the statistics are inconclusive.
+	MayMatch(stats Stats) bool
+}
+
+// Stats summarizes the values of a chunk of rows, such as a Parquet row group.
+type Stats interface {
+	// MinMax returns the smallest and largest non-null values of column as
+	// int64, uint64, float64, string or bool, or ok=false if unknown.
+	MinMax(column string) (min, max any, ok bool)
+	// MightContain reports whether column may contain value. It returns true
+	// when it cannot tell, for example when there is no bloom filter.
+	MightContain(column string, value any) bool
+}
+
+// Op is a comparison operator.
+type Op int
+
+const (
+	Eq Op = iota
+	Ne
+	Lt
+	Le
+	Gt
+	Ge
+)
+
+func (o Op) String() string {
+	switch o {
+	case Eq:
+		return "="
+	case Ne:
+		return "!="
+	case Lt:
+		return "<"
+	case Le:
+		return "<="
+	case Gt:
+		return ">"
+	case Ge:
+		return ">="
+	}
+	return fmt.Sprintf("Op(%d)", int(o))
+}
+
+// holds reports whether a comparison result c (-1, 0 or 1) satisfies o.
+func (o Op) holds(c int) bool {
+	switch o {
+	case Eq:
+		return c == 0
+	case Ne:
+		return c != 0
+	case Lt:
+		return c < 0
+	case Le:
+		return c <= 0
+	case Gt:
+		return c > 0
+	case Ge:
+		return c >= 0
+	}
+	return false
+}
+
+// Comparison compares a column with a literal. Value must be an int64,
+// uint64, float64, string or bool.
+type Comparison struct {
+	Column string
+	Op     Op
+	Value  any
+}
+
+// And matches rows matching every expression.
+type And []Expr
+
+// Or matches rows matching at least one expression.
+type Or []Expr
+
+func (c Comparison) String() string {
+	if s, ok := c.Value.(string); ok {
+		return fmt.Sprintf("%s %s %s", c.Column, c.Op, strconv.Quote(s))
+	}
+	return fmt.Sprintf("%s %s %v", c.Column, c.Op, c.Value)
+}
+
+func (c Comparison) Validate(schema *arrow.Schema) error {
+	indices := schema.FieldIndices(c.Column)
+	if len(indices) == 0 {
+		return fmt.Errorf("filter column %q not found in schema", c.Column)
+	}
+	dt := schema.Field(indices[0]).Type
+	zero, err := zeroValue(dt)
+	if err != nil {
+		return fmt.Errorf("filter column %q: %w", c.Column, err)
+	}
+	if _, err := compareValues(zero, c.Value); err != nil {
+		return fmt.Errorf("filter column %q: %w", c.Column, err)
+	}
+	return nil
+}
+
+func (c Comparison) Evaluate(record arrow.Record) ([]bool, error) {
+	indices := record.Schema().FieldIndices(c.Column)
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("filter column %q not found in record", c.Column)
+	}
+	col := record.Column(indices[0])
+
+	mask := make([]bool, record.NumRows())
+	for i := range mask {
+		if col.IsNull(i) {
+			continue
+		}
+		v, err := valueAt(col, i)
+		if err != nil {
+			return nil, fmt.Errorf("filter column %q: %w", c.Column, err)
+		}
+		cmp, err := compareValues(v, c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("filter column %q: %w", c.Column, err)
+		}
+		mask[i] = c.Op.holds(cmp)
+	}
+	return mask, nil
+}
+
+func (c Comparison) MayMatch(stats Stats) bool {
+	if c.Op == Eq && !stats.MightContain(c.Column, c.Value) {
+		return false
+	}
+
+	min, max, ok := stats.MinMax(c.Column)
+	if !ok {
+		return true
+	}
+	lo, err := compareValues(min, c.Value)
+	if err != nil {
+		return true
+	}
+	hi, err := compareValues(max, c.Value)
+	if err != nil {
+		return true
+	}
+
+	switch c.Op {
+	case Eq:
+		return lo <= 0 && hi >= 0
+	case Ne:
+		return lo != 0 || hi != 0
+	case Lt:
+		return lo < 0
+	case Le:
+		return lo <= 0
+	case Gt:
+		return hi > 0
+	case Ge:
+		return hi >= 0
+	}
+	return true
+}
+
+func (a And) String() string { return joinExprs(a, " AND ") }
+
+func (a And) Validate(schema *arrow.Schema) error { return validateAll(a, schema) }
+
+func (a And) Evaluate(record arrow.Record) ([]bool, error) {
+	return combine(a, record, func(acc, v bool) bool { return acc && v }, true)
+}
+
+func (a And) MayMatch(stats Stats) bool {
+	for _, e := range a {
+		if !e.MayMatch(stats) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o Or) String() string { return joinExprs(o, " OR ") }
+
+func (o Or) Validate(schema *arrow.Schema) error { return validateAll(o, schema) }
+
+func (o Or) Evaluate(record arrow.Record) ([]bool, error) {
+	return combine(o, record, func(acc, v bool) bool { return acc || v }, false)
+}
+
+func (o Or) MayMatch(stats Stats) bool {
+	for _, e := range o {
+		if e.MayMatch(stats) {
+			return true
+		}
+	}
+	return len(o) == 0
+}
+
+func joinExprs(exprs []Expr, sep string) string {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		parts[i] = e.String()
+		if _, ok := e.(Comparison); !ok {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+func validateAll(exprs []Expr, schema *arrow.Schema) error {
+	for _, e := range exprs {
+		if err := e.Validate(schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func combine(exprs []Expr, record arrow.Record, op func(acc, v bool) bool, init bool) ([]bool, error) {
+	mask := make([]bool, record.NumRows())
+	for i := range mask {
+		mask[i] = init
+	}
+	for _, e := range exprs {
+		m, err := e.Evaluate(record)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range m {
+			mask[i] = op(mask[i], v)
+		}
+	}
+	return mask, nil
+}
+
+// Apply returns a new record holding the rows of record that match expr.
+// The caller owns the returned record.
+func Apply(ctx context.Context, record arrow.Record, expr Expr) (arrow.Record, error) {
+	mask, err := expr.Evaluate(record)
+	if err != nil {
+		return nil, err
+	}
+	return Select(ctx, record, mask)
+}
+
+// Select returns a new record holding the rows of record whose entry in
+// mask is true. The caller owns the returned record.
+func Select(ctx context.Context, record arrow.Record, mask []bool) (arrow.Record, error) {
+	b := array.NewBooleanBuilder(memory.DefaultAllocator)
+	defer b.Release()
+	b.AppendValues(mask, nil)
+	selection := b.NewBooleanArray()
+	defer selection.Release()
+
+	if !hasDictionary(record.Schema()) {
+		return compute.FilterRecordBatch(ctx, record, selection, compute.DefaultFilterOptions())
+	}
+	// The take kernels do not support dictionaries, so filter column by
+	// column.
+	cols := make([]arrow.Array, record.NumCols())
+	defer func() {
+		for _, col := range cols {
+			if col != nil {
+				col.Release()
+			}
+		}
+	}()
+	for i, col := range record.Columns() {
+		filtered, err := filterColumn(ctx, col, selection)
+		if err != nil {
+			return nil, err
+		}
+		cols[i] = filtered
+	}
+	return array.NewRecord(record.Schema(), cols, -1), nil
+}
+
+func hasDictionary(schema *arrow.Schema) bool {
+	for _, f := range schema.Fields() {
+		if f.Type.ID() == arrow.DICTIONARY {
+			return true
+		}
+	}
+	return false
+}
+
+// filterColumn keeps the selected values of col. Dictionary columns keep
+// their dictionary and only have their indices filtered.
+func filterColumn(ctx context.Context, col, selection arrow.Array) (arrow.Array, error) {
+	dict, ok := col.(*array.Dictionary)
+	if !ok {
+		return compute.FilterArray(ctx, col, selection, *compute.DefaultFilterOptions())
+	}
+	indices, err := compute.FilterArray(ctx, dict.Indices(), selection, *compute.DefaultFilterOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer indices.Release()
+	return array.NewDictionaryArray(dict.DataType(), indices, dict.Dictionary()), nil
+}
@@ -0,0 +1,185 @@
+package filter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"id = 5", "id = 5"},
+		{"name == 'O\\'Brien'", `name = "O'Brien"`},
+		{"a > 1 AND b <= 2.5 or c <> true", "(a > 1 AND b <= 2.5) OR c != true"},
+		{"a > 1 and (b = x OR c != \"y\")", `a > 1 AND (b = "x" OR c != "y")`},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+		if got := expr.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"", "id", "id =", "id ~ 3", "(id = 1", "id = 1 extra", "id = 'open"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q): expected error", input)
+		}
+	}
+}
+
+func testRecord(t *testing.T) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "score", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*array.Int32Builder).AppendValues([]int32{1, 2, 3, 4}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"a", "b", "", "d"}, []bool{true, true, false, true})
+	b.Field(2).(*array.Float64Builder).AppendValues([]float64{0.5, 1.5, 2.5, 3.5}, nil)
+	rec := b.NewRecord()
+	t.Cleanup(rec.Release)
+	return rec
+}
+
+func TestApply(t *testing.T) {
+	rec := testRecord(t)
+
+	tests := []struct {
+		input string
+		want  []int32
+	}{
+		{"id >= 3", []int32{3, 4}},
+		{"name != 'b'", []int32{1, 4}}, // nulls never match
+		{"score < 2 OR id = 4", []int32{1, 2, 4}},
+		{"score > 1 AND (name = a OR name = d)", []int32{4}},
+		{"id > 1.5", []int32{2, 3, 4}},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := expr.Validate(rec.Schema()); err != nil {
+			t.Fatalf("%s: %v", tt.input, err)
+		}
+		out, err := Apply(context.Background(), rec, expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.input, err)
+		}
+		got := out.Column(0).(*array.Int32).Int32Values()
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got ids %v, want %v", tt.input, got, tt.want)
+		} else {
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("%s: got ids %v, want %v", tt.input, got, tt.want)
+					break
+				}
+			}
+		}
+		out.Release()
+	}
+}
+
+func TestValidate(t *testing.T) {
+	rec := testRecord(t)
+	for _, input := range []string{"missing = 1", "id = 'x'", "name > 3", "score = true"} {
+		expr, err := Parse(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := expr.Validate(rec.Schema()); err == nil {
+			t.Errorf("%s: expected validation error", input)
+		}
+	}
+}
+
+type fakeStats struct {
+	min, max any
+	absent   map[any]bool
+}
+
+func (s fakeStats) MinMax(string) (any, any, bool)    { return s.min, s.max, s.min != nil }
+func (s fakeStats) MightContain(_ string, v any) bool { return !s.absent[v] }
+
+func TestMayMatch(t *testing.T) {
+	stats := fakeStats{min: int64(10), max: int64(20), absent: map[any]bool{int64(15): true}}
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"id = 5", false},
+		{"id = 12", true},
+		{"id = 15", false}, // excluded by the bloom filter
+		{"id < 10", false},
+		{"id <= 10", true},
+		{"id > 20", false},
+		{"id >= 20", true},
+		{"id != 12", true},
+		{"id = 5 OR id = 12", true},
+		{"id = 12 AND id > 30", false},
+		{"id = 2.5", false},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := expr.MayMatch(stats); got != tt.want {
+			t.Errorf("%s: MayMatch = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	if !(Comparison{Column: "id", Op: Eq, Value: int64(1)}).MayMatch(fakeStats{}) {
+		t.Error("expected unknown statistics to match")
+	}
+	if (Comparison{Column: "id", Op: Ne, Value: int64(7)}).MayMatch(fakeStats{min: int64(7), max: int64(7)}) {
+		t.Error("expected != to exclude a constant chunk")
+	}
+}
+
+func TestApplyDictionary(t *testing.T) {
+	dt := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int16, ValueType: arrow.BinaryTypes.String}
+	b := array.NewDictionaryBuilder(memory.NewGoAllocator(), dt).(*array.BinaryDictionaryBuilder)
+	defer b.Release()
+	for _, s := range []string{"x", "y", "x"} {
+		if err := b.AppendString(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	col := b.NewArray()
+	defer col.Release()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "tag", Type: dt}}, nil)
+	rec := array.NewRecord(schema, []arrow.Array{col}, 3)
+	defer rec.Release()
+
+	expr, err := Parse("tag = 'x'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expr.Validate(schema); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Apply(context.Background(), rec, expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	got, ok := out.Column(0).(*array.Dictionary)
+	if !ok || got.Len() != 2 || got.ValueStr(0) != "x" || got.ValueStr(1) != "x" {
+		t.Errorf("got %v, want a dictionary column [x x]", out.Column(0))
+	}
+}
@@ -0,0 +1,256 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse parses a filter expression such as
+//
+//	status = 'active' AND (age >= 18 OR vip = true)
+//
+// Comparisons take the form column op literal with op one of =, ==, !=, <>,
+// <, <=, > or >=. Literals are integers, floats, true/false, or strings in
+// single or double quotes; an unquoted literal that is not a number or
+// boolean is read as a string. AND binds tighter than OR and both keywords
+// are case-insensitive.
+func Parse(input string) (Expr, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+	}
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case strings.ContainsRune("=!<>", rune(c)):
+			start := i
+			for i < len(input) && strings.ContainsRune("=!<>", rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokOp, input[start:i], start})
+		case c == '\'' || c == '"':
+			start := i
+			var sb strings.Builder
+			for i++; ; i++ {
+				if i >= len(input) {
+					return nil, fmt.Errorf("unterminated string starting at offset %d", start)
+				}
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+				} else if input[i] == c {
+					i++
+					break
+				}
+				sb.WriteByte(input[i])
+			}
+			tokens = append(tokens, token{tokString, sb.String(), start})
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t\n\r()=!<>'\"", rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokWord, input[start:i], start})
+		}
+	}
+	return append(tokens, token{tokEOF, "end of input", len(input)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) keyword(kw string) bool {
+	tok := p.peek()
+	if tok.kind == tokWord && strings.EqualFold(tok.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	exprs := Or{left}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, right)
+	}
+	if len(exprs) == 1 {
+		return left, nil
+	}
+	return exprs, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	exprs := And{left}
+	for p.keyword("AND") {
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, right)
+	}
+	if len(exprs) == 1 {
+		return left, nil
+	}
+	return exprs, nil
+}
+
+func (p *parser) parseFactor() (Expr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("expected ) at offset %d, got %q", closing.pos, closing.text)
+		}
+		return expr, nil
+	case tokWord:
+		return p.parseComparison(tok.text)
+	}
+	return nil, fmt.Errorf("expected column name at offset %d, got %q", tok.pos, tok.text)
+}
+
+func (p *parser) parseComparison(column string) (Expr, error) {
+	tok := p.next()
+	if tok.kind != tokOp {
+		return nil, fmt.Errorf("expected comparison operator after %q at offset %d, got %q", column, tok.pos, tok.text)
+	}
+	var op Op
+	switch tok.text {
+	case "=", "==":
+		op = Eq
+	case "!=", "<>":
+		op = Ne
+	case "<":
+		op = Lt
+	case "<=":
+		op = Le
+	case ">":
+		op = Gt
+	case ">=":
+		op = Ge
+	default:
+		return nil, fmt.Errorf("unknown operator %q at offset %d", tok.text, tok.pos)
+	}
+
+	tok = p.next()
+	switch tok.kind {
+	case tokString:
+		return Comparison{Column: column, Op: op, Value: tok.text}, nil
+	case tokWord:
+		return Comparison{Column: column, Op: op, Value: parseLiteral(tok.text)}, nil
+	}
+	return nil, fmt.Errorf("expected literal at offset %d, got %q", tok.pos, tok.text)
+}
+
+func parseLiteral(text string) any {
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i
+	}
+	if u, err := strconv.ParseUint(text, 10, 64); err == nil {
+		return u
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f
+	}
+	switch strings.ToLower(text) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return text
+}
@@ -0,0 +1,149 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package filter
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// valueAt returns the non-null value at index i as one of the literal types.
+func valueAt(arr arrow.Array, i int) (any, error) {
+	switch a := arr.(type) {
+	case *array.Int8:
+		return int64(a.Value(i)), nil
+	case *array.Int16:
+		return int64(a.Value(i)), nil
+	case *array.Int32:
+		return int64(a.Value(i)), nil
+	case *array.Int64:
+		return a.Value(i), nil
+	case *array.Uint8:
+		return uint64(a.Value(i)), nil
+	case *array.Uint16:
+		return uint64(a.Value(i)), nil
+	case *array.Uint32:
+		return uint64(a.Value(i)), nil
+	case *array.Uint64:
+		return a.Value(i), nil
+	case *array.Float32:
+		return float64(a.Value(i)), nil
+	case *array.Float64:
+		return a.Value(i), nil
+	case *array.String:
+		return a.Value(i), nil
+	case *array.LargeString:
+		return a.Value(i), nil
+	case *array.Boolean:
+		return a.Value(i), nil
+	case *array.Dictionary:
+		return valueAt(a.Dictionary(), a.GetValueIndex(i))
+	}
+	return nil, fmt.Errorf("unsupported column type %s", arr.DataType())
+}
+
+// zeroValue returns a value of the literal type that columns of dt map to.
+func zeroValue(dt arrow.DataType) (any, error) {
+	switch dt.ID() {
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64:
+		return int64(0), nil
+	case arrow.UINT8, arrow.UINT16, arrow.UINT32, arrow.UINT64:
+		return uint64(0), nil
+	case arrow.FLOAT32, arrow.FLOAT64:
+		return float64(0), nil
+	case arrow.STRING, arrow.LARGE_STRING:
+		return "", nil
+	case arrow.BOOL:
+		return false, nil
+	case arrow.DICTIONARY:
+		return zeroValue(dt.(*arrow.DictionaryType).ValueType)
+	}
+	return nil, fmt.Errorf("unsupported column type %s", dt)
+}
+
+// compareValues orders a column value a against a literal b. Numbers of
+// different kinds compare by value; other kinds must match exactly.
+func compareValues(a, b any) (int, error) {
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return cmp.Compare(x, y), nil
+		case uint64:
+			return -compareUintInt(y, x), nil
+		case float64:
+			return cmp.Compare(float64(x), y), nil
+		}
+	case uint64:
+		switch y := b.(type) {
+		case int64:
+			return compareUintInt(x, y), nil
+		case uint64:
+			return cmp.Compare(x, y), nil
+		case float64:
+			return cmp.Compare(float64(x), y), nil
+		}
+	case float64:
+		switch y := b.(type) {
+		case int64:
+			return cmp.Compare(x, float64(y)), nil
+		case uint64:
+			return cmp.Compare(x, float64(y)), nil
+		case float64:
+			return cmp.Compare(x, y), nil
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return cmp.Compare(x, y), nil
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, nil
+			case !x:
+				return -1, nil
+			default:
+				return 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %T with %T literal", a, b)
+}
+
+func compareUintInt(u uint64, i int64) int {
+	if i < 0 {
+		return 1
+	}
+	return cmp.Compare(u, uint64(i))
+}
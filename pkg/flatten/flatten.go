@@ -0,0 +1,415 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package flatten reshapes nested records for destinations that hold only
+// flat columns, such as CSV and many warehouses: struct columns become
+// top-level columns, and list columns can be exploded into one row per
+// element.
+package flatten
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/bitutil"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+)
+
+// DefaultSeparator joins the name of a struct column and its fields.
+const DefaultSeparator = "_"
+
+// Options selects the columns to flatten and explode.
+type Options struct {
+	// Separator joins the name of a struct column and the names of its
+	// fields, so that city.name becomes city_name. Defaults to
+	// DefaultSeparator.
+	Separator string
+	// Flatten lists the struct columns to flatten, along with the structs
+	// nested in them. When empty, every struct column is flattened.
+	Flatten []string
+	// Explode lists the list and map columns to explode, by their
+	// flattened names. Each element gets a row of its own, holding copies
+	// of the other columns; a null or empty list gives one row with a null
+	// element. Map entries become structs of their key and value, which are
+	// flattened in turn.
+	Explode []string
+}
+
+// Transformer is a pipeline Transformer flattening and exploding the
+// configured columns. Other columns pass through unchanged. A Transformer
+// is not safe for concurrent use.
+type Transformer struct {
+	opts    Options
+	flatten map[string]bool
+	explode map[string]bool
+	mem     memory.Allocator
+}
+
+// New creates a Transformer. opts may be nil to flatten every struct
+// column and explode none.
+func New(opts *Options) (*Transformer, error) {
+	t := &Transformer{mem: memory.DefaultAllocator}
+	if opts != nil {
+		t.opts = *opts
+	}
+	if t.opts.Separator == "" {
+		t.opts.Separator = DefaultSeparator
+	}
+	if len(t.opts.Flatten) > 0 {
+		t.flatten = make(map[string]bool, len(t.opts.Flatten))
+		for _, name := range t.opts.Flatten {
+			t.flatten[name] = true
+		}
+	}
+	t.explode = make(map[string]bool, len(t.opts.Explode))
+	for _, name := range t.opts.Explode {
+		if name == "" {
+			return nil, fmt.Errorf("column name cannot be empty")
+		}
+		t.explode[name] = true
+	}
+	return t, nil
+}
+
+// FromConfig creates a Transformer from a workflow `transform: flatten`
+// entry: columns with the flatten method are flattened, those with the
+// explode method exploded.
+func FromConfig(t config.Transform) (*Transformer, error) {
+	if t.Transform != config.TransformFlatten {
+		return nil, fmt.Errorf("expected a %q transform, got %q", config.TransformFlatten, t.Transform)
+	}
+	opts := &Options{Separator: t.Separator}
+	for _, col := range t.Columns {
+		switch col.Method {
+		case config.FlattenStruct:
+			opts.Flatten = append(opts.Flatten, col.Name)
+		case config.FlattenExplode:
+			opts.Explode = append(opts.Explode, col.Name)
+		default:
+			return nil, fmt.Errorf("column %q: unknown flatten method %q", col.Name, col.Method)
+		}
+	}
+	return New(opts)
+}
+
+// Schema returns the schema of the records Transform produces for records
+// with schema src.
+func (t *Transformer) Schema(src *arrow.Schema) (*arrow.Schema, error) {
+	fields, _, err := t.reshape(src.Fields(), nil)
+	if err != nil {
+		return nil, err
+	}
+	md := src.Metadata()
+	return arrow.NewSchema(fields, &md), nil
+}
+
+// Transform returns record with the configured columns flattened and
+// exploded.
+func (t *Transformer) Transform(record arrow.Record) (arrow.Record, error) {
+	cols := slices.Clone(record.Columns())
+	for _, col := range cols {
+		col.Retain()
+	}
+	fields, cols, err := t.reshape(record.Schema().Fields(), cols)
+	defer func() {
+		for _, col := range cols {
+			col.Release()
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+	rows := record.NumRows()
+	if len(cols) > 0 {
+		rows = int64(cols[0].Len())
+	}
+	md := record.Schema().Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &md), cols, rows), nil
+}
+
+// reshape flattens and explodes fields, and their columns if cols is not
+// nil, until no selected column is left. It takes over cols and returns
+// the columns to release.
+func (t *Transformer) reshape(fields []arrow.Field, cols []arrow.Array) ([]arrow.Field, []arrow.Array, error) {
+	// flattened holds the columns selected for flattening, the nested
+	// structs of the selected structs among them.
+	flattened := make(map[string]bool, len(t.flatten))
+	for name := range t.flatten {
+		flattened[name] = true
+	}
+	found := make(map[string]bool, len(t.explode))
+	for {
+		changed := false
+		var outFields []arrow.Field
+		var outCols []arrow.Array
+		for i, f := range fields {
+			var col arrow.Array
+			if cols != nil {
+				col = cols[i]
+			}
+			if _, ok := f.Type.(*arrow.StructType); ok && (t.flatten == nil || flattened[f.Name]) {
+				changed = true
+				childFields, childCols := t.flattenStruct(f, col)
+				for _, child := range childFields {
+					flattened[child.Name] = true
+				}
+				outFields = append(outFields, childFields...)
+				outCols = append(outCols, childCols...)
+				continue
+			}
+			outFields = append(outFields, f)
+			if col != nil {
+				outCols = append(outCols, col)
+			}
+		}
+		fields, cols = outFields, outCols
+
+		for i, f := range fields {
+			list, ok := f.Type.(arrow.ListLikeType)
+			if !ok || !t.explode[f.Name] {
+				continue
+			}
+			found[f.Name] = true
+			changed = true
+			elem := list.ElemField()
+			fields[i] = arrow.Field{Name: f.Name, Type: elem.Type, Nullable: true, Metadata: elem.Metadata}
+			flattened[f.Name] = true
+			if cols != nil {
+				exploded, err := t.explodeColumn(cols, i)
+				if err != nil {
+					for _, col := range cols {
+						col.Release()
+					}
+					return nil, nil, fmt.Errorf("column %q: %w", f.Name, err)
+				}
+				cols = exploded
+			}
+			// Explode one column at a time, so that each multiplies the
+			// rows of the others.
+			break
+		}
+		if !changed {
+			break
+		}
+	}
+	for _, name := range t.opts.Explode {
+		if found[name] {
+			continue
+		}
+		for _, col := range cols {
+			col.Release()
+		}
+		if slices.ContainsFunc(fields, func(f arrow.Field) bool { return f.Name == name }) {
+			return nil, nil, fmt.Errorf("column %q is not a list or map", name)
+		}
+		return nil, nil, fmt.Errorf("column %q not found", name)
+	}
+	return fields, cols, nil
+}
+
+// flattenStruct returns the fields of the struct column f, named after it,
+// and their columns if col is not nil. Rows where the struct is null are
+// null in each of them. It takes over col.
+func (t *Transformer) flattenStruct(f arrow.Field, col arrow.Array) ([]arrow.Field, []arrow.Array) {
+	st := f.Type.(*arrow.StructType)
+	fields := make([]arrow.Field, st.NumFields())
+	for i, child := range st.Fields() {
+		child.Name = f.Name + t.opts.Separator + child.Name
+		child.Nullable = child.Nullable || f.Nullable
+		fields[i] = child
+	}
+	if col == nil {
+		return fields, nil
+	}
+	defer col.Release()
+	s := col.(*array.Struct)
+	cols := make([]arrow.Array, s.NumField())
+	for i := range cols {
+		cols[i] = t.withParentNulls(s, s.Field(i))
+	}
+	return fields, cols
+}
+
+// withParentNulls returns child, a field of s, null where s is null.
+func (t *Transformer) withParentNulls(s *array.Struct, child arrow.Array) arrow.Array {
+	if s.NullN() == 0 {
+		child.Retain()
+		return child
+	}
+	parent, data := s.Data(), child.Data()
+	n, offset := data.Len(), data.Offset()
+	// The bitmap starts at the child's offset, so that its other buffers
+	// can be shared as they are.
+	var validity *memory.Buffer
+	if bitmap := data.Buffers()[0]; bitmap == nil || data.NullN() == 0 {
+		validity = memory.NewResizableBuffer(t.mem)
+		validity.Resize(int(bitutil.BytesForBits(int64(offset + n))))
+		bitutil.CopyBitmap(parent.Buffers()[0].Bytes(), parent.Offset(), n, validity.Bytes(), offset)
+	} else {
+		validity = bitutil.BitmapAndAlloc(t.mem, parent.Buffers()[0].Bytes(), bitmap.Bytes(),
+			int64(parent.Offset()), int64(offset), int64(n), int64(offset))
+	}
+	defer validity.Release()
+	buffers := slices.Clone(data.Buffers())
+	buffers[0] = validity
+	out := array.NewData(data.DataType(), n, buffers, data.Children(), array.UnknownNullCount, offset)
+	defer out.Release()
+	return array.MakeFromData(out)
+}
+
+// explodeColumn returns cols with one row per element of the list column
+// at index i. It takes over cols.
+func (t *Transformer) explodeColumn(cols []arrow.Array, i int) ([]arrow.Array, error) {
+	defer func() {
+		for _, col := range cols {
+			col.Release()
+		}
+	}()
+	list := cols[i].(array.ListLike)
+	rowIdx := array.NewInt64Builder(t.mem)
+	defer rowIdx.Release()
+	elemIdx := array.NewInt64Builder(t.mem)
+	defer elemIdx.Release()
+	for row := 0; row < list.Len(); row++ {
+		start, end := list.ValueOffsets(row)
+		if list.IsNull(row) || start == end {
+			rowIdx.Append(int64(row))
+			elemIdx.AppendNull()
+			continue
+		}
+		for j := start; j < end; j++ {
+			rowIdx.Append(int64(row))
+			elemIdx.Append(j)
+		}
+	}
+	rows := rowIdx.NewArray()
+	defer rows.Release()
+	elems := elemIdx.NewArray()
+	defer elems.Release()
+
+	ctx := compute.WithAllocator(context.Background(), t.mem)
+	out := make([]arrow.Array, len(cols))
+	for j, col := range cols {
+		var err error
+		if j == i {
+			out[j], err = take(ctx, list.ListValues(), elems)
+		} else {
+			out[j], err = take(ctx, col, rows)
+		}
+		if err != nil {
+			for _, col := range out[:j] {
+				col.Release()
+			}
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// take is compute.TakeArray, which has no kernel for maps: they are taken
+// as the lists of key and value structs they are stored as.
+func take(ctx context.Context, values, indices arrow.Array) (arrow.Array, error) {
+	dt := values.DataType()
+	listType := mapsAsLists(dt)
+	if arrow.TypeEqual(listType, dt) {
+		return compute.TakeArray(ctx, values, indices)
+	}
+	view := retype(values.Data(), listType)
+	defer view.Release()
+	viewArr := array.MakeFromData(view)
+	defer viewArr.Release()
+	taken, err := compute.TakeArray(ctx, viewArr, indices)
+	if err != nil {
+		return nil, err
+	}
+	defer taken.Release()
+	data := retype(taken.Data(), dt)
+	defer data.Release()
+	return array.MakeFromData(data), nil
+}
+
+// mapsAsLists returns dt with its map types replaced by lists of their
+// entries.
+func mapsAsLists(dt arrow.DataType) arrow.DataType {
+	switch dt := dt.(type) {
+	case *arrow.MapType:
+		elem := dt.ElemField()
+		elem.Type = mapsAsLists(elem.Type)
+		return arrow.ListOfField(elem)
+	case *arrow.ListType:
+		elem := dt.ElemField()
+		elem.Type = mapsAsLists(elem.Type)
+		return arrow.ListOfField(elem)
+	case *arrow.LargeListType:
+		elem := dt.ElemField()
+		elem.Type = mapsAsLists(elem.Type)
+		return arrow.LargeListOfField(elem)
+	case *arrow.FixedSizeListType:
+		elem := dt.ElemField()
+		elem.Type = mapsAsLists(elem.Type)
+		return arrow.FixedSizeListOfField(dt.Len(), elem)
+	case *arrow.StructType:
+		fields := dt.Fields()
+		for i := range fields {
+			fields[i].Type = mapsAsLists(fields[i].Type)
+		}
+		return arrow.StructOf(fields...)
+	}
+	return dt
+}
+
+// retype returns data with type dt, which must share its layout.
+func retype(data arrow.ArrayData, dt arrow.DataType) arrow.ArrayData {
+	var childTypes []arrow.DataType
+	switch dt := dt.(type) {
+	case arrow.ListLikeType:
+		childTypes = []arrow.DataType{dt.Elem()}
+	case *arrow.StructType:
+		for _, f := range dt.Fields() {
+			childTypes = append(childTypes, f.Type)
+		}
+	default:
+		// Leaves are the same on both sides; dictionaries keep their values.
+		data.Retain()
+		return data
+	}
+	children := slices.Clone(data.Children())
+	for i, child := range children {
+		if i < len(childTypes) {
+			children[i] = retype(child, childTypes[i])
+			defer children[i].Release()
+		}
+	}
+	return array.NewData(dt, data.Len(), data.Buffers(), children, data.NullN(), data.Offset())
+}
@@ -0,0 +1,178 @@
+package flatten
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"gopkg.in/yaml.v3"
+)
+
+var nested = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "city", Type: arrow.StructOf(
+		arrow.Field{Name: "name", Type: arrow.BinaryTypes.String},
+		arrow.Field{Name: "geo", Type: arrow.StructOf(
+			arrow.Field{Name: "lat", Type: arrow.PrimitiveTypes.Float64},
+		)},
+	), Nullable: true},
+	{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+	{Name: "attrs", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int64), Nullable: true},
+}, nil)
+
+func nestedRecord(t *testing.T) arrow.Record {
+	t.Helper()
+	record, _, err := array.RecordFromJSON(memory.NewGoAllocator(), nested, strings.NewReader(`[
+		{"id": 1, "city": {"name": "Oslo", "geo": {"lat": 59.9}}, "tags": ["a", "b"], "attrs": [{"key": "x", "value": 1}]},
+		{"id": 2, "city": null, "tags": [], "attrs": null},
+		{"id": 3, "city": {"name": "Rome", "geo": {"lat": 41.9}}, "tags": null, "attrs": [{"key": "y", "value": 2}, {"key": "z", "value": 3}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func transform(t *testing.T, opts *Options) (arrow.Record, string) {
+	t.Helper()
+	tr, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := nestedRecord(t)
+	defer record.Release()
+	out, err := tr.Transform(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := tr.Schema(nested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !schema.Equal(out.Schema()) {
+		t.Fatalf("Schema() = %s, Transform() schema = %s", schema, out.Schema())
+	}
+	var buf bytes.Buffer
+	if err := array.RecordToJSON(out, &buf); err != nil {
+		t.Fatal(err)
+	}
+	return out, buf.String()
+}
+
+func TestFlattenStructs(t *testing.T) {
+	out, got := transform(t, nil)
+	defer out.Release()
+	var names []string
+	for _, f := range out.Schema().Fields() {
+		names = append(names, f.Name)
+	}
+	if want := "id,city_name,city_geo_lat,tags,attrs"; strings.Join(names, ",") != want {
+		t.Fatalf("columns = %v, want %s", names, want)
+	}
+	if !out.Schema().Field(1).Nullable {
+		t.Error("city_name is not nullable though city is")
+	}
+	want := `{"attrs":[{"key":"x","value":1}],"city_geo_lat":59.9,"city_name":"Oslo","id":1,"tags":["a","b"]}
+{"attrs":null,"city_geo_lat":null,"city_name":null,"id":2,"tags":[]}
+{"attrs":[{"key":"y","value":2},{"key":"z","value":3}],"city_geo_lat":41.9,"city_name":"Rome","id":3,"tags":null}
+`
+	if got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFlattenSelectedWithSeparator(t *testing.T) {
+	out, _ := transform(t, &Options{Separator: ".", Flatten: []string{"city"}})
+	defer out.Release()
+	if out.NumCols() != 5 || out.Schema().Field(2).Name != "city.geo.lat" {
+		t.Fatalf("schema = %s", out.Schema())
+	}
+}
+
+func TestExplode(t *testing.T) {
+	out, got := transform(t, &Options{Explode: []string{"tags", "attrs"}})
+	defer out.Release()
+	want := `{"attrs_key":"x","attrs_value":1,"city_geo_lat":59.9,"city_name":"Oslo","id":1,"tags":"a"}
+{"attrs_key":"x","attrs_value":1,"city_geo_lat":59.9,"city_name":"Oslo","id":1,"tags":"b"}
+{"attrs_key":null,"attrs_value":null,"city_geo_lat":null,"city_name":null,"id":2,"tags":null}
+{"attrs_key":"y","attrs_value":2,"city_geo_lat":41.9,"city_name":"Rome","id":3,"tags":null}
+{"attrs_key":"z","attrs_value":3,"city_geo_lat":41.9,"city_name":"Rome","id":3,"tags":null}
+`
+	if got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExplodeSlicedRecord(t *testing.T) {
+	tr, err := New(&Options{Explode: []string{"tags"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := nestedRecord(t)
+	defer record.Release()
+	sliced := record.NewSlice(1, 3)
+	defer sliced.Release()
+	out, err := tr.Transform(sliced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	var buf bytes.Buffer
+	if err := array.RecordToJSON(out, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"attrs":null,"city_geo_lat":null,"city_name":null,"id":2,"tags":null}
+{"attrs":[{"key":"y","value":2},{"key":"z","value":3}],"city_geo_lat":41.9,"city_name":"Rome","id":3,"tags":null}
+`
+	if buf.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestExplodeErrors(t *testing.T) {
+	for _, name := range []string{"missing", "id"} {
+		tr, err := New(&Options{Explode: []string{name}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tr.Schema(nested); err == nil {
+			t.Errorf("exploding %q: expected an error", name)
+		}
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	var transform config.Transform
+	err := yaml.Unmarshal([]byte(`
+transform: flatten
+separator: "__"
+columns:
+  - name: city
+    method: flatten
+  - name: tags
+    method: explode
+`), &transform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := FromConfig(transform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := tr.Schema(nested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Field(1).Name != "city__name" || !arrow.TypeEqual(schema.Field(3).Type, arrow.BinaryTypes.String) {
+		t.Fatalf("schema = %s", schema)
+	}
+
+	transform.Columns[0].Method = "unnest"
+	if _, err := FromConfig(transform); err == nil {
+		t.Fatal("expected an error for an unknown method")
+	}
+}
@@ -0,0 +1,142 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package gcpauth selects the credentials of the Google Cloud clients of
+// the BigQuery, GCS and Pub/Sub integrations, so that each takes the same
+// options.
+package gcpauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+// DefaultScope is the scope of impersonated credentials unless
+// Options.Scopes sets others.
+const DefaultScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Options selects the credentials of a client. With no field set, it uses
+// Application Default Credentials. At most one of CredentialsFile and
+// CredentialsJSON may be set; Impersonate applies to either, or to ADC.
+type Options struct {
+	// CredentialsFile is the path of a service account key file.
+	CredentialsFile string
+	// CredentialsJSON is the content of a service account key file.
+	CredentialsJSON []byte
+	// Impersonate is the email of a service account the credentials act
+	// as, through a chain of Delegates if set.
+	Impersonate string
+	Delegates   []string
+	// Scopes replaces the default scopes of the client.
+	Scopes []string
+}
+
+// FromKey returns options using key, the path of a service account key
+// file or the key itself.
+func FromKey(key string) *Options {
+	if _, err := os.Stat(key); err == nil {
+		return &Options{CredentialsFile: key}
+	}
+	return &Options{CredentialsJSON: []byte(key)}
+}
+
+// FromConfig returns the options of an integration's auth settings, with
+// environment variables expanded, or nil, for ADC, if there are none.
+func FromConfig(s *config.GCPAuthSettings) *Options {
+	if s == nil {
+		return nil
+	}
+	o := &Options{
+		CredentialsFile: os.ExpandEnv(s.CredentialsFile),
+		Impersonate:     os.ExpandEnv(s.Impersonate),
+		Delegates:       s.Delegates,
+		Scopes:          s.Scopes,
+	}
+	if s.CredentialsJSON != "" {
+		o.CredentialsJSON = []byte(os.ExpandEnv(s.CredentialsJSON))
+	}
+	return o
+}
+
+// Validate reports conflicting options.
+func (o *Options) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.CredentialsFile != "" && len(o.CredentialsJSON) > 0 {
+		return errors.New("set either a credentials file or credentials JSON, not both")
+	}
+	if len(o.Delegates) > 0 && o.Impersonate == "" {
+		return errors.New("delegates need a service account to impersonate")
+	}
+	return nil
+}
+
+// ClientOptions returns the client options authenticating as o says,
+// followed by extra. The former are empty for ADC, including when o is nil.
+func (o *Options) ClientOptions(ctx context.Context, extra ...option.ClientOption) ([]option.ClientOption, error) {
+	if o == nil {
+		return extra, nil
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	var opts []option.ClientOption
+	switch {
+	case o.CredentialsFile != "":
+		opts = append(opts, option.WithCredentialsFile(o.CredentialsFile))
+	case len(o.CredentialsJSON) > 0:
+		opts = append(opts, option.WithCredentialsJSON(o.CredentialsJSON))
+	}
+	if o.Impersonate == "" {
+		if len(o.Scopes) > 0 {
+			opts = append(opts, option.WithScopes(o.Scopes...))
+		}
+		return append(opts, extra...), nil
+	}
+	scopes := o.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{DefaultScope}
+	}
+	ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: o.Impersonate,
+		Delegates:       o.Delegates,
+		Scopes:          scopes,
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to impersonate %s: %w", o.Impersonate, err)
+	}
+	return append([]option.ClientOption{option.WithTokenSource(ts)}, extra...), nil
+}
@@ -0,0 +1,87 @@
+package gcpauth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"google.golang.org/api/option"
+)
+
+func TestFromKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(path, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if o := FromKey(path); o.CredentialsFile != path || o.CredentialsJSON != nil {
+		t.Errorf("FromKey(path) = %+v", o)
+	}
+	if o := FromKey(`{"type":"service_account"}`); o.CredentialsFile != "" || string(o.CredentialsJSON) != `{"type":"service_account"}` {
+		t.Errorf("FromKey(json) = %+v", o)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	if o := FromConfig(nil); o != nil {
+		t.Errorf("FromConfig(nil) = %+v", o)
+	}
+	t.Setenv("GCPAUTH_TEST_SA", "loader@project.iam.gserviceaccount.com")
+	o := FromConfig(&config.GCPAuthSettings{
+		CredentialsFile: "/keys/${GCPAUTH_TEST_SA}.json",
+		Impersonate:     "${GCPAUTH_TEST_SA}",
+		Scopes:          []string{"https://www.googleapis.com/auth/bigquery"},
+	})
+	if o.CredentialsFile != "/keys/loader@project.iam.gserviceaccount.com.json" || o.Impersonate != "loader@project.iam.gserviceaccount.com" {
+		t.Errorf("variables not expanded: %+v", o)
+	}
+	if o.CredentialsJSON != nil {
+		t.Errorf("CredentialsJSON = %q, want nil", o.CredentialsJSON)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		o    *Options
+		ok   bool
+	}{
+		{"nil", nil, true},
+		{"adc", &Options{}, true},
+		{"file", &Options{CredentialsFile: "key.json", Impersonate: "sa@p.iam.gserviceaccount.com"}, true},
+		{"file and json", &Options{CredentialsFile: "key.json", CredentialsJSON: []byte(`{}`)}, false},
+		{"delegates alone", &Options{Delegates: []string{"a@p.iam.gserviceaccount.com"}}, false},
+	} {
+		if err := tc.o.Validate(); (err == nil) != tc.ok {
+			t.Errorf("%s: Validate() = %v", tc.name, err)
+		}
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	ctx := context.Background()
+	extra := option.WithEndpoint("http://localhost:9050")
+	for _, tc := range []struct {
+		name string
+		o    *Options
+		want int
+	}{
+		{"nil", nil, 1},
+		{"adc", &Options{}, 1},
+		{"adc with scopes", &Options{Scopes: []string{DefaultScope}}, 2},
+		{"file", &Options{CredentialsFile: "key.json"}, 2},
+		{"json", &Options{CredentialsJSON: []byte(`{}`)}, 2},
+	} {
+		opts, err := tc.o.ClientOptions(ctx, extra)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(opts) != tc.want || opts[len(opts)-1] != extra {
+			t.Errorf("%s: got %d options, want %d ending with the extra one", tc.name, len(opts), tc.want)
+		}
+	}
+	if _, err := (&Options{CredentialsFile: "key.json", CredentialsJSON: []byte(`{}`)}).ClientOptions(ctx); err == nil {
+		t.Error("conflicting options accepted")
+	}
+}
@@ -0,0 +1,161 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package geo
+
+import (
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// FromSchema returns the GeoParquet metadata of schema, or nil if it has
+// none.
+func FromSchema(schema *arrow.Schema) (*Metadata, error) {
+	md := schema.Metadata()
+	i := md.FindKey(MetadataKey)
+	if i < 0 {
+		return nil, nil
+	}
+	return ParseMetadata(md.Values()[i])
+}
+
+// Columns returns the indices of the top-level WKB geometry columns of
+// schema, as its GeoParquet metadata names them.
+func Columns(schema *arrow.Schema) []int {
+	m, err := FromSchema(schema)
+	if err != nil || m == nil {
+		return nil
+	}
+	var indices []int
+	for _, name := range m.Names() {
+		if m.Columns[name].Encoding != EncodingWKB {
+			continue
+		}
+		for _, i := range schema.FieldIndices(name) {
+			if isBinary(schema.Field(i).Type) {
+				indices = append(indices, i)
+			}
+		}
+	}
+	return indices
+}
+
+func isBinary(dt arrow.DataType) bool {
+	return dt.ID() == arrow.BINARY || dt.ID() == arrow.LARGE_BINARY
+}
+
+// WKTSchema returns schema with its WKB geometry columns as strings,
+// without the GeoParquet metadata, or schema itself if it has none.
+func WKTSchema(schema *arrow.Schema) *arrow.Schema {
+	indices := Columns(schema)
+	if indices == nil {
+		return schema
+	}
+	fields := schema.Fields()
+	for _, i := range indices {
+		fields[i].Type = arrow.BinaryTypes.String
+	}
+	md := schema.Metadata()
+	keys, values := md.Keys(), md.Values()
+	var outKeys, outValues []string
+	for i, k := range keys {
+		if k != MetadataKey {
+			outKeys = append(outKeys, k)
+			outValues = append(outValues, values[i])
+		}
+	}
+	out := arrow.NewMetadata(outKeys, outValues)
+	return arrow.NewSchema(fields, &out)
+}
+
+// WKTRecord returns record with the values of its WKB geometry columns as
+// WKT strings. If there are none it returns record, retained.
+func WKTRecord(mem memory.Allocator, record arrow.Record) (arrow.Record, error) {
+	schema := WKTSchema(record.Schema())
+	if schema == record.Schema() {
+		record.Retain()
+		return record, nil
+	}
+	cols := make([]arrow.Array, record.NumCols())
+	defer func() {
+		for _, col := range cols {
+			if col != nil {
+				col.Release()
+			}
+		}
+	}()
+	for i, col := range record.Columns() {
+		if schema.Field(i).Type.ID() == col.DataType().ID() {
+			col.Retain()
+			cols[i] = col
+			continue
+		}
+		wkt, err := wktArray(mem, col)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", schema.Field(i).Name, err)
+		}
+		cols[i] = wkt
+	}
+	return array.NewRecord(schema, cols, record.NumRows()), nil
+}
+
+// wktArray returns the WKB values of col as WKT.
+func wktArray(mem memory.Allocator, col arrow.Array) (arrow.Array, error) {
+	b := array.NewStringBuilder(mem)
+	defer b.Release()
+	b.Reserve(col.Len())
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		wkt, err := WKT(col, i)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		b.Append(wkt)
+	}
+	return b.NewArray(), nil
+}
+
+// WKT returns the WKT of the WKB value at row i of col, a binary column.
+func WKT(col arrow.Array, i int) (string, error) {
+	values, ok := col.(interface{ Value(int) []byte })
+	if !ok {
+		return "", fmt.Errorf("geometry column of type %s", col.DataType())
+	}
+	g, err := ParseWKB(values.Value(i))
+	if err != nil {
+		return "", err
+	}
+	return g.WKT(), nil
+}
@@ -0,0 +1,139 @@
+package geo
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestWKTRoundTrip(t *testing.T) {
+	for _, wkt := range []string{
+		"POINT (1 2)",
+		"POINT Z (1 2 3)",
+		"POINT EMPTY",
+		"LINESTRING (0 0, 1.5 -2, 3 4)",
+		"LINESTRING M (0 0 1, 1 1 2)",
+		"POLYGON ((0 0, 4 0, 4 4, 0 4, 0 0), (1 1, 2 1, 2 2, 1 1))",
+		"MULTIPOINT ((1 2), (3 4))",
+		"MULTILINESTRING ((0 0, 1 1), (2 2, 3 3))",
+		"MULTIPOLYGON (((0 0, 1 0, 1 1, 0 0)), ((5 5, 6 5, 6 6, 5 5)))",
+		"GEOMETRYCOLLECTION (POINT (1 2), LINESTRING (0 0, 1 1))",
+		"GEOMETRYCOLLECTION EMPTY",
+		"POLYGON ZM ((0 0 1 2, 1 0 1 2, 1 1 1 2, 0 0 1 2))",
+	} {
+		g, err := ParseWKT(wkt)
+		if err != nil {
+			t.Fatalf("ParseWKT(%q): %v", wkt, err)
+		}
+		if got := g.WKT(); got != wkt {
+			t.Errorf("WKT of %q = %q", wkt, got)
+		}
+		back, err := ParseWKB(g.WKB())
+		if err != nil {
+			t.Fatalf("ParseWKB of %q: %v", wkt, err)
+		}
+		if got := back.WKT(); got != wkt {
+			t.Errorf("WKB round trip of %q = %q", wkt, got)
+		}
+	}
+}
+
+func TestParseWKTVariants(t *testing.T) {
+	for in, want := range map[string]string{
+		"point(1 2)":                    "POINT (1 2)",
+		"POINTZ (1 2 3)":                "POINT Z (1 2 3)",
+		"POINT (1 2 3)":                 "POINT Z (1 2 3)",
+		"MULTIPOINT (1 2, 3 4)":         "MULTIPOINT ((1 2), (3 4))",
+		"SRID=4326;POINT(-122.35 47.6)": "POINT (-122.35 47.6)",
+	} {
+		g, err := ParseWKT(in)
+		if err != nil {
+			t.Fatalf("ParseWKT(%q): %v", in, err)
+		}
+		if got := g.WKT(); got != want {
+			t.Errorf("ParseWKT(%q) = %q, want %q", in, got, want)
+		}
+	}
+	for _, in := range []string{"", "POINT", "POINT (1)", "CIRCLE (1 2)", "POINT (1 2) x", "LINESTRING (0 0, 1 1"} {
+		if _, err := ParseWKT(in); !errors.Is(err, ErrInvalidWKT) {
+			t.Errorf("ParseWKT(%q): expected ErrInvalidWKT, got %v", in, err)
+		}
+	}
+}
+
+func TestParseWKB(t *testing.T) {
+	// POINT (1 2), big endian.
+	big, _ := hex.DecodeString("00000000013ff00000000000004000000000000000")
+	// Extended WKB POINT Z (1 2 3) with SRID 4326.
+	ewkb, _ := hex.DecodeString("01010000a0e6100000000000000000f03f00000000000000400000000000000840")
+	for b, want := range map[string]string{string(big): "POINT (1 2)", string(ewkb): "POINT Z (1 2 3)"} {
+		g, err := ParseWKB([]byte(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := g.WKT(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	for _, b := range [][]byte{nil, {1}, {2, 1, 0, 0, 0}, {1, 2, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}} {
+		if _, err := ParseWKB(b); !errors.Is(err, ErrInvalidWKB) {
+			t.Errorf("ParseWKB(%x): expected ErrInvalidWKB, got %v", b, err)
+		}
+	}
+}
+
+func TestColumnAdd(t *testing.T) {
+	var c Column
+	for _, wkt := range []string{"POINT (1 5)", "LINESTRING (-1 0, 2 3)", "POINT Z (0 9 1)", "POINT EMPTY"} {
+		g, err := ParseWKT(wkt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Add(g)
+	}
+	if got := strings.Join(c.GeometryTypes, ","); got != "LineString,Point,Point Z" {
+		t.Errorf("geometry types: got %s", got)
+	}
+	if want := []float64{-1, 0, 2, 9}; len(c.BBox) != 4 || c.BBox[0] != want[0] || c.BBox[1] != want[1] || c.BBox[2] != want[2] || c.BBox[3] != want[3] {
+		t.Errorf("bbox: got %v, want %v", c.BBox, want)
+	}
+}
+
+func TestWKTRecord(t *testing.T) {
+	md := arrow.NewMetadata([]string{MetadataKey, "other"}, []string{`{"version":"1.1.0","primary_column":"geom","columns":{"geom":{"encoding":"WKB","geometry_types":[]}}}`, "x"})
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "geom", Type: arrow.BinaryTypes.Binary, Nullable: true},
+	}, &md)
+	point, err := ParseWKT("POINT (1 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	b.Field(1).(*array.BinaryBuilder).Append(point.WKB())
+	b.Field(1).(*array.BinaryBuilder).AppendNull()
+	record := b.NewRecord()
+	defer record.Release()
+
+	out, err := WKTRecord(memory.NewGoAllocator(), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	if out.Column(1).DataType().ID() != arrow.STRING || out.Column(1).ValueStr(0) != "POINT (1 2)" || !out.Column(1).IsNull(1) {
+		t.Errorf("geom: got %v", out.Column(1))
+	}
+	if out.Schema().HasMetadata() && out.Schema().Metadata().FindKey(MetadataKey) >= 0 {
+		t.Errorf("GeoParquet metadata kept in %v", out.Schema().Metadata())
+	}
+	if out.Schema().Metadata().FindKey("other") < 0 {
+		t.Errorf("other metadata dropped")
+	}
+}
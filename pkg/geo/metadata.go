@@ -0,0 +1,132 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// MetadataKey is the key of the GeoParquet metadata in the key-value
+// metadata of a Parquet file, and in the metadata of an Arrow schema.
+const MetadataKey = "geo"
+
+// Version is the GeoParquet version of the metadata written.
+const Version = "1.1.0"
+
+// EncodingWKB is the GeoParquet encoding of geometries as WKB.
+const EncodingWKB = "WKB"
+
+// Metadata is the GeoParquet file metadata: the geometry columns and which
+// of them is the primary one.
+type Metadata struct {
+	Version       string             `json:"version"`
+	PrimaryColumn string             `json:"primary_column"`
+	Columns       map[string]*Column `json:"columns"`
+}
+
+// Column describes a geometry column. CRS, Orientation, Edges and Epoch
+// are kept as read; GeometryTypes and BBox sum up the values written.
+type Column struct {
+	Encoding      string          `json:"encoding"`
+	GeometryTypes []string        `json:"geometry_types"`
+	CRS           json.RawMessage `json:"crs,omitempty"`
+	Orientation   string          `json:"orientation,omitempty"`
+	Edges         string          `json:"edges,omitempty"`
+	BBox          []float64       `json:"bbox,omitempty"`
+	Epoch         *float64        `json:"epoch,omitempty"`
+}
+
+// ParseMetadata parses GeoParquet metadata.
+func ParseMetadata(data string) (*Metadata, error) {
+	var m Metadata
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		return nil, fmt.Errorf("invalid GeoParquet metadata: %w", err)
+	}
+	if len(m.Columns) == 0 {
+		return nil, fmt.Errorf("invalid GeoParquet metadata: no columns")
+	}
+	for name, c := range m.Columns {
+		if c == nil || c.Encoding == "" {
+			return nil, fmt.Errorf("invalid GeoParquet metadata: column %q has no encoding", name)
+		}
+	}
+	return &m, nil
+}
+
+// Marshal returns m as JSON.
+func (m *Metadata) Marshal() (string, error) {
+	for _, c := range m.Columns {
+		if c.GeometryTypes == nil {
+			c.GeometryTypes = []string{}
+		}
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Names returns the names of the geometry columns, sorted.
+func (m *Metadata) Names() []string {
+	names := make([]string, 0, len(m.Columns))
+	for name := range m.Columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Add counts g in the geometry types and bounding box of c.
+func (c *Column) Add(g *Geometry) {
+	name := g.Kind.String()
+	if g.HasZ {
+		name += " Z"
+	}
+	if !slices.Contains(c.GeometryTypes, name) {
+		c.GeometryTypes = append(c.GeometryTypes, name)
+		sort.Strings(c.GeometryTypes)
+	}
+	box, ok := g.Bounds()
+	if !ok {
+		return
+	}
+	if c.BBox == nil {
+		c.BBox = box[:]
+		return
+	}
+	c.BBox[0] = min(c.BBox[0], box[0])
+	c.BBox[1] = min(c.BBox[1], box[1])
+	c.BBox[2] = max(c.BBox[2], box[2])
+	c.BBox[3] = max(c.BBox[3], box[3])
+}
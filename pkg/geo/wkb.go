@@ -0,0 +1,359 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package geo reads and writes geometries in their Well-Known Binary (WKB)
+// and Well-Known Text (WKT) forms, and the GeoParquet metadata naming the
+// geometry columns of a dataset.
+package geo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Kind is the type of a geometry, numbered as in WKB.
+type Kind uint32
+
+const (
+	Point              Kind = 1
+	LineString         Kind = 2
+	Polygon            Kind = 3
+	MultiPoint         Kind = 4
+	MultiLineString    Kind = 5
+	MultiPolygon       Kind = 6
+	GeometryCollection Kind = 7
+)
+
+var kindNames = map[Kind]string{
+	Point:              "Point",
+	LineString:         "LineString",
+	Polygon:            "Polygon",
+	MultiPoint:         "MultiPoint",
+	MultiLineString:    "MultiLineString",
+	MultiPolygon:       "MultiPolygon",
+	GeometryCollection: "GeometryCollection",
+}
+
+// String returns the name of k as GeoParquet spells it.
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Kind(%d)", uint32(k))
+}
+
+// Geometry is a geometry of any kind. Points and line strings hold their
+// coordinates; polygons hold their rings, and multi geometries and
+// collections their members, as Parts.
+type Geometry struct {
+	Kind Kind
+	HasZ bool
+	HasM bool
+	// Coords holds the coordinates of the points in order, Stride values
+	// each. An empty point has none.
+	Coords []float64
+	Parts  []Geometry
+}
+
+// Stride returns the number of values per point: 2, plus one each for Z
+// and M.
+func (g *Geometry) Stride() int {
+	n := 2
+	if g.HasZ {
+		n++
+	}
+	if g.HasM {
+		n++
+	}
+	return n
+}
+
+// IsEmpty reports whether g has no points.
+func (g *Geometry) IsEmpty() bool {
+	if len(g.Coords) > 0 {
+		return false
+	}
+	for i := range g.Parts {
+		if !g.Parts[i].IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrInvalidWKB is returned, wrapped, for malformed WKB.
+var ErrInvalidWKB = errors.New("invalid WKB")
+
+// Extended WKB flags, and the ISO WKB type offsets.
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+// ParseWKB decodes a geometry from ISO or extended WKB, of either byte
+// order.
+func ParseWKB(b []byte) (*Geometry, error) {
+	d := wkbDecoder{buf: b}
+	g, err := d.geometry(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(b) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalidWKB, len(b)-d.pos)
+	}
+	return g, nil
+}
+
+// maxDepth bounds the nesting of collections.
+const maxDepth = 32
+
+type wkbDecoder struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (d *wkbDecoder) uint32() (uint32, error) {
+	if len(d.buf)-d.pos < 4 {
+		return 0, fmt.Errorf("%w: unexpected end", ErrInvalidWKB)
+	}
+	v := d.order.Uint32(d.buf[d.pos:])
+	d.pos += 4
+	return v, nil
+}
+
+func (d *wkbDecoder) float64() (float64, error) {
+	if len(d.buf)-d.pos < 8 {
+		return 0, fmt.Errorf("%w: unexpected end", ErrInvalidWKB)
+	}
+	v := math.Float64frombits(d.order.Uint64(d.buf[d.pos:]))
+	d.pos += 8
+	return v, nil
+}
+
+// count reads a number of items of at least size bytes each, checking
+// that the input can hold them.
+func (d *wkbDecoder) count(size int) (int, error) {
+	n, err := d.uint32()
+	if err != nil {
+		return 0, err
+	}
+	if int64(n)*int64(size) > int64(len(d.buf)-d.pos) {
+		return 0, fmt.Errorf("%w: count %d exceeds the input", ErrInvalidWKB, n)
+	}
+	return int(n), nil
+}
+
+func (d *wkbDecoder) geometry(depth int) (*Geometry, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", ErrInvalidWKB)
+	}
+	if d.pos >= len(d.buf) {
+		return nil, fmt.Errorf("%w: unexpected end", ErrInvalidWKB)
+	}
+	switch d.buf[d.pos] {
+	case 0:
+		d.order = binary.BigEndian
+	case 1:
+		d.order = binary.LittleEndian
+	default:
+		return nil, fmt.Errorf("%w: byte order %d", ErrInvalidWKB, d.buf[d.pos])
+	}
+	d.pos++
+	code, err := d.uint32()
+	if err != nil {
+		return nil, err
+	}
+	g := &Geometry{
+		HasZ: code&ewkbZ != 0,
+		HasM: code&ewkbM != 0,
+	}
+	if code&ewkbSRID != 0 {
+		if _, err := d.uint32(); err != nil {
+			return nil, err
+		}
+	}
+	code &^= ewkbZ | ewkbM | ewkbSRID
+	switch code / 1000 {
+	case 1:
+		g.HasZ = true
+	case 2:
+		g.HasM = true
+	case 3:
+		g.HasZ, g.HasM = true, true
+	}
+	g.Kind = Kind(code % 1000)
+	stride := g.Stride()
+
+	switch g.Kind {
+	case Point:
+		if g.Coords, err = d.coords(1, stride); err != nil {
+			return nil, err
+		}
+		// Empty points are written with NaN coordinates.
+		if math.IsNaN(g.Coords[0]) && math.IsNaN(g.Coords[1]) {
+			g.Coords = nil
+		}
+	case LineString:
+		n, err := d.count(8 * stride)
+		if err != nil {
+			return nil, err
+		}
+		if g.Coords, err = d.coords(n, stride); err != nil {
+			return nil, err
+		}
+	case Polygon:
+		n, err := d.count(4)
+		if err != nil {
+			return nil, err
+		}
+		g.Parts = make([]Geometry, n)
+		for i := range g.Parts {
+			m, err := d.count(8 * stride)
+			if err != nil {
+				return nil, err
+			}
+			ring := Geometry{Kind: LineString, HasZ: g.HasZ, HasM: g.HasM}
+			if ring.Coords, err = d.coords(m, stride); err != nil {
+				return nil, err
+			}
+			g.Parts[i] = ring
+		}
+	case MultiPoint, MultiLineString, MultiPolygon, GeometryCollection:
+		n, err := d.count(5)
+		if err != nil {
+			return nil, err
+		}
+		g.Parts = make([]Geometry, n)
+		for i := range g.Parts {
+			part, err := d.geometry(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if g.Kind != GeometryCollection && part.Kind != g.Kind-3 {
+				return nil, fmt.Errorf("%w: %s in %s", ErrInvalidWKB, part.Kind, g.Kind)
+			}
+			g.Parts[i] = *part
+		}
+	default:
+		return nil, fmt.Errorf("%w: geometry type %d", ErrInvalidWKB, code)
+	}
+	return g, nil
+}
+
+func (d *wkbDecoder) coords(n, stride int) ([]float64, error) {
+	coords := make([]float64, n*stride)
+	for i := range coords {
+		v, err := d.float64()
+		if err != nil {
+			return nil, err
+		}
+		coords[i] = v
+	}
+	return coords, nil
+}
+
+// WKB encodes g as little-endian ISO WKB.
+func (g *Geometry) WKB() []byte {
+	return g.appendWKB(nil)
+}
+
+func (g *Geometry) appendWKB(b []byte) []byte {
+	code := uint32(g.Kind)
+	switch {
+	case g.HasZ && g.HasM:
+		code += 3000
+	case g.HasZ:
+		code += 1000
+	case g.HasM:
+		code += 2000
+	}
+	b = append(b, 1)
+	b = binary.LittleEndian.AppendUint32(b, code)
+	switch g.Kind {
+	case Point:
+		if len(g.Coords) == 0 {
+			for i := 0; i < g.Stride(); i++ {
+				b = binary.LittleEndian.AppendUint64(b, math.Float64bits(math.NaN()))
+			}
+			return b
+		}
+		return appendCoords(b, g.Coords)
+	case LineString:
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(g.Coords)/g.Stride()))
+		return appendCoords(b, g.Coords)
+	case Polygon:
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(g.Parts)))
+		for i := range g.Parts {
+			ring := &g.Parts[i]
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(ring.Coords)/g.Stride()))
+			b = appendCoords(b, ring.Coords)
+		}
+		return b
+	default:
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(g.Parts)))
+		for i := range g.Parts {
+			b = g.Parts[i].appendWKB(b)
+		}
+		return b
+	}
+}
+
+func appendCoords(b []byte, coords []float64) []byte {
+	for _, v := range coords {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	}
+	return b
+}
+
+// Bounds returns the smallest box [xmin, ymin, xmax, ymax] holding the
+// points of g, and false if g is empty.
+func (g *Geometry) Bounds() ([4]float64, bool) {
+	box := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	g.extend(&box)
+	return box, box[0] <= box[2]
+}
+
+func (g *Geometry) extend(box *[4]float64) {
+	stride := g.Stride()
+	for i := 0; i+1 < len(g.Coords); i += stride {
+		x, y := g.Coords[i], g.Coords[i+1]
+		if math.IsNaN(x) || math.IsNaN(y) {
+			continue
+		}
+		box[0], box[1] = math.Min(box[0], x), math.Min(box[1], y)
+		box[2], box[3] = math.Max(box[2], x), math.Max(box[3], y)
+	}
+	for i := range g.Parts {
+		g.Parts[i].extend(box)
+	}
+}
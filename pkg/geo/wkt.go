@@ -0,0 +1,346 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidWKT is returned, wrapped, for malformed WKT.
+var ErrInvalidWKT = errors.New("invalid WKT")
+
+var kindKeywords = map[Kind]string{
+	Point:              "POINT",
+	LineString:         "LINESTRING",
+	Polygon:            "POLYGON",
+	MultiPoint:         "MULTIPOINT",
+	MultiLineString:    "MULTILINESTRING",
+	MultiPolygon:       "MULTIPOLYGON",
+	GeometryCollection: "GEOMETRYCOLLECTION",
+}
+
+// WKT formats g as Well-Known Text, such as "POINT (1 2)" or
+// "LINESTRING Z (0 0 1, 1 1 2)".
+func (g *Geometry) WKT() string {
+	var b strings.Builder
+	g.writeWKT(&b)
+	return b.String()
+}
+
+func (g *Geometry) writeWKT(b *strings.Builder) {
+	b.WriteString(kindKeywords[g.Kind])
+	switch {
+	case g.HasZ && g.HasM:
+		b.WriteString(" ZM")
+	case g.HasZ:
+		b.WriteString(" Z")
+	case g.HasM:
+		b.WriteString(" M")
+	}
+	if g.IsEmpty() {
+		b.WriteString(" EMPTY")
+		return
+	}
+	b.WriteByte(' ')
+	g.writeBody(b)
+}
+
+// writeBody writes the parenthesized coordinates of g, without its
+// keyword.
+func (g *Geometry) writeBody(b *strings.Builder) {
+	if g.IsEmpty() {
+		b.WriteString("EMPTY")
+		return
+	}
+	b.WriteByte('(')
+	switch g.Kind {
+	case Point, LineString:
+		writeCoords(b, g.Coords, g.Stride())
+	case GeometryCollection:
+		for i := range g.Parts {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			g.Parts[i].writeWKT(b)
+		}
+	default:
+		for i := range g.Parts {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			g.Parts[i].writeBody(b)
+		}
+	}
+	b.WriteByte(')')
+}
+
+func writeCoords(b *strings.Builder, coords []float64, stride int) {
+	for i, v := range coords {
+		switch {
+		case i == 0:
+		case i%stride == 0:
+			b.WriteString(", ")
+		default:
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+}
+
+// ParseWKT parses a geometry from Well-Known Text. Keywords are case
+// insensitive, and an extended WKT "SRID=n;" prefix is skipped.
+func ParseWKT(s string) (*Geometry, error) {
+	if rest, ok := cutPrefixFold(s, "SRID="); ok {
+		_, after, found := strings.Cut(rest, ";")
+		if !found {
+			return nil, fmt.Errorf("%w: SRID without ';'", ErrInvalidWKT)
+		}
+		s = after
+	}
+	p := wktParser{s: s}
+	g, err := p.geometry(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.next(); tok != "" {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidWKT, tok)
+	}
+	return g, nil
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
+
+type wktParser struct {
+	s   string
+	pos int
+	// peeked holds a token read ahead.
+	peeked string
+}
+
+// next returns the next token: a word, a number, or one of "(", ")" and
+// ",". It returns "" at the end of the input.
+func (p *wktParser) next() string {
+	if p.peeked != "" {
+		tok := p.peeked
+		p.peeked = ""
+		return tok
+	}
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if p.pos == len(p.s) {
+		return ""
+	}
+	start := p.pos
+	switch p.s[p.pos] {
+	case '(', ')', ',':
+		p.pos++
+		return p.s[start:p.pos]
+	}
+	for p.pos < len(p.s) && !unicode.IsSpace(rune(p.s[p.pos])) && !strings.ContainsRune("(),", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *wktParser) peek() string {
+	if p.peeked == "" {
+		p.peeked = p.next()
+	}
+	return p.peeked
+}
+
+func (p *wktParser) expect(want string) error {
+	if tok := p.next(); tok != want {
+		if tok == "" {
+			return fmt.Errorf("%w: expected %q at the end", ErrInvalidWKT, want)
+		}
+		return fmt.Errorf("%w: expected %q, got %q", ErrInvalidWKT, want, tok)
+	}
+	return nil
+}
+
+func (p *wktParser) geometry(depth int) (*Geometry, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", ErrInvalidWKT)
+	}
+	word := strings.ToUpper(p.next())
+	g := &Geometry{}
+	// Accept the dimensions attached to the keyword, as in "POINTZ".
+	for kind, keyword := range kindKeywords {
+		if dims, ok := strings.CutPrefix(word, keyword); ok && (dims == "" || dims == "Z" || dims == "M" || dims == "ZM") {
+			g.Kind = kind
+			g.HasZ = strings.Contains(dims, "Z")
+			g.HasM = strings.Contains(dims, "M")
+		}
+	}
+	if g.Kind == 0 {
+		if word == "" {
+			return nil, fmt.Errorf("%w: empty", ErrInvalidWKT)
+		}
+		return nil, fmt.Errorf("%w: unknown geometry %q", ErrInvalidWKT, word)
+	}
+	switch strings.ToUpper(p.peek()) {
+	case "Z":
+		g.HasZ = true
+		p.next()
+	case "M":
+		g.HasM = true
+		p.next()
+	case "ZM":
+		g.HasZ, g.HasM = true, true
+		p.next()
+	}
+	if strings.EqualFold(p.peek(), "EMPTY") {
+		p.next()
+		return g, nil
+	}
+	if err := p.body(g, depth); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// body parses the parenthesized coordinates or members of g.
+func (p *wktParser) body(g *Geometry, depth int) error {
+	if strings.EqualFold(p.peek(), "EMPTY") {
+		p.next()
+		return nil
+	}
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	switch g.Kind {
+	case Point, LineString:
+		coords, err := p.coords(g)
+		if err != nil {
+			return err
+		}
+		if g.Kind == Point && len(coords) != g.Stride() {
+			return fmt.Errorf("%w: point with %d values", ErrInvalidWKT, len(coords))
+		}
+		g.Coords = coords
+		return p.expect(")")
+	}
+	for {
+		var part *Geometry
+		var err error
+		switch g.Kind {
+		case GeometryCollection:
+			part, err = p.geometry(depth + 1)
+		case MultiPoint:
+			// Points in a multipoint may go without parentheses.
+			part = &Geometry{Kind: Point, HasZ: g.HasZ, HasM: g.HasM}
+			if p.peek() == "(" || strings.EqualFold(p.peek(), "EMPTY") {
+				err = p.body(part, depth+1)
+			} else {
+				part.Coords, err = p.point(part)
+			}
+		default:
+			// Polygon rings are line strings; the members of the other
+			// multi geometries have the kind three below theirs.
+			kind := LineString
+			if g.Kind != Polygon {
+				kind = g.Kind - 3
+			}
+			part = &Geometry{Kind: kind, HasZ: g.HasZ, HasM: g.HasM}
+			err = p.body(part, depth+1)
+		}
+		if err != nil {
+			return err
+		}
+		g.Parts = append(g.Parts, *part)
+		if g.Kind != GeometryCollection {
+			// The members have the dimensions of the first point.
+			g.HasZ, g.HasM = part.HasZ, part.HasM
+		}
+		switch tok := p.next(); tok {
+		case ",":
+		case ")":
+			return nil
+		default:
+			return fmt.Errorf("%w: expected \",\" or \")\", got %q", ErrInvalidWKT, tok)
+		}
+	}
+}
+
+// coords parses the comma-separated points of g.
+func (p *wktParser) coords(g *Geometry) ([]float64, error) {
+	var coords []float64
+	for {
+		point, err := p.point(g)
+		if err != nil {
+			return nil, err
+		}
+		coords = append(coords, point...)
+		if p.peek() != "," {
+			return coords, nil
+		}
+		p.next()
+	}
+}
+
+// point parses the values of one point. The dimensions of g are taken
+// from the first point when the keyword did not give them.
+func (p *wktParser) point(g *Geometry) ([]float64, error) {
+	var values []float64
+	for {
+		tok := p.peek()
+		if tok == "" || tok == "," || tok == ")" || tok == "(" {
+			break
+		}
+		p.next()
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad number %q", ErrInvalidWKT, tok)
+		}
+		values = append(values, v)
+	}
+	if len(values) == 3 && !g.HasZ && !g.HasM {
+		g.HasZ = true
+	}
+	if len(values) == 4 && !g.HasZ && !g.HasM {
+		g.HasZ, g.HasM = true, true
+	}
+	if len(values) != g.Stride() {
+		return nil, fmt.Errorf("%w: point with %d values, expected %d", ErrInvalidWKT, len(values), g.Stride())
+	}
+	return values, nil
+}
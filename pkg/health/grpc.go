@@ -0,0 +1,93 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package health
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// LivenessService is the service name whose gRPC health status is the
+// server's liveness. Any other name, the empty one included, gets its
+// readiness.
+const LivenessService = "liveness"
+
+// WatchInterval is how often the gRPC Watch method runs the checks to
+// report changes of readiness.
+var WatchInterval = time.Second
+
+// RegisterGRPC registers the gRPC health service with s, such as a Flight
+// server, reporting the status of c.
+func (c *Checker) RegisterGRPC(s grpc.ServiceRegistrar) {
+	healthgrpc.RegisterHealthServer(s, &grpcHealth{checker: c})
+}
+
+type grpcHealth struct {
+	healthgrpc.UnimplementedHealthServer
+	checker *Checker
+}
+
+func (h *grpcHealth) status(ctx context.Context, service string) healthgrpc.HealthCheckResponse_ServingStatus {
+	report := h.checker.Live()
+	if service != LivenessService {
+		report = h.checker.Ready(ctx)
+	}
+	if report.Status != StatusOK {
+		return healthgrpc.HealthCheckResponse_NOT_SERVING
+	}
+	return healthgrpc.HealthCheckResponse_SERVING
+}
+
+func (h *grpcHealth) Check(ctx context.Context, req *healthgrpc.HealthCheckRequest) (*healthgrpc.HealthCheckResponse, error) {
+	return &healthgrpc.HealthCheckResponse{Status: h.status(ctx, req.GetService())}, nil
+}
+
+func (h *grpcHealth) Watch(req *healthgrpc.HealthCheckRequest, stream grpc.ServerStreamingServer[healthgrpc.HealthCheckResponse]) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+	last := healthgrpc.HealthCheckResponse_UNKNOWN
+	for {
+		if status := h.status(ctx, req.GetService()); status != last {
+			if err := stream.Send(&healthgrpc.HealthCheckResponse{Status: status}); err != nil {
+				return err
+			}
+			last = status
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
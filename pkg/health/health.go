@@ -0,0 +1,253 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package health serves the liveness and readiness of ArrowArc servers to
+// probes such as Kubernetes', over HTTP and the gRPC health protocol.
+//
+// Liveness only tells that the process serves requests. Readiness runs the
+// checks of the server's dependencies, such as a database or a catalog, and
+// fails once the server drains for shutdown.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout bounds each check unless Checker.Timeout sets another.
+const DefaultTimeout = 5 * time.Second
+
+// Check reports whether a dependency is usable, returning nil if it is.
+type Check func(ctx context.Context) error
+
+// Status is the outcome of a probe or check.
+type Status string
+
+const (
+	StatusOK          Status = "ok"
+	StatusUnavailable Status = "unavailable"
+)
+
+// Report is the outcome of a probe and, for readiness, of each check.
+type Report struct {
+	Status Status        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of one check.
+type CheckResult struct {
+	Name     string  `json:"name"`
+	Status   Status  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+}
+
+// Checker holds the readiness checks of a server. Its zero value has no
+// checks and is ready.
+type Checker struct {
+	// Timeout bounds each check. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	names    []string
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+// New returns a Checker with the given checks, run in name order.
+func New(checks map[string]Check) *Checker {
+	c := &Checker{}
+	for name, check := range checks {
+		c.Add(name, check)
+	}
+	return c
+}
+
+// Add adds a check, replacing any of the same name.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checks == nil {
+		c.checks = make(map[string]Check)
+	}
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+		slices.Sort(c.names)
+	}
+	c.checks[name] = check
+}
+
+// Drain makes the server unready from then on, so that probes take it out
+// of rotation while it shuts down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Live reports the liveness of the server, which is always ok.
+func (c *Checker) Live() Report {
+	return Report{Status: StatusOK}
+}
+
+// Ready runs the checks concurrently, each bounded by Timeout, and reports
+// the server ready if all of them pass and it is not draining.
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.Lock()
+	names := append([]string(nil), c.names...)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.Unlock()
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	report := Report{Status: StatusOK, Checks: make([]CheckResult, len(names))}
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			err := run(ctx, checks[i])
+			result := CheckResult{Name: names[i], Status: StatusOK, Duration: time.Since(start).Seconds()}
+			if err != nil {
+				result.Status, result.Error = StatusUnavailable, err.Error()
+			}
+			report.Checks[i] = result
+		}()
+	}
+	wg.Wait()
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	if c.draining.Load() {
+		report.Status = StatusUnavailable
+	}
+	return report
+}
+
+// run runs check, returning early with the context's error if the check
+// ignores it.
+func run(ctx context.Context, check Check) error {
+	errc := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errc <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		errc <- check(ctx)
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Register serves liveness on GET /healthz and readiness on GET /readyz of
+// mux. Both answer a JSON Report, with status 503 when unavailable.
+func (c *Checker) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, c.Live())
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, c.Ready(req.Context()))
+	})
+}
+
+// Handler returns a handler serving only /healthz and /readyz, for servers
+// that are not HTTP servers themselves.
+func (c *Checker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	c.Register(mux)
+	return mux
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// Ping checks that db is reachable.
+func Ping(db *sql.DB) Check {
+	return db.PingContext
+}
+
+// Dial checks that a TCP connection to addr can be opened.
+func Dial(addr string) Check {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// HTTP checks that a GET of url succeeds with a 2xx or 3xx status, as the
+// config endpoint of a REST catalog does.
+func HTTP(url string) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return errors.New(resp.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,164 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestReady(t *testing.T) {
+	c := New(map[string]Check{
+		"db":      func(context.Context) error { return nil },
+		"catalog": func(context.Context) error { return errors.New("connection refused") },
+	})
+	c.Timeout = 50 * time.Millisecond
+	c.Add("slow", func(ctx context.Context) error { time.Sleep(300 * time.Millisecond); return nil })
+
+	report := c.Ready(context.Background())
+	if report.Status != StatusUnavailable || len(report.Checks) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	want := map[string]string{"catalog": "connection refused", "db": "", "slow": context.DeadlineExceeded.Error()}
+	for i, name := range []string{"catalog", "db", "slow"} {
+		if got := report.Checks[i]; got.Name != name || got.Error != want[name] {
+			t.Errorf("check %d = %+v, want %s failing with %q", i, got, name, want[name])
+		}
+	}
+
+	var zero Checker
+	if report := zero.Ready(context.Background()); report.Status != StatusOK {
+		t.Errorf("no checks: %+v", report)
+	}
+	zero.Drain()
+	if report := zero.Ready(context.Background()); report.Status != StatusUnavailable {
+		t.Errorf("draining: %+v", report)
+	}
+}
+
+func TestHTTPProbes(t *testing.T) {
+	var err error
+	c := New(map[string]Check{"db": func(context.Context) error { return err }})
+	srv := httptest.NewServer(c.Handler())
+	defer srv.Close()
+
+	get := func(path string) (int, Report) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var report Report
+		if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, report
+	}
+	if code, report := get("/readyz"); code != http.StatusOK || report.Checks[0].Status != StatusOK {
+		t.Errorf("ready: %d %+v", code, report)
+	}
+	err = errors.New("down")
+	if code, report := get("/readyz"); code != http.StatusServiceUnavailable || report.Checks[0].Error != "down" {
+		t.Errorf("unready: %d %+v", code, report)
+	}
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("live: %d", code)
+	}
+
+	if err := HTTP(srv.URL + "/readyz")(context.Background()); err == nil {
+		t.Error("HTTP check passed on a 503")
+	}
+	if err := HTTP(srv.URL + "/healthz")(context.Background()); err != nil {
+		t.Errorf("HTTP check: %v", err)
+	}
+	if err := Dial(srv.Listener.Addr().String())(context.Background()); err != nil {
+		t.Errorf("Dial check: %v", err)
+	}
+}
+
+func TestGRPCHealth(t *testing.T) {
+	var err error
+	c := New(map[string]Check{"db": func(context.Context) error { return err }})
+	lis, lerr := net.Listen("tcp", "localhost:0")
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	s := grpc.NewServer()
+	c.RegisterGRPC(s)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, cerr := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if cerr != nil {
+		t.Fatal(cerr)
+	}
+	defer conn.Close()
+	client := healthgrpc.NewHealthClient(conn)
+	ctx := context.Background()
+
+	check := func(service string) healthgrpc.HealthCheckResponse_ServingStatus {
+		resp, err := client.Check(ctx, &healthgrpc.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Status
+	}
+	if got := check(""); got != healthgrpc.HealthCheckResponse_SERVING {
+		t.Errorf("ready: %v", got)
+	}
+	err = errors.New("down")
+	if got := check(""); got != healthgrpc.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("unready: %v", got)
+	}
+	if got := check(LivenessService); got != healthgrpc.HealthCheckResponse_SERVING {
+		t.Errorf("live: %v", got)
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, werr := client.Watch(watchCtx, &healthgrpc.HealthCheckRequest{})
+	if werr != nil {
+		t.Fatal(werr)
+	}
+	resp, werr := stream.Recv()
+	if werr != nil || resp.Status != healthgrpc.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("first watch status: %v, %v", resp, werr)
+	}
+}
@@ -0,0 +1,177 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package history keeps a record of pipeline runs: what each read and
+// wrote, how it ended and its metrics report, in a JSON lines file or a
+// SQLite database. A store carried in a context records every run made
+// with that context.
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Run outcomes.
+const (
+	Succeeded = "succeeded"
+	Failed    = "failed"
+)
+
+// ErrNotFound is returned by Store.Get when no run matches.
+var ErrNotFound = errors.New("run not found")
+
+// Run is the record of one pipeline run.
+type Run struct {
+	ID          string    `json:"id"`
+	Command     string    `json:"command,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Destination string    `json:"destination,omitempty"`
+	ConfigHash  string    `json:"config_hash,omitempty"`
+	Outcome     string    `json:"outcome"`
+	Error       string    `json:"error,omitempty"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Records     int64     `json:"records"`
+	Bytes       int64     `json:"bytes"`
+	// Report is the metrics report of the pipeline, as JSON.
+	Report json.RawMessage `json:"report,omitempty"`
+}
+
+// Duration returns how long the run took.
+func (r Run) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime)
+}
+
+// ListOptions filters the runs Store.List returns.
+type ListOptions struct {
+	// Limit is the maximum number of runs returned, zero meaning all.
+	Limit int
+	// Outcome, if set, keeps the runs that ended so.
+	Outcome string
+}
+
+// Store persists runs.
+type Store interface {
+	// Append records run.
+	Append(ctx context.Context, run Run) error
+	// List returns the runs recorded, the latest first.
+	List(ctx context.Context, opts ListOptions) ([]Run, error)
+	// Get returns the run whose ID is id, or starts with id if only one
+	// does.
+	Get(ctx context.Context, id string) (Run, error)
+	Close() error
+}
+
+// Open opens the history file at path, creating it if need be: a SQLite
+// database if its extension is .db, .sqlite or .sqlite3, a JSON lines
+// file otherwise.
+func Open(path string) (Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("history path cannot be empty")
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return OpenSQLite(path)
+	default:
+		return OpenJSONL(path)
+	}
+}
+
+// RedactURI returns uri without the credentials, query and fragment it
+// may hold, if it is a URL, for Run.Source and Run.Destination.
+func RedactURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || u.Opaque != "" {
+		return uri
+	}
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+	return u.String()
+}
+
+// ConfigHash returns the hex encoded SHA-256 of the JSON encoding of
+// config, which tells runs of the same configuration apart from others.
+// It returns an empty string if config cannot be encoded.
+func ConfigHash(config any) string {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying store, so that the runs made with
+// it are recorded there.
+func NewContext(ctx context.Context, store Store) context.Context {
+	return context.WithValue(ctx, contextKey{}, store)
+}
+
+// FromContext returns the store ctx carries, or nil.
+func FromContext(ctx context.Context) Store {
+	store, _ := ctx.Value(contextKey{}).(Store)
+	return store
+}
+
+// matches reports whether run passes the filters of opts.
+func (opts ListOptions) matches(run Run) bool {
+	return opts.Outcome == "" || run.Outcome == opts.Outcome
+}
+
+// findRun returns the run of runs whose ID is id, or the only one whose
+// ID starts with it.
+func findRun(runs []Run, id string) (Run, error) {
+	var found []Run
+	for _, run := range runs {
+		if run.ID == id {
+			return run, nil
+		}
+		if id != "" && strings.HasPrefix(run.ID, id) {
+			found = append(found, run)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Run{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	case 1:
+		return found[0], nil
+	default:
+		return Run{}, fmt.Errorf("run ID %s is ambiguous: %d runs match", id, len(found))
+	}
+}
@@ -0,0 +1,143 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package history
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStores(t *testing.T) {
+	for _, name := range []string{"runs.jsonl", "runs.db"} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), name)
+			store, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			runs, err := store.List(ctx, ListOptions{})
+			if err != nil || len(runs) != 0 {
+				t.Fatalf("empty history: %v, %v", runs, err)
+			}
+
+			start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+			for i, id := range []string{"aaa-1", "bbb-2", "aaa-3"} {
+				run := Run{
+					ID:          id,
+					Command:     "convert",
+					Source:      "in.csv",
+					Destination: "out.parquet",
+					ConfigHash:  ConfigHash(map[string]int{"chunk": 1024}),
+					Outcome:     Succeeded,
+					StartTime:   start.Add(time.Duration(i) * time.Minute),
+					EndTime:     start.Add(time.Duration(i)*time.Minute + 1500*time.Millisecond),
+					Records:     int64(i * 10),
+					Report:      []byte(`{"records":"10"}`),
+				}
+				if i == 1 {
+					run.Outcome, run.Error = Failed, "boom"
+				}
+				if err := store.Append(ctx, run); err != nil {
+					t.Fatal(err)
+				}
+			}
+			store.Close()
+
+			// Runs persist once the store is closed.
+			store, err = Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			runs, err = store.List(ctx, ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(runs) != 3 || runs[0].ID != "aaa-3" || runs[2].ID != "aaa-1" {
+				t.Fatalf("runs = %+v", runs)
+			}
+			if runs[0].Duration() != 1500*time.Millisecond || string(runs[0].Report) != `{"records":"10"}` || runs[0].Records != 20 {
+				t.Errorf("run = %+v", runs[0])
+			}
+			runs, _ = store.List(ctx, ListOptions{Outcome: Failed})
+			if len(runs) != 1 || runs[0].Error != "boom" {
+				t.Errorf("failed runs = %+v", runs)
+			}
+			runs, _ = store.List(ctx, ListOptions{Limit: 1})
+			if len(runs) != 1 || runs[0].ID != "aaa-3" {
+				t.Errorf("limited runs = %+v", runs)
+			}
+
+			if run, err := store.Get(ctx, "bbb"); err != nil || run.ID != "bbb-2" {
+				t.Errorf("get by prefix = %+v, %v", run, err)
+			}
+			if run, err := store.Get(ctx, "aaa-1"); err != nil || run.Records != 0 {
+				t.Errorf("get = %+v, %v", run, err)
+			}
+			if _, err := store.Get(ctx, "aaa"); err == nil {
+				t.Error("expected an ambiguous ID to fail")
+			}
+			if _, err := store.Get(ctx, "ccc"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("get unknown = %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigHash(t *testing.T) {
+	a := ConfigHash(map[string]any{"to": "x", "chunk": 1})
+	b := ConfigHash(map[string]any{"chunk": 1, "to": "x"})
+	if a == "" || a != b {
+		t.Errorf("hashes %q and %q differ", a, b)
+	}
+	if ConfigHash(map[string]any{"chunk": 2, "to": "x"}) == a {
+		t.Error("expected different configurations to hash differently")
+	}
+	if ConfigHash(func() {}) != "" {
+		t.Error("expected an unencodable configuration to hash to nothing")
+	}
+}
+
+func TestRedactURI(t *testing.T) {
+	for in, want := range map[string]string{
+		"data/in.csv": "data/in.csv",
+		"postgres://user:secret@db:5432/app?sslmode=disable": "postgres://db:5432/app",
+		"https://example.com/a.csv?token=x#frag":             "https://example.com/a.csv",
+		"-":                                                  "-",
+	} {
+		if got := RedactURI(in); got != want {
+			t.Errorf("RedactURI(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package history
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+)
+
+// JSONLStore keeps runs in a file of JSON lines, one run per line, in
+// the order they were recorded.
+type JSONLStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// OpenJSONL opens the JSON lines history file at path, which is created
+// on the first run recorded.
+func OpenJSONL(path string) (*JSONLStore, error) {
+	return &JSONLStore{path: path}, nil
+}
+
+func (s *JSONLStore) Append(_ context.Context, run Run) error {
+	line, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	// A single write keeps lines whole when processes append at once.
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+	return f.Close()
+}
+
+func (s *JSONLStore) List(_ context.Context, opts ListOptions) ([]Run, error) {
+	runs, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(runs)
+	var out []Run
+	for _, run := range runs {
+		if !opts.matches(run) {
+			continue
+		}
+		out = append(out, run)
+		if opts.Limit > 0 && len(out) == opts.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (s *JSONLStore) Get(_ context.Context, id string) (Run, error) {
+	runs, err := s.read()
+	if err != nil {
+		return Run{}, err
+	}
+	return findRun(runs, id)
+}
+
+// read returns the runs of the file, skipping lines that do not decode,
+// such as the last line of a write that was cut short.
+func (s *JSONLStore) read() ([]Run, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var run Run
+		if json.Unmarshal(scanner.Bytes(), &run) == nil && run.ID != "" {
+			runs = append(runs, run)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return runs, nil
+}
+
+func (s *JSONLStore) Close() error {
+	return nil
+}
@@ -0,0 +1,158 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package history
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS runs (
+	id           TEXT PRIMARY KEY,
+	command      TEXT NOT NULL DEFAULT '',
+	source       TEXT NOT NULL DEFAULT '',
+	destination  TEXT NOT NULL DEFAULT '',
+	config_hash  TEXT NOT NULL DEFAULT '',
+	outcome      TEXT NOT NULL,
+	error        TEXT NOT NULL DEFAULT '',
+	start_time   TEXT NOT NULL,
+	end_time     TEXT NOT NULL,
+	records      INTEGER NOT NULL DEFAULT 0,
+	bytes        INTEGER NOT NULL DEFAULT 0,
+	report       TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS runs_start_time ON runs (start_time)`
+
+// sqliteTime formats times to a fixed width, so that they sort in order.
+const sqliteTime = "2006-01-02T15:04:05.000000000Z"
+
+const sqliteColumns = "id, command, source, destination, config_hash, outcome, error, start_time, end_time, records, bytes, report"
+
+// SQLiteStore keeps runs in the runs table of a SQLite database.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the SQLite history database at path, creating it and
+// its runs table if need be.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	// Runs recorded at once wait for each other rather than fail.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA busy_timeout = 5000"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create history table: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Append(ctx context.Context, run Run) error {
+	_, err := s.db.ExecContext(ctx, "INSERT OR REPLACE INTO runs ("+sqliteColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		run.ID, run.Command, run.Source, run.Destination, run.ConfigHash, run.Outcome, run.Error,
+		run.StartTime.UTC().Format(sqliteTime), run.EndTime.UTC().Format(sqliteTime),
+		run.Records, run.Bytes, string(run.Report))
+	if err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) List(ctx context.Context, opts ListOptions) ([]Run, error) {
+	query := "SELECT " + sqliteColumns + " FROM runs"
+	var args []any
+	if opts.Outcome != "" {
+		query += " WHERE outcome = ?"
+		args = append(args, opts.Outcome)
+	}
+	query += " ORDER BY start_time DESC, rowid DESC"
+	if opts.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+	return s.query(ctx, query, args...)
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id string) (Run, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(id)
+	runs, err := s.query(ctx, "SELECT "+sqliteColumns+` FROM runs WHERE id = ? OR id LIKE ? ESCAPE '\' LIMIT 3`, id, escaped+"%")
+	if err != nil {
+		return Run{}, err
+	}
+	return findRun(runs, id)
+}
+
+func (s *SQLiteStore) query(ctx context.Context, query string, args ...any) ([]Run, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer rows.Close()
+	var runs []Run
+	for rows.Next() {
+		var run Run
+		var start, end, report string
+		if err := rows.Scan(&run.ID, &run.Command, &run.Source, &run.Destination, &run.ConfigHash, &run.Outcome, &run.Error,
+			&start, &end, &run.Records, &run.Bytes, &report); err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		run.StartTime, err = time.Parse(time.RFC3339Nano, start)
+		if err == nil {
+			run.EndTime, err = time.Parse(time.RFC3339Nano, end)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed run %s: %w", run.ID, err)
+		}
+		if report != "" {
+			run.Report = []byte(report)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return runs, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,169 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package limit passes on a window of the rows of a stream of records: the
+// rows after an offset, up to a limit, so that reading an extract of a
+// large source stops once it has the rows it needs.
+package limit
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+)
+
+// Window cuts a stream of records down to the rows after an offset, up to
+// a limit. Readers that cannot skip rows at the source use it to slice
+// the records they read.
+type Window struct {
+	skip int64 // rows still to skip
+	left int64 // rows still to return, or -1 for no limit
+}
+
+// NewWindow returns a Window skipping offset rows, then passing on at most
+// limit rows, or all of them if limit is zero.
+func NewWindow(offset, limit int64) (*Window, error) {
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("limit: offset and limit cannot be negative, got %d and %d", offset, limit)
+	}
+	w := &Window{skip: offset, left: limit}
+	if limit == 0 {
+		w.left = -1
+	}
+	return w, nil
+}
+
+// Slice returns the rows of record inside the window, or nil if there are
+// none. It takes ownership of record; the caller owns the returned one.
+func (w *Window) Slice(record arrow.Record) arrow.Record {
+	n := record.NumRows()
+	start := min(w.skip, n)
+	w.skip -= start
+	end := n
+	if w.left >= 0 {
+		end = min(n, start+w.left)
+		w.left -= end - start
+	}
+	switch {
+	case start == end:
+		record.Release()
+		return nil
+	case start == 0 && end == n:
+		return record
+	}
+	sliced := record.NewSlice(start, end)
+	record.Release()
+	return sliced
+}
+
+// Read calls read until it returns a record with rows inside the window
+// and returns them, or returns io.EOF once the limit is reached, without
+// calling read again.
+func (w *Window) Read(read func() (arrow.Record, error)) (arrow.Record, error) {
+	for !w.Done() {
+		record, err := read()
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			break
+		}
+		if record = w.Slice(record); record != nil {
+			return record, nil
+		}
+	}
+	return nil, io.EOF
+}
+
+// Done reports whether the limit is reached, so that no more rows need to
+// be read.
+func (w *Window) Done() bool {
+	return w.left == 0
+}
+
+// Rows returns the number of rows the window passes on out of n.
+func (w *Window) Rows(n int64) int64 {
+	n = max(n-w.skip, 0)
+	if w.left >= 0 {
+		n = min(n, w.left)
+	}
+	return n
+}
+
+// Reader returns the rows of another reader inside a Window, and stops
+// reading it once the limit is reached.
+type Reader struct {
+	reader interfaces.Reader
+	window *Window
+}
+
+// NewReader returns a Reader skipping the first offset rows of reader, then
+// returning at most limit rows, or all of them if limit is zero.
+func NewReader(reader interfaces.Reader, offset, limit int64) (*Reader, error) {
+	window, err := NewWindow(offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{reader: reader, window: window}, nil
+}
+
+func (r *Reader) Read() (arrow.Record, error) {
+	return r.window.Read(r.reader.Read)
+}
+
+// NumRows returns the number of rows the reader returns, if the size of
+// the underlying reader is known.
+func (r *Reader) NumRows() int64 {
+	if sized, ok := r.reader.(interfaces.SizedReader); ok && sized.NumRows() > 0 {
+		return r.window.Rows(sized.NumRows())
+	}
+	return 0
+}
+
+// Schema returns the schema of the underlying reader, if it tells.
+func (r *Reader) Schema() *arrow.Schema {
+	if reader, ok := r.reader.(interface{ Schema() *arrow.Schema }); ok {
+		return reader.Schema()
+	}
+	return nil
+}
+
+// Position returns the position of the underlying reader, if it tells.
+func (r *Reader) Position() string {
+	if positioned, ok := r.reader.(interfaces.PositionedReader); ok {
+		return positioned.Position()
+	}
+	return ""
+}
+
+func (r *Reader) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,136 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package limit
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// countingReader returns n records of rows consecutive ids and counts the
+// reads.
+type countingReader struct {
+	mem     memory.Allocator
+	n, rows int64
+	reads   int
+	next    int64
+}
+
+func (r *countingReader) Read() (arrow.Record, error) {
+	r.reads++
+	if r.next >= r.n*r.rows {
+		return nil, io.EOF
+	}
+	b := array.NewRecordBuilder(r.mem, arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil))
+	defer b.Release()
+	for i := int64(0); i < r.rows; i++ {
+		b.Field(0).(*array.Int64Builder).Append(r.next)
+		r.next++
+	}
+	return b.NewRecord(), nil
+}
+
+func (r *countingReader) NumRows() int64 { return r.n * r.rows }
+
+func (r *countingReader) Close() error { return nil }
+
+func readAll(t *testing.T, r *Reader) (ids []int64, sizes []int64) {
+	t.Helper()
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return ids, sizes
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, rec.Column(0).(*array.Int64).Int64Values()...)
+		sizes = append(sizes, rec.NumRows())
+		rec.Release()
+	}
+}
+
+func TestReader(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		offset, limit int64
+		first, rows   int64
+		sizes         []int64
+		reads         int
+	}{
+		{"offset and limit", 15, 20, 15, 20, []int64{5, 10, 5}, 4},
+		{"limit only", 0, 10, 0, 10, []int64{10}, 1},
+		{"offset only", 35, 0, 35, 15, []int64{5, 10}, 6},
+		{"offset past the end", 60, 5, 0, 0, nil, 6},
+		{"neither", 0, 0, 0, 50, []int64{10, 10, 10, 10, 10}, 6},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+			defer mem.AssertSize(t, 0)
+			src := &countingReader{mem: mem, n: 5, rows: 10}
+			r, err := NewReader(src, test.offset, test.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.NumRows(); got != test.rows {
+				t.Errorf("NumRows() = %d, want %d", got, test.rows)
+			}
+			ids, sizes := readAll(t, r)
+			if int64(len(ids)) != test.rows {
+				t.Fatalf("read %d rows, want %d", len(ids), test.rows)
+			}
+			for i, id := range ids {
+				if id != test.first+int64(i) {
+					t.Fatalf("row %d has id %d, want %d", i, id, test.first+int64(i))
+				}
+			}
+			if !reflect.DeepEqual(sizes, test.sizes) {
+				t.Errorf("record sizes = %v, want %v", sizes, test.sizes)
+			}
+			if src.reads != test.reads {
+				t.Errorf("read the source %d times, want %d", src.reads, test.reads)
+			}
+		})
+	}
+}
+
+func TestNewWindowRejectsNegative(t *testing.T) {
+	if _, err := NewWindow(-1, 0); err == nil {
+		t.Error("expected an error for a negative offset")
+	}
+	if _, err := NewWindow(0, -1); err == nil {
+		t.Error("expected an error for a negative limit")
+	}
+}
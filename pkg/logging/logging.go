@@ -0,0 +1,135 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package logging sets up structured logging with log/slog and carries
+// loggers in contexts, so that pipelines, readers and writers log with the
+// fields of the run they belong to. Code without a logger in its context
+// logs through slog.Default.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Options configures a logger made by New.
+type Options struct {
+	// Level is the minimum level logged, slog.LevelInfo by default.
+	Level slog.Level
+	// JSON writes one JSON object per line instead of key=value text.
+	JSON bool
+}
+
+// New returns a logger writing to w.
+func New(w io.Writer, opts *Options) *slog.Logger {
+	if opts == nil {
+		opts = &Options{}
+	}
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+	if opts.JSON {
+		return slog.New(slog.NewJSONHandler(w, handlerOpts))
+	}
+	return slog.New(slog.NewTextHandler(w, handlerOpts))
+}
+
+// ParseLevel parses debug, info, warn (or warning) and error, in any case.
+// An empty string is info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+// ParseFormat parses text or json, in any case, and reports whether it is
+// json. An empty string is text.
+func ParseFormat(s string) (json bool, err error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "text":
+		return false, nil
+	case "json":
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown log format %q", s)
+}
+
+type loggerKey struct{}
+
+type runIDKey struct{}
+
+// WithLogger returns a copy of ctx carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or slog.Default.
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+			return l
+		}
+	}
+	return slog.Default()
+}
+
+// WithRunID returns a copy of ctx carrying the run ID id, and a logger
+// that adds it to each entry as run_id.
+func WithRunID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, runIDKey{}, id)
+	return WithLogger(ctx, FromContext(ctx).With("run_id", id))
+}
+
+// RunID returns the run ID carried by ctx, or "".
+func RunID(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+// NewRun returns ctx with a new run ID, unless it already carries one, and
+// the run ID. Everything created with the returned context logs as part of
+// the run.
+func NewRun(ctx context.Context) (context.Context, string) {
+	if id := RunID(ctx); id != "" {
+		return ctx, id
+	}
+	id := uuid.NewString()
+	return WithRunID(ctx, id), id
+}
@@ -0,0 +1,107 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	for input, want := range map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"debug":   slog.LevelDebug,
+		"INFO":    slog.LevelInfo,
+		"Warning": slog.LevelWarn,
+		"warn":    slog.LevelWarn,
+		"error":   slog.LevelError,
+	} {
+		got, err := ParseLevel(input)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel accepted an unknown level")
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat accepted an unknown format")
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, &Options{Level: slog.LevelWarn, JSON: true})
+	logger.Info("hidden")
+	logger.Warn("shown", "rows", 3)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("want a single JSON entry, got %q", buf.String())
+	}
+	if entry["msg"] != "shown" || entry["rows"] != float64(3) {
+		t.Errorf("got %v", entry)
+	}
+
+	buf.Reset()
+	New(&buf, nil).Info("text", "k", "v")
+	if !strings.Contains(buf.String(), "msg=text k=v") {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestRuns(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), New(&buf, &Options{JSON: true}))
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("a context without a logger should give the default logger")
+	}
+
+	ctx, id := NewRun(ctx)
+	if id == "" || RunID(ctx) != id {
+		t.Fatalf("run ID %q, context carries %q", id, RunID(ctx))
+	}
+	if _, again := NewRun(ctx); again != id {
+		t.Errorf("NewRun replaced run %s with %s", id, again)
+	}
+
+	FromContext(ctx).Info("step")
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["run_id"] != id {
+		t.Errorf("entry %v lacks run_id %s", entry, id)
+	}
+}
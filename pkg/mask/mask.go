@@ -0,0 +1,274 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package mask sanitizes configured columns of records by hashing,
+// truncating, redacting or nulling out their values, so data can be shared
+// without exposing sensitive fields.
+package mask
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+)
+
+// Method is how a column is masked.
+type Method string
+
+const (
+	// Hash replaces values with the hex SHA-256 digest of the salt followed
+	// by the value. The column becomes a string column.
+	Hash Method = config.MaskHash
+	// Truncate keeps the first Length characters of string values.
+	Truncate Method = config.MaskTruncate
+	// Redact replaces matches of Pattern in string values with Replacement.
+	Redact Method = config.MaskRedact
+	// Null replaces every value with null.
+	Null Method = config.MaskNull
+)
+
+// DefaultReplacement is used by Redact when no replacement is configured.
+const DefaultReplacement = "[REDACTED]"
+
+// ColumnMask configures how one column is masked.
+type ColumnMask struct {
+	Column      string
+	Method      Method
+	Salt        string // Hash
+	Pattern     string // Redact
+	Replacement string // Redact
+	Length      int    // Truncate
+}
+
+// columnMasker masks the values of one column.
+type columnMasker struct {
+	ColumnMask
+	re *regexp.Regexp
+}
+
+// Masker is a pipeline Transformer that masks configured columns. Columns
+// that are not configured pass through unchanged.
+type Masker struct {
+	masks map[string]*columnMasker
+	mem   memory.Allocator
+
+	src *arrow.Schema
+	dst *arrow.Schema
+}
+
+// New creates a Masker applying masks.
+func New(masks []ColumnMask) (*Masker, error) {
+	m := &Masker{masks: make(map[string]*columnMasker, len(masks)), mem: memory.DefaultAllocator}
+	for _, cm := range masks {
+		if cm.Column == "" {
+			return nil, fmt.Errorf("column name cannot be empty")
+		}
+		if _, ok := m.masks[cm.Column]; ok {
+			return nil, fmt.Errorf("column %q is masked more than once", cm.Column)
+		}
+		cmk := &columnMasker{ColumnMask: cm}
+		switch cm.Method {
+		case Hash, Null:
+		case Truncate:
+			if cm.Length < 0 {
+				return nil, fmt.Errorf("column %q: truncate length cannot be negative", cm.Column)
+			}
+		case Redact:
+			re, err := regexp.Compile(cm.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: invalid pattern: %w", cm.Column, err)
+			}
+			cmk.re = re
+			if cmk.Replacement == "" {
+				cmk.Replacement = DefaultReplacement
+			}
+		default:
+			return nil, fmt.Errorf("column %q: unknown mask method %q", cm.Column, cm.Method)
+		}
+		m.masks[cm.Column] = cmk
+	}
+	return m, nil
+}
+
+// FromConfig creates a Masker from a workflow `transform: mask` entry.
+func FromConfig(t config.Transform) (*Masker, error) {
+	if t.Transform != config.TransformMask {
+		return nil, fmt.Errorf("expected a %q transform, got %q", config.TransformMask, t.Transform)
+	}
+	masks := make([]ColumnMask, len(t.Columns))
+	for i, col := range t.Columns {
+		masks[i] = ColumnMask{
+			Column:      col.Name,
+			Method:      Method(col.Method),
+			Salt:        col.Salt,
+			Pattern:     col.Pattern,
+			Replacement: col.Replacement,
+			Length:      col.Length,
+		}
+	}
+	return New(masks)
+}
+
+// Schema returns the schema of the records Transform produces for records
+// with schema src.
+func (m *Masker) Schema(src *arrow.Schema) (*arrow.Schema, error) {
+	fields := src.Fields()
+	found := 0
+	for i, field := range fields {
+		cm, ok := m.masks[field.Name]
+		if !ok {
+			continue
+		}
+		found++
+		switch cm.Method {
+		case Hash:
+			fields[i].Type = arrow.BinaryTypes.String
+		case Truncate, Redact:
+			if field.Type.ID() != arrow.STRING && field.Type.ID() != arrow.LARGE_STRING {
+				return nil, fmt.Errorf("column %q: %s requires a string column, got %s", field.Name, cm.Method, field.Type)
+			}
+		case Null:
+			fields[i].Nullable = true
+		}
+	}
+	if found < len(m.masks) {
+		for name := range m.masks {
+			if len(src.FieldIndices(name)) == 0 {
+				return nil, fmt.Errorf("column %q not found", name)
+			}
+		}
+	}
+	md := src.Metadata()
+	return arrow.NewSchema(fields, &md), nil
+}
+
+// Transform returns record with the configured columns masked.
+func (m *Masker) Transform(record arrow.Record) (arrow.Record, error) {
+	if m.src == nil || !record.Schema().Equal(m.src) {
+		dst, err := m.Schema(record.Schema())
+		if err != nil {
+			return nil, err
+		}
+		m.src, m.dst = record.Schema(), dst
+	}
+
+	cols := make([]arrow.Array, record.NumCols())
+	defer func() {
+		for _, col := range cols {
+			if col != nil {
+				col.Release()
+			}
+		}
+	}()
+	for i, col := range record.Columns() {
+		cm, ok := m.masks[m.src.Field(i).Name]
+		if !ok {
+			col.Retain()
+			cols[i] = col
+			continue
+		}
+		cols[i] = m.mask(cm, col)
+	}
+	return array.NewRecord(m.dst, cols, record.NumRows()), nil
+}
+
+func (m *Masker) mask(cm *columnMasker, arr arrow.Array) arrow.Array {
+	switch cm.Method {
+	case Null:
+		return array.MakeArrayOfNull(m.mem, arr.DataType(), arr.Len())
+	case Hash:
+		b := array.NewStringBuilder(m.mem)
+		defer b.Release()
+		b.Reserve(arr.Len())
+		for i := 0; i < arr.Len(); i++ {
+			if arr.IsNull(i) {
+				b.AppendNull()
+				continue
+			}
+			sum := sha256.Sum256(append([]byte(cm.Salt), valueBytes(arr, i)...))
+			b.Append(hex.EncodeToString(sum[:]))
+		}
+		return b.NewArray()
+	}
+
+	fn := func(s string) string { return cm.re.ReplaceAllString(s, cm.Replacement) }
+	if cm.Method == Truncate {
+		fn = func(s string) string { return truncate(s, cm.Length) }
+	}
+	b := array.NewBuilder(m.mem, arr.DataType())
+	defer b.Release()
+	b.Reserve(arr.Len())
+	for i := 0; i < arr.Len(); i++ {
+		if arr.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		switch b := b.(type) {
+		case *array.StringBuilder:
+			b.Append(fn(arr.(*array.String).Value(i)))
+		case *array.LargeStringBuilder:
+			b.Append(fn(arr.(*array.LargeString).Value(i)))
+		}
+	}
+	return b.NewArray()
+}
+
+// valueBytes returns the bytes hashed for the value at i: the raw bytes of
+// string and binary values, and the string form of anything else.
+func valueBytes(arr arrow.Array, i int) []byte {
+	switch a := arr.(type) {
+	case *array.String:
+		return []byte(a.Value(i))
+	case *array.LargeString:
+		return []byte(a.Value(i))
+	case *array.Binary:
+		return a.Value(i)
+	case *array.LargeBinary:
+		return a.Value(i)
+	default:
+		return []byte(arr.ValueStr(i))
+	}
+}
+
+// truncate returns the first n characters of s.
+func truncate(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
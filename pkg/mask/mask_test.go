@@ -0,0 +1,121 @@
+package mask
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"gopkg.in/yaml.v3"
+)
+
+func testRecord(t *testing.T) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "email", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "ssn", Type: arrow.BinaryTypes.String},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "notes", Type: arrow.BinaryTypes.String},
+	}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{7, 8}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"a@example.com", ""}, []bool{true, false})
+	b.Field(2).(*array.StringBuilder).AppendValues([]string{"ssn 123-45-6789", "none"}, nil)
+	b.Field(3).(*array.StringBuilder).AppendValues([]string{"Zoë Smith", "Al"}, nil)
+	b.Field(4).(*array.StringBuilder).AppendValues([]string{"secret", "secret"}, nil)
+	return b.NewRecord()
+}
+
+func sha(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestMaskFromConfig(t *testing.T) {
+	var transform config.Transform
+	err := yaml.Unmarshal([]byte(`
+transform: mask
+columns:
+  - name: id
+    method: hash
+    salt: pepper
+  - name: email
+    method: hash
+  - name: ssn
+    method: redact
+    pattern: '\d{3}-\d{2}-\d{4}'
+  - name: name
+    method: truncate
+    length: 3
+  - name: notes
+    method: "null"
+`), &transform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := FromConfig(transform)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := testRecord(t)
+	defer rec.Release()
+	out, err := m.Transform(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+
+	id := out.Column(0).(*array.String)
+	if id.Value(0) != sha("pepper7") || id.Value(1) != sha("pepper8") {
+		t.Errorf("id = %v", id)
+	}
+	email := out.Column(1).(*array.String)
+	if email.Value(0) != sha("a@example.com") || !email.IsNull(1) {
+		t.Errorf("email = %v", email)
+	}
+	ssn := out.Column(2).(*array.String)
+	if ssn.Value(0) != "ssn [REDACTED]" || ssn.Value(1) != "none" {
+		t.Errorf("ssn = %v", ssn)
+	}
+	name := out.Column(3).(*array.String)
+	if name.Value(0) != "Zoë" || name.Value(1) != "Al" {
+		t.Errorf("name = %v", name)
+	}
+	if out.Column(4).NullN() != 2 || !out.Schema().Field(4).Nullable {
+		t.Errorf("notes = %v, nullable = %v", out.Column(4), out.Schema().Field(4).Nullable)
+	}
+}
+
+func TestMaskErrors(t *testing.T) {
+	rec := testRecord(t)
+	defer rec.Release()
+
+	for name, masks := range map[string][]ColumnMask{
+		"unknown method":  {{Column: "id", Method: "scramble"}},
+		"invalid pattern": {{Column: "ssn", Method: Redact, Pattern: "("}},
+		"duplicate":       {{Column: "id", Method: Null}, {Column: "id", Method: Hash}},
+	} {
+		if _, err := New(masks); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	for name, masks := range map[string][]ColumnMask{
+		"missing column":      {{Column: "missing", Method: Null}},
+		"truncate non-string": {{Column: "id", Method: Truncate, Length: 1}},
+	} {
+		m, err := New(masks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Transform(rec); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
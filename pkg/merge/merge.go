@@ -0,0 +1,380 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package merge applies batches of keyed rows to SQL tables as upserts and
+// deletes, for table sinks fed by change data capture streams. The rows of
+// a batch are staged in a table of their own, then merged into the target
+// with either DELETE and INSERT statements or a single MERGE.
+//
+// Rows follow the op column convention of CDC streams, such as the __op
+// column of the debezium transformer: rows whose op is "d" delete the row
+// with their key, and all others, including those without an op, insert it
+// or replace it.
+package merge
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+const (
+	// DefaultOpColumn is the column holding the operation of each row.
+	DefaultOpColumn = "__op"
+	// OpDelete is the operation of rows deleting the row with their key.
+	OpDelete = "d"
+)
+
+// Strategy is how staged rows are merged into the target table.
+type Strategy string
+
+const (
+	// StrategyDeleteInsert deletes the rows of the target with a key of
+	// the batch, then inserts the rows that are not deletes. It needs no
+	// key constraint on the target and works on any SQL database.
+	StrategyDeleteInsert Strategy = "delete_insert"
+	// StrategyMerge runs a single MERGE statement, which updates matched
+	// rows in place. It needs PostgreSQL 15 or DuckDB 1.4 and later.
+	StrategyMerge Strategy = "merge"
+)
+
+// Options configures merging.
+type Options struct {
+	// Keys are the columns identifying a row.
+	Keys []string
+	// OpColumn holds the operation of each row. Defaults to
+	// DefaultOpColumn. Batches without the column only upsert; the column
+	// itself is not written to the target.
+	OpColumn string
+	// Strategy defaults to StrategyDeleteInsert.
+	Strategy Strategy
+	// CreateTable creates the target, if missing, with the columns of the
+	// first batch.
+	CreateTable bool
+}
+
+// withDefaults returns a copy of o with its defaults set, or an error if
+// it is invalid.
+func (o *Options) withDefaults() (Options, error) {
+	if o == nil || len(o.Keys) == 0 {
+		return Options{}, fmt.Errorf("merge needs at least one key column")
+	}
+	opts := *o
+	if opts.OpColumn == "" {
+		opts.OpColumn = DefaultOpColumn
+	}
+	switch opts.Strategy {
+	case "":
+		opts.Strategy = StrategyDeleteInsert
+	case StrategyDeleteInsert, StrategyMerge:
+	default:
+		return Options{}, fmt.Errorf("unknown merge strategy %q", opts.Strategy)
+	}
+	seen := make(map[string]bool, len(opts.Keys))
+	for _, key := range opts.Keys {
+		if key == "" || key == opts.OpColumn {
+			return Options{}, fmt.Errorf("invalid key column %q", key)
+		}
+		if seen[key] {
+			return Options{}, fmt.Errorf("key column %q listed twice", key)
+		}
+		seen[key] = true
+	}
+	return opts, nil
+}
+
+// Validate checks the options.
+func (o *Options) Validate() error {
+	_, err := o.withDefaults()
+	return err
+}
+
+// Compact returns the last row of record for each key, in the order of
+// those rows, so that a batch holds at most one operation per key. Rows
+// with a null key fail. record is returned, retained, when no key repeats.
+func Compact(record arrow.Record, opts *Options) (arrow.Record, error) {
+	o, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]arrow.Array, len(o.Keys))
+	for i, name := range o.Keys {
+		idx := record.Schema().FieldIndices(name)
+		if len(idx) == 0 {
+			return nil, fmt.Errorf("no key column %q", name)
+		}
+		keys[i] = record.Column(idx[0])
+	}
+
+	rows := int(record.NumRows())
+	last := make(map[string]int, rows)
+	var b strings.Builder
+	for row := 0; row < rows; row++ {
+		b.Reset()
+		for i, col := range keys {
+			if col.IsNull(row) {
+				return nil, fmt.Errorf("row %d: key column %q is null", row, o.Keys[i])
+			}
+			// Length prefixes keep ("a,b", "c") and ("a", "b,c") apart.
+			value := col.ValueStr(row)
+			b.WriteString(strconv.Itoa(len(value)))
+			b.WriteByte(':')
+			b.WriteString(value)
+		}
+		last[b.String()] = row
+	}
+	if len(last) == rows {
+		record.Retain()
+		return record, nil
+	}
+
+	keep := make([]bool, rows)
+	for _, row := range last {
+		keep[row] = true
+	}
+	ib := array.NewInt64Builder(memory.DefaultAllocator)
+	defer ib.Release()
+	for row, ok := range keep {
+		if ok {
+			ib.Append(int64(row))
+		}
+	}
+	indices := ib.NewArray()
+	defer indices.Release()
+
+	cols := make([]arrow.Array, record.NumCols())
+	defer func() {
+		for _, col := range cols {
+			if col != nil {
+				col.Release()
+			}
+		}
+	}()
+	for i, col := range record.Columns() {
+		if cols[i], err = compute.TakeArray(context.Background(), col, indices); err != nil {
+			return nil, fmt.Errorf("failed to compact column %q: %w", record.ColumnName(i), err)
+		}
+	}
+	return array.NewRecord(record.Schema(), cols, int64(indices.Len())), nil
+}
+
+// Statements returns the SQL statements merging the rows of the staging
+// table, holding records of schema, into target. Table names may be
+// qualified, such as schema.table.
+func Statements(target, staging string, schema *arrow.Schema, opts *Options) ([]string, error) {
+	o, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range o.Keys {
+		if !schema.HasField(key) {
+			return nil, fmt.Errorf("no key column %q", key)
+		}
+	}
+	hasOp := schema.HasField(o.OpColumn)
+	var columns, values, updates []string
+	isKey := make(map[string]bool, len(o.Keys))
+	for _, key := range o.Keys {
+		isKey[key] = true
+	}
+	for _, f := range schema.Fields() {
+		if f.Name == o.OpColumn {
+			continue
+		}
+		name := QuoteIdentifier(f.Name)
+		columns = append(columns, name)
+		values = append(values, "s."+name)
+		if !isKey[f.Name] {
+			updates = append(updates, name+" = s."+name)
+		}
+	}
+	t, s := QuoteTable(target), QuoteTable(staging)
+	cols := strings.Join(columns, ", ")
+	var notDelete, isDelete string
+	if hasOp {
+		op := QuoteIdentifier(o.OpColumn)
+		notDelete = fmt.Sprintf("(s.%s IS NULL OR s.%s <> '%s')", op, op, OpDelete)
+		isDelete = fmt.Sprintf("s.%s = '%s'", op, OpDelete)
+	}
+
+	var stmts []string
+	if o.CreateTable {
+		stmts = append(stmts, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s AS SELECT %s FROM %s WHERE false", t, cols, s))
+	}
+	on := make([]string, len(o.Keys))
+	for i, key := range o.Keys {
+		k := QuoteIdentifier(key)
+		on[i] = fmt.Sprintf("t.%s = s.%s", k, k)
+	}
+	match := strings.Join(on, " AND ")
+
+	switch o.Strategy {
+	case StrategyDeleteInsert:
+		// DELETE cannot alias its table everywhere, so the keys of the
+		// target are qualified by its name.
+		where := make([]string, len(o.Keys))
+		for i, key := range o.Keys {
+			k := QuoteIdentifier(key)
+			where[i] = fmt.Sprintf("%s.%s = s.%s", t, k, k)
+		}
+		stmts = append(stmts, fmt.Sprintf("DELETE FROM %s WHERE EXISTS (SELECT 1 FROM %s AS s WHERE %s)", t, s, strings.Join(where, " AND ")))
+		insert := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s AS s", t, cols, strings.Join(values, ", "), s)
+		if hasOp {
+			insert += " WHERE " + notDelete
+		}
+		stmts = append(stmts, insert)
+	case StrategyMerge:
+		var b strings.Builder
+		fmt.Fprintf(&b, "MERGE INTO %s AS t USING %s AS s ON %s", t, s, match)
+		if hasOp {
+			fmt.Fprintf(&b, " WHEN MATCHED AND %s THEN DELETE", isDelete)
+		}
+		if len(updates) > 0 {
+			fmt.Fprintf(&b, " WHEN MATCHED THEN UPDATE SET %s", strings.Join(updates, ", "))
+		}
+		b.WriteString(" WHEN NOT MATCHED")
+		if hasOp {
+			b.WriteString(" AND " + notDelete)
+		}
+		fmt.Fprintf(&b, " THEN INSERT (%s) VALUES (%s)", cols, strings.Join(values, ", "))
+		stmts = append(stmts, b.String())
+	}
+	return stmts, nil
+}
+
+// StagingTable returns the name of the staging table of target: an
+// unqualified name derived from it, so that writers to different tables do
+// not share one.
+func StagingTable(target string) string {
+	var b strings.Builder
+	b.WriteString("arrowarc_staging_")
+	for _, r := range target {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// QuoteIdentifier quotes a SQL identifier.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteTable quotes a table name, keeping dotted names such as
+// schema.table as separate parts.
+func QuoteTable(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// ApplyADBC merges record into target over an ADBC connection: it stages
+// the compacted rows with bulk ingestion, then runs the statements of opts
+// in a transaction, and drops the staging table.
+func ApplyADBC(ctx context.Context, conn adbc.Connection, target string, record arrow.Record, opts *Options) error {
+	batch, err := Compact(record, opts)
+	if err != nil {
+		return err
+	}
+	defer batch.Release()
+	staging := StagingTable(target)
+	stmts, err := Statements(target, staging, batch.Schema(), opts)
+	if err != nil {
+		return err
+	}
+
+	if err := execADBC(ctx, conn, "DROP TABLE IF EXISTS "+QuoteTable(staging)); err != nil {
+		return err
+	}
+	if err := ingestADBC(ctx, conn, staging, batch); err != nil {
+		return fmt.Errorf("failed to stage rows: %w", err)
+	}
+	defer execADBC(context.WithoutCancel(ctx), conn, "DROP TABLE IF EXISTS "+QuoteTable(staging))
+
+	if err := execADBC(ctx, conn, "BEGIN"); err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if err := execADBC(ctx, conn, stmt); err != nil {
+			_ = execADBC(context.WithoutCancel(ctx), conn, "ROLLBACK")
+			return fmt.Errorf("failed to merge into %s: %w", target, err)
+		}
+	}
+	return execADBC(ctx, conn, "COMMIT")
+}
+
+// execADBC runs one SQL statement.
+func execADBC(ctx context.Context, conn adbc.Connection, query string) error {
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return fmt.Errorf("failed to create statement: %w", err)
+	}
+	defer stmt.Close()
+	if err := stmt.SetSqlQuery(query); err != nil {
+		return fmt.Errorf("failed to set SQL query: %w", err)
+	}
+	if _, err := stmt.ExecuteUpdate(ctx); err != nil {
+		return fmt.Errorf("%s: %w", query, err)
+	}
+	return nil
+}
+
+// ingestADBC creates table holding the rows of record.
+func ingestADBC(ctx context.Context, conn adbc.Connection, table string, record arrow.Record) error {
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return fmt.Errorf("failed to create statement: %w", err)
+	}
+	defer stmt.Close()
+	if err := stmt.SetOption(adbc.OptionKeyIngestMode, adbc.OptionValueIngestModeCreate); err != nil {
+		return fmt.Errorf("failed to set ingest mode: %w", err)
+	}
+	if err := stmt.SetOption(adbc.OptionKeyIngestTargetTable, table); err != nil {
+		return fmt.Errorf("failed to set target table: %w", err)
+	}
+	if err := stmt.Bind(ctx, record); err != nil {
+		return fmt.Errorf("failed to bind record: %w", err)
+	}
+	if _, err := stmt.ExecuteUpdate(ctx); err != nil {
+		return err
+	}
+	return nil
+}
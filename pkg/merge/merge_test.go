@@ -0,0 +1,144 @@
+package merge
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+var testSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	{Name: "region", Type: arrow.BinaryTypes.String},
+	{Name: "amount", Type: arrow.PrimitiveTypes.Float64},
+	{Name: DefaultOpColumn, Type: arrow.BinaryTypes.String, Nullable: true},
+}, nil)
+
+// makeRecord makes a record of testSchema; an id of -1 is null.
+func makeRecord(ids []int64, regions []string, amounts []float64, ops []string) arrow.Record {
+	b := array.NewRecordBuilder(memory.DefaultAllocator, testSchema)
+	defer b.Release()
+	for i, id := range ids {
+		if id < 0 {
+			b.Field(0).AppendNull()
+		} else {
+			b.Field(0).(*array.Int64Builder).Append(id)
+		}
+		b.Field(1).(*array.StringBuilder).Append(regions[i])
+		b.Field(2).(*array.Float64Builder).Append(amounts[i])
+		b.Field(3).(*array.StringBuilder).Append(ops[i])
+	}
+	return b.NewRecord()
+}
+
+func TestCompact(t *testing.T) {
+	rec := makeRecord(
+		[]int64{1, 2, 1, 1, 3},
+		[]string{"eu", "eu", "us", "eu", "eu"},
+		[]float64{10, 20, 30, 40, 50},
+		[]string{"c", "c", "c", "u", "d"},
+	)
+	defer rec.Release()
+
+	out, err := Compact(rec, &Options{Keys: []string{"id", "region"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	// (1, eu) keeps its update, the last of its rows, in that row's place.
+	if got := out.Column(2).(*array.Float64).Float64Values(); !reflect.DeepEqual(got, []float64{20, 30, 40, 50}) {
+		t.Errorf("amounts = %v, want [20 30 40 50]", got)
+	}
+	if got := out.Column(3).(*array.String).Value(2); got != "u" {
+		t.Errorf("op of (1, eu) = %q, want u", got)
+	}
+
+	// Without repeated keys the record is returned as is.
+	same, err := Compact(rec, &Options{Keys: []string{"amount"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer same.Release()
+	if same != rec {
+		t.Error("Compact copied a record without repeated keys")
+	}
+
+	nulls := makeRecord([]int64{1, -1}, []string{"eu", "eu"}, []float64{1, 2}, []string{"c", "c"})
+	defer nulls.Release()
+	if _, err := Compact(nulls, &Options{Keys: []string{"id"}}); err == nil || !strings.Contains(err.Error(), "row 1") {
+		t.Errorf("Compact with a null key = %v", err)
+	}
+	if _, err := Compact(rec, &Options{Keys: []string{"missing"}}); err == nil {
+		t.Error("Compact with a missing key column should fail")
+	}
+}
+
+func TestStatementsDeleteInsert(t *testing.T) {
+	stmts, err := Statements("sales.orders", StagingTable("sales.orders"), testSchema, &Options{
+		Keys:        []string{"id", "region"},
+		CreateTable: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`CREATE TABLE IF NOT EXISTS "sales"."orders" AS SELECT "id", "region", "amount" FROM "arrowarc_staging_sales_orders" WHERE false`,
+		`DELETE FROM "sales"."orders" WHERE EXISTS (SELECT 1 FROM "arrowarc_staging_sales_orders" AS s WHERE "sales"."orders"."id" = s."id" AND "sales"."orders"."region" = s."region")`,
+		`INSERT INTO "sales"."orders" ("id", "region", "amount") SELECT s."id", s."region", s."amount" FROM "arrowarc_staging_sales_orders" AS s WHERE (s."__op" IS NULL OR s."__op" <> 'd')`,
+	}
+	if !reflect.DeepEqual(stmts, want) {
+		t.Errorf("statements =\n%s\nwant\n%s", strings.Join(stmts, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestStatementsMerge(t *testing.T) {
+	stmts, err := Statements("orders", "stage", testSchema, &Options{Keys: []string{"id"}, Strategy: StrategyMerge})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `MERGE INTO "orders" AS t USING "stage" AS s ON t."id" = s."id"` +
+		` WHEN MATCHED AND s."__op" = 'd' THEN DELETE` +
+		` WHEN MATCHED THEN UPDATE SET "region" = s."region", "amount" = s."amount"` +
+		` WHEN NOT MATCHED AND (s."__op" IS NULL OR s."__op" <> 'd')` +
+		` THEN INSERT ("id", "region", "amount") VALUES (s."id", s."region", s."amount")`
+	if len(stmts) != 1 || stmts[0] != want {
+		t.Errorf("statements = %q, want %q", stmts, want)
+	}
+
+	// Without an op column every row is an upsert.
+	upserts := arrow.NewSchema(testSchema.Fields()[:3], nil)
+	stmts, err = Statements("orders", "stage", upserts, &Options{Keys: []string{"id"}, Strategy: StrategyMerge})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stmts[0], "__op") || strings.Contains(stmts[0], "DELETE") {
+		t.Errorf("upsert-only merge = %q", stmts[0])
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	for _, opts := range []*Options{
+		nil,
+		{},
+		{Keys: []string{""}},
+		{Keys: []string{"id", "id"}},
+		{Keys: []string{"__op"}},
+		{Keys: []string{"id"}, Strategy: "upsert"},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", opts)
+		}
+	}
+	if err := (&Options{Keys: []string{"id"}}).Validate(); err != nil {
+		t.Error(err)
+	}
+	if _, err := Statements("orders", "stage", testSchema, &Options{Keys: []string{"sku"}}); err == nil {
+		t.Error("Statements with a missing key column should fail")
+	}
+	if got := QuoteTable(`a."b`); got != `"a"."""b"` {
+		t.Errorf("QuoteTable = %s", got)
+	}
+}
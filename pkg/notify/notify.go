@@ -0,0 +1,313 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package notify calls webhooks and posts Slack messages when a workflow
+// run completes, with the run's report attached, as the notifications of
+// the workflow ask, so that operators hear about failed runs.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/history"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"github.com/arrowarc/arrowarc/pkg/retry"
+)
+
+// DefaultTimeout bounds each request of a notification that sets no
+// timeout.
+const DefaultTimeout = 10 * time.Second
+
+// Run describes a completed workflow run. It is the body of a webhook
+// without a template, and the data of templates.
+type Run struct {
+	Workflow string `json:"workflow"`
+	ID       string `json:"run_id,omitempty"`
+	// Outcome is history.Succeeded or history.Failed.
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Duration  string    `json:"duration"`
+	// Report is the report of the run, as JSON.
+	Report json.RawMessage `json:"report,omitempty"`
+}
+
+// Succeeded reports whether the run succeeded.
+func (r *Run) Succeeded() bool {
+	return r.Outcome == history.Succeeded
+}
+
+// Notifier sends the notifications of a workflow.
+type Notifier struct {
+	// Client sends the requests, http.DefaultClient if nil.
+	Client  *http.Client
+	targets []*target
+}
+
+type target struct {
+	config.Notification
+	url     string
+	headers map[string]string
+	tmpl    *template.Template
+	timeout time.Duration
+	retry   *retry.Policy
+}
+
+// funcs are the functions of templates besides the builtins: json encodes
+// a value, such as {{json .Error}} for a quoted string.
+var funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// FromConfig returns a notifier sending the notifications of a workflow.
+func FromConfig(cfg *config.Config) (*Notifier, error) {
+	return New(cfg.Workflow.Notifications...)
+}
+
+// New returns a notifier sending notifications, with environment variables
+// expanded in their URLs and headers. It fails if one is invalid or its
+// template does not parse; a URL that expands to nothing fails when sent.
+func New(notifications ...config.Notification) (*Notifier, error) {
+	n := &Notifier{}
+	for _, notification := range notifications {
+		t, err := newTarget(notification)
+		if err != nil {
+			return nil, fmt.Errorf("notification '%s': %w", notification.Name, err)
+		}
+		n.targets = append(n.targets, t)
+	}
+	return n, nil
+}
+
+func newTarget(n config.Notification) (*target, error) {
+	switch n.Type {
+	case config.NotificationWebhook, config.NotificationSlack:
+	default:
+		return nil, fmt.Errorf("unknown type '%s'", n.Type)
+	}
+	t := &target{Notification: n, url: os.ExpandEnv(n.URL), timeout: DefaultTimeout}
+	if n.URL == "" {
+		return nil, errors.New("no url")
+	}
+	if len(n.Headers) > 0 {
+		t.headers = make(map[string]string, len(n.Headers))
+		for name, value := range n.Headers {
+			t.headers[name] = os.ExpandEnv(value)
+		}
+	}
+	if n.Template != "" {
+		tmpl, err := template.New(n.Name).Funcs(funcs).Option("missingkey=error").Parse(n.Template)
+		if err != nil {
+			return nil, err
+		}
+		t.tmpl = tmpl
+	}
+	if n.Timeout != "" {
+		d, err := time.ParseDuration(n.Timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", n.Timeout)
+		}
+		t.timeout = d
+	}
+	if n.Retry != nil {
+		p, err := n.Retry.Policy()
+		if err != nil {
+			return nil, err
+		}
+		t.retry = p
+	}
+	return t, nil
+}
+
+// Run calls fn, which runs a workflow and returns its report, and then
+// sends the notifications that ask for its outcome. It returns the error
+// of fn; notifications that fail are logged. fn runs with a run ID, which
+// the notifications carry.
+func (n *Notifier) Run(ctx context.Context, workflow string, fn func(ctx context.Context) (report string, err error)) error {
+	ctx, id := logging.NewRun(ctx)
+	run := &Run{Workflow: workflow, ID: id, StartTime: time.Now()}
+	report, err := fn(ctx)
+	run.finish(report, err)
+	if err := n.Notify(ctx, run); err != nil {
+		logging.FromContext(ctx).Warn("notification failed", "workflow", workflow, "error", err)
+	}
+	return err
+}
+
+func (r *Run) finish(report string, err error) {
+	r.EndTime = time.Now()
+	r.Duration = r.EndTime.Sub(r.StartTime).Round(time.Millisecond).String()
+	r.Outcome = history.Succeeded
+	if err != nil {
+		r.Outcome = history.Failed
+		r.Error = err.Error()
+	}
+	switch {
+	case report == "":
+	case json.Valid([]byte(report)):
+		r.Report = json.RawMessage(report)
+	default:
+		r.Report, _ = json.Marshal(report)
+	}
+}
+
+// Notify sends the notifications that ask for the outcome of run, and
+// returns the errors of those that could not be sent.
+func (n *Notifier) Notify(ctx context.Context, run *Run) error {
+	if n == nil {
+		return nil
+	}
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	var errs []error
+	for _, t := range n.targets {
+		if run.Succeeded() && !t.OnSuccess || !run.Succeeded() && !t.OnFailure {
+			continue
+		}
+		if err := t.send(ctx, client, run); err != nil {
+			errs = append(errs, fmt.Errorf("notification '%s': %w", t.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// body returns the JSON posted for run: the rendered template or run itself
+// for a webhook, and a message of the rendered template or a summary of run
+// for Slack.
+func (t *target) body(run *Run) ([]byte, error) {
+	var text string
+	if t.tmpl != nil {
+		var b strings.Builder
+		if err := t.tmpl.Execute(&b, run); err != nil {
+			return nil, err
+		}
+		text = b.String()
+	}
+	if t.Type == config.NotificationWebhook {
+		if t.tmpl == nil {
+			return json.Marshal(run)
+		}
+		return []byte(text), nil
+	}
+	if t.tmpl == nil {
+		text = summary(run)
+	}
+	return json.Marshal(map[string]string{"text": text})
+}
+
+// summary is the default text of Slack messages.
+func summary(run *Run) string {
+	var b strings.Builder
+	if run.Succeeded() {
+		fmt.Fprintf(&b, "Workflow *%s* succeeded in %s", run.Workflow, run.Duration)
+	} else {
+		fmt.Fprintf(&b, "Workflow *%s* failed after %s: %s", run.Workflow, run.Duration, run.Error)
+	}
+	if run.ID != "" {
+		fmt.Fprintf(&b, " (run %s)", run.ID)
+	}
+	if len(run.Report) > 0 {
+		fmt.Fprintf(&b, "\n```%s```", run.Report)
+	}
+	return b.String()
+}
+
+func (t *target) send(ctx context.Context, client *http.Client, run *Run) error {
+	if t.url == "" {
+		return fmt.Errorf("url %q is empty once expanded", t.URL)
+	}
+	body, err := t.body(run)
+	if err != nil {
+		return err
+	}
+	return t.retry.OrRetryable(retryable).Do(ctx, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range t.headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			return nil
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+	})
+}
+
+// StatusError is returned when a notification is answered with a status
+// other than 2xx.
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("status %d", e.Code)
+	}
+	return fmt.Sprintf("status %d: %s", e.Code, e.Body)
+}
+
+// retryable retries what retry.IsRetryable does, and the statuses 429 and
+// 5xx.
+func retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Code == http.StatusTooManyRequests || status.Code >= 500
+	}
+	return retry.IsRetryable(err)
+}
@@ -0,0 +1,152 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/history"
+)
+
+// recorder is a server recording the bodies posted to it, and answering
+// with status.
+type recorder struct {
+	mu     sync.Mutex
+	bodies []string
+	header http.Header
+	status int
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.bodies = append(r.bodies, string(body))
+	r.header = req.Header
+	r.mu.Unlock()
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	webhook, slack := &recorder{}, &recorder{}
+	webhookServer, slackServer := httptest.NewServer(webhook), httptest.NewServer(slack)
+	defer webhookServer.Close()
+	defer slackServer.Close()
+	t.Setenv("NOTIFY_TOKEN", "secret")
+
+	n, err := New(
+		config.Notification{Name: "hook", Type: config.NotificationWebhook, URL: webhookServer.URL, OnSuccess: true, OnFailure: true,
+			Headers: map[string]string{"Authorization": "Bearer ${NOTIFY_TOKEN}"}},
+		config.Notification{Name: "ops", Type: config.NotificationSlack, URL: slackServer.URL, OnFailure: true,
+			Template: `{{.Workflow}} {{.Outcome}}: {{.Error}}`},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := n.Run(ctx, "nightly", func(context.Context) (string, error) { return `{"records_processed":3}`, nil }); err != nil {
+		t.Fatal(err)
+	}
+	failure := errors.New("no such table")
+	if err := n.Run(ctx, "nightly", func(context.Context) (string, error) { return "", failure }); err != failure {
+		t.Fatalf("Run returned %v, want the error of the workflow", err)
+	}
+
+	if len(webhook.bodies) != 2 {
+		t.Fatalf("webhook got %d requests, want 2", len(webhook.bodies))
+	}
+	if got := webhook.header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+	var run Run
+	if err := json.Unmarshal([]byte(webhook.bodies[0]), &run); err != nil {
+		t.Fatal(err)
+	}
+	if run.Workflow != "nightly" || run.Outcome != history.Succeeded || run.ID == "" || string(run.Report) != `{"records_processed":3}` {
+		t.Errorf("webhook body = %s", webhook.bodies[0])
+	}
+
+	if len(slack.bodies) != 1 {
+		t.Fatalf("slack got %d requests, want only the failure", len(slack.bodies))
+	}
+	var message map[string]string
+	if err := json.Unmarshal([]byte(slack.bodies[0]), &message); err != nil {
+		t.Fatal(err)
+	}
+	if want := "nightly failed: no such table"; message["text"] != want {
+		t.Errorf("slack text = %q, want %q", message["text"], want)
+	}
+}
+
+func TestNotifyErrors(t *testing.T) {
+	server := httptest.NewServer(&recorder{status: http.StatusBadRequest})
+	defer server.Close()
+	n, err := New(config.Notification{Name: "hook", Type: config.NotificationWebhook, URL: server.URL, OnFailure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Notify(context.Background(), &Run{Workflow: "nightly", Outcome: history.Failed})
+	var status *StatusError
+	if !errors.As(err, &status) || status.Code != http.StatusBadRequest || !strings.Contains(err.Error(), "'hook'") {
+		t.Errorf("Notify returned %v, want status 400", err)
+	}
+	if err := n.Notify(context.Background(), &Run{Outcome: history.Succeeded}); err != nil {
+		t.Errorf("Notify sent a notification not asking for successes: %v", err)
+	}
+
+	unset, err := New(config.Notification{Name: "unset", Type: config.NotificationSlack, URL: "${NOTIFY_UNSET_URL}", OnFailure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unset.Notify(context.Background(), &Run{Outcome: history.Failed}); err == nil {
+		t.Error("Notify sent to an empty url")
+	}
+
+	for _, bad := range []config.Notification{
+		{Name: "type", Type: "email", URL: server.URL},
+		{Name: "url", Type: config.NotificationWebhook},
+		{Name: "template", Type: config.NotificationSlack, URL: server.URL, Template: "{{.Workflow"},
+		{Name: "timeout", Type: config.NotificationSlack, URL: server.URL, Timeout: "soon"},
+	} {
+		if _, err := New(bad); err == nil {
+			t.Errorf("New accepted an invalid %s", bad.Name)
+		}
+	}
+}
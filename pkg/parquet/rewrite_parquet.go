@@ -64,12 +64,9 @@ func RewriteParquetFile(
 	// Create read options
 	readOptions := &integrations.ParquetReadOptions{
 		MemoryMap: memoryMap,
-		Parallel:  true,
+		Parallel:  parallel,
 		ChunkSize: chunkSize,
 	}
-	if parallel {
-		readOptions.Parallel = true
-	}
 
 	// Create the Parquet reader
 	reader, err := integrations.NewParquetReader(ctx, inputFilePath, readOptions)
@@ -96,6 +93,10 @@ func RewriteParquetFile(
 	// Start the pipeline
 	report, err := pipeline.Start(ctx)
 	if err != nil {
+		// The partial report tells how far the rewrite got.
+		if report != "" {
+			fmt.Println(report)
+		}
 		return fmt.Errorf("failed to rewrite Parquet file: %w", err)
 	}
 
@@ -0,0 +1,199 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package parquet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/util"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+)
+
+// SplitLimit bounds the size of each file written by SplitFile. Exactly one
+// of MaxRows and MaxBytes must be set.
+type SplitLimit struct {
+	// MaxRows is the maximum number of rows per output file.
+	MaxRows int64
+	// MaxBytes is the maximum in-memory Arrow size of the rows in each output
+	// file. Encoding and compression usually make the files on disk smaller.
+	MaxBytes int64
+}
+
+// SplitFile splits a Parquet file into smaller files with the same schema.
+// outputPattern must contain a single integer verb such as "part-%03d.parquet",
+// which is replaced with the zero-based index of each file. It returns the
+// paths of the files written, in order. An empty input yields one empty file.
+func SplitFile(ctx context.Context, inputFilePath, outputPattern string, limit SplitLimit) ([]string, error) {
+	if inputFilePath == "" {
+		return nil, errors.New("input file path cannot be empty")
+	}
+	if err := validateOutputPattern(outputPattern); err != nil {
+		return nil, err
+	}
+	if (limit.MaxRows > 0) == (limit.MaxBytes > 0) {
+		return nil, errors.New("exactly one of max rows or max bytes must be greater than zero")
+	}
+	if ctx == nil {
+		return nil, errors.New("context cannot be nil")
+	}
+
+	reader, err := integrations.NewParquetReader(ctx, inputFilePath, &integrations.ParquetReadOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Parquet reader: %w", err)
+	}
+	defer reader.Close()
+
+	s := &splitter{schema: reader.Schema(), pattern: outputPattern, limit: limit}
+	defer s.abort()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read record: %w", err)
+		}
+		err = s.write(record)
+		record.Release()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.writer == nil && len(s.paths) == 0 {
+		if err := s.open(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.closeCurrent(); err != nil {
+		return nil, err
+	}
+	return s.paths, nil
+}
+
+func validateOutputPattern(pattern string) error {
+	if pattern == "" {
+		return errors.New("output pattern cannot be empty")
+	}
+	if a, b := fmt.Sprintf(pattern, 0), fmt.Sprintf(pattern, 1); a == b || strings.Contains(a, "%!") {
+		return fmt.Errorf("output pattern %q must contain exactly one integer verb such as %%d", pattern)
+	}
+	return nil
+}
+
+// splitter routes records into a sequence of Parquet writers, starting a new
+// file whenever the current one reaches the limit.
+type splitter struct {
+	schema  *arrow.Schema
+	pattern string
+	limit   SplitLimit
+
+	writer *integrations.ParquetWriter
+	paths  []string
+	// used is the number of rows or bytes written to the current file.
+	used int64
+}
+
+func (s *splitter) write(record arrow.Record) error {
+	rows := record.NumRows()
+	if rows == 0 {
+		return nil
+	}
+
+	// Cost of one row in units of the limit.
+	capacity, rowCost := s.limit.MaxRows, int64(1)
+	if s.limit.MaxBytes > 0 {
+		capacity, rowCost = s.limit.MaxBytes, max(util.TotalRecordSize(record)/rows, 1)
+	}
+
+	for offset := int64(0); offset < rows; {
+		if s.writer == nil || s.used+rowCost > capacity {
+			if s.writer != nil {
+				if err := s.closeCurrent(); err != nil {
+					return err
+				}
+			}
+			if err := s.open(); err != nil {
+				return err
+			}
+		}
+
+		// Always take at least one row so oversized rows still make progress.
+		n := min(rows-offset, max((capacity-s.used)/rowCost, 1))
+		slice := record.NewSlice(offset, offset+n)
+		err := s.writer.Write(slice)
+		slice.Release()
+		if err != nil {
+			return fmt.Errorf("failed to write to %s: %w", s.paths[len(s.paths)-1], err)
+		}
+		s.used += n * rowCost
+		offset += n
+	}
+	return nil
+}
+
+func (s *splitter) open() error {
+	path := fmt.Sprintf(s.pattern, len(s.paths))
+	writer, err := integrations.NewParquetWriter(path, s.schema, integrations.NewDefaultParquetWriterProperties())
+	if err != nil {
+		return fmt.Errorf("failed to create Parquet writer for %s: %w", path, err)
+	}
+	s.writer, s.used = writer, 0
+	s.paths = append(s.paths, path)
+	return nil
+}
+
+func (s *splitter) closeCurrent() error {
+	if s.writer == nil {
+		return nil
+	}
+	writer := s.writer
+	s.writer = nil
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", s.paths[len(s.paths)-1], err)
+	}
+	return nil
+}
+
+// abort closes a writer left open by an error.
+func (s *splitter) abort() {
+	if s.writer != nil {
+		s.writer.Close()
+	}
+}
@@ -0,0 +1,348 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package preview prints the first, last or all rows of a record stream as
+// an aligned table or as JSON lines, for inspecting data without converting
+// it.
+package preview
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// Format is how rows are printed.
+type Format string
+
+const (
+	// Table prints rows as a table with aligned columns.
+	Table Format = "table"
+	// JSON prints one JSON object per row, with keys in column order.
+	JSON Format = "json"
+)
+
+// DefaultMaxWidth is the width past which table cells are truncated.
+const DefaultMaxWidth = 40
+
+// Reader is a stream of records with a known schema.
+type Reader interface {
+	Read() (arrow.Record, error)
+	Schema() *arrow.Schema
+}
+
+// Options configures how rows are printed.
+type Options struct {
+	// Columns selects and orders the printed columns. All columns are
+	// printed when empty.
+	Columns []string
+	// Format defaults to Table.
+	Format Format
+	// MaxWidth truncates longer table cells. Zero means DefaultMaxWidth and
+	// a negative value disables truncation.
+	MaxWidth int
+	// NullValue is printed for nulls in tables. Defaults to "NULL".
+	NullValue string
+}
+
+// controlEscaper keeps multi-line values on one table row.
+var controlEscaper = strings.NewReplacer("\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+// Head prints the first n rows of reader to w.
+func Head(reader Reader, n int64, w io.Writer, opts Options) error {
+	p, err := newPrinter(reader.Schema(), w, opts)
+	if err != nil {
+		return err
+	}
+	var records []arrow.Record
+	defer func() { releaseAll(records) }()
+
+	for rows := int64(0); rows < n; {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			break
+		}
+		if rows+rec.NumRows() > n {
+			sliced := rec.NewSlice(0, n-rows)
+			rec.Release()
+			rec = sliced
+		}
+		rows += rec.NumRows()
+		records = append(records, rec)
+	}
+	return p.print(records)
+}
+
+// Tail prints the last n rows of reader to w. Only the records holding
+// those rows are kept in memory.
+func Tail(reader Reader, n int64, w io.Writer, opts Options) error {
+	p, err := newPrinter(reader.Schema(), w, opts)
+	if err != nil {
+		return err
+	}
+	var records []arrow.Record
+	defer func() { releaseAll(records) }()
+
+	var rows int64
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF || (err == nil && rec == nil) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		records = append(records, rec)
+		rows += rec.NumRows()
+		for len(records) > 0 && rows-records[0].NumRows() >= n {
+			rows -= records[0].NumRows()
+			records[0].Release()
+			records = records[1:]
+		}
+	}
+	if len(records) > 0 && rows > n {
+		first := records[0]
+		records[0] = first.NewSlice(rows-n, first.NumRows())
+		first.Release()
+	}
+	return p.print(records)
+}
+
+// Cat prints every row of reader to w as it is read. Table column widths
+// are taken from the first record; later cells are padded or truncated to
+// fit.
+func Cat(reader Reader, w io.Writer, opts Options) error {
+	p, err := newPrinter(reader.Schema(), w, opts)
+	if err != nil {
+		return err
+	}
+	printed := false
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF || (err == nil && rec == nil) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		err = p.print([]arrow.Record{rec})
+		rec.Release()
+		if err != nil {
+			return err
+		}
+		printed = true
+	}
+	if !printed {
+		return p.print(nil)
+	}
+	return nil
+}
+
+func releaseAll(records []arrow.Record) {
+	for _, rec := range records {
+		rec.Release()
+	}
+}
+
+// printer writes records in the chosen format, restricted to the selected
+// columns.
+type printer struct {
+	w       io.Writer
+	opts    Options
+	names   []string
+	indices []int
+	widths  []int // set once the table header is printed
+}
+
+func newPrinter(schema *arrow.Schema, w io.Writer, opts Options) (*printer, error) {
+	if opts.Format == "" {
+		opts.Format = Table
+	}
+	if opts.Format != Table && opts.Format != JSON {
+		return nil, fmt.Errorf("unknown preview format %q", opts.Format)
+	}
+	if opts.MaxWidth == 0 {
+		opts.MaxWidth = DefaultMaxWidth
+	}
+	if opts.NullValue == "" {
+		opts.NullValue = "NULL"
+	}
+
+	p := &printer{w: w, opts: opts}
+	if len(opts.Columns) == 0 {
+		for i, f := range schema.Fields() {
+			p.names = append(p.names, f.Name)
+			p.indices = append(p.indices, i)
+		}
+		return p, nil
+	}
+	for _, name := range opts.Columns {
+		indices := schema.FieldIndices(name)
+		if len(indices) == 0 {
+			return nil, fmt.Errorf("column %q not found", name)
+		}
+		p.names = append(p.names, name)
+		p.indices = append(p.indices, indices[0])
+	}
+	return p, nil
+}
+
+func (p *printer) print(records []arrow.Record) error {
+	bw := bufio.NewWriter(p.w)
+	var err error
+	if p.opts.Format == JSON {
+		err = p.printJSON(bw, records)
+	} else {
+		err = p.printTable(bw, records)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (p *printer) printJSON(w *bufio.Writer, records []arrow.Record) error {
+	keys := make([][]byte, len(p.names))
+	for i, name := range p.names {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+	for _, rec := range records {
+		for row := 0; row < int(rec.NumRows()); row++ {
+			w.WriteByte('{')
+			for i, col := range p.indices {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				value, err := json.Marshal(rec.Column(col).GetOneForMarshal(row))
+				if err != nil {
+					return fmt.Errorf("column %q: %w", p.names[i], err)
+				}
+				w.Write(keys[i])
+				w.WriteByte(':')
+				w.Write(value)
+			}
+			w.WriteString("}\n")
+		}
+	}
+	return nil
+}
+
+func (p *printer) printTable(w *bufio.Writer, records []arrow.Record) error {
+	var rows [][]string
+	for _, rec := range records {
+		for row := 0; row < int(rec.NumRows()); row++ {
+			cells := make([]string, len(p.indices))
+			for i, col := range p.indices {
+				cells[i] = p.cell(rec.Column(col), row)
+			}
+			rows = append(rows, cells)
+		}
+	}
+
+	if p.widths == nil {
+		p.widths = make([]int, len(p.names))
+		for i, name := range p.names {
+			p.widths[i] = p.fit(utf8.RuneCountInString(name))
+		}
+		for _, cells := range rows {
+			for i, cell := range cells {
+				p.widths[i] = max(p.widths[i], utf8.RuneCountInString(cell))
+			}
+		}
+		p.writeRow(w, p.names)
+		separators := make([]string, len(p.widths))
+		for i, width := range p.widths {
+			separators[i] = strings.Repeat("-", width)
+		}
+		p.writeRow(w, separators)
+	}
+	for _, cells := range rows {
+		p.writeRow(w, cells)
+	}
+	return nil
+}
+
+func (p *printer) writeRow(w *bufio.Writer, cells []string) {
+	for i, cell := range cells {
+		if i > 0 {
+			w.WriteString(" | ")
+		}
+		cell = truncate(cell, p.widths[i])
+		w.WriteString(cell)
+		if i < len(cells)-1 {
+			w.WriteString(strings.Repeat(" ", p.widths[i]-utf8.RuneCountInString(cell)))
+		}
+	}
+	w.WriteByte('\n')
+}
+
+func (p *printer) cell(arr arrow.Array, row int) string {
+	if arr.IsNull(row) {
+		return p.opts.NullValue
+	}
+	value := controlEscaper.Replace(arr.ValueStr(row))
+	if p.opts.MaxWidth > 0 {
+		value = truncate(value, p.opts.MaxWidth)
+	}
+	return value
+}
+
+// fit caps a column width at MaxWidth.
+func (p *printer) fit(width int) int {
+	if p.opts.MaxWidth > 0 {
+		return min(width, p.opts.MaxWidth)
+	}
+	return width
+}
+
+// truncate shortens s to width runes, marking the cut with an ellipsis.
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	if width <= 1 {
+		return string([]rune(s)[:width])
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
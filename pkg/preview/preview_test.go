@@ -0,0 +1,90 @@
+package preview
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/stretchr/testify/require"
+)
+
+var testSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+}, nil)
+
+type sliceReader struct {
+	records []arrow.Record
+}
+
+func (r *sliceReader) Read() (arrow.Record, error) {
+	if len(r.records) == 0 {
+		return nil, io.EOF
+	}
+	rec := r.records[0]
+	r.records = r.records[1:]
+	return rec, nil
+}
+
+func (r *sliceReader) Schema() *arrow.Schema { return testSchema }
+
+// batches returns records of the given sizes with ids counting from zero.
+func batches(sizes ...int) *sliceReader {
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), testSchema)
+	defer b.Release()
+	r := &sliceReader{}
+	id := int64(0)
+	for _, size := range sizes {
+		for i := 0; i < size; i++ {
+			b.Field(0).(*array.Int64Builder).Append(id)
+			if id == 1 {
+				b.Field(1).AppendNull()
+			} else {
+				b.Field(1).(*array.StringBuilder).Append("row " + string(rune('a'+id)))
+			}
+			id++
+		}
+		r.records = append(r.records, b.NewRecord())
+	}
+	return r
+}
+
+func TestHeadAcrossRecords(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, Head(batches(2, 2, 2), 3, &out, Options{}))
+	require.Equal(t, ""+
+		"id | name\n"+
+		"-- | -----\n"+
+		"0  | row a\n"+
+		"1  | NULL\n"+
+		"2  | row c\n", out.String())
+}
+
+func TestTailKeepsLastRows(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, Tail(batches(2, 3, 1), 3, &out, Options{Format: JSON, Columns: []string{"name", "id"}}))
+	require.Equal(t, ""+
+		`{"name":"row d","id":3}`+"\n"+
+		`{"name":"row e","id":4}`+"\n"+
+		`{"name":"row f","id":5}`+"\n", out.String())
+}
+
+func TestCatTruncatesWideCells(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, Cat(batches(1, 1), &out, Options{Columns: []string{"name"}, MaxWidth: 3}))
+	require.Equal(t, "na…\n---\nro…\nNU…\n", out.String())
+}
+
+func TestEmptySourcePrintsHeader(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, Cat(&sliceReader{}, &out, Options{}))
+	require.Equal(t, "id | name\n-- | ----\n", out.String())
+}
+
+func TestUnknownColumn(t *testing.T) {
+	err := Head(batches(1), 1, io.Discard, Options{Columns: []string{"missing"}})
+	require.ErrorContains(t, err, `column "missing" not found`)
+}
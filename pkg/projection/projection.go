@@ -0,0 +1,252 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package projection selects and renames the columns of records. Every
+// converter and pipeline task applies the same Transformer for its
+// --select and --rename options, and readers that can skip columns, such
+// as Parquet and BigQuery, are given the selected columns so that the
+// others are never read.
+package projection
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/arrowarc/arrowarc/pkg/geo"
+)
+
+// Options selects and renames columns.
+type Options struct {
+	// Select lists the columns to keep, in the order written. When empty,
+	// every column is kept.
+	Select []string
+	// Rename maps the names of columns to their new names.
+	Rename map[string]string
+}
+
+// IsZero reports whether opts keeps every column as it is.
+func (opts *Options) IsZero() bool {
+	return opts == nil || (len(opts.Select) == 0 && len(opts.Rename) == 0)
+}
+
+// Parse parses the comma-separated columns of a --select option and the
+// comma-separated old=new pairs of a --rename option. Either may be empty.
+func Parse(selected, rename string) (*Options, error) {
+	opts := &Options{}
+	for _, name := range strings.Split(selected, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			opts.Select = append(opts.Select, name)
+		}
+	}
+	for _, pair := range strings.Split(rename, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid rename %q: expected old=new", pair)
+		}
+		if opts.Rename == nil {
+			opts.Rename = make(map[string]string)
+		}
+		if _, dup := opts.Rename[from]; dup {
+			return nil, fmt.Errorf("column %q is renamed twice", from)
+		}
+		opts.Rename[from] = to
+	}
+	return opts, nil
+}
+
+// Transformer is a pipeline Transformer keeping the selected columns,
+// renamed. The plan for a source schema is built on the first record and
+// rebuilt if the source schema changes. A Transformer is not safe for
+// concurrent use.
+type Transformer struct {
+	opts Options
+
+	src     *arrow.Schema
+	dst     *arrow.Schema
+	indices []int
+}
+
+// New creates a Transformer. Selecting or renaming a column a record does
+// not have fails its transform, as does giving two columns the same name.
+func New(opts *Options) (*Transformer, error) {
+	t := &Transformer{}
+	if opts != nil {
+		t.opts = *opts
+	}
+	seen := make(map[string]bool, len(t.opts.Select))
+	for _, name := range t.opts.Select {
+		if name == "" {
+			return nil, fmt.Errorf("column name cannot be empty")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %q is selected twice", name)
+		}
+		seen[name] = true
+	}
+	for from, to := range t.opts.Rename {
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("column names cannot be empty")
+		}
+		if len(seen) > 0 && !seen[from] {
+			return nil, fmt.Errorf("column %q is renamed but not selected", from)
+		}
+	}
+	return t, nil
+}
+
+// Columns returns the source columns the Transformer keeps, for readers to
+// read only those, or nil if it keeps every column.
+func (t *Transformer) Columns() []string {
+	return t.opts.Select
+}
+
+// Schema returns the schema of the records Transform produces for records
+// with schema src.
+func (t *Transformer) Schema(src *arrow.Schema) (*arrow.Schema, error) {
+	dst, _, err := t.plan(src)
+	return dst, err
+}
+
+// Transform returns the selected columns of record, renamed.
+func (t *Transformer) Transform(record arrow.Record) (arrow.Record, error) {
+	if t.src == nil || !record.Schema().Equal(t.src) {
+		dst, indices, err := t.plan(record.Schema())
+		if err != nil {
+			return nil, err
+		}
+		t.src, t.dst, t.indices = record.Schema(), dst, indices
+	}
+	cols := make([]arrow.Array, len(t.indices))
+	for i, idx := range t.indices {
+		cols[i] = record.Column(idx)
+	}
+	return array.NewRecord(t.dst, cols, record.NumRows()), nil
+}
+
+// plan returns the schema of the projected records and the source index of
+// each of their columns.
+func (t *Transformer) plan(src *arrow.Schema) (*arrow.Schema, []int, error) {
+	var indices []int
+	if len(t.opts.Select) == 0 {
+		indices = make([]int, src.NumFields())
+		for i := range indices {
+			indices[i] = i
+		}
+	} else {
+		for _, name := range t.opts.Select {
+			i, err := fieldIndex(src, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			indices = append(indices, i)
+		}
+	}
+	for from := range t.opts.Rename {
+		if _, err := fieldIndex(src, from); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	fields := make([]arrow.Field, len(indices))
+	seen := make(map[string]bool, len(indices))
+	for i, idx := range indices {
+		f := src.Field(idx)
+		if to, ok := t.opts.Rename[f.Name]; ok {
+			f.Name = to
+		}
+		if seen[f.Name] {
+			return nil, nil, fmt.Errorf("more than one column would be named %q", f.Name)
+		}
+		seen[f.Name] = true
+		fields[i] = f
+	}
+	md, err := t.metadata(src, seen)
+	if err != nil {
+		return nil, nil, err
+	}
+	return arrow.NewSchema(fields, &md), indices, nil
+}
+
+// metadata returns the metadata of src with its GeoParquet metadata
+// following the geometry columns kept, renamed.
+func (t *Transformer) metadata(src *arrow.Schema, kept map[string]bool) (arrow.Metadata, error) {
+	md := src.Metadata()
+	meta, err := geo.FromSchema(src)
+	if err != nil || meta == nil {
+		return md, nil
+	}
+	columns := make(map[string]*geo.Column, len(meta.Columns))
+	for name, col := range meta.Columns {
+		if to, ok := t.opts.Rename[name]; ok {
+			name = to
+		}
+		if kept[name] {
+			columns[name] = col
+		}
+	}
+	if to, ok := t.opts.Rename[meta.PrimaryColumn]; ok {
+		meta.PrimaryColumn = to
+	}
+	meta.Columns = columns
+	keys, values := md.Keys(), md.Values()
+	i := md.FindKey(geo.MetadataKey)
+	if len(columns) == 0 {
+		keys = append(keys[:i:i], keys[i+1:]...)
+		values = append(values[:i:i], values[i+1:]...)
+		return arrow.NewMetadata(keys, values), nil
+	}
+	if columns[meta.PrimaryColumn] == nil {
+		meta.PrimaryColumn = meta.Names()[0]
+	}
+	data, err := meta.Marshal()
+	if err != nil {
+		return md, err
+	}
+	values = append(values[:0:0], values...)
+	values[i] = data
+	return arrow.NewMetadata(keys, values), nil
+}
+
+// fieldIndex returns the index of the column name of schema.
+func fieldIndex(schema *arrow.Schema, name string) (int, error) {
+	indices := schema.FieldIndices(name)
+	switch len(indices) {
+	case 0:
+		return 0, fmt.Errorf("column %q not found", name)
+	case 1:
+		return indices[0], nil
+	}
+	return 0, fmt.Errorf("column name %q is ambiguous", name)
+}
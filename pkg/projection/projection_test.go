@@ -0,0 +1,153 @@
+package projection
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pkg/geo"
+)
+
+func testRecord(t *testing.T, md *arrow.Metadata) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "geom", Type: arrow.BinaryTypes.Binary, Nullable: true},
+	}, md)
+	record, _, err := array.RecordFromJSON(memory.NewGoAllocator(), schema, strings.NewReader(
+		`[{"id": 1, "name": "a", "geom": null}, {"id": 2, "name": "b", "geom": null}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func names(schema *arrow.Schema) string {
+	var out []string
+	for _, f := range schema.Fields() {
+		out = append(out, f.Name)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestParse(t *testing.T) {
+	opts, err := Parse(" name, id ", "name=label,id = key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(opts.Select, ",") != "name,id" || opts.Rename["name"] != "label" || opts.Rename["id"] != "key" {
+		t.Fatalf("Parse() = %+v", opts)
+	}
+	if opts, err := Parse("", ""); err != nil || !opts.IsZero() {
+		t.Fatalf("Parse of nothing = %+v, %v", opts, err)
+	}
+	for _, rename := range []string{"name", "=x", "name=", "a=b,a=c"} {
+		if _, err := Parse("", rename); err == nil {
+			t.Errorf("Parse(%q): expected an error", rename)
+		}
+	}
+}
+
+func TestSelectAndRename(t *testing.T) {
+	p, err := New(&Options{Select: []string{"name", "id"}, Rename: map[string]string{"name": "label"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := testRecord(t, nil)
+	defer record.Release()
+	out, err := p.Transform(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	if got := names(out.Schema()); got != "label,id" {
+		t.Fatalf("columns = %s", got)
+	}
+	if out.Column(0).(*array.String).Value(1) != "b" || out.NumRows() != 2 {
+		t.Fatalf("unexpected record %v", out)
+	}
+	if got := strings.Join(p.Columns(), ","); got != "name,id" {
+		t.Fatalf("Columns() = %s", got)
+	}
+}
+
+func TestRenameOnly(t *testing.T) {
+	p, err := New(&Options{Rename: map[string]string{"id": "key"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := p.Schema(testRecord(t, nil).Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(schema); got != "key,name,geom" {
+		t.Fatalf("columns = %s", got)
+	}
+	if p.Columns() != nil {
+		t.Fatalf("Columns() = %v, want every column", p.Columns())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := New(&Options{Select: []string{"id", "id"}}); err == nil {
+		t.Error("expected an error selecting a column twice")
+	}
+	if _, err := New(&Options{Select: []string{"id"}, Rename: map[string]string{"name": "x"}}); err == nil {
+		t.Error("expected an error renaming an unselected column")
+	}
+	schema := testRecord(t, nil).Schema()
+	for _, opts := range []*Options{
+		{Select: []string{"missing"}},
+		{Rename: map[string]string{"missing": "x"}},
+		{Rename: map[string]string{"id": "name"}},
+	} {
+		p, err := New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Schema(schema); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
+
+func TestGeoMetadata(t *testing.T) {
+	meta := &geo.Metadata{Version: geo.Version, PrimaryColumn: "geom", Columns: map[string]*geo.Column{"geom": {Encoding: geo.EncodingWKB}}}
+	data, err := meta.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := arrow.NewMetadata([]string{geo.MetadataKey, "other"}, []string{data, "kept"})
+	schema := testRecord(t, &md).Schema()
+
+	p, err := New(&Options{Rename: map[string]string{"geom": "shape"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed, err := p.Schema(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := geo.FromSchema(renamed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PrimaryColumn != "shape" || got.Columns["shape"] == nil || len(geo.Columns(renamed)) != 1 {
+		t.Fatalf("geo metadata = %+v", got)
+	}
+
+	p, err = New(&Options{Select: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected, err := p.Schema(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selected.Metadata().FindKey(geo.MetadataKey) >= 0 || selected.Metadata().FindKey("other") < 0 {
+		t.Fatalf("metadata = %v", selected.Metadata())
+	}
+}
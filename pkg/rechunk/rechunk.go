@@ -0,0 +1,201 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package rechunk cuts records into batches of at most a given number of
+// rows. Batches are zero-copy slices sharing the input's buffers, so a huge
+// Parquet row group can feed a sink with a row limit, such as BigQuery
+// appends or Kafka messages, without being rebuilt.
+package rechunk
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+)
+
+// Slices returns record cut into consecutive slices of at most maxRows rows.
+// Each slice must be released by the caller; a record that already fits is
+// returned retained.
+func Slices(record arrow.Record, maxRows int64) []arrow.Record {
+	n := record.NumRows()
+	if maxRows <= 0 || n <= maxRows {
+		record.Retain()
+		return []arrow.Record{record}
+	}
+	slices := make([]arrow.Record, 0, (n+maxRows-1)/maxRows)
+	for i := int64(0); i < n; i += maxRows {
+		slices = append(slices, record.NewSlice(i, min(i+maxRows, n)))
+	}
+	return slices
+}
+
+// Reader returns the records of another reader cut into batches of at most
+// MaxRows rows.
+type Reader struct {
+	reader  interfaces.Reader
+	maxRows int64
+
+	current arrow.Record
+	offset  int64
+
+	// mu guards the fields below, which Read and Acknowledge share:
+	// emitted counts the records returned; ends holds, for each input
+	// record not yet acknowledged, the count at which its last slice was
+	// returned.
+	mu      sync.Mutex
+	emitted int64
+	ends    []int64
+	acked   int64
+}
+
+// NewReader returns a Reader cutting the records of reader into batches of
+// at most maxRows rows. It acknowledges an input record once all its slices
+// are acknowledged if reader is an interfaces.AckingReader.
+func NewReader(reader interfaces.Reader, maxRows int64) (*Reader, error) {
+	if maxRows <= 0 {
+		return nil, fmt.Errorf("rechunk: max rows must be positive, got %d", maxRows)
+	}
+	return &Reader{reader: reader, maxRows: maxRows}, nil
+}
+
+func (r *Reader) Read() (arrow.Record, error) {
+	for r.current == nil || r.offset >= r.current.NumRows() {
+		if r.current != nil {
+			r.current.Release()
+			r.current = nil
+		}
+		record, err := r.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			return nil, io.EOF
+		}
+		if record.NumRows() == 0 {
+			// Nothing to return: it is acknowledged with the records
+			// before it.
+			record.Release()
+			r.mu.Lock()
+			r.ends = append(r.ends, r.emitted)
+			r.mu.Unlock()
+			continue
+		}
+		r.current, r.offset = record, 0
+	}
+
+	end := min(r.offset+r.maxRows, r.current.NumRows())
+	var out arrow.Record
+	if r.offset == 0 && end == r.current.NumRows() {
+		r.current.Retain()
+		out = r.current
+	} else {
+		out = r.current.NewSlice(r.offset, end)
+	}
+	r.offset = end
+	r.mu.Lock()
+	r.emitted++
+	if end == r.current.NumRows() {
+		r.ends = append(r.ends, r.emitted)
+	}
+	r.mu.Unlock()
+	return out, nil
+}
+
+// NumRows returns the number of rows of the underlying reader, if known.
+func (r *Reader) NumRows() int64 {
+	if sized, ok := r.reader.(interfaces.SizedReader); ok {
+		return sized.NumRows()
+	}
+	return 0
+}
+
+// Acknowledge acknowledges to the underlying reader the input records whose
+// slices are all among the first records returned.
+func (r *Reader) Acknowledge(records int64, final bool) error {
+	acker, ok := r.reader.(interfaces.AckingReader)
+	if !ok {
+		return nil
+	}
+	r.mu.Lock()
+	done := 0
+	for done < len(r.ends) && r.ends[done] <= records {
+		done++
+	}
+	r.ends = r.ends[done:]
+	r.acked += int64(done)
+	acked := r.acked
+	r.mu.Unlock()
+	return acker.Acknowledge(acked, final)
+}
+
+func (r *Reader) Close() error {
+	if r.current != nil {
+		r.current.Release()
+		r.current = nil
+	}
+	return r.reader.Close()
+}
+
+// Writer passes the records it is given to another writer cut into batches
+// of at most MaxRows rows.
+type Writer struct {
+	writer  interfaces.Writer
+	maxRows int64
+}
+
+// NewWriter returns a Writer cutting records into batches of at most
+// maxRows rows before writing them to writer.
+func NewWriter(writer interfaces.Writer, maxRows int64) (*Writer, error) {
+	if maxRows <= 0 {
+		return nil, fmt.Errorf("rechunk: max rows must be positive, got %d", maxRows)
+	}
+	return &Writer{writer: writer, maxRows: maxRows}, nil
+}
+
+func (w *Writer) Write(record arrow.Record) error {
+	slices := Slices(record, w.maxRows)
+	defer func() {
+		for _, s := range slices {
+			s.Release()
+		}
+	}()
+	for _, s := range slices {
+		if err := w.writer.Write(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) Close() error {
+	return w.writer.Close()
+}
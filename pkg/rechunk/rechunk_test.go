@@ -0,0 +1,205 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package rechunk
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pipeline"
+)
+
+func makeRecord(t *testing.T, mem memory.Allocator, from, n int64) arrow.Record {
+	t.Helper()
+	b := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil))
+	defer b.Release()
+	for i := from; i < from+n; i++ {
+		b.Field(0).(*array.Int64Builder).Append(i)
+	}
+	return b.NewRecord()
+}
+
+func ids(rec arrow.Record) []int64 {
+	return append([]int64(nil), rec.Column(0).(*array.Int64).Int64Values()...)
+}
+
+type sliceReader struct {
+	records []arrow.Record
+	acks    []int64
+}
+
+func (r *sliceReader) Read() (arrow.Record, error) {
+	if len(r.records) == 0 {
+		return nil, io.EOF
+	}
+	rec := r.records[0]
+	r.records = r.records[1:]
+	return rec, nil
+}
+
+func (r *sliceReader) Acknowledge(records int64, final bool) error {
+	r.acks = append(r.acks, records)
+	return nil
+}
+
+func (r *sliceReader) Close() error { return nil }
+
+type collectWriter struct {
+	rows [][]int64
+}
+
+func (w *collectWriter) Write(rec arrow.Record) error {
+	w.rows = append(w.rows, ids(rec))
+	return nil
+}
+
+func (w *collectWriter) Close() error { return nil }
+
+func TestSlicesShareBuffers(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	rec := makeRecord(t, mem, 0, 10)
+	before := mem.CurrentAlloc()
+	slices := Slices(rec, 4)
+	if mem.CurrentAlloc() != before {
+		t.Fatalf("slicing allocated %d bytes", mem.CurrentAlloc()-before)
+	}
+	rec.Release()
+
+	var got [][]int64
+	for _, s := range slices {
+		got = append(got, ids(s))
+		s.Release()
+	}
+	want := [][]int64{{0, 1, 2, 3}, {4, 5, 6, 7}, {8, 9}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestReaderRechunksAndAcknowledgesInputs(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	inner := &sliceReader{records: []arrow.Record{
+		makeRecord(t, mem, 0, 10),
+		makeRecord(t, mem, 10, 0),
+		makeRecord(t, mem, 10, 3),
+	}}
+	r, err := NewReader(inner, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var got [][]int64
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, ids(rec))
+		rec.Release()
+
+		if err := r.Acknowledge(int64(len(got)), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := [][]int64{{0, 1, 2, 3}, {4, 5, 6, 7}, {8, 9}, {10, 11, 12}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	// The first input is acknowledged with its last slice; the empty one,
+	// read with the last, goes with it.
+	if want := []int64{0, 0, 1, 3}; !reflect.DeepEqual(inner.acks, want) {
+		t.Fatalf("acknowledged %v, want %v", inner.acks, want)
+	}
+}
+
+func TestReaderAcknowledgesInPipeline(t *testing.T) {
+	// The pipeline reads and acknowledges on separate goroutines, which
+	// go test -race checks.
+	inner := &sliceReader{}
+	for i := int64(0); i < 200; i++ {
+		inner.records = append(inner.records, makeRecord(t, memory.NewGoAllocator(), i*10, 10))
+	}
+	r, err := NewReader(inner, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &collectWriter{}
+	if _, err := pipeline.NewDataPipeline(r, w).WithMonitor(nil).Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.rows) != 200*4 {
+		t.Fatalf("wrote %d records, want %d", len(w.rows), 200*4)
+	}
+	for i := 1; i < len(inner.acks); i++ {
+		if inner.acks[i] < inner.acks[i-1] {
+			t.Fatalf("acknowledgements went back: %v", inner.acks)
+		}
+	}
+	if last := inner.acks[len(inner.acks)-1]; last != 200 {
+		t.Fatalf("last acknowledged %d inputs, want 200", last)
+	}
+}
+
+func TestWriterRechunks(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	inner := &collectWriter{}
+	w, err := NewWriter(inner, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := makeRecord(t, mem, 0, 5)
+	defer rec.Release()
+	if err := w.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int64{{0, 1}, {2, 3}, {4}}
+	if !reflect.DeepEqual(inner.rows, want) {
+		t.Fatalf("got %v, want %v", inner.rows, want)
+	}
+
+	if _, err := NewWriter(inner, 0); err == nil {
+		t.Fatal("expected an error for zero max rows")
+	}
+}
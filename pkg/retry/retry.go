@@ -0,0 +1,241 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package retry runs operations under a retry policy: exponential backoff
+// with jitter, bounded by a number of attempts and by the time spent, and
+// a classifier deciding which errors are worth another attempt.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Policy describes how an operation is retried. Zero fields take the
+// values of Default, so a policy only needs to set what it changes.
+type Policy struct {
+	// MaxAttempts bounds the number of attempts, the first included. One
+	// disables retries; a negative value leaves MaxElapsed as the only bound.
+	MaxAttempts int
+	// InitialInterval is the delay before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between attempts.
+	MaxInterval time.Duration
+	// Multiplier grows the delay after each retry.
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction of it, so that
+	// clients failing together do not retry together. Negative disables it.
+	Jitter float64
+	// MaxElapsed bounds the time from the first attempt after which no
+	// retry is started. Negative removes the bound.
+	MaxElapsed time.Duration
+	// Retryable reports whether an error is worth retrying. Defaults to
+	// IsRetryable.
+	Retryable func(error) bool
+}
+
+// Default returns the default policy: five attempts, waiting 500ms, then
+// doubling up to 30s, with 20% jitter, for at most two minutes.
+func Default() *Policy {
+	return &Policy{
+		MaxAttempts:     5,
+		InitialInterval: 500 * time.Millisecond,
+		MaxInterval:     30 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxElapsed:      2 * time.Minute,
+		Retryable:       IsRetryable,
+	}
+}
+
+// Never returns a policy making a single attempt.
+func Never() *Policy {
+	return &Policy{MaxAttempts: 1}
+}
+
+// Effective returns p with its zero fields filled from Default, as Do
+// applies it. A nil p stands for Default.
+func (p *Policy) Effective() Policy {
+	d := *Default()
+	if p == nil {
+		return d
+	}
+	out := *p
+	if out.MaxAttempts == 0 {
+		out.MaxAttempts = d.MaxAttempts
+	}
+	if out.InitialInterval <= 0 {
+		out.InitialInterval = d.InitialInterval
+	}
+	if out.MaxInterval <= 0 {
+		out.MaxInterval = d.MaxInterval
+	}
+	if out.Multiplier < 1 {
+		out.Multiplier = d.Multiplier
+	}
+	if out.Jitter == 0 {
+		out.Jitter = d.Jitter
+	}
+	out.Jitter = min(max(out.Jitter, 0), 1)
+	if out.MaxElapsed == 0 {
+		out.MaxElapsed = d.MaxElapsed
+	}
+	if out.Retryable == nil {
+		out.Retryable = d.Retryable
+	}
+	return out
+}
+
+// OrRetryable returns a copy of p whose Retryable is fn if p does not set
+// one, letting an integration supply its own classifier under a policy
+// chosen by the caller. A nil p stands for Default.
+func (p *Policy) OrRetryable(fn func(error) bool) *Policy {
+	var out Policy
+	if p == nil {
+		out = *Default()
+		out.Retryable = nil
+	} else {
+		out = *p
+	}
+	if out.Retryable == nil {
+		out.Retryable = fn
+	}
+	return &out
+}
+
+// Delay returns the wait before retry n, counting from one, without jitter.
+func (p *Policy) Delay(n int) time.Duration {
+	c := p.Effective()
+	return c.delay(n)
+}
+
+func (p *Policy) delay(n int) time.Duration {
+	d := float64(p.InitialInterval) * math.Pow(p.Multiplier, float64(n-1))
+	if d > float64(p.MaxInterval) {
+		return p.MaxInterval
+	}
+	return time.Duration(d)
+}
+
+func (p *Policy) jittered(d time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return d
+	}
+	spread := float64(d) * p.Jitter
+	return time.Duration(float64(d) - spread + rand.Float64()*2*spread)
+}
+
+// Do calls op until it succeeds, returns an error that is not retryable,
+// or the policy gives up, and returns op's last error. Do stops waiting
+// when ctx is done. A nil policy stands for Default.
+func (p *Policy) Do(ctx context.Context, op func(ctx context.Context) error) error {
+	c := p.Effective()
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := op(ctx)
+		if err == nil {
+			return nil
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if !c.Retryable(err) {
+			return err
+		}
+		if c.MaxAttempts > 0 && attempt >= c.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		wait := c.jittered(c.delay(attempt))
+		if c.MaxElapsed > 0 && time.Since(start)+wait > c.MaxElapsed {
+			return fmt.Errorf("giving up after %d attempts in %s: %w", attempt, time.Since(start).Round(time.Millisecond), err)
+		}
+
+		logging.FromContext(ctx).Warn("retrying", "attempt", attempt, "delay", wait, "error", err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w; last error: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, whatever the classifier says.
+// Do returns err itself.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// IsRetryable is the default classifier. It retries connection resets and
+// refusals, broken pipes, unexpected EOFs, network timeouts, and gRPC
+// errors with the codes Unavailable, ResourceExhausted, Aborted and
+// DeadlineExceeded. Cancellation is never retried.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errTransient = errors.New("transient")
+
+func fast(attempts int) *Policy {
+	return &Policy{
+		MaxAttempts:     attempts,
+		InitialInterval: time.Millisecond,
+		Jitter:          -1,
+		Retryable:       func(err error) bool { return errors.Is(err, errTransient) },
+	}
+}
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	calls := 0
+	err := fast(5).Do(context.Background(), func(context.Context) error {
+		if calls++; calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, want 3", calls)
+	}
+}
+
+func TestDoGivesUp(t *testing.T) {
+	calls := 0
+	err := fast(3).Do(context.Background(), func(context.Context) error {
+		calls++
+		return errTransient
+	})
+	if !errors.Is(err, errTransient) {
+		t.Fatalf("got %v, want the last error", err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, want 3", calls)
+	}
+}
+
+func TestDoStopsOnPermanentErrors(t *testing.T) {
+	other := errors.New("bad request")
+	for _, tc := range []struct {
+		name string
+		err  error
+	}{
+		{"unclassified", other},
+		{"permanent", Permanent(errTransient)},
+	} {
+		calls := 0
+		err := fast(5).Do(context.Background(), func(context.Context) error {
+			calls++
+			return tc.err
+		})
+		if calls != 1 {
+			t.Errorf("%s: got %d calls, want 1", tc.name, calls)
+		}
+		var perm *permanentError
+		if err == nil || errors.As(err, &perm) {
+			t.Errorf("%s: got %v", tc.name, err)
+		}
+	}
+}
+
+func TestDoMaxElapsed(t *testing.T) {
+	p := fast(-1)
+	p.InitialInterval = 20 * time.Millisecond
+	p.Multiplier = 1
+	p.MaxElapsed = 50 * time.Millisecond
+	calls := 0
+	start := time.Now()
+	err := p.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errTransient
+	})
+	if !errors.Is(err, errTransient) {
+		t.Fatal(err)
+	}
+	// Three calls fit in 50ms, give or take timer latency.
+	if elapsed := time.Since(start); elapsed > time.Second || calls < 2 || calls > 3 {
+		t.Errorf("got %d calls in %s, want 3", calls, elapsed)
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	p := fast(-1)
+	p.InitialInterval = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := p.Do(ctx, func(context.Context) error { return errTransient })
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errTransient) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	p := &Policy{InitialInterval: time.Second, MaxInterval: 5 * time.Second, Multiplier: 2}
+	for n, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := p.Delay(n + 1); got != want {
+			t.Errorf("Delay(%d) = %s, want %s", n+1, got, want)
+		}
+	}
+	c := Default().Effective()
+	for i := 0; i < 100; i++ {
+		if d := c.jittered(time.Second); d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("jittered delay %s outside 20%%", d)
+		}
+	}
+}
+
+func TestOrRetryable(t *testing.T) {
+	fn := func(error) bool { return true }
+	if p := (*Policy)(nil).OrRetryable(fn); p.MaxAttempts != Default().MaxAttempts || !p.Retryable(errors.New("x")) {
+		t.Error("a nil policy should become Default with the classifier")
+	}
+	own := fast(2)
+	if p := own.OrRetryable(fn); p.Retryable(errors.New("x")) {
+		t.Error("a policy's own classifier should be kept")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("invalid"), false},
+		{context.Canceled, false},
+		{io.ErrUnexpectedEOF, true},
+		{fmt.Errorf("write: %w", syscall.ECONNRESET), true},
+		{status.Error(codes.Unavailable, "down"), true},
+		{fmt.Errorf("append: %w", status.Error(codes.ResourceExhausted, "quota")), true},
+		{status.Error(codes.InvalidArgument, "bad"), false},
+	} {
+		if got := IsRetryable(tc.err); got != tc.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
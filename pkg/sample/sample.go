@@ -0,0 +1,180 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package sample passes a random subset of the rows of a reader on, to build
+// small, representative extracts of huge sources.
+package sample
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+)
+
+// SamplingReader returns a random sample of the rows of another reader:
+// each row is kept with a given probability, independently of the others.
+// Records left without rows are skipped.
+type SamplingReader struct {
+	reader   interfaces.Reader
+	fraction float64
+	rng      *rand.Rand
+	mask     []bool
+
+	// mu guards the fields below, which Read and Acknowledge share:
+	// consumed counts the input records read; ends holds, for each record
+	// returned and not yet acknowledged, the count at which it was read.
+	mu          sync.Mutex
+	consumed    int64
+	ends        []int64
+	returned    int64
+	ackedInputs int64
+}
+
+// NewSamplingReader returns a SamplingReader keeping each row of r with
+// probability fraction, which must be in (0, 1]. The same seed draws the
+// same sample of the same input.
+func NewSamplingReader(r interfaces.Reader, fraction float64, seed int64) (*SamplingReader, error) {
+	if !(fraction > 0 && fraction <= 1) {
+		return nil, fmt.Errorf("sample: fraction must be in (0, 1], got %v", fraction)
+	}
+	return &SamplingReader{
+		reader:   r,
+		fraction: fraction,
+		rng:      rand.New(rand.NewPCG(uint64(seed), uint64(seed))),
+	}, nil
+}
+
+func (r *SamplingReader) Read() (arrow.Record, error) {
+	for {
+		record, err := r.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			return nil, io.EOF
+		}
+		r.mu.Lock()
+		r.consumed++
+		r.mu.Unlock()
+
+		sampled, err := r.sample(record)
+		record.Release()
+		if err != nil {
+			return nil, fmt.Errorf("sample: %w", err)
+		}
+		if sampled == nil {
+			continue
+		}
+		r.mu.Lock()
+		r.ends = append(r.ends, r.consumed)
+		r.returned++
+		r.mu.Unlock()
+		return sampled, nil
+	}
+}
+
+// sample draws the rows of record to keep and returns them in a new record,
+// or nil if there are none.
+func (r *SamplingReader) sample(record arrow.Record) (arrow.Record, error) {
+	n := int(record.NumRows())
+	r.mask = slices.Grow(r.mask[:0], n)[:n]
+	kept := 0
+	for i := range r.mask {
+		r.mask[i] = r.rng.Float64() < r.fraction
+		if r.mask[i] {
+			kept++
+		}
+	}
+	switch kept {
+	case 0:
+		return nil, nil
+	case n:
+		record.Retain()
+		return record, nil
+	}
+	return filter.Select(context.Background(), record, r.mask)
+}
+
+// NumRows estimates the number of rows sampled from the size of the
+// underlying reader, if known.
+func (r *SamplingReader) NumRows() int64 {
+	if sized, ok := r.reader.(interfaces.SizedReader); ok {
+		return int64(float64(sized.NumRows()) * r.fraction)
+	}
+	return 0
+}
+
+// Schema returns the schema of the underlying reader, if it tells.
+func (r *SamplingReader) Schema() *arrow.Schema {
+	if reader, ok := r.reader.(interface{ Schema() *arrow.Schema }); ok {
+		return reader.Schema()
+	}
+	return nil
+}
+
+// Position returns the position of the underlying reader, if it tells.
+func (r *SamplingReader) Position() string {
+	if positioned, ok := r.reader.(interfaces.PositionedReader); ok {
+		return positioned.Position()
+	}
+	return ""
+}
+
+// Acknowledge acknowledges to the underlying reader the input records read
+// up to the last of the first records returned, along with those whose rows
+// were all left out. Once final, every record read is acknowledged.
+func (r *SamplingReader) Acknowledge(records int64, final bool) error {
+	acker, ok := r.reader.(interfaces.AckingReader)
+	if !ok {
+		return nil
+	}
+	r.mu.Lock()
+	inputs := r.ackedInputs
+	if n := records - (r.returned - int64(len(r.ends))); n > 0 && n <= int64(len(r.ends)) {
+		inputs = r.ends[n-1]
+		r.ends = r.ends[n:]
+	}
+	if final {
+		inputs = r.consumed
+	}
+	r.ackedInputs = inputs
+	r.mu.Unlock()
+	return acker.Acknowledge(inputs, final)
+}
+
+func (r *SamplingReader) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,179 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package sample
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// sliceReader returns records of rows consecutive ids and records the
+// acknowledgements it receives.
+type sliceReader struct {
+	mem     *memory.CheckedAllocator
+	n, rows int64
+	next    int64
+	acks    []int64
+}
+
+func (r *sliceReader) Read() (arrow.Record, error) {
+	if r.next >= r.n*r.rows {
+		return nil, io.EOF
+	}
+	b := array.NewRecordBuilder(r.mem, arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil))
+	defer b.Release()
+	for i := int64(0); i < r.rows; i++ {
+		b.Field(0).(*array.Int64Builder).Append(r.next)
+		r.next++
+	}
+	return b.NewRecord(), nil
+}
+
+func (r *sliceReader) Acknowledge(records int64, final bool) error {
+	r.acks = append(r.acks, records)
+	return nil
+}
+
+func (r *sliceReader) NumRows() int64 { return r.n * r.rows }
+
+func (r *sliceReader) Close() error { return nil }
+
+func readIDs(t *testing.T, r *SamplingReader) []int64 {
+	t.Helper()
+	var ids []int64
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return ids
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, rec.Column(0).(*array.Int64).Int64Values()...)
+		rec.Release()
+	}
+}
+
+func TestSamplingReaderKeepsAFraction(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	r, err := NewSamplingReader(&sliceReader{mem: mem, n: 100, rows: 1000}, 0.01, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.NumRows(); got != 1000 {
+		t.Errorf("NumRows() = %d, want 1000", got)
+	}
+	ids := readIDs(t, r)
+	if len(ids) < 800 || len(ids) > 1200 {
+		t.Errorf("sampled %d rows of 100000 at 1%%", len(ids))
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("ids out of order: %d after %d", ids[i], ids[i-1])
+		}
+	}
+}
+
+func TestSamplingReaderSeed(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	sample := func(seed int64) []int64 {
+		r, err := NewSamplingReader(&sliceReader{mem: mem, n: 10, rows: 100}, 0.1, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return readIDs(t, r)
+	}
+	first, again, other := sample(1), sample(1), sample(2)
+	if !reflect.DeepEqual(first, again) {
+		t.Errorf("the same seed drew %v then %v", first, again)
+	}
+	if reflect.DeepEqual(first, other) {
+		t.Errorf("seeds 1 and 2 drew the same sample %v", first)
+	}
+}
+
+func TestSamplingReaderFractionOne(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	r, err := NewSamplingReader(&sliceReader{mem: mem, n: 3, rows: 10}, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := readIDs(t, r); len(ids) != 30 {
+		t.Errorf("read %d rows, want all 30", len(ids))
+	}
+}
+
+func TestNewSamplingReaderRejectsFraction(t *testing.T) {
+	for _, fraction := range []float64{0, -0.5, 1.5} {
+		if _, err := NewSamplingReader(&sliceReader{}, fraction, 0); err == nil {
+			t.Errorf("fraction %v: expected an error", fraction)
+		}
+	}
+}
+
+func TestSamplingReaderAcknowledgesSkippedRecords(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	// One row per record at 50%: about half of the records are skipped.
+	src := &sliceReader{mem: mem, n: 40, rows: 1}
+	r, err := NewSamplingReader(src, 0.5, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := first.Column(0).(*array.Int64).Value(0)
+	first.Release()
+	if err := r.Acknowledge(1, false); err != nil {
+		t.Fatal(err)
+	}
+	// The first record returned is input id, so id+1 records are done.
+	if want := []int64{id + 1}; !reflect.DeepEqual(src.acks, want) {
+		t.Errorf("acks = %v, want %v", src.acks, want)
+	}
+
+	returned := int64(1) + int64(len(readIDs(t, r)))
+	if err := r.Acknowledge(returned, true); err != nil {
+		t.Fatal(err)
+	}
+	if last := src.acks[len(src.acks)-1]; last != 40 {
+		t.Errorf("final ack = %d, want all 40 records", last)
+	}
+}
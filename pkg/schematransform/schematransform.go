@@ -0,0 +1,297 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package schematransform reconciles records with a destination schema so
+// that trivial mismatches between a source and a sink do not fail a
+// pipeline. Missing nullable columns are filled with nulls, extra columns are
+// dropped, fields are reordered, and compatible types are cast.
+package schematransform
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pkg/cast"
+)
+
+// Options controls how values are converted.
+type Options struct {
+	// TimestampLayout is the Go time layout used to parse strings into
+	// timestamps. Defaults to time.RFC3339Nano.
+	TimestampLayout string
+	// DateLayout is the Go time layout used to parse strings into dates.
+	// Defaults to time.DateOnly.
+	DateLayout string
+}
+
+// Transformer reshapes records into a destination schema. The plan for a
+// source schema is built on the first record and rebuilt if the source
+// schema changes. A Transformer is not safe for concurrent use.
+type Transformer struct {
+	dst  *arrow.Schema
+	opts Options
+	mem  memory.Allocator
+
+	src  *arrow.Schema
+	plan []fieldPlan
+}
+
+// fieldPlan describes how to produce one destination column.
+type fieldPlan struct {
+	src     int // index in the source schema, -1 if the column is missing
+	convert func(arrow.Array) (arrow.Array, error)
+}
+
+// New creates a Transformer producing records with schema dst.
+func New(dst *arrow.Schema, opts *Options) (*Transformer, error) {
+	if dst == nil {
+		return nil, fmt.Errorf("destination schema cannot be nil")
+	}
+	t := &Transformer{dst: dst, mem: memory.DefaultAllocator}
+	if opts != nil {
+		t.opts = *opts
+	}
+	if t.opts.TimestampLayout == "" {
+		t.opts.TimestampLayout = time.RFC3339Nano
+	}
+	if t.opts.DateLayout == "" {
+		t.opts.DateLayout = time.DateOnly
+	}
+	return t, nil
+}
+
+// Schema returns the destination schema.
+func (t *Transformer) Schema() *arrow.Schema {
+	return t.dst
+}
+
+// Check reports whether records with schema src can be reconciled with the
+// destination schema, so that mismatches can be caught before any data
+// is read.
+func (t *Transformer) Check(src *arrow.Schema) error {
+	_, err := t.buildPlan(src)
+	return err
+}
+
+// Transform returns record reshaped to the destination schema.
+func (t *Transformer) Transform(record arrow.Record) (arrow.Record, error) {
+	if t.src == nil || !record.Schema().Equal(t.src) {
+		plan, err := t.buildPlan(record.Schema())
+		if err != nil {
+			return nil, err
+		}
+		t.src, t.plan = record.Schema(), plan
+	}
+
+	n := int(record.NumRows())
+	cols := make([]arrow.Array, len(t.plan))
+	defer func() {
+		for _, col := range cols {
+			if col != nil {
+				col.Release()
+			}
+		}
+	}()
+
+	for i, p := range t.plan {
+		field := t.dst.Field(i)
+		switch {
+		case p.src < 0:
+			cols[i] = array.MakeArrayOfNull(t.mem, field.Type, n)
+			continue
+		case p.convert == nil:
+			cols[i] = record.Column(p.src)
+			cols[i].Retain()
+		default:
+			col, err := p.convert(record.Column(p.src))
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", field.Name, err)
+			}
+			cols[i] = col
+		}
+		if !field.Nullable && cols[i].NullN() > 0 {
+			return nil, fmt.Errorf("column %q is not nullable but has %d null values", field.Name, cols[i].NullN())
+		}
+	}
+
+	return array.NewRecord(t.dst, cols, int64(n)), nil
+}
+
+func (t *Transformer) buildPlan(src *arrow.Schema) ([]fieldPlan, error) {
+	plan := make([]fieldPlan, t.dst.NumFields())
+	for i, field := range t.dst.Fields() {
+		indices := src.FieldIndices(field.Name)
+		if len(indices) == 0 {
+			if !field.Nullable {
+				return nil, fmt.Errorf("column %q is required but missing from the source", field.Name)
+			}
+			plan[i] = fieldPlan{src: -1}
+			continue
+		}
+
+		from := src.Field(indices[0]).Type
+		plan[i] = fieldPlan{src: indices[0]}
+		if arrow.TypeEqual(from, field.Type) {
+			continue
+		}
+		convert, err := t.converter(from, field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", field.Name, err)
+		}
+		plan[i].convert = convert
+	}
+	return plan, nil
+}
+
+// converter returns a function converting arrays of type from to type to,
+// or an error if the conversion could lose information.
+func (t *Transformer) converter(from, to arrow.DataType) (func(arrow.Array) (arrow.Array, error), error) {
+	if dict, ok := from.(*arrow.DictionaryType); ok {
+		return t.dictionaryConverter(dict, to)
+	}
+
+	if isString(from) {
+		switch to := to.(type) {
+		case *arrow.TimestampType:
+			return func(arr arrow.Array) (arrow.Array, error) {
+				return cast.StringToTimestamp(t.mem, arr, to, t.opts.TimestampLayout)
+			}, nil
+		case *arrow.Date32Type:
+			return func(arr arrow.Array) (arrow.Array, error) {
+				return cast.StringToDate32(t.mem, arr, t.opts.DateLayout)
+			}, nil
+		}
+	}
+	if _, ok := intBits(from); ok && to.ID() == arrow.STRING {
+		return func(arr arrow.Array) (arrow.Array, error) {
+			return cast.IntToDecimalString(t.mem, arr, 0)
+		}, nil
+	}
+	if _, ok := intBits(to); ok && from.ID() == arrow.BOOL {
+		return func(arr arrow.Array) (arrow.Array, error) {
+			return cast.BoolToInt(t.mem, arr, to)
+		}, nil
+	}
+
+	if !isWidening(from, to) || !compute.CanCast(from, to) {
+		return nil, fmt.Errorf("cannot cast %s to %s", from, to)
+	}
+	return func(arr arrow.Array) (arrow.Array, error) {
+		return compute.CastArray(context.Background(), arr, compute.SafeCastOptions(to))
+	}, nil
+}
+
+// dictionaryConverter decodes dictionary arrays, then converts the decoded
+// values if their type differs from to.
+func (t *Transformer) dictionaryConverter(from *arrow.DictionaryType, to arrow.DataType) (func(arrow.Array) (arrow.Array, error), error) {
+	decode := func(arr arrow.Array) (arrow.Array, error) {
+		return cast.DecodeDictionary(t.mem, arr)
+	}
+	if arrow.TypeEqual(from.ValueType, to) {
+		return decode, nil
+	}
+	convert, err := t.converter(from.ValueType, to)
+	if err != nil {
+		return nil, fmt.Errorf("cannot cast %s to %s", from, to)
+	}
+	return func(arr arrow.Array) (arrow.Array, error) {
+		values, err := decode(arr)
+		if err != nil {
+			return nil, err
+		}
+		defer values.Release()
+		return convert(values)
+	}, nil
+}
+
+func isString(dt arrow.DataType) bool {
+	return dt.ID() == arrow.STRING || dt.ID() == arrow.LARGE_STRING
+}
+
+// isWidening reports whether every value of type from can be represented
+// in type to.
+func isWidening(from, to arrow.DataType) bool {
+	if fb, ok := intBits(from); ok {
+		if tb, ok := intBits(to); ok {
+			switch {
+			case isSigned(from) == isSigned(to):
+				return tb > fb
+			case !isSigned(from):
+				// Unsigned fits in a strictly wider signed integer.
+				return tb > fb
+			}
+			return false
+		}
+		return (to.ID() == arrow.FLOAT64 && fb <= 32) || (to.ID() == arrow.FLOAT32 && fb <= 16)
+	}
+
+	switch from.ID() {
+	case arrow.FLOAT16:
+		return to.ID() == arrow.FLOAT32 || to.ID() == arrow.FLOAT64
+	case arrow.FLOAT32:
+		return to.ID() == arrow.FLOAT64
+	case arrow.STRING, arrow.LARGE_STRING:
+		return isString(to)
+	case arrow.DATE32:
+		return to.ID() == arrow.TIMESTAMP || to.ID() == arrow.DATE64
+	case arrow.TIMESTAMP:
+		if to, ok := to.(*arrow.TimestampType); ok {
+			from := from.(*arrow.TimestampType)
+			return to.Unit >= from.Unit && to.TimeZone == from.TimeZone
+		}
+	}
+	return false
+}
+
+func intBits(dt arrow.DataType) (int, bool) {
+	switch dt.ID() {
+	case arrow.INT8, arrow.UINT8:
+		return 8, true
+	case arrow.INT16, arrow.UINT16:
+		return 16, true
+	case arrow.INT32, arrow.UINT32:
+		return 32, true
+	case arrow.INT64, arrow.UINT64:
+		return 64, true
+	}
+	return 0, false
+}
+
+func isSigned(dt arrow.DataType) bool {
+	switch dt.ID() {
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package schematransform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func sourceRecord(t *testing.T) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "extra", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "created", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+	}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*array.StringBuilder).AppendValues([]string{"a", "b"}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"2024-01-02T03:04:05Z", ""}, []bool{true, false})
+	b.Field(2).(*array.Int32Builder).AppendValues([]int32{1, 2}, nil)
+	return b.NewRecord()
+}
+
+func TestTransform(t *testing.T) {
+	dst := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "created", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}, Nullable: true},
+		{Name: "missing", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	}, nil)
+	tr, err := New(dst, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := sourceRecord(t)
+	defer rec.Release()
+	out, err := tr.Transform(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+
+	if !out.Schema().Equal(dst) {
+		t.Fatalf("schema = %s, want %s", out.Schema(), dst)
+	}
+	if got := out.Column(0).(*array.Int64).Int64Values(); got[0] != 1 || got[1] != 2 {
+		t.Errorf("id = %v, want [1 2]", got)
+	}
+	created := out.Column(1).(*array.Timestamp)
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if got := created.Value(0).ToTime(arrow.Microsecond); !got.Equal(want) {
+		t.Errorf("created[0] = %v, want %v", got, want)
+	}
+	if !created.IsNull(1) {
+		t.Error("created[1] should be null")
+	}
+	if out.Column(2).NullN() != 2 {
+		t.Errorf("missing column has %d nulls, want 2", out.Column(2).NullN())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	rec := sourceRecord(t)
+	defer rec.Release()
+
+	tests := []struct {
+		name    string
+		field   arrow.Field
+		wantErr bool
+	}{
+		{"int32 to int64", arrow.Field{Name: "id", Type: arrow.PrimitiveTypes.Int64}, false},
+		{"int32 to float64", arrow.Field{Name: "id", Type: arrow.PrimitiveTypes.Float64}, false},
+		{"int32 to int16", arrow.Field{Name: "id", Type: arrow.PrimitiveTypes.Int16}, true},
+		{"int32 to uint64", arrow.Field{Name: "id", Type: arrow.PrimitiveTypes.Uint64}, true},
+		{"string to date32", arrow.Field{Name: "created", Type: arrow.FixedWidthTypes.Date32, Nullable: true}, false},
+		{"string to int64", arrow.Field{Name: "created", Type: arrow.PrimitiveTypes.Int64}, true},
+		{"int32 to string", arrow.Field{Name: "id", Type: arrow.BinaryTypes.String}, false},
+		{"missing nullable", arrow.Field{Name: "other", Type: arrow.PrimitiveTypes.Int64, Nullable: true}, false},
+		{"missing required", arrow.Field{Name: "other", Type: arrow.PrimitiveTypes.Int64}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := New(arrow.NewSchema([]arrow.Field{tt.field}, nil), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tr.Check(rec.Schema()); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	rec := sourceRecord(t)
+	defer rec.Release()
+
+	t.Run("null in required column", func(t *testing.T) {
+		tr, _ := New(arrow.NewSchema([]arrow.Field{
+			{Name: "created", Type: arrow.BinaryTypes.LargeString},
+		}, nil), nil)
+		if _, err := tr.Transform(rec); err == nil {
+			t.Error("expected an error for nulls in a non-nullable column")
+		}
+	})
+
+	t.Run("unparseable timestamp", func(t *testing.T) {
+		tr, _ := New(arrow.NewSchema([]arrow.Field{
+			{Name: "created", Type: arrow.FixedWidthTypes.Timestamp_s, Nullable: true},
+		}, nil), &Options{TimestampLayout: time.DateOnly})
+		if _, err := tr.Transform(rec); err == nil {
+			t.Error("expected a parse error")
+		}
+	})
+}
+
+func TestTransformKernels(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	dictType := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.BinaryTypes.String}
+	src := arrow.NewSchema([]arrow.Field{
+		{Name: "active", Type: arrow.FixedWidthTypes.Boolean},
+		{Name: "count", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "day", Type: dictType},
+	}, nil)
+	b := array.NewRecordBuilder(mem, src)
+	b.Field(0).(*array.BooleanBuilder).AppendValues([]bool{true, false}, nil)
+	b.Field(1).(*array.Int64Builder).AppendValues([]int64{-3, 40}, nil)
+	days := b.Field(2).(*array.BinaryDictionaryBuilder)
+	for _, d := range []string{"2024-01-02", "2024-01-02"} {
+		if err := days.AppendString(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := b.NewRecord()
+	b.Release()
+	defer rec.Release()
+
+	tr, err := New(arrow.NewSchema([]arrow.Field{
+		{Name: "active", Type: arrow.PrimitiveTypes.Uint8},
+		{Name: "count", Type: arrow.BinaryTypes.String},
+		{Name: "day", Type: arrow.FixedWidthTypes.Date32},
+	}, nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.mem = mem
+	out, err := tr.Transform(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+
+	if got := out.Column(0).(*array.Uint8).Uint8Values(); got[0] != 1 || got[1] != 0 {
+		t.Errorf("active = %v, want [1 0]", got)
+	}
+	if got := out.Column(1).(*array.String); got.Value(0) != "-3" || got.Value(1) != "40" {
+		t.Errorf("count = %v, want [-3 40]", got)
+	}
+	want := arrow.Date32FromTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	if got := out.Column(2).(*array.Date32).Date32Values(); got[0] != want || got[1] != want {
+		t.Errorf("day = %v, want %v twice", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package seal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/openpgp"
+	// Registers the hash openpgp falls back to for keys that state no
+	// preferences.
+	_ "golang.org/x/crypto/ripemd160"
+)
+
+// ReadGPGKeyring reads the OpenPGP public keys in the file at path,
+// armored or not.
+func ReadGPGKeyring(path string) (openpgp.EntityList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GPG keyring: %w", err)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GPG keyring %s: %w", path, err)
+	}
+	if len(keyring) == 0 {
+		return nil, fmt.Errorf("GPG keyring %s holds no keys", path)
+	}
+	return keyring, nil
+}
+
+// EncryptGPG returns a writer encrypting what is written to it into w, as
+// a binary OpenPGP message for every key of keyring. The message is
+// complete once the writer is closed, which does not close w.
+func EncryptGPG(w io.Writer, keyring openpgp.EntityList) (io.WriteCloser, error) {
+	plaintext, err := openpgp.Encrypt(w, keyring, nil, &openpgp.FileHints{IsBinary: true}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt with GPG: %w", err)
+	}
+	return plaintext, nil
+}
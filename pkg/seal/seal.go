@@ -0,0 +1,226 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package seal prepares finished output files for delivery to third
+// parties: it encrypts them to age or GPG recipients and writes SHA-256
+// checksum sidecars, in the format of sha256sum, next to them.
+package seal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/openpgp"
+)
+
+// Suffixes added to the names of sealed files and of their sidecars.
+const (
+	ChecksumSuffix = ".sha256"
+	AgeSuffix      = ".age"
+	GPGSuffix      = ".gpg"
+)
+
+// Encryption schemes, as Result.Encryption names them.
+const (
+	EncryptionAge = "age"
+	EncryptionGPG = "gpg"
+)
+
+// Options says how a file is sealed.
+type Options struct {
+	// Checksum writes a sidecar named after the sealed file with
+	// ChecksumSuffix, holding its SHA-256.
+	Checksum bool
+	// AgeRecipients encrypts the file to these age public keys, as
+	// age1..., or SSH public keys, adding AgeSuffix to its name.
+	AgeRecipients []string
+	// GPGKeyring names a file of OpenPGP public keys, armored or not, to
+	// encrypt the file to, adding GPGSuffix to its name. It cannot be
+	// combined with AgeRecipients.
+	GPGKeyring string
+}
+
+// Encrypted reports whether o encrypts files.
+func (o *Options) Encrypted() bool {
+	return o != nil && (len(o.AgeRecipients) > 0 || o.GPGKeyring != "")
+}
+
+// Result describes a sealed file.
+type Result struct {
+	Path       string // the file delivered, which encryption renames
+	Bytes      int64
+	Checksum   string // SHA-256 of the file delivered, hex encoded
+	Encryption string // EncryptionAge, EncryptionGPG or empty
+}
+
+// Sealer seals files as its Options say.
+type Sealer struct {
+	checksum bool
+	age      []age.Recipient
+	gpg      openpgp.EntityList
+}
+
+// NewSealer parses the recipients or keyring of opts.
+func NewSealer(opts *Options) (*Sealer, error) {
+	s := &Sealer{}
+	if opts == nil {
+		return s, nil
+	}
+	if len(opts.AgeRecipients) > 0 && opts.GPGKeyring != "" {
+		return nil, fmt.Errorf("cannot encrypt with both age and GPG")
+	}
+	s.checksum = opts.Checksum
+	for _, r := range opts.AgeRecipients {
+		recipient, err := ParseAgeRecipient(r)
+		if err != nil {
+			return nil, err
+		}
+		s.age = append(s.age, recipient)
+	}
+	if opts.GPGKeyring != "" {
+		keyring, err := ReadGPGKeyring(opts.GPGKeyring)
+		if err != nil {
+			return nil, err
+		}
+		s.gpg = keyring
+	}
+	return s, nil
+}
+
+// Seal encrypts the file at path, replacing it with the encrypted file,
+// and writes its checksum sidecar, as the options of s say. Without
+// either it only checksums the file.
+func (s *Sealer) Seal(path string) (Result, error) {
+	if len(s.age) == 0 && len(s.gpg) == 0 {
+		res, err := checksumFile(path)
+		if err != nil {
+			return res, err
+		}
+		return res, s.writeSidecar(res)
+	}
+
+	res, err := s.encrypt(path)
+	if err != nil {
+		return res, err
+	}
+	if err := os.Remove(path); err != nil {
+		return res, fmt.Errorf("failed to remove unencrypted %s: %w", path, err)
+	}
+	return res, s.writeSidecar(res)
+}
+
+// encrypt writes the encryption of the file at path next to it.
+func (s *Sealer) encrypt(path string) (Result, error) {
+	res := Result{Path: path + AgeSuffix, Encryption: EncryptionAge}
+	if len(s.gpg) > 0 {
+		res = Result{Path: path + GPGSuffix, Encryption: EncryptionGPG}
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return res, err
+	}
+	defer in.Close()
+	tmp := res.Path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return res, err
+	}
+	defer os.Remove(tmp)
+	defer out.Close()
+
+	h := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(out, h)}
+	var enc io.WriteCloser
+	if res.Encryption == EncryptionGPG {
+		enc, err = EncryptGPG(counter, s.gpg)
+	} else {
+		enc, err = age.Encrypt(counter, s.age...)
+	}
+	if err != nil {
+		return res, err
+	}
+	if _, err := io.Copy(enc, in); err != nil {
+		return res, fmt.Errorf("failed to encrypt %s: %w", path, err)
+	}
+	if err := enc.Close(); err != nil {
+		return res, fmt.Errorf("failed to encrypt %s: %w", path, err)
+	}
+	if err := out.Close(); err != nil {
+		return res, err
+	}
+	if err := os.Rename(tmp, res.Path); err != nil {
+		return res, err
+	}
+	res.Bytes, res.Checksum = counter.n, hex.EncodeToString(h.Sum(nil))
+	return res, nil
+}
+
+// writeSidecar writes the checksum of res next to it, if asked to.
+func (s *Sealer) writeSidecar(res Result) error {
+	if !s.checksum {
+		return nil
+	}
+	line := fmt.Sprintf("%s  %s\n", res.Checksum, filepath.Base(res.Path))
+	if err := os.WriteFile(res.Path+ChecksumSuffix, []byte(line), 0o644); err != nil {
+		return fmt.Errorf("failed to write checksum of %s: %w", res.Path, err)
+	}
+	return nil
+}
+
+// checksumFile returns the size and SHA-256 of the file at path.
+func checksumFile(path string) (Result, error) {
+	res := Result{Path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		return res, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if res.Bytes, err = io.Copy(h, f); err != nil {
+		return res, fmt.Errorf("failed to checksum %s: %w", path, err)
+	}
+	res.Checksum = hex.EncodeToString(h.Sum(nil))
+	return res, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
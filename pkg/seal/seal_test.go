@@ -0,0 +1,189 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package seal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseAgeRecipient(t *testing.T) {
+	// The public key of the age README.
+	const readme = "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"
+	r, err := ParseAgeRecipient(readme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, ok := r.(*age.X25519Recipient); !ok || x.String() != readme {
+		t.Errorf("recipient = %v", r)
+	}
+	id, _ := age.GenerateX25519Identity()
+	if _, err := ParseAgeRecipient(id.String()); err == nil {
+		t.Error("expected an identity not to parse as a recipient")
+	}
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	sshKey, _ := ssh.NewPublicKey(pub)
+	if r, err := ParseAgeRecipient(string(ssh.MarshalAuthorizedKey(sshKey))); err != nil {
+		t.Error(err)
+	} else if _, ok := r.(*agessh.Ed25519Recipient); !ok {
+		t.Errorf("SSH recipient = %T", r)
+	}
+}
+
+func TestSealAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.csv")
+	os.WriteFile(path, []byte("a,b\n1,2\n"), 0o644)
+	id, _ := age.GenerateX25519Identity()
+
+	s, err := NewSealer(&Options{Checksum: true, AgeRecipients: []string{id.Recipient().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.Seal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Path != path+AgeSuffix || res.Encryption != EncryptionAge {
+		t.Fatalf("result = %+v", res)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("unencrypted file left behind: %v", err)
+	}
+	sidecar, _ := os.ReadFile(res.Path + ChecksumSuffix)
+	if want := res.Checksum + "  out.csv.age\n"; string(sidecar) != want {
+		t.Errorf("sidecar = %q, want %q", sidecar, want)
+	}
+	sum, _ := checksumFile(res.Path)
+	if sum.Checksum != res.Checksum || sum.Bytes != res.Bytes {
+		t.Errorf("checksum = %+v, want %+v", res, sum)
+	}
+
+	f, _ := os.Open(res.Path)
+	defer f.Close()
+	r, err := age.Decrypt(f, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(r); string(got) != "a,b\n1,2\n" {
+		t.Errorf("decrypted = %q", got)
+	}
+}
+
+func TestSealAgeSSH(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+	os.WriteFile(path, []byte("x\n"), 0o644)
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	sshKey, _ := ssh.NewPublicKey(pub)
+	s, err := NewSealer(&Options{AgeRecipients: []string{string(ssh.MarshalAuthorizedKey(sshKey))}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.Seal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := agessh.NewEd25519Identity(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := os.Open(res.Path)
+	defer f.Close()
+	r, err := age.Decrypt(f, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(r); string(got) != "x\n" {
+		t.Errorf("decrypted = %q", got)
+	}
+}
+
+func TestSealChecksumOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+	os.WriteFile(path, []byte("x\n"), 0o644)
+	s, _ := NewSealer(&Options{Checksum: true})
+	res, err := s.Seal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sidecar, _ := os.ReadFile(path + ChecksumSuffix)
+	// As sha256sum prints it.
+	want := "73cb3858a687a8494ca3323053016282f3dad39d42cf62ca4e79dda2aac7d9ac  out.csv\n"
+	if res.Path != path || res.Bytes != 2 || string(sidecar) != want {
+		t.Errorf("result = %+v, sidecar %q", res, sidecar)
+	}
+}
+
+func TestSealGPG(t *testing.T) {
+	entity, err := openpgp.NewEntity("Recipient", "", "recipient@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyring := filepath.Join(dir, "key.gpg")
+	f, _ := os.Create(keyring)
+	entity.Serialize(f)
+	f.Close()
+
+	path := filepath.Join(dir, "out.parquet")
+	os.WriteFile(path, []byte("PAR1"), 0o644)
+	if _, err := NewSealer(&Options{GPGKeyring: keyring, AgeRecipients: []string{"age1x"}}); err == nil {
+		t.Error("expected age and GPG together to be refused")
+	}
+	s, err := NewSealer(&Options{GPGKeyring: keyring})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.Seal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Path != path+GPGSuffix || res.Encryption != EncryptionGPG {
+		t.Fatalf("result = %+v", res)
+	}
+	encrypted, _ := os.Open(res.Path)
+	defer encrypted.Close()
+	md, err := openpgp.ReadMessage(encrypted, openpgp.EntityList{entity}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(md.UnverifiedBody); string(got) != "PAR1" {
+		t.Errorf("decrypted = %q", got)
+	}
+}
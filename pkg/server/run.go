@@ -0,0 +1,286 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pipeline"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"github.com/arrowarc/arrowarc/pkg/projection"
+)
+
+// RunSpec defines a run: its steps are run in order, and the run stops at
+// the first step that fails.
+type RunSpec struct {
+	Name  string     `json:"name,omitempty"`
+	Steps []StepSpec `json:"steps"`
+}
+
+// StepSpec defines one pipeline, which converts a file as the convert
+// command does.
+type StepSpec struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	FromFormat string `json:"from_format,omitempty"`
+	ToFormat   string `json:"to_format,omitempty"`
+	ChunkSize  int64  `json:"chunk_size,omitempty"`
+	// Delimiter and NoHeader describe CSV input.
+	Delimiter string `json:"delimiter,omitempty"`
+	NoHeader  bool   `json:"no_header,omitempty"`
+	// Select and Rename select and rename columns as the --select and
+	// --rename options of the convert command do.
+	Select []string          `json:"select,omitempty"`
+	Rename map[string]string `json:"rename,omitempty"`
+	// OnEmpty is write, skip or fail, as the --on-empty option of the
+	// convert command.
+	OnEmpty string `json:"on_empty,omitempty"`
+}
+
+func (s *RunSpec) validate() error {
+	if len(s.Steps) == 0 {
+		return errors.New("a run needs at least one step")
+	}
+	for i, step := range s.Steps {
+		if step.From == "" || step.To == "" {
+			return fmt.Errorf("step %d: from and to are required", i)
+		}
+		// Standard input and output are the server's own, not the client's.
+		if step.From == integrations.StdioPath || step.To == integrations.StdioPath {
+			return fmt.Errorf("step %d: from and to cannot be %q", i, integrations.StdioPath)
+		}
+		if step.Delimiter != "" && utf8.RuneCountInString(step.Delimiter) != 1 {
+			return fmt.Errorf("step %d: the delimiter must be a single character", i)
+		}
+		if step.ChunkSize < 0 {
+			return fmt.Errorf("step %d: chunk_size cannot be negative", i)
+		}
+		if _, err := projection.New(&projection.Options{Select: step.Select, Rename: step.Rename}); err != nil {
+			return fmt.Errorf("step %d: %w", i, err)
+		}
+		if _, err := pipeline.ParseEmptyPolicy(step.OnEmpty); err != nil {
+			return fmt.Errorf("step %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s *StepSpec) convertOptions() *converter.ConvertOptions {
+	delimiter := ','
+	if s.Delimiter != "" {
+		delimiter, _ = utf8.DecodeRuneInString(s.Delimiter)
+	}
+	chunkSize := s.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = 1024
+	}
+	onEmpty, _ := pipeline.ParseEmptyPolicy(s.OnEmpty)
+	return &converter.ConvertOptions{
+		FromFormat: s.FromFormat,
+		ToFormat:   s.ToFormat,
+		ChunkSize:  chunkSize,
+		CSV:        csvschema.CSVReadOptions{Delimiter: delimiter, HasHeader: !s.NoHeader},
+		Select:     s.Select,
+		Rename:     s.Rename,
+		OnEmpty:    onEmpty,
+	}
+}
+
+// State is the state of a run.
+type State string
+
+const (
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCanceled  State = "canceled"
+)
+
+// runStep runs one step, reporting its pipeline to monitor, and returns
+// the pipeline's JSON report.
+type runStep func(ctx context.Context, step StepSpec, monitor pipeline.Monitor) (string, error)
+
+func (s *Server) convertStep(ctx context.Context, step StepSpec, monitor pipeline.Monitor) (string, error) {
+	opts := step.convertOptions()
+	opts.Monitor = monitor
+	opts.SpillThreshold, opts.SpillDir = s.opts.SpillThreshold, s.opts.SpillDir
+	opts.HTTP = s.opts.HTTP
+	return converter.Convert(ctx, step.From, step.To, opts)
+}
+
+// run tracks a submitted run.
+type run struct {
+	id     string
+	spec   RunSpec
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	state    State
+	err      error
+	created  time.Time
+	finished time.Time
+	step     int                    // index of the step running or last run
+	current  *pipeline.DataPipeline // pipeline of the running step, if any
+	reports  []json.RawMessage
+}
+
+// Start and Stop make run the monitor of its steps' pipelines.
+func (r *run) Start(dp *pipeline.DataPipeline) {
+	r.mu.Lock()
+	r.current = dp
+	r.mu.Unlock()
+}
+
+func (r *run) Stop(error) {}
+
+func (r *run) execute(ctx context.Context, step runStep) {
+	defer close(r.done)
+	// Log entries of the run carry its ID, as do its reports.
+	ctx = logging.WithRunID(ctx, r.id)
+	var err error
+	for i, s := range r.spec.Steps {
+		r.mu.Lock()
+		r.step, r.current = i, nil
+		r.mu.Unlock()
+
+		stepCtx := logging.WithLogger(ctx, logging.FromContext(ctx).With("step", i))
+		report, stepErr := step(stepCtx, s, r)
+		// A failed step's report, if any, tells how far it got.
+		if report != "" {
+			r.mu.Lock()
+			if json.Valid([]byte(report)) {
+				r.reports = append(r.reports, json.RawMessage(report))
+			} else {
+				raw, _ := json.Marshal(report)
+				r.reports = append(r.reports, raw)
+			}
+			r.mu.Unlock()
+		}
+		if stepErr != nil {
+			err = fmt.Errorf("step %d: %w", i, stepErr)
+			break
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = time.Now()
+	r.err = err
+	switch {
+	case err == nil:
+		r.state = StateSucceeded
+	case ctx.Err() != nil:
+		r.state = StateCanceled
+	default:
+		r.state = StateFailed
+	}
+}
+
+// RunStatus is the JSON view of a run.
+type RunStatus struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name,omitempty"`
+	State    State             `json:"state"`
+	Error    string            `json:"error,omitempty"`
+	Created  time.Time         `json:"created"`
+	Finished *time.Time        `json:"finished,omitempty"`
+	Steps    int               `json:"steps"`
+	Step     int               `json:"step"`
+	Progress *ProgressStatus   `json:"progress,omitempty"`
+	Reports  []json.RawMessage `json:"reports,omitempty"`
+}
+
+// ProgressStatus is the JSON view of the live metrics of a running step.
+type ProgressStatus struct {
+	Records        int64         `json:"records"`
+	Bytes          int64         `json:"bytes"`
+	ElapsedSeconds float64       `json:"elapsed_seconds"`
+	RecordsPerSec  float64       `json:"records_per_second"`
+	BytesPerSec    float64       `json:"bytes_per_second"`
+	ExpectedRows   int64         `json:"expected_rows,omitempty"`
+	Stages         []StageStatus `json:"stages"`
+}
+
+// StageStatus is the JSON view of one pipeline stage.
+type StageStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	Rows  int64  `json:"rows"`
+}
+
+func (r *run) status(withReports bool) RunStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := RunStatus{
+		ID:      r.id,
+		Name:    r.spec.Name,
+		State:   r.state,
+		Created: r.created,
+		Steps:   len(r.spec.Steps),
+		Step:    r.step,
+	}
+	if r.err != nil {
+		st.Error = r.err.Error()
+	}
+	if !r.finished.IsZero() {
+		finished := r.finished
+		st.Finished = &finished
+	}
+	if withReports {
+		st.Reports = r.reports
+	}
+	if r.state == StateRunning && r.current != nil {
+		p := r.current.Progress()
+		ps := &ProgressStatus{
+			Records:        p.Records,
+			Bytes:          p.Bytes,
+			ElapsedSeconds: p.Elapsed.Seconds(),
+			RecordsPerSec:  p.RecordsPerSec(),
+			BytesPerSec:    p.BytesPerSec(),
+			ExpectedRows:   p.ExpectedRows,
+			Stages:         make([]StageStatus, len(p.Stages)),
+		}
+		for i, s := range p.Stages {
+			ps.Stages[i] = StageStatus{Name: s.Name, State: s.State.String(), Rows: s.Rows}
+		}
+		st.Progress = ps
+	}
+	return st
+}
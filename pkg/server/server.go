@@ -0,0 +1,289 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package server exposes pipelines over HTTP, so that ArrowArc can run as
+// a data-movement service. Clients submit runs as JSON, follow their live
+// metrics, cancel them and fetch their reports:
+//
+//	POST   /runs             submit a RunSpec, returns the run's status
+//	GET    /runs             list runs, newest first
+//	GET    /runs/{id}        status and live metrics of a run
+//	DELETE /runs/{id}        cancel a run
+//	GET    /runs/{id}/report reports of a finished run
+//	GET    /healthz          liveness check
+//	GET    /readyz           readiness check, running Options.Checks
+//
+// Steps read and write paths on the server's file system, so the server
+// should only be reachable by trusted clients.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pkg/health"
+	"github.com/google/uuid"
+)
+
+// Options configures a Server.
+type Options struct {
+	// MaxFinishedRuns is the number of finished runs kept for queries,
+	// oldest dropped first. Defaults to 100.
+	MaxFinishedRuns int
+	// MaxRequestBytes bounds the size of a submitted run. Defaults to 1 MiB.
+	MaxRequestBytes int64
+	// Checks are the readiness checks of the dependencies of runs, such as
+	// the databases and catalogs they read, by name.
+	Checks map[string]health.Check
+	// SpillThreshold, if positive, spills the records each step queues for
+	// a slower writer past that many bytes to files in SpillDir, as
+	// converter.ConvertOptions does.
+	SpillThreshold int64
+	SpillDir       string
+	// HTTP configures the steps reading http:// and https:// inputs, and
+	// the credentials sent to them. Nil sends none.
+	HTTP *integrations.HTTPOptions
+}
+
+// Server runs pipelines submitted over HTTP. Runs execute in the
+// background, independently of the request that submitted them.
+type Server struct {
+	opts   Options
+	step   runStep
+	health *health.Checker
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	runs map[string]*run
+	ids  []string // in submission order
+}
+
+// New creates a Server.
+func New(opts *Options) *Server {
+	s := &Server{runs: make(map[string]*run)}
+	if opts != nil {
+		s.opts = *opts
+	}
+	s.step = s.convertStep
+	if s.opts.MaxFinishedRuns <= 0 {
+		s.opts.MaxFinishedRuns = 100
+	}
+	if s.opts.MaxRequestBytes <= 0 {
+		s.opts.MaxRequestBytes = 1 << 20
+	}
+	s.health = health.New(s.opts.Checks)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Health returns the checker serving /healthz and /readyz, to drain the
+// server before shutting it down.
+func (s *Server) Health() *health.Checker {
+	return s.health
+}
+
+// Handler returns the HTTP handler serving the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", s.submit)
+	mux.HandleFunc("GET /runs", s.list)
+	mux.HandleFunc("GET /runs/{id}", s.get)
+	mux.HandleFunc("DELETE /runs/{id}", s.cancelRun)
+	mux.HandleFunc("GET /runs/{id}/report", s.report)
+	s.health.Register(mux)
+	return mux
+}
+
+// Close makes the server unready, cancels the running runs and waits for
+// them to stop.
+func (s *Server) Close() error {
+	s.health.Drain()
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+// Submit starts a run of spec and returns its ID.
+func (s *Server) Submit(spec RunSpec) (string, error) {
+	if err := spec.validate(); err != nil {
+		return "", err
+	}
+	if s.ctx.Err() != nil {
+		return "", errors.New("server is closed")
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	r := &run{
+		id:      uuid.NewString(),
+		spec:    spec,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		state:   StateRunning,
+		created: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	s.runs[r.id] = r
+	s.ids = append(s.ids, r.id)
+	s.pruneLocked()
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		r.execute(ctx, s.step)
+	}()
+	return r.id, nil
+}
+
+// pruneLocked drops the oldest finished runs past MaxFinishedRuns.
+func (s *Server) pruneLocked() {
+	finished := 0
+	for _, id := range s.ids {
+		if s.runs[id].finishedRun() {
+			finished++
+		}
+	}
+	kept := s.ids[:0]
+	for _, id := range s.ids {
+		if finished > s.opts.MaxFinishedRuns && s.runs[id].finishedRun() {
+			delete(s.runs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.ids = kept
+}
+
+func (r *run) finishedRun() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Server) lookup(w http.ResponseWriter, req *http.Request) *run {
+	s.mu.Lock()
+	r := s.runs[req.PathValue("id")]
+	s.mu.Unlock()
+	if r == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %q not found", req.PathValue("id")))
+	}
+	return r
+}
+
+func (s *Server) submit(w http.ResponseWriter, req *http.Request) {
+	var spec RunSpec
+	dec := json.NewDecoder(http.MaxBytesReader(w, req.Body, s.opts.MaxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid run: %w", err))
+		return
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		writeError(w, http.StatusBadRequest, errors.New("invalid run: unexpected data after the JSON object"))
+		return
+	}
+	id, err := s.Submit(spec)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.mu.Lock()
+	r := s.runs[id]
+	s.mu.Unlock()
+	w.Header().Set("Location", "/runs/"+id)
+	writeJSON(w, http.StatusAccepted, r.status(false))
+}
+
+func (s *Server) list(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	runs := make([]*run, 0, len(s.ids))
+	for i := len(s.ids) - 1; i >= 0; i-- {
+		runs = append(runs, s.runs[s.ids[i]])
+	}
+	s.mu.Unlock()
+	statuses := make([]RunStatus, len(runs))
+	for i, r := range runs {
+		statuses[i] = r.status(false)
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *Server) get(w http.ResponseWriter, req *http.Request) {
+	if r := s.lookup(w, req); r != nil {
+		writeJSON(w, http.StatusOK, r.status(false))
+	}
+}
+
+func (s *Server) cancelRun(w http.ResponseWriter, req *http.Request) {
+	r := s.lookup(w, req)
+	if r == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+	writeJSON(w, http.StatusOK, r.status(false))
+}
+
+func (s *Server) report(w http.ResponseWriter, req *http.Request) {
+	r := s.lookup(w, req)
+	if r == nil {
+		return
+	}
+	if !r.finishedRun() {
+		writeError(w, http.StatusConflict, fmt.Errorf("run %q is still running", r.id))
+		return
+	}
+	writeJSON(w, http.StatusOK, r.status(true))
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
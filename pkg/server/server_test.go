@@ -0,0 +1,251 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/health"
+)
+
+func do(t *testing.T, srv *httptest.Server, method, path, body string, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func waitFinished(t *testing.T, srv *httptest.Server, id string) RunStatus {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		var st RunStatus
+		do(t, srv, http.MethodGet, "/runs/"+id, "", &st)
+		if st.State != StateRunning {
+			return st
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("run %s did not finish", id)
+	return RunStatus{}
+}
+
+func TestConvertRun(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.csv")
+	if err := os.WriteFile(in, []byte("id,name\n1,a\n2,b\n3,c\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.parquet")
+
+	s := New(nil)
+	defer s.Close()
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	spec, _ := json.Marshal(RunSpec{Name: "csv", Steps: []StepSpec{{From: in, To: out}}})
+	var st RunStatus
+	if code := do(t, srv, http.MethodPost, "/runs", string(spec), &st); code != http.StatusAccepted {
+		t.Fatalf("submit: status %d", code)
+	}
+	if st = waitFinished(t, srv, st.ID); st.State != StateSucceeded {
+		t.Fatalf("state %s: %s", st.State, st.Error)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Fatal(err)
+	}
+
+	var report RunStatus
+	if code := do(t, srv, http.MethodGet, "/runs/"+st.ID+"/report", "", &report); code != http.StatusOK {
+		t.Fatalf("report: status %d", code)
+	}
+	if len(report.Reports) != 1 {
+		t.Fatalf("got %d reports, want 1", len(report.Reports))
+	}
+	var metrics map[string]any
+	if err := json.Unmarshal(report.Reports[0], &metrics); err != nil {
+		t.Fatalf("report is not a JSON object: %v", err)
+	}
+
+	var runs []RunStatus
+	do(t, srv, http.MethodGet, "/runs", "", &runs)
+	if len(runs) != 1 || runs[0].ID != st.ID {
+		t.Fatalf("listed %+v", runs)
+	}
+}
+
+func TestSubmitInvalidProjection(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	_, err := s.Submit(RunSpec{Steps: []StepSpec{{From: "a.csv", To: "b.parquet", Select: []string{"id"}, Rename: map[string]string{"name": "label"}}}})
+	if err == nil || !strings.Contains(err.Error(), "step 0") {
+		t.Fatalf("got %v, want an error for step 0", err)
+	}
+}
+
+func TestSubmitInvalidOnEmpty(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	_, err := s.Submit(RunSpec{Steps: []StepSpec{{From: "a.csv", To: "b.parquet", OnEmpty: "ignore"}}})
+	if err == nil || !strings.Contains(err.Error(), "step 0") {
+		t.Fatalf("got %v, want an error for step 0", err)
+	}
+}
+
+func TestStepDelimiter(t *testing.T) {
+	for _, delimiter := range []string{";", "§", "；"} {
+		spec := RunSpec{Steps: []StepSpec{{From: "a.csv", To: "b.parquet", Delimiter: delimiter}}}
+		if err := spec.validate(); err != nil {
+			t.Fatalf("%q: %v", delimiter, err)
+		}
+		if got := spec.Steps[0].convertOptions().CSV.Delimiter; string(got) != delimiter {
+			t.Errorf("%q: got delimiter %q", delimiter, got)
+		}
+	}
+}
+
+func TestCancelRun(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	started := make(chan struct{})
+	s.step = func(ctx context.Context, _ StepSpec, _ pipeline.Monitor) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	var st RunStatus
+	do(t, srv, http.MethodPost, "/runs", `{"steps":[{"from":"a.csv","to":"b.parquet"}]}`, &st)
+	<-started
+	if code := do(t, srv, http.MethodGet, "/runs/"+st.ID+"/report", "", nil); code != http.StatusConflict {
+		t.Fatalf("report of a running run: status %d, want 409", code)
+	}
+	if code := do(t, srv, http.MethodDelete, "/runs/"+st.ID, "", &st); code != http.StatusOK {
+		t.Fatalf("cancel: status %d", code)
+	}
+	if st.State != StateCanceled {
+		t.Fatalf("state %s, want canceled", st.State)
+	}
+}
+
+func TestInvalidRun(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	for _, body := range []string{
+		``, `{"steps":[]}`, `{"steps":[{"from":"a.csv"}]}`, `{"stepz":[]}`,
+		`{"steps":[{"from":"-","to":"b.parquet"}]}`,
+		`{"steps":[{"from":"a.csv","to":"-"}]}`,
+		`{"steps":[{"from":"a.csv","to":"b.parquet","delimiter":";;"}]}`,
+	} {
+		if code := do(t, srv, http.MethodPost, "/runs", body, nil); code != http.StatusBadRequest {
+			t.Errorf("%q: status %d, want 400", body, code)
+		}
+	}
+	if code := do(t, srv, http.MethodGet, "/runs/missing", "", nil); code != http.StatusNotFound {
+		t.Errorf("missing run: status %d, want 404", code)
+	}
+}
+
+func TestPruneFinishedRuns(t *testing.T) {
+	s := New(&Options{MaxFinishedRuns: 2})
+	defer s.Close()
+	s.step = func(context.Context, StepSpec, pipeline.Monitor) (string, error) { return `{}`, nil }
+
+	var last string
+	for i := 0; i < 5; i++ {
+		id, err := s.Submit(RunSpec{Steps: []StepSpec{{From: "a", To: "b"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.mu.Lock()
+		r := s.runs[id]
+		s.mu.Unlock()
+		<-r.done
+		last = id
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.runs) > 3 || s.runs[last] == nil {
+		t.Fatalf("kept %d runs", len(s.runs))
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	var catalogErr error
+	s := New(&Options{Checks: map[string]health.Check{
+		"catalog": func(context.Context) error { return catalogErr },
+	}})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	var report health.Report
+	if code := do(t, srv, http.MethodGet, "/readyz", "", &report); code != http.StatusOK || report.Checks[0].Name != "catalog" {
+		t.Errorf("ready: status %d, %+v", code, report)
+	}
+	catalogErr = errors.New("catalog unreachable")
+	if code := do(t, srv, http.MethodGet, "/readyz", "", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("catalog down: status %d, want 503", code)
+	}
+	catalogErr = nil
+	s.Close()
+	if code := do(t, srv, http.MethodGet, "/readyz", "", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("closed: status %d, want 503", code)
+	}
+	if code := do(t, srv, http.MethodGet, "/healthz", "", nil); code != http.StatusOK {
+		t.Errorf("liveness: status %d", code)
+	}
+}
@@ -0,0 +1,175 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// Rule checks the values of a single column. Null values satisfy every rule
+// except NotNull.
+type Rule interface {
+	// String describes the rule in failure reasons and metric names.
+	String() string
+	// Check reports, for each row of arr, whether the value satisfies the rule.
+	Check(arr arrow.Array) ([]bool, error)
+}
+
+type notNull struct{}
+
+// NotNull rejects null values.
+func NotNull() Rule { return notNull{} }
+
+func (notNull) String() string { return "not null" }
+
+func (notNull) Check(arr arrow.Array) ([]bool, error) {
+	ok := make([]bool, arr.Len())
+	for i := range ok {
+		ok[i] = arr.IsValid(i)
+	}
+	return ok, nil
+}
+
+type matches struct {
+	re *regexp.Regexp
+}
+
+// Matches rejects string values that do not match pattern.
+func Matches(pattern string) (Rule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return matches{re: re}, nil
+}
+
+func (r matches) String() string { return fmt.Sprintf("matches %q", r.re.String()) }
+
+func (r matches) Check(arr arrow.Array) ([]bool, error) {
+	value, err := stringValues(arr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r, err)
+	}
+	return checkValid(arr, func(i int) bool { return r.re.MatchString(value(i)) }), nil
+}
+
+type inRange struct {
+	min, max float64
+}
+
+// Range rejects numeric values outside [min, max].
+func Range(min, max float64) Rule { return inRange{min: min, max: max} }
+
+func (r inRange) String() string {
+	return fmt.Sprintf("range [%s, %s]",
+		strconv.FormatFloat(r.min, 'g', -1, 64), strconv.FormatFloat(r.max, 'g', -1, 64))
+}
+
+func (r inRange) Check(arr arrow.Array) ([]bool, error) {
+	value, err := numericValues(arr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r, err)
+	}
+	return checkValid(arr, func(i int) bool {
+		v := value(i)
+		return v >= r.min && v <= r.max
+	}), nil
+}
+
+type oneOf struct {
+	values []string
+}
+
+// OneOf rejects string values that are not in values.
+func OneOf(values ...string) Rule { return oneOf{values: values} }
+
+func (r oneOf) String() string { return fmt.Sprintf("one of [%s]", strings.Join(r.values, ", ")) }
+
+func (r oneOf) Check(arr arrow.Array) ([]bool, error) {
+	value, err := stringValues(arr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r, err)
+	}
+	return checkValid(arr, func(i int) bool { return slices.Contains(r.values, value(i)) }), nil
+}
+
+// checkValid applies fn to the non-null rows of arr.
+func checkValid(arr arrow.Array, fn func(int) bool) []bool {
+	ok := make([]bool, arr.Len())
+	for i := range ok {
+		ok[i] = arr.IsNull(i) || fn(i)
+	}
+	return ok
+}
+
+func stringValues(arr arrow.Array) (func(int) string, error) {
+	switch a := arr.(type) {
+	case *array.String:
+		return a.Value, nil
+	case *array.LargeString:
+		return a.Value, nil
+	default:
+		return nil, fmt.Errorf("unsupported column type %s", arr.DataType())
+	}
+}
+
+func numericValues(arr arrow.Array) (func(int) float64, error) {
+	switch a := arr.(type) {
+	case *array.Int8:
+		return func(i int) float64 { return float64(a.Value(i)) }, nil
+	case *array.Int16:
+		return func(i int) float64 { return float64(a.Value(i)) }, nil
+	case *array.Int32:
+		return func(i int) float64 { return float64(a.Value(i)) }, nil
+	case *array.Int64:
+		return func(i int) float64 { return float64(a.Value(i)) }, nil
+	case *array.Uint8:
+		return func(i int) float64 { return float64(a.Value(i)) }, nil
+	case *array.Uint16:
+		return func(i int) float64 { return float64(a.Value(i)) }, nil
+	case *array.Uint32:
+		return func(i int) float64 { return float64(a.Value(i)) }, nil
+	case *array.Uint64:
+		return func(i int) float64 { return float64(a.Value(i)) }, nil
+	case *array.Float32:
+		return func(i int) float64 { return float64(a.Value(i)) }, nil
+	case *array.Float64:
+		return a.Value, nil
+	default:
+		return nil, fmt.Errorf("unsupported column type %s", arr.DataType())
+	}
+}
@@ -0,0 +1,228 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package validate checks records against per-column rules and routes the
+// rows that fail to a dead-letter writer instead of failing the pipeline.
+package validate
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+)
+
+// ErrorColumn is the column appended to dead-letter records describing the
+// first rule each row failed.
+const ErrorColumn = "_validation_error"
+
+// Count keys reported by Validator.Counts in addition to per-rule failures.
+const (
+	CountPassed   = "rows_passed"
+	CountRejected = "rows_rejected"
+)
+
+// ColumnRules lists the rules applied to one column.
+type ColumnRules struct {
+	Column string
+	Rules  []Rule
+}
+
+// Validator is a pipeline Transformer that drops rows failing any rule and
+// writes them, with the reason, to a dead-letter writer.
+type Validator struct {
+	rules      []ColumnRules
+	deadLetter interfaces.Writer
+
+	mu       sync.Mutex
+	counts   map[string]int64
+	closeErr error
+	closed   bool
+}
+
+// New creates a Validator. Rejected rows are written to deadLetter, which
+// must accept records with DeadLetterSchema of the source schema; if
+// deadLetter is nil they are only counted. The Validator closes deadLetter
+// when it is closed.
+func New(rules []ColumnRules, deadLetter interfaces.Writer) (*Validator, error) {
+	for _, cr := range rules {
+		if cr.Column == "" {
+			return nil, fmt.Errorf("column name cannot be empty")
+		}
+		for _, rule := range cr.Rules {
+			if rule == nil {
+				return nil, fmt.Errorf("column %q: rule cannot be nil", cr.Column)
+			}
+		}
+	}
+	return &Validator{
+		rules:      rules,
+		deadLetter: deadLetter,
+		counts:     map[string]int64{CountPassed: 0, CountRejected: 0},
+	}, nil
+}
+
+// DeadLetterSchema returns schema with the ErrorColumn appended.
+func DeadLetterSchema(schema *arrow.Schema) *arrow.Schema {
+	fields := append(schema.Fields(), arrow.Field{Name: ErrorColumn, Type: arrow.BinaryTypes.String})
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
+
+// Transform returns the rows of record that satisfy every rule.
+func (v *Validator) Transform(record arrow.Record) (arrow.Record, error) {
+	n := int(record.NumRows())
+	reasons := make([]string, n)
+	failures := make(map[string]int64)
+
+	for _, cr := range v.rules {
+		indices := record.Schema().FieldIndices(cr.Column)
+		if len(indices) == 0 {
+			return nil, fmt.Errorf("column %q not found", cr.Column)
+		}
+		col := record.Column(indices[0])
+		for _, rule := range cr.Rules {
+			ok, err := rule.Check(col)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", cr.Column, err)
+			}
+			reason := fmt.Sprintf("%s: %s", cr.Column, rule)
+			for i, pass := range ok {
+				if pass {
+					continue
+				}
+				failures[reason]++
+				if reasons[i] == "" {
+					reasons[i] = reason
+				}
+			}
+		}
+	}
+
+	valid := make([]bool, n)
+	rejected := 0
+	for i, reason := range reasons {
+		valid[i] = reason == ""
+		if !valid[i] {
+			rejected++
+		}
+	}
+
+	v.mu.Lock()
+	v.counts[CountPassed] += int64(n - rejected)
+	v.counts[CountRejected] += int64(rejected)
+	for reason, count := range failures {
+		v.counts[reason] += count
+	}
+	v.mu.Unlock()
+
+	if rejected == 0 {
+		record.Retain()
+		return record, nil
+	}
+
+	if v.deadLetter != nil {
+		if err := v.writeRejected(record, valid, reasons); err != nil {
+			return nil, fmt.Errorf("dead-letter write failed: %w", err)
+		}
+	}
+	return filterRecord(record, valid, true)
+}
+
+// writeRejected writes the rows of record that are not valid to the
+// dead-letter writer along with their reasons.
+func (v *Validator) writeRejected(record arrow.Record, valid []bool, reasons []string) error {
+	rejected, err := filterRecord(record, valid, false)
+	if err != nil {
+		return err
+	}
+	defer rejected.Release()
+
+	b := array.NewStringBuilder(memory.DefaultAllocator)
+	defer b.Release()
+	for i, reason := range reasons {
+		if !valid[i] {
+			b.Append(reason)
+		}
+	}
+	reasonCol := b.NewArray()
+	defer reasonCol.Release()
+
+	cols := append(slices.Clone(rejected.Columns()), reasonCol)
+	out := array.NewRecord(DeadLetterSchema(record.Schema()), cols, rejected.NumRows())
+	defer out.Release()
+	return v.deadLetter.Write(out)
+}
+
+// filterRecord keeps the rows of record where mask equals keep.
+func filterRecord(record arrow.Record, mask []bool, keep bool) (arrow.Record, error) {
+	b := array.NewBooleanBuilder(memory.DefaultAllocator)
+	defer b.Release()
+	b.Reserve(len(mask))
+	for _, m := range mask {
+		b.UnsafeAppend(m == keep)
+	}
+	selection := b.NewBooleanArray()
+	defer selection.Release()
+
+	return compute.FilterRecordBatch(context.Background(), record, selection, compute.DefaultFilterOptions())
+}
+
+// Name identifies the Validator in the pipeline metrics report.
+func (v *Validator) Name() string {
+	return "validate"
+}
+
+// Counts returns the number of rows passed and rejected, and the number of
+// failures of each rule keyed by "column: rule".
+func (v *Validator) Counts() map[string]int64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return maps.Clone(v.counts)
+}
+
+// Close closes the dead-letter writer. It is safe to call more than once.
+func (v *Validator) Close() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.closed {
+		v.closed = true
+		if v.deadLetter != nil {
+			v.closeErr = v.deadLetter.Close()
+		}
+	}
+	return v.closeErr
+}
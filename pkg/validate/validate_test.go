@@ -0,0 +1,120 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func testRecord(t *testing.T) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "email", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "status", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 0, 400}, []bool{true, true, false, true})
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"a@example.com", "bad", "c@example.com", "d@example.com"}, nil)
+	b.Field(2).(*array.StringBuilder).AppendValues([]string{"active", "active", "active", "unknown"}, nil)
+	return b.NewRecord()
+}
+
+// recordingWriter collects the records written to it.
+type recordingWriter struct {
+	records []arrow.Record
+	closed  bool
+}
+
+func (w *recordingWriter) Write(rec arrow.Record) error {
+	rec.Retain()
+	w.records = append(w.records, rec)
+	return nil
+}
+
+func (w *recordingWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestValidatorRoutesRejectedRows(t *testing.T) {
+	email, err := Matches(`^[^@]+@[^@]+$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadLetter := &recordingWriter{}
+	v, err := New([]ColumnRules{
+		{Column: "id", Rules: []Rule{NotNull(), Range(0, 100)}},
+		{Column: "email", Rules: []Rule{email}},
+		{Column: "status", Rules: []Rule{OneOf("active", "inactive")}},
+	}, deadLetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := testRecord(t)
+	defer rec.Release()
+	out, err := v.Transform(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+
+	if out.NumRows() != 1 || out.Column(0).(*array.Int64).Value(0) != 1 {
+		t.Fatalf("valid rows = %v, want only id 1", out.Column(0))
+	}
+
+	if len(deadLetter.records) != 1 {
+		t.Fatalf("dead-letter writes = %d, want 1", len(deadLetter.records))
+	}
+	rejected := deadLetter.records[0]
+	defer rejected.Release()
+	if !rejected.Schema().Equal(DeadLetterSchema(rec.Schema())) {
+		t.Errorf("dead-letter schema = %s", rejected.Schema())
+	}
+	reasons := rejected.Column(3).(*array.String)
+	want := []string{`email: matches "^[^@]+@[^@]+$"`, "id: not null", "id: range [0, 100]"}
+	for i, w := range want {
+		if reasons.Value(i) != w {
+			t.Errorf("reason[%d] = %q, want %q", i, reasons.Value(i), w)
+		}
+	}
+
+	counts := v.Counts()
+	for key, n := range map[string]int64{
+		CountPassed:                         1,
+		CountRejected:                       3,
+		"id: range [0, 100]":                1,
+		"status: one of [active, inactive]": 1,
+	} {
+		if counts[key] != n {
+			t.Errorf("counts[%q] = %d, want %d", key, counts[key], n)
+		}
+	}
+
+	if err := v.Close(); err != nil || !deadLetter.closed {
+		t.Errorf("Close() = %v, dead-letter closed = %v", err, deadLetter.closed)
+	}
+}
+
+func TestValidatorErrors(t *testing.T) {
+	rec := testRecord(t)
+	defer rec.Release()
+
+	if _, err := Matches("("); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+
+	v, _ := New([]ColumnRules{{Column: "missing", Rules: []Rule{NotNull()}}}, nil)
+	if _, err := v.Transform(rec); err == nil {
+		t.Error("expected an error for a missing column")
+	}
+
+	v, _ = New([]ColumnRules{{Column: "email", Rules: []Rule{Range(0, 1)}}}, nil)
+	if _, err := v.Transform(rec); err == nil {
+		t.Error("expected an error for a range rule on a string column")
+	}
+}
@@ -0,0 +1,234 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package verify computes digests of streams of records, a row count and a
+// hash of the values of each column, to check that a copy of a dataset
+// holds the same data as its source.
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"github.com/arrowarc/arrowarc/pkg/geo"
+)
+
+// ErrMismatch is returned, wrapped, when a copy does not match its source.
+var ErrMismatch = errors.New("verification failed")
+
+// ColumnDigest sums up the values of a column. Hash combines a hash of
+// the text form of each non-null value regardless of row order, so a copy
+// read back in another order, or from a format that reorders rows, still
+// matches.
+type ColumnDigest struct {
+	Name  string `json:"name"`
+	Hash  string `json:"hash"`
+	Nulls int64  `json:"nulls"`
+}
+
+// Digest sums up a stream of records.
+type Digest struct {
+	Rows    int64          `json:"rows"`
+	Columns []ColumnDigest `json:"columns"`
+}
+
+// Hasher computes the Digest of the records added to it.
+type Hasher struct {
+	rows    int64
+	names   []string
+	hashes  []uint64
+	nulls   []int64
+	started bool
+	// geometry marks the WKB geometry columns, hashed as WKT so that a
+	// copy holding WKT matches.
+	geometry []bool
+}
+
+// NewHasher returns an empty Hasher.
+func NewHasher() *Hasher {
+	return &Hasher{}
+}
+
+// Add adds the rows of record to the digest. Columns are told apart by
+// name, from the schema of the first record.
+func (h *Hasher) Add(record arrow.Record) {
+	if !h.started {
+		h.started = true
+		for _, f := range record.Schema().Fields() {
+			h.names = append(h.names, f.Name)
+		}
+		h.hashes = make([]uint64, len(h.names))
+		h.nulls = make([]int64, len(h.names))
+		h.geometry = make([]bool, len(h.names))
+		for _, i := range geo.Columns(record.Schema()) {
+			h.geometry[i] = true
+		}
+	}
+	h.rows += record.NumRows()
+	for i, col := range record.Columns() {
+		if i >= len(h.names) {
+			break
+		}
+		for row := 0; row < col.Len(); row++ {
+			if col.IsNull(row) {
+				h.nulls[i]++
+				continue
+			}
+			h.hashes[i] += mix(hashString(h.valueStr(i, col, row)))
+		}
+	}
+}
+
+// valueStr returns the text form of the value at row of col, column i.
+func (h *Hasher) valueStr(i int, col arrow.Array, row int) string {
+	if h.geometry[i] {
+		if wkt, err := geo.WKT(col, row); err == nil {
+			return wkt
+		}
+	}
+	return col.ValueStr(row)
+}
+
+// Digest returns the digest of the records added so far.
+func (h *Hasher) Digest() Digest {
+	d := Digest{Rows: h.rows, Columns: make([]ColumnDigest, len(h.names))}
+	for i, name := range h.names {
+		d.Columns[i] = ColumnDigest{Name: name, Hash: fmt.Sprintf("%016x", h.hashes[i]), Nulls: h.nulls[i]}
+	}
+	return d
+}
+
+// DigestReader reads every record of r and returns their digest. It does
+// not close r.
+func DigestReader(r interfaces.Reader) (Digest, error) {
+	h := NewHasher()
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return h.Digest(), nil
+		}
+		if err != nil {
+			return Digest{}, err
+		}
+		if record == nil {
+			return h.Digest(), nil
+		}
+		h.Add(record)
+		record.Release()
+	}
+}
+
+// hashString returns the 64-bit FNV-1a hash of s.
+func hashString(s string) uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime
+	}
+	return h
+}
+
+// mix scrambles h so that sums of hashes of similar values do not cancel
+// out, using the splitmix64 finalizer.
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// ColumnResult compares a column of the source with the same column of
+// the copy. Actual is nil if the copy has no such column.
+type ColumnResult struct {
+	Name     string        `json:"name"`
+	Match    bool          `json:"match"`
+	Expected ColumnDigest  `json:"expected"`
+	Actual   *ColumnDigest `json:"actual,omitempty"`
+}
+
+// Result is the outcome of comparing the digest of a copy with that of its
+// source.
+type Result struct {
+	Passed       bool           `json:"passed"`
+	ExpectedRows int64          `json:"expected_rows"`
+	ActualRows   int64          `json:"actual_rows"`
+	Columns      []ColumnResult `json:"columns"`
+}
+
+// Compare compares the digest of a copy, actual, with that of its source,
+// expected, matching columns by name. Columns only in the copy are
+// ignored.
+func Compare(expected, actual Digest) *Result {
+	r := &Result{ExpectedRows: expected.Rows, ActualRows: actual.Rows, Passed: expected.Rows == actual.Rows}
+	byName := make(map[string]ColumnDigest, len(actual.Columns))
+	for _, c := range actual.Columns {
+		byName[c.Name] = c
+	}
+	for _, want := range expected.Columns {
+		col := ColumnResult{Name: want.Name, Expected: want}
+		if got, ok := byName[want.Name]; ok {
+			col.Actual = &got
+			col.Match = got == want
+		}
+		r.Passed = r.Passed && col.Match
+		r.Columns = append(r.Columns, col)
+	}
+	return r
+}
+
+// Err returns nil if the verification passed, or an error matching
+// ErrMismatch that names what differs.
+func (r *Result) Err() error {
+	if r.Passed {
+		return nil
+	}
+	var diffs []string
+	if r.ExpectedRows != r.ActualRows {
+		diffs = append(diffs, fmt.Sprintf("%d rows instead of %d", r.ActualRows, r.ExpectedRows))
+	}
+	for _, c := range r.Columns {
+		switch {
+		case c.Actual == nil:
+			diffs = append(diffs, fmt.Sprintf("column %q is missing", c.Name))
+		case !c.Match:
+			diffs = append(diffs, fmt.Sprintf("column %q differs", c.Name))
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrMismatch, strings.Join(diffs, ", "))
+}
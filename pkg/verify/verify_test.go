@@ -0,0 +1,128 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package verify
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+var schema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+}, nil)
+
+// record builds a record of the given ids, naming each row after its id
+// and leaving the name of negative ids null.
+func record(ids ...int64) arrow.Record {
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	for _, id := range ids {
+		b.Field(0).(*array.Int64Builder).Append(id)
+		if id < 0 {
+			b.Field(1).AppendNull()
+		} else {
+			b.Field(1).(*array.StringBuilder).Append(string(rune('a' + id)))
+		}
+	}
+	return b.NewRecord()
+}
+
+func digest(records ...arrow.Record) Digest {
+	h := NewHasher()
+	for _, rec := range records {
+		h.Add(rec)
+		rec.Release()
+	}
+	return h.Digest()
+}
+
+// recordReader returns its records in turn.
+type recordReader struct {
+	records []arrow.Record
+}
+
+func (r *recordReader) Read() (arrow.Record, error) {
+	if len(r.records) == 0 {
+		return nil, io.EOF
+	}
+	rec := r.records[0]
+	r.records = r.records[1:]
+	return rec, nil
+}
+
+func (r *recordReader) Close() error { return nil }
+
+func TestDigestIgnoresOrderAndBatching(t *testing.T) {
+	want := digest(record(0, 1, 2, -1))
+	if want.Rows != 4 || len(want.Columns) != 2 || want.Columns[1].Nulls != 1 {
+		t.Fatalf("digest = %+v", want)
+	}
+	got, err := DigestReader(&recordReader{records: []arrow.Record{record(2, -1), record(1, 0)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := Compare(want, got); !r.Passed || r.Err() != nil {
+		t.Fatalf("reordered copy does not match: %+v", r)
+	}
+}
+
+func TestCompareReportsDifferences(t *testing.T) {
+	want := digest(record(0, 1, 2))
+	for _, test := range []struct {
+		name string
+		got  Digest
+		err  string
+	}{
+		{"missing row", digest(record(0, 1)), `verification failed: 2 rows instead of 3, column "id" differs, column "name" differs`},
+		{"changed value", digest(record(0, 1, 3)), `verification failed: column "id" differs, column "name" differs`},
+		{"null value", digest(record(0, 1, -1)), `verification failed: column "id" differs, column "name" differs`},
+		{"missing column", Digest{Rows: 3, Columns: want.Columns[:1]}, `verification failed: column "name" is missing`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := Compare(want, test.got)
+			if r.Passed {
+				t.Fatal("copy matches")
+			}
+			err := r.Err()
+			if !errors.Is(err, ErrMismatch) {
+				t.Fatalf("error %v does not match ErrMismatch", err)
+			}
+			if err.Error() != test.err {
+				t.Errorf("error = %q, want %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package watch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Status is the outcome of handling a file.
+type Status string
+
+const (
+	StatusDone      Status = "done"      // processed successfully
+	StatusFailed    Status = "failed"    // processing returned an error
+	StatusDuplicate Status = "duplicate" // same content as a file already done
+)
+
+// Entry is one line of the ledger.
+type Entry struct {
+	File    string    `json:"file"`
+	SHA256  string    `json:"sha256"`
+	Size    int64     `json:"size"`
+	Status  Status    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+	RunID   string    `json:"run_id,omitempty"` // run ID of the file's log entries
+	MovedTo string    `json:"moved_to"`
+	At      time.Time `json:"at"`
+}
+
+// Ledger is an append-only JSON lines log of the files handled, indexed by
+// content hash.
+type Ledger struct {
+	file *os.File
+	done map[string]Entry
+}
+
+// OpenLedger opens the ledger at path, creating it if needed, and loads
+// its entries.
+func OpenLedger(path string) (*Ledger, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %w", err)
+	}
+	l := &Ledger{file: f, done: make(map[string]Entry)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("invalid ledger entry on line %d: %w", line, err)
+		}
+		l.record(e)
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+	return l, nil
+}
+
+func (l *Ledger) record(e Entry) {
+	if e.Status == StatusDone {
+		l.done[e.SHA256] = e
+	}
+}
+
+// Done returns the entry of the file with content hash sum that was
+// processed successfully, if any.
+func (l *Ledger) Done(sum string) (Entry, bool) {
+	e, ok := l.done[sum]
+	return e, ok
+}
+
+// Append writes e to the ledger and syncs it to disk.
+func (l *Ledger) Append(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write ledger: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync ledger: %w", err)
+	}
+	l.record(e)
+	return nil
+}
+
+// Close closes the ledger file.
+func (l *Ledger) Close() error {
+	return l.file.Close()
+}
@@ -0,0 +1,294 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Package watch ingests files dropped into a directory. Each new file is
+// handed to a processing function once it has stopped changing, then
+// moved to a done or failed directory. A ledger of content hashes makes
+// processing idempotent: a file whose content was already processed is
+// moved to the done directory without being processed again.
+package watch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"github.com/fsnotify/fsnotify"
+	"github.com/google/uuid"
+)
+
+// ProcessFunc processes the file at path, typically by running it through
+// a pipeline.
+type ProcessFunc func(ctx context.Context, path string) error
+
+// Options configures a Watcher.
+type Options struct {
+	// DoneDir and FailedDir receive processed files. They default to the
+	// done and failed subdirectories of the watched directory.
+	DoneDir   string
+	FailedDir string
+	// Ledger is the path of the ledger file, .arrowarc-ledger.jsonl in the
+	// watched directory by default.
+	Ledger string
+	// Extensions lists the file extensions to pick up, with their dot.
+	// Defaults to DefaultExtensions.
+	Extensions []string
+	// SettleTime is how long a file must go without changes before it is
+	// processed, so that files still being copied are not read. Defaults
+	// to one second.
+	SettleTime time.Duration
+}
+
+// DefaultExtensions are the extensions of the files a Watcher picks up by
+// default.
+var DefaultExtensions = []string{".csv", ".json", ".ndjson", ".jsonl", ".avro", ".parquet"}
+
+// Watcher processes the files dropped into a directory.
+type Watcher struct {
+	dir     string
+	process ProcessFunc
+	opts    Options
+	ledger  *Ledger
+}
+
+// New creates a Watcher of dir, creating the done and failed directories
+// and loading the ledger.
+func New(dir string, process ProcessFunc, opts *Options) (*Watcher, error) {
+	if process == nil {
+		return nil, errors.New("process function cannot be nil")
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open watched directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	w := &Watcher{dir: dir, process: process}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.DoneDir == "" {
+		w.opts.DoneDir = filepath.Join(dir, "done")
+	}
+	if w.opts.FailedDir == "" {
+		w.opts.FailedDir = filepath.Join(dir, "failed")
+	}
+	if w.opts.Ledger == "" {
+		w.opts.Ledger = filepath.Join(dir, ".arrowarc-ledger.jsonl")
+	}
+	if len(w.opts.Extensions) == 0 {
+		w.opts.Extensions = DefaultExtensions
+	}
+	if w.opts.SettleTime <= 0 {
+		w.opts.SettleTime = time.Second
+	}
+	for _, d := range []string{w.opts.DoneDir, w.opts.FailedDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", d, err)
+		}
+	}
+	if w.ledger, err = OpenLedger(w.opts.Ledger); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Run processes the files already in the directory, then the files that
+// land in it, until ctx is canceled. Files are processed one at a time, in
+// name order when several are ready together.
+func (w *Watcher) Run(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer fsw.Close()
+	if err := fsw.Add(w.dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", w.dir, err)
+	}
+
+	// pending maps files to the time of their last change. Files found at
+	// startup are ready at once.
+	pending := make(map[string]time.Time)
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", w.dir, err)
+	}
+	for _, e := range entries {
+		if path := filepath.Join(w.dir, e.Name()); w.wanted(path) {
+			pending[path] = time.Time{}
+		}
+	}
+
+	ticker := time.NewTicker(min(w.opts.SettleTime/4, 250*time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+				if w.wanted(event.Name) {
+					pending[event.Name] = time.Now()
+				}
+			} else if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				delete(pending, event.Name)
+			}
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			logging.FromContext(ctx).Warn("file watcher error", "dir", w.dir, "error", err)
+		case now := <-ticker.C:
+			var ready []string
+			for path, changed := range pending {
+				if now.Sub(changed) >= w.opts.SettleTime {
+					ready = append(ready, path)
+				}
+			}
+			sort.Strings(ready)
+			for _, path := range ready {
+				if ctx.Err() != nil {
+					return nil
+				}
+				delete(pending, path)
+				if err := w.handle(ctx, path); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// Close closes the ledger.
+func (w *Watcher) Close() error {
+	return w.ledger.Close()
+}
+
+// wanted reports whether path is a regular, visible file with one of the
+// extensions picked up.
+func (w *Watcher) wanted(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") || !slices.Contains(w.opts.Extensions, strings.ToLower(filepath.Ext(name))) {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// handle processes one file and moves it out of the watched directory.
+// Only ledger and move errors are returned; processing errors send the
+// file to the failed directory.
+func (w *Watcher) handle(ctx context.Context, path string) error {
+	name := filepath.Base(path)
+	sum, size, err := hashFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // removed before it settled
+	}
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", name, err)
+	}
+
+	// Each file is a run of its own, so that its log entries can be told
+	// apart from those of other files.
+	entry := Entry{File: name, SHA256: sum, Size: size, RunID: uuid.NewString()}
+	ctx = logging.WithRunID(ctx, entry.RunID)
+	logger := logging.FromContext(ctx).With("file", name)
+	if prev, ok := w.ledger.Done(sum); ok {
+		logger.Info("skipping file, same content already processed", "previous", prev.File)
+		entry.Status = StatusDuplicate
+	} else {
+		logger.Info("processing file", "bytes", size)
+		if err := w.process(ctx, path); err != nil {
+			if ctx.Err() != nil {
+				// Interrupted: leave the file to be processed on restart.
+				return nil
+			}
+			logger.Error("failed to process file", "error", err)
+			entry.Status, entry.Error = StatusFailed, err.Error()
+		} else {
+			entry.Status = StatusDone
+		}
+	}
+
+	dir := w.opts.DoneDir
+	if entry.Status == StatusFailed {
+		dir = w.opts.FailedDir
+	}
+	if entry.MovedTo, err = moveInto(path, dir); err != nil {
+		return fmt.Errorf("failed to move %s: %w", name, err)
+	}
+	entry.At = time.Now().UTC()
+	return w.ledger.Append(entry)
+}
+
+// hashFile returns the hex SHA-256 and the size of the file at path.
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// moveInto moves the file at path into dir, adding a numeric suffix to its
+// name if dir already has a file of that name.
+func moveInto(path, dir string) (string, error) {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	dst := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(dst); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		dst = filepath.Join(dir, strings.TrimSuffix(name, ext)+"."+strconv.Itoa(i)+ext)
+	}
+	return dst, os.Rename(path, dst)
+}
@@ -0,0 +1,170 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a ProcessFunc that records the content of the files it is
+// given and fails on those containing "bad".
+type recorder struct {
+	mu       sync.Mutex
+	contents []string
+}
+
+func (r *recorder) process(_ context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.contents = append(r.contents, string(data))
+	r.mu.Unlock()
+	if strings.Contains(string(data), "bad") {
+		return errors.New("bad input")
+	}
+	return nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.contents)
+}
+
+func startWatcher(t *testing.T, dir string, r *recorder) (*Watcher, func()) {
+	t.Helper()
+	w, err := New(dir, r.process, &Options{SettleTime: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	return w, func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run() = %v", err)
+		}
+		w.Close()
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherMovesFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "existing.csv"), "a\n1\n")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "ignored")
+
+	r := &recorder{}
+	_, stop := startWatcher(t, dir, r)
+	defer stop()
+
+	waitFor(t, func() bool { return exists(filepath.Join(dir, "done", "existing.csv")) })
+	writeFile(t, filepath.Join(dir, "new.json"), `{"a":2}`)
+	writeFile(t, filepath.Join(dir, "broken.csv"), "bad")
+	waitFor(t, func() bool {
+		return exists(filepath.Join(dir, "done", "new.json")) && exists(filepath.Join(dir, "failed", "broken.csv"))
+	})
+
+	if !exists(filepath.Join(dir, "notes.txt")) {
+		t.Error("files with other extensions should be left alone")
+	}
+	if n := r.count(); n != 3 {
+		t.Errorf("processed %d files, want 3", n)
+	}
+}
+
+func TestWatcherSkipsDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	r := &recorder{}
+	_, stop := startWatcher(t, dir, r)
+
+	writeFile(t, filepath.Join(dir, "a.csv"), "x\n1\n")
+	waitFor(t, func() bool { return exists(filepath.Join(dir, "done", "a.csv")) })
+	writeFile(t, filepath.Join(dir, "a.csv"), "x\n1\n")
+	waitFor(t, func() bool { return exists(filepath.Join(dir, "done", "a.1.csv")) })
+	stop()
+	if n := r.count(); n != 1 {
+		t.Fatalf("processed %d files, want 1", n)
+	}
+
+	// The ledger outlives the watcher.
+	writeFile(t, filepath.Join(dir, "b.csv"), "x\n1\n")
+	_, stop = startWatcher(t, dir, r)
+	defer stop()
+	waitFor(t, func() bool { return exists(filepath.Join(dir, "done", "b.csv")) })
+	if n := r.count(); n != 1 {
+		t.Errorf("processed %d files after restart, want 1", n)
+	}
+
+	l, err := OpenLedger(filepath.Join(dir, ".arrowarc-ledger.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	sum, _, err := hashFile(filepath.Join(dir, "done", "a.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := l.Done(sum); !ok || e.File != "a.csv" {
+		t.Errorf("ledger entry = %+v, %v; want a.csv done", e, ok)
+	}
+}
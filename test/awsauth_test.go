@@ -0,0 +1,166 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	kinesis "github.com/arrowarc/arrowarc/integrations/kinesis"
+	"github.com/arrowarc/arrowarc/pkg/awsauth"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/endpoints"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	kinesisapi "github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeAWS serves STS AssumeRole, S3 objects addressed by path, and Kinesis
+// ListShards, recording the access key signing each S3 and Kinesis
+// request and the parameters of each AssumeRole call.
+type fakeAWS struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	keys    []string
+	assumed []string
+	*httptest.Server
+}
+
+func newFakeAWS(t *testing.T) *fakeAWS {
+	f := &fakeAWS{objects: map[string][]byte{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeAWS) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Method == http.MethodPost && r.URL.Path == "/" && r.Header.Get("X-Amz-Target") == "" {
+		r.ParseForm()
+		f.assumed = append(f.assumed, r.Form.Get("RoleArn")+" "+r.Form.Get("ExternalId"))
+		w.Write([]byte(`<AssumeRoleResponse><AssumeRoleResult><Credentials>
+<AccessKeyId>ROLEKEY</AccessKeyId><SecretAccessKey>rolesecret</SecretAccessKey><SessionToken>token</SessionToken>
+<Expiration>2030-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`))
+		return
+	}
+	_, credential, _ := strings.Cut(r.Header.Get("Authorization"), "Credential=")
+	key, _, _ := strings.Cut(credential, "/")
+	f.keys = append(f.keys, key)
+	switch r.Method {
+	case http.MethodPost:
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.Write([]byte(`{"Shards":[]}`))
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		w.Write(data)
+	}
+}
+
+func TestAWSAuthWorkflowSecrets(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeAWS(t)
+	t.Setenv("ARROWARC_TEST_AWS_ENDPOINT", fake.URL)
+	t.Setenv("ARROWARC_TEST_AWS_SECRET", "basesecret")
+	var cfg config.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+workflow:
+  settings: {parallel_tasks: 1, retry_attempts: 1}
+  integrations:
+    - {name: lake, type: storage, provider: s3, mode: write, uri: "s3://lake/2024/orders.csv", credentials: loader}
+    - {name: missing, type: storage, provider: s3, mode: read, uri: "s3://lake/none.csv", credentials: loader}
+  secrets:
+    - name: loader
+      type: aws_credentials
+      provider: aws
+      aws:
+        access_key_id: BASEKEY
+        secret_access_key: ${ARROWARC_TEST_AWS_SECRET}
+        role_arn: arn:aws:iam::123456789012:role/loader
+        external_id: ext-42
+        region: us-west-2
+        endpoint: ${ARROWARC_TEST_AWS_ENDPOINT}
+`), &cfg))
+	require.NoError(t, cfg.Validate())
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	record, _, err := array.RecordFromJSON(memory.NewGoAllocator(), schema, strings.NewReader(`[{"id":1},{"id":2}]`))
+	require.NoError(t, err)
+	defer record.Release()
+
+	opts := &endpoints.Options{TempDir: t.TempDir()}
+	writer, err := endpoints.NewIntegrationWriter(ctx, &cfg, "lake", schema, opts)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+	require.Equal(t, "id\n1\n2\n", string(fake.objects["/lake/2024/orders.csv"]))
+
+	reader, err := endpoints.NewIntegrationReader(ctx, &cfg, "lake", opts)
+	require.NoError(t, err)
+	got, err := reader.Read()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), got.NumRows())
+	require.NoError(t, reader.Close())
+
+	_, err = endpoints.NewIntegrationReader(ctx, &cfg, "missing", opts)
+	require.ErrorContains(t, err, "NoSuchKey")
+
+	// Kinesis clients take the same settings.
+	settings, err := cfg.AWSAuth("lake")
+	require.NoError(t, err)
+	client, err := kinesis.NewKinesisClient(ctx, awsauth.FromConfig(settings))
+	require.NoError(t, err)
+	_, err = client.ListShards(ctx, &kinesisapi.ListShardsInput{StreamName: aws.String("orders")})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"ROLEKEY", "ROLEKEY", "ROLEKEY", "ROLEKEY"}, fake.keys)
+	require.NotEmpty(t, fake.assumed)
+	require.Equal(t, "arn:aws:iam::123456789012:role/loader ext-42", fake.assumed[0])
+
+	cfg.Workflow.Integrations[0].Credentials = "nobody"
+	require.ErrorContains(t, cfg.Validate(), "no secret named 'nobody'")
+}
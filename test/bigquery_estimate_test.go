@@ -0,0 +1,144 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	bq "cloud.google.com/go/bigquery"
+	bigquery "github.com/arrowarc/arrowarc/integrations/bigquery"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/endpoints"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"gopkg.in/yaml.v3"
+)
+
+const tebibyte = 1 << 40
+
+// fakeEstimateAPI serves dry-run query jobs scanning a TiB, and the
+// metadata of tables holding two.
+func fakeEstimateAPI(t *testing.T) []option.ClientOption {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/jobs"):
+			var job map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if dryRun, _ := job["configuration"].(map[string]any)["dryRun"].(bool); !dryRun {
+				http.Error(w, "only dry runs are expected", http.StatusBadRequest)
+				return
+			}
+			job["status"] = map[string]any{"state": "DONE"}
+			job["statistics"] = map[string]any{"totalBytesProcessed": "1099511627776"}
+			writeJSON(w, job)
+		case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/tables/"):
+			writeJSON(w, map[string]any{"numBytes": "2199023255552", "numRows": "1000"})
+		default:
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusNotImplemented)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return []option.ClientOption{option.WithEndpoint(srv.URL + "/bigquery/v2/"), option.WithoutAuthentication()}
+}
+
+func TestBigQueryEstimates(t *testing.T) {
+	ctx := context.Background()
+	opts := fakeEstimateAPI(t)
+	client, err := bq.NewClient(ctx, "p", opts...)
+	require.NoError(t, err)
+	defer client.Close()
+
+	query, err := bigquery.EstimateQuery(ctx, client, "SELECT * FROM d.t")
+	require.NoError(t, err)
+	require.Equal(t, bigquery.OperationQuery, query.Operation)
+	require.Equal(t, int64(tebibyte), query.Bytes)
+	require.InDelta(t, bigquery.DefaultPricing.QueryPerTiB, query.Cost, 1e-9)
+
+	read, err := bigquery.EstimateTableRead(ctx, client, "d", "t")
+	require.NoError(t, err)
+	require.Equal(t, "p.d.t", read.Target)
+	require.Equal(t, int64(1000), read.Rows)
+	require.InDelta(t, 2*bigquery.DefaultPricing.ReadPerTiB, read.Cost, 1e-9)
+
+	write := bigquery.EstimateWrite("p.d.t", 1<<30, false)
+	require.Equal(t, bigquery.OperationStorageWrite, write.Operation)
+	require.InDelta(t, bigquery.DefaultPricing.WritePerGiB, write.Cost, 1e-9)
+	require.NotEmpty(t, write.Quotas)
+	load := bigquery.EstimateWrite("p.d.t", 1<<30, true)
+	require.Equal(t, bigquery.OperationLoadJob, load.Operation)
+	require.Zero(t, load.Cost)
+
+	estimate, err := endpoints.EstimateWrite("parquet://out.parquet", 0)
+	require.NoError(t, err)
+	require.Nil(t, estimate)
+}
+
+func TestEstimateWorkflow(t *testing.T) {
+	opts := &endpoints.Options{ClientOptions: fakeEstimateAPI(t)}
+	var cfg config.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+workflow:
+  integrations:
+    - {name: warehouse, type: database, mode: read, uri: "bq://p"}
+    - {name: orders, type: database, mode: read, uri: "bq://p/d/orders"}
+    - {name: lake, type: storage, mode: write, uri: "out.parquet"}
+    - {name: copy, type: database, mode: write, uri: "bq://p/d/copy"}
+  tasks:
+    - {name: export, source: warehouse, destination: lake, query: "SELECT 1"}
+    - {name: backfill, source: orders, destination: copy, bigquery: {mode: load_job, staging_bucket: b}}
+    - {name: local, source: lake, destination: lake}
+`), &cfg))
+
+	estimates, err := endpoints.EstimateWorkflow(context.Background(), &cfg, opts)
+	require.NoError(t, err)
+	require.Len(t, estimates, 2)
+
+	require.Equal(t, "export", estimates[0].Task)
+	require.Len(t, estimates[0].Estimates, 1)
+	require.Equal(t, bigquery.OperationQuery, estimates[0].Estimates[0].Operation)
+	require.InDelta(t, bigquery.DefaultPricing.QueryPerTiB, estimates[0].Cost, 1e-9)
+
+	require.Equal(t, "backfill", estimates[1].Task)
+	require.Len(t, estimates[1].Estimates, 2)
+	require.Equal(t, bigquery.OperationRead, estimates[1].Estimates[0].Operation)
+	write := estimates[1].Estimates[1]
+	require.Equal(t, bigquery.OperationLoadJob, write.Operation)
+	require.Equal(t, "p.d.copy", write.Target)
+	require.Equal(t, int64(2*tebibyte), write.Bytes)
+}
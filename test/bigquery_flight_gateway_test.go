@@ -0,0 +1,204 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	storagepb "cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	bigquery "github.com/arrowarc/arrowarc/integrations/bigquery"
+	"github.com/arrowarc/arrowarc/pkg/retry"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// startBigQueryGateway serves a BigQueryFlightServer writing to a fake
+// Storage Write API and returns a Flight client connected to it.
+func startBigQueryGateway(t *testing.T, opts *bigquery.BigQueryFlightServerOptions) (*fakeBigQueryWrite, flight.Client) {
+	t.Helper()
+	fake, bqAddr := startFakeBigQueryWrite(t)
+	client, err := bigquery.NewBigQueryWriteClientWithOptions(context.Background(), nil,
+		option.WithEndpoint(bqAddr),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	srv := flight.NewServerWithMiddleware(nil)
+	require.NoError(t, srv.Init("localhost:0"))
+	srv.RegisterFlightService(bigquery.NewBigQueryFlightServer(client, opts))
+	go srv.Serve()
+	t.Cleanup(srv.Shutdown)
+
+	cl, err := flight.NewClientWithMiddleware(srv.Addr().String(), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { cl.Close() })
+	return fake, cl
+}
+
+// putRecords sends the records to path in one DoPut call and returns the
+// results the server sent.
+func putRecords(cl flight.Client, path []string, records ...arrow.Record) ([]*flight.PutResult, error) {
+	stream, err := cl.DoPut(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	writer := flight.NewRecordWriter(stream, ipc.WithSchema(records[0].Schema()))
+	writer.SetFlightDescriptor(&flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: path})
+	for _, rec := range records {
+		if err := writer.Write(rec); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	var results []*flight.PutResult
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return results, err
+		}
+		results = append(results, res)
+	}
+}
+
+func TestBigQueryFlightServerCommitsEachPut(t *testing.T) {
+	opts := bigquery.NewDefaultBigQueryFlightServerOptions()
+	opts.ProjectID = "p"
+	fake, cl := startBigQueryGateway(t, opts)
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	mem := memory.NewGoAllocator()
+	var records []arrow.Record
+	for i := int64(0); i < 3; i++ {
+		rec := writeModeRecord(mem, schema, i*3)
+		defer rec.Release()
+		records = append(records, rec)
+	}
+
+	for _, path := range [][]string{{"d", "t"}, {"other", "d", "t"}} {
+		results, err := putRecords(cl, path, records...)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+
+		var result bigquery.BigQueryPutResult
+		require.NoError(t, json.Unmarshal(results[0].AppMetadata, &result))
+		require.Equal(t, int64(9), result.Rows)
+
+		fake.mu.Lock()
+		require.Equal(t, storagepb.WriteStream_PENDING, fake.streams[result.Stream])
+		require.Contains(t, fake.committed, result.Stream)
+		fake.mu.Unlock()
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	require.Len(t, fake.committed, 2, "each call is committed on its own")
+	require.Regexp(t, "^projects/p/datasets/d/tables/t/", fake.committed[0])
+	require.Regexp(t, "^projects/other/datasets/d/tables/t/", fake.committed[1])
+}
+
+func TestBigQueryFlightServerRejectsStreams(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	other := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.BinaryTypes.String}}, nil)
+	opts := bigquery.NewDefaultBigQueryFlightServerOptions()
+	opts.Schemas = map[string]*arrow.Schema{"d.t": schema}
+	fake, cl := startBigQueryGateway(t, opts)
+
+	rec := writeModeRecord(memory.NewGoAllocator(), schema, 0)
+	defer rec.Release()
+
+	_, err := putRecords(cl, []string{"d", "t"}, rec)
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "no default project: %v", err)
+
+	_, err = putRecords(cl, []string{"p", "d", "missing"}, rec)
+	require.Equal(t, codes.PermissionDenied, status.Code(err), "%v", err)
+
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), other)
+	b.Field(0).(*array.StringBuilder).Append("a")
+	otherRec := b.NewRecord()
+	b.Release()
+	defer otherRec.Release()
+	_, err = putRecords(cl, []string{"p", "d", "t"}, otherRec)
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "%v", err)
+	require.ErrorContains(t, err, "schema mismatch")
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	require.Empty(t, fake.streams, "rejected calls open no write stream")
+}
+
+func TestBigQueryFlightServerAbandonsFailedPut(t *testing.T) {
+	opts := bigquery.NewDefaultBigQueryFlightServerOptions()
+	opts.ProjectID = "p"
+	opts.Retry = retry.Never()
+	fake, cl := startBigQueryGateway(t, opts)
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	mem := memory.NewGoAllocator()
+	first := writeModeRecord(mem, schema, 0)
+	defer first.Release()
+	second := writeModeRecord(mem, schema, 3)
+	defer second.Release()
+
+	fake.mu.Lock()
+	fake.dropAfterAppend = true
+	fake.mu.Unlock()
+
+	_, err := putRecords(cl, []string{"d", "t"}, first, second)
+	require.Error(t, err)
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	require.Len(t, fake.streams, 1)
+	require.Empty(t, fake.committed, "a failed call commits nothing")
+	for name := range fake.streams {
+		require.False(t, fake.finalized[name])
+	}
+}
@@ -0,0 +1,276 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bq "cloud.google.com/go/bigquery"
+	"cloud.google.com/go/storage"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	bigquery "github.com/arrowarc/arrowarc/integrations/bigquery"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeLoadJobAPI serves the parts of the GCS and BigQuery REST APIs a
+// BigQueryLoadWriter uses: object uploads and deletes, and load jobs that
+// finish after a number of polls.
+type fakeLoadJobAPI struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	jobs    []map[string]any
+	polls   int
+	// runningPolls is the number of status checks reporting a job running.
+	runningPolls int
+	// jobError fails the jobs with this message.
+	jobError string
+}
+
+func (f *fakeLoadJobAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/upload/storage/v1/b/"):
+		bucket := strings.Split(strings.TrimPrefix(r.URL.Path, "/upload/storage/v1/b/"), "/")[0]
+		name, data, err := readMultipartUpload(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.objects[bucket+"/"+name] = data
+		writeJSON(w, map[string]any{"bucket": bucket, "name": name, "size": fmt.Sprint(len(data))})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/storage/v1/b/"):
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/storage/v1/b/"), "/o/", 2)
+		delete(f.objects, parts[0]+"/"+parts[1])
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/jobs"):
+		var job map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.jobs = append(f.jobs, job)
+		job["status"] = map[string]any{"state": "RUNNING"}
+		writeJSON(w, job)
+	case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/jobs/"):
+		f.polls++
+		job := f.jobs[len(f.jobs)-1]
+		status := map[string]any{"state": "DONE"}
+		if f.polls <= f.runningPolls {
+			status["state"] = "RUNNING"
+		} else if f.jobError != "" {
+			status["errorResult"] = map[string]any{"reason": "invalid", "message": f.jobError}
+		}
+		writeJSON(w, map[string]any{"jobReference": job["jobReference"], "configuration": job["configuration"], "status": status})
+	default:
+		http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusNotImplemented)
+	}
+}
+
+func readMultipartUpload(r *http.Request) (string, []byte, error) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "", nil, err
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	part, err := mr.NextPart()
+	if err != nil {
+		return "", nil, err
+	}
+	var meta struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(part).Decode(&meta); err != nil {
+		return "", nil, err
+	}
+	part, err = mr.NextPart()
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := io.ReadAll(part)
+	return meta.Name, data, err
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func startFakeLoadJobAPI(t *testing.T) (*fakeLoadJobAPI, *bq.Client, *storage.Client) {
+	t.Helper()
+	fake := &fakeLoadJobAPI{objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	bqClient, err := bq.NewClient(ctx, "p", option.WithEndpoint(srv.URL+"/bigquery/v2/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+	t.Cleanup(func() { bqClient.Close() })
+	gcsClient, err := storage.NewClient(ctx, option.WithEndpoint(srv.URL+"/storage/v1/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+	t.Cleanup(func() { gcsClient.Close() })
+	return fake, bqClient, gcsClient
+}
+
+func TestBigQueryLoadWriterStagesAndLoads(t *testing.T) {
+	fake, bqClient, gcsClient := startFakeLoadJobAPI(t)
+	fake.runningPolls = 2
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+	opts := bigquery.NewDefaultBigQueryLoadOptions("staging")
+	opts.Prefix = "backfill"
+	opts.FileRows = 5
+	opts.Partition = "20240101"
+	opts.WriteDisposition = bq.WriteTruncate
+	opts.PollInterval = 10 * time.Millisecond
+	w, err := bigquery.NewBigQueryLoadWriter(context.Background(), bqClient, gcsClient, "d", "t", schema, opts)
+	require.NoError(t, err)
+
+	mem := memory.NewGoAllocator()
+	for i := int64(0); i < 5; i++ {
+		rec := writeModeRecord(mem, schema, i*3)
+		require.NoError(t, w.Write(rec))
+		rec.Release()
+	}
+	staged := w.StagedFiles()
+	require.Len(t, staged, 3, "15 rows in files closed once they reach 5 rows")
+	fake.mu.Lock()
+	require.Len(t, fake.objects, 2, "the last file is uploaded on Close")
+	require.Empty(t, fake.jobs)
+	fake.mu.Unlock()
+
+	require.NoError(t, w.Close())
+	require.NotEmpty(t, w.JobID())
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	require.Len(t, fake.jobs, 1, "all files are loaded in one job")
+	require.Equal(t, 3, fake.polls, "the job is polled until done")
+	load := fake.jobs[0]["configuration"].(map[string]any)["load"].(map[string]any)
+	require.Equal(t, "PARQUET", load["sourceFormat"])
+	require.Equal(t, "WRITE_TRUNCATE", load["writeDisposition"])
+	require.Equal(t, "t$20240101", load["destinationTable"].(map[string]any)["tableId"])
+	var uris []string
+	for _, uri := range load["sourceUris"].([]any) {
+		uris = append(uris, uri.(string))
+	}
+	require.Equal(t, staged, uris)
+	require.Regexp(t, "^gs://staging/backfill/t-[0-9T]+-[0-9a-f]+/part-00000.parquet$", uris[0])
+	require.Empty(t, fake.objects, "loaded files are deleted")
+}
+
+func TestBigQueryLoadWriterKeepsFilesOfFailedLoad(t *testing.T) {
+	fake, bqClient, gcsClient := startFakeLoadJobAPI(t)
+	fake.jobError = "bad parquet"
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+	opts := bigquery.NewDefaultBigQueryLoadOptions("staging")
+	opts.PollInterval = 10 * time.Millisecond
+	w, err := bigquery.NewBigQueryLoadWriter(context.Background(), bqClient, gcsClient, "d", "t", schema, opts)
+	require.NoError(t, err)
+	rec := writeModeRecord(memory.NewGoAllocator(), schema, 0)
+	defer rec.Release()
+	require.NoError(t, w.Write(rec))
+
+	err = w.Close()
+	require.ErrorContains(t, err, "bad parquet")
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	require.Len(t, fake.objects, 1, "files of a failed load are kept")
+}
+
+func TestConfigBigQueryLoadJobTask(t *testing.T) {
+	var cfg config.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+workflow:
+  settings:
+    parallel_tasks: 1
+    retry_attempts: 1
+  tasks:
+    - name: backfill
+      source: lake
+      destination: warehouse
+      conversion: parquet_to_bigquery
+      bigquery:
+        mode: load_job
+        staging_bucket: staging
+        staging_prefix: backfills/
+        partition: "20240101"
+        write_disposition: truncate
+        poll_interval: 30s
+        timeout: 2h
+    - name: stream
+      source: lake
+      destination: warehouse
+      conversion: parquet_to_bigquery
+`), &cfg))
+	require.NoError(t, cfg.Validate())
+
+	load := cfg.Workflow.Tasks[0].BigQuery
+	require.True(t, load.LoadJob())
+	require.False(t, cfg.Workflow.Tasks[1].BigQuery.LoadJob(), "tasks stream by default")
+
+	opts, err := bigquery.BigQueryLoadOptionsFromConfig(load)
+	require.NoError(t, err)
+	require.Equal(t, "staging", opts.Bucket)
+	require.Equal(t, "backfills/", opts.Prefix)
+	require.Equal(t, "20240101", opts.Partition)
+	require.Equal(t, bq.WriteTruncate, opts.WriteDisposition)
+	require.Equal(t, 30*time.Second, opts.PollInterval)
+	require.Equal(t, 2*time.Hour, opts.Timeout)
+
+	for _, bad := range []config.BigQueryTaskSettings{
+		{Mode: "bulk"},
+		{Mode: config.BigQueryModeLoadJob},
+		{Mode: config.BigQueryModeLoadJob, StagingBucket: "b", Partition: "2024-01-01"},
+		{Mode: config.BigQueryModeLoadJob, StagingBucket: "b", WriteDisposition: "replace"},
+		{Mode: config.BigQueryModeLoadJob, StagingBucket: "b", PollInterval: "often"},
+		{StagingBucket: "b"},
+	} {
+		settings := bad
+		cfg.Workflow.Tasks[0].BigQuery = &settings
+		require.Error(t, cfg.Validate(), "%+v", bad)
+	}
+}
@@ -0,0 +1,165 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"testing"
+
+	bq "cloud.google.com/go/bigquery"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/extensions"
+	bigquery "github.com/arrowarc/arrowarc/integrations/bigquery"
+	"github.com/stretchr/testify/require"
+)
+
+func bigQueryTableArrowSchema() *arrow.Schema {
+	return arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "created_at", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}},
+		{Name: "local_at", Type: &arrow.TimestampType{Unit: arrow.Microsecond}, Nullable: true},
+		{Name: "day", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+		{Name: "price", Type: &arrow.Decimal128Type{Precision: 12, Scale: 2}, Nullable: true},
+		{Name: "wide", Type: &arrow.Decimal256Type{Precision: 50, Scale: 20}, Nullable: true},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		{Name: "attrs", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int32), Nullable: true},
+		{Name: "source", Type: arrow.StructOf(
+			arrow.Field{Name: "host", Type: arrow.BinaryTypes.String, Nullable: true},
+			arrow.Field{Name: "ports", Type: arrow.ListOf(arrow.PrimitiveTypes.Int32)},
+		), Nullable: true},
+		{Name: "request_id", Type: extensions.NewUUIDType(), Nullable: true},
+	}, nil)
+}
+
+func TestArrowSchemaToBigQuery(t *testing.T) {
+	schema, err := bigquery.ArrowSchemaToBigQuery(bigQueryTableArrowSchema())
+	require.NoError(t, err)
+	require.Len(t, schema, 11)
+
+	types := map[string]bq.FieldType{}
+	for _, fs := range schema {
+		types[fs.Name] = fs.Type
+	}
+	require.Equal(t, map[string]bq.FieldType{
+		"id":         bq.IntegerFieldType,
+		"name":       bq.StringFieldType,
+		"created_at": bq.TimestampFieldType,
+		"local_at":   bq.DateTimeFieldType,
+		"day":        bq.DateFieldType,
+		"price":      bq.NumericFieldType,
+		"wide":       bq.BigNumericFieldType,
+		"tags":       bq.StringFieldType,
+		"attrs":      bq.RecordFieldType,
+		"source":     bq.RecordFieldType,
+		"request_id": bq.StringFieldType,
+	}, types)
+
+	require.True(t, schema[0].Required)
+	require.False(t, schema[1].Required)
+	require.Equal(t, int64(12), schema[5].Precision)
+	require.Equal(t, int64(2), schema[5].Scale)
+
+	tags := schema[7]
+	require.True(t, tags.Repeated)
+	require.False(t, tags.Required)
+
+	attrs := schema[8]
+	require.True(t, attrs.Repeated)
+	require.Len(t, attrs.Schema, 2)
+	require.Equal(t, "key", attrs.Schema[0].Name)
+	require.True(t, attrs.Schema[0].Required)
+	require.Equal(t, bq.IntegerFieldType, attrs.Schema[1].Type)
+
+	source := schema[9]
+	require.False(t, source.Repeated)
+	require.Len(t, source.Schema, 2)
+	require.Equal(t, bq.StringFieldType, source.Schema[0].Type)
+	require.True(t, source.Schema[1].Repeated)
+	require.Equal(t, bq.IntegerFieldType, source.Schema[1].Type)
+}
+
+func TestArrowSchemaToBigQueryRejectsNestedLists(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "matrix", Type: arrow.ListOf(arrow.ListOf(arrow.PrimitiveTypes.Float64))},
+	}, nil)
+	_, err := bigquery.ArrowSchemaToBigQuery(schema)
+	require.ErrorContains(t, err, "nested repeated")
+}
+
+func TestNewBigQueryTableMetadata(t *testing.T) {
+	schema := bigQueryTableArrowSchema()
+
+	md, err := bigquery.NewBigQueryTableMetadata(schema, &bigquery.BigQueryTableOptions{
+		TimePartitioning: &bq.TimePartitioning{Type: bq.DayPartitioningType, Field: "created_at"},
+		Clustering:       []string{"name", "id"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "created_at", md.TimePartitioning.Field)
+	require.Equal(t, []string{"name", "id"}, md.Clustering.Fields)
+	require.Len(t, md.Schema, 11)
+
+	tests := []struct {
+		description string
+		opts        *bigquery.BigQueryTableOptions
+		want        string
+	}{
+		{
+			description: "partition column missing",
+			opts:        &bigquery.BigQueryTableOptions{TimePartitioning: &bq.TimePartitioning{Field: "updated_at"}},
+			want:        "not in the schema",
+		},
+		{
+			description: "partition column of the wrong type",
+			opts:        &bigquery.BigQueryTableOptions{TimePartitioning: &bq.TimePartitioning{Field: "name"}},
+			want:        "has type STRING",
+		},
+		{
+			description: "range partitioning on a string",
+			opts:        &bigquery.BigQueryTableOptions{RangePartitioning: &bq.RangePartitioning{Field: "name"}},
+			want:        "has type STRING",
+		},
+		{
+			description: "clustering on a repeated column",
+			opts:        &bigquery.BigQueryTableOptions{Clustering: []string{"tags"}},
+			want:        "top-level scalar",
+		},
+		{
+			description: "too many clustering columns",
+			opts:        &bigquery.BigQueryTableOptions{Clustering: []string{"id", "name", "day", "price", "created_at"}},
+			want:        "at most 4",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			_, err := bigquery.NewBigQueryTableMetadata(schema, test.opts)
+			require.ErrorContains(t, err, test.want)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	storagepb "cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	bigquery "github.com/arrowarc/arrowarc/integrations/bigquery"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeBigQueryWrite is an in-memory Storage Write API that checks offsets
+// the way the service does.
+type fakeBigQueryWrite struct {
+	storagepb.UnimplementedBigQueryWriteServer
+
+	mu        sync.Mutex
+	streams   map[string]storagepb.WriteStream_Type
+	rows      map[string]int64
+	finalized map[string]bool
+	committed []string
+	// dropAfterAppend makes the server store the next append and then fail
+	// the connection before acknowledging it.
+	dropAfterAppend bool
+}
+
+func newFakeBigQueryWrite() *fakeBigQueryWrite {
+	return &fakeBigQueryWrite{
+		streams:   map[string]storagepb.WriteStream_Type{},
+		rows:      map[string]int64{},
+		finalized: map[string]bool{},
+	}
+}
+
+func (f *fakeBigQueryWrite) CreateWriteStream(_ context.Context, req *storagepb.CreateWriteStreamRequest) (*storagepb.WriteStream, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := fmt.Sprintf("%s/streams/s%d", req.GetParent(), len(f.streams))
+	f.streams[name] = req.GetWriteStream().GetType()
+	return &storagepb.WriteStream{Name: name, Type: req.GetWriteStream().GetType()}, nil
+}
+
+func (f *fakeBigQueryWrite) AppendRows(stream storagepb.BigQueryWrite_AppendRowsServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		f.mu.Lock()
+		name := req.GetWriteStream()
+		n := int64(len(req.GetProtoRows().GetRows().GetSerializedRows()))
+		resp := &storagepb.AppendRowsResponse{WriteStream: name}
+		switch {
+		case f.finalized[name]:
+			resp.Response = &storagepb.AppendRowsResponse_Error{Error: &statuspb.Status{Code: int32(codes.FailedPrecondition), Message: "stream finalized"}}
+		case req.GetOffset() != nil && req.GetOffset().GetValue() < f.rows[name]:
+			resp.Response = &storagepb.AppendRowsResponse_Error{Error: &statuspb.Status{Code: int32(codes.AlreadyExists), Message: "offset already written"}}
+		case req.GetOffset() != nil && req.GetOffset().GetValue() > f.rows[name]:
+			resp.Response = &storagepb.AppendRowsResponse_Error{Error: &statuspb.Status{Code: int32(codes.OutOfRange), Message: "offset beyond end of stream"}}
+		default:
+			f.rows[name] += n
+			resp.Response = &storagepb.AppendRowsResponse_AppendResult_{AppendResult: &storagepb.AppendRowsResponse_AppendResult{}}
+		}
+		drop := f.dropAfterAppend
+		f.dropAfterAppend = false
+		f.mu.Unlock()
+
+		if drop {
+			return status.Error(codes.Unavailable, "connection reset")
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *fakeBigQueryWrite) FinalizeWriteStream(_ context.Context, req *storagepb.FinalizeWriteStreamRequest) (*storagepb.FinalizeWriteStreamResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.finalized[req.GetName()] = true
+	return &storagepb.FinalizeWriteStreamResponse{RowCount: f.rows[req.GetName()]}, nil
+}
+
+func (f *fakeBigQueryWrite) BatchCommitWriteStreams(_ context.Context, req *storagepb.BatchCommitWriteStreamsRequest) (*storagepb.BatchCommitWriteStreamsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, name := range req.GetWriteStreams() {
+		if !f.finalized[name] {
+			return &storagepb.BatchCommitWriteStreamsResponse{
+				StreamErrors: []*storagepb.StorageError{{Entity: name, ErrorMessage: "stream not finalized"}},
+			}, nil
+		}
+	}
+	f.committed = append(f.committed, req.GetWriteStreams()...)
+	return &storagepb.BatchCommitWriteStreamsResponse{CommitTime: timestamppb.Now()}, nil
+}
+
+func startFakeBigQueryWrite(t *testing.T) (*fakeBigQueryWrite, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fake := newFakeBigQueryWrite()
+	server := grpc.NewServer()
+	storagepb.RegisterBigQueryWriteServer(server, fake)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+	return fake, lis.Addr().String()
+}
+
+func writeModeRecord(mem memory.Allocator, schema *arrow.Schema, start int64) arrow.Record {
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	for i := start; i < start+3; i++ {
+		b.Field(0).(*array.Int64Builder).Append(i)
+	}
+	return b.NewRecord()
+}
+
+func TestBigQueryWriterStreamModes(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+	tests := []struct {
+		mode       bigquery.WriteStreamMode
+		dropFirst  bool
+		wantStream string
+		wantCommit bool
+	}{
+		{mode: bigquery.WriteStreamModeDefault, wantStream: "_default"},
+		{mode: bigquery.WriteStreamModeCommitted},
+		{mode: bigquery.WriteStreamModePending, wantCommit: true},
+		{mode: bigquery.WriteStreamModePending, dropFirst: true, wantCommit: true},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/drop=%v", test.mode, test.dropFirst), func(t *testing.T) {
+			fake, addr := startFakeBigQueryWrite(t)
+			ctx := context.Background()
+
+			client, err := bigquery.NewBigQueryWriteClientWithOptions(ctx, schema,
+				option.WithEndpoint(addr),
+				option.WithoutAuthentication(),
+				option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+			)
+			require.NoError(t, err)
+			defer client.Close()
+
+			opts := bigquery.NewDefaultBigQueryWriteOptions()
+			opts.WriteStreamMode = test.mode
+			writer, err := bigquery.NewBigQueryRecordWriter(ctx, client, "p", "d", "t", opts)
+			require.NoError(t, err)
+			if test.wantStream != "" {
+				require.Equal(t, "projects/p/datasets/d/tables/t/streams/"+test.wantStream, writer.StreamName())
+			}
+
+			fake.mu.Lock()
+			fake.dropAfterAppend = test.dropFirst
+			fake.mu.Unlock()
+
+			mem := memory.NewGoAllocator()
+			for i := int64(0); i < 3; i++ {
+				rec := writeModeRecord(mem, schema, i*3)
+				require.NoError(t, writer.Write(rec))
+				rec.Release()
+			}
+
+			fake.mu.Lock()
+			require.Empty(t, fake.committed, "nothing is committed before Close")
+			require.Equal(t, int64(3), fake.rows[writer.StreamName()], "a resent append must not be written twice")
+			fake.mu.Unlock()
+
+			require.NoError(t, writer.Close())
+
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			if test.wantCommit {
+				require.Equal(t, []string{writer.StreamName()}, fake.committed)
+			} else {
+				require.Empty(t, fake.committed)
+			}
+			require.Equal(t, test.mode != bigquery.WriteStreamModeDefault, fake.finalized[writer.StreamName()])
+		})
+	}
+}
@@ -76,7 +76,11 @@ func TestConvertAvroToParquet(t *testing.T) {
 			defer cancel()
 
 			// Perform the conversion
-			metrics, err := convert.ConvertAvroToParquet(ctx, test.avroFilePath, test.parquetFilePath, test.chunkSize, compress.Codecs.Snappy)
+			metrics, err := convert.ConvertAvroToParquetWithOptions(ctx, test.avroFilePath, test.parquetFilePath, &convert.AvroToParquetOptions{
+				ChunkSize:   test.chunkSize,
+				Compression: &test.compressionCodec,
+				Concurrency: 1,
+			})
 
 			// Assert no error and non-nil metrics
 			assert.NoError(t, err, "Error should be nil when converting Avro to Parquet")
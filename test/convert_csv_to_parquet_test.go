@@ -33,6 +33,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -44,8 +45,10 @@ import (
 func TestConvertCSVToParquet(t *testing.T) {
 	t.Parallel() // Parallelize the top-level test
 
+	dir := t.TempDir()
+
 	// Generate a sample CSV file for testing with header
-	csvFilePathWithHeader := fmt.Sprintf("sample_test_with_header_%d.csv", time.Now().UnixNano())
+	csvFilePathWithHeader := filepath.Join(dir, "sample_test_with_header.csv")
 	schema := arrow.NewSchema([]arrow.Field{
 		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
 		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
@@ -60,7 +63,7 @@ func TestConvertCSVToParquet(t *testing.T) {
 	assert.NoError(t, err, "Error should be nil when generating CSV file with header")
 
 	// Generate a sample CSV file for testing without header
-	csvFilePathWithoutHeader := fmt.Sprintf("sample_test_without_header_%d.csv", time.Now().UnixNano())
+	csvFilePathWithoutHeader := filepath.Join(dir, "sample_test_without_header.csv")
 	csvContentWithoutHeader := `1,John
 2,Jane
 3,Jack`
@@ -68,12 +71,6 @@ func TestConvertCSVToParquet(t *testing.T) {
 	err = os.WriteFile(csvFilePathWithoutHeader, []byte(csvContentWithoutHeader), 0644)
 	assert.NoError(t, err, "Error should be nil when generating CSV file without header")
 
-	// Use t.Cleanup to ensure the files are removed after all tests complete
-	t.Cleanup(func() {
-		os.Remove(csvFilePathWithHeader)
-		os.Remove(csvFilePathWithoutHeader)
-	})
-
 	tests := []struct {
 		csvFilePath      string
 		parquetFilePath  string
@@ -87,7 +84,7 @@ func TestConvertCSVToParquet(t *testing.T) {
 	}{
 		{
 			csvFilePath:      csvFilePathWithHeader,
-			parquetFilePath:  fmt.Sprintf("output_test_1_%d.parquet", time.Now().UnixNano()),
+			parquetFilePath:  filepath.Join(dir, "output_test_1.parquet"),
 			schema:           schema,
 			hasHeader:        true,
 			chunkSize:        1024,
@@ -98,7 +95,7 @@ func TestConvertCSVToParquet(t *testing.T) {
 		},
 		{
 			csvFilePath:      csvFilePathWithoutHeader,
-			parquetFilePath:  fmt.Sprintf("output_test_2_%d.parquet", time.Now().UnixNano()),
+			parquetFilePath:  filepath.Join(dir, "output_test_2.parquet"),
 			schema:           schema,
 			hasHeader:        false,
 			chunkSize:        2048,
@@ -117,15 +114,36 @@ func TestConvertCSVToParquet(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 
-			metrics, err := converter.ConvertCSVToParquet(ctx, test.csvFilePath, test.parquetFilePath, test.hasHeader, 100000, ',', []string{}, true)
+			metrics, err := converter.ConvertCSVToParquetWithOptions(ctx, test.csvFilePath, test.parquetFilePath, &converter.CSVToParquetOptions{
+				HasHeader:        test.hasHeader,
+				ChunkSize:        100000,
+				Delimiter:        ',',
+				StringsCanBeNull: true,
+				Concurrency:      1,
+			})
 			assert.NoError(t, err, "Error should be nil when converting CSV to Parquet")
 			fmt.Printf("Conversion completed. Summary: %s\n", metrics)
 			_, err = os.Stat(test.parquetFilePath)
 			assert.NoError(t, err, "Parquet file should be created")
-
-			t.Cleanup(func() {
-				os.Remove(test.parquetFilePath)
-			})
 		})
 	}
 }
+
+func TestConvertCSVToParquetPositional(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	csvFilePath := filepath.Join(dir, "positional.csv")
+	err := os.WriteFile(csvFilePath, []byte("id,name\n1,John\n2,Jane\n"), 0644)
+	assert.NoError(t, err)
+	parquetFilePath := filepath.Join(dir, "positional.parquet")
+
+	// The deprecated positional form builds the options and delegates.
+	_, err = converter.ConvertCSVToParquet(context.Background(), csvFilePath, parquetFilePath,
+		true, 1024, ',', []string{"", "NULL"}, true, 1, nil, false, nil, false, false)
+	assert.NoError(t, err)
+
+	_, err = converter.ConvertCSVToParquet(context.Background(), csvFilePath, parquetFilePath,
+		true, 0, ',', nil, true, 1, nil, false, nil, false, false)
+	assert.ErrorContains(t, err, "chunk size")
+}
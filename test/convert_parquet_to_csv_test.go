@@ -33,6 +33,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -42,20 +43,14 @@ import (
 )
 
 func TestConvertParquetToCSV(t *testing.T) {
-
-	parquetFilePath := "sample.parquet"
-	csvFilePathWithHeader := "output_test_with_header.csv"
-	csvFilePathWithoutHeader := "output_test_without_header.csv"
+	dir := t.TempDir()
+	parquetFilePath := filepath.Join(dir, "sample.parquet")
+	csvFilePathWithHeader := filepath.Join(dir, "output_test_with_header.csv")
+	csvFilePathWithoutHeader := filepath.Join(dir, "output_test_without_header.csv")
 
 	err := generator.GenerateParquetFile(parquetFilePath, 100*1024, false) // 100 KB, simple structure
 	assert.NoError(t, err, "Error should be nil when generating Parquet file")
 
-	t.Cleanup(func() {
-		os.Remove(parquetFilePath)
-		os.Remove(csvFilePathWithHeader)
-		os.Remove(csvFilePathWithoutHeader)
-	})
-
 	tests := []struct {
 		parquetFilePath string
 		csvFilePath     string
@@ -104,7 +99,17 @@ func TestConvertParquetToCSV(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 
-			metrics, err := converter.ConvertParquetToCSV(ctx, test.parquetFilePath, test.csvFilePath, test.memoryMap, test.chunkSize, test.columns, test.rowGroups, test.parallel, test.delimiter, test.includeHeader, test.nullValue, nil, nil)
+			metrics, err := converter.ConvertParquetToCSVWithOptions(ctx, test.parquetFilePath, test.csvFilePath, &converter.ParquetToCSVOptions{
+				MemoryMap:     test.memoryMap,
+				ChunkSize:     test.chunkSize,
+				Columns:       test.columns,
+				RowGroups:     test.rowGroups,
+				Parallel:      test.parallel,
+				Concurrency:   1,
+				Delimiter:     test.delimiter,
+				IncludeHeader: test.includeHeader,
+				NullValue:     test.nullValue,
+			})
 			assert.NoError(t, err, "Error should be nil when converting Parquet to CSV")
 			fmt.Println(metrics)
 
@@ -112,10 +117,6 @@ func TestConvertParquetToCSV(t *testing.T) {
 
 			_, err = os.Stat(test.csvFilePath)
 			assert.NoError(t, err, "CSV file should be created")
-
-			t.Cleanup(func() {
-				os.Remove(test.csvFilePath)
-			})
 		})
 	}
 }
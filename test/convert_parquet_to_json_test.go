@@ -33,6 +33,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -42,20 +43,14 @@ import (
 )
 
 func TestConvertParquetToJSON(t *testing.T) {
-
-	parquetFilePath := "sample_test.parquet"
-	jsonFilePathWithStructs := "output_test_with_structs.json"
-	jsonFilePathWithoutStructs := "output_test_without_structs.json"
+	dir := t.TempDir()
+	parquetFilePath := filepath.Join(dir, "sample_test.parquet")
+	jsonFilePathWithStructs := filepath.Join(dir, "output_test_with_structs.json")
+	jsonFilePathWithoutStructs := filepath.Join(dir, "output_test_without_structs.json")
 
 	err := generator.GenerateParquetFile(parquetFilePath, 100*1024, false) // 100 KB, simple structure
 	assert.NoError(t, err, "Error should be nil when generating Parquet file")
 
-	t.Cleanup(func() {
-		os.Remove(parquetFilePath)
-		os.Remove(jsonFilePathWithStructs)
-		os.Remove(jsonFilePathWithoutStructs)
-	})
-
 	tests := []struct {
 		parquetFilePath string
 		jsonFilePath    string
@@ -98,16 +93,20 @@ func TestConvertParquetToJSON(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 
-			metrics, err := converter.ConvertParquetToJSON(ctx, test.parquetFilePath, test.jsonFilePath, test.memoryMap, test.chunkSize, test.columns, test.rowGroups, test.parallel, test.includeStructs)
+			metrics, err := converter.ConvertParquetToJSONWithOptions(ctx, test.parquetFilePath, test.jsonFilePath, &converter.ParquetToJSONOptions{
+				MemoryMap:      test.memoryMap,
+				ChunkSize:      test.chunkSize,
+				Columns:        test.columns,
+				RowGroups:      test.rowGroups,
+				Parallel:       test.parallel,
+				Concurrency:    1,
+				IncludeStructs: test.includeStructs,
+			})
 			assert.NoError(t, err, "Error should be nil when converting Parquet to JSON")
 			fmt.Printf("Conversion completed. Summary: %s\n", metrics)
 
 			_, err = os.Stat(test.jsonFilePath)
 			assert.NoError(t, err, "JSON file should be created")
-
-			t.Cleanup(func() {
-				os.Remove(test.jsonFilePath)
-			})
 		})
 	}
 }
@@ -0,0 +1,78 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/stretchr/testify/require"
+)
+
+// convertSample converts the 500 rows of the filter test file with the
+// given sample options and returns the ids converted.
+func convertSample(t *testing.T, in string, fraction float64, seed int64) []int64 {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "sample.parquet")
+	_, err := converter.Convert(context.Background(), in, out, &converter.ConvertOptions{ChunkSize: 64, Sample: fraction, SampleSeed: seed})
+	require.NoError(t, err)
+
+	reader, err := integrations.OpenSource(context.Background(), out, nil)
+	require.NoError(t, err)
+	defer reader.Close()
+	var ids []int64
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return ids
+		}
+		require.NoError(t, err)
+		ids = append(ids, rec.Column(0).(*array.Int64).Int64Values()...)
+		rec.Release()
+	}
+}
+
+func TestConvertSample(t *testing.T) {
+	in := writeFilterTestFile(t, nil)
+
+	ids := convertSample(t, in, 0.2, 3)
+	require.InDelta(t, 100, len(ids), 40)
+	require.IsIncreasing(t, ids)
+	require.Equal(t, ids, convertSample(t, in, 0.2, 3), "the same seed draws the same sample")
+	require.Len(t, convertSample(t, in, 1, 3), 500)
+
+	_, err := converter.Convert(context.Background(), in, filepath.Join(t.TempDir(), "out.parquet"), &converter.ConvertOptions{Sample: 1.5})
+	require.ErrorContains(t, err, "sample fraction")
+}
@@ -0,0 +1,107 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pkg/preview"
+	"github.com/stretchr/testify/require"
+)
+
+const stdinHelperEnv = "ARROWARC_CONVERT_STDIN_HELPER"
+
+// TestConvertStdinHelper runs as a child process of TestConvertFromStdin,
+// converting its standard input to the file named by stdinHelperEnv.
+func TestConvertStdinHelper(t *testing.T) {
+	out := os.Getenv(stdinHelperEnv)
+	if out == "" {
+		t.Skip("only run by TestConvertFromStdin")
+	}
+	_, err := converter.Convert(context.Background(), integrations.StdioPath, out, &converter.ConvertOptions{FromFormat: "ndjson"})
+	require.NoError(t, err)
+}
+
+func TestConvertFromStdin(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "events.parquet")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestConvertStdinHelper$")
+	cmd.Env = append(os.Environ(), stdinHelperEnv+"="+out)
+	cmd.Stdin = strings.NewReader("{\"id\":1,\"name\":\"ada\"}\n{\"id\":2,\"name\":null}\n{\"id\":3,\"name\":\"edsger\"}\n")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	reader, err := integrations.OpenSource(context.Background(), out, nil)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, preview.Cat(reader, &buf, preview.Options{Format: preview.JSON}))
+	require.Equal(t, "{\"id\":1,\"name\":\"ada\"}\n{\"id\":2,\"name\":null}\n{\"id\":3,\"name\":\"edsger\"}\n", buf.String())
+}
+
+func TestConvertToStdout(t *testing.T) {
+	in := filepath.Join(t.TempDir(), "people.ndjson")
+	require.NoError(t, os.WriteFile(in, []byte("{\"id\":1,\"name\":\"ada\"}\n{\"id\":2,\"name\":\"grace\"}\n"), 0o644))
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	_, err = converter.Convert(context.Background(), in, integrations.StdioPath, &converter.ConvertOptions{ToFormat: "csv"})
+	os.Stdout = stdout
+	w.Close()
+	require.NoError(t, err)
+	require.Equal(t, "id,name\n1,ada\n2,grace\n", <-output)
+}
+
+func TestStdioRequiresFormat(t *testing.T) {
+	_, err := converter.Convert(context.Background(), integrations.StdioPath, "out.parquet", nil)
+	require.ErrorContains(t, err, "standard input")
+
+	_, err = integrations.NewParquetReader(context.Background(), integrations.StdioPath, &integrations.ParquetReadOptions{})
+	require.ErrorContains(t, err, "seekable")
+}
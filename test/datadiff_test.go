@@ -0,0 +1,81 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/arrowarc/arrowarc/converter"
+	"github.com/arrowarc/arrowarc/pkg/arrowschema"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/datadiff"
+	"github.com/stretchr/testify/require"
+)
+
+// diffFiles compares the datasets at a and b, read as Convert reads them.
+func diffFiles(t *testing.T, a, b string, opts datadiff.Options) *datadiff.Report {
+	t.Helper()
+	inputOpts := &converter.ConvertOptions{CSV: csvschema.CSVReadOptions{Delimiter: ',', HasHeader: true}}
+	ra, err := converter.OpenInput(context.Background(), a, inputOpts)
+	require.NoError(t, err)
+	defer ra.Close()
+	rb, err := converter.OpenInput(context.Background(), b, inputOpts)
+	require.NoError(t, err)
+	defer rb.Close()
+	report, err := datadiff.Diff(ra, rb, opts)
+	require.NoError(t, err)
+	return report
+}
+
+func TestDiffAcrossFormats(t *testing.T) {
+	in := writeFilterTestFile(t, nil)
+	dir := t.TempDir()
+	ndjson := filepath.Join(dir, "out.ndjson")
+	_, err := converter.Convert(context.Background(), in, ndjson, nil)
+	require.NoError(t, err)
+
+	// JSON numbers are read back as doubles, which compare equal to the
+	// integer ids.
+	report := diffFiles(t, in, ndjson, datadiff.Options{Keys: []string{"id"}})
+	require.True(t, report.Equal())
+	require.EqualValues(t, 500, report.Matching)
+	require.Contains(t, report.Schema, arrowschema.Change{Kind: arrowschema.Retyped, Column: "id", From: "int64 not null", To: "float64"})
+
+	truncated := filepath.Join(dir, "truncated.csv")
+	_, err = converter.Convert(context.Background(), in, truncated, &converter.ConvertOptions{Offset: 10})
+	require.NoError(t, err)
+	report = diffFiles(t, in, truncated, datadiff.Options{Keys: []string{"id"}})
+	require.False(t, report.Equal())
+	require.EqualValues(t, 490, report.Matching)
+	require.EqualValues(t, 10, report.OnlyInA)
+	require.Equal(t, datadiff.RowDiff{Kind: datadiff.OnlyInA, Row: "id=0"}, report.Examples[0])
+}
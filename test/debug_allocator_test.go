@@ -0,0 +1,94 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+// leakingReader retains every record it returns one time too many.
+type leakingReader struct {
+	n int
+}
+
+func (r *leakingReader) Read() (arrow.Record, error) {
+	if r.n == 0 {
+		return nil, io.EOF
+	}
+	r.n--
+	b := array.NewRecordBuilder(pool.GetAllocator(), arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil))
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+	rec := b.NewRecord()
+	rec.Retain()
+	return rec, nil
+}
+
+func (r *leakingReader) Close() error { return nil }
+
+func TestDebugAllocatorReportsAllocationSites(t *testing.T) {
+	alloc := pool.NewDebugAllocator(memory.NewGoAllocator())
+	kept := alloc.Allocate(64)
+	mark := alloc.Mark()
+	freed := alloc.Allocate(32)
+	leaked := alloc.Reallocate(256, alloc.Allocate(128))
+	alloc.Free(freed)
+
+	leaks := alloc.Leaks(mark)
+	require.Len(t, leaks, 1)
+	require.Equal(t, 1, leaks[0].Buffers)
+	require.EqualValues(t, 256, leaks[0].Bytes)
+	require.Contains(t, leaks[0].Stack, "TestDebugAllocatorReportsAllocationSites")
+	require.Len(t, alloc.Leaks(0), 2)
+
+	alloc.Free(leaked)
+	alloc.Free(kept)
+	require.Empty(t, alloc.Leaks(0))
+}
+
+func TestPipelineReportsUnreleasedBuffers(t *testing.T) {
+	pool.SetDebug(true)
+	defer pool.SetDebug(false)
+
+	p := pipeline.NewDataPipeline(&leakingReader{n: 2}, discardWriter{}).WithMonitor(nil)
+	report, err := p.Start(context.Background())
+	require.NoError(t, err)
+	require.Positive(t, p.Metrics().UnreleasedBytes)
+	require.Contains(t, report, `"unreleased_memory"`)
+}
@@ -0,0 +1,154 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+	"github.com/stretchr/testify/require"
+)
+
+var cityDictType = &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+
+func dictionaryRecord(t *testing.T) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "city", Type: cityDictType, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	cities := b.Field(1).(*array.BinaryDictionaryBuilder)
+	for i, city := range []string{"Oslo", "Lima", "Oslo", "", "Lima, PE"} {
+		b.Field(0).(*array.Int64Builder).Append(int64(i))
+		if city == "" {
+			cities.AppendNull()
+			continue
+		}
+		require.NoError(t, cities.AppendString(city))
+	}
+	return b.NewRecord()
+}
+
+func readAllRecords(t *testing.T, r *integrations.ParquetReader) []arrow.Record {
+	t.Helper()
+	var recs []arrow.Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return recs
+		}
+		require.NoError(t, err)
+		recs = append(recs, rec)
+	}
+}
+
+func TestParquetDictionaryRoundTrip(t *testing.T) {
+	rec := dictionaryRecord(t)
+	defer rec.Release()
+
+	path := filepath.Join(t.TempDir(), "dict.parquet")
+	w, err := integrations.NewParquetWriter(path, rec.Schema(), integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+	require.NoError(t, w.Write(rec))
+	require.NoError(t, w.Close())
+
+	f, err := filter.Parse("city = 'Oslo'")
+	require.NoError(t, err)
+	r, err := integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{Filter: f, ChunkSize: 1024})
+	require.NoError(t, err)
+	defer r.Close()
+
+	recs := readAllRecords(t, r)
+	require.Len(t, recs, 1)
+	defer recs[0].Release()
+	col, ok := recs[0].Column(1).(*array.Dictionary)
+	require.True(t, ok, "city read as %s", recs[0].Column(1).DataType())
+	require.Equal(t, 2, col.Len())
+	require.Equal(t, "Oslo", col.Dictionary().(*array.String).Value(col.GetValueIndex(1)))
+}
+
+func TestParquetReadDictionary(t *testing.T) {
+	path := writeFilterTestFile(t, nil)
+
+	r, err := integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{ReadDictionary: true, ChunkSize: 1024})
+	require.NoError(t, err)
+	defer r.Close()
+
+	var rows int
+	for _, rec := range readAllRecords(t, r) {
+		require.Equal(t, arrow.PrimitiveTypes.Int64, rec.Column(0).DataType())
+		col, ok := rec.Column(1).(*array.Dictionary)
+		require.True(t, ok, "name read as %s", rec.Column(1).DataType())
+		require.Equal(t, fmt.Sprintf("name-%d", rows), col.ValueStr(0))
+		rows += col.Len()
+		rec.Release()
+	}
+	require.Equal(t, 500, rows)
+}
+
+func TestTextWritersDecodeDictionaries(t *testing.T) {
+	rec := dictionaryRecord(t)
+	defer rec.Release()
+	dir := t.TempDir()
+
+	csvPath := filepath.Join(dir, "dict.csv")
+	cw, err := integrations.NewCSVWriter(context.Background(), csvPath, rec.Schema(), &integrations.CSVWriteOptions{IncludeHeader: true, NullValue: "NULL"})
+	require.NoError(t, err)
+	require.NoError(t, cw.Write(rec))
+	require.NoError(t, cw.Close())
+	data, err := os.ReadFile(csvPath)
+	require.NoError(t, err)
+	require.Equal(t, "id,city\n0,Oslo\n1,Lima\n2,Oslo\n3,NULL\n4,\"Lima, PE\"\n", string(data))
+
+	jsonPath := filepath.Join(dir, "dict.ndjson")
+	jw, err := integrations.NewNDJSONWriter(context.Background(), jsonPath)
+	require.NoError(t, err)
+	require.NoError(t, jw.Write(rec))
+	require.NoError(t, jw.Close())
+	data, err = os.ReadFile(jsonPath)
+	require.NoError(t, err)
+	require.Equal(t, `{"city":"Oslo","id":0}
+{"city":"Lima","id":1}
+{"city":"Oslo","id":2}
+{"city":null,"id":3}
+{"city":"Lima, PE","id":4}
+`, string(data))
+}
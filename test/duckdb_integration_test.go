@@ -32,10 +32,16 @@ package test
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
 	duckdb "github.com/arrowarc/arrowarc/integrations/duckdb"
+	filesystem "github.com/arrowarc/arrowarc/integrations/filesystem"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDuckDBIntegration(t *testing.T) {
@@ -61,3 +67,194 @@ func TestDuckDBIntegration(t *testing.T) {
 	schema := reader.Schema()
 	t.Logf("Schema: %v", schema)
 }
+
+func TestDuckDBSQLTransformer(t *testing.T) {
+	// Skip test in CI environment if DuckDB shared library is not available.
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping DuckDB integration test in CI environment.")
+	}
+
+	transformer, err := duckdb.NewSQLTransformer(context.Background(), ":memory:", &duckdb.SQLTransformOptions{
+		SQL:        "SELECT sum(id) AS total FROM batch",
+		WindowRows: 5,
+	})
+	if err != nil {
+		t.Skipf("DuckDB is not available: %v", err)
+	}
+	defer transformer.Close()
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	newRecord := func(ids ...int64) arrow.Record {
+		b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+		defer b.Release()
+		b.Field(0).(*array.Int64Builder).AppendValues(ids, nil)
+		return b.NewRecord()
+	}
+
+	// The first record is buffered until the window holds five rows.
+	first := newRecord(1, 2, 3)
+	defer first.Release()
+	out, err := transformer.Transform(first)
+	require.NoError(t, err)
+	require.Nil(t, out)
+
+	second := newRecord(4, 5)
+	defer second.Release()
+	out, err = transformer.Transform(second)
+	require.NoError(t, err)
+	require.NotNil(t, out)
+	assert.Equal(t, int64(1), out.NumRows())
+	assert.Equal(t, "15", out.Column(0).ValueStr(0))
+	out.Release()
+
+	// Leftover rows are processed on Flush.
+	third := newRecord(10)
+	defer third.Release()
+	out, err = transformer.Transform(third)
+	require.NoError(t, err)
+	require.Nil(t, out)
+	out, err = transformer.Flush()
+	require.NoError(t, err)
+	require.NotNil(t, out)
+	assert.Equal(t, "10", out.Column(0).ValueStr(0))
+	out.Release()
+}
+
+func TestDuckDBReaderOffsetLimit(t *testing.T) {
+	// Skip test in CI environment if DuckDB shared library is not available.
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping DuckDB integration test in CI environment.")
+	}
+
+	reader, err := duckdb.NewDuckDBReader(context.Background(), ":memory:", &duckdb.DuckDBReadOptions{
+		Query:  "SELECT range AS id FROM range(100);",
+		Offset: 40,
+		Limit:  5,
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	var ids []int64
+	for {
+		rec, err := reader.Read()
+		if err != nil {
+			break
+		}
+		ids = append(ids, rec.Column(0).(*array.Int64).Int64Values()...)
+		rec.Release()
+	}
+	require.Equal(t, []int64{40, 41, 42, 43, 44}, ids)
+}
+
+func TestDuckDBGenerateTPCH(t *testing.T) {
+	// Skip test in CI environment if DuckDB shared library is not available.
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping DuckDB integration test in CI environment.")
+	}
+
+	dir := t.TempDir()
+	for _, format := range []string{duckdb.TPCParquet, duckdb.TPCArrow} {
+		paths, err := duckdb.GenerateTPC(context.Background(), dir, &duckdb.TPCOptions{
+			ScaleFactor: 0.01,
+			Format:      format,
+			Tables:      []string{"nation", "region"},
+		})
+		if err != nil && len(paths) == 0 {
+			t.Skipf("DuckDB is not available: %v", err)
+		}
+		require.NoError(t, err)
+		require.Equal(t, []string{filepath.Join(dir, "nation."+format), filepath.Join(dir, "region."+format)}, paths)
+
+		// Arrow IPC files are read as Feather v2. TPC-H has 25 nations and
+		// 5 regions at every scale.
+		sourceFormat := filesystem.SourceParquet
+		if format == duckdb.TPCArrow {
+			sourceFormat = filesystem.SourceFeather
+		}
+		for i, want := range []int64{25, 5} {
+			reader, err := filesystem.OpenSource(context.Background(), paths[i], &filesystem.SourceOptions{Format: sourceFormat})
+			require.NoError(t, err)
+			var rows int64
+			for {
+				rec, err := reader.Read()
+				if err != nil {
+					break
+				}
+				rows += rec.NumRows()
+				rec.Release()
+			}
+			reader.Close()
+			require.Equal(t, want, rows, paths[i])
+		}
+	}
+}
+
+func TestGenerateTPCRejectsInvalidOptions(t *testing.T) {
+	for _, test := range []struct {
+		opts *duckdb.TPCOptions
+		err  string
+	}{
+		{&duckdb.TPCOptions{Benchmark: "tpcc"}, `unknown benchmark "tpcc"`},
+		{&duckdb.TPCOptions{Format: "avro"}, `unsupported format "avro"`},
+		{&duckdb.TPCOptions{ScaleFactor: -1}, "invalid scale factor -1"},
+	} {
+		_, err := duckdb.GenerateTPC(context.Background(), t.TempDir(), test.opts)
+		require.ErrorContains(t, err, test.err)
+	}
+}
+
+func TestDuckDBSQLSession(t *testing.T) {
+	// Skip test in CI environment if DuckDB shared library is not available.
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping DuckDB integration test in CI environment.")
+	}
+
+	ctx := context.Background()
+	session, err := duckdb.NewSQLSession(ctx, nil)
+	if err != nil {
+		t.Skipf("DuckDB is not available: %v", err)
+	}
+	defer session.Close()
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+	}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"a", "b", "c"}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	// A Parquet file is a view; an IPC stream is loaded into a table.
+	dir := t.TempDir()
+	parquetPath := filepath.Join(dir, "users.parquet")
+	pw, err := filesystem.NewParquetWriter(parquetPath, schema, nil)
+	require.NoError(t, err)
+	require.NoError(t, pw.Write(rec))
+	require.NoError(t, pw.Close())
+	require.NoError(t, session.RegisterFile("users", parquetPath, duckdb.SQLParquet))
+
+	ipcPath := filepath.Join(dir, "more.arrows")
+	iw, err := filesystem.NewIPCRecordWriterWithOptions(ctx, ipcPath, schema, nil)
+	require.NoError(t, err)
+	require.NoError(t, iw.Write(rec))
+	require.NoError(t, iw.Close())
+	source, err := filesystem.OpenSource(ctx, ipcPath, nil)
+	require.NoError(t, err)
+	require.NoError(t, session.RegisterSource("more", source))
+	source.Close()
+
+	tables, err := session.Tables()
+	require.NoError(t, err)
+	require.Equal(t, []string{"more", "users"}, tables)
+
+	result, err := session.Query("SELECT sum(u.id + m.id) AS total FROM users u JOIN more m USING (name)")
+	require.NoError(t, err)
+	defer result.Close()
+	out, err := result.Read()
+	require.NoError(t, err)
+	defer out.Release()
+	require.Equal(t, "12", out.Column(0).ValueStr(0))
+}
@@ -0,0 +1,157 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+// writeEmptyParquet writes a Parquet file with a schema and no rows.
+func writeEmptyParquet(t *testing.T, path string) *arrow.Schema {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	writer, err := integrations.NewParquetWriter(path, schema, integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return schema
+}
+
+func TestConvertEmptyInput(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "empty.parquet")
+	schema := writeEmptyParquet(t, input)
+
+	for _, name := range []string{"out.csv", "out.ndjson", "out.parquet", "out.arrow", "out.avro"} {
+		_, err := converter.Convert(ctx, input, filepath.Join(dir, name), nil)
+		require.NoError(t, err, name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "out.csv"))
+	require.NoError(t, err)
+	require.Equal(t, "id,name\n", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "out.ndjson"))
+	require.NoError(t, err)
+	require.Empty(t, data)
+
+	// The Parquet output is a valid file with the schema of the input.
+	reader, err := integrations.NewParquetReader(ctx, filepath.Join(dir, "out.parquet"), &integrations.ParquetReadOptions{ChunkSize: 10})
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, schema.NumFields(), reader.Schema().NumFields())
+	for i, field := range schema.Fields() {
+		require.Equal(t, field.Name, reader.Schema().Field(i).Name)
+		require.True(t, arrow.TypeEqual(field.Type, reader.Schema().Field(i).Type))
+	}
+	_, err = reader.Read()
+	require.ErrorIs(t, err, io.EOF)
+
+	for _, name := range []string{"out.arrow", "out.avro"} {
+		source, err := integrations.OpenSource(ctx, filepath.Join(dir, name), nil)
+		require.NoError(t, err, name)
+		require.Equal(t, []string{"id", "name"}, fieldNames(source.Schema()), name)
+		source.Close()
+	}
+}
+
+func TestConvertEmptyInputProjected(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "empty.parquet")
+	writeEmptyParquet(t, input)
+
+	output := filepath.Join(dir, "out.csv")
+	_, err := converter.Convert(ctx, input, output, &converter.ConvertOptions{
+		Select: []string{"name"},
+		Rename: map[string]string{"name": "label"},
+	})
+	require.NoError(t, err)
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "label\n", string(data))
+}
+
+func TestConvertEmptyInputPolicies(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "empty.parquet")
+	writeEmptyParquet(t, input)
+
+	output := filepath.Join(dir, "skipped.csv")
+	_, err := converter.Convert(ctx, input, output, &converter.ConvertOptions{OnEmpty: pipeline.EmptySkip})
+	require.NoError(t, err)
+	require.NoFileExists(t, output)
+
+	output = filepath.Join(dir, "failed.parquet")
+	_, err = converter.Convert(ctx, input, output, &converter.ConvertOptions{OnEmpty: pipeline.EmptyFail})
+	require.ErrorIs(t, err, pipeline.ErrEmptyResult)
+	require.NoFileExists(t, output)
+
+	_, err = converter.Convert(ctx, input, output, &converter.ConvertOptions{OnEmpty: pipeline.EmptySkip, Verify: true})
+	require.ErrorContains(t, err, "cannot verify")
+}
+
+func TestJSONWriterEmpty(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		opts *integrations.JSONWriteOptions
+		want string
+	}{
+		{&integrations.JSONWriteOptions{}, "[]\n"},
+		{&integrations.JSONWriteOptions{Lines: true}, ""},
+		{&integrations.JSONWriteOptions{Mode: integrations.JSONModeArray}, "[]\n"},
+	} {
+		path := filepath.Join(dir, "out.json")
+		writer, err := integrations.NewJSONWriterWithOptions(ctx, path, tc.opts)
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, string(data))
+	}
+}
@@ -0,0 +1,181 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// tickerServer serves a WebSocket sending, once subscribed, n ticks, a
+// heartbeat and an array of two more ticks, then holding the connection
+// open until the client closes it.
+func tickerServer(t *testing.T, n int) (string, <-chan string) {
+	subscribed := make(chan string, 1)
+	srv := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		var msg string
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			return
+		}
+		subscribed <- msg
+		for i := 0; i < n; i++ {
+			websocket.Message.Send(ws, fmt.Sprintf(`{"symbol":"ABC","price":%d.5,"seq":%d}`, 100+i, i))
+		}
+		websocket.Message.Send(ws, "ping")
+		websocket.Message.Send(ws, fmt.Sprintf(`[{"symbol":"XYZ","price":1,"seq":%d},{"symbol":"XYZ","price":2,"seq":%d}]`, n, n+1))
+		websocket.Message.Receive(ws, &msg)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), subscribed
+}
+
+// readEvents reads r to the end, returning the number of rows of each record.
+func readEvents(t *testing.T, r integrations.FileReader) []int64 {
+	var sizes []int64
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return sizes
+		}
+		require.NoError(t, err)
+		sizes = append(sizes, rec.NumRows())
+		rec.Release()
+	}
+}
+
+func TestEventReaderWebSocket(t *testing.T) {
+	url, subscribed := tickerServer(t, 5)
+	opts := integrations.NewDefaultEventReadOptions()
+	opts.Subscribe = `{"type":"subscribe","channel":"ticker"}`
+	opts.WarmupEvents = 3
+	opts.BatchSize = 4
+	opts.MaxEvents = 7
+	reader, err := integrations.NewEventReader(context.Background(), url, opts)
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, `{"type":"subscribe","channel":"ticker"}`, <-subscribed)
+
+	schema := reader.Schema()
+	require.Equal(t, []string{"price", "seq", "symbol"}, fieldNames(schema))
+	require.Equal(t, arrow.PrimitiveTypes.Float64, schema.Field(0).Type)
+	require.Equal(t, arrow.BinaryTypes.String, schema.Field(2).Type)
+
+	rec, err := reader.Read()
+	require.NoError(t, err)
+	require.EqualValues(t, 4, rec.NumRows())
+	require.Equal(t, 100.5, rec.Column(0).(*array.Float64).Value(0))
+	rec.Release()
+	require.Equal(t, []int64{3}, readEvents(t, reader))
+	require.EqualValues(t, 1, reader.Skipped())
+}
+
+func TestEventReaderSSE(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		require.Equal(t, "secret", r.Header.Get("X-Token"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		fmt.Fprint(w, ": connected\n\n")
+		fmt.Fprint(w, "event: tick\ndata: {\"id\": 1,\ndata: \"name\": \"a\"}\n\n")
+		fmt.Fprint(w, "event: status\ndata: {\"up\": true}\n\n")
+		fmt.Fprint(w, "event: tick\r\ndata: {\"id\": 2, \"name\": \"b\"}\r\n\r\n")
+		flusher.Flush()
+		time.Sleep(300 * time.Millisecond)
+		fmt.Fprint(w, "event: tick\ndata: {\"id\": 3, \"name\": \"c\"}\n\n")
+	}))
+	defer srv.Close()
+
+	opts := integrations.NewDefaultEventReadOptions()
+	opts.Headers = http.Header{"X-Token": {"secret"}}
+	opts.EventType = "tick"
+	opts.WarmupTimeout = 100 * time.Millisecond
+	opts.FlushInterval = 50 * time.Millisecond
+	reader, err := integrations.NewEventReader(context.Background(), srv.URL, opts)
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, []string{"id", "name"}, fieldNames(reader.Schema()))
+
+	// The events of the warm-up window, flushed before the last arrives.
+	require.Equal(t, []int64{2, 1}, readEvents(t, reader))
+}
+
+func TestEventReaderErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "data: not json\n\n")
+	}))
+	defer srv.Close()
+
+	_, err := integrations.NewEventReader(context.Background(), srv.URL+"/missing", nil)
+	require.ErrorContains(t, err, "404")
+
+	// A stream with no JSON objects leaves nothing to infer a schema from.
+	_, err = integrations.NewEventReader(context.Background(), srv.URL, nil)
+	require.ErrorContains(t, err, "no events")
+
+	_, err = integrations.NewEventReader(context.Background(), "/tmp/events.json", nil)
+	require.Error(t, err)
+}
+
+func TestConvertEventStream(t *testing.T) {
+	url, _ := tickerServer(t, 10)
+	out := filepath.Join(t.TempDir(), "ticks.parquet")
+	_, err := converter.Convert(context.Background(), url+"/ticks.json", out, &converter.ConvertOptions{
+		Events: integrations.EventReadOptions{Subscribe: "subscribe", MaxEvents: 6},
+	})
+	require.NoError(t, err)
+
+	reader, err := integrations.OpenSource(context.Background(), out, nil)
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, []string{"price", "seq", "symbol"}, fieldNames(reader.Schema()))
+	var rows int64
+	for _, n := range readEvents(t, reader) {
+		rows += n
+	}
+	require.EqualValues(t, 6, rows)
+}
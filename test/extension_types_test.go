@@ -0,0 +1,135 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/extensions"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func buildExtensionTypesRecord(t *testing.T) arrow.Record {
+	t.Helper()
+	jsonType, err := extensions.NewJSONType(arrow.BinaryTypes.String)
+	require.NoError(t, err)
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: extensions.NewUUIDType(), Nullable: true},
+		{Name: "doc", Type: jsonType, Nullable: true},
+	}, nil)
+	record, _, err := array.RecordFromJSON(memory.NewGoAllocator(), schema, strings.NewReader(
+		`[{"id":"123e4567-e89b-12d3-a456-426614174000","doc":"{\"a\":[1,2]}"},{"id":"00000000-0000-0000-0000-000000000001","doc":"\"x\""}]`))
+	require.NoError(t, err)
+	return record
+}
+
+func writeExtensionTypesParquet(t *testing.T, path string) arrow.Record {
+	t.Helper()
+	record := buildExtensionTypesRecord(t)
+	writer, err := integrations.NewParquetWriter(path, record.Schema(), integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+	return record
+}
+
+func TestParquetExtensionTypes(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "ext.parquet")
+	record := writeExtensionTypesParquet(t, path)
+	defer record.Release()
+
+	reader, err := integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{ChunkSize: 10})
+	require.NoError(t, err)
+	defer reader.Close()
+	got, err := reader.Read()
+	require.NoError(t, err)
+	defer got.Release()
+
+	require.True(t, arrow.TypeEqual(record.Schema().Field(0).Type, got.Schema().Field(0).Type))
+	require.True(t, arrow.TypeEqual(record.Schema().Field(1).Type, got.Schema().Field(1).Type))
+	require.True(t, reader.Schema().Equal(got.Schema()))
+	for i := 0; i < int(record.NumCols()); i++ {
+		for row := 0; row < int(record.NumRows()); row++ {
+			require.Equal(t, record.Column(i).ValueStr(row), got.Column(i).ValueStr(row), "column %d row %d", i, row)
+		}
+	}
+}
+
+func TestConvertExtensionTypes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "ext.parquet")
+	writeExtensionTypesParquet(t, input).Release()
+
+	for _, name := range []string{"ext.csv", "ext.ndjson", "out.parquet"} {
+		_, err := converter.Convert(ctx, input, filepath.Join(dir, name), &converter.ConvertOptions{ChunkSize: 10, Verify: true})
+		require.NoError(t, err, name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "ext.csv"))
+	require.NoError(t, err)
+	require.Equal(t, "id,doc\n123e4567-e89b-12d3-a456-426614174000,\"{\"\"a\"\":[1,2]}\"\n00000000-0000-0000-0000-000000000001,\"\"\"x\"\"\"\n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(dir, "ext.ndjson"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), `{"doc":{"a":[1,2]},"id":"123e4567-e89b-12d3-a456-426614174000"}`)
+}
+
+func TestReadNDJSONMixedValues(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "mixed.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte("{\"v\":1}\n{\"v\":\"a\"}\n{\"v\":{\"b\":[true]}}\n{\"v\":null}\n"), 0o644))
+
+	reader, err := converter.OpenInput(context.Background(), path, &converter.ConvertOptions{ChunkSize: 10})
+	require.NoError(t, err)
+	defer reader.Close()
+	record, err := reader.Read()
+	require.NoError(t, err)
+	defer record.Release()
+
+	// Values of conflicting kinds are read as their JSON text.
+	col := record.Column(0)
+	require.Equal(t, "arrow.json", col.DataType().(arrow.ExtensionType).ExtensionName())
+	require.Equal(t, `1`, col.ValueStr(0))
+	require.Equal(t, `"a"`, col.ValueStr(1))
+	require.Equal(t, `{"b":[true]}`, col.ValueStr(2))
+	require.True(t, col.IsNull(3))
+}
@@ -0,0 +1,100 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	sqlite "github.com/arrowarc/arrowarc/integrations/flight/sqlite"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// serveSQLiteDB serves db over Flight SQL and returns a client for it.
+func serveSQLiteDB(t *testing.T, db *sql.DB) *flightsql.Client {
+	t.Helper()
+	srv, err := sqlite.NewSQLiteFlightSQLServer(db)
+	require.NoError(t, err)
+	s := flight.NewServerWithMiddleware(nil)
+	require.NoError(t, s.Init("localhost:0"))
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	go s.Serve()
+	t.Cleanup(s.Shutdown)
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { cl.Close() })
+	return cl
+}
+
+func TestFlightSQLiteOpenDB(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "orders #1.db")
+
+	db, err := sqlite.OpenDB(path, false)
+	require.NoError(t, err)
+	cl := serveSQLiteDB(t, db)
+
+	n, err := cl.ExecuteUpdate(ctx, `CREATE TABLE orders (id INTEGER, item TEXT)`)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	n, err = cl.ExecuteUpdate(ctx, `INSERT INTO orders VALUES (1, 'tea'), (2, 'milk')`)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+	require.NoError(t, db.Close())
+
+	// the rows were written to the file
+	ro, err := sqlite.OpenDB(path, true)
+	require.NoError(t, err)
+	t.Cleanup(func() { ro.Close() })
+	cl = serveSQLiteDB(t, ro)
+
+	info, err := cl.Execute(ctx, `SELECT id, item FROM orders ORDER BY id`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1|tea", "2|milk"}, fetchRows(t, cl, info))
+
+	_, err = cl.ExecuteUpdate(ctx, `DELETE FROM orders`)
+	require.ErrorContains(t, err, "readonly database")
+
+	info, err = cl.GetSqlInfo(ctx, []flightsql.SqlInfo{flightsql.SqlInfoFlightSqlServerReadOnly})
+	require.NoError(t, err)
+	require.Equal(t, []string{"3|[1,true]"}, fetchRows(t, cl, info)) // a dense union value, code 1 being bool
+}
+
+func TestFlightSQLiteOpenDBReadOnlyMissing(t *testing.T) {
+	_, err := sqlite.OpenDB(filepath.Join(t.TempDir(), "missing.db"), true)
+	require.ErrorContains(t, err, "no such file")
+}
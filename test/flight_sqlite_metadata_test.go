@@ -0,0 +1,360 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	sqlite "github.com/arrowarc/arrowarc/integrations/flight/sqlite"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// startSQLiteMetadataServer serves a database with a mix of declared types,
+// a view, a composite primary key and an attached database, and returns a
+// client connected to it.
+func startSQLiteMetadataServer(t *testing.T) *flightsql.Client {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`
+	CREATE TABLE accounts (
+		id INTEGER PRIMARY KEY,
+		name VARCHAR(64) NOT NULL,
+		balance DECIMAL(10,2),
+		opened DATE,
+		active BOOLEAN,
+		visits BIGINT,
+		avatar BLOB);
+	CREATE TABLE memberships (
+		account_id INTEGER,
+		group_name TEXT,
+		PRIMARY KEY (group_name, account_id));
+	CREATE VIEW active_accounts AS SELECT id, name FROM accounts WHERE active;
+	ATTACH DATABASE ':memory:' AS archive;
+	CREATE TABLE archive.old_accounts (id INTEGER PRIMARY KEY, name TEXT);
+	INSERT INTO accounts VALUES (1, 'ada', 10.5, '2024-01-02', 1, 7, x'00ff');
+	`)
+	require.NoError(t, err)
+
+	srv, err := sqlite.NewSQLiteFlightSQLServer(db)
+	require.NoError(t, err)
+	s := flight.NewServerWithMiddleware(nil)
+	require.NoError(t, s.Init("localhost:0"))
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	go s.Serve()
+	t.Cleanup(s.Shutdown)
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { cl.Close() })
+	return cl
+}
+
+// fetchRows reads every endpoint of info and returns its rows, with the
+// column values joined by "|".
+func fetchRows(t *testing.T, cl *flightsql.Client, info *flight.FlightInfo) []string {
+	t.Helper()
+	var rows []string
+	for _, ep := range info.Endpoint {
+		rdr, err := cl.DoGet(context.Background(), ep.Ticket)
+		require.NoError(t, err)
+		for rdr.Next() {
+			rec := rdr.Record()
+			for i := 0; i < int(rec.NumRows()); i++ {
+				cols := make([]string, rec.NumCols())
+				for c := range cols {
+					cols[c] = rec.Column(c).ValueStr(i)
+				}
+				rows = append(rows, strings.Join(cols, "|"))
+			}
+		}
+		require.NoError(t, rdr.Err())
+		rdr.Release()
+	}
+	return rows
+}
+
+func TestFlightSQLiteCatalogsAndSchemas(t *testing.T) {
+	cl := startSQLiteMetadataServer(t)
+	ctx := context.Background()
+
+	info, err := cl.GetCatalogs(ctx)
+	require.NoError(t, err)
+	rows := fetchRows(t, cl, info)
+	require.Equal(t, []string{"archive", "main"}, rows)
+
+	pattern := "%"
+	info, err = cl.GetDBSchemas(ctx, &flightsql.GetDBSchemasOpts{DbSchemaFilterPattern: &pattern})
+	require.NoError(t, err)
+	rows = fetchRows(t, cl, info)
+	require.Equal(t, []string{"archive|", "main|"}, rows)
+
+	catalog := "main"
+	info, err = cl.GetDBSchemas(ctx, &flightsql.GetDBSchemasOpts{Catalog: &catalog})
+	require.NoError(t, err)
+	rows = fetchRows(t, cl, info)
+	require.Equal(t, []string{"main|"}, rows)
+
+	info, err = cl.GetTableTypes(ctx)
+	require.NoError(t, err)
+	rows = fetchRows(t, cl, info)
+	require.Equal(t, []string{"TABLE", "VIEW"}, rows)
+}
+
+func TestFlightSQLiteGetTables(t *testing.T) {
+	cl := startSQLiteMetadataServer(t)
+	ctx := context.Background()
+
+	info, err := cl.GetTables(ctx, &flightsql.GetTablesOpts{})
+	require.NoError(t, err)
+	rows := fetchRows(t, cl, info)
+	require.Equal(t, []string{
+		"archive||old_accounts|TABLE",
+		"main||accounts|TABLE",
+		"main||active_accounts|VIEW",
+		"main||memberships|TABLE",
+	}, rows)
+
+	catalog, pattern := "main", "%acc%"
+	info, err = cl.GetTables(ctx, &flightsql.GetTablesOpts{
+		Catalog:                &catalog,
+		TableNameFilterPattern: &pattern,
+		TableTypes:             []string{"view"},
+	})
+	require.NoError(t, err)
+	rows = fetchRows(t, cl, info)
+	require.Equal(t, []string{"main||active_accounts|VIEW"}, rows)
+
+	// a quote in a pattern is matched, not interpreted
+	pattern = "x' OR '1'='1"
+	info, err = cl.GetTables(ctx, &flightsql.GetTablesOpts{TableNameFilterPattern: &pattern})
+	require.NoError(t, err)
+	rows = fetchRows(t, cl, info)
+	require.Empty(t, rows)
+
+	pattern = "accounts"
+	info, err = cl.GetTables(ctx, &flightsql.GetTablesOpts{
+		Catalog:                &catalog,
+		TableNameFilterPattern: &pattern,
+		IncludeSchema:          true,
+	})
+	require.NoError(t, err)
+	rec := fetchRecord(t, cl, info)
+	defer rec.Release()
+	require.EqualValues(t, 1, rec.NumRows())
+
+	schemaBytes := rec.Column(int(rec.NumCols()) - 1).(*array.Binary).Value(0)
+	schema, err := flight.DeserializeSchema(schemaBytes, memory.DefaultAllocator)
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "name", "balance", "opened", "active", "visits", "avatar"}, fieldNames(schema))
+	require.Equal(t, []arrow.DataType{
+		arrow.PrimitiveTypes.Int64, arrow.BinaryTypes.String, arrow.PrimitiveTypes.Float64,
+		arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int8, arrow.PrimitiveTypes.Int64,
+		arrow.BinaryTypes.Binary,
+	}, fieldTypes(schema))
+	require.False(t, schema.Field(0).Nullable)
+	require.False(t, schema.Field(1).Nullable)
+
+	idField, balanceField := schema.Field(0), schema.Field(2)
+	idMeta := flightsql.ColumnMetadata{Data: &idField.Metadata}
+	autoIncrement, _ := idMeta.IsAutoIncrement()
+	require.True(t, autoIncrement)
+	balanceMeta := flightsql.ColumnMetadata{Data: &balanceField.Metadata}
+	typeName, _ := balanceMeta.TypeName()
+	require.Equal(t, "DECIMAL(10,2)", typeName)
+}
+
+func TestFlightSQLiteGetPrimaryKeys(t *testing.T) {
+	cl := startSQLiteMetadataServer(t)
+	ctx := context.Background()
+
+	info, err := cl.GetPrimaryKeys(ctx, flightsql.TableRef{Table: "memberships"})
+	require.NoError(t, err)
+	rows := fetchRows(t, cl, info)
+	require.Equal(t, []string{
+		"main||memberships|group_name|1|(null)",
+		"main||memberships|account_id|2|(null)",
+	}, rows)
+
+	catalog := "archive"
+	info, err = cl.GetPrimaryKeys(ctx, flightsql.TableRef{Catalog: &catalog, Table: "old_accounts"})
+	require.NoError(t, err)
+	rows = fetchRows(t, cl, info)
+	require.Equal(t, []string{"archive||old_accounts|id|1|(null)"}, rows)
+
+	// a LIKE pattern is not a table name
+	info, err = cl.GetPrimaryKeys(ctx, flightsql.TableRef{Table: "%"})
+	require.NoError(t, err)
+	rows = fetchRows(t, cl, info)
+	require.Empty(t, rows)
+}
+
+func TestFlightSQLiteGetTypeInfo(t *testing.T) {
+	cl := startSQLiteMetadataServer(t)
+	ctx := context.Background()
+
+	info, err := cl.GetXdbcTypeInfo(ctx, nil)
+	require.NoError(t, err)
+	rec := fetchRecord(t, cl, info)
+	defer rec.Release()
+
+	dataTypes := rec.Column(1).(*array.Int32)
+	for i := 1; i < dataTypes.Len(); i++ {
+		require.LessOrEqual(t, dataTypes.Value(i-1), dataTypes.Value(i), "type info must be ordered by data type")
+	}
+
+	// filtering on the last data type and on an unknown one
+	for filter, want := range map[int32][]string{93: {"timestamp"}, -1: {"text", "longvarchar"}, 1111: nil} {
+		info, err := cl.GetXdbcTypeInfo(ctx, &filter)
+		require.NoError(t, err)
+		rows := fetchRows(t, cl, info)
+		var names []string
+		for _, r := range rows {
+			names = append(names, strings.SplitN(r, "|", 2)[0])
+		}
+		require.Equal(t, want, names)
+	}
+}
+
+func TestFlightSQLitePreparedStatementParameters(t *testing.T) {
+	cl := startSQLiteMetadataServer(t)
+	ctx := context.Background()
+
+	prep, err := cl.Prepare(ctx, "SELECT id, name FROM accounts WHERE name = ? OR id = :id OR avatar = ?")
+	require.NoError(t, err)
+	defer prep.Close(ctx)
+	require.Equal(t, []string{"parameter_1", ":id", "parameter_3"}, fieldNames(prep.ParameterSchema()))
+	require.Equal(t, []string{"id", "name"}, fieldNames(prep.DatasetSchema()))
+
+	insert, err := cl.Prepare(ctx, "INSERT INTO accounts (id, name, balance, opened, active, visits, avatar) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	require.NoError(t, err)
+	defer insert.Close(ctx)
+	require.Len(t, insert.ParameterSchema().Fields(), 7)
+	require.Nil(t, insert.DatasetSchema())
+
+	params := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Uint16},
+		{Name: "name", Type: arrow.BinaryTypes.LargeString},
+		{Name: "balance", Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}},
+		{Name: "opened", Type: arrow.FixedWidthTypes.Date32},
+		{Name: "active", Type: arrow.FixedWidthTypes.Boolean},
+		{Name: "visits", Type: arrow.PrimitiveTypes.Uint32},
+		{Name: "avatar", Type: &arrow.FixedSizeBinaryType{ByteWidth: 2}, Nullable: true},
+	}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, params, strings.NewReader(
+		`[{"id": 2, "name": "grace", "balance": "99.95", "opened": "2024-03-04", "active": true, "visits": 3, "avatar": null}]`))
+	require.NoError(t, err)
+	defer rec.Release()
+	insert.SetParameters(rec)
+	n, err := insert.ExecuteUpdate(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	// dates are stored as ISO-8601 text
+	info, err := cl.Execute(ctx, `SELECT id, name, balance, active, visits FROM accounts
+		WHERE id = 2 AND opened = '2024-03-04' AND typeof(opened) = 'text' AND avatar IS NULL`)
+	require.NoError(t, err)
+	rows := fetchRows(t, cl, info)
+	require.Equal(t, []string{"2|grace|99.95|1|3"}, rows)
+
+	prep.SetParameters(bindingRecord(t, `[{"name": "grace", "id": null, "avatar": null}]`))
+	info, err = prep.Execute(ctx)
+	require.NoError(t, err)
+	rows = fetchRows(t, cl, info)
+	require.Equal(t, []string{"2|grace"}, rows)
+
+	prep.SetParameters(bindingRecord(t, `[{"name": null, "id": 1, "avatar": null}]`))
+	info, err = prep.Execute(ctx)
+	require.NoError(t, err)
+	rows = fetchRows(t, cl, info)
+	require.Equal(t, []string{"1|ada"}, rows)
+}
+
+// bindingRecord builds the parameters of the accounts lookup statement.
+func bindingRecord(t *testing.T, rows string) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "avatar", Type: arrow.BinaryTypes.Binary, Nullable: true},
+	}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(rows))
+	require.NoError(t, err)
+	t.Cleanup(rec.Release)
+	return rec
+}
+
+// fetchRecord reads info's single endpoint into one record.
+func fetchRecord(t *testing.T, cl *flightsql.Client, info *flight.FlightInfo) arrow.Record {
+	t.Helper()
+	require.Len(t, info.Endpoint, 1)
+	rdr, err := cl.DoGet(context.Background(), info.Endpoint[0].Ticket)
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	var recs []arrow.Record
+	for rdr.Next() {
+		rdr.Record().Retain()
+		recs = append(recs, rdr.Record())
+	}
+	require.NoError(t, rdr.Err())
+	tbl := array.NewTableFromRecords(rdr.Schema(), recs)
+	defer tbl.Release()
+	for _, r := range recs {
+		r.Release()
+	}
+
+	tr := array.NewTableReader(tbl, tbl.NumRows())
+	defer tr.Release()
+	require.True(t, tr.Next())
+	out := tr.Record()
+	out.Retain()
+	return out
+}
+
+func fieldTypes(s *arrow.Schema) []arrow.DataType {
+	types := make([]arrow.DataType, s.NumFields())
+	for i, f := range s.Fields() {
+		types[i] = f.Type
+	}
+	return types
+}
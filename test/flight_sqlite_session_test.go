@@ -0,0 +1,175 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	sqlite "github.com/arrowarc/arrowarc/integrations/flight/sqlite"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// startSessionServer serves a SQLite file with sessions enabled and returns
+// a function connecting a new client, which keeps the session cookie.
+func startSessionServer(t *testing.T) (func() *flightsql.Client, *sqlite.SQLiteFlightSQLServer, func() int) {
+	t.Helper()
+	db, err := sqlite.OpenDB(t.TempDir()+"/sessions.db", false)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`CREATE TABLE shared (v TEXT); INSERT INTO shared VALUES ('everyone')`)
+	require.NoError(t, err)
+
+	srv, err := sqlite.NewSQLiteFlightSQLServer(db)
+	require.NoError(t, err)
+	mw, opt := srv.Sessions()
+	s := flight.NewServerWithMiddleware([]flight.ServerMiddleware{mw}, opt)
+	require.NoError(t, s.Init("localhost:0"))
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	go s.Serve()
+	t.Cleanup(s.Shutdown)
+
+	connect := func() *flightsql.Client {
+		cl, err := flightsql.NewClient(s.Addr().String(), nil,
+			[]flight.ClientMiddleware{flight.NewClientCookieMiddleware()},
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		t.Cleanup(func() { cl.Close() })
+
+		// the client only stores cookies set on streaming calls once the
+		// stream is done, so open the session with a unary call
+		_, err = cl.GetCatalogs(context.Background())
+		require.NoError(t, err)
+		return cl
+	}
+	inUse := func() int { return db.Stats().InUse }
+	return connect, srv, inUse
+}
+
+func TestFlightSQLiteSessionsIsolateTempTables(t *testing.T) {
+	ctx := context.Background()
+	connect, _, _ := startSessionServer(t)
+	alice, bob := connect(), connect()
+
+	_, err := alice.ExecuteUpdate(ctx, `CREATE TEMP TABLE scratch (v TEXT)`)
+	require.NoError(t, err)
+	_, err = alice.ExecuteUpdate(ctx, `INSERT INTO scratch VALUES ('alice')`)
+	require.NoError(t, err)
+
+	info, err := alice.Execute(ctx, `SELECT v FROM scratch`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice"}, fetchRows(t, alice, info))
+
+	// bob's session doesn't see alice's table, and can have one of its own
+	require.ErrorContains(t, runQuery(bob, `SELECT v FROM scratch`), "no such table")
+	_, err = bob.ExecuteUpdate(ctx, `CREATE TEMP TABLE scratch (v TEXT)`)
+	require.NoError(t, err)
+	info, err = bob.Execute(ctx, `SELECT count(*) FROM scratch`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0"}, fetchRows(t, bob, info))
+
+	// both see the database's own tables
+	for _, cl := range []*flightsql.Client{alice, bob} {
+		info, err = cl.Execute(ctx, `SELECT v FROM shared`)
+		require.NoError(t, err)
+		require.Equal(t, []string{"everyone"}, fetchRows(t, cl, info))
+	}
+
+	// prepared statements stay in the session too
+	prep, err := alice.Prepare(ctx, `SELECT v FROM scratch`)
+	require.NoError(t, err)
+	info, err = prep.Execute(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice"}, fetchRows(t, alice, info))
+	require.ErrorContains(t, drain(bob.DoGet(ctx, info.Endpoint[0].Ticket)), "prepared statement not found")
+	require.NoError(t, prep.Close(ctx))
+}
+
+func TestFlightSQLiteCloseSession(t *testing.T) {
+	ctx := context.Background()
+	connect, _, inUse := startSessionServer(t)
+	cl := connect()
+
+	_, err := cl.ExecuteUpdate(ctx, `CREATE TEMP TABLE scratch (v TEXT)`)
+	require.NoError(t, err)
+	require.Equal(t, 1, inUse())
+
+	res, err := cl.CloseSession(ctx, &flight.CloseSessionRequest{})
+	require.NoError(t, err)
+	require.Equal(t, flight.CloseSessionResultClosed, res.GetStatus())
+	require.Equal(t, 0, inUse())
+
+	// the next call starts a new session, without the temporary table
+	require.ErrorContains(t, runQuery(cl, `SELECT v FROM scratch`), "no such table")
+}
+
+func TestFlightSQLiteSessionClosedOnDisconnect(t *testing.T) {
+	ctx := context.Background()
+	connect, _, inUse := startSessionServer(t)
+	cl := connect()
+
+	_, err := cl.ExecuteUpdate(ctx, `CREATE TEMP TABLE scratch (v TEXT)`)
+	require.NoError(t, err)
+	tx, err := cl.BeginTransaction(ctx)
+	require.NoError(t, err)
+	_, err = tx.ExecuteUpdate(ctx, `INSERT INTO scratch VALUES ('pending')`)
+	require.NoError(t, err)
+	require.Equal(t, 1, inUse())
+
+	require.NoError(t, cl.Close())
+	require.Eventually(t, func() bool { return inUse() == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	// the connection was discarded along with its temporary tables
+	require.ErrorContains(t, runQuery(connect(), `SELECT v FROM scratch`), "no such table")
+}
+
+// runQuery executes query and reads its results, returning the first error.
+func runQuery(cl *flightsql.Client, query string) error {
+	info, err := cl.Execute(context.Background(), query)
+	if err != nil {
+		return err
+	}
+	return drain(cl.DoGet(context.Background(), info.Endpoint[0].Ticket))
+}
+
+func drain(rdr *flight.Reader, err error) error {
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+	for rdr.Next() {
+	}
+	return rdr.Err()
+}
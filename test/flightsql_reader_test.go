@@ -0,0 +1,100 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	flightsource "github.com/arrowarc/arrowarc/integrations/flight"
+	sqlite "github.com/arrowarc/arrowarc/integrations/flight/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+// startFlightSQLServer serves an in-memory SQLite database holding a
+// table "numbers" of n rows over Flight SQL and returns its address.
+func startFlightSQLServer(t *testing.T, n int) string {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	// Every connection to :memory: opens a database of its own.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`CREATE TABLE numbers (n INTEGER, label TEXT)`)
+	require.NoError(t, err)
+	for i := 0; i < n; i++ {
+		_, err = db.Exec(`INSERT INTO numbers VALUES (?, ?)`, i, "row")
+		require.NoError(t, err)
+	}
+
+	srv, err := sqlite.NewSQLiteFlightSQLServer(db)
+	require.NoError(t, err)
+	s := flight.NewServerWithMiddleware(nil)
+	require.NoError(t, s.Init("localhost:0"))
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	go s.Serve()
+	t.Cleanup(s.Shutdown)
+	return s.Addr().String()
+}
+
+func TestFlightSQLReader(t *testing.T) {
+	addr := startFlightSQLServer(t, 100)
+
+	r, err := flightsource.NewFlightSQLReader(context.Background(), addr, "SELECT n FROM numbers WHERE n >= 90", &flightsource.FlightSQLReadOptions{
+		Headers: map[string]string{"authorization": "Bearer token"},
+	})
+	require.NoError(t, err)
+	defer r.Close()
+	require.Equal(t, []string{"n"}, fieldNames(r.Schema()))
+
+	var values []string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		for i := 0; i < int(rec.NumRows()); i++ {
+			values = append(values, rec.Column(0).ValueStr(i))
+		}
+		rec.Release()
+	}
+	require.Equal(t, []string{"90", "91", "92", "93", "94", "95", "96", "97", "98", "99"}, values)
+}
+
+func TestFlightSQLReaderQueryError(t *testing.T) {
+	addr := startFlightSQLServer(t, 1)
+	_, err := flightsource.NewFlightSQLReader(context.Background(), addr, "SELECT * FROM missing", nil)
+	require.ErrorContains(t, err, "no such table: missing")
+}
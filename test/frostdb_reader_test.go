@@ -0,0 +1,196 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	filesystem "github.com/arrowarc/arrowarc/integrations/filesystem"
+	frostdbwriter "github.com/arrowarc/arrowarc/integrations/frostdb"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/polarsignals/frostdb"
+	"github.com/stretchr/testify/require"
+)
+
+// frostDBEvents returns a store holding an "events" table of 100 rows with
+// ids 0..99, hosts "host-<id%4>" and sizes id*1.5.
+func frostDBEvents(t *testing.T) *frostdb.ColumnStore {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "host", Type: arrow.BinaryTypes.String},
+		{Name: "size", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	store, err := frostdb.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	w, err := frostdbwriter.NewFrostDBWriter(context.Background(), "events", schema, &frostdbwriter.FrostDBWriteOptions{Store: store})
+	require.NoError(t, err)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	for i := 0; i < 100; i++ {
+		b.Field(0).(*array.Int64Builder).Append(int64(i))
+		b.Field(1).(*array.StringBuilder).Append(fmt.Sprintf("host-%d", i%4))
+		b.Field(2).(*array.Float64Builder).Append(float64(i) * 1.5)
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+	require.NoError(t, w.Write(rec))
+	require.NoError(t, w.Close())
+	return store
+}
+
+// readFrostDB returns the rows of column name from the results of q.
+func readFrostDB(t *testing.T, store *frostdb.ColumnStore, q frostdbwriter.FrostDBQuery, name string) []string {
+	t.Helper()
+	r, err := frostdbwriter.NewFrostDBReader(context.Background(), q, &frostdbwriter.FrostDBReadOptions{Store: store})
+	require.NoError(t, err)
+	defer r.Close()
+
+	var values []string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		idx := rec.Schema().FieldIndices(name)
+		require.Len(t, idx, 1)
+		for i := 0; i < int(rec.NumRows()); i++ {
+			values = append(values, rec.Column(idx[0]).ValueStr(i))
+		}
+		rec.Release()
+	}
+	return values
+}
+
+func TestFrostDBReaderFilter(t *testing.T) {
+	store := frostDBEvents(t)
+
+	ids := readFrostDB(t, store, frostdbwriter.FrostDBQuery{
+		Table:  "events",
+		Filter: "host = 'host-1' AND id >= 80",
+	}, "id")
+	require.ElementsMatch(t, []string{"81", "85", "89", "93", "97"}, ids)
+
+	ids = readFrostDB(t, store, frostdbwriter.FrostDBQuery{
+		Table:  "events",
+		Filter: "id < 2 OR id = 99",
+	}, "id")
+	require.ElementsMatch(t, []string{"0", "1", "99"}, ids)
+}
+
+func TestFrostDBReaderProjectionAndLimit(t *testing.T) {
+	store := frostDBEvents(t)
+	r, err := frostdbwriter.NewFrostDBReader(context.Background(), frostdbwriter.FrostDBQuery{
+		Table:   "events",
+		Columns: []string{"host"},
+		Limit:   10,
+	}, &frostdbwriter.FrostDBReadOptions{Store: store})
+	require.NoError(t, err)
+	defer r.Close()
+
+	schema := r.Schema()
+	require.NotNil(t, schema)
+	require.Equal(t, []string{"host"}, fieldNames(schema))
+	var rows int64
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		rows += rec.NumRows()
+		rec.Release()
+	}
+	require.EqualValues(t, 10, rows)
+}
+
+func TestFrostDBReaderToParquet(t *testing.T) {
+	ctx := context.Background()
+	store := frostDBEvents(t)
+	r, err := frostdbwriter.NewFrostDBReader(ctx, frostdbwriter.FrostDBQuery{
+		Table:  "events",
+		Filter: "id < 50",
+	}, &frostdbwriter.FrostDBReadOptions{Store: store})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "events.parquet")
+	w, err := filesystem.NewParquetWriter(path, r.Schema(), nil)
+	require.NoError(t, err)
+	p := pipeline.NewDataPipeline(r, w).WithMonitor(nil)
+	_, err = p.Start(ctx)
+	require.NoError(t, err)
+	<-p.Done()
+
+	pr, err := filesystem.NewParquetReader(ctx, path, &filesystem.ParquetReadOptions{})
+	require.NoError(t, err)
+	defer pr.Close()
+	var rows int64
+	for {
+		rec, err := pr.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		rows += rec.NumRows()
+		rec.Release()
+	}
+	require.EqualValues(t, 50, rows)
+}
+
+func TestFrostDBReaderRejectsInvalidQueries(t *testing.T) {
+	store := frostDBEvents(t)
+	for _, tc := range []struct {
+		q   frostdbwriter.FrostDBQuery
+		err string
+	}{
+		{frostdbwriter.FrostDBQuery{}, "a table is required"},
+		{frostdbwriter.FrostDBQuery{Table: "missing"}, "missing"},
+		{frostdbwriter.FrostDBQuery{Table: "events", Filter: "nope > 1"}, `unknown column "nope"`},
+		{frostdbwriter.FrostDBQuery{Table: "events", Filter: "id = 'x'"}, `column "id"`},
+		{frostdbwriter.FrostDBQuery{Table: "events", Filter: "size > 1"}, "cannot filter on double columns"},
+		{frostdbwriter.FrostDBQuery{Table: "events", Aggregations: []string{"median(size)"}}, `unknown aggregation function "median"`},
+		{frostdbwriter.FrostDBQuery{Table: "events", Aggregations: []string{"sum size"}}, "expected function(column)"},
+		{frostdbwriter.FrostDBQuery{Table: "events", Columns: []string{"id"}, Aggregations: []string{"sum(size)"}}, "cannot be projected"},
+		{frostdbwriter.FrostDBQuery{Table: "events", GroupBy: []string{"host"}}, "group_by requires aggregations"},
+	} {
+		_, err := frostdbwriter.NewFrostDBReader(context.Background(), tc.q, &frostdbwriter.FrostDBReadOptions{Store: store})
+		require.ErrorContains(t, err, tc.err)
+	}
+}
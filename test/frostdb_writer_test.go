@@ -0,0 +1,169 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	arrowv16 "github.com/apache/arrow/go/v16/arrow"
+	v16array "github.com/apache/arrow/go/v16/arrow/array"
+	memoryv16 "github.com/apache/arrow/go/v16/arrow/memory"
+	frostdbwriter "github.com/arrowarc/arrowarc/integrations/frostdb"
+	"github.com/polarsignals/frostdb"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrostDBWriter(t *testing.T) {
+	ctx := context.Background()
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "host", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.BinaryTypes.String}},
+		{Name: "cpu", Type: arrow.PrimitiveTypes.Int16},
+		{Name: "load", Type: arrow.PrimitiveTypes.Float32, Nullable: true},
+		{Name: "ts", Type: arrow.FixedWidthTypes.Timestamp_ms},
+		{Name: "up", Type: arrow.FixedWidthTypes.Boolean},
+	}, nil)
+
+	store, err := frostdb.New()
+	require.NoError(t, err)
+	defer store.Close()
+
+	w, err := frostdbwriter.NewFrostDBWriter(ctx, "metrics", schema, &frostdbwriter.FrostDBWriteOptions{
+		Store:          store,
+		SortingColumns: []string{"host", "ts"},
+	})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for batch := 0; batch < 2; batch++ {
+		b := array.NewRecordBuilder(mem, schema)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, b.Field(0).(*array.BinaryDictionaryBuilder).AppendString([]string{"a", "b", "c"}[i]))
+			b.Field(1).(*array.Int16Builder).Append(int16(i))
+			if i == 2 {
+				b.Field(2).AppendNull()
+			} else {
+				b.Field(2).(*array.Float32Builder).Append(float32(batch + i))
+			}
+			ts, _ := arrow.TimestampFromTime(start.Add(time.Duration(batch*3+i)*time.Minute), arrow.Millisecond)
+			b.Field(3).(*array.TimestampBuilder).Append(ts)
+			b.Field(4).(*array.BooleanBuilder).Append(i != 1)
+		}
+		rec := b.NewRecord()
+		b.Release()
+		require.NoError(t, w.Write(rec))
+		rec.Release()
+	}
+	require.NoError(t, w.Close())
+
+	db, err := store.DB(ctx, frostdbwriter.DefaultDatabase)
+	require.NoError(t, err)
+
+	var rows int64
+	cpu := map[string]int64{}
+	var nulls int
+	err = query.NewEngine(memoryv16.NewGoAllocator(), db.TableProvider()).
+		ScanTable("metrics").
+		Execute(ctx, func(_ context.Context, r arrowv16.Record) error {
+			cols := map[string]arrowv16.Array{}
+			for i, f := range r.Schema().Fields() {
+				cols[f.Name] = r.Column(i)
+			}
+			for i := 0; i < int(r.NumRows()); i++ {
+				cpu[cols["host"].ValueStr(i)] += cols["cpu"].(*v16array.Int64).Value(i)
+				if cols["load"].IsNull(i) {
+					nulls++
+				}
+			}
+			rows += r.NumRows()
+			return nil
+		})
+	require.NoError(t, err)
+	require.Equal(t, int64(6), rows)
+	require.Equal(t, map[string]int64{"a": 0, "b": 2, "c": 4}, cpu)
+	require.Equal(t, 2, nulls)
+}
+
+func TestFrostDBSchemaRejectsUnsupportedTypes(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String)},
+	}, nil)
+	_, _, err := frostdbwriter.FrostDBSchema("t", schema, nil)
+	require.ErrorContains(t, err, `column "tags"`)
+
+	schema = arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	_, _, err = frostdbwriter.FrostDBSchema("t", schema, []string{"missing"})
+	require.ErrorContains(t, err, `unknown sorting column "missing"`)
+}
+
+func TestFrostDBWriterStoragePath(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Uint32},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+	}, nil)
+
+	w, err := frostdbwriter.NewFrostDBWriter(ctx, "users", schema, &frostdbwriter.FrostDBWriteOptions{StoragePath: dir})
+	require.NoError(t, err)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	b.Field(0).(*array.Uint32Builder).AppendValues([]uint32{1, 2, 3}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"x", "y", "z"}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+	require.NoError(t, w.Write(rec))
+	require.NoError(t, w.Close())
+
+	// The records are replayed from the write-ahead log.
+	store, err := frostdb.New(frostdb.WithWAL(), frostdb.WithStoragePath(dir))
+	require.NoError(t, err)
+	defer store.Close()
+	db, err := store.DB(ctx, frostdbwriter.DefaultDatabase)
+	require.NoError(t, err)
+
+	var rows int64
+	err = query.NewEngine(memoryv16.NewGoAllocator(), db.TableProvider()).
+		ScanTable("users").
+		Execute(ctx, func(_ context.Context, r arrowv16.Record) error {
+			rows += r.NumRows()
+			return nil
+		})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), rows)
+}
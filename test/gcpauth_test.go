@@ -0,0 +1,128 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/endpoints"
+	"github.com/arrowarc/arrowarc/pkg/gcpauth"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeServiceAccount serves an OAuth token endpoint and the BigQuery table
+// metadata API, and returns the key of a service account whose tokens it
+// issues, and the Authorization headers of the BigQuery requests.
+func fakeServiceAccount(t *testing.T) (key string, endpoint option.ClientOption, authorizations func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			writeJSON(w, map[string]any{"access_token": "loader-token", "token_type": "Bearer", "expires_in": 3600})
+		case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/tables/"):
+			mu.Lock()
+			seen = append(seen, r.Header.Get("Authorization"))
+			mu.Unlock()
+			writeJSON(w, map[string]any{"numBytes": "1024", "numRows": "8"})
+		default:
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusNotImplemented)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "p",
+		"private_key_id": "1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})),
+		"client_email":   "loader@p.iam.gserviceaccount.com",
+		"token_uri":      srv.URL + "/token",
+	})
+	require.NoError(t, err)
+	return string(data), option.WithEndpoint(srv.URL + "/bigquery/v2/"), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+}
+
+func TestGCPAuthEndpointOptions(t *testing.T) {
+	key, endpoint, authorizations := fakeServiceAccount(t)
+	opts := &endpoints.Options{ClientOptions: []option.ClientOption{endpoint}, Auth: gcpauth.FromKey(key)}
+	_, err := endpoints.EstimateRead(context.Background(), "bq://p/d/t", opts)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Bearer loader-token"}, authorizations())
+}
+
+func TestGCPAuthIntegrationSettings(t *testing.T) {
+	key, endpoint, authorizations := fakeServiceAccount(t)
+	t.Setenv("ARROWARC_TEST_SA_KEY", key)
+	var cfg config.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+workflow:
+  settings: {parallel_tasks: 1, retry_attempts: 1}
+  integrations:
+    - name: orders
+      type: database
+      provider: bigquery
+      mode: read
+      uri: "bq://p/d/orders"
+      auth: {credentials_json: "${ARROWARC_TEST_SA_KEY}"}
+    - {name: lake, type: storage, mode: write, uri: "out.parquet"}
+  tasks:
+    - {name: export, source: orders, destination: lake}
+`), &cfg))
+
+	estimates, err := endpoints.EstimateWorkflow(context.Background(), &cfg, &endpoints.Options{ClientOptions: []option.ClientOption{endpoint}})
+	require.NoError(t, err)
+	require.Len(t, estimates, 1)
+	require.Equal(t, []string{"Bearer loader-token"}, authorizations())
+
+	// Conflicting settings are rejected when the configuration is checked.
+	cfg.Workflow.Integrations[0].Auth.CredentialsFile = "key.json"
+	require.ErrorContains(t, cfg.Validate(), "credentials_file or credentials_json")
+}
@@ -0,0 +1,154 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bq "cloud.google.com/go/bigquery"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/converter"
+	bigquery "github.com/arrowarc/arrowarc/integrations/bigquery"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pkg/arrowproto"
+	"github.com/arrowarc/arrowarc/pkg/geo"
+	"github.com/stretchr/testify/require"
+)
+
+// writeGeoParquet writes WKT geometries to a GeoParquet file at path.
+func writeGeoParquet(t *testing.T, path string) {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "geom", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	record, _, err := array.RecordFromJSON(memory.NewGoAllocator(), schema, strings.NewReader(
+		`[{"id":1,"geom":"POINT (1 2)"},{"id":2,"geom":"LINESTRING (-3 0, 4 5)"},{"id":3,"geom":"POINT(0 -1)"}]`))
+	require.NoError(t, err)
+	defer record.Release()
+
+	writer, err := integrations.NewParquetWriterWithOptions(path, schema, &integrations.ParquetWriteOptions{Geometry: []string{"geom"}})
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+}
+
+func TestGeoParquetWriteRead(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "geo.parquet")
+	writeGeoParquet(t, path)
+
+	reader, err := integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{ChunkSize: 10})
+	require.NoError(t, err)
+	defer reader.Close()
+	record, err := reader.Read()
+	require.NoError(t, err)
+	defer record.Release()
+
+	meta, err := geo.FromSchema(record.Schema())
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	require.Equal(t, "geom", meta.PrimaryColumn)
+	col := meta.Columns["geom"]
+	require.Equal(t, geo.EncodingWKB, col.Encoding)
+	require.Equal(t, []string{"LineString", "Point"}, col.GeometryTypes)
+	require.Equal(t, []float64{-3, -1, 4, 5}, col.BBox)
+
+	require.Equal(t, arrow.BINARY, record.Column(1).DataType().ID())
+	wkt, err := geo.WKT(record.Column(1), 2)
+	require.NoError(t, err)
+	require.Equal(t, "POINT (0 -1)", wkt)
+
+	// BigQuery maps the geometry column to GEOGRAPHY, and the protobuf
+	// messages sent to it carry WKT.
+	bqSchema, err := bigquery.ArrowSchemaToBigQuery(reader.Schema())
+	require.NoError(t, err)
+	require.Equal(t, bq.GeographyFieldType, bqSchema[1].Type)
+	dp, err := arrowproto.DescriptorFromArrowSchema(reader.Schema())
+	require.NoError(t, err)
+	msgType, err := arrowproto.NewMessage(dp)
+	require.NoError(t, err)
+	msgs, err := arrowproto.ConvertArrowRecordToProtoMessages(record, msgType)
+	require.NoError(t, err)
+	m := msgs[1].ProtoReflect()
+	require.Equal(t, "LINESTRING (-3 0, 4 5)", m.Get(m.Descriptor().Fields().ByName("geom")).String())
+}
+
+func TestConvertGeoParquet(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "geo.parquet")
+	writeGeoParquet(t, input)
+
+	for _, name := range []string{"geo.csv", "geo.ndjson", "out.parquet"} {
+		_, err := converter.Convert(ctx, input, filepath.Join(dir, name), &converter.ConvertOptions{ChunkSize: 2, Verify: true})
+		require.NoError(t, err, name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "geo.csv"))
+	require.NoError(t, err)
+	require.Equal(t, "id,geom\n1,POINT (1 2)\n2,\"LINESTRING (-3 0, 4 5)\"\n3,POINT (0 -1)\n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(dir, "geo.ndjson"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), `{"geom":"POINT (1 2)","id":1}`)
+
+	// Parquet output keeps the geometry columns, summed up anew.
+	reader, err := integrations.NewParquetReader(ctx, filepath.Join(dir, "out.parquet"), &integrations.ParquetReadOptions{ChunkSize: 10})
+	require.NoError(t, err)
+	defer reader.Close()
+	meta, err := geo.FromSchema(reader.Schema())
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	require.Equal(t, []float64{-3, -1, 4, 5}, meta.Columns["geom"].BBox)
+}
+
+func TestGeoParquetBadGeometry(t *testing.T) {
+	t.Parallel()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "geom", Type: arrow.BinaryTypes.String}}, nil)
+	record, _, err := array.RecordFromJSON(memory.NewGoAllocator(), schema, strings.NewReader(`[{"geom":"CIRCLE (1 2)"}]`))
+	require.NoError(t, err)
+	defer record.Release()
+
+	writer, err := integrations.NewParquetWriterWithOptions(filepath.Join(t.TempDir(), "bad.parquet"), schema, &integrations.ParquetWriteOptions{Geometry: []string{"geom"}})
+	require.NoError(t, err)
+	require.ErrorIs(t, writer.Write(record), geo.ErrInvalidWKT)
+	require.NoError(t, writer.Close())
+
+	_, err = integrations.NewParquetWriterWithOptions(filepath.Join(t.TempDir(), "missing.parquet"), schema, &integrations.ParquetWriteOptions{Geometry: []string{"shape"}})
+	require.ErrorContains(t, err, `geometry column "shape" not found`)
+}
@@ -0,0 +1,406 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/thrift/lib/go/thrift"
+	hive "github.com/arrowarc/arrowarc/integrations/hive"
+	icebergsink "github.com/arrowarc/arrowarc/integrations/iceberg"
+	"github.com/parquet-go/parquet-go"
+	"github.com/polarsignals/iceberg-go/catalog"
+	"github.com/polarsignals/iceberg-go/table"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+// thriftValue is a Thrift value the fake metastore decodes generically:
+// a struct is a map of fields by id, a list a thriftList, a map a
+// thriftMap, and other types their Go value.
+type thriftValue struct {
+	typ thrift.TType
+	v   any
+}
+
+type thriftStruct map[int16]thriftValue
+
+type thriftList struct {
+	elem  thrift.TType
+	items []any
+}
+
+type thriftMap struct {
+	key, val thrift.TType
+	pairs    [][2]any
+}
+
+func readThrift(ctx context.Context, p thrift.TProtocol, typ thrift.TType) (any, error) {
+	switch typ {
+	case thrift.BOOL:
+		return p.ReadBool(ctx)
+	case thrift.I16:
+		return p.ReadI16(ctx)
+	case thrift.I32:
+		return p.ReadI32(ctx)
+	case thrift.I64:
+		return p.ReadI64(ctx)
+	case thrift.STRING:
+		return p.ReadString(ctx)
+	case thrift.STRUCT:
+		s := thriftStruct{}
+		p.ReadStructBegin(ctx)
+		for {
+			_, ft, id, err := p.ReadFieldBegin(ctx)
+			if err != nil || ft == thrift.STOP {
+				return s, err
+			}
+			v, err := readThrift(ctx, p, ft)
+			if err != nil {
+				return nil, err
+			}
+			s[id] = thriftValue{ft, v}
+		}
+	case thrift.LIST:
+		elem, n, err := p.ReadListBegin(ctx)
+		l := &thriftList{elem: elem}
+		for i := 0; i < n && err == nil; i++ {
+			var v any
+			v, err = readThrift(ctx, p, elem)
+			l.items = append(l.items, v)
+		}
+		return l, err
+	case thrift.MAP:
+		k, v, n, err := p.ReadMapBegin(ctx)
+		m := &thriftMap{key: k, val: v}
+		for i := 0; i < n && err == nil; i++ {
+			var kv, vv any
+			if kv, err = readThrift(ctx, p, k); err == nil {
+				vv, err = readThrift(ctx, p, v)
+			}
+			m.pairs = append(m.pairs, [2]any{kv, vv})
+		}
+		return m, err
+	}
+	return nil, fmt.Errorf("unexpected thrift type %v", typ)
+}
+
+func writeThrift(ctx context.Context, p thrift.TProtocol, typ thrift.TType, v any) {
+	switch typ {
+	case thrift.BOOL:
+		p.WriteBool(ctx, v.(bool))
+	case thrift.I16:
+		p.WriteI16(ctx, v.(int16))
+	case thrift.I32:
+		p.WriteI32(ctx, v.(int32))
+	case thrift.I64:
+		p.WriteI64(ctx, v.(int64))
+	case thrift.STRING:
+		p.WriteString(ctx, v.(string))
+	case thrift.STRUCT:
+		p.WriteStructBegin(ctx, "")
+		for id, f := range v.(thriftStruct) {
+			p.WriteFieldBegin(ctx, "", f.typ, id)
+			writeThrift(ctx, p, f.typ, f.v)
+		}
+		p.WriteFieldStop(ctx)
+	case thrift.LIST:
+		l := v.(*thriftList)
+		p.WriteListBegin(ctx, l.elem, len(l.items))
+		for _, item := range l.items {
+			writeThrift(ctx, p, l.elem, item)
+		}
+	case thrift.MAP:
+		m := v.(*thriftMap)
+		p.WriteMapBegin(ctx, m.key, m.val, len(m.pairs))
+		for _, kv := range m.pairs {
+			writeThrift(ctx, p, m.key, kv[0])
+			writeThrift(ctx, p, m.val, kv[1])
+		}
+	}
+}
+
+func thriftStrings(items ...string) thriftValue {
+	l := &thriftList{elem: thrift.STRING}
+	for _, s := range items {
+		l.items = append(l.items, s)
+	}
+	return thriftValue{thrift.LIST, l}
+}
+
+// fakeMetastore serves the Thrift calls of integrations/hive from
+// databases, tables and partitions kept in memory.
+type fakeMetastore struct {
+	addr       string
+	mu         sync.Mutex
+	databases  []string
+	tables     map[string]thriftStruct
+	partitions map[string][]fakePartition
+}
+
+// fakePartition is a partition and its spec, such as region=eu, which
+// filters of the form region = "eu" match.
+type fakePartition struct {
+	spec     string
+	values   []string
+	location string
+}
+
+func newFakeMetastore(t *testing.T) *fakeMetastore {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeMetastore{addr: ln.Addr().String(), tables: map[string]thriftStruct{}, partitions: map[string][]fakePartition{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeMetastore) addPartition(db, name, spec, location string, values ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.partitions[db+"."+name] = append(f.partitions[db+"."+name], fakePartition{spec, values, location})
+}
+
+func (f *fakeMetastore) serve(conn net.Conn) {
+	defer conn.Close()
+	ctx := context.Background()
+	transport := thrift.NewTBufferedTransport(thrift.NewTSocketFromConnConf(conn, nil), 8192)
+	p := thrift.NewTBinaryProtocolConf(transport, nil)
+	for {
+		method, _, seq, err := p.ReadMessageBegin(ctx)
+		if err != nil {
+			return
+		}
+		args, err := readThrift(ctx, p, thrift.STRUCT)
+		if err != nil {
+			return
+		}
+		p.ReadMessageEnd(ctx)
+		f.mu.Lock()
+		result := f.handle(method, args.(thriftStruct))
+		f.mu.Unlock()
+		p.WriteMessageBegin(ctx, method, thrift.REPLY, seq)
+		writeThrift(ctx, p, thrift.STRUCT, result)
+		p.WriteMessageEnd(ctx)
+		if p.Flush(ctx) != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeMetastore) handle(method string, args thriftStruct) thriftStruct {
+	str := func(s thriftStruct, id int16) string { v, _ := s[id].v.(string); return v }
+	exception := func(id int16, msg string) thriftStruct {
+		return thriftStruct{id: {thrift.STRUCT, thriftStruct{1: {thrift.STRING, msg}}}}
+	}
+	switch method {
+	case "get_all_databases":
+		return thriftStruct{0: thriftStrings(f.databases...)}
+	case "create_database":
+		f.databases = append(f.databases, str(args[1].v.(thriftStruct), 1))
+		return thriftStruct{}
+	case "get_all_tables":
+		var names []string
+		for key := range f.tables {
+			if db, name, _ := strings.Cut(key, "."); db == str(args, 1) {
+				names = append(names, name)
+			}
+		}
+		return thriftStruct{0: thriftStrings(names...)}
+	case "get_table":
+		if t, ok := f.tables[str(args, 1)+"."+str(args, 2)]; ok {
+			return thriftStruct{0: {thrift.STRUCT, t}}
+		}
+		return exception(2, "table not found")
+	case "create_table":
+		t := args[1].v.(thriftStruct)
+		f.tables[str(t, 2)+"."+str(t, 1)] = t
+		return thriftStruct{}
+	case "alter_table":
+		t := args[3].v.(thriftStruct)
+		delete(f.tables, str(args, 1)+"."+str(args, 2))
+		f.tables[str(t, 2)+"."+str(t, 1)] = t
+		return thriftStruct{}
+	case "get_partitions", "get_partitions_by_filter":
+		filter := strings.ReplaceAll(strings.ReplaceAll(str(args, 3), " ", ""), `"`, "")
+		l := &thriftList{elem: thrift.STRUCT}
+		for _, p := range f.partitions[str(args, 1)+"."+str(args, 2)] {
+			if method == "get_partitions" || p.spec == filter {
+				l.items = append(l.items, thriftStruct{
+					1: thriftStrings(p.values...),
+					6: {thrift.STRUCT, thriftStruct{2: {thrift.STRING, p.location}}},
+				})
+			}
+		}
+		return thriftStruct{0: {thrift.LIST, l}}
+	}
+	return exception(1, "unexpected call "+method)
+}
+
+func TestHiveIcebergCatalog(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fake := newFakeMetastore(t)
+	ms, err := hive.NewMetastore(ctx, "thrift://"+fake.addr, nil)
+	require.NoError(t, err)
+	defer ms.Close()
+
+	const warehouse = "warehouse"
+	bucket := objstore.NewInMemBucket()
+	ctlg := hive.NewIcebergCatalog(ms, warehouse, bucket)
+	require.NoError(t, ctlg.CreateNamespace(ctx, table.Identifier{"db"}, nil))
+	namespaces, err := ctlg.ListNamespaces(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, []table.Identifier{{"db"}}, namespaces)
+
+	_, err = ctlg.LoadTable(ctx, table.Identifier{"db", "events"}, nil)
+	require.ErrorIs(t, err, catalog.ErrorTableNotFound)
+
+	// The Iceberg integration creates the table, then commits each upload
+	// through the metastore.
+	berg, err := icebergsink.NewIceberg(warehouse, ctlg, bucket, icebergsink.WithCompaction(2, 1024*1024))
+	require.NoError(t, err)
+	defer berg.Close()
+	for n := 0; n < 2; n++ {
+		var buf bytes.Buffer
+		require.NoError(t, parquet.Write(&buf, []icebergRow{{"eu", int64(n)}}))
+		require.NoError(t, berg.Upload(ctx, fmt.Sprintf("db/events/%d/data.parquet", n), &buf))
+	}
+
+	hiveTable, err := ms.Table(ctx, "db", "events")
+	require.NoError(t, err)
+	require.Equal(t, hive.ExternalTable, hiveTable.Type)
+	require.Equal(t, "ICEBERG", hiveTable.Parameters[hive.ParamTableType])
+	require.Equal(t, "warehouse/db/events/metadata/v2.metadata.json", hiveTable.Parameters[hive.ParamMetadataLocation])
+	require.Equal(t, "warehouse/db/events/metadata/v1.metadata.json", hiveTable.Parameters[hive.ParamPreviousMetadataLocation])
+
+	// Plain Hive tables are not listed.
+	require.NoError(t, ms.CreateTable(ctx, &hive.Table{Database: "db", Name: "logs", Type: hive.ExternalTable, Location: "/tmp/logs"}))
+	tables, err := ctlg.ListTables(ctx, table.Identifier{"db"})
+	require.NoError(t, err)
+	require.Equal(t, []table.Identifier{{"db", "events"}}, tables)
+
+	tbl, err := ctlg.LoadTable(ctx, table.Identifier{"db", "events"}, nil)
+	require.NoError(t, err)
+	require.Len(t, icebergDataFiles(t, tbl), 2)
+
+	// Maintenance finds the table through the metastore and compacts it.
+	require.NoError(t, berg.Maintenance(ctx))
+	compacted, err := ctlg.LoadTable(ctx, table.Identifier{"db", "events"}, nil)
+	require.NoError(t, err)
+	require.Len(t, icebergDataFiles(t, compacted), 1)
+	require.Equal(t, "warehouse/db/events/metadata/v3.metadata.json", compacted.MetadataLocation())
+
+	// A writer of the table as it was before maintenance loses the race.
+	w, err := tbl.SnapshotWriter()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, parquet.Write(&buf, []icebergRow{{"us", 9}}))
+	require.NoError(t, w.Append(ctx, &buf))
+	require.ErrorIs(t, w.Close(ctx), hive.ErrCommitConflict)
+}
+
+func TestHiveParquetReader(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fake := newFakeMetastore(t)
+	ms, err := hive.NewMetastore(ctx, fake.addr, nil)
+	require.NoError(t, err)
+	defer ms.Close()
+
+	dir := t.TempDir()
+	for _, region := range []string{"eu", "us"} {
+		rows := map[string][]icebergRow{"eu": {{"a", 1}, {"b", 2}}, "us": {{"c", 3}}}[region]
+		partition := filepath.Join(dir, "part="+region)
+		require.NoError(t, os.MkdirAll(partition, 0o755))
+		var buf bytes.Buffer
+		require.NoError(t, parquet.Write(&buf, rows))
+		require.NoError(t, os.WriteFile(filepath.Join(partition, "000000_0"), buf.Bytes(), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(partition, "_SUCCESS"), nil, 0o644))
+		fake.addPartition("db", "logs", "part="+region, "file://"+partition, region)
+	}
+	require.NoError(t, ms.CreateTable(ctx, &hive.Table{
+		Database:      "db",
+		Name:          "logs",
+		Type:          hive.ExternalTable,
+		Location:      "file://" + dir,
+		InputFormat:   "org.apache.hadoop.hive.ql.io.parquet.MapredParquetInputFormat",
+		SerDe:         "org.apache.hadoop.hive.ql.io.parquet.serde.ParquetHiveSerDe",
+		Columns:       []hive.Column{{Name: "region", Type: "string"}, {Name: "value", Type: "bigint"}},
+		PartitionKeys: []hive.Column{{Name: "part", Type: "string"}},
+	}))
+
+	read := func(filter string) []string {
+		reader, err := hive.NewHiveReader(ctx, ms, "db", "logs", &hive.HiveReadOptions{Filter: filter})
+		require.NoError(t, err)
+		defer reader.Close()
+		require.Equal(t, []string{"region", "value", "part"}, fieldNames(reader.Schema()))
+		var rows []string
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return rows
+			}
+			require.NoError(t, err)
+			for i := 0; i < int(record.NumRows()); i++ {
+				rows = append(rows, fmt.Sprintf("%s %d %s",
+					record.Column(0).(*array.String).Value(i),
+					record.Column(1).(*array.Int64).Value(i),
+					record.Column(2).(*array.String).Value(i)))
+			}
+			record.Release()
+		}
+	}
+	require.Equal(t, []string{"a 1 eu", "b 2 eu", "c 3 us"}, read(""))
+	require.Equal(t, []string{"c 3 us"}, read(`part = "us"`))
+
+	_, err = hive.NewHiveReader(ctx, ms, "db", "missing", nil)
+	require.ErrorIs(t, err, hive.ErrNoSuchObject)
+}
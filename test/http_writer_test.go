@@ -0,0 +1,211 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pkg/endpoints"
+	"github.com/arrowarc/arrowarc/pkg/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// upload is a request received by an uploadServer.
+type upload struct {
+	query  string
+	header http.Header
+	body   []byte
+}
+
+// uploadServer records the uploads it receives, answering each with the
+// next of statuses, then 204.
+func uploadServer(t *testing.T, statuses ...int) (*httptest.Server, func() []upload) {
+	var (
+		mu      sync.Mutex
+		uploads []upload
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+		data, _ := io.ReadAll(body)
+		mu.Lock()
+		defer mu.Unlock()
+		uploads = append(uploads, upload{query: r.URL.RawQuery, header: r.Header, body: data})
+		if len(statuses) > 0 {
+			w.WriteHeader(statuses[0])
+			statuses = statuses[1:]
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []upload {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]upload(nil), uploads...)
+	}
+}
+
+func uploadRecord(t *testing.T, ids ...int64) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	for _, id := range ids {
+		b.Field(0).(*array.Int64Builder).Append(id)
+		b.Field(1).(*array.StringBuilder).Append(string(rune('a' + id)))
+	}
+	return b.NewRecord()
+}
+
+func TestHTTPWriterIPC(t *testing.T) {
+	srv, uploads := uploadServer(t)
+	rec := uploadRecord(t, 0, 1, 2, 3, 4)
+	defer rec.Release()
+
+	w, err := integrations.NewHTTPWriter(context.Background(), srv.URL+"/ingest", rec.Schema(), &integrations.HTTPWriteOptions{
+		Client:      srv.Client(),
+		BearerToken: "secret",
+		Gzip:        true,
+		BatchRows:   3,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Write(rec))
+	require.NoError(t, w.Write(rec))
+	require.NoError(t, w.Close())
+	require.EqualValues(t, 4, w.Batches())
+
+	var ids []int64
+	for _, u := range uploads() {
+		require.Equal(t, "Bearer secret", u.header.Get("Authorization"))
+		require.Equal(t, "application/vnd.apache.arrow.stream", u.header.Get("Content-Type"))
+		r, err := ipc.NewReader(bytes.NewReader(u.body))
+		require.NoError(t, err)
+		require.True(t, r.Schema().Equal(rec.Schema()))
+		var rows int64
+		for r.Next() {
+			rows += r.Record().NumRows()
+			ids = append(ids, r.Record().Column(0).(*array.Int64).Int64Values()...)
+		}
+		require.NoError(t, r.Err())
+		r.Release()
+		require.LessOrEqual(t, rows, int64(3))
+	}
+	require.Equal(t, []int64{0, 1, 2, 3, 4, 0, 1, 2, 3, 4}, ids)
+}
+
+func TestHTTPWriterFormats(t *testing.T) {
+	rec := uploadRecord(t, 1, 2)
+	defer rec.Release()
+	for format, want := range map[string]string{
+		integrations.HTTPFormatNDJSON: `{"id":1,"name":"b"}` + "\n" + `{"id":2,"name":"c"}` + "\n",
+		integrations.HTTPFormatCSV:    "id,name\n1,b\n2,c\n",
+	} {
+		srv, uploads := uploadServer(t)
+		w, err := integrations.NewHTTPWriter(context.Background(), srv.URL, rec.Schema(), &integrations.HTTPWriteOptions{Client: srv.Client(), Format: format})
+		require.NoError(t, err)
+		require.NoError(t, w.Write(rec))
+		require.NoError(t, w.Close())
+		got := uploads()
+		require.Len(t, got, 1, format)
+		require.Equal(t, want, string(got[0].body), format)
+	}
+}
+
+func TestHTTPWriterRetry(t *testing.T) {
+	rec := uploadRecord(t, 1)
+	defer rec.Release()
+	policy := &retry.Policy{MaxAttempts: 3, InitialInterval: time.Millisecond}
+
+	srv, uploads := uploadServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	w, err := integrations.NewHTTPWriter(context.Background(), srv.URL, rec.Schema(), &integrations.HTTPWriteOptions{Client: srv.Client(), Retry: policy})
+	require.NoError(t, err)
+	require.NoError(t, w.Write(rec))
+	require.NoError(t, w.Close())
+	require.Len(t, uploads(), 3, "two failures and a success")
+
+	srv, uploads = uploadServer(t, http.StatusBadRequest)
+	w, err = integrations.NewHTTPWriter(context.Background(), srv.URL, rec.Schema(), &integrations.HTTPWriteOptions{Client: srv.Client(), Retry: policy})
+	require.NoError(t, err)
+	require.NoError(t, w.Write(rec))
+	err = w.Close()
+	var status *integrations.HTTPStatusError
+	require.ErrorAs(t, err, &status)
+	require.Equal(t, http.StatusBadRequest, status.Code)
+	require.Len(t, uploads(), 1, "client errors are not retried")
+}
+
+func TestHTTPEndpointWriter(t *testing.T) {
+	srv, uploads := uploadServer(t)
+	rec := uploadRecord(t, 1, 2, 3)
+	defer rec.Release()
+	opts := &endpoints.Options{HTTP: &integrations.HTTPOptions{Client: srv.Client(), Credentials: []integrations.HTTPCredentials{
+		{Prefix: srv.URL + "/ingest", BearerToken: "secret"},
+		{Prefix: srv.URL + "/other", Headers: http.Header{"X-Other": {"no"}}},
+	}}}
+
+	w, err := endpoints.NewWriter(context.Background(), srv.URL+"/ingest?format=ndjson&batch_rows=2&gzip=true&source=arrowarc", rec.Schema(), opts)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(rec))
+	require.NoError(t, w.Close())
+
+	got := uploads()
+	require.Len(t, got, 2)
+	require.Equal(t, "source=arrowarc", got[0].query, "writer parameters are not sent")
+	require.Equal(t, "Bearer secret", got[0].header.Get("Authorization"))
+	require.Empty(t, got[0].header.Get("X-Other"), "credentials of other prefixes are not sent")
+	require.Equal(t, "application/x-ndjson", got[0].header.Get("Content-Type"))
+	require.Equal(t, `{"id":3,"name":"d"}`+"\n", string(got[1].body))
+
+	_, err = integrations.NewHTTPWriter(context.Background(), "http://localhost/ingest", rec.Schema(), &integrations.HTTPWriteOptions{BearerToken: "secret"})
+	require.ErrorContains(t, err, "refusing to send a bearer token over plain http")
+}
@@ -0,0 +1,136 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	icebergsink "github.com/arrowarc/arrowarc/integrations/iceberg"
+	"github.com/parquet-go/parquet-go"
+	"github.com/polarsignals/iceberg-go"
+	"github.com/polarsignals/iceberg-go/catalog"
+	"github.com/polarsignals/iceberg-go/table"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+type icebergRow struct {
+	Region string `parquet:"region"`
+	Value  int64  `parquet:"value"`
+}
+
+// icebergDataFiles returns the row counts of the data files of the current
+// snapshot of t, by path.
+func icebergDataFiles(t *testing.T, tbl table.Table) map[string]int64 {
+	t.Helper()
+	bucket := tbl.Bucket()
+	manifests, err := tbl.CurrentSnapshot().Manifests(bucket)
+	require.NoError(t, err)
+	files := map[string]int64{}
+	for _, m := range manifests {
+		entries, _, err := m.FetchEntries(bucket, true)
+		require.NoError(t, err)
+		for _, e := range entries {
+			files[e.DataFile().FilePath()] = e.DataFile().Count()
+		}
+	}
+	return files
+}
+
+func TestIcebergCompaction(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	const uri = "warehouse"
+	bucket := objstore.NewInMemBucket()
+	ctlg := catalog.NewHDFS(uri, bucket)
+	spec := iceberg.NewPartitionSpec(iceberg.PartitionField{Name: "region", Transform: iceberg.IdentityTransform{}})
+	berg, err := icebergsink.NewIceberg(uri, ctlg, bucket,
+		icebergsink.WithIcebergPartitionSpec(spec),
+		icebergsink.WithCompaction(3, 1024*1024),
+		icebergsink.WithReadCache(icebergsink.NewBlockCache(0, 0)),
+	)
+	require.NoError(t, err)
+	defer berg.Close()
+
+	// Three small files of region us, two of eu and one spanning both.
+	uploads := [][]icebergRow{
+		{{"us", 1}, {"us", 2}},
+		{{"us", 3}},
+		{{"eu", 4}},
+		{{"us", 5}, {"us", 6}},
+		{{"eu", 7}},
+		{{"eu", 8}, {"us", 9}},
+	}
+	for n, rows := range uploads {
+		var buf bytes.Buffer
+		require.NoError(t, parquet.Write(&buf, rows))
+		require.NoError(t, berg.Upload(ctx, fmt.Sprintf("db/events/%d/data.parquet", n), &buf))
+	}
+
+	tbl, err := ctlg.LoadTable(ctx, []string{uri + "/db/events"}, iceberg.Properties{})
+	require.NoError(t, err)
+	require.Len(t, icebergDataFiles(t, tbl), 6)
+
+	require.NoError(t, berg.Compact(ctx, tbl))
+	tbl, err = ctlg.LoadTable(ctx, []string{uri + "/db/events"}, iceberg.Properties{})
+	require.NoError(t, err)
+	files := icebergDataFiles(t, tbl)
+	require.Len(t, files, 4)
+
+	// The us files were merged into one; the others are left as they were.
+	var counts []int64
+	var total int64
+	for path, count := range files {
+		counts = append(counts, count)
+		total += count
+		if count != 5 {
+			continue
+		}
+		data, err := tbl.Bucket().Get(ctx, path)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(data)
+		require.NoError(t, err)
+		rows, err := parquet.Read[icebergRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		require.Equal(t, []icebergRow{{"us", 1}, {"us", 2}, {"us", 3}, {"us", 5}, {"us", 6}}, rows)
+	}
+	require.ElementsMatch(t, []int64{5, 1, 1, 2}, counts)
+	require.EqualValues(t, 9, total)
+
+	// Compacting again finds nothing left to merge.
+	require.NoError(t, berg.Compact(ctx, tbl))
+	tbl, err = ctlg.LoadTable(ctx, []string{uri + "/db/events"}, iceberg.Properties{})
+	require.NoError(t, err)
+	require.Len(t, icebergDataFiles(t, tbl), 4)
+}
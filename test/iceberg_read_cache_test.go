@@ -0,0 +1,108 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	icebergsink "github.com/arrowarc/arrowarc/integrations/iceberg"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+// rangeCountingBucket counts the range requests made to a bucket.
+type rangeCountingBucket struct {
+	objstore.Bucket
+	ranges atomic.Int64
+}
+
+func (b *rangeCountingBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	b.ranges.Add(1)
+	return b.Bucket.GetRange(ctx, name, off, length)
+}
+
+func TestBucketReaderAtCache(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	data := make([]byte, 990)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	bucket := &rangeCountingBucket{Bucket: objstore.NewInMemBucket()}
+	require.NoError(t, bucket.Upload(ctx, "a.parquet", bytes.NewReader(data)))
+
+	cache := icebergsink.NewBlockCache(100, 500)
+	read := func(name string, off int64, n int) ([]byte, error) {
+		r, err := icebergsink.NewCachedBucketReaderAt(bucket, cache).GetReaderAt(ctx, name)
+		require.NoError(t, err)
+		p := make([]byte, n)
+		n, err = r.ReadAt(p, off)
+		return p[:n], err
+	}
+
+	// The footer is read with one request, then from the cache.
+	for range 3 {
+		p, err := read("a.parquet", 940, 50)
+		require.NoError(t, err)
+		require.Equal(t, data[940:], p)
+	}
+	require.EqualValues(t, 1, bucket.ranges.Load())
+
+	// A read spanning cached and missing blocks fetches the missing ones
+	// with a single request.
+	p, err := read("a.parquet", 750, 240)
+	require.NoError(t, err)
+	require.Equal(t, data[750:], p)
+	require.EqualValues(t, 2, bucket.ranges.Load())
+	stats := cache.Stats()
+	require.EqualValues(t, 290, stats.Bytes)
+	require.EqualValues(t, 2, stats.Requests)
+
+	// Reads past the end return what there is.
+	p, err = read("a.parquet", 980, 40)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, data[980:], p)
+	require.EqualValues(t, 2, bucket.ranges.Load())
+
+	// The least recently used blocks are evicted over budget.
+	p, err = read("a.parquet", 0, 400)
+	require.NoError(t, err)
+	require.Equal(t, data[:400], p)
+	stats = cache.Stats()
+	require.EqualValues(t, 490, stats.Bytes)
+	require.EqualValues(t, 2, stats.Evictions)
+	_, err = read("a.parquet", 750, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, bucket.ranges.Load())
+}
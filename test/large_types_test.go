@@ -0,0 +1,139 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func buildLargeTypesRecord(t *testing.T) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "s", Type: arrow.BinaryTypes.LargeString, Nullable: true},
+		{Name: "b", Type: arrow.BinaryTypes.LargeBinary, Nullable: true},
+		{Name: "l", Type: arrow.LargeListOf(arrow.PrimitiveTypes.Int64), Nullable: true},
+		{Name: "ls", Type: arrow.LargeListOf(arrow.BinaryTypes.LargeString), Nullable: true},
+	}, nil)
+	record, _, err := array.RecordFromJSON(memory.NewGoAllocator(), schema, strings.NewReader(
+		`[{"s":"a","b":"AQI=","l":[1,2],"ls":["x"]},{"s":null,"b":null,"l":null,"ls":[]}]`))
+	require.NoError(t, err)
+	return record
+}
+
+func TestParquetLargeTypes(t *testing.T) {
+	t.Parallel()
+	record := buildLargeTypesRecord(t)
+	defer record.Release()
+	path := filepath.Join(t.TempDir(), "large.parquet")
+
+	writer, err := integrations.NewParquetWriterWithOptions(path, record.Schema(), &integrations.ParquetWriteOptions{})
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	reader, err := integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{ChunkSize: 10})
+	require.NoError(t, err)
+	defer reader.Close()
+	got, err := reader.Read()
+	require.NoError(t, err)
+	defer got.Release()
+
+	// Large strings and binaries come back as they were written, large
+	// lists as lists.
+	require.Equal(t, arrow.LARGE_STRING, got.Column(0).DataType().ID())
+	require.Equal(t, arrow.LARGE_BINARY, got.Column(1).DataType().ID())
+	require.Equal(t, arrow.LIST, got.Column(2).DataType().ID())
+	require.Equal(t, arrow.LARGE_STRING, got.Column(3).DataType().(*arrow.ListType).Elem().ID())
+	for i := 0; i < int(record.NumCols()); i++ {
+		for row := 0; row < int(record.NumRows()); row++ {
+			require.Equal(t, record.Column(i).ValueStr(row), got.Column(i).ValueStr(row), "column %d row %d", i, row)
+		}
+	}
+}
+
+func TestParquetReadLarge(t *testing.T) {
+	t.Parallel()
+	record := buildCSVTestRecord(t)
+	defer record.Release()
+	path := filepath.Join(t.TempDir(), "strings.parquet")
+
+	writer, err := integrations.NewParquetWriter(path, record.Schema(), integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	reader, err := integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{ChunkSize: 10, ReadLarge: true})
+	require.NoError(t, err)
+	defer reader.Close()
+	got, err := reader.Read()
+	require.NoError(t, err)
+	defer got.Release()
+	require.Equal(t, arrow.LARGE_STRING, got.Column(1).DataType().ID())
+	require.Equal(t, `say "hi", bob`, got.Column(1).(*array.LargeString).Value(0))
+}
+
+func TestWriteLargeTypes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	record := buildLargeTypesRecord(t)
+	defer record.Release()
+	dir := t.TempDir()
+
+	csvWriter, err := integrations.NewCSVWriter(ctx, filepath.Join(dir, "large.csv"), record.Schema(), nil)
+	require.NoError(t, err)
+	require.NoError(t, csvWriter.Write(record))
+	require.NoError(t, csvWriter.Close())
+	data, err := os.ReadFile(filepath.Join(dir, "large.csv"))
+	require.NoError(t, err)
+	require.Equal(t, "s,b,l,ls\na,AQI=,\"[1,2]\",\"[\"\"x\"\"]\"\n,,,[]\n", string(data))
+
+	jsonWriter, err := integrations.NewNDJSONWriter(ctx, filepath.Join(dir, "large.ndjson"))
+	require.NoError(t, err)
+	require.NoError(t, jsonWriter.Write(record))
+	require.NoError(t, jsonWriter.Close())
+	data, err = os.ReadFile(filepath.Join(dir, "large.ndjson"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), `{"b":"AQI=","l":[1,2],"ls":["x"],"s":"a"}`)
+
+	avroWriter, err := integrations.NewAvroWriter(ctx, filepath.Join(dir, "large.avro"), record.Schema(), nil)
+	require.NoError(t, err)
+	require.NoError(t, avroWriter.Write(record))
+	require.NoError(t, avroWriter.Close())
+}
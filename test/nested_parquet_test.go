@@ -0,0 +1,116 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/converter"
+	x "github.com/arrowarc/arrowarc/experiments"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/stretchr/testify/require"
+)
+
+// writeNestedParquet writes a Parquet file with map and fixed-size list
+// columns.
+func writeNestedParquet(t *testing.T, path string, rows string) {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "attrs", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int64), Nullable: true},
+		{Name: "point", Type: arrow.FixedSizeListOf(2, arrow.PrimitiveTypes.Float64), Nullable: true},
+	}, nil)
+	record, _, err := array.RecordFromJSON(memory.NewGoAllocator(), schema, strings.NewReader(rows))
+	require.NoError(t, err)
+	defer record.Release()
+
+	writer, err := integrations.NewParquetWriter(path, schema, integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+}
+
+func TestParquetRowsNestedColumns(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "nested.parquet")
+	writeNestedParquet(t, path, `[
+		{"id": 1, "attrs": [{"key": "a", "value": 1}], "point": [1.5, 2]},
+		{"id": 2, "attrs": null, "point": null}
+	]`)
+
+	rows, err := x.NewParquetRowsReader(context.Background(), path)
+	require.NoError(t, err)
+	defer rows.Close()
+	require.Equal(t, "string", rows.ColumnTypeScanType(1).String())
+
+	dest := make([]driver.Value, len(rows.Columns()))
+	require.NoError(t, rows.Next(dest))
+	require.Equal(t, []driver.Value{int64(1), `[{"key":"a","value":1}]`, `[1.5,2]`}, dest)
+	require.NoError(t, rows.Next(dest))
+	require.Equal(t, []driver.Value{int64(2), nil, nil}, dest)
+	require.Equal(t, io.EOF, rows.Next(dest))
+}
+
+func TestConvertNestedParquet(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	from := filepath.Join(dir, "nested.parquet")
+	writeNestedParquet(t, from, `[
+		{"id": 1, "attrs": [{"key": "a", "value": 1}, {"key": "b", "value": 2}], "point": [1.5, 2]},
+		{"id": 2, "attrs": [], "point": [3, 4]}
+	]`)
+
+	for _, ext := range []string{"parquet", "arrow", "avro", "ndjson", "csv"} {
+		to := filepath.Join(dir, "out."+ext)
+		_, err := converter.Convert(context.Background(), from, to, &converter.ConvertOptions{ChunkSize: 10, Verify: true})
+		require.NoError(t, err, ext)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "out.ndjson"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"attrs":[{"key":"a","value":1},{"key":"b","value":2}]`)
+
+	// Read back, the JSON arrays and objects are nested columns again.
+	reader, err := converter.OpenInput(context.Background(), filepath.Join(dir, "out.ndjson"), nil)
+	require.NoError(t, err)
+	defer reader.Close()
+	attrs, ok := reader.Schema().FieldsByName("attrs")
+	require.True(t, ok)
+	require.Equal(t, "list<item: struct<key: utf8, value: float64>, nullable>", attrs[0].Type.String())
+}
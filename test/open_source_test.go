@@ -0,0 +1,81 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/preview"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSourceDetectsFormat(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	csvPath := filepath.Join(dir, "people.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("id,name\n1,ada\n2,grace\n3,edsger\n"), 0o644))
+	csvReader, err := integrations.OpenSource(ctx, csvPath, &integrations.SourceOptions{
+		CSV: csvschema.CSVReadOptions{HasHeader: true},
+	})
+	require.NoError(t, err)
+	defer csvReader.Close()
+	require.Equal(t, "id", csvReader.Schema().Field(0).Name)
+
+	parquetPath := filepath.Join(dir, "people.parquet")
+	writer, err := integrations.NewParquetWriter(parquetPath, csvReader.Schema(), integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+	for {
+		rec, err := csvReader.Read()
+		if err != nil {
+			break
+		}
+		require.NoError(t, writer.Write(rec))
+		rec.Release()
+	}
+	require.NoError(t, writer.Close())
+
+	parquetReader, err := integrations.OpenSource(ctx, parquetPath, nil)
+	require.NoError(t, err)
+	defer parquetReader.Close()
+
+	var out bytes.Buffer
+	require.NoError(t, preview.Tail(parquetReader, 1, &out, preview.Options{Format: preview.JSON}))
+	require.Equal(t, `{"id":3,"name":"edsger"}`+"\n", out.String())
+
+	_, err = integrations.OpenSource(ctx, filepath.Join(dir, "people.xlsx"), nil)
+	require.ErrorContains(t, err, "cannot detect the format")
+}
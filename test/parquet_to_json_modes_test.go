@@ -0,0 +1,129 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/stretchr/testify/require"
+)
+
+// writeNestedJSONParquet writes rows with struct, list and map columns to a
+// Parquet file at path.
+func writeNestedJSONParquet(t *testing.T, path string) {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "city", Type: arrow.StructOf(
+			arrow.Field{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		), Nullable: true},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		{Name: "attrs", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int64), Nullable: true},
+	}, nil)
+	record, _, err := array.RecordFromJSON(memory.NewGoAllocator(), schema, strings.NewReader(`[
+		{"id": 1, "city": {"name": "Oslo"}, "tags": ["a", "b"], "attrs": [{"key": "x", "value": 1}]},
+		{"id": 2, "city": null, "tags": null, "attrs": null}
+	]`))
+	require.NoError(t, err)
+	defer record.Release()
+
+	writer, err := integrations.NewParquetWriter(path, schema, nil)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+}
+
+func TestConvertParquetToJSONModes(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "nested.parquet")
+	writeNestedJSONParquet(t, input)
+
+	convert := func(name string, includeStructs bool, opts *integrations.JSONWriteOptions) string {
+		t.Helper()
+		output := filepath.Join(dir, name)
+		// One row per record, so that the array modes span records.
+		_, err := converter.ConvertParquetToJSONWithOptions(context.Background(), input, output, &converter.ParquetToJSONOptions{
+			ChunkSize:      1,
+			Concurrency:    1,
+			IncludeStructs: includeStructs,
+			JSON:           opts,
+		})
+		require.NoError(t, err)
+		file, err := os.Open(output)
+		require.NoError(t, err)
+		defer file.Close()
+		var r io.Reader = file
+		if strings.HasSuffix(name, ".gz") {
+			gz, err := gzip.NewReader(file)
+			require.NoError(t, err)
+			defer gz.Close()
+			r = gz
+		}
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	got := convert("array.json", true, &integrations.JSONWriteOptions{Mode: integrations.JSONModeArray})
+	require.Equal(t, `[{"id":1,"city":{"name":"Oslo"},"tags":["a","b"],"attrs":{"x":1}},{"id":2,"city":null,"tags":null,"attrs":null}]`+"\n", got)
+	require.True(t, json.Valid([]byte(got)))
+
+	got = convert("lines.json", false, &integrations.JSONWriteOptions{Mode: integrations.JSONModeNDJSON, NullValue: `""`})
+	require.Equal(t, `{"id":1,"city_name":"Oslo","tags":["a","b"],"attrs":{"x":1}}`+"\n"+
+		`{"id":2,"city_name":"","tags":"","attrs":""}`+"\n", got)
+
+	got = convert("pretty.json.gz", true, &integrations.JSONWriteOptions{Mode: integrations.JSONModePretty})
+	var rows []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(got), &rows))
+	require.Len(t, rows, 2)
+	require.Equal(t, map[string]any{"name": "Oslo"}, rows[0]["city"])
+	require.True(t, strings.HasPrefix(got, "[\n  {\n    \"id\": 1,"), got)
+
+	_, err := converter.ConvertParquetToJSONWithOptions(context.Background(), input, filepath.Join(dir, "bad.json"), &converter.ParquetToJSONOptions{
+		ChunkSize:      1,
+		Concurrency:    1,
+		IncludeStructs: true,
+		JSON:           &integrations.JSONWriteOptions{Mode: integrations.JSONModeArray, NullValue: "N/A"},
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,132 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+type ackCall struct {
+	records      int64
+	final        bool
+	writerClosed bool
+}
+
+// ackingReader returns n one-row records and records its acknowledgements.
+type ackingReader struct {
+	n, read int
+	writer  *closeTrackingWriter
+	acks    []ackCall
+}
+
+func (r *ackingReader) Read() (arrow.Record, error) {
+	if r.read == r.n {
+		return nil, io.EOF
+	}
+	r.read++
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil))
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).Append(int64(r.read))
+	return b.NewRecord(), nil
+}
+
+func (r *ackingReader) Acknowledge(records int64, final bool) error {
+	r.acks = append(r.acks, ackCall{records: records, final: final, writerClosed: r.writer.closed})
+	return nil
+}
+
+func (r *ackingReader) Close() error { return nil }
+
+type closeTrackingWriter struct {
+	closed   bool
+	closeErr error
+}
+
+func (w *closeTrackingWriter) Write(arrow.Record) error { return nil }
+
+func (w *closeTrackingWriter) Close() error {
+	w.closed = true
+	return w.closeErr
+}
+
+func TestPipelineAcknowledgesWrittenRecords(t *testing.T) {
+	writer := &closeTrackingWriter{}
+	reader := &ackingReader{n: 3, writer: writer}
+
+	_, err := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil).Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []ackCall{
+		{records: 1},
+		{records: 2},
+		{records: 3},
+		{records: 3, final: true, writerClosed: true},
+	}, reader.acks)
+}
+
+func TestPipelineSkipsFinalAckWhenCloseFails(t *testing.T) {
+	writer := &closeTrackingWriter{closeErr: errors.New("disk full")}
+	reader := &ackingReader{n: 2, writer: writer}
+
+	_, err := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil).Start(context.Background())
+	require.ErrorContains(t, err, "disk full")
+	for _, ack := range reader.acks {
+		require.False(t, ack.final)
+	}
+}
+
+// holdingTransformer holds back every record, and fails to flush them.
+type holdingTransformer struct{}
+
+func (holdingTransformer) Transform(rec arrow.Record) (arrow.Record, error) {
+	rec.Release()
+	return nil, nil
+}
+
+func (holdingTransformer) Flush() (arrow.Record, error) {
+	return nil, errors.New("flush failed")
+}
+
+func TestPipelineSkipsFinalAckWhenFlushFails(t *testing.T) {
+	writer := &closeTrackingWriter{}
+	reader := &ackingReader{n: 3, writer: writer}
+
+	_, err := pipeline.NewDataPipeline(reader, writer).WithTransformers(holdingTransformer{}).WithMonitor(nil).Start(context.Background())
+	require.ErrorContains(t, err, "flush failed")
+	require.Empty(t, reader.acks, "records held by the transformer were acknowledged")
+}
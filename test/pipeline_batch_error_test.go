@@ -0,0 +1,158 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+var errDiskFull = errors.New("disk full")
+
+// failAtWriter fails to write the record holding the id at.
+type failAtWriter struct {
+	at int64
+}
+
+func (w *failAtWriter) Write(record arrow.Record) error {
+	for _, id := range record.Column(0).(*array.Int64).Int64Values() {
+		if id == w.at {
+			return errDiskFull
+		}
+	}
+	return nil
+}
+
+func (w *failAtWriter) Close() error { return nil }
+
+func TestPipelineBatchErrorReportsBatchAndRows(t *testing.T) {
+	reader := &sequenceReader{alloc: memory.NewGoAllocator(), n: 5, rows: 10, done: make(chan struct{})}
+	p := pipeline.NewDataPipeline(reader, &failAtWriter{at: 25}).WithMonitor(nil)
+
+	report, err := p.Start(context.Background())
+	require.ErrorIs(t, err, errDiskFull)
+	require.Contains(t, err.Error(), "writer error: batch 2 (rows 20-29): disk full")
+	var batchErr *pipeline.BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.Equal(t, "test.failAtWriter", batchErr.Stage)
+	require.EqualValues(t, 2, batchErr.Batch)
+	require.EqualValues(t, 20, batchErr.FirstRow)
+	require.EqualValues(t, 10, batchErr.Rows)
+
+	// The report of the failed run tells how far it got.
+	var parsed pipeline.MetricsReport
+	require.NoError(t, json.Unmarshal([]byte(report), &parsed))
+	require.NotNil(t, parsed.Failure)
+	require.Equal(t, "test.failAtWriter", parsed.Failure.Stage)
+	require.EqualValues(t, 2, *parsed.Failure.Batch)
+	require.Equal(t, "20-29", parsed.Failure.Rows)
+	require.EqualValues(t, 2, parsed.Failure.CompletedBatches)
+	require.EqualValues(t, 20, parsed.Failure.CompletedRows)
+	require.Contains(t, parsed.Failure.Error, "disk full")
+
+	<-p.Done()
+	require.Same(t, batchErr, p.Metrics().Failure)
+}
+
+func TestPipelineBatchErrorNamesParquetRowGroup(t *testing.T) {
+	path := writeFilterTestFile(t, nil)
+	for _, test := range []struct {
+		name     string
+		opts     *integrations.ParquetReadOptions
+		batch    int64
+		rows     string
+		position string
+	}{
+		// Row groups hold 100 rows each.
+		{"sequential", &integrations.ParquetReadOptions{ChunkSize: 30}, 8, "240-269", "row group 2"},
+		{"parallel", &integrations.ParquetReadOptions{ChunkSize: 30, Parallel: true, Workers: 2}, 9, "230-259", "row group 2"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := integrations.NewParquetReader(context.Background(), path, test.opts)
+			require.NoError(t, err)
+			p := pipeline.NewDataPipeline(reader, &failAtWriter{at: 250}).WithMonitor(nil)
+
+			report, err := p.Start(context.Background())
+			require.ErrorIs(t, err, errDiskFull)
+			var batchErr *pipeline.BatchError
+			require.ErrorAs(t, err, &batchErr)
+			require.Equal(t, test.batch, batchErr.Batch)
+			require.Equal(t, test.position, batchErr.Position)
+			require.Contains(t, err.Error(), test.rows+", "+test.position)
+			require.Contains(t, report, `"position": "`+test.position+`"`)
+			<-p.Done()
+		})
+	}
+}
+
+func TestPipelineBatchErrorNamesFile(t *testing.T) {
+	first, second := writeFilterTestFile(t, nil), writeFilterTestFile(t, nil)
+	open := func(ctx context.Context, path string) (integrations.FileReader, error) {
+		return integrations.NewParquetReader(ctx, path, &integrations.ParquetReadOptions{ChunkSize: 100})
+	}
+	reader, err := integrations.NewMultiFileReader(context.Background(), []string{first, second}, 1, open)
+	require.NoError(t, err)
+	// The first file holds ids 0 to 499, so the second id 499 is in the
+	// last row group of the second file.
+	writer := &failAtWriter{at: 499}
+	p := pipeline.NewDataPipeline(reader, &secondMatchWriter{failAtWriter: writer}).WithMonitor(nil)
+
+	_, err = p.Start(context.Background())
+	var batchErr *pipeline.BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.EqualValues(t, 9, batchErr.Batch)
+	require.EqualValues(t, 900, batchErr.FirstRow)
+	require.Equal(t, second+", row group 4", batchErr.Position)
+	<-p.Done()
+}
+
+// secondMatchWriter lets the first failure of failAtWriter pass.
+type secondMatchWriter struct {
+	*failAtWriter
+	passed bool
+}
+
+func (w *secondMatchWriter) Write(record arrow.Record) error {
+	err := w.failAtWriter.Write(record)
+	if err != nil && !w.passed {
+		w.passed = true
+		return nil
+	}
+	return err
+}
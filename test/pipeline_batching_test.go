@@ -0,0 +1,176 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRecordingWriter collects the values and the row counts of the
+// records written, and calls onWrite, if set, after each write.
+type batchRecordingWriter struct {
+	ids     []int64
+	sizes   []int64
+	onWrite func(n int) error
+}
+
+func (w *batchRecordingWriter) Write(record arrow.Record) error {
+	w.ids = append(w.ids, record.Column(0).(*array.Int64).Int64Values()...)
+	w.sizes = append(w.sizes, record.NumRows())
+	if w.onWrite != nil {
+		return w.onWrite(len(w.sizes))
+	}
+	return nil
+}
+
+func (w *batchRecordingWriter) Close() error { return nil }
+
+// trickleReader returns one-row records, blocking before the record after
+// each group of burst until release is closed.
+type trickleReader struct {
+	n, burst, read int
+	release        <-chan struct{}
+}
+
+func (r *trickleReader) Read() (arrow.Record, error) {
+	if r.read == r.n {
+		return nil, io.EOF
+	}
+	if r.read == r.burst {
+		<-r.release
+	}
+	r.read++
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil))
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).Append(int64(r.read))
+	return b.NewRecord(), nil
+}
+
+func (r *trickleReader) Close() error { return nil }
+
+func TestPipelineBatchingByRows(t *testing.T) {
+	reader := &sequenceReader{n: 25, rows: 10, done: make(chan struct{})}
+	writer := &batchRecordingWriter{}
+	p := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil).WithMemoryLimit(0).
+		WithBatching(pipeline.BatchPolicy{MaxRows: 100})
+	reader.alloc = p.Allocator()
+
+	_, err := p.Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []int64{100, 100, 50}, writer.sizes)
+	require.Len(t, writer.ids, 250)
+	for i, id := range writer.ids {
+		require.EqualValues(t, i, id)
+	}
+	require.EqualValues(t, 25, p.Metrics().BatchesCompleted)
+	require.Zero(t, p.Allocator().(*pool.TrackedAllocator).CurrentBytes(), "records are released")
+}
+
+func TestPipelineBatchingByBytes(t *testing.T) {
+	reader := &sequenceReader{n: 10, rows: 100, alloc: memory.NewGoAllocator(), done: make(chan struct{})}
+	writer := &batchRecordingWriter{}
+	// Each record holds 800 bytes of values, so three fit under the bound.
+	_, err := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil).
+		WithBatching(pipeline.BatchPolicy{MaxBytes: 2500}).Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []int64{300, 300, 300, 100}, writer.sizes)
+}
+
+func TestPipelineBatchingByLatency(t *testing.T) {
+	release := make(chan struct{})
+	reader := &trickleReader{n: 5, burst: 3, release: release}
+	writer := &batchRecordingWriter{onWrite: func(n int) error {
+		if n == 1 {
+			close(release)
+		}
+		return nil
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The reader blocks until the first batch is written, which only the
+	// latency bound flushes.
+	_, err := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil).
+		WithBatching(pipeline.BatchPolicy{MaxRows: 1000, MaxLatency: 50 * time.Millisecond}).Start(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int64{3, 2}, writer.sizes)
+	require.Equal(t, []int64{1, 2, 3, 4, 5}, writer.ids)
+}
+
+func TestPipelineBatchingAcknowledgesRecordsRead(t *testing.T) {
+	writer := &closeTrackingWriter{}
+	reader := &ackingReader{n: 5, writer: writer}
+
+	_, err := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil).
+		WithBatching(pipeline.BatchPolicy{MaxRows: 2}).Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []ackCall{
+		{records: 2},
+		{records: 4},
+		{records: 5},
+		{records: 5, final: true, writerClosed: true},
+	}, reader.acks)
+}
+
+func TestPipelineBatchingFailureCountsRecordsRead(t *testing.T) {
+	reader := &sequenceReader{n: 10, rows: 10, alloc: memory.NewGoAllocator(), done: make(chan struct{})}
+	writer := &batchRecordingWriter{onWrite: func(n int) error {
+		if n == 2 {
+			return errors.New("disk full")
+		}
+		return nil
+	}}
+
+	_, err := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil).
+		WithBatching(pipeline.BatchPolicy{MaxRows: 40}).Start(context.Background())
+	var batchErr *pipeline.BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.EqualValues(t, 4, batchErr.Batch)
+	require.EqualValues(t, 40, batchErr.FirstRow)
+	require.EqualValues(t, 40, batchErr.Rows)
+}
+
+func TestPipelineBatchingRequiresABound(t *testing.T) {
+	reader := &sequenceReader{n: 1, rows: 1, alloc: memory.NewGoAllocator(), done: make(chan struct{})}
+	_, err := pipeline.NewDataPipeline(reader, &batchRecordingWriter{}).WithMonitor(nil).
+		WithBatching(pipeline.BatchPolicy{}).Start(context.Background())
+	require.ErrorContains(t, err, "needs a row, byte or latency bound")
+}
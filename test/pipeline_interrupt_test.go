@@ -0,0 +1,142 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+// interruptingReader returns records of ten rows without end, and cancels
+// its pipeline's context once it has returned after of them.
+type interruptingReader struct {
+	cancel context.CancelFunc
+	after  int
+	read   int
+}
+
+func (r *interruptingReader) Read() (arrow.Record, error) {
+	if r.read == r.after {
+		r.cancel()
+	}
+	rec := cancelRecord(int64(r.read) * 10)
+	r.read++
+	return rec, nil
+}
+
+func (r *interruptingReader) Close() error { return nil }
+
+func (r *interruptingReader) Schema() *arrow.Schema { return cancelSchema }
+
+// slowClosingWriter takes a while to close, like a writer finalizing its
+// output.
+type slowClosingWriter struct {
+	closed atomic.Bool
+}
+
+func (w *slowClosingWriter) Write(arrow.Record) error { return nil }
+
+func (w *slowClosingWriter) Close() error {
+	time.Sleep(100 * time.Millisecond)
+	w.closed.Store(true)
+	return nil
+}
+
+func TestPipelineInterruptedClosesWriterFirst(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	writer := &slowClosingWriter{}
+	p := pipeline.NewDataPipeline(&interruptingReader{cancel: cancel, after: 5}, writer).WithMonitor(nil)
+
+	report, err := p.Start(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	require.True(t, writer.closed.Load(), "Start returned before the writer was closed")
+
+	// The report of the interrupted run tells how far it got.
+	var parsed pipeline.MetricsReport
+	require.NoError(t, json.Unmarshal([]byte(report), &parsed))
+	require.NotEmpty(t, parsed.RunID)
+}
+
+func TestPipelineInterruptedLeavesValidParquet(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "interrupted.parquet")
+	writer, err := integrations.NewParquetWriter(path, cancelSchema, integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+	reader := &interruptingReader{cancel: cancel, after: 20}
+
+	p := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil)
+	_, err = p.Start(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	<-p.Done()
+
+	// The file has its footer, so the rows written before the interrupt
+	// read back.
+	r, err := integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{ChunkSize: 10})
+	require.NoError(t, err)
+	defer r.Close()
+	var rows int64
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		rows += rec.NumRows()
+		rec.Release()
+	}
+	require.Zero(t, rows%10)
+	require.LessOrEqual(t, rows, int64(reader.read)*10)
+}
+
+func TestPipelineReportHasEndTime(t *testing.T) {
+	// The report is built as soon as the stages finish; it must never see
+	// the metrics before they are final.
+	unset := time.Unix(0, 0).Format(time.RFC3339)
+	for i := 0; i < 200; i++ {
+		p := pipeline.NewDataPipeline(&trickleReader{n: 1, burst: -1}, discardWriter{}).WithMonitor(nil)
+		report, err := p.Start(context.Background())
+		require.NoError(t, err)
+		var parsed pipeline.MetricsReport
+		require.NoError(t, json.Unmarshal([]byte(report), &parsed))
+		require.NotEqual(t, unset, parsed.EndTime, "run %d", i)
+	}
+}
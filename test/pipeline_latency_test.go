@@ -0,0 +1,91 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+type passThrough struct{}
+
+func (passThrough) Name() string { return "pass" }
+
+func (passThrough) Transform(record arrow.Record) (arrow.Record, error) {
+	record.Retain()
+	return record, nil
+}
+
+func TestPipelineReportsStageLatency(t *testing.T) {
+	reader := &sequenceReader{n: 20, rows: 10, alloc: memory.NewGoAllocator(), done: make(chan struct{})}
+	// The writer stalls on one batch of twenty, which shows in the tail
+	// percentiles but not the median.
+	writer := &batchRecordingWriter{onWrite: func(n int) error {
+		if n == 10 {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return nil
+	}}
+	p := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil).WithTransformers(passThrough{})
+
+	report, err := p.Start(context.Background())
+	require.NoError(t, err)
+
+	latency := p.Metrics().Latency
+	require.Len(t, latency, 3)
+	require.Equal(t, pipeline.StageKindRead, latency[0].Kind)
+	require.Equal(t, "pass", latency[1].Stage)
+	require.Equal(t, pipeline.StageKindTransform, latency[1].Kind)
+	require.Equal(t, pipeline.StageKindWrite, latency[2].Kind)
+	for _, l := range latency {
+		require.EqualValues(t, 20, l.Batches, l.Stage)
+		require.LessOrEqual(t, l.P50, l.P95)
+		require.LessOrEqual(t, l.P95, l.P99)
+		require.LessOrEqual(t, l.P99, l.Max)
+	}
+	write := latency[2]
+	require.GreaterOrEqual(t, write.Max, 30*time.Millisecond)
+	require.GreaterOrEqual(t, write.P99, 30*time.Millisecond)
+	require.Less(t, write.P50, 30*time.Millisecond)
+	require.GreaterOrEqual(t, write.Total, write.Max)
+
+	var parsed pipeline.MetricsReport
+	require.NoError(t, json.Unmarshal([]byte(report), &parsed))
+	require.Len(t, parsed.Stages, 3)
+	require.Equal(t, "write", parsed.Stages[2].Kind)
+	require.NotEmpty(t, parsed.Stages[2].P99)
+}
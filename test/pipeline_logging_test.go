@@ -0,0 +1,139 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/logging"
+	"github.com/stretchr/testify/require"
+)
+
+// logEntries decodes the JSON lines logged to buf.
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry), scanner.Text())
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestPipelineLogsWithRunID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, &logging.Options{Level: slog.LevelDebug, JSON: true})
+
+	reader := &sequenceReader{alloc: memory.NewGoAllocator(), n: 3, rows: 10, done: make(chan struct{})}
+	open := make(chan struct{})
+	close(open)
+	p := pipeline.NewDataPipeline(reader, &laggingWriter{gate: open}).WithMonitor(nil).WithLogger(logger)
+
+	report, err := p.Start(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, p.RunID())
+	require.Contains(t, report, `"run_id": "`+p.RunID()+`"`)
+
+	entries := logEntries(t, &buf)
+	var messages []string
+	for _, entry := range entries {
+		require.Equal(t, p.RunID(), entry["run_id"], entry)
+		messages = append(messages, entry["msg"].(string))
+	}
+	require.Contains(t, messages, "pipeline started")
+	require.Contains(t, messages, "reader finished")
+	require.Contains(t, messages, "pipeline finished")
+	last := entries[len(entries)-1]
+	require.Equal(t, "INFO", last["level"])
+	require.EqualValues(t, 30, last["records"])
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(arrow.Record) error { return errors.New("disk full") }
+func (failingWriter) Close() error             { return nil }
+
+func TestPipelineLogsContextRunAndErrors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, &logging.Options{Level: slog.LevelWarn, JSON: true})
+	ctx := logging.WithRunID(logging.WithLogger(context.Background(), logger), "run-42")
+
+	reader := &sequenceReader{alloc: memory.NewGoAllocator(), n: 3, rows: 10, done: make(chan struct{})}
+	p := pipeline.NewDataPipeline(reader, failingWriter{}).WithMonitor(nil)
+	_, err := p.Start(ctx)
+	require.Error(t, err)
+	require.Equal(t, "run-42", p.RunID())
+
+	entries := logEntries(t, &buf)
+	require.NotEmpty(t, entries)
+	var failed bool
+	for _, entry := range entries {
+		require.Equal(t, "run-42", entry["run_id"])
+		require.NotEqual(t, "DEBUG", entry["level"])
+		require.NotEqual(t, "INFO", entry["level"], "info entries are below the level")
+		if entry["msg"] == "write failed" {
+			failed = true
+			require.Contains(t, entry["error"], "disk full")
+		}
+	}
+	require.True(t, failed, "the failed write is logged")
+}
+
+func TestConfigLogger(t *testing.T) {
+	var cfg config.Config
+	cfg.Workflow.Settings.LogLevel = "warn"
+	cfg.Workflow.Settings.LogFormat = "json"
+
+	var buf bytes.Buffer
+	logger, err := cfg.Logger(&buf)
+	require.NoError(t, err)
+	logger.Info("hidden")
+	logger.Warn("shown")
+	entries := logEntries(t, &buf)
+	require.Len(t, entries, 1)
+	require.Equal(t, "shown", entries[0]["msg"])
+
+	cfg.Workflow.Settings.LogLevel = "verbose"
+	_, err = cfg.Logger(&buf)
+	require.Error(t, err)
+}
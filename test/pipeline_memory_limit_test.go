@@ -0,0 +1,152 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/stretchr/testify/require"
+)
+
+// allocatingReader returns n records of rows int64 values built with alloc.
+type allocatingReader struct {
+	alloc   memory.Allocator
+	n, rows int
+}
+
+func (r *allocatingReader) Read() (arrow.Record, error) {
+	if r.n == 0 {
+		return nil, io.EOF
+	}
+	r.n--
+	b := array.NewRecordBuilder(r.alloc, arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil))
+	defer b.Release()
+	for i := 0; i < r.rows; i++ {
+		b.Field(0).(*array.Int64Builder).Append(int64(i))
+	}
+	return b.NewRecord(), nil
+}
+
+func (r *allocatingReader) Close() error { return nil }
+
+type discardWriter struct{}
+
+func (discardWriter) Write(arrow.Record) error { return nil }
+func (discardWriter) Close() error             { return nil }
+
+func TestPipelineReportsPeakMemory(t *testing.T) {
+	reader := &allocatingReader{n: 5, rows: 1000}
+	p := pipeline.NewDataPipeline(reader, discardWriter{}).WithMonitor(nil).WithMemoryLimit(1 << 20)
+	reader.alloc = p.Allocator()
+
+	report, err := p.Start(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, p.Metrics().PeakMemoryBytes, int64(8000))
+	require.Contains(t, report, `"peak_memory"`)
+	require.Contains(t, report, `"memory_limit": "1.00 MB"`)
+	require.Zero(t, p.Allocator().(*pool.TrackedAllocator).CurrentBytes(), "records are released")
+}
+
+func TestPipelineFailsOverMemoryLimit(t *testing.T) {
+	reader := &allocatingReader{n: 5, rows: 100000}
+	p := pipeline.NewDataPipeline(reader, discardWriter{}).WithMonitor(nil).WithMemoryLimit(64 << 10)
+	reader.alloc = p.Allocator()
+
+	_, err := p.Start(context.Background())
+	require.ErrorIs(t, err, pool.ErrMemoryLimitExceeded)
+	var limitErr *pool.LimitError
+	require.True(t, errors.As(err, &limitErr))
+	require.EqualValues(t, 64<<10, limitErr.Limit)
+}
+
+func TestTrackedAllocatorCountsReallocations(t *testing.T) {
+	alloc := pool.NewTrackedAllocator(nil, 0)
+	b := alloc.Allocate(100)
+	b = alloc.Reallocate(300, b)
+	require.EqualValues(t, 300, alloc.CurrentBytes())
+	alloc.Free(b)
+	require.Zero(t, alloc.CurrentBytes())
+	require.EqualValues(t, 300, alloc.PeakBytes())
+	require.EqualValues(t, 1, alloc.Allocations())
+	require.EqualValues(t, 300, alloc.AllocatedBytes())
+	require.NoError(t, alloc.Err())
+}
+
+func TestPipelineReportsMemoryStats(t *testing.T) {
+	reader := &allocatingReader{n: 5, rows: 1000}
+	p := pipeline.NewDataPipeline(reader, discardWriter{}).WithMonitor(nil).WithMemoryLimit(0)
+	reader.alloc = p.Allocator()
+
+	report, err := p.Start(context.Background())
+	require.NoError(t, err)
+	stats := p.Metrics().Memory
+	require.Positive(t, stats.PeakHeapBytes)
+	require.Positive(t, stats.HeapAllocBytes)
+	require.Positive(t, stats.HeapObjects)
+	require.GreaterOrEqual(t, stats.GCCycles, int64(0))
+	require.GreaterOrEqual(t, stats.AllocatorAllocations, int64(5))
+	require.GreaterOrEqual(t, stats.AllocatorBytes, int64(5*8000))
+
+	var parsed pipeline.MetricsReport
+	require.NoError(t, json.Unmarshal([]byte(report), &parsed))
+	require.NotNil(t, parsed.Memory)
+	require.NotEmpty(t, parsed.Memory.PeakHeap)
+	require.NotEmpty(t, parsed.Memory.GCPauseTotal)
+	require.NotEmpty(t, parsed.Memory.AllocatorAllocated)
+}
+
+func TestParseByteSize(t *testing.T) {
+	for input, want := range map[string]int64{
+		"1048576": 1 << 20,
+		"512MiB":  512 << 20,
+		"2GB":     2e9,
+		"1.5g":    3 << 29,
+		"16 GB":   16e9,
+		"64k":     64 << 10,
+	} {
+		got, err := config.ParseByteSize(input)
+		require.NoError(t, err, input)
+		require.Equal(t, want, got, input)
+	}
+	for _, input := range []string{"", "GB", "12 parsecs", "-1MB"} {
+		_, err := config.ParseByteSize(input)
+		require.Error(t, err, input)
+	}
+}
@@ -0,0 +1,188 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/ui"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/validate"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMonitor struct {
+	started bool
+	stopped bool
+}
+
+func (m *recordingMonitor) Start(dp *pipeline.DataPipeline) { m.started = true }
+
+func (m *recordingMonitor) Stop(err error) { m.stopped = true }
+
+func progressPipeline(t *testing.T) (*integrations.ParquetReader, *integrations.ParquetWriter) {
+	t.Helper()
+	dir := t.TempDir()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "status", Type: arrow.BinaryTypes.String}}, nil)
+	inputPath := filepath.Join(dir, "input.parquet")
+	writer, err := integrations.NewParquetWriter(inputPath, schema, integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	b.Field(0).(*array.StringBuilder).AppendValues([]string{"active", "bogus", "inactive", "bogus"}, nil)
+	rec := b.NewRecord()
+	b.Release()
+	require.NoError(t, writer.Write(rec))
+	rec.Release()
+	require.NoError(t, writer.Close())
+
+	reader, err := integrations.NewParquetReader(context.Background(), inputPath, &integrations.ParquetReadOptions{ChunkSize: 1024})
+	require.NoError(t, err)
+	sink, err := integrations.NewParquetWriter(filepath.Join(dir, "output.parquet"), reader.Schema(), integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+	return reader, sink
+}
+
+func TestPipelineProgressStages(t *testing.T) {
+	reader, sink := progressPipeline(t)
+	validator, err := validate.New([]validate.ColumnRules{
+		{Column: "status", Rules: []validate.Rule{validate.OneOf("active", "inactive")}},
+	}, nil)
+	require.NoError(t, err)
+
+	monitor := &recordingMonitor{}
+	dp := pipeline.NewDataPipeline(reader, sink).WithTransformers(validator).WithMonitor(monitor)
+	_, err = dp.Start(context.Background())
+	require.NoError(t, err)
+	require.True(t, monitor.started)
+	require.True(t, monitor.stopped)
+
+	p := dp.Progress()
+	require.True(t, p.Done)
+	require.NoError(t, p.Err)
+	require.Equal(t, int64(4), p.Records)
+	require.Equal(t, int64(4), p.ExpectedRows)
+	fraction, ok := p.Fraction()
+	require.True(t, ok)
+	require.Equal(t, 1.0, fraction)
+
+	require.Len(t, p.Stages, 3)
+	for i, want := range []struct {
+		name string
+		rows int64
+	}{{"integrations.ParquetReader", 4}, {"validate", 2}, {"integrations.ParquetWriter", 2}} {
+		require.Equal(t, want.name, p.Stages[i].Name)
+		require.Equal(t, want.rows, p.Stages[i].Rows)
+		require.Equal(t, pipeline.StageDone, p.Stages[i].State)
+	}
+}
+
+func TestPipelineProgressLogFallback(t *testing.T) {
+	reader, sink := progressPipeline(t)
+
+	var out bytes.Buffer
+	monitor := ui.NewProgressMonitor("Parquet to Parquet", &out, false)
+	_, err := pipeline.NewDataPipeline(reader, sink).WithMonitor(monitor).Start(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "Parquet to Parquet: completed, 4 records")
+}
+
+// slowTransformer passes records through after a pause.
+type slowTransformer struct{ pause time.Duration }
+
+func (s slowTransformer) Transform(record arrow.Record) (arrow.Record, error) {
+	time.Sleep(s.pause)
+	record.Retain()
+	return record, nil
+}
+
+func TestPipelineWithProgress(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.csv")
+	require.NoError(t, os.WriteFile(input, []byte("id,name\n1,a\n2,b\n3,c\n4,d\n"), 0o644))
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+	}, nil)
+	reader, err := integrations.NewCSVReader(context.Background(), input, schema, &integrations.CSVReadOptions{ChunkSize: 1, HasHeader: true})
+	require.NoError(t, err)
+	sink, err := integrations.NewParquetWriter(filepath.Join(dir, "output.parquet"), schema, integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+
+	var events []pipeline.ProgressEvent
+	_, err = pipeline.NewDataPipeline(reader, sink).
+		WithTransformers(slowTransformer{pause: 20 * time.Millisecond}).
+		WithMonitor(nil).
+		WithProgress(func(e pipeline.ProgressEvent) { events = append(events, e) }).
+		WithProgressInterval(5 * time.Millisecond).
+		Start(context.Background())
+	require.NoError(t, err)
+
+	require.Greater(t, len(events), 1)
+	for _, e := range events[:len(events)-1] {
+		require.False(t, e.Done)
+	}
+	last := events[len(events)-1]
+	require.True(t, last.Done)
+	require.NoError(t, last.Err)
+	require.Equal(t, int64(4), last.Records)
+	// The size of a CSV source is known from its file.
+	require.Equal(t, int64(0), last.ExpectedRows)
+	require.Equal(t, last.SourceSize, last.SourceBytes)
+	require.Equal(t, 100.0, last.Percent)
+	require.Equal(t, time.Duration(0), last.Remaining)
+}
+
+func TestProgressEstimates(t *testing.T) {
+	p := pipeline.Progress{SourceBytes: 50, SourceSize: 200, Elapsed: time.Second}
+	fraction, ok := p.Fraction()
+	require.True(t, ok)
+	require.Equal(t, 0.25, fraction)
+	eta, ok := p.ETA()
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, eta)
+
+	// Rows take precedence over bytes.
+	p.Records, p.ExpectedRows = 10, 20
+	eta, ok = p.ETA()
+	require.True(t, ok)
+	require.Equal(t, time.Second, eta)
+
+	_, ok = pipeline.Progress{Records: 10, Elapsed: time.Second}.ETA()
+	require.False(t, ok)
+}
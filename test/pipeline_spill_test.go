@@ -0,0 +1,136 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc"
+	pool "github.com/arrowarc/arrowarc/internal/memory"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+// sequenceReader returns n records of rows consecutive int64 values and
+// closes done once it is exhausted.
+type sequenceReader struct {
+	alloc   memory.Allocator
+	n, rows int
+	next    int64
+	done    chan struct{}
+}
+
+func (r *sequenceReader) Read() (arrow.Record, error) {
+	if r.n == 0 {
+		close(r.done)
+		return nil, io.EOF
+	}
+	r.n--
+	b := array.NewRecordBuilder(r.alloc, arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil))
+	defer b.Release()
+	for i := 0; i < r.rows; i++ {
+		b.Field(0).(*array.Int64Builder).Append(r.next)
+		r.next++
+	}
+	return b.NewRecord(), nil
+}
+
+func (r *sequenceReader) Close() error { return nil }
+
+// laggingWriter holds its first write until gate is closed and collects
+// the values written.
+type laggingWriter struct {
+	gate <-chan struct{}
+	ids  []int64
+}
+
+func (w *laggingWriter) Write(record arrow.Record) error {
+	<-w.gate
+	w.ids = append(w.ids, record.Column(0).(*array.Int64).Int64Values()...)
+	return nil
+}
+
+func (w *laggingWriter) Close() error { return nil }
+
+func TestPipelineSpillsWhenWriterLags(t *testing.T) {
+	dir := t.TempDir()
+	reader := &sequenceReader{n: 50, rows: 1000, done: make(chan struct{})}
+	writer := &laggingWriter{gate: reader.done}
+	p := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil).WithMemoryLimit(0).WithSpill(16<<10, dir)
+	reader.alloc = p.Allocator()
+
+	report, err := p.Start(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, writer.ids, 50*1000)
+	for i, id := range writer.ids {
+		require.EqualValues(t, i, id)
+	}
+	require.GreaterOrEqual(t, p.Metrics().SpilledRecords, int64(40))
+	require.Contains(t, report, `"spilled_records"`)
+	// All 50 records are read before the first is written, but at most
+	// a few of them are ever in memory.
+	require.Less(t, p.Metrics().PeakMemoryBytes, int64(200<<10))
+	require.Zero(t, p.Allocator().(*pool.TrackedAllocator).CurrentBytes(), "records are released")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "spill files are removed")
+}
+
+func TestPipelineDoesNotSpillWhenWriterKeepsUp(t *testing.T) {
+	reader := &sequenceReader{n: 10, rows: 100, done: make(chan struct{})}
+	open := make(chan struct{})
+	close(open)
+	writer := &laggingWriter{gate: open}
+	p := pipeline.NewDataPipeline(reader, writer).WithMonitor(nil).WithSpill(1<<20, t.TempDir())
+	reader.alloc = memory.NewGoAllocator()
+
+	report, err := p.Start(context.Background())
+	require.NoError(t, err)
+	require.Len(t, writer.ids, 1000)
+	require.Zero(t, p.Metrics().SpilledRecords)
+	require.NotContains(t, report, `"spilled_records"`)
+}
+
+func TestFlowSpillsWhenWriterLags(t *testing.T) {
+	reader := &sequenceReader{alloc: memory.NewGoAllocator(), n: 50, rows: 1000, done: make(chan struct{})}
+	writer := &laggingWriter{gate: reader.done}
+	res, err := arrowarc.ReadFrom(reader).Monitor(nil).Spill(16<<10, t.TempDir()).WriteTo(context.Background(), writer)
+	require.NoError(t, err)
+	require.Len(t, writer.ids, 50*1000)
+	require.Positive(t, res.Metrics.SpilledRecords)
+}
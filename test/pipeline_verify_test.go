@@ -0,0 +1,96 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/converter"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/verify"
+	"github.com/stretchr/testify/require"
+)
+
+// verifyPipeline writes 5 batches of 10 ids and verifies them against a
+// copy of rows ids.
+func verifyPipeline(t *testing.T, rows int) (string, error) {
+	t.Helper()
+	reader := &sequenceReader{alloc: memory.NewGoAllocator(), n: 5, rows: 10, done: make(chan struct{})}
+	p := pipeline.NewDataPipeline(reader, discardWriter{}).WithMonitor(nil).
+		WithVerification(func(context.Context) (interfaces.Reader, error) {
+			return &sequenceReader{alloc: memory.NewGoAllocator(), n: 1, rows: rows, done: make(chan struct{})}, nil
+		})
+	report, err := p.Start(context.Background())
+	<-p.Done()
+	return report, err
+}
+
+func TestPipelineVerification(t *testing.T) {
+	report, err := verifyPipeline(t, 50)
+	require.NoError(t, err)
+	var parsed pipeline.MetricsReport
+	require.NoError(t, json.Unmarshal([]byte(report), &parsed))
+	require.NotNil(t, parsed.Verification)
+	require.True(t, parsed.Verification.Passed)
+	require.EqualValues(t, 50, parsed.Verification.ActualRows)
+	require.Len(t, parsed.Verification.Columns, 1)
+	require.True(t, parsed.Verification.Columns[0].Match)
+}
+
+func TestPipelineVerificationMismatch(t *testing.T) {
+	report, err := verifyPipeline(t, 49)
+	require.ErrorIs(t, err, verify.ErrMismatch)
+	require.Contains(t, err.Error(), `49 rows instead of 50, column "id" differs`)
+	var parsed pipeline.MetricsReport
+	require.NoError(t, json.Unmarshal([]byte(report), &parsed))
+	require.NotNil(t, parsed.Verification)
+	require.False(t, parsed.Verification.Passed)
+	require.EqualValues(t, 50, parsed.Verification.ExpectedRows)
+}
+
+func TestConvertVerify(t *testing.T) {
+	in := writeFilterTestFile(t, nil)
+	for _, out := range []string{"out.parquet", "out.csv", "out.ndjson"} {
+		t.Run(out, func(t *testing.T) {
+			report, err := converter.Convert(context.Background(), in, filepath.Join(t.TempDir(), out), &converter.ConvertOptions{ChunkSize: 64, Verify: true})
+			require.NoError(t, err)
+			require.Contains(t, report, `"verification"`)
+			require.Contains(t, report, `"passed": true`)
+		})
+	}
+
+	_, err := converter.Convert(context.Background(), in, "-", &converter.ConvertOptions{ToFormat: "csv", Verify: true})
+	require.ErrorContains(t, err, "cannot verify output written to standard output")
+}
@@ -0,0 +1,139 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/testutil"
+	"github.com/arrowarc/arrowarc/pkg/projection"
+	"github.com/stretchr/testify/require"
+)
+
+// writeProjectionParquet writes a three-column Parquet file at path.
+func writeProjectionParquet(t *testing.T, path string) {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "city", Type: arrow.StructOf(arrow.Field{Name: "name", Type: arrow.BinaryTypes.String})},
+		{Name: "amount", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	testutil.WriteParquet(t, path, schema, nil, testutil.JSONRows(t,
+		`[{"id": 1, "city": {"name": "Oslo"}, "amount": 1.5}, {"id": 2, "city": {"name": "Rome"}, "amount": 2}]`))
+}
+
+func TestParquetReaderColumns(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "in.parquet")
+	writeProjectionParquet(t, path)
+
+	reader, err := integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{
+		ChunkSize: 10,
+		Columns:   []string{"amount", "city"},
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+	// Columns keep the order of the file.
+	require.Equal(t, []string{"city", "amount"}, fieldNames(reader.Schema()))
+	record, err := reader.Read()
+	require.NoError(t, err)
+	defer record.Release()
+	require.True(t, reader.Schema().Equal(record.Schema()))
+	require.EqualValues(t, 2, record.NumCols())
+
+	_, err = integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{Columns: []string{"missing"}})
+	require.ErrorContains(t, err, "missing")
+}
+
+func TestConvertSelectRename(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.parquet")
+	writeProjectionParquet(t, input)
+	project := &projection.Options{Select: []string{"amount", "id"}, Rename: map[string]string{"amount": "total"}}
+	want := "total,id\n1.5,1\n2,2\n"
+
+	output := filepath.Join(dir, "convert.csv")
+	_, err := converter.Convert(context.Background(), input, output, &converter.ConvertOptions{
+		ChunkSize: 10,
+		Select:    project.Select,
+		Rename:    project.Rename,
+		Verify:    true,
+	})
+	require.NoError(t, err)
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, want, string(data))
+
+	output = filepath.Join(dir, "legacy.csv")
+	_, err = converter.ConvertParquetToCSVWithOptions(context.Background(), input, output, &converter.ParquetToCSVOptions{
+		ChunkSize:     10,
+		Concurrency:   1,
+		Delimiter:     ',',
+		IncludeHeader: true,
+		Project:       project,
+	})
+	require.NoError(t, err)
+	data, err = os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, want, string(data))
+
+	_, err = converter.Convert(context.Background(), input, filepath.Join(dir, "bad.csv"), &converter.ConvertOptions{
+		Rename: map[string]string{"nope": "x"},
+	})
+	require.ErrorContains(t, err, "nope")
+}
+
+func TestConvertCSVToParquetRename(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	require.NoError(t, os.WriteFile(input, []byte("a,b,c\n1,x,true\n2,y,false\n"), 0o644))
+	output := filepath.Join(dir, "out.parquet")
+	_, err := converter.ConvertCSVToParquetWithOptions(context.Background(), input, output, &converter.CSVToParquetOptions{
+		HasHeader:   true,
+		ChunkSize:   1024,
+		Delimiter:   ',',
+		Concurrency: 1,
+		Project:     &projection.Options{Select: []string{"c", "a"}, Rename: map[string]string{"a": "key"}},
+	})
+	require.NoError(t, err)
+
+	reader, err := integrations.NewParquetReader(context.Background(), output, &integrations.ParquetReadOptions{ChunkSize: 10})
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, []string{"c", "key"}, fieldNames(reader.Schema()))
+}
@@ -0,0 +1,238 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pubsubsource "github.com/arrowarc/arrowarc/integrations/pubsub"
+	"github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func newPubSubTestClient(t *testing.T) (*pstest.Server, *pubsub.Client) {
+	t.Helper()
+	server := pstest.NewServer()
+	t.Cleanup(func() { server.Close() })
+	conn, err := grpc.NewClient(server.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	client, err := pubsub.NewClient(context.Background(), "arrowarc", option.WithGRPCConn(conn))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func newPubSubSubscription(t *testing.T, client *pubsub.Client, id string) *pubsub.Topic {
+	t.Helper()
+	ctx := context.Background()
+	topic, err := client.CreateTopic(ctx, id)
+	require.NoError(t, err)
+	_, err = client.CreateSubscription(ctx, id, pubsub.SubscriptionConfig{
+		Topic:                 topic,
+		AckDeadline:           10 * time.Second,
+		EnableMessageOrdering: true,
+	})
+	require.NoError(t, err)
+	return topic
+}
+
+func pubSubAcked(server *pstest.Server) int {
+	acked := 0
+	for _, m := range server.Messages() {
+		if m.Acks > 0 {
+			acked++
+		}
+	}
+	return acked
+}
+
+func TestPubSubReaderJSON(t *testing.T) {
+	server, client := newPubSubTestClient(t)
+	ctx := context.Background()
+	topic := newPubSubSubscription(t, client, "events")
+	for i := 0; i < 5; i++ {
+		server.Publish("projects/arrowarc/topics/events", []byte(fmt.Sprintf(`{"id":%d,"name":"event-%d"}`, i, i)), nil)
+	}
+	topic.Stop()
+
+	opts := pubsubsource.NewDefaultPubSubReadOptions()
+	opts.Schema = arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	opts.BatchSize = 2
+	opts.MaxMessages = 5
+	opts.FlushInterval = 200 * time.Millisecond
+	reader, err := pubsubsource.NewPubSubReader(ctx, client, "events", opts)
+	require.NoError(t, err)
+
+	var ids []int64
+	records := int64(0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		records++
+		col := record.Column(0).(*array.Int64)
+		for i := 0; i < col.Len(); i++ {
+			ids = append(ids, col.Value(i))
+		}
+		record.Release()
+	}
+	require.ElementsMatch(t, []int64{0, 1, 2, 3, 4}, ids)
+	require.Equal(t, int64(3), records)
+
+	// Nothing is acknowledged until the rows are written.
+	require.Equal(t, 0, pubSubAcked(server))
+	require.NoError(t, reader.Acknowledge(1, false))
+	require.Eventually(t, func() bool { return pubSubAcked(server) == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, reader.Acknowledge(records, true))
+	require.Eventually(t, func() bool { return pubSubAcked(server) == 5 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, reader.Close())
+}
+
+func TestPubSubReaderAvroAckOnClose(t *testing.T) {
+	server, client := newPubSubTestClient(t)
+	ctx := context.Background()
+	topic := newPubSubSubscription(t, client, "orders")
+	defer topic.Stop()
+
+	avroSchema := `{"type":"record","name":"Order","fields":[{"name":"id","type":"long"},{"name":"amount","type":"double"}]}`
+	schema := avro.MustParse(avroSchema)
+	for i := 0; i < 3; i++ {
+		data, err := avro.Marshal(schema, map[string]any{"id": int64(i), "amount": float64(i) * 1.5})
+		require.NoError(t, err)
+		server.Publish("projects/arrowarc/topics/orders", data, nil)
+	}
+
+	opts := pubsubsource.NewDefaultPubSubReadOptions()
+	opts.Format = pubsubsource.PubSubAvro
+	opts.AvroSchema = avroSchema
+	opts.AckMode = pubsubsource.AckOnClose
+	opts.IdleTimeout = 300 * time.Millisecond
+	opts.FlushInterval = 100 * time.Millisecond
+	reader, err := pubsubsource.NewPubSubReader(ctx, client, "orders", opts)
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "amount"}, fieldNames(reader.Schema()))
+
+	var rows int64
+	records := int64(0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		rows += record.NumRows()
+		records++
+		record.Release()
+	}
+	require.Equal(t, int64(3), rows)
+
+	require.NoError(t, reader.Acknowledge(records, false))
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, 0, pubSubAcked(server))
+	require.NoError(t, reader.Acknowledge(records, true))
+	require.Eventually(t, func() bool { return pubSubAcked(server) == 3 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, reader.Close())
+}
+
+func TestPubSubWriter(t *testing.T) {
+	server, client := newPubSubTestClient(t)
+	ctx := context.Background()
+	newPubSubSubscription(t, client, "rows")
+	newPubSubSubscription(t, client, "batches")
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "customer", Type: arrow.BinaryTypes.String},
+	}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"acme", "globex", "acme"}, nil)
+	record := b.NewRecord()
+	defer record.Release()
+
+	rowOpts := pubsubsource.NewDefaultPubSubWriteOptions()
+	rowOpts.OrderingKeyColumn = "customer"
+	rowOpts.Attributes = map[string]string{"source": "arrowarc"}
+	writer, err := pubsubsource.NewPubSubWriter(ctx, client, "rows", rowOpts)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	batchWriter, err := pubsubsource.NewPubSubWriter(ctx, client, "batches", &pubsubsource.PubSubWriteOptions{Mode: pubsubsource.PublishPerBatch})
+	require.NoError(t, err)
+	require.NoError(t, batchWriter.Write(record))
+	require.NoError(t, batchWriter.Close())
+
+	var rows, batches []*pstest.Message
+	for _, m := range server.Messages() {
+		if m.Attributes["source"] == "arrowarc" {
+			rows = append(rows, m)
+		} else {
+			batches = append(batches, m)
+		}
+	}
+	require.Len(t, rows, 3)
+	keys := map[string]string{}
+	for _, m := range rows {
+		keys[string(m.Data)] = m.OrderingKey
+	}
+	require.Equal(t, map[string]string{
+		`{"customer":"acme","id":1}`:   "acme",
+		`{"customer":"globex","id":2}`: "globex",
+		`{"customer":"acme","id":3}`:   "acme",
+	}, keys)
+
+	require.Len(t, batches, 1)
+	ipcReader, err := ipc.NewReader(bytes.NewReader(batches[0].Data))
+	require.NoError(t, err)
+	defer ipcReader.Release()
+	require.True(t, ipcReader.Next())
+	require.True(t, array.RecordEqual(record, ipcReader.Record()))
+}
@@ -0,0 +1,210 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/extensions"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/stretchr/testify/require"
+)
+
+const avroTypesSchema = `{
+  "type": "record", "name": "Event", "namespace": "test",
+  "fields": [
+    {"name": "id", "type": "int"},
+    {"name": "price", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+    {"name": "amount", "type": {"type": "fixed", "name": "Amount", "size": 8, "logicalType": "decimal", "precision": 18, "scale": 3}},
+    {"name": "ts_ms", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "ts_us", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+    {"name": "local_ts", "type": {"type": "long", "logicalType": "local-timestamp-millis"}},
+    {"name": "day", "type": {"type": "int", "logicalType": "date"}},
+    {"name": "clock", "type": {"type": "int", "logicalType": "time-millis"}},
+    {"name": "uid", "type": {"type": "string", "logicalType": "uuid"}},
+    {"name": "note", "type": ["string", "null"]},
+    {"name": "choice", "type": ["null", "int", "string"]},
+    {"name": "number", "type": ["int", "double"]},
+    {"name": "color", "type": {"type": "enum", "name": "Color", "symbols": ["RED", "GREEN"]}}
+  ]
+}`
+
+func writeAvroFile(t *testing.T, schema string, codec ocf.CodecName, rows []map[string]any) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data.avro")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	enc, err := ocf.NewEncoder(schema, f, ocf.WithCodec(codec))
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NoError(t, enc.Encode(row))
+	}
+	require.NoError(t, enc.Close())
+	return path
+}
+
+func readAvroRecord(t *testing.T, path string, opts *integrations.AvroReadOptions) arrow.Record {
+	t.Helper()
+	reader, err := integrations.NewAvroReader(context.Background(), path, opts)
+	require.NoError(t, err)
+	defer reader.Close()
+	rec, err := reader.Read()
+	require.NoError(t, err)
+	_, err = reader.Read()
+	require.ErrorIs(t, err, io.EOF)
+	return rec
+}
+
+func TestReadAvroLogicalTypesAndUnions(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	rows := []map[string]any{
+		{
+			"id": 1, "price": big.NewRat(-12345, 100), "amount": big.NewRat(1500, 1000),
+			"ts_ms": ts, "ts_us": ts, "local_ts": ts, "day": ts, "clock": 90 * time.Minute,
+			"uid": "0f8fad5b-d9cb-469f-a165-70867728950e", "note": "hello", "choice": 7,
+			"number": 3, "color": "GREEN",
+		},
+		{
+			"id": 2, "price": big.NewRat(5, 1), "amount": big.NewRat(-1, 1000),
+			"ts_ms": ts, "ts_us": ts, "local_ts": ts, "day": ts, "clock": time.Duration(0),
+			"uid": "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "note": nil, "choice": "seven",
+			"number": 2.5, "color": "RED",
+		},
+	}
+
+	for _, codec := range []ocf.CodecName{ocf.Null, ocf.Deflate, ocf.Snappy, ocf.ZStandard} {
+		t.Run(string(codec), func(t *testing.T) {
+			rec := readAvroRecord(t, writeAvroFile(t, avroTypesSchema, codec, rows), nil)
+			defer rec.Release()
+			schema := rec.Schema()
+			require.EqualValues(t, 2, rec.NumRows())
+
+			col := func(name string) arrow.Array {
+				idx := schema.FieldIndices(name)
+				require.Len(t, idx, 1, name)
+				return rec.Column(idx[0])
+			}
+
+			require.Equal(t, &arrow.Decimal128Type{Precision: 10, Scale: 2}, col("price").DataType())
+			require.Equal(t, "-123.45", col("price").ValueStr(0))
+			require.Equal(t, "5", col("price").ValueStr(1))
+			require.Equal(t, "1.5", col("amount").ValueStr(0))
+			require.Equal(t, "-0.001", col("amount").ValueStr(1))
+
+			require.Equal(t, &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}, col("ts_ms").DataType())
+			require.Equal(t, &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}, col("ts_us").DataType())
+			require.Equal(t, &arrow.TimestampType{Unit: arrow.Millisecond}, col("local_ts").DataType())
+			require.Equal(t, arrow.Timestamp(ts.UnixMilli()), col("ts_ms").(*array.Timestamp).Value(0))
+			require.Equal(t, arrow.Timestamp(ts.UnixMicro()), col("ts_us").(*array.Timestamp).Value(0))
+			require.Equal(t, arrow.FixedWidthTypes.Date32, col("day").DataType())
+			require.Equal(t, arrow.Date32FromTime(ts), col("day").(*array.Date32).Value(0))
+			require.Equal(t, arrow.FixedWidthTypes.Time32ms, col("clock").DataType())
+			require.Equal(t, arrow.Time32(90*60*1000), col("clock").(*array.Time32).Value(0))
+
+			require.True(t, arrow.TypeEqual(extensions.NewUUIDType(), col("uid").DataType()))
+			require.Equal(t, "0f8fad5b-d9cb-469f-a165-70867728950e", col("uid").ValueStr(0))
+
+			note := col("note")
+			require.Equal(t, arrow.BinaryTypes.String, note.DataType())
+			require.True(t, schema.Field(schema.FieldIndices("note")[0]).Nullable)
+			require.Equal(t, "hello", note.ValueStr(0))
+			require.True(t, note.IsNull(1))
+
+			choice := col("choice").(*array.Struct)
+			require.Equal(t, "int", choice.DataType().(*arrow.StructType).Field(0).Name)
+			require.Equal(t, "string", choice.DataType().(*arrow.StructType).Field(1).Name)
+			require.Equal(t, int32(7), choice.Field(0).(*array.Int32).Value(0))
+			require.True(t, choice.Field(1).IsNull(0))
+			require.True(t, choice.Field(0).IsNull(1))
+			require.Equal(t, "seven", choice.Field(1).ValueStr(1))
+
+			require.Equal(t, arrow.PrimitiveTypes.Float64, col("number").DataType())
+			require.Equal(t, []float64{3, 2.5}, col("number").(*array.Float64).Float64Values())
+
+			require.Equal(t, "GREEN", col("color").ValueStr(0))
+			require.Equal(t, "RED", col("color").ValueStr(1))
+		})
+	}
+}
+
+func TestReadAvroWithReaderSchema(t *testing.T) {
+	writer := `{
+	  "type": "record", "name": "User",
+	  "fields": [
+	    {"name": "id", "type": "int"},
+	    {"name": "score", "type": "float"},
+	    {"name": "nick", "type": "string"},
+	    {"name": "dropped", "type": {"type": "array", "items": "string"}},
+	    {"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["LOW", "HIGH", "EXTREME"]}}
+	  ]
+	}`
+	path := writeAvroFile(t, writer, ocf.Deflate, []map[string]any{
+		{"id": 1, "score": float32(1.5), "nick": "ann", "dropped": []any{"x"}, "level": "HIGH"},
+		{"id": 2, "score": float32(2), "nick": "bob", "dropped": []any{}, "level": "EXTREME"},
+	})
+
+	reader := `{
+	  "type": "record", "name": "User",
+	  "fields": [
+	    {"name": "id", "type": "long"},
+	    {"name": "score", "type": ["null", "double"]},
+	    {"name": "name", "type": "string", "aliases": ["nick"]},
+	    {"name": "country", "type": "string", "default": "unknown"},
+	    {"name": "tags", "type": {"type": "array", "items": "string"}, "default": ["a", "b"]},
+	    {"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["LOW", "HIGH"], "default": "HIGH"}}
+	  ]
+	}`
+	rec := readAvroRecord(t, path, &integrations.AvroReadOptions{ReaderSchema: reader})
+	defer rec.Release()
+
+	schema := rec.Schema()
+	require.Equal(t, []string{"id", "score", "name", "country", "tags", "level"}, fieldNames(schema))
+	require.Equal(t, []int64{1, 2}, rec.Column(0).(*array.Int64).Int64Values())
+	require.Equal(t, []float64{1.5, 2}, rec.Column(1).(*array.Float64).Float64Values())
+	require.Equal(t, "bob", rec.Column(2).ValueStr(1))
+	require.Equal(t, "unknown", rec.Column(3).ValueStr(0))
+	require.Equal(t, `["a","b"]`, rec.Column(4).ValueStr(1))
+	require.Equal(t, "HIGH", rec.Column(5).ValueStr(1))
+
+	_, err := integrations.NewAvroReader(context.Background(), path, &integrations.AvroReadOptions{
+		ReaderSchema: `{"type": "record", "name": "User", "fields": [{"name": "missing", "type": "int"}]}`,
+	})
+	require.ErrorContains(t, err, "has no default")
+}
@@ -0,0 +1,82 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	bq "cloud.google.com/go/bigquery"
+	bigquery "github.com/arrowarc/arrowarc/integrations/bigquery"
+	helper "github.com/arrowarc/arrowarc/pkg/common/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBigQueryQuery(t *testing.T) {
+	helper.LoadEnv()
+	// Skip this test in CI environment if GCP credentials are not set
+	if os.Getenv("CI") == "true" || os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") == "" {
+		t.Skip("Skipping BigQuery integration test in CI environment or when GCP credentials are not set.")
+	}
+
+	projectID := "tfmv-371720"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := bigquery.NewBigQueryReadClient(ctx)
+	require.NoError(t, err)
+
+	reader, err := client.NewBigQueryQueryReader(ctx, projectID,
+		"SELECT r_regionkey, UPPER(r_name) AS name FROM tpch.region WHERE r_regionkey < @max ORDER BY r_regionkey",
+		bq.QueryParameter{Name: "max", Value: 3},
+	)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	schema, err := reader.Schema()
+	require.NoError(t, err)
+	require.Equal(t, []string{"r_regionkey", "name"}, fieldNames(schema))
+
+	var rows int64
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		rows += record.NumRows()
+		record.Release()
+	}
+	require.Equal(t, int64(3), rows)
+}
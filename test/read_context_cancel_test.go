@@ -0,0 +1,290 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	storagepb "cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/converter"
+	bigquery "github.com/arrowarc/arrowarc/integrations/bigquery"
+	duckdb "github.com/arrowarc/arrowarc/integrations/duckdb"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	interfaces "github.com/arrowarc/arrowarc/internal/interfaces"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var cancelSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "name", Type: arrow.BinaryTypes.String},
+}, nil)
+
+// cancelRecord returns a record of ten rows with ids from start.
+func cancelRecord(start int64) arrow.Record {
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), cancelSchema)
+	defer b.Release()
+	for i := start; i < start+10; i++ {
+		b.Field(0).(*array.Int64Builder).Append(i)
+		b.Field(1).(*array.StringBuilder).Append("row")
+	}
+	return b.NewRecord()
+}
+
+// writeCancelFile writes three records of ten rows with w.
+func writeCancelFile(t *testing.T, w interfaces.Writer, err error) {
+	t.Helper()
+	require.NoError(t, err)
+	for start := int64(0); start < 30; start += 10 {
+		rec := cancelRecord(start)
+		require.NoError(t, w.Write(rec))
+		rec.Release()
+	}
+	require.NoError(t, w.Close())
+}
+
+// requireStopsWhenCanceled reads a record from the reader open returns,
+// cancels its context and requires reading on to fail with
+// context.Canceled, after at most the records the reader read ahead.
+func requireStopsWhenCanceled(t *testing.T, open func(ctx context.Context) (interfaces.Reader, error)) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, err := open(ctx)
+	require.NoError(t, err)
+	defer r.Close()
+
+	rec, err := r.Read()
+	require.NoError(t, err)
+	rec.Release()
+	cancel()
+	for ahead := 0; ; ahead++ {
+		rec, err := r.Read()
+		if err != nil {
+			require.ErrorIs(t, err, context.Canceled)
+			return
+		}
+		rec.Release()
+		require.Less(t, ahead, 10, "the reader kept returning records once canceled")
+	}
+}
+
+func TestFileReadersStopWhenCanceled(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	csvPath := filepath.Join(dir, "rows.csv")
+	csvWriter, err := integrations.NewCSVWriter(ctx, csvPath, cancelSchema, &integrations.CSVWriteOptions{Delimiter: ',', IncludeHeader: true})
+	writeCancelFile(t, csvWriter, err)
+	avroPath := filepath.Join(dir, "rows.avro")
+	avroWriter, err := integrations.NewAvroWriter(ctx, avroPath, cancelSchema, nil)
+	writeCancelFile(t, avroWriter, err)
+	ipcPath := filepath.Join(dir, "rows.arrows")
+	ipcWriter, err := integrations.NewIPCRecordWriterWithOptions(ctx, ipcPath, cancelSchema, nil)
+	writeCancelFile(t, ipcWriter, err)
+	featherPath := filepath.Join(dir, "rows.feather")
+	featherWriter, err := integrations.NewFeatherWriter(ctx, featherPath, cancelSchema, nil)
+	writeCancelFile(t, featherWriter, err)
+	parquetPath := writeFilterTestFile(t, nil)
+
+	tests := []struct {
+		name string
+		open func(ctx context.Context) (interfaces.Reader, error)
+	}{
+		{"csv", func(ctx context.Context) (interfaces.Reader, error) {
+			return integrations.NewCSVReader(ctx, csvPath, cancelSchema, &integrations.CSVReadOptions{HasHeader: true, ChunkSize: 10, Delimiter: ','})
+		}},
+		{"avro", func(ctx context.Context) (interfaces.Reader, error) {
+			return integrations.NewAvroReader(ctx, avroPath, &integrations.AvroReadOptions{ChunkSize: 10})
+		}},
+		{"ipc", func(ctx context.Context) (interfaces.Reader, error) {
+			r, err := integrations.NewIPCRecordReader(ctx, ipcPath)
+			if err != nil {
+				return nil, err
+			}
+			return r.(*integrations.IPCRecordReader), nil
+		}},
+		{"feather", func(ctx context.Context) (interfaces.Reader, error) {
+			return integrations.NewFeatherReader(ctx, featherPath, nil)
+		}},
+		{"parquet", func(ctx context.Context) (interfaces.Reader, error) {
+			return integrations.NewParquetReader(ctx, parquetPath, &integrations.ParquetReadOptions{ChunkSize: 10})
+		}},
+		{"parquet parallel", func(ctx context.Context) (interfaces.Reader, error) {
+			return integrations.NewParquetReader(ctx, parquetPath, &integrations.ParquetReadOptions{ChunkSize: 10, Parallel: true, Workers: 2})
+		}},
+		{"parquet unordered", func(ctx context.Context) (interfaces.Reader, error) {
+			return integrations.NewParquetReader(ctx, parquetPath, &integrations.ParquetReadOptions{ChunkSize: 10, Parallel: true, Unordered: true})
+		}},
+		{"multiple files", func(ctx context.Context) (interfaces.Reader, error) {
+			paths, err := integrations.ExpandInputPaths(multiParquetDir(t), ".parquet")
+			if err != nil {
+				return nil, err
+			}
+			return integrations.NewMultiFileReader(ctx, paths, 2, openMultiParquet)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requireStopsWhenCanceled(t, test.open)
+		})
+	}
+}
+
+func TestConvertStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	out := filepath.Join(t.TempDir(), "out.csv")
+	_, err := converter.ConvertParquetToCSVWithOptions(ctx, writeFilterTestFile(t, nil), out, &converter.ParquetToCSVOptions{
+		ChunkSize:     10,
+		Concurrency:   1,
+		Delimiter:     ',',
+		IncludeHeader: true,
+	})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+// fakeBigQueryRead serves one stream of one record batch, then holds the
+// stream open until the client goes away.
+type fakeBigQueryRead struct {
+	storagepb.UnimplementedBigQueryReadServer
+	schema []byte
+	batch  []byte
+	rows   int64
+}
+
+func (f *fakeBigQueryRead) CreateReadSession(ctx context.Context, req *storagepb.CreateReadSessionRequest) (*storagepb.ReadSession, error) {
+	return &storagepb.ReadSession{
+		Name:    req.GetParent() + "/locations/us/sessions/cancel",
+		Schema:  &storagepb.ReadSession_ArrowSchema{ArrowSchema: &storagepb.ArrowSchema{SerializedSchema: f.schema}},
+		Streams: []*storagepb.ReadStream{{Name: req.GetParent() + "/locations/us/sessions/cancel/streams/0"}},
+	}, nil
+}
+
+func (f *fakeBigQueryRead) ReadRows(req *storagepb.ReadRowsRequest, stream storagepb.BigQueryRead_ReadRowsServer) error {
+	if err := stream.Send(&storagepb.ReadRowsResponse{
+		Rows:     &storagepb.ReadRowsResponse_ArrowRecordBatch{ArrowRecordBatch: &storagepb.ArrowRecordBatch{SerializedRecordBatch: f.batch, RowCount: f.rows}},
+		RowCount: f.rows,
+	}); err != nil {
+		return err
+	}
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+// newFakeBigQueryRead serializes rec as the Storage Read API does: the
+// schema message once, and the record batch message in each response.
+func newFakeBigQueryRead(t *testing.T, rec arrow.Record) *fakeBigQueryRead {
+	t.Helper()
+	var schemaOnly, full bytes.Buffer
+	w := ipc.NewWriter(&schemaOnly, ipc.WithSchema(rec.Schema()))
+	require.NoError(t, w.Close())
+	// Closing an empty stream writes the schema and an 8 byte end marker.
+	schemaLen := schemaOnly.Len() - 8
+	w = ipc.NewWriter(&full, ipc.WithSchema(rec.Schema()))
+	require.NoError(t, w.Write(rec))
+	require.NoError(t, w.Close())
+	require.Equal(t, schemaOnly.Bytes()[:schemaLen], full.Bytes()[:schemaLen])
+	return &fakeBigQueryRead{
+		schema: schemaOnly.Bytes()[:schemaLen],
+		batch:  full.Bytes()[schemaLen : full.Len()-8],
+		rows:   rec.NumRows(),
+	}
+}
+
+func TestBigQueryReaderStopsWhenCanceled(t *testing.T) {
+	rec := cancelRecord(0)
+	defer rec.Release()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	storagepb.RegisterBigQueryReadServer(server, newFakeBigQueryRead(t, rec))
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, err := bigquery.NewBigQueryReadClient(ctx,
+		option.WithEndpoint(lis.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	require.NoError(t, err)
+	r, err := client.NewBigQueryReader(ctx, "project", "dataset", "table")
+	require.NoError(t, err)
+	defer r.Close()
+
+	got, err := r.Read()
+	require.NoError(t, err)
+	require.EqualValues(t, 10, got.NumRows())
+	got.Release()
+
+	// The next read blocks on the open stream until canceled.
+	time.AfterFunc(50*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Read()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.True(t, errors.Is(err, context.Canceled), "got %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Read did not return once canceled")
+	}
+}
+
+func TestDuckDBReaderStopsWhenCanceled(t *testing.T) {
+	// Skip test in CI environment if DuckDB shared library is not available.
+	if os.Getenv("CI") == "true" {
+		t.Skip("Skipping DuckDB integration test in CI environment.")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, err := duckdb.NewDuckDBReader(ctx, ":memory:", &duckdb.DuckDBReadOptions{Query: "SELECT * FROM range(10)"})
+	require.NoError(t, err)
+	defer r.Close()
+	cancel()
+	_, err = r.Read()
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,204 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	converter "github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/stretchr/testify/require"
+)
+
+var csvStreamSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "score", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	{Name: "name", Type: arrow.BinaryTypes.String},
+}, nil)
+
+// writeHeaderlessCSV writes rows of about 40 bytes without a header.
+func writeHeaderlessCSV(t testing.TB, path string, rows int) int64 {
+	t.Helper()
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	w := bufio.NewWriter(f)
+	for i := 0; i < rows; i++ {
+		score := fmt.Sprintf("%d.5", i%100)
+		if i%10 == 0 {
+			score = ""
+		}
+		fmt.Fprintf(w, "%d,%s,\"name, number %08d\"\n", i, score, i)
+	}
+	require.NoError(t, w.Flush())
+	info, err := f.Stat()
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return info.Size()
+}
+
+func TestCSVReaderMaxBatchBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rows.csv")
+	writeHeaderlessCSV(t, path, 1000)
+
+	r, err := integrations.NewCSVReader(context.Background(), path, csvStreamSchema, &integrations.CSVReadOptions{
+		ChunkSize:     1 << 20,
+		MaxBatchBytes: 4 << 10,
+	})
+	require.NoError(t, err)
+	defer r.Close()
+
+	var rows, records int64
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Less(t, rec.NumRows(), int64(200), "records end after about 4 KiB of input")
+		if rows == 0 {
+			require.True(t, rec.Column(1).IsNull(0))
+			require.Equal(t, "name, number 00000000", rec.Column(2).(*array.String).Value(0))
+		}
+		rows += rec.NumRows()
+		records++
+		rec.Release()
+	}
+	require.EqualValues(t, 1000, rows)
+	require.Greater(t, records, int64(5))
+}
+
+func TestCSVReaderRingWaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rows.csv")
+	writeHeaderlessCSV(t, path, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, err := integrations.NewCSVReader(ctx, path, csvStreamSchema, &integrations.CSVReadOptions{
+		ChunkSize:       10,
+		BufferedBatches: 2,
+	})
+	require.NoError(t, err)
+	defer r.Close()
+
+	first, err := r.Read()
+	require.NoError(t, err)
+	second, err := r.Read()
+	require.NoError(t, err)
+	second.Release()
+
+	// The ring is back at the builder of the first record, still held.
+	var third atomic.Pointer[arrow.Record]
+	done := make(chan error, 1)
+	go func() {
+		rec, err := r.Read()
+		if err == nil {
+			third.Store(&rec)
+		}
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("Read returned while every builder's record was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.EqualValues(t, 0, first.Column(0).(*array.Int64).Value(0))
+	first.Release()
+	require.NoError(t, <-done)
+	rec := *third.Load()
+	require.EqualValues(t, 20, rec.Column(0).(*array.Int64).Value(0))
+	rec.Release()
+}
+
+func TestCSVReaderReportsBadValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.csv")
+	require.NoError(t, os.WriteFile(path, []byte("id,score,name\n1,2.5,a\nx,3,b\n"), 0o644))
+
+	r, err := integrations.NewCSVReader(context.Background(), path, csvStreamSchema, &integrations.CSVReadOptions{ChunkSize: 10, HasHeader: true})
+	require.NoError(t, err)
+	defer r.Close()
+	_, err = r.Read()
+	require.ErrorContains(t, err, `line 3, column "id"`)
+}
+
+// BenchmarkConvertHeaderlessCSVToParquet converts a 64 MiB headerless CSV
+// and reports the peak heap in use, which stays bounded by the reader's
+// batch limits rather than growing with the file.
+func BenchmarkConvertHeaderlessCSVToParquet(b *testing.B) {
+	dir := b.TempDir()
+	csvPath := filepath.Join(dir, "rows.csv")
+	size := writeHeaderlessCSV(b, csvPath, 1_600_000)
+	b.SetBytes(size)
+	b.ResetTimer()
+
+	var peak uint64
+	for i := 0; i < b.N; i++ {
+		stop := make(chan struct{})
+		sampled := make(chan uint64)
+		go func() {
+			var peakInUse uint64
+			var m runtime.MemStats
+			ticker := time.NewTicker(10 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				runtime.ReadMemStats(&m)
+				peakInUse = max(peakInUse, m.HeapInuse)
+				select {
+				case <-stop:
+					sampled <- peakInUse
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+
+		_, err := converter.ConvertCSVToParquetWithOptions(context.Background(), csvPath, filepath.Join(dir, "rows.parquet"), &converter.CSVToParquetOptions{
+			ChunkSize:   1 << 20,
+			Delimiter:   ',',
+			Concurrency: 1,
+		})
+		close(stop)
+		peak = max(peak, <-sampled)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MiB")
+}
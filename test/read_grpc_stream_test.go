@@ -0,0 +1,152 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	grpcsource "github.com/arrowarc/arrowarc/integrations/grpc"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// startEventServer serves logs.v1.EventService/Tail, which streams count
+// events, with only the reflection service to describe it.
+func startEventServer(t *testing.T, delay time.Duration) string {
+	t.Helper()
+	files, err := protodesc.NewFiles(eventDescriptorSet())
+	require.NoError(t, err)
+	d, err := files.FindDescriptorByName("logs.v1.EventService.Tail")
+	require.NoError(t, err)
+	method := d.(protoreflect.MethodDescriptor)
+
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "logs.v1.EventService",
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Tail",
+			ServerStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				req := dynamicpb.NewMessage(method.Input())
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				count := int(req.Get(method.Input().Fields().ByName("count")).Int())
+				for i := 0; i < count; i++ {
+					if err := stream.SendMsg(newEventMessage(method.Output(), i)); err != nil {
+						return err
+					}
+					time.Sleep(delay)
+				}
+				return nil
+			},
+		}},
+	}, struct{}{})
+	rpb.RegisterServerReflectionServer(server, reflection.NewServerV1(reflection.ServerOptions{
+		Services:           server,
+		DescriptorResolver: files,
+	}))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestReadGRPCStream(t *testing.T) {
+	ctx := context.Background()
+	addr := startEventServer(t, 0)
+
+	reader, err := integrations.OpenSource(ctx, grpcsource.SchemeGRPC+addr+"/logs.v1.EventService/Tail", &integrations.SourceOptions{
+		ChunkSize: 4,
+		GRPC:      grpcsource.StreamOptions{Request: `{"count": 10}`, MaxMessages: 6},
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, []string{"id", "message", "score", "tags", "attrs", "source", "level"}, fieldNames(reader.Schema()))
+
+	var ids []int64
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, rec.Column(0).(*array.Int64).Int64Values()...)
+		rec.Release()
+	}
+	require.Equal(t, []int64{0, 1, 2, 3, 4, 5}, ids)
+}
+
+func TestReadGRPCStreamFlushInterval(t *testing.T) {
+	addr := startEventServer(t, 50*time.Millisecond)
+	reader, err := grpcsource.NewStreamReader(context.Background(), grpcsource.SchemeGRPC+addr+"/logs.v1.EventService.Tail", &grpcsource.StreamOptions{
+		Request:       `{"count": 3}`,
+		ChunkSize:     100,
+		FlushInterval: 20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	rec, err := reader.Read()
+	require.NoError(t, err)
+	defer rec.Release()
+	require.Less(t, rec.NumRows(), int64(3))
+}
+
+func TestReadDelimitedProtobufWithReflection(t *testing.T) {
+	ctx := context.Background()
+	addr := startEventServer(t, 0)
+	_, dataPath := writeProtobufLog(t, t.TempDir())
+
+	reader, err := integrations.NewProtobufReader(ctx, dataPath, &integrations.ProtobufReadOptions{
+		Reflection:  grpcsource.SchemeGRPC + addr,
+		MessageName: "logs.v1.Event",
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, []int64{0, 1, 2, 3, 4}, readAllIDs(t, reader))
+
+	_, err = grpcsource.ResolveMessage(ctx, grpcsource.SchemeGRPC+addr, "logs.v1.Missing")
+	require.Error(t, err)
+}
@@ -0,0 +1,213 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/preview"
+	"github.com/stretchr/testify/require"
+)
+
+// serveFile serves data with range support. handle runs first and may
+// answer the request itself by returning true.
+func serveFile(t *testing.T, name string, data []byte, handle func(w http.ResponseWriter, r *http.Request) bool) *httptest.Server {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handle != nil && handle(w, r) {
+			return
+		}
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// httpOptions returns options trusting srv, sending creds.
+func httpOptions(srv *httptest.Server, creds ...integrations.HTTPCredentials) *integrations.HTTPOptions {
+	return &integrations.HTTPOptions{Client: srv.Client(), Credentials: creds, MaxRetries: 2, Backoff: time.Millisecond}
+}
+
+func TestReadParquetOverHTTP(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "payload", Type: arrow.BinaryTypes.String},
+	}, nil)
+	path := filepath.Join(t.TempDir(), "data.parquet")
+	writer, err := integrations.NewParquetWriter(path, schema, parquet.NewWriterProperties(parquet.WithMaxRowGroupLength(100)))
+	require.NoError(t, err)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	for i := 0; i < 1000; i++ {
+		b.Field(0).(*array.Int64Builder).Append(int64(i))
+		b.Field(1).(*array.StringBuilder).Append(strings.Repeat("x", 100))
+	}
+	rec := b.NewRecord()
+	require.NoError(t, writer.Write(rec))
+	rec.Release()
+	b.Release()
+	require.NoError(t, writer.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var unranged, ranged atomic.Int64
+	srv := serveFile(t, "data.parquet", data, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return true
+		}
+		if r.Header.Get("Range") == "" {
+			unranged.Add(1)
+		} else {
+			ranged.Add(1)
+		}
+		return false
+	})
+	ctx := integrations.WithHTTPOptions(context.Background(), httpOptions(srv, integrations.HTTPCredentials{Prefix: srv.URL + "/", BearerToken: "secret"}))
+
+	reader, err := integrations.NewParquetReader(ctx, srv.URL+"/data.parquet?sig=abc", &integrations.ParquetReadOptions{
+		ChunkSize: 1000,
+		RowGroups: []int{9},
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, int64(100), reader.NumRows())
+
+	var out bytes.Buffer
+	require.NoError(t, preview.Head(reader, 1, &out, preview.Options{Format: preview.JSON, Columns: []string{"id"}}))
+	require.Equal(t, `{"id":900}`+"\n", out.String())
+	require.Zero(t, unranged.Load(), "the whole file was downloaded")
+	require.NotZero(t, ranged.Load())
+
+	ctx = integrations.WithHTTPOptions(context.Background(), httpOptions(srv, integrations.HTTPCredentials{Prefix: srv.URL + "/private/", BearerToken: "secret"}))
+	_, err = integrations.NewParquetReader(ctx, srv.URL+"/data.parquet?sig=abc", &integrations.ParquetReadOptions{})
+	require.ErrorContains(t, err, "401")
+	require.NotContains(t, err.Error(), "sig=abc")
+}
+
+func TestReadCSVOverHTTPRetries(t *testing.T) {
+	data := []byte("id,name\n1,ada\n2,grace\n3,edsger\n")
+	var failures atomic.Int64
+	srv := serveFile(t, "people.csv", data, func(w http.ResponseWriter, r *http.Request) bool {
+		// Fail every other request.
+		if failures.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+	reader, err := integrations.OpenSource(context.Background(), srv.URL+"/people.csv", &integrations.SourceOptions{
+		CSV:  csvschema.CSVReadOptions{HasHeader: true},
+		HTTP: httpOptions(srv),
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	var out bytes.Buffer
+	require.NoError(t, preview.Cat(reader, &out, preview.Options{Format: preview.JSON}))
+	require.Equal(t, "{\"id\":1,\"name\":\"ada\"}\n{\"id\":2,\"name\":\"grace\"}\n{\"id\":3,\"name\":\"edsger\"}\n", out.String())
+}
+
+func TestHTTPStreamResumes(t *testing.T) {
+	data := []byte(strings.Repeat("{\"n\":1}\n", 10000))
+	var cut atomic.Bool
+	srv := serveFile(t, "rows.ndjson", data, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Range") != "" || cut.Swap(true) {
+			return false
+		}
+		w.Header().Set("Content-Length", "80000")
+		w.Write(data[:40000])
+		panic(http.ErrAbortHandler)
+	})
+	ctx := integrations.WithHTTPOptions(context.Background(), httpOptions(srv))
+
+	stream, err := integrations.OpenFile(ctx, srv.URL+"/rows.ndjson")
+	require.NoError(t, err)
+	defer stream.Close()
+	var got bytes.Buffer
+	_, err = got.ReadFrom(stream)
+	require.NoError(t, err)
+	require.Equal(t, data, got.Bytes())
+}
+
+func TestHTTPCredentialsStayInScope(t *testing.T) {
+	var leaked atomic.Value
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked.Store(r.Header.Get("Authorization") + r.Header.Get("X-Api-Key"))
+		w.Write([]byte("id\n1\n"))
+	}))
+	defer plain.Close()
+	srv := serveFile(t, "ids.csv", nil, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return true
+		}
+		http.Redirect(w, r, plain.URL+"/ids.csv", http.StatusFound)
+		return true
+	})
+	opts := httpOptions(srv, integrations.HTTPCredentials{
+		Prefix:      srv.URL + "/data",
+		Headers:     http.Header{"X-Api-Key": {"key"}},
+		BearerToken: "secret",
+	})
+
+	stream, err := integrations.OpenFile(integrations.WithHTTPOptions(context.Background(), opts), srv.URL+"/data/ids.csv")
+	require.NoError(t, err)
+	stream.Close()
+	require.Equal(t, "", leaked.Load(), "credentials followed the redirect to another host")
+
+	headers, err := opts.HeadersFor(srv.URL + "/database/ids.csv")
+	require.NoError(t, err)
+	require.Empty(t, headers, "the prefix covers whole path segments only")
+
+	opts.Credentials[0].Prefix = plain.URL
+	_, err = integrations.OpenFile(integrations.WithHTTPOptions(context.Background(), opts), plain.URL+"/ids.csv")
+	require.ErrorContains(t, err, "refusing to send a bearer token over plain http")
+
+	t.Setenv(integrations.EnvHTTPBearerToken, "secret")
+	_, err = integrations.NewHTTPOptionsFromEnv()
+	require.ErrorContains(t, err, integrations.EnvHTTPURLPrefix)
+	t.Setenv(integrations.EnvHTTPURLPrefix, plain.URL)
+	_, err = integrations.NewHTTPOptionsFromEnv()
+	require.ErrorContains(t, err, "refusing to send a bearer token over plain http")
+}
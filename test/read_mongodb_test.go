@@ -0,0 +1,203 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	mongodb "github.com/arrowarc/arrowarc/integrations/mongodb"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func mongoTestCursor(t *testing.T, docs ...bson.D) *mongo.Cursor {
+	t.Helper()
+	values := make([]interface{}, len(docs))
+	for i, d := range docs {
+		values[i] = d
+	}
+	cursor, err := mongo.NewCursorFromDocuments(values, nil, nil)
+	require.NoError(t, err)
+	return cursor
+}
+
+func mongoTestDocuments(t *testing.T) []bson.D {
+	t.Helper()
+	price, err := bson.ParseDecimal128("19.99")
+	require.NoError(t, err)
+	bigPrice, err := bson.ParseDecimal128("1250.5")
+	require.NoError(t, err)
+	id, err := bson.ObjectIDFromHex("65f1c0a2b3d4e5f601234567")
+	require.NoError(t, err)
+	created := time.Date(2024, 8, 31, 13, 10, 54, 0, time.UTC)
+
+	return []bson.D{
+		{
+			{Key: "_id", Value: id},
+			{Key: "qty", Value: int32(3)},
+			{Key: "price", Value: price},
+			{Key: "created", Value: bson.NewDateTimeFromTime(created)},
+			{Key: "address", Value: bson.D{{Key: "city", Value: "Austin"}, {Key: "zip", Value: "78701"}}},
+			{Key: "tags", Value: bson.A{"a", "b"}},
+			{Key: "note", Value: "first"},
+		},
+		{
+			{Key: "_id", Value: bson.NewObjectID()},
+			{Key: "qty", Value: int64(1) << 40},
+			{Key: "price", Value: bigPrice},
+			{Key: "created", Value: nil},
+			{Key: "address", Value: bson.D{{Key: "city", Value: "Dallas"}}},
+			{Key: "tags", Value: bson.A{}},
+			{Key: "note", Value: int32(7)},
+		},
+		{
+			{Key: "_id", Value: bson.NewObjectID()},
+			{Key: "extra", Value: true},
+		},
+	}
+}
+
+func TestMongoReaderInfersSchema(t *testing.T) {
+	ctx := context.Background()
+	docs := mongoTestDocuments(t)
+
+	reader, err := mongodb.NewMongoCursorReader(ctx, mongoTestCursor(t, docs...), &mongodb.MongoReadOptions{
+		BatchSize: 2,
+		Allocator: memory.NewGoAllocator(),
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	schema := reader.Schema()
+	require.Equal(t, []string{"_id", "qty", "price", "created", "address", "tags", "note", "extra"}, fieldNames(schema))
+	require.Equal(t, arrow.BinaryTypes.String, schema.Field(0).Type)
+	require.Equal(t, arrow.PrimitiveTypes.Int64, schema.Field(1).Type)
+	require.Equal(t, &arrow.Decimal128Type{Precision: 38, Scale: 2}, schema.Field(2).Type)
+	require.Equal(t, &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}, schema.Field(3).Type)
+	require.Equal(t, arrow.STRUCT, schema.Field(4).Type.ID())
+	require.Equal(t, arrow.ListOf(arrow.BinaryTypes.String), schema.Field(5).Type)
+	// Conflicting types fall back to strings.
+	require.Equal(t, arrow.BinaryTypes.String, schema.Field(6).Type)
+	require.Equal(t, arrow.FixedWidthTypes.Boolean, schema.Field(7).Type)
+
+	first, err := reader.Read()
+	require.NoError(t, err)
+	defer first.Release()
+	require.Equal(t, int64(2), first.NumRows())
+
+	require.Equal(t, "65f1c0a2b3d4e5f601234567", first.Column(0).(*array.String).Value(0))
+	require.Equal(t, int64(1)<<40, first.Column(1).(*array.Int64).Value(1))
+	require.Equal(t, "19.99", first.Column(2).ValueStr(0))
+	require.Equal(t, "1250.5", first.Column(2).ValueStr(1))
+	require.Equal(t, "2024-08-31 13:10:54Z", first.Column(3).ValueStr(0))
+	require.True(t, first.Column(3).IsNull(1))
+	require.Equal(t, "7", first.Column(6).ValueStr(1))
+	require.True(t, first.Column(7).IsNull(0))
+
+	address := first.Column(4).(*array.Struct)
+	require.Equal(t, "Austin", address.Field(0).ValueStr(0))
+	require.True(t, address.Field(1).IsNull(1))
+
+	second, err := reader.Read()
+	require.NoError(t, err)
+	defer second.Release()
+	require.Equal(t, int64(1), second.NumRows())
+	require.True(t, second.Column(1).IsNull(0))
+	require.True(t, second.Column(7).(*array.Boolean).Value(0))
+
+	_, err = reader.Read()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestMongoReaderFlattensDocuments(t *testing.T) {
+	ctx := context.Background()
+	reader, err := mongodb.NewMongoCursorReader(ctx, mongoTestCursor(t, mongoTestDocuments(t)...), &mongodb.MongoReadOptions{
+		Flatten:   true,
+		Allocator: memory.NewGoAllocator(),
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	require.Equal(t, []string{"_id", "qty", "price", "created", "address.city", "address.zip", "tags", "note", "extra"}, fieldNames(reader.Schema()))
+
+	record, err := reader.Read()
+	require.NoError(t, err)
+	defer record.Release()
+	require.Equal(t, int64(3), record.NumRows())
+	require.Equal(t, "Dallas", record.Column(4).ValueStr(1))
+	require.Equal(t, "78701", record.Column(5).ValueStr(0))
+}
+
+func TestMongoReaderWithSchema(t *testing.T) {
+	ctx := context.Background()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "_id", Type: arrow.BinaryTypes.Binary, Nullable: true},
+		{Name: "qty", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "price", Type: &arrow.Decimal128Type{Precision: 10, Scale: 1}, Nullable: true},
+		{Name: "created", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+		{Name: "address.city", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	reader, err := mongodb.NewMongoCursorReader(ctx, mongoTestCursor(t, mongoTestDocuments(t)...), &mongodb.MongoReadOptions{
+		Schema:    schema,
+		Flatten:   true,
+		Allocator: memory.NewGoAllocator(),
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+	require.True(t, schema.Equal(reader.Schema()))
+
+	record, err := reader.Read()
+	require.NoError(t, err)
+	defer record.Release()
+	require.Equal(t, 12, len(record.Column(0).(*array.Binary).Value(0)))
+	require.Equal(t, float64(3), record.Column(1).(*array.Float64).Value(0))
+	// 19.99 rounds to one decimal place.
+	require.Equal(t, "20", record.Column(2).ValueStr(0))
+	require.Equal(t, "2024-08-31", record.Column(3).ValueStr(0))
+	require.Equal(t, "Austin", record.Column(4).ValueStr(0))
+	require.True(t, record.Column(4).IsNull(2))
+
+	mismatched, err := mongodb.NewMongoCursorReader(ctx, mongoTestCursor(t, bson.D{{Key: "qty", Value: "many"}}), &mongodb.MongoReadOptions{
+		Schema:    arrow.NewSchema([]arrow.Field{{Name: "qty", Type: arrow.PrimitiveTypes.Int64, Nullable: true}}, nil),
+		Allocator: memory.NewGoAllocator(),
+	})
+	require.NoError(t, err)
+	defer mismatched.Close()
+	_, err = mismatched.Read()
+	require.ErrorContains(t, err, `field "qty": cannot convert BSON string to int64`)
+}
@@ -0,0 +1,307 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	converter "github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func writeMultiParquetFile(t *testing.T, path string, schema *arrow.Schema, start, n int64) {
+	t.Helper()
+	testutil.WriteParquet(t, path, schema, nil, func(b *array.RecordBuilder) {
+		for i := start; i < start+n; i++ {
+			b.Field(0).(*array.Int64Builder).Append(i)
+		}
+	})
+}
+
+func openMultiParquet(ctx context.Context, path string) (integrations.FileReader, error) {
+	return integrations.NewParquetReader(ctx, path, &integrations.ParquetReadOptions{ChunkSize: 1024})
+}
+
+func multiParquetDir(t *testing.T) string {
+	dir := t.TempDir()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	for i := int64(0); i < 3; i++ {
+		writeMultiParquetFile(t, filepath.Join(dir, fmt.Sprintf("part-%d.parquet", i)), schema, i*10, 10)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "_SUCCESS"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("skip"), 0o644))
+	return dir
+}
+
+func TestExpandInputPaths(t *testing.T) {
+	dir := multiParquetDir(t)
+
+	paths, err := integrations.ExpandInputPaths(dir, ".parquet")
+	require.NoError(t, err)
+	require.Len(t, paths, 3)
+
+	paths, err = integrations.ExpandInputPaths(filepath.Join(dir, "part-[12].parquet"), ".parquet")
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "part-1.parquet"), filepath.Join(dir, "part-2.parquet")}, paths)
+
+	_, err = integrations.ExpandInputPaths(filepath.Join(dir, "*.csv"))
+	require.Error(t, err)
+}
+
+func TestMultiFileReaderConcurrent(t *testing.T) {
+	dir := multiParquetDir(t)
+	paths, err := integrations.ExpandInputPaths(dir, ".parquet")
+	require.NoError(t, err)
+
+	reader, err := integrations.NewMultiFileReader(context.Background(), paths, 2, openMultiParquet)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	seen := make(map[int64]bool)
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids := rec.Column(0).(*array.Int64)
+		for i := 0; i < ids.Len(); i++ {
+			seen[ids.Value(i)] = true
+		}
+		rec.Release()
+	}
+	require.Len(t, seen, 30)
+}
+
+func TestMultiFileReaderSchemaMismatch(t *testing.T) {
+	dir := multiParquetDir(t)
+	other := arrow.NewSchema([]arrow.Field{{Name: "other", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	writeMultiParquetFile(t, filepath.Join(dir, "part-3.parquet"), other, 0, 5)
+	paths, err := integrations.ExpandInputPaths(dir, ".parquet")
+	require.NoError(t, err)
+
+	reader, err := integrations.NewMultiFileReader(context.Background(), paths, 1, openMultiParquet)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	for {
+		rec, err := reader.Read()
+		if err != nil {
+			require.NotEqual(t, io.EOF, err)
+			require.Contains(t, err.Error(), "part-3.parquet")
+			return
+		}
+		rec.Release()
+	}
+}
+
+func TestConvertParquetGlobToCSV(t *testing.T) {
+	dir := multiParquetDir(t)
+	csvPath := filepath.Join(t.TempDir(), "out.csv")
+
+	_, err := converter.ConvertParquetToCSVWithOptions(context.Background(), filepath.Join(dir, "*.parquet"), csvPath, &converter.ParquetToCSVOptions{
+		ChunkSize:     1024,
+		Concurrency:   2,
+		Delimiter:     ',',
+		IncludeHeader: true,
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(csvPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Equal(t, "id", lines[0])
+	require.Len(t, lines, 31)
+}
+
+// slowFileReader returns rows one-row records of consecutive ids from
+// start, sleeping delay before each, and counts the records not released.
+type slowFileReader struct {
+	schema      *arrow.Schema
+	start, rows int64
+	read        int64
+	delay       time.Duration
+	live        *atomic.Int64
+}
+
+func (r *slowFileReader) Read() (arrow.Record, error) {
+	if r.read == r.rows {
+		return nil, io.EOF
+	}
+	time.Sleep(r.delay)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), r.schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).Append(r.start + r.read)
+	r.read++
+	r.live.Add(1)
+	return &countedRecord{Record: b.NewRecord(), live: r.live}, nil
+}
+
+func (r *slowFileReader) Schema() *arrow.Schema { return r.schema }
+func (r *slowFileReader) Close() error          { return nil }
+
+// countedRecord decrements live once released.
+type countedRecord struct {
+	arrow.Record
+	live *atomic.Int64
+}
+
+func (r *countedRecord) Release() {
+	r.Record.Release()
+	r.live.Add(-1)
+}
+
+// slowFiles returns n file names and a factory opening each as five
+// records, the first file being much slower to read than the others.
+func slowFiles(n int, live *atomic.Int64) ([]string, integrations.FileReaderFactory) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("file-%d", i)
+	}
+	open := func(_ context.Context, path string) (integrations.FileReader, error) {
+		var i int64
+		fmt.Sscanf(path, "file-%d", &i)
+		delay := time.Millisecond
+		if i == 0 {
+			delay = 10 * time.Millisecond
+		}
+		return &slowFileReader{schema: schema, start: i * 5, rows: 5, delay: delay, live: live}, nil
+	}
+	return paths, open
+}
+
+func TestMultiFileReaderOrdered(t *testing.T) {
+	var live atomic.Int64
+	paths, open := slowFiles(8, &live)
+	reader, err := integrations.NewMultiFileReaderWithOptions(context.Background(), paths, open,
+		&integrations.MultiFileReadOptions{Concurrency: 4, Ordered: true, ReorderBuffer: 1})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	var ids []int64
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, rec.Column(0).(*array.Int64).Value(0))
+		require.Equal(t, fmt.Sprintf("file-%d", ids[len(ids)-1]/5), reader.Source())
+		// Files read ahead hold at most one record each, and at most
+		// twice the concurrency of them are started.
+		require.LessOrEqual(t, live.Load(), int64(1+2*4))
+		rec.Release()
+	}
+	require.Len(t, ids, 40)
+	for i, id := range ids {
+		require.EqualValues(t, i, id)
+	}
+}
+
+func TestMultiFileReaderOrderedSchemaMismatch(t *testing.T) {
+	dir := multiParquetDir(t)
+	other := arrow.NewSchema([]arrow.Field{{Name: "other", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	writeMultiParquetFile(t, filepath.Join(dir, "part-1a.parquet"), other, 0, 5)
+	paths, err := integrations.ExpandInputPaths(dir, ".parquet")
+	require.NoError(t, err)
+
+	reader, err := integrations.NewMultiFileReaderWithOptions(context.Background(), paths, openMultiParquet,
+		&integrations.MultiFileReadOptions{Concurrency: 3, Ordered: true})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	// The files before the mismatching one are returned whole first.
+	var rows int64
+	for {
+		rec, err := reader.Read()
+		if err != nil {
+			require.NotEqual(t, io.EOF, err)
+			require.Contains(t, err.Error(), "part-1a.parquet")
+			break
+		}
+		rows += rec.NumRows()
+		rec.Release()
+	}
+	require.EqualValues(t, 20, rows)
+}
+
+func TestMultiFileReaderOrderedCloseEarly(t *testing.T) {
+	var live atomic.Int64
+	paths, open := slowFiles(6, &live)
+	reader, err := integrations.NewMultiFileReaderWithOptions(context.Background(), paths, open,
+		&integrations.MultiFileReadOptions{Concurrency: 3, Ordered: true})
+	require.NoError(t, err)
+
+	rec, err := reader.Read()
+	require.NoError(t, err)
+	rec.Release()
+	require.NoError(t, reader.Close())
+	require.Zero(t, live.Load(), "records read ahead are released")
+}
+
+func TestConvertParquetGlobToCSVOrdered(t *testing.T) {
+	dir := t.TempDir()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	for i := int64(0); i < 6; i++ {
+		writeMultiParquetFile(t, filepath.Join(dir, fmt.Sprintf("part-%d.parquet", i)), schema, i*1000, 1000)
+	}
+	csvPath := filepath.Join(t.TempDir(), "out.csv")
+
+	_, err := converter.ConvertParquetToCSVWithOptions(context.Background(), filepath.Join(dir, "*.parquet"), csvPath, &converter.ParquetToCSVOptions{
+		ChunkSize:     100,
+		Concurrency:   4,
+		Delimiter:     ',',
+		IncludeHeader: true,
+		Ordered:       true,
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(csvPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 6001)
+	for i, line := range lines[1:] {
+		require.Equal(t, fmt.Sprint(i), line)
+	}
+}
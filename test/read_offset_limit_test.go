@@ -0,0 +1,157 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+	"github.com/arrowarc/arrowarc/pkg/preview"
+	"github.com/stretchr/testify/require"
+)
+
+// idRange returns the ids from first, n of them.
+func idRange(first, n int64) []int64 {
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = first + int64(i)
+	}
+	return ids
+}
+
+func TestParquetReaderOffsetLimit(t *testing.T) {
+	path := writeFilterTestFile(t, nil)
+	ctx := context.Background()
+
+	for _, parallel := range []bool{false, true} {
+		t.Run(fmt.Sprintf("parallel=%v", parallel), func(t *testing.T) {
+			reader, err := integrations.NewParquetReader(ctx, path, &integrations.ParquetReadOptions{
+				ChunkSize: 30, Parallel: parallel, Offset: 250, Limit: 120,
+			})
+			require.NoError(t, err)
+			defer reader.Close()
+			// Row groups hold 100 rows: only the third and fourth are read.
+			require.Equal(t, []int{2, 3}, reader.RowGroups())
+			require.EqualValues(t, 120, reader.NumRows())
+			require.Equal(t, idRange(250, 120), readAllIDs(t, reader))
+		})
+	}
+
+	t.Run("past the end", func(t *testing.T) {
+		reader, err := integrations.NewParquetReader(ctx, path, &integrations.ParquetReadOptions{Offset: 500})
+		require.NoError(t, err)
+		defer reader.Close()
+		require.Empty(t, reader.RowGroups())
+		require.Empty(t, readAllIDs(t, reader))
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		// The window applies to the rows matching the filter.
+		f, err := filter.Parse("id >= 400")
+		require.NoError(t, err)
+		reader, err := integrations.NewParquetReader(ctx, path, &integrations.ParquetReadOptions{Filter: f, Offset: 10, Limit: 5})
+		require.NoError(t, err)
+		defer reader.Close()
+		require.Equal(t, idRange(410, 5), readAllIDs(t, reader))
+	})
+}
+
+func TestCSVReaderOffsetLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rows.csv")
+	writeHeaderlessCSV(t, path, 1000)
+
+	reader, err := integrations.NewCSVReader(context.Background(), path, csvStreamSchema, &integrations.CSVReadOptions{
+		ChunkSize: 64, Offset: 100, Limit: 150,
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, idRange(100, 150), readAllIDs(t, reader))
+
+	_, err = integrations.NewCSVReader(context.Background(), path, csvStreamSchema, &integrations.CSVReadOptions{Offset: -1})
+	require.Error(t, err)
+}
+
+func TestJSONReaderOffsetLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rows.ndjson")
+	var lines strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&lines, "{\"id\":%d}\n", i)
+	}
+	require.NoError(t, os.WriteFile(path, []byte(lines.String()), 0o644))
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+	reader, err := integrations.NewJSONReader(context.Background(), path, schema, &integrations.JSONReadOptions{
+		ChunkSize: 16, Offset: 90, Limit: 20,
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, idRange(90, 10), readAllIDs(t, reader))
+
+	out := filepath.Join(t.TempDir(), "out.parquet")
+	_, err = converter.Convert(context.Background(), path, out, &converter.ConvertOptions{Offset: 5, Limit: 3})
+	require.NoError(t, err)
+	converted, err := integrations.OpenSource(context.Background(), out, nil)
+	require.NoError(t, err)
+	defer converted.Close()
+	var buf bytes.Buffer
+	require.NoError(t, preview.Cat(converted, &buf, preview.Options{Format: preview.JSON}))
+	require.Equal(t, "{\"id\":5}\n{\"id\":6}\n{\"id\":7}\n", buf.String())
+}
+
+func TestOpenSourceOffsetLimit(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.arrows")
+	rec := featherRecord(0, 100)
+	defer rec.Release()
+	writer, err := integrations.NewIPCRecordWriterWithOptions(ctx, path, rec.Schema(), nil)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(rec))
+	require.NoError(t, writer.Write(rec))
+	require.NoError(t, writer.Close())
+
+	// IPC streams cannot skip rows, so OpenSource slices the records.
+	reader, err := integrations.OpenSource(ctx, path, &integrations.SourceOptions{Offset: 95, Limit: 10})
+	require.NoError(t, err)
+	defer reader.Close()
+	require.True(t, reader.Schema().Equal(rec.Schema()))
+	require.Equal(t, []int64{95, 96, 97, 98, 99, 0, 1, 2, 3, 4}, readAllIDs(t, reader))
+
+	_, err = integrations.OpenSource(ctx, path, &integrations.SourceOptions{Limit: -1})
+	require.ErrorContains(t, err, "cannot be negative")
+}
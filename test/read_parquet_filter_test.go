@@ -0,0 +1,129 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/testutil"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFilterTestFile writes 500 rows in row groups of 100 with ids 0..499
+// and names "name-<id>".
+func writeFilterTestFile(t *testing.T, bloomColumns []string) string {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+	}, nil)
+
+	path := filepath.Join(t.TempDir(), "filter.parquet")
+	testutil.WriteParquet(t, path, schema, &integrations.ParquetWriteOptions{
+		MaxRowGroupLength:  100,
+		BloomFilterColumns: bloomColumns,
+	}, func(b *array.RecordBuilder) {
+		for i := 0; i < 500; i++ {
+			b.Field(0).(*array.Int64Builder).Append(int64(i))
+			b.Field(1).(*array.StringBuilder).Append(fmt.Sprintf("name-%d", i))
+		}
+	})
+	return path
+}
+
+func readFiltered(t *testing.T, path, expr string) ([]int, []int64) {
+	t.Helper()
+	f, err := filter.Parse(expr)
+	require.NoError(t, err)
+
+	reader, err := integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{Filter: f})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	var ids []int64
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, rec.Column(0).(*array.Int64).Int64Values()...)
+		rec.Release()
+	}
+	return reader.RowGroups(), ids
+}
+
+func TestReadParquetWithFilter(t *testing.T) {
+	path := writeFilterTestFile(t, []string{"name"})
+
+	rowGroups, ids := readFiltered(t, path, "id >= 420")
+	require.Equal(t, []int{4}, rowGroups)
+	require.Len(t, ids, 80)
+	require.EqualValues(t, 420, ids[0])
+
+	rowGroups, ids = readFiltered(t, path, "id < 50 OR id >= 450")
+	require.Equal(t, []int{0, 4}, rowGroups)
+	require.Len(t, ids, 100)
+
+	rowGroups, ids = readFiltered(t, path, "id > 1000")
+	require.Empty(t, rowGroups)
+	require.Empty(t, ids)
+
+	// "name-150" sorts between "name-0" and "name-99", so only the bloom
+	// filter can rule out the first row group.
+	rowGroups, ids = readFiltered(t, path, "name = 'name-150'")
+	require.Equal(t, []int{1}, rowGroups)
+	require.Equal(t, []int64{150}, ids)
+}
+
+func TestReadParquetWithFilterNoBloom(t *testing.T) {
+	path := writeFilterTestFile(t, nil)
+
+	rowGroups, ids := readFiltered(t, path, "name = 'name-150'")
+	require.Equal(t, []int{0, 1}, rowGroups)
+	require.Equal(t, []int64{150}, ids)
+}
+
+func TestReadParquetWithInvalidFilter(t *testing.T) {
+	path := writeFilterTestFile(t, nil)
+
+	f, err := filter.Parse("missing = 1")
+	require.NoError(t, err)
+	_, err = integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{Filter: f})
+	require.Error(t, err)
+}
@@ -0,0 +1,98 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pkg/filter"
+	"github.com/stretchr/testify/require"
+)
+
+func readParallelIDs(t *testing.T, opts *integrations.ParquetReadOptions) []int64 {
+	t.Helper()
+	path := writeFilterTestFile(t, nil)
+	r, err := integrations.NewParquetReader(context.Background(), path, opts)
+	require.NoError(t, err)
+	defer r.Close()
+
+	var ids []int64
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, rec.Column(0).(*array.Int64).Int64Values()...)
+		rec.Release()
+	}
+	return ids
+}
+
+func TestParquetParallelReadKeepsRowGroupOrder(t *testing.T) {
+	ids := readParallelIDs(t, &integrations.ParquetReadOptions{Parallel: true, Workers: 3, ChunkSize: 30})
+	require.Len(t, ids, 500)
+	for i, id := range ids {
+		require.EqualValues(t, i, id)
+	}
+}
+
+func TestParquetParallelReadUnordered(t *testing.T) {
+	ids := readParallelIDs(t, &integrations.ParquetReadOptions{Parallel: true, Unordered: true, ChunkSize: 30})
+	require.Len(t, ids, 500)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for i, id := range ids {
+		require.EqualValues(t, i, id)
+	}
+}
+
+func TestParquetParallelReadFiltersRowGroups(t *testing.T) {
+	f, err := filter.Parse("id >= 150 AND id < 420")
+	require.NoError(t, err)
+	ids := readParallelIDs(t, &integrations.ParquetReadOptions{Parallel: true, Workers: 2, Filter: f, RowGroups: []int{0, 1, 2, 3}})
+	require.Len(t, ids, 250)
+	require.EqualValues(t, 150, ids[0])
+	require.EqualValues(t, 399, ids[len(ids)-1])
+}
+
+func TestParquetParallelReadCloseEarly(t *testing.T) {
+	path := writeFilterTestFile(t, nil)
+	r, err := integrations.NewParquetReader(context.Background(), path, &integrations.ParquetReadOptions{Parallel: true, ChunkSize: 10})
+	require.NoError(t, err)
+	rec, err := r.Read()
+	require.NoError(t, err)
+	rec.Release()
+	require.NoError(t, r.Close())
+}
@@ -0,0 +1,238 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// eventDescriptorSet describes logs.v1.Event the way protoc would.
+func eventDescriptorSet() *descriptorpb.FileDescriptorSet {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name: proto.String(name), JsonName: proto.String(name), Number: proto.Int32(number),
+			Type: typ.Enum(), Label: label.Enum(),
+		}
+	}
+	optional, repeated := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+
+	score := field("score", 3, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, optional)
+	score.Proto3Optional, score.OneofIndex = proto.Bool(true), proto.Int32(0)
+	attrs := field("attrs", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, repeated)
+	attrs.TypeName = proto.String(".logs.v1.Event.AttrsEntry")
+	source := field("source", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional)
+	source.TypeName = proto.String(".logs.v1.Source")
+	level := field("level", 7, descriptorpb.FieldDescriptorProto_TYPE_ENUM, optional)
+	level.TypeName = proto.String(".logs.v1.Level")
+
+	return &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("logs/v1/event.proto"),
+		Package: proto.String("logs.v1"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Level"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("INFO"), Number: proto.Int32(0)},
+				{Name: proto.String("ERROR"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:  proto.String("TailRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("count", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional)},
+			},
+			{
+				Name:  proto.String("Source"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("host", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional)},
+			},
+			{
+				Name: proto.String("Event"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional),
+					field("message", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+					score,
+					field("tags", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING, repeated),
+					attrs, source, level,
+				},
+				NestedType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("AttrsEntry"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+						field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional),
+					},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				}},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("_score")}},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("EventService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:            proto.String("Tail"),
+				InputType:       proto.String(".logs.v1.TailRequest"),
+				OutputType:      proto.String(".logs.v1.Event"),
+				ServerStreaming: proto.Bool(true),
+			}},
+		}},
+	}}}
+}
+
+func writeProtobufLog(t *testing.T, dir string) (descPath, dataPath string) {
+	t.Helper()
+	set := eventDescriptorSet()
+	raw, err := proto.Marshal(set)
+	require.NoError(t, err)
+	descPath = filepath.Join(dir, "events.desc")
+	require.NoError(t, os.WriteFile(descPath, raw, 0o644))
+
+	files, err := protodesc.NewFiles(set)
+	require.NoError(t, err)
+	d, err := files.FindDescriptorByName("logs.v1.Event")
+	require.NoError(t, err)
+	md := d.(protoreflect.MessageDescriptor)
+
+	dataPath = filepath.Join(dir, "events.pb")
+	f, err := os.Create(dataPath)
+	require.NoError(t, err)
+	defer f.Close()
+	for i := 0; i < 5; i++ {
+		msg := newEventMessage(md, i)
+		_, err := protodelim.MarshalTo(f, msg)
+		require.NoError(t, err)
+	}
+	return descPath, dataPath
+}
+
+// newEventMessage returns the i-th logs.v1.Event of the test stream.
+func newEventMessage(md protoreflect.MessageDescriptor, i int) *dynamicpb.Message {
+	fields := md.Fields()
+	msg := dynamicpb.NewMessage(md)
+	msg.Set(fields.ByName("id"), protoreflect.ValueOfInt64(int64(i)))
+	msg.Set(fields.ByName("message"), protoreflect.ValueOfString("event"))
+	if i%2 == 0 {
+		msg.Set(fields.ByName("score"), protoreflect.ValueOfFloat64(float64(i)/2))
+	}
+	tags := msg.Mutable(fields.ByName("tags")).List()
+	for j := 0; j < i; j++ {
+		tags.Append(protoreflect.ValueOfString("t"))
+	}
+	attrs := msg.Mutable(fields.ByName("attrs")).Map()
+	attrs.Set(protoreflect.ValueOfString("b").MapKey(), protoreflect.ValueOfInt32(2))
+	attrs.Set(protoreflect.ValueOfString("a").MapKey(), protoreflect.ValueOfInt32(int32(i)))
+	if i == 1 {
+		src := msg.Mutable(fields.ByName("source")).Message()
+		src.Set(src.Descriptor().Fields().ByName("host"), protoreflect.ValueOfString("web-1"))
+	}
+	msg.Set(fields.ByName("level"), protoreflect.ValueOfEnum(protoreflect.EnumNumber(i%2)))
+	return msg
+}
+
+func TestReadDelimitedProtobuf(t *testing.T) {
+	ctx := context.Background()
+	descPath, dataPath := writeProtobufLog(t, t.TempDir())
+
+	reader, err := integrations.OpenSource(ctx, dataPath, &integrations.SourceOptions{
+		Format:    integrations.SourceProtobuf,
+		ChunkSize: 3,
+		Protobuf: integrations.ProtobufReadOptions{
+			DescriptorSet: descPath,
+			MessageName:   "logs.v1.Event",
+		},
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	schema := reader.Schema()
+	require.Equal(t, []string{"id", "message", "score", "tags", "attrs", "source", "level"}, fieldNames(schema))
+	require.True(t, schema.Field(2).Nullable)
+	require.Equal(t, arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int32), schema.Field(4).Type)
+
+	first, err := reader.Read()
+	require.NoError(t, err)
+	defer first.Release()
+	require.EqualValues(t, 3, first.NumRows())
+
+	second, err := reader.Read()
+	require.NoError(t, err)
+	defer second.Release()
+	require.EqualValues(t, 2, second.NumRows())
+
+	require.Equal(t, []int64{0, 1, 2}, first.Column(0).(*array.Int64).Int64Values())
+	require.Equal(t, "event", first.Column(1).ValueStr(2))
+	require.Equal(t, 0.0, first.Column(2).(*array.Float64).Value(0))
+	require.True(t, first.Column(2).IsNull(1))
+	require.Equal(t, 1.0, first.Column(2).(*array.Float64).Value(2))
+	require.Equal(t, `["t","t"]`, first.Column(3).ValueStr(2))
+	require.Equal(t, `[{"key":"a","value":1},{"key":"b","value":2}]`, first.Column(4).ValueStr(1))
+	require.True(t, first.Column(5).IsNull(0))
+	require.Equal(t, `{"host":"web-1"}`, first.Column(5).ValueStr(1))
+	require.Equal(t, []int32{0, 1, 0}, first.Column(6).(*array.Int32).Int32Values())
+	require.Equal(t, []int64{3, 4}, second.Column(0).(*array.Int64).Int64Values())
+
+	_, err = reader.Read()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestConvertDelimitedProtobufToParquet(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	descPath, dataPath := writeProtobufLog(t, dir)
+	out := filepath.Join(dir, "events.parquet")
+
+	_, err := converter.Convert(ctx, dataPath, out, &converter.ConvertOptions{
+		FromFormat: integrations.SourceProtobuf,
+		Protobuf: integrations.ProtobufReadOptions{
+			DescriptorSet: descPath,
+			MessageName:   ".logs.v1.Event",
+		},
+	})
+	require.NoError(t, err)
+
+	reader, err := integrations.NewParquetReader(ctx, out, &integrations.ParquetReadOptions{ChunkSize: 100})
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, []int64{0, 1, 2, 3, 4}, readAllIDs(t, reader))
+}
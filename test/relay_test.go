@@ -0,0 +1,196 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	flightrelay "github.com/arrowarc/arrowarc/integrations/flight"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/endpoints"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// relayRows returns n records of 100 rows, numbered from zero, with a
+// repetitive label column.
+type relayRows struct {
+	n, next int
+	schema  *arrow.Schema
+}
+
+func newRelayRows(n int) *relayRows {
+	return &relayRows{n: n, schema: arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "label", Type: arrow.BinaryTypes.String},
+	}, nil)}
+}
+
+func (r *relayRows) Read() (arrow.Record, error) {
+	if r.n == 0 {
+		return nil, io.EOF
+	}
+	r.n--
+	b := array.NewRecordBuilder(memory.DefaultAllocator, r.schema)
+	defer b.Release()
+	for i := 0; i < 100; i++ {
+		b.Field(0).(*array.Int64Builder).Append(int64(r.next))
+		b.Field(1).(*array.StringBuilder).Append("relayed from the other data center")
+		r.next++
+	}
+	return b.NewRecord(), nil
+}
+
+func (r *relayRows) Schema() *arrow.Schema { return r.schema }
+func (r *relayRows) Close() error          { return nil }
+
+// startRelay serves a relay of a generated source, seq, and of a CSV
+// file, orders, and returns its address.
+func startRelay(t *testing.T, opts *flightrelay.FlightRelayServerOptions) string {
+	t.Helper()
+	csvPath := filepath.Join(t.TempDir(), "orders.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("id,amount\n1,9.5\n2,12.25\n"), 0o644))
+	relay, err := flightrelay.NewFlightRelayServer(map[string]flightrelay.RelaySource{
+		"seq": func(context.Context) (flightrelay.RelayReader, error) { return newRelayRows(3), nil },
+		"orders": func(ctx context.Context) (flightrelay.RelayReader, error) {
+			return integrations.OpenSource(ctx, csvPath, &integrations.SourceOptions{
+				CSV: csvschema.CSVReadOptions{Delimiter: ',', HasHeader: true},
+			})
+		},
+	}, opts)
+	require.NoError(t, err)
+
+	srv := flight.NewServerWithMiddleware(nil)
+	require.NoError(t, srv.Init("localhost:0"))
+	srv.RegisterFlightService(relay)
+	go srv.Serve()
+	t.Cleanup(srv.Shutdown)
+	return srv.Addr().String()
+}
+
+// readRelayIDs reads every record of reader, returning the values of its
+// first column.
+func readRelayIDs(t *testing.T, reader interface{ Read() (arrow.Record, error) }) []int64 {
+	t.Helper()
+	var ids []int64
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return ids
+		}
+		require.NoError(t, err)
+		ids = append(ids, rec.Column(0).(*array.Int64).Int64Values()...)
+		rec.Release()
+	}
+}
+
+func TestRelayReader(t *testing.T) {
+	addr := startRelay(t, &flightrelay.FlightRelayServerOptions{Compression: flightrelay.RelayCompressionZstd})
+	for _, compression := range []string{"", flightrelay.RelayCompressionNone, flightrelay.RelayCompressionLZ4, flightrelay.RelayCompressionZstd} {
+		t.Run(fmt.Sprintf("compression=%q", compression), func(t *testing.T) {
+			reader, err := flightrelay.NewFlightRelayReader(context.Background(), addr, "seq", &flightrelay.FlightRelayReadOptions{Compression: compression})
+			require.NoError(t, err)
+			defer reader.Close()
+			require.Equal(t, []string{"id", "label"}, fieldNames(reader.Schema()))
+			ids := readRelayIDs(t, reader)
+			require.Len(t, ids, 300)
+			require.EqualValues(t, 299, ids[299])
+		})
+	}
+
+	reader, err := flightrelay.NewFlightRelayReader(context.Background(), addr, "orders", nil)
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Equal(t, []string{"id", "amount"}, fieldNames(reader.Schema()))
+	rec, err := reader.Read()
+	require.NoError(t, err)
+	require.EqualValues(t, 2, rec.NumRows())
+	rec.Release()
+}
+
+func TestRelayEndpoint(t *testing.T) {
+	addr := startRelay(t, nil)
+	reader, err := endpoints.NewReader(context.Background(), "flight://"+addr+"/seq?compression=lz4", nil)
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Len(t, readRelayIDs(t, reader), 300)
+}
+
+func TestRelayServerErrors(t *testing.T) {
+	addr := startRelay(t, nil)
+	ctx := context.Background()
+
+	_, err := flightrelay.NewFlightRelayReader(ctx, addr, "missing", nil)
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = flightrelay.NewFlightRelayReader(ctx, addr, "seq", &flightrelay.FlightRelayReadOptions{Compression: "brotli"})
+	require.ErrorContains(t, err, "unsupported relay compression")
+	_, err = flightrelay.NewFlightRelayServer(nil, &flightrelay.FlightRelayServerOptions{Compression: "brotli"})
+	require.Error(t, err)
+
+	client, err := flight.NewClientWithMiddleware(addr, nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer client.Close()
+	ticket, _ := json.Marshal(flightrelay.RelayTicket{Source: "seq", Compression: "brotli"})
+	stream, err := client.DoGet(ctx, &flight.Ticket{Ticket: ticket})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// The sources are listed in name order, with their schemas.
+	list, err := client.ListFlights(ctx, &flight.Criteria{})
+	require.NoError(t, err)
+	var names []string
+	for {
+		info, err := list.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, info.FlightDescriptor.Path[0])
+		schema, err := flight.DeserializeSchema(info.Schema, memory.DefaultAllocator)
+		require.NoError(t, err)
+		require.Equal(t, "id", schema.Field(0).Name)
+	}
+	require.Equal(t, []string{"orders", "seq"}, names)
+}
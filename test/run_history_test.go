@@ -0,0 +1,98 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arrowarc/arrowarc"
+	"github.com/arrowarc/arrowarc/converter"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/history"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunHistory(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	require.NoError(t, os.WriteFile(input, []byte("id,name\n1,a\n2,b\n3,c\n"), 0o644))
+	store, err := history.Open(filepath.Join(dir, "runs.jsonl"))
+	require.NoError(t, err)
+	defer store.Close()
+	ctx := history.NewContext(context.Background(), store)
+
+	csvOpts := csvschema.CSVReadOptions{Delimiter: ',', HasHeader: true}
+	output := filepath.Join(dir, "out.parquet")
+	for _, limit := range []int64{0, 0, 2} {
+		_, err := converter.Convert(ctx, input, output, &converter.ConvertOptions{CSV: csvOpts, Limit: limit})
+		require.NoError(t, err)
+	}
+	_, err = converter.Convert(ctx, filepath.Join(dir, "missing.csv"), output, &converter.ConvertOptions{CSV: csvOpts})
+	require.Error(t, err)
+	_, err = arrowarc.Read(input).Write(ctx, filepath.Join(dir, "out.ndjson"))
+	require.NoError(t, err)
+
+	runs, err := store.List(ctx, history.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, runs, 5)
+	flow, failed, limited, second, first := runs[0], runs[1], runs[2], runs[3], runs[4]
+
+	require.Equal(t, "convert", first.Command)
+	require.Equal(t, input, first.Source)
+	require.Equal(t, output, first.Destination)
+	require.Equal(t, history.Succeeded, first.Outcome)
+	require.EqualValues(t, 3, first.Records)
+	require.NotEmpty(t, first.ID)
+	var report map[string]any
+	require.NoError(t, json.Unmarshal(first.Report, &report))
+	require.Equal(t, first.ID, report["run_id"])
+
+	// The same conversion hashes alike, another one differently.
+	require.NotEmpty(t, first.ConfigHash)
+	require.Equal(t, first.ConfigHash, second.ConfigHash)
+	require.NotEqual(t, first.ConfigHash, limited.ConfigHash)
+	require.EqualValues(t, 2, limited.Records)
+
+	require.Equal(t, history.Failed, failed.Outcome)
+	require.Contains(t, failed.Error, "missing.csv")
+
+	require.Equal(t, input, flow.Source)
+	require.Equal(t, filepath.Join(dir, "out.ndjson"), flow.Destination)
+	require.EqualValues(t, 3, flow.Records)
+
+	got, err := store.Get(ctx, first.ID[:8])
+	require.NoError(t, err)
+	require.Equal(t, first.ID, got.ID)
+}
@@ -0,0 +1,167 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/seal"
+	"github.com/stretchr/testify/require"
+)
+
+// decryptAge decrypts the age file at path to a file next to it, without
+// its suffix, and returns the path of the plaintext.
+func decryptAge(t *testing.T, path string, id age.Identity) string {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := age.Decrypt(f, id)
+	require.NoError(t, err)
+	out := strings.TrimSuffix(path, seal.AgeSuffix)
+	w, err := os.Create(out)
+	require.NoError(t, err)
+	defer w.Close()
+	_, err = w.ReadFrom(r)
+	require.NoError(t, err)
+	return out
+}
+
+func TestConvertSealedOutput(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	require.NoError(t, os.WriteFile(input, []byte("id,name\n1,a\n2,b\n"), 0o644))
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	output := filepath.Join(dir, "out.ndjson")
+	_, err = converter.Convert(ctx, input, output, &converter.ConvertOptions{
+		CSV:  csvschema.CSVReadOptions{Delimiter: ',', HasHeader: true},
+		Seal: &seal.Options{Checksum: true, AgeRecipients: []string{id.Recipient().String()}},
+	})
+	require.NoError(t, err)
+	require.NoFileExists(t, output)
+	sidecar, err := os.ReadFile(output + seal.AgeSuffix + seal.ChecksumSuffix)
+	require.NoError(t, err)
+	require.Equal(t, fileChecksum(t, output+seal.AgeSuffix)+"  out.ndjson.age\n", string(sidecar))
+
+	plain, err := os.ReadFile(decryptAge(t, output+seal.AgeSuffix, id))
+	require.NoError(t, err)
+	require.Equal(t, "{\"id\":1,\"name\":\"a\"}\n{\"id\":2,\"name\":\"b\"}\n", string(plain))
+
+	// Checksums alone leave the output in place.
+	_, err = converter.Convert(ctx, input, filepath.Join(dir, "plain.csv"), &converter.ConvertOptions{
+		CSV:    csvschema.CSVReadOptions{Delimiter: ',', HasHeader: true},
+		Verify: true,
+		Seal:   &seal.Options{Checksum: true},
+	})
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(dir, "plain.csv"+seal.ChecksumSuffix))
+
+	_, err = converter.Convert(ctx, input, filepath.Join(dir, "x.csv"), &converter.ConvertOptions{
+		Verify: true,
+		Seal:   &seal.Options{AgeRecipients: []string{id.Recipient().String()}},
+	})
+	require.ErrorContains(t, err, "cannot verify encrypted output")
+	_, err = converter.Convert(ctx, input, filepath.Join(dir, "x.csv"), &converter.ConvertOptions{
+		Seal: &seal.Options{AgeRecipients: []string{"age1bad"}},
+	})
+	require.ErrorContains(t, err, "invalid age recipient")
+	require.NoFileExists(t, filepath.Join(dir, "x.csv"))
+}
+
+func TestPartitionedParquetWriterSealed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "region", Type: arrow.BinaryTypes.String},
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+	opts := &integrations.PartitionedParquetWriteOptions{
+		PartitionColumns: []string{"region"},
+		Seal:             &seal.Options{Checksum: true, AgeRecipients: []string{id.Recipient().String()}},
+	}
+	writer, err := integrations.NewPartitionedParquetWriter(ctx, dir, schema, opts)
+	require.NoError(t, err)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*array.StringBuilder).AppendValues([]string{"eu", "us", "eu"}, nil)
+	b.Field(1).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+	require.NoError(t, writer.Write(rec))
+	require.NoError(t, writer.Close())
+
+	manifest, err := integrations.ReadPartitionManifest(dir, "")
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 2)
+	for _, f := range manifest.Files {
+		require.True(t, strings.HasSuffix(f.Path, ".parquet.age"), f.Path)
+		require.Equal(t, seal.EncryptionAge, f.Encryption)
+		path := filepath.Join(dir, f.Path)
+		require.Equal(t, fileChecksum(t, path), f.Checksum)
+		require.FileExists(t, path+seal.ChecksumSuffix)
+		require.NoFileExists(t, strings.TrimSuffix(path, seal.AgeSuffix))
+
+		reader, err := integrations.NewParquetReader(ctx, decryptAge(t, path, id), &integrations.ParquetReadOptions{ChunkSize: 1024})
+		require.NoError(t, err)
+		record, err := reader.Read()
+		require.NoError(t, err)
+		require.EqualValues(t, f.Rows, record.NumRows())
+		record.Release()
+		reader.Close()
+	}
+
+	// Resuming keeps the sealed files of the completed run.
+	opts.Resume = true
+	writer, err = integrations.NewPartitionedParquetWriter(ctx, dir, schema, opts)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	for _, f := range manifest.Files {
+		require.FileExists(t, filepath.Join(dir, f.Path))
+		require.FileExists(t, filepath.Join(dir, f.Path)+seal.ChecksumSuffix)
+	}
+}
@@ -0,0 +1,116 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/parquet/file"
+	generator "github.com/arrowarc/arrowarc/generator"
+	parquet "github.com/arrowarc/arrowarc/pkg/parquet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitParquetFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	inputFilePath := filepath.Join(dir, "input.parquet")
+	require.NoError(t, generator.GenerateParquetFile(inputFilePath, 100*1024, false))
+
+	input, err := file.OpenParquetFile(inputFilePath, false)
+	require.NoError(t, err)
+	totalRows := input.NumRows()
+	inputSchema := input.MetaData().Schema
+	input.Close()
+	require.Greater(t, totalRows, int64(10))
+
+	tests := []struct {
+		description string
+		limit       parquet.SplitLimit
+		check       func(t *testing.T, rows []int64)
+	}{
+		{
+			description: "Split by row count",
+			limit:       parquet.SplitLimit{MaxRows: totalRows / 3},
+			check: func(t *testing.T, rows []int64) {
+				require.GreaterOrEqual(t, len(rows), 3)
+				for _, n := range rows {
+					require.LessOrEqual(t, n, totalRows/3)
+				}
+			},
+		},
+		{
+			description: "Split by size",
+			limit:       parquet.SplitLimit{MaxBytes: 32 * 1024},
+			check: func(t *testing.T, rows []int64) {
+				require.Greater(t, len(rows), 1)
+			},
+		},
+	}
+
+	for i, test := range tests {
+		test := test
+		pattern := filepath.Join(dir, string(rune('a'+i))+"-part-%03d.parquet")
+		t.Run(test.description, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			paths, err := parquet.SplitFile(ctx, inputFilePath, pattern, test.limit)
+			require.NoError(t, err)
+
+			var rows []int64
+			var sum int64
+			for _, path := range paths {
+				rdr, err := file.OpenParquetFile(path, false)
+				require.NoError(t, err)
+				require.True(t, rdr.MetaData().Schema.Equals(inputSchema), "schema of %s should match the input", path)
+				rows = append(rows, rdr.NumRows())
+				sum += rdr.NumRows()
+				rdr.Close()
+			}
+			require.Equal(t, totalRows, sum)
+			test.check(t, rows)
+		})
+	}
+}
+
+func TestSplitParquetFileInvalidArguments(t *testing.T) {
+	ctx := context.Background()
+	_, err := parquet.SplitFile(ctx, "in.parquet", "out.parquet", parquet.SplitLimit{MaxRows: 10})
+	require.Error(t, err, "pattern without a verb should be rejected")
+	_, err = parquet.SplitFile(ctx, "in.parquet", "out-%d.parquet", parquet.SplitLimit{})
+	require.Error(t, err, "a limit is required")
+	_, err = parquet.SplitFile(ctx, "in.parquet", "out-%d.parquet", parquet.SplitLimit{MaxRows: 1, MaxBytes: 1})
+	require.Error(t, err, "only one limit may be set")
+}
@@ -0,0 +1,269 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCSVWithTimestamps(t *testing.T, opts integrations.TimestampOptions) []string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out.csv")
+	writeCSVTestFile(t, path, &integrations.CSVWriteOptions{IncludeHeader: true, Timestamps: opts})
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var ts []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n")[1:] {
+		fields := strings.Split(line, ",")
+		ts = append(ts, fields[len(fields)-1])
+	}
+	return ts
+}
+
+func TestCSVWriterTimestampOptions(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"1714566600", "1714566600"},
+		writeCSVWithTimestamps(t, integrations.TimestampOptions{Unit: "s", Format: integrations.TimestampEpoch}))
+	require.Equal(t, []string{"1714566600000", "1714566600000"},
+		writeCSVWithTimestamps(t, integrations.TimestampOptions{Format: integrations.TimestampEpoch}))
+	require.Equal(t, []string{"2024-05-01T14:30:00+02:00", "2024-05-01T14:30:00+02:00"},
+		writeCSVWithTimestamps(t, integrations.TimestampOptions{TimeZone: "Europe/Paris"}))
+	require.Equal(t, []string{"01/05/2024 12:30", "01/05/2024 12:30"},
+		writeCSVWithTimestamps(t, integrations.TimestampOptions{Format: "02/01/2006 15:04"}))
+
+	_, err := integrations.NewCSVWriter(context.Background(), filepath.Join(t.TempDir(), "bad.csv"),
+		arrow.NewSchema(nil, nil), &integrations.CSVWriteOptions{Timestamps: integrations.TimestampOptions{Unit: "h"}})
+	require.Error(t, err)
+}
+
+func readTimestampColumn(t *testing.T, reader interface {
+	Read() (arrow.Record, error)
+	Close() error
+}) (*arrow.TimestampType, []time.Time) {
+	t.Helper()
+	defer reader.Close()
+	var dt *arrow.TimestampType
+	var values []time.Time
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		col := record.Column(0).(*array.Timestamp)
+		dt = col.DataType().(*arrow.TimestampType)
+		toTime, err := dt.GetToTimeFunc()
+		require.NoError(t, err)
+		for i := 0; i < col.Len(); i++ {
+			values = append(values, toTime(col.Value(i)))
+		}
+		record.Release()
+	}
+	return dt, values
+}
+
+func TestCSVReaderTimestampOptions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Microsecond}}}, nil)
+	want := time.Date(2024, 5, 1, 16, 30, 0, 0, time.UTC)
+
+	path := filepath.Join(dir, "zone.csv")
+	require.NoError(t, os.WriteFile(path, []byte("ts\n2024-05-01 12:30:00\n2024-05-01T16:30:00Z\n"), 0o644))
+	reader, err := integrations.NewCSVReader(ctx, path, schema, &integrations.CSVReadOptions{
+		ChunkSize:  10,
+		HasHeader:  true,
+		Timestamps: integrations.TimestampOptions{TimeZone: "America/New_York", Unit: "ms"},
+	})
+	require.NoError(t, err)
+	dt, values := readTimestampColumn(t, reader)
+	require.Equal(t, arrow.Millisecond, dt.Unit)
+	require.Equal(t, "America/New_York", dt.TimeZone)
+	require.Len(t, values, 2)
+	for _, v := range values {
+		require.True(t, want.Equal(v), "got %v", v)
+	}
+
+	path = filepath.Join(dir, "layout.csv")
+	require.NoError(t, os.WriteFile(path, []byte("ts\n01/05/2024 16:30\n"), 0o644))
+	reader, err = integrations.NewCSVReader(ctx, path, schema, &integrations.CSVReadOptions{
+		ChunkSize:  10,
+		HasHeader:  true,
+		Timestamps: integrations.TimestampOptions{Format: "02/01/2006 15:04"},
+	})
+	require.NoError(t, err)
+	_, values = readTimestampColumn(t, reader)
+	require.Len(t, values, 1)
+	require.True(t, want.Equal(values[0]), "got %v", values[0])
+
+	path = filepath.Join(dir, "epoch.csv")
+	require.NoError(t, os.WriteFile(path, []byte("ts\n1714581000000000\n"), 0o644))
+	reader, err = integrations.NewCSVReader(ctx, path, schema, &integrations.CSVReadOptions{
+		ChunkSize:  10,
+		HasHeader:  true,
+		Timestamps: integrations.TimestampOptions{Format: integrations.TimestampEpoch},
+	})
+	require.NoError(t, err)
+	_, values = readTimestampColumn(t, reader)
+	require.Len(t, values, 1)
+	require.True(t, want.Equal(values[0]), "got %v", values[0])
+}
+
+func TestCSVReaderDateLayouts(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	layouts := []string{time.DateOnly, "02/01/2006"}
+
+	path := filepath.Join(t.TempDir(), "dates.csv")
+	require.NoError(t, os.WriteFile(path, []byte("day\n15/04/2024\n2024-04-16\nNULL\n"), 0o644))
+	schema, err := csvschema.InferCSVArrowSchema(ctx, path, &csvschema.CSVReadOptions{
+		HasHeader:   true,
+		ParseDates:  true,
+		DateLayouts: layouts,
+	})
+	require.NoError(t, err)
+	require.Equal(t, arrow.FixedWidthTypes.Date32, schema.Field(0).Type)
+
+	reader, err := integrations.NewCSVReader(ctx, path, schema, &integrations.CSVReadOptions{
+		ChunkSize:   10,
+		HasHeader:   true,
+		DateLayouts: layouts,
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+	record, err := reader.Read()
+	require.NoError(t, err)
+	defer record.Release()
+	col := record.Column(0).(*array.Date32)
+	require.Equal(t, 3, col.Len())
+	require.Equal(t, time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC), col.Value(0).ToTime())
+	require.Equal(t, time.Date(2024, 4, 16, 0, 0, 0, 0, time.UTC), col.Value(1).ToTime())
+	require.True(t, col.IsNull(2))
+
+	reader, err = integrations.NewCSVReader(ctx, path, schema, &integrations.CSVReadOptions{
+		ChunkSize:   10,
+		HasHeader:   true,
+		DateLayouts: []string{time.DateOnly},
+	})
+	require.NoError(t, err)
+	defer reader.Close()
+	_, err = reader.Read()
+	require.ErrorContains(t, err, "15/04/2024")
+}
+
+func TestJSONTimestampOptions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	record := buildCSVTestRecord(t)
+	defer record.Release()
+
+	path := filepath.Join(dir, "epoch.ndjson")
+	writer, err := integrations.NewJSONWriterWithOptions(ctx, path, &integrations.JSONWriteOptions{
+		Lines:      true,
+		Timestamps: integrations.TimestampOptions{Unit: "s", Format: integrations.TimestampEpoch},
+	})
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"ts":1714566600`)
+
+	path = filepath.Join(dir, "zone.ndjson")
+	writer, err = integrations.NewJSONWriterWithOptions(ctx, path, &integrations.JSONWriteOptions{
+		Lines:      true,
+		Timestamps: integrations.TimestampOptions{TimeZone: "Asia/Tokyo", Format: integrations.TimestampRFC3339},
+	})
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"ts":"2024-05-01T21:30:00+09:00"`)
+
+	path = filepath.Join(dir, "naive.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte("{\"ts\":\"2024-05-01 12:30:00\"}\n"), 0o644))
+	reader, err := integrations.NewJSONReader(ctx, path, arrow.NewSchema([]arrow.Field{{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Second}}}, nil), &integrations.JSONReadOptions{
+		ChunkSize:  10,
+		Timestamps: integrations.TimestampOptions{TimeZone: "Asia/Tokyo"},
+	})
+	require.NoError(t, err)
+	_, values := readTimestampColumn(t, reader)
+	require.Len(t, values, 1)
+	require.True(t, time.Date(2024, 5, 1, 3, 30, 0, 0, time.UTC).Equal(values[0]), "got %v", values[0])
+
+	path = filepath.Join(dir, "layout.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte("{\"ts\":\"01/05/2024 12:30\"}\n{\"ts\":null}\n"), 0o644))
+	schema := arrow.NewSchema([]arrow.Field{{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Nanosecond}, Nullable: true}}, nil)
+	reader, err = integrations.NewJSONReader(ctx, path, schema, &integrations.JSONReadOptions{
+		ChunkSize:  10,
+		Timestamps: integrations.TimestampOptions{Unit: "ms", TimeZone: "Europe/London", Format: "02/01/2006 15:04"},
+	})
+	require.NoError(t, err)
+	dt, values := readTimestampColumn(t, reader)
+	require.Equal(t, arrow.Millisecond, dt.Unit)
+	require.Equal(t, "Europe/London", dt.TimeZone)
+	require.Len(t, values, 2)
+	require.True(t, time.Date(2024, 5, 1, 11, 30, 0, 0, time.UTC).Equal(values[0]), "got %v", values[0])
+}
+
+func TestConvertTimestampOptions(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	from := filepath.Join(dir, "in.csv")
+	to := filepath.Join(dir, "out.ndjson")
+	require.NoError(t, os.WriteFile(from, []byte("id,ts\n1,2024-05-01T12:30:00Z\n2,2024-05-01T12:30:01.5Z\n"), 0o644))
+
+	_, err := converter.Convert(context.Background(), from, to, &converter.ConvertOptions{
+		ChunkSize:  10,
+		CSV:        csvschema.CSVReadOptions{Delimiter: ',', HasHeader: true},
+		Timestamps: integrations.TimestampOptions{Unit: "ms", Format: integrations.TimestampEpoch},
+	})
+	require.NoError(t, err)
+	data, err := os.ReadFile(to)
+	require.NoError(t, err)
+	require.Equal(t, "{\"id\":1,\"ts\":1714566600000}\n{\"id\":2,\"ts\":1714566601500}\n", string(data))
+}
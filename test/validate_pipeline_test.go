@@ -0,0 +1,110 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/arrowarc/arrowarc/pkg/validate"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePipelineDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "status", Type: arrow.BinaryTypes.String},
+	}, nil)
+
+	inputPath := filepath.Join(dir, "input.parquet")
+	writer, err := integrations.NewParquetWriter(inputPath, schema, integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3, 4}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"active", "bogus", "inactive", "bogus"}, nil)
+	rec := b.NewRecord()
+	b.Release()
+	require.NoError(t, writer.Write(rec))
+	rec.Release()
+	require.NoError(t, writer.Close())
+
+	reader, err := integrations.NewParquetReader(ctx, inputPath, &integrations.ParquetReadOptions{ChunkSize: 1024})
+	require.NoError(t, err)
+
+	deadLetterPath := filepath.Join(dir, "quarantine.csv")
+	deadLetter, err := integrations.NewCSVWriter(ctx, deadLetterPath, validate.DeadLetterSchema(reader.Schema()), nil)
+	require.NoError(t, err)
+	validator, err := validate.New([]validate.ColumnRules{
+		{Column: "status", Rules: []validate.Rule{validate.OneOf("active", "inactive")}},
+	}, deadLetter)
+	require.NoError(t, err)
+
+	outputPath := filepath.Join(dir, "output.parquet")
+	sink, err := integrations.NewParquetWriter(outputPath, reader.Schema(), integrations.NewDefaultParquetWriterProperties())
+	require.NoError(t, err)
+
+	report, err := pipeline.NewDataPipeline(reader, sink).WithTransformers(validator).Start(ctx)
+	require.NoError(t, err)
+
+	var parsed pipeline.MetricsReport
+	require.NoError(t, json.Unmarshal([]byte(report), &parsed))
+	require.Equal(t, int64(2), parsed.Transforms["validate"][validate.CountPassed])
+	require.Equal(t, int64(2), parsed.Transforms["validate"][validate.CountRejected])
+
+	quarantined, err := os.ReadFile(deadLetterPath)
+	require.NoError(t, err)
+	require.Equal(t, "id,status,_validation_error\n"+
+		"2,bogus,\"status: one of [active, inactive]\"\n"+
+		"4,bogus,\"status: one of [active, inactive]\"\n", string(quarantined))
+
+	out, err := integrations.NewParquetReader(ctx, outputPath, &integrations.ParquetReadOptions{ChunkSize: 1024})
+	require.NoError(t, err)
+	defer out.Close()
+	var rows int64
+	for {
+		rec, err := out.Read()
+		if err != nil {
+			break
+		}
+		rows += rec.NumRows()
+		rec.Release()
+	}
+	require.Equal(t, int64(2), rows)
+}
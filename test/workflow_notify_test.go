@@ -0,0 +1,126 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/arrowarc/arrowarc/converter"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	csvschema "github.com/arrowarc/arrowarc/pkg/csv"
+	"github.com/arrowarc/arrowarc/pkg/history"
+	"github.com/arrowarc/arrowarc/pkg/notify"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWorkflowNotifications(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests = map[string][]string{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests[r.URL.Path] = append(requests[r.URL.Path], string(body))
+		mu.Unlock()
+	}))
+	defer server.Close()
+	t.Setenv("TEST_NOTIFY_URL", server.URL)
+
+	var cfg config.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+workflow:
+  name: nightly
+  settings:
+    parallel_tasks: 1
+    retry_attempts: 1
+  notifications:
+    - name: alerts
+      type: slack
+      url: ${TEST_NOTIFY_URL}/slack
+      on_failure: true
+      template: "{{.Workflow}} failed: {{.Error}}"
+    - name: audit
+      type: webhook
+      url: ${TEST_NOTIFY_URL}/audit
+      on_success: true
+      on_failure: true
+      timeout: 5s
+`), &cfg))
+	require.NoError(t, cfg.Validate())
+	n, err := notify.FromConfig(&cfg)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.csv")
+	require.NoError(t, os.WriteFile(input, []byte("id,name\n1,a\n2,b\n"), 0o644))
+	convert := func(input string) func(ctx context.Context) (string, error) {
+		return func(ctx context.Context) (string, error) {
+			return converter.Convert(ctx, input, filepath.Join(dir, "out.parquet"), &converter.ConvertOptions{
+				CSV: csvschema.CSVReadOptions{Delimiter: ',', HasHeader: true},
+			})
+		}
+	}
+	ctx := context.Background()
+	require.NoError(t, n.Run(ctx, cfg.Workflow.Name, convert(input)))
+	require.Error(t, n.Run(ctx, cfg.Workflow.Name, convert(filepath.Join(dir, "missing.csv"))))
+
+	require.Len(t, requests["/audit"], 2)
+	var run notify.Run
+	require.NoError(t, json.Unmarshal([]byte(requests["/audit"][0]), &run))
+	require.Equal(t, "nightly", run.Workflow)
+	require.Equal(t, history.Succeeded, run.Outcome)
+	var report map[string]any
+	require.NoError(t, json.Unmarshal(run.Report, &report))
+	require.NotEmpty(t, report["records"])
+	require.Equal(t, run.ID, report["run_id"], "the report is of the notified run")
+
+	require.NoError(t, json.Unmarshal([]byte(requests["/audit"][1]), &run))
+	require.Equal(t, history.Failed, run.Outcome)
+	require.Contains(t, run.Error, "missing.csv")
+
+	require.Len(t, requests["/slack"], 1, "only failures are posted to slack")
+	var message map[string]string
+	require.NoError(t, json.Unmarshal([]byte(requests["/slack"][0]), &message))
+	require.Contains(t, message["text"], "nightly failed: ")
+	require.Contains(t, message["text"], "missing.csv")
+
+	cfg.Workflow.Notifications[0].OnFailure = false
+	require.ErrorContains(t, cfg.Validate(), "on_success or on_failure")
+}
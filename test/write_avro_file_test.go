@@ -0,0 +1,160 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+func avroWriterRecord(start, n int) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "price", Type: &arrow.Decimal128Type{Precision: 12, Scale: 2}},
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}},
+		{Name: "day", Type: arrow.FixedWidthTypes.Date32},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String)},
+		{Name: "point", Type: arrow.StructOf(
+			arrow.Field{Name: "x", Type: arrow.PrimitiveTypes.Float64},
+			arrow.Field{Name: "y", Type: arrow.PrimitiveTypes.Float64},
+		)},
+	}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := start; i < start+n; i++ {
+		b.Field(0).(*array.Int64Builder).Append(int64(i))
+		if i%2 == 0 {
+			b.Field(1).(*array.StringBuilder).Append("even")
+		} else {
+			b.Field(1).AppendNull()
+		}
+		b.Field(2).(*array.Decimal128Builder).Append(decimal128.FromI64(int64(i*100 - 150)))
+		b.Field(3).(*array.TimestampBuilder).Append(arrow.Timestamp(base.Add(time.Duration(i) * time.Second).UnixNano()))
+		b.Field(4).(*array.Date32Builder).Append(arrow.Date32FromTime(base))
+		lb := b.Field(5).(*array.ListBuilder)
+		lb.Append(true)
+		lb.ValueBuilder().(*array.StringBuilder).AppendValues([]string{"a", "b"}[:i%3%2+1], nil)
+		sb := b.Field(6).(*array.StructBuilder)
+		sb.Append(true)
+		sb.FieldBuilder(0).(*array.Float64Builder).Append(float64(i))
+		sb.FieldBuilder(1).(*array.Float64Builder).Append(-float64(i))
+	}
+	return b.NewRecord()
+}
+
+func TestAvroWriterRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	for _, codec := range []integrations.AvroCodec{
+		integrations.AvroCodecNull, integrations.AvroCodecDeflate, integrations.AvroCodecSnappy, integrations.AvroCodecZstandard,
+	} {
+		t.Run(string(codec), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.avro")
+			first, second := avroWriterRecord(0, 5), avroWriterRecord(5, 4)
+			defer first.Release()
+			defer second.Release()
+
+			writer, err := integrations.NewAvroWriter(ctx, path, first.Schema(), &integrations.AvroWriteOptions{
+				Codec:     codec,
+				BlockRows: 3,
+			})
+			require.NoError(t, err)
+			require.NoError(t, writer.Write(first))
+			require.NoError(t, writer.Write(second))
+			require.NoError(t, writer.Close())
+
+			reader, err := integrations.NewAvroReader(ctx, path, &integrations.AvroReadOptions{ChunkSize: 100})
+			require.NoError(t, err)
+			defer reader.Close()
+			rec, err := reader.Read()
+			require.NoError(t, err)
+			defer rec.Release()
+
+			require.EqualValues(t, 9, rec.NumRows())
+			schema := rec.Schema()
+			require.Equal(t, []string{"id", "name", "price", "ts", "day", "tags", "point"}, fieldNames(schema))
+			require.True(t, schema.Field(1).Nullable)
+			require.Equal(t, &arrow.Decimal128Type{Precision: 12, Scale: 2}, schema.Field(2).Type)
+			require.Equal(t, &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}, schema.Field(3).Type)
+
+			require.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8}, rec.Column(0).(*array.Int64).Int64Values())
+			require.Equal(t, "even", rec.Column(1).ValueStr(4))
+			require.True(t, rec.Column(1).IsNull(5))
+			require.Equal(t, "-1.5", rec.Column(2).ValueStr(0))
+			require.Equal(t, "6.5", rec.Column(2).ValueStr(8))
+			base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			require.Equal(t, arrow.Timestamp(base.Add(8*time.Second).UnixMicro()), rec.Column(3).(*array.Timestamp).Value(8))
+			require.Equal(t, arrow.Date32FromTime(base), rec.Column(4).(*array.Date32).Value(0))
+			require.Equal(t, `["a","b"]`, rec.Column(5).ValueStr(1))
+			require.Equal(t, `{"x":7,"y":-7}`, rec.Column(6).ValueStr(7))
+		})
+	}
+}
+
+func TestAvroWriterAsPipelineSink(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in.arrow")
+	dst := filepath.Join(dir, "out.avro")
+
+	rec := featherRecord(0, 50)
+	defer rec.Release()
+	fw, err := integrations.NewFeatherWriter(ctx, src, rec.Schema(), nil)
+	require.NoError(t, err)
+	require.NoError(t, fw.Write(rec))
+	require.NoError(t, fw.Close())
+
+	reader, err := integrations.NewFeatherReader(ctx, src, nil)
+	require.NoError(t, err)
+	writer, err := integrations.NewAvroWriter(ctx, dst, reader.Schema(), nil)
+	require.NoError(t, err)
+	p := pipeline.NewDataPipeline(reader, writer)
+	_, err = p.Start(ctx)
+	require.NoError(t, err)
+	require.NoError(t, <-p.Done())
+
+	avroReader, err := integrations.NewAvroReader(ctx, dst, nil)
+	require.NoError(t, err)
+	defer avroReader.Close()
+	ids := readAllIDs(t, avroReader)
+	require.Len(t, ids, 50)
+	require.Equal(t, int64(49), ids[49])
+}
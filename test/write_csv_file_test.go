@@ -0,0 +1,173 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildCSVTestRecord(t *testing.T) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "score", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}},
+	}, nil)
+
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	ts, err := arrow.TimestampFromTime(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), arrow.Millisecond)
+	require.NoError(t, err)
+
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{`say "hi", bob`, ""}, []bool{true, false})
+	b.Field(2).(*array.Float64Builder).AppendValues([]float64{1.23456, 2}, nil)
+	b.Field(3).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{ts, ts}, nil)
+	return b.NewRecord()
+}
+
+func writeCSVTestFile(t *testing.T, path string, opts *integrations.CSVWriteOptions) {
+	t.Helper()
+	record := buildCSVTestRecord(t)
+	defer record.Release()
+
+	writer, err := integrations.NewCSVWriter(context.Background(), path, record.Schema(), opts)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+}
+
+func TestCSVWriterFormatting(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		opts     *integrations.CSVWriteOptions
+		expected string
+	}{
+		{
+			name: "minimal quoting",
+			opts: &integrations.CSVWriteOptions{Delimiter: ',', IncludeHeader: true, NullValue: "NULL"},
+			expected: "id,name,score,ts\n" +
+				"1,\"say \"\"hi\"\", bob\",1.23456,2024-05-01T12:30:00Z\n" +
+				"2,NULL,2,2024-05-01T12:30:00Z\n",
+		},
+		{
+			name: "non-numeric quoting with CRLF, BOM and precision",
+			opts: &integrations.CSVWriteOptions{
+				Delimiter:       ';',
+				IncludeHeader:   true,
+				Quoting:         integrations.QuoteNonNumeric,
+				UseCRLF:         true,
+				WriteBOM:        true,
+				FloatPrecision:  2,
+				TimestampLayout: "2006-01-02 15:04",
+			},
+			expected: "\xEF\xBB\xBF\"id\";\"name\";\"score\";\"ts\"\r\n" +
+				"1;\"say \"\"hi\"\", bob\";1.23;\"2024-05-01 12:30\"\r\n" +
+				"2;;2.00;\"2024-05-01 12:30\"\r\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".csv")
+			writeCSVTestFile(t, path, tt.opts)
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data), "case %d", i)
+		})
+	}
+}
+
+func TestCSVWriterQuoteNoneRejectsSpecialFields(t *testing.T) {
+	t.Parallel()
+	record := buildCSVTestRecord(t)
+	defer record.Release()
+
+	writer, err := integrations.NewCSVWriter(context.Background(), filepath.Join(t.TempDir(), "out.csv"), record.Schema(), &integrations.CSVWriteOptions{
+		Quoting: integrations.QuoteNone,
+	})
+	require.NoError(t, err)
+	defer writer.Close()
+	assert.Error(t, writer.Write(record))
+}
+
+func TestCSVWriterCompression(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	opts := func() *integrations.CSVWriteOptions {
+		return &integrations.CSVWriteOptions{Delimiter: ',', IncludeHeader: true}
+	}
+
+	plainPath := filepath.Join(dir, "plain.csv")
+	writeCSVTestFile(t, plainPath, opts())
+	plain, err := os.ReadFile(plainPath)
+	require.NoError(t, err)
+
+	gzPath := filepath.Join(dir, "out.csv.gz")
+	writeCSVTestFile(t, gzPath, opts())
+	gzFile, err := os.Open(gzPath)
+	require.NoError(t, err)
+	defer gzFile.Close()
+	gzReader, err := gzip.NewReader(gzFile)
+	require.NoError(t, err)
+	gzData, err := io.ReadAll(gzReader)
+	require.NoError(t, err)
+	assert.Equal(t, string(plain), string(gzData))
+
+	zstPath := filepath.Join(dir, "out.csv.zst")
+	writeCSVTestFile(t, zstPath, opts())
+	zstFile, err := os.Open(zstPath)
+	require.NoError(t, err)
+	defer zstFile.Close()
+	zstReader, err := zstd.NewReader(zstFile)
+	require.NoError(t, err)
+	defer zstReader.Close()
+	zstData, err := io.ReadAll(zstReader)
+	require.NoError(t, err)
+	assert.Equal(t, string(plain), string(zstData))
+}
@@ -0,0 +1,139 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/pipeline"
+	"github.com/stretchr/testify/require"
+)
+
+func featherRecord(start, n int64) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	for i := start; i < start+n; i++ {
+		b.Field(0).(*array.Int64Builder).Append(i)
+		b.Field(1).(*array.StringBuilder).Append(fmt.Sprintf("row %d", i))
+	}
+	return b.NewRecord()
+}
+
+func readAllIDs(t *testing.T, reader integrations.FileReader) []int64 {
+	t.Helper()
+	var ids []int64
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return ids
+		}
+		require.NoError(t, err)
+		ids = append(ids, rec.Column(0).(*array.Int64).Int64Values()...)
+		rec.Release()
+	}
+}
+
+func TestFeatherRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	for _, compression := range []integrations.IPCCompression{
+		integrations.IPCCompressionNone, integrations.IPCCompressionLZ4, integrations.IPCCompressionZstd,
+	} {
+		for _, memoryMap := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/mmap=%v", compression, memoryMap), func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "data.feather")
+				first, second := featherRecord(0, 5), featherRecord(5, 3)
+				defer first.Release()
+				defer second.Release()
+
+				writer, err := integrations.NewFeatherWriter(ctx, path, first.Schema(), &integrations.IPCWriteOptions{Compression: compression})
+				require.NoError(t, err)
+				require.NoError(t, writer.Write(first))
+				require.NoError(t, writer.Write(second))
+				require.NoError(t, writer.Close())
+
+				feather, err := integrations.IsFeatherFile(path)
+				require.NoError(t, err)
+				require.True(t, feather)
+
+				reader, err := integrations.NewFeatherReader(ctx, path, &integrations.FeatherReadOptions{MemoryMap: memoryMap})
+				require.NoError(t, err)
+				require.True(t, reader.Schema().Equal(first.Schema()))
+				require.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7}, readAllIDs(t, reader))
+				require.NoError(t, reader.Close())
+				require.NoError(t, reader.Close())
+			})
+		}
+	}
+}
+
+func TestFeatherPipelineFromIPCStream(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	streamPath := filepath.Join(dir, "data.arrows")
+	rec := featherRecord(0, 100)
+	defer rec.Release()
+
+	stream, err := integrations.NewIPCRecordWriterWithOptions(ctx, streamPath, rec.Schema(),
+		&integrations.IPCWriteOptions{Compression: integrations.IPCCompressionLZ4})
+	require.NoError(t, err)
+	require.NoError(t, stream.Write(rec))
+	require.NoError(t, stream.Close())
+
+	_, err = integrations.NewIPCRecordWriterWithOptions(ctx, filepath.Join(dir, "bad.arrows"), rec.Schema(),
+		&integrations.IPCWriteOptions{Compression: "brotli"})
+	require.ErrorContains(t, err, "unsupported IPC compression")
+
+	source, err := integrations.OpenSource(ctx, streamPath, nil)
+	require.NoError(t, err)
+	featherPath := filepath.Join(dir, "data.arrow")
+	sink, err := integrations.NewFeatherWriter(ctx, featherPath, source.Schema(), nil)
+	require.NoError(t, err)
+	_, err = pipeline.NewDataPipeline(source, sink).Start(ctx)
+	require.NoError(t, err)
+
+	// .arrow files are sniffed for the IPC file format.
+	reader, err := integrations.OpenSource(ctx, featherPath, &integrations.SourceOptions{MemoryMap: true})
+	require.NoError(t, err)
+	defer reader.Close()
+	require.IsType(t, &integrations.FeatherReader{}, reader)
+	require.Len(t, readAllIDs(t, reader), 100)
+}
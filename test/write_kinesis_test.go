@@ -0,0 +1,270 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	kinesissink "github.com/arrowarc/arrowarc/integrations/kinesis"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	firehosetypes "github.com/aws/aws-sdk-go-v2/service/firehose/types"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	kinesistypes "github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKinesis records the entries put and throttles the first throttle
+// entries of each request while throttle is positive.
+type fakeKinesis struct {
+	shards   []kinesistypes.Shard
+	requests [][]kinesistypes.PutRecordsRequestEntry
+	accepted []kinesistypes.PutRecordsRequestEntry
+	throttle int
+	listed   int
+}
+
+func (f *fakeKinesis) PutRecords(_ context.Context, in *kinesis.PutRecordsInput, _ ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error) {
+	f.requests = append(f.requests, in.Records)
+	out := &kinesis.PutRecordsOutput{}
+	var failed int32
+	for i, r := range in.Records {
+		if i < f.throttle {
+			failed++
+			out.Records = append(out.Records, kinesistypes.PutRecordsResultEntry{ErrorCode: aws.String("ProvisionedThroughputExceededException")})
+			continue
+		}
+		f.accepted = append(f.accepted, r)
+		out.Records = append(out.Records, kinesistypes.PutRecordsResultEntry{SequenceNumber: aws.String("1"), ShardId: aws.String("shard")})
+	}
+	if f.throttle > 0 {
+		f.throttle--
+	}
+	out.FailedRecordCount = aws.Int32(failed)
+	return out, nil
+}
+
+func (f *fakeKinesis) ListShards(_ context.Context, in *kinesis.ListShardsInput, _ ...func(*kinesis.Options)) (*kinesis.ListShardsOutput, error) {
+	f.listed++
+	// Page one shard at a time.
+	i := 0
+	if in.NextToken != nil {
+		i = int(aws.ToString(in.NextToken)[0] - '0')
+	}
+	out := &kinesis.ListShardsOutput{Shards: f.shards[i : i+1]}
+	if i+1 < len(f.shards) {
+		out.NextToken = aws.String(string(rune('0' + i + 1)))
+	}
+	return out, nil
+}
+
+type fakeFirehose struct {
+	requests [][]firehosetypes.Record
+}
+
+func (f *fakeFirehose) PutRecordBatch(_ context.Context, in *firehose.PutRecordBatchInput, _ ...func(*firehose.Options)) (*firehose.PutRecordBatchOutput, error) {
+	f.requests = append(f.requests, in.Records)
+	out := &firehose.PutRecordBatchOutput{FailedPutCount: aws.Int32(0)}
+	for range in.Records {
+		out.RequestResponses = append(out.RequestResponses, firehosetypes.PutRecordBatchResponseEntry{RecordId: aws.String("id")})
+	}
+	return out, nil
+}
+
+func kinesisShard(id, startHash string, closed bool) kinesistypes.Shard {
+	shard := kinesistypes.Shard{
+		ShardId:             aws.String(id),
+		HashKeyRange:        &kinesistypes.HashKeyRange{StartingHashKey: aws.String(startHash), EndingHashKey: aws.String(startHash)},
+		SequenceNumberRange: &kinesistypes.SequenceNumberRange{StartingSequenceNumber: aws.String("0")},
+	}
+	if closed {
+		shard.SequenceNumberRange.EndingSequenceNumber = aws.String("9")
+	}
+	return shard
+}
+
+func kinesisTestRecord(t *testing.T, rows int) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "customer", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	for i := 0; i < rows; i++ {
+		b.Field(0).(*array.Int64Builder).Append(int64(i))
+		if i%3 == 2 {
+			b.Field(1).AppendNull()
+		} else {
+			b.Field(1).(*array.StringBuilder).Append([]string{"acme", "globex"}[i%3])
+		}
+	}
+	return b.NewRecord()
+}
+
+func TestKinesisWriterJSONBatchesAndSpreadsOverShards(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKinesis{shards: []kinesistypes.Shard{
+		kinesisShard("a", "0", false),
+		kinesisShard("old", "5", true),
+		kinesisShard("b", "100", false),
+	}}
+	opts := kinesissink.NewDefaultKinesisWriteOptions()
+	opts.MaxBatchRecords = 4
+	w, err := kinesissink.NewKinesisStreamWriter(ctx, client, "events", opts)
+	require.NoError(t, err)
+	require.Equal(t, 3, client.listed)
+
+	record := kinesisTestRecord(t, 10)
+	defer record.Release()
+	require.NoError(t, w.Write(record))
+	require.Len(t, client.requests, 2, "a partial batch waits for Close")
+	require.NoError(t, w.Close())
+	require.Len(t, client.requests, 3)
+	require.Len(t, client.requests[2], 2)
+
+	require.Len(t, client.accepted, 10)
+	for i, entry := range client.accepted {
+		require.Equal(t, []string{"0", "100"}[i%2], aws.ToString(entry.ExplicitHashKey), "closed shards are skipped")
+		require.True(t, bytes.HasSuffix(entry.Data, []byte("\n")))
+		var row map[string]any
+		require.NoError(t, json.Unmarshal(entry.Data, &row))
+		require.EqualValues(t, i, row["id"])
+	}
+}
+
+func TestKinesisWriterPartitionKeyColumnAndThrottling(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKinesis{throttle: 2}
+	opts := kinesissink.NewDefaultKinesisWriteOptions()
+	opts.PartitionKeyColumn = "customer"
+	opts.RetryBackoff = time.Millisecond
+	w, err := kinesissink.NewKinesisStreamWriter(ctx, client, "events", opts)
+	require.NoError(t, err)
+	require.Zero(t, client.listed)
+
+	record := kinesisTestRecord(t, 3)
+	defer record.Release()
+	require.NoError(t, w.Write(record))
+	require.NoError(t, w.Close())
+
+	// Two throttled entries, then one, then none.
+	require.Len(t, client.requests, 3)
+	require.Len(t, client.requests[1], 2)
+	require.Len(t, client.requests[2], 1)
+	require.Len(t, client.accepted, 3)
+	keys := map[string]bool{}
+	for _, entry := range client.accepted {
+		require.Nil(t, entry.ExplicitHashKey)
+		keys[aws.ToString(entry.PartitionKey)] = true
+	}
+	require.Equal(t, map[string]bool{"acme": true, "globex": true, "null": true}, keys)
+}
+
+func TestKinesisWriterGivesUpAfterMaxRetries(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKinesis{throttle: 100}
+	opts := kinesissink.NewDefaultKinesisWriteOptions()
+	opts.PartitionKeyColumn = "id"
+	opts.MaxRetries = 2
+	opts.RetryBackoff = time.Millisecond
+	w, err := kinesissink.NewKinesisStreamWriter(ctx, client, "events", opts)
+	require.NoError(t, err)
+
+	record := kinesisTestRecord(t, 1)
+	defer record.Release()
+	require.NoError(t, w.Write(record))
+	err = w.Close()
+	require.ErrorContains(t, err, "ProvisionedThroughputExceededException")
+	require.Len(t, client.requests, 3)
+}
+
+func TestKinesisWriterParquetSplitsLargeRecords(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKinesis{}
+	opts := kinesissink.NewDefaultKinesisWriteOptions()
+	opts.Format = kinesissink.KinesisParquet
+	opts.PartitionKeyColumn = "id"
+	opts.MaxBatchBytes = 8 << 10
+	w, err := kinesissink.NewKinesisStreamWriter(ctx, client, "events", opts)
+	require.NoError(t, err)
+
+	record := kinesisTestRecord(t, 5000)
+	defer record.Release()
+	require.NoError(t, w.Write(record))
+	require.NoError(t, w.Close())
+
+	require.Greater(t, len(client.accepted), 1)
+	var rows int64
+	for i, entry := range client.accepted {
+		require.LessOrEqual(t, len(entry.Data), 8<<10)
+		pf, err := file.NewParquetReader(bytes.NewReader(entry.Data))
+		require.NoError(t, err)
+		fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.NewGoAllocator())
+		require.NoError(t, err)
+		table, err := fr.ReadTable(ctx)
+		require.NoError(t, err)
+		first := table.Column(0).Data().Chunk(0).(*array.Int64).Value(0)
+		require.Equal(t, strconv.FormatInt(first, 10), aws.ToString(entry.PartitionKey))
+		if i == 0 {
+			require.Zero(t, first)
+		}
+		rows += table.NumRows()
+		table.Release()
+	}
+	require.EqualValues(t, 5000, rows)
+}
+
+func TestFirehoseWriter(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeFirehose{}
+	w, err := kinesissink.NewFirehoseWriter(ctx, client, "deliveries", nil)
+	require.NoError(t, err)
+
+	record := kinesisTestRecord(t, 1200)
+	defer record.Release()
+	require.NoError(t, w.Write(record))
+	require.NoError(t, w.Close())
+
+	require.Len(t, client.requests, 3)
+	require.Len(t, client.requests[0], 500)
+	require.Len(t, client.requests[2], 200)
+	require.Equal(t, `{"customer":"acme","id":0}`+"\n", string(client.requests[0][0].Data))
+}
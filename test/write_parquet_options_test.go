@@ -0,0 +1,155 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/arrowarc/arrowarc/internal/testutil"
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+)
+
+func writeOptionsTestFile(t *testing.T, opts *integrations.ParquetWriteOptions, numRows int) string {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	path := filepath.Join(t.TempDir(), "options.parquet")
+	testutil.WriteParquet(t, path, schema, opts, func(b *array.RecordBuilder) {
+		for i := 0; i < numRows; i++ {
+			b.Field(0).(*array.Int64Builder).Append(int64(i))
+			if i%10 == 0 {
+				b.Field(1).AppendNull()
+			} else {
+				b.Field(1).(*array.StringBuilder).Append(fmt.Sprintf("name-%d", i))
+			}
+		}
+	})
+	return path
+}
+
+func TestParquetWriteOptions(t *testing.T) {
+	zstd := compress.Codecs.Zstd
+	path := writeOptionsTestFile(t, &integrations.ParquetWriteOptions{
+		MaxRowGroupLength: 100,
+		DataPageSize:      4096,
+		Compression:       &zstd,
+		ColumnCompression: map[string]compress.Compression{"name": compress.Codecs.Gzip},
+		DisableDictionary: true,
+		ColumnDictionary:  map[string]bool{"name": true},
+		SortingColumns:    []integrations.ParquetSortingColumn{{Column: "id", Descending: false}},
+	}, 250)
+
+	rdr, err := file.OpenParquetFile(path, false)
+	require.NoError(t, err)
+	defer rdr.Close()
+
+	require.Equal(t, 3, rdr.NumRowGroups())
+	require.EqualValues(t, 250, rdr.NumRows())
+
+	rg := rdr.MetaData().RowGroup(0)
+	id, err := rg.ColumnChunk(0)
+	require.NoError(t, err)
+	name, err := rg.ColumnChunk(1)
+	require.NoError(t, err)
+
+	require.Equal(t, compress.Codecs.Zstd, id.Compression())
+	require.Equal(t, compress.Codecs.Gzip, name.Compression())
+	require.False(t, id.HasDictionaryPage(), "dictionary should be disabled by default")
+	require.True(t, name.HasDictionaryPage(), "dictionary should be enabled for name")
+	require.Equal(t, []parquet.SortingColumn{{ColumnIdx: 0}}, rg.SortingColumns())
+}
+
+func TestParquetWriteOptionsBloomFilter(t *testing.T) {
+	path := writeOptionsTestFile(t, &integrations.ParquetWriteOptions{
+		MaxRowGroupLength:  500,
+		BloomFilterColumns: []string{"id", "name"},
+	}, 1000)
+
+	// The rewritten footer must still be readable by the Arrow reader.
+	rdr, err := file.OpenParquetFile(path, false)
+	require.NoError(t, err)
+	require.EqualValues(t, 1000, rdr.NumRows())
+	require.Equal(t, 2, rdr.NumRowGroups())
+	rdr.Close()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := f.Stat()
+	require.NoError(t, err)
+	pf, err := pq.OpenFile(f, info.Size())
+	require.NoError(t, err)
+
+	for rgIdx, rg := range pf.RowGroups() {
+		idFilter := rg.ColumnChunks()[0].BloomFilter()
+		require.NotNil(t, idFilter, "row group %d should have an id bloom filter", rgIdx)
+		nameFilter := rg.ColumnChunks()[1].BloomFilter()
+		require.NotNil(t, nameFilter, "row group %d should have a name bloom filter", rgIdx)
+
+		first := int64(rgIdx * 500)
+		ok, err := idFilter.Check(pq.ValueOf(first + 1))
+		require.NoError(t, err)
+		require.True(t, ok)
+		ok, err = nameFilter.Check(pq.ValueOf(fmt.Sprintf("name-%d", first+1)))
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		// Values from the other row group should mostly be rejected.
+		var falsePositives int
+		for i := int64(0); i < 500; i++ {
+			other := (first + 500 + i) % 1000
+			if ok, _ := idFilter.Check(pq.ValueOf(other)); ok {
+				falsePositives++
+			}
+		}
+		require.Less(t, falsePositives, 25)
+	}
+}
+
+func TestParquetWriteOptionsUnknownColumn(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	_, err := integrations.NewParquetWriterWithOptions(filepath.Join(t.TempDir(), "x.parquet"), schema, &integrations.ParquetWriteOptions{
+		BloomFilterColumns: []string{"missing"},
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,105 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/stretchr/testify/require"
+)
+
+// parquetFileMetadata returns the created_by string and the key-value
+// metadata of the Parquet file at path.
+func parquetFileMetadata(t *testing.T, path string) (string, map[string]string) {
+	t.Helper()
+	rdr, err := file.OpenParquetFile(path, false)
+	require.NoError(t, err)
+	defer rdr.Close()
+	md := map[string]string{}
+	kv := rdr.MetaData().KeyValueMetadata()
+	for i, key := range kv.Keys() {
+		md[key] = kv.Values()[i]
+	}
+	return rdr.MetaData().GetCreatedBy(), md
+}
+
+func TestConvertCSVToParquetReproducible(t *testing.T) {
+	in := t.TempDir()
+	for i := 0; i < 6; i++ {
+		var sb strings.Builder
+		sb.WriteString("id,region\n")
+		for j := 0; j < 200; j++ {
+			fmt.Fprintf(&sb, "%d,r%d\n", i*200+j, j%3)
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(in, fmt.Sprintf("part-%d.csv", i)), []byte(sb.String()), 0o644))
+	}
+
+	convert := func(out string, partitionBy []string) {
+		_, err := converter.ConvertCSVToParquetWithOptions(context.Background(), in, out, &converter.CSVToParquetOptions{
+			HasHeader:    true,
+			ChunkSize:    1024,
+			Delimiter:    ',',
+			Concurrency:  4,
+			PartitionBy:  partitionBy,
+			Reproducible: true,
+		})
+		require.NoError(t, err)
+	}
+
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.parquet"), filepath.Join(dir, "second.parquet")
+	convert(first, nil)
+	convert(second, nil)
+	a, err := os.ReadFile(first)
+	require.NoError(t, err)
+	b, err := os.ReadFile(second)
+	require.NoError(t, err)
+	require.Equal(t, a, b, "converting the same input twice should write the same bytes")
+	createdBy, md := parquetFileMetadata(t, first)
+	require.Equal(t, integrations.ParquetCreatedBy, createdBy)
+	require.NotContains(t, md, "inferred_at")
+
+	// Partitioned files are written reproducibly as well.
+	partitioned := func(out string) []byte {
+		convert(out, []string{"region"})
+		data, err := os.ReadFile(filepath.Join(out, "region=r0", "part-00000.parquet"))
+		require.NoError(t, err)
+		return data
+	}
+	require.Equal(t, partitioned(filepath.Join(dir, "p1")), partitioned(filepath.Join(dir, "p2")))
+}
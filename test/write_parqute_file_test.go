@@ -31,7 +31,7 @@ package test
 
 import (
 	"context"
-	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -44,21 +44,17 @@ import (
 func TestWriteParquetFileStream(t *testing.T) {
 	t.Parallel() // Parallelize the top-level test
 
+	dir := t.TempDir()
+
 	// Generate two sample Parquet files for testing: one simple and one complex
-	inputSimpleFilePath := "sample_input_simple.parquet"
+	inputSimpleFilePath := filepath.Join(dir, "sample_input_simple.parquet")
 	err := generator.GenerateParquetFile(inputSimpleFilePath, 100*1024, false) // 100 KB, simple structure
 	require.NoError(t, err, "Error should be nil when generating simple input Parquet file")
 
-	inputComplexFilePath := "sample_input_complex.parquet"
+	inputComplexFilePath := filepath.Join(dir, "sample_input_complex.parquet")
 	err = generator.GenerateParquetFile(inputComplexFilePath, 100*1024, true) // 100 KB, complex structure
 	require.NoError(t, err, "Error should be nil when generating complex input Parquet file")
 
-	// Ensure the files are removed after all tests complete
-	t.Cleanup(func() {
-		os.Remove(inputSimpleFilePath)
-		os.Remove(inputComplexFilePath)
-	})
-
 	tests := []struct {
 		inputFilePath  string
 		outputFilePath string
@@ -68,14 +64,14 @@ func TestWriteParquetFileStream(t *testing.T) {
 	}{
 		{
 			inputFilePath:  inputSimpleFilePath,
-			outputFilePath: "sample_output_simple.parquet",
+			outputFilePath: filepath.Join(dir, "sample_output_simple.parquet"),
 			chunkSize:      1024,
 			description:    "Read and write simple Parquet file",
 			useCustomOpts:  false,
 		},
 		{
 			inputFilePath:  inputComplexFilePath,
-			outputFilePath: "sample_output_complex.parquet",
+			outputFilePath: filepath.Join(dir, "sample_output_complex.parquet"),
 			chunkSize:      2048,
 			description:    "Read and write complex Parquet file",
 			useCustomOpts:  false,
@@ -114,9 +110,6 @@ func TestWriteParquetFileStream(t *testing.T) {
 
 			// Print the metrics report
 			t.Log(metrics)
-			t.Cleanup(func() {
-				os.Remove(test.outputFilePath)
-			})
 		})
 	}
 }
@@ -0,0 +1,260 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/arrowarc/arrowarc/converter"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionedParquetWriter(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "region", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+
+	writer, err := integrations.NewPartitionedParquetWriter(ctx, dir, schema, &integrations.PartitionedParquetWriteOptions{
+		PartitionColumns: []string{"region"},
+		SortColumns:      []integrations.ParquetSortingColumn{{Column: "id"}},
+		MaxFileBytes:     40,
+	})
+	require.NoError(t, err)
+
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	for batch := 0; batch < 4; batch++ {
+		for i := 9; i >= 0; i-- {
+			id := int64(batch*10 + i)
+			switch id % 3 {
+			case 0:
+				b.Field(0).(*array.StringBuilder).Append("eu/west")
+			case 1:
+				b.Field(0).(*array.StringBuilder).Append("us")
+			default:
+				b.Field(0).(*array.StringBuilder).AppendNull()
+			}
+			b.Field(1).(*array.Int64Builder).Append(id)
+		}
+		rec := b.NewRecord()
+		require.NoError(t, writer.Write(rec))
+		rec.Release()
+	}
+	require.NoError(t, writer.Close())
+
+	data, err := os.ReadFile(filepath.Join(dir, integrations.DefaultPartitionManifest))
+	require.NoError(t, err)
+	var manifest integrations.PartitionManifest
+	require.NoError(t, json.Unmarshal(data, &manifest))
+
+	filesPerPartition := map[string]int{}
+	var total int64
+	for _, f := range manifest.Files {
+		filesPerPartition[f.Partition["region"]]++
+		total += f.Rows
+		require.FileExists(t, filepath.Join(dir, f.Path))
+		require.Equal(t, fileChecksum(t, filepath.Join(dir, f.Path)), f.Checksum)
+
+		reader, err := integrations.NewParquetReader(ctx, filepath.Join(dir, f.Path), &integrations.ParquetReadOptions{ChunkSize: 1024})
+		require.NoError(t, err)
+		require.Equal(t, []string{"id"}, fieldNames(reader.Schema()))
+		var ids []int64
+		for {
+			rec, err := reader.Read()
+			if err != nil {
+				break
+			}
+			ids = append(ids, rec.Column(0).(*array.Int64).Int64Values()...)
+			rec.Release()
+		}
+		reader.Close()
+		require.Len(t, ids, int(f.Rows))
+		require.IsIncreasing(t, ids, "rows of %s should be sorted", f.Path)
+	}
+	require.Equal(t, int64(40), total)
+	require.True(t, manifest.Complete)
+	require.Len(t, filesPerPartition, 3)
+	require.Greater(t, filesPerPartition["us"], 1, "partitions should rotate by size")
+	require.DirExists(t, filepath.Join(dir, "region=eu%2Fwest"))
+	require.DirExists(t, filepath.Join(dir, "region="+integrations.HivePartitionNull))
+}
+
+func fieldNames(schema *arrow.Schema) []string {
+	names := make([]string, schema.NumFields())
+	for i, f := range schema.Fields() {
+		names[i] = f.Name
+	}
+	return names
+}
+
+func fileChecksum(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sourceRecord returns a record of rows ids from start, partitioned by id
+// parity and tagged with source.
+func sourceRecord(schema *arrow.Schema, source string, start int64) arrow.Record {
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	for id := start; id < start+10; id++ {
+		b.Field(0).(*array.StringBuilder).Append([]string{"even", "odd"}[id%2])
+		b.Field(1).(*array.Int64Builder).Append(id)
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+	md := arrow.NewMetadata([]string{integrations.SourceMetadataKey}, []string{source})
+	return array.NewRecord(arrow.NewSchema(schema.Fields(), &md), rec.Columns(), rec.NumRows())
+}
+
+func TestPartitionedParquetWriterResumes(t *testing.T) {
+	dir := t.TempDir()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "parity", Type: arrow.BinaryTypes.String},
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+	opts := &integrations.PartitionedParquetWriteOptions{PartitionColumns: []string{"parity"}, Resume: true}
+	write := func(w *integrations.PartitionedParquetWriter, source string, start int64) {
+		rec := sourceRecord(schema, source, start)
+		defer rec.Release()
+		require.NoError(t, w.Write(rec))
+	}
+
+	// The first run is interrupted while writing c.
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := integrations.NewPartitionedParquetWriter(ctx, dir, schema, opts)
+	require.NoError(t, err)
+	write(w, "a", 0)
+	write(w, "b", 10)
+	write(w, "c", 20)
+	cancel()
+	require.NoError(t, w.Close())
+
+	manifest, err := integrations.ReadPartitionManifest(dir, "")
+	require.NoError(t, err)
+	require.False(t, manifest.Complete)
+	require.Equal(t, []string{"a", "b"}, manifest.Completed)
+	require.Len(t, manifest.Files, 6)
+	for _, f := range manifest.Files {
+		require.Len(t, f.Sources, 1)
+		require.EqualValues(t, 5, f.Rows)
+	}
+
+	// A stray file of a run that was killed goes too.
+	stray := filepath.Join(dir, "parity=odd", "part-00009.parquet")
+	require.NoError(t, os.WriteFile(stray, []byte("partial"), 0o644))
+
+	w, err = integrations.NewPartitionedParquetWriter(context.Background(), dir, schema, opts)
+	require.NoError(t, err)
+	require.NoFileExists(t, filepath.Join(dir, "parity=even", "part-00002.parquet"))
+	require.NoFileExists(t, stray)
+	write(w, "c", 20)
+	require.NoError(t, w.Close())
+
+	manifest, err = integrations.ReadPartitionManifest(dir, "")
+	require.NoError(t, err)
+	require.True(t, manifest.Complete)
+	require.Equal(t, []string{"a", "b", "c"}, manifest.Completed)
+	require.Len(t, manifest.Files, 6)
+	var total int64
+	for _, f := range manifest.Files {
+		require.Equal(t, fileChecksum(t, filepath.Join(dir, f.Path)), f.Checksum)
+		total += f.Rows
+	}
+	require.EqualValues(t, 30, total)
+	require.Equal(t, "c", manifest.Files[5].Sources[0])
+	require.Equal(t, "parity=odd/part-00002.parquet", manifest.Files[5].Path)
+}
+
+func TestConvertCSVToParquetResumes(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	for i := 0; i < 3; i++ {
+		data := fmt.Sprintf("region,id\nus,%d\neu,%d\n", 2*i, 2*i+1)
+		require.NoError(t, os.WriteFile(filepath.Join(in, fmt.Sprintf("day%d.csv", i)), []byte(data), 0o644))
+	}
+	convert := func() string {
+		report, err := converter.ConvertCSVToParquetWithOptions(context.Background(), in, out, &converter.CSVToParquetOptions{
+			HasHeader:   true,
+			ChunkSize:   1024,
+			Delimiter:   ',',
+			Concurrency: 4,
+			PartitionBy: []string{"region"},
+			Resume:      true,
+		})
+		require.NoError(t, err)
+		return report
+	}
+
+	require.NotEmpty(t, convert())
+	manifest, err := integrations.ReadPartitionManifest(out, "")
+	require.NoError(t, err)
+	require.Len(t, manifest.Completed, 3)
+	require.Len(t, manifest.Files, 6)
+
+	// Nothing is left to convert once every input is completed.
+	require.Empty(t, convert())
+
+	// Forget the last input, as if the run stopped while converting it.
+	last := manifest.Completed[2]
+	manifest.Completed = manifest.Completed[:2]
+	manifest.Complete = false
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(out, integrations.DefaultPartitionManifest), data, 0o644))
+
+	require.NotEmpty(t, convert())
+	manifest, err = integrations.ReadPartitionManifest(out, "")
+	require.NoError(t, err)
+	require.Equal(t, last, manifest.Completed[2])
+	require.Len(t, manifest.Files, 6)
+	var total int64
+	for _, f := range manifest.Files {
+		require.FileExists(t, filepath.Join(out, f.Path))
+		total += f.Rows
+	}
+	require.EqualValues(t, 6, total)
+}
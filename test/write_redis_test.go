@@ -0,0 +1,160 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	redissink "github.com/arrowarc/arrowarc/integrations/redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func redisTestRecord(t *testing.T) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "customer", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		{Name: "address", Type: arrow.StructOf(arrow.Field{Name: "city", Type: arrow.BinaryTypes.String, Nullable: true}), Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"acme", "globex", ""}, []bool{true, true, false})
+	tags := b.Field(2).(*array.ListBuilder)
+	tagValues := tags.ValueBuilder().(*array.StringBuilder)
+	tags.Append(true)
+	tagValues.AppendValues([]string{"new", "vip"}, nil)
+	tags.Append(true)
+	tags.AppendNull()
+	address := b.Field(3).(*array.StructBuilder)
+	city := address.FieldBuilder(0).(*array.StringBuilder)
+	for _, c := range []string{"Austin", "Dallas", "Houston"} {
+		address.Append(true)
+		city.Append(c)
+	}
+	return b.NewRecord()
+}
+
+func TestRedisWriterStreamJSON(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	opts := redissink.NewDefaultRedisWriteOptions()
+	opts.Key = "orders:{customer}"
+	opts.PipelineSize = 2
+	writer, err := redissink.NewRedisWriter(ctx, "redis://"+server.Addr(), opts)
+	require.NoError(t, err)
+
+	record := redisTestRecord(t)
+	defer record.Release()
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	entries, err := client.XRange(ctx, "orders:acme", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	var row map[string]any
+	require.NoError(t, json.Unmarshal([]byte(entries[0].Values["data"].(string)), &row))
+	require.Equal(t, map[string]any{
+		"id":       float64(1),
+		"customer": "acme",
+		"tags":     []any{"new", "vip"},
+		"address":  map[string]any{"city": "Austin"},
+	}, row)
+
+	// A null key column renders empty.
+	entries, err = client.XRange(ctx, "orders:", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Contains(t, entries[0].Values["data"], `"customer":null`)
+}
+
+func TestRedisWriterListMsgpack(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	opts := redissink.NewDefaultRedisWriteOptions()
+	opts.Mode = redissink.RedisList
+	opts.Encoding = redissink.RedisEncodingMsgpack
+	opts.Key = "{{queue}}:orders"
+	writer, err := redissink.NewRedisWriter(ctx, "redis://"+server.Addr(), opts)
+	require.NoError(t, err)
+
+	record := redisTestRecord(t)
+	defer record.Release()
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	items, err := server.List("{queue}:orders")
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+
+	var row map[string]any
+	require.NoError(t, msgpack.Unmarshal([]byte(items[1]), &row))
+	require.Equal(t, int64(2), row["id"])
+	require.Equal(t, "globex", row["customer"])
+	require.Equal(t, []any{}, row["tags"])
+	require.Equal(t, map[string]any{"city": "Dallas"}, row["address"])
+}
+
+func TestRedisWriterKeyTemplate(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"", "orders:{customer", "orders:}", "orders:{}"} {
+		opts := redissink.NewDefaultRedisWriteOptions()
+		opts.Key = key
+		_, err := redissink.NewRedisWriter(ctx, "redis://"+server.Addr(), opts)
+		require.Error(t, err, key)
+	}
+
+	opts := redissink.NewDefaultRedisWriteOptions()
+	opts.Key = "orders:{region}"
+	writer, err := redissink.NewRedisWriter(ctx, "redis://"+server.Addr(), opts)
+	require.NoError(t, err)
+	defer writer.Close()
+
+	record := redisTestRecord(t)
+	defer record.Release()
+	require.ErrorContains(t, writer.Write(record), `key column "region" is not in the schema`)
+}
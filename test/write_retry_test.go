@@ -0,0 +1,188 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	flightwriter "github.com/arrowarc/arrowarc/integrations/flight"
+	"github.com/arrowarc/arrowarc/pkg/common/config"
+	"github.com/arrowarc/arrowarc/pkg/retry"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// flakyPutServer fails the first calls to DoPut, then counts the rows put.
+type flakyPutServer struct {
+	flight.BaseFlightServer
+
+	mu       sync.Mutex
+	failures int
+	calls    int
+	rows     int64
+	paths    [][]string
+}
+
+func (s *flakyPutServer) DoPut(stream flight.FlightService_DoPutServer) error {
+	s.mu.Lock()
+	s.calls++
+	fail := s.calls <= s.failures
+	s.mu.Unlock()
+	if fail {
+		return status.Error(codes.Unavailable, "try again")
+	}
+
+	reader, err := flight.NewRecordReader(stream)
+	if err != nil {
+		return err
+	}
+	defer reader.Release()
+	path := reader.LatestFlightDescriptor().GetPath()
+	for reader.Next() {
+		s.mu.Lock()
+		s.rows += reader.Record().NumRows()
+		s.mu.Unlock()
+	}
+	if err := reader.Err(); err != nil && err != io.EOF {
+		return err
+	}
+	s.mu.Lock()
+	s.paths = append(s.paths, path)
+	s.mu.Unlock()
+	return nil
+}
+
+func startFlakyPutServer(t *testing.T, failures int) (*flakyPutServer, string) {
+	t.Helper()
+	svc := &flakyPutServer{failures: failures}
+	srv := flight.NewServerWithMiddleware(nil)
+	require.NoError(t, srv.Init("localhost:0"))
+	srv.RegisterFlightService(svc)
+	go srv.Serve()
+	t.Cleanup(srv.Shutdown)
+	return svc, srv.Addr().String()
+}
+
+func int64Record(t *testing.T, n int) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	for i := 0; i < n; i++ {
+		b.Field(0).(*array.Int64Builder).Append(int64(i))
+	}
+	return b.NewRecord()
+}
+
+func TestFlightWriterRetries(t *testing.T) {
+	svc, addr := startFlakyPutServer(t, 2)
+	record := int64Record(t, 10)
+	defer record.Release()
+
+	w, err := flightwriter.NewFlightWriter(context.Background(), addr, record.Schema(), &flightwriter.FlightWriteOptions{
+		Path:  []string{"events"},
+		Retry: &retry.Policy{MaxAttempts: 3, InitialInterval: time.Millisecond},
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	require.NoError(t, w.Write(record))
+	require.NoError(t, w.Write(record))
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	require.Equal(t, 4, svc.calls)
+	require.Equal(t, int64(20), svc.rows)
+	require.Equal(t, [][]string{{"events"}, {"events"}}, svc.paths)
+}
+
+func TestFlightWriterGivesUp(t *testing.T) {
+	svc, addr := startFlakyPutServer(t, 5)
+	record := int64Record(t, 1)
+	defer record.Release()
+
+	w, err := flightwriter.NewFlightWriter(context.Background(), addr, record.Schema(), &flightwriter.FlightWriteOptions{
+		Retry: &retry.Policy{MaxAttempts: 2, InitialInterval: time.Millisecond},
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	err = w.Write(record)
+	require.Error(t, err)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	require.Equal(t, 2, svc.calls)
+}
+
+func TestConfigRetryPolicy(t *testing.T) {
+	var cfg config.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+workflow:
+  settings:
+    retry:
+      max_attempts: 4
+      initial_interval: 250ms
+  integrations:
+    - name: warehouse
+      type: destination
+      provider: bigquery
+      retry:
+        max_attempts: 8
+        max_elapsed: 5m
+    - name: lake
+      type: destination
+      provider: gcs
+`), &cfg))
+
+	p, err := cfg.RetryPolicy("warehouse")
+	require.NoError(t, err)
+	require.Equal(t, 8, p.MaxAttempts)
+	require.Equal(t, 5*time.Minute, p.MaxElapsed)
+
+	p, err = cfg.RetryPolicy("lake")
+	require.NoError(t, err)
+	require.Equal(t, 4, p.MaxAttempts)
+	require.Equal(t, 250*time.Millisecond, p.InitialInterval)
+
+	cfg.Workflow.Integrations[1].Retry = &config.RetrySettings{MaxElapsed: "soon"}
+	_, err = cfg.RetryPolicy("lake")
+	require.Error(t, err)
+}
@@ -0,0 +1,109 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	integrations "github.com/arrowarc/arrowarc/integrations/filesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func rotationRecord(start, n int64) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	for i := start; i < start+n; i++ {
+		b.Field(0).(*array.Int64Builder).Append(i)
+	}
+	return b.NewRecord()
+}
+
+func TestRotatingWriterMaxRows(t *testing.T) {
+	dir := t.TempDir()
+	rec := rotationRecord(0, 25)
+	defer rec.Release()
+
+	template := filepath.Join(dir, "{date}", "part-{seq}.csv")
+	writer, err := integrations.NewRotatingCSVWriter(context.Background(), template, rec.Schema(), nil,
+		integrations.RotationOptions{MaxRows: 10})
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(rec))
+	require.NoError(t, writer.Close())
+
+	files := writer.Files()
+	require.Len(t, files, 3)
+	date := time.Now().UTC().Format(time.DateOnly)
+	for i, want := range []int{10, 10, 5} {
+		require.Equal(t, filepath.Join(dir, date, fmt.Sprintf("part-%05d.csv", i)), files[i])
+		data, err := os.ReadFile(files[i])
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Equal(t, "id", lines[0])
+		require.Len(t, lines, want+1)
+	}
+}
+
+func TestRotatingWriterInterval(t *testing.T) {
+	dir := t.TempDir()
+	rec := rotationRecord(0, 5)
+	defer rec.Release()
+
+	writer, err := integrations.NewRotatingParquetWriter(filepath.Join(dir, "events-{uuid}.parquet"), rec.Schema(), nil,
+		integrations.RotationOptions{Interval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(rec))
+	require.NoError(t, writer.Write(rec))
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, writer.Write(rec))
+	require.NoError(t, writer.Close())
+
+	files := writer.Files()
+	require.Len(t, files, 2)
+	require.NotEqual(t, files[0], files[1])
+	for _, f := range files {
+		require.FileExists(t, f)
+	}
+}
+
+func TestRotatingWriterTemplateNeedsUniqueNames(t *testing.T) {
+	_, err := integrations.NewRotatingJSONWriter(context.Background(), "out-{date}.json",
+		integrations.RotationOptions{MaxBytes: 1 << 20})
+	require.Error(t, err)
+}